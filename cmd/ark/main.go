@@ -24,10 +24,12 @@ import (
 
 	"github.com/heptio/ark/pkg/cmd"
 	"github.com/heptio/ark/pkg/cmd/ark"
+	"github.com/heptio/ark/pkg/logging"
 )
 
 func main() {
 	defer glog.Flush()
+	defer logging.Flush()
 
 	baseName := filepath.Base(os.Args[0])
 
@@ -41,14 +41,67 @@ type BackupSpec struct {
 	// or nil, all objects are included. Optional.
 	LabelSelector *metav1.LabelSelector `json:"labelSelector"`
 
+	// IncludeClusterResources specifies whether cluster-scoped resources
+	// are included in the backup. If false, only the cluster-scoped items
+	// that included namespaced items depend on, e.g. the PersistentVolumes
+	// of PersistentVolumeClaims, are included. Optional; defaults to true.
+	IncludeClusterResources *bool `json:"includeClusterResources"`
+
+	// IgnoreDefaultExcludes specifies whether the server's
+	// defaultExcludedResources and excludeCompletedPods are ignored, so
+	// that only the backup's own filters apply. Optional.
+	IgnoreDefaultExcludes bool `json:"ignoreDefaultExcludes"`
+
+	// ExcludeCompletedPods specifies whether pods that have finished
+	// running, with phase Succeeded or Failed, are left out of the
+	// backup. It's set on backups when the server's excludeCompletedPods
+	// is. Optional.
+	ExcludeCompletedPods bool `json:"excludeCompletedPods"`
+
 	// SnapshotVolumes specifies whether to take cloud snapshots
 	// of any PV's referenced in the set of objects included
 	// in the Backup.
 	SnapshotVolumes *bool `json:"snapshotVolumes"`
 
+	// MoveVolumeData specifies whether to copy the data of the
+	// PersistentVolumeClaim-backed volumes of running pods into
+	// object storage using restic, so that it can be restored into
+	// new volumes on any cloud provider. Requires the server to be
+	// configured for restic.
+	MoveVolumeData bool `json:"moveVolumeData"`
+
+	// UploaderType is the uploader that backs up the data of pod volumes,
+	// UploaderTypeRestic or UploaderTypeKopia. If empty, each namespace's
+	// UploaderAnnotation chooses it, and restic is used for namespaces
+	// without one. Optional.
+	UploaderType string `json:"uploaderType"`
+
 	// TTL is a time.Duration-parseable string describing how long
 	// the Backup should be retained for.
 	TTL metav1.Duration `json:"ttl"`
+
+	// ParentBackup is the name of a completed backup that this backup is
+	// incremental to. If set, only items that have changed since the parent
+	// was taken are stored in this backup; restoring it layers its items over
+	// those of the parent. Optional.
+	ParentBackup string `json:"parentBackup"`
+
+	// StorageLocation is the name of the BackupStorageLocation to store
+	// the backup in. It must not be read-only. If empty, the backup is
+	// stored in the Config's defaultBackupStorageLocation. Optional.
+	StorageLocation string `json:"storageLocation"`
+
+	// VolumeSnapshotLocation is the name of the VolumeSnapshotLocation to
+	// take all of the backup's volume snapshots in, whatever their cloud
+	// provider. It's ignored if VolumeSnapshotLocations is set. Optional.
+	VolumeSnapshotLocation string `json:"volumeSnapshotLocation"`
+
+	// VolumeSnapshotLocations are the names of the VolumeSnapshotLocations
+	// to take the backup's volume snapshots in, at most one for each cloud
+	// provider. Each volume is snapshotted in the location for its cloud
+	// provider, or in the Config's default location for the provider if
+	// there isn't one. Optional.
+	VolumeSnapshotLocations []string `json:"volumeSnapshotLocations"`
 }
 
 // BackupPhase is a string representation of the lifecycle phase
@@ -71,9 +124,17 @@ const (
 	// errors.
 	BackupPhaseCompleted BackupPhase = "Completed"
 
+	// BackupPhasePartiallyFailed means the backup has run to completion
+	// but encountered 1+ errors backing up individual items.
+	BackupPhasePartiallyFailed BackupPhase = "PartiallyFailed"
+
 	// BackupPhaseFailed mean the backup ran but encountered an error that
 	// prevented it from completing successfully.
 	BackupPhaseFailed BackupPhase = "Failed"
+
+	// BackupPhaseCanceled means the backup was canceled before it
+	// completed. Nothing it collected is kept.
+	BackupPhaseCanceled BackupPhase = "Canceled"
 )
 
 // BackupStatus captures the current status of an Ark backup.
@@ -87,14 +148,109 @@ type BackupStatus struct {
 	// Phase is the current state of the Backup.
 	Phase BackupPhase `json:"phase"`
 
+	// CompletionTimestamp is when the Backup finished running, whether
+	// it completed, failed, or was canceled.
+	CompletionTimestamp metav1.Time `json:"completionTimestamp"`
+
+	// StorageBucket is the bucket in object storage that the Backup is
+	// stored in, followed by the prefix within it if its location has one.
+	// If empty, it's stored in the default backup storage location.
+	StorageBucket string `json:"storageBucket"`
+
 	// VolumeBackups is a map of PersistentVolume names to
 	// information about the backed-up volume in the cloud
 	// provider API.
 	VolumeBackups map[string]*VolumeBackupInfo `json:"volumeBackups"`
 
+	// PodVolumeSnapshots lists the snapshots of pod volumes' data taken
+	// using restic or kopia.
+	PodVolumeSnapshots []PodVolumeSnapshotInfo `json:"podVolumeSnapshots,omitempty"`
+
 	// ValidationErrors is a slice of all validation errors (if
 	// applicable).
 	ValidationErrors []string `json:"validationErrors"`
+
+	// Warnings is a count of all warning messages that were generated during
+	// execution of the backup. The actual warnings are in the backup's log
+	// file in object storage.
+	Warnings int `json:"warnings"`
+
+	// Errors is a count of all error messages that were generated during
+	// execution of the backup. The actual errors are in the backup's log
+	// file in object storage.
+	Errors int `json:"errors"`
+
+	// ContentChecksum is a SHA-256 checksum of the names and contents of the
+	// files in the backup tarball. It's used to verify the integrity of the
+	// backup in object storage.
+	ContentChecksum string `json:"contentChecksum"`
+
+	// TarballSize is the size, in bytes, of the backup tarball, before
+	// it's deduplicated if its storage location deduplicates backups.
+	TarballSize int64 `json:"tarballSize"`
+
+	// Progress contains information about the backup's execution progress. Note
+	// that this information is best-effort only -- if Ark fails to update it for
+	// any reason, it may be inaccurate/stale.
+	Progress *BackupProgress `json:"progress,omitempty"`
+
+	// Checkpoint records how far the backup got while it was running. It's
+	// used to clean up after a backup that was interrupted by the Ark server
+	// restarting, and is cleared once the backup finishes.
+	Checkpoint *BackupCheckpoint `json:"checkpoint,omitempty"`
+}
+
+// BackupCheckpoint stores the state of a running Backup that's needed to clean
+// up after it if it's interrupted.
+type BackupCheckpoint struct {
+	// CompletedResources lists the resources whose items have all been backed
+	// up so far, in the order in which they were completed.
+	CompletedResources []string `json:"completedResources"`
+
+	// VolumeBackups holds the volume snapshots taken so far, keyed by
+	// PersistentVolume name.
+	VolumeBackups map[string]*VolumeBackupInfo `json:"volumeBackups,omitempty"`
+
+	// PodVolumeSnapshots holds the pod volume snapshots taken so far.
+	PodVolumeSnapshots []PodVolumeSnapshotInfo `json:"podVolumeSnapshots,omitempty"`
+}
+
+// PodVolumeSnapshotInfo describes a snapshot of the data of a pod volume, or
+// of a local or hostPath PersistentVolume, taken using restic or kopia.
+type PodVolumeSnapshotInfo struct {
+	// RepoNamespace is the namespace whose repository holds the snapshot.
+	RepoNamespace string `json:"repoNamespace"`
+
+	// Snapshot identifies the snapshot the way it's recorded on the
+	// backed-up item.
+	Snapshot string `json:"snapshot"`
+}
+
+// BackupProgress stores information about the progress of a Backup's execution.
+type BackupProgress struct {
+	// TotalItems is the total number of items to be backed up. This number may change
+	// throughout the execution of the backup due to new items being discovered.
+	TotalItems int `json:"totalItems"`
+
+	// ItemsBackedUp is the number of items that have actually been written to the
+	// backup tarball so far.
+	ItemsBackedUp int `json:"itemsBackedUp"`
+
+	// VolumeSnapshotsAttempted is the number of PersistentVolumes for which a
+	// snapshot has been requested so far.
+	VolumeSnapshotsAttempted int `json:"volumeSnapshotsAttempted"`
+
+	// VolumeSnapshotsCompleted is the number of PersistentVolume snapshots that
+	// have been successfully created so far.
+	VolumeSnapshotsCompleted int `json:"volumeSnapshotsCompleted"`
+
+	// PodVolumeBackupsAttempted is the number of pod volumes whose data
+	// has been requested to be backed up using restic so far.
+	PodVolumeBackupsAttempted int `json:"podVolumeBackupsAttempted"`
+
+	// PodVolumeBackupsCompleted is the number of pod volumes whose data
+	// has been successfully backed up using restic so far.
+	PodVolumeBackupsCompleted int `json:"podVolumeBackupsCompleted"`
 }
 
 // VolumeBackupInfo captures the required information about
@@ -112,6 +268,44 @@ type VolumeBackupInfo struct {
 	// Iops is the optional value of provisioned IOPS for the
 	// disk/volume in the cloud provider API.
 	Iops *int64 `json:"iops,omitempty"`
+
+	// FreezeError describes why the volume's filesystem couldn't be
+	// frozen, or stay frozen, while it was snapshotted. It's only set if
+	// freezing was requested for the volume and failed.
+	FreezeError string `json:"freezeError,omitempty"`
+
+	// CSISnapshot describes the snapshot of the volume, if it was taken
+	// through the CSI external-snapshotter's VolumeSnapshot API rather
+	// than the cloud provider API. SnapshotID is set to its snapshot
+	// handle.
+	CSISnapshot *CSISnapshotInfo `json:"csiSnapshot,omitempty"`
+
+	// Location is the name of the VolumeSnapshotLocation that the
+	// snapshot was taken in. It's empty for snapshots taken by older
+	// servers, which are in the backup's volumeSnapshotLocation, or if it
+	// doesn't have one, in the location named "default" that replaces the
+	// persistentVolumeProvider.
+	Location string `json:"location,omitempty"`
+}
+
+// CSISnapshotInfo describes a volume snapshot taken by a CSI driver.
+type CSISnapshotInfo struct {
+	// Driver is the name of the CSI driver that took the snapshot.
+	Driver string `json:"driver"`
+
+	// SnapshotHandle is the ID of the snapshot in the CSI driver's
+	// storage system.
+	SnapshotHandle string `json:"snapshotHandle"`
+
+	// VolumeSnapshotClassName is the VolumeSnapshotClass the snapshot
+	// was taken with, if one was configured.
+	VolumeSnapshotClassName string `json:"volumeSnapshotClassName,omitempty"`
+
+	// VolumeSnapshotNamespace and VolumeSnapshotName identify the
+	// VolumeSnapshot that was created to take the snapshot. They're empty
+	// for snapshots taken by older servers.
+	VolumeSnapshotNamespace string `json:"volumeSnapshotNamespace,omitempty"`
+	VolumeSnapshotName      string `json:"volumeSnapshotName,omitempty"`
 }
 
 // +genclient=true
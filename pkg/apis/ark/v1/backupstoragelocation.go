@@ -0,0 +1,100 @@
+/*
+Copyright 2017 Heptio Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+// BackupStorageLocationAccessMode represents the permissions the Ark server
+// has for a BackupStorageLocation.
+type BackupStorageLocationAccessMode string
+
+const (
+	// BackupStorageLocationAccessModeReadWrite means backups can be stored
+	// in, restored from, and deleted from the location.
+	BackupStorageLocationAccessModeReadWrite BackupStorageLocationAccessMode = "ReadWrite"
+
+	// BackupStorageLocationAccessModeReadOnly means backups in the location
+	// can be synced and restored from, but nothing is ever written to it or
+	// deleted from it, e.g. for another cluster's bucket.
+	BackupStorageLocationAccessModeReadOnly BackupStorageLocationAccessMode = "ReadOnly"
+)
+
+// BackupStorageLocationSpec defines where in object storage a
+// BackupStorageLocation's backups are stored.
+type BackupStorageLocationSpec struct {
+	// ObjectStorageProviderConfig is the cloud, bucket, and optional prefix
+	// within the bucket that the location's backups are stored in. Each
+	// location's bucket and prefix must be different.
+	ObjectStorageProviderConfig `json:",inline"`
+
+	// AccessMode is the server's permissions for the location. Optional;
+	// defaults to ReadWrite.
+	AccessMode BackupStorageLocationAccessMode `json:"accessMode"`
+}
+
+// BackupStorageLocationPhase is whether a BackupStorageLocation could be reached the last time
+// the Ark server checked.
+type BackupStorageLocationPhase string
+
+const (
+	// BackupStorageLocationPhaseAvailable means the location's bucket could be listed and, unless
+	// it's read-only, written to.
+	BackupStorageLocationPhaseAvailable BackupStorageLocationPhase = "Available"
+
+	// BackupStorageLocationPhaseUnavailable means the location's bucket couldn't be listed or
+	// written to. New backups aren't stored in unavailable locations.
+	BackupStorageLocationPhaseUnavailable BackupStorageLocationPhase = "Unavailable"
+)
+
+// BackupStorageLocationStatus is the result of the Ark server's last check of a
+// BackupStorageLocation.
+type BackupStorageLocationStatus struct {
+	// Phase is whether the location was available. It's empty until the
+	// location has been checked.
+	Phase BackupStorageLocationPhase `json:"phase"`
+
+	// Reason is why the location is unavailable: ListFailed, WriteFailed,
+	// or DeleteFailed.
+	Reason string `json:"reason"`
+
+	// Message is the error that made the location unavailable.
+	Message string `json:"message"`
+
+	// LastValidationTime is when the location was last checked.
+	LastValidationTime metav1.Time `json:"lastValidationTime"`
+}
+
+// +genclient=true
+
+// BackupStorageLocation is a named location in object storage that backups
+// can be stored in by setting their storageLocation. Backups that don't set
+// one are stored in the location named by the Config's
+// defaultBackupStorageLocation.
+type BackupStorageLocation struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata"`
+
+	Spec   BackupStorageLocationSpec   `json:"spec"`
+	Status BackupStorageLocationStatus `json:"status,omitempty"`
+}
+
+// BackupStorageLocationList is a list of BackupStorageLocations.
+type BackupStorageLocationList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata"`
+	Items           []BackupStorageLocation `json:"items"`
+}
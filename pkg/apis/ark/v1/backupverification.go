@@ -0,0 +1,102 @@
+/*
+Copyright 2017 Heptio Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+// BackupVerificationSpec defines the specification for an Ark backup verification.
+type BackupVerificationSpec struct {
+	// BackupName is the name of the Ark backup to verify.
+	BackupName string `json:"backupName"`
+}
+
+// BackupVerificationPhase is a string representation of the lifecycle phase
+// of an Ark backup verification.
+type BackupVerificationPhase string
+
+const (
+	// BackupVerificationPhaseNew means the verification has been created but not
+	// yet processed by the BackupVerificationController.
+	BackupVerificationPhaseNew BackupVerificationPhase = "New"
+
+	// BackupVerificationPhaseInProgress means the verification is currently executing.
+	BackupVerificationPhaseInProgress BackupVerificationPhase = "InProgress"
+
+	// BackupVerificationPhaseCompleted means all of the verification checks have
+	// been run. Whether they passed is captured in the Status.
+	BackupVerificationPhaseCompleted BackupVerificationPhase = "Completed"
+)
+
+// Names of the checks run by a backup verification.
+const (
+	BackupVerificationCheckMetadata  = "Metadata"
+	BackupVerificationCheckArchive   = "Archive"
+	BackupVerificationCheckChecksum  = "Checksum"
+	BackupVerificationCheckSnapshots = "Snapshots"
+)
+
+// BackupVerificationStatus captures the current status of an Ark backup verification.
+type BackupVerificationStatus struct {
+	// Phase is the current state of the BackupVerification.
+	Phase BackupVerificationPhase `json:"phase"`
+
+	// Passed is true if every check passed.
+	Passed bool `json:"passed"`
+
+	// Unverified is true if no check failed, but some couldn't be run, so
+	// the backup's integrity couldn't be fully verified.
+	Unverified bool `json:"unverified,omitempty"`
+
+	// Checks is the result of each of the checks that was run, in order.
+	Checks []BackupVerificationCheck `json:"checks"`
+}
+
+// BackupVerificationCheck is the result of a single check run against a backup.
+type BackupVerificationCheck struct {
+	// Name is the name of the check.
+	Name string `json:"name"`
+
+	// Passed is true if the check passed.
+	Passed bool `json:"passed"`
+
+	// Unverified is true if the check couldn't be run, e.g. because the
+	// backup has no recorded checksum to compare. Unverified checks haven't
+	// passed.
+	Unverified bool `json:"unverified,omitempty"`
+
+	// Message describes the outcome of the check.
+	Message string `json:"message"`
+}
+
+// +genclient=true
+
+// BackupVerification is an Ark resource that represents a request to check the
+// integrity of an Ark backup in object storage.
+type BackupVerification struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata"`
+
+	Spec   BackupVerificationSpec   `json:"spec"`
+	Status BackupVerificationStatus `json:"status,omitempty"`
+}
+
+// BackupVerificationList is a list of BackupVerifications.
+type BackupVerificationList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata"`
+	Items           []BackupVerification `json:"items"`
+}
@@ -16,7 +16,11 @@ limitations under the License.
 
 package v1
 
-import metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+import (
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	corev1 "k8s.io/client-go/pkg/api/v1"
+)
 
 // ConfigList is a list of Configs.
 type ConfigList struct {
@@ -34,14 +38,17 @@ type Config struct {
 	metav1.TypeMeta   `json:",inline"`
 	metav1.ObjectMeta `json:"metadata"`
 
-	// PersistentVolumeProvider is the configuration information for the cloud where
-	// the cluster is running and has PersistentVolumes to snapshot or restore. Optional.
-	PersistentVolumeProvider *CloudProviderConfig `json:"persistentVolumeProvider"`
+	// DefaultBackupStorageLocation is the name of the BackupStorageLocation,
+	// in the server's namespace, that backups without a storageLocation are
+	// stored in. Optional; defaults to "default".
+	DefaultBackupStorageLocation string `json:"defaultBackupStorageLocation"`
 
-	// BackupStorageProvider is the configuration information for the cloud where
-	// Ark backups are stored in object storage. This may be a different cloud than
-	// where the cluster is running.
-	BackupStorageProvider ObjectStorageProviderConfig `json:"backupStorageProvider"`
+	// DefaultVolumeSnapshotLocations are the names of the
+	// VolumeSnapshotLocations, in the server's namespace, that volumes are
+	// snapshotted in when their backup doesn't have a location for their
+	// cloud provider, keyed by cloud provider: "aws", "gcp", or "azure".
+	// Optional; a provider with only one location defaults to it.
+	DefaultVolumeSnapshotLocations map[string]string `json:"defaultVolumeSnapshotLocations"`
 
 	// BackupSyncPeriod is how often the BackupSyncController runs to ensure all
 	// Ark backups in object storage exist as Backup API objects in the cluster.
@@ -55,19 +62,364 @@ type Config struct {
 	// new backups that should be triggered based on schedules.
 	ScheduleSyncPeriod metav1.Duration `json:"scheduleSyncPeriod"`
 
+	// StorageLocationProbePeriod is how often the server checks that each
+	// BackupStorageLocation can be reached, and records the result in its
+	// status.
+	StorageLocationProbePeriod metav1.Duration `json:"storageLocationProbePeriod"`
+
 	// ResourcePriorities is an ordered slice of resources specifying the desired
 	// order of resource restores. Any resources not in the list will be restored
-	// alphabetically after the prioritized resources.
+	// alphabetically in place of a "*" entry, or after the prioritized resources
+	// if there isn't one.
 	ResourcePriorities []string `json:"resourcePriorities"`
 
+	// BackupResourcePriorities is an ordered slice of resources specifying the
+	// desired order in which resources are backed up. Any resources not in the
+	// list will be backed up in discovery order after the prioritized resources.
+	BackupResourcePriorities []string `json:"backupResourcePriorities"`
+
+	// DefaultExcludedResources are resources that are excluded from every
+	// backup, e.g. events, unless the backup includes them by name or sets
+	// ignoreDefaultExcludes. Optional.
+	DefaultExcludedResources []string `json:"defaultExcludedResources"`
+
+	// ExcludeCompletedPods is whether pods that have finished running are
+	// excluded from every backup that doesn't set ignoreDefaultExcludes.
+	// Optional.
+	ExcludeCompletedPods bool `json:"excludeCompletedPods"`
+
+	// BackupItemTransforms is an ordered list of transformations applied to
+	// items as they're written to backups, e.g. to redact sensitive data or
+	// remove generated fields. Optional.
+	BackupItemTransforms []BackupItemTransform `json:"backupItemTransforms"`
+
+	// ResourceCollectionWorkers is the number of resources whose items are
+	// listed and serialized concurrently while taking a backup. Optional;
+	// defaults to 1.
+	ResourceCollectionWorkers int `json:"resourceCollectionWorkers"`
+
+	// ResourceListPageSize is the most items of a resource that are listed
+	// from the Kubernetes API server at a time while taking a backup. Each
+	// page is written to the backup before the next is listed. Optional;
+	// defaults to 500.
+	ResourceListPageSize int64 `json:"resourceListPageSize"`
+
 	// RestoreOnlyMode is whether Ark should run in a mode where only restores
 	// are allowed; backups, schedules, and garbage-collection are all disabled.
 	RestoreOnlyMode bool `json:"restoreOnlyMode"`
+
+	// TenantMode is whether backups and restores can be created in
+	// namespaces other than the server's, by teams with access only to
+	// those namespaces. They're constrained to their own namespace, and
+	// can't include other cluster-scoped resources than the ones their
+	// items depend on. Backups and restores in the server's namespace
+	// aren't constrained.
+	TenantMode bool `json:"tenantMode"`
+
+	// TenantQuota limits the backups of each tenant namespace. Optional;
+	// if it's not specified, tenants' backups aren't limited. It requires
+	// TenantMode.
+	TenantQuota *TenantQuotaConfig `json:"tenantQuota"`
+
+	// Restic is the configuration for backing up the data in pod volumes using
+	// restic. Optional; if it's not specified, pod volumes aren't backed up
+	// using restic.
+	Restic *ResticConfig `json:"restic"`
+
+	// VolumeFreeze is the configuration for freezing the filesystems of
+	// PersistentVolumes while they're snapshotted. Optional; if it's not
+	// specified, volumes aren't frozen.
+	VolumeFreeze *VolumeFreezeConfig `json:"volumeFreeze"`
+
+	// CSISnapshots is the configuration for snapshotting PersistentVolumes
+	// backed by CSI drivers using the CSI external-snapshotter's
+	// VolumeSnapshot API. Optional; if it's not specified, CSI volumes are
+	// snapshotted in a VolumeSnapshotLocation, if they're supported by its
+	// cloud provider.
+	CSISnapshots *CSISnapshotsConfig `json:"csiSnapshots"`
+
+	// ClusterName is a name identifying the cluster Ark is running in. It's
+	// recorded on every backup so backups from multiple clusters sharing a
+	// bucket can be told apart. Optional.
+	ClusterName string `json:"clusterName"`
+
+	// ClusterUID is a unique identifier for the cluster Ark is running in,
+	// recorded on every backup alongside ClusterName. Optional; defaults to
+	// the UID of the cluster's kube-system namespace.
+	ClusterUID string `json:"clusterUID"`
+
+	// ImmutableBackups is whether backups are protected from modification
+	// once they've completed: the admission webhook rejects changes to
+	// their specs, and a backup is never written to object storage if one
+	// with the same name already exists there.
+	ImmutableBackups bool `json:"immutableBackups"`
+
+	// AdmissionWebhook is the configuration for the validating admission
+	// webhook served by the Ark server. Optional; if it's not specified,
+	// the webhook isn't served.
+	AdmissionWebhook *AdmissionWebhookConfig `json:"admissionWebhook"`
+
+	// Notifications is the configuration for sending notifications about
+	// completed, failed, and expired backups and restores to HTTP webhooks.
+	// Optional; if it's not specified, no notifications are sent.
+	Notifications *NotificationsConfig `json:"notifications"`
+
+	// Audit is the configuration for writing an audit log of backups,
+	// restores, and backup deletions to the default backup storage
+	// location. Optional; if it's not specified, no audit log is written.
+	Audit *AuditConfig `json:"audit"`
+
+	// Tracing is the configuration for exporting traces of backups and
+	// restores to an OpenTelemetry collector. Optional; if it's not
+	// specified, backups and restores aren't traced.
+	Tracing *TracingConfig `json:"tracing"`
+
+	// LogLevel is the verbosity of the Ark server's log, overriding its
+	// -v flag. Optional; if it's not specified, the flag's verbosity is
+	// used. Unlike other settings, changing it doesn't restart the server.
+	LogLevel *int32 `json:"logLevel"`
+
+	// Status reports which of the settings the Ark server is using, and
+	// which are invalid. It's written by the server when it starts, and
+	// when its logLevel changes.
+	Status ConfigStatus `json:"status,omitempty"`
+}
+
+// ConfigSettingStatus represents whether the Ark server is using a setting
+// of the Config.
+type ConfigSettingStatus string
+
+const (
+	// ConfigSettingStatusActive means the setting is valid and the server
+	// is using it, or its default.
+	ConfigSettingStatusActive ConfigSettingStatus = "Active"
+
+	// ConfigSettingStatusDisabled means the setting is optional, isn't
+	// specified, and turns off the feature it configures.
+	ConfigSettingStatusDisabled ConfigSettingStatus = "Disabled"
+
+	// ConfigSettingStatusInvalid means the setting failed validation. The
+	// server doesn't run while any of the Config's settings are invalid.
+	ConfigSettingStatusInvalid ConfigSettingStatus = "Invalid"
+)
+
+// ConfigStatus is the status of a Config, as seen by the Ark server.
+type ConfigStatus struct {
+	// Conditions has a condition for each of the Config's settings.
+	Conditions []ConfigCondition `json:"conditions"`
+}
+
+// ConfigCondition is the status of one of a Config's settings.
+type ConfigCondition struct {
+	// Setting is the name of the setting as it's written in the Config,
+	// e.g. gcSyncPeriod or restic.
+	Setting string `json:"setting"`
+
+	// Status is whether the server is using the setting.
+	Status ConfigSettingStatus `json:"status"`
+
+	// Message describes the value the server is using, including any
+	// defaults, or why the setting is invalid.
+	Message string `json:"message"`
+
+	// LastTransitionTime is when the setting's status last changed.
+	LastTransitionTime metav1.Time `json:"lastTransitionTime"`
+}
+
+// ResticConfig is configuration information for backing up and restoring
+// pod volumes using restic.
+type ResticConfig struct {
+	// Image is the container image used to run restic. Optional; defaults
+	// to restic/restic:0.8.1.
+	Image string `json:"image"`
+
+	// KopiaImage is the container image kopia is copied from into the
+	// helper pods that delete kopia snapshots. Optional; defaults to
+	// kopia/kopia:0.8.4.
+	KopiaImage string `json:"kopiaImage"`
+
+	// Timeout is how long the backup or restore of a single pod volume may
+	// take. Optional; defaults to 1 hour.
+	Timeout metav1.Duration `json:"timeout"`
+
+	// NodeAgent is whether pod volumes are backed up and restored by the
+	// node agents, run on every node by the ark node-agent DaemonSet,
+	// rather than by helper pods the server creates for each volume.
+	// Optional; defaults to false.
+	NodeAgent bool `json:"nodeAgent"`
+
+	// MaintenanceFrequency is how often each restic repository is pruned
+	// and checked. Kopia repositories are maintained whenever the server
+	// deletes a snapshot from them instead.
+	// Optional; defaults to 7 days.
+	MaintenanceFrequency metav1.Duration `json:"maintenanceFrequency"`
+
+	// HelperPodResources are the compute resource requests and limits of
+	// the helper pods that run restic, so that moving volume data can't
+	// starve the workloads on their nodes. The node agents' resources are
+	// set on their DaemonSet instead. Optional.
+	HelperPodResources corev1.ResourceRequirements `json:"helperPodResources"`
+
+	// UploadLimit is the bandwidth, in KiB/s, restic may use in each helper
+	// pod to write to object storage. The node agents' limit is set by
+	// their --upload-limit flag instead. Optional; 0 means no limit.
+	UploadLimit int `json:"uploadLimit"`
+
+	// DownloadLimit is the bandwidth, in KiB/s, restic may use in each
+	// helper pod to read from object storage. The node agents' limit is
+	// set by their --download-limit flag instead. Optional; 0 means no
+	// limit.
+	DownloadLimit int `json:"downloadLimit"`
+}
+
+// TenantQuotaConfig limits the backups of each namespace other than the
+// server's in tenant mode. Backups that would exceed a limit fail
+// validation. A limit of 0 means there's no limit.
+type TenantQuotaConfig struct {
+	// MaxConcurrentBackups is the number of backups in a namespace that
+	// may be running at once.
+	MaxConcurrentBackups int `json:"maxConcurrentBackups"`
+
+	// MaxBackups is the number of backups that a namespace may have stored
+	// in object storage, including the ones running.
+	MaxBackups int `json:"maxBackups"`
+
+	// MaxStoredBytes is the total size of the tarballs of a namespace's
+	// stored backups, e.g. 100Gi. Once it's reached, new backups in the
+	// namespace are rejected until stored ones are deleted. It only
+	// covers the tarballs of backups' metadata: volume snapshots and
+	// volume data backed up by restic or kopia aren't counted, and
+	// neither are the chunks of deduplicated backups, which are shared
+	// between backups.
+	MaxStoredBytes resource.Quantity `json:"maxStoredBytes"`
+}
+
+// BackupItemTransform describes a transformation applied to the items of
+// some resources as they're backed up.
+type BackupItemTransform struct {
+	// Resources is a list of resources, in <RESOURCE>.<GROUP> format, whose
+	// items are transformed. "*" matches all resources.
+	Resources []string `json:"resources"`
+
+	// LabelSelector restricts the transformation to items whose labels
+	// match it. Optional; if it's not specified, all items of the resources
+	// are transformed.
+	LabelSelector *metav1.LabelSelector `json:"labelSelector"`
+
+	// RemoveFields is a list of dot-separated paths of fields to remove from
+	// matching items. Paths through lists apply to each of their elements.
+	RemoveFields []string `json:"removeFields"`
+}
+
+// VolumeFreezeConfig is configuration information for freezing the
+// filesystems of PersistentVolumes while they're snapshotted.
+type VolumeFreezeConfig struct {
+	// Image is the container image used to run fsfreeze. Optional;
+	// defaults to debian:stretch-slim.
+	Image string `json:"image"`
+
+	// MaxFreezeDuration is the longest a volume's filesystem may stay
+	// frozen. If a snapshot takes longer, the filesystem is thawed anyway
+	// and the volume's freeze error is recorded. Optional; defaults to 1
+	// minute.
+	MaxFreezeDuration metav1.Duration `json:"maxFreezeDuration"`
+}
+
+// AdmissionWebhookConfig is the configuration for the Ark server's
+// validating admission webhook.
+type AdmissionWebhookConfig struct {
+	// Port is the port the webhook is served on over HTTPS. Optional;
+	// defaults to 8443.
+	Port int `json:"port"`
+
+	// CertFile is the path to the TLS certificate the webhook is served
+	// with, e.g. from a mounted secret.
+	CertFile string `json:"certFile"`
+
+	// KeyFile is the path to the TLS certificate's private key.
+	KeyFile string `json:"keyFile"`
+}
+
+// NotificationsConfig is configuration information for sending
+// notifications about backups and restores.
+type NotificationsConfig struct {
+	// Webhooks are the HTTP endpoints that notifications are posted to.
+	Webhooks []NotificationWebhook `json:"webhooks"`
+}
+
+// NotificationWebhook is an HTTP endpoint that notifications are posted to.
+type NotificationWebhook struct {
+	// Name identifies the webhook in schedules' notification overrides and
+	// in the server's logs.
+	Name string `json:"name"`
+
+	// URL is the URL notifications are posted to.
+	URL string `json:"url"`
+
+	// Format is the format of the notifications' bodies: "json", for Ark's
+	// own JSON payload, or "slack", for a Slack incoming webhook. Optional;
+	// defaults to "json". It's ignored if Template is set.
+	Format string `json:"format"`
+
+	// Template is a Go text/template that the notifications' bodies are
+	// generated from instead, for services with other payload formats. It's
+	// executed with the notification, whose fields are Event, Kind,
+	// Namespace, Name, Schedule, Phase, Message, and Timestamp. Optional.
+	Template string `json:"template"`
+
+	// Events are the notification events posted to the webhook:
+	// BackupCompleted, BackupPartiallyFailed, BackupFailed, BackupExpired,
+	// RestoreCompleted, RestorePartiallyFailed, and RestoreFailed. Optional;
+	// defaults to all of them.
+	Events []string `json:"events"`
+
+	// MaxRetries is the number of times a notification is retried, with
+	// exponential backoff, if the webhook can't be reached or replies with
+	// a server error. Optional; defaults to 3.
+	MaxRetries int `json:"maxRetries"`
+
+	// Timeout is how long each attempt to post a notification may take.
+	// Optional; defaults to 10 seconds.
+	Timeout metav1.Duration `json:"timeout"`
+}
+
+// AuditConfig is configuration information for writing the audit log.
+type AuditConfig struct {
+	// FlushInterval is how often entries that couldn't be written to object
+	// storage when they were recorded are retried. Each entry is written as
+	// a new segment of the log when it's recorded. Optional; defaults to 1
+	// minute.
+	FlushInterval metav1.Duration `json:"flushInterval"`
+}
+
+// TracingConfig is configuration information for exporting traces.
+type TracingConfig struct {
+	// Endpoint is the base URL of the OTLP/HTTP endpoint that spans are
+	// exported to, e.g. http://otel-collector.monitoring:4318. They're
+	// posted to its /v1/traces path.
+	Endpoint string `json:"endpoint"`
+
+	// ServiceName is the service.name the spans are exported with.
+	// Optional; defaults to "ark".
+	ServiceName string `json:"serviceName"`
+}
+
+// CSISnapshotsConfig is configuration information for snapshotting
+// PersistentVolumes backed by CSI drivers.
+type CSISnapshotsConfig struct {
+	// VolumeSnapshotClassName is the VolumeSnapshotClass to create
+	// VolumeSnapshots with. Optional; defaults to the cluster's default
+	// VolumeSnapshotClass.
+	VolumeSnapshotClassName string `json:"volumeSnapshotClassName"`
+
+	// Timeout is how long to wait for a VolumeSnapshot to be ready to
+	// use. Optional; defaults to 10 minutes.
+	Timeout metav1.Duration `json:"timeout"`
 }
 
 // CloudProviderConfig is configuration information about how to connect
-// to a particular cloud. Only one of the members (AWS, GCP, Azure) may
-// be present.
+// to a particular cloud. Only one of the members (AWS, GCP, Azure, Plugin)
+// may be present.
 type CloudProviderConfig struct {
 	// AWS is configuration information for connecting to AWS.
 	AWS *AWSConfig `json:"aws"`
@@ -77,6 +429,27 @@ type CloudProviderConfig struct {
 
 	// Azure is configuration information for connecting to Azure.
 	Azure *AzureConfig `json:"azure"`
+
+	// Plugin is configuration information for a cloud provider that's
+	// implemented by a plugin binary rather than compiled into Ark.
+	Plugin *PluginConfig `json:"plugin"`
+
+	// RateLimit limits the rate of Ark's calls to the cloud provider's API
+	// through this configuration, so that Ark doesn't exhaust API quota it
+	// shares with other clients, like the cluster autoscaler. Optional;
+	// calls aren't limited if it's not specified.
+	RateLimit *RateLimitConfig `json:"rateLimit"`
+}
+
+// RateLimitConfig is configuration information for limiting the rate of
+// calls to a cloud provider's API.
+type RateLimitConfig struct {
+	// QPS is the number of calls per second allowed on average.
+	QPS float32 `json:"qps"`
+
+	// Burst is the number of calls that may be made at once after a period
+	// with fewer than QPS. Optional; defaults to 1.
+	Burst int `json:"burst"`
 }
 
 // ObjectStorageProviderConfig is configuration information for connecting to
@@ -89,6 +462,16 @@ type ObjectStorageProviderConfig struct {
 	// Bucket is the name of the bucket in object storage where Ark backups
 	// are stored.
 	Bucket string `json:"bucket"`
+
+	// Prefix is the path within the bucket that Ark backups are stored
+	// under, so a bucket can be shared with other data or other locations.
+	// Optional; defaults to the root of the bucket.
+	Prefix string `json:"prefix"`
+
+	// Deduplicate is whether backup contents should be stored as
+	// content-addressed chunks shared by all backups in the bucket, so
+	// that content that's unchanged between backups is only stored once.
+	Deduplicate bool `json:"deduplicate"`
 }
 
 // AWSConfig is configuration information for connecting to AWS.
@@ -107,6 +490,17 @@ type GCPConfig struct {
 	Zone    string `json:"zone"`
 }
 
+// PluginConfig is configuration information for a cloud provider that's
+// implemented by a plugin binary in the Ark server's plugin directory.
+type PluginConfig struct {
+	// Name is the name of the plugin. Its binary is ark-plugin-<name>.
+	Name string `json:"name"`
+
+	// Config is passed to the plugin as is, and is specific to it.
+	// Optional.
+	Config map[string]string `json:"config"`
+}
+
 // AzureConfig is configuration information for connecting to Azure.
 type AzureConfig struct {
 	Location   string          `json:"location"`
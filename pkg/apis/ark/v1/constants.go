@@ -21,6 +21,16 @@ const (
 	// the Ark server and API objects.
 	DefaultNamespace = "heptio-ark"
 
+	// DefaultBackupStorageLocation is the name of the BackupStorageLocation
+	// that backups are stored in if the Config doesn't name another one.
+	DefaultBackupStorageLocation = "default"
+
+	// LegacyVolumeSnapshotLocation is the name of the VolumeSnapshotLocation
+	// that snapshots recorded without a location are in. They were taken
+	// using the Config's persistentVolumeProvider, which the location
+	// replaces.
+	LegacyVolumeSnapshotLocation = "default"
+
 	// RestoreLabelKey is the label key that's applied to all resources that
 	// are created during a restore. This is applied for ease of identification
 	// of restored resources. The value will be the restore's name.
@@ -33,4 +43,90 @@ const (
 	// NamespaceScopedDir is the name of the directory containing namespace-scoped
 	// resource within an Ark backup.
 	NamespaceScopedDir = "namespaces"
+
+	// ItemIndexFile is the name of the file within an Ark backup that lists
+	// every item included in the backup, along with its resource version.
+	// Incremental backups use it to determine which items have changed since
+	// their parent, and restores use it to determine which of the parent's
+	// items still belong in the backup.
+	ItemIndexFile = "index.json"
+
+	// ResourceVersionsFile is the name of the file within an Ark backup that
+	// lists, for each backed-up resource, the group versions the backed-up
+	// cluster served it at, in order of preference, including those of other
+	// groups that serve the same kind (e.g. deployments in both apps and
+	// extensions). Restores use it to choose the version to restore items at
+	// when the cluster doesn't serve the one they were backed up at.
+	ResourceVersionsFile = "resource-versions.json"
+
+	// SnapshotVolumeAnnotation is the annotation key on a PersistentVolume, or
+	// on the PersistentVolumeClaim bound to it, that overrides the backup's
+	// SnapshotVolumes setting for that volume. Valid values are "true" and
+	// "false". An annotation on the PersistentVolume takes precedence over one
+	// on its claim.
+	SnapshotVolumeAnnotation = "ark.heptio.com/snapshot"
+
+	// ScheduleNameLabel is the label key that's applied to backups created
+	// by a schedule to record the schedule's name.
+	ScheduleNameLabel = "ark-schedule"
+
+	// ClusterNameLabel is the label key that's applied to backups to record
+	// the name of the cluster they were taken in, and to restores to record
+	// the name of the cluster their backup was taken in.
+	ClusterNameLabel = "ark.heptio.com/cluster-name"
+
+	// ClusterUIDLabel is the label key that's applied to backups to record
+	// the UID of the cluster they were taken in, and to restores to record
+	// the UID of the cluster their backup was taken in.
+	ClusterUIDLabel = "ark.heptio.com/cluster-uid"
+
+	// CancelAnnotation is the annotation key on a backup that requests its
+	// cancellation. Setting it to "true" on a New or InProgress backup stops
+	// it and marks it Canceled.
+	CancelAnnotation = "ark.heptio.com/cancel"
+
+	// RetentionAnnotation is the annotation key that's applied to a
+	// schedule's backups that its retention policy keeps, with the
+	// comma-separated rules that keep them, e.g. "daily,weekly". Backups
+	// with it aren't garbage-collected when their TTL expires.
+	RetentionAnnotation = "ark.heptio.com/retention"
+
+	// RetentionExpiredAnnotation is the annotation key that's applied to a
+	// schedule's backups that its retention policy doesn't keep, with the
+	// RFC 3339 time at which the policy stopped keeping them. They expire
+	// then, unless their TTL expires them sooner. Retention policies expire
+	// backups this way rather than by changing their expiration so that
+	// immutable backups, whose status can't change, can be expired too.
+	RetentionExpiredAnnotation = "ark.heptio.com/retention-expired"
+
+	// BackupNameLabel is the label key that's applied to DeleteBackupRequests
+	// to record the name of the backup they delete, and to PodVolumeBackups
+	// to record the name of the backup they're part of.
+	BackupNameLabel = "ark.heptio.com/backup-name"
+
+	// RestoreNameLabel is the label key that's applied to PodVolumeRestores
+	// to record the name of the restore they're part of.
+	RestoreNameLabel = "ark.heptio.com/restore-name"
+
+	// RequesterAnnotation is the annotation key on backups and restores that
+	// identifies who asked for them, like a DeleteBackupRequest's Requester.
+	// The ark CLI sets it to the local user name. It's informational only;
+	// the Kubernetes audit log records the user that actually created the
+	// object.
+	RequesterAnnotation = "ark.heptio.com/requester"
+
+	// UploaderAnnotation is the annotation key on a namespace that chooses
+	// the uploader that backs up the data of its pods' volumes, for backups
+	// that don't set their UploaderType. Valid values are "restic" and
+	// "kopia".
+	UploaderAnnotation = "ark.heptio.com/uploader"
+
+	// UploaderTypeRestic is the uploader that backs up pod volumes to
+	// restic repositories. It's the default.
+	UploaderTypeRestic = "restic"
+
+	// UploaderTypeKopia is the uploader that backs up pod volumes to kopia
+	// repositories, which compress their data and handle volumes with many
+	// small files better than restic's. It needs the node agents.
+	UploaderTypeKopia = "kopia"
 )
@@ -0,0 +1,91 @@
+/*
+Copyright 2017 Heptio Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+// DeleteBackupRequestSpec defines the specification for a request to delete an Ark backup.
+type DeleteBackupRequestSpec struct {
+	// BackupName is the name of the Ark backup to delete.
+	BackupName string `json:"backupName"`
+
+	// Requester identifies who asked for the backup to be deleted. It's
+	// informational only; the Kubernetes audit log records the user that
+	// actually created the request.
+	Requester string `json:"requester,omitempty"`
+}
+
+// DeleteBackupRequestPhase is a string representation of the lifecycle phase
+// of a request to delete an Ark backup.
+type DeleteBackupRequestPhase string
+
+const (
+	// DeleteBackupRequestPhaseNew means the request has been created but not
+	// yet processed by the DeleteBackupRequestController.
+	DeleteBackupRequestPhaseNew DeleteBackupRequestPhase = "New"
+
+	// DeleteBackupRequestPhaseInProgress means the request is currently being
+	// processed.
+	DeleteBackupRequestPhaseInProgress DeleteBackupRequestPhase = "InProgress"
+
+	// DeleteBackupRequestPhaseProcessed means the request has been processed.
+	// Whether the backup was deleted is captured in the Status.
+	DeleteBackupRequestPhaseProcessed DeleteBackupRequestPhase = "Processed"
+)
+
+// DeleteBackupRequestStatus captures the current status of a request to delete
+// an Ark backup.
+type DeleteBackupRequestStatus struct {
+	// Phase is the current state of the DeleteBackupRequest.
+	Phase DeleteBackupRequestPhase `json:"phase"`
+
+	// Errors lists the errors encountered while deleting the backup. The
+	// Backup is only removed if there were none.
+	Errors []string `json:"errors"`
+
+	// DeletedSnapshots lists the IDs of the backup's volume snapshots that
+	// were deleted.
+	DeletedSnapshots []string `json:"deletedSnapshots,omitempty"`
+
+	// BackupDataDeleted is true once the backup's data is no longer in
+	// object storage, either because it was deleted or because the backup
+	// never ran to completion and so was never uploaded.
+	BackupDataDeleted bool `json:"backupDataDeleted"`
+
+	// ProcessedTimestamp is when the request was processed.
+	ProcessedTimestamp metav1.Time `json:"processedTimestamp"`
+}
+
+// +genclient=true
+
+// DeleteBackupRequest is an Ark resource that represents a request to delete
+// an Ark backup, along with its volume snapshots and the backup's data in
+// object storage.
+type DeleteBackupRequest struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata"`
+
+	Spec   DeleteBackupRequestSpec   `json:"spec"`
+	Status DeleteBackupRequestStatus `json:"status,omitempty"`
+}
+
+// DeleteBackupRequestList is a list of DeleteBackupRequests.
+type DeleteBackupRequestList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata"`
+	Items           []DeleteBackupRequest `json:"items"`
+}
@@ -0,0 +1,106 @@
+/*
+Copyright 2017 Heptio Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+// DownloadRequestSpec is the specification for a download request.
+type DownloadRequestSpec struct {
+	// Target is what to download (e.g. the contents of a backup, or a log).
+	Target DownloadTarget `json:"target"`
+}
+
+// DownloadTargetKind represents what type of file to download.
+type DownloadTargetKind string
+
+const (
+	// DownloadTargetKindBackupContents is a backup's gzip-compressed tarball.
+	DownloadTargetKindBackupContents DownloadTargetKind = "BackupContents"
+
+	// DownloadTargetKindBackupItems is the gzip-compressed list of the
+	// items in a backup, in JSON.
+	DownloadTargetKindBackupItems DownloadTargetKind = "BackupItems"
+
+	// DownloadTargetKindBackupLog is a backup's gzip-compressed log file.
+	DownloadTargetKindBackupLog DownloadTargetKind = "BackupLog"
+
+	// DownloadTargetKindRestoreLog is a restore's gzip-compressed log file.
+	DownloadTargetKindRestoreLog DownloadTargetKind = "RestoreLog"
+
+	// DownloadTargetKindRestorePlan is a previewed restore's
+	// gzip-compressed plan, in JSON.
+	DownloadTargetKindRestorePlan DownloadTargetKind = "RestorePlan"
+
+	// DownloadTargetKindRestoreResults is a restore's gzip-compressed
+	// warnings and errors, in JSON.
+	DownloadTargetKindRestoreResults DownloadTargetKind = "RestoreResults"
+)
+
+// DownloadTarget is the specification for what kind of file to download, and
+// the name of the resource with which it's associated.
+type DownloadTarget struct {
+	// Kind is the type of file to download.
+	Kind DownloadTargetKind `json:"kind"`
+
+	// Name is the name of the backup or restore the file belongs to.
+	Name string `json:"name"`
+}
+
+// DownloadRequestPhase represents the lifecycle phase of a DownloadRequest.
+type DownloadRequestPhase string
+
+const (
+	// DownloadRequestPhaseNew means the DownloadRequest has not been processed
+	// by the DownloadRequestController yet.
+	DownloadRequestPhaseNew DownloadRequestPhase = "New"
+
+	// DownloadRequestPhaseProcessed means the DownloadRequest has been
+	// processed by the DownloadRequestController.
+	DownloadRequestPhaseProcessed DownloadRequestPhase = "Processed"
+)
+
+// DownloadRequestStatus is the current status of a DownloadRequest.
+type DownloadRequestStatus struct {
+	// Phase is the current state of the DownloadRequest.
+	Phase DownloadRequestPhase `json:"phase"`
+
+	// DownloadURL contains a pre-signed URL for the target file.
+	DownloadURL string `json:"downloadURL"`
+
+	// Expiration is when the DownloadURL expires. The DownloadRequest is
+	// deleted after this time.
+	Expiration metav1.Time `json:"expiration"`
+}
+
+// +genclient=true
+
+// DownloadRequest is a request to download an artifact from object storage,
+// such as a backup's contents or log, without needing cloud credentials.
+type DownloadRequest struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata"`
+
+	Spec   DownloadRequestSpec   `json:"spec"`
+	Status DownloadRequestStatus `json:"status,omitempty"`
+}
+
+// DownloadRequestList is a list of DownloadRequests.
+type DownloadRequestList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata"`
+	Items           []DownloadRequest `json:"items"`
+}
@@ -0,0 +1,125 @@
+/*
+Copyright 2017 Heptio Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	corev1 "k8s.io/client-go/pkg/api/v1"
+)
+
+// PodVolumeBackupSpec is the specification for a PodVolumeBackup.
+type PodVolumeBackupSpec struct {
+	// Node is the name of the node the pod is running on. The backup is
+	// done by the node agent running there.
+	Node string `json:"node"`
+
+	// Pod is a reference to the pod whose volume is backed up. It's empty
+	// if HostPath is set.
+	Pod corev1.ObjectReference `json:"pod"`
+
+	// Volume is the name of the pod's volume to back up, or of the
+	// PersistentVolume if HostPath is set.
+	Volume string `json:"volume"`
+
+	// HostPath is the path, on Node, of the data of the local or hostPath
+	// PersistentVolume to back up, which isn't necessarily used by any
+	// pod. Optional.
+	HostPath string `json:"hostPath"`
+
+	// RepoIdentifier is the restic identifier of the repository the volume
+	// is backed up to. Kopia repositories are identified the same way.
+	RepoIdentifier string `json:"repoIdentifier"`
+
+	// Tags are the tags applied to the volume's snapshot.
+	Tags map[string]string `json:"tags"`
+
+	// UploaderType is the uploader that backs up the volume, restic or
+	// kopia. If empty, restic is used.
+	UploaderType string `json:"uploaderType"`
+}
+
+// PodVolumeBackupPhase represents the lifecycle phase of a PodVolumeBackup.
+type PodVolumeBackupPhase string
+
+const (
+	// PodVolumeBackupPhaseNew means the PodVolumeBackup hasn't been picked
+	// up by its node's agent yet.
+	PodVolumeBackupPhaseNew PodVolumeBackupPhase = "New"
+
+	// PodVolumeBackupPhaseInProgress means the node agent is backing up the
+	// volume.
+	PodVolumeBackupPhaseInProgress PodVolumeBackupPhase = "InProgress"
+
+	// PodVolumeBackupPhaseCompleted means the volume was backed up.
+	PodVolumeBackupPhaseCompleted PodVolumeBackupPhase = "Completed"
+
+	// PodVolumeBackupPhaseFailed means the volume couldn't be backed up.
+	PodVolumeBackupPhaseFailed PodVolumeBackupPhase = "Failed"
+)
+
+// PodVolumeBackupStatus is the current status of a PodVolumeBackup.
+type PodVolumeBackupStatus struct {
+	// Phase is the current state of the PodVolumeBackup.
+	Phase PodVolumeBackupPhase `json:"phase"`
+
+	// SnapshotID is the ID of the snapshot the volume was backed up
+	// to, once it's Completed.
+	SnapshotID string `json:"snapshotID"`
+
+	// Message is why the PodVolumeBackup Failed.
+	Message string `json:"message"`
+
+	// StartTimestamp is when the node agent started backing up the volume.
+	StartTimestamp metav1.Time `json:"startTimestamp"`
+
+	// CompletionTimestamp is when the PodVolumeBackup Completed or Failed.
+	CompletionTimestamp metav1.Time `json:"completionTimestamp"`
+
+	// Progress is how much of the volume's data has been backed up.
+	Progress PodVolumeOperationProgress `json:"progress"`
+}
+
+// PodVolumeOperationProgress is the progress of a PodVolumeBackup or
+// PodVolumeRestore. It's best-effort only: the node agent measures the
+// volume's data when it starts backing it up, and records what was done
+// when it finishes.
+type PodVolumeOperationProgress struct {
+	// TotalBytes is the size of the data in the volume.
+	TotalBytes int64 `json:"totalBytes"`
+
+	// BytesDone is how much of the data has been backed up or restored.
+	BytesDone int64 `json:"bytesDone"`
+}
+
+// +genclient=true
+
+// PodVolumeBackup is a request for the node agent on a pod's node to back
+// up the data of one of the pod's volumes using restic.
+type PodVolumeBackup struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata"`
+
+	Spec   PodVolumeBackupSpec   `json:"spec"`
+	Status PodVolumeBackupStatus `json:"status,omitempty"`
+}
+
+// PodVolumeBackupList is a list of PodVolumeBackups.
+type PodVolumeBackupList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata"`
+	Items           []PodVolumeBackup `json:"items"`
+}
@@ -0,0 +1,118 @@
+/*
+Copyright 2017 Heptio Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	corev1 "k8s.io/client-go/pkg/api/v1"
+)
+
+// PodVolumeRestoreSpec is the specification for a PodVolumeRestore.
+type PodVolumeRestoreSpec struct {
+	// Node is the name of the node the pod is running on. The restore is
+	// done by the node agent running there.
+	Node string `json:"node"`
+
+	// Pod is a reference to the pod whose volume is restored into. It's
+	// empty if HostPath is set.
+	Pod corev1.ObjectReference `json:"pod"`
+
+	// Volume is the name of the pod's volume to restore into, or of the
+	// PersistentVolume if HostPath is set.
+	Volume string `json:"volume"`
+
+	// HostPath is the path, on Node, of the data of the local or hostPath
+	// PersistentVolume to restore into. It's created if it doesn't exist.
+	// Optional.
+	HostPath string `json:"hostPath"`
+
+	// RepoIdentifier is the restic identifier of the repository holding
+	// the snapshot.
+	RepoIdentifier string `json:"repoIdentifier"`
+
+	// SnapshotID is the ID of the snapshot to restore.
+	SnapshotID string `json:"snapshotID"`
+
+	// UploaderType is the uploader that took the snapshot, restic or
+	// kopia. If empty, restic is used.
+	UploaderType string `json:"uploaderType"`
+
+	// RestoreUID is the UID of the Ark restore. Once the snapshot has been
+	// restored, the node agent creates the file .ark/<RestoreUID> in the
+	// volume, which the pod's restic-wait init container waits for. It's
+	// empty if HostPath is set, since no pod waits for the data.
+	RestoreUID types.UID `json:"restoreUID"`
+}
+
+// PodVolumeRestorePhase represents the lifecycle phase of a PodVolumeRestore.
+type PodVolumeRestorePhase string
+
+const (
+	// PodVolumeRestorePhaseNew means the PodVolumeRestore hasn't been picked
+	// up by its node's agent yet.
+	PodVolumeRestorePhaseNew PodVolumeRestorePhase = "New"
+
+	// PodVolumeRestorePhaseInProgress means the node agent is restoring the
+	// snapshot.
+	PodVolumeRestorePhaseInProgress PodVolumeRestorePhase = "InProgress"
+
+	// PodVolumeRestorePhaseCompleted means the snapshot was restored.
+	PodVolumeRestorePhaseCompleted PodVolumeRestorePhase = "Completed"
+
+	// PodVolumeRestorePhaseFailed means the snapshot couldn't be restored.
+	PodVolumeRestorePhaseFailed PodVolumeRestorePhase = "Failed"
+)
+
+// PodVolumeRestoreStatus is the current status of a PodVolumeRestore.
+type PodVolumeRestoreStatus struct {
+	// Phase is the current state of the PodVolumeRestore.
+	Phase PodVolumeRestorePhase `json:"phase"`
+
+	// Message is why the PodVolumeRestore Failed.
+	Message string `json:"message"`
+
+	// StartTimestamp is when the node agent started restoring the snapshot.
+	StartTimestamp metav1.Time `json:"startTimestamp"`
+
+	// CompletionTimestamp is when the PodVolumeRestore Completed or Failed.
+	CompletionTimestamp metav1.Time `json:"completionTimestamp"`
+
+	// Progress is how much of the snapshot's data has been restored. The
+	// size of a snapshot isn't known until it's restored, so it's only set
+	// once the PodVolumeRestore Completed.
+	Progress PodVolumeOperationProgress `json:"progress"`
+}
+
+// +genclient=true
+
+// PodVolumeRestore is a request for the node agent on a pod's node to
+// restore a restic snapshot into one of the pod's volumes.
+type PodVolumeRestore struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata"`
+
+	Spec   PodVolumeRestoreSpec   `json:"spec"`
+	Status PodVolumeRestoreStatus `json:"status,omitempty"`
+}
+
+// PodVolumeRestoreList is a list of PodVolumeRestores.
+type PodVolumeRestoreList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata"`
+	Items           []PodVolumeRestore `json:"items"`
+}
@@ -45,12 +45,30 @@ func addKnownTypes(scheme *runtime.Scheme) error {
 	scheme.AddKnownTypes(SchemeGroupVersion,
 		&Backup{},
 		&BackupList{},
+		&BackupStorageLocation{},
+		&BackupStorageLocationList{},
 		&Schedule{},
 		&ScheduleList{},
 		&Restore{},
 		&RestoreList{},
 		&Config{},
 		&ConfigList{},
+		&BackupVerification{},
+		&BackupVerificationList{},
+		&DeleteBackupRequest{},
+		&DeleteBackupRequestList{},
+		&DownloadRequest{},
+		&DownloadRequestList{},
+		&PodVolumeBackup{},
+		&PodVolumeBackupList{},
+		&PodVolumeRestore{},
+		&PodVolumeRestoreList{},
+		&ResticRepository{},
+		&ResticRepositoryList{},
+		&ServerStatusRequest{},
+		&ServerStatusRequestList{},
+		&VolumeSnapshotLocation{},
+		&VolumeSnapshotLocationList{},
 	)
 	metav1.AddToGroupVersion(scheme, SchemeGroupVersion)
 	return nil
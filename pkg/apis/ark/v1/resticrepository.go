@@ -0,0 +1,96 @@
+/*
+Copyright 2017 Heptio Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ResticRepositorySpec identifies a restic repository holding the pod
+// volume backups of one namespace in one backup storage location.
+type ResticRepositorySpec struct {
+	// VolumeNamespace is the namespace whose pod volumes are backed up
+	// to the repository.
+	VolumeNamespace string `json:"volumeNamespace"`
+
+	// BackupStorageLocation is the name of the backup storage location
+	// whose bucket the repository is stored in.
+	BackupStorageLocation string `json:"backupStorageLocation"`
+
+	// ResticIdentifier is the restic identifier of the repository.
+	ResticIdentifier string `json:"resticIdentifier"`
+
+	// MaintenanceFrequency is how often the repository is pruned and
+	// checked.
+	MaintenanceFrequency metav1.Duration `json:"maintenanceFrequency"`
+}
+
+// ResticRepositoryPhase represents whether a ResticRepository was healthy
+// the last time it was maintained.
+type ResticRepositoryPhase string
+
+const (
+	// ResticRepositoryPhaseNew means the repository hasn't been maintained
+	// yet.
+	ResticRepositoryPhaseNew ResticRepositoryPhase = "New"
+
+	// ResticRepositoryPhaseReady means the repository was pruned and
+	// passed restic's integrity check.
+	ResticRepositoryPhaseReady ResticRepositoryPhase = "Ready"
+
+	// ResticRepositoryPhaseNotReady means the repository couldn't be
+	// pruned or failed restic's integrity check.
+	ResticRepositoryPhaseNotReady ResticRepositoryPhase = "NotReady"
+)
+
+// ResticRepositoryStatus is the result of the last maintenance of a
+// ResticRepository.
+type ResticRepositoryStatus struct {
+	// Phase is whether the repository was healthy.
+	Phase ResticRepositoryPhase `json:"phase"`
+
+	// Message is why the repository is NotReady.
+	Message string `json:"message"`
+
+	// LastMaintenanceTime is when the repository was last maintained,
+	// whether or not it succeeded.
+	LastMaintenanceTime metav1.Time `json:"lastMaintenanceTime"`
+
+	// SizeBytes is the approximate size, in bytes, of the data in the
+	// repository after it was last pruned, as reported by restic.
+	SizeBytes int64 `json:"sizeBytes"`
+}
+
+// +genclient=true
+
+// ResticRepository is a restic repository that the Ark server maintains:
+// it removes stale locks from it, prunes the data that's no longer
+// referenced by any snapshot, and checks its integrity.
+type ResticRepository struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata"`
+
+	Spec   ResticRepositorySpec   `json:"spec"`
+	Status ResticRepositoryStatus `json:"status,omitempty"`
+}
+
+// ResticRepositoryList is a list of ResticRepositories.
+type ResticRepositoryList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata"`
+	Items           []ResticRepository `json:"items"`
+}
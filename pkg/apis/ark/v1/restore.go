@@ -16,7 +16,10 @@ limitations under the License.
 
 package v1
 
-import metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	corev1 "k8s.io/client-go/pkg/api/v1"
+)
 
 // RestoreSpec defines the specification for an Ark restore.
 type RestoreSpec struct {
@@ -33,14 +36,232 @@ type RestoreSpec struct {
 	// namespaces of the same name.
 	NamespaceMapping map[string]string `json:"namespaceMapping"`
 
+	// StorageClassMapping is a map of source storage class names
+	// to target storage class names. PersistentVolumeClaims and
+	// PersistentVolumes using a source storage class are restored
+	// using the target one. Optional.
+	StorageClassMapping map[string]string `json:"storageClassMapping"`
+
+	// NodeMapping is a map of the names of the nodes that local and
+	// hostPath PersistentVolumes were backed up from to the names of the
+	// nodes to restore them onto. The volumes' node affinity is changed
+	// to match. Volumes on other nodes are restored onto the same nodes.
+	// Optional.
+	NodeMapping map[string]string `json:"nodeMapping"`
+
+	// RegistryMapping is a map of source image registries, optionally
+	// followed by a repository path prefix (e.g. "gcr.io" or
+	// "docker.io/library"), to the registries and prefixes that
+	// replace them in the images of restored pods and pod templates.
+	// Optional.
+	RegistryMapping map[string]string `json:"registryMapping"`
+
 	// LabelSelector is a metav1.LabelSelector to filter with
 	// when restoring individual objects from the backup. If empty
-	// or nil, all objects are included. Optional.
+	// or nil, all objects are included. PersistentVolumes bound to
+	// included PersistentVolumeClaims are included whether or not
+	// they match. Optional.
 	LabelSelector *metav1.LabelSelector `json:"labelSelector"`
 
+	// IncludeClusterResources specifies whether cluster-scoped
+	// resources are restored. If nil, they're restored only when
+	// all namespaces are; when specific namespaces are restored,
+	// the only cluster-scoped resources restored are those
+	// namespaces and the PersistentVolumes bound to their
+	// PersistentVolumeClaims. Optional.
+	IncludeClusterResources *bool `json:"includeClusterResources"`
+
 	// RestorePVs specifies whether to restore all included
 	// PVs from snapshot (via the cloudprovider).
 	RestorePVs *bool `json:"restorePVs"`
+
+	// UnsnapshottedVolumePolicy defines what happens to PersistentVolumes
+	// that aren't restored from a snapshot, because RestorePVs is false
+	// or the backup has no snapshot of them. Defaults to Retain.
+	UnsnapshottedVolumePolicy UnsnapshottedVolumePolicy `json:"unsnapshottedVolumePolicy"`
+
+	// PreserveClusterIPs specifies that restored Services keep the
+	// cluster IPs they were backed up with, rather than being
+	// allocated new ones. Optional.
+	PreserveClusterIPs bool `json:"preserveClusterIPs"`
+
+	// PreserveNodePorts specifies that restored Services keep the
+	// node ports they were backed up with, rather than being
+	// allocated new ones. Optional.
+	PreserveNodePorts bool `json:"preserveNodePorts"`
+
+	// DataOnly specifies that only the data of the backup's
+	// PersistentVolumeClaims is restored, into the existing claims of
+	// the same names, and that nothing else is restored. The workloads
+	// using the claims are scaled down while their data is restored,
+	// then scaled back up. Claims backed up using restic have their
+	// contents replaced in place; claims whose volumes were
+	// snapshotted are re-created, bound to new volumes restored from
+	// the snapshots. Optional.
+	DataOnly bool `json:"dataOnly"`
+
+	// Prune specifies that items in the namespaces restored into
+	// that aren't in the backup are deleted once the backup's items
+	// have been restored, rolling the namespaces back to how they
+	// were when the backup was taken. Only items of resources that
+	// the backup includes, and with labels that both the backup's and
+	// the restore's label selectors match, are deleted. Items managed
+	// by controllers, and those of protected resources such as Events,
+	// never are. The backup must be Completed, and nothing is deleted
+	// if restoring any of its items fails. Set Preview to see what
+	// would be deleted. Optional.
+	Prune bool `json:"prune"`
+
+	// PruneExcludedResources is a list of resources whose items are
+	// never deleted by Prune. Optional.
+	PruneExcludedResources []string `json:"pruneExcludedResources"`
+
+	// Preview specifies that the restore shouldn't change the
+	// cluster, but should instead work out what it would do and
+	// store the result as a plan alongside the backup. Optional.
+	Preview bool `json:"preview"`
+
+	// Hooks specifies actions to take on restored pods, e.g. to run
+	// recovery commands once a database pod has been restored. Optional.
+	Hooks RestoreHooks `json:"hooks"`
+
+	// ResourceModifiers is the name of a ConfigMap, in the restore's
+	// namespace, of rules for patching items before they're
+	// restored, e.g. to change replica counts or ingress hosts for
+	// the target environment. Each of the ConfigMap's values is a
+	// YAML or JSON list of rules, and the rules of all values are
+	// applied in order of their keys. Optional.
+	ResourceModifiers string `json:"resourceModifiers"`
+
+	// ExistingResourcePolicy defines what happens to resources in the
+	// backup that already exist in the cluster. Defaults to Skip.
+	ExistingResourcePolicy ExistingResourcePolicy `json:"existingResourcePolicy"`
+
+	// ExistingResourcePolicyOverrides is a map of resources, in
+	// <RESOURCE>.<GROUP> form, to the policy for existing resources of
+	// that type, overriding ExistingResourcePolicy. Optional.
+	ExistingResourcePolicyOverrides map[string]ExistingResourcePolicy `json:"existingResourcePolicyOverrides"`
+}
+
+// ExistingResourcePolicy defines how a restore handles resources that
+// already exist in the cluster.
+type ExistingResourcePolicy string
+
+const (
+	// ExistingResourcePolicySkip leaves existing resources alone, and
+	// records a warning for each.
+	ExistingResourcePolicySkip ExistingResourcePolicy = "Skip"
+
+	// ExistingResourcePolicyPatch patches existing resources with their
+	// backed-up state. Fields that aren't in the backup are left as they
+	// are.
+	ExistingResourcePolicyPatch ExistingResourcePolicy = "Patch"
+
+	// ExistingResourcePolicyReplace deletes existing resources and
+	// re-creates them from the backup. Namespaces are never replaced;
+	// they're patched instead.
+	ExistingResourcePolicyReplace ExistingResourcePolicy = "Replace"
+)
+
+// UnsnapshottedVolumePolicy defines how a restore handles
+// PersistentVolumes that aren't restored from a snapshot.
+type UnsnapshottedVolumePolicy string
+
+const (
+	// UnsnapshottedVolumePolicyRetain restores the PersistentVolume
+	// as it was backed up, with its claimRef reset, so that its claim
+	// binds to the volume's existing storage.
+	UnsnapshottedVolumePolicyRetain UnsnapshottedVolumePolicy = "Retain"
+
+	// UnsnapshottedVolumePolicyProvision doesn't restore the
+	// PersistentVolume, and resets its claim's volume binding, so that
+	// a new, empty volume is dynamically provisioned for the claim.
+	UnsnapshottedVolumePolicyProvision UnsnapshottedVolumePolicy = "Provision"
+)
+
+// RestoreHooks contains the hooks to apply to the pods in a restore.
+type RestoreHooks struct {
+	// Resources are the hook specs, each of which applies to the pods
+	// that match its namespaces and label selector.
+	Resources []RestoreResourceHookSpec `json:"resources"`
+}
+
+// RestoreResourceHookSpec defines a set of hooks and the restored pods
+// they apply to.
+type RestoreResourceHookSpec struct {
+	// Name identifies the hook spec in the restore's warnings and errors.
+	Name string `json:"name"`
+
+	// IncludedNamespaces is a slice of namespaces, as they're named after
+	// any namespace mapping, whose pods the hooks apply to. If empty, the
+	// hooks apply to pods in all namespaces.
+	IncludedNamespaces []string `json:"includedNamespaces"`
+
+	// ExcludedNamespaces is a slice of namespaces whose pods the hooks
+	// don't apply to.
+	ExcludedNamespaces []string `json:"excludedNamespaces"`
+
+	// LabelSelector restricts the hooks to pods with matching labels. If
+	// nil, the hooks apply to all pods in the included namespaces.
+	LabelSelector *metav1.LabelSelector `json:"labelSelector"`
+
+	// PostHooks are the hooks applied to each matching pod as it's
+	// restored.
+	PostHooks []RestoreResourceHook `json:"postHooks"`
+}
+
+// RestoreResourceHook is a single hook. Exactly one of Init and Exec must
+// be set.
+type RestoreResourceHook struct {
+	// Init adds init containers to the pod.
+	Init *InitRestoreHook `json:"init"`
+
+	// Exec runs a command in one of the pod's containers once it's
+	// running.
+	Exec *ExecRestoreHook `json:"exec"`
+}
+
+// InitRestoreHook adds init containers to restored pods. They run after
+// any init containers that restore the pod's volume data, and before the
+// pod's own init containers.
+type InitRestoreHook struct {
+	// InitContainers are the containers to add.
+	InitContainers []corev1.Container `json:"initContainers"`
+}
+
+// HookErrorMode defines how a restore handles a failed hook.
+type HookErrorMode string
+
+const (
+	// HookErrorModeContinue records a failed hook as a warning.
+	HookErrorModeContinue HookErrorMode = "Continue"
+
+	// HookErrorModeFail records a failed hook as an error. This is the
+	// default.
+	HookErrorModeFail HookErrorMode = "Fail"
+)
+
+// ExecRestoreHook runs a command in a container of a restored pod.
+type ExecRestoreHook struct {
+	// Container is the name of the container to run the command in.
+	// Defaults to the pod's first container.
+	Container string `json:"container"`
+
+	// Command is the command and its arguments. It isn't run in a
+	// shell.
+	Command []string `json:"command"`
+
+	// OnError defines how a failure of the command is recorded in the
+	// restore's status. Defaults to Fail.
+	OnError HookErrorMode `json:"onError"`
+
+	// ExecTimeout is how long the command may run for. Defaults to 30
+	// seconds.
+	ExecTimeout metav1.Duration `json:"execTimeout"`
+
+	// WaitTimeout is how long to wait for the container to be running
+	// before giving up on the hook. Defaults to 5 minutes.
+	WaitTimeout metav1.Duration `json:"waitTimeout"`
 }
 
 // RestorePhase is a string representation of the lifecycle phase
@@ -59,9 +280,17 @@ const (
 	// RestorePhaseInProgress means the restore is currently executing.
 	RestorePhaseInProgress RestorePhase = "InProgress"
 
-	// RestorePhaseCompleted means the restore has finished executing.
-	// Any relevant warnings or errors will be captured in the Status.
+	// RestorePhaseCompleted means the restore has finished executing
+	// without errors. Any relevant warnings will be captured in the Status.
 	RestorePhaseCompleted RestorePhase = "Completed"
+
+	// RestorePhasePartiallyFailed means the restore has run to completion
+	// but encountered 1+ errors restoring individual items.
+	RestorePhasePartiallyFailed RestorePhase = "PartiallyFailed"
+
+	// RestorePhaseFailed means the restore couldn't run, e.g. because its
+	// backup couldn't be retrieved from object storage.
+	RestorePhaseFailed RestorePhase = "Failed"
 )
 
 // RestoreStatus captures the current status of an Ark restore
@@ -74,12 +303,84 @@ type RestoreStatus struct {
 	ValidationErrors []string `json:"validationErrors"`
 
 	// Warnings is a collection of all warning messages that were
-	// generated during execution of the restore
+	// generated during execution of the restore. It's only set if
+	// the restore's results couldn't be stored alongside its backup.
 	Warnings RestoreResult `json:"warnings"`
 
 	// Errors is a collection of all error messages that were
-	// generated during execution of the restore
+	// generated during execution of the restore. It's only set if
+	// the restore's results couldn't be stored alongside its backup.
 	Errors RestoreResult `json:"errors"`
+
+	// WarningCounts is the number of warning messages that were
+	// generated during execution of the restore.
+	WarningCounts RestoreResultCounts `json:"warningCounts"`
+
+	// ErrorCounts is the number of error messages that were
+	// generated during execution of the restore.
+	ErrorCounts RestoreResultCounts `json:"errorCounts"`
+
+	// RestoredVolumes lists the volumes whose data was restored, and
+	// where it was restored from.
+	RestoredVolumes []RestoredVolume `json:"restoredVolumes,omitempty"`
+
+	// HooksAttempted is the number of exec hooks that were run in
+	// restored pods.
+	HooksAttempted int `json:"hooksAttempted"`
+
+	// HooksFailed is the number of exec hooks that failed. Their
+	// messages are recorded with the restore's warnings or errors,
+	// depending on their OnError mode.
+	HooksFailed int `json:"hooksFailed"`
+
+	// PodVolumeRestoresAttempted is the number of pod volumes and
+	// PersistentVolumeClaims whose data was restored using restic.
+	PodVolumeRestoresAttempted int `json:"podVolumeRestoresAttempted"`
+
+	// PodVolumeRestoresFailed is the number of pod volumes and
+	// PersistentVolumeClaims whose data couldn't be restored. Their
+	// messages are recorded with the restore's errors.
+	PodVolumeRestoresFailed int `json:"podVolumeRestoresFailed"`
+}
+
+// RestoredVolumeSource is where a restored volume's data came from.
+type RestoredVolumeSource string
+
+const (
+	// RestoredVolumeSourceSnapshot means a PersistentVolume was
+	// restored from its snapshot, taken in one of the server's
+	// VolumeSnapshotLocations.
+	RestoredVolumeSourceSnapshot RestoredVolumeSource = "Snapshot"
+
+	// RestoredVolumeSourceCSISnapshot means a PersistentVolumeClaim's
+	// volume was provisioned from its CSI snapshot.
+	RestoredVolumeSourceCSISnapshot RestoredVolumeSource = "CSISnapshot"
+
+	// RestoredVolumeSourceRestic means a pod volume's data is restored
+	// from its restic snapshot.
+	RestoredVolumeSourceRestic RestoredVolumeSource = "Restic"
+)
+
+// RestoredVolume is a volume whose data was restored.
+type RestoredVolume struct {
+	// Source is where the volume's data came from.
+	Source RestoredVolumeSource `json:"source"`
+
+	// Resource is the resource of the item the volume belongs to: a
+	// PersistentVolume, a PersistentVolumeClaim, or a pod.
+	Resource string `json:"resource"`
+
+	// Namespace is the namespace the item was restored into.
+	Namespace string `json:"namespace,omitempty"`
+
+	// Name is the name of the item.
+	Name string `json:"name"`
+
+	// Volume is the name of the pod volume, for restic volumes.
+	Volume string `json:"volume,omitempty"`
+
+	// Snapshot is the ID of the snapshot the volume was restored from.
+	Snapshot string `json:"snapshot"`
 }
 
 // RestoreResult is a collection of messages that were generated
@@ -100,6 +401,31 @@ type RestoreResult struct {
 	Namespaces map[string][]string `json:"namespaces"`
 }
 
+// RestoreResultCounts is the number of messages in each part of a
+// RestoreResult.
+type RestoreResultCounts struct {
+	// Ark is the number of messages related to the operation of Ark
+	// itself.
+	Ark int `json:"ark"`
+
+	// Cluster is the number of messages related to restoring
+	// cluster-scoped resources.
+	Cluster int `json:"cluster"`
+
+	// Namespaces is a map of namespace name to the number of
+	// messages related to restoring resources in that namespace.
+	Namespaces map[string]int `json:"namespaces"`
+}
+
+// Total returns the total number of messages.
+func (c RestoreResultCounts) Total() int {
+	total := c.Ark + c.Cluster
+	for _, count := range c.Namespaces {
+		total += count
+	}
+	return total
+}
+
 // +genclient=true
 
 // Restore is an Ark resource that represents the application of
@@ -27,8 +27,107 @@ type ScheduleSpec struct {
 	// Schedule is a Cron expression defining when to run
 	// the Backup.
 	Schedule string `json:"schedule"`
+
+	// Timezone is the IANA name of the time zone, such as
+	// "America/New_York", that Schedule is evaluated in. If
+	// empty, the Ark server's local time zone is used. Optional.
+	Timezone string `json:"timezone"`
+
+	// BackupNameTemplate is the template the names of the schedule's
+	// Backups are generated from, e.g. "{schedule}-{cluster}-{date}".
+	// Its placeholders are {schedule}, {cluster}, {timestamp}
+	// (YYYYMMDDhhmmss), {date} (YYYYMMDD), {time} (hhmmss), {year},
+	// {month}, {day}, {hour}, {minute}, {second}, and {weekday}, with
+	// date parts in the schedule's time zone. Defaults to
+	// "{schedule}-{timestamp}". Optional.
+	BackupNameTemplate string `json:"backupNameTemplate"`
+
+	// Paused specifies that the schedule shouldn't create any
+	// Backups until it's resumed. A backup that was due while the
+	// schedule was paused is created as soon as it's resumed.
+	// Optional.
+	Paused bool `json:"paused"`
+
+	// ConcurrencyPolicy defines what happens when the schedule is due
+	// to create a Backup while one it created earlier is still
+	// running. Defaults to Allow.
+	ConcurrencyPolicy ConcurrencyPolicy `json:"concurrencyPolicy"`
+
+	// Jitter is the length of the window after each scheduled time
+	// that the Backup is created in. Each run starts at an offset
+	// within it that's derived from the schedule's name and the
+	// scheduled time, so that schedules with the same Cron expression
+	// don't all start at once. It should be shorter than the interval
+	// between runs. Optional.
+	Jitter metav1.Duration `json:"jitter"`
+
+	// Retention is the policy for how many of the schedule's completed
+	// Backups to keep. If it's set, it decides which Backups expire
+	// instead of their TTL. Optional.
+	Retention *RetentionPolicy `json:"retention,omitempty"`
+
+	// Notifications overrides which notifications are sent about the
+	// schedule's backups. Optional; if it's not specified, they're sent
+	// as configured in the Config's notifications.
+	Notifications *NotificationOverrides `json:"notifications,omitempty"`
+}
+
+// NotificationOverrides overrides which of the Config's notification
+// webhooks are notified about a schedule's backups, and of which events.
+type NotificationOverrides struct {
+	// Disabled turns off notifications about the schedule's backups.
+	Disabled bool `json:"disabled"`
+
+	// Webhooks are the names of the webhooks notified about the schedule's
+	// backups, instead of all of them. Optional.
+	Webhooks []string `json:"webhooks"`
+
+	// Events are the notification events that the webhooks are notified
+	// of, instead of each webhook's own events. Optional.
+	Events []string `json:"events"`
+}
+
+// RetentionPolicy is a grandfather-father-son policy for how many of
+// a schedule's completed Backups to keep. Each rule keeps the most
+// recent Backup in each of that many of the most recent periods that
+// have one, in the schedule's time zone; a Backup that any rule keeps
+// is kept, and the rest expire.
+type RetentionPolicy struct {
+	// Last is the number of most recent Backups to keep.
+	Last int `json:"last"`
+
+	// Daily is the number of days to keep a Backup from.
+	Daily int `json:"daily"`
+
+	// Weekly is the number of ISO 8601 weeks, which start on
+	// Mondays, to keep a Backup from.
+	Weekly int `json:"weekly"`
+
+	// Monthly is the number of months to keep a Backup from.
+	Monthly int `json:"monthly"`
+
+	// Yearly is the number of years to keep a Backup from.
+	Yearly int `json:"yearly"`
 }
 
+// ConcurrencyPolicy defines how a schedule handles a run that's due
+// while its previous Backup is still running.
+type ConcurrencyPolicy string
+
+const (
+	// ConcurrencyPolicyAllow creates the Backup, so it runs alongside
+	// the ones that are still running.
+	ConcurrencyPolicyAllow ConcurrencyPolicy = "Allow"
+
+	// ConcurrencyPolicyForbid skips the run. The schedule creates its
+	// next Backup at the following scheduled time.
+	ConcurrencyPolicyForbid ConcurrencyPolicy = "Forbid"
+
+	// ConcurrencyPolicyReplace cancels the Backups that are still
+	// running and creates the new one.
+	ConcurrencyPolicyReplace ConcurrencyPolicy = "Replace"
+)
+
 // SchedulePhase is a string representation of the lifecycle phase
 // of an Ark schedule
 type SchedulePhase string
@@ -56,6 +155,26 @@ type ScheduleStatus struct {
 	// Schedule schedule
 	LastBackup metav1.Time `json:"lastBackup"`
 
+	// LastBackupName is the name of the last Backup created for
+	// this Schedule.
+	LastBackupName string `json:"lastBackupName"`
+
+	// LastBackupPhase is the phase of the last Backup created for
+	// this Schedule.
+	LastBackupPhase BackupPhase `json:"lastBackupPhase"`
+
+	// LastBackupCompletionTimestamp is when the last Backup created
+	// for this Schedule finished running. It's zero while the Backup
+	// is running.
+	LastBackupCompletionTimestamp metav1.Time `json:"lastBackupCompletionTimestamp"`
+
+	// LastSkipped is the last time a run of this Schedule was
+	// skipped because its previous Backup was still running.
+	LastSkipped metav1.Time `json:"lastSkipped"`
+
+	// NextRunTime is when the Schedule will next create a Backup.
+	NextRunTime metav1.Time `json:"nextRunTime"`
+
 	// ValidationErrors is a slice of all validation errors (if
 	// applicable)
 	ValidationErrors []string `json:"validationErrors"`
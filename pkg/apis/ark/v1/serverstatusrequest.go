@@ -0,0 +1,68 @@
+/*
+Copyright 2017 Heptio Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+// ServerStatusRequestPhase represents the lifecycle phase of a ServerStatusRequest.
+type ServerStatusRequestPhase string
+
+const (
+	// ServerStatusRequestPhaseNew means the ServerStatusRequest has not been
+	// processed by the ServerStatusRequestController yet.
+	ServerStatusRequestPhaseNew ServerStatusRequestPhase = "New"
+
+	// ServerStatusRequestPhaseProcessed means the ServerStatusRequest has been
+	// processed by the ServerStatusRequestController.
+	ServerStatusRequestPhaseProcessed ServerStatusRequestPhase = "Processed"
+)
+
+// ServerStatusRequestStatus is the current status of a ServerStatusRequest.
+type ServerStatusRequestStatus struct {
+	// Phase is the current state of the ServerStatusRequest.
+	Phase ServerStatusRequestPhase `json:"phase"`
+
+	// ProcessedTimestamp is when the ServerStatusRequest was processed. The
+	// ServerStatusRequest is deleted a minute after this time.
+	ProcessedTimestamp metav1.Time `json:"processedTimestamp"`
+
+	// ServerVersion is the version of the Ark server that processed the
+	// ServerStatusRequest.
+	ServerVersion string `json:"serverVersion"`
+
+	// ServerGitSHA is the git commit the Ark server was built from.
+	ServerGitSHA string `json:"serverGitSHA"`
+}
+
+// +genclient=true
+
+// ServerStatusRequest is a request for the Ark server to report its status,
+// such as its version, so that clients can find out about the server without
+// access to its pod.
+type ServerStatusRequest struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata"`
+
+	Status ServerStatusRequestStatus `json:"status,omitempty"`
+}
+
+// ServerStatusRequestList is a list of ServerStatusRequests.
+type ServerStatusRequestList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata"`
+	Items           []ServerStatusRequest `json:"items"`
+}
@@ -0,0 +1,48 @@
+/*
+Copyright 2017 Heptio Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+// VolumeSnapshotLocationSpec defines where a VolumeSnapshotLocation's
+// snapshots are taken.
+type VolumeSnapshotLocationSpec struct {
+	// CloudProviderConfig is the cloud, and its region, project, or
+	// other configuration, that the location's snapshots are taken in.
+	CloudProviderConfig `json:",inline"`
+}
+
+// +genclient=true
+
+// VolumeSnapshotLocation is a named location that PersistentVolume
+// snapshots can be taken in. Backups choose at most one location for
+// each cloud provider by setting their volumeSnapshotLocations; the
+// volumes of other providers are snapshotted in the provider's default
+// location.
+type VolumeSnapshotLocation struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata"`
+
+	Spec VolumeSnapshotLocationSpec `json:"spec"`
+}
+
+// VolumeSnapshotLocationList is a list of VolumeSnapshotLocations.
+type VolumeSnapshotLocationList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata"`
+	Items           []VolumeSnapshotLocation `json:"items"`
+}
@@ -0,0 +1,325 @@
+/*
+Copyright 2017 Heptio Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package audit writes a tamper-evident, append-only log of the backups, restores, and backup
+// deletions that the Ark server processes to object storage.
+package audit
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+
+	"k8s.io/apimachinery/pkg/util/clock"
+
+	"github.com/heptio/ark/pkg/cloudprovider"
+)
+
+const (
+	// Dir is the top-level "directory" in the bucket that the log is written under. Its
+	// leading "." reserves it, so it isn't mistaken for a backup.
+	Dir = ".audit"
+
+	// segmentFile is the name of each segment's object, under a directory named for when the
+	// segment was written.
+	segmentFile = "log.jsonl"
+
+	// segmentTimeFormat formats segments' directory names so they sort in the order the
+	// segments were written.
+	segmentTimeFormat = "20060102T150405.000000000Z"
+)
+
+// Entry is a record in the audit log of something that happened to a backup, restore, or
+// DeleteBackupRequest.
+type Entry struct {
+	Timestamp time.Time `json:"timestamp"`
+
+	// Event is the reason of the event recorded about it, e.g. BackupStarted or BackupDeleted.
+	Event string `json:"event"`
+
+	// Kind is the kind of the object the entry is about: Backup, Restore, or
+	// DeleteBackupRequest.
+	Kind string `json:"kind"`
+
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+
+	// Backup is the name of the backup that a restore or DeleteBackupRequest is for.
+	Backup string `json:"backup,omitempty"`
+
+	// Schedule is the name of the schedule that created a backup, if any.
+	Schedule string `json:"schedule,omitempty"`
+
+	// Requester identifies who asked for the backup, restore, or deletion, as recorded on the
+	// object.
+	Requester string `json:"requester,omitempty"`
+
+	// Phase is the object's phase.
+	Phase string `json:"phase,omitempty"`
+
+	// Message describes what happened, as in the event recorded about it.
+	Message string `json:"message"`
+
+	// PreviousHash is the Hash of the entry before this one in the log, or empty for the
+	// first entry.
+	PreviousHash string `json:"previousHash"`
+
+	// Hash is the hex-encoded SHA-256 hash of PreviousHash followed by the entry's JSON
+	// encoding without its Hash. Since each entry's hash covers the one before it, changing
+	// or removing an entry breaks the chain of hashes after it.
+	Hash string `json:"hash,omitempty"`
+}
+
+// hash returns the hash of e, computed as described for Entry.Hash.
+func (e Entry) hash() (string, error) {
+	e.Hash = ""
+	data, err := json.Marshal(e)
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(append([]byte(e.PreviousHash), data...))
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// Log is an audit log.
+type Log interface {
+	// Record adds e to the log, with its Timestamp and hashes filled in, and writes it to object
+	// storage before returning. If it can't be written, it's kept to be written by Run.
+	Record(e Entry)
+
+	// Run retries writing the entries that couldn't be written when they were recorded every
+	// retryInterval, until ctx is done, when it tries once more and returns.
+	Run(ctx context.Context, retryInterval time.Duration)
+}
+
+// Lease is held by the one server replica that's allowed to append to the log, so that
+// replicas don't fork its chain of hashes.
+type Lease interface {
+	// Held returns whether the lease is currently held.
+	Held() bool
+}
+
+// errLeaseNotHeld is returned when the log can't be appended to because the lease isn't held.
+var errLeaseNotHeld = errors.New("the leader lease isn't held, so the audit log can't be appended to")
+
+type log struct {
+	objectStore cloudprovider.ObjectStorageAdapter
+	bucket      string
+	lease       Lease
+	clock       clock.Clock
+
+	lock     sync.Mutex
+	lastHash string
+	pending  []Entry
+	// lastSegment is when the last segment was written, so the next one's name sorts after it.
+	lastSegment time.Time
+}
+
+// NewLog returns a Log that's written to bucket in objectStore, continuing the chain of hashes
+// of the log that's already there, after verifying the whole chain. It's only appended to while
+// lease is held, so it must be created after the lease has been acquired; lease is nil if the
+// server doesn't run with leader election, in which case only one replica may run. Run must be
+// called for entries that can't be written when they're recorded to be written later.
+func NewLog(objectStore cloudprovider.ObjectStorageAdapter, bucket string, lease Lease) (Log, error) {
+	return newLog(objectStore, bucket, lease, clock.RealClock{})
+}
+
+func newLog(objectStore cloudprovider.ObjectStorageAdapter, bucket string, lease Lease, clock clock.Clock) (*log, error) {
+	l := &log{
+		objectStore: objectStore,
+		bucket:      bucket,
+		lease:       lease,
+		clock:       clock,
+	}
+
+	if l.lease != nil && !l.lease.Held() {
+		return nil, errLeaseNotHeld
+	}
+
+	if err := l.verify(); err != nil {
+		return nil, fmt.Errorf("error verifying audit log: %v", err)
+	}
+
+	return l, nil
+}
+
+// verify checks the chain of hashes across all of the log's segments, in order, and continues
+// the log after its last entry and segment.
+func (l *log) verify() error {
+	segments, err := l.objectStore.ListCommonPrefixes(l.bucket, Dir+"/", "/")
+	if err != nil {
+		return err
+	}
+	sort.Strings(segments)
+
+	for _, segment := range segments {
+		dir := strings.TrimSuffix(segment, "/")
+		key := dir + "/" + segmentFile
+		if l.lastHash, err = l.verifySegment(key, l.lastHash); err != nil {
+			return fmt.Errorf("segment %s: %v", key, err)
+		}
+
+		if segmentTime, err := time.Parse(segmentTimeFormat, strings.TrimPrefix(dir, Dir+"/")); err == nil {
+			l.lastSegment = segmentTime
+		}
+	}
+
+	return nil
+}
+
+// verifySegment verifies the segment with key, which follows the entry with previousHash, and
+// returns the hash of its last entry.
+func (l *log) verifySegment(key, previousHash string) (string, error) {
+	body, err := l.objectStore.GetObject(l.bucket, key)
+	if err != nil {
+		return "", err
+	}
+	defer body.Close()
+
+	return Verify(body, previousHash)
+}
+
+func (l *log) Record(e Entry) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	e.Timestamp = l.clock.Now().UTC()
+	e.PreviousHash = l.lastHash
+	hash, err := e.hash()
+	if err != nil {
+		glog.Errorf("error adding %s event for %s %s/%s to audit log: %v", e.Event, e.Kind, e.Namespace, e.Name, err)
+		return
+	}
+	e.Hash = hash
+
+	l.pending = append(l.pending, e)
+	l.lastHash = hash
+
+	if err := l.flushLocked(); err != nil {
+		glog.Errorf("error writing audit log, retrying later: %v", err)
+	}
+}
+
+func (l *log) Run(ctx context.Context, retryInterval time.Duration) {
+	ticker := time.NewTicker(retryInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := l.flush(); err != nil {
+				glog.Errorf("error writing audit log: %v", err)
+			}
+		case <-ctx.Done():
+			if err := l.flush(); err != nil {
+				glog.Errorf("error writing audit log: %v", err)
+			}
+			return
+		}
+	}
+}
+
+// flush writes the pending entries to a new segment of the log. Existing segments are never
+// overwritten. If the segment can't be written, its entries are kept to be written at the next
+// flush.
+func (l *log) flush() error {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	return l.flushLocked()
+}
+
+// flushLocked is flush for callers that hold l.lock.
+func (l *log) flushLocked() error {
+	if len(l.pending) == 0 {
+		return nil
+	}
+
+	if l.lease != nil && !l.lease.Held() {
+		return errLeaseNotHeld
+	}
+
+	buf := new(bytes.Buffer)
+	encoder := json.NewEncoder(buf)
+	for _, e := range l.pending {
+		if err := encoder.Encode(e); err != nil {
+			return err
+		}
+	}
+
+	// segments written within the same nanosecond, or after the clock went back, still have to
+	// be named in order
+	segmentTime := l.clock.Now().UTC()
+	if !segmentTime.After(l.lastSegment) {
+		segmentTime = l.lastSegment.Add(time.Nanosecond)
+	}
+
+	key := fmt.Sprintf("%s/%s/%s", Dir, segmentTime.Format(segmentTimeFormat), segmentFile)
+	if err := l.objectStore.PutObject(l.bucket, key, bytes.NewReader(buf.Bytes())); err != nil {
+		return err
+	}
+	l.lastSegment = segmentTime
+
+	glog.V(4).Infof("Wrote %d audit log entries to %s", len(l.pending), key)
+	l.pending = nil
+
+	return nil
+}
+
+// Verify checks the chain of hashes of the entries in a segment of the log read from r, the
+// first of which must follow the entry with previousHash, or be the first entry of the log if
+// previousHash is empty. It returns the hash of the segment's last entry, which the next
+// segment's first entry follows.
+func Verify(r io.Reader, previousHash string) (string, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	for line := 1; scanner.Scan(); line++ {
+		var e Entry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			return "", fmt.Errorf("line %d: %v", line, err)
+		}
+
+		if e.PreviousHash != previousHash {
+			return "", fmt.Errorf("line %d: previous hash %q doesn't match the hash of the entry before it, %q", line, e.PreviousHash, previousHash)
+		}
+
+		hash, err := e.hash()
+		if err != nil {
+			return "", fmt.Errorf("line %d: %v", line, err)
+		}
+		if e.Hash != hash {
+			return "", fmt.Errorf("line %d: hash %q doesn't match the entry's contents", line, e.Hash)
+		}
+
+		previousHash = hash
+	}
+
+	return previousHash, scanner.Err()
+}
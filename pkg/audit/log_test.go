@@ -0,0 +1,299 @@
+/*
+Copyright 2017 Heptio Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package audit
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"k8s.io/apimachinery/pkg/util/clock"
+)
+
+// fakeObjectStore is an ObjectStorageAdapter that stores objects in memory, and fails to put
+// them while putErr is set.
+type fakeObjectStore struct {
+	objects map[string][]byte
+	putErr  error
+}
+
+func (s *fakeObjectStore) PutObject(bucket string, key string, body io.ReadSeeker) error {
+	if s.putErr != nil {
+		return s.putErr
+	}
+	if _, ok := s.objects[key]; ok {
+		return errors.New("object already exists")
+	}
+
+	data, err := ioutil.ReadAll(body)
+	if err != nil {
+		return err
+	}
+	s.objects[key] = data
+	return nil
+}
+
+func (s *fakeObjectStore) GetObject(bucket string, key string) (io.ReadCloser, error) {
+	data, ok := s.objects[key]
+	if !ok {
+		return nil, errors.New("object not found")
+	}
+	return ioutil.NopCloser(bytes.NewReader(data)), nil
+}
+
+func (s *fakeObjectStore) ListCommonPrefixes(bucket, prefix, delimiter string) ([]string, error) {
+	var prefixes []string
+	seen := make(map[string]bool)
+	for key := range s.objects {
+		if !strings.HasPrefix(key, prefix) {
+			continue
+		}
+		i := strings.Index(key[len(prefix):], delimiter)
+		if i < 0 {
+			continue
+		}
+		p := key[:len(prefix)+i+len(delimiter)]
+		if !seen[p] {
+			seen[p] = true
+			prefixes = append(prefixes, p)
+		}
+	}
+	return prefixes, nil
+}
+
+func (s *fakeObjectStore) DeleteObject(bucket string, key string) error {
+	delete(s.objects, key)
+	return nil
+}
+
+func (s *fakeObjectStore) CreateSignedURL(bucket, key string, ttl time.Duration) (string, error) {
+	return "", errors.New("not implemented")
+}
+
+// segments returns the keys of the log's segments, in order.
+func (s *fakeObjectStore) segments() []string {
+	var keys []string
+	for key := range s.objects {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// fakeLease is a Lease that's held while held is set.
+type fakeLease struct {
+	held bool
+}
+
+func (l *fakeLease) Held() bool {
+	return l.held
+}
+
+func TestLogWritesChainedSegments(t *testing.T) {
+	store := &fakeObjectStore{objects: make(map[string][]byte)}
+	now := time.Date(2018, 4, 1, 12, 0, 0, 0, time.UTC)
+	fakeClock := clock.NewFakeClock(now)
+
+	l, err := newLog(store, "bucket", nil, fakeClock)
+	require.NoError(t, err)
+
+	// nothing is written if nothing was recorded
+	require.NoError(t, l.flush())
+	assert.Empty(t, store.objects)
+
+	// each entry is written as it's recorded, in segments named in order even when they're
+	// written at the same time
+	l.Record(Entry{Event: "BackupStarted", Kind: "Backup", Namespace: "heptio-ark", Name: "backup-1", Requester: "alice"})
+	l.Record(Entry{Event: "BackupCompleted", Kind: "Backup", Namespace: "heptio-ark", Name: "backup-1", Requester: "alice"})
+
+	// entries that can't be written are kept until the next flush
+	fakeClock.Step(time.Minute)
+	store.putErr = errors.New("unavailable")
+	l.Record(Entry{Event: "BackupDeleted", Kind: "DeleteBackupRequest", Namespace: "heptio-ark", Name: "backup-1-abcde", Backup: "backup-1", Requester: "bob"})
+	assert.Len(t, store.objects, 2)
+	store.putErr = nil
+	fakeClock.Step(time.Minute)
+	require.NoError(t, l.flush())
+
+	require.Equal(t, []string{
+		".audit/20180401T120000.000000000Z/log.jsonl",
+		".audit/20180401T120000.000000001Z/log.jsonl",
+		".audit/20180401T120200.000000000Z/log.jsonl",
+	}, store.segments())
+
+	var lastHash string
+	for _, key := range store.segments() {
+		lastHash, err = Verify(bytes.NewReader(store.objects[key]), lastHash)
+		require.NoError(t, err, key)
+	}
+	assert.Contains(t, string(store.objects[".audit/20180401T120200.000000000Z/log.jsonl"]), `"timestamp":"2018-04-01T12:01:00Z","event":"BackupDeleted"`)
+
+	// a new log continues the chain
+	fakeClock.Step(time.Minute)
+	l, err = newLog(store, "bucket", nil, fakeClock)
+	require.NoError(t, err)
+	assert.Equal(t, lastHash, l.lastHash)
+	l.Record(Entry{Event: "RestoreStarted", Kind: "Restore", Namespace: "heptio-ark", Name: "restore-1"})
+	_, err = Verify(bytes.NewReader(store.objects[".audit/20180401T120300.000000000Z/log.jsonl"]), lastHash)
+	assert.NoError(t, err)
+}
+
+func TestLogIsOnlyAppendedToWhileLeaseIsHeld(t *testing.T) {
+	store := &fakeObjectStore{objects: make(map[string][]byte)}
+	fakeClock := clock.NewFakeClock(time.Date(2018, 4, 1, 12, 0, 0, 0, time.UTC))
+	lease := &fakeLease{}
+
+	_, err := newLog(store, "bucket", lease, fakeClock)
+	assert.EqualError(t, err, errLeaseNotHeld.Error(), "the log can't be created before the lease is acquired")
+
+	lease.held = true
+	l, err := newLog(store, "bucket", lease, fakeClock)
+	require.NoError(t, err)
+
+	l.Record(Entry{Event: "BackupStarted", Kind: "Backup", Namespace: "heptio-ark", Name: "backup-1"})
+	assert.Len(t, store.objects, 1)
+
+	lease.held = false
+	fakeClock.Step(time.Minute)
+	l.Record(Entry{Event: "BackupCompleted", Kind: "Backup", Namespace: "heptio-ark", Name: "backup-1"})
+	assert.Len(t, store.objects, 1)
+	assert.Equal(t, errLeaseNotHeld, l.flush())
+	assert.Len(t, store.objects, 1)
+}
+
+func TestNewLogVerifiesAllSegments(t *testing.T) {
+	store := &fakeObjectStore{objects: make(map[string][]byte)}
+	fakeClock := clock.NewFakeClock(time.Date(2018, 4, 1, 12, 0, 0, 0, time.UTC))
+
+	l, err := newLog(store, "bucket", nil, fakeClock)
+	require.NoError(t, err)
+	for _, name := range []string{"backup-1", "backup-2", "backup-3"} {
+		l.Record(Entry{Event: "BackupStarted", Kind: "Backup", Namespace: "heptio-ark", Name: name, Requester: "alice"})
+		fakeClock.Step(time.Minute)
+	}
+	segments := store.segments()
+	require.Len(t, segments, 3)
+
+	tests := []struct {
+		name        string
+		tamper      func()
+		expectedErr string
+	}{
+		{
+			name: "changed entry in an earlier segment",
+			tamper: func() {
+				store.objects[segments[0]] = bytes.Replace(store.objects[segments[0]], []byte("alice"), []byte("carol"), 1)
+			},
+			expectedErr: "segment " + segments[0] + ": line 1: hash",
+		},
+		{
+			name:        "removed segment",
+			tamper:      func() { delete(store.objects, segments[1]) },
+			expectedErr: "segment " + segments[2] + ": line 1: previous hash",
+		},
+		{
+			name:        "removed first segment",
+			tamper:      func() { delete(store.objects, segments[0]) },
+			expectedErr: "segment " + segments[1] + ": line 1: previous hash",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			original := make(map[string][]byte)
+			for key, data := range store.objects {
+				original[key] = data
+			}
+			defer func() { store.objects = original }()
+
+			test.tamper()
+			_, err := newLog(store, "bucket", nil, fakeClock)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), test.expectedErr)
+		})
+	}
+}
+
+func TestVerifyDetectsTampering(t *testing.T) {
+	store := &fakeObjectStore{objects: make(map[string][]byte)}
+	l, err := newLog(store, "bucket", nil, clock.NewFakeClock(time.Date(2018, 4, 1, 12, 0, 0, 0, time.UTC)))
+	require.NoError(t, err)
+
+	// entries that can't be written when they're recorded are written together in one segment
+	store.putErr = errors.New("unavailable")
+	l.Record(Entry{Event: "BackupStarted", Kind: "Backup", Namespace: "heptio-ark", Name: "backup-1", Requester: "alice"})
+	l.Record(Entry{Event: "BackupCompleted", Kind: "Backup", Namespace: "heptio-ark", Name: "backup-1", Requester: "alice"})
+	l.Record(Entry{Event: "BackupDeleted", Kind: "DeleteBackupRequest", Namespace: "heptio-ark", Name: "backup-1-abcde", Backup: "backup-1", Requester: "bob"})
+	store.putErr = nil
+	require.NoError(t, l.flush())
+
+	segment := string(store.objects[".audit/20180401T120000.000000000Z/log.jsonl"])
+	lines := strings.SplitAfter(segment, "\n")
+
+	tests := []struct {
+		name         string
+		segment      string
+		previousHash string
+		expectedErr  string
+	}{
+		{
+			name:    "untouched segment verifies",
+			segment: segment,
+		},
+		{
+			name:        "changed entry",
+			segment:     strings.Replace(segment, `"requester":"bob"`, `"requester":"carol"`, 1),
+			expectedErr: "line 3: hash",
+		},
+		{
+			name:        "removed entry",
+			segment:     lines[0] + lines[2],
+			expectedErr: "line 2: previous hash",
+		},
+		{
+			name:         "segment that doesn't follow the previous one",
+			segment:      segment,
+			previousHash: "abc",
+			expectedErr:  "line 1: previous hash",
+		},
+		{
+			name:        "invalid JSON",
+			segment:     lines[0] + "{\n",
+			expectedErr: "line 2:",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := Verify(strings.NewReader(test.segment), test.previousHash)
+			if test.expectedErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), test.expectedErr)
+		})
+	}
+}
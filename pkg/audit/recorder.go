@@ -0,0 +1,129 @@
+/*
+Copyright 2017 Heptio Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package audit
+
+import (
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	api "github.com/heptio/ark/pkg/apis/ark/v1"
+	"github.com/heptio/ark/pkg/event"
+)
+
+// backupEvents, restoreEvents, and deletionEvents are the reasons of the events about backups,
+// restores, and DeleteBackupRequests that are added to the audit log: those that record them
+// starting and finishing, but not the progress in between.
+var (
+	backupEvents = sets.NewString(
+		event.ReasonBackupStarted,
+		event.ReasonBackupFailedValidation,
+		event.ReasonBackupCompleted,
+		event.ReasonBackupPartiallyFailed,
+		event.ReasonBackupFailed,
+		event.ReasonBackupExpired,
+		event.ReasonBackupCanceled,
+		event.ReasonBackupInterrupted,
+	)
+
+	restoreEvents = sets.NewString(
+		event.ReasonRestoreStarted,
+		event.ReasonRestoreFailedValidation,
+		event.ReasonRestoreCompleted,
+		event.ReasonRestorePartiallyFailed,
+		event.ReasonRestoreFailed,
+		event.ReasonRestoreInterrupted,
+	)
+
+	deletionEvents = sets.NewString(
+		event.ReasonBackupDeleted,
+		event.ReasonBackupDeletionFailed,
+	)
+)
+
+type auditingRecorder struct {
+	recorder event.Recorder
+	log      Log
+}
+
+// NewRecorder returns an event.Recorder that records events with recorder, and adds the events
+// that mark backups, restores, and backup deletions starting and finishing to log.
+func NewRecorder(recorder event.Recorder, log Log) event.Recorder {
+	return &auditingRecorder{
+		recorder: recorder,
+		log:      log,
+	}
+}
+
+func (r *auditingRecorder) Eventf(obj runtime.Object, eventType, reason, messageFmt string, args ...interface{}) {
+	r.recorder.Eventf(obj, eventType, reason, messageFmt, args...)
+
+	if e, ok := entryFor(obj, reason, fmt.Sprintf(messageFmt, args...)); ok {
+		r.log.Record(e)
+	}
+}
+
+// entryFor returns the audit log entry for an event, and false if it isn't added to the log.
+func entryFor(obj runtime.Object, reason, message string) (Entry, bool) {
+	switch obj := obj.(type) {
+	case *api.Backup:
+		if !backupEvents.Has(reason) {
+			return Entry{}, false
+		}
+		return Entry{
+			Event:     reason,
+			Kind:      "Backup",
+			Namespace: obj.Namespace,
+			Name:      obj.Name,
+			Schedule:  obj.Labels[api.ScheduleNameLabel],
+			Requester: obj.Annotations[api.RequesterAnnotation],
+			Phase:     string(obj.Status.Phase),
+			Message:   message,
+		}, true
+	case *api.Restore:
+		if !restoreEvents.Has(reason) {
+			return Entry{}, false
+		}
+		return Entry{
+			Event:     reason,
+			Kind:      "Restore",
+			Namespace: obj.Namespace,
+			Name:      obj.Name,
+			Backup:    obj.Spec.BackupName,
+			Requester: obj.Annotations[api.RequesterAnnotation],
+			Phase:     string(obj.Status.Phase),
+			Message:   message,
+		}, true
+	case *api.DeleteBackupRequest:
+		if !deletionEvents.Has(reason) {
+			return Entry{}, false
+		}
+		return Entry{
+			Event:     reason,
+			Kind:      "DeleteBackupRequest",
+			Namespace: obj.Namespace,
+			Name:      obj.Name,
+			Backup:    obj.Spec.BackupName,
+			Requester: obj.Spec.Requester,
+			Phase:     string(obj.Status.Phase),
+			Message:   message,
+		}, true
+	default:
+		return Entry{}, false
+	}
+}
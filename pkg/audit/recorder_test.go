@@ -0,0 +1,97 @@
+/*
+Copyright 2017 Heptio Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package audit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"k8s.io/client-go/pkg/api/v1"
+
+	api "github.com/heptio/ark/pkg/apis/ark/v1"
+	"github.com/heptio/ark/pkg/event"
+	. "github.com/heptio/ark/pkg/util/test"
+)
+
+type fakeLog struct {
+	entries []Entry
+}
+
+func (l *fakeLog) Record(e Entry) {
+	l.entries = append(l.entries, e)
+}
+
+func (l *fakeLog) Run(ctx context.Context, flushInterval time.Duration) {}
+
+func TestAuditingRecorder(t *testing.T) {
+	var (
+		recorder = &FakeEventRecorder{}
+		log      = &fakeLog{}
+		r        = NewRecorder(recorder, log)
+	)
+
+	backup := NewTestBackup().WithName("backup-1").WithPhase(api.BackupPhaseInProgress).WithAnnotation(api.RequesterAnnotation, "alice").Backup
+	restore := NewTestRestore(api.DefaultNamespace, "restore-1", api.RestorePhaseCompleted).WithBackup("backup-1").WithAnnotation(api.RequesterAnnotation, "bob").Restore
+	req := &api.DeleteBackupRequest{
+		ObjectMeta: backup.ObjectMeta,
+		Spec:       api.DeleteBackupRequestSpec{BackupName: "backup-1", Requester: "carol"},
+		Status:     api.DeleteBackupRequestStatus{Phase: api.DeleteBackupRequestPhaseProcessed},
+	}
+	req.Name = "backup-1-abcde"
+
+	r.Eventf(backup, v1.EventTypeNormal, event.ReasonBackupStarted, "Started backup")
+	r.Eventf(backup, v1.EventTypeNormal, event.ReasonSnapshotCreated, "Created snapshot %s", "snap-1")
+	r.Eventf(restore, v1.EventTypeNormal, event.ReasonRestoreCompleted, "Restore completed with %d warning(s)", 0)
+	r.Eventf(req, v1.EventTypeNormal, event.ReasonBackupDeleted, "Deleted backup %s", "backup-1")
+	r.Eventf(&api.Schedule{}, v1.EventTypeNormal, event.ReasonBackupStarted, "not a backup")
+
+	assert.Len(t, recorder.Events, 5)
+	assert.Equal(t, []Entry{
+		{
+			Event:     event.ReasonBackupStarted,
+			Kind:      "Backup",
+			Namespace: api.DefaultNamespace,
+			Name:      "backup-1",
+			Requester: "alice",
+			Phase:     string(api.BackupPhaseInProgress),
+			Message:   "Started backup",
+		},
+		{
+			Event:     event.ReasonRestoreCompleted,
+			Kind:      "Restore",
+			Namespace: api.DefaultNamespace,
+			Name:      "restore-1",
+			Backup:    "backup-1",
+			Requester: "bob",
+			Phase:     string(api.RestorePhaseCompleted),
+			Message:   "Restore completed with 0 warning(s)",
+		},
+		{
+			Event:     event.ReasonBackupDeleted,
+			Kind:      "DeleteBackupRequest",
+			Namespace: api.DefaultNamespace,
+			Name:      "backup-1-abcde",
+			Backup:    "backup-1",
+			Requester: "carol",
+			Phase:     string(api.DeleteBackupRequestPhaseProcessed),
+			Message:   "Deleted backup backup-1",
+		},
+	}, log.entries)
+}
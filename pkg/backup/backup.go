@@ -19,31 +19,60 @@ package backup
 import (
 	"archive/tar"
 	"compress/gzip"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"strings"
+	"sync"
 	"time"
 
-	"github.com/golang/glog"
-
 	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	kuberrs "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/pkg/api/v1"
 
 	api "github.com/heptio/ark/pkg/apis/ark/v1"
 	"github.com/heptio/ark/pkg/client"
 	"github.com/heptio/ark/pkg/discovery"
+	"github.com/heptio/ark/pkg/logging"
+	"github.com/heptio/ark/pkg/tracing"
 	"github.com/heptio/ark/pkg/util/collections"
 )
 
 // Backupper performs backups.
 type Backupper interface {
-	// Backup takes a backup using the specification in the api.Backup and writes backup data to the
-	// given writers.
-	Backup(backup *api.Backup, data io.Writer) error
+	// Backup takes a backup using the specification in the api.Backup and writes backup data and
+	// the backup's log to the given writers. If the backup is incremental, parent must provide the
+	// data of its parent backup; otherwise it should be nil. If progress is non-nil, it is notified
+	// as items are discovered and backed up. Errors encountered while backing up individual items
+	// are recorded in the backup's status and log rather than returned; the returned error is only
+	// non-nil if the backup could not be written at all. If ctx is canceled, item collection stops
+	// and ErrCanceled is returned.
+	Backup(ctx context.Context, backup *api.Backup, parent io.Reader, data, log io.Writer, progress ProgressReporter) error
+}
+
+// ErrCanceled is returned by Backupper.Backup when the backup is canceled before it finishes.
+var ErrCanceled = errors.New("backup canceled")
+
+// ProgressReporter receives updates about a backup's progress while it is executing.
+type ProgressReporter interface {
+	// ReportProgress is invoked with a copy of the backup's current progress.
+	ReportProgress(progress api.BackupProgress)
+
+	// ReportItemError is invoked with each error encountered while backing up an individual
+	// item, as it happens. It may be invoked concurrently.
+	ReportItemError(err error)
+
+	// ReportCheckpoint is invoked with a copy of the backup's checkpoint each time a resource
+	// has been completely backed up, and each time an item's actions have taken volume or pod
+	// volume snapshots, so that every snapshot can be cleaned up if the backup is interrupted.
+	ReportCheckpoint(checkpoint api.BackupCheckpoint)
 }
 
 // kubernetesBackupper implements Backupper.
@@ -51,7 +80,14 @@ type kubernetesBackupper struct {
 	dynamicFactory  client.DynamicFactory
 	discoveryHelper discovery.Helper
 	actions         map[schema.GroupResource]Action
+	itemActions     []resolvedItemAction
+	transforms      []resolvedItemTransform
 	itemBackupper   itemBackupper
+	workers         int
+	// pageSize is the most items of a resource that are listed, and held in memory, at a time.
+	pageSize int64
+
+	resourcePriorities []string
 }
 
 var _ Backupper = &kubernetesBackupper{}
@@ -63,22 +99,65 @@ type Action interface {
 	Execute(item map[string]interface{}, backup *api.Backup) error
 }
 
-// NewKubernetesBackupper creates a new kubernetesBackupper.
+// backupFinisher is implemented by Actions that keep state for each backup, so the state can be
+// discarded once the backup is finished.
+type backupFinisher interface {
+	// BackupFinished is invoked once the backup is finished, whether or not it succeeded.
+	BackupFinished(backup *api.Backup)
+}
+
+// NewKubernetesBackupper creates a new kubernetesBackupper. itemActions are executed, in order, on
+// each item they apply to, after its Action. transforms are applied, in order, to each matching
+// item before it's written. resourcePriorities lists resources that are backed up,
+// in order, before all others. workers is the number of resources whose
+// items are collected concurrently; values less than 1 are treated as 1.
 func NewKubernetesBackupper(
 	discoveryHelper discovery.Helper,
 	dynamicFactory client.DynamicFactory,
 	actions map[string]Action,
+	itemActions []ItemAction,
+	transforms []ItemTransform,
+	resourcePriorities []string,
+	workers int,
+	pageSize int64,
 ) (Backupper, error) {
 	resolvedActions, err := resolveActions(discoveryHelper.Mapper(), actions)
 	if err != nil {
 		return nil, err
 	}
 
+	resolvedItemActions, err := resolveItemActions(discoveryHelper.Mapper(), itemActions)
+	if err != nil {
+		return nil, err
+	}
+
+	resolvedTransforms, err := resolveItemTransforms(discoveryHelper.Mapper(), transforms)
+	if err != nil {
+		return nil, err
+	}
+
+	if workers < 1 {
+		workers = 1
+	}
+	if pageSize < 1 {
+		return nil, fmt.Errorf("page size must be at least 1")
+	}
+
 	return &kubernetesBackupper{
 		discoveryHelper: discoveryHelper,
 		dynamicFactory:  dynamicFactory,
 		actions:         resolvedActions,
-		itemBackupper:   &realItemBackupper{},
+		itemActions:     resolvedItemActions,
+		transforms:      resolvedTransforms,
+		itemBackupper: &realItemBackupper{
+			dynamicFactory:  dynamicFactory,
+			discoveryHelper: discoveryHelper,
+			actions:         resolvedActions,
+		},
+		workers:         workers,
+		pageSize:        pageSize,
+
+		resourcePriorities: resourcePriorities,
 	}, nil
 }
 
@@ -101,7 +180,7 @@ func resolveActions(mapper meta.RESTMapper, actions map[string]Action) (map[sche
 // getResourceIncludesExcludes takes the lists of resources to include and exclude from the
 // backup, uses the RESTMapper to resolve them to fully-qualified group-resource names, and returns
 // an IncludesExcludes list.
-func getResourceIncludesExcludes(mapper meta.RESTMapper, backup *api.Backup) *collections.IncludesExcludes {
+func getResourceIncludesExcludes(mapper meta.RESTMapper, backup *api.Backup, log *backupLog) *collections.IncludesExcludes {
 	resources := collections.NewIncludesExcludes()
 
 	resolve := func(list []string, allowAll bool, f func(string)) {
@@ -112,7 +191,8 @@ func getResourceIncludesExcludes(mapper meta.RESTMapper, backup *api.Backup) *co
 			}
 			gr, err := resolveGroupResource(mapper, resource)
 			if err != nil {
-				glog.Errorf("unable to include resource %q in backup: %v", resource, err)
+				log.Warningf("unable to resolve resource %q: %v", resource, err)
+				backup.Status.Warnings++
 				continue
 			}
 			f(gr.String())
@@ -146,6 +226,10 @@ type backupContext struct {
 	w                         tarWriter
 	namespaceIncludesExcludes *collections.IncludesExcludes
 	resourceIncludesExcludes  *collections.IncludesExcludes
+	// itemActions are executed on each item they apply to.
+	itemActions []resolvedItemAction
+	// transforms are applied to each item before it's written.
+	transforms []resolvedItemTransform
 	// deploymentsBackedUp marks whether we've seen and are backing up the deployments resource, from
 	// either the apps or extensions api groups. We only want to back them up once, from whichever api
 	// group we see first.
@@ -154,34 +238,440 @@ type backupContext struct {
 	// resource, from either the networking.k8s.io or extensions api groups. We only want to back them
 	// up once, from whichever api group we see first.
 	networkPoliciesBackedUp bool
+	progress                ProgressReporter
+	log                     *backupLog
+	// parentIndex is the item index of the backup's parent, if it's incremental.
+	parentIndex itemIndex
+	// index records every item included in the backup, whether it's written to the tarball or
+	// unchanged since the parent backup.
+	index itemIndex
+	// backedUp holds the paths of the items that have been backed up, so that items declared as
+	// additional items by ItemActions are only backed up once.
+	backedUp sets.String
+	// statusLock, if set, guards modifications to the backup's status when resources are being
+	// backed up concurrently. It's a pointer so it's shared by copies of the context.
+	statusLock *sync.Mutex
+	// done is closed when the backup is canceled.
+	done <-chan struct{}
+	// spanCtx has the trace span that the spans of the backup's work are children of.
+	spanCtx context.Context
+	// completedResources lists the resources that have been completely backed up. It's only
+	// modified through the backup's top-level context, and it's a pointer so that copies of the
+	// context can include it in the checkpoints they report.
+	completedResources *[]string
+}
+
+// canceled returns whether the backup has been canceled.
+func (ctx *backupContext) canceled() bool {
+	select {
+	case <-ctx.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// withStatusLock runs f while holding the context's status lock, if there is one.
+func (ctx *backupContext) withStatusLock(f func()) {
+	if ctx.statusLock != nil {
+		ctx.statusLock.Lock()
+		defer ctx.statusLock.Unlock()
+	}
+
+	f()
+}
+
+// executeAction runs execute, which may modify the status of the backup it's given. When resources
+// are being backed up concurrently, execute is given a copy of the backup with an empty status
+// so that slow actions (e.g. volume snapshots) don't hold the status lock; the changes it makes
+// are then added to the backup's status while holding the lock.
+func (ctx *backupContext) executeAction(execute func(*api.Backup) error) error {
+	if ctx.statusLock == nil {
+		snapshots := snapshotCount(&ctx.backup.Status)
+		err := execute(ctx.backup)
+		if snapshotCount(&ctx.backup.Status) != snapshots {
+			ctx.reportCheckpoint(ctx.checkpoint())
+		}
+		return err
+	}
+
+	ctx.statusLock.Lock()
+	backup := *ctx.backup
+	backup.Status = api.BackupStatus{}
+	if ctx.backup.Status.Progress != nil {
+		backup.Status.Progress = &api.BackupProgress{}
+	}
+	ctx.statusLock.Unlock()
+
+	err := execute(&backup)
+
+	var checkpoint *api.BackupCheckpoint
+	ctx.statusLock.Lock()
+	addStatusChanges(&ctx.backup.Status, &backup.Status)
+	if ctx.progress != nil && backup.Status.Progress != nil && *backup.Status.Progress != (api.BackupProgress{}) {
+		ctx.progress.ReportProgress(*ctx.backup.Status.Progress)
+	}
+	if snapshotCount(&backup.Status) > 0 {
+		current := ctx.checkpoint()
+		checkpoint = &current
+	}
+	ctx.statusLock.Unlock()
+
+	// checkpoints may be written to the API synchronously, so they're reported
+	// without holding the lock.
+	if checkpoint != nil {
+		ctx.reportCheckpoint(*checkpoint)
+	}
+
+	return err
+}
+
+// snapshotCount returns the number of volume and pod volume snapshots recorded in status.
+func snapshotCount(status *api.BackupStatus) int {
+	return len(status.VolumeBackups) + len(status.PodVolumeSnapshots)
+}
+
+// addStatusChanges adds the changes an action made to an empty backup status to status.
+func addStatusChanges(status, changes *api.BackupStatus) {
+	status.Warnings += changes.Warnings
+	status.Errors += changes.Errors
+
+	for name, info := range changes.VolumeBackups {
+		if status.VolumeBackups == nil {
+			status.VolumeBackups = make(map[string]*api.VolumeBackupInfo)
+		}
+		status.VolumeBackups[name] = info
+	}
+	status.PodVolumeSnapshots = append(status.PodVolumeSnapshots, changes.PodVolumeSnapshots...)
+
+	if status.Progress != nil && changes.Progress != nil {
+		status.Progress.TotalItems += changes.Progress.TotalItems
+		status.Progress.ItemsBackedUp += changes.Progress.ItemsBackedUp
+		status.Progress.VolumeSnapshotsAttempted += changes.Progress.VolumeSnapshotsAttempted
+		status.Progress.VolumeSnapshotsCompleted += changes.Progress.VolumeSnapshotsCompleted
+		status.Progress.PodVolumeBackupsAttempted += changes.Progress.PodVolumeBackupsAttempted
+		status.Progress.PodVolumeBackupsCompleted += changes.Progress.PodVolumeBackupsCompleted
+	}
+}
+
+// itemFailed records an error backing up an individual item (or listing a resource's items) in
+// the backup's log and error count.
+func (ctx *backupContext) itemFailed(err error) {
+	ctx.log.Errorf("Backup %s/%s: %v", ctx.backup.Namespace, ctx.backup.Name, err)
+	ctx.withStatusLock(func() { ctx.backup.Status.Errors++ })
+
+	if ctx.progress != nil {
+		ctx.progress.ReportItemError(err)
+	}
+}
+
+// recordItem adds the item at filePath to the backup's item index.
+func (ctx *backupContext) recordItem(filePath, resourceVersion string) {
+	if ctx.index == nil {
+		return
+	}
+
+	ctx.withStatusLock(func() { ctx.index[filePath] = resourceVersion })
+}
+
+// unchangedSinceParent returns whether the item at filePath is included in the backup's parent
+// with the same resource version.
+func (ctx *backupContext) unchangedSinceParent(filePath, resourceVersion string) bool {
+	if ctx.parentIndex == nil || resourceVersion == "" {
+		return false
+	}
+
+	parentVersion, found := ctx.parentIndex[filePath]
+	return found && parentVersion == resourceVersion
+}
+
+// markBackedUp records that the item at filePath is being backed up, returning false if it
+// already was.
+func (ctx *backupContext) markBackedUp(filePath string) bool {
+	if ctx.backedUp == nil {
+		return true
+	}
+
+	marked := false
+	ctx.withStatusLock(func() {
+		if !ctx.backedUp.Has(filePath) {
+			ctx.backedUp.Insert(filePath)
+			marked = true
+		}
+	})
+	return marked
+}
+
+// resourceCompleted records that all of a resource's items have been backed up, and reports the
+// backup's updated checkpoint.
+func (ctx *backupContext) resourceCompleted(resource string) {
+	// a canceled backup stops partway through its current resource.
+	if ctx.canceled() {
+		return
+	}
+
+	var checkpoint api.BackupCheckpoint
+	ctx.withStatusLock(func() {
+		if ctx.completedResources == nil {
+			ctx.completedResources = new([]string)
+		}
+		*ctx.completedResources = append(*ctx.completedResources, resource)
+
+		checkpoint = ctx.checkpoint()
+	})
+
+	ctx.reportCheckpoint(checkpoint)
+}
+
+// checkpoint returns a copy of the backup's current checkpoint. The status lock must be held, if
+// there is one.
+func (ctx *backupContext) checkpoint() api.BackupCheckpoint {
+	var checkpoint api.BackupCheckpoint
+
+	if ctx.completedResources != nil {
+		checkpoint.CompletedResources = append([]string(nil), *ctx.completedResources...)
+	}
+	if len(ctx.backup.Status.VolumeBackups) > 0 {
+		checkpoint.VolumeBackups = make(map[string]*api.VolumeBackupInfo, len(ctx.backup.Status.VolumeBackups))
+		for name, info := range ctx.backup.Status.VolumeBackups {
+			infoCopy := *info
+			checkpoint.VolumeBackups[name] = &infoCopy
+		}
+	}
+	checkpoint.PodVolumeSnapshots = append([]api.PodVolumeSnapshotInfo(nil), ctx.backup.Status.PodVolumeSnapshots...)
+
+	return checkpoint
+}
+
+// reportCheckpoint reports checkpoint, if the backup's progress is being reported.
+func (ctx *backupContext) reportCheckpoint(checkpoint api.BackupCheckpoint) {
+	if ctx.progress != nil {
+		ctx.progress.ReportCheckpoint(checkpoint)
+	}
+}
+
+// itemsDiscovered adds n to the backup's total item count.
+func (ctx *backupContext) itemsDiscovered(n int) {
+	ctx.updateProgress(func(p *api.BackupProgress) { p.TotalItems += n })
+}
+
+// itemExcluded removes an item that was previously discovered, but that will not be backed up,
+// from the backup's total item count.
+func (ctx *backupContext) itemExcluded() {
+	ctx.updateProgress(func(p *api.BackupProgress) { p.TotalItems-- })
+}
+
+// itemBackedUp increments the count of items written to the backup tarball.
+func (ctx *backupContext) itemBackedUp() {
+	ctx.updateProgress(func(p *api.BackupProgress) { p.ItemsBackedUp++ })
+}
+
+// updateProgress applies update to the backup's progress (if it's being tracked) and notifies
+// the progress reporter (if there is one).
+func (ctx *backupContext) updateProgress(update func(*api.BackupProgress)) {
+	if ctx.backup == nil {
+		return
+	}
+
+	ctx.withStatusLock(func() {
+		if ctx.backup.Status.Progress == nil {
+			return
+		}
+
+		update(ctx.backup.Status.Progress)
+
+		if ctx.progress != nil {
+			ctx.progress.ReportProgress(*ctx.backup.Status.Progress)
+		}
+	})
 }
 
 // Backup backs up the items specified in the Backup, placing them in a gzip-compressed tar file
 // written to data. The finalized api.Backup is written to metadata.
-func (kb *kubernetesBackupper) Backup(backup *api.Backup, data io.Writer) error {
-	gzw := gzip.NewWriter(data)
-	defer gzw.Close()
+func (kb *kubernetesBackupper) Backup(runCtx context.Context, backup *api.Backup, parent io.Reader, data, log io.Writer, progress ProgressReporter) error {
+	backupLog := newBackupLog(log).withFields(logging.Fields{"backup": backup.Name, "namespace": backup.Namespace})
+	backupLog.Infof("Starting backup %s/%s", backup.Namespace, backup.Name)
+
+	defer func() {
+		for _, action := range kb.actions {
+			if finisher, ok := action.(backupFinisher); ok {
+				finisher.BackupFinished(backup)
+			}
+		}
+	}()
+
+	var parentIndex itemIndex
+	if parent != nil {
+		var err error
+		if parentIndex, err = readItemIndex(parent); err != nil {
+			err = fmt.Errorf("error reading item index of parent backup %s: %v", backup.Spec.ParentBackup, err)
+			backupLog.Errorf("%v", err)
+			return err
+		}
+		if parentIndex == nil {
+			backupLog.Warningf("parent backup %s has no item index; backing up all items", backup.Spec.ParentBackup)
+			backup.Status.Warnings++
+		}
+	}
 
-	tw := tar.NewWriter(gzw)
-	defer tw.Close()
+	gzw := gzip.NewWriter(data)
+	tw := newChecksumTarWriter(tar.NewWriter(gzw))
 
-	var errs []error
+	backup.Status.Errors = 0
+	backup.Status.Warnings = 0
+	backup.Status.Progress = &api.BackupProgress{}
 
 	ctx := &backupContext{
 		backup: backup,
 		w:      tw,
 		namespaceIncludesExcludes: getNamespaceIncludesExcludes(backup),
-		resourceIncludesExcludes:  getResourceIncludesExcludes(kb.discoveryHelper.Mapper(), backup),
+		resourceIncludesExcludes:  getResourceIncludesExcludes(kb.discoveryHelper.Mapper(), backup, backupLog),
+		itemActions:               kb.itemActions,
+		transforms:                kb.transforms,
+		progress:                  progress,
+		log:                       backupLog,
+		parentIndex:               parentIndex,
+		index:                     make(itemIndex),
+		backedUp:                  sets.NewString(),
+		completedResources:        new([]string),
+		done:                      runCtx.Done(),
+		spanCtx:                   runCtx,
+	}
+
+	ctx.updateProgress(func(*api.BackupProgress) {})
+
+	backupLog.Infof("Including namespaces: %s", strings.Join(ctx.namespaceIncludesExcludes.GetIncludes(), ", "))
+	backupLog.Infof("Excluding namespaces: %s", strings.Join(ctx.namespaceIncludesExcludes.GetExcludes(), ", "))
+	backupLog.Infof("Including resources: %s", strings.Join(ctx.resourceIncludesExcludes.GetIncludes(), ", "))
+	backupLog.Infof("Excluding resources: %s", strings.Join(ctx.resourceIncludesExcludes.GetExcludes(), ", "))
+
+	resources := kb.prioritizeResources(backup, backupLog)
+
+	if kb.workers > 1 {
+		kb.backupResourcesConcurrently(ctx, resources)
+	} else {
+		for _, group := range resources {
+			ctx.log.Infof("Backing up group %s", group.GroupVersion)
+			kb.backupGroup(ctx, group)
+		}
+	}
+
+	// a canceled backup is discarded, so there's no point finishing the tarball.
+	if ctx.canceled() {
+		backupLog.Infof("Backup canceled after backing up %d item(s)", backup.Status.Progress.ItemsBackedUp)
+		return ErrCanceled
+	}
+
+	// the index, tar and gzip writers must all be written/closed successfully for
+	// the backup file to be valid, so failures here fail the entire backup.
+	var errs []error
+	versions := getResourceVersions(*ctx.completedResources, kb.discoveryHelper.Resources(), kb.discoveryHelper.Mapper())
+	if err := writeResourceVersions(tw, versions); err != nil {
+		errs = append(errs, err)
+	}
+	if err := writeItemIndex(tw, ctx.index); err != nil {
+		errs = append(errs, err)
+	}
+	if err := tw.Close(); err != nil {
+		errs = append(errs, err)
+	}
+	if err := gzw.Close(); err != nil {
+		errs = append(errs, err)
+	}
+
+	backup.Status.ContentChecksum = tw.checksum()
+
+	if err := kuberrs.NewAggregate(errs); err != nil {
+		backupLog.Errorf("error writing backup: %v", err)
+		return err
+	}
+
+	backupLog.Infof("Backup finished with %d error(s) and %d warning(s)", backup.Status.Errors, backup.Status.Warnings)
+
+	return nil
+}
+
+// prioritizeResources returns the discovered resources in the order in which they should be
+// backed up: each of kb.resourcePriorities, in order, followed by all other resources in
+// discovery order. Prioritized resources that can't be resolved or aren't served by the cluster
+// are recorded as warnings and otherwise ignored.
+func (kb *kubernetesBackupper) prioritizeResources(backup *api.Backup, log *backupLog) []*metav1.APIResourceList {
+	resources := kb.discoveryHelper.Resources()
+	if len(kb.resourcePriorities) == 0 {
+		return resources
+	}
+
+	var (
+		ret         []*metav1.APIResourceList
+		prioritized = sets.NewString()
+	)
+
+	for _, resource := range kb.resourcePriorities {
+		gr, err := resolveGroupResource(kb.discoveryHelper.Mapper(), resource)
+		if err != nil {
+			log.Warningf("unable to resolve prioritized resource %q: %v", resource, err)
+			backup.Status.Warnings++
+			continue
+		}
+		if prioritized.Has(gr.String()) {
+			continue
+		}
+
+		list := findAPIResource(resources, gr)
+		if list == nil {
+			log.Warningf("prioritized resource %s was not found in discovery", gr)
+			backup.Status.Warnings++
+			continue
+		}
+
+		ret = append(ret, list)
+		prioritized.Insert(gr.String())
+	}
+
+	for _, group := range resources {
+		gv, err := schema.ParseGroupVersion(group.GroupVersion)
+		if err != nil {
+			// leave it to the caller to report the error
+			ret = append(ret, group)
+			continue
+		}
+
+		remaining := &metav1.APIResourceList{GroupVersion: group.GroupVersion}
+		for _, resource := range group.APIResources {
+			gr := schema.GroupResource{Group: gv.Group, Resource: resource.Name}
+			if !prioritized.Has(gr.String()) {
+				remaining.APIResources = append(remaining.APIResources, resource)
+			}
+		}
+
+		if len(remaining.APIResources) > 0 {
+			ret = append(ret, remaining)
+		}
 	}
 
-	for _, group := range kb.discoveryHelper.Resources() {
-		glog.V(2).Infof("Backing up group %q\n", group.GroupVersion)
-		if err := kb.backupGroup(ctx, group); err != nil {
-			errs = append(errs, err)
+	return ret
+}
+
+// findAPIResource returns an APIResourceList containing only the resource gr, or nil if gr isn't
+// in resources.
+func findAPIResource(resources []*metav1.APIResourceList, gr schema.GroupResource) *metav1.APIResourceList {
+	for _, group := range resources {
+		gv, err := schema.ParseGroupVersion(group.GroupVersion)
+		if err != nil || gv.Group != gr.Group {
+			continue
+		}
+
+		for _, resource := range group.APIResources {
+			if resource.Name == gr.Resource {
+				return &metav1.APIResourceList{
+					GroupVersion: group.GroupVersion,
+					APIResources: []metav1.APIResource{resource},
+				}
+			}
 		}
 	}
 
-	return kuberrs.NewAggregate(errs)
+	return nil
 }
 
 type tarWriter interface {
@@ -190,18 +680,18 @@ type tarWriter interface {
 	WriteHeader(*tar.Header) error
 }
 
-// backupGroup backs up a single API group.
-func (kb *kubernetesBackupper) backupGroup(ctx *backupContext, group *metav1.APIResourceList) error {
-	var errs []error
-
+// backupGroup backs up a single API group. Any errors are recorded in the backup's status and log.
+func (kb *kubernetesBackupper) backupGroup(ctx *backupContext, group *metav1.APIResourceList) {
 	for _, resource := range group.APIResources {
-		glog.V(2).Infof("Backing up resource %s/%s\n", group.GroupVersion, resource.Name)
+		if ctx.canceled() {
+			return
+		}
+
+		ctx.log.Infof("Backing up resource %s/%s", group.GroupVersion, resource.Name)
 		if err := kb.backupResource(ctx, group, resource); err != nil {
-			errs = append(errs, err)
+			ctx.itemFailed(fmt.Errorf("error backing up resource %s/%s: %v", group.GroupVersion, resource.Name, err))
 		}
 	}
-
-	return kuberrs.NewAggregate(errs)
 }
 
 const (
@@ -211,25 +701,45 @@ const (
 	extensionsNetworkPoliciesResource = "networkpolicies.extensions"
 )
 
-// backupResource backs up all the objects for a given group-version-resource.
+// backupResource backs up all the objects for a given group-version-resource. Errors backing up
+// individual items are recorded in the backup's status and log; the returned error is non-nil
+// only if the resource's items could not be listed.
 func (kb *kubernetesBackupper) backupResource(
 	ctx *backupContext,
 	group *metav1.APIResourceList,
 	resource metav1.APIResource,
 ) error {
-	var errs []error
-
 	gv, err := schema.ParseGroupVersion(group.GroupVersion)
 	if err != nil {
 		return err
 	}
-	gvr := schema.GroupVersionResource{Group: gv.Group, Version: gv.Version}
+
+	if !ctx.shouldBackupResource(gv, resource) {
+		return nil
+	}
+
+	gr := schema.GroupResource{Group: gv.Group, Resource: resource.Name}
+	defer ctx.resourceCompleted(gr.String())
+
+	return kb.backupResourceItems(ctx, gv, resource)
+}
+
+// shouldBackupResource returns whether resource should be included in the backup, taking into
+// account the backup's resource includes/excludes and whether it includes cluster-scoped
+// resources, and skipping resources that are duplicates of ones already being backed up from
+// another API group. It must be called for resources in the order in which they're discovered.
+func (ctx *backupContext) shouldBackupResource(gv schema.GroupVersion, resource metav1.APIResource) bool {
 	gr := schema.GroupResource{Group: gv.Group, Resource: resource.Name}
 	grString := gr.String()
 
 	if !ctx.resourceIncludesExcludes.ShouldInclude(grString) {
-		glog.V(2).Infof("Not including resource %s\n", grString)
-		return nil
+		ctx.log.Infof("Not including resource %s", grString)
+		return false
+	}
+
+	if !resource.Namespaced && ctx.backup.Spec.IncludeClusterResources != nil && !*ctx.backup.Spec.IncludeClusterResources {
+		ctx.log.Infof("Not including cluster-scoped resource %s", grString)
+		return false
 	}
 
 	if grString == appsDeploymentsResource || grString == extensionsDeploymentsResource {
@@ -240,8 +750,8 @@ func (kb *kubernetesBackupper) backupResource(
 			} else {
 				other = appsDeploymentsResource
 			}
-			glog.V(4).Infof("Skipping resource %q because it's a duplicate of %q", grString, other)
-			return nil
+			ctx.log.Debugf("Skipping resource %q because it's a duplicate of %q", grString, other)
+			return false
 		}
 
 		ctx.deploymentsBackedUp = true
@@ -255,13 +765,37 @@ func (kb *kubernetesBackupper) backupResource(
 			} else {
 				other = networkingNetworkPoliciesResource
 			}
-			glog.V(4).Infof("Skipping resource %q because it's a duplicate of %q", grString, other)
-			return nil
+			ctx.log.Debugf("Skipping resource %q because it's a duplicate of %q", grString, other)
+			return false
 		}
 
 		ctx.networkPoliciesBackedUp = true
 	}
 
+	return true
+}
+
+// backupResourceItems lists and backs up all the items for resource in the group-version gv.
+func (kb *kubernetesBackupper) backupResourceItems(ctx *backupContext, gv schema.GroupVersion, resource metav1.APIResource) (err error) {
+	gvr := schema.GroupVersionResource{Group: gv.Group, Version: gv.Version}
+	gr := schema.GroupResource{Group: gv.Group, Resource: resource.Name}
+	grString := gr.String()
+
+	// the spans of the resource's items are children of its span, and its messages identify it,
+	// so they're started from and logged with a copy of the context.
+	spanCtx, span := tracing.Start(ctx.spanCtx, "collect items")
+	span.SetAttribute("ark.resource", grString)
+	itemCount := 0
+	defer func() {
+		span.SetAttribute("ark.items", itemCount)
+		span.RecordError(err)
+		span.End()
+	}()
+	resourceCtx := *ctx
+	resourceCtx.spanCtx = spanCtx
+	resourceCtx.log = ctx.log.withFields(logging.Fields{"resource": grString})
+	ctx = &resourceCtx
+
 	var namespacesToList []string
 	if resource.Namespaced {
 		namespacesToList = getNamespacesToList(ctx.namespaceIncludesExcludes)
@@ -269,6 +803,10 @@ func (kb *kubernetesBackupper) backupResource(
 		namespacesToList = []string{""}
 	}
 	for _, namespace := range namespacesToList {
+		if ctx.canceled() {
+			return nil
+		}
+
 		resourceClient, err := kb.dynamicFactory.ClientForGroupVersionResource(gvr, resource, namespace)
 		if err != nil {
 			return err
@@ -278,35 +816,78 @@ func (kb *kubernetesBackupper) backupResource(
 		if ctx.backup.Spec.LabelSelector != nil {
 			labelSelector = metav1.FormatLabelSelector(ctx.backup.Spec.LabelSelector)
 		}
-		unstructuredList, err := resourceClient.List(metav1.ListOptions{LabelSelector: labelSelector})
-		if err != nil {
-			return err
+
+		// items are listed a page at a time, and each page is written to the backup before the
+		// next is listed, so at most one page of items is held in memory.
+		continueToken := ""
+		for page := 1; ; page++ {
+			if ctx.canceled() {
+				return nil
+			}
+
+			unstructuredList, next, err := resourceClient.ListPage(metav1.ListOptions{LabelSelector: labelSelector}, kb.pageSize, continueToken)
+			if err != nil {
+				return err
+			}
+
+			items, err := meta.ExtractList(unstructuredList)
+			if err != nil {
+				return err
+			}
+			ctx.log.Debugf("Listed page %d of resource %s, with %d items", page, grString, len(items))
+
+			ctx.itemsDiscovered(len(items))
+			itemCount += len(items)
+
+			if canceled := kb.backupListedItems(ctx, gr, items); canceled {
+				return nil
+			}
+
+			if next == "" {
+				break
+			}
+			continueToken = next
 		}
+	}
 
-		// do the backup
-		items, err := meta.ExtractList(unstructuredList)
-		if err != nil {
-			return err
+	return nil
+}
+
+// backupListedItems backs up items, a page of the items listed for resource gr. It returns true
+// if the backup was canceled.
+func (kb *kubernetesBackupper) backupListedItems(ctx *backupContext, gr schema.GroupResource, items []runtime.Object) bool {
+	grString := gr.String()
+	action := kb.actions[gr]
+
+	for _, item := range items {
+		if ctx.canceled() {
+			return true
 		}
 
-		action := kb.actions[gr]
+		unstructured, ok := item.(runtime.Unstructured)
+		if !ok {
+			ctx.itemExcluded()
+			ctx.itemFailed(fmt.Errorf("unexpected type %T for resource %s", item, grString))
+			continue
+		}
 
-		for _, item := range items {
-			unstructured, ok := item.(runtime.Unstructured)
-			if !ok {
-				errs = append(errs, fmt.Errorf("unexpected type %T", item))
+		obj := unstructured.UnstructuredContent()
+
+		if grString == "pods" && ctx.backup.Spec.ExcludeCompletedPods {
+			if phase, _ := collections.GetString(obj, "status.phase"); phase == string(v1.PodSucceeded) || phase == string(v1.PodFailed) {
+				name, _ := collections.GetString(obj, "metadata.name")
+				ctx.log.Infof("Excluding pod %s because it has completed", name)
+				ctx.itemExcluded()
 				continue
 			}
+		}
 
-			obj := unstructured.UnstructuredContent()
-
-			if err := kb.itemBackupper.backupItem(ctx, obj, grString, action); err != nil {
-				errs = append(errs, err)
-			}
+		if err := kb.itemBackupper.backupItem(ctx, obj, grString, action); err != nil {
+			ctx.itemFailed(fmt.Errorf("error backing up item of resource %s: %v", grString, err))
 		}
 	}
 
-	return kuberrs.NewAggregate(errs)
+	return false
 }
 
 // getNamespacesToList examines ie and resolves the includes and excludes to a full list of
@@ -336,11 +917,15 @@ type itemBackupper interface {
 	backupItem(ctx *backupContext, item map[string]interface{}, groupResource string, action Action) error
 }
 
-type realItemBackupper struct{}
+type realItemBackupper struct {
+	dynamicFactory  client.DynamicFactory
+	discoveryHelper discovery.Helper
+	actions         map[schema.GroupResource]Action
+}
 
 // backupItem backs up an individual item to tarWriter. The item may be excluded based on the
 // namespaces IncludesExcludes list.
-func (*realItemBackupper) backupItem(ctx *backupContext, item map[string]interface{}, groupResource string, action Action) error {
+func (b *realItemBackupper) backupItem(ctx *backupContext, item map[string]interface{}, groupResource string, action Action) error {
 	// Never save status
 	delete(item, "status")
 
@@ -355,30 +940,87 @@ func (*realItemBackupper) backupItem(ctx *backupContext, item map[string]interfa
 	}
 
 	namespace, err := collections.GetString(metadata, "namespace")
+	log := ctx.log.withFields(logging.Fields{"resource": groupResource, "itemNamespace": namespace, "itemName": name})
 	if err == nil {
 		if !ctx.namespaceIncludesExcludes.ShouldInclude(namespace) {
-			glog.V(2).Infof("Excluding item %s because namespace %s is excluded\n", name, namespace)
+			log.Infof("Excluding item %s because namespace %s is excluded", name, namespace)
+			ctx.itemExcluded()
 			return nil
 		}
 	}
 
+	var filePath string
+	if namespace != "" {
+		filePath = strings.Join([]string{api.NamespaceScopedDir, namespace, groupResource, name + ".json"}, "/")
+	} else {
+		filePath = strings.Join([]string{api.ClusterScopedDir, groupResource, name + ".json"}, "/")
+	}
+
+	if !ctx.markBackedUp(filePath) {
+		log.Debugf("Skipping resource=%s, ns=%s, name=%s because it's already backed up", groupResource, namespace, name)
+		ctx.itemExcluded()
+		return nil
+	}
+
+	var itemActions []resolvedItemAction
+	if len(ctx.itemActions) > 0 {
+		itemLabels := getItemLabels(item)
+		for _, itemAction := range ctx.itemActions {
+			if itemAction.Matches(groupResource, namespace, itemLabels) {
+				itemActions = append(itemActions, itemAction)
+			}
+		}
+	}
+
+	// items with actions are always backed up in full, since actions (e.g. taking
+	// volume snapshots) capture state that isn't reflected in the resource version.
+	resourceVersion, _ := collections.GetString(metadata, "resourceVersion")
+	if action == nil && len(itemActions) == 0 && ctx.unchangedSinceParent(filePath, resourceVersion) {
+		log.Debugf("Skipping resource=%s, ns=%s, name=%s because it's unchanged since the parent backup", groupResource, namespace, name)
+		ctx.recordItem(filePath, resourceVersion)
+		ctx.itemBackedUp()
+		return nil
+	}
+
 	if action != nil {
-		glog.V(4).Infof("Executing action on %s, ns=%s, name=%s", groupResource, namespace, name)
+		log.Debugf("Executing action on %s, ns=%s, name=%s", groupResource, namespace, name)
+
+		// actions, e.g. taking volume snapshots, can take a while, so each is traced.
+		_, span := tracing.Start(ctx.spanCtx, "execute action")
+		span.SetAttribute("ark.resource", groupResource)
+		span.SetAttribute("ark.namespace", namespace)
+		span.SetAttribute("ark.name", name)
 
-		if err := action.Execute(item, ctx.backup); err != nil {
+		err := ctx.executeAction(func(backup *api.Backup) error { return action.Execute(item, backup) })
+		span.RecordError(err)
+		span.End()
+		if err != nil {
 			return err
 		}
 	}
 
-	glog.V(2).Infof("Backing up resource=%s, ns=%s, name=%s", groupResource, namespace, name)
+	var additionalItems []ResourceIdentifier
+	for _, itemAction := range itemActions {
+		log.Debugf("Executing item action on %s, ns=%s, name=%s", groupResource, namespace, name)
 
-	var filePath string
-	if namespace != "" {
-		filePath = strings.Join([]string{api.NamespaceScopedDir, namespace, groupResource, name + ".json"}, "/")
-	} else {
-		filePath = strings.Join([]string{api.ClusterScopedDir, groupResource, name + ".json"}, "/")
+		var ids []ResourceIdentifier
+		err := ctx.executeAction(func(backup *api.Backup) error {
+			var err error
+			ids, err = itemAction.Execute(&unstructured.Unstructured{Object: item}, backup)
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("error executing item action on %s %s/%s: %v", groupResource, namespace, name, err)
+		}
+		additionalItems = append(additionalItems, ids...)
+	}
+
+	if err := transformItem(ctx.transforms, item, groupResource); err != nil {
+		return fmt.Errorf("error transforming %s %s/%s: %v", groupResource, namespace, name, err)
 	}
 
+	log.Infof("Backing up resource=%s, ns=%s, name=%s", groupResource, namespace, name)
+
 	itemBytes, err := json.Marshal(item)
 	if err != nil {
 		return err
@@ -400,5 +1042,53 @@ func (*realItemBackupper) backupItem(ctx *backupContext, item map[string]interfa
 		return err
 	}
 
+	ctx.recordItem(filePath, resourceVersion)
+	ctx.itemBackedUp()
+
+	for _, id := range additionalItems {
+		if err := b.backupAdditionalItem(ctx, id); err != nil {
+			ctx.itemFailed(fmt.Errorf("error backing up additional item %s: %v", id, err))
+		}
+	}
+
 	return nil
 }
+
+// backupAdditionalItem retrieves and backs up an item declared by an ItemAction. Items of
+// resources that are excluded from the backup are skipped.
+func (b *realItemBackupper) backupAdditionalItem(ctx *backupContext, id ResourceIdentifier) error {
+	gr, err := resolveGroupResource(b.discoveryHelper.Mapper(), id.GroupResource.String())
+	if err != nil {
+		return err
+	}
+
+	if ctx.resourceIncludesExcludes != nil && !ctx.resourceIncludesExcludes.ShouldInclude(gr.String()) {
+		ctx.log.Infof("Not including additional item %s because resource %s is excluded", id, gr)
+		return nil
+	}
+
+	list := findAPIResource(b.discoveryHelper.Resources(), gr)
+	if list == nil {
+		return fmt.Errorf("resource %s was not found in discovery", gr)
+	}
+
+	gv, err := schema.ParseGroupVersion(list.GroupVersion)
+	if err != nil {
+		return err
+	}
+
+	resource := list.APIResources[0]
+	resourceClient, err := b.dynamicFactory.ClientForGroupVersionResource(gv.WithResource(resource.Name), resource, id.Namespace)
+	if err != nil {
+		return err
+	}
+
+	item, err := resourceClient.Get(id.Name, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+
+	ctx.itemsDiscovered(1)
+
+	return b.backupItem(ctx, item.UnstructuredContent(), gr.String(), b.actions[gr])
+}
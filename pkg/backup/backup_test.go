@@ -20,11 +20,14 @@ import (
 	"archive/tar"
 	"bytes"
 	"compress/gzip"
+	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
 	"reflect"
 	"sort"
+	"sync"
 	"testing"
 	"time"
 
@@ -184,7 +187,7 @@ func TestGetResourceIncludesExcludes(t *testing.T) {
 				},
 			}
 
-			actual := getResourceIncludesExcludes(mapper, backup)
+			actual := getResourceIncludesExcludes(mapper, backup, nil)
 
 			sort.Strings(test.expectedIncludes)
 			actualIncludes := actual.GetIncludes()
@@ -230,6 +233,91 @@ func TestGetNamespaceIncludesExcludes(t *testing.T) {
 	}
 }
 
+func TestPrioritizeResources(t *testing.T) {
+	resources := []*metav1.APIResourceList{
+		{
+			GroupVersion: "v1",
+			APIResources: []metav1.APIResource{{Name: "configmaps"}, {Name: "pods"}, {Name: "secrets"}},
+		},
+		{
+			GroupVersion: "apps/v1beta1",
+			APIResources: []metav1.APIResource{{Name: "deployments"}},
+		},
+		{
+			GroupVersion: "ark.heptio.com/v1",
+			APIResources: []metav1.APIResource{{Name: "backups"}},
+		},
+	}
+
+	tests := []struct {
+		name             string
+		priorities       []string
+		expected         []*metav1.APIResourceList
+		expectedWarnings int
+	}{
+		{
+			name:       "no priorities keeps discovery order",
+			priorities: nil,
+			expected:   resources,
+		},
+		{
+			name:       "prioritized resources come first, in order",
+			priorities: []string{"backups.ark.heptio.com", "secrets"},
+			expected: []*metav1.APIResourceList{
+				{GroupVersion: "ark.heptio.com/v1", APIResources: []metav1.APIResource{{Name: "backups"}}},
+				{GroupVersion: "v1", APIResources: []metav1.APIResource{{Name: "secrets"}}},
+				{GroupVersion: "v1", APIResources: []metav1.APIResource{{Name: "configmaps"}, {Name: "pods"}}},
+				{GroupVersion: "apps/v1beta1", APIResources: []metav1.APIResource{{Name: "deployments"}}},
+			},
+		},
+		{
+			name:       "duplicate priorities are ignored",
+			priorities: []string{"deployments.apps", "deployments.apps"},
+			expected: []*metav1.APIResourceList{
+				{GroupVersion: "apps/v1beta1", APIResources: []metav1.APIResource{{Name: "deployments"}}},
+				{GroupVersion: "v1", APIResources: []metav1.APIResource{{Name: "configmaps"}, {Name: "pods"}, {Name: "secrets"}}},
+				{GroupVersion: "ark.heptio.com/v1", APIResources: []metav1.APIResource{{Name: "backups"}}},
+			},
+		},
+		{
+			name:       "unresolvable and undiscovered priorities are warnings",
+			priorities: []string{"foo", "jobs.batch", "pods"},
+			expected: []*metav1.APIResourceList{
+				{GroupVersion: "v1", APIResources: []metav1.APIResource{{Name: "pods"}}},
+				{GroupVersion: "v1", APIResources: []metav1.APIResource{{Name: "configmaps"}, {Name: "secrets"}}},
+				{GroupVersion: "apps/v1beta1", APIResources: []metav1.APIResource{{Name: "deployments"}}},
+				{GroupVersion: "ark.heptio.com/v1", APIResources: []metav1.APIResource{{Name: "backups"}}},
+			},
+			expectedWarnings: 2,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			mapper := &FakeMapper{
+				Resources: map[schema.GroupVersionResource]schema.GroupVersionResource{
+					schema.GroupVersionResource{Resource: "secrets"}:                          schema.GroupVersionResource{Resource: "secrets"},
+					schema.GroupVersionResource{Resource: "pods"}:                             schema.GroupVersionResource{Resource: "pods"},
+					schema.GroupVersionResource{Group: "apps", Resource: "deployments"}:       schema.GroupVersionResource{Group: "apps", Resource: "deployments"},
+					schema.GroupVersionResource{Group: "batch", Resource: "jobs"}:             schema.GroupVersionResource{Group: "batch", Resource: "jobs"},
+					schema.GroupVersionResource{Group: "ark.heptio.com", Resource: "backups"}: schema.GroupVersionResource{Group: "ark.heptio.com", Resource: "backups"},
+				},
+			}
+
+			kb := &kubernetesBackupper{
+				discoveryHelper:    &fakeDiscoveryHelper{mapper: mapper, resources: resources},
+				resourcePriorities: test.priorities,
+			}
+
+			backup := &v1.Backup{}
+			actual := kb.prioritizeResources(backup, nil)
+
+			assert.Equal(t, test.expected, actual)
+			assert.Equal(t, test.expectedWarnings, backup.Status.Warnings)
+		})
+	}
+}
+
 type fakeDiscoveryHelper struct {
 	resources []*metav1.APIResourceList
 	mapper    meta.RESTMapper
@@ -341,7 +429,7 @@ func TestBackupMethod(t *testing.T) {
 			}
 		]
 	}`)
-	configMapsClientA.On("List", metav1.ListOptions{}).Return(configMapsA, nil)
+	configMapsClientA.On("ListPage", metav1.ListOptions{}, int64(500), "").Return(configMapsA, "", nil)
 	dynamicFactory.On("ClientForGroupVersionResource", legacyGV, configMapsResource, "a").Return(configMapsClientA, nil)
 
 	configMapsClientB := &FakeDynamicClient{}
@@ -360,7 +448,7 @@ func TestBackupMethod(t *testing.T) {
 			}
 		]
 	}`)
-	configMapsClientB.On("List", metav1.ListOptions{}).Return(configMapsB, nil)
+	configMapsClientB.On("ListPage", metav1.ListOptions{}, int64(500), "").Return(configMapsB, "", nil)
 	dynamicFactory.On("ClientForGroupVersionResource", legacyGV, configMapsResource, "b").Return(configMapsClientB, nil)
 
 	certificatesGV := schema.GroupVersionResource{Group: "certificates.k8s.io", Version: "v1beta1"}
@@ -389,7 +477,7 @@ func TestBackupMethod(t *testing.T) {
 		]
 	}`)
 	csrClient := &FakeDynamicClient{}
-	csrClient.On("List", metav1.ListOptions{}).Return(csrList, nil)
+	csrClient.On("ListPage", metav1.ListOptions{}, int64(500), "").Return(csrList, "", nil)
 	dynamicFactory.On("ClientForGroupVersionResource", certificatesGV, certificateSigningRequestsResource, "").Return(csrClient, nil)
 
 	roleListA := toRuntimeObject(t, `{
@@ -421,10 +509,10 @@ func TestBackupMethod(t *testing.T) {
 	rbacGV := schema.GroupVersionResource{Group: "rbac.authorization.k8s.io", Version: "v1beta1"}
 
 	rolesClientA := &FakeDynamicClient{}
-	rolesClientA.On("List", metav1.ListOptions{}).Return(roleListA, nil)
+	rolesClientA.On("ListPage", metav1.ListOptions{}, int64(500), "").Return(roleListA, "", nil)
 	dynamicFactory.On("ClientForGroupVersionResource", rbacGV, rolesResource, "a").Return(rolesClientA, nil)
 	rolesClientB := &FakeDynamicClient{}
-	rolesClientB.On("List", metav1.ListOptions{}).Return(roleListB, nil)
+	rolesClientB.On("ListPage", metav1.ListOptions{}, int64(500), "").Return(roleListB, "", nil)
 	dynamicFactory.On("ClientForGroupVersionResource", rbacGV, rolesResource, "b").Return(rolesClientB, nil)
 
 	cmAction := &fakeAction{}
@@ -435,13 +523,27 @@ func TestBackupMethod(t *testing.T) {
 		"csr": csrAction,
 	}
 
-	backupper, err := NewKubernetesBackupper(discoveryHelper, dynamicFactory, actions)
+	backupper, err := NewKubernetesBackupper(discoveryHelper, dynamicFactory, actions, nil, nil, nil, 1, 500)
 	require.NoError(t, err)
 
 	output := new(bytes.Buffer)
-	err = backupper.Backup(backup, output)
+	log := new(bytes.Buffer)
+	progress := &fakeProgressReporter{}
+	err = backupper.Backup(context.Background(), backup, nil, output, log, progress)
 	require.NoError(t, err)
 
+	expectedProgress := v1.BackupProgress{TotalItems: 4, ItemsBackedUp: 4}
+	require.NotNil(t, backup.Status.Progress)
+	assert.Equal(t, expectedProgress, *backup.Status.Progress)
+	require.NotEmpty(t, progress.reported)
+	assert.Equal(t, expectedProgress, progress.reported[len(progress.reported)-1])
+	assert.Empty(t, progress.itemErrors)
+	require.NotEmpty(t, progress.checkpoints)
+	assert.Equal(t,
+		v1.BackupCheckpoint{CompletedResources: []string{"configmaps", "certificatesigningrequests.certificates.k8s.io", "roles.rbac.authorization.k8s.io"}},
+		progress.checkpoints[len(progress.checkpoints)-1],
+	)
+
 	expectedFiles := sets.NewString(
 		"namespaces/a/configmaps/configMap1.json",
 		"namespaces/b/configmaps/configMap2.json",
@@ -526,6 +628,10 @@ func TestBackupMethod(t *testing.T) {
 		}
 		require.NoError(t, err)
 
+		if header.Name == v1.ItemIndexFile || header.Name == v1.ResourceVersionsFile {
+			continue
+		}
+
 		switch header.Typeflag {
 		case tar.TypeReg:
 			seenFiles.Insert(header.Name)
@@ -574,6 +680,7 @@ func TestBackupResource(t *testing.T) {
 		resourceGV                      string
 		resourceName                    string
 		resourceNamespaced              bool
+		includeClusterResources         *bool
 		namespaceIncludesExcludes       *collections.IncludesExcludes
 		expectedListedNamespaces        []string
 		lists                           []string
@@ -584,6 +691,8 @@ func TestBackupResource(t *testing.T) {
 		expectedDeploymentsBackedUp     bool
 		networkPoliciesBackedUp         bool
 		expectedNetworkPoliciesBackedUp bool
+		itemBackupErr                   error
+		expectedErrors                  int
 	}{
 		{
 			name: "should not include resource",
@@ -592,6 +701,14 @@ func TestBackupResource(t *testing.T) {
 			resourceName:             "secrets",
 			resourceNamespaced:       true,
 		},
+		{
+			name: "should not include cluster-scoped resource when cluster resources aren't included",
+			resourceIncludesExcludes: collections.NewIncludesExcludes().Includes("*"),
+			resourceGV:               "v1",
+			resourceName:             "persistentvolumes",
+			resourceNamespaced:       false,
+			includeClusterResources:  boolPtr(false),
+		},
 		{
 			name: "should skip deployments.extensions if we've seen deployments.apps",
 			resourceIncludesExcludes:    collections.NewIncludesExcludes().Includes("*"),
@@ -666,6 +783,46 @@ func TestBackupResource(t *testing.T) {
 			},
 			expectedDeploymentsBackedUp: true,
 		},
+		{
+			name: "item errors are recorded and don't stop the backup",
+			resourceIncludesExcludes:  collections.NewIncludesExcludes().Includes("*"),
+			resourceGroup:             "apps",
+			resourceVersion:           "v1beta1",
+			resourceGV:                "apps/v1beta1",
+			resourceName:              "deployments",
+			resourceNamespaced:        true,
+			namespaceIncludesExcludes: collections.NewIncludesExcludes().Includes("a", "b"),
+			expectedListedNamespaces:  []string{"a", "b"},
+			lists: []string{
+				`{
+	"apiVersion": "apps/v1beta1",
+	"kind": "DeploymentList",
+	"items": [
+		{
+			"metadata": {
+				"namespace": "a",
+				"name": "1"
+			}
+		}
+	]
+}`,
+				`{
+	"apiVersion": "apps/v1beta1v1",
+	"kind": "DeploymentList",
+	"items": [
+		{
+			"metadata": {
+				"namespace": "b",
+				"name": "2"
+			}
+		}
+	]
+}`,
+			},
+			expectedDeploymentsBackedUp: true,
+			itemBackupErr:               errors.New("bad item"),
+			expectedErrors:              2,
+		},
 		{
 			name: "list all namespaces when including *",
 			resourceIncludesExcludes:  collections.NewIncludesExcludes().Includes("*"),
@@ -768,13 +925,15 @@ func TestBackupResource(t *testing.T) {
 			ctx := &backupContext{
 				backup: &v1.Backup{
 					Spec: v1.BackupSpec{
-						LabelSelector: labelSelector,
+						LabelSelector:           labelSelector,
+						IncludeClusterResources: test.includeClusterResources,
 					},
 				},
 				resourceIncludesExcludes:  test.resourceIncludesExcludes,
 				namespaceIncludesExcludes: test.namespaceIncludesExcludes,
 				deploymentsBackedUp:       test.deploymentsBackedUp,
 				networkPoliciesBackedUp:   test.networkPoliciesBackedUp,
+				spanCtx:                   context.Background(),
 			}
 
 			group := &metav1.APIResourceList{
@@ -794,7 +953,7 @@ func TestBackupResource(t *testing.T) {
 				obj := toRuntimeObject(t, test.lists[i])
 
 				client := &FakeDynamicClient{}
-				client.On("List", metav1.ListOptions{LabelSelector: test.labelSelector}).Return(obj, nil)
+				client.On("ListPage", metav1.ListOptions{LabelSelector: test.labelSelector}, int64(500), "").Return(obj, "", nil)
 				dynamicFactory.On("ClientForGroupVersionResource", gvr, resource, namespace).Return(client, nil)
 
 				action := test.actions[test.resourceName]
@@ -803,7 +962,8 @@ func TestBackupResource(t *testing.T) {
 				require.NoError(t, err)
 				for i := range list {
 					item := list[i].(*unstructured.Unstructured)
-					itemBackupper.On("backupItem", ctx, item.Object, gr.String(), action).Return(nil)
+					// items are backed up with a copy of ctx that identifies their resource.
+					itemBackupper.On("backupItem", mock.AnythingOfType("*backup.backupContext"), item.Object, gr.String(), action).Return(test.itemBackupErr)
 					if action != nil {
 						a, err := meta.Accessor(item)
 						require.NoError(t, err)
@@ -831,13 +991,15 @@ func TestBackupResource(t *testing.T) {
 				},
 			}
 
-			kb, err := NewKubernetesBackupper(discoveryHelper, dynamicFactory, test.actions)
+			kb, err := NewKubernetesBackupper(discoveryHelper, dynamicFactory, test.actions, nil, nil, nil, 1, 500)
 			require.NoError(t, err)
 			backupper := kb.(*kubernetesBackupper)
 			backupper.itemBackupper = itemBackupper
 
 			err = backupper.backupResource(ctx, group, resource)
+			require.NoError(t, err)
 
+			assert.Equal(t, test.expectedErrors, ctx.backup.Status.Errors)
 			assert.Equal(t, test.expectedDeploymentsBackedUp, ctx.deploymentsBackedUp)
 			assert.Equal(t, test.expectedNetworkPoliciesBackedUp, ctx.networkPoliciesBackedUp)
 			assert.Equal(t, test.expectedActionIDs, actualActionIDs)
@@ -849,6 +1011,260 @@ type fakeItemBackupper struct {
 	mock.Mock
 }
 
+func TestBackupResourceExcludesCompletedPods(t *testing.T) {
+	ctx := &backupContext{
+		backup: &v1.Backup{
+			Spec: v1.BackupSpec{ExcludeCompletedPods: true},
+			Status: v1.BackupStatus{
+				Progress: &v1.BackupProgress{},
+			},
+		},
+		resourceIncludesExcludes:  collections.NewIncludesExcludes().Includes("*"),
+		namespaceIncludesExcludes: collections.NewIncludesExcludes().Includes("*"),
+		spanCtx:                   context.Background(),
+	}
+
+	list := toRuntimeObject(t, `{
+	"apiVersion": "v1",
+	"kind": "PodList",
+	"items": [
+		{"metadata": {"namespace": "ns-1", "name": "running"}, "status": {"phase": "Running"}},
+		{"metadata": {"namespace": "ns-1", "name": "succeeded"}, "status": {"phase": "Succeeded"}},
+		{"metadata": {"namespace": "ns-1", "name": "failed"}, "status": {"phase": "Failed"}}
+	]
+}`)
+
+	resource := metav1.APIResource{Name: "pods", Namespaced: true}
+	client := &FakeDynamicClient{}
+	client.On("ListPage", metav1.ListOptions{}, int64(500), "").Return(list, "", nil)
+	dynamicFactory := &FakeDynamicFactory{}
+	dynamicFactory.On("ClientForGroupVersionResource", schema.GroupVersionResource{Version: "v1"}, resource, "").Return(client, nil)
+
+	items, err := meta.ExtractList(list)
+	require.NoError(t, err)
+	running := items[0].(*unstructured.Unstructured).Object
+
+	itemBackupper := &fakeItemBackupper{}
+	itemBackupper.On("backupItem", mock.AnythingOfType("*backup.backupContext"), running, "pods", nil).Return(nil)
+
+	kb, err := NewKubernetesBackupper(&fakeDiscoveryHelper{mapper: &FakeMapper{}}, dynamicFactory, nil, nil, nil, nil, 1, 500)
+	require.NoError(t, err)
+	backupper := kb.(*kubernetesBackupper)
+	backupper.itemBackupper = itemBackupper
+
+	require.NoError(t, backupper.backupResource(ctx, &metav1.APIResourceList{GroupVersion: "v1"}, resource))
+
+	itemBackupper.AssertNumberOfCalls(t, "backupItem", 1)
+	assert.Equal(t, 1, ctx.backup.Status.Progress.TotalItems)
+}
+
+func TestBackupResourceListsPages(t *testing.T) {
+	ctx := &backupContext{
+		backup: &v1.Backup{
+			Status: v1.BackupStatus{
+				Progress: &v1.BackupProgress{},
+			},
+		},
+		resourceIncludesExcludes:  collections.NewIncludesExcludes().Includes("*"),
+		namespaceIncludesExcludes: collections.NewIncludesExcludes().Includes("*"),
+		spanCtx:                   context.Background(),
+	}
+
+	page1 := toRuntimeObject(t, `{
+	"apiVersion": "v1",
+	"kind": "ConfigMapList",
+	"metadata": {"continue": "page-2"},
+	"items": [
+		{"metadata": {"namespace": "ns-1", "name": "cm-1"}},
+		{"metadata": {"namespace": "ns-1", "name": "cm-2"}}
+	]
+}`)
+	page2 := toRuntimeObject(t, `{
+	"apiVersion": "v1",
+	"kind": "ConfigMapList",
+	"items": [
+		{"metadata": {"namespace": "ns-2", "name": "cm-3"}}
+	]
+}`)
+
+	resource := metav1.APIResource{Name: "configmaps", Namespaced: true}
+	client := &FakeDynamicClient{}
+	client.On("ListPage", metav1.ListOptions{}, int64(2), "").Return(page1, "page-2", nil)
+	client.On("ListPage", metav1.ListOptions{}, int64(2), "page-2").Return(page2, "", nil)
+	dynamicFactory := &FakeDynamicFactory{}
+	dynamicFactory.On("ClientForGroupVersionResource", schema.GroupVersionResource{Version: "v1"}, resource, "").Return(client, nil)
+
+	itemBackupper := &fakeItemBackupper{}
+	var names []string
+	for _, page := range []runtime.Object{page1, page2} {
+		items, err := meta.ExtractList(page)
+		require.NoError(t, err)
+		for _, item := range items {
+			obj := item.(*unstructured.Unstructured)
+			names = append(names, obj.GetName())
+			itemBackupper.On("backupItem", mock.AnythingOfType("*backup.backupContext"), obj.Object, "configmaps", nil).Return(nil)
+		}
+	}
+
+	kb, err := NewKubernetesBackupper(&fakeDiscoveryHelper{mapper: &FakeMapper{}}, dynamicFactory, nil, nil, nil, nil, 1, 2)
+	require.NoError(t, err)
+	backupper := kb.(*kubernetesBackupper)
+	backupper.itemBackupper = itemBackupper
+
+	require.NoError(t, backupper.backupResource(ctx, &metav1.APIResourceList{GroupVersion: "v1"}, resource))
+
+	client.AssertExpectations(t)
+	var backedUp []string
+	for _, call := range itemBackupper.Calls {
+		backedUp = append(backedUp, call.Arguments.Get(1).(map[string]interface{})["metadata"].(map[string]interface{})["name"].(string))
+	}
+	assert.Equal(t, names, backedUp)
+	assert.Equal(t, 3, ctx.backup.Status.Progress.TotalItems)
+}
+
+func TestItemFailedReportsError(t *testing.T) {
+	progress := &fakeProgressReporter{}
+	ctx := &backupContext{
+		backup:   &v1.Backup{},
+		progress: progress,
+	}
+
+	ctx.itemFailed(errors.New("foo"))
+
+	assert.Equal(t, 1, ctx.backup.Status.Errors)
+	assert.Equal(t, []error{errors.New("foo")}, progress.itemErrors)
+}
+
+func TestResourceCompletedReportsCheckpoint(t *testing.T) {
+	progress := &fakeProgressReporter{}
+	ctx := &backupContext{
+		backup: &v1.Backup{
+			Status: v1.BackupStatus{
+				VolumeBackups: map[string]*v1.VolumeBackupInfo{
+					"pv-1": {SnapshotID: "snap-1"},
+				},
+			},
+		},
+		progress: progress,
+	}
+
+	ctx.resourceCompleted("configmaps")
+	ctx.resourceCompleted("persistentvolumes")
+
+	expected := []v1.BackupCheckpoint{
+		{
+			CompletedResources: []string{"configmaps"},
+			VolumeBackups:      map[string]*v1.VolumeBackupInfo{"pv-1": {SnapshotID: "snap-1"}},
+		},
+		{
+			CompletedResources: []string{"configmaps", "persistentvolumes"},
+			VolumeBackups:      map[string]*v1.VolumeBackupInfo{"pv-1": {SnapshotID: "snap-1"}},
+		},
+	}
+	assert.Equal(t, expected, progress.checkpoints)
+
+	// the reported checkpoint must not share state with the backup
+	ctx.backup.Status.VolumeBackups["pv-1"].SnapshotID = "changed"
+	assert.Equal(t, "snap-1", progress.checkpoints[1].VolumeBackups["pv-1"].SnapshotID)
+}
+
+func TestExecuteActionReportsCheckpointForSnapshots(t *testing.T) {
+	for _, concurrent := range []bool{false, true} {
+		t.Run(fmt.Sprintf("concurrent=%t", concurrent), func(t *testing.T) {
+			progress := &fakeProgressReporter{}
+			ctx := &backupContext{
+				backup:             &v1.Backup{},
+				progress:           progress,
+				completedResources: &[]string{"configmaps"},
+			}
+			if concurrent {
+				ctx.statusLock = &sync.Mutex{}
+			}
+
+			// an action that doesn't take a snapshot doesn't report a checkpoint
+			require.NoError(t, ctx.executeAction(func(backup *v1.Backup) error {
+				backup.Status.Warnings++
+				return nil
+			}))
+			assert.Empty(t, progress.checkpoints)
+
+			require.NoError(t, ctx.executeAction(func(backup *v1.Backup) error {
+				backup.Status.VolumeBackups = map[string]*v1.VolumeBackupInfo{"pv-1": {SnapshotID: "snap-1"}}
+				return nil
+			}))
+			// the snapshots of an action that fails partway through are checkpointed too
+			err := ctx.executeAction(func(backup *v1.Backup) error {
+				backup.Status.PodVolumeSnapshots = append(backup.Status.PodVolumeSnapshots, v1.PodVolumeSnapshotInfo{RepoNamespace: "ns-1", Snapshot: "abc123"})
+				return errors.New("second volume failed")
+			})
+			assert.EqualError(t, err, "second volume failed")
+
+			expected := []v1.BackupCheckpoint{
+				{
+					CompletedResources: []string{"configmaps"},
+					VolumeBackups:      map[string]*v1.VolumeBackupInfo{"pv-1": {SnapshotID: "snap-1"}},
+				},
+				{
+					CompletedResources: []string{"configmaps"},
+					VolumeBackups:      map[string]*v1.VolumeBackupInfo{"pv-1": {SnapshotID: "snap-1"}},
+					PodVolumeSnapshots: []v1.PodVolumeSnapshotInfo{{RepoNamespace: "ns-1", Snapshot: "abc123"}},
+				},
+			}
+			assert.Equal(t, expected, progress.checkpoints)
+		})
+	}
+}
+
+func TestBackupCanceled(t *testing.T) {
+	discoveryHelper := &fakeDiscoveryHelper{
+		mapper: &FakeMapper{},
+		resources: []*metav1.APIResourceList{
+			{
+				GroupVersion: "v1",
+				APIResources: []metav1.APIResource{{Name: "configmaps", Namespaced: true}},
+			},
+		},
+	}
+	dynamicFactory := &FakeDynamicFactory{}
+
+	backupper, err := NewKubernetesBackupper(discoveryHelper, dynamicFactory, nil, nil, nil, nil, 1, 500)
+	require.NoError(t, err)
+
+	backup := &v1.Backup{
+		Spec: v1.BackupSpec{
+			IncludedResources:  []string{"*"},
+			IncludedNamespaces: []string{"*"},
+		},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err = backupper.Backup(ctx, backup, nil, new(bytes.Buffer), new(bytes.Buffer), nil)
+	assert.Equal(t, ErrCanceled, err)
+	assert.Equal(t, v1.BackupProgress{}, *backup.Status.Progress)
+	// no items should have been listed
+	dynamicFactory.AssertExpectations(t)
+}
+
+type fakeProgressReporter struct {
+	reported    []v1.BackupProgress
+	itemErrors  []error
+	checkpoints []v1.BackupCheckpoint
+}
+
+func (r *fakeProgressReporter) ReportProgress(progress v1.BackupProgress) {
+	r.reported = append(r.reported, progress)
+}
+
+func (r *fakeProgressReporter) ReportItemError(err error) {
+	r.itemErrors = append(r.itemErrors, err)
+}
+
+func (r *fakeProgressReporter) ReportCheckpoint(checkpoint v1.BackupCheckpoint) {
+	r.checkpoints = append(r.checkpoints, checkpoint)
+}
+
 func (f *fakeItemBackupper) backupItem(ctx *backupContext, obj map[string]interface{}, groupResource string, action Action) error {
 	args := f.Called(ctx, obj, groupResource, action)
 	return args.Error(0)
@@ -1001,7 +1417,8 @@ func TestBackupItem(t *testing.T) {
 			ctx := &backupContext{
 				backup: backup,
 				namespaceIncludesExcludes: namespaces,
-				w: w,
+				w:       w,
+				spanCtx: context.Background(),
 			}
 			b := &realItemBackupper{}
 			err = b.backupItem(ctx, item, "resource.group", actionParam)
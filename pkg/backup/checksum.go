@@ -0,0 +1,98 @@
+/*
+Copyright 2017 Heptio Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package backup
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+)
+
+// ContentChecksum reads a gzip-compressed backup tarball and returns a hex-encoded SHA-256
+// checksum of the names and contents of its files. Since the checksum doesn't depend on the
+// compressed bytes, it's the same no matter how the tarball is stored in object storage. An
+// error is returned if the tarball can't be read.
+func ContentChecksum(r io.Reader) (string, error) {
+	gzr, err := gzip.NewReader(r)
+	if err != nil {
+		return "", err
+	}
+	defer gzr.Close()
+
+	h := sha256.New()
+	tr := tar.NewReader(gzr)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+
+		writeChecksumHeader(h, header)
+		if _, err := io.Copy(h, tr); err != nil {
+			return "", err
+		}
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// writeChecksumHeader adds the name and size of a tar entry to h. Including the size
+// delimits each file's contents in the checksum.
+func writeChecksumHeader(h hash.Hash, header *tar.Header) {
+	fmt.Fprintf(h, "%s\x00%d\x00", header.Name, header.Size)
+}
+
+// checksumTarWriter is a tarWriter that computes the ContentChecksum of everything
+// successfully written through it.
+type checksumTarWriter struct {
+	tarWriter
+	hash hash.Hash
+}
+
+func newChecksumTarWriter(w tarWriter) *checksumTarWriter {
+	return &checksumTarWriter{
+		tarWriter: w,
+		hash:      sha256.New(),
+	}
+}
+
+func (w *checksumTarWriter) WriteHeader(header *tar.Header) error {
+	if err := w.tarWriter.WriteHeader(header); err != nil {
+		return err
+	}
+
+	writeChecksumHeader(w.hash, header)
+	return nil
+}
+
+func (w *checksumTarWriter) Write(p []byte) (int, error) {
+	n, err := w.tarWriter.Write(p)
+	w.hash.Write(p[:n])
+	return n, err
+}
+
+// checksum returns the hex-encoded checksum of the files written so far.
+func (w *checksumTarWriter) checksum() string {
+	return hex.EncodeToString(w.hash.Sum(nil))
+}
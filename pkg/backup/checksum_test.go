@@ -0,0 +1,59 @@
+/*
+Copyright 2017 Heptio Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package backup
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestContentChecksum(t *testing.T) {
+	writeTarball := func(files ...string) ([]byte, string) {
+		buf := new(bytes.Buffer)
+		gzw := gzip.NewWriter(buf)
+		tw := newChecksumTarWriter(tar.NewWriter(gzw))
+
+		for i := 0; i < len(files); i += 2 {
+			name, data := files[i], files[i+1]
+			require.NoError(t, tw.WriteHeader(&tar.Header{Name: name, Size: int64(len(data)), Typeflag: tar.TypeReg}))
+			_, err := tw.Write([]byte(data))
+			require.NoError(t, err)
+		}
+
+		require.NoError(t, tw.Close())
+		require.NoError(t, gzw.Close())
+
+		return buf.Bytes(), tw.checksum()
+	}
+
+	tarball, written := writeTarball("a.json", "ab", "b.json", "c")
+	read, err := ContentChecksum(bytes.NewReader(tarball))
+	require.NoError(t, err)
+	assert.Equal(t, written, read)
+
+	// moving data between files changes the checksum
+	_, other := writeTarball("a.json", "a", "b.json", "bc")
+	assert.NotEqual(t, written, other)
+
+	_, err = ContentChecksum(bytes.NewReader([]byte("not a tarball")))
+	assert.Error(t, err)
+}
@@ -0,0 +1,163 @@
+/*
+Copyright 2017 Heptio Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package backup
+
+import (
+	"archive/tar"
+	"fmt"
+	"sync"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// resourceJob is a single resource whose items are collected by a worker.
+type resourceJob struct {
+	groupVersion string
+	gv           schema.GroupVersion
+	resource     metav1.APIResource
+	ctx          *backupContext
+	buffer       *bufferedTarWriter
+	err          error
+	done         chan struct{}
+}
+
+// backupResourcesConcurrently backs up resources using kb.workers goroutines to list and
+// serialize their items. Each resource's items are buffered in memory and written to the backup
+// tarball in the order of resources, so the resulting tarball is laid out exactly as it would be
+// if the resources were backed up one at a time. At most kb.workers resources are collected or
+// waiting to be written at once, so workers can't run arbitrarily far ahead of a slow resource and
+// buffer the rest of the backup in memory.
+func (kb *kubernetesBackupper) backupResourcesConcurrently(ctx *backupContext, resources []*metav1.APIResourceList) {
+	ctx.statusLock = &sync.Mutex{}
+
+	// determining which resources to back up depends on the order in which they're
+	// seen, so this has to happen before any work is handed off.
+	var jobs []*resourceJob
+	for _, group := range resources {
+		gv, err := schema.ParseGroupVersion(group.GroupVersion)
+		if err != nil {
+			ctx.itemFailed(fmt.Errorf("error parsing group version %q: %v", group.GroupVersion, err))
+			continue
+		}
+
+		for _, resource := range group.APIResources {
+			if !ctx.shouldBackupResource(gv, resource) {
+				continue
+			}
+
+			buffer := &bufferedTarWriter{}
+			jobCtx := *ctx
+			jobCtx.w = buffer
+
+			jobs = append(jobs, &resourceJob{
+				groupVersion: group.GroupVersion,
+				gv:           gv,
+				resource:     resource,
+				ctx:          &jobCtx,
+				buffer:       buffer,
+				done:         make(chan struct{}),
+			})
+		}
+	}
+
+	// a job takes a slot before it's handed to a worker and gives it back once it's been written
+	slots := make(chan struct{}, kb.workers)
+	jobCh := make(chan *resourceJob)
+	for i := 0; i < kb.workers; i++ {
+		go func() {
+			for job := range jobCh {
+				job.ctx.log.Infof("Backing up resource %s/%s", job.groupVersion, job.resource.Name)
+				job.err = kb.backupResourceItems(job.ctx, job.gv, job.resource)
+				close(job.done)
+			}
+		}()
+	}
+
+	go func() {
+		for _, job := range jobs {
+			slots <- struct{}{}
+			jobCh <- job
+		}
+		close(jobCh)
+	}()
+
+	for _, job := range jobs {
+		<-job.done
+
+		if job.err != nil {
+			ctx.itemFailed(fmt.Errorf("error backing up resource %s/%s: %v", job.groupVersion, job.resource.Name, job.err))
+		}
+
+		if err := job.buffer.writeTo(ctx.w); err != nil {
+			ctx.itemFailed(fmt.Errorf("error writing resource %s/%s to backup: %v", job.groupVersion, job.resource.Name, err))
+		}
+
+		gr := schema.GroupResource{Group: job.gv.Group, Resource: job.resource.Name}
+		ctx.resourceCompleted(gr.String())
+
+		// release the buffered items as soon as they've been written
+		job.buffer = nil
+		<-slots
+	}
+}
+
+// bufferedTarWriter is a tarWriter that holds entries in memory until they're written to
+// another tarWriter.
+type bufferedTarWriter struct {
+	entries []*bufferedTarEntry
+}
+
+type bufferedTarEntry struct {
+	header *tar.Header
+	data   []byte
+}
+
+var _ tarWriter = &bufferedTarWriter{}
+
+func (w *bufferedTarWriter) WriteHeader(header *tar.Header) error {
+	w.entries = append(w.entries, &bufferedTarEntry{header: header})
+	return nil
+}
+
+func (w *bufferedTarWriter) Write(data []byte) (int, error) {
+	if len(w.entries) == 0 {
+		return 0, tar.ErrWriteTooLong
+	}
+
+	entry := w.entries[len(w.entries)-1]
+	entry.data = append(entry.data, data...)
+	return len(data), nil
+}
+
+func (w *bufferedTarWriter) Close() error {
+	return nil
+}
+
+// writeTo writes all of the buffered entries to tw, in the order in which they were buffered.
+func (w *bufferedTarWriter) writeTo(tw tarWriter) error {
+	for _, entry := range w.entries {
+		if err := tw.WriteHeader(entry.header); err != nil {
+			return err
+		}
+		if _, err := tw.Write(entry.data); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,281 @@
+/*
+Copyright 2017 Heptio Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package backup
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	kubev1 "k8s.io/client-go/pkg/api/v1"
+
+	"github.com/heptio/ark/pkg/apis/ark/v1"
+	. "github.com/heptio/ark/pkg/util/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBackupConcurrentlyPreservesOrder(t *testing.T) {
+	resourceNames := []string{"configmaps", "secrets", "services", "pods", "endpoints"}
+
+	newBackupper := func(workers int) Backupper {
+		var apiResources []metav1.APIResource
+		dynamicFactory := &FakeDynamicFactory{}
+
+		for _, name := range resourceNames {
+			resource := metav1.APIResource{Name: name, Namespaced: true}
+			apiResources = append(apiResources, resource)
+
+			list := toRuntimeObject(t, fmt.Sprintf(`{
+				"apiVersion": "v1",
+				"kind": "List",
+				"items": [
+					{"apiVersion": "v1", "kind": "Item", "metadata": {"namespace": "a", "name": "%[1]s-1"}},
+					{"apiVersion": "v1", "kind": "Item", "metadata": {"namespace": "a", "name": "%[1]s-2"}}
+				]
+			}`, name))
+
+			client := &FakeDynamicClient{}
+			client.On("ListPage", metav1.ListOptions{}, int64(500), "").Return(list, "", nil)
+			dynamicFactory.On("ClientForGroupVersionResource", schema.GroupVersionResource{Version: "v1"}, resource, "").Return(client, nil)
+		}
+
+		discoveryHelper := &fakeDiscoveryHelper{
+			mapper: &FakeMapper{},
+			resources: []*metav1.APIResourceList{
+				{
+					GroupVersion: "v1",
+					APIResources: apiResources,
+				},
+			},
+		}
+
+		backupper, err := NewKubernetesBackupper(discoveryHelper, dynamicFactory, nil, nil, nil, nil, workers, 500)
+		require.NoError(t, err)
+		return backupper
+	}
+
+	backupFileNames := func(workers int) []string {
+		backup := &v1.Backup{
+			Spec: v1.BackupSpec{
+				IncludedResources:  []string{"*"},
+				IncludedNamespaces: []string{"*"},
+			},
+		}
+
+		output := new(bytes.Buffer)
+		require.NoError(t, newBackupper(workers).Backup(context.Background(), backup, nil, output, ioutil.Discard, nil))
+		assert.Equal(t, 0, backup.Status.Errors)
+		assert.Equal(t, v1.BackupProgress{TotalItems: 10, ItemsBackedUp: 10}, *backup.Status.Progress)
+
+		gzipReader, err := gzip.NewReader(output)
+		require.NoError(t, err)
+		defer gzipReader.Close()
+
+		var names []string
+		tarReader := tar.NewReader(gzipReader)
+		for {
+			header, err := tarReader.Next()
+			if err == io.EOF {
+				break
+			}
+			require.NoError(t, err)
+			names = append(names, header.Name)
+		}
+		return names
+	}
+
+	var expected []string
+	for _, name := range resourceNames {
+		expected = append(expected,
+			fmt.Sprintf("namespaces/a/%s/%s-1.json", name, name),
+			fmt.Sprintf("namespaces/a/%s/%s-2.json", name, name),
+		)
+	}
+	expected = append(expected, v1.ResourceVersionsFile, v1.ItemIndexFile)
+
+	assert.Equal(t, expected, backupFileNames(1))
+	assert.Equal(t, expected, backupFileNames(3))
+}
+
+// onListDynamicClient is a FakeDynamicClient that calls onList whenever a page is listed.
+type onListDynamicClient struct {
+	*FakeDynamicClient
+	onList func()
+}
+
+func (c *onListDynamicClient) ListPage(options metav1.ListOptions, limit int64, continueToken string) (runtime.Object, string, error) {
+	c.onList()
+	return c.FakeDynamicClient.ListPage(options, limit, continueToken)
+}
+
+func TestBackupConcurrentlyBoundsBufferedResources(t *testing.T) {
+	const workers = 2
+	resourceNames := []string{"slow", "configmaps", "secrets", "services", "pods", "endpoints"}
+
+	var (
+		apiResources   []metav1.APIResource
+		dynamicFactory = &FakeDynamicFactory{}
+		release        = make(chan struct{})
+		othersListed   int32
+	)
+	for _, name := range resourceNames {
+		resource := metav1.APIResource{Name: name, Namespaced: true}
+		apiResources = append(apiResources, resource)
+
+		list := toRuntimeObject(t, fmt.Sprintf(`{
+			"apiVersion": "v1",
+			"kind": "List",
+			"items": [
+				{"apiVersion": "v1", "kind": "Item", "metadata": {"namespace": "a", "name": "%s-1"}}
+			]
+		}`, name))
+
+		client := &onListDynamicClient{FakeDynamicClient: &FakeDynamicClient{}}
+		client.On("ListPage", metav1.ListOptions{}, int64(500), "").Return(list, "", nil)
+		if name == "slow" {
+			client.onList = func() { <-release }
+		} else {
+			client.onList = func() { atomic.AddInt32(&othersListed, 1) }
+		}
+		dynamicFactory.On("ClientForGroupVersionResource", schema.GroupVersionResource{Version: "v1"}, resource, "").Return(client, nil)
+	}
+
+	discoveryHelper := &fakeDiscoveryHelper{
+		mapper:    &FakeMapper{},
+		resources: []*metav1.APIResourceList{{GroupVersion: "v1", APIResources: apiResources}},
+	}
+
+	backupper, err := NewKubernetesBackupper(discoveryHelper, dynamicFactory, nil, nil, nil, nil, workers, 500)
+	require.NoError(t, err)
+
+	backup := &v1.Backup{
+		Spec: v1.BackupSpec{
+			IncludedResources:  []string{"*"},
+			IncludedNamespaces: []string{"*"},
+		},
+	}
+	errs := make(chan error)
+	go func() {
+		errs <- backupper.Backup(context.Background(), backup, nil, ioutil.Discard, ioutil.Discard, nil)
+	}()
+
+	// while the first resource can't be written, only the other workers can collect a resource each;
+	// the rest wait rather than being buffered
+	for atomic.LoadInt32(&othersListed) < workers-1 {
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(100 * time.Millisecond)
+	assert.EqualValues(t, workers-1, atomic.LoadInt32(&othersListed))
+
+	close(release)
+	select {
+	case err := <-errs:
+		require.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the backup")
+	}
+	assert.EqualValues(t, len(resourceNames)-1, atomic.LoadInt32(&othersListed))
+	assert.Equal(t, v1.BackupProgress{TotalItems: 6, ItemsBackedUp: 6}, *backup.Status.Progress)
+}
+
+func TestExecuteActionDoesntHoldStatusLock(t *testing.T) {
+	backup := &v1.Backup{Status: v1.BackupStatus{Warnings: 1, Progress: &v1.BackupProgress{TotalItems: 2}}}
+	ctx := &backupContext{backup: backup, statusLock: &sync.Mutex{}}
+
+	// each action waits for the other to start, so they only finish if they run concurrently.
+	started := make(chan struct{}, 2)
+	errs := make(chan error, 2)
+	for _, name := range []string{"a", "b"} {
+		go func(name string) {
+			errs <- ctx.executeAction(func(backup *v1.Backup) error {
+				started <- struct{}{}
+				for len(started) < 2 {
+					time.Sleep(time.Millisecond)
+				}
+
+				backup.Status.Warnings++
+				backup.Status.Progress.VolumeSnapshotsAttempted++
+				if backup.Status.VolumeBackups == nil {
+					backup.Status.VolumeBackups = make(map[string]*v1.VolumeBackupInfo)
+				}
+				backup.Status.VolumeBackups[name] = &v1.VolumeBackupInfo{SnapshotID: "snap-" + name}
+				return nil
+			})
+		}(name)
+	}
+
+	for i := 0; i < 2; i++ {
+		select {
+		case err := <-errs:
+			require.NoError(t, err)
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for actions; they were run one at a time")
+		}
+	}
+
+	assert.Equal(t, 3, backup.Status.Warnings)
+	assert.Equal(t, v1.BackupProgress{TotalItems: 2, VolumeSnapshotsAttempted: 2}, *backup.Status.Progress)
+	assert.Equal(t, map[string]*v1.VolumeBackupInfo{
+		"a": {SnapshotID: "snap-a"},
+		"b": {SnapshotID: "snap-b"},
+	}, backup.Status.VolumeBackups)
+}
+
+func TestExecuteActionGetsClaimsWithoutStatusLock(t *testing.T) {
+	backup := &v1.Backup{Spec: v1.BackupSpec{SnapshotVolumes: boolPtr(false)}}
+	ctx := &backupContext{backup: backup, statusLock: &sync.Mutex{}}
+
+	var gets int
+	pvcClient := &fakePVCGetter{
+		claims: map[string]*kubev1.PersistentVolumeClaim{
+			"ns-1/claim-1": {ObjectMeta: metav1.ObjectMeta{Namespace: "ns-1", Name: "claim-1"}},
+		},
+		onGet: func() {
+			gets++
+			if !ctx.statusLock.TryLock() {
+				t.Error("claim was retrieved while holding the status lock")
+				return
+			}
+			ctx.statusLock.Unlock()
+		},
+	}
+	action, err := NewVolumeSnapshotAction(&FakeSnapshotService{}, nil, pvcClient, nil, nil, false)
+	require.NoError(t, err)
+
+	pv, err := getAsMap(`{
+		"apiVersion": "v1",
+		"kind": "PersistentVolume",
+		"metadata": {"name": "mypv"},
+		"spec": {"claimRef": {"namespace": "ns-1", "name": "claim-1"}}
+	}`)
+	require.NoError(t, err)
+
+	require.NoError(t, ctx.executeAction(func(backup *v1.Backup) error { return action.Execute(pv, backup) }))
+	assert.Equal(t, 1, gets)
+}
@@ -0,0 +1,113 @@
+/*
+Copyright 2017 Heptio Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package backup
+
+import (
+	"errors"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	api "github.com/heptio/ark/pkg/apis/ark/v1"
+	"github.com/heptio/ark/pkg/logging"
+	"github.com/heptio/ark/pkg/restic"
+	kubeutil "github.com/heptio/ark/pkg/util/kube"
+)
+
+// hostVolumeBackupAction is a struct that knows how to back up the data of local and hostPath
+// PersistentVolumes, which can't be snapshotted, through the node agent on their node.
+type hostVolumeBackupAction struct {
+	backupper restic.HostVolumeBackupper
+	next      Action
+}
+
+var _ Action = &hostVolumeBackupAction{}
+
+// NewHostVolumeBackupAction creates an Action that backs up the data of local and hostPath
+// PersistentVolumes using backupper, and passes all other PersistentVolumes to next, if it isn't
+// nil.
+func NewHostVolumeBackupAction(backupper restic.HostVolumeBackupper, next Action) (Action, error) {
+	if backupper == nil {
+		return nil, errors.New("backupper cannot be nil")
+	}
+
+	return &hostVolumeBackupAction{
+		backupper: backupper,
+		next:      next,
+	}, nil
+}
+
+// Execute backs up the data of a local or hostPath PersistentVolume, if volume snapshots are
+// enabled for it, from the node its node affinity names, and records the ID of the resulting
+// snapshot as an annotation on the PV so it can be restored later.
+func (a *hostVolumeBackupAction) Execute(volume map[string]interface{}, backup *api.Backup) error {
+	path, node, err := kubeutil.GetHostVolume(volume)
+	if err != nil {
+		return err
+	}
+	if path == "" {
+		if a.next == nil {
+			return nil
+		}
+		return a.next.Execute(volume, backup)
+	}
+
+	obj := &unstructured.Unstructured{Object: volume}
+	name := obj.GetName()
+	log := logging.WithFields(logging.Fields{"backup": backup.Name, "namespace": backup.Namespace, "resource": "persistentvolumes", "itemName": name})
+
+	if !shouldBackUpHostVolume(volume, backup) {
+		log.V(2).Infof("Backup %s/%s: volume snapshots are disabled for PersistentVolume %s; skipping backup of its data", backup.Namespace, backup.Name, name)
+		return nil
+	}
+
+	if node == "" {
+		log.Warningf("Backup %s/%s: the node affinity of PersistentVolume %s doesn't name a single node; skipping backup of its data", backup.Namespace, backup.Name, name)
+		backup.Status.Warnings++
+		return nil
+	}
+
+	if backup.Status.Progress != nil {
+		backup.Status.Progress.PodVolumeBackupsAttempted++
+	}
+
+	repoNamespace := restic.HostVolumeRepoNamespace(volume)
+	snapshotID, err := a.backupper.BackupHostVolume(backup, repoNamespace, name, node, path)
+	if err != nil {
+		return fmt.Errorf("error backing up data of PersistentVolume %s: %v", name, err)
+	}
+
+	if backup.Status.Progress != nil {
+		backup.Status.Progress.PodVolumeBackupsCompleted++
+	}
+
+	log.V(2).Infof("Backup %s/%s: backed up data of PersistentVolume %s from %s on node %s as snapshot %s", backup.Namespace, backup.Name, name, path, node, snapshotID)
+	restic.SetHostVolumeSnapshot(obj, snapshotID)
+	backup.Status.PodVolumeSnapshots = append(backup.Status.PodVolumeSnapshots, api.PodVolumeSnapshotInfo{RepoNamespace: repoNamespace, Snapshot: snapshotID})
+
+	return nil
+}
+
+// shouldBackUpHostVolume returns whether the data of volume should be backed up: according to its
+// snapshot annotation, if it has one, or else to the backup's SnapshotVolumes.
+func shouldBackUpHostVolume(volume map[string]interface{}, backup *api.Backup) bool {
+	if value, ok := parseSnapshotAnnotation(getSnapshotAnnotation(volume)); ok {
+		return value
+	}
+
+	return backup.Spec.SnapshotVolumes == nil || *backup.Spec.SnapshotVolumes
+}
@@ -0,0 +1,146 @@
+/*
+Copyright 2017 Heptio Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package backup
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/heptio/ark/pkg/apis/ark/v1"
+)
+
+type fakeHostVolumeBackupper struct {
+	// snapshots maps PersistentVolume name to snapshot ID; PVs not in the map fail to back up.
+	snapshots map[string]string
+	backedUp  []string
+}
+
+func (b *fakeHostVolumeBackupper) BackupHostVolume(backup *v1.Backup, repoNamespace, volumeName, node, path string) (string, error) {
+	b.backedUp = append(b.backedUp, repoNamespace+"/"+volumeName+"@"+node+":"+path)
+
+	if snapshotID, found := b.snapshots[volumeName]; found {
+		return snapshotID, nil
+	}
+	return "", errors.New("backup failed")
+}
+
+func TestHostVolumeBackupAction(t *testing.T) {
+	const nodeAffinity = `"nodeAffinity": {"required": {"nodeSelectorTerms": [{"matchExpressions": [{"key": "kubernetes.io/hostname", "operator": "In", "values": ["node-1"]}]}]}}`
+
+	tests := []struct {
+		name                string
+		pv                  string
+		snapshotVolumes     *bool
+		expectError         bool
+		expectedBackedUp    []string
+		expectedNext        []string
+		expectedWarnings    int
+		expectedAnnotations map[string]interface{}
+	}{
+		{
+			name:         "other volumes are passed to the next action",
+			pv:           `{"apiVersion": "v1", "kind": "PersistentVolume", "metadata": {"name": "pv-1"}, "spec": {"awsElasticBlockStore": {"volumeID": "vol-1"}}}`,
+			expectedNext: []string{"pv-1"},
+		},
+		{
+			name:             "local volume is backed up from its node and the snapshot recorded",
+			pv:               `{"apiVersion": "v1", "kind": "PersistentVolume", "metadata": {"name": "pv-1"}, "spec": {"local": {"path": "/mnt/disks/ssd1"}, "claimRef": {"namespace": "ns-1", "name": "pvc-1"}, ` + nodeAffinity + `}}`,
+			expectedBackedUp: []string{"ns-1/pv-1@node-1:/mnt/disks/ssd1"},
+			expectedAnnotations: map[string]interface{}{
+				"backup.ark.heptio.com/host-volume-snapshot": "snapshot-1",
+			},
+		},
+		{
+			name:             "unbound hostPath volume is backed up to the default namespace's repository",
+			pv:               `{"apiVersion": "v1", "kind": "PersistentVolume", "metadata": {"name": "pv-1"}, "spec": {"hostPath": {"path": "/data"}, ` + nodeAffinity + `}}`,
+			expectedBackedUp: []string{"heptio-ark/pv-1@node-1:/data"},
+			expectedAnnotations: map[string]interface{}{
+				"backup.ark.heptio.com/host-volume-snapshot": "snapshot-1",
+			},
+		},
+		{
+			name:             "volume without a node is skipped with a warning",
+			pv:               `{"apiVersion": "v1", "kind": "PersistentVolume", "metadata": {"name": "pv-1"}, "spec": {"hostPath": {"path": "/data"}}}`,
+			expectedWarnings: 1,
+		},
+		{
+			name:            "volume isn't backed up when the backup doesn't snapshot volumes",
+			pv:              `{"apiVersion": "v1", "kind": "PersistentVolume", "metadata": {"name": "pv-1"}, "spec": {"local": {"path": "/mnt/disks/ssd1"}, ` + nodeAffinity + `}}`,
+			snapshotVolumes: boolPtr(false),
+		},
+		{
+			name: "volume isn't backed up when its annotation opts out",
+			pv:   `{"apiVersion": "v1", "kind": "PersistentVolume", "metadata": {"name": "pv-1", "annotations": {"ark.heptio.com/snapshot": "false"}}, "spec": {"local": {"path": "/mnt/disks/ssd1"}, ` + nodeAffinity + `}}`,
+			expectedAnnotations: map[string]interface{}{
+				"ark.heptio.com/snapshot": "false",
+			},
+		},
+		{
+			name:             "failed backup is returned as an error",
+			pv:               `{"apiVersion": "v1", "kind": "PersistentVolume", "metadata": {"name": "pv-2"}, "spec": {"local": {"path": "/mnt/disks/ssd1"}, ` + nodeAffinity + `}}`,
+			expectError:      true,
+			expectedBackedUp: []string{"heptio-ark/pv-2@node-1:/mnt/disks/ssd1"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			backupper := &fakeHostVolumeBackupper{
+				snapshots: map[string]string{"pv-1": "snapshot-1"},
+			}
+			next := &fakeAction{}
+			action, err := NewHostVolumeBackupAction(backupper, next)
+			require.NoError(t, err)
+
+			pv := make(map[string]interface{})
+			require.NoError(t, json.Unmarshal([]byte(test.pv), &pv))
+
+			backup := &v1.Backup{
+				Spec:   v1.BackupSpec{SnapshotVolumes: test.snapshotVolumes},
+				Status: v1.BackupStatus{Progress: &v1.BackupProgress{}},
+			}
+			err = action.Execute(pv, backup)
+			assert.Equal(t, test.expectError, err != nil)
+			assert.Equal(t, test.expectedBackedUp, backupper.backedUp)
+			assert.Equal(t, test.expectedNext, next.ids)
+			assert.Equal(t, test.expectedWarnings, backup.Status.Warnings)
+
+			assert.Equal(t, len(test.expectedBackedUp), backup.Status.Progress.PodVolumeBackupsAttempted)
+			if test.expectError {
+				assert.Equal(t, 0, backup.Status.Progress.PodVolumeBackupsCompleted)
+			} else {
+				assert.Equal(t, len(test.expectedBackedUp), backup.Status.Progress.PodVolumeBackupsCompleted)
+			}
+
+			annotations, _ := pv["metadata"].(map[string]interface{})["annotations"].(map[string]interface{})
+			if test.expectedAnnotations == nil {
+				assert.Empty(t, annotations)
+			} else {
+				assert.Equal(t, test.expectedAnnotations, annotations)
+			}
+		})
+	}
+}
+
+func TestNewHostVolumeBackupActionRequiresBackupper(t *testing.T) {
+	_, err := NewHostVolumeBackupAction(nil, nil)
+	assert.Error(t, err)
+}
@@ -0,0 +1,85 @@
+/*
+Copyright 2017 Heptio Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package backup
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"time"
+
+	api "github.com/heptio/ark/pkg/apis/ark/v1"
+)
+
+// itemIndex maps the path of each item within a backup tarball to the item's resource version.
+type itemIndex map[string]string
+
+// readItemIndex reads the item index from a gzip-compressed backup tarball. If the backup
+// doesn't contain an index (because it was taken before indexes were written), the returned
+// index is nil.
+func readItemIndex(r io.Reader) (itemIndex, error) {
+	gzr, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	defer gzr.Close()
+
+	tr := tar.NewReader(gzr)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if header.Name != api.ItemIndexFile {
+			continue
+		}
+
+		index := make(itemIndex)
+		if err := json.NewDecoder(tr).Decode(&index); err != nil {
+			return nil, err
+		}
+		return index, nil
+	}
+}
+
+// writeItemIndex writes index to the backup tarball.
+func writeItemIndex(w tarWriter, index itemIndex) error {
+	data, err := json.Marshal(index)
+	if err != nil {
+		return err
+	}
+
+	hdr := &tar.Header{
+		Name:     api.ItemIndexFile,
+		Size:     int64(len(data)),
+		Typeflag: tar.TypeReg,
+		Mode:     0755,
+		ModTime:  time.Now(),
+	}
+
+	if err := w.WriteHeader(hdr); err != nil {
+		return err
+	}
+
+	_, err = w.Write(data)
+	return err
+}
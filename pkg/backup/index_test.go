@@ -0,0 +1,136 @@
+/*
+Copyright 2017 Heptio Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package backup
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
+	"io/ioutil"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"github.com/heptio/ark/pkg/apis/ark/v1"
+	. "github.com/heptio/ark/pkg/util/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIncrementalBackup(t *testing.T) {
+	configMapsResource := metav1.APIResource{Name: "configmaps", Namespaced: true}
+
+	newBackupper := func(list string) Backupper {
+		client := &FakeDynamicClient{}
+		client.On("ListPage", metav1.ListOptions{}, int64(500), "").Return(toRuntimeObject(t, list), "", nil)
+
+		dynamicFactory := &FakeDynamicFactory{}
+		dynamicFactory.On("ClientForGroupVersionResource", schema.GroupVersionResource{Version: "v1"}, configMapsResource, "").Return(client, nil)
+
+		discoveryHelper := &fakeDiscoveryHelper{
+			mapper: &FakeMapper{},
+			resources: []*metav1.APIResourceList{
+				{
+					GroupVersion: "v1",
+					APIResources: []metav1.APIResource{configMapsResource},
+				},
+			},
+		}
+
+		backupper, err := NewKubernetesBackupper(discoveryHelper, dynamicFactory, nil, nil, nil, nil, 1, 500)
+		require.NoError(t, err)
+		return backupper
+	}
+
+	newBackup := func(parent string) *v1.Backup {
+		return &v1.Backup{
+			Spec: v1.BackupSpec{
+				IncludedResources:  []string{"*"},
+				IncludedNamespaces: []string{"*"},
+				ParentBackup:       parent,
+			},
+		}
+	}
+
+	// full backup
+	parentBackup := newBackup("")
+	parentData := new(bytes.Buffer)
+	err := newBackupper(`{
+		"apiVersion": "v1",
+		"kind": "ConfigMapList",
+		"items": [
+			{"apiVersion": "v1", "kind": "ConfigMap", "metadata": {"namespace": "a", "name": "unchanged", "resourceVersion": "1"}},
+			{"apiVersion": "v1", "kind": "ConfigMap", "metadata": {"namespace": "a", "name": "changed", "resourceVersion": "2"}},
+			{"apiVersion": "v1", "kind": "ConfigMap", "metadata": {"namespace": "a", "name": "deleted", "resourceVersion": "3"}}
+		]
+	}`).Backup(context.Background(), parentBackup, nil, parentData, ioutil.Discard, nil)
+	require.NoError(t, err)
+
+	parentIndex, err := readItemIndex(bytes.NewReader(parentData.Bytes()))
+	require.NoError(t, err)
+	assert.Equal(t, itemIndex{
+		"namespaces/a/configmaps/unchanged.json": "1",
+		"namespaces/a/configmaps/changed.json":   "2",
+		"namespaces/a/configmaps/deleted.json":   "3",
+	}, parentIndex)
+
+	// incremental backup
+	backup := newBackup("parent")
+	data := new(bytes.Buffer)
+	err = newBackupper(`{
+		"apiVersion": "v1",
+		"kind": "ConfigMapList",
+		"items": [
+			{"apiVersion": "v1", "kind": "ConfigMap", "metadata": {"namespace": "a", "name": "unchanged", "resourceVersion": "1"}},
+			{"apiVersion": "v1", "kind": "ConfigMap", "metadata": {"namespace": "a", "name": "changed", "resourceVersion": "4"}},
+			{"apiVersion": "v1", "kind": "ConfigMap", "metadata": {"namespace": "a", "name": "new", "resourceVersion": "5"}}
+		]
+	}`).Backup(context.Background(), backup, bytes.NewReader(parentData.Bytes()), data, ioutil.Discard, nil)
+	require.NoError(t, err)
+
+	assert.Equal(t, v1.BackupProgress{TotalItems: 3, ItemsBackedUp: 3}, *backup.Status.Progress)
+
+	gzr, err := gzip.NewReader(bytes.NewReader(data.Bytes()))
+	require.NoError(t, err)
+	var names []string
+	tr := tar.NewReader(gzr)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		names = append(names, header.Name)
+	}
+	assert.Equal(t, []string{
+		"namespaces/a/configmaps/changed.json",
+		"namespaces/a/configmaps/new.json",
+		v1.ResourceVersionsFile,
+		v1.ItemIndexFile,
+	}, names)
+
+	index, err := readItemIndex(bytes.NewReader(data.Bytes()))
+	require.NoError(t, err)
+	assert.Equal(t, itemIndex{
+		"namespaces/a/configmaps/unchanged.json": "1",
+		"namespaces/a/configmaps/changed.json":   "4",
+		"namespaces/a/configmaps/new.json":       "5",
+	}, index)
+}
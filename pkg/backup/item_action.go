@@ -0,0 +1,129 @@
+/*
+Copyright 2017 Heptio Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package backup
+
+import (
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	api "github.com/heptio/ark/pkg/apis/ark/v1"
+	"github.com/heptio/ark/pkg/util/collections"
+	"github.com/heptio/ark/pkg/util/itemaction"
+)
+
+// ItemAction is an extension point that lets code outside of Ark inspect and modify individual
+// items while they're backed up, and declare additional items that must be backed up along with
+// them (e.g. the secrets used by a database).
+type ItemAction interface {
+	// AppliesTo returns a ResourceSelector describing the items the action is executed on.
+	AppliesTo() (ResourceSelector, error)
+
+	// Execute is invoked on each item the action applies to, before it's written to the
+	// backup. It may modify item in place, and returns identifiers of additional items to back
+	// up. If an error is returned, the item isn't backed up.
+	Execute(item *unstructured.Unstructured, backup *api.Backup) ([]ResourceIdentifier, error)
+}
+
+// ResourceSelector selects the items an ItemAction applies to.
+type ResourceSelector = itemaction.ResourceSelector
+
+// ResourceIdentifier identifies an item to back up.
+type ResourceIdentifier struct {
+	schema.GroupResource
+	// Namespace is empty for cluster-scoped items.
+	Namespace string
+	Name      string
+}
+
+func (id ResourceIdentifier) String() string {
+	if id.Namespace == "" {
+		return fmt.Sprintf("%s %s", id.GroupResource.String(), id.Name)
+	}
+	return fmt.Sprintf("%s %s/%s", id.GroupResource.String(), id.Namespace, id.Name)
+}
+
+var itemActions = itemaction.NewRegistry("backup")
+
+// RegisterItemAction registers action under name so that the Ark server executes it during every
+// backup. It's intended to be called from an init function of a package compiled into the server
+// binary. It panics if an action is already registered under name.
+func RegisterItemAction(name string, action ItemAction) {
+	itemActions.Register(name, action)
+}
+
+// RegisteredItemActions returns the names of the registered ItemActions and the actions
+// themselves, sorted by name.
+func RegisteredItemActions() ([]string, []ItemAction) {
+	names, registered := itemActions.Registered()
+
+	var actions []ItemAction
+	for _, action := range registered {
+		actions = append(actions, action.(ItemAction))
+	}
+
+	return names, actions
+}
+
+// resolvedItemAction is an ItemAction whose ResourceSelector has been resolved.
+type resolvedItemAction struct {
+	ItemAction
+	*itemaction.Selector
+}
+
+// resolveItemActions resolves the ResourceSelector of each of actions using mapper.
+func resolveItemActions(mapper meta.RESTMapper, actions []ItemAction) ([]resolvedItemAction, error) {
+	var ret []resolvedItemAction
+
+	for _, action := range actions {
+		selector, err := action.AppliesTo()
+		if err != nil {
+			return nil, err
+		}
+
+		resolved, err := itemaction.Resolve(mapper, selector)
+		if err != nil {
+			return nil, err
+		}
+
+		ret = append(ret, resolvedItemAction{
+			ItemAction: action,
+			Selector:   resolved,
+		})
+	}
+
+	return ret, nil
+}
+
+// getItemLabels returns the labels of item.
+func getItemLabels(item map[string]interface{}) labels.Set {
+	labelsMap, err := collections.GetMap(item, "metadata.labels")
+	if err != nil {
+		return nil
+	}
+
+	itemLabels := make(labels.Set)
+	for key, value := range labelsMap {
+		if s, ok := value.(string); ok {
+			itemLabels[key] = s
+		}
+	}
+	return itemLabels
+}
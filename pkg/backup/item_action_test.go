@@ -0,0 +1,312 @@
+/*
+Copyright 2017 Heptio Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package backup
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"github.com/heptio/ark/pkg/apis/ark/v1"
+	"github.com/heptio/ark/pkg/util/collections"
+	"github.com/heptio/ark/pkg/util/itemaction"
+	. "github.com/heptio/ark/pkg/util/test"
+)
+
+type fakeItemAction struct {
+	selector        ResourceSelector
+	additionalItems []ResourceIdentifier
+	err             error
+	executed        []string
+}
+
+var _ ItemAction = &fakeItemAction{}
+
+func (a *fakeItemAction) AppliesTo() (ResourceSelector, error) {
+	return a.selector, nil
+}
+
+func (a *fakeItemAction) Execute(item *unstructured.Unstructured, backup *v1.Backup) ([]ResourceIdentifier, error) {
+	if a.err != nil {
+		return nil, a.err
+	}
+
+	a.executed = append(a.executed, item.GetNamespace()+"/"+item.GetName())
+
+	annotations := item.GetAnnotations()
+	if annotations == nil {
+		annotations = make(map[string]string)
+	}
+	annotations["executed"] = "true"
+	item.SetAnnotations(annotations)
+
+	return a.additionalItems, nil
+}
+
+func TestRegisterItemAction(t *testing.T) {
+	defer func() { itemActions = itemaction.NewRegistry("backup") }()
+
+	a, b := &fakeItemAction{}, &fakeItemAction{}
+	RegisterItemAction("b", b)
+	RegisterItemAction("a", a)
+
+	names, actions := RegisteredItemActions()
+	assert.Equal(t, []string{"a", "b"}, names)
+	require.Len(t, actions, 2)
+	assert.True(t, actions[0] == a)
+	assert.True(t, actions[1] == b)
+
+	assert.Panics(t, func() { RegisterItemAction("a", &fakeItemAction{}) })
+}
+
+func TestItemActionAppliesTo(t *testing.T) {
+	mapper := &FakeMapper{
+		Resources: map[schema.GroupVersionResource]schema.GroupVersionResource{
+			schema.GroupVersionResource{Resource: "pods"}:    schema.GroupVersionResource{Version: "v1", Resource: "pods"},
+			schema.GroupVersionResource{Resource: "secrets"}: schema.GroupVersionResource{Version: "v1", Resource: "secrets"},
+		},
+	}
+
+	tests := []struct {
+		name          string
+		selector      ResourceSelector
+		groupResource string
+		namespace     string
+		labels        labels.Set
+		expected      bool
+	}{
+		{
+			name:          "empty selector matches everything",
+			groupResource: "pods",
+			namespace:     "ns-1",
+			expected:      true,
+		},
+		{
+			name:          "empty selector matches cluster-scoped items",
+			groupResource: "persistentvolumes",
+			expected:      true,
+		},
+		{
+			name:          "included resource matches",
+			selector:      ResourceSelector{IncludedResources: []string{"pods"}},
+			groupResource: "pods",
+			namespace:     "ns-1",
+			expected:      true,
+		},
+		{
+			name:          "other resource doesn't match",
+			selector:      ResourceSelector{IncludedResources: []string{"pods"}},
+			groupResource: "secrets",
+			namespace:     "ns-1",
+			expected:      false,
+		},
+		{
+			name:          "excluded resource doesn't match",
+			selector:      ResourceSelector{ExcludedResources: []string{"secrets"}},
+			groupResource: "secrets",
+			namespace:     "ns-1",
+			expected:      false,
+		},
+		{
+			name:          "excluded namespace doesn't match",
+			selector:      ResourceSelector{ExcludedNamespaces: []string{"ns-1"}},
+			groupResource: "pods",
+			namespace:     "ns-1",
+			expected:      false,
+		},
+		{
+			name:          "cluster-scoped item doesn't match explicit namespaces",
+			selector:      ResourceSelector{IncludedNamespaces: []string{"ns-1"}},
+			groupResource: "persistentvolumes",
+			expected:      false,
+		},
+		{
+			name:          "label selector matches",
+			selector:      ResourceSelector{LabelSelector: labels.SelectorFromSet(labels.Set{"app": "db"})},
+			groupResource: "pods",
+			namespace:     "ns-1",
+			labels:        labels.Set{"app": "db"},
+			expected:      true,
+		},
+		{
+			name:          "label selector doesn't match",
+			selector:      ResourceSelector{LabelSelector: labels.SelectorFromSet(labels.Set{"app": "db"})},
+			groupResource: "pods",
+			namespace:     "ns-1",
+			labels:        labels.Set{"app": "web"},
+			expected:      false,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			resolved, err := resolveItemActions(mapper, []ItemAction{&fakeItemAction{selector: test.selector}})
+			require.NoError(t, err)
+			require.Len(t, resolved, 1)
+
+			assert.Equal(t, test.expected, resolved[0].Matches(test.groupResource, test.namespace, test.labels))
+		})
+	}
+}
+
+func TestBackupItemWithItemActions(t *testing.T) {
+	secretsResource := metav1.APIResource{Name: "secrets", Namespaced: true}
+
+	discoveryHelper := &fakeDiscoveryHelper{
+		mapper: &FakeMapper{
+			Resources: map[schema.GroupVersionResource]schema.GroupVersionResource{
+				schema.GroupVersionResource{Resource: "pods"}:    schema.GroupVersionResource{Version: "v1", Resource: "pods"},
+				schema.GroupVersionResource{Resource: "secrets"}: schema.GroupVersionResource{Version: "v1", Resource: "secrets"},
+			},
+		},
+		resources: []*metav1.APIResourceList{
+			{
+				GroupVersion: "v1",
+				APIResources: []metav1.APIResource{{Name: "pods", Namespaced: true}, secretsResource},
+			},
+		},
+	}
+
+	tests := []struct {
+		name              string
+		action            *fakeItemAction
+		backedUp          []string
+		excludedResources []string
+		expectErr         bool
+		expectedHeaders   []string
+		expectedErrors    int
+	}{
+		{
+			name:            "item action modifies the item",
+			action:          &fakeItemAction{},
+			expectedHeaders: []string{"namespaces/ns-1/pods/pod-1.json"},
+		},
+		{
+			name: "additional items are backed up",
+			action: &fakeItemAction{
+				selector:        ResourceSelector{IncludedResources: []string{"pods"}},
+				additionalItems: []ResourceIdentifier{{GroupResource: schema.GroupResource{Resource: "secrets"}, Namespace: "ns-1", Name: "secret-1"}},
+			},
+			expectedHeaders: []string{"namespaces/ns-1/pods/pod-1.json", "namespaces/ns-1/secrets/secret-1.json"},
+		},
+		{
+			name: "additional items that are already backed up are skipped",
+			action: &fakeItemAction{
+				selector:        ResourceSelector{IncludedResources: []string{"pods"}},
+				additionalItems: []ResourceIdentifier{{GroupResource: schema.GroupResource{Resource: "secrets"}, Namespace: "ns-1", Name: "secret-1"}},
+			},
+			backedUp:        []string{"namespaces/ns-1/secrets/secret-1.json"},
+			expectedHeaders: []string{"namespaces/ns-1/pods/pod-1.json"},
+		},
+		{
+			name: "additional items of excluded resources are skipped",
+			action: &fakeItemAction{
+				selector:        ResourceSelector{IncludedResources: []string{"pods"}},
+				additionalItems: []ResourceIdentifier{{GroupResource: schema.GroupResource{Resource: "secrets"}, Namespace: "ns-1", Name: "secret-1"}},
+			},
+			excludedResources: []string{"secrets"},
+			expectedHeaders:   []string{"namespaces/ns-1/pods/pod-1.json"},
+		},
+		{
+			name: "missing additional item is recorded as an error",
+			action: &fakeItemAction{
+				selector:        ResourceSelector{IncludedResources: []string{"pods"}},
+				additionalItems: []ResourceIdentifier{{GroupResource: schema.GroupResource{Resource: "secrets"}, Namespace: "ns-1", Name: "missing"}},
+			},
+			expectedHeaders: []string{"namespaces/ns-1/pods/pod-1.json"},
+			expectedErrors:  1,
+		},
+		{
+			name:      "item action error fails the item",
+			action:    &fakeItemAction{err: errors.New("bad")},
+			expectErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			resolved, err := resolveItemActions(discoveryHelper.Mapper(), []ItemAction{test.action})
+			require.NoError(t, err)
+
+			secretsClient := &FakeDynamicClient{}
+			secretsClient.On("Get", "secret-1", metav1.GetOptions{}).Return(&unstructured.Unstructured{Object: map[string]interface{}{
+				"metadata": map[string]interface{}{"namespace": "ns-1", "name": "secret-1"},
+			}}, nil)
+			secretsClient.On("Get", "missing", metav1.GetOptions{}).Return(&unstructured.Unstructured{}, errors.New("not found"))
+
+			dynamicFactory := &FakeDynamicFactory{}
+			dynamicFactory.On("ClientForGroupVersionResource", schema.GroupVersionResource{Version: "v1", Resource: "secrets"}, secretsResource, "ns-1").Return(secretsClient, nil)
+
+			w := &fakeTarWriter{}
+			ctx := &backupContext{
+				backup:                    &v1.Backup{},
+				w:                         w,
+				namespaceIncludesExcludes: collections.NewIncludesExcludes().Includes("*"),
+				resourceIncludesExcludes:  collections.NewIncludesExcludes().Includes("*").Excludes(test.excludedResources...),
+				itemActions:               resolved,
+				backedUp:                  sets.NewString(test.backedUp...),
+				log:                       newBackupLog(nil),
+			}
+
+			b := &realItemBackupper{
+				dynamicFactory:  dynamicFactory,
+				discoveryHelper: discoveryHelper,
+			}
+
+			item := map[string]interface{}{
+				"metadata": map[string]interface{}{"namespace": "ns-1", "name": "pod-1"},
+			}
+			err = b.backupItem(ctx, item, "pods", nil)
+			if test.expectErr {
+				assert.Error(t, err)
+				assert.Empty(t, w.headers)
+				return
+			}
+			require.NoError(t, err)
+
+			var headers []string
+			for _, header := range w.headers {
+				headers = append(headers, header.Name)
+			}
+			assert.Equal(t, test.expectedHeaders, headers)
+			assert.Equal(t, test.expectedErrors, ctx.backup.Status.Errors)
+
+			written := make(map[string]interface{})
+			require.NoError(t, json.Unmarshal(w.data[0], &written))
+			annotations, err := collections.GetMap(written, "metadata.annotations")
+			require.NoError(t, err)
+			assert.Equal(t, "true", annotations["executed"])
+		})
+	}
+}
+
+func TestResourceIdentifierString(t *testing.T) {
+	id := ResourceIdentifier{GroupResource: schema.GroupResource{Group: "apps", Resource: "deployments"}, Namespace: "ns-1", Name: "deploy-1"}
+	assert.Equal(t, "deployments.apps ns-1/deploy-1", id.String())
+
+	id = ResourceIdentifier{GroupResource: schema.GroupResource{Resource: "persistentvolumes"}, Name: "pv-1"}
+	assert.Equal(t, "persistentvolumes pv-1", id.String())
+}
@@ -0,0 +1,93 @@
+/*
+Copyright 2017 Heptio Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package backup
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"io"
+	"sort"
+	"strings"
+
+	api "github.com/heptio/ark/pkg/apis/ark/v1"
+)
+
+// Item identifies an item in a backup by its resource, namespace, and name. Namespace is empty
+// for cluster-scoped items.
+type Item struct {
+	Resource  string `json:"resource"`
+	Namespace string `json:"namespace,omitempty"`
+	Name      string `json:"name"`
+}
+
+// ItemForPath returns the item stored at the given path in a backup tarball, or false if the
+// path isn't an item's.
+func ItemForPath(path string) (Item, bool) {
+	if !strings.HasSuffix(path, ".json") {
+		return Item{}, false
+	}
+	parts := strings.Split(strings.TrimSuffix(path, ".json"), "/")
+
+	switch {
+	case len(parts) == 3 && parts[0] == api.ClusterScopedDir:
+		return Item{Resource: parts[1], Name: parts[2]}, true
+	case len(parts) == 4 && parts[0] == api.NamespaceScopedDir:
+		return Item{Resource: parts[2], Namespace: parts[1], Name: parts[3]}, true
+	default:
+		return Item{}, false
+	}
+}
+
+// ItemLess orders items by resource, namespace, and name.
+func ItemLess(a, b Item) bool {
+	if a.Resource != b.Resource {
+		return a.Resource < b.Resource
+	}
+	if a.Namespace != b.Namespace {
+		return a.Namespace < b.Namespace
+	}
+	return a.Name < b.Name
+}
+
+// WriteItemList writes the list of the items in the gzip-compressed backup tarball read from
+// backup to w, as gzip-compressed JSON, sorted by resource, namespace, and name. The list is
+// read from the backup's item index, so it includes the items of an incremental backup that
+// are stored in its ancestors.
+func WriteItemList(backup io.Reader, w io.Writer) error {
+	index, err := readItemIndex(backup)
+	if err != nil {
+		return err
+	}
+	if index == nil {
+		return errors.New("backup has no item index")
+	}
+
+	items := make([]Item, 0, len(index))
+	for path := range index {
+		if item, ok := ItemForPath(path); ok {
+			items = append(items, item)
+		}
+	}
+	sort.Slice(items, func(i, j int) bool { return ItemLess(items[i], items[j]) })
+
+	gzw := gzip.NewWriter(w)
+	if err := json.NewEncoder(gzw).Encode(items); err != nil {
+		return err
+	}
+	return gzw.Close()
+}
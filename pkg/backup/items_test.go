@@ -0,0 +1,98 @@
+/*
+Copyright 2017 Heptio Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package backup
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestItemForPath(t *testing.T) {
+	tests := []struct {
+		path     string
+		expected Item
+		ok       bool
+	}{
+		{
+			path:     "cluster/persistentvolumes/pv-1.json",
+			expected: Item{Resource: "persistentvolumes", Name: "pv-1"},
+			ok:       true,
+		},
+		{
+			path:     "namespaces/ns-1/deployments.apps/web.json",
+			expected: Item{Resource: "deployments.apps", Namespace: "ns-1", Name: "web"},
+			ok:       true,
+		},
+		{
+			path: "index.json",
+		},
+		{
+			path: "namespaces/ns-1/pods/web.yaml",
+		},
+		{
+			path: "namespaces/ns-1/pods",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.path, func(t *testing.T) {
+			item, ok := ItemForPath(test.path)
+			assert.Equal(t, test.ok, ok)
+			assert.Equal(t, test.expected, item)
+		})
+	}
+}
+
+func TestWriteItemList(t *testing.T) {
+	backup := new(bytes.Buffer)
+	gzw := gzip.NewWriter(backup)
+	tw := tar.NewWriter(gzw)
+	require.NoError(t, writeItemIndex(tw, itemIndex{
+		"namespaces/b/pods/web.json":          "3",
+		"namespaces/a/pods/web.json":          "2",
+		"cluster/persistentvolumes/pv-1.json": "1",
+	}))
+	require.NoError(t, tw.Close())
+	require.NoError(t, gzw.Close())
+
+	list := new(bytes.Buffer)
+	require.NoError(t, WriteItemList(backup, list))
+
+	gzr, err := gzip.NewReader(list)
+	require.NoError(t, err)
+	var items []Item
+	require.NoError(t, json.NewDecoder(gzr).Decode(&items))
+
+	assert.Equal(t, []Item{
+		{Resource: "persistentvolumes", Name: "pv-1"},
+		{Resource: "pods", Namespace: "a", Name: "web"},
+		{Resource: "pods", Namespace: "b", Name: "web"},
+	}, items)
+
+	// backups without an index don't have an item list
+	backup.Reset()
+	gzw = gzip.NewWriter(backup)
+	require.NoError(t, tar.NewWriter(gzw).Close())
+	require.NoError(t, gzw.Close())
+	assert.Error(t, WriteItemList(backup, new(bytes.Buffer)))
+}
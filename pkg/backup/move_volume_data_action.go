@@ -0,0 +1,88 @@
+/*
+Copyright 2017 Heptio Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package backup
+
+import (
+	"errors"
+
+	corev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+
+	api "github.com/heptio/ark/pkg/apis/ark/v1"
+	"github.com/heptio/ark/pkg/logging"
+	"github.com/heptio/ark/pkg/util/collections"
+)
+
+// moveVolumeDataAction is a struct that knows how to report the PersistentVolumes whose data a
+// backup that moves volume data can't move.
+type moveVolumeDataAction struct {
+	podClient corev1.PodsGetter
+	next      Action
+}
+
+var _ Action = &moveVolumeDataAction{}
+
+// NewMoveVolumeDataAction creates an Action that records a warning for each bound PersistentVolume
+// in a backup that moves volume data whose claim no running pod mounts, since the data of claims
+// is only moved through the running pods that mount them. Each PersistentVolume is then passed to
+// next, if it isn't nil.
+func NewMoveVolumeDataAction(podClient corev1.PodsGetter, next Action) (Action, error) {
+	if podClient == nil {
+		return nil, errors.New("podClient cannot be nil")
+	}
+
+	return &moveVolumeDataAction{
+		podClient: podClient,
+		next:      next,
+	}, nil
+}
+
+// Execute records a warning if the backup moves volume data and the PersistentVolume is bound to a
+// claim that no running pod mounts, then passes the PersistentVolume to the next action.
+func (a *moveVolumeDataAction) Execute(volume map[string]interface{}, backup *api.Backup) error {
+	if backup.Spec.MoveVolumeData {
+		a.checkMounted(volume, backup)
+	}
+
+	if a.next == nil {
+		return nil
+	}
+	return a.next.Execute(volume, backup)
+}
+
+func (a *moveVolumeDataAction) checkMounted(volume map[string]interface{}, backup *api.Backup) {
+	claimNamespace, err := collections.GetString(volume, "spec.claimRef.namespace")
+	if err != nil {
+		return
+	}
+	claimName, err := collections.GetString(volume, "spec.claimRef.name")
+	if err != nil {
+		return
+	}
+
+	name, _ := collections.GetString(volume, "metadata.name")
+	log := logging.WithFields(logging.Fields{"backup": backup.Name, "namespace": backup.Namespace, "resource": "persistentvolumes", "itemName": name})
+
+	mounted, err := mountedByRunningPod(a.podClient, claimNamespace, claimName)
+	switch {
+	case err != nil:
+		log.Warningf("Backup %s/%s: the pods that mount PersistentVolumeClaim %s/%s couldn't be listed, so it's unknown whether the data of PersistentVolume %s is moved: %v", backup.Namespace, backup.Name, claimNamespace, claimName, name, err)
+		backup.Status.Warnings++
+	case !mounted:
+		log.Warningf("Backup %s/%s: no running pod mounts PersistentVolumeClaim %s/%s, so the data of PersistentVolume %s isn't moved", backup.Namespace, backup.Name, claimNamespace, claimName, name)
+		backup.Status.Warnings++
+	}
+}
@@ -0,0 +1,109 @@
+/*
+Copyright 2017 Heptio Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package backup
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubev1 "k8s.io/client-go/pkg/api/v1"
+
+	"github.com/heptio/ark/pkg/apis/ark/v1"
+)
+
+func TestMoveVolumeDataAction(t *testing.T) {
+	const boundPV = `{"apiVersion": "v1", "kind": "PersistentVolume", "metadata": {"name": "mypv"}, "spec": {"claimRef": {"namespace": "ns-1", "name": "data"}}}`
+
+	tests := []struct {
+		name             string
+		pv               string
+		moveVolumeData   bool
+		pods             []kubev1.Pod
+		listErr          error
+		expectedWarnings int
+	}{
+		{
+			name:           "claim mounted by a running pod isn't reported",
+			pv:             boundPV,
+			moveVolumeData: true,
+			pods: []kubev1.Pod{
+				podMountingClaim("ns-1", "pod-1", "data", kubev1.PodRunning),
+			},
+		},
+		{
+			name:           "claim mounted by no running pod is reported",
+			pv:             boundPV,
+			moveVolumeData: true,
+			pods: []kubev1.Pod{
+				podMountingClaim("ns-1", "pod-1", "data", kubev1.PodSucceeded),
+				podMountingClaim("ns-1", "pod-2", "other", kubev1.PodRunning),
+				podMountingClaim("ns-2", "pod-3", "data", kubev1.PodRunning),
+			},
+			expectedWarnings: 1,
+		},
+		{
+			name:             "claim whose pods can't be listed is reported",
+			pv:               boundPV,
+			moveVolumeData:   true,
+			listErr:          errors.New("forbidden"),
+			expectedWarnings: 1,
+		},
+		{
+			name:           "unbound volume isn't reported",
+			pv:             `{"apiVersion": "v1", "kind": "PersistentVolume", "metadata": {"name": "mypv"}, "spec": {}}`,
+			moveVolumeData: true,
+		},
+		{
+			name: "backup that doesn't move volume data isn't checked",
+			pv:   boundPV,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			next := &fakeAction{}
+			action, err := NewMoveVolumeDataAction(&fakePodGetter{pods: test.pods, listErr: test.listErr}, next)
+			require.NoError(t, err)
+
+			pv, err := getAsMap(test.pv)
+			require.NoError(t, err)
+
+			backup := &v1.Backup{ObjectMeta: metav1.ObjectMeta{Namespace: "heptio-ark", Name: "backup-1"}}
+			backup.Spec.MoveVolumeData = test.moveVolumeData
+			require.NoError(t, action.Execute(pv, backup))
+
+			assert.Equal(t, test.expectedWarnings, backup.Status.Warnings)
+			assert.Equal(t, []string{"mypv"}, next.ids, "expected the volume to be passed to the next action")
+		})
+	}
+}
+
+func TestMoveVolumeDataActionWithoutNext(t *testing.T) {
+	action, err := NewMoveVolumeDataAction(&fakePodGetter{}, nil)
+	require.NoError(t, err)
+
+	pv, err := getAsMap(`{"apiVersion": "v1", "kind": "PersistentVolume", "metadata": {"name": "mypv"}, "spec": {"claimRef": {"namespace": "ns-1", "name": "data"}}}`)
+	require.NoError(t, err)
+
+	backup := &v1.Backup{Spec: v1.BackupSpec{MoveVolumeData: true}}
+	require.NoError(t, action.Execute(pv, backup))
+	assert.Equal(t, 1, backup.Status.Warnings)
+}
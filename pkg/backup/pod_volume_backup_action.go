@@ -0,0 +1,241 @@
+/*
+Copyright 2017 Heptio Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package backup
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/golang/glog"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	kerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/sets"
+	corev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/pkg/api/v1"
+
+	api "github.com/heptio/ark/pkg/apis/ark/v1"
+	"github.com/heptio/ark/pkg/client"
+	"github.com/heptio/ark/pkg/logging"
+	"github.com/heptio/ark/pkg/restic"
+	kubeutil "github.com/heptio/ark/pkg/util/kube"
+)
+
+// podVolumeBackupAction is a struct that knows how to back up the data in pod volumes using
+// restic.
+type podVolumeBackupAction struct {
+	backupper restic.Backupper
+	pvcClient corev1.PersistentVolumeClaimsGetter
+	pvClient  client.Dynamic
+
+	// claims holds the claims whose data each in-progress backup, keyed by namespace and name,
+	// has already backed up through one of the pods that mount them.
+	claimsLock sync.Mutex
+	claims     map[string]sets.String
+}
+
+var _ Action = &podVolumeBackupAction{}
+
+// NewPodVolumeBackupAction creates an Action that backs up the data in the volumes listed in each
+// pod's restic.VolumesToBackupAnnotation, along with all of its PersistentVolumeClaim-backed
+// volumes if the backup moves volume data. Otherwise, if pvcClient and pvClient aren't nil, the
+// pod's volumes whose claims are bound to NFS or EFS PersistentVolumes, which can't be
+// snapshotted, are backed up too, unless snapshots are disabled for them.
+func NewPodVolumeBackupAction(backupper restic.Backupper, pvcClient corev1.PersistentVolumeClaimsGetter, pvClient client.Dynamic) (Action, error) {
+	if backupper == nil {
+		return nil, errors.New("backupper cannot be nil")
+	}
+
+	return &podVolumeBackupAction{
+		backupper: backupper,
+		pvcClient: pvcClient,
+		pvClient:  pvClient,
+		claims:    make(map[string]sets.String),
+	}, nil
+}
+
+// BackupFinished discards the claims recorded for the backup.
+func (a *podVolumeBackupAction) BackupFinished(backup *api.Backup) {
+	a.claimsLock.Lock()
+	defer a.claimsLock.Unlock()
+
+	delete(a.claims, backup.Namespace+"/"+backup.Name)
+}
+
+// claimBackedUp returns whether the backup has already backed up the claim's data through
+// another pod.
+func (a *podVolumeBackupAction) claimBackedUp(backup *api.Backup, claimNamespace, claimName string) bool {
+	a.claimsLock.Lock()
+	defer a.claimsLock.Unlock()
+
+	return a.claims[backup.Namespace+"/"+backup.Name].Has(claimNamespace + "/" + claimName)
+}
+
+// recordClaimBackedUp records that the backup has backed up the claim's data, so that it isn't
+// backed up again through the other pods that mount it. It's only called once the claim's volume
+// has been backed up successfully, so a claim whose backup failed is tried again through the
+// next pod.
+func (a *podVolumeBackupAction) recordClaimBackedUp(backup *api.Backup, claimNamespace, claimName string) {
+	a.claimsLock.Lock()
+	defer a.claimsLock.Unlock()
+
+	key := backup.Namespace + "/" + backup.Name
+	claims, found := a.claims[key]
+	if !found {
+		claims = sets.NewString()
+		a.claims[key] = claims
+	}
+	claims.Insert(claimNamespace + "/" + claimName)
+}
+
+// Execute backs up each of the pod's opted-in volumes using restic, and records the IDs of the
+// resulting restic snapshots as annotations on the pod so they can be restored later.
+func (a *podVolumeBackupAction) Execute(item map[string]interface{}, backup *api.Backup) error {
+	obj := &unstructured.Unstructured{Object: item}
+
+	volumes := restic.GetVolumesToBackup(obj)
+
+	pod := new(v1.Pod)
+	data, err := json.Marshal(item)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, pod); err != nil {
+		return err
+	}
+	log := logging.WithFields(logging.Fields{"backup": backup.Name, "namespace": backup.Namespace, "resource": "pods", "itemNamespace": pod.Namespace, "itemName": pod.Name})
+
+	var fileSystemClaims map[string]string
+	if backup.Spec.MoveVolumeData {
+		volumes = appendClaimVolumes(volumes, pod)
+	} else {
+		volumes, fileSystemClaims = a.appendFileSystemVolumes(volumes, pod, backup)
+	}
+	if len(volumes) == 0 {
+		return nil
+	}
+
+	if pod.Status.Phase != v1.PodRunning {
+		log.V(2).Infof("Backup %s/%s: pod %s/%s is not running; skipping restic backup of its volumes", backup.Namespace, backup.Name, pod.Namespace, pod.Name)
+		return nil
+	}
+
+	var errs []error
+	for _, volume := range volumes {
+		if backup.Status.Progress != nil {
+			backup.Status.Progress.PodVolumeBackupsAttempted++
+		}
+
+		snapshotID, err := a.backupper.BackupPodVolume(backup, pod, volume)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		if backup.Status.Progress != nil {
+			backup.Status.Progress.PodVolumeBackupsCompleted++
+		}
+		if claimName, found := fileSystemClaims[volume]; found {
+			a.recordClaimBackedUp(backup, pod.Namespace, claimName)
+		}
+
+		log.V(2).Infof("Backup %s/%s: backed up volume %s of pod %s/%s as restic snapshot %s", backup.Namespace, backup.Name, volume, pod.Namespace, pod.Name, snapshotID)
+		restic.SetSnapshot(obj, volume, snapshotID)
+		backup.Status.PodVolumeSnapshots = append(backup.Status.PodVolumeSnapshots, api.PodVolumeSnapshotInfo{RepoNamespace: pod.Namespace, Snapshot: snapshotID})
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("error backing up volumes of pod %s/%s: %v", pod.Namespace, pod.Name, kerrors.NewAggregate(errs))
+	}
+
+	return nil
+}
+
+// appendClaimVolumes appends the names of pod's PersistentVolumeClaim-backed volumes to volumes,
+// skipping any that are already in it.
+func appendClaimVolumes(volumes []string, pod *v1.Pod) []string {
+	listed := sets.NewString(volumes...)
+
+	for _, volume := range pod.Spec.Volumes {
+		if volume.PersistentVolumeClaim != nil && !listed.Has(volume.Name) {
+			volumes = append(volumes, volume.Name)
+		}
+	}
+
+	return volumes
+}
+
+// appendFileSystemVolumes appends the names of pod's volumes whose claims are bound to NFS or EFS
+// PersistentVolumes to volumes, skipping any that are already in it or that snapshots are
+// disabled for, by the volume's or claim's snapshot annotation or else by the backup. Since NFS
+// and EFS claims are often mounted by many pods, volumes whose claims the backup has already
+// backed up through another pod are skipped. It also returns the names of the appended volumes'
+// claims, keyed by volume name.
+func (a *podVolumeBackupAction) appendFileSystemVolumes(volumes []string, pod *v1.Pod, backup *api.Backup) ([]string, map[string]string) {
+	if a.pvcClient == nil || a.pvClient == nil {
+		return volumes, nil
+	}
+
+	listed := sets.NewString(volumes...)
+	claims := make(map[string]string)
+
+	for _, volume := range pod.Spec.Volumes {
+		if volume.PersistentVolumeClaim == nil || listed.Has(volume.Name) {
+			continue
+		}
+
+		claimName := volume.PersistentVolumeClaim.ClaimName
+		claim, err := a.pvcClient.PersistentVolumeClaims(pod.Namespace).Get(claimName, metav1.GetOptions{})
+		if err != nil {
+			glog.Warningf("error getting PersistentVolumeClaim %s/%s: %v", pod.Namespace, claimName, err)
+			continue
+		}
+		if claim.Spec.VolumeName == "" {
+			continue
+		}
+
+		pv, err := a.pvClient.Get(claim.Spec.VolumeName, metav1.GetOptions{})
+		if err != nil {
+			glog.Warningf("error getting PersistentVolume %s: %v", claim.Spec.VolumeName, err)
+			continue
+		}
+		if !kubeutil.IsFileSystemVolume(pv.Object) {
+			continue
+		}
+
+		include := backup.Spec.SnapshotVolumes == nil || *backup.Spec.SnapshotVolumes
+		if value, ok := parseSnapshotAnnotation(pv.GetAnnotations()[api.SnapshotVolumeAnnotation]); ok {
+			include = value
+		} else if value, ok := parseSnapshotAnnotation(claim.Annotations[api.SnapshotVolumeAnnotation]); ok {
+			include = value
+		}
+		if !include {
+			continue
+		}
+		if a.claimBackedUp(backup, pod.Namespace, claimName) {
+			glog.V(2).Infof("Backup %s/%s: skipping volume %s of pod %s/%s; PersistentVolumeClaim %s is already backed up through another pod", backup.Namespace, backup.Name, volume.Name, pod.Namespace, pod.Name, claimName)
+			continue
+		}
+		volumes = append(volumes, volume.Name)
+		claims[volume.Name] = claimName
+	}
+
+	return volumes, claims
+}
@@ -0,0 +1,296 @@
+/*
+Copyright 2017 Heptio Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package backup
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	kubev1 "k8s.io/client-go/pkg/api/v1"
+
+	"github.com/heptio/ark/pkg/apis/ark/v1"
+	"github.com/heptio/ark/pkg/restic"
+	. "github.com/heptio/ark/pkg/util/test"
+)
+
+type fakeResticBackupper struct {
+	// snapshots maps volume name to snapshot ID; volumes not in the map fail to back up.
+	snapshots map[string]string
+	backedUp  []string
+}
+
+func (b *fakeResticBackupper) BackupPodVolume(backup *v1.Backup, pod *kubev1.Pod, volumeName string) (string, error) {
+	b.backedUp = append(b.backedUp, volumeName)
+
+	if snapshotID, found := b.snapshots[volumeName]; found {
+		return snapshotID, nil
+	}
+	return "", errors.New("backup failed")
+}
+
+func TestPodVolumeBackupAction(t *testing.T) {
+	tests := []struct {
+		name                string
+		pod                 string
+		moveVolumeData      bool
+		expectError         bool
+		expectedBackedUp    []string
+		expectedAnnotations map[string]interface{}
+	}{
+		{
+			name: "no volumes to back up",
+			pod:  `{"apiVersion": "v1", "kind": "Pod", "metadata": {"name": "pod-1"}, "status": {"phase": "Running"}}`,
+		},
+		{
+			name: "pod not running",
+			pod:  `{"apiVersion": "v1", "kind": "Pod", "metadata": {"name": "pod-1", "annotations": {"backup.ark.heptio.com/backup-volumes": "data"}}, "status": {"phase": "Pending"}}`,
+			expectedAnnotations: map[string]interface{}{
+				"backup.ark.heptio.com/backup-volumes": "data",
+			},
+		},
+		{
+			name:             "volumes are backed up and snapshots recorded",
+			pod:              `{"apiVersion": "v1", "kind": "Pod", "metadata": {"name": "pod-1", "annotations": {"backup.ark.heptio.com/backup-volumes": "data,cache"}}, "status": {"phase": "Running"}}`,
+			expectedBackedUp: []string{"data", "cache"},
+			expectedAnnotations: map[string]interface{}{
+				"backup.ark.heptio.com/backup-volumes": "data,cache",
+				"snapshot.ark.heptio.com/data":         "snapshot-1",
+				"snapshot.ark.heptio.com/cache":        "snapshot-2",
+			},
+		},
+		{
+			name:             "failed volume backups are returned as an error",
+			pod:              `{"apiVersion": "v1", "kind": "Pod", "metadata": {"name": "pod-1", "annotations": {"backup.ark.heptio.com/backup-volumes": "data,logs"}}, "status": {"phase": "Running"}}`,
+			expectError:      true,
+			expectedBackedUp: []string{"data", "logs"},
+			expectedAnnotations: map[string]interface{}{
+				"backup.ark.heptio.com/backup-volumes": "data,logs",
+				"snapshot.ark.heptio.com/data":         "snapshot-1",
+			},
+		},
+		{
+			name:                "claim volumes aren't backed up unless the backup moves volume data",
+			pod:                 `{"apiVersion": "v1", "kind": "Pod", "metadata": {"name": "pod-1"}, "spec": {"volumes": [{"name": "data", "persistentVolumeClaim": {"claimName": "pvc-1"}}]}, "status": {"phase": "Running"}}`,
+			expectedAnnotations: nil,
+		},
+		{
+			name:             "claim volumes are backed up when the backup moves volume data",
+			pod:              `{"apiVersion": "v1", "kind": "Pod", "metadata": {"name": "pod-1", "annotations": {"backup.ark.heptio.com/backup-volumes": "cache,data"}}, "spec": {"volumes": [{"name": "data", "persistentVolumeClaim": {"claimName": "pvc-1"}}, {"name": "cache", "emptyDir": {}}, {"name": "logs", "persistentVolumeClaim": {"claimName": "pvc-2"}}]}, "status": {"phase": "Running"}}`,
+			moveVolumeData:   true,
+			expectError:      true,
+			expectedBackedUp: []string{"cache", "data", "logs"},
+			expectedAnnotations: map[string]interface{}{
+				"backup.ark.heptio.com/backup-volumes": "cache,data",
+				"snapshot.ark.heptio.com/data":         "snapshot-1",
+				"snapshot.ark.heptio.com/cache":        "snapshot-2",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			backupper := &fakeResticBackupper{
+				snapshots: map[string]string{"data": "snapshot-1", "cache": "snapshot-2"},
+			}
+			action, err := NewPodVolumeBackupAction(backupper, nil, nil)
+			require.NoError(t, err)
+
+			pod := make(map[string]interface{})
+			require.NoError(t, json.Unmarshal([]byte(test.pod), &pod))
+
+			backup := &v1.Backup{
+				Spec:   v1.BackupSpec{MoveVolumeData: test.moveVolumeData},
+				Status: v1.BackupStatus{Progress: &v1.BackupProgress{}},
+			}
+			err = action.Execute(pod, backup)
+			assert.Equal(t, test.expectError, err != nil)
+			assert.Equal(t, test.expectedBackedUp, backupper.backedUp)
+
+			completed := 0
+			for _, volume := range test.expectedBackedUp {
+				if _, found := backupper.snapshots[volume]; found {
+					completed++
+				}
+			}
+			assert.Equal(t, len(test.expectedBackedUp), backup.Status.Progress.PodVolumeBackupsAttempted)
+			assert.Equal(t, completed, backup.Status.Progress.PodVolumeBackupsCompleted)
+			assert.Len(t, backup.Status.PodVolumeSnapshots, completed)
+
+			annotations, _ := pod["metadata"].(map[string]interface{})["annotations"].(map[string]interface{})
+			if test.expectedAnnotations == nil {
+				assert.Empty(t, annotations)
+			} else {
+				assert.Equal(t, test.expectedAnnotations, annotations)
+			}
+		})
+	}
+}
+
+func TestPodVolumeBackupActionFileSystemVolumes(t *testing.T) {
+	tests := []struct {
+		name             string
+		snapshotVolumes  *bool
+		claimAnnotation  string
+		expectedBackedUp []string
+	}{
+		{
+			name:             "volumes of NFS and EFS claims are backed up",
+			expectedBackedUp: []string{"data", "cache"},
+		},
+		{
+			name:            "volumes aren't backed up when the backup doesn't snapshot volumes",
+			snapshotVolumes: boolPtr(false),
+		},
+		{
+			name:             "claim annotation opts out",
+			claimAnnotation:  "false",
+			expectedBackedUp: []string{"cache"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			pvcClient := &fakePVCGetter{claims: map[string]*kubev1.PersistentVolumeClaim{
+				"ns-1/nfs":     claimBoundTo("nfs", "pv-nfs"),
+				"ns-1/efs":     claimBoundTo("efs", "pv-efs"),
+				"ns-1/ebs":     claimBoundTo("ebs", "pv-ebs"),
+				"ns-1/unbound": claimBoundTo("unbound", ""),
+			}}
+			if test.claimAnnotation != "" {
+				pvcClient.claims["ns-1/nfs"].Annotations = map[string]string{v1.SnapshotVolumeAnnotation: test.claimAnnotation}
+			}
+
+			pvClient := &FakeDynamicClient{}
+			for name, spec := range map[string]map[string]interface{}{
+				"pv-nfs": {"nfs": map[string]interface{}{"server": "nfs.example.com", "path": "/exports/data"}},
+				"pv-efs": {"csi": map[string]interface{}{"driver": "efs.csi.aws.com", "volumeHandle": "fs-1"}},
+				"pv-ebs": {"awsElasticBlockStore": map[string]interface{}{"volumeID": "vol-1"}},
+			} {
+				pvClient.On("Get", name, metav1.GetOptions{}).Return(&unstructured.Unstructured{
+					Object: map[string]interface{}{"metadata": map[string]interface{}{"name": name}, "spec": spec},
+				}, nil)
+			}
+
+			backupper := &fakeResticBackupper{
+				snapshots: map[string]string{"data": "snapshot-1", "cache": "snapshot-2"},
+			}
+			action, err := NewPodVolumeBackupAction(backupper, pvcClient, pvClient)
+			require.NoError(t, err)
+
+			pod := make(map[string]interface{})
+			require.NoError(t, json.Unmarshal([]byte(`{"apiVersion": "v1", "kind": "Pod", "metadata": {"namespace": "ns-1", "name": "pod-1"}, "spec": {"volumes": [{"name": "data", "persistentVolumeClaim": {"claimName": "nfs"}}, {"name": "cache", "persistentVolumeClaim": {"claimName": "efs"}}, {"name": "logs", "persistentVolumeClaim": {"claimName": "ebs"}}, {"name": "tmp", "persistentVolumeClaim": {"claimName": "unbound"}}]}, "status": {"phase": "Running"}}`), &pod))
+
+			backup := &v1.Backup{
+				Spec:   v1.BackupSpec{SnapshotVolumes: test.snapshotVolumes},
+				Status: v1.BackupStatus{Progress: &v1.BackupProgress{}},
+			}
+			require.NoError(t, action.Execute(pod, backup))
+			assert.Equal(t, test.expectedBackedUp, backupper.backedUp)
+		})
+	}
+}
+
+func claimBoundTo(name, volumeName string) *kubev1.PersistentVolumeClaim {
+	return &kubev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns-1", Name: name},
+		Spec:       kubev1.PersistentVolumeClaimSpec{VolumeName: volumeName},
+	}
+}
+
+func TestPodVolumeBackupActionBacksUpSharedClaimOnce(t *testing.T) {
+	pvcClient := &fakePVCGetter{claims: map[string]*kubev1.PersistentVolumeClaim{
+		"ns-1/nfs": claimBoundTo("nfs", "pv-nfs"),
+	}}
+
+	pvClient := &FakeDynamicClient{}
+	pvClient.On("Get", "pv-nfs", metav1.GetOptions{}).Return(&unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"metadata": map[string]interface{}{"name": "pv-nfs"},
+			"spec":     map[string]interface{}{"nfs": map[string]interface{}{"server": "nfs.example.com", "path": "/exports/data"}},
+		},
+	}, nil)
+
+	backupper := &fakeResticBackupper{snapshots: map[string]string{"data": "snapshot-1", "shared": "snapshot-2"}}
+	action, err := NewPodVolumeBackupAction(backupper, pvcClient, pvClient)
+	require.NoError(t, err)
+
+	execute := func(backup *v1.Backup, podName, volumeName string) {
+		pod := make(map[string]interface{})
+		require.NoError(t, json.Unmarshal([]byte(`{"apiVersion": "v1", "kind": "Pod", "metadata": {"namespace": "ns-1", "name": "`+podName+`"}, "spec": {"volumes": [{"name": "`+volumeName+`", "persistentVolumeClaim": {"claimName": "nfs"}}]}, "status": {"phase": "Running"}}`), &pod))
+		require.NoError(t, action.Execute(pod, backup))
+	}
+
+	backup := &v1.Backup{ObjectMeta: metav1.ObjectMeta{Namespace: "heptio-ark", Name: "backup-1"}}
+	execute(backup, "pod-1", "data")
+	execute(backup, "pod-2", "shared")
+	assert.Equal(t, []string{"data"}, backupper.backedUp)
+
+	// another backup backs the claim up again
+	execute(&v1.Backup{ObjectMeta: metav1.ObjectMeta{Namespace: "heptio-ark", Name: "backup-2"}}, "pod-2", "shared")
+	assert.Equal(t, []string{"data", "shared"}, backupper.backedUp)
+
+	// and so does the same backup once it's finished and taken again
+	action.(*podVolumeBackupAction).BackupFinished(backup)
+	execute(backup, "pod-1", "data")
+	assert.Equal(t, []string{"data", "shared", "data"}, backupper.backedUp)
+}
+
+func TestPodVolumeBackupActionRetriesSharedClaimAfterFailure(t *testing.T) {
+	pvcClient := &fakePVCGetter{claims: map[string]*kubev1.PersistentVolumeClaim{
+		"ns-1/nfs": claimBoundTo("nfs", "pv-nfs"),
+	}}
+
+	pvClient := &FakeDynamicClient{}
+	pvClient.On("Get", "pv-nfs", metav1.GetOptions{}).Return(&unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"metadata": map[string]interface{}{"name": "pv-nfs"},
+			"spec":     map[string]interface{}{"nfs": map[string]interface{}{"server": "nfs.example.com", "path": "/exports/data"}},
+		},
+	}, nil)
+
+	// pod-1's volume fails to back up, since it has no snapshot
+	backupper := &fakeResticBackupper{snapshots: map[string]string{"shared": "snapshot-2", "other": "snapshot-3"}}
+	action, err := NewPodVolumeBackupAction(backupper, pvcClient, pvClient)
+	require.NoError(t, err)
+
+	backup := &v1.Backup{ObjectMeta: metav1.ObjectMeta{Namespace: "heptio-ark", Name: "backup-1"}}
+	execute := func(podName, volumeName string) (map[string]interface{}, error) {
+		pod := make(map[string]interface{})
+		require.NoError(t, json.Unmarshal([]byte(`{"apiVersion": "v1", "kind": "Pod", "metadata": {"namespace": "ns-1", "name": "`+podName+`"}, "spec": {"volumes": [{"name": "`+volumeName+`", "persistentVolumeClaim": {"claimName": "nfs"}}]}, "status": {"phase": "Running"}}`), &pod))
+		return pod, action.Execute(pod, backup)
+	}
+
+	_, err = execute("pod-1", "data")
+	assert.Error(t, err)
+
+	// the claim is backed up through the next pod that mounts it
+	pod, err := execute("pod-2", "shared")
+	require.NoError(t, err)
+	assert.Equal(t, "snapshot-2", restic.GetSnapshots(&unstructured.Unstructured{Object: pod})["shared"])
+
+	// and only then is it skipped
+	_, err = execute("pod-3", "other")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"data", "shared"}, backupper.backedUp)
+	assert.Equal(t, []v1.PodVolumeSnapshotInfo{{RepoNamespace: "ns-1", Snapshot: "snapshot-2"}}, backup.Status.PodVolumeSnapshots)
+}
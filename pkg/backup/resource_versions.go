@@ -0,0 +1,132 @@
+/*
+Copyright 2017 Heptio Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package backup
+
+import (
+	"archive/tar"
+	"encoding/json"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	api "github.com/heptio/ark/pkg/apis/ark/v1"
+)
+
+// resourceVersions maps each backed-up resource, e.g. deployments.apps, to the group versions the
+// cluster serves it at, in the order a restore should prefer them.
+type resourceVersions map[string][]string
+
+// getResourceVersions returns the group versions the cluster serves each of the given resources
+// at: the versions of the resource's own group, preferred version first, followed by those of any
+// other groups that serve a resource with the same name and kind, in discovery order. Resources
+// that discovery doesn't know are left out.
+func getResourceVersions(resources []string, discovered []*metav1.APIResourceList, mapper meta.RESTMapper) resourceVersions {
+	versions := make(resourceVersions, len(resources))
+
+	for _, resource := range resources {
+		gr := schema.ParseGroupResource(resource)
+
+		var (
+			kind   string
+			groups []schema.GroupVersion
+		)
+		for _, list := range discovered {
+			gv, err := schema.ParseGroupVersion(list.GroupVersion)
+			if err != nil || gv.Group != gr.Group {
+				continue
+			}
+			for _, apiResource := range list.APIResources {
+				if apiResource.Name == gr.Resource {
+					kind = apiResource.Kind
+					groups = append(groups, gv)
+				}
+			}
+		}
+		if kind == "" {
+			continue
+		}
+
+		for _, list := range discovered {
+			gv, err := schema.ParseGroupVersion(list.GroupVersion)
+			if err != nil || gv.Group == gr.Group {
+				continue
+			}
+			for _, apiResource := range list.APIResources {
+				if apiResource.Name == gr.Resource && apiResource.Kind == kind {
+					groups = append(groups, gv)
+				}
+			}
+		}
+
+		for _, preferred := range groups {
+			versions[resource] = appendGroupVersions(versions[resource], mapper, preferred, kind)
+		}
+	}
+
+	return versions
+}
+
+// appendGroupVersions appends preferred, followed by the other versions the mapper knows of
+// preferred's group serving kind at, to versions, skipping any that are already in it.
+func appendGroupVersions(versions []string, mapper meta.RESTMapper, preferred schema.GroupVersion, kind string) []string {
+	candidates := []string{preferred.String()}
+	if mappings, err := mapper.RESTMappings(schema.GroupKind{Group: preferred.Group, Kind: kind}); err == nil {
+		for _, mapping := range mappings {
+			candidates = append(candidates, mapping.GroupVersionKind.GroupVersion().String())
+		}
+	}
+
+	for _, candidate := range candidates {
+		found := false
+		for _, version := range versions {
+			if version == candidate {
+				found = true
+				break
+			}
+		}
+		if !found {
+			versions = append(versions, candidate)
+		}
+	}
+
+	return versions
+}
+
+// writeResourceVersions writes versions to the backup tarball.
+func writeResourceVersions(w tarWriter, versions resourceVersions) error {
+	data, err := json.Marshal(versions)
+	if err != nil {
+		return err
+	}
+
+	hdr := &tar.Header{
+		Name:     api.ResourceVersionsFile,
+		Size:     int64(len(data)),
+		Typeflag: tar.TypeReg,
+		Mode:     0755,
+		ModTime:  time.Now(),
+	}
+
+	if err := w.WriteHeader(hdr); err != nil {
+		return err
+	}
+
+	_, err = w.Write(data)
+	return err
+}
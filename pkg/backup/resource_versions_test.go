@@ -0,0 +1,66 @@
+/*
+Copyright 2017 Heptio Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package backup
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	. "github.com/heptio/ark/pkg/util/test"
+)
+
+func TestGetResourceVersions(t *testing.T) {
+	deployments := func(kind string) metav1.APIResource {
+		return metav1.APIResource{Name: "deployments", Kind: kind, Namespaced: true}
+	}
+
+	discovered := []*metav1.APIResourceList{
+		{
+			GroupVersion: "v1",
+			APIResources: []metav1.APIResource{{Name: "configmaps", Kind: "ConfigMap", Namespaced: true}},
+		},
+		{
+			GroupVersion: "apps/v1",
+			APIResources: []metav1.APIResource{deployments("Deployment")},
+		},
+		{
+			GroupVersion: "extensions/v1beta1",
+			APIResources: []metav1.APIResource{deployments("Deployment")},
+		},
+		{
+			GroupVersion: "other/v1",
+			APIResources: []metav1.APIResource{deployments("OtherDeployment")},
+		},
+	}
+
+	mapper := &FakeMapper{
+		KindVersions: map[schema.GroupKind][]string{
+			{Group: "apps", Kind: "Deployment"}: {"v1", "v1beta2", "v1beta1"},
+		},
+	}
+
+	versions := getResourceVersions([]string{"configmaps", "deployments.extensions", "unknown.example.com"}, discovered, mapper)
+
+	assert.Equal(t, resourceVersions{
+		"configmaps":             {"v1"},
+		"deployments.extensions": {"extensions/v1beta1", "apps/v1", "apps/v1beta2", "apps/v1beta1"},
+	}, versions)
+}
@@ -0,0 +1,149 @@
+/*
+Copyright 2017 Heptio Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package backup
+
+import (
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/labels"
+
+	"github.com/heptio/ark/pkg/util/collections"
+)
+
+// ItemTransformer modifies an item before it's written to a backup, e.g. to redact sensitive
+// data or remove generated fields.
+type ItemTransformer interface {
+	// Transform modifies item in place. If an error is returned, the item isn't backed up.
+	Transform(item map[string]interface{}) error
+}
+
+// ItemTransform applies an ItemTransformer to the items of some resources.
+type ItemTransform struct {
+	// Resources are the resources, in <RESOURCE>.<GROUP> format, whose items are transformed.
+	// "*" matches all resources.
+	Resources []string
+	// Selector restricts the transform to items whose labels match it. If it's nil, all items
+	// of the resources are transformed.
+	Selector labels.Selector
+	// Transformer is applied to each matching item.
+	Transformer ItemTransformer
+}
+
+// resolvedItemTransform is an ItemTransform whose resources have been resolved to
+// fully-qualified group-resource names.
+type resolvedItemTransform struct {
+	resources   *collections.IncludesExcludes
+	selector    labels.Selector
+	transformer ItemTransformer
+}
+
+// resolveItemTransforms resolves the resources of each of transforms using mapper.
+func resolveItemTransforms(mapper meta.RESTMapper, transforms []ItemTransform) ([]resolvedItemTransform, error) {
+	var ret []resolvedItemTransform
+
+	for _, transform := range transforms {
+		resources := collections.NewIncludesExcludes()
+		for _, resource := range transform.Resources {
+			if resource == "*" {
+				resources.Includes("*")
+				continue
+			}
+
+			gr, err := resolveGroupResource(mapper, resource)
+			if err != nil {
+				return nil, fmt.Errorf("error resolving resource %q of item transform: %v", resource, err)
+			}
+			resources.Includes(gr.String())
+		}
+
+		selector := transform.Selector
+		if selector == nil {
+			selector = labels.Everything()
+		}
+
+		ret = append(ret, resolvedItemTransform{
+			resources:   resources,
+			selector:    selector,
+			transformer: transform.Transformer,
+		})
+	}
+
+	return ret, nil
+}
+
+// transformItem applies each of transforms that matches the item, in order.
+func transformItem(transforms []resolvedItemTransform, item map[string]interface{}, groupResource string) error {
+	itemLabels := getItemLabels(item)
+
+	for _, transform := range transforms {
+		if !transform.resources.ShouldInclude(groupResource) || !transform.selector.Matches(itemLabels) {
+			continue
+		}
+
+		if err := transform.transformer.Transform(item); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// removeFieldsTransformer is an ItemTransformer that removes fields from items.
+type removeFieldsTransformer struct {
+	fields [][]string
+}
+
+var _ ItemTransformer = &removeFieldsTransformer{}
+
+// NewRemoveFieldsTransformer creates an ItemTransformer that removes each of fields from items.
+// Fields are dot-separated paths, and a path through a list applies to each of the list's
+// elements, e.g. "webhooks.clientConfig.caBundle". Fields that don't exist are ignored.
+func NewRemoveFieldsTransformer(fields []string) ItemTransformer {
+	t := &removeFieldsTransformer{}
+	for _, field := range fields {
+		t.fields = append(t.fields, strings.Split(field, "."))
+	}
+	return t
+}
+
+func (t *removeFieldsTransformer) Transform(item map[string]interface{}) error {
+	for _, path := range t.fields {
+		removeField(item, path)
+	}
+	return nil
+}
+
+// removeField removes the field at path from obj, descending into each element of any lists
+// along the way.
+func removeField(obj interface{}, path []string) {
+	switch obj := obj.(type) {
+	case map[string]interface{}:
+		if len(path) == 1 {
+			delete(obj, path[0])
+			return
+		}
+		if child, found := obj[path[0]]; found {
+			removeField(child, path[1:])
+		}
+	case []interface{}:
+		for _, element := range obj {
+			removeField(element, path)
+		}
+	}
+}
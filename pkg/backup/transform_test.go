@@ -0,0 +1,179 @@
+/*
+Copyright 2017 Heptio Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package backup
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	. "github.com/heptio/ark/pkg/util/test"
+)
+
+func TestRemoveFieldsTransformer(t *testing.T) {
+	tests := []struct {
+		name     string
+		fields   []string
+		item     string
+		expected string
+	}{
+		{
+			name:     "top-level field",
+			fields:   []string{"data"},
+			item:     `{"metadata": {"name": "foo"}, "data": {"password": "secret"}}`,
+			expected: `{"metadata": {"name": "foo"}}`,
+		},
+		{
+			name:     "nested field",
+			fields:   []string{"metadata.annotations.secret"},
+			item:     `{"metadata": {"name": "foo", "annotations": {"secret": "a", "other": "b"}}}`,
+			expected: `{"metadata": {"name": "foo", "annotations": {"other": "b"}}}`,
+		},
+		{
+			name:     "field in each list element",
+			fields:   []string{"webhooks.clientConfig.caBundle"},
+			item:     `{"webhooks": [{"name": "a", "clientConfig": {"caBundle": "x"}}, {"name": "b", "clientConfig": {"caBundle": "y", "url": "z"}}]}`,
+			expected: `{"webhooks": [{"name": "a", "clientConfig": {}}, {"name": "b", "clientConfig": {"url": "z"}}]}`,
+		},
+		{
+			name:     "missing fields are ignored",
+			fields:   []string{"data", "spec.foo.bar", "metadata.name.foo"},
+			item:     `{"metadata": {"name": "foo"}}`,
+			expected: `{"metadata": {"name": "foo"}}`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			item := make(map[string]interface{})
+			require.NoError(t, json.Unmarshal([]byte(test.item), &item))
+			expected := make(map[string]interface{})
+			require.NoError(t, json.Unmarshal([]byte(test.expected), &expected))
+
+			require.NoError(t, NewRemoveFieldsTransformer(test.fields).Transform(item))
+			assert.Equal(t, expected, item)
+		})
+	}
+}
+
+type fakeItemTransformer struct {
+	name string
+	err  error
+}
+
+func (t *fakeItemTransformer) Transform(item map[string]interface{}) error {
+	if t.err != nil {
+		return t.err
+	}
+	applied, _ := item["applied"].([]string)
+	item["applied"] = append(applied, t.name)
+	return nil
+}
+
+func TestTransformItem(t *testing.T) {
+	mapper := &FakeMapper{
+		Resources: map[schema.GroupVersionResource]schema.GroupVersionResource{
+			schema.GroupVersionResource{Resource: "secrets"}: schema.GroupVersionResource{Group: "", Version: "v1", Resource: "secrets"},
+			schema.GroupVersionResource{Resource: "mutatingwebhookconfigurations"}: schema.GroupVersionResource{
+				Group: "admissionregistration.k8s.io", Version: "v1beta1", Resource: "mutatingwebhookconfigurations",
+			},
+		},
+	}
+
+	transforms, err := resolveItemTransforms(mapper, []ItemTransform{
+		{
+			Resources:   []string{"*"},
+			Transformer: &fakeItemTransformer{name: "all"},
+		},
+		{
+			Resources:   []string{"secrets"},
+			Selector:    labels.SelectorFromSet(labels.Set{"redact": "true"}),
+			Transformer: &fakeItemTransformer{name: "redact-secrets"},
+		},
+		{
+			Resources:   []string{"mutatingwebhookconfigurations"},
+			Transformer: &fakeItemTransformer{name: "webhooks"},
+		},
+	})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name          string
+		groupResource string
+		labels        map[string]interface{}
+		expected      []string
+	}{
+		{
+			name:          "matches wildcard only",
+			groupResource: "configmaps",
+			expected:      []string{"all"},
+		},
+		{
+			name:          "label selector doesn't match",
+			groupResource: "secrets",
+			labels:        map[string]interface{}{"redact": "false"},
+			expected:      []string{"all"},
+		},
+		{
+			name:          "label selector matches",
+			groupResource: "secrets",
+			labels:        map[string]interface{}{"redact": "true"},
+			expected:      []string{"all", "redact-secrets"},
+		},
+		{
+			name:          "resource with group",
+			groupResource: "mutatingwebhookconfigurations.admissionregistration.k8s.io",
+			expected:      []string{"all", "webhooks"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			item := map[string]interface{}{
+				"metadata": map[string]interface{}{"name": "foo"},
+			}
+			if test.labels != nil {
+				item["metadata"].(map[string]interface{})["labels"] = test.labels
+			}
+
+			require.NoError(t, transformItem(transforms, item, test.groupResource))
+			assert.Equal(t, test.expected, item["applied"])
+		})
+	}
+}
+
+func TestTransformItemError(t *testing.T) {
+	transforms, err := resolveItemTransforms(&FakeMapper{}, []ItemTransform{
+		{Resources: []string{"*"}, Transformer: &fakeItemTransformer{err: errors.New("bad item")}},
+	})
+	require.NoError(t, err)
+
+	assert.Error(t, transformItem(transforms, map[string]interface{}{}, "configmaps"))
+}
+
+func TestResolveItemTransformsInvalidResource(t *testing.T) {
+	_, err := resolveItemTransforms(&FakeMapper{}, []ItemTransform{
+		{Resources: []string{"foo"}, Transformer: &fakeItemTransformer{}},
+	})
+	assert.Error(t, err)
+}
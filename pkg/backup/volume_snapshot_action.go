@@ -19,85 +19,360 @@ package backup
 import (
 	"errors"
 	"fmt"
+	"strconv"
 
 	"github.com/golang/glog"
 
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/clock"
+	corev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/pkg/api/v1"
 
 	api "github.com/heptio/ark/pkg/apis/ark/v1"
 	"github.com/heptio/ark/pkg/cloudprovider"
+	"github.com/heptio/ark/pkg/csi"
+	"github.com/heptio/ark/pkg/logging"
+	"github.com/heptio/ark/pkg/quiesce"
+	"github.com/heptio/ark/pkg/util/collections"
 	kubeutil "github.com/heptio/ark/pkg/util/kube"
 )
 
 // volumeSnapshotAction is a struct that knows how to take snapshots of PersistentVolumes
-// that are backed by compatible cloud volumes.
+// that are backed by compatible cloud volumes or CSI drivers.
 type volumeSnapshotAction struct {
 	snapshotService cloudprovider.SnapshotService
+	csiSnapshotter  csi.Snapshotter
+	pvcClient       corev1.PersistentVolumeClaimsGetter
+	podClient       corev1.PodsGetter
+	freezer         quiesce.Freezer
 	clock           clock.Clock
+	// podVolumeBackups is whether the data of NFS and EFS volumes, which can't be snapshotted,
+	// is backed up at the file level through the pods that mount them.
+	podVolumeBackups bool
 }
 
 var _ Action = &volumeSnapshotAction{}
 
-func NewVolumeSnapshotAction(snapshotService cloudprovider.SnapshotService) (Action, error) {
-	if snapshotService == nil {
-		return nil, errors.New("snapshotService cannot be nil")
+// NewVolumeSnapshotAction creates an Action that snapshots PersistentVolumes. If csiSnapshotter
+// isn't nil, it's used to snapshot PVs backed by CSI drivers; all other PVs are snapshotted using
+// snapshotService. At least one of them must be non-nil. pvcClient is used to look up the
+// snapshot annotation on each PV's claim; if it's nil, only annotations on the PVs themselves are
+// honored. If freezer isn't nil, it's used to freeze the filesystem of each PV while it's
+// snapshotted. NFS and EFS volumes aren't snapshotted; podVolumeBackups says whether their data is
+// backed up through the pods that mount them instead, and if it isn't, or if podClient is given
+// and no running pod mounts the volume, a warning is recorded.
+func NewVolumeSnapshotAction(snapshotService cloudprovider.SnapshotService, csiSnapshotter csi.Snapshotter, pvcClient corev1.PersistentVolumeClaimsGetter, podClient corev1.PodsGetter, freezer quiesce.Freezer, podVolumeBackups bool) (Action, error) {
+	if snapshotService == nil && csiSnapshotter == nil {
+		return nil, errors.New("snapshotService and csiSnapshotter cannot both be nil")
 	}
 
 	return &volumeSnapshotAction{
-		snapshotService: snapshotService,
-		clock:           clock.RealClock{},
+		snapshotService:  snapshotService,
+		csiSnapshotter:   csiSnapshotter,
+		pvcClient:        pvcClient,
+		podClient:        podClient,
+		freezer:          freezer,
+		clock:            clock.RealClock{},
+		podVolumeBackups: podVolumeBackups,
 	}, nil
 }
 
-// Execute triggers a snapshot for the volume/disk underlying a PersistentVolume if the provided
-// backup has volume snapshots enabled and the PV is of a compatible type. Also records cloud
-// disk type and IOPS (if applicable) to be able to restore to current state later.
+// Execute triggers a snapshot for the volume/disk underlying a PersistentVolume if snapshots are
+// enabled for it and the PV is of a compatible type. Also records cloud disk type and IOPS (if
+// applicable), or the CSI snapshot handle, to be able to restore to current state later.
 func (a *volumeSnapshotAction) Execute(volume map[string]interface{}, backup *api.Backup) error {
 	backupName := fmt.Sprintf("%s/%s", backup.Namespace, backup.Name)
-	if backup.Spec.SnapshotVolumes != nil && !*backup.Spec.SnapshotVolumes {
-		glog.V(2).Infof("Backup %q has volume snapshots disabled; skipping volume snapshot action.", backupName)
-		return nil
-	}
 
 	metadata := volume["metadata"].(map[string]interface{})
 	name := metadata["name"].(string)
+	log := logging.WithFields(logging.Fields{"backup": backup.Name, "namespace": backup.Namespace, "resource": "persistentvolumes", "itemName": name})
 
-	volumeID, err := kubeutil.GetVolumeID(volume)
-	// non-nil error means it's a supported PV source but volume ID can't be found
-	if err != nil {
-		return fmt.Errorf("error getting volume ID for backup %q, PersistentVolume %q: %v", backupName, name, err)
+	if !a.shouldSnapshot(volume, backup) {
+		log.V(2).Infof("Backup %q: volume snapshots are disabled for PersistentVolume %q; skipping volume snapshot action.", backupName, name)
+		return nil
 	}
-	// no volumeID / nil error means unsupported PV source
-	if volumeID == "" {
-		glog.V(2).Infof("Backup %q: PersistentVolume %q is not a supported volume type for snapshots, skipping.", backupName, name)
+
+	if kubeutil.IsFileSystemVolume(volume) {
+		if !a.podVolumeBackups {
+			log.Warningf("Backup %q: PersistentVolume %q is an NFS or EFS volume, which can't be snapshotted, and the server isn't configured for restic; its data isn't backed up.", backupName, name)
+			backup.Status.Warnings++
+			return nil
+		}
+
+		// moveVolumeDataAction reports the unmounted claims of backups that
+		// move volume data
+		mounted := true
+		var err error
+		if !backup.Spec.MoveVolumeData {
+			mounted, err = a.mountedByRunningPod(volume)
+		}
+		switch {
+		case err != nil:
+			log.Warningf("Backup %q: PersistentVolume %q is an NFS or EFS volume, whose data is backed up at the file level through the pods that mount it, but the pods that mount it couldn't be listed: %v", backupName, name, err)
+			backup.Status.Warnings++
+		case !mounted:
+			log.Warningf("Backup %q: PersistentVolume %q is an NFS or EFS volume, whose data is backed up at the file level through the pods that mount it, but no running pod mounts it; its data isn't backed up.", backupName, name)
+			backup.Status.Warnings++
+		default:
+			log.Infof("Backup %q: PersistentVolume %q is an NFS or EFS volume, which can't be snapshotted; its data is backed up at the file level through the pods that mount it.", backupName, name)
+		}
 		return nil
 	}
 
-	expiration := a.clock.Now().Add(backup.Spec.TTL.Duration)
+	useCSI := a.csiSnapshotter != nil && isCSIVolume(volume)
 
-	glog.Infof("Backup %q: snapshotting PersistentVolume %q, volume-id %q, expiration %v", backupName, name, volumeID, expiration)
+	var (
+		volumeID        string
+		snapshotService cloudprovider.SnapshotService
+		location        string
+	)
+	if !useCSI {
+		if a.snapshotService == nil {
+			log.V(2).Infof("Backup %q: PersistentVolume %q is not backed by a CSI driver and no volume snapshot locations exist, skipping.", backupName, name)
+			return nil
+		}
 
-	snapshotID, err := a.snapshotService.CreateSnapshot(volumeID)
-	if err != nil {
-		glog.V(4).Infof("error creating snapshot for backup %q, volume %q, volume-id %q: %v", backupName, name, volumeID, err)
-		return err
+		var err error
+		volumeID, err = kubeutil.GetVolumeID(volume)
+		// non-nil error means it's a supported PV source but volume ID can't be found
+		if err != nil {
+			return fmt.Errorf("error getting volume ID for backup %q, PersistentVolume %q: %v", backupName, name, err)
+		}
+		// no volumeID / nil error means unsupported PV source
+		if volumeID == "" {
+			log.V(2).Infof("Backup %q: PersistentVolume %q is not a supported volume type for snapshots, skipping.", backupName, name)
+			return nil
+		}
+
+		spec, _ := collections.GetMap(volume, "spec")
+		volumeSource, _ := kubeutil.GetPVSource(spec)
+		if snapshotService, location, err = cloudprovider.SnapshotServiceForVolume(a.snapshotService, backup, volumeSource); err != nil {
+			return fmt.Errorf("error snapshotting PersistentVolume %q for backup %q: %v", name, backupName, err)
+		}
+
+		expiration := a.clock.Now().Add(backup.Spec.TTL.Duration)
+
+		log.Infof("Backup %q: snapshotting PersistentVolume %q, volume-id %q, expiration %v", backupName, name, volumeID, expiration)
+	} else {
+		log.Infof("Backup %q: snapshotting PersistentVolume %q using a CSI VolumeSnapshot", backupName, name)
+	}
+
+	if backup.Status.Progress != nil {
+		backup.Status.Progress.VolumeSnapshotsAttempted++
+	}
+
+	thaw, freezeErr := a.freeze(volume, name)
+	if freezeErr != nil {
+		log.Warningf("Backup %q: %v; snapshotting PersistentVolume %q without freezing it", backupName, freezeErr, name)
+	}
+
+	var (
+		snapshotID  string
+		csiSnapshot *api.CSISnapshotInfo
+		err         error
+	)
+	if useCSI {
+		csiSnapshot, err = a.createCSISnapshot(volume, name, backup)
+	} else {
+		snapshotID, err = snapshotService.CreateSnapshot(volumeID)
+	}
+
+	if thaw != nil {
+		if thawErr := thaw(); thawErr != nil {
+			log.Warningf("Backup %q: error thawing PersistentVolume %q: %v", backupName, name, thawErr)
+			freezeErr = thawErr
+		}
 	}
 
-	volumeType, iops, err := a.snapshotService.GetVolumeInfo(volumeID)
 	if err != nil {
-		glog.V(4).Infof("error getting volume info for backup %q, volume %q, volume-id %q: %v", backupName, name, volumeID, err)
+		log.V(4).Infof("error creating snapshot for backup %q, volume %q, volume-id %q: %v", backupName, name, volumeID, err)
 		return err
 	}
 
+	info := &api.VolumeBackupInfo{
+		SnapshotID: snapshotID,
+	}
+
+	if useCSI {
+		info.SnapshotID = csiSnapshot.SnapshotHandle
+		info.CSISnapshot = csiSnapshot
+	} else {
+		volumeType, iops, err := snapshotService.GetVolumeInfo(volumeID)
+		if err != nil {
+			log.V(4).Infof("error getting volume info for backup %q, volume %q, volume-id %q: %v", backupName, name, volumeID, err)
+			return err
+		}
+
+		info.Type = volumeType
+		info.Iops = iops
+		info.Location = location
+	}
+
 	if backup.Status.VolumeBackups == nil {
 		backup.Status.VolumeBackups = make(map[string]*api.VolumeBackupInfo)
 	}
 
-	backup.Status.VolumeBackups[name] = &api.VolumeBackupInfo{
-		SnapshotID: snapshotID,
-		Type:       volumeType,
-		Iops:       iops,
+	backup.Status.VolumeBackups[name] = info
+
+	if freezeErr != nil {
+		backup.Status.VolumeBackups[name].FreezeError = freezeErr.Error()
+		backup.Status.Warnings++
+	}
+
+	if backup.Status.Progress != nil {
+		backup.Status.Progress.VolumeSnapshotsCompleted++
 	}
 
 	return nil
 }
+
+// createCSISnapshot snapshots the PersistentVolume, which is backed by a CSI driver, by creating a
+// VolumeSnapshot of its claim.
+func (a *volumeSnapshotAction) createCSISnapshot(volume map[string]interface{}, name string, backup *api.Backup) (*api.CSISnapshotInfo, error) {
+	claimNamespace, err := collections.GetString(volume, "spec.claimRef.namespace")
+	if err != nil {
+		return nil, fmt.Errorf("PersistentVolume %q can't be snapshotted using the CSI VolumeSnapshot API because it isn't bound to a claim", name)
+	}
+	claimName, err := collections.GetString(volume, "spec.claimRef.name")
+	if err != nil {
+		return nil, fmt.Errorf("PersistentVolume %q can't be snapshotted using the CSI VolumeSnapshot API because it isn't bound to a claim", name)
+	}
+
+	return a.csiSnapshotter.CreateSnapshot(claimNamespace, claimName, fmt.Sprintf("%s-%s", backup.Name, name))
+}
+
+// mountedByRunningPod returns whether a running pod mounts the claim that the PersistentVolume is
+// bound to. It's assumed to be mounted if the action has no pod client.
+func (a *volumeSnapshotAction) mountedByRunningPod(volume map[string]interface{}) (bool, error) {
+	if a.podClient == nil {
+		return true, nil
+	}
+
+	claimNamespace, err := collections.GetString(volume, "spec.claimRef.namespace")
+	if err != nil {
+		return false, nil
+	}
+	claimName, err := collections.GetString(volume, "spec.claimRef.name")
+	if err != nil {
+		return false, nil
+	}
+
+	return mountedByRunningPod(a.podClient, claimNamespace, claimName)
+}
+
+// mountedByRunningPod returns whether a running pod mounts the named claim.
+func mountedByRunningPod(podClient corev1.PodsGetter, claimNamespace, claimName string) (bool, error) {
+	pods, err := podClient.Pods(claimNamespace).List(metav1.ListOptions{})
+	if err != nil {
+		return false, err
+	}
+
+	for _, pod := range pods.Items {
+		if pod.Status.Phase != v1.PodRunning {
+			continue
+		}
+		for _, podVolume := range pod.Spec.Volumes {
+			if podVolume.PersistentVolumeClaim != nil && podVolume.PersistentVolumeClaim.ClaimName == claimName {
+				return true, nil
+			}
+		}
+	}
+
+	return false, nil
+}
+
+// isCSIVolume returns whether the PersistentVolume is backed by a CSI driver.
+func isCSIVolume(volume map[string]interface{}) bool {
+	_, err := collections.GetMap(volume, "spec.csi")
+	return err == nil
+}
+
+// freeze freezes the filesystem of the PersistentVolume, if a freezer is configured and the volume
+// is bound to a claim, returning a function that thaws it. The returned function is nil if
+// nothing was frozen.
+func (a *volumeSnapshotAction) freeze(volume map[string]interface{}, name string) (func() error, error) {
+	if a.freezer == nil {
+		return nil, nil
+	}
+
+	claimNamespace, err := collections.GetString(volume, "spec.claimRef.namespace")
+	if err != nil {
+		return nil, nil
+	}
+	claimName, err := collections.GetString(volume, "spec.claimRef.name")
+	if err != nil {
+		return nil, nil
+	}
+
+	return a.freezer.Freeze(claimNamespace, claimName, name)
+}
+
+// shouldSnapshot returns whether the PersistentVolume should be snapshotted. The snapshot
+// annotation on the PV takes precedence over the annotation on its claim, which takes precedence
+// over the backup's SnapshotVolumes setting.
+func (a *volumeSnapshotAction) shouldSnapshot(volume map[string]interface{}, backup *api.Backup) bool {
+	if value, ok := parseSnapshotAnnotation(getSnapshotAnnotation(volume)); ok {
+		return value
+	}
+
+	if value, ok := parseSnapshotAnnotation(a.getClaimSnapshotAnnotation(volume)); ok {
+		return value
+	}
+
+	return backup.Spec.SnapshotVolumes == nil || *backup.Spec.SnapshotVolumes
+}
+
+// getSnapshotAnnotation returns the value of the snapshot annotation on volume, or "" if it
+// isn't set.
+func getSnapshotAnnotation(volume map[string]interface{}) string {
+	annotations, err := collections.GetMap(volume, "metadata.annotations")
+	if err != nil {
+		return ""
+	}
+
+	value, _ := annotations[api.SnapshotVolumeAnnotation].(string)
+	return value
+}
+
+// getClaimSnapshotAnnotation returns the value of the snapshot annotation on the
+// PersistentVolumeClaim bound to volume, or "" if it isn't set, the volume isn't bound, or
+// the claim can't be retrieved. It retrieves the claim from the API server, so it mustn't be
+// called while holding the backup's status lock (see backupContext.executeAction).
+func (a *volumeSnapshotAction) getClaimSnapshotAnnotation(volume map[string]interface{}) string {
+	if a.pvcClient == nil {
+		return ""
+	}
+
+	namespace, err := collections.GetString(volume, "spec.claimRef.namespace")
+	if err != nil {
+		return ""
+	}
+	name, err := collections.GetString(volume, "spec.claimRef.name")
+	if err != nil {
+		return ""
+	}
+
+	claim, err := a.pvcClient.PersistentVolumeClaims(namespace).Get(name, metav1.GetOptions{})
+	if err != nil {
+		glog.Warningf("error getting PersistentVolumeClaim %s/%s: %v", namespace, name, err)
+		return ""
+	}
+
+	return claim.Annotations[api.SnapshotVolumeAnnotation]
+}
+
+// parseSnapshotAnnotation parses the value of a snapshot annotation. ok is false if the
+// annotation isn't set or isn't a valid boolean.
+func parseSnapshotAnnotation(annotation string) (value bool, ok bool) {
+	if annotation == "" {
+		return false, false
+	}
+
+	value, err := strconv.ParseBool(annotation)
+	if err != nil {
+		glog.Warningf("ignoring invalid value %q for annotation %s", annotation, api.SnapshotVolumeAnnotation)
+		return false, false
+	}
+
+	return value, true
+}
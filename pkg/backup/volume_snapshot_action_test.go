@@ -17,14 +17,19 @@ limitations under the License.
 package backup
 
 import (
+	"errors"
+	"fmt"
 	"reflect"
 	"testing"
 	"time"
 
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/clock"
+	corev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	kubev1 "k8s.io/client-go/pkg/api/v1"
 
 	"github.com/heptio/ark/pkg/apis/ark/v1"
 	. "github.com/heptio/ark/pkg/util/test"
@@ -174,7 +179,7 @@ func TestVolumeSnapshotAction(t *testing.T) {
 
 			snapshotService := &FakeSnapshotService{SnapshottableVolumes: test.volumeInfo}
 
-			vsa, _ := NewVolumeSnapshotAction(snapshotService)
+			vsa, _ := NewVolumeSnapshotAction(snapshotService, nil, nil, nil, nil, false)
 			action := vsa.(*volumeSnapshotAction)
 
 			fakeClock := clock.NewFakeClock(time.Now())
@@ -226,3 +231,420 @@ func TestVolumeSnapshotAction(t *testing.T) {
 		})
 	}
 }
+
+func TestVolumeSnapshotActionShouldSnapshot(t *testing.T) {
+	tests := []struct {
+		name            string
+		snapshotVolumes *bool
+		pvAnnotation    string
+		claimAnnotation string
+		expected        bool
+	}{
+		{
+			name:     "no annotations, backup default",
+			expected: true,
+		},
+		{
+			name:            "no annotations, backup disabled",
+			snapshotVolumes: boolPtr(false),
+			expected:        false,
+		},
+		{
+			name:         "PV opts out",
+			pvAnnotation: "false",
+			expected:     false,
+		},
+		{
+			name:            "PV opts in when backup disabled",
+			snapshotVolumes: boolPtr(false),
+			pvAnnotation:    "true",
+			expected:        true,
+		},
+		{
+			name:            "claim opts out",
+			snapshotVolumes: boolPtr(true),
+			claimAnnotation: "false",
+			expected:        false,
+		},
+		{
+			name:            "claim opts in when backup disabled",
+			snapshotVolumes: boolPtr(false),
+			claimAnnotation: "true",
+			expected:        true,
+		},
+		{
+			name:            "PV annotation takes precedence over claim",
+			pvAnnotation:    "true",
+			claimAnnotation: "false",
+			expected:        true,
+		},
+		{
+			name:            "invalid annotation is ignored",
+			snapshotVolumes: boolPtr(false),
+			pvAnnotation:    "maybe",
+			expected:        false,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			pvcClient := &fakePVCGetter{claims: map[string]*kubev1.PersistentVolumeClaim{
+				"ns-1/claim-1": {
+					ObjectMeta: metav1.ObjectMeta{
+						Namespace:   "ns-1",
+						Name:        "claim-1",
+						Annotations: map[string]string{},
+					},
+				},
+			}}
+			if test.claimAnnotation != "" {
+				pvcClient.claims["ns-1/claim-1"].Annotations[v1.SnapshotVolumeAnnotation] = test.claimAnnotation
+			}
+
+			annotations := "{}"
+			if test.pvAnnotation != "" {
+				annotations = fmt.Sprintf(`{"%s": "%s"}`, v1.SnapshotVolumeAnnotation, test.pvAnnotation)
+			}
+			pv, err := getAsMap(fmt.Sprintf(`{
+				"apiVersion": "v1",
+				"kind": "PersistentVolume",
+				"metadata": {"name": "mypv", "annotations": %s},
+				"spec": {"claimRef": {"namespace": "ns-1", "name": "claim-1"}}
+			}`, annotations))
+			require.NoError(t, err)
+
+			action := &volumeSnapshotAction{pvcClient: pvcClient}
+			backup := &v1.Backup{Spec: v1.BackupSpec{SnapshotVolumes: test.snapshotVolumes}}
+
+			assert.Equal(t, test.expected, action.shouldSnapshot(pv, backup))
+		})
+	}
+}
+
+func boolPtr(b bool) *bool {
+	return &b
+}
+
+type fakePVCGetter struct {
+	claims map[string]*kubev1.PersistentVolumeClaim
+	// onGet, if set, is called whenever a claim is retrieved.
+	onGet func()
+}
+
+func (g *fakePVCGetter) PersistentVolumeClaims(namespace string) corev1.PersistentVolumeClaimInterface {
+	return &fakePVCClient{getter: g, namespace: namespace}
+}
+
+type fakePVCClient struct {
+	corev1.PersistentVolumeClaimInterface
+	getter    *fakePVCGetter
+	namespace string
+}
+
+func (c *fakePVCClient) Get(name string, options metav1.GetOptions) (*kubev1.PersistentVolumeClaim, error) {
+	if c.getter.onGet != nil {
+		c.getter.onGet()
+	}
+	if claim, found := c.getter.claims[c.namespace+"/"+name]; found {
+		return claim, nil
+	}
+	return nil, errors.New("not found")
+}
+
+func podMountingClaim(namespace, name, claimName string, phase kubev1.PodPhase) kubev1.Pod {
+	return kubev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name},
+		Spec: kubev1.PodSpec{
+			Volumes: []kubev1.Volume{
+				{
+					Name: "data",
+					VolumeSource: kubev1.VolumeSource{
+						PersistentVolumeClaim: &kubev1.PersistentVolumeClaimVolumeSource{ClaimName: claimName},
+					},
+				},
+			},
+		},
+		Status: kubev1.PodStatus{Phase: phase},
+	}
+}
+
+type fakePodGetter struct {
+	pods    []kubev1.Pod
+	listErr error
+}
+
+func (g *fakePodGetter) Pods(namespace string) corev1.PodInterface {
+	return &fakePodClient{getter: g, namespace: namespace}
+}
+
+type fakePodClient struct {
+	corev1.PodInterface
+	getter    *fakePodGetter
+	namespace string
+}
+
+func (c *fakePodClient) List(options metav1.ListOptions) (*kubev1.PodList, error) {
+	if c.getter.listErr != nil {
+		return nil, c.getter.listErr
+	}
+
+	list := &kubev1.PodList{}
+	for _, pod := range c.getter.pods {
+		if pod.Namespace == c.namespace {
+			list.Items = append(list.Items, pod)
+		}
+	}
+	return list, nil
+}
+
+type fakeFreezer struct {
+	freezeErr error
+	thawErr   error
+	frozen    []string
+	thawed    bool
+}
+
+func (f *fakeFreezer) Freeze(claimNamespace, claimName, volumeName string) (func() error, error) {
+	if f.freezeErr != nil {
+		return nil, f.freezeErr
+	}
+	f.frozen = append(f.frozen, claimNamespace+"/"+claimName+"/"+volumeName)
+	return func() error {
+		f.thawed = true
+		return f.thawErr
+	}, nil
+}
+
+func TestVolumeSnapshotActionFreeze(t *testing.T) {
+	tests := []struct {
+		name                string
+		freezer             *fakeFreezer
+		pv                  string
+		expectedFrozen      []string
+		expectedThawed      bool
+		expectedFreezeError string
+		expectedWarnings    int
+	}{
+		{
+			name:           "volume is frozen and thawed",
+			freezer:        &fakeFreezer{},
+			pv:             `{"apiVersion": "v1", "kind": "PersistentVolume", "metadata": {"name": "mypv"}, "spec": {"awsElasticBlockStore": {"volumeID": "aws://us-east-1c/vol-abc123"}, "claimRef": {"namespace": "ns-1", "name": "claim-1"}}}`,
+			expectedFrozen: []string{"ns-1/claim-1/mypv"},
+			expectedThawed: true,
+		},
+		{
+			name:    "unbound volume isn't frozen",
+			freezer: &fakeFreezer{},
+			pv:      `{"apiVersion": "v1", "kind": "PersistentVolume", "metadata": {"name": "mypv"}, "spec": {"awsElasticBlockStore": {"volumeID": "aws://us-east-1c/vol-abc123"}}}`,
+		},
+		{
+			name:                "freeze failure is reported and the volume is still snapshotted",
+			freezer:             &fakeFreezer{freezeErr: errors.New("freeze failed")},
+			pv:                  `{"apiVersion": "v1", "kind": "PersistentVolume", "metadata": {"name": "mypv"}, "spec": {"awsElasticBlockStore": {"volumeID": "aws://us-east-1c/vol-abc123"}, "claimRef": {"namespace": "ns-1", "name": "claim-1"}}}`,
+			expectedFreezeError: "freeze failed",
+			expectedWarnings:    1,
+		},
+		{
+			name:                "thaw failure is reported",
+			freezer:             &fakeFreezer{thawErr: errors.New("freeze window elapsed")},
+			pv:                  `{"apiVersion": "v1", "kind": "PersistentVolume", "metadata": {"name": "mypv"}, "spec": {"awsElasticBlockStore": {"volumeID": "aws://us-east-1c/vol-abc123"}, "claimRef": {"namespace": "ns-1", "name": "claim-1"}}}`,
+			expectedFrozen:      []string{"ns-1/claim-1/mypv"},
+			expectedThawed:      true,
+			expectedFreezeError: "freeze window elapsed",
+			expectedWarnings:    1,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			snapshotService := &FakeSnapshotService{SnapshottableVolumes: map[string]v1.VolumeBackupInfo{
+				"vol-abc123": {SnapshotID: "snap-1"},
+			}}
+
+			action, err := NewVolumeSnapshotAction(snapshotService, nil, nil, nil, test.freezer, false)
+			require.NoError(t, err)
+
+			pv, err := getAsMap(test.pv)
+			require.NoError(t, err)
+
+			backup := &v1.Backup{}
+			require.NoError(t, action.Execute(pv, backup))
+
+			assert.Equal(t, test.expectedFrozen, test.freezer.frozen)
+			assert.Equal(t, test.expectedThawed, test.freezer.thawed)
+			assert.Equal(t, "snap-1", backup.Status.VolumeBackups["mypv"].SnapshotID)
+			assert.Equal(t, test.expectedFreezeError, backup.Status.VolumeBackups["mypv"].FreezeError)
+			assert.Equal(t, test.expectedWarnings, backup.Status.Warnings)
+		})
+	}
+}
+
+type fakeCSISnapshotter struct {
+	err       error
+	snapshots []string
+}
+
+func (s *fakeCSISnapshotter) CreateSnapshot(claimNamespace, claimName, name string) (*v1.CSISnapshotInfo, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	s.snapshots = append(s.snapshots, claimNamespace+"/"+claimName+"/"+name)
+	return &v1.CSISnapshotInfo{Driver: "csi.example.com", SnapshotHandle: "handle-" + name}, nil
+}
+
+func (s *fakeCSISnapshotter) PrepareRestore(info *v1.CSISnapshotInfo, namespace, name string) error {
+	return nil
+}
+
+func (s *fakeCSISnapshotter) DeleteSnapshot(info *v1.CSISnapshotInfo) error {
+	return errors.New("not implemented")
+}
+
+func TestVolumeSnapshotActionCSI(t *testing.T) {
+	tests := []struct {
+		name              string
+		snapshotter       *fakeCSISnapshotter
+		pv                string
+		expectErr         bool
+		expectedSnapshots []string
+		expectedInfo      *v1.VolumeBackupInfo
+	}{
+		{
+			name:              "CSI volume is snapshotted using a VolumeSnapshot",
+			snapshotter:       &fakeCSISnapshotter{},
+			pv:                `{"apiVersion": "v1", "kind": "PersistentVolume", "metadata": {"name": "mypv"}, "spec": {"csi": {"driver": "csi.example.com", "volumeHandle": "vol-1"}, "claimRef": {"namespace": "ns-1", "name": "claim-1"}}}`,
+			expectedSnapshots: []string{"ns-1/claim-1/backup-1-mypv"},
+			expectedInfo: &v1.VolumeBackupInfo{
+				SnapshotID:  "handle-backup-1-mypv",
+				CSISnapshot: &v1.CSISnapshotInfo{Driver: "csi.example.com", SnapshotHandle: "handle-backup-1-mypv"},
+			},
+		},
+		{
+			name:        "unbound CSI volume returns an error",
+			snapshotter: &fakeCSISnapshotter{},
+			pv:          `{"apiVersion": "v1", "kind": "PersistentVolume", "metadata": {"name": "mypv"}, "spec": {"csi": {"driver": "csi.example.com", "volumeHandle": "vol-1"}}}`,
+			expectErr:   true,
+		},
+		{
+			name:        "VolumeSnapshot failure returns an error",
+			snapshotter: &fakeCSISnapshotter{err: errors.New("snapshot failed")},
+			pv:          `{"apiVersion": "v1", "kind": "PersistentVolume", "metadata": {"name": "mypv"}, "spec": {"csi": {"driver": "csi.example.com", "volumeHandle": "vol-1"}, "claimRef": {"namespace": "ns-1", "name": "claim-1"}}}`,
+			expectErr:   true,
+		},
+		{
+			name:        "non-CSI volume is skipped without a snapshot service",
+			snapshotter: &fakeCSISnapshotter{},
+			pv:          `{"apiVersion": "v1", "kind": "PersistentVolume", "metadata": {"name": "mypv"}, "spec": {"awsElasticBlockStore": {"volumeID": "aws://us-east-1c/vol-abc123"}, "claimRef": {"namespace": "ns-1", "name": "claim-1"}}}`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			action, err := NewVolumeSnapshotAction(nil, test.snapshotter, nil, nil, nil, false)
+			require.NoError(t, err)
+
+			pv, err := getAsMap(test.pv)
+			require.NoError(t, err)
+
+			backup := &v1.Backup{ObjectMeta: metav1.ObjectMeta{Namespace: "heptio-ark", Name: "backup-1"}}
+			err = action.Execute(pv, backup)
+			if test.expectErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+
+			assert.Equal(t, test.expectedSnapshots, test.snapshotter.snapshots)
+			assert.Equal(t, test.expectedInfo, backup.Status.VolumeBackups["mypv"])
+		})
+	}
+}
+
+func TestVolumeSnapshotActionFileSystemVolumes(t *testing.T) {
+	tests := []struct {
+		name             string
+		pv               string
+		podVolumeBackups bool
+		moveVolumeData   bool
+		pods             []kubev1.Pod
+		expectedWarnings int
+	}{
+		{
+			name:             "NFS volume is left to pod volume backups",
+			pv:               `{"apiVersion": "v1", "kind": "PersistentVolume", "metadata": {"name": "mypv"}, "spec": {"nfs": {"server": "fs-1.efs.us-east-1.amazonaws.com", "path": "/"}}}`,
+			podVolumeBackups: true,
+		},
+		{
+			name:             "EFS CSI volume is left to pod volume backups",
+			pv:               `{"apiVersion": "v1", "kind": "PersistentVolume", "metadata": {"name": "mypv"}, "spec": {"csi": {"driver": "efs.csi.aws.com", "volumeHandle": "fs-1"}}}`,
+			podVolumeBackups: true,
+		},
+		{
+			name:             "NFS volume is skipped with a warning without pod volume backups",
+			pv:               `{"apiVersion": "v1", "kind": "PersistentVolume", "metadata": {"name": "mypv"}, "spec": {"nfs": {"server": "nfs.example.com", "path": "/exports/data"}}}`,
+			expectedWarnings: 1,
+		},
+		{
+			name:             "NFS volume mounted by a running pod is left to pod volume backups",
+			pv:               `{"apiVersion": "v1", "kind": "PersistentVolume", "metadata": {"name": "mypv"}, "spec": {"nfs": {"server": "nfs.example.com", "path": "/"}, "claimRef": {"namespace": "ns-1", "name": "shared"}}}`,
+			podVolumeBackups: true,
+			pods: []kubev1.Pod{
+				podMountingClaim("ns-1", "pod-1", "shared", kubev1.PodRunning),
+			},
+		},
+		{
+			name:             "NFS volume mounted by no running pod is skipped with a warning",
+			pv:               `{"apiVersion": "v1", "kind": "PersistentVolume", "metadata": {"name": "mypv"}, "spec": {"nfs": {"server": "nfs.example.com", "path": "/"}, "claimRef": {"namespace": "ns-1", "name": "shared"}}}`,
+			podVolumeBackups: true,
+			pods: []kubev1.Pod{
+				podMountingClaim("ns-1", "pod-1", "shared", kubev1.PodSucceeded),
+				podMountingClaim("ns-1", "pod-2", "other", kubev1.PodRunning),
+				podMountingClaim("ns-2", "pod-3", "shared", kubev1.PodRunning),
+			},
+			expectedWarnings: 1,
+		},
+		{
+			name:             "NFS volume mounted by no running pod is left to the move volume data action to report",
+			pv:               `{"apiVersion": "v1", "kind": "PersistentVolume", "metadata": {"name": "mypv"}, "spec": {"nfs": {"server": "nfs.example.com", "path": "/"}, "claimRef": {"namespace": "ns-1", "name": "shared"}}}`,
+			podVolumeBackups: true,
+			moveVolumeData:   true,
+			pods:             []kubev1.Pod{},
+		},
+		{
+			name:             "unbound NFS volume is skipped with a warning",
+			pv:               `{"apiVersion": "v1", "kind": "PersistentVolume", "metadata": {"name": "mypv"}, "spec": {"nfs": {"server": "nfs.example.com", "path": "/"}}}`,
+			podVolumeBackups: true,
+			pods:             []kubev1.Pod{},
+			expectedWarnings: 1,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var podClient corev1.PodsGetter
+			if test.pods != nil {
+				podClient = &fakePodGetter{pods: test.pods}
+			}
+
+			snapshotter := &fakeCSISnapshotter{}
+			action, err := NewVolumeSnapshotAction(nil, snapshotter, nil, podClient, nil, test.podVolumeBackups)
+			require.NoError(t, err)
+
+			pv, err := getAsMap(test.pv)
+			require.NoError(t, err)
+
+			backup := &v1.Backup{ObjectMeta: metav1.ObjectMeta{Namespace: "heptio-ark", Name: "backup-1"}}
+			backup.Spec.MoveVolumeData = test.moveVolumeData
+			require.NoError(t, action.Execute(pv, backup))
+
+			assert.Empty(t, snapshotter.snapshots)
+			assert.Empty(t, backup.Status.VolumeBackups)
+			assert.Equal(t, test.expectedWarnings, backup.Status.Warnings)
+		})
+	}
+}
+
+func TestNewVolumeSnapshotActionRequiresSnapshotter(t *testing.T) {
+	_, err := NewVolumeSnapshotAction(nil, nil, nil, nil, nil, false)
+	assert.Error(t, err)
+}
@@ -22,6 +22,9 @@ package buildinfo
 // Version is the current version of Ark, set by the go linker's -X flag at build time.
 var Version string
 
+// GitSHA is the git commit Ark was built from, set by the go linker's -X flag at build time.
+var GitSHA string
+
 // DockerImage is the full path to the docker image for this build, for example
 // gcr.io/heptio-images/ark.
 var DockerImage string
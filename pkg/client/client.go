@@ -21,11 +21,14 @@ import (
 	"k8s.io/client-go/tools/clientcmd"
 )
 
-// Config returns a *rest.Config, using either the kubeconfig (if specified) or an in-cluster
-// configuration.
-func Config(kubeconfig string) (*rest.Config, error) {
-	if len(kubeconfig) > 0 {
-		return clientcmd.BuildConfigFromFlags("", kubeconfig)
-	}
-	return rest.InClusterConfig()
+// Config returns a *rest.Config, using the kubeconfig (if specified), the KUBECONFIG environment
+// variable, the default kubeconfig file, or an in-cluster configuration, in that order. If
+// kubecontext is non-empty, it overrides the kubeconfig's current context.
+func Config(kubeconfig, kubecontext string) (*rest.Config, error) {
+	loadingRules := clientcmd.NewDefaultClientConfigLoadingRules()
+	loadingRules.ExplicitPath = kubeconfig
+
+	overrides := &clientcmd.ConfigOverrides{CurrentContext: kubecontext}
+
+	return clientcmd.NewNonInteractiveDeferredLoadingClientConfig(loadingRules, overrides).ClientConfig()
 }
@@ -17,12 +17,20 @@ limitations under the License.
 package client
 
 import (
+	"errors"
+	"net/url"
+	"strconv"
+
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/conversion/queryparams"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/watch"
 	"k8s.io/client-go/dynamic"
+
+	"github.com/heptio/ark/pkg/util/collections"
 )
 
 // DynamicFactory contains methods for retrieving dynamic clients for GroupVersionResources and
@@ -55,6 +63,9 @@ func (f *dynamicFactory) ClientForGroupVersionResource(gvr schema.GroupVersionRe
 	}
 
 	return &dynamicResourceClient{
+		client:         dynamicClient,
+		resource:       resource,
+		namespace:      namespace,
 		resourceClient: dynamicClient.Resource(&resource, namespace),
 	}, nil
 }
@@ -66,6 +77,9 @@ func (f *dynamicFactory) ClientForGroupVersionKind(gvk schema.GroupVersionKind,
 	}
 
 	return &dynamicResourceClient{
+		client:         dynamicClient,
+		resource:       resource,
+		namespace:      namespace,
 		resourceClient: dynamicClient.Resource(&resource, namespace),
 	}, nil
 }
@@ -74,14 +88,28 @@ func (f *dynamicFactory) ClientForGroupVersionKind(gvk schema.GroupVersionKind,
 type Dynamic interface {
 	// Create creates an object.
 	Create(obj *unstructured.Unstructured) (*unstructured.Unstructured, error)
+	// Get gets the object with the given name.
+	Get(name string, opts metav1.GetOptions) (*unstructured.Unstructured, error)
 	// List lists all the objects of a given resource.
 	List(metav1.ListOptions) (runtime.Object, error)
+	// ListPage lists at most limit objects of a given resource, starting where the page whose
+	// continue token is continueToken left off, or at the beginning if it's empty. It returns the
+	// continue token of the next page, which is empty if this is the last page. API servers that
+	// don't support paging return every object in one page.
+	ListPage(options metav1.ListOptions, limit int64, continueToken string) (runtime.Object, string, error)
 	// Watch watches for changes to objects of a given resource.
 	Watch(metav1.ListOptions) (watch.Interface, error)
+	// Patch patches the object with the given name.
+	Patch(name string, pt types.PatchType, data []byte) (*unstructured.Unstructured, error)
+	// Delete deletes the object with the given name.
+	Delete(name string, opts *metav1.DeleteOptions) error
 }
 
 // dynamicResourceClient implements Dynamic.
 type dynamicResourceClient struct {
+	client         *dynamic.Client
+	resource       metav1.APIResource
+	namespace      string
 	resourceClient *dynamic.ResourceClient
 }
 
@@ -91,10 +119,61 @@ func (d *dynamicResourceClient) Create(obj *unstructured.Unstructured) (*unstruc
 	return d.resourceClient.Create(obj)
 }
 
+func (d *dynamicResourceClient) Get(name string, opts metav1.GetOptions) (*unstructured.Unstructured, error) {
+	return d.resourceClient.Get(name, opts)
+}
+
 func (d *dynamicResourceClient) List(options metav1.ListOptions) (runtime.Object, error) {
 	return d.resourceClient.List(options)
 }
 
+func (d *dynamicResourceClient) ListPage(options metav1.ListOptions, limit int64, continueToken string) (runtime.Object, string, error) {
+	codec := pageParameterCodec{limit: limit, continueToken: continueToken}
+	list, err := d.client.ParameterCodec(codec).Resource(&d.resource, d.namespace).List(options)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var next string
+	if unstructuredList, ok := list.(*unstructured.UnstructuredList); ok {
+		// a missing continue token means this is the last page
+		next, _ = collections.GetString(unstructuredList.Object, "metadata.continue")
+	}
+	return list, next, nil
+}
+
 func (d *dynamicResourceClient) Watch(options metav1.ListOptions) (watch.Interface, error) {
 	return d.resourceClient.Watch(options)
 }
+
+func (d *dynamicResourceClient) Patch(name string, pt types.PatchType, data []byte) (*unstructured.Unstructured, error) {
+	return d.resourceClient.Patch(name, pt, data)
+}
+
+func (d *dynamicResourceClient) Delete(name string, opts *metav1.DeleteOptions) error {
+	return d.resourceClient.Delete(name, opts)
+}
+
+// pageParameterCodec encodes list options as the dynamic client does by default, adding the
+// limit and continue parameters of paged lists, which the vendored ListOptions doesn't have.
+type pageParameterCodec struct {
+	limit         int64
+	continueToken string
+}
+
+func (c pageParameterCodec) EncodeParameters(obj runtime.Object, to schema.GroupVersion) (url.Values, error) {
+	params, err := queryparams.Convert(obj)
+	if err != nil {
+		return nil, err
+	}
+
+	params.Set("limit", strconv.FormatInt(c.limit, 10))
+	if c.continueToken != "" {
+		params.Set("continue", c.continueToken)
+	}
+	return params, nil
+}
+
+func (pageParameterCodec) DecodeParameters(parameters url.Values, from schema.GroupVersion, into runtime.Object) error {
+	return errors.New("DecodeParameters not implemented on pageParameterCodec")
+}
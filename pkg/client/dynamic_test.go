@@ -0,0 +1,76 @@
+/*
+Copyright 2017 Heptio Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/rest"
+)
+
+func TestListPage(t *testing.T) {
+	var query url.Values
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/v1/namespaces/ns-1/configmaps", r.URL.Path)
+		query = r.URL.Query()
+
+		w.Header().Set("Content-Type", "application/json")
+		if query.Get("continue") == "" {
+			w.Write([]byte(`{"apiVersion": "v1", "kind": "ConfigMapList", "metadata": {"continue": "page-2"}, "items": [{"metadata": {"name": "cm-1"}}]}`))
+		} else {
+			w.Write([]byte(`{"apiVersion": "v1", "kind": "ConfigMapList", "metadata": {}, "items": [{"metadata": {"name": "cm-2"}}]}`))
+		}
+	}))
+	defer server.Close()
+
+	dynamicClient, err := dynamic.NewClient(&rest.Config{
+		Host:          server.URL,
+		ContentConfig: rest.ContentConfig{GroupVersion: &schema.GroupVersion{Version: "v1"}},
+	})
+	require.NoError(t, err)
+
+	resource := metav1.APIResource{Name: "configmaps", Namespaced: true}
+	client := &dynamicResourceClient{
+		client:         dynamicClient,
+		resource:       resource,
+		namespace:      "ns-1",
+		resourceClient: dynamicClient.Resource(&resource, "ns-1"),
+	}
+
+	list, next, err := client.ListPage(metav1.ListOptions{LabelSelector: "app=nginx"}, 1, "")
+	require.NoError(t, err)
+	assert.Equal(t, "page-2", next)
+	assert.Equal(t, "cm-1", list.(*unstructured.UnstructuredList).Items[0].GetName())
+	assert.Equal(t, "1", query.Get("limit"))
+	assert.Equal(t, "app=nginx", query.Get("labelSelector"))
+	assert.Empty(t, query.Get("continue"))
+
+	list, next, err = client.ListPage(metav1.ListOptions{LabelSelector: "app=nginx"}, 1, "page-2")
+	require.NoError(t, err)
+	assert.Empty(t, next)
+	assert.Equal(t, "cm-2", list.(*unstructured.UnstructuredList).Items[0].GetName())
+	assert.Equal(t, "page-2", query.Get("continue"))
+}
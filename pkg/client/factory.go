@@ -21,21 +21,40 @@ import (
 
 	"github.com/spf13/pflag"
 
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+
+	api "github.com/heptio/ark/pkg/apis/ark/v1"
 	"github.com/heptio/ark/pkg/generated/clientset"
 )
 
 // Factory knows how to create an ArkClient.
 type Factory interface {
-	// BindFlags binds common flags such as --kubeconfig to the passed-in FlagSet.
+	// BindFlags binds common flags such as --kubeconfig, --context, and --namespace to the
+	// passed-in FlagSet.
 	BindFlags(flags *pflag.FlagSet)
 	// Client returns an ArkClient. It uses the following priority to specify the cluster
-	// configuration:  --kubeconfig flag, KUBECONFIG environment variable, in-cluster configuration.
+	// configuration:  --kubeconfig flag, KUBECONFIG environment variable, default kubeconfig
+	// file, in-cluster configuration. The --context flag selects a context other than the
+	// kubeconfig's current one.
 	Client() (clientset.Interface, error)
+	// KubeClient returns a Kubernetes client, using the same cluster configuration as Client.
+	KubeClient() (kubernetes.Interface, error)
+	// Namespace returns the namespace whose Ark resources are read and created. It uses the
+	// following priority: --namespace flag, ARK_NAMESPACE environment variable, the default ark
+	// namespace.
+	Namespace() string
 }
 
+// namespaceEnvVar is the environment variable consulted for the namespace of Ark resources when
+// --namespace is not specified.
+const namespaceEnvVar = "ARK_NAMESPACE"
+
 type factory struct {
-	flags      *pflag.FlagSet
-	kubeconfig string
+	flags       *pflag.FlagSet
+	kubeconfig  string
+	kubecontext string
+	namespace   string
 }
 
 // NewFactory returns a Factory.
@@ -43,7 +62,9 @@ func NewFactory() Factory {
 	f := &factory{
 		flags: pflag.NewFlagSet("", pflag.ContinueOnError),
 	}
-	f.flags.StringVar(&f.kubeconfig, "kubeconfig", "", "Path to the kubeconfig file to use to talk to the Kubernetes apiserver. If unset, try the environment variable KUBECONFIG, as well as in-cluster configuration")
+	f.flags.StringVar(&f.kubeconfig, "kubeconfig", "", "Path to the kubeconfig file to use to talk to the Kubernetes apiserver. If unset, try the environment variable KUBECONFIG, the default kubeconfig file, as well as in-cluster configuration")
+	f.flags.StringVar(&f.kubecontext, "context", "", "The name of the kubeconfig context to use. If unset, use the kubeconfig's current context")
+	f.flags.StringVarP(&f.namespace, "namespace", "n", "", "The namespace whose Ark resources (backups, restores, schedules, and so on) are read and created, e.g. a tenant namespace in tenant mode. The Ark server itself always runs in "+api.DefaultNamespace+". If unset, try the environment variable "+namespaceEnvVar+", as well as "+api.DefaultNamespace)
 
 	return f
 }
@@ -52,14 +73,12 @@ func (f *factory) BindFlags(flags *pflag.FlagSet) {
 	flags.AddFlagSet(f.flags)
 }
 
-func (f *factory) Client() (clientset.Interface, error) {
-	kubeconfig := f.kubeconfig
-	if kubeconfig == "" {
-		// if the command line flag was not specified, try the environment variable
-		kubeconfig = os.Getenv("KUBECONFIG")
-	}
+func (f *factory) clientConfig() (*rest.Config, error) {
+	return Config(f.kubeconfig, f.kubecontext)
+}
 
-	clientConfig, err := Config(kubeconfig)
+func (f *factory) Client() (clientset.Interface, error) {
+	clientConfig, err := f.clientConfig()
 	if err != nil {
 		return nil, err
 	}
@@ -70,3 +89,25 @@ func (f *factory) Client() (clientset.Interface, error) {
 	}
 	return arkClient, nil
 }
+
+func (f *factory) KubeClient() (kubernetes.Interface, error) {
+	clientConfig, err := f.clientConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	return kubernetes.NewForConfig(clientConfig)
+}
+
+func (f *factory) Namespace() string {
+	if f.namespace != "" {
+		return f.namespace
+	}
+
+	// if the command line flag was not specified, try the environment variable
+	if namespace := os.Getenv(namespaceEnvVar); namespace != "" {
+		return namespace
+	}
+
+	return api.DefaultNamespace
+}
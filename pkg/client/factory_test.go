@@ -0,0 +1,144 @@
+/*
+Copyright 2017 Heptio Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/pflag"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	api "github.com/heptio/ark/pkg/apis/ark/v1"
+)
+
+const kubeconfig = `apiVersion: v1
+kind: Config
+clusters:
+- name: cluster-1
+  cluster:
+    server: https://cluster-1.example.com
+- name: cluster-2
+  cluster:
+    server: https://cluster-2.example.com
+contexts:
+- name: context-1
+  context:
+    cluster: cluster-1
+- name: context-2
+  context:
+    cluster: cluster-2
+current-context: context-1
+`
+
+func TestFactoryNamespace(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		env      string
+		expected string
+	}{
+		{
+			name:     "default",
+			expected: api.DefaultNamespace,
+		},
+		{
+			name:     "environment variable",
+			env:      "ns-env",
+			expected: "ns-env",
+		},
+		{
+			name:     "--namespace takes precedence over the environment variable",
+			args:     []string{"--namespace", "ns-flag"},
+			env:      "ns-env",
+			expected: "ns-flag",
+		},
+		{
+			name:     "-n",
+			args:     []string{"-n", "ns-flag"},
+			expected: "ns-flag",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if test.env != "" {
+				os.Setenv(namespaceEnvVar, test.env)
+				defer os.Unsetenv(namespaceEnvVar)
+			}
+
+			f := NewFactory()
+			flags := pflag.NewFlagSet("", pflag.ContinueOnError)
+			f.BindFlags(flags)
+			require.NoError(t, flags.Parse(test.args))
+
+			assert.Equal(t, test.expected, f.Namespace())
+		})
+	}
+}
+
+func TestFactoryContext(t *testing.T) {
+	dir, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "kubeconfig")
+	require.NoError(t, ioutil.WriteFile(path, []byte(kubeconfig), 0600))
+
+	tests := []struct {
+		name           string
+		args           []string
+		expectedServer string
+		expectedErr    bool
+	}{
+		{
+			name:           "the kubeconfig's current context is used by default",
+			args:           []string{"--kubeconfig", path},
+			expectedServer: "https://cluster-1.example.com",
+		},
+		{
+			name:           "--context selects another context",
+			args:           []string{"--kubeconfig", path, "--context", "context-2"},
+			expectedServer: "https://cluster-2.example.com",
+		},
+		{
+			name:        "unknown contexts are an error",
+			args:        []string{"--kubeconfig", path, "--context", "context-3"},
+			expectedErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			f := NewFactory()
+			flags := pflag.NewFlagSet("", pflag.ContinueOnError)
+			f.BindFlags(flags)
+			require.NoError(t, flags.Parse(test.args))
+
+			config, err := f.(*factory).clientConfig()
+			if test.expectedErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.expectedServer, config.Host)
+		})
+	}
+}
@@ -21,6 +21,7 @@ import (
 	"fmt"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/ec2"
 
@@ -217,5 +218,10 @@ func (op *blockStorageAdapter) DeleteSnapshot(snapshotID string) error {
 
 	_, err := op.ec2.DeleteSnapshot(req)
 
+	// a snapshot that's already gone doesn't need deleting
+	if awsErr, ok := err.(awserr.Error); ok && awsErr.Code() == "InvalidSnapshot.NotFound" {
+		return nil
+	}
+
 	return err
 }
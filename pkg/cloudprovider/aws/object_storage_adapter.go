@@ -19,8 +19,10 @@ package aws
 import (
 	"errors"
 	"io"
+	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/aws/endpoints"
 	"github.com/aws/aws-sdk-go/service/s3"
 
@@ -94,15 +96,19 @@ func (op *objectStorageAdapter) GetObject(bucket string, key string) (io.ReadClo
 
 	res, err := op.s3.GetObject(req)
 	if err != nil {
+		if awsErr, ok := err.(awserr.Error); ok && awsErr.Code() == "NoSuchKey" {
+			return nil, cloudprovider.NewObjectNotFoundError(bucket, key)
+		}
 		return nil, err
 	}
 
 	return res.Body, nil
 }
 
-func (op *objectStorageAdapter) ListCommonPrefixes(bucket string, delimiter string) ([]string, error) {
+func (op *objectStorageAdapter) ListCommonPrefixes(bucket, prefix, delimiter string) ([]string, error) {
 	req := &s3.ListObjectsV2Input{
 		Bucket:    &bucket,
+		Prefix:    &prefix,
 		Delimiter: &delimiter,
 	}
 
@@ -130,3 +136,12 @@ func (op *objectStorageAdapter) DeleteObject(bucket string, key string) error {
 
 	return err
 }
+
+func (op *objectStorageAdapter) CreateSignedURL(bucket, key string, ttl time.Duration) (string, error) {
+	req, _ := op.s3.GetObjectRequest(&s3.GetObjectInput{
+		Bucket: &bucket,
+		Key:    &key,
+	})
+
+	return req.Presign(ttl)
+}
@@ -19,7 +19,9 @@ package azure
 import (
 	"fmt"
 	"io"
+	"net/http"
 	"strings"
+	"time"
 
 	"github.com/Azure/azure-sdk-for-go/storage"
 
@@ -89,19 +91,23 @@ func (op *objectStorageAdapter) GetObject(bucket string, key string) (io.ReadClo
 
 	res, err := blob.Get(nil)
 	if err != nil {
+		if azureErr, ok := err.(storage.AzureStorageServiceError); ok && azureErr.StatusCode == http.StatusNotFound {
+			return nil, cloudprovider.NewObjectNotFoundError(bucket, key)
+		}
 		return nil, err
 	}
 
 	return res, nil
 }
 
-func (op *objectStorageAdapter) ListCommonPrefixes(bucket string, delimiter string) ([]string, error) {
+func (op *objectStorageAdapter) ListCommonPrefixes(bucket, prefix, delimiter string) ([]string, error) {
 	container, err := getContainerReference(op.blobClient, bucket)
 	if err != nil {
 		return nil, err
 	}
 
 	params := storage.ListBlobsParameters{
+		Prefix:    prefix,
 		Delimiter: delimiter,
 	}
 
@@ -134,6 +140,20 @@ func (op *objectStorageAdapter) DeleteObject(bucket string, key string) error {
 	return blob.Delete(nil)
 }
 
+func (op *objectStorageAdapter) CreateSignedURL(bucket, key string, ttl time.Duration) (string, error) {
+	container, err := getContainerReference(op.blobClient, bucket)
+	if err != nil {
+		return "", err
+	}
+
+	blob, err := getBlobReference(container, key)
+	if err != nil {
+		return "", err
+	}
+
+	return blob.GetSASURI(time.Now().Add(ttl), "r")
+}
+
 func getContainerReference(blobClient *storage.BlobStorageClient, bucket string) (*storage.Container, error) {
 	container := blobClient.GetContainerReference(bucket)
 	if container == nil {
@@ -21,6 +21,7 @@ import (
 	"fmt"
 	"io"
 	"io/ioutil"
+	"strings"
 	"time"
 
 	"github.com/golang/glog"
@@ -35,17 +36,47 @@ import (
 type BackupService interface {
 	BackupGetter
 	// UploadBackup uploads the specified Ark backup of a set of Kubernetes API objects, whose manifests are
-	// stored in the specified file, into object storage in an Ark bucket, tagged with Ark metadata. Returns
-	// an error if a problem is encountered accessing the file or performing the upload via the cloud API.
-	UploadBackup(bucket, name string, metadata, backup io.ReadSeeker) error
+	// stored in the specified file, into object storage in an Ark bucket, tagged with Ark metadata, along
+	// with the backup's log file. Returns an error if a problem is encountered accessing the file or
+	// performing the upload via the cloud API. A failure to upload the log file is not returned as an error.
+	UploadBackup(bucket, name string, metadata, backup, log io.ReadSeeker) error
+
+	// UploadBackupLog uploads only the log file of a backup, e.g. when the backup failed and so
+	// couldn't be uploaded using UploadBackup.
+	UploadBackupLog(bucket, name string, log io.ReadSeeker) error
+
+	// UploadBackupItemList uploads the list of the items in a backup, which lets its contents be
+	// listed without downloading it.
+	UploadBackupItemList(bucket, name string, items io.ReadSeeker) error
+
+	// UploadRestoreLog uploads the log file of a restore of the named backup.
+	UploadRestoreLog(bucket, backupName, restoreName string, log io.ReadSeeker) error
+
+	// UploadRestorePlan uploads the plan of a previewed restore of the named backup.
+	UploadRestorePlan(bucket, backupName, restoreName string, plan io.ReadSeeker) error
+
+	// UploadRestoreResults uploads the warnings and errors of a restore of the named backup.
+	UploadRestoreResults(bucket, backupName, restoreName string, results io.ReadSeeker) error
 
 	// DownloadBackup downloads an Ark backup with the specified object key from object storage via the cloud API.
 	// It returns the snapshot metadata and data (separately), or an error if a problem is encountered
 	// downloading or reading the file from the cloud API.
 	DownloadBackup(bucket, name string) (io.ReadCloser, error)
 
+	// DownloadBackupLogs downloads the gzip-compressed log file of the backup with the specified
+	// name from object storage.
+	DownloadBackupLogs(bucket, name string) (io.ReadCloser, error)
+
 	// DeleteBackup deletes the backup content in object storage for the given api.Backup.
 	DeleteBackup(bucket, backupName string) error
+
+	// GetBackup gets the specified api.Backup from object storage. Returns an error if the
+	// backup's metadata can't be downloaded or decoded.
+	GetBackup(bucket, name string) (*api.Backup, error)
+
+	// CreateSignedURL creates a pre-signed URL for downloading target, which belongs to the backup
+	// with the given name (for restore logs, the backup that was restored), that expires after ttl.
+	CreateSignedURL(target api.DownloadTarget, bucket, backupName string, ttl time.Duration) (string, error)
 }
 
 // BackupGetter knows how to list backups in object storage.
@@ -55,10 +86,22 @@ type BackupGetter interface {
 }
 
 const (
-	metadataFileFormatString string = "%s/ark-backup.json"
-	backupFileFormatString   string = "%s/%s.tar.gz"
+	metadataFileFormatString   string = "%s/ark-backup.json"
+	backupFileFormatString     string = "%s/%s.tar.gz"
+	logFileFormatString        string = "%s/%s-logs.gz"
+	itemListFormatString       string = "%s/%s-items.json.gz"
+	restoreLogFormatString     string = "%s/restore-%s-logs.gz"
+	restorePlanFormatString    string = "%s/restore-%s-plan.json.gz"
+	restoreResultsFormatString string = "%s/restore-%s-results.json.gz"
 )
 
+// isReservedDir returns whether a top-level "directory" in a bucket is used by Ark itself rather
+// than holding a backup. Reserved directories start with a "." so they can never collide with a
+// backup's name.
+func isReservedDir(dir string) bool {
+	return strings.HasPrefix(dir, ".")
+}
+
 type backupService struct {
 	objectStorage ObjectStorageAdapter
 }
@@ -73,15 +116,29 @@ func NewBackupService(objectStorage ObjectStorageAdapter) BackupService {
 	}
 }
 
-func (br *backupService) UploadBackup(bucket, backupName string, metadata, backup io.ReadSeeker) error {
+func (br *backupService) UploadBackup(bucket, backupName string, metadata, backup, log io.ReadSeeker) error {
+	return br.uploadBackupFiles(bucket, backupName, metadata, fmt.Sprintf(backupFileFormatString, backupName, backupName), backup, log)
+}
+
+// uploadBackupFiles uploads a backup's log file, metadata file, and data (stored under dataKey).
+func (br *backupService) uploadBackupFiles(bucket, backupName string, metadata io.ReadSeeker, dataKey string, data, log io.ReadSeeker) error {
+	// upload the log file first. if this fails, we still want to upload the
+	// backup itself, so only log the error.
+	if log != nil {
+		logKey := fmt.Sprintf(logFileFormatString, backupName, backupName)
+		if err := br.objectStorage.PutObject(bucket, logKey, log); err != nil {
+			glog.Errorf("error uploading log file %s/%s: %v", bucket, logKey, err)
+		}
+	}
+
 	// upload metadata file
 	metadataKey := fmt.Sprintf(metadataFileFormatString, backupName)
 	if err := br.objectStorage.PutObject(bucket, metadataKey, metadata); err != nil {
 		return err
 	}
 
-	// upload tar file
-	if err := br.objectStorage.PutObject(bucket, fmt.Sprintf(backupFileFormatString, backupName, backupName), backup); err != nil {
+	// upload data file
+	if err := br.objectStorage.PutObject(bucket, dataKey, data); err != nil {
 		// try to delete the metadata file since the data upload failed
 		deleteErr := br.objectStorage.DeleteObject(bucket, metadataKey)
 
@@ -95,8 +152,32 @@ func (br *backupService) DownloadBackup(bucket, backupName string) (io.ReadClose
 	return br.objectStorage.GetObject(bucket, fmt.Sprintf(backupFileFormatString, backupName, backupName))
 }
 
+func (br *backupService) UploadBackupLog(bucket, backupName string, log io.ReadSeeker) error {
+	return br.objectStorage.PutObject(bucket, fmt.Sprintf(logFileFormatString, backupName, backupName), log)
+}
+
+func (br *backupService) UploadBackupItemList(bucket, backupName string, items io.ReadSeeker) error {
+	return br.objectStorage.PutObject(bucket, fmt.Sprintf(itemListFormatString, backupName, backupName), items)
+}
+
+func (br *backupService) UploadRestoreLog(bucket, backupName, restoreName string, log io.ReadSeeker) error {
+	return br.objectStorage.PutObject(bucket, fmt.Sprintf(restoreLogFormatString, backupName, restoreName), log)
+}
+
+func (br *backupService) UploadRestorePlan(bucket, backupName, restoreName string, plan io.ReadSeeker) error {
+	return br.objectStorage.PutObject(bucket, fmt.Sprintf(restorePlanFormatString, backupName, restoreName), plan)
+}
+
+func (br *backupService) UploadRestoreResults(bucket, backupName, restoreName string, results io.ReadSeeker) error {
+	return br.objectStorage.PutObject(bucket, fmt.Sprintf(restoreResultsFormatString, backupName, restoreName), results)
+}
+
+func (br *backupService) DownloadBackupLogs(bucket, backupName string) (io.ReadCloser, error) {
+	return br.objectStorage.GetObject(bucket, fmt.Sprintf(logFileFormatString, backupName, backupName))
+}
+
 func (br *backupService) GetAllBackups(bucket string) ([]*api.Backup, error) {
-	prefixes, err := br.objectStorage.ListCommonPrefixes(bucket, "/")
+	prefixes, err := br.objectStorage.ListCommonPrefixes(bucket, "", "/")
 	if err != nil {
 		return nil, err
 	}
@@ -106,50 +187,84 @@ func (br *backupService) GetAllBackups(bucket string) ([]*api.Backup, error) {
 
 	output := make([]*api.Backup, 0, len(prefixes))
 
-	decoder := scheme.Codecs.UniversalDecoder(api.SchemeGroupVersion)
-
 	for _, backupDir := range prefixes {
-		err := func() error {
-			key := fmt.Sprintf(metadataFileFormatString, backupDir)
+		if isReservedDir(backupDir) {
+			continue
+		}
 
-			res, err := br.objectStorage.GetObject(bucket, key)
-			if err != nil {
-				return err
-			}
-			defer res.Close()
+		backup, err := br.GetBackup(bucket, backupDir)
+		// directories without metadata aren't backups, e.g. they hold only the logs of backups
+		// that failed before they could be uploaded.
+		if IsObjectNotFound(err) {
+			glog.V(2).Infof("Skipping %s/%s, which has no backup metadata", bucket, backupDir)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
 
-			data, err := ioutil.ReadAll(res)
-			if err != nil {
-				return err
-			}
+		output = append(output, backup)
+	}
 
-			obj, _, err := decoder.Decode(data, nil, nil)
-			if err != nil {
-				return err
-			}
+	return output, nil
+}
 
-			backup, ok := obj.(*api.Backup)
-			if !ok {
-				return fmt.Errorf("unexpected type for %s/%s: %T", bucket, key, obj)
-			}
+func (br *backupService) GetBackup(bucket, name string) (*api.Backup, error) {
+	key := fmt.Sprintf(metadataFileFormatString, name)
 
-			output = append(output, backup)
+	res, err := br.objectStorage.GetObject(bucket, key)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Close()
 
-			return nil
-		}()
+	data, err := ioutil.ReadAll(res)
+	if err != nil {
+		return nil, err
+	}
 
-		if err != nil {
-			return nil, err
-		}
+	decoder := scheme.Codecs.UniversalDecoder(api.SchemeGroupVersion)
+	obj, _, err := decoder.Decode(data, nil, nil)
+	if err != nil {
+		return nil, err
 	}
 
-	return output, nil
+	backup, ok := obj.(*api.Backup)
+	if !ok {
+		return nil, fmt.Errorf("unexpected type for %s/%s: %T", bucket, key, obj)
+	}
+
+	return backup, nil
+}
+
+func (br *backupService) CreateSignedURL(target api.DownloadTarget, bucket, backupName string, ttl time.Duration) (string, error) {
+	switch target.Kind {
+	case api.DownloadTargetKindBackupContents:
+		return br.objectStorage.CreateSignedURL(bucket, fmt.Sprintf(backupFileFormatString, backupName, backupName), ttl)
+	case api.DownloadTargetKindBackupItems:
+		return br.objectStorage.CreateSignedURL(bucket, fmt.Sprintf(itemListFormatString, backupName, backupName), ttl)
+	case api.DownloadTargetKindBackupLog:
+		return br.objectStorage.CreateSignedURL(bucket, fmt.Sprintf(logFileFormatString, backupName, backupName), ttl)
+	case api.DownloadTargetKindRestoreLog:
+		return br.objectStorage.CreateSignedURL(bucket, fmt.Sprintf(restoreLogFormatString, backupName, target.Name), ttl)
+	case api.DownloadTargetKindRestorePlan:
+		return br.objectStorage.CreateSignedURL(bucket, fmt.Sprintf(restorePlanFormatString, backupName, target.Name), ttl)
+	case api.DownloadTargetKindRestoreResults:
+		return br.objectStorage.CreateSignedURL(bucket, fmt.Sprintf(restoreResultsFormatString, backupName, target.Name), ttl)
+	default:
+		return "", fmt.Errorf("unsupported download target kind %q", target.Kind)
+	}
 }
 
 func (br *backupService) DeleteBackup(bucket, backupName string) error {
+	return br.deleteBackupFiles(bucket, backupName, fmt.Sprintf(backupFileFormatString, backupName, backupName))
+}
+
+// deleteBackupFiles deletes a backup's data (stored under dataKey), metadata file, and log file.
+func (br *backupService) deleteBackupFiles(bucket, backupName, dataKey string) error {
 	var errs []error
 
-	key := fmt.Sprintf(backupFileFormatString, backupName, backupName)
+	key := dataKey
 	glog.V(4).Infof("Trying to delete bucket=%s, key=%s", bucket, key)
 	if err := br.objectStorage.DeleteObject(bucket, key); err != nil {
 		errs = append(errs, err)
@@ -161,6 +276,21 @@ func (br *backupService) DeleteBackup(bucket, backupName string) error {
 		errs = append(errs, err)
 	}
 
+	// backups created before logs were persisted won't have a log file, so
+	// don't treat a failure to delete it as an error.
+	key = fmt.Sprintf(logFileFormatString, backupName, backupName)
+	glog.V(4).Infof("Trying to delete bucket=%s, key=%s", bucket, key)
+	if err := br.objectStorage.DeleteObject(bucket, key); err != nil {
+		glog.Warningf("error deleting log file %s/%s: %v", bucket, key, err)
+	}
+
+	// the same goes for backups created before item lists were uploaded.
+	key = fmt.Sprintf(itemListFormatString, backupName, backupName)
+	glog.V(4).Infof("Trying to delete bucket=%s, key=%s", bucket, key)
+	if err := br.objectStorage.DeleteObject(bucket, key); err != nil {
+		glog.Warningf("error deleting item list %s/%s: %v", bucket, key, err)
+	}
+
 	return errors.NewAggregate(errs)
 }
 
@@ -20,10 +20,12 @@ import (
 	"bytes"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
 	"io/ioutil"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 
@@ -43,6 +45,7 @@ func TestUploadBackup(t *testing.T) {
 		backupName      string
 		metadata        io.ReadSeeker
 		backup          io.ReadSeeker
+		log             io.ReadSeeker
 		objectStoreErrs map[string]map[string]interface{}
 		expectedErr     bool
 		expectedRes     map[string][]byte
@@ -60,6 +63,40 @@ func TestUploadBackup(t *testing.T) {
 				"test-backup/test-backup.tar.gz": []byte("bar"),
 			},
 		},
+		{
+			name:         "log is uploaded alongside backup",
+			bucket:       "test-bucket",
+			bucketExists: true,
+			backupName:   "test-backup",
+			metadata:     newStringReadSeeker("foo"),
+			backup:       newStringReadSeeker("bar"),
+			log:          newStringReadSeeker("baz"),
+			expectedErr:  false,
+			expectedRes: map[string][]byte{
+				"test-backup/ark-backup.json":     []byte("foo"),
+				"test-backup/test-backup.tar.gz":  []byte("bar"),
+				"test-backup/test-backup-logs.gz": []byte("baz"),
+			},
+		},
+		{
+			name:         "error on log upload is ignored",
+			bucket:       "test-bucket",
+			bucketExists: true,
+			backupName:   "test-backup",
+			metadata:     newStringReadSeeker("foo"),
+			backup:       newStringReadSeeker("bar"),
+			log:          newStringReadSeeker("baz"),
+			objectStoreErrs: map[string]map[string]interface{}{
+				"putobject": map[string]interface{}{
+					"test-bucket||test-backup/test-backup-logs.gz": true,
+				},
+			},
+			expectedErr: false,
+			expectedRes: map[string][]byte{
+				"test-backup/ark-backup.json":    []byte("foo"),
+				"test-backup/test-backup.tar.gz": []byte("bar"),
+			},
+		},
 		{
 			name:         "no such bucket causes error",
 			bucket:       "test-bucket",
@@ -111,7 +148,7 @@ func TestUploadBackup(t *testing.T) {
 
 			backupService := NewBackupService(objStore)
 
-			err := backupService.UploadBackup(test.bucket, test.backupName, test.metadata, test.backup)
+			err := backupService.UploadBackup(test.bucket, test.backupName, test.metadata, test.backup, test.log)
 
 			assert.Equal(t, test.expectedErr, err != nil, "got error %v", err)
 
@@ -121,6 +158,19 @@ func TestUploadBackup(t *testing.T) {
 	}
 }
 
+func TestUploadRestoreResults(t *testing.T) {
+	objStore := &fakeObjectStorage{
+		storage: map[string]map[string][]byte{"test-bucket": {}},
+	}
+
+	backupService := NewBackupService(objStore)
+
+	assert.NoError(t, backupService.UploadRestoreResults("test-bucket", "test-backup", "test-restore", newStringReadSeeker("foo")))
+	assert.Equal(t, map[string][]byte{
+		"test-backup/restore-test-restore-results.json.gz": []byte("foo"),
+	}, objStore.storage["test-bucket"])
+}
+
 func TestDownloadBackup(t *testing.T) {
 	tests := []struct {
 		name        string
@@ -201,6 +251,34 @@ func TestDeleteBackup(t *testing.T) {
 			expectedErr: false,
 			expectedRes: make(map[string][]byte),
 		},
+		{
+			name:       "log file is deleted along with backup",
+			bucket:     "test-bucket",
+			backupName: "bak",
+			storage: map[string]map[string][]byte{
+				"test-bucket": map[string][]byte{
+					"bak/bak.tar.gz":      nil,
+					"bak/ark-backup.json": nil,
+					"bak/bak-logs.gz":     nil,
+				},
+			},
+			expectedErr: false,
+			expectedRes: make(map[string][]byte),
+		},
+		{
+			name:       "item list is deleted along with backup",
+			bucket:     "test-bucket",
+			backupName: "bak",
+			storage: map[string]map[string][]byte{
+				"test-bucket": map[string][]byte{
+					"bak/bak.tar.gz":        nil,
+					"bak/ark-backup.json":   nil,
+					"bak/bak-items.json.gz": nil,
+				},
+			},
+			expectedErr: false,
+			expectedRes: make(map[string][]byte),
+		},
 		{
 			name:       "failed delete of backup doesn't prevent metadata delete but returns error",
 			bucket:     "test-bucket",
@@ -270,6 +348,23 @@ func TestGetAllBackups(t *testing.T) {
 				},
 			},
 		},
+		{
+			name:   "directories without metadata, like the logs of failed backups, are skipped",
+			bucket: "test-bucket",
+			storage: map[string]map[string][]byte{
+				"test-bucket": map[string][]byte{
+					"backup-1/ark-backup.json": encodeToBytes(&api.Backup{ObjectMeta: metav1.ObjectMeta{Name: "backup-1"}}),
+					"failed/failed-logs.gz":    []byte("log"),
+				},
+			},
+			expectedErr: false,
+			expectedRes: []*api.Backup{
+				&api.Backup{
+					TypeMeta:   metav1.TypeMeta{Kind: "Backup", APIVersion: "ark.heptio.com/v1"},
+					ObjectMeta: metav1.ObjectMeta{Name: "backup-1"},
+				},
+			},
+		},
 		{
 			name:   "decode error returns nil/error",
 			bucket: "test-bucket",
@@ -297,6 +392,70 @@ func TestGetAllBackups(t *testing.T) {
 	}
 }
 
+func TestCreateSignedURL(t *testing.T) {
+	tests := []struct {
+		name        string
+		target      api.DownloadTarget
+		backupName  string
+		expectedURL string
+		expectedErr bool
+	}{
+		{
+			name:        "backup contents",
+			target:      api.DownloadTarget{Kind: api.DownloadTargetKindBackupContents, Name: "backup-1"},
+			backupName:  "backup-1",
+			expectedURL: "https://test-bucket/backup-1/backup-1.tar.gz?ttl=10m0s",
+		},
+		{
+			name:        "backup log",
+			target:      api.DownloadTarget{Kind: api.DownloadTargetKindBackupLog, Name: "backup-1"},
+			backupName:  "backup-1",
+			expectedURL: "https://test-bucket/backup-1/backup-1-logs.gz?ttl=10m0s",
+		},
+		{
+			name:        "backup item list",
+			target:      api.DownloadTarget{Kind: api.DownloadTargetKindBackupItems, Name: "backup-1"},
+			backupName:  "backup-1",
+			expectedURL: "https://test-bucket/backup-1/backup-1-items.json.gz?ttl=10m0s",
+		},
+		{
+			name:        "restore log",
+			target:      api.DownloadTarget{Kind: api.DownloadTargetKindRestoreLog, Name: "restore-1"},
+			backupName:  "backup-1",
+			expectedURL: "https://test-bucket/backup-1/restore-restore-1-logs.gz?ttl=10m0s",
+		},
+		{
+			name:        "restore plan",
+			target:      api.DownloadTarget{Kind: api.DownloadTargetKindRestorePlan, Name: "restore-1"},
+			backupName:  "backup-1",
+			expectedURL: "https://test-bucket/backup-1/restore-restore-1-plan.json.gz?ttl=10m0s",
+		},
+		{
+			name:        "restore results",
+			target:      api.DownloadTarget{Kind: api.DownloadTargetKindRestoreResults, Name: "restore-1"},
+			backupName:  "backup-1",
+			expectedURL: "https://test-bucket/backup-1/restore-restore-1-results.json.gz?ttl=10m0s",
+		},
+		{
+			name:        "unknown kind",
+			target:      api.DownloadTarget{Kind: "foo", Name: "backup-1"},
+			backupName:  "backup-1",
+			expectedErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			backupService := NewBackupService(&fakeObjectStorage{})
+
+			url, err := backupService.CreateSignedURL(test.target, "test-bucket", test.backupName, 10*time.Minute)
+
+			assert.Equal(t, test.expectedErr, err != nil, "got error %v", err)
+			assert.Equal(t, test.expectedURL, url)
+		})
+	}
+}
+
 func jsonMarshal(obj interface{}) []byte {
 	res, err := json.Marshal(obj)
 	if err != nil {
@@ -360,13 +519,13 @@ func (os *fakeObjectStorage) GetObject(bucket string, key string) (io.ReadCloser
 	}
 
 	if os.storage[bucket][key] == nil {
-		return nil, errors.New("key not found")
+		return nil, NewObjectNotFoundError(bucket, key)
 	}
 
 	return ioutil.NopCloser(bytes.NewReader(os.storage[bucket][key])), nil
 }
 
-func (os *fakeObjectStorage) ListCommonPrefixes(bucket string, delimiter string) ([]string, error) {
+func (os *fakeObjectStorage) ListCommonPrefixes(bucket, prefix, delimiter string) ([]string, error) {
 	if os.storage == nil {
 		return nil, errors.New("storage not initialized")
 	}
@@ -377,6 +536,10 @@ func (os *fakeObjectStorage) ListCommonPrefixes(bucket string, delimiter string)
 	prefixes := sets.NewString()
 
 	for key := range os.storage[bucket] {
+		if !strings.HasPrefix(key, prefix) {
+			continue
+		}
+
 		delimIdx := strings.LastIndex(key, delimiter)
 
 		if delimIdx == -1 {
@@ -405,3 +568,7 @@ func (os *fakeObjectStorage) DeleteObject(bucket string, key string) error {
 
 	return nil
 }
+
+func (os *fakeObjectStorage) CreateSignedURL(bucket, key string, ttl time.Duration) (string, error) {
+	return fmt.Sprintf("https://%s/%s?ttl=%s", bucket, key, ttl), nil
+}
@@ -0,0 +1,347 @@
+/*
+Copyright 2017 Heptio Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloudprovider
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+
+	"k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	api "github.com/heptio/ark/pkg/apis/ark/v1"
+)
+
+const (
+	// chunkDir is the top-level "directory" in a bucket where deduplicated backup content
+	// is stored.
+	chunkDir                 string = ".ark-chunks"
+	chunkFileFormatString    string = chunkDir + "/%s"
+	manifestFileFormatString string = "%s/%s-manifest.json"
+)
+
+// backupManifest lists the files in a deduplicated backup tarball, in order, along with the
+// content-addressed chunks holding their data.
+type backupManifest struct {
+	Files []manifestFile `json:"files"`
+}
+
+type manifestFile struct {
+	Name    string    `json:"name"`
+	Mode    int64     `json:"mode"`
+	ModTime time.Time `json:"modTime"`
+	Size    int64     `json:"size"`
+	Chunk   string    `json:"chunk"`
+}
+
+// dedupBackupService is a BackupService that stores the contents of each backup tarball as a
+// set of content-addressed chunks shared by all backups in the bucket, plus a per-backup
+// manifest. Files that are identical across backups are only stored once.
+type dedupBackupService struct {
+	*backupService
+
+	// chunksLock keeps chunks from being deleted while uploads may be referencing them: uploads
+	// hold it for reading, from when they list the existing chunks until their manifests are
+	// stored, and deletions hold it for writing while they find and delete unreferenced chunks.
+	// Only the server leading the cluster uploads and deletes backups, so it's enough to guard
+	// against its own uploads.
+	chunksLock sync.RWMutex
+}
+
+var _ BackupService = &dedupBackupService{}
+
+// NewDeduplicatingBackupService creates a backup service that deduplicates backup contents
+// across all backups in a bucket, using the provided object storage adapter. Backups that were
+// uploaded without deduplication can still be downloaded and deleted.
+func NewDeduplicatingBackupService(objectStorage ObjectStorageAdapter) BackupService {
+	return &dedupBackupService{
+		backupService: &backupService{
+			objectStorage: objectStorage,
+		},
+	}
+}
+
+func (s *dedupBackupService) UploadBackup(bucket, backupName string, metadata, backup, log io.ReadSeeker) error {
+	s.chunksLock.RLock()
+	defer s.chunksLock.RUnlock()
+
+	existingChunks, err := s.referencedChunks(bucket, "")
+	if err != nil {
+		return err
+	}
+
+	manifest, err := s.uploadChunks(bucket, backup, existingChunks)
+	if err != nil {
+		return err
+	}
+
+	manifestBytes, err := json.Marshal(manifest)
+	if err != nil {
+		return err
+	}
+
+	return s.uploadBackupFiles(bucket, backupName, metadata, fmt.Sprintf(manifestFileFormatString, backupName, backupName), bytes.NewReader(manifestBytes), log)
+}
+
+// uploadChunks splits the gzip-compressed backup tarball into one chunk per file, uploads any
+// chunks that aren't in existingChunks, and returns the backup's manifest.
+func (s *dedupBackupService) uploadChunks(bucket string, backup io.Reader, existingChunks sets.String) (*backupManifest, error) {
+	gzr, err := gzip.NewReader(backup)
+	if err != nil {
+		return nil, err
+	}
+	defer gzr.Close()
+
+	manifest := &backupManifest{}
+	uploaded := 0
+
+	tr := tar.NewReader(gzr)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if header.Typeflag != tar.TypeReg {
+			return nil, fmt.Errorf("unsupported type %q for file %s in backup", header.Typeflag, header.Name)
+		}
+
+		data, err := ioutil.ReadAll(tr)
+		if err != nil {
+			return nil, err
+		}
+
+		sum := sha256.Sum256(data)
+		chunk := hex.EncodeToString(sum[:])
+
+		if !existingChunks.Has(chunk) {
+			buf := new(bytes.Buffer)
+			gzw := gzip.NewWriter(buf)
+			if _, err := gzw.Write(data); err != nil {
+				return nil, err
+			}
+			if err := gzw.Close(); err != nil {
+				return nil, err
+			}
+
+			if err := s.objectStorage.PutObject(bucket, fmt.Sprintf(chunkFileFormatString, chunk), bytes.NewReader(buf.Bytes())); err != nil {
+				return nil, err
+			}
+			existingChunks.Insert(chunk)
+			uploaded++
+		}
+
+		manifest.Files = append(manifest.Files, manifestFile{
+			Name:    header.Name,
+			Mode:    header.Mode,
+			ModTime: header.ModTime,
+			Size:    int64(len(data)),
+			Chunk:   chunk,
+		})
+	}
+
+	glog.V(4).Infof("Uploaded %d new chunks for %d files", uploaded, len(manifest.Files))
+
+	return manifest, nil
+}
+
+func (s *dedupBackupService) DownloadBackup(bucket, backupName string) (io.ReadCloser, error) {
+	manifest, err := s.getManifest(bucket, backupName)
+	if err != nil {
+		return nil, err
+	}
+	if manifest == nil {
+		// backup was uploaded without deduplication
+		return s.backupService.DownloadBackup(bucket, backupName)
+	}
+
+	// reassemble the tarball from its chunks as it's being read.
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(s.writeTarball(bucket, manifest, pw))
+	}()
+
+	return pr, nil
+}
+
+func (s *dedupBackupService) CreateSignedURL(target api.DownloadTarget, bucket, backupName string, ttl time.Duration) (string, error) {
+	if target.Kind == api.DownloadTargetKindBackupContents {
+		manifest, err := s.getManifest(bucket, backupName)
+		if err != nil {
+			return "", err
+		}
+		if manifest != nil {
+			return "", fmt.Errorf("backup %s is deduplicated, so its contents are stored in chunks that can't be downloaded from a single URL", backupName)
+		}
+	}
+
+	return s.backupService.CreateSignedURL(target, bucket, backupName, ttl)
+}
+
+// writeTarball writes a gzip-compressed tarball containing the files in manifest to w.
+func (s *dedupBackupService) writeTarball(bucket string, manifest *backupManifest, w io.Writer) error {
+	gzw := gzip.NewWriter(w)
+	tw := tar.NewWriter(gzw)
+
+	for _, file := range manifest.Files {
+		hdr := &tar.Header{
+			Name:     file.Name,
+			Size:     file.Size,
+			Typeflag: tar.TypeReg,
+			Mode:     file.Mode,
+			ModTime:  file.ModTime,
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+
+		if err := s.copyChunk(bucket, file.Chunk, tw); err != nil {
+			return fmt.Errorf("error reading chunk %s for file %s: %v", file.Chunk, file.Name, err)
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gzw.Close()
+}
+
+// copyChunk writes the decompressed contents of chunk to w.
+func (s *dedupBackupService) copyChunk(bucket, chunk string, w io.Writer) error {
+	res, err := s.objectStorage.GetObject(bucket, fmt.Sprintf(chunkFileFormatString, chunk))
+	if err != nil {
+		return err
+	}
+	defer res.Close()
+
+	gzr, err := gzip.NewReader(res)
+	if err != nil {
+		return err
+	}
+	defer gzr.Close()
+
+	_, err = io.Copy(w, gzr)
+	return err
+}
+
+func (s *dedupBackupService) DeleteBackup(bucket, backupName string) error {
+	manifest, err := s.getManifest(bucket, backupName)
+	if err != nil {
+		return err
+	}
+	if manifest == nil {
+		// backup was uploaded without deduplication
+		return s.backupService.DeleteBackup(bucket, backupName)
+	}
+
+	s.chunksLock.Lock()
+	defer s.chunksLock.Unlock()
+
+	// find the chunks other backups reference before deleting the manifest, so that if they
+	// can't be found, the deletion can be retried without losing track of the backup's chunks.
+	stillReferenced, err := s.referencedChunks(bucket, backupName)
+	if err != nil {
+		return err
+	}
+
+	if err := s.deleteBackupFiles(bucket, backupName, fmt.Sprintf(manifestFileFormatString, backupName, backupName)); err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, file := range manifest.Files {
+		if stillReferenced.Has(file.Chunk) {
+			continue
+		}
+
+		key := fmt.Sprintf(chunkFileFormatString, file.Chunk)
+		glog.V(4).Infof("Trying to delete bucket=%s, key=%s", bucket, key)
+		if err := s.objectStorage.DeleteObject(bucket, key); err != nil {
+			errs = append(errs, err)
+		}
+		// the same chunk may be used by several files in the backup
+		stillReferenced.Insert(file.Chunk)
+	}
+
+	return errors.NewAggregate(errs)
+}
+
+// getManifest returns the manifest for the backup, or nil if the backup doesn't have one.
+func (s *dedupBackupService) getManifest(bucket, backupName string) (*backupManifest, error) {
+	res, err := s.objectStorage.GetObject(bucket, fmt.Sprintf(manifestFileFormatString, backupName, backupName))
+	if IsObjectNotFound(err) {
+		glog.V(4).Infof("No manifest found for backup %s", backupName)
+		return nil, nil
+	}
+	if err != nil {
+		// any other error may hide a manifest, whose chunks mustn't be mistaken for
+		// unreferenced ones.
+		return nil, fmt.Errorf("error getting manifest for backup %s: %v", backupName, err)
+	}
+	defer res.Close()
+
+	manifest := &backupManifest{}
+	if err := json.NewDecoder(res).Decode(manifest); err != nil {
+		return nil, fmt.Errorf("error decoding manifest for backup %s: %v", backupName, err)
+	}
+
+	return manifest, nil
+}
+
+// referencedChunks returns the set of chunks referenced by the manifests of all backups in the
+// bucket other than except.
+func (s *dedupBackupService) referencedChunks(bucket, except string) (sets.String, error) {
+	prefixes, err := s.objectStorage.ListCommonPrefixes(bucket, "", "/")
+	if err != nil {
+		return nil, err
+	}
+
+	chunks := sets.NewString()
+	for _, backupName := range prefixes {
+		if isReservedDir(backupName) || backupName == except {
+			continue
+		}
+
+		manifest, err := s.getManifest(bucket, backupName)
+		if err != nil {
+			return nil, err
+		}
+		if manifest == nil {
+			continue
+		}
+
+		for _, file := range manifest.Files {
+			chunks.Insert(file.Chunk)
+		}
+	}
+
+	return chunks, nil
+}
@@ -0,0 +1,256 @@
+/*
+Copyright 2017 Heptio Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloudprovider
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	api "github.com/heptio/ark/pkg/apis/ark/v1"
+)
+
+type tarFile struct {
+	name string
+	data string
+}
+
+func newTarball(t *testing.T, files ...tarFile) []byte {
+	buf := new(bytes.Buffer)
+	gzw := gzip.NewWriter(buf)
+	tw := tar.NewWriter(gzw)
+
+	for _, file := range files {
+		require.NoError(t, tw.WriteHeader(&tar.Header{
+			Name:     file.name,
+			Size:     int64(len(file.data)),
+			Typeflag: tar.TypeReg,
+			Mode:     0755,
+			ModTime:  time.Now(),
+		}))
+		_, err := tw.Write([]byte(file.data))
+		require.NoError(t, err)
+	}
+
+	require.NoError(t, tw.Close())
+	require.NoError(t, gzw.Close())
+
+	return buf.Bytes()
+}
+
+func readTarball(t *testing.T, r io.Reader) []tarFile {
+	gzr, err := gzip.NewReader(r)
+	require.NoError(t, err)
+	defer gzr.Close()
+
+	var files []tarFile
+	tr := tar.NewReader(gzr)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+
+		data, err := ioutil.ReadAll(tr)
+		require.NoError(t, err)
+		files = append(files, tarFile{name: header.Name, data: string(data)})
+	}
+
+	return files
+}
+
+func chunkCount(storage map[string][]byte) int {
+	count := 0
+	for key := range storage {
+		if strings.HasPrefix(key, chunkDir+"/") {
+			count++
+		}
+	}
+	return count
+}
+
+func TestDeduplicatingBackupService(t *testing.T) {
+	objStore := &fakeObjectStorage{
+		storage: map[string]map[string][]byte{
+			"bucket": make(map[string][]byte),
+		},
+	}
+	backupService := NewDeduplicatingBackupService(objStore)
+
+	backup1Files := []tarFile{
+		{name: "namespaces/a/configmaps/shared.json", data: "shared"},
+		{name: "namespaces/a/configmaps/only-in-1.json", data: "one"},
+		{name: "namespaces/b/configmaps/shared.json", data: "shared"},
+	}
+	backup2Files := []tarFile{
+		{name: "namespaces/a/configmaps/shared.json", data: "shared"},
+		{name: "namespaces/a/configmaps/only-in-2.json", data: "two"},
+	}
+
+	require.NoError(t, backupService.UploadBackup("bucket", "backup-1", newStringReadSeeker("{}"), bytes.NewReader(newTarball(t, backup1Files...)), nil))
+	assert.Equal(t, 2, chunkCount(objStore.storage["bucket"]))
+
+	require.NoError(t, backupService.UploadBackup("bucket", "backup-2", newStringReadSeeker("{}"), bytes.NewReader(newTarball(t, backup2Files...)), nil))
+	assert.Equal(t, 3, chunkCount(objStore.storage["bucket"]))
+
+	// the backup tarballs themselves aren't stored
+	assert.NotContains(t, objStore.storage["bucket"], "backup-1/backup-1.tar.gz")
+	assert.Contains(t, objStore.storage["bucket"], "backup-1/backup-1-manifest.json")
+	assert.Contains(t, objStore.storage["bucket"], "backup-1/ark-backup.json")
+
+	res, err := backupService.DownloadBackup("bucket", "backup-1")
+	require.NoError(t, err)
+	assert.Equal(t, backup1Files, readTarball(t, res))
+	res.Close()
+
+	res, err = backupService.DownloadBackup("bucket", "backup-2")
+	require.NoError(t, err)
+	assert.Equal(t, backup2Files, readTarball(t, res))
+	res.Close()
+
+	// chunks still used by backup-2 are kept
+	require.NoError(t, backupService.DeleteBackup("bucket", "backup-1"))
+	assert.Equal(t, 2, chunkCount(objStore.storage["bucket"]))
+	assert.NotContains(t, objStore.storage["bucket"], "backup-1/backup-1-manifest.json")
+
+	res, err = backupService.DownloadBackup("bucket", "backup-2")
+	require.NoError(t, err)
+	assert.Equal(t, backup2Files, readTarball(t, res))
+	res.Close()
+
+	// deduplicated contents can't be downloaded directly, but logs can
+	_, err = backupService.CreateSignedURL(api.DownloadTarget{Kind: api.DownloadTargetKindBackupContents, Name: "backup-2"}, "bucket", "backup-2", time.Minute)
+	assert.Error(t, err)
+	url, err := backupService.CreateSignedURL(api.DownloadTarget{Kind: api.DownloadTargetKindBackupLog, Name: "backup-2"}, "bucket", "backup-2", time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, "https://bucket/backup-2/backup-2-logs.gz?ttl=1m0s", url)
+
+	require.NoError(t, backupService.DeleteBackup("bucket", "backup-2"))
+	assert.Empty(t, objStore.storage["bucket"])
+}
+
+func TestDeduplicatingBackupServiceReadsNonDeduplicatedBackups(t *testing.T) {
+	files := []tarFile{{name: "cluster/persistentvolumes/pv-1.json", data: "pv"}}
+
+	objStore := &fakeObjectStorage{
+		storage: map[string]map[string][]byte{
+			"bucket": {
+				"backup-1/ark-backup.json":  []byte("{}"),
+				"backup-1/backup-1.tar.gz":  newTarball(t, files...),
+				"backup-1/backup-1-logs.gz": []byte("logs"),
+			},
+		},
+	}
+	backupService := NewDeduplicatingBackupService(objStore)
+
+	res, err := backupService.DownloadBackup("bucket", "backup-1")
+	require.NoError(t, err)
+	assert.Equal(t, files, readTarball(t, res))
+	res.Close()
+
+	url, err := backupService.CreateSignedURL(api.DownloadTarget{Kind: api.DownloadTargetKindBackupContents, Name: "backup-1"}, "bucket", "backup-1", time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, "https://bucket/backup-1/backup-1.tar.gz?ttl=1m0s", url)
+
+	require.NoError(t, backupService.DeleteBackup("bucket", "backup-1"))
+	assert.Empty(t, objStore.storage["bucket"])
+}
+
+// unreadableObjectStorage is a fakeObjectStorage whose GetObject fails for the given keys with
+// errors other than not found.
+type unreadableObjectStorage struct {
+	*fakeObjectStorage
+	unreadable map[string]bool
+}
+
+func (os *unreadableObjectStorage) GetObject(bucket string, key string) (io.ReadCloser, error) {
+	if os.unreadable[key] {
+		return nil, errors.New("connection reset by peer")
+	}
+	return os.fakeObjectStorage.GetObject(bucket, key)
+}
+
+func TestDeduplicatingBackupServiceKeepsChunksWhenManifestsCantBeRead(t *testing.T) {
+	objStore := &unreadableObjectStorage{
+		fakeObjectStorage: &fakeObjectStorage{
+			storage: map[string]map[string][]byte{
+				"bucket": make(map[string][]byte),
+			},
+		},
+		unreadable: make(map[string]bool),
+	}
+	backupService := NewDeduplicatingBackupService(objStore)
+
+	files := []tarFile{{name: "namespaces/a/configmaps/shared.json", data: "shared"}}
+	require.NoError(t, backupService.UploadBackup("bucket", "backup-1", newStringReadSeeker("{}"), bytes.NewReader(newTarball(t, files...)), nil))
+	require.NoError(t, backupService.UploadBackup("bucket", "backup-2", newStringReadSeeker("{}"), bytes.NewReader(newTarball(t, files...)), nil))
+
+	// backup-2's manifest can't be read, so it can't be known whether its chunks are still used
+	objStore.unreadable["backup-2/backup-2-manifest.json"] = true
+	assert.Error(t, backupService.DeleteBackup("bucket", "backup-1"))
+	assert.Equal(t, 1, chunkCount(objStore.storage["bucket"]))
+
+	// nor can backup-2 be mistaken for a backup that isn't deduplicated
+	assert.Error(t, backupService.DeleteBackup("bucket", "backup-2"))
+	_, err := backupService.DownloadBackup("bucket", "backup-2")
+	assert.Error(t, err)
+	assert.Contains(t, objStore.storage["bucket"], "backup-2/ark-backup.json")
+
+	objStore.unreadable = nil
+	require.NoError(t, backupService.DeleteBackup("bucket", "backup-1"))
+	require.NoError(t, backupService.DeleteBackup("bucket", "backup-2"))
+	assert.Empty(t, objStore.storage["bucket"])
+}
+
+func TestDeduplicatingBackupServiceDeletionWaitsForUploads(t *testing.T) {
+	objStore := &fakeObjectStorage{
+		storage: map[string]map[string][]byte{
+			"bucket": make(map[string][]byte),
+		},
+	}
+	backupService := NewDeduplicatingBackupService(objStore).(*dedupBackupService)
+
+	files := []tarFile{{name: "namespaces/a/configmaps/shared.json", data: "shared"}}
+	require.NoError(t, backupService.UploadBackup("bucket", "backup-1", newStringReadSeeker("{}"), bytes.NewReader(newTarball(t, files...)), nil))
+
+	// while an upload holds the lock, having found backup-1's chunk and not yet stored its
+	// own manifest, deleting backup-1 has to wait
+	backupService.chunksLock.RLock()
+	deleted := make(chan error)
+	go func() {
+		deleted <- backupService.DeleteBackup("bucket", "backup-1")
+	}()
+
+	select {
+	case <-deleted:
+		t.Fatal("backup was deleted during an upload")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	backupService.chunksLock.RUnlock()
+	require.NoError(t, <-deleted)
+}
@@ -19,6 +19,7 @@ package gcp
 import (
 	"errors"
 	"fmt"
+	"net/http"
 	"strings"
 	"time"
 
@@ -26,6 +27,7 @@ import (
 	"golang.org/x/oauth2"
 	"golang.org/x/oauth2/google"
 	"google.golang.org/api/compute/v0.beta"
+	"google.golang.org/api/googleapi"
 
 	"k8s.io/apimachinery/pkg/util/wait"
 
@@ -189,5 +191,10 @@ func (op *blockStorageAdapter) CreateSnapshot(volumeID string, tags map[string]s
 func (op *blockStorageAdapter) DeleteSnapshot(snapshotID string) error {
 	_, err := op.gce.Snapshots.Delete(op.project, snapshotID).Do()
 
+	// a snapshot that's already gone doesn't need deleting
+	if gceErr, ok := err.(*googleapi.Error); ok && gceErr.Code == http.StatusNotFound {
+		return nil
+	}
+
 	return err
 }
@@ -17,11 +17,26 @@ limitations under the License.
 package gcp
 
 import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
 	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
 	"strings"
+	"time"
 
 	"golang.org/x/oauth2"
 	"golang.org/x/oauth2/google"
+	"google.golang.org/api/googleapi"
 	storage "google.golang.org/api/storage/v1"
 
 	"github.com/heptio/ark/pkg/cloudprovider"
@@ -29,6 +44,11 @@ import (
 
 type objectStorageAdapter struct {
 	gcs *storage.Service
+
+	// googleAccessID and privateKey are the service account credentials used to sign URLs. They're
+	// only set if the server is using a service account key file.
+	googleAccessID string
+	privateKey     *rsa.PrivateKey
 }
 
 var _ cloudprovider.ObjectStorageAdapter = &objectStorageAdapter{}
@@ -44,9 +64,49 @@ func NewObjectStorageAdapter() (cloudprovider.ObjectStorageAdapter, error) {
 		return nil, err
 	}
 
-	return &objectStorageAdapter{
+	op := &objectStorageAdapter{
 		gcs: gcs,
-	}, nil
+	}
+
+	// signing URLs requires a service account's private key, which is only available if the
+	// credentials come from a key file.
+	if credentialsFile := os.Getenv("GOOGLE_APPLICATION_CREDENTIALS"); credentialsFile != "" {
+		jsonKey, err := ioutil.ReadFile(credentialsFile)
+		if err != nil {
+			return nil, err
+		}
+
+		if jwtConfig, err := google.JWTConfigFromJSON(jsonKey); err == nil {
+			privateKey, err := parsePrivateKey(jwtConfig.PrivateKey)
+			if err != nil {
+				return nil, err
+			}
+			op.googleAccessID = jwtConfig.Email
+			op.privateKey = privateKey
+		}
+	}
+
+	return op, nil
+}
+
+// parsePrivateKey parses a PEM-encoded RSA private key in PKCS #8 or PKCS #1 form.
+func parsePrivateKey(data []byte) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("service account private key is not PEM-encoded")
+	}
+
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return x509.ParsePKCS1PrivateKey(block.Bytes)
+	}
+
+	rsaKey, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("service account private key has unexpected type %T", key)
+	}
+
+	return rsaKey, nil
 }
 
 func (op *objectStorageAdapter) PutObject(bucket string, key string, body io.ReadSeeker) error {
@@ -62,14 +122,17 @@ func (op *objectStorageAdapter) PutObject(bucket string, key string, body io.Rea
 func (op *objectStorageAdapter) GetObject(bucket string, key string) (io.ReadCloser, error) {
 	res, err := op.gcs.Objects.Get(bucket, key).Download()
 	if err != nil {
+		if gcsErr, ok := err.(*googleapi.Error); ok && gcsErr.Code == http.StatusNotFound {
+			return nil, cloudprovider.NewObjectNotFoundError(bucket, key)
+		}
 		return nil, err
 	}
 
 	return res.Body, nil
 }
 
-func (op *objectStorageAdapter) ListCommonPrefixes(bucket string, delimiter string) ([]string, error) {
-	res, err := op.gcs.Objects.List(bucket).Delimiter(delimiter).Do()
+func (op *objectStorageAdapter) ListCommonPrefixes(bucket, prefix, delimiter string) ([]string, error) {
+	res, err := op.gcs.Objects.List(bucket).Prefix(prefix).Delimiter(delimiter).Do()
 	if err != nil {
 		return nil, err
 	}
@@ -87,3 +150,28 @@ func (op *objectStorageAdapter) ListCommonPrefixes(bucket string, delimiter stri
 func (op *objectStorageAdapter) DeleteObject(bucket string, key string) error {
 	return op.gcs.Objects.Delete(bucket, key).Do()
 }
+
+// CreateSignedURL creates a V2 signed URL, as described in
+// https://cloud.google.com/storage/docs/access-control/signed-urls.
+func (op *objectStorageAdapter) CreateSignedURL(bucket, key string, ttl time.Duration) (string, error) {
+	if op.privateKey == nil {
+		return "", errors.New("signed URLs require the GOOGLE_APPLICATION_CREDENTIALS environment variable to point to a service account key file")
+	}
+
+	path := (&url.URL{Path: fmt.Sprintf("/%s/%s", bucket, key)}).EscapedPath()
+	expires := time.Now().Add(ttl).Unix()
+
+	digest := sha256.Sum256([]byte(fmt.Sprintf("GET\n\n\n%d\n%s", expires, path)))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, op.privateKey, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", err
+	}
+
+	query := url.Values{
+		"GoogleAccessId": {op.googleAccessID},
+		"Expires":        {fmt.Sprintf("%d", expires)},
+		"Signature":      {base64.StdEncoding.EncodeToString(signature)},
+	}
+
+	return fmt.Sprintf("https://storage.googleapis.com%s?%s", path, query.Encode()), nil
+}
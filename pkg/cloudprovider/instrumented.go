@@ -0,0 +1,130 @@
+/*
+Copyright 2017 Heptio Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloudprovider
+
+import (
+	"io"
+	"time"
+
+	"github.com/heptio/ark/pkg/metrics"
+)
+
+// instrumentedObjectStorage records each call to an ObjectStorageAdapter, and whether it
+// failed, in the server's metrics.
+type instrumentedObjectStorage struct {
+	delegate ObjectStorageAdapter
+	metrics  *metrics.ServerMetrics
+	location string
+}
+
+var _ ObjectStorageAdapter = &instrumentedObjectStorage{}
+
+// NewInstrumentedObjectStorageAdapter returns an ObjectStorageAdapter that records calls to
+// delegate, the provider configured as location, in m.
+func NewInstrumentedObjectStorageAdapter(delegate ObjectStorageAdapter, m *metrics.ServerMetrics, location string) ObjectStorageAdapter {
+	return &instrumentedObjectStorage{
+		delegate: delegate,
+		metrics:  m,
+		location: location,
+	}
+}
+
+func (i *instrumentedObjectStorage) PutObject(bucket string, key string, body io.ReadSeeker) error {
+	err := i.delegate.PutObject(bucket, key, body)
+	i.metrics.RegisterCloudAPIRequest(i.location, "PutObject", err)
+	return err
+}
+
+func (i *instrumentedObjectStorage) GetObject(bucket string, key string) (io.ReadCloser, error) {
+	body, err := i.delegate.GetObject(bucket, key)
+	i.metrics.RegisterCloudAPIRequest(i.location, "GetObject", err)
+	return body, err
+}
+
+func (i *instrumentedObjectStorage) ListCommonPrefixes(bucket, prefix, delimiter string) ([]string, error) {
+	prefixes, err := i.delegate.ListCommonPrefixes(bucket, prefix, delimiter)
+	i.metrics.RegisterCloudAPIRequest(i.location, "ListCommonPrefixes", err)
+	return prefixes, err
+}
+
+func (i *instrumentedObjectStorage) DeleteObject(bucket string, key string) error {
+	err := i.delegate.DeleteObject(bucket, key)
+	i.metrics.RegisterCloudAPIRequest(i.location, "DeleteObject", err)
+	return err
+}
+
+func (i *instrumentedObjectStorage) CreateSignedURL(bucket, key string, ttl time.Duration) (string, error) {
+	url, err := i.delegate.CreateSignedURL(bucket, key, ttl)
+	i.metrics.RegisterCloudAPIRequest(i.location, "CreateSignedURL", err)
+	return url, err
+}
+
+// instrumentedBlockStorage records each call to a BlockStorageAdapter, and whether it failed,
+// in the server's metrics.
+type instrumentedBlockStorage struct {
+	delegate BlockStorageAdapter
+	metrics  *metrics.ServerMetrics
+	location string
+}
+
+var _ BlockStorageAdapter = &instrumentedBlockStorage{}
+
+// NewInstrumentedBlockStorageAdapter returns a BlockStorageAdapter that records calls to
+// delegate, the provider configured as location, in m.
+func NewInstrumentedBlockStorageAdapter(delegate BlockStorageAdapter, m *metrics.ServerMetrics, location string) BlockStorageAdapter {
+	return &instrumentedBlockStorage{
+		delegate: delegate,
+		metrics:  m,
+		location: location,
+	}
+}
+
+func (i *instrumentedBlockStorage) CreateVolumeFromSnapshot(snapshotID, volumeType string, iops *int64) (string, error) {
+	volumeID, err := i.delegate.CreateVolumeFromSnapshot(snapshotID, volumeType, iops)
+	i.metrics.RegisterCloudAPIRequest(i.location, "CreateVolumeFromSnapshot", err)
+	return volumeID, err
+}
+
+func (i *instrumentedBlockStorage) GetVolumeInfo(volumeID string) (string, *int64, error) {
+	volumeType, iops, err := i.delegate.GetVolumeInfo(volumeID)
+	i.metrics.RegisterCloudAPIRequest(i.location, "GetVolumeInfo", err)
+	return volumeType, iops, err
+}
+
+func (i *instrumentedBlockStorage) IsVolumeReady(volumeID string) (bool, error) {
+	ready, err := i.delegate.IsVolumeReady(volumeID)
+	i.metrics.RegisterCloudAPIRequest(i.location, "IsVolumeReady", err)
+	return ready, err
+}
+
+func (i *instrumentedBlockStorage) ListSnapshots(tagFilters map[string]string) ([]string, error) {
+	snapshotIDs, err := i.delegate.ListSnapshots(tagFilters)
+	i.metrics.RegisterCloudAPIRequest(i.location, "ListSnapshots", err)
+	return snapshotIDs, err
+}
+
+func (i *instrumentedBlockStorage) CreateSnapshot(volumeID string, tags map[string]string) (string, error) {
+	snapshotID, err := i.delegate.CreateSnapshot(volumeID, tags)
+	i.metrics.RegisterCloudAPIRequest(i.location, "CreateSnapshot", err)
+	return snapshotID, err
+}
+
+func (i *instrumentedBlockStorage) DeleteSnapshot(snapshotID string) error {
+	err := i.delegate.DeleteSnapshot(snapshotID)
+	i.metrics.RegisterCloudAPIRequest(i.location, "DeleteSnapshot", err)
+	return err
+}
@@ -0,0 +1,468 @@
+/*
+Copyright 2017 Heptio Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloudprovider
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	api "github.com/heptio/ark/pkg/apis/ark/v1"
+)
+
+// bucketRoutingBackupService is a BackupService that uses a different BackupService for each of
+// the buckets of the server's backup storage locations, which may be in different regions or
+// clouds.
+type bucketRoutingBackupService struct {
+	defaultService BackupService
+	services       map[string]BackupService
+}
+
+// NewBucketRoutingBackupService returns a BackupService that uses the BackupService in services
+// for each bucket that has one, and defaultService for the rest.
+func NewBucketRoutingBackupService(defaultService BackupService, services map[string]BackupService) BackupService {
+	return &bucketRoutingBackupService{
+		defaultService: defaultService,
+		services:       services,
+	}
+}
+
+func (s *bucketRoutingBackupService) service(bucket string) BackupService {
+	if service, ok := s.services[bucket]; ok {
+		return service
+	}
+	return s.defaultService
+}
+
+func (s *bucketRoutingBackupService) GetAllBackups(bucket string) ([]*api.Backup, error) {
+	return s.service(bucket).GetAllBackups(bucket)
+}
+
+func (s *bucketRoutingBackupService) UploadBackup(bucket, name string, metadata, backup, log io.ReadSeeker) error {
+	return s.service(bucket).UploadBackup(bucket, name, metadata, backup, log)
+}
+
+func (s *bucketRoutingBackupService) UploadBackupLog(bucket, name string, log io.ReadSeeker) error {
+	return s.service(bucket).UploadBackupLog(bucket, name, log)
+}
+
+func (s *bucketRoutingBackupService) UploadBackupItemList(bucket, name string, items io.ReadSeeker) error {
+	return s.service(bucket).UploadBackupItemList(bucket, name, items)
+}
+
+func (s *bucketRoutingBackupService) UploadRestoreLog(bucket, backupName, restoreName string, log io.ReadSeeker) error {
+	return s.service(bucket).UploadRestoreLog(bucket, backupName, restoreName, log)
+}
+
+func (s *bucketRoutingBackupService) UploadRestorePlan(bucket, backupName, restoreName string, plan io.ReadSeeker) error {
+	return s.service(bucket).UploadRestorePlan(bucket, backupName, restoreName, plan)
+}
+
+func (s *bucketRoutingBackupService) UploadRestoreResults(bucket, backupName, restoreName string, results io.ReadSeeker) error {
+	return s.service(bucket).UploadRestoreResults(bucket, backupName, restoreName, results)
+}
+
+func (s *bucketRoutingBackupService) DownloadBackup(bucket, name string) (io.ReadCloser, error) {
+	return s.service(bucket).DownloadBackup(bucket, name)
+}
+
+func (s *bucketRoutingBackupService) DownloadBackupLogs(bucket, name string) (io.ReadCloser, error) {
+	return s.service(bucket).DownloadBackupLogs(bucket, name)
+}
+
+func (s *bucketRoutingBackupService) DeleteBackup(bucket, backupName string) error {
+	return s.service(bucket).DeleteBackup(bucket, backupName)
+}
+
+func (s *bucketRoutingBackupService) GetBackup(bucket, name string) (*api.Backup, error) {
+	return s.service(bucket).GetBackup(bucket, name)
+}
+
+func (s *bucketRoutingBackupService) CreateSignedURL(target api.DownloadTarget, bucket, backupName string, ttl time.Duration) (string, error) {
+	return s.service(bucket).CreateSignedURL(target, bucket, backupName, ttl)
+}
+
+// ErrReadOnlyLocation is returned when writing to, or deleting from, a read-only backup storage
+// location.
+var ErrReadOnlyLocation = errors.New("backup storage location is read-only")
+
+// readOnlyBackupService is a BackupService for a read-only backup storage location, which returns
+// ErrReadOnlyLocation instead of writing to or deleting from it.
+type readOnlyBackupService struct {
+	BackupService
+}
+
+// NewReadOnlyBackupService returns a BackupService that gets and downloads backups using delegate,
+// but never writes to or deletes from object storage.
+func NewReadOnlyBackupService(delegate BackupService) BackupService {
+	return &readOnlyBackupService{BackupService: delegate}
+}
+
+func (s *readOnlyBackupService) UploadBackup(bucket, name string, metadata, backup, log io.ReadSeeker) error {
+	return ErrReadOnlyLocation
+}
+
+func (s *readOnlyBackupService) UploadBackupLog(bucket, name string, log io.ReadSeeker) error {
+	return ErrReadOnlyLocation
+}
+
+func (s *readOnlyBackupService) UploadBackupItemList(bucket, name string, items io.ReadSeeker) error {
+	return ErrReadOnlyLocation
+}
+
+func (s *readOnlyBackupService) UploadRestoreLog(bucket, backupName, restoreName string, log io.ReadSeeker) error {
+	return ErrReadOnlyLocation
+}
+
+func (s *readOnlyBackupService) UploadRestorePlan(bucket, backupName, restoreName string, plan io.ReadSeeker) error {
+	return ErrReadOnlyLocation
+}
+
+func (s *readOnlyBackupService) UploadRestoreResults(bucket, backupName, restoreName string, results io.ReadSeeker) error {
+	return ErrReadOnlyLocation
+}
+
+func (s *readOnlyBackupService) DeleteBackup(bucket, backupName string) error {
+	return ErrReadOnlyLocation
+}
+
+// IsReadOnly returns whether service's backups in bucket are in a read-only backup storage
+// location.
+func IsReadOnly(service BackupService, bucket string) bool {
+	switch s := service.(type) {
+	case *readOnlyBackupService:
+		return true
+	case *bucketRoutingBackupService:
+		return IsReadOnly(s.service(bucket), bucket)
+	case *cachedBackupService:
+		return IsReadOnly(s.BackupService, bucket)
+	default:
+		return false
+	}
+}
+
+// LocationBucket returns the bucket that backups stored in the given location are recorded as being
+// stored in: the location's bucket, followed by its prefix if it has one. Bucket names can't
+// contain slashes, so it's unique to the location.
+func LocationBucket(config api.ObjectStorageProviderConfig) string {
+	if prefix := strings.Trim(config.Prefix, "/"); prefix != "" {
+		return config.Bucket + "/" + prefix
+	}
+	return config.Bucket
+}
+
+// LocationsOverlap returns whether backups stored in one of the given locations could be seen
+// in the other, because they're in the same bucket and neither is under a prefix that the other
+// isn't also under.
+func LocationsOverlap(a, b api.ObjectStorageProviderConfig) bool {
+	aBucket, bBucket := LocationBucket(a)+"/", LocationBucket(b)+"/"
+	return strings.HasPrefix(aBucket, bBucket) || strings.HasPrefix(bBucket, aBucket)
+}
+
+// prefixedObjectStorage is an ObjectStorageAdapter that stores all of its objects under a prefix
+// within one bucket. The buckets it's called with are ignored, since they're the LocationBucket of
+// its location rather than the name of a bucket in object storage.
+type prefixedObjectStorage struct {
+	delegate ObjectStorageAdapter
+	bucket   string
+	prefix   string
+}
+
+var _ ObjectStorageAdapter = &prefixedObjectStorage{}
+
+// NewPrefixedObjectStorageAdapter returns an ObjectStorageAdapter that stores objects in bucket
+// using delegate, with prefix prepended to their keys.
+func NewPrefixedObjectStorageAdapter(delegate ObjectStorageAdapter, bucket, prefix string) ObjectStorageAdapter {
+	return &prefixedObjectStorage{
+		delegate: delegate,
+		bucket:   bucket,
+		prefix:   strings.Trim(prefix, "/") + "/",
+	}
+}
+
+func (p *prefixedObjectStorage) PutObject(bucket string, key string, body io.ReadSeeker) error {
+	return p.delegate.PutObject(p.bucket, p.prefix+key, body)
+}
+
+func (p *prefixedObjectStorage) GetObject(bucket string, key string) (io.ReadCloser, error) {
+	return p.delegate.GetObject(p.bucket, p.prefix+key)
+}
+
+func (p *prefixedObjectStorage) ListCommonPrefixes(bucket, prefix, delimiter string) ([]string, error) {
+	prefixes, err := p.delegate.ListCommonPrefixes(p.bucket, p.prefix+prefix, delimiter)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range prefixes {
+		prefixes[i] = strings.TrimPrefix(prefixes[i], p.prefix)
+	}
+	return prefixes, nil
+}
+
+func (p *prefixedObjectStorage) DeleteObject(bucket string, key string) error {
+	return p.delegate.DeleteObject(p.bucket, p.prefix+key)
+}
+
+func (p *prefixedObjectStorage) CreateSignedURL(bucket, key string, ttl time.Duration) (string, error) {
+	return p.delegate.CreateSignedURL(p.bucket, p.prefix+key, ttl)
+}
+
+// snapshotServiceWithLocations is a SnapshotService that has the SnapshotServices of the
+// server's volume snapshot locations. Its own methods use the location that snapshots recorded
+// without a location are in, api.LegacyVolumeSnapshotLocation.
+type snapshotServiceWithLocations struct {
+	locations map[string]SnapshotService
+	providers map[string]string
+	defaults  map[string]string
+}
+
+var _ SnapshotService = &snapshotServiceWithLocations{}
+
+// NewSnapshotServiceWithLocations returns a SnapshotService that SnapshotServiceForLocation returns
+// the SnapshotServices in locations for. providers has the name of each location's cloud provider,
+// as returned by ProviderName, and defaults has the name of the default location of each cloud
+// provider that has one.
+func NewSnapshotServiceWithLocations(locations map[string]SnapshotService, providers, defaults map[string]string) SnapshotService {
+	return &snapshotServiceWithLocations{
+		locations: locations,
+		providers: providers,
+		defaults:  defaults,
+	}
+}
+
+func (s *snapshotServiceWithLocations) legacyService() (SnapshotService, error) {
+	if service, ok := s.locations[api.LegacyVolumeSnapshotLocation]; ok {
+		return service, nil
+	}
+	return nil, fmt.Errorf("snapshots taken without a volume snapshot location are in volume snapshot location %q, which isn't configured", api.LegacyVolumeSnapshotLocation)
+}
+
+func (s *snapshotServiceWithLocations) GetAllSnapshots() ([]string, error) {
+	service, err := s.legacyService()
+	if err != nil {
+		return nil, err
+	}
+	return service.GetAllSnapshots()
+}
+
+func (s *snapshotServiceWithLocations) CreateSnapshot(volumeID string) (string, error) {
+	service, err := s.legacyService()
+	if err != nil {
+		return "", err
+	}
+	return service.CreateSnapshot(volumeID)
+}
+
+func (s *snapshotServiceWithLocations) CreateVolumeFromSnapshot(snapshotID, volumeType string, iops *int64) (string, error) {
+	service, err := s.legacyService()
+	if err != nil {
+		return "", err
+	}
+	return service.CreateVolumeFromSnapshot(snapshotID, volumeType, iops)
+}
+
+func (s *snapshotServiceWithLocations) DeleteSnapshot(snapshotID string) error {
+	service, err := s.legacyService()
+	if err != nil {
+		return err
+	}
+	return service.DeleteSnapshot(snapshotID)
+}
+
+func (s *snapshotServiceWithLocations) GetVolumeInfo(volumeID string) (string, *int64, error) {
+	service, err := s.legacyService()
+	if err != nil {
+		return "", nil, err
+	}
+	return service.GetVolumeInfo(volumeID)
+}
+
+// ProviderName returns the name of the cloud provider that config is for: "aws", "gcp", "azure",
+// or the name of its plugin. It returns "" if config has none.
+func ProviderName(config api.CloudProviderConfig) string {
+	switch {
+	case config.AWS != nil:
+		return "aws"
+	case config.GCP != nil:
+		return "gcp"
+	case config.Azure != nil:
+		return "azure"
+	case config.Plugin != nil:
+		return config.Plugin.Name
+	default:
+		return ""
+	}
+}
+
+// DefaultSnapshotLocations returns the name of the default volume snapshot location of each cloud
+// provider that has one, keyed by provider: the one configured in the Config's
+// defaultVolumeSnapshotLocations, if any, or else the provider's only location. It returns an error
+// if a location has no cloud provider, or if a configured default doesn't exist or is for another
+// provider.
+func DefaultSnapshotLocations(locations map[string]*api.VolumeSnapshotLocation, configured map[string]string) (map[string]string, error) {
+	// check the locations in a consistent order, so the same error is always returned
+	names := make([]string, 0, len(locations))
+	for name := range locations {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	providerLocations := make(map[string][]string)
+	for _, name := range names {
+		provider := ProviderName(locations[name].Spec.CloudProviderConfig)
+		if provider == "" {
+			return nil, fmt.Errorf("volume snapshot location %s must specify a cloud provider", name)
+		}
+		providerLocations[provider] = append(providerLocations[provider], name)
+	}
+
+	configuredProviders := make([]string, 0, len(configured))
+	for provider := range configured {
+		configuredProviders = append(configuredProviders, provider)
+	}
+	sort.Strings(configuredProviders)
+
+	defaults := make(map[string]string)
+	for _, provider := range configuredProviders {
+		name := configured[provider]
+		location, ok := locations[name]
+		if !ok {
+			return nil, fmt.Errorf("default volume snapshot location %s for %s doesn't exist", name, provider)
+		}
+		if locationProvider := ProviderName(location.Spec.CloudProviderConfig); locationProvider != provider {
+			return nil, fmt.Errorf("default volume snapshot location %s for %s is for %s", name, provider, locationProvider)
+		}
+		defaults[provider] = name
+	}
+
+	for provider, names := range providerLocations {
+		if _, ok := defaults[provider]; !ok && len(names) == 1 {
+			defaults[provider] = names[0]
+		}
+	}
+
+	return defaults, nil
+}
+
+// volumeSourceProviders maps the PersistentVolume sources that can be snapshotted to the names of
+// the cloud providers whose volumes they are.
+var volumeSourceProviders = map[string]string{
+	"awsElasticBlockStore": "aws",
+	"gcePersistentDisk":    "gcp",
+	"azureDisk":            "azure",
+}
+
+// SnapshotServiceForLocation returns the SnapshotService for service's named volume snapshot
+// location. Snapshots recorded without a location, "", are in api.LegacyVolumeSnapshotLocation. It
+// returns an error if service doesn't have the location.
+func SnapshotServiceForLocation(service SnapshotService, location string) (SnapshotService, error) {
+	withLocations, ok := service.(*snapshotServiceWithLocations)
+	if location == "" {
+		if ok {
+			return withLocations.legacyService()
+		}
+		return service, nil
+	}
+
+	if ok {
+		if locationService, ok := withLocations.locations[location]; ok {
+			return locationService, nil
+		}
+	}
+
+	return nil, fmt.Errorf("volume snapshot location %q isn't configured", location)
+}
+
+// SnapshotServiceForVolume returns the SnapshotService that backup takes the snapshot of a
+// PersistentVolume with the given source, e.g. "awsElasticBlockStore", in, and the name of its
+// volume snapshot location. That's the first of the backup's VolumeSnapshotLocations for the
+// volume's cloud provider, or the provider's default location if none of them is. Backups that only
+// set VolumeSnapshotLocation take all of their snapshots in it. It returns an error if there's no
+// location for the volume.
+func SnapshotServiceForVolume(service SnapshotService, backup *api.Backup, volumeSource string) (SnapshotService, string, error) {
+	if len(backup.Spec.VolumeSnapshotLocations) == 0 && backup.Spec.VolumeSnapshotLocation != "" {
+		locationService, err := SnapshotServiceForLocation(service, backup.Spec.VolumeSnapshotLocation)
+		return locationService, backup.Spec.VolumeSnapshotLocation, err
+	}
+
+	provider := volumeSourceProviders[volumeSource]
+	for _, location := range backup.Spec.VolumeSnapshotLocations {
+		if location == "" {
+			continue
+		}
+		if _, err := SnapshotServiceForLocation(service, location); err != nil {
+			return nil, "", err
+		}
+
+		withLocations := service.(*snapshotServiceWithLocations)
+		if withLocations.providers[location] == provider {
+			return withLocations.locations[location], location, nil
+		}
+	}
+
+	withLocations, ok := service.(*snapshotServiceWithLocations)
+	if !ok {
+		return service, "", nil
+	}
+	if location, ok := withLocations.defaults[provider]; ok {
+		return withLocations.locations[location], location, nil
+	}
+
+	return nil, "", fmt.Errorf("the backup has no volume snapshot location for %s volumes, and there's no default location for them", provider)
+}
+
+// ValidateVolumeSnapshotLocations returns an error if any of backup's volume snapshot locations
+// isn't configured in service, or if more than one of them is for the same cloud provider.
+func ValidateVolumeSnapshotLocations(service SnapshotService, backup *api.Backup) error {
+	if backup.Spec.VolumeSnapshotLocation != "" {
+		if _, err := SnapshotServiceForLocation(service, backup.Spec.VolumeSnapshotLocation); err != nil {
+			return err
+		}
+	}
+
+	locationsByProvider := make(map[string]string)
+	for _, location := range backup.Spec.VolumeSnapshotLocations {
+		if location == "" {
+			continue
+		}
+		if _, err := SnapshotServiceForLocation(service, location); err != nil {
+			return err
+		}
+
+		provider := service.(*snapshotServiceWithLocations).providers[location]
+		if other, ok := locationsByProvider[provider]; ok {
+			return fmt.Errorf("volume snapshot locations %q and %q are both for %s; only one location per cloud provider can be used", other, location, provider)
+		}
+		locationsByProvider[provider] = location
+	}
+
+	return nil
+}
+
+// VolumeSnapshotLocation returns the name of the volume snapshot location that backup took the
+// snapshot described by info in, which is "" for api.LegacyVolumeSnapshotLocation. Backups taken
+// before snapshots' locations were recorded took them all in the backup's VolumeSnapshotLocation.
+func VolumeSnapshotLocation(backup *api.Backup, info *api.VolumeBackupInfo) string {
+	if info.Location != "" || len(backup.Spec.VolumeSnapshotLocations) > 0 {
+		return info.Location
+	}
+	return backup.Spec.VolumeSnapshotLocation
+}
@@ -0,0 +1,412 @@
+/*
+Copyright 2017 Heptio Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloudprovider
+
+import (
+	"bytes"
+	"io/ioutil"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"github.com/heptio/ark/pkg/apis/ark/v1"
+	"github.com/heptio/ark/pkg/util/test"
+)
+
+func TestBucketRoutingBackupService(t *testing.T) {
+	defaultService := &test.FakeBackupService{}
+	otherService := &test.FakeBackupService{}
+
+	defaultBackups := []*v1.Backup{test.NewTestBackup().WithName("backup1").Backup}
+	otherBackups := []*v1.Backup{test.NewTestBackup().WithName("backup2").Backup}
+
+	defaultService.On("GetAllBackups", "bucket").Return(defaultBackups, nil)
+	otherService.On("GetAllBackups", "other-bucket").Return(otherBackups, nil)
+
+	s := NewBucketRoutingBackupService(defaultService, map[string]BackupService{"other-bucket": otherService})
+
+	backups, err := s.GetAllBackups("bucket")
+	require.NoError(t, err)
+	assert.Equal(t, defaultBackups, backups)
+
+	backups, err = s.GetAllBackups("other-bucket")
+	require.NoError(t, err)
+	assert.Equal(t, otherBackups, backups)
+
+	defaultService.AssertExpectations(t)
+	otherService.AssertExpectations(t)
+}
+
+func TestReadOnlyBackupService(t *testing.T) {
+	defaultService := &test.FakeBackupService{}
+	readOnlyService := &test.FakeBackupService{}
+
+	backups := []*v1.Backup{test.NewTestBackup().WithName("backup1").Backup}
+	readOnlyService.On("GetAllBackups", "other-bucket").Return(backups, nil)
+
+	s := NewBucketRoutingBackupService(defaultService, map[string]BackupService{
+		"bucket":       defaultService,
+		"other-bucket": NewReadOnlyBackupService(readOnlyService),
+	})
+
+	res, err := s.GetAllBackups("other-bucket")
+	require.NoError(t, err)
+	assert.Equal(t, backups, res)
+
+	assert.Equal(t, ErrReadOnlyLocation, s.UploadBackupLog("other-bucket", "backup1", nil))
+	assert.Equal(t, ErrReadOnlyLocation, s.DeleteBackup("other-bucket", "backup1"))
+
+	assert.False(t, IsReadOnly(s, "bucket"))
+	assert.True(t, IsReadOnly(s, "other-bucket"))
+	assert.False(t, IsReadOnly(defaultService, "other-bucket"))
+
+	readOnlyService.AssertExpectations(t)
+}
+
+func TestLocationsOverlap(t *testing.T) {
+	location := func(bucket, prefix string) v1.ObjectStorageProviderConfig {
+		return v1.ObjectStorageProviderConfig{Bucket: bucket, Prefix: prefix}
+	}
+
+	tests := []struct {
+		name     string
+		a, b     v1.ObjectStorageProviderConfig
+		expected bool
+	}{
+		{name: "different buckets", a: location("bucket-1", ""), b: location("bucket-2", ""), expected: false},
+		{name: "same bucket", a: location("bucket-1", ""), b: location("bucket-1", ""), expected: true},
+		{name: "same bucket and prefix", a: location("bucket-1", "a/"), b: location("bucket-1", "/a"), expected: true},
+		{name: "different prefixes", a: location("bucket-1", "a"), b: location("bucket-1", "ab"), expected: false},
+		{name: "prefix within the other's", a: location("bucket-1", "a/b"), b: location("bucket-1", "a"), expected: true},
+		{name: "prefix and no prefix", a: location("bucket-1", ""), b: location("bucket-1", "a"), expected: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.expected, LocationsOverlap(test.a, test.b))
+			assert.Equal(t, test.expected, LocationsOverlap(test.b, test.a))
+		})
+	}
+}
+
+func TestPrefixedObjectStorageAdapter(t *testing.T) {
+	delegate := &fakeObjectStorage{storage: map[string]map[string][]byte{
+		"bucket": {"other/backup-2/ark-backup.json": []byte("other")},
+	}}
+	adapter := NewPrefixedObjectStorageAdapter(delegate, "bucket", "cluster-1/")
+
+	// the bucket adapters are called with is the location's, which includes the prefix
+	require.NoError(t, adapter.PutObject("bucket/cluster-1", "backup-1/ark-backup.json", bytes.NewReader([]byte("data"))))
+	assert.Equal(t, []byte("data"), delegate.storage["bucket"]["cluster-1/backup-1/ark-backup.json"])
+
+	prefixes, err := adapter.ListCommonPrefixes("bucket/cluster-1", "", "/")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"backup-1"}, prefixes)
+
+	rc, err := adapter.GetObject("bucket/cluster-1", "backup-1/ark-backup.json")
+	require.NoError(t, err)
+	data, err := ioutil.ReadAll(rc)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("data"), data)
+
+	require.NoError(t, adapter.DeleteObject("bucket/cluster-1", "backup-1/ark-backup.json"))
+	assert.Equal(t, map[string][]byte{"other/backup-2/ark-backup.json": []byte("other")}, delegate.storage["bucket"])
+}
+
+func TestSnapshotServiceForLocation(t *testing.T) {
+	defaultService := &test.FakeSnapshotService{SnapshotsTaken: sets.NewString("snap-1")}
+	eastService := &test.FakeSnapshotService{SnapshotsTaken: sets.NewString("snap-2")}
+	legacyService := &test.FakeSnapshotService{SnapshotsTaken: sets.NewString("snap-3")}
+	withLocations := NewSnapshotServiceWithLocations(
+		map[string]SnapshotService{"east": eastService, "default": legacyService},
+		map[string]string{"east": "aws", "default": "aws"},
+		map[string]string{"aws": "east"},
+	)
+	withoutLegacy := NewSnapshotServiceWithLocations(
+		map[string]SnapshotService{"east": eastService},
+		map[string]string{"east": "aws"},
+		map[string]string{"aws": "east"},
+	)
+
+	tests := []struct {
+		name          string
+		service       SnapshotService
+		location      string
+		expected      SnapshotService
+		expectedError string
+	}{
+		{
+			name:     "default location of a service without locations",
+			service:  defaultService,
+			expected: defaultService,
+		},
+		{
+			name:     "default location of a nil service",
+			service:  nil,
+			expected: nil,
+		},
+		{
+			name:          "named location of a service without locations",
+			service:       defaultService,
+			location:      "east",
+			expectedError: `volume snapshot location "east" isn't configured`,
+		},
+		{
+			name:     "snapshots without a location are in the legacy location",
+			service:  withLocations,
+			expected: legacyService,
+		},
+		{
+			name:          "legacy location that isn't configured",
+			service:       withoutLegacy,
+			expectedError: `snapshots taken without a volume snapshot location are in volume snapshot location "default", which isn't configured`,
+		},
+		{
+			name:     "configured location",
+			service:  withLocations,
+			location: "east",
+			expected: eastService,
+		},
+		{
+			name:          "unconfigured location",
+			service:       withLocations,
+			location:      "west",
+			expectedError: `volume snapshot location "west" isn't configured`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			service, err := SnapshotServiceForLocation(test.service, test.location)
+			if test.expectedError != "" {
+				assert.EqualError(t, err, test.expectedError)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, service)
+		})
+	}
+
+	// the service with locations uses the legacy location for its own methods
+	snapshots, err := withLocations.GetAllSnapshots()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"snap-3"}, snapshots)
+}
+
+func TestDefaultSnapshotLocations(t *testing.T) {
+	aws := &v1.VolumeSnapshotLocation{Spec: v1.VolumeSnapshotLocationSpec{CloudProviderConfig: v1.CloudProviderConfig{AWS: &v1.AWSConfig{}}}}
+	gcp := &v1.VolumeSnapshotLocation{Spec: v1.VolumeSnapshotLocationSpec{CloudProviderConfig: v1.CloudProviderConfig{GCP: &v1.GCPConfig{}}}}
+
+	tests := []struct {
+		name        string
+		locations   map[string]*v1.VolumeSnapshotLocation
+		configured  map[string]string
+		expected    map[string]string
+		expectedErr string
+	}{
+		{
+			name:      "provider's only location is its default",
+			locations: map[string]*v1.VolumeSnapshotLocation{"aws-east": aws, "aws-west": aws, "gcp-east": gcp},
+			expected:  map[string]string{"gcp": "gcp-east"},
+		},
+		{
+			name:       "configured default is used",
+			locations:  map[string]*v1.VolumeSnapshotLocation{"aws-east": aws, "aws-west": aws, "gcp-east": gcp},
+			configured: map[string]string{"aws": "aws-west"},
+			expected:   map[string]string{"aws": "aws-west", "gcp": "gcp-east"},
+		},
+		{
+			name:        "location without a provider is an error",
+			locations:   map[string]*v1.VolumeSnapshotLocation{"aws-east": aws, "none": {}},
+			expectedErr: "volume snapshot location none must specify a cloud provider",
+		},
+		{
+			name:        "configured default that doesn't exist is an error",
+			locations:   map[string]*v1.VolumeSnapshotLocation{"aws-east": aws},
+			configured:  map[string]string{"aws": "aws-west"},
+			expectedErr: "default volume snapshot location aws-west for aws doesn't exist",
+		},
+		{
+			name:        "configured default for another provider is an error",
+			locations:   map[string]*v1.VolumeSnapshotLocation{"aws-east": aws, "gcp-east": gcp},
+			configured:  map[string]string{"aws": "gcp-east"},
+			expectedErr: "default volume snapshot location gcp-east for aws is for gcp",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			defaults, err := DefaultSnapshotLocations(test.locations, test.configured)
+			if test.expectedErr != "" {
+				assert.EqualError(t, err, test.expectedErr)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected, defaults)
+		})
+	}
+}
+
+func TestSnapshotServiceForVolume(t *testing.T) {
+	eastService := &test.FakeSnapshotService{}
+	westService := &test.FakeSnapshotService{}
+	gcpService := &test.FakeSnapshotService{}
+	withLocations := NewSnapshotServiceWithLocations(
+		map[string]SnapshotService{"east": eastService, "west": westService, "gcp-east": gcpService},
+		map[string]string{"east": "aws", "west": "aws", "gcp-east": "gcp"},
+		map[string]string{"aws": "west"},
+	)
+
+	tests := []struct {
+		name             string
+		backup           *v1.Backup
+		volumeSource     string
+		expected         SnapshotService
+		expectedLocation string
+		expectedError    string
+	}{
+		{
+			name:             "backup without locations uses the provider's default location",
+			backup:           test.NewTestBackup().Backup,
+			volumeSource:     "awsElasticBlockStore",
+			expected:         westService,
+			expectedLocation: "west",
+		},
+		{
+			name:             "backup's single location is used for all volumes",
+			backup:           test.NewTestBackup().WithVolumeSnapshotLocation("east").Backup,
+			volumeSource:     "gcePersistentDisk",
+			expected:         eastService,
+			expectedLocation: "east",
+		},
+		{
+			name:             "location for the volume's provider is used",
+			backup:           test.NewTestBackup().WithVolumeSnapshotLocations("east", "gcp-east").Backup,
+			volumeSource:     "gcePersistentDisk",
+			expected:         gcpService,
+			expectedLocation: "gcp-east",
+		},
+		{
+			name:             "provider's default location is used when no location is for the volume's provider",
+			backup:           test.NewTestBackup().WithVolumeSnapshotLocations("gcp-east").Backup,
+			volumeSource:     "awsElasticBlockStore",
+			expected:         westService,
+			expectedLocation: "west",
+		},
+		{
+			name:          "provider without a default location is an error",
+			backup:        test.NewTestBackup().WithVolumeSnapshotLocations("east").Backup,
+			volumeSource:  "azureDisk",
+			expectedError: "the backup has no volume snapshot location for azure volumes, and there's no default location for them",
+		},
+		{
+			name:          "unconfigured location is an error",
+			backup:        test.NewTestBackup().WithVolumeSnapshotLocations("north").Backup,
+			volumeSource:  "awsElasticBlockStore",
+			expectedError: `volume snapshot location "north" isn't configured`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			service, location, err := SnapshotServiceForVolume(withLocations, test.backup, test.volumeSource)
+			if test.expectedError != "" {
+				assert.EqualError(t, err, test.expectedError)
+				return
+			}
+			require.NoError(t, err)
+			assert.True(t, test.expected == service)
+			assert.Equal(t, test.expectedLocation, location)
+		})
+	}
+}
+
+func TestValidateVolumeSnapshotLocations(t *testing.T) {
+	withLocations := NewSnapshotServiceWithLocations(
+		map[string]SnapshotService{"east": &test.FakeSnapshotService{}, "west": &test.FakeSnapshotService{}, "gcp-east": &test.FakeSnapshotService{}},
+		map[string]string{"east": "aws", "west": "aws", "gcp-east": "gcp"},
+		map[string]string{"aws": "east"},
+	)
+
+	tests := []struct {
+		name          string
+		backup        *v1.Backup
+		expectedError string
+	}{
+		{
+			name:   "locations for different providers are valid",
+			backup: test.NewTestBackup().WithVolumeSnapshotLocations("east", "gcp-east").Backup,
+		},
+		{
+			name:          "unconfigured location is an error",
+			backup:        test.NewTestBackup().WithVolumeSnapshotLocations("north").Backup,
+			expectedError: `volume snapshot location "north" isn't configured`,
+		},
+		{
+			name:          "locations for the same provider are an error",
+			backup:        test.NewTestBackup().WithVolumeSnapshotLocations("east", "west").Backup,
+			expectedError: `volume snapshot locations "east" and "west" are both for aws; only one location per cloud provider can be used`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := ValidateVolumeSnapshotLocations(withLocations, test.backup)
+			if test.expectedError != "" {
+				assert.EqualError(t, err, test.expectedError)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestVolumeSnapshotLocation(t *testing.T) {
+	tests := []struct {
+		name     string
+		backup   *v1.Backup
+		info     *v1.VolumeBackupInfo
+		expected string
+	}{
+		{
+			name:     "recorded location",
+			backup:   test.NewTestBackup().WithVolumeSnapshotLocations("east").Backup,
+			info:     &v1.VolumeBackupInfo{Location: "east"},
+			expected: "east",
+		},
+		{
+			name:   "default location of a backup with locations",
+			backup: test.NewTestBackup().WithVolumeSnapshotLocations("east").Backup,
+			info:   &v1.VolumeBackupInfo{},
+		},
+		{
+			name:     "older backup's single location",
+			backup:   test.NewTestBackup().WithVolumeSnapshotLocation("east").Backup,
+			info:     &v1.VolumeBackupInfo{},
+			expected: "east",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.expected, VolumeSnapshotLocation(test.backup, test.info))
+		})
+	}
+}
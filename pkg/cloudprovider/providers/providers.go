@@ -0,0 +1,172 @@
+/*
+Copyright 2017 Heptio Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package providers creates the cloud provider adapters described by Ark's configuration.
+package providers
+
+import (
+	"fmt"
+
+	"k8s.io/client-go/util/flowcontrol"
+
+	api "github.com/heptio/ark/pkg/apis/ark/v1"
+	"github.com/heptio/ark/pkg/cloudprovider"
+	arkaws "github.com/heptio/ark/pkg/cloudprovider/aws"
+	"github.com/heptio/ark/pkg/cloudprovider/azure"
+	"github.com/heptio/ark/pkg/cloudprovider/gcp"
+	"github.com/heptio/ark/pkg/plugin"
+)
+
+func hasOneCloudProvider(cloudConfig api.CloudProviderConfig) bool {
+	found := false
+
+	if cloudConfig.AWS != nil {
+		found = true
+	}
+
+	if cloudConfig.GCP != nil {
+		if found {
+			return false
+		}
+		found = true
+	}
+
+	if cloudConfig.Azure != nil {
+		if found {
+			return false
+		}
+		found = true
+	}
+
+	if cloudConfig.Plugin != nil {
+		if found {
+			return false
+		}
+		found = true
+	}
+
+	return found
+}
+
+// newRateLimiter returns the rate limiter for the calls made through cloudConfig, or nil if they
+// aren't limited. field is the name of the config field cloudConfig came from, and is used in
+// error messages.
+func newRateLimiter(cloudConfig api.CloudProviderConfig, field string) (flowcontrol.RateLimiter, error) {
+	config := cloudConfig.RateLimit
+	if config == nil {
+		return nil, nil
+	}
+
+	if config.QPS <= 0 {
+		return nil, fmt.Errorf("rateLimit.qps must be greater than 0 for %s", field)
+	}
+	if config.Burst < 0 {
+		return nil, fmt.Errorf("rateLimit.burst must not be negative for %s", field)
+	}
+
+	burst := config.Burst
+	if burst == 0 {
+		burst = 1
+	}
+
+	return flowcontrol.NewTokenBucketRateLimiter(config.QPS, burst), nil
+}
+
+// NewObjectStorageAdapter creates an ObjectStorageAdapter for the cloud described by cloudConfig.
+// field is the name of the config field cloudConfig came from, and is used in error messages.
+// Plugin providers are run by plugins. If cloudConfig has a rate limit, the adapter's calls are
+// limited to it.
+func NewObjectStorageAdapter(cloudConfig api.CloudProviderConfig, field string, plugins *plugin.Manager) (cloudprovider.ObjectStorageAdapter, error) {
+	var (
+		objectStorage cloudprovider.ObjectStorageAdapter
+		err           error
+	)
+
+	if !hasOneCloudProvider(cloudConfig) {
+		return nil, fmt.Errorf("you must specify exactly one of aws, gcp, azure, or plugin for %s", field)
+	}
+
+	limiter, err := newRateLimiter(cloudConfig, field)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case cloudConfig.AWS != nil:
+		objectStorage, err = arkaws.NewObjectStorageAdapter(
+			cloudConfig.AWS.Region,
+			cloudConfig.AWS.S3Url,
+			cloudConfig.AWS.KMSKeyID,
+			cloudConfig.AWS.S3ForcePathStyle)
+	case cloudConfig.GCP != nil:
+		objectStorage, err = gcp.NewObjectStorageAdapter()
+	case cloudConfig.Azure != nil:
+		objectStorage, err = azure.NewObjectStorageAdapter()
+	case cloudConfig.Plugin != nil:
+		objectStorage, err = plugins.ObjectStore(cloudConfig.Plugin.Name, cloudConfig.Plugin.Config)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if limiter != nil {
+		objectStorage = cloudprovider.NewRateLimitedObjectStorageAdapter(objectStorage, limiter)
+	}
+
+	return objectStorage, nil
+}
+
+// NewBlockStorageAdapter creates a BlockStorageAdapter for the cloud described by cloudConfig.
+// field is the name of the config field cloudConfig came from, and is used in error messages.
+// Plugin providers are run by plugins. If cloudConfig has a rate limit, the adapter's calls are
+// limited to it.
+func NewBlockStorageAdapter(cloudConfig api.CloudProviderConfig, field string, plugins *plugin.Manager) (cloudprovider.BlockStorageAdapter, error) {
+	var (
+		blockStorage cloudprovider.BlockStorageAdapter
+		err          error
+	)
+
+	if !hasOneCloudProvider(cloudConfig) {
+		return nil, fmt.Errorf("you must specify exactly one of aws, gcp, azure, or plugin for %s", field)
+	}
+
+	limiter, err := newRateLimiter(cloudConfig, field)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case cloudConfig.AWS != nil:
+		blockStorage, err = arkaws.NewBlockStorageAdapter(cloudConfig.AWS.Region, cloudConfig.AWS.AvailabilityZone)
+	case cloudConfig.GCP != nil:
+		blockStorage, err = gcp.NewBlockStorageAdapter(cloudConfig.GCP.Project, cloudConfig.GCP.Zone)
+	case cloudConfig.Azure != nil:
+		blockStorage, err = azure.NewBlockStorageAdapter(cloudConfig.Azure.Location, cloudConfig.Azure.APITimeout.Duration)
+	case cloudConfig.Plugin != nil:
+		blockStorage, err = plugins.BlockStore(cloudConfig.Plugin.Name, cloudConfig.Plugin.Config)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if limiter != nil {
+		blockStorage = cloudprovider.NewRateLimitedBlockStorageAdapter(blockStorage, limiter)
+	}
+
+	return blockStorage, nil
+}
@@ -0,0 +1,82 @@
+/*
+Copyright 2017 Heptio Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package providers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	api "github.com/heptio/ark/pkg/apis/ark/v1"
+)
+
+func TestNewRateLimiter(t *testing.T) {
+	tests := []struct {
+		name          string
+		rateLimit     *api.RateLimitConfig
+		expectedQPS   float32
+		expectLimiter bool
+		expectedErr   string
+	}{
+		{
+			name: "no rate limit",
+		},
+		{
+			name:          "qps and burst",
+			rateLimit:     &api.RateLimitConfig{QPS: 5, Burst: 10},
+			expectedQPS:   5,
+			expectLimiter: true,
+		},
+		{
+			name:          "burst defaults to 1",
+			rateLimit:     &api.RateLimitConfig{QPS: 0.5},
+			expectedQPS:   0.5,
+			expectLimiter: true,
+		},
+		{
+			name:        "qps is required",
+			rateLimit:   &api.RateLimitConfig{Burst: 10},
+			expectedErr: "rateLimit.qps must be greater than 0 for backupStorageLocations.default",
+		},
+		{
+			name:        "negative burst",
+			rateLimit:   &api.RateLimitConfig{QPS: 5, Burst: -1},
+			expectedErr: "rateLimit.burst must not be negative for backupStorageLocations.default",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			limiter, err := newRateLimiter(api.CloudProviderConfig{RateLimit: test.rateLimit}, "backupStorageLocations.default")
+			if test.expectedErr != "" {
+				assert.EqualError(t, err, test.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+
+			if !test.expectLimiter {
+				assert.Nil(t, limiter)
+				return
+			}
+			require.NotNil(t, limiter)
+			assert.Equal(t, test.expectedQPS, limiter.QPS())
+			// the burst is available immediately
+			assert.True(t, limiter.TryAccept())
+		})
+	}
+}
@@ -0,0 +1,113 @@
+/*
+Copyright 2017 Heptio Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloudprovider
+
+import (
+	"io"
+	"time"
+
+	"k8s.io/client-go/util/flowcontrol"
+)
+
+// rateLimitedObjectStorage waits for limiter to allow each call to an ObjectStorageAdapter.
+type rateLimitedObjectStorage struct {
+	delegate ObjectStorageAdapter
+	limiter  flowcontrol.RateLimiter
+}
+
+var _ ObjectStorageAdapter = &rateLimitedObjectStorage{}
+
+// NewRateLimitedObjectStorageAdapter returns an ObjectStorageAdapter that makes calls to
+// delegate at the rate allowed by limiter.
+func NewRateLimitedObjectStorageAdapter(delegate ObjectStorageAdapter, limiter flowcontrol.RateLimiter) ObjectStorageAdapter {
+	return &rateLimitedObjectStorage{
+		delegate: delegate,
+		limiter:  limiter,
+	}
+}
+
+func (r *rateLimitedObjectStorage) PutObject(bucket string, key string, body io.ReadSeeker) error {
+	r.limiter.Accept()
+	return r.delegate.PutObject(bucket, key, body)
+}
+
+func (r *rateLimitedObjectStorage) GetObject(bucket string, key string) (io.ReadCloser, error) {
+	r.limiter.Accept()
+	return r.delegate.GetObject(bucket, key)
+}
+
+func (r *rateLimitedObjectStorage) ListCommonPrefixes(bucket, prefix, delimiter string) ([]string, error) {
+	r.limiter.Accept()
+	return r.delegate.ListCommonPrefixes(bucket, prefix, delimiter)
+}
+
+func (r *rateLimitedObjectStorage) DeleteObject(bucket string, key string) error {
+	r.limiter.Accept()
+	return r.delegate.DeleteObject(bucket, key)
+}
+
+func (r *rateLimitedObjectStorage) CreateSignedURL(bucket, key string, ttl time.Duration) (string, error) {
+	r.limiter.Accept()
+	return r.delegate.CreateSignedURL(bucket, key, ttl)
+}
+
+// rateLimitedBlockStorage waits for limiter to allow each call to a BlockStorageAdapter.
+type rateLimitedBlockStorage struct {
+	delegate BlockStorageAdapter
+	limiter  flowcontrol.RateLimiter
+}
+
+var _ BlockStorageAdapter = &rateLimitedBlockStorage{}
+
+// NewRateLimitedBlockStorageAdapter returns a BlockStorageAdapter that makes calls to delegate
+// at the rate allowed by limiter.
+func NewRateLimitedBlockStorageAdapter(delegate BlockStorageAdapter, limiter flowcontrol.RateLimiter) BlockStorageAdapter {
+	return &rateLimitedBlockStorage{
+		delegate: delegate,
+		limiter:  limiter,
+	}
+}
+
+func (r *rateLimitedBlockStorage) CreateVolumeFromSnapshot(snapshotID, volumeType string, iops *int64) (string, error) {
+	r.limiter.Accept()
+	return r.delegate.CreateVolumeFromSnapshot(snapshotID, volumeType, iops)
+}
+
+func (r *rateLimitedBlockStorage) GetVolumeInfo(volumeID string) (string, *int64, error) {
+	r.limiter.Accept()
+	return r.delegate.GetVolumeInfo(volumeID)
+}
+
+func (r *rateLimitedBlockStorage) IsVolumeReady(volumeID string) (bool, error) {
+	r.limiter.Accept()
+	return r.delegate.IsVolumeReady(volumeID)
+}
+
+func (r *rateLimitedBlockStorage) ListSnapshots(tagFilters map[string]string) ([]string, error) {
+	r.limiter.Accept()
+	return r.delegate.ListSnapshots(tagFilters)
+}
+
+func (r *rateLimitedBlockStorage) CreateSnapshot(volumeID string, tags map[string]string) (string, error) {
+	r.limiter.Accept()
+	return r.delegate.CreateSnapshot(volumeID, tags)
+}
+
+func (r *rateLimitedBlockStorage) DeleteSnapshot(snapshotID string) error {
+	r.limiter.Accept()
+	return r.delegate.DeleteSnapshot(snapshotID)
+}
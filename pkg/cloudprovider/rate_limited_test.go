@@ -0,0 +1,50 @@
+/*
+Copyright 2017 Heptio Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloudprovider
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"k8s.io/client-go/util/flowcontrol"
+)
+
+// countingRateLimiter counts the calls it allows.
+type countingRateLimiter struct {
+	flowcontrol.RateLimiter
+	accepted int
+}
+
+func (l *countingRateLimiter) Accept() {
+	l.accepted++
+}
+
+func TestRateLimitedObjectStorage(t *testing.T) {
+	delegate := &fakeObjectStorage{storage: map[string]map[string][]byte{"bucket": {}}}
+	limiter := &countingRateLimiter{}
+	objectStorage := NewRateLimitedObjectStorageAdapter(delegate, limiter)
+
+	require.NoError(t, objectStorage.PutObject("bucket", "backup-1/ark-backup.json", newStringReadSeeker("{}")))
+	prefixes, err := objectStorage.ListCommonPrefixes("bucket", "", "/")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"backup-1"}, prefixes)
+	require.NoError(t, objectStorage.DeleteObject("bucket", "backup-1/ark-backup.json"))
+
+	assert.Equal(t, 3, limiter.accepted)
+}
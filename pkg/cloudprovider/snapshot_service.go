@@ -40,7 +40,8 @@ type SnapshotService interface {
 	CreateVolumeFromSnapshot(snapshotID, volumeType string, iops *int64) (string, error)
 
 	// DeleteSnapshot triggers a deletion of the specified Ark snapshot via the cloud API. It returns an
-	// error if a problem is encountered triggering the deletion via the cloud API.
+	// error if a problem is encountered triggering the deletion via the cloud API, but not if the
+	// snapshot has already been deleted.
 	DeleteSnapshot(snapshotID string) error
 
 	// GetVolumeInfo gets the type and IOPS (if applicable) from the cloud API.
@@ -16,7 +16,11 @@ limitations under the License.
 
 package cloudprovider
 
-import "io"
+import (
+	"fmt"
+	"io"
+	"time"
+)
 
 // ObjectStorageAdapter exposes basic object-storage operations required
 // by Ark.
@@ -26,17 +30,46 @@ type ObjectStorageAdapter interface {
 	PutObject(bucket string, key string, body io.ReadSeeker) error
 
 	// GetObject retrieves the object with the given key from the specified
-	// bucket in object storage.
+	// bucket in object storage. If the object doesn't exist, the error
+	// satisfies IsObjectNotFound.
 	GetObject(bucket string, key string) (io.ReadCloser, error)
 
-	// ListCommonPrefixes gets a list of all object key prefixes that come
-	// before the provided delimiter (this is often used to simulate a directory
-	// hierarchy in object storage).
-	ListCommonPrefixes(bucket string, delimiter string) ([]string, error)
+	// ListCommonPrefixes gets a list of all object key prefixes that start
+	// with prefix and come before the provided delimiter (this is often used
+	// to simulate a directory hierarchy in object storage). The returned
+	// prefixes include prefix.
+	ListCommonPrefixes(bucket, prefix, delimiter string) ([]string, error)
 
 	// DeleteObject removes object with the specified key from the given
 	// bucket.
 	DeleteObject(bucket string, key string) error
+
+	// CreateSignedURL creates a pre-signed URL for the given bucket and key that expires after ttl.
+	CreateSignedURL(bucket, key string, ttl time.Duration) (string, error)
+}
+
+// objectNotFoundError is returned by ObjectStorageAdapter.GetObject when the object
+// doesn't exist.
+type objectNotFoundError struct {
+	bucket string
+	key    string
+}
+
+func (e *objectNotFoundError) Error() string {
+	return fmt.Sprintf("object %s not found in bucket %s", e.key, e.bucket)
+}
+
+// NewObjectNotFoundError returns the error ObjectStorageAdapters return from GetObject when the
+// object with the given key doesn't exist in bucket.
+func NewObjectNotFoundError(bucket, key string) error {
+	return &objectNotFoundError{bucket: bucket, key: key}
+}
+
+// IsObjectNotFound returns whether err says that an object doesn't exist, as opposed to it
+// not being retrievable.
+func IsObjectNotFound(err error) bool {
+	_, ok := err.(*objectNotFoundError)
+	return ok
 }
 
 // BlockStorageAdapter exposes basic block-storage operations required
@@ -60,6 +93,7 @@ type BlockStorageAdapter interface {
 	// set of tags to the snapshot.
 	CreateSnapshot(volumeID string, tags map[string]string) (snapshotID string, err error)
 
-	// DeleteSnapshot deletes the specified volume snapshot.
+	// DeleteSnapshot deletes the specified volume snapshot. It returns nil if the snapshot
+	// doesn't exist, so that deletions can be retried.
 	DeleteSnapshot(snapshotID string) error
 }
@@ -23,8 +23,15 @@ import (
 
 	"github.com/heptio/ark/pkg/client"
 	"github.com/heptio/ark/pkg/cmd/cli/backup"
+	"github.com/heptio/ark/pkg/cmd/cli/backuplocation"
+	"github.com/heptio/ark/pkg/cmd/cli/debug"
+	"github.com/heptio/ark/pkg/cmd/cli/plugin"
+	"github.com/heptio/ark/pkg/cmd/cli/resticrepository"
 	"github.com/heptio/ark/pkg/cmd/cli/restore"
 	"github.com/heptio/ark/pkg/cmd/cli/schedule"
+	"github.com/heptio/ark/pkg/cmd/cli/snapshotlocation"
+	"github.com/heptio/ark/pkg/cmd/completion"
+	"github.com/heptio/ark/pkg/cmd/nodeagent"
 	"github.com/heptio/ark/pkg/cmd/server"
 	"github.com/heptio/ark/pkg/cmd/version"
 )
@@ -44,10 +51,17 @@ associated data.`,
 
 	c.AddCommand(
 		backup.NewCommand(f),
+		backuplocation.NewCommand(f),
 		schedule.NewCommand(f),
 		restore.NewCommand(f),
+		resticrepository.NewCommand(f),
+		snapshotlocation.NewCommand(f),
+		plugin.NewCommand(f),
 		server.NewCommand(),
-		version.NewCommand(),
+		nodeagent.NewCommand(),
+		version.NewCommand(f),
+		debug.NewCommand(f),
+		completion.NewCommand(),
 	)
 
 	// add the glog flags
@@ -32,13 +32,14 @@ func NewCommand(f client.Factory) *cobra.Command {
 	c.AddCommand(
 		NewCreateCommand(f),
 		NewGetCommand(f),
-
-		// Will implement describe later
-		// NewDescribeCommand(f),
-
-		// If you delete a backup and it still exists in object storage, the backup sync controller will
-		// recreate it. Until we have a good UX around this, we're disabling the delete command.
-		// NewDeleteCommand(f),
+		NewDescribeCommand(f),
+		NewVerifyCommand(f),
+		NewLogsCommand(f),
+		NewDownloadCommand(f),
+		NewContentsCommand(f),
+		NewDiffCommand(f),
+		NewCancelCommand(f),
+		NewDeleteCommand(f),
 	)
 
 	return c
@@ -0,0 +1,66 @@
+/*
+Copyright 2017 Heptio Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package backup
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"k8s.io/apimachinery/pkg/types"
+
+	api "github.com/heptio/ark/pkg/apis/ark/v1"
+	"github.com/heptio/ark/pkg/client"
+	"github.com/heptio/ark/pkg/cmd"
+)
+
+func NewCancelCommand(f client.Factory) *cobra.Command {
+	c := &cobra.Command{
+		Use:   "cancel NAME",
+		Short: "Cancel a backup",
+		Long:  "Cancel a backup that is waiting to run or in progress. Item collection stops, any volume snapshots it has taken are deleted, and it's marked Canceled.",
+		Run: func(c *cobra.Command, args []string) {
+			if len(args) != 1 {
+				c.Usage()
+				os.Exit(1)
+			}
+
+			arkClient, err := f.Client()
+			cmd.CheckError(err)
+
+			backupName := args[0]
+
+			patch, err := json.Marshal(map[string]interface{}{
+				"metadata": map[string]interface{}{
+					"annotations": map[string]string{
+						api.CancelAnnotation: "true",
+					},
+				},
+			})
+			cmd.CheckError(err)
+
+			_, err = arkClient.ArkV1().Backups(f.Namespace()).Patch(backupName, types.MergePatchType, patch)
+			cmd.CheckError(err)
+
+			fmt.Printf("Backup %q cancellation requested\n", backupName)
+		},
+	}
+
+	return c
+}
@@ -0,0 +1,184 @@
+/*
+Copyright 2017 Heptio Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package backup
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/ghodss/yaml"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	api "github.com/heptio/ark/pkg/apis/ark/v1"
+	"github.com/heptio/ark/pkg/backup"
+	"github.com/heptio/ark/pkg/client"
+	"github.com/heptio/ark/pkg/cmd"
+	"github.com/heptio/ark/pkg/cmd/util/downloadrequest"
+	"github.com/heptio/ark/pkg/cmd/util/flag"
+)
+
+func NewContentsCommand(f client.Factory) *cobra.Command {
+	o := NewContentsOptions()
+
+	c := &cobra.Command{
+		Use:   "contents NAME",
+		Short: "List the items in a backup",
+		Long: `List the items in a backup by resource, namespace, and name, without downloading it. The list is read from the
+item list that the Ark server uploads alongside each backup, through a temporary URL generated by the server, so no
+object storage credentials are needed. For an incremental backup, it includes the items stored in its ancestors.
+
+Use --namespaces and --resources to list only the items in some namespaces, or of some resources. Resources can be
+given with or without their API group, e.g. deployments or deployments.apps.`,
+		Run: func(c *cobra.Command, args []string) {
+			cmd.CheckError(o.Validate(args))
+			cmd.CheckError(o.Complete(args))
+			cmd.CheckError(o.Run(f, os.Stdout))
+		},
+	}
+
+	o.BindFlags(c.Flags())
+
+	return c
+}
+
+type ContentsOptions struct {
+	Name       string
+	Namespaces flag.StringArray
+	Resources  flag.StringArray
+	Output     string
+	Timeout    time.Duration
+}
+
+func NewContentsOptions() *ContentsOptions {
+	return &ContentsOptions{
+		Output:  "table",
+		Timeout: time.Minute,
+	}
+}
+
+func (o *ContentsOptions) BindFlags(flags *pflag.FlagSet) {
+	flags.Var(&o.Namespaces, "namespaces", "namespaces to list the items of; use '' for cluster-scoped items. Optional; defaults to all.")
+	flags.Var(&o.Resources, "resources", "resources to list the items of. Optional; defaults to all.")
+	flags.StringVarP(&o.Output, "output", "o", o.Output, "Output display format. Valid formats are 'table', 'json', and 'yaml'.")
+	flags.DurationVar(&o.Timeout, "timeout", o.Timeout, "maximum time to wait to process download request")
+}
+
+func (o *ContentsOptions) Validate(args []string) error {
+	if len(args) != 1 {
+		return errors.New("you must specify only one argument, the backup's name")
+	}
+
+	switch o.Output {
+	case "table", "json", "yaml":
+	default:
+		return fmt.Errorf("invalid output format %q; valid values are 'table', 'json', and 'yaml'", o.Output)
+	}
+
+	return nil
+}
+
+func (o *ContentsOptions) Complete(args []string) error {
+	o.Name = args[0]
+	return nil
+}
+
+func (o *ContentsOptions) Run(f client.Factory, w io.Writer) error {
+	arkClient, err := f.Client()
+	if err != nil {
+		return err
+	}
+
+	// only backups that ran to completion were uploaded, so check before waiting on a download
+	// request that can't be fulfilled
+	b, err := arkClient.ArkV1().Backups(f.Namespace()).Get(o.Name, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	if b.Status.Phase != api.BackupPhaseCompleted && b.Status.Phase != api.BackupPhasePartiallyFailed {
+		return fmt.Errorf("backup %s has phase %s; only %s and %s backups have contents", o.Name, b.Status.Phase, api.BackupPhaseCompleted, api.BackupPhasePartiallyFailed)
+	}
+
+	// backups taken before item lists were uploaded don't have one, so downloading it fails
+	buf := new(bytes.Buffer)
+	if err := downloadrequest.Stream(arkClient.ArkV1(), f.Namespace(), o.Name, api.DownloadTargetKindBackupItems, buf, o.Timeout); err != nil {
+		return fmt.Errorf("error downloading item list of backup %s (if it was taken by an older Ark server, use ark backup download instead): %v", o.Name, err)
+	}
+
+	var items []backup.Item
+	if err := json.NewDecoder(buf).Decode(&items); err != nil {
+		return fmt.Errorf("error reading item list of backup %s: %v", o.Name, err)
+	}
+
+	items = o.filter(items)
+
+	switch o.Output {
+	case "json":
+		data, err := json.MarshalIndent(items, "", "    ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(w, string(data))
+		return err
+	case "yaml":
+		data, err := yaml.Marshal(items)
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(data)
+		return err
+	}
+
+	tw := tabwriter.NewWriter(w, 10, 4, 3, ' ', 0)
+	fmt.Fprintln(tw, "RESOURCE\tNAMESPACE\tNAME")
+	for _, item := range items {
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", item.Resource, item.Namespace, item.Name)
+	}
+	return tw.Flush()
+}
+
+// filter returns the items that are in one of o's namespaces and of one of its resources, if
+// it has any.
+func (o *ContentsOptions) filter(items []backup.Item) []backup.Item {
+	namespaces := sets.NewString(o.Namespaces...)
+	resources := sets.NewString(o.Resources...)
+
+	filtered := []backup.Item{}
+	for _, item := range items {
+		if namespaces.Len() > 0 && !namespaces.Has(item.Namespace) {
+			continue
+		}
+		// items are stored by group-qualified resource, e.g. deployments.apps, but the group
+		// is optional when filtering.
+		if resources.Len() > 0 && !resources.Has(item.Resource) && !resources.Has(strings.SplitN(item.Resource, ".", 2)[0]) {
+			continue
+		}
+		filtered = append(filtered, item)
+	}
+
+	return filtered
+}
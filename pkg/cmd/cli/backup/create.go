@@ -19,26 +19,46 @@ package backup
 import (
 	"errors"
 	"fmt"
+	"io"
+	"os"
+	"os/user"
+	"strings"
 	"time"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/wait"
 
 	api "github.com/heptio/ark/pkg/apis/ark/v1"
 	"github.com/heptio/ark/pkg/client"
 	"github.com/heptio/ark/pkg/cmd"
 	"github.com/heptio/ark/pkg/cmd/util/flag"
 	"github.com/heptio/ark/pkg/cmd/util/output"
+	"github.com/heptio/ark/pkg/cmd/util/progress"
+	arkv1client "github.com/heptio/ark/pkg/generated/clientset/typed/ark/v1"
+	"github.com/heptio/ark/pkg/schedule"
 )
 
+// configName is the name of the Config the Ark server reads.
+const configName = "default"
+
 func NewCreateCommand(f client.Factory) *cobra.Command {
 	o := NewCreateOptions()
 
 	c := &cobra.Command{
 		Use:   "create NAME",
 		Short: "Create a backup",
+		Long: `Create a backup.
+
+To run an existing schedule's backup right away, e.g. before making a change to the cluster, use --from-schedule. The backup
+gets the schedule's backup spec, and is named by the schedule's backup name template unless NAME is given.
+
+To wait for the backup to finish, e.g. in a CI pipeline, use --wait. Its progress and the time elapsed are printed as it
+runs, on a single line that's updated in place when writing to a terminal, and the command exits with a non-zero status
+unless the backup completes without errors.`,
 		Run: func(c *cobra.Command, args []string) {
 			cmd.CheckError(o.Validate(c, args))
 			cmd.CheckError(o.Complete(args))
@@ -47,6 +67,10 @@ func NewCreateCommand(f client.Factory) *cobra.Command {
 	}
 
 	o.BindFlags(c.Flags())
+	o.bindParentBackupFlag(c.Flags())
+	c.Flags().StringVar(&o.FromSchedule, "from-schedule", "", "create a backup with the spec of this schedule's backups, instead of the one given by the other flags")
+	c.Flags().BoolVar(&o.Wait, "wait", o.Wait, "wait for the backup to finish, printing its progress, and exit with a non-zero status unless it completes without errors")
+	c.Flags().DurationVar(&o.WaitTimeout, "wait-timeout", o.WaitTimeout, "maximum time to wait for the backup to finish when --wait is used (0 means no limit)")
 	output.BindFlags(c.Flags())
 	output.ClearOutputFlagDefault(c)
 
@@ -54,42 +78,104 @@ func NewCreateCommand(f client.Factory) *cobra.Command {
 }
 
 type CreateOptions struct {
-	Name              string
-	TTL               time.Duration
-	SnapshotVolumes   flag.OptionalBool
-	IncludeNamespaces flag.StringArray
-	ExcludeNamespaces flag.StringArray
-	IncludeResources  flag.StringArray
-	ExcludeResources  flag.StringArray
-	Labels            flag.Map
-	Selector          flag.LabelSelector
+	Name                    string
+	TTL                     time.Duration
+	SnapshotVolumes         flag.OptionalBool
+	MoveVolumeData          bool
+	Uploader                string
+	StorageLocation         string
+	SnapshotLocations       flag.StringArray
+	IncludeNamespaces       flag.StringArray
+	ExcludeNamespaces       flag.StringArray
+	IncludeResources        flag.StringArray
+	ExcludeResources        flag.StringArray
+	IncludeClusterResources flag.OptionalBool
+	IgnoreDefaultExcludes   bool
+	Labels                  flag.Map
+	Selector                flag.LabelSelector
+	ParentBackup            string
+	FromSchedule            string
+	Wait                    bool
+	WaitTimeout             time.Duration
 }
 
 func NewCreateOptions() *CreateOptions {
 	return &CreateOptions{
-		TTL:               24 * time.Hour,
-		IncludeNamespaces: flag.NewStringArray("*"),
-		Labels:            flag.NewMap(),
-		SnapshotVolumes:   flag.NewOptionalBool(nil),
+		TTL:                     24 * time.Hour,
+		IncludeNamespaces:       flag.NewStringArray("*"),
+		Labels:                  flag.NewMap(),
+		SnapshotVolumes:         flag.NewOptionalBool(nil),
+		IncludeClusterResources: flag.NewOptionalBool(nil),
 	}
 }
 
 func (o *CreateOptions) BindFlags(flags *pflag.FlagSet) {
+	o.bindSpecFlags(flags)
+	flags.Var(&o.Labels, "labels", "labels to apply to the backup")
+}
+
+// bindSpecFlags binds the flags that give the backup's spec, which comes from the schedule
+// instead when --from-schedule is used.
+func (o *CreateOptions) bindSpecFlags(flags *pflag.FlagSet) {
 	flags.DurationVar(&o.TTL, "ttl", o.TTL, "how long before the backup can be garbage collected")
 	flags.Var(&o.IncludeNamespaces, "include-namespaces", "namespaces to include in the backup (use '*' for all namespaces)")
 	flags.Var(&o.ExcludeNamespaces, "exclude-namespaces", "namespaces to exclude from the backup")
 	flags.Var(&o.IncludeResources, "include-resources", "resources to include in the backup, formatted as resource.group, such as storageclasses.storage.k8s.io (use '*' for all resources)")
 	flags.Var(&o.ExcludeResources, "exclude-resources", "resources to exclude from the backup, formatted as resource.group, such as storageclasses.storage.k8s.io")
-	flags.Var(&o.Labels, "labels", "labels to apply to the backup")
+	f := flags.VarPF(&o.IncludeClusterResources, "include-cluster-resources", "", "include cluster-scoped resources in the backup (by default, they're all included; if false, only the ones that included items depend on, such as PersistentVolumes, are)")
+	f.NoOptDefVal = "true"
+	flags.BoolVar(&o.IgnoreDefaultExcludes, "ignore-default-excludes", o.IgnoreDefaultExcludes, "ignore the server's default excluded resources and its exclusion of completed pods, so that only this backup's filters apply")
 	flags.VarP(&o.Selector, "selector", "l", "only back up resources matching this label selector")
-	f := flags.VarPF(&o.SnapshotVolumes, "snapshot-volumes", "", "take snapshots of PersistentVolumes as part of the backup")
+	f = flags.VarPF(&o.SnapshotVolumes, "snapshot-volumes", "", "take snapshots of PersistentVolumes as part of the backup")
 	// this allows the user to just specify "--snapshot-volumes" as shorthand for "--snapshot-volumes=true"
 	// like a normal bool flag
 	f.NoOptDefVal = "true"
+	flags.BoolVar(&o.MoveVolumeData, "move-volume-data", o.MoveVolumeData, "copy the data of pods' PersistentVolumeClaim volumes into object storage using restic, so it can be restored on any cloud provider")
+	flags.StringVar(&o.Uploader, "uploader", "", "the uploader that backs up pod volume data, restic or kopia (default each namespace's ark.heptio.com/uploader annotation, or restic)")
+	flags.StringVar(&o.StorageLocation, "storage-location", "", "the server's backup storage location to store the backup in (default the server's default location)")
+	flags.Var(&o.SnapshotLocations, "volume-snapshot-locations", "the server's volume snapshot locations to take the backup's PersistentVolume snapshots in, at most one per cloud provider (default each provider's default location)")
+	// --volume-snapshot-location could only name one location
+	flags.Var(&o.SnapshotLocations, "volume-snapshot-location", "")
+	flags.MarkDeprecated("volume-snapshot-location", "use --volume-snapshot-locations instead")
+}
+
+// bindParentBackupFlag binds --parent-backup, which is part of the backup's spec too, but isn't
+// bound by BindFlags (which is shared with schedules), since a fixed parent only makes sense for a
+// single backup.
+func (o *CreateOptions) bindParentBackupFlag(flags *pflag.FlagSet) {
+	flags.StringVar(&o.ParentBackup, "parent-backup", "", "take an incremental backup containing only the items that have changed since this backup")
+}
+
+// specFlagNames returns the names of the flags that give the backup's spec.
+func specFlagNames() sets.String {
+	flags := pflag.NewFlagSet("", pflag.ContinueOnError)
+	o := NewCreateOptions()
+	o.bindSpecFlags(flags)
+	o.bindParentBackupFlag(flags)
+
+	names := sets.NewString()
+	flags.VisitAll(func(f *pflag.Flag) {
+		names.Insert(f.Name)
+	})
+	return names
 }
 
 func (o *CreateOptions) Validate(c *cobra.Command, args []string) error {
-	if len(args) != 1 {
+	if o.FromSchedule != "" {
+		if len(args) > 1 {
+			return errors.New("you must specify at most one argument, the backup's name")
+		}
+		specFlags := specFlagNames()
+		var err error
+		c.Flags().Visit(func(f *pflag.Flag) {
+			if err == nil && specFlags.Has(f.Name) {
+				err = fmt.Errorf("--%s can't be used with --from-schedule, since the backup's spec comes from the schedule", f.Name)
+			}
+		})
+		if err != nil {
+			return err
+		}
+	} else if len(args) != 1 {
 		return errors.New("you must specify only one argument, the backup's name")
 	}
 
@@ -97,11 +183,17 @@ func (o *CreateOptions) Validate(c *cobra.Command, args []string) error {
 		return err
 	}
 
+	if o.Wait && output.GetOutputFlagValue(c) != "" {
+		return errors.New("--wait can't be used with --output, since the backup isn't created")
+	}
+
 	return nil
 }
 
 func (o *CreateOptions) Complete(args []string) error {
-	o.Name = args[0]
+	if len(args) > 0 {
+		o.Name = args[0]
+	}
 	return nil
 }
 
@@ -111,32 +203,170 @@ func (o *CreateOptions) Run(c *cobra.Command, f client.Factory) error {
 		return err
 	}
 
-	backup := &api.Backup{
+	var backup *api.Backup
+	if o.FromSchedule != "" {
+		if backup, err = o.backupFromSchedule(arkClient.ArkV1(), f.Namespace(), time.Now()); err != nil {
+			return err
+		}
+	} else {
+		backup = o.backup(f.Namespace())
+	}
+
+	if u, err := user.Current(); err == nil {
+		if backup.Annotations == nil {
+			backup.Annotations = make(map[string]string)
+		}
+		backup.Annotations[api.RequesterAnnotation] = u.Username
+	}
+
+	if printed, err := output.PrintWithFormat(c, backup); printed || err != nil {
+		return err
+	}
+
+	_, err = arkClient.ArkV1().Backups(backup.Namespace).Create(backup)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Backup %q created successfully.\n", backup.Name)
+
+	if !o.Wait {
+		return nil
+	}
+
+	return waitForBackup(arkClient.ArkV1(), f.Namespace(), backup.Name, o.WaitTimeout, os.Stdout)
+}
+
+// backup returns the backup given by the flags.
+func (o *CreateOptions) backup(namespace string) *api.Backup {
+	return &api.Backup{
 		ObjectMeta: metav1.ObjectMeta{
-			Namespace: api.DefaultNamespace,
+			Namespace: namespace,
 			Name:      o.Name,
 			Labels:    o.Labels.Data(),
 		},
 		Spec: api.BackupSpec{
-			IncludedNamespaces: o.IncludeNamespaces,
-			ExcludedNamespaces: o.ExcludeNamespaces,
-			IncludedResources:  o.IncludeResources,
-			ExcludedResources:  o.ExcludeResources,
-			LabelSelector:      o.Selector.LabelSelector,
-			SnapshotVolumes:    o.SnapshotVolumes.Value,
-			TTL:                metav1.Duration{Duration: o.TTL},
+			IncludedNamespaces:      o.IncludeNamespaces,
+			ExcludedNamespaces:      o.ExcludeNamespaces,
+			IncludedResources:       o.IncludeResources,
+			ExcludedResources:       o.ExcludeResources,
+			LabelSelector:           o.Selector.LabelSelector,
+			IncludeClusterResources: o.IncludeClusterResources.Value,
+			IgnoreDefaultExcludes:   o.IgnoreDefaultExcludes,
+			SnapshotVolumes:         o.SnapshotVolumes.Value,
+			MoveVolumeData:          o.MoveVolumeData,
+			UploaderType:            o.Uploader,
+			StorageLocation:         o.StorageLocation,
+			VolumeSnapshotLocations: o.SnapshotLocations,
+			TTL:                     metav1.Duration{Duration: o.TTL},
+			ParentBackup:            o.ParentBackup,
 		},
 	}
+}
 
-	if printed, err := output.PrintWithFormat(c, backup); printed || err != nil {
-		return err
+// backupFromSchedule returns a backup of the schedule named by --from-schedule, created the same
+// way as the schedule's own backups, so it gets the schedule's backup spec, name, label and owner
+// reference, and is subject to the schedule's retention policy. It's named by NAME instead, if
+// it's given, and gets the labels given by --labels as well.
+func (o *CreateOptions) backupFromSchedule(client arkv1client.ArkV1Interface, namespace string, now time.Time) (*api.Backup, error) {
+	sched, err := client.Schedules(namespace).Get(o.FromSchedule, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
 	}
 
-	_, err = arkClient.ArkV1().Backups(backup.Namespace).Create(backup)
+	name := o.Name
+	if name == "" {
+		// the {cluster} placeholder of backup name templates is the cluster name from the
+		// server's config, which not every user can read, so it's only read when NAME isn't given
+		clusterName := ""
+		if config, err := client.Configs(namespace).Get(configName, metav1.GetOptions{}); err == nil {
+			clusterName = config.ClusterName
+		}
+
+		if name, err = schedule.BackupName(sched, now, clusterName); err != nil {
+			return nil, fmt.Errorf("error naming the backup with schedule %s's backup name template: %v", sched.Name, err)
+		}
+	}
+
+	backup := schedule.NewBackup(sched, name)
+	for k, v := range o.Labels.Data() {
+		if _, found := backup.Labels[k]; !found {
+			backup.Labels[k] = v
+		}
+	}
+
+	return backup, nil
+}
+
+// waitForBackup polls the named backup in namespace until it reaches a terminal phase, displaying its
+// progress on w with a progress.Line. It returns an error if timeout is non-zero and passes
+// first, or if the backup didn't complete without errors.
+func waitForBackup(client arkv1client.BackupsGetter, namespace, name string, timeout time.Duration, w io.Writer) error {
+	fmt.Fprintf(w, "Waiting for backup %q to finish...\n", name)
+
+	var backup *api.Backup
+	line := progress.NewLine(w)
+	condition := func() (bool, error) {
+		var err error
+		if backup, err = client.Backups(namespace).Get(name, metav1.GetOptions{}); err != nil {
+			return false, err
+		}
+
+		line.Update(describeProgress(backup))
+
+		switch backup.Status.Phase {
+		case api.BackupPhaseCompleted, api.BackupPhasePartiallyFailed, api.BackupPhaseFailed, api.BackupPhaseFailedValidation, api.BackupPhaseCanceled:
+			return true, nil
+		}
+		return false, nil
+	}
+
+	var err error
+	if timeout > 0 {
+		err = wait.PollImmediate(time.Second, timeout, condition)
+	} else {
+		err = wait.PollImmediateInfinite(time.Second, condition)
+	}
+	line.Done()
+
+	if err == wait.ErrWaitTimeout {
+		return fmt.Errorf("timed out waiting for backup %q to finish", name)
+	}
 	if err != nil {
-		return err
+		return fmt.Errorf("error waiting for backup %q to finish: %v", name, err)
 	}
 
-	fmt.Printf("Backup %q created successfully.\n", backup.Name)
-	return nil
+	switch backup.Status.Phase {
+	case api.BackupPhaseCompleted:
+		fmt.Fprintf(w, "Backup %q completed with %d warning(s).\n", name, backup.Status.Warnings)
+		return nil
+	case api.BackupPhaseFailedValidation:
+		return fmt.Errorf("backup %q failed validation: %s", name, strings.Join(backup.Status.ValidationErrors, "; "))
+	case api.BackupPhaseCanceled:
+		return fmt.Errorf("backup %q was canceled", name)
+	default:
+		return fmt.Errorf("backup %q finished with phase %s and %d error(s); run 'ark backup logs %s' for details", name, backup.Status.Phase, backup.Status.Errors, name)
+	}
+}
+
+// describeProgress returns a one-line summary of backup's phase and progress.
+func describeProgress(backup *api.Backup) string {
+	phase := backup.Status.Phase
+	if phase == "" {
+		phase = api.BackupPhaseNew
+	}
+
+	progress := backup.Status.Progress
+	if progress == nil || progress.TotalItems == 0 {
+		return fmt.Sprintf("Phase: %s", phase)
+	}
+
+	s := fmt.Sprintf("Phase: %s, %d of %d items backed up", phase, progress.ItemsBackedUp, progress.TotalItems)
+	if progress.VolumeSnapshotsAttempted > 0 {
+		s += fmt.Sprintf(", %d of %d volume snapshots completed", progress.VolumeSnapshotsCompleted, progress.VolumeSnapshotsAttempted)
+	}
+	if progress.PodVolumeBackupsAttempted > 0 {
+		s += fmt.Sprintf(", %d of %d restic backups completed", progress.PodVolumeBackupsCompleted, progress.PodVolumeBackupsAttempted)
+	}
+	return s
 }
@@ -0,0 +1,326 @@
+/*
+Copyright 2017 Heptio Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package backup
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	core "k8s.io/client-go/testing"
+
+	api "github.com/heptio/ark/pkg/apis/ark/v1"
+	"github.com/heptio/ark/pkg/generated/clientset/fake"
+)
+
+func TestBackupFromSchedule(t *testing.T) {
+	schedule := &api.Schedule{
+		ObjectMeta: metav1.ObjectMeta{Namespace: api.DefaultNamespace, Name: "daily", UID: "uid-1"},
+		Spec: api.ScheduleSpec{
+			Timezone:           "Asia/Tokyo",
+			BackupNameTemplate: "{cluster}-{schedule}-{date}",
+			Template: api.BackupSpec{
+				IncludedNamespaces: []string{"ns-1"},
+				StorageLocation:    "location-1",
+				TTL:                metav1.Duration{Duration: time.Hour},
+			},
+		},
+	}
+	config := &api.Config{
+		ObjectMeta:  metav1.ObjectMeta{Namespace: api.DefaultNamespace, Name: configName},
+		ClusterName: "prod",
+	}
+	now := time.Date(2017, 7, 25, 18, 15, 0, 0, time.UTC)
+
+	tests := []struct {
+		name          string
+		backupName    string
+		labels        string
+		noConfig      bool
+		expectedName  string
+		expectedError string
+	}{
+		{
+			name:         "backup is named by the schedule's backup name template",
+			expectedName: "prod-daily-20170726",
+		},
+		{
+			name:         "NAME replaces the generated name",
+			backupName:   "before-upgrade",
+			expectedName: "before-upgrade",
+		},
+		{
+			name:          "templates with the cluster name can't be used if the config can't be read",
+			noConfig:      true,
+			expectedError: `error naming the backup with schedule daily's backup name template: placeholder {cluster} in "{cluster}-{schedule}-{date}" has no value`,
+		},
+		{
+			name:         "NAME doesn't need the config",
+			backupName:   "before-upgrade",
+			noConfig:     true,
+			expectedName: "before-upgrade",
+		},
+		{
+			name:         "labels don't replace the schedule's label",
+			labels:       api.ScheduleNameLabel + "=other",
+			expectedName: "prod-daily-20170726",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			client := fake.NewSimpleClientset(schedule)
+			if !test.noConfig {
+				client = fake.NewSimpleClientset(schedule, config)
+			}
+
+			o := NewCreateOptions()
+			o.Name = test.backupName
+			o.FromSchedule = schedule.Name
+			require.NoError(t, o.Labels.Set("team=a"))
+			if test.labels != "" {
+				require.NoError(t, o.Labels.Set("team=a,"+test.labels))
+			}
+
+			backup, err := o.backupFromSchedule(client.ArkV1(), api.DefaultNamespace, now)
+			if test.expectedError != "" {
+				assert.EqualError(t, err, test.expectedError)
+				return
+			}
+			require.NoError(t, err)
+
+			assert.Equal(t, api.DefaultNamespace, backup.Namespace)
+			assert.Equal(t, test.expectedName, backup.Name)
+			assert.Equal(t, schedule.Spec.Template, backup.Spec)
+			assert.Equal(t, map[string]string{api.ScheduleNameLabel: "daily", "team": "a"}, backup.Labels)
+			require.Len(t, backup.OwnerReferences, 1)
+			assert.Equal(t, "Schedule", backup.OwnerReferences[0].Kind)
+			assert.Equal(t, schedule.UID, backup.OwnerReferences[0].UID)
+		})
+	}
+}
+
+func TestBackupFromMissingSchedule(t *testing.T) {
+	o := NewCreateOptions()
+	o.FromSchedule = "missing"
+
+	_, err := o.backupFromSchedule(fake.NewSimpleClientset().ArkV1(), api.DefaultNamespace, time.Now())
+	assert.EqualError(t, err, `schedules.ark.heptio.com "missing" not found`)
+}
+
+func TestValidateFromScheduleRejectsSpecFlags(t *testing.T) {
+	tests := []struct {
+		name  string
+		flags map[string]string
+		err   string
+	}{
+		{
+			name:  "labels are allowed",
+			flags: map[string]string{"labels": "team=a"},
+		},
+		{
+			name:  "spec flag is rejected",
+			flags: map[string]string{"include-namespaces": "ns-1"},
+			err:   "--include-namespaces can't be used with --from-schedule, since the backup's spec comes from the schedule",
+		},
+		{
+			name:  "parent backup is rejected",
+			flags: map[string]string{"parent-backup": "backup-1"},
+			err:   "--parent-backup can't be used with --from-schedule, since the backup's spec comes from the schedule",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := NewCreateCommand(nil)
+			require.NoError(t, c.Flags().Set("from-schedule", "daily"))
+			for name, value := range test.flags {
+				require.NoError(t, c.Flags().Set(name, value))
+			}
+
+			o := NewCreateOptions()
+			o.FromSchedule = "daily"
+			err := o.Validate(c, nil)
+			if test.err == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, test.err)
+			}
+		})
+	}
+}
+
+func TestValidateWaitRejectsOutput(t *testing.T) {
+	c := NewCreateCommand(nil)
+	require.NoError(t, c.Flags().Set("wait", "true"))
+	require.NoError(t, c.Flags().Set("output", "yaml"))
+
+	o := NewCreateOptions()
+	o.Wait = true
+	assert.EqualError(t, o.Validate(c, []string{"backup-1"}), "--wait can't be used with --output, since the backup isn't created")
+}
+
+func TestWaitForBackup(t *testing.T) {
+	tests := []struct {
+		name           string
+		status         api.BackupStatus
+		timeout        time.Duration
+		expectedError  string
+		expectedOutput string
+	}{
+		{
+			name:           "completed backups succeed",
+			status:         api.BackupStatus{Phase: api.BackupPhaseCompleted, Warnings: 2},
+			expectedOutput: "Backup \"backup-1\" completed with 2 warning(s).\n",
+		},
+		{
+			name:          "partially failed backups fail",
+			status:        api.BackupStatus{Phase: api.BackupPhasePartiallyFailed, Errors: 3},
+			expectedError: "backup \"backup-1\" finished with phase PartiallyFailed and 3 error(s); run 'ark backup logs backup-1' for details",
+		},
+		{
+			name:          "failed backups fail",
+			status:        api.BackupStatus{Phase: api.BackupPhaseFailed},
+			expectedError: "backup \"backup-1\" finished with phase Failed and 0 error(s); run 'ark backup logs backup-1' for details",
+		},
+		{
+			name:          "backups that fail validation fail",
+			status:        api.BackupStatus{Phase: api.BackupPhaseFailedValidation, ValidationErrors: []string{"error 1", "error 2"}},
+			expectedError: "backup \"backup-1\" failed validation: error 1; error 2",
+		},
+		{
+			name:          "canceled backups fail",
+			status:        api.BackupStatus{Phase: api.BackupPhaseCanceled},
+			expectedError: "backup \"backup-1\" was canceled",
+		},
+		{
+			name:          "backups that don't finish in time fail",
+			status:        api.BackupStatus{Phase: api.BackupPhaseInProgress},
+			timeout:       10 * time.Millisecond,
+			expectedError: "timed out waiting for backup \"backup-1\" to finish",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			backup := &api.Backup{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "ark-ns", Name: "backup-1"},
+				Status:     test.status,
+			}
+			client := fake.NewSimpleClientset(backup)
+
+			buf := new(bytes.Buffer)
+			err := waitForBackup(client.ArkV1(), "ark-ns", "backup-1", test.timeout, buf)
+			if test.expectedError != "" {
+				assert.EqualError(t, err, test.expectedError)
+			} else {
+				assert.NoError(t, err)
+			}
+
+			assert.True(t, strings.HasPrefix(buf.String(), "Waiting for backup \"backup-1\" to finish...\n"), "unexpected output %q", buf.String())
+			assert.True(t, strings.HasSuffix(buf.String(), test.expectedOutput), "unexpected output %q", buf.String())
+		})
+	}
+}
+
+func TestWaitForBackupReportsProgress(t *testing.T) {
+	backup := &api.Backup{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ark-ns", Name: "backup-1"},
+		Status: api.BackupStatus{
+			Phase:    api.BackupPhaseInProgress,
+			Progress: &api.BackupProgress{TotalItems: 10, ItemsBackedUp: 4},
+		},
+	}
+	client := fake.NewSimpleClientset(backup)
+
+	// the backup completes after it's first retrieved
+	gets := 0
+	client.PrependReactor("get", "backups", func(action core.Action) (bool, runtime.Object, error) {
+		gets++
+		if gets == 1 {
+			return false, nil, nil
+		}
+		completed := *backup
+		completed.Status = api.BackupStatus{
+			Phase:    api.BackupPhaseCompleted,
+			Progress: &api.BackupProgress{TotalItems: 10, ItemsBackedUp: 10},
+		}
+		return true, &completed, nil
+	})
+
+	buf := new(bytes.Buffer)
+	require.NoError(t, waitForBackup(client.ArkV1(), "ark-ns", "backup-1", 0, buf))
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	require.Len(t, lines, 4)
+	assert.True(t, strings.HasPrefix(lines[1], "Phase: InProgress, 4 of 10 items backed up (elapsed: "), lines[1])
+	assert.True(t, strings.HasPrefix(lines[2], "Phase: Completed, 10 of 10 items backed up (elapsed: "), lines[2])
+	assert.Equal(t, "Backup \"backup-1\" completed with 0 warning(s).", lines[3])
+}
+
+func TestDescribeProgress(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   api.BackupStatus
+		expected string
+	}{
+		{
+			name:     "new backups",
+			expected: "Phase: New",
+		},
+		{
+			name:     "backups without progress",
+			status:   api.BackupStatus{Phase: api.BackupPhaseInProgress},
+			expected: "Phase: InProgress",
+		},
+		{
+			name: "items",
+			status: api.BackupStatus{
+				Phase:    api.BackupPhaseInProgress,
+				Progress: &api.BackupProgress{TotalItems: 10, ItemsBackedUp: 4},
+			},
+			expected: "Phase: InProgress, 4 of 10 items backed up",
+		},
+		{
+			name: "volume snapshots and restic backups",
+			status: api.BackupStatus{
+				Phase: api.BackupPhaseInProgress,
+				Progress: &api.BackupProgress{
+					TotalItems:                10,
+					ItemsBackedUp:             10,
+					VolumeSnapshotsAttempted:  2,
+					VolumeSnapshotsCompleted:  1,
+					PodVolumeBackupsAttempted: 3,
+					PodVolumeBackupsCompleted: 3,
+				},
+			},
+			expected: "Phase: InProgress, 10 of 10 items backed up, 1 of 2 volume snapshots completed, 3 of 3 restic backups completed",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.expected, describeProgress(&api.Backup{Status: test.status}))
+		})
+	}
+}
@@ -17,37 +17,179 @@ limitations under the License.
 package backup
 
 import (
+	"bufio"
+	"errors"
 	"fmt"
+	"io"
 	"os"
+	"os/user"
+	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
 
 	api "github.com/heptio/ark/pkg/apis/ark/v1"
 	"github.com/heptio/ark/pkg/client"
 	"github.com/heptio/ark/pkg/cmd"
+	arkv1client "github.com/heptio/ark/pkg/generated/clientset/typed/ark/v1"
 )
 
 func NewDeleteCommand(f client.Factory) *cobra.Command {
+	o := NewDeleteOptions()
+
 	c := &cobra.Command{
 		Use:   "delete NAME",
 		Short: "Delete a backup",
+		Long: `Delete a backup, along with its volume snapshots and its data in object storage. The deletion is carried out by the Ark
+server, which records the outcome in a DeleteBackupRequest. Deleting a Backup with kubectl instead would leave its snapshots
+and data behind, and the backup would be synced back into the cluster from object storage.
+
+You're asked to confirm the deletion unless --confirm is given. Use --wait to wait for the server to process the request
+and report which snapshots and data were deleted.`,
 		Run: func(c *cobra.Command, args []string) {
-			if len(args) != 1 {
-				c.Usage()
-				os.Exit(1)
-			}
+			cmd.CheckError(o.Validate(args))
+			cmd.CheckError(o.Complete(args))
+			cmd.CheckError(o.Run(f))
+		},
+	}
+
+	o.BindFlags(c.Flags())
+
+	return c
+}
+
+type DeleteOptions struct {
+	BackupName  string
+	Confirm     bool
+	Wait        bool
+	WaitTimeout time.Duration
+}
+
+func NewDeleteOptions() *DeleteOptions {
+	return &DeleteOptions{
+		WaitTimeout: 10 * time.Minute,
+	}
+}
+
+func (o *DeleteOptions) BindFlags(flags *pflag.FlagSet) {
+	flags.BoolVar(&o.Confirm, "confirm", o.Confirm, "delete the backup without asking for confirmation")
+	flags.BoolVar(&o.Wait, "wait", o.Wait, "wait for the deletion to be processed, and exit with a non-zero status if it failed")
+	flags.DurationVar(&o.WaitTimeout, "wait-timeout", o.WaitTimeout, "maximum time to wait for the deletion to be processed when --wait is used")
+}
+
+func (o *DeleteOptions) Validate(args []string) error {
+	if len(args) != 1 {
+		return errors.New("you must specify only one argument, the backup's name")
+	}
 
-			arkClient, err := f.Client()
-			cmd.CheckError(err)
+	return nil
+}
+
+func (o *DeleteOptions) Complete(args []string) error {
+	o.BackupName = args[0]
+	return nil
+}
 
-			backupName := args[0]
+func (o *DeleteOptions) Run(f client.Factory) error {
+	if !o.Confirm && !getConfirmation(os.Stdin, os.Stdout, fmt.Sprintf("Are you sure you want to delete backup %q, along with its volume snapshots and its data in object storage?", o.BackupName)) {
+		fmt.Println("Backup not deleted.")
+		return nil
+	}
 
-			err = arkClient.ArkV1().Backups(api.DefaultNamespace).Delete(backupName, nil)
-			cmd.CheckError(err)
+	arkClient, err := f.Client()
+	if err != nil {
+		return err
+	}
 
-			fmt.Printf("Backup %q deleted\n", backupName)
+	req := &api.DeleteBackupRequest{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:    f.Namespace(),
+			GenerateName: o.BackupName + "-",
+			Labels: map[string]string{
+				api.BackupNameLabel: o.BackupName,
+			},
+		},
+		Spec: api.DeleteBackupRequestSpec{
+			BackupName: o.BackupName,
 		},
 	}
+	if u, err := user.Current(); err == nil {
+		req.Spec.Requester = u.Username
+	}
 
-	return c
+	req, err = arkClient.ArkV1().DeleteBackupRequests(f.Namespace()).Create(req)
+	if err != nil {
+		return err
+	}
+
+	if !o.Wait {
+		fmt.Printf("Request to delete backup %q submitted successfully.\nRun `kubectl get deletebackuprequest %s -n %s -o yaml` to see its outcome.\n", o.BackupName, req.Name, f.Namespace())
+		return nil
+	}
+
+	fmt.Printf("Request to delete backup %q submitted successfully, waiting for it to be processed...\n", o.BackupName)
+
+	return waitForDeletion(arkClient.ArkV1(), f.Namespace(), req.Name, o.WaitTimeout, os.Stdout)
+}
+
+// waitForDeletion polls the named DeleteBackupRequest in namespace until it's been processed, then prints what
+// was deleted to w. It returns an error if the deletion failed.
+func waitForDeletion(client arkv1client.DeleteBackupRequestsGetter, namespace, name string, timeout time.Duration, w io.Writer) error {
+	var req *api.DeleteBackupRequest
+	err := wait.PollImmediate(time.Second, timeout, func() (bool, error) {
+		var err error
+		if req, err = client.DeleteBackupRequests(namespace).Get(name, metav1.GetOptions{}); err != nil {
+			return false, err
+		}
+		return req.Status.Phase == api.DeleteBackupRequestPhaseProcessed, nil
+	})
+	if err == wait.ErrWaitTimeout {
+		return fmt.Errorf("timed out waiting for delete backup request %q to be processed", name)
+	}
+	if err != nil {
+		return fmt.Errorf("error waiting for delete backup request %q to be processed: %v", name, err)
+	}
+
+	fmt.Fprintf(w, "Volume snapshots deleted: %d\n", len(req.Status.DeletedSnapshots))
+	for _, snapshotID := range req.Status.DeletedSnapshots {
+		fmt.Fprintf(w, "\t%s\n", snapshotID)
+	}
+	if req.Status.BackupDataDeleted {
+		fmt.Fprintln(w, "Data in object storage deleted: yes")
+	} else {
+		fmt.Fprintln(w, "Data in object storage deleted: no")
+	}
+
+	if len(req.Status.Errors) > 0 {
+		for _, e := range req.Status.Errors {
+			fmt.Fprintf(w, "Error: %s\n", e)
+		}
+		return fmt.Errorf("backup %q was not deleted", req.Spec.BackupName)
+	}
+
+	fmt.Fprintf(w, "Backup %q deleted.\n", req.Spec.BackupName)
+	return nil
+}
+
+// getConfirmation prints prompt to w and reads a yes or no answer from r, returning true only for
+// yes. Anything other than y or yes (in any case), including the end of input, counts as no.
+func getConfirmation(r io.Reader, w io.Writer, prompt string) bool {
+	fmt.Fprintf(w, "%s [y/N]: ", prompt)
+
+	answer, err := bufio.NewReader(r).ReadString('\n')
+	if err != nil && answer == "" {
+		fmt.Fprintln(w)
+		return false
+	}
+
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return true
+	default:
+		return false
+	}
 }
@@ -17,18 +17,105 @@ limitations under the License.
 package backup
 
 import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
+	api "github.com/heptio/ark/pkg/apis/ark/v1"
 	"github.com/heptio/ark/pkg/client"
+	"github.com/heptio/ark/pkg/cmd"
+	"github.com/heptio/ark/pkg/cmd/util/downloadrequest"
+	"github.com/heptio/ark/pkg/cmd/util/output"
 )
 
 func NewDescribeCommand(f client.Factory) *cobra.Command {
+	o := NewDescribeOptions()
+
 	c := &cobra.Command{
-		Use:   "describe",
-		Short: "Describe a backup",
+		Use:   "describe NAME [NAME...]",
+		Short: "Describe backups",
+		Long:  "Print a human-readable description of one or more backups. With --details, the messages of a backup's warnings and errors are read from its log in object storage, using a temporary URL generated by the Ark server.",
 		Run: func(c *cobra.Command, args []string) {
+			cmd.CheckError(o.Validate(args))
+			cmd.CheckError(o.Complete(args))
+			cmd.CheckError(o.Run(f, os.Stdout))
 		},
 	}
 
+	o.BindFlags(c.Flags())
+
 	return c
 }
+
+type DescribeOptions struct {
+	Names   []string
+	Details bool
+	Timeout time.Duration
+}
+
+func NewDescribeOptions() *DescribeOptions {
+	return &DescribeOptions{
+		Timeout: time.Minute,
+	}
+}
+
+func (o *DescribeOptions) BindFlags(flags *pflag.FlagSet) {
+	flags.BoolVar(&o.Details, "details", o.Details, "list the messages of the backup's warnings and errors, which are read from its log")
+	flags.DurationVar(&o.Timeout, "timeout", o.Timeout, "maximum time to wait to process download request when --details is used")
+}
+
+func (o *DescribeOptions) Validate(args []string) error {
+	if len(args) == 0 {
+		return errors.New("you must specify at least one argument, the name of a backup")
+	}
+
+	return nil
+}
+
+func (o *DescribeOptions) Complete(args []string) error {
+	o.Names = args
+	return nil
+}
+
+func (o *DescribeOptions) Run(f client.Factory, w io.Writer) error {
+	arkClient, err := f.Client()
+	if err != nil {
+		return err
+	}
+
+	for i, name := range o.Names {
+		backup, err := arkClient.ArkV1().Backups(f.Namespace()).Get(name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+
+		var logMessages *output.BackupLogMessages
+		// only backups that were uploaded have a log, and it only needs to be read if there are
+		// messages in it to list
+		if o.Details && backup.Status.Warnings+backup.Status.Errors > 0 {
+			buf := new(bytes.Buffer)
+			if err := downloadrequest.Stream(arkClient.ArkV1(), f.Namespace(), name, api.DownloadTargetKindBackupLog, buf, o.Timeout); err != nil {
+				return fmt.Errorf("error downloading log of backup %q: %v", name, err)
+			}
+
+			if logMessages, err = output.ParseBackupLog(buf); err != nil {
+				return fmt.Errorf("error reading log of backup %q: %v", name, err)
+			}
+		}
+
+		if i > 0 {
+			fmt.Fprintln(w)
+		}
+		fmt.Fprint(w, output.DescribeBackup(backup, logMessages))
+	}
+
+	return nil
+}
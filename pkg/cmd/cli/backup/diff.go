@@ -0,0 +1,203 @@
+/*
+Copyright 2017 Heptio Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package backup
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/ghodss/yaml"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	api "github.com/heptio/ark/pkg/apis/ark/v1"
+	"github.com/heptio/ark/pkg/client"
+	"github.com/heptio/ark/pkg/cmd"
+	"github.com/heptio/ark/pkg/cmd/util/backupdiff"
+	"github.com/heptio/ark/pkg/cmd/util/downloadrequest"
+	arkv1client "github.com/heptio/ark/pkg/generated/clientset/typed/ark/v1"
+)
+
+func NewDiffCommand(f client.Factory) *cobra.Command {
+	o := NewDiffOptions()
+
+	c := &cobra.Command{
+		Use:   "diff OLD NEW",
+		Short: "Compare the contents of two backups",
+		Long: `Download two backups and list the items that were added to, removed from, or changed in the second one since
+the first, by resource, namespace, and name, to audit how the cluster's configuration drifted between them. For
+changed items, the fields that differ are listed; metadata that the API server maintains on its own, like
+resourceVersion and uid, is ignored.
+
+The Ark server generates temporary URLs for the backups, so no object storage credentials are needed. Incremental
+backups are compared with the items they'd restore, so their ancestors are downloaded too.`,
+		Run: func(c *cobra.Command, args []string) {
+			cmd.CheckError(o.Validate(args))
+			cmd.CheckError(o.Complete(args))
+			cmd.CheckError(o.Run(f, os.Stdout))
+		},
+	}
+
+	o.BindFlags(c.Flags())
+
+	return c
+}
+
+type DiffOptions struct {
+	OldName string
+	NewName string
+	Output  string
+	Timeout time.Duration
+}
+
+func NewDiffOptions() *DiffOptions {
+	return &DiffOptions{
+		Output:  "table",
+		Timeout: time.Minute,
+	}
+}
+
+func (o *DiffOptions) BindFlags(flags *pflag.FlagSet) {
+	flags.StringVarP(&o.Output, "output", "o", o.Output, "Output display format. Valid formats are 'table', 'json', and 'yaml'.")
+	flags.DurationVar(&o.Timeout, "timeout", o.Timeout, "maximum time to wait to process each download request")
+}
+
+func (o *DiffOptions) Validate(args []string) error {
+	if len(args) != 2 {
+		return errors.New("you must specify two arguments, the names of the backups to compare")
+	}
+
+	switch o.Output {
+	case "table", "json", "yaml":
+	default:
+		return fmt.Errorf("invalid output format %q; valid values are 'table', 'json', and 'yaml'", o.Output)
+	}
+
+	return nil
+}
+
+func (o *DiffOptions) Complete(args []string) error {
+	o.OldName = args[0]
+	o.NewName = args[1]
+	return nil
+}
+
+func (o *DiffOptions) Run(f client.Factory, w io.Writer) error {
+	arkClient, err := f.Client()
+	if err != nil {
+		return err
+	}
+
+	oldItems, err := o.downloadItems(arkClient.ArkV1(), f.Namespace(), o.OldName)
+	if err != nil {
+		return err
+	}
+
+	newItems, err := o.downloadItems(arkClient.ArkV1(), f.Namespace(), o.NewName)
+	if err != nil {
+		return err
+	}
+
+	result, err := backupdiff.Diff(oldItems, newItems)
+	if err != nil {
+		return err
+	}
+
+	switch o.Output {
+	case "json":
+		data, err := json.MarshalIndent(result, "", "    ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(w, string(data))
+		return err
+	case "yaml":
+		data, err := yaml.Marshal(result)
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(data)
+		return err
+	}
+
+	if result.Empty() {
+		fmt.Fprintf(w, "Backups %s and %s have the same items.\n", o.OldName, o.NewName)
+		return nil
+	}
+
+	tw := tabwriter.NewWriter(w, 10, 4, 3, ' ', 0)
+	fmt.Fprintln(tw, "CHANGE\tRESOURCE\tNAMESPACE\tNAME\tFIELDS")
+	for _, item := range result.Added {
+		fmt.Fprintf(tw, "added\t%s\t%s\t%s\t\n", item.Resource, item.Namespace, item.Name)
+	}
+	for _, item := range result.Removed {
+		fmt.Fprintf(tw, "removed\t%s\t%s\t%s\t\n", item.Resource, item.Namespace, item.Name)
+	}
+	for _, change := range result.Changed {
+		fmt.Fprintf(tw, "changed\t%s\t%s\t%s\t%s\n", change.Resource, change.Namespace, change.Name, strings.Join(change.Fields, ","))
+	}
+	return tw.Flush()
+}
+
+// downloadItems downloads the named backup in namespace, along with its ancestors if it's incremental, and
+// returns its items layered the same way a restore does.
+func (o *DiffOptions) downloadItems(client arkv1client.ArkV1Interface, namespace, name string) (map[string][]byte, error) {
+	var archives []*backupdiff.Archive
+
+	seen := sets.NewString()
+	for current := name; current != ""; {
+		if seen.Has(current) {
+			return nil, fmt.Errorf("backup %s has a cycle in its parent backups", name)
+		}
+		seen.Insert(current)
+
+		// only backups that ran to completion were uploaded, so check before waiting on a
+		// download request that can't be fulfilled
+		backup, err := client.Backups(namespace).Get(current, metav1.GetOptions{})
+		if err != nil {
+			return nil, err
+		}
+		if backup.Status.Phase != api.BackupPhaseCompleted && backup.Status.Phase != api.BackupPhasePartiallyFailed {
+			return nil, fmt.Errorf("backup %s has phase %s; only %s and %s backups can be compared", current, backup.Status.Phase, api.BackupPhaseCompleted, api.BackupPhasePartiallyFailed)
+		}
+
+		buf := new(bytes.Buffer)
+		if err := downloadrequest.Stream(client, namespace, current, api.DownloadTargetKindBackupContents, buf, o.Timeout); err != nil {
+			return nil, fmt.Errorf("error downloading backup %s: %v", current, err)
+		}
+
+		archive, err := backupdiff.ReadArchive(buf)
+		if err != nil {
+			return nil, fmt.Errorf("error reading backup %s: %v", current, err)
+		}
+		archives = append([]*backupdiff.Archive{archive}, archives...)
+
+		current = backup.Spec.ParentBackup
+	}
+
+	return backupdiff.Layer(archives...), nil
+}
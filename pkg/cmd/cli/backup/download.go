@@ -0,0 +1,141 @@
+/*
+Copyright 2017 Heptio Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package backup
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	api "github.com/heptio/ark/pkg/apis/ark/v1"
+	"github.com/heptio/ark/pkg/client"
+	"github.com/heptio/ark/pkg/cmd"
+	"github.com/heptio/ark/pkg/cmd/util/downloadrequest"
+)
+
+func NewDownloadCommand(f client.Factory) *cobra.Command {
+	o := NewDownloadOptions()
+
+	c := &cobra.Command{
+		Use:   "download NAME",
+		Short: "Download a backup",
+		Long:  "Download the contents of a backup as a gzipped tarball. The Ark server generates a temporary URL for the file, so no object storage credentials are needed. Use --output - to write the tarball to stdout, e.g. to pipe it into other tools.",
+		Run: func(c *cobra.Command, args []string) {
+			cmd.CheckError(o.Validate(args))
+			cmd.CheckError(o.Complete(args))
+			cmd.CheckError(o.Run(f))
+		},
+	}
+
+	o.BindFlags(c.Flags())
+
+	return c
+}
+
+type DownloadOptions struct {
+	Name         string
+	Output       string
+	Force        bool
+	Timeout      time.Duration
+	writeOptions int
+	// out is where the tarball is written with --output -, and messages otherwise.
+	out io.Writer
+}
+
+func NewDownloadOptions() *DownloadOptions {
+	return &DownloadOptions{
+		Timeout: time.Minute,
+		out:     os.Stdout,
+	}
+}
+
+func (o *DownloadOptions) BindFlags(flags *pflag.FlagSet) {
+	flags.StringVarP(&o.Output, "output", "o", o.Output, "path to output file, or - for stdout. Defaults to <NAME>-data.tar.gz in the current directory")
+	flags.BoolVar(&o.Force, "force", o.Force, "forces the download and will overwrite file if it exists already")
+	flags.DurationVar(&o.Timeout, "timeout", o.Timeout, "maximum time to wait to process download request")
+}
+
+func (o *DownloadOptions) Validate(args []string) error {
+	if len(args) != 1 {
+		return errors.New("you must specify only one argument, the backup's name")
+	}
+
+	return nil
+}
+
+func (o *DownloadOptions) Complete(args []string) error {
+	o.Name = args[0]
+
+	o.writeOptions = os.O_RDWR | os.O_CREATE | os.O_EXCL
+	if o.Force {
+		o.writeOptions = os.O_RDWR | os.O_CREATE | os.O_TRUNC
+	}
+
+	if o.Output == "" {
+		path, err := os.Getwd()
+		if err != nil {
+			return fmt.Errorf("error getting current directory: %v", err)
+		}
+		o.Output = filepath.Join(path, fmt.Sprintf("%s-data.tar.gz", o.Name))
+	}
+
+	return nil
+}
+
+func (o *DownloadOptions) Run(f client.Factory) error {
+	arkClient, err := f.Client()
+	if err != nil {
+		return err
+	}
+
+	// only backups that ran to completion were uploaded, so check before waiting on a download
+	// request that can't be fulfilled
+	backup, err := arkClient.ArkV1().Backups(f.Namespace()).Get(o.Name, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	if backup.Status.Phase != api.BackupPhaseCompleted && backup.Status.Phase != api.BackupPhasePartiallyFailed {
+		return fmt.Errorf("backup %s has phase %s; only %s and %s backups can be downloaded", o.Name, backup.Status.Phase, api.BackupPhaseCompleted, api.BackupPhasePartiallyFailed)
+	}
+
+	if o.Output == "-" {
+		return downloadrequest.Stream(arkClient.ArkV1(), f.Namespace(), o.Name, api.DownloadTargetKindBackupContents, o.out, o.Timeout)
+	}
+
+	backupDest, err := os.OpenFile(o.Output, o.writeOptions, 0600)
+	if err != nil {
+		return err
+	}
+	defer backupDest.Close()
+
+	err = downloadrequest.Stream(arkClient.ArkV1(), f.Namespace(), o.Name, api.DownloadTargetKindBackupContents, backupDest, o.Timeout)
+	if err != nil {
+		os.Remove(o.Output)
+		return err
+	}
+
+	fmt.Fprintf(o.out, "Backup %s has been successfully downloaded to %s\n", o.Name, backupDest.Name())
+	return nil
+}
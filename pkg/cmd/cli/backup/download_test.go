@@ -0,0 +1,164 @@
+/*
+Copyright 2017 Heptio Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package backup
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	api "github.com/heptio/ark/pkg/apis/ark/v1"
+	"github.com/heptio/ark/pkg/generated/clientset/fake"
+	. "github.com/heptio/ark/pkg/util/test"
+)
+
+func TestDownload(t *testing.T) {
+	contents := gzipped(t, "contents")
+
+	tests := []struct {
+		name          string
+		phase         api.BackupPhase
+		backupName    string
+		output        string
+		existing      bool
+		force         bool
+		expectedError string
+		expectedFile  []byte
+		expectedOut   []byte
+	}{
+		{
+			name:         "completed backups are downloaded to the output file",
+			phase:        api.BackupPhaseCompleted,
+			output:       "backup.tar.gz",
+			expectedFile: contents,
+		},
+		{
+			name:         "partially failed backups can be downloaded",
+			phase:        api.BackupPhasePartiallyFailed,
+			output:       "backup.tar.gz",
+			expectedFile: contents,
+		},
+		{
+			name:          "backups that haven't finished can't be downloaded",
+			phase:         api.BackupPhaseInProgress,
+			output:        "backup.tar.gz",
+			expectedError: "backup backup-1 has phase InProgress; only Completed and PartiallyFailed backups can be downloaded",
+		},
+		{
+			name:          "failed backups can't be downloaded",
+			phase:         api.BackupPhaseFailed,
+			output:        "backup.tar.gz",
+			expectedError: "backup backup-1 has phase Failed; only Completed and PartiallyFailed backups can be downloaded",
+		},
+		{
+			name:        "--output - writes the tarball to stdout",
+			phase:       api.BackupPhaseCompleted,
+			output:      "-",
+			expectedOut: contents,
+		},
+		{
+			name:          "existing files aren't overwritten",
+			phase:         api.BackupPhaseCompleted,
+			output:        "backup.tar.gz",
+			existing:      true,
+			expectedError: "file exists",
+			expectedFile:  []byte("existing"),
+		},
+		{
+			name:         "existing files are overwritten with --force",
+			phase:        api.BackupPhaseCompleted,
+			output:       "backup.tar.gz",
+			existing:     true,
+			force:        true,
+			expectedFile: contents,
+		},
+		{
+			name:          "the output file is removed if the download fails",
+			phase:         api.BackupPhaseCompleted,
+			backupName:    "backup-2",
+			output:        "backup.tar.gz",
+			expectedError: "request failed: 404 Not Found",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "")
+			require.NoError(t, err)
+			defer os.RemoveAll(dir)
+
+			backupName := test.backupName
+			if backupName == "" {
+				backupName = "backup-1"
+			}
+
+			client := fake.NewSimpleClientset(
+				NewTestBackup().WithNamespace("ark-ns").WithName(backupName).WithPhase(test.phase).Backup,
+			)
+			server := NewFakeDownloadServer(client, map[api.DownloadTarget][]byte{
+				{Kind: api.DownloadTargetKindBackupContents, Name: "backup-1"}: contents,
+				{Kind: api.DownloadTargetKindBackupContents, Name: "backup-2"}: nil,
+			})
+			defer server.Close()
+
+			output := test.output
+			if output != "-" {
+				output = filepath.Join(dir, output)
+			}
+			if test.existing {
+				require.NoError(t, ioutil.WriteFile(output, []byte("existing"), 0600))
+			}
+
+			o := NewDownloadOptions()
+			o.Output = output
+			o.Force = test.force
+			o.Timeout = time.Second
+			out := new(bytes.Buffer)
+			o.out = out
+			require.NoError(t, o.Complete([]string{backupName}))
+
+			err = o.Run(NewFakeFactory(client, nil, "ark-ns"))
+			if test.expectedError != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), test.expectedError)
+			} else {
+				require.NoError(t, err)
+			}
+
+			if test.expectedOut != nil {
+				assert.Equal(t, test.expectedOut, out.Bytes())
+			}
+
+			if output == "-" {
+				return
+			}
+			data, err := ioutil.ReadFile(output)
+			if test.expectedFile == nil {
+				assert.True(t, os.IsNotExist(err), "expected %s not to exist, got error %v", output, err)
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, test.expectedFile, data)
+			}
+		})
+	}
+}
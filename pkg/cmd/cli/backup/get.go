@@ -17,9 +17,14 @@ limitations under the License.
 package backup
 
 import (
+	"fmt"
+	"strings"
+
 	"github.com/spf13/cobra"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/util/sets"
 
 	api "github.com/heptio/ark/pkg/apis/ark/v1"
 	"github.com/heptio/ark/pkg/client"
@@ -28,15 +33,28 @@ import (
 )
 
 func NewGetCommand(f client.Factory) *cobra.Command {
-	var listOptions metav1.ListOptions
+	var (
+		listOptions   metav1.ListOptions
+		clusterName   string
+		sortBy        string
+		fieldSelector string
+	)
 
 	c := &cobra.Command{
 		Use:   "get",
 		Short: "Get backups",
+		Long: `Get backups, optionally filtered by label selector (-l) or field selector (--field-selector), and sorted by
+one of their fields (--sort-by).
+
+Field selectors are evaluated by the CLI, and can use the fields ` + strings.Join(backupSelectorFields.List(), ", ") + `, e.g.
+--field-selector phase=Failed or --field-selector schedule=daily,phase!=Completed.`,
 		Run: func(c *cobra.Command, args []string) {
 			err := output.ValidateFlags(c)
 			cmd.CheckError(err)
 
+			selector, err := parseBackupFieldSelector(fieldSelector)
+			cmd.CheckError(err)
+
 			arkClient, err := f.Client()
 			cmd.CheckError(err)
 
@@ -44,23 +62,81 @@ func NewGetCommand(f client.Factory) *cobra.Command {
 			if len(args) > 0 {
 				backups = new(api.BackupList)
 				for _, name := range args {
-					backup, err := arkClient.Ark().Backups(api.DefaultNamespace).Get(name, metav1.GetOptions{})
+					backup, err := arkClient.Ark().Backups(f.Namespace()).Get(name, metav1.GetOptions{})
 					cmd.CheckError(err)
 					backups.Items = append(backups.Items, *backup)
 				}
 			} else {
-				backups, err = arkClient.ArkV1().Backups(api.DefaultNamespace).List(metav1.ListOptions{})
+				if clusterName != "" {
+					clusterSelector := fmt.Sprintf("%s=%s", api.ClusterNameLabel, clusterName)
+					if listOptions.LabelSelector == "" {
+						listOptions.LabelSelector = clusterSelector
+					} else {
+						listOptions.LabelSelector += "," + clusterSelector
+					}
+				}
+
+				backups, err = arkClient.ArkV1().Backups(f.Namespace()).List(listOptions)
 				cmd.CheckError(err)
 			}
 
+			filtered := backups.Items[:0]
+			for _, backup := range backups.Items {
+				if selector.Matches(backupFields(&backup)) {
+					filtered = append(filtered, backup)
+				}
+			}
+			backups.Items = filtered
+
+			cmd.CheckError(output.SortBackups(backups, sortBy))
+
 			_, err = output.PrintWithFormat(c, backups)
 			cmd.CheckError(err)
 		},
 	}
 
 	c.Flags().StringVarP(&listOptions.LabelSelector, "selector", "l", listOptions.LabelSelector, "only show items matching this label selector")
+	c.Flags().StringVar(&clusterName, "cluster", clusterName, "only show backups taken in the cluster with this name")
+	c.Flags().StringVar(&fieldSelector, "field-selector", fieldSelector, "only show backups whose fields match this selector, e.g. phase=Failed")
+	c.Flags().StringVar(&sortBy, "sort-by", sortBy, fmt.Sprintf("field to sort backups by: %s. Optional; by default, backups are sorted by name, with those of each schedule newest first.", strings.Join(output.BackupSortFields, ", ")))
 
 	output.BindFlags(c.Flags())
 
 	return c
 }
+
+// backupSelectorFields are the fields that backups can be selected by with --field-selector.
+var backupSelectorFields = sets.NewString("name", "phase", "schedule", "storageLocation", "parentBackup")
+
+// parseBackupFieldSelector parses a field selector for backups, returning an error if it uses
+// fields that backups can't be selected by.
+func parseBackupFieldSelector(selector string) (fields.Selector, error) {
+	parsed, err := fields.ParseSelector(selector)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, requirement := range parsed.Requirements() {
+		if !backupSelectorFields.Has(requirement.Field) {
+			return nil, fmt.Errorf("backups can't be selected by field %q; valid fields are %s", requirement.Field, strings.Join(backupSelectorFields.List(), ", "))
+		}
+	}
+
+	return parsed, nil
+}
+
+// backupFields returns the fields of a backup that a field selector can match.
+func backupFields(backup *api.Backup) fields.Set {
+	phase := backup.Status.Phase
+	if phase == "" {
+		phase = api.BackupPhaseNew
+	}
+
+	return fields.Set{
+		"name":            backup.Name,
+		"phase":           string(phase),
+		"schedule":        backup.Labels[api.ScheduleNameLabel],
+		"storageLocation": backup.Spec.StorageLocation,
+		"parentBackup":    backup.Spec.ParentBackup,
+	}
+}
@@ -0,0 +1,88 @@
+/*
+Copyright 2017 Heptio Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package backup
+
+import (
+	"errors"
+	"io"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+
+	api "github.com/heptio/ark/pkg/apis/ark/v1"
+	"github.com/heptio/ark/pkg/client"
+	"github.com/heptio/ark/pkg/cmd"
+	"github.com/heptio/ark/pkg/cmd/util/downloadrequest"
+)
+
+func NewLogsCommand(f client.Factory) *cobra.Command {
+	o := NewLogsOptions()
+
+	c := &cobra.Command{
+		Use:   "logs NAME",
+		Short: "Get the log of a backup",
+		Long:  "Print the log of a backup to stdout. The Ark server generates a temporary URL for it, so no object storage credentials are needed.",
+		Run: func(c *cobra.Command, args []string) {
+			cmd.CheckError(o.Validate(args))
+			cmd.CheckError(o.Complete(args))
+			cmd.CheckError(o.Run(f, os.Stdout))
+		},
+	}
+
+	o.BindFlags(c.Flags())
+
+	return c
+}
+
+type LogsOptions struct {
+	BackupName string
+	Timeout    time.Duration
+}
+
+func NewLogsOptions() *LogsOptions {
+	return &LogsOptions{
+		Timeout: time.Minute,
+	}
+}
+
+func (o *LogsOptions) BindFlags(flags *pflag.FlagSet) {
+	flags.DurationVar(&o.Timeout, "timeout", o.Timeout, "maximum time to wait to process download request")
+}
+
+func (o *LogsOptions) Validate(args []string) error {
+	if len(args) != 1 {
+		return errors.New("you must specify only one argument, the backup's name")
+	}
+
+	return nil
+}
+
+func (o *LogsOptions) Complete(args []string) error {
+	o.BackupName = args[0]
+	return nil
+}
+
+func (o *LogsOptions) Run(f client.Factory, w io.Writer) error {
+	arkClient, err := f.Client()
+	if err != nil {
+		return err
+	}
+
+	return downloadrequest.Stream(arkClient.ArkV1(), f.Namespace(), o.BackupName, api.DownloadTargetKindBackupLog, w, o.Timeout)
+}
@@ -0,0 +1,71 @@
+/*
+Copyright 2017 Heptio Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package backup
+
+import (
+	"bytes"
+	"compress/gzip"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	api "github.com/heptio/ark/pkg/apis/ark/v1"
+	"github.com/heptio/ark/pkg/generated/clientset/fake"
+	. "github.com/heptio/ark/pkg/util/test"
+)
+
+func gzipped(t *testing.T, s string) []byte {
+	buf := new(bytes.Buffer)
+	gzw := gzip.NewWriter(buf)
+	_, err := gzw.Write([]byte(s))
+	require.NoError(t, err)
+	require.NoError(t, gzw.Close())
+	return buf.Bytes()
+}
+
+func TestLogs(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	target := api.DownloadTarget{Kind: api.DownloadTargetKindBackupLog, Name: "backup-1"}
+	server := NewFakeDownloadServer(client, map[api.DownloadTarget][]byte{
+		target: gzipped(t, "log line 1\nlog line 2\n"),
+	})
+	defer server.Close()
+
+	o := NewLogsOptions()
+	o.Timeout = time.Second
+	require.NoError(t, o.Validate([]string{"backup-1"}))
+	require.NoError(t, o.Complete([]string{"backup-1"}))
+
+	buf := new(bytes.Buffer)
+	require.NoError(t, o.Run(NewFakeFactory(client, nil, "ark-ns"), buf))
+
+	assert.Equal(t, "log line 1\nlog line 2\n", buf.String())
+
+	// the log is requested from the server in the namespace it's installed in
+	require.Len(t, server.Requests, 1)
+	assert.Equal(t, "ark-ns", server.Requests[0].Namespace)
+	assert.Equal(t, target, server.Requests[0].Spec.Target)
+}
+
+func TestLogsValidate(t *testing.T) {
+	o := NewLogsOptions()
+	assert.Error(t, o.Validate(nil))
+	assert.Error(t, o.Validate([]string{"backup-1", "backup-2"}))
+	assert.NoError(t, o.Validate([]string{"backup-1"}))
+}
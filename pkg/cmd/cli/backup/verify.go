@@ -0,0 +1,152 @@
+/*
+Copyright 2017 Heptio Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package backup
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+
+	api "github.com/heptio/ark/pkg/apis/ark/v1"
+	"github.com/heptio/ark/pkg/client"
+	"github.com/heptio/ark/pkg/cmd"
+)
+
+func NewVerifyCommand(f client.Factory) *cobra.Command {
+	o := NewVerifyOptions()
+
+	c := &cobra.Command{
+		Use:   "verify NAME",
+		Short: "Verify the integrity of a backup",
+		Long:  "Verify that a backup's metadata and archive in object storage are readable, that the archive matches the checksum recorded when the backup was taken, and that all of its volume snapshots still exist.",
+		Run: func(c *cobra.Command, args []string) {
+			cmd.CheckError(o.Validate(args))
+			cmd.CheckError(o.Complete(args))
+			cmd.CheckError(o.Run(f))
+		},
+	}
+
+	o.BindFlags(c.Flags())
+
+	return c
+}
+
+type VerifyOptions struct {
+	BackupName string
+	Timeout    time.Duration
+}
+
+func NewVerifyOptions() *VerifyOptions {
+	return &VerifyOptions{
+		Timeout: 10 * time.Minute,
+	}
+}
+
+func (o *VerifyOptions) BindFlags(flags *pflag.FlagSet) {
+	flags.DurationVar(&o.Timeout, "timeout", o.Timeout, "how long to wait for the verification to complete")
+}
+
+func (o *VerifyOptions) Validate(args []string) error {
+	if len(args) != 1 {
+		return errors.New("you must specify only one argument, the backup's name")
+	}
+
+	return nil
+}
+
+func (o *VerifyOptions) Complete(args []string) error {
+	o.BackupName = args[0]
+	return nil
+}
+
+func (o *VerifyOptions) Run(f client.Factory) error {
+	arkClient, err := f.Client()
+	if err != nil {
+		return err
+	}
+
+	verification := &api.BackupVerification{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: f.Namespace(),
+			Name:      fmt.Sprintf("%s-%s", o.BackupName, time.Now().Format("20060102150405")),
+		},
+		Spec: api.BackupVerificationSpec{
+			BackupName: o.BackupName,
+		},
+	}
+
+	verifications := arkClient.ArkV1().BackupVerifications(verification.Namespace)
+
+	verification, err = verifications.Create(verification)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Backup verification %q created, waiting for it to complete...\n", verification.Name)
+
+	err = wait.PollImmediate(time.Second, o.Timeout, func() (bool, error) {
+		verification, err = verifications.Get(verification.Name, metav1.GetOptions{})
+		if err != nil {
+			return false, err
+		}
+		return verification.Status.Phase == api.BackupVerificationPhaseCompleted, nil
+	})
+	if err != nil {
+		return fmt.Errorf("error waiting for backup verification %q to complete: %v", verification.Name, err)
+	}
+
+	if err := printVerificationChecks(os.Stdout, verification); err != nil {
+		return err
+	}
+
+	if verification.Status.Unverified {
+		return fmt.Errorf("backup %q couldn't be fully verified", o.BackupName)
+	}
+	if !verification.Status.Passed {
+		return fmt.Errorf("backup %q failed verification", o.BackupName)
+	}
+
+	fmt.Printf("Backup %q passed verification.\n", o.BackupName)
+	return nil
+}
+
+func printVerificationChecks(w io.Writer, verification *api.BackupVerification) error {
+	tw := tabwriter.NewWriter(w, 10, 4, 3, ' ', 0)
+
+	fmt.Fprintln(tw, "CHECK\tRESULT\tMESSAGE")
+	for _, check := range verification.Status.Checks {
+		result := "Failed"
+		switch {
+		case check.Passed:
+			result = "Passed"
+		case check.Unverified:
+			result = "Unverified"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", check.Name, result, check.Message)
+	}
+
+	return tw.Flush()
+}
@@ -0,0 +1,65 @@
+/*
+Copyright 2017 Heptio Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package backuplocation
+
+import (
+	"github.com/spf13/cobra"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	api "github.com/heptio/ark/pkg/apis/ark/v1"
+	"github.com/heptio/ark/pkg/client"
+	arkv1client "github.com/heptio/ark/pkg/generated/clientset/typed/ark/v1"
+)
+
+// configName is the name of the Config that the Ark server reads, in the Ark namespace.
+const configName = "default"
+
+func NewCommand(f client.Factory) *cobra.Command {
+	c := &cobra.Command{
+		Use:   "backup-location",
+		Short: "Work with backup storage locations",
+		Long: `Work with the Ark server's backup storage locations: the buckets, and prefixes within them, in object storage
+that backups can be stored in.
+
+Each location is a BackupStorageLocation in the Ark namespace. Backups without a --storage-location are stored in the
+location named by the server's Config, "` + api.DefaultBackupStorageLocation + `" unless it's changed with set-default. Read-only locations
+can be synced and restored from, but backups can't be stored in them. The Ark server restarts to pick up changes to
+the locations.`,
+	}
+
+	c.AddCommand(
+		NewGetCommand(f),
+		NewCreateCommand(f),
+		NewSetDefaultCommand(f),
+	)
+
+	return c
+}
+
+// getConfig returns the Config in namespace that the Ark server reads.
+func getConfig(client arkv1client.ConfigsGetter, namespace string) (*api.Config, error) {
+	return client.Configs(namespace).Get(configName, metav1.GetOptions{})
+}
+
+// defaultLocation returns the name of config's default backup storage location.
+func defaultLocation(config *api.Config) string {
+	if config.DefaultBackupStorageLocation != "" {
+		return config.DefaultBackupStorageLocation
+	}
+	return api.DefaultBackupStorageLocation
+}
@@ -0,0 +1,162 @@
+/*
+Copyright 2017 Heptio Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package backuplocation
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	api "github.com/heptio/ark/pkg/apis/ark/v1"
+	"github.com/heptio/ark/pkg/client"
+	"github.com/heptio/ark/pkg/cloudprovider"
+	"github.com/heptio/ark/pkg/cmd"
+	"github.com/heptio/ark/pkg/cmd/util/cloudconfig"
+	"github.com/heptio/ark/pkg/cmd/util/flag"
+)
+
+func NewCreateCommand(f client.Factory) *cobra.Command {
+	o := NewCreateOptions()
+
+	c := &cobra.Command{
+		Use:   "create NAME --bucket BUCKET --provider PROVIDER",
+		Short: "Create a backup storage location",
+		Long: `Create a BackupStorageLocation for the Ark server to store backups in. Its bucket may be shared with other
+locations, as long as each of them is under a different --prefix.
+
+Provider configuration is set with --config:
+` + cloudconfig.Keys,
+		Example: `  ark backup-location create secondary --bucket ark-backups-west --provider aws --config region=us-west-2
+
+  # restore from another cluster's backups, without ever writing to its bucket
+  ark backup-location create cluster-2 --bucket ark-backups --prefix cluster-2 --provider gcp --access-mode ReadOnly`,
+		Run: func(c *cobra.Command, args []string) {
+			cmd.CheckError(o.Validate(args))
+			cmd.CheckError(o.Complete(args))
+			cmd.CheckError(o.Run(f))
+		},
+	}
+
+	o.BindFlags(c.Flags())
+
+	return c
+}
+
+type CreateOptions struct {
+	Name        string
+	Bucket      string
+	Prefix      string
+	Provider    flag.Enum
+	Config      flag.Map
+	Deduplicate bool
+	AccessMode  flag.Enum
+	SetDefault  bool
+}
+
+func NewCreateOptions() *CreateOptions {
+	return &CreateOptions{
+		Provider: flag.NewEnum("", "aws", "gcp", "azure"),
+		Config:   flag.NewMap(),
+		AccessMode: flag.NewEnum(
+			string(api.BackupStorageLocationAccessModeReadWrite),
+			string(api.BackupStorageLocationAccessModeReadWrite),
+			string(api.BackupStorageLocationAccessModeReadOnly),
+		),
+	}
+}
+
+func (o *CreateOptions) BindFlags(flags *pflag.FlagSet) {
+	flags.StringVar(&o.Bucket, "bucket", o.Bucket, "the bucket to store backups in")
+	flags.StringVar(&o.Prefix, "prefix", o.Prefix, "the path within the bucket to store backups under")
+	flags.Var(&o.Provider, "provider", "the cloud provider of the bucket: aws, gcp, or azure")
+	flags.Var(&o.Config, "config", "configuration of the cloud provider, as key=value pairs")
+	flags.BoolVar(&o.Deduplicate, "deduplicate", o.Deduplicate, "store backup contents as content-addressed chunks shared by all backups in the location")
+	flags.Var(&o.AccessMode, "access-mode", "the server's access to the location: ReadWrite, or ReadOnly to only sync and restore backups from it")
+	flags.BoolVar(&o.SetDefault, "default", o.SetDefault, "make the location the default for backups without a --storage-location")
+}
+
+func (o *CreateOptions) Validate(args []string) error {
+	if len(args) != 1 {
+		return errors.New("you must specify only one argument, the location's name")
+	}
+	if o.Bucket == "" {
+		return errors.New("--bucket is required")
+	}
+	if o.Provider.String() == "" {
+		return errors.New("--provider is required")
+	}
+
+	return nil
+}
+
+func (o *CreateOptions) Complete(args []string) error {
+	o.Name = args[0]
+	return nil
+}
+
+func (o *CreateOptions) Run(f client.Factory) error {
+	providerConfig, err := cloudconfig.Parse(o.Provider.String(), o.Config.Data())
+	if err != nil {
+		return err
+	}
+
+	arkClient, err := f.Client()
+	if err != nil {
+		return err
+	}
+
+	location := &api.BackupStorageLocation{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: f.Namespace(),
+			Name:      o.Name,
+		},
+		Spec: api.BackupStorageLocationSpec{
+			ObjectStorageProviderConfig: api.ObjectStorageProviderConfig{
+				CloudProviderConfig: providerConfig,
+				Bucket:              o.Bucket,
+				Prefix:              o.Prefix,
+				Deduplicate:         o.Deduplicate,
+			},
+			AccessMode: api.BackupStorageLocationAccessMode(o.AccessMode.String()),
+		},
+	}
+
+	// the server won't start with overlapping locations, so refuse to create one
+	existing, err := arkClient.ArkV1().BackupStorageLocations(f.Namespace()).List(metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+	for _, other := range existing.Items {
+		if cloudprovider.LocationsOverlap(location.Spec.ObjectStorageProviderConfig, other.Spec.ObjectStorageProviderConfig) {
+			return fmt.Errorf("bucket %s is already used by backup storage location %q; use a --prefix neither of them is under", o.Bucket, other.Name)
+		}
+	}
+
+	if _, err := arkClient.ArkV1().BackupStorageLocations(f.Namespace()).Create(location); err != nil {
+		return err
+	}
+	fmt.Printf("Backup storage location %q created.\n", o.Name)
+
+	if o.SetDefault {
+		return setDefault(arkClient.ArkV1(), f.Namespace(), o.Name)
+	}
+	return nil
+}
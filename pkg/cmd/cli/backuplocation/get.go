@@ -0,0 +1,95 @@
+/*
+Copyright 2017 Heptio Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package backuplocation
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	api "github.com/heptio/ark/pkg/apis/ark/v1"
+	"github.com/heptio/ark/pkg/client"
+	"github.com/heptio/ark/pkg/cloudprovider"
+	"github.com/heptio/ark/pkg/cmd"
+)
+
+func NewGetCommand(f client.Factory) *cobra.Command {
+	c := &cobra.Command{
+		Use:   "get [NAME...]",
+		Short: "Get backup storage locations",
+		Long:  "List the Ark server's backup storage locations, or the named ones, with their buckets and cloud providers.",
+		Run: func(c *cobra.Command, args []string) {
+			arkClient, err := f.Client()
+			cmd.CheckError(err)
+
+			config, err := getConfig(arkClient.ArkV1(), f.Namespace())
+			cmd.CheckError(err)
+
+			var locations []api.BackupStorageLocation
+			if len(args) > 0 {
+				for _, name := range args {
+					location, err := arkClient.ArkV1().BackupStorageLocations(f.Namespace()).Get(name, metav1.GetOptions{})
+					cmd.CheckError(err)
+					locations = append(locations, *location)
+				}
+			} else {
+				list, err := arkClient.ArkV1().BackupStorageLocations(f.Namespace()).List(metav1.ListOptions{})
+				cmd.CheckError(err)
+				locations = list.Items
+				sort.Slice(locations, func(i, j int) bool { return locations[i].Name < locations[j].Name })
+			}
+
+			tw := tabwriter.NewWriter(os.Stdout, 10, 4, 3, ' ', 0)
+			fmt.Fprintln(tw, "NAME\tBUCKET\tPROVIDER\tACCESS MODE\tDEDUPLICATE\tDEFAULT\tPHASE")
+			for _, location := range locations {
+				fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%t\t%t\t%s\n",
+					location.Name,
+					cloudprovider.LocationBucket(location.Spec.ObjectStorageProviderConfig),
+					cloudprovider.ProviderName(location.Spec.CloudProviderConfig),
+					accessMode(location.Spec),
+					location.Spec.Deduplicate,
+					location.Name == defaultLocation(config),
+					phase(location.Status),
+				)
+			}
+			cmd.CheckError(tw.Flush())
+		},
+	}
+
+	return c
+}
+
+// phase returns status's phase, which is Unknown until the location has been checked.
+func phase(status api.BackupStorageLocationStatus) string {
+	if status.Phase == "" {
+		return "Unknown"
+	}
+	return string(status.Phase)
+}
+
+// accessMode returns spec's access mode, which defaults to ReadWrite.
+func accessMode(spec api.BackupStorageLocationSpec) api.BackupStorageLocationAccessMode {
+	if spec.AccessMode == "" {
+		return api.BackupStorageLocationAccessModeReadWrite
+	}
+	return spec.AccessMode
+}
@@ -0,0 +1,283 @@
+/*
+Copyright 2017 Heptio Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package debug
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ghodss/yaml"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/pkg/api/v1"
+
+	api "github.com/heptio/ark/pkg/apis/ark/v1"
+	"github.com/heptio/ark/pkg/cmd/version"
+	arkv1client "github.com/heptio/ark/pkg/generated/clientset/typed/ark/v1"
+	"github.com/heptio/ark/pkg/util/encode"
+)
+
+// bundle writes files into a gzipped tarball, under a top-level directory, and keeps track of
+// anything that couldn't be gathered into it. Ark resources and their events are gathered from
+// namespace, and the server's config, deployment, and logs from the default Ark namespace, where
+// the server always runs.
+type bundle struct {
+	gzw       *gzip.Writer
+	tw        *tar.Writer
+	dir       string
+	namespace string
+	now       time.Time
+	errs      []string
+}
+
+func newBundle(w io.Writer, dir, namespace string) *bundle {
+	gzw := gzip.NewWriter(w)
+
+	return &bundle{
+		gzw:       gzw,
+		tw:        tar.NewWriter(gzw),
+		dir:       dir,
+		namespace: namespace,
+		now:       time.Now(),
+	}
+}
+
+// add writes a file named name, relative to the bundle's directory, with the given contents.
+func (b *bundle) add(name string, data []byte) {
+	hdr := &tar.Header{
+		Name:     path.Join(b.dir, name),
+		Size:     int64(len(data)),
+		Typeflag: tar.TypeReg,
+		Mode:     0644,
+		ModTime:  b.now,
+	}
+
+	if err := b.tw.WriteHeader(hdr); err != nil {
+		b.errorf("error writing header for %s: %v", name, err)
+		return
+	}
+	if _, err := b.tw.Write(data); err != nil {
+		b.errorf("error writing %s: %v", name, err)
+	}
+}
+
+func (b *bundle) errorf(format string, args ...interface{}) {
+	b.errs = append(b.errs, fmt.Sprintf(format, args...))
+}
+
+// Close writes errors.txt, if anything couldn't be gathered, and flushes the tarball.
+func (b *bundle) Close() error {
+	if len(b.errs) > 0 {
+		b.add("errors.txt", []byte(strings.Join(b.errs, "\n")+"\n"))
+	}
+
+	if err := b.tw.Close(); err != nil {
+		return err
+	}
+	return b.gzw.Close()
+}
+
+func gatherVersions(b *bundle, client arkv1client.ServerStatusRequestsGetter, timeout time.Duration) {
+	buf := new(bytes.Buffer)
+	version.PrintClientVersion(buf)
+	if err := version.PrintServerVersion(buf, client, b.namespace, timeout); err != nil {
+		b.errorf("error getting server version: %v", err)
+	}
+
+	b.add("version.txt", buf.Bytes())
+}
+
+func gatherResources(b *bundle, client arkv1client.ArkV1Interface) {
+	backups, err := client.Backups(b.namespace).List(metav1.ListOptions{})
+	if err != nil {
+		b.errorf("error listing backups: %v", err)
+	} else {
+		for i := range backups.Items {
+			sanitizeObjectMeta(&backups.Items[i].ObjectMeta)
+		}
+		addEncoded(b, "backups.yaml", backups)
+	}
+
+	restores, err := client.Restores(b.namespace).List(metav1.ListOptions{})
+	if err != nil {
+		b.errorf("error listing restores: %v", err)
+	} else {
+		for i := range restores.Items {
+			sanitizeObjectMeta(&restores.Items[i].ObjectMeta)
+		}
+		addEncoded(b, "restores.yaml", restores)
+	}
+
+	locations, err := client.BackupStorageLocations(b.namespace).List(metav1.ListOptions{})
+	if err != nil {
+		b.errorf("error listing backup storage locations: %v", err)
+	} else {
+		for i := range locations.Items {
+			sanitizeBackupStorageLocation(&locations.Items[i])
+		}
+		addEncoded(b, "backupstoragelocations.yaml", locations)
+	}
+
+	snapshotLocations, err := client.VolumeSnapshotLocations(b.namespace).List(metav1.ListOptions{})
+	if err != nil {
+		b.errorf("error listing volume snapshot locations: %v", err)
+	} else {
+		for i := range snapshotLocations.Items {
+			sanitizeVolumeSnapshotLocation(&snapshotLocations.Items[i])
+		}
+		addEncoded(b, "volumesnapshotlocations.yaml", snapshotLocations)
+	}
+
+	schedules, err := client.Schedules(b.namespace).List(metav1.ListOptions{})
+	if err != nil {
+		b.errorf("error listing schedules: %v", err)
+	} else {
+		for i := range schedules.Items {
+			sanitizeObjectMeta(&schedules.Items[i].ObjectMeta)
+		}
+		addEncoded(b, "schedules.yaml", schedules)
+	}
+}
+
+func gatherConfig(b *bundle, client arkv1client.ConfigsGetter) {
+	config, err := client.Configs(api.DefaultNamespace).Get(configName, metav1.GetOptions{})
+	if err != nil {
+		b.errorf("error getting config: %v", err)
+		return
+	}
+
+	sanitizeConfig(config)
+	addEncoded(b, "config.yaml", config)
+}
+
+func gatherDeployment(b *bundle, client kubernetes.Interface) {
+	deployment, err := client.AppsV1beta1().Deployments(api.DefaultNamespace).Get(serverDeployment, metav1.GetOptions{})
+	if err != nil {
+		b.errorf("error getting deployment: %v", err)
+		return
+	}
+
+	sanitizeObjectMeta(&deployment.ObjectMeta)
+	sanitizePodSpec(&deployment.Spec.Template.Spec)
+	addYAML(b, "deployment.yaml", deployment)
+}
+
+func gatherEvents(b *bundle, client kubernetes.Interface) {
+	events, err := client.CoreV1().Events(b.namespace).List(metav1.ListOptions{})
+	if err != nil {
+		b.errorf("error listing events: %v", err)
+		return
+	}
+
+	sort.Slice(events.Items, func(i, j int) bool {
+		return events.Items[i].LastTimestamp.Before(events.Items[j].LastTimestamp)
+	})
+
+	buf := new(bytes.Buffer)
+	for _, event := range events.Items {
+		fmt.Fprintf(buf, "%s\t%s\t%s/%s\t%s\t%s\n",
+			event.LastTimestamp.UTC().Format(time.RFC3339),
+			event.Type,
+			strings.ToLower(event.InvolvedObject.Kind),
+			event.InvolvedObject.Name,
+			event.Reason,
+			event.Message,
+		)
+	}
+
+	b.add("events.txt", buf.Bytes())
+}
+
+// gatherLogs adds the logs of the Ark server's container in each of its pods, and the logs of
+// its previous run for pods where it has restarted.
+func gatherLogs(b *bundle, client kubernetes.Interface) {
+	pods, err := client.CoreV1().Pods(api.DefaultNamespace).List(metav1.ListOptions{
+		LabelSelector: labels.Set{"component": serverComponent}.String(),
+	})
+	if err != nil {
+		b.errorf("error listing server pods: %v", err)
+		return
+	}
+
+	for _, pod := range pods.Items {
+		addLogs(b, client, pod.Name, false)
+
+		for _, status := range pod.Status.ContainerStatuses {
+			if status.Name == serverContainer && status.RestartCount > 0 {
+				addLogs(b, client, pod.Name, true)
+			}
+		}
+	}
+}
+
+func addLogs(b *bundle, client kubernetes.Interface, pod string, previous bool) {
+	name := path.Join("logs", pod+".log")
+	if previous {
+		name = path.Join("logs", pod+".previous.log")
+	}
+
+	stream, err := client.CoreV1().Pods(api.DefaultNamespace).GetLogs(pod, &v1.PodLogOptions{
+		Container: serverContainer,
+		Previous:  previous,
+	}).Stream()
+	if err != nil {
+		b.errorf("error getting logs for %s: %v", name, err)
+		return
+	}
+	defer stream.Close()
+
+	data, err := ioutil.ReadAll(stream)
+	if err != nil {
+		b.errorf("error reading logs for %s: %v", name, err)
+		return
+	}
+
+	b.add(name, sanitizeLog(data))
+}
+
+// addEncoded adds an Ark API object, encoded as YAML with its API version and kind.
+func addEncoded(b *bundle, name string, obj runtime.Object) {
+	data, err := encode.Encode(obj, "yaml")
+	if err != nil {
+		b.errorf("error encoding %s: %v", name, err)
+		return
+	}
+
+	b.add(name, data)
+}
+
+// addYAML adds a Kubernetes API object, which isn't registered with Ark's scheme, as YAML.
+func addYAML(b *bundle, name string, obj interface{}) {
+	data, err := yaml.Marshal(obj)
+	if err != nil {
+		b.errorf("error encoding %s: %v", name, err)
+		return
+	}
+
+	b.add(name, data)
+}
@@ -0,0 +1,241 @@
+/*
+Copyright 2017 Heptio Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package debug
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes"
+	appsv1beta1client "k8s.io/client-go/kubernetes/typed/apps/v1beta1"
+	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/pkg/api/v1"
+	appsv1beta1 "k8s.io/client-go/pkg/apis/apps/v1beta1"
+	core "k8s.io/client-go/testing"
+
+	api "github.com/heptio/ark/pkg/apis/ark/v1"
+	"github.com/heptio/ark/pkg/generated/clientset/fake"
+	. "github.com/heptio/ark/pkg/util/test"
+)
+
+func TestRun(t *testing.T) {
+	dir, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	backup := NewTestBackup().WithNamespace("ark-ns").WithName("backup-1").Backup
+	backup.Annotations = map[string]string{lastAppliedConfigAnnotation: "{}"}
+	config := &api.Config{
+		ObjectMeta: metav1.ObjectMeta{Namespace: api.DefaultNamespace, Name: configName},
+		Notifications: &api.NotificationsConfig{
+			Webhooks: []api.NotificationWebhook{{Name: "slack", URL: "https://hooks.slack.com/services/T000/B000/XXXX"}},
+		},
+	}
+	arkClient := fake.NewSimpleClientset(backup, config)
+	arkClient.PrependReactor("get", "serverstatusrequests", func(action core.Action) (bool, runtime.Object, error) {
+		req := &api.ServerStatusRequest{}
+		req.Status.Phase = api.ServerStatusRequestPhaseProcessed
+		req.Status.ServerVersion = "v1.0.0"
+		return true, req, nil
+	})
+
+	deployment := &appsv1beta1.Deployment{ObjectMeta: metav1.ObjectMeta{Namespace: api.DefaultNamespace, Name: serverDeployment}}
+	deployment.Spec.Template.Spec.Containers = []v1.Container{{Name: serverContainer, Env: []v1.EnvVar{{Name: "AWS_SECRET", Value: "secret"}}}}
+	kubeClient := &fakeKubeClient{
+		deployment:      deployment,
+		eventsNamespace: "ark-ns",
+		events: []v1.Event{
+			{
+				InvolvedObject: v1.ObjectReference{Kind: "Backup", Name: "backup-2"},
+				Type:           v1.EventTypeWarning,
+				Reason:         "BackupFailed",
+				Message:        "failed",
+				LastTimestamp:  metav1.NewTime(time.Date(2018, 1, 1, 12, 5, 0, 0, time.UTC)),
+			},
+			{
+				InvolvedObject: v1.ObjectReference{Kind: "Backup", Name: "backup-1"},
+				Type:           v1.EventTypeNormal,
+				Reason:         "BackupCompleted",
+				Message:        "completed",
+				LastTimestamp:  metav1.NewTime(time.Date(2018, 1, 1, 12, 0, 0, 0, time.UTC)),
+			},
+		},
+	}
+
+	// Ark resources come from the chosen namespace, and the server's config and deployment from the
+	// default Ark namespace
+	o := NewOptions()
+	o.OutputFile = filepath.Join(dir, "bundle.tar.gz")
+	o.Timeout = time.Second
+	require.NoError(t, o.Complete(nil))
+	require.NoError(t, o.Run(NewFakeFactory(arkClient, kubeClient, "ark-ns")))
+
+	files := readBundle(t, o.OutputFile, o.name)
+
+	assert.Contains(t, files["version.txt"], "Client:")
+	assert.Contains(t, files["version.txt"], "Version: v1.0.0")
+
+	assert.Contains(t, files["backups.yaml"], "name: backup-1")
+	assert.NotContains(t, files["backups.yaml"], lastAppliedConfigAnnotation)
+	for _, name := range []string{"restores.yaml", "backupstoragelocations.yaml", "volumesnapshotlocations.yaml", "schedules.yaml"} {
+		assert.Contains(t, files, name)
+	}
+
+	assert.Contains(t, files["config.yaml"], "https://hooks.slack.com/"+redacted)
+	assert.NotContains(t, files["config.yaml"], "XXXX")
+
+	assert.Contains(t, files["deployment.yaml"], redacted)
+	assert.NotContains(t, files["deployment.yaml"], "secret")
+
+	// events are sorted by when they last happened
+	assert.Equal(t, "2018-01-01T12:00:00Z\tNormal\tbackup/backup-1\tBackupCompleted\tcompleted\n"+
+		"2018-01-01T12:05:00Z\tWarning\tbackup/backup-2\tBackupFailed\tfailed\n", files["events.txt"])
+
+	// anything that can't be gathered is listed rather than failing the command
+	assert.Equal(t, "error listing server pods: pods are forbidden\n", files["errors.txt"])
+}
+
+func TestRunDoesntOverwriteFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	o := NewOptions()
+	o.OutputFile = filepath.Join(dir, "bundle.tar.gz")
+	require.NoError(t, ioutil.WriteFile(o.OutputFile, []byte("existing"), 0600))
+	require.NoError(t, o.Complete(nil))
+
+	assert.Error(t, o.Run(NewFakeFactory(fake.NewSimpleClientset(), &fakeKubeClient{}, "ark-ns")))
+
+	data, err := ioutil.ReadFile(o.OutputFile)
+	require.NoError(t, err)
+	assert.Equal(t, "existing", string(data))
+}
+
+// readBundle returns the contents of the files in the bundle at path, by their names relative to
+// its directory.
+func readBundle(t *testing.T, path, dir string) map[string]string {
+	file, err := os.Open(path)
+	require.NoError(t, err)
+	defer file.Close()
+
+	gzr, err := gzip.NewReader(file)
+	require.NoError(t, err)
+	tr := tar.NewReader(gzr)
+
+	files := make(map[string]string)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+
+		rel, err := filepath.Rel(dir, hdr.Name)
+		require.NoError(t, err)
+		data, err := ioutil.ReadAll(tr)
+		require.NoError(t, err)
+		files[rel] = string(data)
+	}
+	return files
+}
+
+// fakeKubeClient returns the server's deployment and the events in eventsNamespace, and fails to
+// list pods.
+type fakeKubeClient struct {
+	kubernetes.Interface
+	deployment      *appsv1beta1.Deployment
+	eventsNamespace string
+	events          []v1.Event
+}
+
+func (c *fakeKubeClient) AppsV1beta1() appsv1beta1client.AppsV1beta1Interface {
+	return &fakeAppsClient{client: c}
+}
+
+func (c *fakeKubeClient) CoreV1() corev1client.CoreV1Interface {
+	return &fakeCoreClient{client: c}
+}
+
+type fakeAppsClient struct {
+	appsv1beta1client.AppsV1beta1Interface
+	client *fakeKubeClient
+}
+
+func (c *fakeAppsClient) Deployments(namespace string) appsv1beta1client.DeploymentInterface {
+	return &fakeDeployments{client: c.client, namespace: namespace}
+}
+
+type fakeDeployments struct {
+	appsv1beta1client.DeploymentInterface
+	client    *fakeKubeClient
+	namespace string
+}
+
+func (d *fakeDeployments) Get(name string, options metav1.GetOptions) (*appsv1beta1.Deployment, error) {
+	deployment := d.client.deployment
+	if deployment == nil || deployment.Namespace != d.namespace || deployment.Name != name {
+		return nil, errors.New("not found")
+	}
+	return deployment, nil
+}
+
+type fakeCoreClient struct {
+	corev1client.CoreV1Interface
+	client *fakeKubeClient
+}
+
+func (c *fakeCoreClient) Events(namespace string) corev1client.EventInterface {
+	return &fakeEvents{client: c.client, namespace: namespace}
+}
+
+func (c *fakeCoreClient) Pods(namespace string) corev1client.PodInterface {
+	return &fakePods{}
+}
+
+type fakeEvents struct {
+	corev1client.EventInterface
+	client    *fakeKubeClient
+	namespace string
+}
+
+func (e *fakeEvents) List(options metav1.ListOptions) (*v1.EventList, error) {
+	if e.namespace != e.client.eventsNamespace {
+		return &v1.EventList{}, nil
+	}
+	return &v1.EventList{Items: e.client.events}, nil
+}
+
+type fakePods struct {
+	corev1client.PodInterface
+}
+
+func (p *fakePods) List(options metav1.ListOptions) (*v1.PodList, error) {
+	return nil, errors.New("pods are forbidden")
+}
@@ -0,0 +1,140 @@
+/*
+Copyright 2017 Heptio Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package debug
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+
+	"github.com/heptio/ark/pkg/client"
+	"github.com/heptio/ark/pkg/cmd"
+)
+
+const (
+	// serverDeployment is the name of the Ark server's deployment, in the default Ark namespace, and
+	// serverComponent the value of the "component" label on its pods.
+	serverDeployment = "ark"
+	serverComponent  = "ark"
+	serverContainer  = "ark"
+
+	// configName is the name of the Config the Ark server reads.
+	configName = "default"
+)
+
+func NewCommand(f client.Factory) *cobra.Command {
+	o := NewOptions()
+
+	c := &cobra.Command{
+		Use:   "debug",
+		Short: "Gather information about Ark into a support bundle",
+		Long: `Gather the backups, restores, schedules, and backup and volume snapshot locations in the namespace chosen with
+--namespace, and the events there, the Ark server's config, deployment, and pods' logs from the heptio-ark namespace,
+where it always runs, and the client and server versions into a gzipped tarball that can be attached to a bug report.
+
+The bundle is sanitized as it's written: environment variable values, KMS key IDs, plugin configuration, the paths of
+notification webhook URLs, and last-applied-configuration annotations are removed, as are the query strings of URLs in
+logs, which may hold signatures. Review its contents before sharing it.
+
+Anything that can't be gathered is listed in the bundle's errors.txt, rather than failing the command.`,
+		Run: func(c *cobra.Command, args []string) {
+			cmd.CheckError(o.Validate(args))
+			cmd.CheckError(o.Complete(args))
+			cmd.CheckError(o.Run(f))
+		},
+	}
+
+	o.BindFlags(c.Flags())
+
+	return c
+}
+
+type Options struct {
+	OutputFile string
+	Timeout    time.Duration
+
+	name string
+}
+
+func NewOptions() *Options {
+	return &Options{
+		Timeout: 5 * time.Second,
+	}
+}
+
+func (o *Options) BindFlags(flags *pflag.FlagSet) {
+	flags.StringVar(&o.OutputFile, "output-file", o.OutputFile, "file to write the support bundle to. Optional; defaults to ark-debug-<timestamp>.tar.gz in the current directory.")
+	flags.DurationVar(&o.Timeout, "timeout", o.Timeout, "maximum time to wait for the server to report its version")
+}
+
+func (o *Options) Validate(args []string) error {
+	if len(args) > 0 {
+		return errors.New("this command takes no arguments")
+	}
+
+	return nil
+}
+
+func (o *Options) Complete(args []string) error {
+	o.name = fmt.Sprintf("ark-debug-%s", time.Now().Format("20060102150405"))
+	if o.OutputFile == "" {
+		o.OutputFile = o.name + ".tar.gz"
+	}
+
+	return nil
+}
+
+func (o *Options) Run(f client.Factory) error {
+	arkClient, err := f.Client()
+	if err != nil {
+		return err
+	}
+
+	kubeClient, err := f.KubeClient()
+	if err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(o.OutputFile, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return fmt.Errorf("error creating support bundle: %v", err)
+	}
+	defer file.Close()
+
+	b := newBundle(file, o.name, f.Namespace())
+	gatherVersions(b, arkClient.ArkV1(), o.Timeout)
+	gatherResources(b, arkClient.ArkV1())
+	gatherConfig(b, arkClient.ArkV1())
+	gatherDeployment(b, kubeClient)
+	gatherEvents(b, kubeClient)
+	gatherLogs(b, kubeClient)
+
+	if err := b.Close(); err != nil {
+		return fmt.Errorf("error writing support bundle: %v", err)
+	}
+
+	fmt.Printf("Support bundle written to %s\n", o.OutputFile)
+	if len(b.errs) > 0 {
+		fmt.Printf("%d item(s) couldn't be gathered; see errors.txt in the bundle\n", len(b.errs))
+	}
+
+	return nil
+}
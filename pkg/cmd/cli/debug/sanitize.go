@@ -0,0 +1,126 @@
+/*
+Copyright 2017 Heptio Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package debug
+
+import (
+	"net/url"
+	"regexp"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/pkg/api/v1"
+
+	api "github.com/heptio/ark/pkg/apis/ark/v1"
+)
+
+const (
+	redacted = "<redacted>"
+
+	// lastAppliedConfigAnnotation is set by kubectl apply to a copy of the object as it was
+	// applied, so it has to be removed along with anything redacted from the object itself.
+	lastAppliedConfigAnnotation = "kubectl.kubernetes.io/last-applied-configuration"
+)
+
+// urlQuery matches the query strings of URLs, which for pre-signed URLs hold their signatures
+// and sometimes credentials.
+var urlQuery = regexp.MustCompile(`(https?://[^\s"'?]+)\?[^\s"']*`)
+
+func sanitizeObjectMeta(meta *metav1.ObjectMeta) {
+	delete(meta.Annotations, lastAppliedConfigAnnotation)
+}
+
+// sanitizeConfig removes the paths and query strings of the Config's notification webhook URLs,
+// which often hold tokens, e.g. for Slack.
+func sanitizeConfig(config *api.Config) {
+	sanitizeObjectMeta(&config.ObjectMeta)
+	if config.Notifications == nil {
+		return
+	}
+
+	notifications := *config.Notifications
+	notifications.Webhooks = make([]api.NotificationWebhook, len(config.Notifications.Webhooks))
+	for i, webhook := range config.Notifications.Webhooks {
+		if u, err := url.Parse(webhook.URL); err == nil && u.Host != "" {
+			webhook.URL = u.Scheme + "://" + u.Host + "/" + redacted
+		} else {
+			webhook.URL = redacted
+		}
+		notifications.Webhooks[i] = webhook
+	}
+	config.Notifications = &notifications
+}
+
+// sanitizeBackupStorageLocation removes the KMS key ID and plugin config from a
+// BackupStorageLocation's provider.
+func sanitizeBackupStorageLocation(location *api.BackupStorageLocation) {
+	sanitizeObjectMeta(&location.ObjectMeta)
+	sanitizeProvider(&location.Spec.CloudProviderConfig)
+}
+
+// sanitizeVolumeSnapshotLocation removes the KMS key ID and plugin config from a
+// VolumeSnapshotLocation's provider.
+func sanitizeVolumeSnapshotLocation(location *api.VolumeSnapshotLocation) {
+	sanitizeObjectMeta(&location.ObjectMeta)
+	sanitizeProvider(&location.Spec.CloudProviderConfig)
+}
+
+func sanitizeProvider(provider *api.CloudProviderConfig) {
+	if provider == nil {
+		return
+	}
+
+	// plugins' config is specific to them, so it's all redacted in case it holds credentials
+	if provider.Plugin != nil && len(provider.Plugin.Config) > 0 {
+		plugin := *provider.Plugin
+		plugin.Config = make(map[string]string, len(provider.Plugin.Config))
+		for key := range provider.Plugin.Config {
+			plugin.Config[key] = redacted
+		}
+		provider.Plugin = &plugin
+	}
+
+	if provider.AWS == nil || provider.AWS.KMSKeyID == "" {
+		return
+	}
+
+	aws := *provider.AWS
+	aws.KMSKeyID = redacted
+	provider.AWS = &aws
+}
+
+// sanitizePodSpec redacts the values of the environment variables of a pod's containers, which
+// may hold credentials. References to secrets and config maps are kept, since they don't.
+func sanitizePodSpec(spec *v1.PodSpec) {
+	for i := range spec.InitContainers {
+		sanitizeContainer(&spec.InitContainers[i])
+	}
+	for i := range spec.Containers {
+		sanitizeContainer(&spec.Containers[i])
+	}
+}
+
+func sanitizeContainer(container *v1.Container) {
+	for i := range container.Env {
+		if container.Env[i].Value != "" {
+			container.Env[i].Value = redacted
+		}
+	}
+}
+
+// sanitizeLog removes the query strings of the URLs in a log.
+func sanitizeLog(data []byte) []byte {
+	return urlQuery.ReplaceAll(data, []byte("$1?"+redacted))
+}
@@ -0,0 +1,103 @@
+/*
+Copyright 2017 Heptio Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package debug
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/pkg/api/v1"
+
+	api "github.com/heptio/ark/pkg/apis/ark/v1"
+)
+
+func TestSanitizeConfig(t *testing.T) {
+	original := &api.NotificationsConfig{
+		Webhooks: []api.NotificationWebhook{
+			{Name: "slack", URL: "https://hooks.slack.com/services/T000/B000/XXXX?token=abc"},
+			{Name: "invalid", URL: "not a url"},
+		},
+	}
+	config := &api.Config{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        configName,
+			Annotations: map[string]string{lastAppliedConfigAnnotation: "{}", "team": "a"},
+		},
+		Notifications: original,
+	}
+
+	sanitizeConfig(config)
+
+	assert.Equal(t, map[string]string{"team": "a"}, config.Annotations)
+	assert.Equal(t, []api.NotificationWebhook{
+		{Name: "slack", URL: "https://hooks.slack.com/" + redacted},
+		{Name: "invalid", URL: redacted},
+	}, config.Notifications.Webhooks)
+
+	// the webhooks are copied before they're redacted
+	assert.Equal(t, "https://hooks.slack.com/services/T000/B000/XXXX?token=abc", original.Webhooks[0].URL)
+}
+
+func TestSanitizeProvider(t *testing.T) {
+	plugin := &api.PluginConfig{Name: "my-plugin", Config: map[string]string{"apiKey": "secret"}}
+	aws := &api.AWSConfig{Region: "us-east-1", KMSKeyID: "key-1"}
+	location := &api.BackupStorageLocation{}
+	location.Spec.CloudProviderConfig = api.CloudProviderConfig{AWS: aws, Plugin: plugin}
+
+	sanitizeBackupStorageLocation(location)
+
+	provider := location.Spec.CloudProviderConfig
+	assert.Equal(t, &api.AWSConfig{Region: "us-east-1", KMSKeyID: redacted}, provider.AWS)
+	assert.Equal(t, &api.PluginConfig{Name: "my-plugin", Config: map[string]string{"apiKey": redacted}}, provider.Plugin)
+
+	// the provider's config is copied before it's redacted
+	assert.Equal(t, "key-1", aws.KMSKeyID)
+	assert.Equal(t, "secret", plugin.Config["apiKey"])
+
+	// locations without a KMS key ID or plugin config are left alone
+	snapshotLocation := &api.VolumeSnapshotLocation{}
+	snapshotLocation.Spec.AWS = &api.AWSConfig{Region: "us-east-1"}
+	sanitizeVolumeSnapshotLocation(snapshotLocation)
+	assert.Equal(t, &api.AWSConfig{Region: "us-east-1"}, snapshotLocation.Spec.AWS)
+}
+
+func TestSanitizePodSpec(t *testing.T) {
+	secretRef := &v1.EnvVarSource{SecretKeyRef: &v1.SecretKeySelector{Key: "key"}}
+	spec := &v1.PodSpec{
+		InitContainers: []v1.Container{{Name: "plugin", Env: []v1.EnvVar{{Name: "A", Value: "a"}}}},
+		Containers: []v1.Container{{Name: "ark", Env: []v1.EnvVar{
+			{Name: "B", Value: "b"},
+			{Name: "C", ValueFrom: secretRef},
+		}}},
+	}
+
+	sanitizePodSpec(spec)
+
+	assert.Equal(t, []v1.EnvVar{{Name: "A", Value: redacted}}, spec.InitContainers[0].Env)
+	assert.Equal(t, []v1.EnvVar{{Name: "B", Value: redacted}, {Name: "C", ValueFrom: secretRef}}, spec.Containers[0].Env)
+}
+
+func TestSanitizeLog(t *testing.T) {
+	log := `time="2018-01-01" msg="downloading https://bucket.s3.amazonaws.com/backup-1/backup-1.tar.gz?X-Amz-Signature=abc&X-Amz-Credential=def" url=http://example.com/path`
+
+	assert.Equal(t,
+		`time="2018-01-01" msg="downloading https://bucket.s3.amazonaws.com/backup-1/backup-1.tar.gz?<redacted>" url=http://example.com/path`,
+		string(sanitizeLog([]byte(log))),
+	)
+}
@@ -0,0 +1,161 @@
+/*
+Copyright 2017 Heptio Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plugin
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/pkg/api/v1"
+	appsv1beta1 "k8s.io/client-go/pkg/apis/apps/v1beta1"
+
+	api "github.com/heptio/ark/pkg/apis/ark/v1"
+	"github.com/heptio/ark/pkg/client"
+	"github.com/heptio/ark/pkg/cmd"
+)
+
+func NewAddCommand(f client.Factory) *cobra.Command {
+	c := &cobra.Command{
+		Use:   "add IMAGE",
+		Short: "Add a plugin",
+		Long:  "Add a plugin image to the Ark server's deployment. The deployment's pods are replaced, so the Ark server restarts with the plugin installed.",
+		Run: func(c *cobra.Command, args []string) {
+			if len(args) != 1 {
+				cmd.CheckError(errors.New("you must specify only one argument, the plugin's image"))
+			}
+
+			kubeClient, err := f.KubeClient()
+			cmd.CheckError(err)
+
+			deployments := kubeClient.AppsV1beta1().Deployments(api.DefaultNamespace)
+
+			deployment, err := deployments.Get(serverDeployment, metav1.GetOptions{})
+			cmd.CheckError(err)
+
+			name, err := addPlugin(deployment, args[0])
+			cmd.CheckError(err)
+
+			_, err = deployments.Update(deployment)
+			cmd.CheckError(err)
+
+			fmt.Printf("Plugin %q added to deployment %s/%s.\n", name, api.DefaultNamespace, serverDeployment)
+		},
+	}
+
+	return c
+}
+
+var invalidNameChars = regexp.MustCompile("[^a-z0-9-]+")
+
+// pluginName returns the name of the init container for image: the last component of its
+// repository, without its tag or digest, made into a valid container name.
+func pluginName(image string) string {
+	name := image
+	if i := strings.Index(name, "@"); i >= 0 {
+		name = name[:i]
+	}
+	if i := strings.LastIndex(name, "/"); i >= 0 {
+		name = name[i+1:]
+	}
+	if i := strings.Index(name, ":"); i >= 0 {
+		name = name[:i]
+	}
+
+	return strings.Trim(invalidNameChars.ReplaceAllString(strings.ToLower(name), "-"), "-")
+}
+
+// addPlugin adds image to deployment as an init container that copies its binaries into the
+// plugins volume, adding the volume and mounting it in the Ark server's container if they're not
+// there yet. It returns the init container's name.
+func addPlugin(deployment *appsv1beta1.Deployment, image string) (string, error) {
+	name := pluginName(image)
+	if name == "" {
+		return "", fmt.Errorf("unable to name a plugin for image %q", image)
+	}
+
+	podSpec := &deployment.Spec.Template.Spec
+	for _, container := range podSpec.InitContainers {
+		if container.Image == image {
+			return "", fmt.Errorf("plugin image %q is already installed as %q", image, container.Name)
+		}
+		if container.Name == name {
+			return "", fmt.Errorf("an init container named %q already exists", name)
+		}
+	}
+
+	server := serverContainerOf(podSpec)
+	if server == nil {
+		return "", fmt.Errorf("deployment %s/%s has no container named %q", deployment.Namespace, deployment.Name, serverContainer)
+	}
+
+	if !hasVolume(podSpec, pluginsVolume) {
+		podSpec.Volumes = append(podSpec.Volumes, v1.Volume{
+			Name:         pluginsVolume,
+			VolumeSource: v1.VolumeSource{EmptyDir: &v1.EmptyDirVolumeSource{}},
+		})
+	}
+
+	if !hasVolumeMount(server, pluginsVolume) {
+		server.VolumeMounts = append(server.VolumeMounts, v1.VolumeMount{Name: pluginsVolume, MountPath: PluginsDir})
+	}
+
+	podSpec.InitContainers = append(podSpec.InitContainers, v1.Container{
+		Name:         name,
+		Image:        image,
+		VolumeMounts: []v1.VolumeMount{{Name: pluginsVolume, MountPath: pluginTargetDir}},
+	})
+
+	return name, nil
+}
+
+func serverContainerOf(podSpec *v1.PodSpec) *v1.Container {
+	for i := range podSpec.Containers {
+		if podSpec.Containers[i].Name == serverContainer {
+			return &podSpec.Containers[i]
+		}
+	}
+	return nil
+}
+
+func hasVolume(podSpec *v1.PodSpec, name string) bool {
+	for _, volume := range podSpec.Volumes {
+		if volume.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func hasVolumeMount(container *v1.Container, name string) bool {
+	for _, mount := range container.VolumeMounts {
+		if mount.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// isPlugin returns whether container is a plugin's init container, i.e. one that mounts the
+// plugins volume.
+func isPlugin(container v1.Container) bool {
+	return hasVolumeMount(&container, pluginsVolume)
+}
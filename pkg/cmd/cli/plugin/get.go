@@ -0,0 +1,57 @@
+/*
+Copyright 2017 Heptio Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plugin
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	api "github.com/heptio/ark/pkg/apis/ark/v1"
+	"github.com/heptio/ark/pkg/client"
+	"github.com/heptio/ark/pkg/cmd"
+)
+
+func NewGetCommand(f client.Factory) *cobra.Command {
+	c := &cobra.Command{
+		Use:   "get",
+		Short: "Get plugins",
+		Long:  "List the plugins installed in the Ark server's deployment, with their images.",
+		Run: func(c *cobra.Command, args []string) {
+			kubeClient, err := f.KubeClient()
+			cmd.CheckError(err)
+
+			deployment, err := kubeClient.AppsV1beta1().Deployments(api.DefaultNamespace).Get(serverDeployment, metav1.GetOptions{})
+			cmd.CheckError(err)
+
+			tw := tabwriter.NewWriter(os.Stdout, 10, 4, 3, ' ', 0)
+			fmt.Fprintln(tw, "NAME\tIMAGE")
+			for _, container := range deployment.Spec.Template.Spec.InitContainers {
+				if isPlugin(container) {
+					fmt.Fprintf(tw, "%s\t%s\n", container.Name, container.Image)
+				}
+			}
+			cmd.CheckError(tw.Flush())
+		},
+	}
+
+	return c
+}
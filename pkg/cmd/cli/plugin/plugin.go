@@ -0,0 +1,59 @@
+/*
+Copyright 2017 Heptio Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plugin
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/heptio/ark/pkg/client"
+)
+
+const (
+	// serverDeployment and serverContainer are the names of the Ark server's deployment, which is
+	// always in the default Ark namespace whatever --namespace is, and of its container.
+	serverDeployment = "ark"
+	serverContainer  = "ark"
+
+	// pluginsVolume is the name of the volume that plugin images copy their binaries into.
+	pluginsVolume = "plugins"
+
+	// PluginsDir is where the plugins volume is mounted in the Ark server's container.
+	PluginsDir = "/plugins"
+
+	// pluginTargetDir is where the plugins volume is mounted in plugin images' init containers.
+	// Plugin images are expected to copy their binaries into it when run.
+	pluginTargetDir = "/target"
+)
+
+func NewCommand(f client.Factory) *cobra.Command {
+	c := &cobra.Command{
+		Use:   "plugin",
+		Short: "Work with plugins",
+		Long: `Work with the plugins installed in the Ark server's deployment.
+
+A plugin is installed by adding its image to the deployment as an init container, which copies the plugin's binaries into
+a volume that's shared with the Ark server's container, mounted at ` + PluginsDir + `.`,
+	}
+
+	c.AddCommand(
+		NewAddCommand(f),
+		NewRemoveCommand(f),
+		NewGetCommand(f),
+	)
+
+	return c
+}
@@ -0,0 +1,179 @@
+/*
+Copyright 2017 Heptio Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plugin
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/pkg/api/v1"
+	appsv1beta1 "k8s.io/client-go/pkg/apis/apps/v1beta1"
+)
+
+func newDeployment(initContainers ...v1.Container) *appsv1beta1.Deployment {
+	deployment := &appsv1beta1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "heptio-ark", Name: serverDeployment},
+	}
+	deployment.Spec.Template.Spec.InitContainers = initContainers
+	deployment.Spec.Template.Spec.Containers = []v1.Container{{Name: serverContainer, Image: "gcr.io/heptio-images/ark"}}
+	return deployment
+}
+
+func pluginContainer(name, image string) v1.Container {
+	return v1.Container{
+		Name:         name,
+		Image:        image,
+		VolumeMounts: []v1.VolumeMount{{Name: pluginsVolume, MountPath: pluginTargetDir}},
+	}
+}
+
+func TestPluginName(t *testing.T) {
+	tests := map[string]string{
+		"ark-plugin":                                "ark-plugin",
+		"example.com/plugins/ark-plugin:v1":         "ark-plugin",
+		"example.com:5000/ark-plugin@sha256:abc123": "ark-plugin",
+		"example.com/Ark_Plugin.v2:latest":          "ark-plugin-v2",
+		"example.com/___":                           "",
+	}
+
+	for image, expected := range tests {
+		assert.Equal(t, expected, pluginName(image), "image %q", image)
+	}
+}
+
+func TestAddPlugin(t *testing.T) {
+	deployment := newDeployment()
+
+	name, err := addPlugin(deployment, "example.com/plugins/ark-plugin:v1")
+	require.NoError(t, err)
+	assert.Equal(t, "ark-plugin", name)
+
+	podSpec := deployment.Spec.Template.Spec
+	assert.Equal(t, []v1.Container{pluginContainer("ark-plugin", "example.com/plugins/ark-plugin:v1")}, podSpec.InitContainers)
+	assert.Equal(t, []v1.Volume{{Name: pluginsVolume, VolumeSource: v1.VolumeSource{EmptyDir: &v1.EmptyDirVolumeSource{}}}}, podSpec.Volumes)
+	assert.Equal(t, []v1.VolumeMount{{Name: pluginsVolume, MountPath: PluginsDir}}, podSpec.Containers[0].VolumeMounts)
+
+	// the plugins volume and its mount are only added once
+	_, err = addPlugin(deployment, "example.com/plugins/other-plugin:v1")
+	require.NoError(t, err)
+
+	podSpec = deployment.Spec.Template.Spec
+	assert.Len(t, podSpec.InitContainers, 2)
+	assert.Len(t, podSpec.Volumes, 1)
+	assert.Len(t, podSpec.Containers[0].VolumeMounts, 1)
+}
+
+func TestAddPluginErrors(t *testing.T) {
+	tests := []struct {
+		name       string
+		deployment *appsv1beta1.Deployment
+		image      string
+		expected   string
+	}{
+		{
+			name:       "image that can't be named",
+			deployment: newDeployment(),
+			image:      "example.com/___",
+			expected:   `unable to name a plugin for image "example.com/___"`,
+		},
+		{
+			name:       "image already installed",
+			deployment: newDeployment(pluginContainer("my-plugin", "example.com/ark-plugin:v1")),
+			image:      "example.com/ark-plugin:v1",
+			expected:   `plugin image "example.com/ark-plugin:v1" is already installed as "my-plugin"`,
+		},
+		{
+			name:       "init container with the plugin's name",
+			deployment: newDeployment(v1.Container{Name: "ark-plugin", Image: "example.com/setup"}),
+			image:      "example.com/ark-plugin:v1",
+			expected:   `an init container named "ark-plugin" already exists`,
+		},
+		{
+			name:       "deployment without the server's container",
+			deployment: &appsv1beta1.Deployment{ObjectMeta: metav1.ObjectMeta{Namespace: "heptio-ark", Name: serverDeployment}},
+			image:      "example.com/ark-plugin:v1",
+			expected:   `deployment heptio-ark/ark has no container named "ark"`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := addPlugin(test.deployment, test.image)
+			assert.EqualError(t, err, test.expected)
+		})
+	}
+}
+
+func TestRemovePlugin(t *testing.T) {
+	setup := v1.Container{Name: "setup", Image: "example.com/setup"}
+
+	tests := []struct {
+		name        string
+		nameOrImage string
+		expected    string
+		expectedErr string
+	}{
+		{
+			name:        "by name",
+			nameOrImage: "plugin-1",
+			expected:    "plugin-1",
+		},
+		{
+			name:        "by image",
+			nameOrImage: "example.com/plugin-2:v1",
+			expected:    "plugin-2",
+		},
+		{
+			name:        "init containers that aren't plugins aren't removed",
+			nameOrImage: "setup",
+			expectedErr: `plugin "setup" not found in deployment heptio-ark/ark`,
+		},
+		{
+			name:        "missing plugin",
+			nameOrImage: "plugin-3",
+			expectedErr: `plugin "plugin-3" not found in deployment heptio-ark/ark`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			plugin1 := pluginContainer("plugin-1", "example.com/plugin-1:v1")
+			plugin2 := pluginContainer("plugin-2", "example.com/plugin-2:v1")
+			deployment := newDeployment(setup, plugin1, plugin2)
+
+			removed, err := removePlugin(deployment, test.nameOrImage)
+			if test.expectedErr != "" {
+				assert.EqualError(t, err, test.expectedErr)
+				assert.Len(t, deployment.Spec.Template.Spec.InitContainers, 3)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, removed)
+
+			var remaining []string
+			for _, container := range deployment.Spec.Template.Spec.InitContainers {
+				remaining = append(remaining, container.Name)
+			}
+			assert.NotContains(t, remaining, test.expected)
+			assert.Contains(t, remaining, "setup")
+			assert.Len(t, remaining, 2)
+		})
+	}
+}
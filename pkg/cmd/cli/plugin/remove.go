@@ -0,0 +1,88 @@
+/*
+Copyright 2017 Heptio Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plugin
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/pkg/api/v1"
+	appsv1beta1 "k8s.io/client-go/pkg/apis/apps/v1beta1"
+
+	api "github.com/heptio/ark/pkg/apis/ark/v1"
+	"github.com/heptio/ark/pkg/client"
+	"github.com/heptio/ark/pkg/cmd"
+)
+
+func NewRemoveCommand(f client.Factory) *cobra.Command {
+	c := &cobra.Command{
+		Use:   "remove NAME|IMAGE",
+		Short: "Remove a plugin",
+		Long:  "Remove a plugin, given its name or image, from the Ark server's deployment. The deployment's pods are replaced, so the Ark server restarts without the plugin.",
+		Run: func(c *cobra.Command, args []string) {
+			if len(args) != 1 {
+				cmd.CheckError(errors.New("you must specify only one argument, the plugin's name or image"))
+			}
+
+			kubeClient, err := f.KubeClient()
+			cmd.CheckError(err)
+
+			deployments := kubeClient.AppsV1beta1().Deployments(api.DefaultNamespace)
+
+			deployment, err := deployments.Get(serverDeployment, metav1.GetOptions{})
+			cmd.CheckError(err)
+
+			name, err := removePlugin(deployment, args[0])
+			cmd.CheckError(err)
+
+			_, err = deployments.Update(deployment)
+			cmd.CheckError(err)
+
+			fmt.Printf("Plugin %q removed from deployment %s/%s.\n", name, api.DefaultNamespace, serverDeployment)
+		},
+	}
+
+	return c
+}
+
+// removePlugin removes the plugin init container whose name or image is nameOrImage from
+// deployment, and returns its name. The plugins volume is left in place.
+func removePlugin(deployment *appsv1beta1.Deployment, nameOrImage string) (string, error) {
+	podSpec := &deployment.Spec.Template.Spec
+
+	var (
+		kept    []v1.Container
+		removed string
+	)
+	for _, container := range podSpec.InitContainers {
+		if removed == "" && isPlugin(container) && (container.Name == nameOrImage || container.Image == nameOrImage) {
+			removed = container.Name
+			continue
+		}
+		kept = append(kept, container)
+	}
+
+	if removed == "" {
+		return "", fmt.Errorf("plugin %q not found in deployment %s/%s", nameOrImage, deployment.Namespace, deployment.Name)
+	}
+
+	podSpec.InitContainers = kept
+	return removed, nil
+}
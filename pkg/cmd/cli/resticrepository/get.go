@@ -0,0 +1,82 @@
+/*
+Copyright 2017 Heptio Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resticrepository
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	api "github.com/heptio/ark/pkg/apis/ark/v1"
+	"github.com/heptio/ark/pkg/client"
+	"github.com/heptio/ark/pkg/cmd"
+)
+
+func NewGetCommand(f client.Factory) *cobra.Command {
+	c := &cobra.Command{
+		Use:   "get [NAME...]",
+		Short: "Get restic repositories",
+		Long:  "List the restic repositories, or the named ones, with whether they were healthy and their size when they were last maintained.",
+		Run: func(c *cobra.Command, args []string) {
+			arkClient, err := f.Client()
+			cmd.CheckError(err)
+
+			var repos []api.ResticRepository
+			if len(args) > 0 {
+				for _, name := range args {
+					repo, err := arkClient.ArkV1().ResticRepositories(f.Namespace()).Get(name, metav1.GetOptions{})
+					cmd.CheckError(err)
+					repos = append(repos, *repo)
+				}
+			} else {
+				list, err := arkClient.ArkV1().ResticRepositories(f.Namespace()).List(metav1.ListOptions{})
+				cmd.CheckError(err)
+				repos = list.Items
+				sort.Slice(repos, func(i, j int) bool { return repos[i].Name < repos[j].Name })
+			}
+
+			tw := tabwriter.NewWriter(os.Stdout, 10, 4, 3, ' ', 0)
+			fmt.Fprintln(tw, "NAME\tNAMESPACE\tSTORAGE LOCATION\tPHASE\tLAST MAINTENANCE\tSIZE (BYTES)")
+			for _, repo := range repos {
+				fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%d\n",
+					repo.Name,
+					repo.Spec.VolumeNamespace,
+					repo.Spec.BackupStorageLocation,
+					repo.Status.Phase,
+					lastMaintenance(repo.Status),
+					repo.Status.SizeBytes,
+				)
+			}
+			cmd.CheckError(tw.Flush())
+		},
+	}
+
+	return c
+}
+
+// lastMaintenance returns when the repository was last maintained, or "never".
+func lastMaintenance(status api.ResticRepositoryStatus) string {
+	if status.LastMaintenanceTime.IsZero() {
+		return "never"
+	}
+	return status.LastMaintenanceTime.Time.String()
+}
@@ -0,0 +1,43 @@
+/*
+Copyright 2017 Heptio Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resticrepository
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/heptio/ark/pkg/client"
+)
+
+func NewCommand(f client.Factory) *cobra.Command {
+	c := &cobra.Command{
+		Use:   "restic-repository",
+		Short: "Work with restic repositories",
+		Long: `Work with the restic repositories that pod volumes are backed up to, one for each namespace in each backup storage
+location.
+
+Each repository is a ResticRepository in the Ark namespace, which the Ark server creates once the repository exists
+in object storage. The server removes stale locks from each repository, prunes the data that's no longer referenced by
+any snapshot, and checks its integrity every spec.maintenanceFrequency, defaulting to the Config's
+restic.maintenanceFrequency.`,
+	}
+
+	c.AddCommand(
+		NewGetCommand(f),
+	)
+
+	return c
+}
@@ -19,18 +19,26 @@ package restore
 import (
 	"errors"
 	"fmt"
+	"io"
+	"os"
+	"os/user"
+	"strings"
 	"time"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
 
 	api "github.com/heptio/ark/pkg/apis/ark/v1"
 	"github.com/heptio/ark/pkg/client"
 	"github.com/heptio/ark/pkg/cmd"
+	"github.com/heptio/ark/pkg/cmd/util/downloadrequest"
 	"github.com/heptio/ark/pkg/cmd/util/flag"
 	"github.com/heptio/ark/pkg/cmd/util/output"
+	"github.com/heptio/ark/pkg/cmd/util/progress"
+	arkv1client "github.com/heptio/ark/pkg/generated/clientset/typed/ark/v1"
 )
 
 func NewCreateCommand(f client.Factory) *cobra.Command {
@@ -39,6 +47,11 @@ func NewCreateCommand(f client.Factory) *cobra.Command {
 	c := &cobra.Command{
 		Use:   "create BACKUP",
 		Short: "Create a restore",
+		Long: `Create a restore from a backup.
+
+To wait for the restore to finish, e.g. in a CI pipeline, use --wait. Its progress and the time elapsed are printed as it
+runs, on a single line that's updated in place when writing to a terminal, and the command exits with a non-zero status
+unless the restore completes without errors.`,
 		Run: func(c *cobra.Command, args []string) {
 			cmd.CheckError(o.Validate(c, args))
 			cmd.CheckError(o.Complete(args))
@@ -54,19 +67,53 @@ func NewCreateCommand(f client.Factory) *cobra.Command {
 }
 
 type CreateOptions struct {
-	BackupName        string
-	RestoreVolumes    flag.OptionalBool
-	Labels            flag.Map
-	Namespaces        flag.StringArray
-	NamespaceMappings flag.Map
-	Selector          flag.LabelSelector
+	BackupName              string
+	RestoreVolumes          flag.OptionalBool
+	IncludeClusterResources flag.OptionalBool
+	Labels                  flag.Map
+	Namespaces              flag.StringArray
+	NamespaceMappings       flag.Map
+	StorageClassMappings    flag.Map
+	NodeMappings            flag.Map
+	RegistryMappings        flag.Map
+	ResourceModifiers       string
+	Selector                flag.LabelSelector
+	ExistingPolicy          flag.Enum
+	PolicyOverrides         flag.Map
+	VolumePolicy            flag.Enum
+	PreserveClusterIPs      bool
+	PreserveNodePorts       bool
+	DataOnly                bool
+	Prune                   bool
+	PruneExcludedResources  flag.StringArray
+	Preview                 bool
+	PreviewTimeout          time.Duration
+	Wait                    bool
+	WaitTimeout             time.Duration
 }
 
 func NewCreateOptions() *CreateOptions {
 	return &CreateOptions{
-		Labels:            flag.NewMap(),
-		NamespaceMappings: flag.NewMap().WithEntryDelimiter(",").WithKeyValueDelimiter(":"),
-		RestoreVolumes:    flag.NewOptionalBool(nil),
+		Labels:                  flag.NewMap(),
+		NamespaceMappings:       flag.NewMap().WithEntryDelimiter(",").WithKeyValueDelimiter(":"),
+		StorageClassMappings:    flag.NewMap().WithEntryDelimiter(",").WithKeyValueDelimiter(":"),
+		NodeMappings:            flag.NewMap().WithEntryDelimiter(",").WithKeyValueDelimiter(":"),
+		RegistryMappings:        flag.NewMap(),
+		RestoreVolumes:          flag.NewOptionalBool(nil),
+		IncludeClusterResources: flag.NewOptionalBool(nil),
+		ExistingPolicy: flag.NewEnum(
+			"",
+			string(api.ExistingResourcePolicySkip),
+			string(api.ExistingResourcePolicyPatch),
+			string(api.ExistingResourcePolicyReplace),
+		),
+		PolicyOverrides: flag.NewMap(),
+		VolumePolicy: flag.NewEnum(
+			"",
+			string(api.UnsnapshottedVolumePolicyRetain),
+			string(api.UnsnapshottedVolumePolicyProvision),
+		),
+		PreviewTimeout: 10 * time.Minute,
 	}
 }
 
@@ -74,11 +121,30 @@ func (o *CreateOptions) BindFlags(flags *pflag.FlagSet) {
 	flags.Var(&o.Labels, "labels", "labels to apply to the restore")
 	flags.Var(&o.Namespaces, "namespaces", "comma-separated list of namespaces to restore")
 	flags.Var(&o.NamespaceMappings, "namespace-mappings", "namespace mappings from name in the backup to desired restored name in the form src1:dst1,src2:dst2,...")
+	flags.Var(&o.StorageClassMappings, "storage-class-mappings", "storage class mappings from name in the backup to desired restored name in the form src1:dst1,src2:dst2,...")
+	flags.Var(&o.NodeMappings, "node-mappings", "mappings from the nodes that local and hostPath PersistentVolumes were backed up from to the nodes to restore them onto, in the form src1:dst1,src2:dst2,...")
+	flags.Var(&o.RegistryMappings, "registry-mappings", "image registry mappings from registry (and optional repository path prefix) in the backup to the one to restore images from, in the form src1=dst1,src2=dst2,... Images without a registry are from docker.io")
+	flags.StringVar(&o.ResourceModifiers, "resource-modifiers", o.ResourceModifiers, "name of a ConfigMap, in the Ark namespace, of rules for patching items before they're restored")
 	flags.VarP(&o.Selector, "selector", "l", "only restore resources matching this label selector")
+	flags.Var(&o.ExistingPolicy, "existing-resource-policy", "what to do with resources that already exist in the cluster: Skip, Patch, or Replace (default Skip)")
+	flags.Var(&o.PolicyOverrides, "existing-resource-policy-overrides", "per-resource existing resource policies in the form resource1=policy1,resource2=policy2,...")
+	flags.Var(&o.VolumePolicy, "unsnapshotted-volume-policy", "what to do with persistent volumes that aren't restored from a snapshot: Retain the volume as it was backed up, or Provision a new one for its claim (default Retain)")
+	flags.BoolVar(&o.PreserveClusterIPs, "preserve-cluster-ips", o.PreserveClusterIPs, "keep the cluster IPs of restored services, rather than allocating new ones")
+	flags.BoolVar(&o.PreserveNodePorts, "preserve-node-ports", o.PreserveNodePorts, "keep the node ports of restored services, rather than allocating new ones")
+	flags.BoolVar(&o.DataOnly, "data-only", o.DataOnly, "only restore the data of the backup's persistent volume claims into the existing claims of the same names, scaling down the workloads using them while it does")
+	flags.BoolVar(&o.Prune, "prune", o.Prune, "also delete the items in the namespaces restored into that aren't in the backup. Use with --preview to see what would be deleted")
+	flags.Var(&o.PruneExcludedResources, "prune-exclude-resources", "resources whose items are never deleted by --prune, formatted as resource.group, such as configmaps or deployments.apps")
+	flags.BoolVar(&o.Preview, "preview", o.Preview, "don't change the cluster, but print a JSON plan of what the restore would do")
+	flags.DurationVar(&o.PreviewTimeout, "preview-timeout", o.PreviewTimeout, "maximum time to wait for a preview to finish")
+	flags.BoolVar(&o.Wait, "wait", o.Wait, "wait for the restore to finish, printing its progress, and exit with a non-zero status unless it completes without errors")
+	flags.DurationVar(&o.WaitTimeout, "wait-timeout", o.WaitTimeout, "maximum time to wait for the restore to finish when --wait is used (0 means no limit)")
 	f := flags.VarPF(&o.RestoreVolumes, "restore-volumes", "", "whether to restore volumes from snapshots")
 	// this allows the user to just specify "--restore-volumes" as shorthand for "--restore-volumes=true"
 	// like a normal bool flag
 	f.NoOptDefVal = "true"
+
+	f = flags.VarPF(&o.IncludeClusterResources, "include-cluster-resources", "", "include cluster-scoped resources in the restore (by default, they're only included when all namespaces are restored)")
+	f.NoOptDefVal = "true"
 }
 
 func (o *CreateOptions) Validate(c *cobra.Command, args []string) error {
@@ -90,6 +156,22 @@ func (o *CreateOptions) Validate(c *cobra.Command, args []string) error {
 		return err
 	}
 
+	if o.Wait && output.GetOutputFlagValue(c) != "" {
+		return errors.New("--wait can't be used with --output, since the restore isn't created")
+	}
+
+	if o.Wait && o.Preview {
+		return errors.New("--wait can't be used with --preview, which already waits for the preview to finish")
+	}
+
+	for resource, policy := range o.PolicyOverrides.Data() {
+		switch api.ExistingResourcePolicy(policy) {
+		case api.ExistingResourcePolicySkip, api.ExistingResourcePolicyPatch, api.ExistingResourcePolicyReplace:
+		default:
+			return fmt.Errorf("invalid existing resource policy %q for %s", policy, resource)
+		}
+	}
+
 	return nil
 }
 
@@ -106,19 +188,46 @@ func (o *CreateOptions) Run(c *cobra.Command, f client.Factory) error {
 
 	restore := &api.Restore{
 		ObjectMeta: metav1.ObjectMeta{
-			Namespace: api.DefaultNamespace,
+			Namespace: f.Namespace(),
 			Name:      fmt.Sprintf("%s-%s", o.BackupName, time.Now().Format("20060102150405")),
 			Labels:    o.Labels.Data(),
 		},
 		Spec: api.RestoreSpec{
-			BackupName:       o.BackupName,
-			Namespaces:       o.Namespaces,
-			NamespaceMapping: o.NamespaceMappings.Data(),
-			LabelSelector:    o.Selector.LabelSelector,
-			RestorePVs:       o.RestoreVolumes.Value,
+			BackupName:                o.BackupName,
+			Namespaces:                o.Namespaces,
+			NamespaceMapping:          o.NamespaceMappings.Data(),
+			StorageClassMapping:       o.StorageClassMappings.Data(),
+			NodeMapping:               o.NodeMappings.Data(),
+			RegistryMapping:           o.RegistryMappings.Data(),
+			ResourceModifiers:         o.ResourceModifiers,
+			DataOnly:                  o.DataOnly,
+			Prune:                     o.Prune,
+			PruneExcludedResources:    o.PruneExcludedResources,
+			Preview:                   o.Preview,
+			LabelSelector:             o.Selector.LabelSelector,
+			IncludeClusterResources:   o.IncludeClusterResources.Value,
+			RestorePVs:                o.RestoreVolumes.Value,
+			UnsnapshottedVolumePolicy: api.UnsnapshottedVolumePolicy(o.VolumePolicy.String()),
+			PreserveClusterIPs:        o.PreserveClusterIPs,
+			PreserveNodePorts:         o.PreserveNodePorts,
+			ExistingResourcePolicy:    api.ExistingResourcePolicy(o.ExistingPolicy.String()),
 		},
 	}
 
+	if overrides := o.PolicyOverrides.Data(); len(overrides) > 0 {
+		restore.Spec.ExistingResourcePolicyOverrides = make(map[string]api.ExistingResourcePolicy, len(overrides))
+		for resource, policy := range overrides {
+			restore.Spec.ExistingResourcePolicyOverrides[resource] = api.ExistingResourcePolicy(policy)
+		}
+	}
+
+	if u, err := user.Current(); err == nil {
+		if restore.Annotations == nil {
+			restore.Annotations = make(map[string]string)
+		}
+		restore.Annotations[api.RequesterAnnotation] = u.Username
+	}
+
 	if printed, err := output.PrintWithFormat(c, restore); printed || err != nil {
 		return err
 	}
@@ -128,6 +237,106 @@ func (o *CreateOptions) Run(c *cobra.Command, f client.Factory) error {
 		return err
 	}
 
-	fmt.Printf("Restore %q created successfully.\n", restore.Name)
-	return nil
+	if !o.Preview {
+		fmt.Printf("Restore %q created successfully.\n", restore.Name)
+
+		if !o.Wait {
+			return nil
+		}
+
+		return waitForRestore(arkClient.ArkV1(), f.Namespace(), restore.Name, o.WaitTimeout, os.Stdout)
+	}
+
+	// the plan goes to stdout, so progress goes to stderr
+	fmt.Fprintf(os.Stderr, "Restore preview %q created, waiting for it to finish...\n", restore.Name)
+
+	err = wait.PollImmediate(time.Second, o.PreviewTimeout, func() (bool, error) {
+		restore, err = arkClient.ArkV1().Restores(restore.Namespace).Get(restore.Name, metav1.GetOptions{})
+		if err != nil {
+			return false, err
+		}
+
+		switch restore.Status.Phase {
+		case api.RestorePhaseCompleted, api.RestorePhasePartiallyFailed:
+			return true, nil
+		case api.RestorePhaseFailedValidation:
+			return false, fmt.Errorf("restore preview failed validation: %s", strings.Join(restore.Status.ValidationErrors, "; "))
+		case api.RestorePhaseFailed:
+			return false, fmt.Errorf("restore preview failed; run 'ark restore results %s' for details", restore.Name)
+		default:
+			return false, nil
+		}
+	})
+	if err == wait.ErrWaitTimeout {
+		return fmt.Errorf("timed out waiting for restore preview %q to finish", restore.Name)
+	}
+	if err != nil {
+		return err
+	}
+
+	return downloadrequest.Stream(arkClient.ArkV1(), f.Namespace(), restore.Name, api.DownloadTargetKindRestorePlan, os.Stdout, o.PreviewTimeout)
+}
+
+// waitForRestore polls the named restore until it reaches a terminal phase, displaying its
+// progress on w with a progress.Line. It returns an error if timeout is non-zero and passes
+// first, or if the restore didn't complete without errors.
+func waitForRestore(client arkv1client.RestoresGetter, namespace, name string, timeout time.Duration, w io.Writer) error {
+	fmt.Fprintf(w, "Waiting for restore %q to finish...\n", name)
+
+	var restore *api.Restore
+	line := progress.NewLine(w)
+	condition := func() (bool, error) {
+		var err error
+		if restore, err = client.Restores(namespace).Get(name, metav1.GetOptions{}); err != nil {
+			return false, err
+		}
+
+		line.Update(describeProgress(restore))
+
+		switch restore.Status.Phase {
+		case api.RestorePhaseCompleted, api.RestorePhasePartiallyFailed, api.RestorePhaseFailed, api.RestorePhaseFailedValidation:
+			return true, nil
+		}
+		return false, nil
+	}
+
+	var err error
+	if timeout > 0 {
+		err = wait.PollImmediate(time.Second, timeout, condition)
+	} else {
+		err = wait.PollImmediateInfinite(time.Second, condition)
+	}
+	line.Done()
+
+	if err == wait.ErrWaitTimeout {
+		return fmt.Errorf("timed out waiting for restore %q to finish", name)
+	}
+	if err != nil {
+		return fmt.Errorf("error waiting for restore %q to finish: %v", name, err)
+	}
+
+	switch restore.Status.Phase {
+	case api.RestorePhaseCompleted:
+		fmt.Fprintf(w, "Restore %q completed with %d warning(s).\n", name, restore.Status.WarningCounts.Total())
+		return nil
+	case api.RestorePhaseFailedValidation:
+		return fmt.Errorf("restore %q failed validation: %s", name, strings.Join(restore.Status.ValidationErrors, "; "))
+	default:
+		return fmt.Errorf("restore %q finished with phase %s and %d error(s); run 'ark restore results %s' for details", name, restore.Status.Phase, restore.Status.ErrorCounts.Total(), name)
+	}
+}
+
+// describeProgress returns a one-line summary of restore's phase and results so far.
+func describeProgress(restore *api.Restore) string {
+	phase := restore.Status.Phase
+	if phase == "" {
+		phase = api.RestorePhaseNew
+	}
+
+	switch phase {
+	case api.RestorePhaseCompleted, api.RestorePhasePartiallyFailed, api.RestorePhaseFailed:
+		return fmt.Sprintf("Phase: %s, %d warning(s), %d error(s)", phase, restore.Status.WarningCounts.Total(), restore.Status.ErrorCounts.Total())
+	default:
+		return fmt.Sprintf("Phase: %s", phase)
+	}
 }
@@ -0,0 +1,208 @@
+/*
+Copyright 2017 Heptio Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package restore
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	core "k8s.io/client-go/testing"
+
+	api "github.com/heptio/ark/pkg/apis/ark/v1"
+	"github.com/heptio/ark/pkg/cmd/util/output"
+	"github.com/heptio/ark/pkg/generated/clientset/fake"
+)
+
+func TestValidateWait(t *testing.T) {
+	tests := []struct {
+		name          string
+		flags         map[string]string
+		expectedError string
+	}{
+		{
+			name:  "--wait is allowed on its own",
+			flags: map[string]string{"wait": "true"},
+		},
+		{
+			name:          "--wait can't be used with --output",
+			flags:         map[string]string{"wait": "true", "output": "yaml"},
+			expectedError: "--wait can't be used with --output, since the restore isn't created",
+		},
+		{
+			name:          "--wait can't be used with --preview",
+			flags:         map[string]string{"wait": "true", "preview": "true"},
+			expectedError: "--wait can't be used with --preview, which already waits for the preview to finish",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			o := NewCreateOptions()
+			c := &cobra.Command{}
+			o.BindFlags(c.Flags())
+			output.BindFlags(c.Flags())
+			output.ClearOutputFlagDefault(c)
+			for name, value := range test.flags {
+				require.NoError(t, c.Flags().Set(name, value))
+			}
+
+			err := o.Validate(c, []string{"backup-1"})
+			if test.expectedError != "" {
+				assert.EqualError(t, err, test.expectedError)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestWaitForRestore(t *testing.T) {
+	tests := []struct {
+		name           string
+		status         api.RestoreStatus
+		timeout        time.Duration
+		expectedError  string
+		expectedOutput string
+	}{
+		{
+			name: "completed restores succeed",
+			status: api.RestoreStatus{
+				Phase:         api.RestorePhaseCompleted,
+				WarningCounts: api.RestoreResultCounts{Cluster: 1, Namespaces: map[string]int{"ns-1": 2}},
+			},
+			expectedOutput: "Restore \"restore-1\" completed with 3 warning(s).\n",
+		},
+		{
+			name: "partially failed restores fail",
+			status: api.RestoreStatus{
+				Phase:       api.RestorePhasePartiallyFailed,
+				ErrorCounts: api.RestoreResultCounts{Namespaces: map[string]int{"ns-1": 2}},
+			},
+			expectedError: "restore \"restore-1\" finished with phase PartiallyFailed and 2 error(s); run 'ark restore results restore-1' for details",
+		},
+		{
+			name:          "failed restores fail",
+			status:        api.RestoreStatus{Phase: api.RestorePhaseFailed, ErrorCounts: api.RestoreResultCounts{Ark: 1}},
+			expectedError: "restore \"restore-1\" finished with phase Failed and 1 error(s); run 'ark restore results restore-1' for details",
+		},
+		{
+			name:          "restores that fail validation fail",
+			status:        api.RestoreStatus{Phase: api.RestorePhaseFailedValidation, ValidationErrors: []string{"error 1", "error 2"}},
+			expectedError: "restore \"restore-1\" failed validation: error 1; error 2",
+		},
+		{
+			name:          "restores that don't finish in time fail",
+			status:        api.RestoreStatus{Phase: api.RestorePhaseInProgress},
+			timeout:       10 * time.Millisecond,
+			expectedError: "timed out waiting for restore \"restore-1\" to finish",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			restore := &api.Restore{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "ark-ns", Name: "restore-1"},
+				Status:     test.status,
+			}
+			client := fake.NewSimpleClientset(restore)
+
+			buf := new(bytes.Buffer)
+			err := waitForRestore(client.ArkV1(), "ark-ns", "restore-1", test.timeout, buf)
+			if test.expectedError != "" {
+				assert.EqualError(t, err, test.expectedError)
+			} else {
+				assert.NoError(t, err)
+			}
+
+			assert.True(t, strings.HasPrefix(buf.String(), "Waiting for restore \"restore-1\" to finish...\n"), "unexpected output %q", buf.String())
+			assert.True(t, strings.HasSuffix(buf.String(), test.expectedOutput), "unexpected output %q", buf.String())
+		})
+	}
+}
+
+func TestWaitForRestoreReportsProgress(t *testing.T) {
+	restore := &api.Restore{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ark-ns", Name: "restore-1"},
+		Status:     api.RestoreStatus{Phase: api.RestorePhaseInProgress},
+	}
+	client := fake.NewSimpleClientset(restore)
+
+	// the restore completes after it's first retrieved
+	gets := 0
+	client.PrependReactor("get", "restores", func(action core.Action) (bool, runtime.Object, error) {
+		gets++
+		if gets == 1 {
+			return false, nil, nil
+		}
+		completed := *restore
+		completed.Status = api.RestoreStatus{
+			Phase:         api.RestorePhaseCompleted,
+			WarningCounts: api.RestoreResultCounts{Ark: 1},
+		}
+		return true, &completed, nil
+	})
+
+	buf := new(bytes.Buffer)
+	require.NoError(t, waitForRestore(client.ArkV1(), "ark-ns", "restore-1", 0, buf))
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	require.Len(t, lines, 4)
+	assert.True(t, strings.HasPrefix(lines[1], "Phase: InProgress (elapsed: "), lines[1])
+	assert.True(t, strings.HasPrefix(lines[2], "Phase: Completed, 1 warning(s), 0 error(s) (elapsed: "), lines[2])
+	assert.Equal(t, "Restore \"restore-1\" completed with 1 warning(s).", lines[3])
+}
+
+func TestDescribeProgress(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   api.RestoreStatus
+		expected string
+	}{
+		{
+			name:     "new restores",
+			expected: "Phase: New",
+		},
+		{
+			name:     "running restores",
+			status:   api.RestoreStatus{Phase: api.RestorePhaseInProgress},
+			expected: "Phase: InProgress",
+		},
+		{
+			name: "finished restores",
+			status: api.RestoreStatus{
+				Phase:         api.RestorePhasePartiallyFailed,
+				WarningCounts: api.RestoreResultCounts{Ark: 1, Cluster: 2},
+				ErrorCounts:   api.RestoreResultCounts{Namespaces: map[string]int{"ns-1": 1, "ns-2": 3}},
+			},
+			expected: "Phase: PartiallyFailed, 3 warning(s), 4 error(s)",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.expected, describeProgress(&api.Restore{Status: test.status}))
+		})
+	}
+}
@@ -17,18 +17,112 @@ limitations under the License.
 package restore
 
 import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
+	api "github.com/heptio/ark/pkg/apis/ark/v1"
 	"github.com/heptio/ark/pkg/client"
+	"github.com/heptio/ark/pkg/cmd"
+	"github.com/heptio/ark/pkg/cmd/util/downloadrequest"
+	"github.com/heptio/ark/pkg/cmd/util/output"
 )
 
 func NewDescribeCommand(f client.Factory) *cobra.Command {
+	o := NewDescribeOptions()
+
 	c := &cobra.Command{
-		Use:   "describe",
-		Short: "Describe a backup",
+		Use:   "describe NAME [NAME...]",
+		Short: "Describe restores",
+		Long:  "Print a human-readable description of one or more restores: their backup, filters and mappings, warnings and errors, restored volumes, and hook results. With --details, the messages of a restore's warnings and errors are read from its results in object storage, using a temporary URL generated by the Ark server.",
 		Run: func(c *cobra.Command, args []string) {
+			cmd.CheckError(o.Validate(args))
+			cmd.CheckError(o.Complete(args))
+			cmd.CheckError(o.Run(f, os.Stdout))
 		},
 	}
 
+	o.BindFlags(c.Flags())
+
 	return c
 }
+
+type DescribeOptions struct {
+	Names   []string
+	Details bool
+	Timeout time.Duration
+}
+
+func NewDescribeOptions() *DescribeOptions {
+	return &DescribeOptions{
+		Timeout: time.Minute,
+	}
+}
+
+func (o *DescribeOptions) BindFlags(flags *pflag.FlagSet) {
+	flags.BoolVar(&o.Details, "details", o.Details, "list the messages of the restore's warnings and errors, which are read from its results")
+	flags.DurationVar(&o.Timeout, "timeout", o.Timeout, "maximum time to wait to process download request when --details is used")
+}
+
+func (o *DescribeOptions) Validate(args []string) error {
+	if len(args) == 0 {
+		return errors.New("you must specify at least one argument, the name of a restore")
+	}
+
+	return nil
+}
+
+func hasMessages(result api.RestoreResult) bool {
+	return len(result.Ark) > 0 || len(result.Cluster) > 0 || len(result.Namespaces) > 0
+}
+
+func (o *DescribeOptions) Complete(args []string) error {
+	o.Names = args
+	return nil
+}
+
+func (o *DescribeOptions) Run(f client.Factory, w io.Writer) error {
+	arkClient, err := f.Client()
+	if err != nil {
+		return err
+	}
+
+	for i, name := range o.Names {
+		restore, err := arkClient.ArkV1().Restores(f.Namespace()).Get(name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+
+		var results *output.RestoreResults
+		// results are only stored for restores that ran, and only need to be read if there are
+		// messages in them to list that aren't already in the restore's status
+		if o.Details && restore.Status.WarningCounts.Total()+restore.Status.ErrorCounts.Total() > 0 &&
+			!hasMessages(restore.Status.Warnings) && !hasMessages(restore.Status.Errors) {
+			buf := new(bytes.Buffer)
+			if err := downloadrequest.Stream(arkClient.ArkV1(), f.Namespace(), name, api.DownloadTargetKindRestoreResults, buf, o.Timeout); err != nil {
+				return fmt.Errorf("error downloading results of restore %q: %v", name, err)
+			}
+
+			results = new(output.RestoreResults)
+			if err := json.NewDecoder(buf).Decode(results); err != nil {
+				return fmt.Errorf("error reading results of restore %q: %v", name, err)
+			}
+		}
+
+		if i > 0 {
+			fmt.Fprintln(w)
+		}
+		fmt.Fprint(w, output.DescribeRestore(restore, results))
+	}
+
+	return nil
+}
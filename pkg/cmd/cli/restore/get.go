@@ -17,6 +17,8 @@ limitations under the License.
 package restore
 
 import (
+	"fmt"
+
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	"github.com/spf13/cobra"
@@ -28,7 +30,10 @@ import (
 )
 
 func NewGetCommand(f client.Factory) *cobra.Command {
-	var listOptions metav1.ListOptions
+	var (
+		listOptions metav1.ListOptions
+		clusterName string
+	)
 
 	c := &cobra.Command{
 		Use:   "get",
@@ -44,12 +49,21 @@ func NewGetCommand(f client.Factory) *cobra.Command {
 			if len(args) > 0 {
 				restores = new(api.RestoreList)
 				for _, name := range args {
-					restore, err := arkClient.Ark().Restores(api.DefaultNamespace).Get(name, metav1.GetOptions{})
+					restore, err := arkClient.Ark().Restores(f.Namespace()).Get(name, metav1.GetOptions{})
 					cmd.CheckError(err)
 					restores.Items = append(restores.Items, *restore)
 				}
 			} else {
-				restores, err = arkClient.ArkV1().Restores(api.DefaultNamespace).List(metav1.ListOptions{})
+				if clusterName != "" {
+					clusterSelector := fmt.Sprintf("%s=%s", api.ClusterNameLabel, clusterName)
+					if listOptions.LabelSelector == "" {
+						listOptions.LabelSelector = clusterSelector
+					} else {
+						listOptions.LabelSelector += "," + clusterSelector
+					}
+				}
+
+				restores, err = arkClient.ArkV1().Restores(f.Namespace()).List(listOptions)
 				cmd.CheckError(err)
 			}
 
@@ -64,6 +78,7 @@ func NewGetCommand(f client.Factory) *cobra.Command {
 	}
 
 	c.Flags().StringVarP(&listOptions.LabelSelector, "selector", "l", listOptions.LabelSelector, "only show items matching this label selector")
+	c.Flags().StringVar(&clusterName, "cluster", clusterName, "only show restores of backups taken in the cluster with this name")
 
 	output.BindFlags(c.Flags())
 
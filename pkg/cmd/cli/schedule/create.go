@@ -19,6 +19,7 @@ package schedule
 import (
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
@@ -29,7 +30,9 @@ import (
 	"github.com/heptio/ark/pkg/client"
 	"github.com/heptio/ark/pkg/cmd"
 	"github.com/heptio/ark/pkg/cmd/cli/backup"
+	"github.com/heptio/ark/pkg/cmd/util/flag"
 	"github.com/heptio/ark/pkg/cmd/util/output"
+	"github.com/heptio/ark/pkg/util/nametemplate"
 )
 
 func NewCreateCommand(f client.Factory) *cobra.Command {
@@ -55,6 +58,15 @@ func NewCreateCommand(f client.Factory) *cobra.Command {
 type CreateOptions struct {
 	BackupOptions *backup.CreateOptions
 	Schedule      string
+	Timezone      string
+	NameTemplate  string
+	Concurrency   flag.Enum
+	Jitter        time.Duration
+	KeepLast      int
+	KeepDaily     int
+	KeepWeekly    int
+	KeepMonthly   int
+	KeepYearly    int
 
 	labelSelector *metav1.LabelSelector
 }
@@ -62,12 +74,27 @@ type CreateOptions struct {
 func NewCreateOptions() *CreateOptions {
 	return &CreateOptions{
 		BackupOptions: backup.NewCreateOptions(),
+		Concurrency: flag.NewEnum(
+			"",
+			string(api.ConcurrencyPolicyAllow),
+			string(api.ConcurrencyPolicyForbid),
+			string(api.ConcurrencyPolicyReplace),
+		),
 	}
 }
 
 func (o *CreateOptions) BindFlags(flags *pflag.FlagSet) {
 	o.BackupOptions.BindFlags(flags)
 	flags.StringVar(&o.Schedule, "schedule", o.Schedule, "a cron expression specifying a recurring schedule for this backup to run")
+	flags.StringVar(&o.NameTemplate, "backup-name-template", o.NameTemplate, "the template the names of the schedule's backups are generated from, such as {schedule}-{cluster}-{date} (default {schedule}-{timestamp})")
+	flags.StringVar(&o.Timezone, "timezone", o.Timezone, "the IANA name of the time zone to evaluate the schedule in, such as America/New_York (default the Ark server's local time zone)")
+	flags.Var(&o.Concurrency, "concurrency-policy", "what to do when a backup is due while the schedule's previous backup is still running: Allow them to run concurrently, Forbid the new one and skip this run, or Replace the running one (default Allow)")
+	flags.DurationVar(&o.Jitter, "jitter", o.Jitter, "the length of the window after each scheduled time to start the backup in, at an offset derived from the schedule's name, so that schedules with the same cron expression don't all start at once")
+	flags.IntVar(&o.KeepLast, "keep-last", o.KeepLast, "the number of most recent completed backups to keep; setting any --keep flag replaces the backups' TTL with a retention policy")
+	flags.IntVar(&o.KeepDaily, "keep-daily", o.KeepDaily, "the number of days to keep the most recent completed backup from")
+	flags.IntVar(&o.KeepWeekly, "keep-weekly", o.KeepWeekly, "the number of weeks to keep the most recent completed backup from")
+	flags.IntVar(&o.KeepMonthly, "keep-monthly", o.KeepMonthly, "the number of months to keep the most recent completed backup from")
+	flags.IntVar(&o.KeepYearly, "keep-yearly", o.KeepYearly, "the number of years to keep the most recent completed backup from")
 }
 
 func (o *CreateOptions) Validate(c *cobra.Command, args []string) error {
@@ -77,6 +104,23 @@ func (o *CreateOptions) Validate(c *cobra.Command, args []string) error {
 	if len(o.Schedule) == 0 {
 		return errors.New("--schedule is required")
 	}
+	if o.Timezone != "" {
+		if _, err := time.LoadLocation(o.Timezone); err != nil {
+			return fmt.Errorf("invalid --timezone: %v", err)
+		}
+	}
+	if o.Jitter < 0 {
+		return errors.New("--jitter must not be negative")
+	}
+	if o.KeepLast < 0 || o.KeepDaily < 0 || o.KeepWeekly < 0 || o.KeepMonthly < 0 || o.KeepYearly < 0 {
+		return errors.New("--keep-last, --keep-daily, --keep-weekly, --keep-monthly, and --keep-yearly must not be negative")
+	}
+	if o.NameTemplate != "" {
+		// the server's cluster name isn't known here, so any name stands in for it
+		if err := nametemplate.Validate(o.NameTemplate, args[0], "cluster"); err != nil {
+			return fmt.Errorf("invalid --backup-name-template: %v", err)
+		}
+	}
 
 	return o.BackupOptions.Validate(c, args)
 }
@@ -93,23 +137,43 @@ func (o *CreateOptions) Run(c *cobra.Command, f client.Factory) error {
 
 	schedule := &api.Schedule{
 		ObjectMeta: metav1.ObjectMeta{
-			Namespace: api.DefaultNamespace,
+			Namespace: f.Namespace(),
 			Name:      o.BackupOptions.Name,
 		},
 		Spec: api.ScheduleSpec{
 			Template: api.BackupSpec{
-				IncludedNamespaces: o.BackupOptions.IncludeNamespaces,
-				ExcludedNamespaces: o.BackupOptions.ExcludeNamespaces,
-				IncludedResources:  o.BackupOptions.IncludeResources,
-				ExcludedResources:  o.BackupOptions.ExcludeResources,
-				LabelSelector:      o.BackupOptions.Selector.LabelSelector,
-				SnapshotVolumes:    o.BackupOptions.SnapshotVolumes.Value,
-				TTL:                metav1.Duration{Duration: o.BackupOptions.TTL},
+				IncludedNamespaces:      o.BackupOptions.IncludeNamespaces,
+				ExcludedNamespaces:      o.BackupOptions.ExcludeNamespaces,
+				IncludedResources:       o.BackupOptions.IncludeResources,
+				ExcludedResources:       o.BackupOptions.ExcludeResources,
+				LabelSelector:           o.BackupOptions.Selector.LabelSelector,
+				IncludeClusterResources: o.BackupOptions.IncludeClusterResources.Value,
+				IgnoreDefaultExcludes:   o.BackupOptions.IgnoreDefaultExcludes,
+				SnapshotVolumes:         o.BackupOptions.SnapshotVolumes.Value,
+				MoveVolumeData:          o.BackupOptions.MoveVolumeData,
+				UploaderType:            o.BackupOptions.Uploader,
+				StorageLocation:         o.BackupOptions.StorageLocation,
+				VolumeSnapshotLocations: o.BackupOptions.SnapshotLocations,
+				TTL:                     metav1.Duration{Duration: o.BackupOptions.TTL},
 			},
-			Schedule: o.Schedule,
+			Schedule:           o.Schedule,
+			Timezone:           o.Timezone,
+			BackupNameTemplate: o.NameTemplate,
+			ConcurrencyPolicy:  api.ConcurrencyPolicy(o.Concurrency.String()),
+			Jitter:             metav1.Duration{Duration: o.Jitter},
 		},
 	}
 
+	if o.KeepLast > 0 || o.KeepDaily > 0 || o.KeepWeekly > 0 || o.KeepMonthly > 0 || o.KeepYearly > 0 {
+		schedule.Spec.Retention = &api.RetentionPolicy{
+			Last:    o.KeepLast,
+			Daily:   o.KeepDaily,
+			Weekly:  o.KeepWeekly,
+			Monthly: o.KeepMonthly,
+			Yearly:  o.KeepYearly,
+		}
+	}
+
 	if printed, err := output.PrintWithFormat(c, schedule); printed || err != nil {
 		return err
 	}
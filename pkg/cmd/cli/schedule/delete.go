@@ -22,15 +22,22 @@ import (
 
 	"github.com/spf13/cobra"
 
-	api "github.com/heptio/ark/pkg/apis/ark/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
 	"github.com/heptio/ark/pkg/client"
 	"github.com/heptio/ark/pkg/cmd"
+	arkv1client "github.com/heptio/ark/pkg/generated/clientset/typed/ark/v1"
 )
 
 func NewDeleteCommand(f client.Factory) *cobra.Command {
 	c := &cobra.Command{
 		Use:   "delete NAME",
 		Short: "Delete a schedule",
+		Long: `Delete a schedule.
+
+The schedule's backups are left in place. Its backups have an owner reference to it, so deleting it in another way,
+such as with 'kubectl delete schedule', has the Kubernetes garbage collector delete their API objects (but not their
+contents in object storage, from which they're synced back) unless you pass --cascade=false.`,
 		Run: func(c *cobra.Command, args []string) {
 			if len(args) != 1 {
 				c.Usage()
@@ -42,8 +49,7 @@ func NewDeleteCommand(f client.Factory) *cobra.Command {
 
 			name := args[0]
 
-			err = arkClient.ArkV1().Schedules(api.DefaultNamespace).Delete(name, nil)
-			cmd.CheckError(err)
+			cmd.CheckError(deleteSchedule(arkClient.ArkV1(), f.Namespace(), name))
 
 			fmt.Printf("Schedule %q deleted\n", name)
 		},
@@ -51,3 +57,10 @@ func NewDeleteCommand(f client.Factory) *cobra.Command {
 
 	return c
 }
+
+// deleteSchedule deletes the named schedule, orphaning its backups rather than having the garbage
+// collector delete them along with it.
+func deleteSchedule(client arkv1client.SchedulesGetter, namespace, name string) error {
+	orphan := metav1.DeletePropagationOrphan
+	return client.Schedules(namespace).Delete(name, &metav1.DeleteOptions{PropagationPolicy: &orphan})
+}
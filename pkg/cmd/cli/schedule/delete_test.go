@@ -0,0 +1,58 @@
+/*
+Copyright 2017 Heptio Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package schedule
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	arkv1client "github.com/heptio/ark/pkg/generated/clientset/typed/ark/v1"
+)
+
+// fakeSchedules records the schedules deleted through it, since the generated fake clientset
+// doesn't record delete options.
+type fakeSchedules struct {
+	arkv1client.ScheduleInterface
+	namespace string
+	deleted   map[string]*metav1.DeleteOptions
+}
+
+func (s *fakeSchedules) Schedules(namespace string) arkv1client.ScheduleInterface {
+	s.namespace = namespace
+	return s
+}
+
+func (s *fakeSchedules) Delete(name string, options *metav1.DeleteOptions) error {
+	s.deleted[name] = options
+	return nil
+}
+
+func TestDeleteScheduleOrphansBackups(t *testing.T) {
+	schedules := &fakeSchedules{deleted: make(map[string]*metav1.DeleteOptions)}
+
+	require.NoError(t, deleteSchedule(schedules, "ark-ns", "daily"))
+
+	assert.Equal(t, "ark-ns", schedules.namespace)
+	require.Contains(t, schedules.deleted, "daily")
+	require.NotNil(t, schedules.deleted["daily"])
+	require.NotNil(t, schedules.deleted["daily"].PropagationPolicy)
+	assert.Equal(t, metav1.DeletePropagationOrphan, *schedules.deleted["daily"].PropagationPolicy)
+}
@@ -17,18 +17,97 @@ limitations under the License.
 package schedule
 
 import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 
+	api "github.com/heptio/ark/pkg/apis/ark/v1"
 	"github.com/heptio/ark/pkg/client"
+	"github.com/heptio/ark/pkg/cmd"
+	"github.com/heptio/ark/pkg/cmd/util/output"
 )
 
 func NewDescribeCommand(f client.Factory) *cobra.Command {
+	o := NewDescribeOptions()
+
 	c := &cobra.Command{
-		Use:   "describe",
-		Short: "Describe a backup",
+		Use:   "describe NAME [NAME...]",
+		Short: "Describe schedules",
+		Long:  "Print a human-readable description of one or more schedules: their Cron expressions, pause state, backup templates, most recent backups, and when they'll next run.",
 		Run: func(c *cobra.Command, args []string) {
+			cmd.CheckError(o.Validate(args))
+			cmd.CheckError(o.Complete(args))
+			cmd.CheckError(o.Run(f, os.Stdout))
 		},
 	}
 
+	o.BindFlags(c.Flags())
+
 	return c
 }
+
+type DescribeOptions struct {
+	Names   []string
+	Backups int
+}
+
+func NewDescribeOptions() *DescribeOptions {
+	return &DescribeOptions{
+		Backups: 5,
+	}
+}
+
+func (o *DescribeOptions) BindFlags(flags *pflag.FlagSet) {
+	flags.IntVar(&o.Backups, "backups", o.Backups, "number of the schedule's most recent backups to list")
+}
+
+func (o *DescribeOptions) Validate(args []string) error {
+	if len(args) == 0 {
+		return errors.New("you must specify at least one argument, the name of a schedule")
+	}
+
+	if o.Backups < 0 {
+		return errors.New("--backups must be at least 0")
+	}
+
+	return nil
+}
+
+func (o *DescribeOptions) Complete(args []string) error {
+	o.Names = args
+	return nil
+}
+
+func (o *DescribeOptions) Run(f client.Factory, w io.Writer) error {
+	arkClient, err := f.Client()
+	if err != nil {
+		return err
+	}
+
+	for i, name := range o.Names {
+		schedule, err := arkClient.ArkV1().Schedules(f.Namespace()).Get(name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+
+		selector := labels.SelectorFromSet(labels.Set{api.ScheduleNameLabel: name})
+		backups, err := arkClient.ArkV1().Backups(f.Namespace()).List(metav1.ListOptions{LabelSelector: selector.String()})
+		if err != nil {
+			return fmt.Errorf("error listing backups of schedule %q: %v", name, err)
+		}
+
+		if i > 0 {
+			fmt.Fprintln(w)
+		}
+		fmt.Fprint(w, output.DescribeSchedule(schedule, backups.Items, o.Backups))
+	}
+
+	return nil
+}
@@ -44,12 +44,12 @@ func NewGetCommand(f client.Factory) *cobra.Command {
 			if len(args) > 0 {
 				schedules = new(api.ScheduleList)
 				for _, name := range args {
-					schedule, err := arkClient.Ark().Schedules(api.DefaultNamespace).Get(name, metav1.GetOptions{})
+					schedule, err := arkClient.Ark().Schedules(f.Namespace()).Get(name, metav1.GetOptions{})
 					cmd.CheckError(err)
 					schedules.Items = append(schedules.Items, *schedule)
 				}
 			} else {
-				schedules, err = arkClient.ArkV1().Schedules(api.DefaultNamespace).List(metav1.ListOptions{})
+				schedules, err = arkClient.ArkV1().Schedules(f.Namespace()).List(metav1.ListOptions{})
 				cmd.CheckError(err)
 			}
 
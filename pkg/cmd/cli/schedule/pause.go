@@ -0,0 +1,92 @@
+/*
+Copyright 2017 Heptio Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package schedule
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/heptio/ark/pkg/client"
+	"github.com/heptio/ark/pkg/cmd"
+)
+
+func NewPauseCommand(f client.Factory) *cobra.Command {
+	c := &cobra.Command{
+		Use:   "pause NAME",
+		Short: "Pause a schedule",
+		Long:  "Pause a schedule so that it doesn't create any backups until it's resumed with 'ark schedule unpause'. The schedule and its backups are kept.",
+		Run: func(c *cobra.Command, args []string) {
+			if len(args) != 1 {
+				c.Usage()
+				os.Exit(1)
+			}
+
+			name := args[0]
+			cmd.CheckError(setPaused(f, name, true))
+
+			fmt.Printf("Schedule %q paused\n", name)
+		},
+	}
+
+	return c
+}
+
+func NewUnpauseCommand(f client.Factory) *cobra.Command {
+	c := &cobra.Command{
+		Use:   "unpause NAME",
+		Short: "Resume a paused schedule",
+		Long:  "Resume a paused schedule. If a backup was due while it was paused, one is created right away.",
+		Run: func(c *cobra.Command, args []string) {
+			if len(args) != 1 {
+				c.Usage()
+				os.Exit(1)
+			}
+
+			name := args[0]
+			cmd.CheckError(setPaused(f, name, false))
+
+			fmt.Printf("Schedule %q resumed\n", name)
+		},
+	}
+
+	return c
+}
+
+// setPaused patches the schedule called name to set whether it's paused.
+func setPaused(f client.Factory, name string, paused bool) error {
+	arkClient, err := f.Client()
+	if err != nil {
+		return err
+	}
+
+	patch, err := json.Marshal(map[string]interface{}{
+		"spec": map[string]interface{}{
+			"paused": paused,
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = arkClient.ArkV1().Schedules(f.Namespace()).Patch(name, types.MergePatchType, patch)
+	return err
+}
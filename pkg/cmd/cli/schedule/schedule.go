@@ -32,9 +32,10 @@ func NewCommand(f client.Factory) *cobra.Command {
 	c.AddCommand(
 		NewCreateCommand(f),
 		NewGetCommand(f),
-		// Will implement later
-		// NewDescribeCommand(f),
+		NewDescribeCommand(f),
 		NewDeleteCommand(f),
+		NewPauseCommand(f),
+		NewUnpauseCommand(f),
 	)
 
 	return c
@@ -0,0 +1,128 @@
+/*
+Copyright 2017 Heptio Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package snapshotlocation
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	api "github.com/heptio/ark/pkg/apis/ark/v1"
+	"github.com/heptio/ark/pkg/client"
+	"github.com/heptio/ark/pkg/cmd"
+	"github.com/heptio/ark/pkg/cmd/util/cloudconfig"
+	"github.com/heptio/ark/pkg/cmd/util/flag"
+)
+
+func NewCreateCommand(f client.Factory) *cobra.Command {
+	o := NewCreateOptions()
+
+	c := &cobra.Command{
+		Use:   "create NAME --provider PROVIDER",
+		Short: "Create a volume snapshot location",
+		Long: `Create a VolumeSnapshotLocation for the Ark server to take PersistentVolume snapshots in. Snapshots in it are taken
+using the server's credentials for its cloud provider.
+
+Provider configuration is set with --config:
+` + cloudconfig.Keys,
+		Example: `  ark snapshot-location create us-west --provider aws --config region=us-west-2,availabilityZone=us-west-2a
+
+  # snapshot AWS volumes in us-east-1 unless their backup chooses another location
+  ark snapshot-location create us-east --provider aws --config region=us-east-1 --default`,
+		Run: func(c *cobra.Command, args []string) {
+			cmd.CheckError(o.Validate(args))
+			cmd.CheckError(o.Complete(args))
+			cmd.CheckError(o.Run(f))
+		},
+	}
+
+	o.BindFlags(c.Flags())
+
+	return c
+}
+
+type CreateOptions struct {
+	Name       string
+	Provider   flag.Enum
+	Config     flag.Map
+	SetDefault bool
+}
+
+func NewCreateOptions() *CreateOptions {
+	return &CreateOptions{
+		Provider: flag.NewEnum("", "aws", "gcp", "azure"),
+		Config:   flag.NewMap(),
+	}
+}
+
+func (o *CreateOptions) BindFlags(flags *pflag.FlagSet) {
+	flags.Var(&o.Provider, "provider", "the cloud provider of the location: aws, gcp, or azure")
+	flags.Var(&o.Config, "config", "configuration of the cloud provider, as key=value pairs")
+	flags.BoolVar(&o.SetDefault, "default", o.SetDefault, "make the location the default for volumes of its cloud provider")
+}
+
+func (o *CreateOptions) Validate(args []string) error {
+	if len(args) != 1 {
+		return errors.New("you must specify only one argument, the location's name")
+	}
+	if o.Provider.String() == "" {
+		return errors.New("--provider is required")
+	}
+
+	return nil
+}
+
+func (o *CreateOptions) Complete(args []string) error {
+	o.Name = args[0]
+	return nil
+}
+
+func (o *CreateOptions) Run(f client.Factory) error {
+	providerConfig, err := cloudconfig.Parse(o.Provider.String(), o.Config.Data())
+	if err != nil {
+		return err
+	}
+
+	arkClient, err := f.Client()
+	if err != nil {
+		return err
+	}
+
+	location := &api.VolumeSnapshotLocation{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: f.Namespace(),
+			Name:      o.Name,
+		},
+		Spec: api.VolumeSnapshotLocationSpec{
+			CloudProviderConfig: providerConfig,
+		},
+	}
+
+	if _, err := arkClient.ArkV1().VolumeSnapshotLocations(f.Namespace()).Create(location); err != nil {
+		return err
+	}
+	fmt.Printf("Volume snapshot location %q created.\n", o.Name)
+
+	if o.SetDefault {
+		return setDefault(arkClient.ArkV1(), f.Namespace(), o.Name)
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+/*
+Copyright 2017 Heptio Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package snapshotlocation
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	api "github.com/heptio/ark/pkg/apis/ark/v1"
+	"github.com/heptio/ark/pkg/client"
+	"github.com/heptio/ark/pkg/cloudprovider"
+	"github.com/heptio/ark/pkg/cmd"
+	"github.com/heptio/ark/pkg/cmd/util/cloudconfig"
+)
+
+func NewGetCommand(f client.Factory) *cobra.Command {
+	c := &cobra.Command{
+		Use:   "get [NAME...]",
+		Short: "Get volume snapshot locations",
+		Long:  "List the Ark server's volume snapshot locations, or the named ones, with their cloud providers and configuration.",
+		Run: func(c *cobra.Command, args []string) {
+			arkClient, err := f.Client()
+			cmd.CheckError(err)
+
+			config, err := getConfig(arkClient.ArkV1(), f.Namespace())
+			cmd.CheckError(err)
+
+			list, err := arkClient.ArkV1().VolumeSnapshotLocations(f.Namespace()).List(metav1.ListOptions{})
+			cmd.CheckError(err)
+
+			all := make(map[string]*api.VolumeSnapshotLocation, len(list.Items))
+			for i := range list.Items {
+				all[list.Items[i].Name] = &list.Items[i]
+			}
+
+			// the defaults are shown as the server determines them, ignoring invalid locations
+			defaults, _ := cloudprovider.DefaultSnapshotLocations(all, config.DefaultVolumeSnapshotLocations)
+
+			names := args
+			if len(names) == 0 {
+				for name := range all {
+					names = append(names, name)
+				}
+				sort.Strings(names)
+			}
+
+			tw := tabwriter.NewWriter(os.Stdout, 10, 4, 3, ' ', 0)
+			fmt.Fprintln(tw, "NAME\tPROVIDER\tCONFIG\tDEFAULT")
+			for _, name := range names {
+				location, ok := all[name]
+				if !ok {
+					cmd.CheckError(fmt.Errorf("volume snapshot location %q doesn't exist", name))
+				}
+
+				provider := cloudprovider.ProviderName(location.Spec.CloudProviderConfig)
+				fmt.Fprintf(tw, "%s\t%s\t%s\t%t\n", name, provider, cloudconfig.Describe(location.Spec.CloudProviderConfig), defaults[provider] == name)
+			}
+			cmd.CheckError(tw.Flush())
+		},
+	}
+
+	return c
+}
@@ -0,0 +1,77 @@
+/*
+Copyright 2017 Heptio Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package snapshotlocation
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/heptio/ark/pkg/client"
+	"github.com/heptio/ark/pkg/cloudprovider"
+	"github.com/heptio/ark/pkg/cmd"
+	arkv1client "github.com/heptio/ark/pkg/generated/clientset/typed/ark/v1"
+)
+
+func NewSetDefaultCommand(f client.Factory) *cobra.Command {
+	c := &cobra.Command{
+		Use:   "set-default NAME",
+		Short: "Set the default volume snapshot location of a cloud provider",
+		Long:  "Set the volume snapshot location that volumes of its cloud provider are snapshotted in when their backup doesn't have a location for the provider. Existing snapshots stay where they are.",
+		Run: func(c *cobra.Command, args []string) {
+			if len(args) != 1 {
+				cmd.CheckError(errors.New("you must specify only one argument, the location's name"))
+			}
+
+			arkClient, err := f.Client()
+			cmd.CheckError(err)
+
+			cmd.CheckError(setDefault(arkClient.ArkV1(), f.Namespace(), args[0]))
+		},
+	}
+
+	return c
+}
+
+// setDefault makes the named volume snapshot location in namespace the default for its cloud
+// provider in the Config.
+func setDefault(client arkv1client.ArkV1Interface, namespace, name string) error {
+	location, err := client.VolumeSnapshotLocations(namespace).Get(name, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	provider := cloudprovider.ProviderName(location.Spec.CloudProviderConfig)
+
+	config, err := getConfig(client, namespace)
+	if err != nil {
+		return err
+	}
+
+	if config.DefaultVolumeSnapshotLocations == nil {
+		config.DefaultVolumeSnapshotLocations = make(map[string]string)
+	}
+	config.DefaultVolumeSnapshotLocations[provider] = name
+	if _, err := client.Configs(namespace).Update(config); err != nil {
+		return err
+	}
+
+	fmt.Printf("Volume snapshot location %q is now the default for %s.\n", name, provider)
+	return nil
+}
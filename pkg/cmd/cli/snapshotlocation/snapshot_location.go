@@ -0,0 +1,57 @@
+/*
+Copyright 2017 Heptio Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package snapshotlocation
+
+import (
+	"github.com/spf13/cobra"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	api "github.com/heptio/ark/pkg/apis/ark/v1"
+	"github.com/heptio/ark/pkg/client"
+	arkv1client "github.com/heptio/ark/pkg/generated/clientset/typed/ark/v1"
+)
+
+// configName is the name of the Config that the Ark server reads, in the Ark namespace.
+const configName = "default"
+
+func NewCommand(f client.Factory) *cobra.Command {
+	c := &cobra.Command{
+		Use:   "snapshot-location",
+		Short: "Work with volume snapshot locations",
+		Long: `Work with the Ark server's volume snapshot locations: the clouds and regions that PersistentVolume snapshots can be
+taken in.
+
+Each location is a VolumeSnapshotLocation in the Ark namespace. A backup chooses locations with
+--volume-snapshot-locations, at most one for each cloud provider, and volumes of other providers are snapshotted in
+the provider's default location: the one set with set-default, or the provider's only location. The Ark server
+restarts to pick up changes to the locations.`,
+	}
+
+	c.AddCommand(
+		NewGetCommand(f),
+		NewCreateCommand(f),
+		NewSetDefaultCommand(f),
+	)
+
+	return c
+}
+
+// getConfig returns the Config in namespace that the Ark server reads.
+func getConfig(client arkv1client.ConfigsGetter, namespace string) (*api.Config, error) {
+	return client.Configs(namespace).Get(configName, metav1.GetOptions{})
+}
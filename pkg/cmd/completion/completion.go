@@ -0,0 +1,458 @@
+/*
+Copyright 2017 Heptio Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package completion
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+
+	"github.com/heptio/ark/pkg/cmd"
+)
+
+// nameArgs maps the commands whose arguments are names of Ark resources, identified by their
+// path below the root command, to the command that lists those resources.
+var nameArgs = map[string]string{
+	"backup get":                  "backup",
+	"backup describe":             "backup",
+	"backup verify":               "backup",
+	"backup logs":                 "backup",
+	"backup download":             "backup",
+	"backup diff":                 "backup",
+	"backup contents":             "backup",
+	"backup cancel":               "backup",
+	"backup delete":               "backup",
+	"backup-location get":         "backup-location",
+	"backup-location set-default": "backup-location",
+	"restore get":                 "restore",
+	"restore describe":            "restore",
+	"restore logs":                "restore",
+	"restore results":             "restore",
+	"restore delete":              "restore",
+	"restore create":              "backup",
+	"schedule get":                "schedule",
+	"schedule describe":           "schedule",
+	"schedule delete":             "schedule",
+	"schedule pause":              "schedule",
+	"schedule unpause":            "schedule",
+	"snapshot-location get":       "snapshot-location",
+	"plugin remove":               "plugin",
+}
+
+// nameFlags maps the flags whose values are names of Ark resources, identified by their command's
+// path below the root command and the flag's name, to the command that lists those resources.
+var nameFlags = map[string]map[string]string{
+	"backup create": {
+		"parent-backup":             "backup",
+		"from-schedule":             "schedule",
+		"storage-location":          "backup-location",
+		"volume-snapshot-locations": "snapshot-location",
+	},
+	"schedule create": {
+		"storage-location":          "backup-location",
+		"volume-snapshot-locations": "snapshot-location",
+	},
+}
+
+func NewCommand() *cobra.Command {
+	c := &cobra.Command{
+		Use:       "completion SHELL",
+		Short:     "Output shell completion code for bash, zsh, or fish",
+		ValidArgs: []string{"bash", "zsh", "fish"},
+		Long: `Output shell completion code for bash, zsh, or fish. Besides commands and flags, the names of
+backups, restores, schedules, plugins, and storage and snapshot locations are completed, by listing them with the
+ark CLI.
+
+To load completion in bash (which requires the bash-completion package):
+
+    source <(ark completion bash)
+
+To load completion in zsh:
+
+    source <(ark completion zsh)
+
+To load completion in fish:
+
+    ark completion fish | source`,
+		Run: func(c *cobra.Command, args []string) {
+			if len(args) != 1 {
+				cmd.CheckError(errors.New("you must specify only one argument, the shell"))
+			}
+			cmd.CheckError(Generate(c.Root(), args[0], os.Stdout))
+		},
+	}
+
+	return c
+}
+
+// Generate writes the completion code for root's command tree in the given shell to w.
+func Generate(root *cobra.Command, shell string, w io.Writer) error {
+	switch shell {
+	case "bash":
+		return generateBash(root, w)
+	case "zsh":
+		return generateZsh(root, w)
+	case "fish":
+		return generateFish(root, w)
+	default:
+		return fmt.Errorf("unsupported shell %q - supported shells are bash, zsh, and fish", shell)
+	}
+}
+
+// commandPath returns c's path below the root command, e.g. "backup describe".
+func commandPath(c *cobra.Command) string {
+	path := c.CommandPath()
+	return strings.TrimPrefix(path, c.Root().Name()+" ")
+}
+
+// walk calls fn for each command in root's tree, in the order they're listed in help.
+func walk(c *cobra.Command, fn func(*cobra.Command)) {
+	fn(c)
+	for _, child := range c.Commands() {
+		if !child.IsAvailableCommand() {
+			continue
+		}
+		walk(child, fn)
+	}
+}
+
+// bashFunctionPrefix is the prefix of the names of the functions added to the bash completion
+// script for root.
+func bashFunctionPrefix(root *cobra.Command) string {
+	return "__" + strings.Replace(root.Name(), "-", "_", -1)
+}
+
+func generateBash(root *cobra.Command, w io.Writer) error {
+	prefix := bashFunctionPrefix(root)
+
+	custom := new(bytes.Buffer)
+	fmt.Fprintf(custom, `%s_get_names()
+{
+    local ark_out
+    if ark_out=$(%s "$1" get 2>/dev/null | awk 'NR > 1 { print $1 }'); then
+        COMPREPLY=( $( compgen -W "${ark_out[*]}" -- "$cur" ) )
+    fi
+}
+
+`, prefix, root.Name())
+
+	resources := make(map[string]bool)
+	for _, resource := range nameArgs {
+		resources[resource] = true
+	}
+	for _, flags := range nameFlags {
+		for _, resource := range flags {
+			resources[resource] = true
+		}
+	}
+	for _, resource := range sortedKeys(resources) {
+		fmt.Fprintf(custom, "%s_get_%ss()\n{\n    %s_get_names %s\n}\n\n", prefix, resource, prefix, resource)
+	}
+
+	fmt.Fprintln(custom, "__custom_func() {\n    case ${last_command} in")
+	argCommands := make(map[string]bool)
+	for path := range nameArgs {
+		argCommands[path] = true
+	}
+	for _, path := range sortedKeys(argCommands) {
+		lastCommand := strings.Replace(root.Name()+" "+path, " ", "_", -1)
+		fmt.Fprintf(custom, "        %s)\n            %s_get_%ss\n            return\n            ;;\n", lastCommand, prefix, nameArgs[path])
+	}
+	fmt.Fprintln(custom, "        *)\n            ;;\n    esac\n}")
+
+	var err error
+	walk(root, func(c *cobra.Command) {
+		for flag, resource := range nameFlags[commandPath(c)] {
+			if markErr := cobra.MarkFlagCustom(c.Flags(), flag, fmt.Sprintf("%s_get_%ss", prefix, resource)); markErr != nil && err == nil {
+				err = markErr
+			}
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	root.BashCompletionFunction = custom.String()
+	return root.GenBashCompletion(w)
+}
+
+// generateZsh writes a zsh script that emulates the bash completion functions the bash script
+// uses and sources it with zsh's bashcompinit, which is how kubectl supports zsh.
+func generateZsh(root *cobra.Command, w io.Writer) error {
+	name := strings.Replace(root.Name(), "-", "_", -1)
+
+	fmt.Fprintf(w, "#compdef %s\n", root.Name())
+	fmt.Fprint(w, strings.Replace(zshHead, "__ark", "__"+name, -1))
+
+	if err := generateBash(root, w); err != nil {
+		return err
+	}
+
+	_, err := fmt.Fprint(w, strings.Replace(zshTail, "__ark", "__"+name, -1))
+	return err
+}
+
+const zshHead = `
+__ark_bash_source() {
+	alias shopt=':'
+	alias _expand=_bash_expand
+	alias _complete=_bash_comp
+	emulate -L sh
+	setopt kshglob noshglob braceexpand
+
+	source "$@"
+}
+
+__ark_type() {
+	# -t is not supported by zsh
+	if [ "$1" == "-t" ]; then
+		shift
+
+		# fake Bash 4 to disable "complete -o nospace". Instead
+		# "compopt +-o nospace" is used in the code to toggle trailing
+		# spaces. We don't support that, but leave trailing spaces on
+		# all the time
+		if [ "$1" = "__ark_compopt" ]; then
+			echo builtin
+			return 0
+		fi
+	fi
+	type "$@"
+}
+
+__ark_compgen() {
+	local completions w
+	completions=( $(compgen "$@") ) || return $?
+
+	# filter by given word as prefix
+	while [[ "$1" = -* && "$1" != -- ]]; do
+		shift
+		shift
+	done
+	if [[ "$1" == -- ]]; then
+		shift
+	fi
+	for w in "${completions[@]}"; do
+		if [[ "${w}" = "$1"* ]]; then
+			echo "${w}"
+		fi
+	done
+}
+
+__ark_compopt() {
+	true # don't do anything. Not supported by bashcompinit in zsh
+}
+
+__ark_declare() {
+	if [ "$1" == "-F" ]; then
+		whence -w "$@"
+	else
+		builtin declare "$@"
+	fi
+}
+
+__ark_ltrim_colon_completions()
+{
+	if [[ "$1" == *:* && "$COMP_WORDBREAKS" == *:* ]]; then
+		# Remove colon-word prefix from COMPREPLY items
+		local colon_word=${1%${1##*:}}
+		local i=${#COMPREPLY[*]}
+		while [[ $((--i)) -ge 0 ]]; do
+			COMPREPLY[$i]=${COMPREPLY[$i]#"$colon_word"}
+		done
+	fi
+}
+
+__ark_get_comp_words_by_ref() {
+	cur="${COMP_WORDS[COMP_CWORD]}"
+	prev="${COMP_WORDS[${COMP_CWORD}-1]}"
+	words=("${COMP_WORDS[@]}")
+	cword=("${COMP_CWORD[@]}")
+}
+
+__ark_filedir() {
+	local RET OLD_IFS w qw
+
+	if [[ "$1" = \~* ]]; then
+		eval echo "$1"
+		return 0
+	fi
+
+	OLD_IFS="$IFS"
+	IFS=$'\n'
+	if [ "$1" = "-d" ]; then
+		shift
+		RET=( $(compgen -d) )
+	else
+		RET=( $(compgen -f) )
+	fi
+	IFS="$OLD_IFS"
+
+	for w in ${RET[@]}; do
+		if [[ ! "${w}" = "${cur}"* ]]; then
+			continue
+		fi
+		if eval "[[ \"\${w}\" = *.$1 || -d \"\${w}\" ]]"; then
+			qw="$(printf %q "${w}")"
+			if [ -d "${w}" ]; then
+				COMPREPLY+=("${qw}/")
+			else
+				COMPREPLY+=("${qw}")
+			fi
+		fi
+	done
+}
+
+autoload -U +X bashcompinit && bashcompinit
+
+# use word boundary patterns for BSD or GNU sed
+LWORD='[[:<:]]'
+RWORD='[[:>:]]'
+if sed --help 2>&1 | grep -q GNU; then
+	LWORD='\<'
+	RWORD='\>'
+fi
+
+__ark_convert_bash_to_zsh() {
+	sed \
+	-e 's/declare -F/whence -w/' \
+	-e 's/_get_comp_words_by_ref "\$@"/_get_comp_words_by_ref "\$*"/' \
+	-e 's/local \([a-zA-Z0-9_]*\)=/local \1; \1=/' \
+	-e 's/flags+=("\(--.*\)=")/flags+=("\1"); two_word_flags+=("\1")/' \
+	-e 's/must_have_one_flag+=("\(--.*\)=")/must_have_one_flag+=("\1")/' \
+	-e "s/${LWORD}_filedir${RWORD}/__ark_filedir/g" \
+	-e "s/${LWORD}_get_comp_words_by_ref${RWORD}/__ark_get_comp_words_by_ref/g" \
+	-e "s/${LWORD}__ltrim_colon_completions${RWORD}/__ark_ltrim_colon_completions/g" \
+	-e "s/${LWORD}compgen${RWORD}/__ark_compgen/g" \
+	-e "s/${LWORD}compopt${RWORD}/__ark_compopt/g" \
+	-e "s/${LWORD}declare${RWORD}/__ark_declare/g" \
+	-e "s/\\\$(type${RWORD}/\$(__ark_type/g" \
+	<<'BASH_COMPLETION_EOF'
+`
+
+const zshTail = `
+BASH_COMPLETION_EOF
+}
+
+__ark_bash_source <(__ark_convert_bash_to_zsh)
+`
+
+// generateFish writes fish completions for each of the commands and flags in root's tree. A
+// command's completions only apply when exactly its path has been typed, ignoring flags.
+func generateFish(root *cobra.Command, w io.Writer) error {
+	name := root.Name()
+	fn := "__" + strings.Replace(name, "-", "_", -1)
+
+	fmt.Fprintf(w, `# fish completion for %s
+
+function %s_using_command
+    set -l words
+    for word in (commandline -opc)[2..-1]
+        if not string match -q -- '-*' $word
+            set words $words $word
+        end
+    end
+    test "$words" = "$argv"
+end
+
+function %s_get_names
+    %s $argv[1] get 2>/dev/null | awk 'NR > 1 { print $1 }'
+end
+
+`, name, fn, fn, name)
+
+	root.PersistentFlags().VisitAll(func(f *pflag.Flag) {
+		if f.Hidden {
+			return
+		}
+		fmt.Fprintf(w, "complete -c %s%s\n", name, fishFlag(f))
+	})
+
+	walk(root, func(c *cobra.Command) {
+		path := ""
+		if c != root {
+			path = commandPath(c)
+		}
+		condition := fmt.Sprintf("-n '%s_using_command %s'", fn, path)
+
+		for _, child := range c.Commands() {
+			if !child.IsAvailableCommand() {
+				continue
+			}
+			fmt.Fprintf(w, "complete -c %s %s -f -a %s -d %s\n", name, condition, child.Name(), fishQuote(child.Short))
+		}
+
+		if c == root {
+			return
+		}
+
+		c.NonInheritedFlags().VisitAll(func(f *pflag.Flag) {
+			if f.Hidden || root.PersistentFlags().Lookup(f.Name) != nil {
+				return
+			}
+			completion := ""
+			if resource, ok := nameFlags[path][f.Name]; ok {
+				completion = fmt.Sprintf(" -x -a '(%s_get_names %s)'", fn, resource)
+			}
+			fmt.Fprintf(w, "complete -c %s %s%s%s\n", name, condition, fishFlag(f), completion)
+		})
+
+		if resource, ok := nameArgs[path]; ok {
+			fmt.Fprintf(w, "complete -c %s %s -f -a '(%s_get_names %s)'\n", name, condition, fn, resource)
+		}
+		for _, arg := range c.ValidArgs {
+			fmt.Fprintf(w, "complete -c %s %s -f -a %s\n", name, condition, arg)
+		}
+	})
+
+	return nil
+}
+
+// fishFlag returns the arguments to fish's complete command that describe f.
+func fishFlag(f *pflag.Flag) string {
+	s := " -l " + f.Name
+	if f.Shorthand != "" {
+		s += " -s " + f.Shorthand
+	}
+	if f.Value.Type() != "bool" && f.NoOptDefVal == "" {
+		s += " -r"
+	}
+	return s + " -d " + fishQuote(f.Usage)
+}
+
+// fishQuote returns s as a single-quoted fish string.
+func fishQuote(s string) string {
+	s = strings.Replace(s, `\`, `\\`, -1)
+	s = strings.Replace(s, `'`, `\'`, -1)
+	return "'" + s + "'"
+}
+
+func sortedKeys(m map[string]bool) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
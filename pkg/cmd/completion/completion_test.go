@@ -0,0 +1,122 @@
+/*
+Copyright 2017 Heptio Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package completion
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestCommand returns a command tree like ark's, with commands and flags whose arguments are
+// completed with the names of Ark resources.
+func newTestCommand() *cobra.Command {
+	run := func(*cobra.Command, []string) {}
+
+	root := &cobra.Command{Use: "ark"}
+	root.PersistentFlags().StringP("namespace", "n", "", "the namespace")
+
+	backup := &cobra.Command{Use: "backup", Short: "Work with backups"}
+	create := &cobra.Command{Use: "create", Short: "Create a backup", Run: run}
+	create.Flags().String("parent-backup", "", "the parent backup")
+	create.Flags().String("from-schedule", "", "the schedule")
+	create.Flags().String("storage-location", "", "the storage location")
+	create.Flags().String("volume-snapshot-locations", "", "the snapshot locations")
+	create.Flags().Bool("wait", false, "wait for the backup's")
+	backup.AddCommand(
+		create,
+		&cobra.Command{Use: "describe", Short: "Describe backups", Run: run},
+		&cobra.Command{Use: "hidden", Short: "Hidden", Hidden: true, Run: run},
+	)
+
+	root.AddCommand(
+		backup,
+		&cobra.Command{Use: "completion", Short: "Output shell completion code", ValidArgs: []string{"bash", "zsh"}, Run: run},
+	)
+
+	return root
+}
+
+func generate(t *testing.T, shell string) string {
+	buf := new(bytes.Buffer)
+	require.NoError(t, Generate(newTestCommand(), shell, buf))
+	return buf.String()
+}
+
+func TestGenerateUnsupportedShell(t *testing.T) {
+	assert.EqualError(t, Generate(newTestCommand(), "tcsh", new(bytes.Buffer)), `unsupported shell "tcsh" - supported shells are bash, zsh, and fish`)
+}
+
+func TestGenerateBash(t *testing.T) {
+	script := generate(t, "bash")
+
+	// names are listed with the ark CLI
+	assert.Contains(t, script, "__ark_get_names()\n{\n    local ark_out\n    if ark_out=$(ark \"$1\" get 2>/dev/null")
+	assert.Contains(t, script, "__ark_get_backups()\n{\n    __ark_get_names backup\n}\n")
+
+	// commands' arguments and flags are completed with them
+	assert.Contains(t, script, "        ark_backup_describe)\n            __ark_get_backups\n            return\n            ;;\n")
+	assert.Contains(t, script, `flags_completion+=("__ark_get_backups")`)
+	assert.Contains(t, script, `flags_completion+=("__ark_get_schedules")`)
+}
+
+func TestGenerateZsh(t *testing.T) {
+	script := generate(t, "zsh")
+
+	assert.True(t, strings.HasPrefix(script, "#compdef ark\n"))
+	assert.Contains(t, script, "__ark_get_backups()")
+	assert.True(t, strings.HasSuffix(script, "__ark_bash_source <(__ark_convert_bash_to_zsh)\n"))
+}
+
+func TestGenerateFish(t *testing.T) {
+	lines := strings.Split(generate(t, "fish"), "\n")
+
+	expected := []string{
+		// persistent flags apply to every command, so they're only listed once
+		"complete -c ark -l namespace -s n -r -d 'the namespace'",
+		"complete -c ark -n '__ark_using_command ' -f -a backup -d 'Work with backups'",
+		"complete -c ark -n '__ark_using_command ' -f -a completion -d 'Output shell completion code'",
+		"complete -c ark -n '__ark_using_command backup' -f -a create -d 'Create a backup'",
+		"complete -c ark -n '__ark_using_command backup' -f -a describe -d 'Describe backups'",
+		"complete -c ark -n '__ark_using_command backup create' -l from-schedule -r -d 'the schedule' -x -a '(__ark_get_names schedule)'",
+		"complete -c ark -n '__ark_using_command backup create' -l parent-backup -r -d 'the parent backup' -x -a '(__ark_get_names backup)'",
+		"complete -c ark -n '__ark_using_command backup create' -l storage-location -r -d 'the storage location' -x -a '(__ark_get_names backup-location)'",
+		"complete -c ark -n '__ark_using_command backup create' -l volume-snapshot-locations -r -d 'the snapshot locations' -x -a '(__ark_get_names snapshot-location)'",
+		"complete -c ark -n '__ark_using_command backup create' -l wait -d 'wait for the backup\\'s'",
+		"complete -c ark -n '__ark_using_command backup describe' -f -a '(__ark_get_names backup)'",
+		"complete -c ark -n '__ark_using_command completion' -f -a bash",
+		"complete -c ark -n '__ark_using_command completion' -f -a zsh",
+	}
+
+	var completions []string
+	for _, line := range lines {
+		if strings.HasPrefix(line, "complete ") {
+			completions = append(completions, line)
+		}
+	}
+	assert.Equal(t, expected, completions)
+}
+
+func TestFishQuote(t *testing.T) {
+	assert.Equal(t, `'plain'`, fishQuote("plain"))
+	assert.Equal(t, `'it\'s'`, fishQuote("it's"))
+	assert.Equal(t, `'back\\slash'`, fishQuote(`back\slash`))
+}
@@ -20,6 +20,8 @@ import (
 	"context"
 	"fmt"
 	"os"
+
+	"github.com/heptio/ark/pkg/logging"
 )
 
 // CheckError prints err to stderr and exits with code 1 if err is not nil. Otherwise, it is a
@@ -29,6 +31,7 @@ func CheckError(err error) {
 		if err != context.Canceled {
 			fmt.Fprintf(os.Stderr, fmt.Sprintf("An error occurred: %v\n", err))
 		}
+		logging.Flush()
 		os.Exit(1)
 	}
 }
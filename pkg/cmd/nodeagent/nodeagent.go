@@ -0,0 +1,167 @@
+/*
+Copyright 2017 Heptio Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nodeagent
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+
+	"github.com/golang/glog"
+	"github.com/spf13/cobra"
+
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/heptio/ark/pkg/buildinfo"
+	"github.com/heptio/ark/pkg/client"
+	"github.com/heptio/ark/pkg/cmd"
+	"github.com/heptio/ark/pkg/controller"
+	"github.com/heptio/ark/pkg/generated/clientset"
+	informers "github.com/heptio/ark/pkg/generated/informers/externalversions"
+	"github.com/heptio/ark/pkg/logging"
+	"github.com/heptio/ark/pkg/uploader"
+)
+
+// nodeNameEnvVar is the environment variable the node agent's DaemonSet sets to the name of the
+// node the agent is running on.
+const nodeNameEnvVar = "NODE_NAME"
+
+func NewCommand() *cobra.Command {
+	var (
+		kubeconfig string
+		node       = os.Getenv(nodeNameEnvVar)
+		workers    = 1
+		maxVolumes = 0
+		limits     uploader.Limits
+		logFormat  = logging.FormatText
+	)
+
+	var command = &cobra.Command{
+		Use:   "node-agent",
+		Short: "Run the ark node agent",
+		Long: `Run the ark node agent, which backs up and restores the data of the volumes of the
+pods on its node using restic, as requested by the server through PodVolumeBackups
+and PodVolumeRestores. It's run on every node by a DaemonSet, in a container that
+has restic and the kubelet's pods directory, /var/lib/kubelet/pods, mounted at
+/host_pods, with HostToContainer mount propagation so it sees the volumes the
+kubelet mounts.`,
+		Run: func(c *cobra.Command, args []string) {
+			cmd.CheckError(logging.SetFormat(logFormat))
+			if node == "" {
+				cmd.CheckError(errors.New("--node-name is required"))
+			}
+			if workers < 1 {
+				cmd.CheckError(errors.New("--workers must be at least 1"))
+			}
+			if maxVolumes < 0 || limits.UploadKiBps < 0 || limits.DownloadKiBps < 0 || limits.CPUs < 0 {
+				cmd.CheckError(errors.New("--max-concurrent-volumes, --upload-limit, --download-limit, and --uploader-cpus must not be negative"))
+			}
+
+			cmd.CheckError(run(kubeconfig, node, workers, maxVolumes, limits))
+		},
+	}
+
+	command.Flags().StringVar(&kubeconfig, "kubeconfig", "", "Path to the kubeconfig file to use to talk to the Kubernetes apiserver. If unset, try the environment variable KUBECONFIG, the default kubeconfig file, as well as in-cluster configuration")
+	command.Flags().StringVar(&node, "node-name", node, "The name of the node the agent is running on. Defaults to the "+nodeNameEnvVar+" environment variable")
+	command.Flags().IntVar(&workers, "workers", workers, "The number of pod volumes to back up, and to restore, at the same time")
+	command.Flags().IntVar(&maxVolumes, "max-concurrent-volumes", maxVolumes, "The number of pod volumes to back up and restore at the same time, counting both backups and restores. 0 means only --workers limits them")
+	command.Flags().IntVar(&limits.UploadKiBps, "upload-limit", limits.UploadKiBps, "The bandwidth, in KiB/s, each backup of a pod volume may use to write to object storage. 0 means no limit")
+	command.Flags().IntVar(&limits.DownloadKiBps, "download-limit", limits.DownloadKiBps, "The bandwidth, in KiB/s, each restore of a pod volume may use to read from object storage. 0 means no limit")
+	command.Flags().IntVar(&limits.CPUs, "uploader-cpus", limits.CPUs, "The number of CPUs restic or kopia may use at the same time for each pod volume. 0 means no limit")
+	command.Flags().StringVar(&logFormat, "log-format", logFormat, "The format of the agent's log: "+strings.Join(logging.Formats, " or "))
+
+	return command
+}
+
+// run runs the node agent's controllers until it receives SIGTERM or SIGINT. The controllers
+// move the data of at most maxVolumes pod volumes at the same time, if it isn't 0, each within
+// limits.
+func run(kubeconfig, node string, workers, maxVolumes int, limits uploader.Limits) error {
+	clientConfig, err := client.Config(kubeconfig, "")
+	if err != nil {
+		return err
+	}
+
+	kubeClient, err := kubernetes.NewForConfig(clientConfig)
+	if err != nil {
+		return err
+	}
+
+	arkClient, err := clientset.NewForConfig(clientConfig)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancelFunc := context.WithCancel(context.Background())
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGTERM, os.Interrupt)
+	go func() {
+		sig := <-signals
+		glog.Infof("Received %s, shutting down", sig)
+		cancelFunc()
+	}()
+
+	glog.Infof("Starting Ark node agent %s (%s) on node %s", buildinfo.Version, buildinfo.GitSHA, node)
+
+	sharedInformerFactory := informers.NewSharedInformerFactory(arkClient, 0)
+
+	var volumeSlots chan struct{}
+	if maxVolumes > 0 {
+		volumeSlots = make(chan struct{}, maxVolumes)
+	}
+
+	podVolumeBackupController := controller.NewPodVolumeBackupController(
+		arkClient.ArkV1(),
+		sharedInformerFactory.Ark().V1().PodVolumeBackups(),
+		kubeClient.CoreV1(),
+		kubeClient.CoreV1(),
+		node,
+		volumeSlots,
+		limits,
+	)
+	podVolumeRestoreController := controller.NewPodVolumeRestoreController(
+		arkClient.ArkV1(),
+		sharedInformerFactory.Ark().V1().PodVolumeRestores(),
+		kubeClient.CoreV1(),
+		kubeClient.CoreV1(),
+		node,
+		volumeSlots,
+		limits,
+	)
+
+	var wg sync.WaitGroup
+	for _, c := range []controller.Interface{podVolumeBackupController, podVolumeRestoreController} {
+		wg.Add(1)
+		go func(c controller.Interface) {
+			if err := c.Run(ctx, workers); err != nil {
+				glog.Errorf("error running controller: %v", err)
+			}
+			wg.Done()
+		}(c)
+	}
+
+	// shared informers have to be started after all controllers
+	go sharedInformerFactory.Start(ctx.Done())
+
+	wg.Wait()
+	glog.Infof("Ark node agent stopped")
+	return nil
+}
@@ -0,0 +1,317 @@
+/*
+Copyright 2017 Heptio Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"fmt"
+	"net/url"
+	"reflect"
+	"strings"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/validation"
+
+	api "github.com/heptio/ark/pkg/apis/ark/v1"
+	"github.com/heptio/ark/pkg/notification"
+)
+
+// configSetting is a setting of the Config whose status is reported in the Config's conditions.
+// check is called with the defaulted Config, and returns the setting's status and a message
+// describing its value, or an error if it's invalid.
+type configSetting struct {
+	name  string
+	check func(c *api.Config) (api.ConfigSettingStatus, string, error)
+}
+
+var configSettings = []configSetting{
+	{"defaultBackupStorageLocation", func(c *api.Config) (api.ConfigSettingStatus, string, error) {
+		return api.ConfigSettingStatusActive, c.DefaultBackupStorageLocation, nil
+	}},
+	{"defaultVolumeSnapshotLocations", func(c *api.Config) (api.ConfigSettingStatus, string, error) {
+		if len(c.DefaultVolumeSnapshotLocations) == 0 {
+			return api.ConfigSettingStatusActive, "Providers with only one location default to it", nil
+		}
+		defaults := sets.NewString()
+		for provider, location := range c.DefaultVolumeSnapshotLocations {
+			defaults.Insert(provider + "=" + location)
+		}
+		return api.ConfigSettingStatusActive, strings.Join(defaults.List(), ", "), nil
+	}},
+	{"backupSyncPeriod", durationSetting(func(c *api.Config) time.Duration { return c.BackupSyncPeriod.Duration })},
+	{"gcSyncPeriod", durationSetting(func(c *api.Config) time.Duration { return c.GCSyncPeriod.Duration })},
+	{"scheduleSyncPeriod", durationSetting(func(c *api.Config) time.Duration { return c.ScheduleSyncPeriod.Duration })},
+	{"storageLocationProbePeriod", durationSetting(func(c *api.Config) time.Duration { return c.StorageLocationProbePeriod.Duration })},
+	{"resourcePriorities", func(c *api.Config) (api.ConfigSettingStatus, string, error) {
+		return api.ConfigSettingStatusActive, strings.Join(c.ResourcePriorities, ", "), nil
+	}},
+	{"backupResourcePriorities", func(c *api.Config) (api.ConfigSettingStatus, string, error) {
+		if len(c.BackupResourcePriorities) == 0 {
+			return api.ConfigSettingStatusDisabled, "Resources are backed up in discovery order", nil
+		}
+		return api.ConfigSettingStatusActive, strings.Join(c.BackupResourcePriorities, ", "), nil
+	}},
+	{"backupItemTransforms", func(c *api.Config) (api.ConfigSettingStatus, string, error) {
+		if len(c.BackupItemTransforms) == 0 {
+			return api.ConfigSettingStatusDisabled, "", nil
+		}
+		for i, transform := range c.BackupItemTransforms {
+			if len(transform.Resources) == 0 {
+				return "", "", fmt.Errorf("transform #%d: resources is required", i)
+			}
+			if transform.LabelSelector != nil {
+				if _, err := metav1.LabelSelectorAsSelector(transform.LabelSelector); err != nil {
+					return "", "", fmt.Errorf("transform #%d: invalid labelSelector: %v", i, err)
+				}
+			}
+		}
+		return api.ConfigSettingStatusActive, fmt.Sprintf("%d transform(s)", len(c.BackupItemTransforms)), nil
+	}},
+	{"resourceCollectionWorkers", func(c *api.Config) (api.ConfigSettingStatus, string, error) {
+		if c.ResourceCollectionWorkers < 1 {
+			return "", "", fmt.Errorf("must be at least 1")
+		}
+		return api.ConfigSettingStatusActive, fmt.Sprintf("%d", c.ResourceCollectionWorkers), nil
+	}},
+	{"resourceListPageSize", func(c *api.Config) (api.ConfigSettingStatus, string, error) {
+		if c.ResourceListPageSize < 1 {
+			return "", "", fmt.Errorf("must be at least 1")
+		}
+		return api.ConfigSettingStatusActive, fmt.Sprintf("%d", c.ResourceListPageSize), nil
+	}},
+	{"defaultExcludedResources", func(c *api.Config) (api.ConfigSettingStatus, string, error) {
+		if len(c.DefaultExcludedResources) == 0 {
+			return api.ConfigSettingStatusDisabled, "", nil
+		}
+		for _, resource := range c.DefaultExcludedResources {
+			if resource == "*" {
+				return "", "", fmt.Errorf("can't exclude all resources")
+			}
+		}
+		return api.ConfigSettingStatusActive, strings.Join(c.DefaultExcludedResources, ", "), nil
+	}},
+	{"excludeCompletedPods", func(c *api.Config) (api.ConfigSettingStatus, string, error) {
+		if !c.ExcludeCompletedPods {
+			return api.ConfigSettingStatusDisabled, "", nil
+		}
+		return api.ConfigSettingStatusActive, "Pods that have succeeded or failed aren't backed up", nil
+	}},
+	{"restoreOnlyMode", func(c *api.Config) (api.ConfigSettingStatus, string, error) {
+		if !c.RestoreOnlyMode {
+			return api.ConfigSettingStatusDisabled, "", nil
+		}
+		return api.ConfigSettingStatusActive, "Backups, schedules, and garbage collection are disabled", nil
+	}},
+	{"tenantMode", func(c *api.Config) (api.ConfigSettingStatus, string, error) {
+		if !c.TenantMode {
+			return api.ConfigSettingStatusDisabled, "", nil
+		}
+		return api.ConfigSettingStatusActive, fmt.Sprintf("Backups and restores outside the %s namespace are constrained to their own namespace", api.DefaultNamespace), nil
+	}},
+	{"tenantQuota", func(c *api.Config) (api.ConfigSettingStatus, string, error) {
+		if c.TenantQuota == nil {
+			return api.ConfigSettingStatusDisabled, "", nil
+		}
+		if !c.TenantMode {
+			return "", "", fmt.Errorf("tenantMode must be true")
+		}
+		if c.TenantQuota.MaxConcurrentBackups < 0 || c.TenantQuota.MaxBackups < 0 || c.TenantQuota.MaxStoredBytes.Sign() < 0 {
+			return "", "", fmt.Errorf("limits must not be negative")
+		}
+		return api.ConfigSettingStatusActive, fmt.Sprintf("maxConcurrentBackups %d, maxBackups %d, maxStoredBytes %s (0 means no limit)",
+			c.TenantQuota.MaxConcurrentBackups, c.TenantQuota.MaxBackups, c.TenantQuota.MaxStoredBytes.String()), nil
+	}},
+	{"restic", func(c *api.Config) (api.ConfigSettingStatus, string, error) {
+		if c.Restic == nil {
+			return api.ConfigSettingStatusDisabled, "", nil
+		}
+		if c.Restic.Timeout.Duration < 0 {
+			return "", "", fmt.Errorf("timeout must not be negative")
+		}
+		if c.Restic.MaintenanceFrequency.Duration < 0 {
+			return "", "", fmt.Errorf("maintenanceFrequency must not be negative")
+		}
+		if c.Restic.UploadLimit < 0 || c.Restic.DownloadLimit < 0 {
+			return "", "", fmt.Errorf("uploadLimit and downloadLimit must not be negative")
+		}
+		limits := fmt.Sprintf("helper pod bandwidth %d KiB/s up, %d KiB/s down (0 means no limit)", c.Restic.UploadLimit, c.Restic.DownloadLimit)
+		if c.Restic.NodeAgent {
+			return api.ConfigSettingStatusActive, fmt.Sprintf("Node agents, timeout %s, maintenance every %s, %s", c.Restic.Timeout.Duration, c.Restic.MaintenanceFrequency.Duration, limits), nil
+		}
+		return api.ConfigSettingStatusActive, fmt.Sprintf("Image %s, timeout %s, maintenance every %s, %s", c.Restic.Image, c.Restic.Timeout.Duration, c.Restic.MaintenanceFrequency.Duration, limits), nil
+	}},
+	{"volumeFreeze", func(c *api.Config) (api.ConfigSettingStatus, string, error) {
+		if c.VolumeFreeze == nil {
+			return api.ConfigSettingStatusDisabled, "", nil
+		}
+		if c.VolumeFreeze.MaxFreezeDuration.Duration < 0 {
+			return "", "", fmt.Errorf("maxFreezeDuration must not be negative")
+		}
+		return api.ConfigSettingStatusActive, fmt.Sprintf("Image %s, maxFreezeDuration %s", c.VolumeFreeze.Image, c.VolumeFreeze.MaxFreezeDuration.Duration), nil
+	}},
+	{"csiSnapshots", func(c *api.Config) (api.ConfigSettingStatus, string, error) {
+		if c.CSISnapshots == nil {
+			return api.ConfigSettingStatusDisabled, "", nil
+		}
+		if c.CSISnapshots.Timeout.Duration < 0 {
+			return "", "", fmt.Errorf("timeout must not be negative")
+		}
+		class := c.CSISnapshots.VolumeSnapshotClassName
+		if class == "" {
+			class = "the default VolumeSnapshotClass"
+		}
+		return api.ConfigSettingStatusActive, fmt.Sprintf("Using %s, timeout %s", class, c.CSISnapshots.Timeout.Duration), nil
+	}},
+	{"clusterName", labelValueSetting(func(c *api.Config) string { return c.ClusterName })},
+	{"clusterUID", func(c *api.Config) (api.ConfigSettingStatus, string, error) {
+		if c.ClusterUID == "" {
+			return api.ConfigSettingStatusActive, "Defaults to the UID of the kube-system namespace", nil
+		}
+		return labelValueSetting(func(c *api.Config) string { return c.ClusterUID })(c)
+	}},
+	{"immutableBackups", func(c *api.Config) (api.ConfigSettingStatus, string, error) {
+		if !c.ImmutableBackups {
+			return api.ConfigSettingStatusDisabled, "", nil
+		}
+		if c.AdmissionWebhook == nil {
+			return api.ConfigSettingStatusActive, "The admission webhook isn't configured, so backups' API objects can still be modified", nil
+		}
+		return api.ConfigSettingStatusActive, "", nil
+	}},
+	{"admissionWebhook", func(c *api.Config) (api.ConfigSettingStatus, string, error) {
+		if c.AdmissionWebhook == nil {
+			return api.ConfigSettingStatusDisabled, "", nil
+		}
+		if c.AdmissionWebhook.Port < 1 || c.AdmissionWebhook.Port > 65535 {
+			return "", "", fmt.Errorf("port %d is out of range", c.AdmissionWebhook.Port)
+		}
+		if c.AdmissionWebhook.CertFile == "" || c.AdmissionWebhook.KeyFile == "" {
+			return "", "", fmt.Errorf("certFile and keyFile are required")
+		}
+		return api.ConfigSettingStatusActive, fmt.Sprintf("Port %d", c.AdmissionWebhook.Port), nil
+	}},
+	{"notifications", func(c *api.Config) (api.ConfigSettingStatus, string, error) {
+		if c.Notifications == nil {
+			return api.ConfigSettingStatusDisabled, "", nil
+		}
+		if _, err := notification.NewNotifier(c.Notifications, nil); err != nil {
+			return "", "", err
+		}
+		return api.ConfigSettingStatusActive, fmt.Sprintf("%d webhook(s)", len(c.Notifications.Webhooks)), nil
+	}},
+	{"audit", func(c *api.Config) (api.ConfigSettingStatus, string, error) {
+		if c.Audit == nil {
+			return api.ConfigSettingStatusDisabled, "", nil
+		}
+		if c.Audit.FlushInterval.Duration < 0 {
+			return "", "", fmt.Errorf("flushInterval must not be negative")
+		}
+		return api.ConfigSettingStatusActive, fmt.Sprintf("Flushed every %s", c.Audit.FlushInterval.Duration), nil
+	}},
+	{"tracing", func(c *api.Config) (api.ConfigSettingStatus, string, error) {
+		if c.Tracing == nil {
+			return api.ConfigSettingStatusDisabled, "", nil
+		}
+		if u, err := url.Parse(c.Tracing.Endpoint); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return "", "", fmt.Errorf("endpoint must be an http or https URL")
+		}
+		return api.ConfigSettingStatusActive, fmt.Sprintf("Exported to %s as %s", c.Tracing.Endpoint, c.Tracing.ServiceName), nil
+	}},
+	{"logLevel", func(c *api.Config) (api.ConfigSettingStatus, string, error) {
+		if c.LogLevel == nil {
+			return api.ConfigSettingStatusActive, "Set by the -v flag", nil
+		}
+		if *c.LogLevel < 0 {
+			return "", "", fmt.Errorf("must not be negative")
+		}
+		return api.ConfigSettingStatusActive, fmt.Sprintf("%d", *c.LogLevel), nil
+	}},
+}
+
+func durationSetting(get func(c *api.Config) time.Duration) func(c *api.Config) (api.ConfigSettingStatus, string, error) {
+	return func(c *api.Config) (api.ConfigSettingStatus, string, error) {
+		if get(c) < 0 {
+			return "", "", fmt.Errorf("must not be negative")
+		}
+		return api.ConfigSettingStatusActive, get(c).String(), nil
+	}
+}
+
+func labelValueSetting(get func(c *api.Config) string) func(c *api.Config) (api.ConfigSettingStatus, string, error) {
+	return func(c *api.Config) (api.ConfigSettingStatus, string, error) {
+		value := get(c)
+		if value == "" {
+			return api.ConfigSettingStatusDisabled, "", nil
+		}
+		if errs := validation.IsValidLabelValue(value); len(errs) > 0 {
+			return "", "", fmt.Errorf("%q must be a valid label value: %s", value, strings.Join(errs, "; "))
+		}
+		return api.ConfigSettingStatusActive, value, nil
+	}
+}
+
+// validateConfig returns the conditions of config's settings, which must have had their defaults
+// applied, and an error listing the invalid ones if there are any. Conditions whose status hasn't
+// changed since previous keep their LastTransitionTime.
+func validateConfig(config *api.Config, previous []api.ConfigCondition, now time.Time) ([]api.ConfigCondition, error) {
+	transitionTimes := make(map[string]metav1.Time)
+	for _, condition := range previous {
+		transitionTimes[condition.Setting+"/"+string(condition.Status)] = condition.LastTransitionTime
+	}
+
+	var (
+		conditions []api.ConfigCondition
+		errs       []string
+	)
+	for _, setting := range configSettings {
+		status, message, err := setting.check(config)
+		if err != nil {
+			status, message = api.ConfigSettingStatusInvalid, err.Error()
+			errs = append(errs, fmt.Sprintf("%s: %v", setting.name, err))
+		}
+
+		transitionTime, ok := transitionTimes[setting.name+"/"+string(status)]
+		if !ok {
+			transitionTime = metav1.NewTime(now)
+		}
+
+		conditions = append(conditions, api.ConfigCondition{
+			Setting:            setting.name,
+			Status:             status,
+			Message:            message,
+			LastTransitionTime: transitionTime,
+		})
+	}
+
+	if len(errs) > 0 {
+		return conditions, fmt.Errorf("invalid Ark configuration: %s", strings.Join(errs, "; "))
+	}
+	return conditions, nil
+}
+
+// configSettingsEqual returns whether a and b have the same settings, ignoring their type and
+// object metadata and their status, and their logLevel, which is applied without restarting.
+func configSettingsEqual(a, b *api.Config) bool {
+	aSettings, bSettings := *a, *b
+	aSettings.TypeMeta, bSettings.TypeMeta = metav1.TypeMeta{}, metav1.TypeMeta{}
+	aSettings.ObjectMeta, bSettings.ObjectMeta = metav1.ObjectMeta{}, metav1.ObjectMeta{}
+	aSettings.Status, bSettings.Status = api.ConfigStatus{}, api.ConfigStatus{}
+	aSettings.LogLevel, bSettings.LogLevel = nil, nil
+
+	return reflect.DeepEqual(aSettings, bSettings)
+}
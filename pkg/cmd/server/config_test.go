@@ -0,0 +1,134 @@
+/*
+Copyright 2017 Heptio Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/heptio/ark/pkg/apis/ark/v1"
+)
+
+func conditionFor(conditions []v1.ConfigCondition, setting string) *v1.ConfigCondition {
+	for i := range conditions {
+		if conditions[i].Setting == setting {
+			return &conditions[i]
+		}
+	}
+	return nil
+}
+
+func TestValidateConfig(t *testing.T) {
+	now := time.Date(2018, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	t.Run("defaults are valid", func(t *testing.T) {
+		c := &v1.Config{Restic: &v1.ResticConfig{}}
+		applyConfigDefaults(c)
+
+		conditions, err := validateConfig(c, nil, now)
+		require.NoError(t, err)
+		require.Len(t, conditions, len(configSettings))
+
+		for _, condition := range conditions {
+			assert.NotEqual(t, v1.ConfigSettingStatusInvalid, condition.Status, condition.Setting)
+			assert.Equal(t, metav1.NewTime(now), condition.LastTransitionTime, condition.Setting)
+		}
+
+		assert.Equal(t, v1.ConfigCondition{
+			Setting:            "gcSyncPeriod",
+			Status:             v1.ConfigSettingStatusActive,
+			Message:            defaultGCSyncPeriod.String(),
+			LastTransitionTime: metav1.NewTime(now),
+		}, *conditionFor(conditions, "gcSyncPeriod"))
+		assert.Equal(t, v1.ConfigSettingStatusActive, conditionFor(conditions, "restic").Status)
+		assert.Equal(t, v1.ConfigSettingStatusDisabled, conditionFor(conditions, "volumeFreeze").Status)
+	})
+
+	t.Run("invalid settings are reported", func(t *testing.T) {
+		c := &v1.Config{
+			DefaultExcludedResources: []string{"events", "*"},
+			ClusterName:              "not a label value",
+			AdmissionWebhook:         &v1.AdmissionWebhookConfig{CertFile: "tls.crt"},
+			Notifications: &v1.NotificationsConfig{
+				Webhooks: []v1.NotificationWebhook{{Name: "slack", URL: "https://hooks.example.com", Format: "xml"}},
+			},
+			Tracing: &v1.TracingConfig{Endpoint: "otel-collector:4318"},
+		}
+		c.GCSyncPeriod.Duration = -time.Minute
+		logLevel := int32(-1)
+		c.LogLevel = &logLevel
+		applyConfigDefaults(c)
+
+		conditions, err := validateConfig(c, nil, now)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "gcSyncPeriod: must not be negative")
+
+		var invalid []string
+		for _, condition := range conditions {
+			if condition.Status == v1.ConfigSettingStatusInvalid {
+				invalid = append(invalid, condition.Setting)
+			}
+		}
+		assert.Equal(t, []string{"gcSyncPeriod", "defaultExcludedResources", "clusterName", "admissionWebhook", "notifications", "tracing", "logLevel"}, invalid)
+		assert.Equal(t, "certFile and keyFile are required", conditionFor(conditions, "admissionWebhook").Message)
+	})
+
+	t.Run("transition times are kept for unchanged statuses", func(t *testing.T) {
+		earlier := metav1.NewTime(now.Add(-time.Hour))
+		previous := []v1.ConfigCondition{
+			{Setting: "gcSyncPeriod", Status: v1.ConfigSettingStatusActive, Message: "1h0m0s", LastTransitionTime: earlier},
+			{Setting: "restic", Status: v1.ConfigSettingStatusActive, LastTransitionTime: earlier},
+		}
+
+		c := &v1.Config{}
+		c.GCSyncPeriod.Duration = 2 * time.Hour
+		applyConfigDefaults(c)
+
+		conditions, err := validateConfig(c, previous, now)
+		require.NoError(t, err)
+		assert.Equal(t, earlier, conditionFor(conditions, "gcSyncPeriod").LastTransitionTime)
+		assert.Equal(t, "2h0m0s", conditionFor(conditions, "gcSyncPeriod").Message)
+		assert.Equal(t, metav1.NewTime(now), conditionFor(conditions, "restic").LastTransitionTime)
+	})
+}
+
+func TestConfigSettingsEqual(t *testing.T) {
+	a := &v1.Config{
+		ObjectMeta:         metav1.ObjectMeta{Name: "default", ResourceVersion: "1"},
+		ResourcePriorities: []string{"namespaces"},
+	}
+	b := &v1.Config{
+		TypeMeta:           metav1.TypeMeta{Kind: "Config"},
+		ObjectMeta:         metav1.ObjectMeta{Name: "default", ResourceVersion: "2"},
+		ResourcePriorities: []string{"namespaces"},
+		Status: v1.ConfigStatus{
+			Conditions: []v1.ConfigCondition{{Setting: "restic", Status: v1.ConfigSettingStatusDisabled}},
+		},
+	}
+	assert.True(t, configSettingsEqual(a, b))
+
+	logLevel := int32(4)
+	b.LogLevel = &logLevel
+	assert.True(t, configSettingsEqual(a, b))
+
+	b.RestoreOnlyMode = true
+	assert.False(t, configSettingsEqual(a, b))
+}
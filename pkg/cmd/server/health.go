@@ -0,0 +1,150 @@
+/*
+Copyright 2017 Heptio Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/clock"
+
+	api "github.com/heptio/ark/pkg/apis/ark/v1"
+	arkv1client "github.com/heptio/ark/pkg/generated/clientset/typed/ark/v1"
+	"github.com/heptio/ark/pkg/health"
+)
+
+// informerSyncTimeout is how long the shared informers' caches can take to sync once they've
+// been started before the server is considered wedged.
+const informerSyncTimeout = 5 * time.Minute
+
+// controllerStatus is the state of the server's controllers, as reported by its health probes.
+type controllerStatus struct {
+	clock clock.Clock
+	// electing is whether the server runs its controllers only while it's the elected leader.
+	electing bool
+
+	lock    sync.Mutex
+	leading bool
+	// informersStarted is when the shared informers were started, or zero if they haven't been.
+	informersStarted time.Time
+	informersSynced  bool
+}
+
+func newControllerStatus(electing bool) *controllerStatus {
+	return &controllerStatus{
+		clock:    clock.RealClock{},
+		electing: electing,
+	}
+}
+
+// setLeading records whether the server is the elected leader.
+func (s *controllerStatus) setLeading(leading bool) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.leading = leading
+}
+
+// startInformers records that the shared informers were started.
+func (s *controllerStatus) startInformers() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.informersStarted = s.clock.Now()
+	s.informersSynced = false
+}
+
+// syncInformers records that the shared informers' caches have synced.
+func (s *controllerStatus) syncInformers() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.informersSynced = true
+}
+
+// checkInformersLive fails if the shared informers' caches haven't synced within
+// informerSyncTimeout of their being started.
+func (s *controllerStatus) checkInformersLive() error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.informersStarted.IsZero() || s.informersSynced {
+		return nil
+	}
+	if waited := s.clock.Since(s.informersStarted); waited > informerSyncTimeout {
+		return fmt.Errorf("caches haven't synced after %s", waited.Round(time.Second))
+	}
+	return nil
+}
+
+// checkInformersReady fails if the shared informers haven't been started, or their caches
+// haven't synced.
+func (s *controllerStatus) checkInformersReady() error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.informersStarted.IsZero() {
+		return errors.New("controllers aren't running")
+	}
+	if !s.informersSynced {
+		return errors.New("caches haven't synced")
+	}
+	return nil
+}
+
+// checkLeader fails if the server runs its controllers only while it's the leader, and it
+// isn't.
+func (s *controllerStatus) checkLeader() error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.electing && !s.leading {
+		return errors.New("not the leader")
+	}
+	return nil
+}
+
+// storageLocationCheck returns a check that fails if the backup storage location name, in the
+// server's namespace, was unavailable when it was last probed.
+func storageLocationCheck(client arkv1client.BackupStorageLocationsGetter, name string) func() error {
+	return func() error {
+		location, err := client.BackupStorageLocations(api.DefaultNamespace).Get(name, metav1.GetOptions{})
+		if err != nil {
+			return fmt.Errorf("error getting backup storage location %s: %v", name, err)
+		}
+		if location.Status.Phase == api.BackupStorageLocationPhaseUnavailable {
+			return fmt.Errorf("backup storage location %s is unavailable: %s", name, location.Status.Message)
+		}
+		return nil
+	}
+}
+
+// healthChecks returns the checks of the server's liveness and readiness probes.
+func (s *server) healthChecks() (liveness, readiness []health.Check) {
+	liveness = []health.Check{
+		{Name: "informers", Func: s.controllerStatus.checkInformersLive},
+	}
+	readiness = []health.Check{
+		{Name: "leader", Func: s.controllerStatus.checkLeader},
+		{Name: "informers", Func: s.controllerStatus.checkInformersReady},
+		{Name: "storage-location", Func: storageLocationCheck(s.arkClient.ArkV1(), s.defaultStorageLocation.Name)},
+	}
+	return liveness, readiness
+}
@@ -0,0 +1,114 @@
+/*
+Copyright 2017 Heptio Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/clock"
+
+	"github.com/heptio/ark/pkg/apis/ark/v1"
+	"github.com/heptio/ark/pkg/generated/clientset/fake"
+)
+
+func TestControllerStatusInformers(t *testing.T) {
+	clock := clock.NewFakeClock(time.Now())
+	status := newControllerStatus(false)
+	status.clock = clock
+
+	assert.NoError(t, status.checkInformersLive())
+	assert.EqualError(t, status.checkInformersReady(), "controllers aren't running")
+
+	status.startInformers()
+	assert.NoError(t, status.checkInformersLive())
+	assert.EqualError(t, status.checkInformersReady(), "caches haven't synced")
+
+	clock.Step(informerSyncTimeout + time.Second)
+	assert.EqualError(t, status.checkInformersLive(), "caches haven't synced after 5m1s")
+
+	status.syncInformers()
+	assert.NoError(t, status.checkInformersLive())
+	assert.NoError(t, status.checkInformersReady())
+}
+
+func TestControllerStatusLeader(t *testing.T) {
+	assert.NoError(t, newControllerStatus(false).checkLeader())
+
+	status := newControllerStatus(true)
+	assert.EqualError(t, status.checkLeader(), "not the leader")
+
+	status.setLeading(true)
+	assert.NoError(t, status.checkLeader())
+
+	status.setLeading(false)
+	assert.EqualError(t, status.checkLeader(), "not the leader")
+}
+
+func TestStorageLocationCheck(t *testing.T) {
+	location := func(phase v1.BackupStorageLocationPhase) *v1.BackupStorageLocation {
+		return &v1.BackupStorageLocation{
+			ObjectMeta: metav1.ObjectMeta{Namespace: v1.DefaultNamespace, Name: "default"},
+			Status: v1.BackupStorageLocationStatus{
+				Phase:   phase,
+				Message: "access denied",
+			},
+		}
+	}
+
+	tests := []struct {
+		name          string
+		location      *v1.BackupStorageLocation
+		expectedError string
+	}{
+		{
+			name:          "missing location",
+			expectedError: `error getting backup storage location default: backupstoragelocations.ark.heptio.com "default" not found`,
+		},
+		{
+			name:     "unprobed location",
+			location: location(""),
+		},
+		{
+			name:     "available location",
+			location: location(v1.BackupStorageLocationPhaseAvailable),
+		},
+		{
+			name:          "unavailable location",
+			location:      location(v1.BackupStorageLocationPhaseUnavailable),
+			expectedError: "backup storage location default is unavailable: access denied",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			client := fake.NewSimpleClientset()
+			if test.location != nil {
+				client = fake.NewSimpleClientset(test.location)
+			}
+
+			err := storageLocationCheck(client.ArkV1(), "default")()
+			if test.expectedError == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, test.expectedError)
+			}
+		})
+	}
+}
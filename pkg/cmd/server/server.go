@@ -19,15 +19,24 @@ package server
 import (
 	"context"
 	"fmt"
+	"os"
+	"os/signal"
 	"reflect"
+	"sort"
+	"strings"
 	"sync"
+	"syscall"
 	"time"
 
 	"github.com/golang/glog"
+	uuid "github.com/satori/go.uuid"
 	"github.com/spf13/cobra"
 
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation"
 	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/client-go/discovery"
 	"k8s.io/client-go/dynamic"
@@ -36,39 +45,97 @@ import (
 	"k8s.io/client-go/tools/cache"
 
 	api "github.com/heptio/ark/pkg/apis/ark/v1"
+	"github.com/heptio/ark/pkg/audit"
 	"github.com/heptio/ark/pkg/backup"
+	"github.com/heptio/ark/pkg/buildinfo"
 	"github.com/heptio/ark/pkg/client"
 	"github.com/heptio/ark/pkg/cloudprovider"
-	arkaws "github.com/heptio/ark/pkg/cloudprovider/aws"
-	"github.com/heptio/ark/pkg/cloudprovider/azure"
-	"github.com/heptio/ark/pkg/cloudprovider/gcp"
+	"github.com/heptio/ark/pkg/cloudprovider/providers"
 	"github.com/heptio/ark/pkg/cmd"
 	"github.com/heptio/ark/pkg/controller"
+	"github.com/heptio/ark/pkg/csi"
 	arkdiscovery "github.com/heptio/ark/pkg/discovery"
+	"github.com/heptio/ark/pkg/event"
 	"github.com/heptio/ark/pkg/generated/clientset"
+	"github.com/heptio/ark/pkg/generated/clientset/scheme"
 	arkv1client "github.com/heptio/ark/pkg/generated/clientset/typed/ark/v1"
 	informers "github.com/heptio/ark/pkg/generated/informers/externalversions"
+	"github.com/heptio/ark/pkg/health"
+	"github.com/heptio/ark/pkg/leaderelection"
+	"github.com/heptio/ark/pkg/logging"
+	"github.com/heptio/ark/pkg/metrics"
+	"github.com/heptio/ark/pkg/notification"
+	"github.com/heptio/ark/pkg/plugin"
+	"github.com/heptio/ark/pkg/podexec"
+	"github.com/heptio/ark/pkg/quiesce"
+	"github.com/heptio/ark/pkg/restic"
 	"github.com/heptio/ark/pkg/restore"
 	"github.com/heptio/ark/pkg/restore/restorers"
+	"github.com/heptio/ark/pkg/tracing"
+	"github.com/heptio/ark/pkg/uploader"
 	"github.com/heptio/ark/pkg/util/kube"
+	"github.com/heptio/ark/pkg/webhook"
 )
 
 func NewCommand() *cobra.Command {
-	var kubeconfig string
+	var (
+		kubeconfig     string
+		controllerOpts = defaultControllerOptions()
+		metricsAddress = metrics.DefaultAddress
+		healthAddress  = health.DefaultAddress
+		pluginDir      = plugin.DefaultDir
+		gracePeriod    = controller.DefaultTerminationGracePeriod
+		leaderElect    bool
+		logFormat      = logging.FormatText
+		leaderElection = leaderelection.Config{
+			Namespace:     api.DefaultNamespace,
+			Name:          leaderelection.DefaultLockName,
+			LeaseDuration: leaderelection.DefaultLeaseDuration,
+			RenewDeadline: leaderelection.DefaultRenewDeadline,
+			RetryPeriod:   leaderelection.DefaultRetryPeriod,
+		}
+	)
 
 	var command = &cobra.Command{
 		Use:   "server",
 		Short: "Run the ark server",
 		Long:  "Run the ark server",
 		Run: func(c *cobra.Command, args []string) {
-			s, err := newServer(kubeconfig)
+			cmd.CheckError(logging.SetFormat(logFormat))
+			cmd.CheckError(controllerOpts.validate())
+
+			var electionConfig *leaderelection.Config
+			if leaderElect {
+				hostname, err := os.Hostname()
+				cmd.CheckError(err)
+				// the hostname is the pod's name, but a replacement pod can have the same name
+				leaderElection.Identity = hostname + "_" + uuid.NewV4().String()
+				electionConfig = &leaderElection
+			}
+
+			s, err := newServer(kubeconfig, controllerOpts, metricsAddress, healthAddress, pluginDir, gracePeriod, electionConfig)
 			cmd.CheckError(err)
 
 			cmd.CheckError(s.run())
 		},
 	}
 
-	command.Flags().StringVar(&kubeconfig, "kubeconfig", "", "Path to the kubeconfig file to use to talk to the Kubernetes apiserver. If unset, try the environment variable KUBECONFIG, as well as in-cluster configuration")
+	command.Flags().StringVar(&kubeconfig, "kubeconfig", "", "Path to the kubeconfig file to use to talk to the Kubernetes apiserver. If unset, try the environment variable KUBECONFIG, the default kubeconfig file, as well as in-cluster configuration")
+	command.Flags().StringVar(&metricsAddress, "metrics-address", metricsAddress, "The address to serve Prometheus metrics on, at "+metrics.Path+". If empty, metrics aren't served")
+	command.Flags().StringVar(&healthAddress, "health-address", healthAddress, "The address to serve the liveness and readiness probes on, at "+health.LivenessPath+" and "+health.ReadinessPath+". If empty, they aren't served")
+	command.Flags().StringVar(&logFormat, "log-format", logFormat, "The format of the server's log: "+strings.Join(logging.Formats, " or ")+". With json, each line is a JSON object including fields that identify the backup or restore it's about; it requires --logtostderr")
+	command.Flags().StringVar(&pluginDir, "plugin-dir", pluginDir, "The directory to run cloud provider plugins from. A plugin named NAME is the binary "+plugin.BinaryPrefix+"NAME")
+	command.Flags().IntVar(&controllerOpts.backupWorkers, "max-concurrent-backups", controllerOpts.backupWorkers, "The maximum number of backups to run at the same time. Additional backups wait in the New phase until a running backup finishes")
+	command.Flags().IntVar(&controllerOpts.restoreWorkers, "max-concurrent-restores", controllerOpts.restoreWorkers, "The maximum number of restores to run at the same time. Additional restores wait in the New phase until a running restore finishes")
+	command.Flags().IntVar(&controllerOpts.scheduleWorkers, "schedule-workers", controllerOpts.scheduleWorkers, "The number of schedules to check for due backups at the same time")
+	command.Flags().IntVar(&controllerOpts.gcWorkers, "gc-workers", controllerOpts.gcWorkers, "The number of expired backups to delete at the same time when garbage-collecting")
+	command.Flags().IntVar(&controllerOpts.backupDeletionWorkers, "backup-deletion-workers", controllerOpts.backupDeletionWorkers, "The number of backup deletion requests to process at the same time")
+	command.Flags().DurationVar(&controllerOpts.informerResyncPeriod, "informer-resync-period", controllerOpts.informerResyncPeriod, "How often the controllers reprocess every Ark API object they watch, in addition to processing changes as they happen. 0 disables resyncing")
+	command.Flags().DurationVar(&gracePeriod, "termination-grace-period", gracePeriod, "How long the server has to stop, once it receives SIGTERM, before it's killed. Set it to the pod's terminationGracePeriodSeconds. The server waits half of it for running backups and restores to finish, and then interrupts the backups still running, which are run again later; backups that are being uploaded are given another quarter of it")
+	command.Flags().BoolVar(&leaderElect, "leader-elect", leaderElect, "Elect a leader among the server's replicas, so that only one of them runs the controllers at a time. Required when running more than one replica")
+	command.Flags().DurationVar(&leaderElection.LeaseDuration, "leader-elect-lease-duration", leaderElection.LeaseDuration, "How long a replica waits, after the leader stops renewing its lease, before taking over")
+	command.Flags().DurationVar(&leaderElection.RenewDeadline, "leader-elect-renew-deadline", leaderElection.RenewDeadline, "How long the leader keeps retrying to renew its lease before stopping. Must be less than the lease duration")
+	command.Flags().DurationVar(&leaderElection.RetryPeriod, "leader-elect-retry-period", leaderElection.RetryPeriod, "How often replicas try to acquire the lease, and the leader renews it. Must be less than the renew deadline")
 
 	return command
 }
@@ -83,10 +150,81 @@ type server struct {
 	sharedInformerFactory informers.SharedInformerFactory
 	ctx                   context.Context
 	cancelFunc            context.CancelFunc
+	controllerOptions     controllerOptions
+	podCommandExecutor    podexec.Executor
+	metrics               *metrics.ServerMetrics
+	metricsAddress        string
+	healthAddress         string
+	controllerStatus      *controllerStatus
+	leaderElection        *leaderelection.Config
+	plugins               *plugin.Manager
+	gracePeriod           time.Duration
+
+	// leaderLease is the lease held while the controllers run as the elected leader, or nil if
+	// the server doesn't run with leader election.
+	leaderLease audit.Lease
+
+	// storageLocations are the server's backup storage locations, by name, and
+	// defaultStorageLocation is the one backups are stored in by default.
+	storageLocations       map[string]*api.BackupStorageLocation
+	defaultStorageLocation *api.BackupStorageLocation
+
+	// objectStorage is the object storage of each backup storage location, by
+	// name.
+	objectStorage map[string]cloudprovider.ObjectStorageAdapter
+
+	// snapshotLocations are the server's volume snapshot locations, by name.
+	snapshotLocations map[string]*api.VolumeSnapshotLocation
+}
+
+// controllerOptions are the number of workers of the controllers whose work can be done
+// concurrently, and how often the shared informers resync.
+type controllerOptions struct {
+	backupWorkers         int
+	restoreWorkers        int
+	scheduleWorkers       int
+	gcWorkers             int
+	backupDeletionWorkers int
+	informerResyncPeriod  time.Duration
+}
+
+func defaultControllerOptions() controllerOptions {
+	return controllerOptions{
+		backupWorkers:         1,
+		restoreWorkers:        1,
+		scheduleWorkers:       1,
+		gcWorkers:             1,
+		backupDeletionWorkers: 1,
+	}
+}
+
+// validate returns an error naming the first flag that has an invalid value.
+func (o controllerOptions) validate() error {
+	workers := []struct {
+		flag  string
+		value int
+	}{
+		{"--max-concurrent-backups", o.backupWorkers},
+		{"--max-concurrent-restores", o.restoreWorkers},
+		{"--schedule-workers", o.scheduleWorkers},
+		{"--gc-workers", o.gcWorkers},
+		{"--backup-deletion-workers", o.backupDeletionWorkers},
+	}
+	for _, w := range workers {
+		if w.value < 1 {
+			return fmt.Errorf("%s must be at least 1", w.flag)
+		}
+	}
+
+	if o.informerResyncPeriod < 0 {
+		return fmt.Errorf("--informer-resync-period must not be negative")
+	}
+
+	return nil
 }
 
-func newServer(kubeconfig string) (*server, error) {
-	clientConfig, err := client.Config(kubeconfig)
+func newServer(kubeconfig string, controllerOptions controllerOptions, metricsAddress, healthAddress, pluginDir string, gracePeriod time.Duration, leaderElection *leaderelection.Config) (*server, error) {
+	clientConfig, err := client.Config(kubeconfig, "")
 	if err != nil {
 		return nil, err
 	}
@@ -101,6 +239,11 @@ func newServer(kubeconfig string) (*server, error) {
 		return nil, err
 	}
 
+	podCommandExecutor, err := podexec.NewExecutor(clientConfig)
+	if err != nil {
+		return nil, err
+	}
+
 	ctx, cancelFunc := context.WithCancel(context.Background())
 
 	s := &server{
@@ -108,40 +251,202 @@ func newServer(kubeconfig string) (*server, error) {
 		arkClient:             arkClient,
 		discoveryClient:       arkClient.Discovery(),
 		clientPool:            dynamic.NewDynamicClientPool(clientConfig),
-		sharedInformerFactory: informers.NewSharedInformerFactory(arkClient, 0),
-		ctx:        ctx,
-		cancelFunc: cancelFunc,
+		sharedInformerFactory: informers.NewSharedInformerFactory(arkClient, controllerOptions.informerResyncPeriod),
+		ctx:                   ctx,
+		cancelFunc:            cancelFunc,
+		controllerOptions:     controllerOptions,
+		podCommandExecutor:    podCommandExecutor,
+		metrics:               metrics.NewServerMetrics(),
+		metricsAddress:        metricsAddress,
+		healthAddress:         healthAddress,
+		controllerStatus:      newControllerStatus(leaderElection != nil),
+		leaderElection:        leaderElection,
+		plugins:               plugin.NewManager(pluginDir),
+		gracePeriod:           gracePeriod,
 	}
 
 	return s, nil
 }
 
 func (s *server) run() error {
+	s.handleShutdownSignals()
+
 	if err := s.ensureArkNamespace(); err != nil {
 		return err
 	}
 
-	config, err := s.loadConfig()
+	// plugins are started as the cloud providers of locations need them
+	defer s.plugins.Stop()
+	go s.plugins.RunHealthChecks(s.ctx)
+
+	original, err := s.loadConfig()
+	if err != nil {
+		return err
+	}
+	config, err := cloneConfig(original)
 	if err != nil {
 		return err
 	}
 	applyConfigDefaults(config)
 
-	s.watchConfig(config)
+	if err := s.checkConfig(original, config); err != nil {
+		return err
+	}
+	s.applyLogLevel(config)
+
+	s.watchConfig(original)
+
+	s.storageLocations = s.loadBackupStorageLocations(config)
+	s.defaultStorageLocation = s.storageLocations[config.DefaultBackupStorageLocation]
+
+	s.watchBackupStorageLocations(s.storageLocations)
+
+	if err := s.initBackupService(); err != nil {
+		return err
+	}
 
-	if err := s.initBackupService(config); err != nil {
+	if s.snapshotLocations, err = s.loadVolumeSnapshotLocations(); err != nil {
 		return err
 	}
 
+	s.watchVolumeSnapshotLocations(s.snapshotLocations)
+
 	if err := s.initSnapshotService(config); err != nil {
 		return err
 	}
 
-	if err := s.runControllers(config); err != nil {
+	discoveryHelper, err := arkdiscovery.NewHelper(s.discoveryClient)
+	if err != nil {
 		return err
 	}
+	go wait.Until(
+		func() {
+			if err := discoveryHelper.Refresh(); err != nil {
+				glog.Errorf("error refreshing discovery: %v", err)
+			}
+		},
+		5*time.Minute,
+		s.ctx.Done(),
+	)
 
-	return nil
+	// the webhook, metrics, and health probes are served by every replica, whether or not it's
+	// the leader
+	var wg sync.WaitGroup
+	s.runServers(config, discoveryHelper, &wg)
+
+	if s.leaderElection == nil {
+		err = s.runControllers(config, discoveryHelper)
+	} else {
+		err = s.runControllersAsLeader(config, discoveryHelper)
+	}
+
+	s.cancelFunc()
+	wg.Wait()
+
+	return err
+}
+
+// handleShutdownSignals stops the server gracefully when it receives SIGTERM or SIGINT: the
+// controllers stop taking on new work and wait for their running backups and restores, and the
+// leader lease is released. A second signal exits immediately.
+func (s *server) handleShutdownSignals() {
+	signals := make(chan os.Signal, 2)
+	signal.Notify(signals, syscall.SIGTERM, os.Interrupt)
+
+	go func() {
+		sig := <-signals
+		glog.Infof("Received %s, shutting down gracefully", sig)
+		s.cancelFunc()
+
+		sig = <-signals
+		glog.Infof("Received %s again, exiting immediately", sig)
+		glog.Flush()
+		os.Exit(1)
+	}()
+}
+
+// runControllersAsLeader runs the controllers once this replica has been elected leader, until
+// it stops being the leader. Losing the lease is an error, so the server exits and restarts as a
+// candidate, without waiting for the controllers to stop if they take longer than the lease has
+// left.
+func (s *server) runControllersAsLeader(config *api.Config, discoveryHelper arkdiscovery.Helper) error {
+	elector, err := leaderelection.NewElector(s.kubeClient.CoreV1(), *s.leaderElection)
+	if err != nil {
+		return err
+	}
+	s.leaderLease = elector
+
+	var controllersErr error
+	err = elector.Run(s.ctx, func(ctx context.Context) {
+		// the controllers stop when the server's context is done, so losing the lease has
+		// to cancel it
+		go func() {
+			<-ctx.Done()
+			s.cancelFunc()
+		}()
+
+		s.controllerStatus.setLeading(true)
+		defer s.controllerStatus.setLeading(false)
+
+		controllersErr = s.runControllers(config, discoveryHelper)
+	})
+	if err == context.Canceled {
+		// the server was stopped, e.g. because the config changed, before this replica
+		// became the leader
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return controllersErr
+}
+
+// runServers starts the admission webhook, if it's configured, and the metrics and health
+// servers, adding them to wg. They're stopped when the server's context is done.
+func (s *server) runServers(config *api.Config, discoveryHelper arkdiscovery.Helper, wg *sync.WaitGroup) {
+	if config.AdmissionWebhook != nil {
+		webhookServer := webhook.NewServer(
+			config.AdmissionWebhook.Port,
+			config.AdmissionWebhook.CertFile,
+			config.AdmissionWebhook.KeyFile,
+			map[string]webhook.Validator{
+				webhook.BackupsPath:   webhook.NewBackupValidator(discoveryHelper, config.ImmutableBackups),
+				webhook.SchedulesPath: webhook.NewScheduleValidator(discoveryHelper),
+			},
+		)
+		wg.Add(1)
+		go func() {
+			if err := webhookServer.Run(s.ctx); err != nil {
+				glog.Errorf("error serving admission webhook: %v", err)
+			}
+			wg.Done()
+		}()
+	} else if config.ImmutableBackups {
+		glog.Warningf("Backups are immutable but the admission webhook isn't configured, so their API objects can still be modified")
+	}
+
+	if s.metricsAddress != "" {
+		metricsServer := metrics.NewServer(s.metricsAddress, s.metrics.Registry())
+		wg.Add(1)
+		go func() {
+			if err := metricsServer.Run(s.ctx); err != nil {
+				glog.Errorf("error serving metrics: %v", err)
+			}
+			wg.Done()
+		}()
+	}
+
+	if s.healthAddress != "" {
+		liveness, readiness := s.healthChecks()
+		healthServer := health.NewServer(s.healthAddress, liveness, readiness)
+		wg.Add(1)
+		go func() {
+			if err := healthServer.Run(s.ctx); err != nil {
+				glog.Errorf("error serving health probes: %v", err)
+			}
+			wg.Done()
+		}()
+	}
 }
 
 func (s *server) ensureArkNamespace() error {
@@ -182,18 +487,78 @@ func (s *server) loadConfig() (*api.Config, error) {
 	return config, nil
 }
 
+// checkConfig records the status of config's settings in original, the Config they were
+// loaded from, and returns an error if any of them are invalid. Failing to record the status
+// isn't an error.
+func (s *server) checkConfig(original, config *api.Config) error {
+	conditions, validationErr := validateConfig(config, original.Status.Conditions, time.Now())
+	if validationErr == nil {
+		glog.Infof("Ark configuration is valid")
+	}
+
+	if !reflect.DeepEqual(original.Status.Conditions, conditions) {
+		updated, err := cloneConfig(original)
+		if err != nil {
+			return err
+		}
+		updated.Status.Conditions = conditions
+
+		if _, err := s.arkClient.ArkV1().Configs(updated.Namespace).Update(updated); err != nil {
+			glog.Errorf("error updating status of Ark configuration: %v", err)
+		}
+	}
+
+	return validationErr
+}
+
+func cloneConfig(in interface{}) (*api.Config, error) {
+	clone, err := scheme.Scheme.DeepCopy(in)
+	if err != nil {
+		return nil, err
+	}
+
+	out, ok := clone.(*api.Config)
+	if !ok {
+		return nil, fmt.Errorf("unexpected type: %T", clone)
+	}
+
+	return out, nil
+}
+
 const (
 	defaultGCSyncPeriod       = 60 * time.Minute
 	defaultBackupSyncPeriod   = 60 * time.Minute
 	defaultScheduleSyncPeriod = time.Minute
+
+	defaultStorageLocationProbePeriod = time.Minute
+
+	defaultResourceCollectionWorkers = 1
+	defaultResourceListPageSize      = 500
+
+	defaultResticTimeout              = time.Hour
+	defaultResticMaintenanceFrequency = 7 * 24 * time.Hour
+
+	defaultMaxFreezeDuration = time.Minute
+
+	defaultCSISnapshotTimeout = 10 * time.Minute
+
+	defaultAuditFlushInterval = time.Minute
+
+	defaultTracingServiceName = "ark"
+
+	// tracingFlushInterval is how often ended spans are exported.
+	tracingFlushInterval = 5 * time.Second
 )
 
 var defaultResourcePriorities = []string{
+	"customresourcedefinitions",
 	"namespaces",
+	"storageclasses",
 	"persistentvolumes",
 	"persistentvolumeclaims",
 	"secrets",
 	"configmaps",
+	"pods",
 }
 
 func applyConfigDefaults(c *api.Config) {
@@ -209,6 +574,62 @@ func applyConfigDefaults(c *api.Config) {
 		c.ScheduleSyncPeriod.Duration = defaultScheduleSyncPeriod
 	}
 
+	if c.StorageLocationProbePeriod.Duration == 0 {
+		c.StorageLocationProbePeriod.Duration = defaultStorageLocationProbePeriod
+	}
+
+	if c.DefaultBackupStorageLocation == "" {
+		c.DefaultBackupStorageLocation = api.DefaultBackupStorageLocation
+	}
+
+	if c.ResourceCollectionWorkers == 0 {
+		c.ResourceCollectionWorkers = defaultResourceCollectionWorkers
+	}
+
+	if c.ResourceListPageSize == 0 {
+		c.ResourceListPageSize = defaultResourceListPageSize
+	}
+
+	if c.Restic != nil {
+		if c.Restic.Image == "" {
+			c.Restic.Image = restic.DefaultImage
+		}
+		if c.Restic.KopiaImage == "" {
+			c.Restic.KopiaImage = restic.DefaultKopiaImage
+		}
+		if c.Restic.Timeout.Duration == 0 {
+			c.Restic.Timeout.Duration = defaultResticTimeout
+		}
+		if c.Restic.MaintenanceFrequency.Duration == 0 {
+			c.Restic.MaintenanceFrequency.Duration = defaultResticMaintenanceFrequency
+		}
+	}
+
+	if c.VolumeFreeze != nil {
+		if c.VolumeFreeze.Image == "" {
+			c.VolumeFreeze.Image = quiesce.DefaultImage
+		}
+		if c.VolumeFreeze.MaxFreezeDuration.Duration == 0 {
+			c.VolumeFreeze.MaxFreezeDuration.Duration = defaultMaxFreezeDuration
+		}
+	}
+
+	if c.AdmissionWebhook != nil && c.AdmissionWebhook.Port == 0 {
+		c.AdmissionWebhook.Port = webhook.DefaultPort
+	}
+
+	if c.CSISnapshots != nil && c.CSISnapshots.Timeout.Duration == 0 {
+		c.CSISnapshots.Timeout.Duration = defaultCSISnapshotTimeout
+	}
+
+	if c.Audit != nil && c.Audit.FlushInterval.Duration == 0 {
+		c.Audit.FlushInterval.Duration = defaultAuditFlushInterval
+	}
+
+	if c.Tracing != nil && c.Tracing.ServiceName == "" {
+		c.Tracing.ServiceName = defaultTracingServiceName
+	}
+
 	if len(c.ResourcePriorities) == 0 {
 		c.ResourcePriorities = defaultResourcePriorities
 		glog.Infof("Using default resource priorities: %v", c.ResourcePriorities)
@@ -218,7 +639,8 @@ func applyConfigDefaults(c *api.Config) {
 }
 
 // watchConfig adds an update event handler to the Config shared informer, invoking s.cancelFunc
-// when it sees a change.
+// when it sees a change to config's settings. Changes to its logLevel are applied without
+// restarting, and changes to its status, e.g. by other replicas, are ignored.
 func (s *server) watchConfig(config *api.Config) {
 	s.sharedInformerFactory.Ark().V1().Configs().Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
 		UpdateFunc: func(oldObj, newObj interface{}) {
@@ -229,118 +651,290 @@ func (s *server) watchConfig(config *api.Config) {
 				return
 			}
 
-			if !reflect.DeepEqual(config, updated) {
+			if !configSettingsEqual(config, updated) {
 				glog.Infof("Detected a config change. Gracefully shutting down")
 				s.cancelFunc()
+				return
+			}
+
+			if !reflect.DeepEqual(config.LogLevel, updated.LogLevel) {
+				config = updated
+
+				defaulted, err := cloneConfig(updated)
+				if err != nil {
+					glog.Errorf("error applying log level: %v", err)
+					return
+				}
+				applyConfigDefaults(defaulted)
+
+				if err := s.checkConfig(updated, defaulted); err != nil {
+					glog.Errorf("Not changing the log level: %v", err)
+					return
+				}
+				s.applyLogLevel(defaulted)
 			}
 		},
 	})
 }
 
-func (s *server) initBackupService(config *api.Config) error {
-	glog.Infof("Configuring cloud provider for backup service")
-	objectStorage, err := getObjectStorageProvider(config.BackupStorageProvider.CloudProviderConfig, "backupStorageProvider")
-	if err != nil {
-		return err
+// applyLogLevel sets the server's log verbosity to config's logLevel, or, if it's not set, to
+// the -v flag's.
+func (s *server) applyLogLevel(config *api.Config) {
+	if err := logging.SetVerbosity(config.LogLevel); err != nil {
+		glog.Errorf("error setting log level: %v", err)
+		return
+	}
+	if config.LogLevel != nil {
+		glog.Infof("Log level set to %d by config", *config.LogLevel)
+	} else {
+		glog.Infof("Log level set by the -v flag")
 	}
-
-	s.backupService = cloudprovider.NewBackupService(objectStorage)
-	return nil
 }
 
-func (s *server) initSnapshotService(config *api.Config) error {
-	if config.PersistentVolumeProvider == nil {
-		glog.Infof("PersistentVolumeProvider config not provided, volume snapshots and restores are disabled")
-		return nil
+// loadBackupStorageLocations retrieves the server's backup storage locations, by name, waiting
+// until the config's default location exists.
+func (s *server) loadBackupStorageLocations(config *api.Config) map[string]*api.BackupStorageLocation {
+	glog.Infof("Retrieving backup storage locations")
+	for {
+		list, err := s.arkClient.ArkV1().BackupStorageLocations(api.DefaultNamespace).List(metav1.ListOptions{})
+		if err == nil {
+			locations := make(map[string]*api.BackupStorageLocation, len(list.Items))
+			for i := range list.Items {
+				locations[list.Items[i].Name] = &list.Items[i]
+			}
+
+			if _, ok := locations[config.DefaultBackupStorageLocation]; ok {
+				glog.Infof("Successfully retrieved %d backup storage locations", len(locations))
+				return locations
+			}
+			glog.Errorf("default backup storage location %s doesn't exist", config.DefaultBackupStorageLocation)
+		} else {
+			glog.Errorf("error retrieving backup storage locations: %v", err)
+		}
+		glog.Infof("Will attempt to retrieve backup storage locations again in 5 seconds")
+		time.Sleep(5 * time.Second)
 	}
+}
 
-	glog.Infof("Configuring cloud provider for snapshot service")
-	blockStorage, err := getBlockStorageProvider(*config.PersistentVolumeProvider, "persistentVolumeProvider")
-	if err != nil {
-		return err
+// watchBackupStorageLocations adds event handlers to the BackupStorageLocation shared informer,
+// invoking s.cancelFunc when a location is added, changed, or deleted.
+func (s *server) watchBackupStorageLocations(locations map[string]*api.BackupStorageLocation) {
+	// spec is nil if the location was deleted
+	check := func(name string, spec *api.BackupStorageLocationSpec) {
+		var current *api.BackupStorageLocationSpec
+		if location, ok := locations[name]; ok {
+			current = &location.Spec
+		}
+
+		if !reflect.DeepEqual(current, spec) {
+			glog.Infof("Detected a change to backup storage location %s. Gracefully shutting down", name)
+			s.cancelFunc()
+		}
 	}
-	s.snapshotService = cloudprovider.NewSnapshotService(blockStorage)
-	return nil
+
+	s.sharedInformerFactory.Ark().V1().BackupStorageLocations().Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			location := obj.(*api.BackupStorageLocation)
+			check(location.Name, &location.Spec)
+		},
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			location := newObj.(*api.BackupStorageLocation)
+			check(location.Name, &location.Spec)
+		},
+		DeleteFunc: func(obj interface{}) {
+			if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+				obj = tombstone.Obj
+			}
+			if location, ok := obj.(*api.BackupStorageLocation); ok {
+				check(location.Name, nil)
+			}
+		},
+	})
 }
 
-func hasOneCloudProvider(cloudConfig api.CloudProviderConfig) bool {
-	found := false
+// loadVolumeSnapshotLocations retrieves the server's volume snapshot locations, by name.
+func (s *server) loadVolumeSnapshotLocations() (map[string]*api.VolumeSnapshotLocation, error) {
+	glog.Infof("Retrieving volume snapshot locations")
+	list, err := s.arkClient.ArkV1().VolumeSnapshotLocations(api.DefaultNamespace).List(metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("error retrieving volume snapshot locations: %v", err)
+	}
 
-	if cloudConfig.AWS != nil {
-		found = true
+	locations := make(map[string]*api.VolumeSnapshotLocation, len(list.Items))
+	for i := range list.Items {
+		locations[list.Items[i].Name] = &list.Items[i]
 	}
+	glog.Infof("Successfully retrieved %d volume snapshot locations", len(locations))
 
-	if cloudConfig.GCP != nil {
-		if found {
-			return false
+	return locations, nil
+}
+
+// watchVolumeSnapshotLocations adds event handlers to the VolumeSnapshotLocation shared informer,
+// invoking s.cancelFunc when a location is added, changed, or deleted.
+func (s *server) watchVolumeSnapshotLocations(locations map[string]*api.VolumeSnapshotLocation) {
+	// spec is nil if the location was deleted
+	check := func(name string, spec *api.VolumeSnapshotLocationSpec) {
+		var current *api.VolumeSnapshotLocationSpec
+		if location, ok := locations[name]; ok {
+			current = &location.Spec
 		}
-		found = true
-	}
 
-	if cloudConfig.Azure != nil {
-		if found {
-			return false
+		if !reflect.DeepEqual(current, spec) {
+			glog.Infof("Detected a change to volume snapshot location %s. Gracefully shutting down", name)
+			s.cancelFunc()
 		}
-		found = true
 	}
 
-	return found
+	s.sharedInformerFactory.Ark().V1().VolumeSnapshotLocations().Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			location := obj.(*api.VolumeSnapshotLocation)
+			check(location.Name, &location.Spec)
+		},
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			location := newObj.(*api.VolumeSnapshotLocation)
+			check(location.Name, &location.Spec)
+		},
+		DeleteFunc: func(obj interface{}) {
+			if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+				obj = tombstone.Obj
+			}
+			if location, ok := obj.(*api.VolumeSnapshotLocation); ok {
+				check(location.Name, nil)
+			}
+		},
+	})
 }
 
-func getObjectStorageProvider(cloudConfig api.CloudProviderConfig, field string) (cloudprovider.ObjectStorageAdapter, error) {
-	var (
-		objectStorage cloudprovider.ObjectStorageAdapter
-		err           error
-	)
+func (s *server) initBackupService() error {
+	if _, err := storageLocationBuckets(s.storageLocations); err != nil {
+		return err
+	}
+
+	s.objectStorage = make(map[string]cloudprovider.ObjectStorageAdapter, len(s.storageLocations))
+	services := make(map[string]cloudprovider.BackupService, len(s.storageLocations))
+	for name, location := range s.storageLocations {
+		glog.Infof("Configuring cloud provider for backup storage location %s", name)
+
+		objectStorage, err := providers.NewObjectStorageAdapter(location.Spec.CloudProviderConfig, "backupStorageLocations."+name, s.plugins)
+		if err != nil {
+			return err
+		}
+		objectStorage = cloudprovider.NewInstrumentedObjectStorageAdapter(objectStorage, s.metrics, "backupStorageLocations."+name)
+
+		if location.Spec.Prefix != "" {
+			objectStorage = cloudprovider.NewPrefixedObjectStorageAdapter(objectStorage, location.Spec.Bucket, location.Spec.Prefix)
+		}
+		s.objectStorage[name] = objectStorage
+
+		var service cloudprovider.BackupService
+		if location.Spec.Deduplicate {
+			glog.Infof("Backup deduplication is enabled for backup storage location %s", name)
+			service = cloudprovider.NewDeduplicatingBackupService(objectStorage)
+		} else {
+			service = cloudprovider.NewBackupService(objectStorage)
+		}
+
+		if location.Spec.AccessMode == api.BackupStorageLocationAccessModeReadOnly {
+			glog.Infof("Backup storage location %s is read-only", name)
+			service = cloudprovider.NewReadOnlyBackupService(service)
+		}
 
-	if !hasOneCloudProvider(cloudConfig) {
-		return nil, fmt.Errorf("you must specify exactly one of aws, gcp, or azure for %s", field)
+		services[cloudprovider.LocationBucket(location.Spec.ObjectStorageProviderConfig)] = service
 	}
 
-	switch {
-	case cloudConfig.AWS != nil:
-		objectStorage, err = arkaws.NewObjectStorageAdapter(
-			cloudConfig.AWS.Region,
-			cloudConfig.AWS.S3Url,
-			cloudConfig.AWS.KMSKeyID,
-			cloudConfig.AWS.S3ForcePathStyle)
-	case cloudConfig.GCP != nil:
-		objectStorage, err = gcp.NewObjectStorageAdapter()
-	case cloudConfig.Azure != nil:
-		objectStorage, err = azure.NewObjectStorageAdapter()
+	defaultBucket := cloudprovider.LocationBucket(s.defaultStorageLocation.Spec.ObjectStorageProviderConfig)
+	s.backupService = cloudprovider.NewBucketRoutingBackupService(services[defaultBucket], services)
+
+	return nil
+}
+
+// storageLocationBuckets returns the bucket of each of the given backup storage locations, as
+// returned by cloudprovider.LocationBucket, keyed by location name. It returns an error if a
+// location has no bucket or an invalid access mode, or if it overlaps with another location.
+func storageLocationBuckets(locations map[string]*api.BackupStorageLocation) (map[string]string, error) {
+	// check the locations in a consistent order, so the same error is always returned
+	names := make([]string, 0, len(locations))
+	for name := range locations {
+		names = append(names, name)
 	}
+	sort.Strings(names)
 
-	if err != nil {
-		return nil, err
+	buckets := make(map[string]string, len(locations))
+	for i, name := range names {
+		spec := locations[name].Spec
+
+		if spec.Bucket == "" {
+			return nil, fmt.Errorf("backup storage location %s must specify a bucket", name)
+		}
+
+		switch spec.AccessMode {
+		case "", api.BackupStorageLocationAccessModeReadWrite, api.BackupStorageLocationAccessModeReadOnly:
+		default:
+			return nil, fmt.Errorf("backup storage location %s has invalid access mode %q; it must be %s or %s",
+				name, spec.AccessMode, api.BackupStorageLocationAccessModeReadWrite, api.BackupStorageLocationAccessModeReadOnly)
+		}
+
+		for _, other := range names[:i] {
+			if cloudprovider.LocationsOverlap(spec.ObjectStorageProviderConfig, locations[other].Spec.ObjectStorageProviderConfig) {
+				return nil, fmt.Errorf("backup storage location %s uses the same bucket as %s, and neither is under a prefix the other isn't", name, other)
+			}
+		}
+
+		buckets[name] = cloudprovider.LocationBucket(spec.ObjectStorageProviderConfig)
 	}
 
-	return objectStorage, nil
+	return buckets, nil
 }
 
-func getBlockStorageProvider(cloudConfig api.CloudProviderConfig, field string) (cloudprovider.BlockStorageAdapter, error) {
-	var (
-		blockStorage cloudprovider.BlockStorageAdapter
-		err          error
-	)
+func (s *server) initSnapshotService(config *api.Config) error {
+	if len(s.snapshotLocations) == 0 {
+		glog.Infof("No volume snapshot locations exist, volume snapshots and restores are disabled")
+		return nil
+	}
 
-	if !hasOneCloudProvider(cloudConfig) {
-		return nil, fmt.Errorf("you must specify exactly one of aws, gcp, or azure for %s", field)
+	defaults, err := cloudprovider.DefaultSnapshotLocations(s.snapshotLocations, config.DefaultVolumeSnapshotLocations)
+	if err != nil {
+		return err
 	}
 
-	switch {
-	case cloudConfig.AWS != nil:
-		blockStorage, err = arkaws.NewBlockStorageAdapter(cloudConfig.AWS.Region, cloudConfig.AWS.AvailabilityZone)
-	case cloudConfig.GCP != nil:
-		blockStorage, err = gcp.NewBlockStorageAdapter(cloudConfig.GCP.Project, cloudConfig.GCP.Zone)
-	case cloudConfig.Azure != nil:
-		blockStorage, err = azure.NewBlockStorageAdapter(cloudConfig.Azure.Location, cloudConfig.Azure.APITimeout.Duration)
+	locationServices := make(map[string]cloudprovider.SnapshotService, len(s.snapshotLocations))
+	locationProviders := make(map[string]string, len(s.snapshotLocations))
+	for name, location := range s.snapshotLocations {
+		glog.Infof("Configuring cloud provider for volume snapshot location %s", name)
+		blockStorage, err := providers.NewBlockStorageAdapter(location.Spec.CloudProviderConfig, "volumeSnapshotLocations."+name, s.plugins)
+		if err != nil {
+			return err
+		}
+		blockStorage = cloudprovider.NewInstrumentedBlockStorageAdapter(blockStorage, s.metrics, "volumeSnapshotLocations."+name)
+		locationServices[name] = cloudprovider.NewSnapshotService(blockStorage)
+		locationProviders[name] = cloudprovider.ProviderName(location.Spec.CloudProviderConfig)
+	}
+	for provider, name := range defaults {
+		glog.Infof("Volume snapshot location %s is the default for %s volumes", name, provider)
 	}
+	s.snapshotService = cloudprovider.NewSnapshotServiceWithLocations(locationServices, locationProviders, defaults)
 
-	if err != nil {
-		return nil, err
+	return nil
+}
+
+// getClusterUID returns the UID of the cluster the server is running in, which is the configured
+// ClusterUID if there is one, and otherwise the UID of the kube-system namespace. It returns an
+// error if the kube-system namespace's UID can't be used as a label value.
+func (s *server) getClusterUID(config *api.Config) (string, error) {
+	uid := config.ClusterUID
+	if uid == "" {
+		ns, err := s.kubeClient.CoreV1().Namespaces().Get("kube-system", metav1.GetOptions{})
+		if err != nil {
+			return "", fmt.Errorf("error getting kube-system namespace to determine the cluster's UID: %v", err)
+		}
+		uid = string(ns.UID)
+	}
+
+	if errs := validation.IsValidLabelValue(uid); len(errs) > 0 {
+		return "", fmt.Errorf("invalid clusterUID %q: %s", uid, strings.Join(errs, "; "))
 	}
 
-	return blockStorage, nil
+	return uid, nil
 }
 
 func durationMin(a, b time.Duration) time.Duration {
@@ -350,7 +944,7 @@ func durationMin(a, b time.Duration) time.Duration {
 	return b
 }
 
-func (s *server) runControllers(config *api.Config) error {
+func (s *server) runControllers(config *api.Config, discoveryHelper arkdiscovery.Helper) error {
 	glog.Infof("Starting controllers")
 
 	ctx := s.ctx
@@ -364,10 +958,15 @@ func (s *server) runControllers(config *api.Config) error {
 		cloudBackupCacheResyncPeriod,
 	)
 
+	// the buckets were validated when the backup service was initialized
+	storageLocations, _ := storageLocationBuckets(s.storageLocations)
+	defaultBucket := storageLocations[config.DefaultBackupStorageLocation]
+
 	backupSyncController := controller.NewBackupSyncController(
 		s.arkClient.ArkV1(),
 		s.backupService,
-		config.BackupStorageProvider.Bucket,
+		defaultBucket,
+		storageLocations,
 		config.BackupSyncPeriod.Duration,
 	)
 	wg.Add(1)
@@ -376,36 +975,147 @@ func (s *server) runControllers(config *api.Config) error {
 		wg.Done()
 	}()
 
-	discoveryHelper, err := arkdiscovery.NewHelper(s.discoveryClient)
+	storageLocationController := controller.NewStorageLocationController(
+		s.objectStorage,
+		config.StorageLocationProbePeriod.Duration,
+		s.sharedInformerFactory.Ark().V1().BackupStorageLocations(),
+		s.arkClient.ArkV1(),
+	)
+	wg.Add(1)
+	go func() {
+		storageLocationController.Run(ctx, 1)
+		wg.Done()
+	}()
+
+	clusterUID, err := s.getClusterUID(config)
 	if err != nil {
 		return err
 	}
-	go wait.Until(
-		func() {
-			if err := discoveryHelper.Refresh(); err != nil {
-				glog.Errorf("error refreshing discovery: %v", err)
-			}
-		},
-		5*time.Minute,
-		ctx.Done(),
+	glog.Infof("Recording cluster name %q and UID %q on backups", config.ClusterName, clusterUID)
+
+	notifier, err := notification.NewNotifier(config.Notifications, s.sharedInformerFactory.Ark().V1().Schedules().Lister())
+	if err != nil {
+		return err
+	}
+	if config.Notifications != nil {
+		glog.Infof("Sending notifications to %d webhook(s)", len(config.Notifications.Webhooks))
+	}
+	eventRecorder := notification.NewRecorder(event.NewRecorder(s.kubeClient.CoreV1(), event.Component), notifier)
+
+	if config.Audit != nil {
+		if s.defaultStorageLocation.Spec.AccessMode == api.BackupStorageLocationAccessModeReadOnly {
+			return fmt.Errorf("the audit log can't be written to read-only backup storage location %s", s.defaultStorageLocation.Name)
+		}
+
+		auditLog, err := audit.NewLog(s.objectStorage[s.defaultStorageLocation.Name], s.defaultStorageLocation.Spec.Bucket, s.leaderLease)
+		if err != nil {
+			return err
+		}
+		glog.Infof("Writing audit log to backup storage location %s, retrying failed writes every %s", s.defaultStorageLocation.Name, config.Audit.FlushInterval.Duration)
+		eventRecorder = audit.NewRecorder(eventRecorder, auditLog)
+
+		wg.Add(1)
+		go func() {
+			auditLog.Run(ctx, config.Audit.FlushInterval.Duration)
+			wg.Done()
+		}()
+	}
+
+	if config.Tracing != nil {
+		glog.Infof("Exporting traces of backups and restores to %s", config.Tracing.Endpoint)
+		tracer := tracing.NewTracer(config.Tracing.Endpoint, config.Tracing.ServiceName)
+		tracing.SetTracer(tracer)
+
+		wg.Add(1)
+		go func() {
+			tracer.Run(ctx, tracingFlushInterval)
+			wg.Done()
+		}()
+	}
+
+	var (
+		resticRunner restic.Runner
+		resticImage  string
 	)
+	if config.Restic != nil {
+		if config.Restic.NodeAgent {
+			glog.Infof("Backing up and restoring pod volumes using the node agents")
+			resticRunner, err = restic.NewAgentRunner(
+				s.kubeClient.CoreV1(),
+				s.kubeClient.CoreV1(),
+				s.arkClient.ArkV1(),
+				s.arkClient.ArkV1(),
+				s.defaultStorageLocation.Spec.ObjectStorageProviderConfig,
+				api.DefaultNamespace,
+				config.Restic.Image,
+				config.Restic.KopiaImage,
+				config.Restic.Timeout.Duration,
+				config.Restic.HelperPodResources,
+				uploader.Limits{UploadKiBps: config.Restic.UploadLimit, DownloadKiBps: config.Restic.DownloadLimit},
+			)
+		} else {
+			glog.Infof("Backing up and restoring pod volumes using restic image %s", config.Restic.Image)
+			resticRunner, err = restic.NewPodRunner(
+				s.kubeClient.CoreV1(),
+				s.kubeClient.CoreV1(),
+				s.defaultStorageLocation.Spec.ObjectStorageProviderConfig,
+				api.DefaultNamespace,
+				config.Restic.Image,
+				config.Restic.KopiaImage,
+				config.Restic.Timeout.Duration,
+				config.Restic.HelperPodResources,
+				uploader.Limits{UploadKiBps: config.Restic.UploadLimit, DownloadKiBps: config.Restic.DownloadLimit},
+			)
+		}
+		cmd.CheckError(err)
+		resticImage = config.Restic.Image
+	}
+
+	var freezer quiesce.Freezer
+	if config.VolumeFreeze != nil {
+		glog.Infof("Freezing volumes while they're snapshotted using image %s", config.VolumeFreeze.Image)
+		freezer = quiesce.NewPodFreezer(s.kubeClient.CoreV1(), api.DefaultNamespace, config.VolumeFreeze.Image, config.VolumeFreeze.MaxFreezeDuration.Duration)
+	}
+
+	var csiSnapshotter csi.Snapshotter
+	if config.CSISnapshots != nil {
+		glog.Infof("Snapshotting CSI volumes using the VolumeSnapshot API")
+		csiSnapshotter = csi.NewSnapshotter(client.NewDynamicFactory(s.clientPool), config.CSISnapshots.VolumeSnapshotClassName, config.CSISnapshots.Timeout.Duration)
+	}
 
 	if config.RestoreOnlyMode {
-		glog.Infof("Restore only mode - not starting the backup, schedule or GC controllers")
+		glog.Infof("Restore only mode - not starting the backup, schedule, GC, retention or backup deletion controllers")
 	} else {
-		backupper, err := newBackupper(discoveryHelper, s.clientPool, s.backupService, s.snapshotService)
+		backupper, err := newBackupper(discoveryHelper, s.clientPool, s.backupService, s.snapshotService, csiSnapshotter, freezer, resticRunner, config.BackupItemTransforms, config.BackupResourcePriorities, config.ResourceCollectionWorkers, config.ResourceListPageSize, s.kubeClient)
 		cmd.CheckError(err)
 		backupController := controller.NewBackupController(
 			s.sharedInformerFactory.Ark().V1().Backups(),
+			s.sharedInformerFactory.Ark().V1().BackupStorageLocations(),
 			s.arkClient.ArkV1(),
+			eventRecorder,
 			backupper,
 			s.backupService,
-			config.BackupStorageProvider.Bucket,
-			s.snapshotService != nil,
+			s.snapshotService,
+			csiSnapshotter,
+			resticRunner,
+			defaultBucket,
+			storageLocations,
+			config.DefaultBackupStorageLocation,
+			config.ClusterName,
+			clusterUID,
+			config.ImmutableBackups,
+			s.snapshotService != nil || csiSnapshotter != nil,
+			resticRunner != nil,
+			config.TenantMode,
+			config.TenantQuota,
+			config.DefaultExcludedResources,
+			config.ExcludeCompletedPods,
+			s.metrics,
+			s.gracePeriod,
 		)
 		wg.Add(1)
 		go func() {
-			backupController.Run(ctx, 1)
+			backupController.Run(ctx, s.controllerOptions.backupWorkers)
 			wg.Done()
 		}()
 
@@ -413,25 +1123,86 @@ func (s *server) runControllers(config *api.Config) error {
 			s.arkClient.ArkV1(),
 			s.arkClient.ArkV1(),
 			s.sharedInformerFactory.Ark().V1().Schedules(),
+			s.sharedInformerFactory.Ark().V1().Backups(),
 			config.ScheduleSyncPeriod.Duration,
+			config.ClusterName,
 		)
 		wg.Add(1)
 		go func() {
-			scheduleController.Run(ctx, 1)
+			scheduleController.Run(ctx, s.controllerOptions.scheduleWorkers)
 			wg.Done()
 		}()
 
 		gcController := controller.NewGCController(
 			s.backupService,
 			s.snapshotService,
-			config.BackupStorageProvider.Bucket,
+			csiSnapshotter,
+			resticRunner,
+			defaultBucket,
+			storageLocations,
+			config.GCSyncPeriod.Duration,
+			s.sharedInformerFactory.Ark().V1().Backups(),
+			s.arkClient.ArkV1(),
+			eventRecorder,
+			s.metrics,
+		)
+		wg.Add(1)
+		go func() {
+			gcController.Run(ctx, s.controllerOptions.gcWorkers)
+			wg.Done()
+		}()
+
+		if resticRunner != nil {
+			if s.defaultStorageLocation.Spec.AccessMode == api.BackupStorageLocationAccessModeReadOnly {
+				glog.Infof("Not maintaining restic repositories since the default backup storage location is read-only")
+			} else {
+				resticRepositoryController := controller.NewResticRepositoryController(
+					s.sharedInformerFactory.Ark().V1().ResticRepositories(),
+					s.arkClient.ArkV1(),
+					s.sharedInformerFactory.Ark().V1().Backups(),
+					s.sharedInformerFactory.Ark().V1().Restores(),
+					s.objectStorage[s.defaultStorageLocation.Name],
+					s.defaultStorageLocation,
+					resticRunner,
+					config.Restic.MaintenanceFrequency.Duration,
+					api.DefaultNamespace,
+				)
+				wg.Add(1)
+				go func() {
+					resticRepositoryController.Run(ctx, 1)
+					wg.Done()
+				}()
+			}
+		}
+
+		retentionController := controller.NewRetentionController(
+			s.sharedInformerFactory.Ark().V1().Schedules(),
+			s.sharedInformerFactory.Ark().V1().Backups(),
+			s.arkClient.ArkV1(),
 			config.GCSyncPeriod.Duration,
+		)
+		wg.Add(1)
+		go func() {
+			retentionController.Run(ctx, 1)
+			wg.Done()
+		}()
+
+		backupDeletionController := controller.NewBackupDeletionController(
+			s.sharedInformerFactory.Ark().V1().DeleteBackupRequests(),
+			s.arkClient.ArkV1(),
 			s.sharedInformerFactory.Ark().V1().Backups(),
 			s.arkClient.ArkV1(),
+			s.backupService,
+			s.snapshotService,
+			csiSnapshotter,
+			resticRunner,
+			defaultBucket,
+			config.ImmutableBackups,
+			eventRecorder,
 		)
 		wg.Add(1)
 		go func() {
-			gcController.Run(ctx, 1)
+			backupDeletionController.Run(ctx, s.controllerOptions.backupDeletionWorkers)
 			wg.Done()
 		}()
 	}
@@ -441,9 +1212,13 @@ func (s *server) runControllers(config *api.Config) error {
 		s.clientPool,
 		s.backupService,
 		s.snapshotService,
+		csiSnapshotter,
 		config.ResourcePriorities,
 		s.arkClient.ArkV1(),
 		s.kubeClient,
+		resticRunner,
+		resticImage,
+		s.podCommandExecutor,
 	)
 	cmd.CheckError(err)
 
@@ -453,18 +1228,72 @@ func (s *server) runControllers(config *api.Config) error {
 		s.arkClient.ArkV1(),
 		restorer,
 		s.backupService,
-		config.BackupStorageProvider.Bucket,
+		defaultBucket,
+		s.sharedInformerFactory.Ark().V1().Backups(),
+		s.snapshotService != nil || csiSnapshotter != nil,
+		config.TenantMode,
+		s.metrics,
+		eventRecorder,
+		s.gracePeriod,
+	)
+	wg.Add(1)
+	go func() {
+		restoreController.Run(ctx, s.controllerOptions.restoreWorkers)
+		wg.Done()
+	}()
+
+	backupVerificationController := controller.NewBackupVerificationController(
+		s.sharedInformerFactory.Ark().V1().BackupVerifications(),
 		s.sharedInformerFactory.Ark().V1().Backups(),
-		s.snapshotService != nil,
+		s.arkClient.ArkV1(),
+		s.backupService,
+		s.snapshotService,
+		defaultBucket,
 	)
 	wg.Add(1)
 	go func() {
-		restoreController.Run(ctx, 1)
+		backupVerificationController.Run(ctx, 1)
+		wg.Done()
+	}()
+
+	downloadRequestController := controller.NewDownloadRequestController(
+		s.arkClient.ArkV1(),
+		s.sharedInformerFactory.Ark().V1().DownloadRequests(),
+		s.sharedInformerFactory.Ark().V1().Restores(),
+		s.sharedInformerFactory.Ark().V1().Backups(),
+		s.backupService,
+		defaultBucket,
+		config.TenantMode,
+	)
+	wg.Add(1)
+	go func() {
+		downloadRequestController.Run(ctx, 1)
+		wg.Done()
+	}()
+
+	serverStatusRequestController := controller.NewServerStatusRequestController(
+		s.arkClient.ArkV1(),
+		s.sharedInformerFactory.Ark().V1().ServerStatusRequests(),
+		buildinfo.Version,
+		buildinfo.GitSHA,
+	)
+	wg.Add(1)
+	go func() {
+		serverStatusRequestController.Run(ctx, 1)
 		wg.Done()
 	}()
 
 	// SHARED INFORMERS HAVE TO BE STARTED AFTER ALL CONTROLLERS
-	go s.sharedInformerFactory.Start(ctx.Done())
+	s.controllerStatus.startInformers()
+	go func() {
+		s.sharedInformerFactory.Start(ctx.Done())
+		for _, synced := range s.sharedInformerFactory.WaitForCacheSync(ctx.Done()) {
+			if !synced {
+				return
+			}
+		}
+		s.controllerStatus.syncInformers()
+	}()
 
 	glog.Infof("Server started successfully")
 
@@ -481,11 +1310,19 @@ func newBackupper(
 	clientPool dynamic.ClientPool,
 	backupService cloudprovider.BackupService,
 	snapshotService cloudprovider.SnapshotService,
+	csiSnapshotter csi.Snapshotter,
+	freezer quiesce.Freezer,
+	resticBackupper restic.Backupper,
+	itemTransforms []api.BackupItemTransform,
+	resourcePriorities []string,
+	resourceCollectionWorkers int,
+	resourceListPageSize int64,
+	kubeClient kubernetes.Interface,
 ) (backup.Backupper, error) {
 	actions := map[string]backup.Action{}
 
-	if snapshotService != nil {
-		action, err := backup.NewVolumeSnapshotAction(snapshotService)
+	if snapshotService != nil || csiSnapshotter != nil {
+		action, err := backup.NewVolumeSnapshotAction(snapshotService, csiSnapshotter, kubeClient.CoreV1(), kubeClient.CoreV1(), freezer, resticBackupper != nil)
 		if err != nil {
 			return nil, err
 		}
@@ -493,10 +1330,76 @@ func newBackupper(
 		actions["persistentvolumes"] = action
 	}
 
+	if resticBackupper != nil {
+		// NFS and EFS volumes, which can't be snapshotted, are backed up at the file level
+		pvClient, err := client.NewDynamicFactory(clientPool).ClientForGroupVersionResource(
+			schema.GroupVersionResource{Version: "v1", Resource: "persistentvolumes"},
+			metav1.APIResource{Name: "persistentvolumes"},
+			"",
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		action, err := backup.NewPodVolumeBackupAction(resticBackupper, kubeClient.CoreV1(), pvClient)
+		if err != nil {
+			return nil, err
+		}
+
+		actions["pods"] = action
+
+		// backups that move volume data can only move it through running pods, so report the
+		// claims that aren't mounted by one
+		action, err = backup.NewMoveVolumeDataAction(kubeClient.CoreV1(), actions["persistentvolumes"])
+		if err != nil {
+			return nil, err
+		}
+
+		actions["persistentvolumes"] = action
+
+		// the data of local and hostPath PVs, which can't be snapshotted, is backed up through
+		// the node agents, when they're in use
+		if hostVolumeBackupper, ok := resticBackupper.(restic.HostVolumeBackupper); ok {
+			action, err := backup.NewHostVolumeBackupAction(hostVolumeBackupper, actions["persistentvolumes"])
+			if err != nil {
+				return nil, err
+			}
+
+			actions["persistentvolumes"] = action
+		}
+	}
+
+	var transforms []backup.ItemTransform
+	for _, itemTransform := range itemTransforms {
+		selector := labels.Everything()
+		if itemTransform.LabelSelector != nil {
+			var err error
+			if selector, err = metav1.LabelSelectorAsSelector(itemTransform.LabelSelector); err != nil {
+				return nil, fmt.Errorf("error parsing label selector of backup item transform: %v", err)
+			}
+		}
+
+		transforms = append(transforms, backup.ItemTransform{
+			Resources:   itemTransform.Resources,
+			Selector:    selector,
+			Transformer: backup.NewRemoveFieldsTransformer(itemTransform.RemoveFields),
+		})
+	}
+
+	itemActionNames, itemActions := backup.RegisteredItemActions()
+	if len(itemActionNames) > 0 {
+		glog.Infof("Using backup item actions: %v", itemActionNames)
+	}
+
 	return backup.NewKubernetesBackupper(
 		discoveryHelper,
 		client.NewDynamicFactory(clientPool),
 		actions,
+		itemActions,
+		transforms,
+		resourcePriorities,
+		resourceCollectionWorkers,
+		resourceListPageSize,
 	)
 }
 
@@ -505,26 +1408,43 @@ func newRestorer(
 	clientPool dynamic.ClientPool,
 	backupService cloudprovider.BackupService,
 	snapshotService cloudprovider.SnapshotService,
+	csiSnapshotter csi.Snapshotter,
 	resourcePriorities []string,
 	backupClient arkv1client.BackupsGetter,
 	kubeClient kubernetes.Interface,
+	resticRestorer restic.Restorer,
+	resticImage string,
+	podCommandExecutor podexec.Executor,
 ) (restore.Restorer, error) {
 	restorers := map[string]restorers.ResourceRestorer{
-		"persistentvolumes":      restorers.NewPersistentVolumeRestorer(snapshotService),
-		"persistentvolumeclaims": restorers.NewPersistentVolumeClaimRestorer(),
-		"services":               restorers.NewServiceRestorer(),
-		"namespaces":             restorers.NewNamespaceRestorer(),
-		"pods":                   restorers.NewPodRestorer(),
-		"jobs":                   restorers.NewJobRestorer(),
+		"persistentvolumes":                      restorers.NewPersistentVolumeRestorer(snapshotService),
+		"persistentvolumeclaims":                 restorers.NewPersistentVolumeClaimRestorer(csiSnapshotter),
+		"services":                               restorers.NewServiceRestorer(),
+		"namespaces":                             restorers.NewNamespaceRestorer(),
+		"pods":                                   restorers.NewPodRestorer(resticImage),
+		"jobs":                                   restorers.NewJobRestorer(),
+		"rolebindings.rbac.authorization.k8s.io": restorers.NewRoleBindingRestorer(),
+		"clusterrolebindings.rbac.authorization.k8s.io": restorers.NewRoleBindingRestorer(),
+	}
+
+	itemActionNames, itemActions := restore.RegisteredItemActions()
+	if len(itemActionNames) > 0 {
+		glog.Infof("Using restore item actions: %v", itemActionNames)
 	}
 
 	return restore.NewKubernetesRestorer(
 		discoveryHelper,
 		client.NewDynamicFactory(clientPool),
 		restorers,
+		itemActions,
 		backupService,
 		resourcePriorities,
 		backupClient,
 		kubeClient.CoreV1().Namespaces(),
+		kubeClient.CoreV1(),
+		resticRestorer,
+		kubeClient.CoreV1(),
+		podCommandExecutor,
+		snapshotService != nil,
 	)
 }
@@ -34,6 +34,7 @@ func TestApplyConfigDefaults(t *testing.T) {
 	assert.Equal(t, defaultBackupSyncPeriod, c.BackupSyncPeriod.Duration)
 	assert.Equal(t, defaultScheduleSyncPeriod, c.ScheduleSyncPeriod.Duration)
 	assert.Equal(t, defaultResourcePriorities, c.ResourcePriorities)
+	assert.Equal(t, v1.DefaultBackupStorageLocation, c.DefaultBackupStorageLocation)
 
 	// make sure defaulting doesn't overwrite real values
 	c.GCSyncPeriod.Duration = 5 * time.Minute
@@ -48,3 +49,87 @@ func TestApplyConfigDefaults(t *testing.T) {
 	assert.Equal(t, 3*time.Minute, c.ScheduleSyncPeriod.Duration)
 	assert.Equal(t, []string{"a", "b"}, c.ResourcePriorities)
 }
+
+func TestStorageLocationBuckets(t *testing.T) {
+	location := func(bucket, prefix string) *v1.BackupStorageLocation {
+		return &v1.BackupStorageLocation{
+			Spec: v1.BackupStorageLocationSpec{
+				ObjectStorageProviderConfig: v1.ObjectStorageProviderConfig{Bucket: bucket, Prefix: prefix},
+			},
+		}
+	}
+
+	readOnly := location("bucket-2", "")
+	readOnly.Spec.AccessMode = v1.BackupStorageLocationAccessModeReadOnly
+
+	invalidAccessMode := location("bucket-2", "")
+	invalidAccessMode.Spec.AccessMode = "WriteOnly"
+
+	tests := []struct {
+		name        string
+		locations   map[string]*v1.BackupStorageLocation
+		expected    map[string]string
+		expectedErr string
+	}{
+		{
+			name:      "buckets are keyed by location name",
+			locations: map[string]*v1.BackupStorageLocation{"default": location("bucket-1", ""), "secondary": readOnly},
+			expected:  map[string]string{"default": "bucket-1", "secondary": "bucket-2"},
+		},
+		{
+			name:      "locations can share a bucket under different prefixes",
+			locations: map[string]*v1.BackupStorageLocation{"default": location("bucket-1", "a"), "secondary": location("bucket-1", "/b/")},
+			expected:  map[string]string{"default": "bucket-1/a", "secondary": "bucket-1/b"},
+		},
+		{
+			name:        "location without a bucket is an error",
+			locations:   map[string]*v1.BackupStorageLocation{"default": location("bucket-1", ""), "secondary": location("", "")},
+			expectedErr: "backup storage location secondary must specify a bucket",
+		},
+		{
+			name:        "location with an invalid access mode is an error",
+			locations:   map[string]*v1.BackupStorageLocation{"default": location("bucket-1", ""), "secondary": invalidAccessMode},
+			expectedErr: `backup storage location secondary has invalid access mode "WriteOnly"; it must be ReadWrite or ReadOnly`,
+		},
+		{
+			name:        "locations sharing a bucket without prefixes is an error",
+			locations:   map[string]*v1.BackupStorageLocation{"default": location("bucket-1", ""), "secondary": location("bucket-1", "")},
+			expectedErr: "backup storage location secondary uses the same bucket as default, and neither is under a prefix the other isn't",
+		},
+		{
+			name:        "location under another's prefix is an error",
+			locations:   map[string]*v1.BackupStorageLocation{"default": location("bucket-1", "a"), "secondary": location("bucket-1", "a/b")},
+			expectedErr: "backup storage location secondary uses the same bucket as default, and neither is under a prefix the other isn't",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			buckets, err := storageLocationBuckets(test.locations)
+			if test.expectedErr != "" {
+				assert.EqualError(t, err, test.expectedErr)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected, buckets)
+		})
+	}
+}
+
+func TestControllerOptionsValidate(t *testing.T) {
+	assert.NoError(t, defaultControllerOptions().validate())
+
+	opts := defaultControllerOptions()
+	opts.restoreWorkers = 4
+	opts.informerResyncPeriod = 30 * time.Minute
+	assert.NoError(t, opts.validate())
+
+	opts = defaultControllerOptions()
+	opts.gcWorkers = 0
+	assert.EqualError(t, opts.validate(), "--gc-workers must be at least 1")
+
+	opts = defaultControllerOptions()
+	opts.informerResyncPeriod = -time.Minute
+	assert.EqualError(t, opts.validate(), "--informer-resync-period must not be negative")
+}
@@ -0,0 +1,113 @@
+/*
+Copyright 2017 Heptio Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package backupdiff
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+
+	api "github.com/heptio/ark/pkg/apis/ark/v1"
+	"github.com/heptio/ark/pkg/backup"
+)
+
+// Archive holds the contents of a backup tarball.
+type Archive struct {
+	// Items maps the path of each item in the tarball to its JSON.
+	Items map[string][]byte
+
+	// Index is the tarball's item index, which lists every item in the backup, including
+	// those of an incremental backup that are stored in its ancestors. It's nil for backups
+	// taken before indexes were written.
+	Index map[string]string
+}
+
+// ReadArchive reads the items and item index from a gzip-compressed backup tarball.
+func ReadArchive(r io.Reader) (*Archive, error) {
+	gzr, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	defer gzr.Close()
+
+	archive := &Archive{
+		Items: make(map[string][]byte),
+	}
+
+	tr := tar.NewReader(gzr)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return archive, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		if header.Name == api.ItemIndexFile {
+			archive.Index = make(map[string]string)
+			if err := json.NewDecoder(tr).Decode(&archive.Index); err != nil {
+				return nil, fmt.Errorf("error reading item index: %v", err)
+			}
+			continue
+		}
+
+		if _, ok := backup.ItemForPath(header.Name); !ok {
+			continue
+		}
+
+		data, err := ioutil.ReadAll(tr)
+		if err != nil {
+			return nil, fmt.Errorf("error reading %s: %v", header.Name, err)
+		}
+		archive.Items[header.Name] = data
+	}
+}
+
+// Layer returns the items of a backup given its archive and those of its ancestors, ordered from
+// the original full backup to the backup itself, the same way a restore does: each archive's
+// items are layered over those of the archives before it, and if the last archive has an index,
+// any items it doesn't list are removed.
+func Layer(archives ...*Archive) map[string][]byte {
+	items := make(map[string][]byte)
+	if len(archives) == 0 {
+		return items
+	}
+
+	for _, archive := range archives {
+		for path, data := range archive.Items {
+			items[path] = data
+		}
+	}
+
+	if index := archives[len(archives)-1].Index; index != nil {
+		for path := range items {
+			if _, found := index[path]; !found {
+				delete(items, path)
+			}
+		}
+	}
+
+	return items
+}
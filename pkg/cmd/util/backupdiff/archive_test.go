@@ -0,0 +1,124 @@
+/*
+Copyright 2017 Heptio Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package backupdiff
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTarball returns a gzip-compressed tarball of the given files.
+func newTarball(t *testing.T, files map[string]string) *bytes.Buffer {
+	buf := new(bytes.Buffer)
+	gzw := gzip.NewWriter(buf)
+	tw := tar.NewWriter(gzw)
+
+	for name, contents := range files {
+		require.NoError(t, tw.WriteHeader(&tar.Header{
+			Name:     name,
+			Size:     int64(len(contents)),
+			Typeflag: tar.TypeReg,
+			Mode:     0755,
+		}))
+		_, err := tw.Write([]byte(contents))
+		require.NoError(t, err)
+	}
+
+	require.NoError(t, tw.Close())
+	require.NoError(t, gzw.Close())
+	return buf
+}
+
+func TestReadArchive(t *testing.T) {
+	archive, err := ReadArchive(newTarball(t, map[string]string{
+		"index.json":                   `{"cluster/namespaces/ns-1.json": "1"}`,
+		"cluster/namespaces/ns-1.json": `{"kind": "Namespace"}`,
+		"metadata/checksums.json":      `{}`,
+	}))
+	require.NoError(t, err)
+
+	assert.Equal(t, map[string][]byte{"cluster/namespaces/ns-1.json": []byte(`{"kind": "Namespace"}`)}, archive.Items)
+	assert.Equal(t, map[string]string{"cluster/namespaces/ns-1.json": "1"}, archive.Index)
+
+	archive, err = ReadArchive(newTarball(t, map[string]string{
+		"cluster/namespaces/ns-1.json": `{"kind": "Namespace"}`,
+	}))
+	require.NoError(t, err)
+	assert.Nil(t, archive.Index)
+
+	_, err = ReadArchive(bytes.NewBufferString("not a tarball"))
+	assert.Error(t, err)
+}
+
+func TestLayer(t *testing.T) {
+	tests := []struct {
+		name     string
+		archives []*Archive
+		expected map[string][]byte
+	}{
+		{
+			name:     "no archives",
+			expected: map[string][]byte{},
+		},
+		{
+			name: "full backup without an index",
+			archives: []*Archive{
+				{Items: map[string][]byte{"cluster/namespaces/a.json": []byte("a")}},
+			},
+			expected: map[string][]byte{"cluster/namespaces/a.json": []byte("a")},
+		},
+		{
+			name: "incremental backup overrides changed items and drops deleted ones",
+			archives: []*Archive{
+				{
+					Items: map[string][]byte{
+						"namespaces/a/configmaps/unchanged.json": []byte("1"),
+						"namespaces/a/configmaps/changed.json":   []byte("2"),
+						"namespaces/a/configmaps/deleted.json":   []byte("3"),
+					},
+				},
+				{
+					Items: map[string][]byte{
+						"namespaces/a/configmaps/changed.json": []byte("4"),
+						"namespaces/a/configmaps/added.json":   []byte("5"),
+					},
+					Index: map[string]string{
+						"namespaces/a/configmaps/unchanged.json": "1",
+						"namespaces/a/configmaps/changed.json":   "4",
+						"namespaces/a/configmaps/added.json":     "5",
+					},
+				},
+			},
+			expected: map[string][]byte{
+				"namespaces/a/configmaps/unchanged.json": []byte("1"),
+				"namespaces/a/configmaps/changed.json":   []byte("4"),
+				"namespaces/a/configmaps/added.json":     []byte("5"),
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.expected, Layer(test.archives...))
+		})
+	}
+}
@@ -0,0 +1,164 @@
+/*
+Copyright 2017 Heptio Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package backupdiff
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/heptio/ark/pkg/backup"
+)
+
+// ignoredMetadata are the fields of an item's metadata that the API server sets and updates on
+// its own, so differences in them don't reflect changes to the item's configuration.
+var ignoredMetadata = []string{
+	"creationTimestamp",
+	"generation",
+	"resourceVersion",
+	"selfLink",
+	"uid",
+}
+
+// Change is an item that's in both backups but differs between them.
+type Change struct {
+	backup.Item `json:",inline"`
+
+	// Fields are the paths of the fields that differ, e.g. "spec.replicas". Lists are compared
+	// as a whole, so a change to an element of one is reported as a change to the list.
+	Fields []string `json:"fields"`
+}
+
+// Result is the difference between two backups.
+type Result struct {
+	Added   []backup.Item `json:"added"`
+	Removed []backup.Item `json:"removed"`
+	Changed []Change      `json:"changed"`
+}
+
+// Empty returns true if the backups have the same items.
+func (r *Result) Empty() bool {
+	return len(r.Added) == 0 && len(r.Removed) == 0 && len(r.Changed) == 0
+}
+
+// Diff compares the items of two backups, as returned by Layer, and returns the items that were
+// added to, removed from, or changed in the new backup, sorted by resource, namespace, and name.
+func Diff(old, new map[string][]byte) (*Result, error) {
+	result := &Result{
+		Added:   []backup.Item{},
+		Removed: []backup.Item{},
+		Changed: []Change{},
+	}
+
+	for path, newData := range new {
+		item, _ := backup.ItemForPath(path)
+
+		oldData, found := old[path]
+		if !found {
+			result.Added = append(result.Added, item)
+			continue
+		}
+
+		fields, err := changedItemFields(oldData, newData)
+		if err != nil {
+			return nil, fmt.Errorf("error comparing %s: %v", path, err)
+		}
+		if len(fields) > 0 {
+			result.Changed = append(result.Changed, Change{Item: item, Fields: fields})
+		}
+	}
+
+	for path := range old {
+		if _, found := new[path]; !found {
+			item, _ := backup.ItemForPath(path)
+			result.Removed = append(result.Removed, item)
+		}
+	}
+
+	sort.Slice(result.Added, func(i, j int) bool { return backup.ItemLess(result.Added[i], result.Added[j]) })
+	sort.Slice(result.Removed, func(i, j int) bool { return backup.ItemLess(result.Removed[i], result.Removed[j]) })
+	sort.Slice(result.Changed, func(i, j int) bool { return backup.ItemLess(result.Changed[i].Item, result.Changed[j].Item) })
+
+	return result, nil
+}
+
+func changedItemFields(oldData, newData []byte) ([]string, error) {
+	var oldItem, newItem map[string]interface{}
+	if err := json.Unmarshal(oldData, &oldItem); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(newData, &newItem); err != nil {
+		return nil, err
+	}
+
+	for _, item := range []map[string]interface{}{oldItem, newItem} {
+		if metadata, ok := item["metadata"].(map[string]interface{}); ok {
+			for _, field := range ignoredMetadata {
+				delete(metadata, field)
+			}
+		}
+	}
+
+	return changedFields("", oldItem, newItem), nil
+}
+
+// changedFields returns the paths, under prefix, of the fields that differ between two decoded
+// JSON values. Objects are compared field by field; anything else, including lists, as a whole.
+func changedFields(prefix string, old, new interface{}) []string {
+	oldMap, oldIsMap := old.(map[string]interface{})
+	newMap, newIsMap := new.(map[string]interface{})
+	if !oldIsMap || !newIsMap {
+		if reflect.DeepEqual(old, new) {
+			return nil
+		}
+		return []string{prefix}
+	}
+
+	keys := make(map[string]bool)
+	for key := range oldMap {
+		keys[key] = true
+	}
+	for key := range newMap {
+		keys[key] = true
+	}
+
+	sorted := make([]string, 0, len(keys))
+	for key := range keys {
+		sorted = append(sorted, key)
+	}
+	sort.Strings(sorted)
+
+	var fields []string
+	for _, key := range sorted {
+		fields = append(fields, changedFields(fieldPath(prefix, key), oldMap[key], newMap[key])...)
+	}
+	return fields
+}
+
+// fieldPath appends key to prefix, in brackets if it contains a dot, like the keys of labels and
+// annotations often do.
+func fieldPath(prefix, key string) string {
+	if strings.Contains(key, ".") {
+		return fmt.Sprintf("%s[%s]", prefix, key)
+	}
+	if prefix == "" {
+		return key
+	}
+	return prefix + "." + key
+}
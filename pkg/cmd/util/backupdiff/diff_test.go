@@ -0,0 +1,82 @@
+/*
+Copyright 2017 Heptio Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package backupdiff
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/heptio/ark/pkg/backup"
+)
+
+func TestDiff(t *testing.T) {
+	old := map[string][]byte{
+		"cluster/namespaces/removed.json": []byte(`{"metadata": {"name": "removed"}}`),
+		"namespaces/a/configmaps/same.json": []byte(`{
+			"metadata": {"name": "same", "resourceVersion": "1", "uid": "1"},
+			"data": {"key": "value"}
+		}`),
+		"namespaces/a/deployments.apps/web.json": []byte(`{
+			"metadata": {"name": "web", "labels": {"app": "web"}, "annotations": {"ark.heptio.com/x": "1"}},
+			"spec": {"replicas": 1, "paused": true, "template": {"spec": {"containers": [{"image": "web:1"}]}}}
+		}`),
+	}
+	new := map[string][]byte{
+		"namespaces/b/configmaps/added.json": []byte(`{"metadata": {"name": "added"}}`),
+		"namespaces/a/configmaps/same.json": []byte(`{
+			"metadata": {"name": "same", "resourceVersion": "2", "uid": "2"},
+			"data": {"key": "value"}
+		}`),
+		"namespaces/a/deployments.apps/web.json": []byte(`{
+			"metadata": {"name": "web", "labels": {"app": "web", "tier": "front"}, "annotations": {"ark.heptio.com/x": "2"}},
+			"spec": {"replicas": 3, "template": {"spec": {"containers": [{"image": "web:2"}]}}}
+		}`),
+	}
+
+	result, err := Diff(old, new)
+	require.NoError(t, err)
+
+	assert.Equal(t, &Result{
+		Added:   []backup.Item{{Resource: "configmaps", Namespace: "b", Name: "added"}},
+		Removed: []backup.Item{{Resource: "namespaces", Name: "removed"}},
+		Changed: []Change{
+			{
+				Item: backup.Item{Resource: "deployments.apps", Namespace: "a", Name: "web"},
+				Fields: []string{
+					"metadata.annotations[ark.heptio.com/x]",
+					"metadata.labels.tier",
+					"spec.paused",
+					"spec.replicas",
+					"spec.template.spec.containers",
+				},
+			},
+		},
+	}, result)
+	assert.False(t, result.Empty())
+
+	result, err = Diff(old, old)
+	require.NoError(t, err)
+	assert.True(t, result.Empty())
+
+	_, err = Diff(
+		map[string][]byte{"cluster/namespaces/a.json": []byte(`{}`)},
+		map[string][]byte{"cluster/namespaces/a.json": []byte(`not json`)},
+	)
+	assert.Error(t, err)
+}
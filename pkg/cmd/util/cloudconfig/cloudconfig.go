@@ -0,0 +1,155 @@
+/*
+Copyright 2017 Heptio Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package cloudconfig parses the cloud provider configuration of Ark's storage and snapshot
+// locations from the key=value pairs given to the CLI.
+package cloudconfig
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	api "github.com/heptio/ark/pkg/apis/ark/v1"
+)
+
+// Keys describes the configuration keys of each cloud provider, for command help.
+const Keys = `  aws:    region, availabilityZone, disableSSL, s3ForcePathStyle, s3Url, kmsKeyId
+  gcp:    project, zone
+  azure:  location, apiTimeout`
+
+// Parse returns the configuration of the named cloud provider, with the keys of values set. An
+// empty provider has no configuration. It returns an error for unknown keys and invalid values.
+func Parse(provider string, values map[string]string) (api.CloudProviderConfig, error) {
+	var config api.CloudProviderConfig
+
+	switch provider {
+	case "":
+		return config, nil
+	case "aws":
+		config.AWS = new(api.AWSConfig)
+	case "gcp":
+		config.GCP = new(api.GCPConfig)
+	case "azure":
+		config.Azure = new(api.AzureConfig)
+	default:
+		return config, fmt.Errorf("unknown cloud provider %q", provider)
+	}
+	fields := configFields(&config)
+
+	for key, value := range values {
+		switch field := fields[key].(type) {
+		case *string:
+			*field = value
+		case *bool:
+			b, err := strconv.ParseBool(value)
+			if err != nil {
+				return config, fmt.Errorf("invalid value for %s: %v", key, err)
+			}
+			*field = b
+		case *metav1.Duration:
+			d, err := time.ParseDuration(value)
+			if err != nil {
+				return config, fmt.Errorf("invalid value for %s: %v", key, err)
+			}
+			field.Duration = d
+		default:
+			return config, fmt.Errorf("unknown %s config key %q; valid keys are %s", provider, key, strings.Join(sortedKeys(fields), ", "))
+		}
+	}
+
+	return config, nil
+}
+
+// Describe returns config's settings as comma-separated key=value pairs, omitting those that
+// aren't set, or "<none>" if there aren't any.
+func Describe(config api.CloudProviderConfig) string {
+	fields := configFields(&config)
+
+	var pairs []string
+	for _, key := range sortedKeys(fields) {
+		var value string
+		switch field := fields[key].(type) {
+		case *string:
+			value = *field
+		case *bool:
+			if *field {
+				value = "true"
+			}
+		case *metav1.Duration:
+			if field.Duration > 0 {
+				value = field.Duration.String()
+			}
+		}
+
+		if value != "" {
+			pairs = append(pairs, key+"="+value)
+		}
+	}
+
+	if len(pairs) == 0 {
+		return "<none>"
+	}
+	return strings.Join(pairs, ",")
+}
+
+// configFields returns pointers to the fields of config's cloud provider configuration, keyed by
+// their config keys. A plugin's config is free-form, so its fields are its values.
+func configFields(config *api.CloudProviderConfig) map[string]interface{} {
+	switch {
+	case config.Plugin != nil:
+		fields := make(map[string]interface{}, len(config.Plugin.Config))
+		for key, value := range config.Plugin.Config {
+			value := value
+			fields[key] = &value
+		}
+		return fields
+	case config.AWS != nil:
+		return map[string]interface{}{
+			"region":           &config.AWS.Region,
+			"availabilityZone": &config.AWS.AvailabilityZone,
+			"disableSSL":       &config.AWS.DisableSSL,
+			"s3ForcePathStyle": &config.AWS.S3ForcePathStyle,
+			"s3Url":            &config.AWS.S3Url,
+			"kmsKeyId":         &config.AWS.KMSKeyID,
+		}
+	case config.GCP != nil:
+		return map[string]interface{}{
+			"project": &config.GCP.Project,
+			"zone":    &config.GCP.Zone,
+		}
+	case config.Azure != nil:
+		return map[string]interface{}{
+			"location":   &config.Azure.Location,
+			"apiTimeout": &config.Azure.APITimeout,
+		}
+	default:
+		return nil
+	}
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
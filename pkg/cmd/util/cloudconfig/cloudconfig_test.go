@@ -0,0 +1,87 @@
+/*
+Copyright 2017 Heptio Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloudconfig
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	api "github.com/heptio/ark/pkg/apis/ark/v1"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name          string
+		provider      string
+		values        map[string]string
+		expected      api.CloudProviderConfig
+		expectedError string
+	}{
+		{
+			name:     "no provider",
+			expected: api.CloudProviderConfig{},
+		},
+		{
+			name:     "aws",
+			provider: "aws",
+			values:   map[string]string{"region": "us-west-2", "s3ForcePathStyle": "true"},
+			expected: api.CloudProviderConfig{AWS: &api.AWSConfig{Region: "us-west-2", S3ForcePathStyle: true}},
+		},
+		{
+			name:     "azure",
+			provider: "azure",
+			values:   map[string]string{"location": "westus", "apiTimeout": "5m"},
+			expected: api.CloudProviderConfig{Azure: &api.AzureConfig{Location: "westus", APITimeout: metav1.Duration{Duration: 5 * time.Minute}}},
+		},
+		{
+			name:          "unknown key",
+			provider:      "gcp",
+			values:        map[string]string{"region": "us-east1"},
+			expectedError: `unknown gcp config key "region"; valid keys are project, zone`,
+		},
+		{
+			name:          "invalid value",
+			provider:      "aws",
+			values:        map[string]string{"disableSSL": "maybe"},
+			expectedError: `invalid value for disableSSL: strconv.ParseBool: parsing "maybe": invalid syntax`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			config, err := Parse(test.provider, test.values)
+			if test.expectedError != "" {
+				assert.EqualError(t, err, test.expectedError)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, config)
+		})
+	}
+}
+
+func TestDescribe(t *testing.T) {
+	assert.Equal(t, "<none>", Describe(api.CloudProviderConfig{}))
+	assert.Equal(t, "region=us-west-2,s3ForcePathStyle=true", Describe(api.CloudProviderConfig{AWS: &api.AWSConfig{Region: "us-west-2", S3ForcePathStyle: true}}))
+	assert.Equal(t, "project=my-project", Describe(api.CloudProviderConfig{GCP: &api.GCPConfig{Project: "my-project"}}))
+	assert.Equal(t, "endpoint=https://minio:9000,region=minio", Describe(api.CloudProviderConfig{Plugin: &api.PluginConfig{Name: "minio", Config: map[string]string{"region": "minio", "endpoint": "https://minio:9000"}}}))
+}
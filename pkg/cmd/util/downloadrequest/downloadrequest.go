@@ -0,0 +1,94 @@
+/*
+Copyright 2017 Heptio Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package downloadrequest
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+
+	"github.com/heptio/ark/pkg/apis/ark/v1"
+	arkclientv1 "github.com/heptio/ark/pkg/generated/clientset/typed/ark/v1"
+)
+
+// Stream creates a DownloadRequest in namespace for the file of the given kind belonging to the
+// named backup or restore, waits for the server to process it, and copies the file to w. Logs are
+// decompressed; backup contents are written as the gzipped tarball stored in object storage.
+func Stream(client arkclientv1.DownloadRequestsGetter, namespace, name string, kind v1.DownloadTargetKind, w io.Writer, timeout time.Duration) error {
+	req := &v1.DownloadRequest{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: namespace,
+			Name:      fmt.Sprintf("%s-%s", name, time.Now().Format("20060102150405")),
+		},
+		Spec: v1.DownloadRequestSpec{
+			Target: v1.DownloadTarget{
+				Kind: kind,
+				Name: name,
+			},
+		},
+	}
+
+	requests := client.DownloadRequests(req.Namespace)
+
+	req, err := requests.Create(req)
+	if err != nil {
+		return err
+	}
+	defer requests.Delete(req.Name, nil)
+
+	err = wait.PollImmediate(250*time.Millisecond, timeout, func() (bool, error) {
+		req, err = requests.Get(req.Name, metav1.GetOptions{})
+		if err != nil {
+			return false, err
+		}
+		return req.Status.Phase == v1.DownloadRequestPhaseProcessed && req.Status.DownloadURL != "", nil
+	})
+	if err == wait.ErrWaitTimeout {
+		return fmt.Errorf("timed out waiting for download URL")
+	}
+	if err != nil {
+		return err
+	}
+
+	resp, err := http.Get(req.Status.DownloadURL)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("request failed: %s", resp.Status)
+	}
+
+	reader := io.Reader(resp.Body)
+	if kind != v1.DownloadTargetKindBackupContents {
+		gzipReader, err := gzip.NewReader(resp.Body)
+		if err != nil {
+			return err
+		}
+		defer gzipReader.Close()
+		reader = gzipReader
+	}
+
+	_, err = io.Copy(w, reader)
+	return err
+}
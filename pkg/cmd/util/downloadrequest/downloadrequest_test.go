@@ -0,0 +1,107 @@
+/*
+Copyright 2017 Heptio Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package downloadrequest
+
+import (
+	"bytes"
+	"compress/gzip"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	core "k8s.io/client-go/testing"
+
+	"github.com/heptio/ark/pkg/apis/ark/v1"
+	"github.com/heptio/ark/pkg/generated/clientset/fake"
+	. "github.com/heptio/ark/pkg/util/test"
+)
+
+func gzipped(t *testing.T, s string) []byte {
+	buf := new(bytes.Buffer)
+	gzw := gzip.NewWriter(buf)
+	_, err := gzw.Write([]byte(s))
+	require.NoError(t, err)
+	require.NoError(t, gzw.Close())
+	return buf.Bytes()
+}
+
+func TestStream(t *testing.T) {
+	logTarget := v1.DownloadTarget{Kind: v1.DownloadTargetKindBackupLog, Name: "backup-1"}
+	contentsTarget := v1.DownloadTarget{Kind: v1.DownloadTargetKindBackupContents, Name: "backup-1"}
+	missingTarget := v1.DownloadTarget{Kind: v1.DownloadTargetKindRestoreLog, Name: "restore-1"}
+	contents := gzipped(t, "contents")
+
+	tests := []struct {
+		name          string
+		target        v1.DownloadTarget
+		expected      []byte
+		expectedError string
+	}{
+		{
+			name:     "logs are decompressed",
+			target:   logTarget,
+			expected: []byte("log line\n"),
+		},
+		{
+			name:     "backup contents are written as they're stored",
+			target:   contentsTarget,
+			expected: contents,
+		},
+		{
+			name:          "files missing from object storage fail",
+			target:        missingTarget,
+			expectedError: "request failed: 404 Not Found",
+		},
+		{
+			name:          "requests that aren't processed in time fail",
+			target:        v1.DownloadTarget{Kind: v1.DownloadTargetKindBackupLog, Name: "backup-2"},
+			expectedError: "timed out waiting for download URL",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			client := fake.NewSimpleClientset()
+			server := NewFakeDownloadServer(client, map[v1.DownloadTarget][]byte{
+				logTarget:      gzipped(t, "log line\n"),
+				contentsTarget: contents,
+				missingTarget:  nil,
+			})
+			defer server.Close()
+
+			buf := new(bytes.Buffer)
+			err := Stream(client.ArkV1(), "ark-ns", test.target.Name, test.target.Kind, buf, 100*time.Millisecond)
+			if test.expectedError != "" {
+				assert.EqualError(t, err, test.expectedError)
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, test.expected, buf.Bytes())
+			}
+
+			require.Len(t, server.Requests, 1)
+			assert.Equal(t, "ark-ns", server.Requests[0].Namespace)
+			assert.Equal(t, test.target, server.Requests[0].Spec.Target)
+
+			// the request is deleted once it's no longer needed
+			actions := client.Actions()
+			require.NotEmpty(t, actions)
+			assert.Equal(t, core.NewDeleteAction(v1.SchemeGroupVersion.WithResource("downloadrequests"), "ark-ns", server.Requests[0].Name), actions[len(actions)-1])
+		})
+	}
+}
@@ -0,0 +1,260 @@
+/*
+Copyright 2017 Heptio Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package output
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/heptio/ark/pkg/apis/ark/v1"
+)
+
+// BackupLogMessages are the warning and error messages from a backup's log.
+type BackupLogMessages struct {
+	Warnings []string
+	Errors   []string
+}
+
+// ParseBackupLog reads the warning and error messages from a backup's (decompressed) log. Lines
+// that aren't in the backup log's format are ignored.
+func ParseBackupLog(r io.Reader) (*BackupLogMessages, error) {
+	messages := new(BackupLogMessages)
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		level, msg, ok := parseBackupLogLine(scanner.Text())
+		if !ok {
+			continue
+		}
+
+		switch level {
+		case "warning":
+			messages.Warnings = append(messages.Warnings, msg)
+		case "error":
+			messages.Errors = append(messages.Errors, msg)
+		}
+	}
+
+	return messages, scanner.Err()
+}
+
+// parseBackupLogLine returns the level and message of a backup log line, which is formatted as
+// time="<time>" level=<level> msg="<message>".
+func parseBackupLogLine(line string) (level, msg string, ok bool) {
+	levelStart := strings.Index(line, " level=")
+	msgStart := strings.Index(line, " msg=")
+	if levelStart < 0 || msgStart < levelStart {
+		return "", "", false
+	}
+
+	level = line[levelStart+len(" level=") : msgStart]
+	msg, err := strconv.Unquote(line[msgStart+len(" msg="):])
+	if err != nil {
+		return "", "", false
+	}
+
+	return level, msg, true
+}
+
+// DescribeBackup returns a human-readable description of backup. If logMessages isn't nil, the
+// warning and error messages in it are listed along with the backup's counts of them.
+func DescribeBackup(backup *v1.Backup, logMessages *BackupLogMessages) string {
+	buf := new(bytes.Buffer)
+	w := tabwriter.NewWriter(buf, 0, 8, 2, ' ', 0)
+
+	fmt.Fprintf(w, "Name:\t%s\n", backup.Name)
+	fmt.Fprintf(w, "Namespace:\t%s\n", backup.Namespace)
+	fmt.Fprintf(w, "Labels:\t%s\n", describeMap(backup.Labels))
+	fmt.Fprintf(w, "Annotations:\t%s\n", describeMap(backup.Annotations))
+	fmt.Fprintln(w)
+
+	phase := backup.Status.Phase
+	if phase == "" {
+		phase = v1.BackupPhaseNew
+	}
+	fmt.Fprintf(w, "Phase:\t%s\n", phase)
+	if len(backup.Status.ValidationErrors) > 0 {
+		fmt.Fprintln(w, "Validation errors:")
+		for _, err := range backup.Status.ValidationErrors {
+			fmt.Fprintf(w, "  %s\n", err)
+		}
+	}
+	fmt.Fprintln(w)
+
+	describeBackupSpec(w, backup.Spec)
+	fmt.Fprintln(w)
+
+	fmt.Fprintf(w, "Created:\t%s\n", describeTime(backup.CreationTimestamp.Time))
+	fmt.Fprintf(w, "Completed:\t%s\n", describeTime(backup.Status.CompletionTimestamp.Time))
+	fmt.Fprintf(w, "Expiration:\t%s\n", describeTime(backup.Status.Expiration.Time))
+	fmt.Fprintln(w)
+
+	if progress := backup.Status.Progress; progress != nil {
+		fmt.Fprintf(w, "Items backed up:\t%d of %d\n", progress.ItemsBackedUp, progress.TotalItems)
+		fmt.Fprintf(w, "Volume snapshots completed:\t%d of %d\n", progress.VolumeSnapshotsCompleted, progress.VolumeSnapshotsAttempted)
+		fmt.Fprintf(w, "Restic backups completed:\t%d of %d\n", progress.PodVolumeBackupsCompleted, progress.PodVolumeBackupsAttempted)
+		fmt.Fprintln(w)
+	}
+
+	fmt.Fprintf(w, "Warnings:\t%d\n", backup.Status.Warnings)
+	if logMessages != nil {
+		describeMessages(w, logMessages.Warnings)
+	}
+	fmt.Fprintf(w, "Errors:\t%d\n", backup.Status.Errors)
+	if logMessages != nil {
+		describeMessages(w, logMessages.Errors)
+	}
+	fmt.Fprintln(w)
+
+	describeVolumeBackups(w, backup.Status.VolumeBackups)
+
+	w.Flush()
+	return buf.String()
+}
+
+func describeBackupSpec(w io.Writer, spec v1.BackupSpec) {
+	fmt.Fprintln(w, "Namespaces:")
+	fmt.Fprintf(w, "  Included:\t%s\n", describeList(spec.IncludedNamespaces, "*"))
+	fmt.Fprintf(w, "  Excluded:\t%s\n", describeList(spec.ExcludedNamespaces, "<none>"))
+	fmt.Fprintln(w)
+
+	fmt.Fprintln(w, "Resources:")
+	fmt.Fprintf(w, "  Included:\t%s\n", describeList(spec.IncludedResources, "*"))
+	fmt.Fprintf(w, "  Excluded:\t%s\n", describeList(spec.ExcludedResources, "<none>"))
+	fmt.Fprintln(w)
+
+	selector := "<none>"
+	if spec.LabelSelector != nil {
+		selector = metav1.FormatLabelSelector(spec.LabelSelector)
+	}
+	fmt.Fprintf(w, "Label selector:\t%s\n", selector)
+
+	includeClusterResources := "true"
+	if spec.IncludeClusterResources != nil {
+		includeClusterResources = strconv.FormatBool(*spec.IncludeClusterResources)
+	}
+	fmt.Fprintf(w, "Include cluster resources:\t%s\n", includeClusterResources)
+	fmt.Fprintf(w, "Exclude completed pods:\t%t\n", spec.ExcludeCompletedPods)
+	fmt.Fprintln(w)
+
+	snapshotVolumes := "auto"
+	if spec.SnapshotVolumes != nil {
+		snapshotVolumes = strconv.FormatBool(*spec.SnapshotVolumes)
+	}
+	fmt.Fprintf(w, "Snapshot PVs:\t%s\n", snapshotVolumes)
+	fmt.Fprintf(w, "Move volume data:\t%t\n", spec.MoveVolumeData)
+	fmt.Fprintf(w, "Uploader:\t%s\n", describeString(spec.UploaderType, "<default>"))
+	fmt.Fprintf(w, "Storage location:\t%s\n", describeString(spec.StorageLocation, "<default>"))
+	snapshotLocations := describeString(spec.VolumeSnapshotLocation, "<default>")
+	if len(spec.VolumeSnapshotLocations) > 0 {
+		snapshotLocations = describeList(spec.VolumeSnapshotLocations, "")
+	}
+	fmt.Fprintf(w, "Volume snapshot locations:\t%s\n", snapshotLocations)
+	fmt.Fprintf(w, "Parent backup:\t%s\n", describeString(spec.ParentBackup, "<none>"))
+	fmt.Fprintf(w, "TTL:\t%s\n", spec.TTL.Duration)
+}
+
+func describeVolumeBackups(w io.Writer, volumeBackups map[string]*v1.VolumeBackupInfo) {
+	if len(volumeBackups) == 0 {
+		fmt.Fprintln(w, "Persistent Volumes:\t<none included>")
+		return
+	}
+
+	fmt.Fprintln(w, "Persistent Volumes:")
+
+	pvNames := make([]string, 0, len(volumeBackups))
+	for pvName := range volumeBackups {
+		pvNames = append(pvNames, pvName)
+	}
+	sort.Strings(pvNames)
+
+	for _, pvName := range pvNames {
+		info := volumeBackups[pvName]
+
+		fmt.Fprintf(w, "  %s:\n", pvName)
+		fmt.Fprintf(w, "    Snapshot ID:\t%s\n", info.SnapshotID)
+		if info.CSISnapshot != nil {
+			fmt.Fprintf(w, "    CSI driver:\t%s\n", info.CSISnapshot.Driver)
+		} else {
+			fmt.Fprintf(w, "    Type:\t%s\n", describeString(info.Type, "<none>"))
+			iops := "<none>"
+			if info.Iops != nil {
+				iops = strconv.FormatInt(*info.Iops, 10)
+			}
+			fmt.Fprintf(w, "    IOPS:\t%s\n", iops)
+			if info.Location != "" {
+				fmt.Fprintf(w, "    Location:\t%s\n", info.Location)
+			}
+		}
+
+		status := "Completed"
+		if info.FreezeError != "" {
+			status = fmt.Sprintf("Completed without freezing the volume: %s", info.FreezeError)
+		}
+		fmt.Fprintf(w, "    Status:\t%s\n", status)
+	}
+}
+
+func describeMessages(w io.Writer, messages []string) {
+	for _, msg := range messages {
+		fmt.Fprintf(w, "  %s\n", msg)
+	}
+}
+
+func describeMap(m map[string]string) string {
+	if len(m) == 0 {
+		return "<none>"
+	}
+
+	pairs := make([]string, 0, len(m))
+	for k, v := range m {
+		pairs = append(pairs, fmt.Sprintf("%s=%s", k, v))
+	}
+	sort.Strings(pairs)
+
+	return strings.Join(pairs, ",")
+}
+
+func describeList(list []string, empty string) string {
+	if len(list) == 0 {
+		return empty
+	}
+	return strings.Join(list, ", ")
+}
+
+func describeString(s, empty string) string {
+	if s == "" {
+		return empty
+	}
+	return s
+}
+
+func describeTime(t time.Time) string {
+	if t.IsZero() {
+		return "<n/a>"
+	}
+	return t.String()
+}
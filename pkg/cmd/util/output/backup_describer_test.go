@@ -0,0 +1,131 @@
+/*
+Copyright 2017 Heptio Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package output
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/heptio/ark/pkg/apis/ark/v1"
+)
+
+func TestParseBackupLog(t *testing.T) {
+	log := strings.Join([]string{
+		`time="2017-10-01T12:00:00Z" level=info msg="Starting backup"`,
+		`time="2017-10-01T12:00:01Z" level=warning msg="unable to resolve resource \"foo\""`,
+		`not a log line`,
+		`time="2017-10-01T12:00:02Z" level=error msg="Backup heptio-ark/backup1: error listing pods"`,
+		`time="2017-10-01T12:00:03Z" level=error msg=unquoted`,
+	}, "\n")
+
+	messages, err := ParseBackupLog(strings.NewReader(log))
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{`unable to resolve resource "foo"`}, messages.Warnings)
+	assert.Equal(t, []string{"Backup heptio-ark/backup1: error listing pods"}, messages.Errors)
+}
+
+func TestDescribeBackup(t *testing.T) {
+	iops := int64(100)
+	snapshotVolumes := true
+
+	backup := &v1.Backup{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:         v1.DefaultNamespace,
+			Name:              "backup1",
+			Labels:            map[string]string{"b": "2", "a": "1"},
+			CreationTimestamp: metav1.NewTime(time.Date(2017, 10, 1, 12, 0, 0, 0, time.UTC)),
+		},
+		Spec: v1.BackupSpec{
+			IncludedNamespaces:      []string{"ns-1", "ns-2"},
+			ExcludedResources:       []string{"secrets"},
+			SnapshotVolumes:         &snapshotVolumes,
+			StorageLocation:         "secondary",
+			VolumeSnapshotLocations: []string{"us-west", "gcp-west"},
+			TTL:                     metav1.Duration{Duration: 24 * time.Hour},
+		},
+		Status: v1.BackupStatus{
+			Phase:               v1.BackupPhasePartiallyFailed,
+			CompletionTimestamp: metav1.NewTime(time.Date(2017, 10, 1, 12, 5, 0, 0, time.UTC)),
+			Warnings:            1,
+			Errors:              1,
+			Progress: &v1.BackupProgress{
+				TotalItems:               10,
+				ItemsBackedUp:            9,
+				VolumeSnapshotsAttempted: 2,
+				VolumeSnapshotsCompleted: 2,
+			},
+			VolumeBackups: map[string]*v1.VolumeBackupInfo{
+				"pv-2": {SnapshotID: "snap-2", CSISnapshot: &v1.CSISnapshotInfo{Driver: "csi.example.com"}},
+				"pv-1": {SnapshotID: "snap-1", Type: "gp2", Iops: &iops, FreezeError: "fsfreeze failed", Location: "us-west"},
+			},
+		},
+	}
+
+	tests := []struct {
+		name        string
+		logMessages *BackupLogMessages
+		expected    []string
+	}{
+		{
+			name: "without log messages",
+			expected: []string{
+				"Name:         backup1\n",
+				"Labels:       a=1,b=2\n",
+				"Annotations:  <none>\n",
+				"Phase:  PartiallyFailed\n",
+				"  Included:  ns-1, ns-2\n  Excluded:  <none>\n",
+				"  Included:  *\n  Excluded:  secrets\n",
+				"Snapshot PVs:               true\n",
+				"Storage location:           secondary\n",
+				"Volume snapshot locations:  us-west, gcp-west\n",
+				"Expiration:  <n/a>\n",
+				"Items backed up:             9 of 10\n",
+				"Volume snapshots completed:  2 of 2\n",
+				"Warnings:  1\nErrors:    1\n",
+				"  pv-1:\n    Snapshot ID:  snap-1\n    Type:         gp2\n    IOPS:         100\n    Location:     us-west\n    Status:       Completed without freezing the volume: fsfreeze failed\n",
+				"  pv-2:\n    Snapshot ID:  snap-2\n    CSI driver:   csi.example.com\n    Status:       Completed\n",
+			},
+		},
+		{
+			name: "with log messages",
+			logMessages: &BackupLogMessages{
+				Warnings: []string{"a warning"},
+				Errors:   []string{"an error"},
+			},
+			expected: []string{
+				"Warnings:  1\n  a warning\nErrors:  1\n  an error\n",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			description := DescribeBackup(backup, test.logMessages)
+
+			for _, s := range test.expected {
+				assert.Contains(t, description, s)
+			}
+		})
+	}
+}
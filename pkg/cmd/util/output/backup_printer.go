@@ -21,6 +21,7 @@ import (
 	"io"
 	"regexp"
 	"sort"
+	"strings"
 	"time"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -30,17 +31,69 @@ import (
 )
 
 var (
-	backupColumns = []string{"NAME", "STATUS", "CREATED", "EXPIRES", "SELECTOR"}
+	backupColumns     = []string{"NAME", "STATUS", "CREATED", "EXPIRES", "SELECTOR"}
+	backupWideColumns = []string{"WARNINGS", "ERRORS", "EXPIRATION", "STORAGE LOCATION", "SNAPSHOT LOCATIONS"}
 )
 
-func printBackupList(list *v1.BackupList, w io.Writer, options printers.PrintOptions) error {
-	sortBackupsByPrefixAndTimestamp(list)
+// BackupSortFields are the fields that SortBackups can sort backups by.
+var BackupSortFields = []string{"name", "created", "expiration", "phase"}
 
-	for i := range list.Items {
-		if err := printBackup(&list.Items[i], w, options); err != nil {
+// backupListPrinter returns a function that prints a list of backups sorted by sortBy, as
+// SortBackups sorts them.
+func backupListPrinter(sortBy string) func(*v1.BackupList, io.Writer, printers.PrintOptions) error {
+	return func(list *v1.BackupList, w io.Writer, options printers.PrintOptions) error {
+		if err := SortBackups(list, sortBy); err != nil {
 			return err
 		}
+
+		for i := range list.Items {
+			if err := printBackup(&list.Items[i], w, options); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// SortBackups sorts list by one of BackupSortFields, in ascending order, with ties broken by
+// name. Backups that don't expire are sorted after those that do. If field is empty, backups
+// are sorted by name, except that those of each schedule are sorted newest first.
+func SortBackups(list *v1.BackupList, field string) error {
+	var less func(a, b *v1.Backup) bool
+
+	switch field {
+	case "":
+		sortBackupsByPrefixAndTimestamp(list)
+		return nil
+	case "name":
+		less = func(a, b *v1.Backup) bool { return false }
+	case "created":
+		less = func(a, b *v1.Backup) bool { return a.CreationTimestamp.Before(b.CreationTimestamp) }
+	case "expiration":
+		less = func(a, b *v1.Backup) bool {
+			aExpiration, bExpiration := backupExpiration(a), backupExpiration(b)
+			if aExpiration.IsZero() || bExpiration.IsZero() {
+				return !aExpiration.IsZero() && bExpiration.IsZero()
+			}
+			return aExpiration.Before(bExpiration)
+		}
+	case "phase":
+		less = func(a, b *v1.Backup) bool { return backupPhase(a) < backupPhase(b) }
+	default:
+		return fmt.Errorf("invalid sort field %q; valid fields are %s", field, strings.Join(BackupSortFields, ", "))
 	}
+
+	sort.Slice(list.Items, func(i, j int) bool {
+		a, b := &list.Items[i], &list.Items[j]
+		if less(a, b) {
+			return true
+		}
+		if less(b, a) {
+			return false
+		}
+		return a.Name < b.Name
+	})
+
 	return nil
 }
 
@@ -78,20 +131,24 @@ func printBackup(backup *v1.Backup, w io.Writer, options printers.PrintOptions)
 		}
 	}
 
-	expiration := backup.Status.Expiration.Time
-	if expiration.IsZero() && backup.Spec.TTL.Duration > 0 {
-		expiration = backup.CreationTimestamp.Add(backup.Spec.TTL.Duration)
-	}
-
-	status := backup.Status.Phase
-	if status == "" {
-		status = v1.BackupPhaseNew
-	}
+	expiration := backupExpiration(backup)
+	status := backupPhase(backup)
 
 	if _, err := fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s", name, status, backup.CreationTimestamp.Time, humanReadableTimeFromNow(expiration), metav1.FormatLabelSelector(backup.Spec.LabelSelector)); err != nil {
 		return err
 	}
 
+	if options.Wide {
+		expirationTime := "n/a"
+		if !expiration.IsZero() {
+			expirationTime = expiration.String()
+		}
+
+		if _, err := fmt.Fprintf(w, "\t%d\t%d\t%s\t%s\t%s", backup.Status.Warnings, backup.Status.Errors, expirationTime, locationOrDefault(backup.Spec.StorageLocation), snapshotLocations(backup.Spec)); err != nil {
+			return err
+		}
+	}
+
 	if _, err := fmt.Fprint(w, printers.AppendLabels(backup.Labels, options.ColumnLabels)); err != nil {
 		return err
 	}
@@ -100,6 +157,42 @@ func printBackup(backup *v1.Backup, w io.Writer, options printers.PrintOptions)
 	return err
 }
 
+// backupExpiration returns when a backup expires, which for backups that haven't run yet is
+// estimated from their TTL. It returns the zero time if the backup doesn't expire.
+func backupExpiration(backup *v1.Backup) time.Time {
+	expiration := backup.Status.Expiration.Time
+	if expiration.IsZero() && backup.Spec.TTL.Duration > 0 {
+		expiration = backup.CreationTimestamp.Add(backup.Spec.TTL.Duration)
+	}
+	return expiration
+}
+
+// backupPhase returns a backup's phase, which is New if the server hasn't set one yet.
+func backupPhase(backup *v1.Backup) v1.BackupPhase {
+	if backup.Status.Phase == "" {
+		return v1.BackupPhaseNew
+	}
+	return backup.Status.Phase
+}
+
+// locationOrDefault returns the name of a storage or snapshot location for display, where ""
+// is the server's default location.
+func locationOrDefault(location string) string {
+	if location == "" {
+		return "default"
+	}
+	return location
+}
+
+// snapshotLocations returns the names of the volume snapshot locations that a backup with spec
+// takes its snapshots in, for display.
+func snapshotLocations(spec v1.BackupSpec) string {
+	if len(spec.VolumeSnapshotLocations) > 0 {
+		return strings.Join(spec.VolumeSnapshotLocations, ",")
+	}
+	return locationOrDefault(spec.VolumeSnapshotLocation)
+}
+
 func humanReadableTimeFromNow(when time.Time) string {
 	if when.IsZero() {
 		return "n/a"
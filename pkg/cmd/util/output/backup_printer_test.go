@@ -17,11 +17,15 @@ limitations under the License.
 package output
 
 import (
+	"bytes"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/kubernetes/pkg/printers"
 
 	"github.com/heptio/ark/pkg/apis/ark/v1"
 )
@@ -87,3 +91,91 @@ func TestSortBackups(t *testing.T) {
 		})
 	}
 }
+
+func TestSortBackupsByField(t *testing.T) {
+	now := time.Date(2017, 1, 2, 3, 4, 5, 0, time.UTC)
+	backup := func(name string, phase v1.BackupPhase, created time.Time, expiration time.Time) v1.Backup {
+		return v1.Backup{
+			ObjectMeta: metav1.ObjectMeta{Name: name, CreationTimestamp: metav1.NewTime(created)},
+			Status:     v1.BackupStatus{Phase: phase, Expiration: metav1.NewTime(expiration)},
+		}
+	}
+
+	list := func() *v1.BackupList {
+		return &v1.BackupList{Items: []v1.Backup{
+			backup("c", v1.BackupPhaseCompleted, now, now.Add(time.Hour)),
+			backup("a", v1.BackupPhaseFailed, now.Add(time.Minute), time.Time{}),
+			backup("d", "", now.Add(-time.Minute), now.Add(-time.Hour)),
+			backup("b", v1.BackupPhaseCompleted, now, now.Add(2*time.Hour)),
+		}}
+	}
+
+	tests := []struct {
+		field    string
+		expected []string
+	}{
+		{field: "name", expected: []string{"a", "b", "c", "d"}},
+		{field: "created", expected: []string{"d", "b", "c", "a"}},
+		{field: "expiration", expected: []string{"d", "c", "b", "a"}},
+		{field: "phase", expected: []string{"b", "c", "a", "d"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.field, func(t *testing.T) {
+			backups := list()
+			require.NoError(t, SortBackups(backups, test.field))
+
+			var names []string
+			for _, backup := range backups.Items {
+				names = append(names, backup.Name)
+			}
+			assert.Equal(t, test.expected, names)
+		})
+	}
+
+	assert.Error(t, SortBackups(list(), "size"))
+}
+
+func TestPrintBackupWide(t *testing.T) {
+	created := time.Date(2017, 10, 1, 12, 0, 0, 0, time.UTC)
+	backup := &v1.Backup{
+		ObjectMeta: metav1.ObjectMeta{Name: "backup1", CreationTimestamp: metav1.NewTime(created)},
+		Spec: v1.BackupSpec{
+			StorageLocation: "secondary",
+		},
+		Status: v1.BackupStatus{
+			Phase:      v1.BackupPhasePartiallyFailed,
+			Expiration: metav1.NewTime(created.Add(24 * time.Hour)),
+			Warnings:   2,
+			Errors:     1,
+		},
+	}
+
+	tests := []struct {
+		name     string
+		wide     bool
+		expected string
+	}{
+		{
+			name:     "table",
+			expected: "backup1\tPartiallyFailed\t2017-10-01 12:00:00 +0000 UTC\t",
+		},
+		{
+			name:     "wide",
+			wide:     true,
+			expected: "\t<none>\t2\t1\t2017-10-02 12:00:00 +0000 UTC\tsecondary\tdefault",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			buf := new(bytes.Buffer)
+			require.NoError(t, printBackup(backup, buf, printers.PrintOptions{Wide: test.wide}))
+
+			assert.Contains(t, buf.String(), test.expected)
+			if !test.wide {
+				assert.NotContains(t, buf.String(), "secondary")
+			}
+		})
+	}
+}
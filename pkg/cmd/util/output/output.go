@@ -36,7 +36,7 @@ import (
 // BindFlags defines a set of output-specific flags within the provided
 // FlagSet.
 func BindFlags(flags *pflag.FlagSet) {
-	flags.StringP("output", "o", "table", "Output display format. For create commands, display the object but do not send it to the server. Valid formats are 'table', 'json', and 'yaml'.")
+	flags.StringP("output", "o", "table", "Output display format. For create commands, display the object but do not send it to the server. Valid formats are 'table', 'wide', 'json', and 'yaml'; 'wide' is a table with additional columns.")
 	labelColumns := flag.NewStringArray()
 	flags.Var(&labelColumns, "label-columns", "a comma-separated list of labels to be displayed as columns")
 	flags.Bool("show-labels", false, "show labels in the last column")
@@ -83,9 +83,9 @@ func ValidateFlags(cmd *cobra.Command) error {
 func validateOutputFlag(cmd *cobra.Command) error {
 	output := GetOutputFlagValue(cmd)
 	switch output {
-	case "", "table", "json", "yaml":
+	case "", "table", "wide", "json", "yaml":
 	default:
-		return fmt.Errorf("invalid output format %q - valid values are 'table', 'json', and 'yaml'", output)
+		return fmt.Errorf("invalid output format %q - valid values are 'table', 'wide', 'json', and 'yaml'", output)
 	}
 	return nil
 }
@@ -99,13 +99,13 @@ func PrintWithFormat(c *cobra.Command, obj runtime.Object) (bool, error) {
 	}
 
 	switch format {
-	case "table":
+	case "table", "wide":
 		return printTable(c, obj)
 	case "json", "yaml":
 		return printEncoded(obj, format)
 	}
 
-	return false, fmt.Errorf("unsupported output format %q; valid values are 'table', 'json', and 'yaml'", format)
+	return false, fmt.Errorf("unsupported output format %q; valid values are 'table', 'wide', 'json', and 'yaml'", format)
 }
 
 func printEncoded(obj runtime.Object, format string) (bool, error) {
@@ -136,12 +136,12 @@ func printTable(cmd *cobra.Command, obj runtime.Object) (bool, error) {
 		return false, err
 	}
 
-	printer.Handler(backupColumns, nil, printBackup)
-	printer.Handler(backupColumns, nil, printBackupList)
-	printer.Handler(restoreColumns, nil, printRestore)
-	printer.Handler(restoreColumns, nil, printRestoreList)
-	printer.Handler(scheduleColumns, nil, printSchedule)
-	printer.Handler(scheduleColumns, nil, printScheduleList)
+	printer.Handler(backupColumns, backupWideColumns, printBackup)
+	printer.Handler(backupColumns, backupWideColumns, backupListPrinter(flag.GetOptionalStringFlag(cmd, "sort-by")))
+	printer.Handler(restoreColumns, restoreWideColumns, printRestore)
+	printer.Handler(restoreColumns, restoreWideColumns, printRestoreList)
+	printer.Handler(scheduleColumns, scheduleWideColumns, printSchedule)
+	printer.Handler(scheduleColumns, scheduleWideColumns, printScheduleList)
 
 	err = printer.PrintObj(obj, os.Stdout)
 	if err != nil {
@@ -161,6 +161,7 @@ func NewPrinter(cmd *cobra.Command) (*printers.HumanReadablePrinter, error) {
 
 	options := printers.PrintOptions{
 		NoHeaders:    flag.GetOptionalBoolFlag(cmd, "no-headers"),
+		Wide:         GetOutputFlagValue(cmd) == "wide",
 		ShowLabels:   GetShowLabelsValue(cmd),
 		ColumnLabels: GetLabelColumnsValues(cmd),
 	}
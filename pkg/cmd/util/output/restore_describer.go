@@ -0,0 +1,202 @@
+/*
+Copyright 2017 Heptio Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package output
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"text/tabwriter"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/heptio/ark/pkg/apis/ark/v1"
+)
+
+// RestoreResults are the warning and error messages of a restore, as stored alongside its
+// backup.
+type RestoreResults struct {
+	Warnings v1.RestoreResult `json:"warnings"`
+	Errors   v1.RestoreResult `json:"errors"`
+}
+
+// DescribeRestore returns a human-readable description of restore. If results isn't nil, the
+// messages in it are listed along with the restore's counts of its warnings and errors.
+// Otherwise any messages in the restore's status are, since they're only there when they
+// couldn't be stored alongside the backup.
+func DescribeRestore(restore *v1.Restore, results *RestoreResults) string {
+	buf := new(bytes.Buffer)
+	w := tabwriter.NewWriter(buf, 0, 8, 2, ' ', 0)
+
+	fmt.Fprintf(w, "Name:\t%s\n", restore.Name)
+	fmt.Fprintf(w, "Namespace:\t%s\n", restore.Namespace)
+	fmt.Fprintf(w, "Labels:\t%s\n", describeMap(restore.Labels))
+	fmt.Fprintf(w, "Annotations:\t%s\n", describeMap(restore.Annotations))
+	fmt.Fprintln(w)
+
+	phase := restore.Status.Phase
+	if phase == "" {
+		phase = v1.RestorePhaseNew
+	}
+	fmt.Fprintf(w, "Phase:\t%s\n", phase)
+	if len(restore.Status.ValidationErrors) > 0 {
+		fmt.Fprintln(w, "Validation errors:")
+		for _, err := range restore.Status.ValidationErrors {
+			fmt.Fprintf(w, "  %s\n", err)
+		}
+	}
+	fmt.Fprintln(w)
+
+	describeRestoreSpec(w, restore.Spec)
+	fmt.Fprintln(w)
+
+	fmt.Fprintf(w, "Created:\t%s\n", describeTime(restore.CreationTimestamp.Time))
+	fmt.Fprintln(w)
+
+	warnings, errors := &restore.Status.Warnings, &restore.Status.Errors
+	if results != nil {
+		warnings, errors = &results.Warnings, &results.Errors
+	}
+	describeRestoreResult(w, "Warnings", restore.Status.WarningCounts, *warnings)
+	describeRestoreResult(w, "Errors", restore.Status.ErrorCounts, *errors)
+	fmt.Fprintln(w)
+
+	describeRestoredVolumes(w, restore.Status.RestoredVolumes)
+	fmt.Fprintln(w)
+
+	fmt.Fprintf(w, "Hooks attempted:\t%d\n", restore.Status.HooksAttempted)
+	fmt.Fprintf(w, "Hooks failed:\t%d\n", restore.Status.HooksFailed)
+	fmt.Fprintln(w)
+
+	fmt.Fprintf(w, "Restic restores attempted:\t%d\n", restore.Status.PodVolumeRestoresAttempted)
+	fmt.Fprintf(w, "Restic restores failed:\t%d\n", restore.Status.PodVolumeRestoresFailed)
+
+	w.Flush()
+	return buf.String()
+}
+
+func describeRestoreSpec(w io.Writer, spec v1.RestoreSpec) {
+	fmt.Fprintf(w, "Backup:\t%s\n", spec.BackupName)
+	fmt.Fprintln(w)
+
+	fmt.Fprintf(w, "Namespaces:\t%s\n", describeList(spec.Namespaces, "*"))
+	fmt.Fprintf(w, "Namespace mappings:\t%s\n", describeMap(spec.NamespaceMapping))
+	fmt.Fprintf(w, "Storage class mappings:\t%s\n", describeMap(spec.StorageClassMapping))
+	fmt.Fprintf(w, "Node mappings:\t%s\n", describeMap(spec.NodeMapping))
+	fmt.Fprintf(w, "Registry mappings:\t%s\n", describeMap(spec.RegistryMapping))
+	fmt.Fprintln(w)
+
+	selector := "<none>"
+	if spec.LabelSelector != nil {
+		selector = metav1.FormatLabelSelector(spec.LabelSelector)
+	}
+	fmt.Fprintf(w, "Label selector:\t%s\n", selector)
+
+	includeClusterResources := "auto"
+	if spec.IncludeClusterResources != nil {
+		includeClusterResources = strconv.FormatBool(*spec.IncludeClusterResources)
+	}
+	fmt.Fprintf(w, "Include cluster resources:\t%s\n", includeClusterResources)
+
+	restorePVs := "auto"
+	if spec.RestorePVs != nil {
+		restorePVs = strconv.FormatBool(*spec.RestorePVs)
+	}
+	fmt.Fprintf(w, "Restore PVs:\t%s\n", restorePVs)
+	fmt.Fprintf(w, "Unsnapshotted volume policy:\t%s\n", describeString(string(spec.UnsnapshottedVolumePolicy), string(v1.UnsnapshottedVolumePolicyRetain)))
+
+	fmt.Fprintf(w, "Existing resource policy:\t%s\n", describeString(string(spec.ExistingResourcePolicy), string(v1.ExistingResourcePolicySkip)))
+	if len(spec.ExistingResourcePolicyOverrides) > 0 {
+		overrides := make(map[string]string, len(spec.ExistingResourcePolicyOverrides))
+		for resource, policy := range spec.ExistingResourcePolicyOverrides {
+			overrides[resource] = string(policy)
+		}
+		fmt.Fprintf(w, "Existing resource policy overrides:\t%s\n", describeMap(overrides))
+	}
+	fmt.Fprintf(w, "Resource modifiers:\t%s\n", describeString(spec.ResourceModifiers, "<none>"))
+	fmt.Fprintf(w, "Prune:\t%t\n", spec.Prune)
+	fmt.Fprintf(w, "Preview:\t%t\n", spec.Preview)
+}
+
+// describeRestoreResult writes the counts of a restore's warnings or errors, for Ark itself,
+// cluster-scoped resources, and each namespace, followed by their messages if there are any in
+// result. Restores processed by older servers have no counts, so they're taken from result.
+func describeRestoreResult(w io.Writer, title string, counts v1.RestoreResultCounts, result v1.RestoreResult) {
+	if counts.Total() == 0 {
+		counts = v1.RestoreResultCounts{Ark: len(result.Ark), Cluster: len(result.Cluster)}
+		for ns, messages := range result.Namespaces {
+			if counts.Namespaces == nil {
+				counts.Namespaces = make(map[string]int)
+			}
+			counts.Namespaces[ns] = len(messages)
+		}
+	}
+
+	if counts.Total() == 0 {
+		fmt.Fprintf(w, "%s:\t<none>\n", title)
+		return
+	}
+
+	fmt.Fprintf(w, "%s:\n", title)
+	describeResultMessages(w, "  ", "Ark", counts.Ark, result.Ark)
+	describeResultMessages(w, "  ", "Cluster", counts.Cluster, result.Cluster)
+
+	namespaces := make([]string, 0, len(counts.Namespaces))
+	for ns := range counts.Namespaces {
+		namespaces = append(namespaces, ns)
+	}
+	sort.Strings(namespaces)
+
+	if len(namespaces) > 0 {
+		fmt.Fprintln(w, "  Namespaces:")
+	}
+	for _, ns := range namespaces {
+		describeResultMessages(w, "    ", ns, counts.Namespaces[ns], result.Namespaces[ns])
+	}
+}
+
+func describeResultMessages(w io.Writer, indent, label string, count int, messages []string) {
+	if count == 0 {
+		return
+	}
+
+	fmt.Fprintf(w, "%s%s:\t%d\n", indent, label, count)
+	for _, msg := range messages {
+		fmt.Fprintf(w, "%s  %s\n", indent, msg)
+	}
+}
+
+func describeRestoredVolumes(w io.Writer, volumes []v1.RestoredVolume) {
+	if len(volumes) == 0 {
+		fmt.Fprintln(w, "Restored volumes:\t<none>")
+		return
+	}
+
+	fmt.Fprintln(w, "Restored volumes:")
+	for _, volume := range volumes {
+		name := volume.Name
+		if volume.Namespace != "" {
+			name = volume.Namespace + "/" + name
+		}
+		if volume.Volume != "" {
+			name += " (" + volume.Volume + ")"
+		}
+		fmt.Fprintf(w, "  %s %s:\t%s from %s\n", volume.Resource, name, volume.Source, volume.Snapshot)
+	}
+}
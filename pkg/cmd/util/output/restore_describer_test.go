@@ -0,0 +1,118 @@
+/*
+Copyright 2017 Heptio Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package output
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/heptio/ark/pkg/apis/ark/v1"
+)
+
+func TestDescribeRestore(t *testing.T) {
+	restorePVs := true
+
+	restore := &v1.Restore{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:         v1.DefaultNamespace,
+			Name:              "restore-1",
+			CreationTimestamp: metav1.NewTime(time.Date(2017, 10, 1, 12, 0, 0, 0, time.UTC)),
+		},
+		Spec: v1.RestoreSpec{
+			BackupName:       "backup-1",
+			Namespaces:       []string{"ns-1", "ns-2"},
+			NamespaceMapping: map[string]string{"ns-1": "ns-3"},
+			RestorePVs:       &restorePVs,
+			ExistingResourcePolicyOverrides: map[string]v1.ExistingResourcePolicy{
+				"configmaps": v1.ExistingResourcePolicyReplace,
+			},
+		},
+		Status: v1.RestoreStatus{
+			Phase:         v1.RestorePhasePartiallyFailed,
+			WarningCounts: v1.RestoreResultCounts{Namespaces: map[string]int{"ns-3": 2}},
+			ErrorCounts:   v1.RestoreResultCounts{Cluster: 1},
+			RestoredVolumes: []v1.RestoredVolume{
+				{Source: v1.RestoredVolumeSourceSnapshot, Resource: "persistentvolumes", Name: "pv-1", Snapshot: "snap-1"},
+				{Source: v1.RestoredVolumeSourceRestic, Resource: "pods", Namespace: "ns-3", Name: "pod-1", Volume: "data", Snapshot: "abc123"},
+			},
+			HooksAttempted: 3,
+			HooksFailed:    1,
+		},
+	}
+
+	results := &RestoreResults{
+		Warnings: v1.RestoreResult{Namespaces: map[string][]string{"ns-3": {"warning 1", "warning 2"}}},
+		Errors:   v1.RestoreResult{Cluster: []string{"error 1"}},
+	}
+
+	tests := []struct {
+		name     string
+		restore  *v1.Restore
+		results  *RestoreResults
+		expected []string
+	}{
+		{
+			name:    "counts are described without results",
+			restore: restore,
+			expected: []string{
+				"Phase:  PartiallyFailed\n",
+				"Backup:  backup-1\n",
+				"Namespaces:              ns-1, ns-2\nNamespace mappings:      ns-1=ns-3\n",
+				"Restore PVs:                         true\n",
+				"Existing resource policy:            Skip\nExisting resource policy overrides:  configmaps=Replace\n",
+				"Warnings:\n  Namespaces:\n    ns-3:  2\nErrors:\n  Cluster:  1\n",
+				"Restored volumes:\n  persistentvolumes pv-1:  Snapshot from snap-1\n  pods ns-3/pod-1 (data):  Restic from abc123\n",
+				"Hooks attempted:  3\nHooks failed:     1\n",
+			},
+		},
+		{
+			name:    "messages are listed with results",
+			restore: restore,
+			results: results,
+			expected: []string{
+				"Warnings:\n  Namespaces:\n    ns-3:  2\n      warning 1\n      warning 2\nErrors:\n  Cluster:  1\n    error 1\n",
+			},
+		},
+		{
+			name: "older restores' counts come from their status messages",
+			restore: &v1.Restore{
+				Status: v1.RestoreStatus{
+					Errors: v1.RestoreResult{Ark: []string{"error 1"}},
+				},
+			},
+			expected: []string{
+				"Phase:  New\n",
+				"Warnings:  <none>\nErrors:\n  Ark:  1\n    error 1\n",
+				"Restored volumes:  <none>\n",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			description := DescribeRestore(test.restore, test.results)
+
+			for _, s := range test.expected {
+				assert.Contains(t, description, s)
+			}
+		})
+	}
+}
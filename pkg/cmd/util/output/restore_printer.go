@@ -19,6 +19,8 @@ package output
 import (
 	"fmt"
 	"io"
+	"strconv"
+	"strings"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/kubernetes/pkg/printers"
@@ -27,7 +29,8 @@ import (
 )
 
 var (
-	restoreColumns = []string{"NAME", "BACKUP", "STATUS", "WARNINGS", "ERRORS", "CREATED", "SELECTOR"}
+	restoreColumns     = []string{"NAME", "BACKUP", "STATUS", "WARNINGS", "ERRORS", "CREATED", "SELECTOR"}
+	restoreWideColumns = []string{"NAMESPACES", "RESTORE PVS", "VALIDATION ERRORS"}
 )
 
 func printRestoreList(list *v1.RestoreList, w io.Writer, options printers.PrintOptions) error {
@@ -53,18 +56,36 @@ func printRestore(restore *v1.Restore, w io.Writer, options printers.PrintOption
 		status = v1.RestorePhaseNew
 	}
 
-	warnings := len(restore.Status.Warnings.Ark) + len(restore.Status.Warnings.Cluster)
-	for _, w := range restore.Status.Warnings.Namespaces {
-		warnings += len(w)
+	// restores processed by older servers have their results, but not counts of them, in their
+	// status
+	warnings := restore.Status.WarningCounts.Total()
+	if warnings == 0 {
+		warnings = countMessages(restore.Status.Warnings)
 	}
-	errors := len(restore.Status.Errors.Ark) + len(restore.Status.Errors.Cluster)
-	for _, e := range restore.Status.Errors.Namespaces {
-		errors += len(e)
+	errors := restore.Status.ErrorCounts.Total()
+	if errors == 0 {
+		errors = countMessages(restore.Status.Errors)
 	}
 	if _, err := fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%d\t%s\t%s", name, restore.Spec.BackupName, status, warnings, errors, restore.CreationTimestamp.Time, metav1.FormatLabelSelector(restore.Spec.LabelSelector)); err != nil {
 		return err
 	}
 
+	if options.Wide {
+		namespaces := "*"
+		if len(restore.Spec.Namespaces) > 0 {
+			namespaces = strings.Join(restore.Spec.Namespaces, ",")
+		}
+
+		restorePVs := "auto"
+		if restore.Spec.RestorePVs != nil {
+			restorePVs = strconv.FormatBool(*restore.Spec.RestorePVs)
+		}
+
+		if _, err := fmt.Fprintf(w, "\t%s\t%s\t%d", namespaces, restorePVs, len(restore.Status.ValidationErrors)); err != nil {
+			return err
+		}
+	}
+
 	if _, err := fmt.Fprint(w, printers.AppendLabels(restore.Labels, options.ColumnLabels)); err != nil {
 		return err
 	}
@@ -72,3 +93,11 @@ func printRestore(restore *v1.Restore, w io.Writer, options printers.PrintOption
 	_, err := fmt.Fprint(w, printers.AppendAllLabels(options.ShowLabels, restore.Labels))
 	return err
 }
+
+func countMessages(result v1.RestoreResult) int {
+	count := len(result.Ark) + len(result.Cluster)
+	for _, messages := range result.Namespaces {
+		count += len(messages)
+	}
+	return count
+}
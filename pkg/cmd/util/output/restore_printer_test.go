@@ -0,0 +1,78 @@
+/*
+Copyright 2017 Heptio Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package output
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/kubernetes/pkg/printers"
+
+	"github.com/heptio/ark/pkg/apis/ark/v1"
+)
+
+func TestPrintRestoreCounts(t *testing.T) {
+	created := time.Date(2017, 10, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		status   v1.RestoreStatus
+		expected string
+	}{
+		{
+			name: "counts",
+			status: v1.RestoreStatus{
+				Phase:         v1.RestorePhasePartiallyFailed,
+				WarningCounts: v1.RestoreResultCounts{Cluster: 1, Namespaces: map[string]int{"ns-1": 2}},
+				ErrorCounts:   v1.RestoreResultCounts{Ark: 1},
+			},
+			expected: "restore-1\tbackup-1\tPartiallyFailed\t3\t1\t2017-10-01 12:00:00 +0000 UTC\t<none>\n",
+		},
+		{
+			name: "older restores' counts come from their status messages",
+			status: v1.RestoreStatus{
+				Phase:    v1.RestorePhaseCompleted,
+				Warnings: v1.RestoreResult{Namespaces: map[string][]string{"ns-1": {"warning 1", "warning 2"}}},
+				Errors:   v1.RestoreResult{Ark: []string{"error 1"}, Cluster: []string{"error 2"}},
+			},
+			expected: "restore-1\tbackup-1\tCompleted\t2\t2\t2017-10-01 12:00:00 +0000 UTC\t<none>\n",
+		},
+		{
+			name:     "no results",
+			expected: "restore-1\tbackup-1\tNew\t0\t0\t2017-10-01 12:00:00 +0000 UTC\t<none>\n",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			restore := &v1.Restore{
+				ObjectMeta: metav1.ObjectMeta{Name: "restore-1", CreationTimestamp: metav1.NewTime(created)},
+				Spec:       v1.RestoreSpec{BackupName: "backup-1"},
+				Status:     test.status,
+			}
+
+			buf := new(bytes.Buffer)
+			require.NoError(t, printRestore(restore, buf, printers.PrintOptions{}))
+			assert.Equal(t, test.expected, buf.String())
+		})
+	}
+}
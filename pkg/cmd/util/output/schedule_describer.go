@@ -0,0 +1,146 @@
+/*
+Copyright 2017 Heptio Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package output
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/heptio/ark/pkg/apis/ark/v1"
+)
+
+// DescribeSchedule returns a human-readable description of schedule, including its backup
+// template and the maxBackups most recent of backups, which are the Backups it created.
+func DescribeSchedule(schedule *v1.Schedule, backups []v1.Backup, maxBackups int) string {
+	buf := new(bytes.Buffer)
+	w := tabwriter.NewWriter(buf, 0, 8, 2, ' ', 0)
+
+	fmt.Fprintf(w, "Name:\t%s\n", schedule.Name)
+	fmt.Fprintf(w, "Namespace:\t%s\n", schedule.Namespace)
+	fmt.Fprintf(w, "Labels:\t%s\n", describeMap(schedule.Labels))
+	fmt.Fprintf(w, "Annotations:\t%s\n", describeMap(schedule.Annotations))
+	fmt.Fprintln(w)
+
+	phase := schedule.Status.Phase
+	if phase == "" {
+		phase = v1.SchedulePhaseNew
+	}
+	fmt.Fprintf(w, "Phase:\t%s\n", phase)
+	if len(schedule.Status.ValidationErrors) > 0 {
+		fmt.Fprintln(w, "Validation errors:")
+		for _, err := range schedule.Status.ValidationErrors {
+			fmt.Fprintf(w, "  %s\n", err)
+		}
+	}
+	fmt.Fprintf(w, "Paused:\t%t\n", schedule.Spec.Paused)
+	fmt.Fprintln(w)
+
+	fmt.Fprintf(w, "Schedule:\t%s\n", schedule.Spec.Schedule)
+	fmt.Fprintf(w, "Time zone:\t%s\n", describeString(schedule.Spec.Timezone, "<server's local time zone>"))
+	fmt.Fprintf(w, "Jitter:\t%s\n", schedule.Spec.Jitter.Duration)
+	concurrencyPolicy := schedule.Spec.ConcurrencyPolicy
+	if concurrencyPolicy == "" {
+		concurrencyPolicy = v1.ConcurrencyPolicyAllow
+	}
+	fmt.Fprintf(w, "Concurrency policy:\t%s\n", concurrencyPolicy)
+	fmt.Fprintf(w, "Backup name template:\t%s\n", describeString(schedule.Spec.BackupNameTemplate, "{schedule}-{timestamp}"))
+	fmt.Fprintf(w, "Retention:\t%s\n", describeRetention(schedule.Spec.Retention))
+	fmt.Fprintln(w)
+
+	fmt.Fprintln(w, "Backup template:")
+	describeBackupSpec(w, schedule.Spec.Template)
+	fmt.Fprintln(w)
+
+	fmt.Fprintf(w, "Created:\t%s\n", describeTime(schedule.CreationTimestamp.Time))
+	fmt.Fprintf(w, "Last backup:\t%s\n", describeTime(schedule.Status.LastBackup.Time))
+	fmt.Fprintf(w, "Last skipped run:\t%s\n", describeTime(schedule.Status.LastSkipped.Time))
+	nextRun := describeTime(schedule.Status.NextRunTime.Time)
+	if schedule.Spec.Paused {
+		nextRun = "<n/a, paused>"
+	}
+	fmt.Fprintf(w, "Next run:\t%s\n", nextRun)
+	fmt.Fprintln(w)
+
+	describeScheduleBackups(w, backups, maxBackups)
+
+	w.Flush()
+	return buf.String()
+}
+
+func describeRetention(retention *v1.RetentionPolicy) string {
+	if retention == nil {
+		return "<none, backups expire after their TTL>"
+	}
+
+	var rules []string
+	for _, rule := range []struct {
+		name  string
+		count int
+	}{
+		{"last", retention.Last},
+		{"daily", retention.Daily},
+		{"weekly", retention.Weekly},
+		{"monthly", retention.Monthly},
+		{"yearly", retention.Yearly},
+	} {
+		if rule.count > 0 {
+			rules = append(rules, fmt.Sprintf("%s=%d", rule.name, rule.count))
+		}
+	}
+
+	return describeList(rules, "<none>")
+}
+
+// describeScheduleBackups lists the max most recent of backups, newest first.
+func describeScheduleBackups(w io.Writer, backups []v1.Backup, max int) {
+	if len(backups) == 0 {
+		fmt.Fprintln(w, "Recent backups:\t<none>")
+		return
+	}
+
+	sorted := make([]v1.Backup, len(backups))
+	copy(sorted, backups)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[j].CreationTimestamp.Before(sorted[i].CreationTimestamp)
+	})
+
+	if len(sorted) > max {
+		sorted = sorted[:max]
+	}
+
+	fmt.Fprintf(w, "Recent backups (%d of %d):\n", len(sorted), len(backups))
+	for _, backup := range sorted {
+		phase := backup.Status.Phase
+		if phase == "" {
+			phase = v1.BackupPhaseNew
+		}
+
+		var counts []string
+		if backup.Status.Warnings > 0 {
+			counts = append(counts, fmt.Sprintf("%d warning(s)", backup.Status.Warnings))
+		}
+		if backup.Status.Errors > 0 {
+			counts = append(counts, fmt.Sprintf("%d error(s)", backup.Status.Errors))
+		}
+
+		fmt.Fprintf(w, "  %s\t%s\t%s\t%s\n", backup.Name, phase, describeTime(backup.CreationTimestamp.Time), strings.Join(counts, ", "))
+	}
+}
@@ -0,0 +1,118 @@
+/*
+Copyright 2017 Heptio Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package output
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/heptio/ark/pkg/apis/ark/v1"
+)
+
+func TestDescribeSchedule(t *testing.T) {
+	created := time.Date(2017, 10, 1, 12, 0, 0, 0, time.UTC)
+
+	newSchedule := func(paused bool) *v1.Schedule {
+		return &v1.Schedule{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace:         v1.DefaultNamespace,
+				Name:              "daily",
+				CreationTimestamp: metav1.NewTime(created),
+			},
+			Spec: v1.ScheduleSpec{
+				Schedule: "0 1 * * *",
+				Timezone: "America/New_York",
+				Paused:   paused,
+				Template: v1.BackupSpec{
+					IncludedNamespaces: []string{"ns-1"},
+					TTL:                metav1.Duration{Duration: 24 * time.Hour},
+				},
+				Retention: &v1.RetentionPolicy{Daily: 7, Weekly: 4},
+			},
+			Status: v1.ScheduleStatus{
+				Phase:       v1.SchedulePhaseEnabled,
+				LastBackup:  metav1.NewTime(created.Add(48 * time.Hour)),
+				NextRunTime: metav1.NewTime(created.Add(72 * time.Hour)),
+			},
+		}
+	}
+
+	backups := []v1.Backup{
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "daily-1", CreationTimestamp: metav1.NewTime(created.Add(24 * time.Hour))},
+			Status:     v1.BackupStatus{Phase: v1.BackupPhaseCompleted, Warnings: 2},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "daily-2", CreationTimestamp: metav1.NewTime(created.Add(48 * time.Hour))},
+			Status:     v1.BackupStatus{Phase: v1.BackupPhasePartiallyFailed, Errors: 1},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "daily-0", CreationTimestamp: metav1.NewTime(created)},
+			Status:     v1.BackupStatus{Phase: v1.BackupPhaseFailed},
+		},
+	}
+
+	tests := []struct {
+		name       string
+		schedule   *v1.Schedule
+		backups    []v1.Backup
+		maxBackups int
+		expected   []string
+	}{
+		{
+			name:       "enabled schedule lists its most recent backups",
+			schedule:   newSchedule(false),
+			backups:    backups,
+			maxBackups: 2,
+			expected: []string{
+				"Name:         daily\n",
+				"Phase:   Enabled\nPaused:  false\n",
+				"Schedule:              0 1 * * *\n",
+				"Time zone:             America/New_York\n",
+				"Concurrency policy:    Allow\n",
+				"Backup name template:  {schedule}-{timestamp}\n",
+				"Retention:             daily=7, weekly=4\n",
+				"  Included:  ns-1\n",
+				"Next run:          2017-10-04 12:00:00 +0000 UTC\n",
+				"Recent backups (2 of 3):\n  daily-2  PartiallyFailed  2017-10-03 12:00:00 +0000 UTC  1 error(s)\n  daily-1  Completed        2017-10-02 12:00:00 +0000 UTC  2 warning(s)\n",
+			},
+		},
+		{
+			name:     "paused schedule has no next run",
+			schedule: newSchedule(true),
+			expected: []string{
+				"Phase:   Enabled\nPaused:  true\n",
+				"Next run:          <n/a, paused>\n",
+				"Recent backups:  <none>\n",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			description := DescribeSchedule(test.schedule, test.backups, test.maxBackups)
+
+			for _, s := range test.expected {
+				assert.Contains(t, description, s)
+			}
+		})
+	}
+}
@@ -27,7 +27,8 @@ import (
 )
 
 var (
-	scheduleColumns = []string{"NAME", "STATUS", "CREATED", "SCHEDULE", "BACKUP TTL", "LAST BACKUP", "SELECTOR"}
+	scheduleColumns     = []string{"NAME", "STATUS", "CREATED", "SCHEDULE", "BACKUP TTL", "LAST BACKUP", "NEXT BACKUP", "SELECTOR"}
+	scheduleWideColumns = []string{"LAST BACKUP NAME", "CONCURRENCY POLICY", "STORAGE LOCATION", "SNAPSHOT LOCATIONS"}
 )
 
 func printScheduleList(list *v1.ScheduleList, w io.Writer, options printers.PrintOptions) error {
@@ -48,20 +49,39 @@ func printSchedule(schedule *v1.Schedule, w io.Writer, options printers.PrintOpt
 		}
 	}
 
-	status := schedule.Status.Phase
+	status := string(schedule.Status.Phase)
 	if status == "" {
-		status = v1.SchedulePhaseNew
+		status = string(v1.SchedulePhaseNew)
+	}
+	if schedule.Spec.Paused && schedule.Status.Phase != v1.SchedulePhaseFailedValidation {
+		status = "Paused"
+	}
+
+	cronSchedule := schedule.Spec.Schedule
+	if schedule.Spec.Timezone != "" {
+		cronSchedule = fmt.Sprintf("%s (%s)", cronSchedule, schedule.Spec.Timezone)
+	}
+
+	lastBackup := humanReadableTimeFromNow(schedule.Status.LastBackup.Time)
+	if schedule.Status.LastBackupPhase != "" {
+		lastBackup = fmt.Sprintf("%s (%s)", lastBackup, schedule.Status.LastBackupPhase)
+	}
+
+	nextBackup := humanReadableTimeFromNow(schedule.Status.NextRunTime.Time)
+	if schedule.Spec.Paused {
+		nextBackup = "n/a"
 	}
 
 	_, err := fmt.Fprintf(
 		w,
-		"%s\t%s\t%s\t%s\t%s\t%s\t%s",
+		"%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s",
 		name,
 		status,
 		schedule.CreationTimestamp.Time,
-		schedule.Spec.Schedule,
+		cronSchedule,
 		schedule.Spec.Template.TTL.Duration,
-		humanReadableTimeFromNow(schedule.Status.LastBackup.Time),
+		lastBackup,
+		nextBackup,
 		metav1.FormatLabelSelector(schedule.Spec.Template.LabelSelector),
 	)
 
@@ -69,6 +89,22 @@ func printSchedule(schedule *v1.Schedule, w io.Writer, options printers.PrintOpt
 		return err
 	}
 
+	if options.Wide {
+		lastBackupName := schedule.Status.LastBackupName
+		if lastBackupName == "" {
+			lastBackupName = "n/a"
+		}
+
+		concurrencyPolicy := schedule.Spec.ConcurrencyPolicy
+		if concurrencyPolicy == "" {
+			concurrencyPolicy = v1.ConcurrencyPolicyAllow
+		}
+
+		if _, err := fmt.Fprintf(w, "\t%s\t%s\t%s\t%s", lastBackupName, concurrencyPolicy, locationOrDefault(schedule.Spec.Template.StorageLocation), snapshotLocations(schedule.Spec.Template)); err != nil {
+			return err
+		}
+	}
+
 	if _, err := fmt.Fprint(w, printers.AppendLabels(schedule.Labels, options.ColumnLabels)); err != nil {
 		return err
 	}
@@ -0,0 +1,78 @@
+/*
+Copyright 2017 Heptio Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package progress
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"golang.org/x/crypto/ssh/terminal"
+
+	"k8s.io/apimachinery/pkg/util/clock"
+)
+
+// Line displays the progress of an operation being waited on, with the time elapsed since it
+// was created. On a terminal, the line is redrawn in place each time it's updated, so the
+// elapsed time keeps ticking; otherwise, a new line is written each time the progress changes,
+// so logs of the output stay readable.
+type Line struct {
+	w        io.Writer
+	terminal bool
+	clock    clock.Clock
+	start    time.Time
+	last     string
+}
+
+// NewLine returns a Line that writes to w.
+func NewLine(w io.Writer) *Line {
+	l := &Line{
+		w:     w,
+		clock: clock.RealClock{},
+	}
+	if f, ok := w.(*os.File); ok {
+		l.terminal = terminal.IsTerminal(int(f.Fd()))
+	}
+	l.start = l.clock.Now()
+
+	return l
+}
+
+// Update displays progress, a one-line summary of the operation's progress.
+func (l *Line) Update(progress string) {
+	elapsed := l.clock.Since(l.start) / time.Second * time.Second
+
+	if l.terminal {
+		// return to the start of the line and clear it before redrawing
+		fmt.Fprintf(l.w, "\r\033[K%s (elapsed: %s)", progress, elapsed)
+		l.last = progress
+		return
+	}
+
+	if progress != l.last {
+		fmt.Fprintf(l.w, "%s (elapsed: %s)\n", progress, elapsed)
+		l.last = progress
+	}
+}
+
+// Done finishes the line, so that anything written after it starts on a new line.
+func (l *Line) Done() {
+	if l.terminal && l.last != "" {
+		fmt.Fprintln(l.w)
+	}
+}
@@ -0,0 +1,69 @@
+/*
+Copyright 2017 Heptio Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package progress
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"k8s.io/apimachinery/pkg/util/clock"
+)
+
+func TestLine(t *testing.T) {
+	tests := []struct {
+		name     string
+		terminal bool
+		expected string
+	}{
+		{
+			name:     "lines are written when progress changes",
+			expected: "Phase: New (elapsed: 0s)\nPhase: InProgress (elapsed: 1s)\nPhase: Completed (elapsed: 3s)\n",
+		},
+		{
+			name:     "line is redrawn on a terminal",
+			terminal: true,
+			expected: "\r\033[KPhase: New (elapsed: 0s)" +
+				"\r\033[KPhase: InProgress (elapsed: 1s)" +
+				"\r\033[KPhase: InProgress (elapsed: 2s)" +
+				"\r\033[KPhase: Completed (elapsed: 3s)\n",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			buf := new(bytes.Buffer)
+			fakeClock := clock.NewFakeClock(time.Now())
+
+			line := NewLine(buf)
+			line.terminal = test.terminal
+			line.clock = fakeClock
+			line.start = fakeClock.Now()
+
+			for _, progress := range []string{"Phase: New", "Phase: InProgress", "Phase: InProgress", "Phase: Completed"} {
+				line.Update(progress)
+				// elapsed times are truncated to seconds
+				fakeClock.Step(1100 * time.Millisecond)
+			}
+			line.Done()
+
+			assert.Equal(t, test.expected, buf.String())
+		})
+	}
+}
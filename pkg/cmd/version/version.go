@@ -17,22 +17,98 @@ limitations under the License.
 package version
 
 import (
+	"errors"
 	"fmt"
+	"io"
+	"os"
+	"time"
 
 	"github.com/spf13/cobra"
 
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+
+	api "github.com/heptio/ark/pkg/apis/ark/v1"
 	"github.com/heptio/ark/pkg/buildinfo"
+	"github.com/heptio/ark/pkg/client"
+	"github.com/heptio/ark/pkg/cmd"
+	arkv1client "github.com/heptio/ark/pkg/generated/clientset/typed/ark/v1"
 )
 
-func NewCommand() *cobra.Command {
+func NewCommand(f client.Factory) *cobra.Command {
+	clientOnly := false
+	timeout := 5 * time.Second
+
 	c := &cobra.Command{
 		Use:   "version",
-		Short: "Print the ark version and associated image",
-		Run: func(cmd *cobra.Command, args []string) {
-			fmt.Println(buildinfo.Version)
-			fmt.Println("Configured docker image:", buildinfo.DockerImage)
+		Short: "Print the ark client and server versions",
+		Long: `Print the version and git commit of the ark client, and those of the Ark server running in the cluster,
+which are reported through a ServerStatusRequest. A warning is printed if they don't match.`,
+		Run: func(c *cobra.Command, args []string) {
+			PrintClientVersion(os.Stdout)
+			if clientOnly {
+				return
+			}
+
+			arkClient, err := f.Client()
+			cmd.CheckError(err)
+
+			cmd.CheckError(PrintServerVersion(os.Stdout, arkClient.ArkV1(), f.Namespace(), timeout))
 		},
 	}
 
+	c.Flags().BoolVar(&clientOnly, "client-only", clientOnly, "only print the client version, without contacting the server")
+	c.Flags().DurationVar(&timeout, "timeout", timeout, "maximum time to wait for the server to report its version")
+
 	return c
 }
+
+// PrintClientVersion prints the version, git commit, and configured docker image of this client.
+func PrintClientVersion(w io.Writer) {
+	fmt.Fprintln(w, "Client:")
+	fmt.Fprintf(w, "\tVersion: %s\n", buildinfo.Version)
+	fmt.Fprintf(w, "\tGit commit: %s\n", buildinfo.GitSHA)
+	fmt.Fprintf(w, "\tConfigured docker image: %s\n", buildinfo.DockerImage)
+}
+
+// PrintServerVersion asks the server for its version with a ServerStatusRequest in namespace, and
+// prints it along with a warning if it doesn't match the client's.
+func PrintServerVersion(w io.Writer, client arkv1client.ServerStatusRequestsGetter, namespace string, timeout time.Duration) error {
+	requests := client.ServerStatusRequests(namespace)
+
+	req, err := requests.Create(&api.ServerStatusRequest{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:    namespace,
+			GenerateName: "ark-cli-",
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("error creating server status request: %v", err)
+	}
+	// the server deletes processed requests after a while, but there's no need to keep this one
+	defer requests.Delete(req.Name, nil)
+
+	err = wait.PollImmediate(250*time.Millisecond, timeout, func() (bool, error) {
+		req, err = requests.Get(req.Name, metav1.GetOptions{})
+		if err != nil {
+			return false, err
+		}
+		return req.Status.Phase == api.ServerStatusRequestPhaseProcessed, nil
+	})
+	if err == wait.ErrWaitTimeout {
+		return errors.New("timed out waiting for the server to report its version; is the Ark server running, and at least as new as this client?")
+	}
+	if err != nil {
+		return fmt.Errorf("error getting server version: %v", err)
+	}
+
+	fmt.Fprintln(w, "\nServer:")
+	fmt.Fprintf(w, "\tVersion: %s\n", req.Status.ServerVersion)
+	fmt.Fprintf(w, "\tGit commit: %s\n", req.Status.ServerGitSHA)
+
+	if req.Status.ServerVersion != buildinfo.Version {
+		fmt.Fprintf(w, "\nWARNING: the client version (%s) doesn't match the server version (%s). Some commands may not work as expected; use matching versions of the client and server.\n", buildinfo.Version, req.Status.ServerVersion)
+	}
+
+	return nil
+}
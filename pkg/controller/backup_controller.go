@@ -18,71 +18,167 @@ package controller
 
 import (
 	"bytes"
+	"compress/gzip"
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"os"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/golang/glog"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/clock"
 	kuberrs "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/pkg/api/v1"
 	"k8s.io/client-go/tools/cache"
 	"k8s.io/client-go/util/workqueue"
 
 	api "github.com/heptio/ark/pkg/apis/ark/v1"
 	"github.com/heptio/ark/pkg/backup"
 	"github.com/heptio/ark/pkg/cloudprovider"
+	"github.com/heptio/ark/pkg/csi"
+	"github.com/heptio/ark/pkg/event"
 	"github.com/heptio/ark/pkg/generated/clientset/scheme"
 	arkv1client "github.com/heptio/ark/pkg/generated/clientset/typed/ark/v1"
 	informers "github.com/heptio/ark/pkg/generated/informers/externalversions/ark/v1"
 	listers "github.com/heptio/ark/pkg/generated/listers/ark/v1"
+	"github.com/heptio/ark/pkg/logging"
+	"github.com/heptio/ark/pkg/metrics"
+	"github.com/heptio/ark/pkg/restic"
+	"github.com/heptio/ark/pkg/tracing"
 	"github.com/heptio/ark/pkg/util/collections"
 	"github.com/heptio/ark/pkg/util/encode"
 )
 
-const backupVersion = 1
+const (
+	backupVersion = 1
 
-type backupController struct {
-	backupper        backup.Backupper
-	backupService    cloudprovider.BackupService
-	bucket           string
-	pvProviderExists bool
+	// defaultProgressUpdateInterval is how often the progress of a running backup
+	// is persisted to its API object.
+	defaultProgressUpdateInterval = 10 * time.Second
 
-	lister       listers.BackupLister
-	listerSynced cache.InformerSynced
-	client       arkv1client.BackupsGetter
-	syncHandler  func(backupName string) error
-	queue        workqueue.RateLimitingInterface
+	// parentBackupWaitInterval is how long an incremental backup waits before being
+	// processed again when its parent hasn't finished running yet.
+	parentBackupWaitInterval = 10 * time.Second
+)
 
-	clock clock.Clock
+type backupController struct {
+	backupper              backup.Backupper
+	backupService          cloudprovider.BackupService
+	snapshotService        cloudprovider.SnapshotService
+	csiSnapshotter         csi.Snapshotter
+	podVolumeSnapshots     restic.SnapshotDeleter
+	bucket                 string
+	storageLocations       map[string]string
+	defaultStorageLocation string
+	clusterName            string
+	clusterUID             string
+	immutableBackups       bool
+	pvProviderExists       bool
+	resticEnabled          bool
+	tenantMode             bool
+	tenantQuota            *api.TenantQuotaConfig
+	defaultExcludes        []string
+	excludeCompletedPods   bool
+	progressUpdateInterval time.Duration
+	metrics                *metrics.ServerMetrics
+
+	lister                      listers.BackupLister
+	listerSynced                cache.InformerSynced
+	storageLocationLister       listers.BackupStorageLocationLister
+	storageLocationListerSynced cache.InformerSynced
+	client                      arkv1client.BackupsGetter
+	recorder                    event.Recorder
+	syncHandler                 func(backupName string) error
+	queue                       workqueue.RateLimitingInterface
+
+	clock       clock.Clock
+	gracePeriod time.Duration
+
+	// running holds the functions that cancel the backups currently being run, by key.
+	// uploading holds the keys of the running backups that are being uploaded, and
+	// interrupted the keys of those that were canceled because the server is stopping.
+	runningLock sync.Mutex
+	running     map[string]context.CancelFunc
+	uploading   sets.String
+	interrupted sets.String
+	// backupNames holds, in tenant mode, the keys of the backups being processed by name, so
+	// that backups with the same name in different namespaces can't run at the same time.
+	backupNames map[string]string
 }
 
 func NewBackupController(
 	backupInformer informers.BackupInformer,
+	storageLocationInformer informers.BackupStorageLocationInformer,
 	client arkv1client.BackupsGetter,
+	recorder event.Recorder,
 	backupper backup.Backupper,
 	backupService cloudprovider.BackupService,
+	snapshotService cloudprovider.SnapshotService,
+	csiSnapshotter csi.Snapshotter,
+	podVolumeSnapshots restic.SnapshotDeleter,
 	bucket string,
+	storageLocations map[string]string,
+	defaultStorageLocation string,
+	clusterName string,
+	clusterUID string,
+	immutableBackups bool,
 	pvProviderExists bool,
+	resticEnabled bool,
+	tenantMode bool,
+	tenantQuota *api.TenantQuotaConfig,
+	defaultExcludes []string,
+	excludeCompletedPods bool,
+	metrics *metrics.ServerMetrics,
+	terminationGracePeriod time.Duration,
 ) Interface {
 	c := &backupController{
-		backupper:        backupper,
-		backupService:    backupService,
-		bucket:           bucket,
-		pvProviderExists: pvProviderExists,
-
-		lister:       backupInformer.Lister(),
-		listerSynced: backupInformer.Informer().HasSynced,
-		client:       client,
-		queue:        workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "backup"),
-
-		clock: &clock.RealClock{},
+		backupper:              backupper,
+		backupService:          backupService,
+		snapshotService:        snapshotService,
+		csiSnapshotter:         csiSnapshotter,
+		podVolumeSnapshots:     podVolumeSnapshots,
+		bucket:                 bucket,
+		storageLocations:       storageLocations,
+		defaultStorageLocation: defaultStorageLocation,
+		clusterName:            clusterName,
+		clusterUID:             clusterUID,
+		immutableBackups:       immutableBackups,
+		pvProviderExists:       pvProviderExists,
+		resticEnabled:          resticEnabled,
+		tenantMode:             tenantMode,
+		tenantQuota:            tenantQuota,
+		defaultExcludes:        defaultExcludes,
+		excludeCompletedPods:   excludeCompletedPods,
+		progressUpdateInterval: defaultProgressUpdateInterval,
+		metrics:                metrics,
+
+		lister:                      backupInformer.Lister(),
+		listerSynced:                backupInformer.Informer().HasSynced,
+		storageLocationLister:       storageLocationInformer.Lister(),
+		storageLocationListerSynced: storageLocationInformer.Informer().HasSynced,
+		client:                      client,
+		recorder:                    recorder,
+		queue:                       workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "backup"),
+
+		clock:       &clock.RealClock{},
+		gracePeriod: terminationGracePeriod,
+
+		running:     make(map[string]context.CancelFunc),
+		uploading:   sets.NewString(),
+		interrupted: sets.NewString(),
+		backupNames: make(map[string]string),
 	}
 
 	c.syncHandler = c.processBackup
@@ -107,6 +203,21 @@ func NewBackupController(
 				}
 				c.queue.Add(key)
 			},
+			UpdateFunc: func(_, obj interface{}) {
+				backup := obj.(*api.Backup)
+
+				if !cancelRequested(backup) {
+					return
+				}
+
+				key, err := cache.MetaNamespaceKeyFunc(backup)
+				if err != nil {
+					glog.Errorf("error creating queue key for %#v: %v", backup, err)
+					return
+				}
+				// backups that haven't started yet are canceled when they're processed
+				c.cancelRunning(key)
+			},
 		},
 	)
 
@@ -115,7 +226,10 @@ func NewBackupController(
 
 // Run is a blocking function that runs the specified number of worker goroutines
 // to process items in the work queue. It will return when it receives on the
-// ctx.Done() channel.
+// ctx.Done() channel, once the running backups have finished. Backups that
+// are still running after half of the termination grace period are interrupted
+// and requeued, unless they're being uploaded, in which case they're given
+// another quarter of it to finish uploading before they're interrupted too.
 func (controller *backupController) Run(ctx context.Context, numWorkers int) error {
 	var wg sync.WaitGroup
 
@@ -127,7 +241,14 @@ func (controller *backupController) Run(ctx context.Context, numWorkers int) err
 		// We have to wait here in the deferred function instead of at the bottom of the function body
 		// because we have to shut down the queue in order for the workers to shut down gracefully, and
 		// we want to shut down the queue via defer and not at the end of the body.
-		wg.Wait()
+		runTimeout, uploadTimeout := shutdownTimeouts(controller.gracePeriod)
+		if !waitTimeout(&wg, runTimeout) {
+			controller.interruptRunning(false)
+			if !waitTimeout(&wg, uploadTimeout) {
+				controller.interruptRunning(true)
+				wg.Wait()
+			}
+		}
 
 		glog.Infof("All workers have finished")
 	}()
@@ -136,11 +257,13 @@ func (controller *backupController) Run(ctx context.Context, numWorkers int) err
 	defer glog.Infof("Shutting down BackupController")
 
 	glog.Info("Waiting for caches to sync")
-	if !cache.WaitForCacheSync(ctx.Done(), controller.listerSynced) {
+	if !cache.WaitForCacheSync(ctx.Done(), controller.listerSynced, controller.storageLocationListerSynced) {
 		return errors.New("timed out waiting for caches to sync")
 	}
 	glog.Info("Caches are synced")
 
+	controller.failInterruptedBackups()
+
 	wg.Add(numWorkers)
 	for i := 0; i < numWorkers; i++ {
 		go func() {
@@ -193,11 +316,12 @@ func (controller *backupController) processBackup(key string) error {
 		glog.V(4).Infof("error splitting key %q: %v", key, err)
 		return err
 	}
+	log := logging.WithFields(logging.Fields{"backup": name, "namespace": ns})
 
-	glog.V(4).Infof("Getting backup %s", key)
+	log.V(4).Infof("Getting backup %s", key)
 	backup, err := controller.lister.Backups(ns).Get(name)
 	if err != nil {
-		glog.V(4).Infof("error getting backup %s: %v", key, err)
+		log.V(4).Infof("error getting backup %s: %v", key, err)
 		return err
 	}
 
@@ -215,17 +339,33 @@ func (controller *backupController) processBackup(key string) error {
 		return nil
 	}
 
-	glog.V(4).Infof("Cloning backup %s", key)
+	if cancelRequested(backup) {
+		return controller.cancelBeforeStart(backup)
+	}
+
+	// when backups run concurrently, an incremental backup can be picked up
+	// while its parent is still waiting or running, so leave it queued until
+	// the parent has finished.
+	if parent := controller.unfinishedParent(backup); parent != nil {
+		log.V(4).Infof("Parent backup %s of backup %s has phase %s, waiting for it to finish", parent.Name, key, parent.Status.Phase)
+		controller.queue.AddAfter(key, parentBackupWaitInterval)
+		return nil
+	}
+
+	log.V(4).Infof("Cloning backup %s", key)
 	// don't modify items in the cache
 	backup, err = cloneBackup(backup)
 	if err != nil {
-		glog.V(4).Infof("error cloning backup %s: %v", key, err)
+		log.V(4).Infof("error cloning backup %s: %v", key, err)
 		return err
 	}
 
 	// set backup version
 	backup.Status.Version = backupVersion
 
+	// record the cluster the backup is being taken in
+	setClusterLabels(&backup.ObjectMeta, controller.clusterName, controller.clusterUID)
+
 	// included resources defaulting
 	if len(backup.Spec.IncludedResources) == 0 {
 		backup.Spec.IncludedResources = []string{"*"}
@@ -236,45 +376,376 @@ func (controller *backupController) processBackup(key string) error {
 		backup.Spec.IncludedNamespaces = []string{"*"}
 	}
 
+	// server-wide default excludes, unless the backup opts out of them
+	if !backup.Spec.IgnoreDefaultExcludes {
+		backup.Spec.ExcludedResources = mergeDefaultExcludes(backup.Spec, controller.defaultExcludes)
+		backup.Spec.ExcludeCompletedPods = backup.Spec.ExcludeCompletedPods || controller.excludeCompletedPods
+	}
+
 	// calculate expiration
 	if backup.Spec.TTL.Duration > 0 {
 		backup.Status.Expiration = metav1.NewTime(controller.clock.Now().Add(backup.Spec.TTL.Duration))
 	}
 
 	// validation
+	defer controller.releaseBackupName(backup)
 	if backup.Status.ValidationErrors = controller.getValidationErrors(backup); len(backup.Status.ValidationErrors) > 0 {
 		backup.Status.Phase = api.BackupPhaseFailedValidation
 	} else {
+		// backups in tenant namespaces only include their own namespace
+		if isTenantNamespace(controller.tenantMode, backup.Namespace) {
+			includeClusterResources := false
+			backup.Spec.IncludedNamespaces = []string{backup.Namespace}
+			backup.Spec.IncludeClusterResources = &includeClusterResources
+		}
+
 		backup.Status.Phase = api.BackupPhaseInProgress
+		// record the bucket of the backup's storage location, so it can be found there later
+		if location := controller.storageLocation(backup); location != "" {
+			backup.Status.StorageBucket = controller.storageLocations[location]
+		}
 	}
 
 	// update status
 	updatedBackup, err := controller.client.Backups(ns).Update(backup)
 	if err != nil {
-		glog.V(4).Infof("error updating status to %s: %v", backup.Status.Phase, err)
+		log.V(4).Infof("error updating status to %s: %v", backup.Status.Phase, err)
 		return err
 	}
 	backup = updatedBackup
 
+	schedule, location := metricLabels(backup)
+	controller.metrics.RegisterBackupAttempt(schedule, location)
+
 	if backup.Status.Phase == api.BackupPhaseFailedValidation {
+		controller.metrics.RegisterBackupFailure(schedule, location)
+		controller.recorder.Eventf(backup, v1.EventTypeWarning, event.ReasonBackupFailedValidation, "Backup failed validation: %s", strings.Join(backup.Status.ValidationErrors, "; "))
 		return nil
 	}
 
-	glog.V(4).Infof("running backup for %s", key)
+	controller.recorder.Eventf(backup, v1.EventTypeNormal, event.ReasonBackupStarted, "Started backup")
+
+	log.V(4).Infof("running backup for %s", key)
+	start := controller.clock.Now()
 	// execution & upload of backup
-	if err := controller.runBackup(backup, controller.bucket); err != nil {
-		glog.V(4).Infof("backup %s failed: %v", key, err)
+	spanCtx, span := tracing.Start(context.Background(), "backup")
+	span.SetAttribute("ark.backup", key)
+	span.SetAttribute("ark.storage_location", location)
+	err = controller.runBackup(spanCtx, backup, backupBucket(backup, controller.bucket))
+	span.RecordError(err)
+	span.End()
+	if err == context.Canceled {
+		controller.deleteSnapshots(backup)
+
+		// requesting the cancellation modified the API object, so base the final
+		// status update on its latest version.
+		if current, err := controller.client.Backups(ns).Get(backup.Name, metav1.GetOptions{}); err == nil {
+			backup.Annotations = current.Annotations
+			backup.ResourceVersion = current.ResourceVersion
+		}
+
+		if controller.wasInterrupted(backup) && !cancelRequested(backup) {
+			log.V(4).Infof("backup %s interrupted", key)
+			return controller.requeueInterrupted(backup)
+		}
+
+		log.V(4).Infof("backup %s canceled", key)
+		backup.Status.Phase = api.BackupPhaseCanceled
+		controller.recorder.Eventf(backup, v1.EventTypeNormal, event.ReasonBackupCanceled, "Canceled backup")
+	} else if err != nil {
+		log.V(4).Infof("backup %s failed: %v", key, err)
 		backup.Status.Phase = api.BackupPhaseFailed
+		controller.metrics.RegisterBackupFailure(schedule, location)
+		controller.recorder.Eventf(backup, v1.EventTypeWarning, event.ReasonBackupFailed, "Backup failed: %v", err)
+	} else {
+		controller.recordCompletionMetrics(backup, schedule, location, controller.clock.Since(start))
+		controller.recordCompletionEvents(backup)
 	}
 
-	glog.V(4).Infof("updating backup %s final status", key)
+	if backup.Status.CompletionTimestamp.IsZero() {
+		backup.Status.CompletionTimestamp = metav1.NewTime(controller.clock.Now())
+	}
+
+	log.V(4).Infof("updating backup %s final status", key)
 	if _, err = controller.client.Backups(ns).Update(backup); err != nil {
-		glog.V(4).Infof("error updating backup %s final status: %v", key, err)
+		log.V(4).Infof("error updating backup %s final status: %v", key, err)
+	}
+
+	return nil
+}
+
+// cancelRequested returns whether a backup has been annotated for cancellation.
+func cancelRequested(backup *api.Backup) bool {
+	return backup.Annotations[api.CancelAnnotation] == "true"
+}
+
+// cancelBeforeStart marks a backup that was canceled before it started running as Canceled.
+func (controller *backupController) cancelBeforeStart(backup *api.Backup) error {
+	backup, err := cloneBackup(backup)
+	if err != nil {
+		return err
+	}
+
+	backup.Status.Phase = api.BackupPhaseCanceled
+	backup.Status.CompletionTimestamp = metav1.NewTime(controller.clock.Now())
+	if backup, err = controller.client.Backups(backup.Namespace).Update(backup); err != nil {
+		return err
+	}
+
+	controller.recorder.Eventf(backup, v1.EventTypeNormal, event.ReasonBackupCanceled, "Canceled backup before it started")
+	return nil
+}
+
+// requeueInterrupted resets a backup that was interrupted because the server is stopping, and
+// whose snapshots have been deleted, to the New phase, so it's run again from the start once the
+// server, or another replica, is running.
+func (controller *backupController) requeueInterrupted(backup *api.Backup) error {
+	backup.Status = api.BackupStatus{Phase: api.BackupPhaseNew}
+	backup, err := controller.client.Backups(backup.Namespace).Update(backup)
+	if err != nil {
+		return err
+	}
+
+	controller.recorder.Eventf(backup, v1.EventTypeNormal, event.ReasonBackupInterrupted, "Backup was interrupted by the server stopping, and will be run again")
+	return nil
+}
+
+// startRunning returns a context, derived from parent, for running a backup that's canceled when
+// the backup's cancellation is requested. stopRunning must be called once the backup has finished.
+func (controller *backupController) startRunning(parent context.Context, backup *api.Backup) context.Context {
+	ctx, cancel := context.WithCancel(parent)
+	key := runningKey(backup)
+
+	controller.runningLock.Lock()
+	controller.running[key] = cancel
+	controller.runningLock.Unlock()
+
+	// the cancellation may have been requested before the backup was registered as running.
+	if current, err := controller.lister.Backups(backup.Namespace).Get(backup.Name); err == nil && cancelRequested(current) {
+		cancel()
+	}
+
+	return ctx
+}
+
+func (controller *backupController) stopRunning(backup *api.Backup) {
+	key := runningKey(backup)
+
+	controller.runningLock.Lock()
+	defer controller.runningLock.Unlock()
+
+	if cancel, ok := controller.running[key]; ok {
+		cancel()
+		delete(controller.running, key)
+	}
+	controller.uploading.Delete(key)
+}
+
+// startUploading records that a running backup is being uploaded, so it isn't interrupted if the
+// server stops.
+func (controller *backupController) startUploading(backup *api.Backup) {
+	controller.runningLock.Lock()
+	defer controller.runningLock.Unlock()
+
+	controller.uploading.Insert(runningKey(backup))
+}
+
+// interruptRunning cancels the running backups, because the server is stopping. Backups that are
+// being uploaded are only canceled if includeUploading is true.
+func (controller *backupController) interruptRunning(includeUploading bool) {
+	controller.runningLock.Lock()
+	defer controller.runningLock.Unlock()
+
+	for key, cancel := range controller.running {
+		if controller.uploading.Has(key) && !includeUploading {
+			glog.Infof("Waiting for backup %s to finish uploading", key)
+			continue
+		}
+
+		glog.Infof("Interrupting backup %s because the server is stopping", key)
+		controller.interrupted.Insert(key)
+		cancel()
+	}
+}
+
+// wasInterrupted returns whether a backup that was canceled was interrupted by interruptRunning.
+func (controller *backupController) wasInterrupted(backup *api.Backup) bool {
+	key := runningKey(backup)
+
+	controller.runningLock.Lock()
+	defer controller.runningLock.Unlock()
+
+	interrupted := controller.interrupted.Has(key)
+	controller.interrupted.Delete(key)
+	return interrupted
+}
+
+// runningKey returns the key of a running backup, which matches its queue key.
+func runningKey(backup *api.Backup) string {
+	key, _ := cache.MetaNamespaceKeyFunc(backup)
+	return key
+}
+
+// cancelRunning cancels the backup with the given key if it's running.
+func (controller *backupController) cancelRunning(key string) {
+	controller.runningLock.Lock()
+	defer controller.runningLock.Unlock()
+
+	if cancel, ok := controller.running[key]; ok {
+		glog.Infof("Canceling backup %s", key)
+		cancel()
+	}
+}
+
+// deleteSnapshots deletes the volume snapshots, CSI VolumeSnapshots, and pod volume snapshots
+// taken by a backup that didn't finish. Those that are deleted are removed from its status, and
+// those that can't be are logged.
+func (controller *backupController) deleteSnapshots(backup *api.Backup) {
+	log := backupLogger(backup)
+
+	for volume, volumeBackup := range backup.Status.VolumeBackups {
+		if volumeBackup.CSISnapshot != nil {
+			if controller.csiSnapshotter == nil {
+				log.Errorf("error deleting VolumeSnapshot of PersistentVolume %s of backup %s/%s: server has no CSI snapshotter", volume, backup.Namespace, backup.Name)
+				continue
+			}
+
+			log.Infof("Removing VolumeSnapshot of PersistentVolume %s associated with unfinished backup %s/%s", volume, backup.Namespace, backup.Name)
+			if err := controller.csiSnapshotter.DeleteSnapshot(volumeBackup.CSISnapshot); err != nil {
+				log.Errorf("error deleting VolumeSnapshot of PersistentVolume %s: %v", volume, err)
+				continue
+			}
+
+			delete(backup.Status.VolumeBackups, volume)
+			continue
+		}
+
+		if volumeBackup.SnapshotID == "" {
+			continue
+		}
+
+		snapshotService, err := cloudprovider.SnapshotServiceForLocation(controller.snapshotService, cloudprovider.VolumeSnapshotLocation(backup, volumeBackup))
+		if err != nil {
+			log.Errorf("error deleting snapshot %s of backup %s/%s: %v", volumeBackup.SnapshotID, backup.Namespace, backup.Name, err)
+			continue
+		}
+		if snapshotService == nil {
+			log.Errorf("error deleting snapshot %s of backup %s/%s: server has no volume snapshot locations", volumeBackup.SnapshotID, backup.Namespace, backup.Name)
+			continue
+		}
+
+		log.Infof("Removing snapshot %s associated with unfinished backup %s/%s", volumeBackup.SnapshotID, backup.Namespace, backup.Name)
+		if err := snapshotService.DeleteSnapshot(volumeBackup.SnapshotID); err != nil {
+			log.Errorf("error deleting snapshot %s: %v", volumeBackup.SnapshotID, err)
+			continue
+		}
+
+		delete(backup.Status.VolumeBackups, volume)
+	}
+
+	var remaining []api.PodVolumeSnapshotInfo
+	for _, snapshot := range backup.Status.PodVolumeSnapshots {
+		if controller.podVolumeSnapshots == nil {
+			log.Errorf("error deleting pod volume snapshot %s of backup %s/%s: server has no restic runner", snapshot.Snapshot, backup.Namespace, backup.Name)
+			remaining = append(remaining, snapshot)
+			continue
+		}
+
+		log.Infof("Removing pod volume snapshot %s associated with unfinished backup %s/%s", snapshot.Snapshot, backup.Namespace, backup.Name)
+		if err := controller.podVolumeSnapshots.DeleteSnapshot(snapshot.RepoNamespace, snapshot.Snapshot); err != nil {
+			log.Errorf("error deleting pod volume snapshot %s: %v", snapshot.Snapshot, err)
+			remaining = append(remaining, snapshot)
+		}
+	}
+	backup.Status.PodVolumeSnapshots = remaining
+}
+
+// failInterruptedBackups fails the backups left InProgress by a previous run of the server, and
+// deletes the volume snapshots recorded in their checkpoints. The data they were collecting was
+// lost along with the previous server, so they can't be resumed.
+func (controller *backupController) failInterruptedBackups() {
+	backups, err := controller.lister.List(labels.Everything())
+	if err != nil {
+		glog.Errorf("error listing backups: %v", err)
+		return
 	}
 
+	for _, backup := range backups {
+		if backup.Status.Phase != api.BackupPhaseInProgress {
+			continue
+		}
+
+		glog.Infof("Backup %s/%s was interrupted by a server restart, failing it", backup.Namespace, backup.Name)
+		if err := controller.failInterruptedBackup(backup); err != nil {
+			glog.Errorf("error failing interrupted backup %s/%s: %v", backup.Namespace, backup.Name, err)
+		}
+	}
+}
+
+func (controller *backupController) failInterruptedBackup(backup *api.Backup) error {
+	backup, err := cloneBackup(backup)
+	if err != nil {
+		return err
+	}
+
+	var completedResources int
+	if checkpoint := backup.Status.Checkpoint; checkpoint != nil {
+		completedResources = len(checkpoint.CompletedResources)
+		backup.Status.VolumeBackups = checkpoint.VolumeBackups
+		backup.Status.PodVolumeSnapshots = checkpoint.PodVolumeSnapshots
+		controller.deleteSnapshots(backup)
+	}
+
+	backup.Status.Phase = api.BackupPhaseFailed
+	backup.Status.CompletionTimestamp = metav1.NewTime(controller.clock.Now())
+	backup.Status.Checkpoint = nil
+
+	if backup, err = controller.client.Backups(backup.Namespace).Update(backup); err != nil {
+		return err
+	}
+
+	controller.recorder.Eventf(backup, v1.EventTypeWarning, event.ReasonBackupFailed, "Backup was interrupted by a server restart after backing up %d resource(s)", completedResources)
 	return nil
 }
 
+// unfinishedParent returns the parent of an incremental backup if it hasn't finished running,
+// and nil otherwise.
+func (controller *backupController) unfinishedParent(backup *api.Backup) *api.Backup {
+	if backup.Spec.ParentBackup == "" {
+		return nil
+	}
+
+	parent, err := controller.lister.Backups(backup.Namespace).Get(backup.Spec.ParentBackup)
+	if err != nil {
+		// a missing parent is reported by validation
+		return nil
+	}
+
+	switch parent.Status.Phase {
+	case "", api.BackupPhaseNew, api.BackupPhaseInProgress:
+		return parent
+	default:
+		return nil
+	}
+}
+
+// setClusterLabels records the name and UID of a cluster in an object's labels.
+// Empty values aren't recorded.
+func setClusterLabels(obj *metav1.ObjectMeta, clusterName, clusterUID string) {
+	for key, val := range map[string]string{
+		api.ClusterNameLabel: clusterName,
+		api.ClusterUIDLabel:  clusterUID,
+	} {
+		if val == "" {
+			continue
+		}
+		if obj.Labels == nil {
+			obj.Labels = make(map[string]string)
+		}
+		obj.Labels[key] = val
+	}
+}
+
 func cloneBackup(in interface{}) (*api.Backup, error) {
 	clone, err := scheme.Scheme.DeepCopy(in)
 	if err != nil {
@@ -300,14 +771,121 @@ func (controller *backupController) getValidationErrors(itm *api.Backup) []strin
 		validationErrors = append(validationErrors, fmt.Sprintf("Invalid included/excluded namespace lists: %v", err))
 	}
 
+	if isTenantNamespace(controller.tenantMode, itm.Namespace) {
+		validationErrors = append(validationErrors, getTenantValidationErrors(itm.Namespace, itm.Spec.IncludedNamespaces, itm.Spec.IncludeClusterResources)...)
+		validationErrors = append(validationErrors, controller.getQuotaErrors(itm)...)
+	}
+
 	if !controller.pvProviderExists && itm.Spec.SnapshotVolumes != nil && *itm.Spec.SnapshotVolumes {
 		validationErrors = append(validationErrors, "Server is not configured for PV snapshots")
 	}
 
+	if !controller.resticEnabled && itm.Spec.MoveVolumeData {
+		validationErrors = append(validationErrors, "Server is not configured for restic, which is needed to move volume data")
+	}
+
+	switch itm.Spec.UploaderType {
+	case "", api.UploaderTypeRestic, api.UploaderTypeKopia:
+	default:
+		validationErrors = append(validationErrors, fmt.Sprintf("Invalid uploader type %q; must be %s or %s", itm.Spec.UploaderType, api.UploaderTypeRestic, api.UploaderTypeKopia))
+	}
+
+	bucket := controller.bucket
+	if location := controller.storageLocation(itm); location != "" {
+		var ok bool
+		if bucket, ok = controller.storageLocations[location]; !ok {
+			validationErrors = append(validationErrors, fmt.Sprintf("Backup storage location %q isn't configured", location))
+			return validationErrors
+		}
+
+		// locations that haven't been checked yet are assumed to be available, and errors
+		// getting the location are left to the backup itself to surface
+		if bsl, err := controller.storageLocationLister.BackupStorageLocations(api.DefaultNamespace).Get(location); err == nil && bsl.Status.Phase == api.BackupStorageLocationPhaseUnavailable {
+			validationErrors = append(validationErrors, fmt.Sprintf("Backup storage location %q is unavailable: %s", location, bsl.Status.Message))
+			return validationErrors
+		}
+	}
+
+	if cloudprovider.IsReadOnly(controller.backupService, bucket) {
+		validationErrors = append(validationErrors, fmt.Sprintf("Backup storage location %q is read-only", controller.storageLocation(itm)))
+		return validationErrors
+	}
+
+	if err := cloudprovider.ValidateVolumeSnapshotLocations(controller.snapshotService, itm); err != nil {
+		validationErrors = append(validationErrors, fmt.Sprintf("Invalid volume snapshot location: %v", err))
+	}
+
+	// backups are stored in object storage by name, so in tenant mode, where backups can be
+	// created in many namespaces, their names must be unique across them.
+	if controller.tenantMode {
+		if err := controller.reserveBackupName(itm, bucket); err != nil {
+			validationErrors = append(validationErrors, err.Error())
+		}
+	}
+
+	// immutable backups must never be overwritten, so refuse to run a backup
+	// whose name is already taken in object storage, or if we can't tell
+	// whether it is.
+	if controller.immutableBackups {
+		_, err := controller.backupService.GetBackup(bucket, itm.Name)
+		switch {
+		case err == nil:
+			validationErrors = append(validationErrors, fmt.Sprintf("Backup %s already exists in object storage and backups are immutable", itm.Name))
+		case !cloudprovider.IsObjectNotFound(err):
+			validationErrors = append(validationErrors, fmt.Sprintf("Error checking whether backup %s already exists in object storage, which immutable backups mustn't overwrite: %v", itm.Name, err))
+		}
+	}
+
+	if itm.Spec.ParentBackup != "" {
+		parent, err := controller.lister.Backups(itm.Namespace).Get(itm.Spec.ParentBackup)
+		switch {
+		case err != nil:
+			validationErrors = append(validationErrors, fmt.Sprintf("Error getting parent backup %s: %v", itm.Spec.ParentBackup, err))
+		case parent.Status.Phase != api.BackupPhaseCompleted && parent.Status.Phase != api.BackupPhasePartiallyFailed:
+			validationErrors = append(validationErrors, fmt.Sprintf("Parent backup %s has phase %s; it must be %s or %s", parent.Name, parent.Status.Phase, api.BackupPhaseCompleted, api.BackupPhasePartiallyFailed))
+		case backupBucket(parent, controller.bucket) != bucket:
+			validationErrors = append(validationErrors, fmt.Sprintf("Parent backup %s is in a different storage location; incremental backups must be stored with their parents", parent.Name))
+		}
+	}
+
 	return validationErrors
 }
 
-func (controller *backupController) runBackup(backup *api.Backup, bucket string) error {
+// mergeDefaultExcludes returns spec's excluded resources, followed by those of defaults that it
+// doesn't already exclude or include by name.
+func mergeDefaultExcludes(spec api.BackupSpec, defaults []string) []string {
+	excludes := spec.ExcludedResources
+	listed := sets.NewString(spec.IncludedResources...)
+	listed.Insert(spec.ExcludedResources...)
+	for _, resource := range defaults {
+		if !listed.Has(resource) {
+			excludes = append(excludes, resource)
+			listed.Insert(resource)
+		}
+	}
+	return excludes
+}
+
+// storageLocation returns the name of the backup storage location that backup is stored in,
+// which is the server's default location if the backup doesn't specify one.
+func (controller *backupController) storageLocation(backup *api.Backup) string {
+	if backup.Spec.StorageLocation != "" {
+		return backup.Spec.StorageLocation
+	}
+	return controller.defaultStorageLocation
+}
+
+// backupBucket returns the bucket that backup is stored in, given the server's default bucket.
+func backupBucket(backup *api.Backup, defaultBucket string) string {
+	if backup.Status.StorageBucket != "" {
+		return backup.Status.StorageBucket
+	}
+	return defaultBucket
+}
+
+func (controller *backupController) runBackup(ctx context.Context, backup *api.Backup, bucket string) error {
+	log := backupLogger(backup)
+
 	backupFile, err := ioutil.TempFile("", "")
 	if err != nil {
 		return err
@@ -326,25 +904,360 @@ func (controller *backupController) runBackup(backup *api.Backup, bucket string)
 		err = kuberrs.NewAggregate(errs)
 	}()
 
-	if err := controller.backupper.Backup(backup, backupFile); err != nil {
+	logFile, err := ioutil.TempFile("", "")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if closeErr := logFile.Close(); closeErr != nil {
+			log.Errorf("error closing log file %s: %v", logFile.Name(), closeErr)
+		}
+		if removeErr := os.Remove(logFile.Name()); removeErr != nil {
+			log.Errorf("error removing log file %s: %v", logFile.Name(), removeErr)
+		}
+	}()
+	logGzip := gzip.NewWriter(logFile)
+
+	var parent io.Reader
+	if backup.Spec.ParentBackup != "" {
+		_, span := tracing.Start(ctx, "download parent backup")
+		span.SetAttribute("ark.backup", backup.Spec.ParentBackup)
+		parentData, err := controller.backupService.DownloadBackup(bucket, backup.Spec.ParentBackup)
+		span.RecordError(err)
+		span.End()
+		if err != nil {
+			return fmt.Errorf("error downloading parent backup %s: %v", backup.Spec.ParentBackup, err)
+		}
+		defer parentData.Close()
+		parent = parentData
+	}
+
+	runCtx := controller.startRunning(ctx, backup)
+	defer controller.stopRunning(backup)
+
+	progress := newBackupProgressUpdater(controller.client, controller.recorder, backup)
+	stopProgress := make(chan struct{})
+	progressDone := make(chan struct{})
+	go func() {
+		wait.Until(progress.flush, controller.progressUpdateInterval, stopProgress)
+		close(progressDone)
+	}()
+
+	err = controller.backupper.Backup(runCtx, backup, parent, backupFile, logGzip, progress)
+
+	close(stopProgress)
+	<-progressDone
+
+	// progress updates bump the API object's resource version, so make sure the
+	// final status update is based on the latest one.
+	if resourceVersion := progress.resourceVersion(); resourceVersion != "" {
+		backup.ResourceVersion = resourceVersion
+	}
+
+	// nothing is kept from a canceled backup, including its log.
+	if runCtx.Err() != nil {
+		return context.Canceled
+	}
+
+	if err != nil {
+		controller.uploadFailedBackupLog(bucket, backup.Name, logGzip, logFile)
+		return err
+	}
+
+	if err := logGzip.Close(); err != nil {
 		return err
 	}
 
-	// note: updating this here so the uploaded JSON shows "completed". If
+	// note: updating this here so the uploaded JSON shows the final phase. If
 	// the upload fails, we'll alter the phase in the calling func.
-	glog.V(4).Infof("backup %s/%s completed", backup.Namespace, backup.Name)
-	backup.Status.Phase = api.BackupPhaseCompleted
+	if backup.Status.Errors > 0 {
+		log.V(4).Infof("backup %s/%s partially failed with %d error(s)", backup.Namespace, backup.Name, backup.Status.Errors)
+		backup.Status.Phase = api.BackupPhasePartiallyFailed
+	} else {
+		log.V(4).Infof("backup %s/%s completed", backup.Namespace, backup.Name)
+		backup.Status.Phase = api.BackupPhaseCompleted
+	}
+	backup.Status.CompletionTimestamp = metav1.NewTime(controller.clock.Now())
+	if info, err := backupFile.Stat(); err == nil {
+		backup.Status.TarballSize = info.Size()
+	}
 
 	buf := new(bytes.Buffer)
 	if err := encode.EncodeTo(backup, "json", buf); err != nil {
 		return err
 	}
 
-	// re-set the file offset to 0 for reading
+	// the item list lets the backup's contents be listed without downloading it, but isn't
+	// needed to restore it, so the backup is uploaded without it if it can't be written.
+	items, itemsErr := itemList(backupFile)
+	if itemsErr != nil {
+		log.Errorf("error writing item list of backup %s/%s: %v", backup.Namespace, backup.Name, itemsErr)
+	}
+
+	// re-set the file offsets to 0 for reading
 	_, err = backupFile.Seek(0, 0)
 	if err != nil {
 		return err
 	}
+	_, err = logFile.Seek(0, 0)
+	if err != nil {
+		return err
+	}
+
+	controller.startUploading(backup)
+	_, uploadSpan := tracing.Start(runCtx, "upload backup")
+	uploadSpan.SetAttribute("ark.bytes", backup.Status.TarballSize)
+	err = controller.backupService.UploadBackup(bucket, backup.Name, bytes.NewReader(buf.Bytes()), contextReadSeeker{backupFile, runCtx}, contextReadSeeker{logFile, runCtx})
+	uploadSpan.RecordError(err)
+	uploadSpan.End()
+
+	// if the backup was canceled while it was being uploaded, remove whatever made it
+	// to object storage.
+	if runCtx.Err() != nil {
+		if deleteErr := controller.backupService.DeleteBackup(bucket, backup.Name); deleteErr != nil {
+			log.Errorf("error deleting canceled backup %s/%s from object storage: %v", backup.Namespace, backup.Name, deleteErr)
+		}
+		return context.Canceled
+	}
+
+	if err == nil {
+		schedule, location := metricLabels(backup)
+		controller.metrics.ObserveBackupSize(schedule, location, backup.Status.TarballSize)
+	}
+
+	if err == nil && items != nil {
+		_, span := tracing.Start(runCtx, "upload item list")
+		span.SetAttribute("ark.bytes", len(items))
+		uploadErr := controller.backupService.UploadBackupItemList(bucket, backup.Name, bytes.NewReader(items))
+		span.RecordError(uploadErr)
+		span.End()
+		if uploadErr != nil {
+			log.Errorf("error uploading item list of backup %s/%s: %v", backup.Namespace, backup.Name, uploadErr)
+		}
+	}
+
+	return err
+}
+
+// contextReadSeeker is an io.ReadSeeker whose reads fail once its context is done, so that
+// uploading it can be canceled.
+type contextReadSeeker struct {
+	io.ReadSeeker
+	ctx context.Context
+}
+
+func (r contextReadSeeker) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return r.ReadSeeker.Read(p)
+}
+
+// itemList returns the gzip-compressed list of the items in the backup tarball in backupFile.
+func itemList(backupFile io.ReadSeeker) ([]byte, error) {
+	if _, err := backupFile.Seek(0, 0); err != nil {
+		return nil, err
+	}
+
+	buf := new(bytes.Buffer)
+	if err := backup.WriteItemList(backupFile, buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// backupLogger returns a logger whose lines identify backup.
+func backupLogger(backup *api.Backup) logging.Logger {
+	return logging.WithFields(logging.Fields{"backup": backup.Name, "namespace": backup.Namespace})
+}
+
+// metricLabels returns the schedule and backup storage location that backup's metrics are
+// labeled with.
+func metricLabels(backup *api.Backup) (schedule, location string) {
+	return backup.Labels[api.ScheduleNameLabel], backup.Spec.StorageLocation
+}
+
+// recordCompletionMetrics records the outcome, duration, and volume snapshots of a backup that ran
+// to completion.
+func (controller *backupController) recordCompletionMetrics(backup *api.Backup, schedule, location string, duration time.Duration) {
+	if backup.Status.Phase == api.BackupPhasePartiallyFailed {
+		controller.metrics.RegisterBackupPartialFailure(schedule, location)
+	} else {
+		controller.metrics.RegisterBackupSuccess(schedule, location)
+	}
+	controller.metrics.ObserveBackupDuration(schedule, location, duration)
+
+	var snapshots int
+	for _, volumeBackup := range backup.Status.VolumeBackups {
+		if volumeBackup.SnapshotID != "" {
+			snapshots++
+		}
+	}
+	controller.metrics.RegisterVolumeSnapshots(schedule, location, snapshots)
+}
+
+// recordCompletionEvents records events for the volume snapshots taken by a backup that ran to
+// completion, and for its completion.
+func (controller *backupController) recordCompletionEvents(backup *api.Backup) {
+	volumes := make([]string, 0, len(backup.Status.VolumeBackups))
+	for volume := range backup.Status.VolumeBackups {
+		volumes = append(volumes, volume)
+	}
+	sort.Strings(volumes)
+
+	for _, volume := range volumes {
+		if snapshotID := backup.Status.VolumeBackups[volume].SnapshotID; snapshotID != "" {
+			controller.recorder.Eventf(backup, v1.EventTypeNormal, event.ReasonSnapshotCreated, "Created snapshot %s of PersistentVolume %s", snapshotID, volume)
+		}
+	}
+
+	if backup.Status.Phase == api.BackupPhasePartiallyFailed {
+		controller.recorder.Eventf(backup, v1.EventTypeWarning, event.ReasonBackupPartiallyFailed, "Backup completed with %d error(s) and %d warning(s); run 'ark backup logs %s' for details", backup.Status.Errors, backup.Status.Warnings, backup.Name)
+	} else {
+		controller.recorder.Eventf(backup, v1.EventTypeNormal, event.ReasonBackupCompleted, "Backup completed with %d warning(s)", backup.Status.Warnings)
+	}
+}
+
+// uploadFailedBackupLog uploads the log of a backup that failed, so that the failure can be
+// debugged even though the backup itself isn't uploaded. Errors are logged but otherwise ignored.
+func (controller *backupController) uploadFailedBackupLog(bucket, name string, logGzip *gzip.Writer, logFile *os.File) {
+	if err := logGzip.Close(); err != nil {
+		glog.Errorf("error closing log of failed backup %s: %v", name, err)
+		return
+	}
+
+	if _, err := logFile.Seek(0, 0); err != nil {
+		glog.Errorf("error reading log of failed backup %s: %v", name, err)
+		return
+	}
+
+	if err := controller.backupService.UploadBackupLog(bucket, name, logFile); err != nil {
+		glog.Errorf("error uploading log of failed backup %s: %v", name, err)
+	}
+}
+
+// maxItemErrorEvents is the number of item errors per backup that are recorded as events, so that
+// a backup with many failing items doesn't flood its namespace with events.
+const maxItemErrorEvents = 10
+
+// backupProgressUpdater implements backup.ProgressReporter by recording the latest reported
+// progress and patching it onto the Backup API object whenever flush is called, and by recording
+// an event for each reported item error.
+type backupProgressUpdater struct {
+	client    arkv1client.BackupsGetter
+	recorder  event.Recorder
+	namespace string
+	name      string
+	// eventObject identifies the backup for recording events about it, since the backup itself
+	// is modified concurrently by the backupper.
+	eventObject *api.Backup
+
+	// flushLock serializes flushes, so that an older checkpoint can't overwrite a newer one.
+	flushLock sync.Mutex
+
+	lock           sync.Mutex
+	latest         api.BackupProgress
+	checkpoint     *api.BackupCheckpoint
+	dirty          bool
+	latestVersion  string
+	itemErrorCount int
+}
+
+var _ backup.ProgressReporter = &backupProgressUpdater{}
+
+func newBackupProgressUpdater(client arkv1client.BackupsGetter, recorder event.Recorder, backup *api.Backup) *backupProgressUpdater {
+	return &backupProgressUpdater{
+		client:    client,
+		recorder:  recorder,
+		namespace: backup.Namespace,
+		name:      backup.Name,
+		eventObject: &api.Backup{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: backup.Namespace,
+				Name:      backup.Name,
+				UID:       backup.UID,
+			},
+		},
+	}
+}
+
+func (u *backupProgressUpdater) ReportProgress(progress api.BackupProgress) {
+	u.lock.Lock()
+	defer u.lock.Unlock()
+
+	u.latest = progress
+	u.dirty = true
+}
+
+// ReportCheckpoint records checkpoint and flushes it right away, so that the snapshots it lists can
+// be cleaned up even if the server stops before the next periodic flush.
+func (u *backupProgressUpdater) ReportCheckpoint(checkpoint api.BackupCheckpoint) {
+	u.lock.Lock()
+	u.checkpoint = &checkpoint
+	u.dirty = true
+	u.lock.Unlock()
+
+	u.flush()
+}
+
+func (u *backupProgressUpdater) ReportItemError(err error) {
+	u.lock.Lock()
+	u.itemErrorCount++
+	count := u.itemErrorCount
+	u.lock.Unlock()
+
+	switch {
+	case count < maxItemErrorEvents:
+		u.recorder.Eventf(u.eventObject, v1.EventTypeWarning, event.ReasonItemBackupFailed, "%v", err)
+	case count == maxItemErrorEvents:
+		u.recorder.Eventf(u.eventObject, v1.EventTypeWarning, event.ReasonItemBackupFailed, "%v (further item errors won't be recorded as events; run 'ark backup logs %s' for details)", err, u.name)
+	}
+}
+
+// flush patches the Backup's status with the most recently reported progress and checkpoint, if
+// they have changed since the last flush. Errors are logged but otherwise ignored since progress is best-effort.
+func (u *backupProgressUpdater) flush() {
+	u.flushLock.Lock()
+	defer u.flushLock.Unlock()
+
+	u.lock.Lock()
+	if !u.dirty {
+		u.lock.Unlock()
+		return
+	}
+	status := map[string]interface{}{
+		"progress": u.latest,
+	}
+	if u.checkpoint != nil {
+		status["checkpoint"] = u.checkpoint
+	}
+	u.dirty = false
+	u.lock.Unlock()
+
+	patch, err := json.Marshal(map[string]interface{}{
+		"status": status,
+	})
+	if err != nil {
+		glog.Errorf("error marshaling progress for backup %s/%s: %v", u.namespace, u.name, err)
+		return
+	}
+
+	updated, err := u.client.Backups(u.namespace).Patch(u.name, types.MergePatchType, patch)
+	if err != nil {
+		glog.Errorf("error updating progress for backup %s/%s: %v", u.namespace, u.name, err)
+		return
+	}
+
+	u.lock.Lock()
+	u.latestVersion = updated.ResourceVersion
+	u.lock.Unlock()
+}
+
+// resourceVersion returns the resource version of the Backup as of the last successful flush, or
+// the empty string if no flush has happened.
+func (u *backupProgressUpdater) resourceVersion() string {
+	u.lock.Lock()
+	defer u.lock.Unlock()
 
-	return controller.backupService.UploadBackup(bucket, backup.Name, bytes.NewReader(buf.Bytes()), backupFile)
+	return u.latestVersion
 }
@@ -17,12 +17,22 @@ limitations under the License.
 package controller
 
 import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
+	"strings"
 	"testing"
 	"time"
 
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/util/clock"
+	"k8s.io/apimachinery/pkg/util/sets"
 	core "k8s.io/client-go/testing"
 
 	"github.com/stretchr/testify/assert"
@@ -30,9 +40,12 @@ import (
 	"github.com/stretchr/testify/require"
 
 	"github.com/heptio/ark/pkg/apis/ark/v1"
+	"github.com/heptio/ark/pkg/backup"
+	"github.com/heptio/ark/pkg/cloudprovider"
 	"github.com/heptio/ark/pkg/generated/clientset/fake"
 	"github.com/heptio/ark/pkg/generated/clientset/scheme"
 	informers "github.com/heptio/ark/pkg/generated/informers/externalversions"
+	"github.com/heptio/ark/pkg/metrics"
 	. "github.com/heptio/ark/pkg/util/test"
 )
 
@@ -40,8 +53,8 @@ type fakeBackupper struct {
 	mock.Mock
 }
 
-func (b *fakeBackupper) Backup(backup *v1.Backup, data io.Writer) error {
-	args := b.Called(backup, data)
+func (b *fakeBackupper) Backup(ctx context.Context, backup *v1.Backup, parent io.Reader, data, log io.Writer, progress backup.ProgressReporter) error {
+	args := b.Called(backup, parent, data, log, progress)
 	return args.Error(0)
 }
 
@@ -53,8 +66,22 @@ func TestProcessBackup(t *testing.T) {
 		expectedIncludes []string
 		expectedExcludes []string
 		backup           *TestBackup
+		parentBackup     *TestBackup
 		expectBackup     bool
 		allowSnapshots   bool
+		resticEnabled    bool
+		clusterName      string
+		clusterUID       string
+		immutable        bool
+		existingBackup   *TestBackup
+		storageLocations map[string]string
+		defaultLocation  string
+		readOnlyBucket   string
+		unavailable      string
+		defaultExcludes  []string
+		excludeCompleted bool
+		expectedBucket   string
+		expectedEvents   []string
 	}{
 		{
 			name:        "bad key",
@@ -116,6 +143,26 @@ func TestProcessBackup(t *testing.T) {
 			expectedExcludes: []string{"k", "l"},
 			expectBackup:     true,
 		},
+		{
+			name:             "server's default excludes are added unless the backup includes or excludes them",
+			key:              "heptio-ark/backup1",
+			backup:           NewTestBackup().WithName("backup1").WithPhase(v1.BackupPhaseNew).WithIncludedResources("pods", "secrets").WithExcludedResources("k"),
+			defaultExcludes:  []string{"events", "k", "secrets"},
+			excludeCompleted: true,
+			expectedIncludes: []string{"pods", "secrets"},
+			expectedExcludes: []string{"k", "events"},
+			expectBackup:     true,
+		},
+		{
+			name:             "server's default excludes are ignored if the backup opts out",
+			key:              "heptio-ark/backup1",
+			backup:           NewTestBackup().WithName("backup1").WithPhase(v1.BackupPhaseNew).WithExcludedResources("k").WithIgnoreDefaultExcludes(true),
+			defaultExcludes:  []string{"events"},
+			excludeCompleted: true,
+			expectedIncludes: []string{"*"},
+			expectedExcludes: []string{"k"},
+			expectBackup:     true,
+		},
 		{
 			name:             "if includednamespaces are specified, don't default to *",
 			key:              "heptio-ark/backup1",
@@ -129,6 +176,10 @@ func TestProcessBackup(t *testing.T) {
 			backup:           NewTestBackup().WithName("backup1").WithPhase(v1.BackupPhaseNew).WithTTL(10 * time.Minute),
 			expectedIncludes: []string{"*"},
 			expectBackup:     true,
+			expectedEvents: []string{
+				"Normal BackupStarted Started backup",
+				"Normal BackupCompleted Backup completed with 0 warning(s)",
+			},
 		},
 		{
 			name:         "backup with SnapshotVolumes when allowSnapshots=false fails validation",
@@ -144,6 +195,135 @@ func TestProcessBackup(t *testing.T) {
 			expectedIncludes: []string{"*"},
 			expectBackup:     true,
 		},
+		{
+			name:         "backup with MoveVolumeData when resticEnabled=false fails validation",
+			key:          "heptio-ark/backup1",
+			backup:       NewTestBackup().WithName("backup1").WithPhase(v1.BackupPhaseNew).WithMoveVolumeData(true),
+			expectBackup: false,
+		},
+		{
+			name:             "backup with MoveVolumeData when resticEnabled=true gets executed",
+			key:              "heptio-ark/backup1",
+			backup:           NewTestBackup().WithName("backup1").WithPhase(v1.BackupPhaseNew).WithMoveVolumeData(true),
+			resticEnabled:    true,
+			expectedIncludes: []string{"*"},
+			expectBackup:     true,
+		},
+		{
+			name:         "backup with an unknown UploaderType fails validation",
+			key:          "heptio-ark/backup1",
+			backup:       NewTestBackup().WithName("backup1").WithPhase(v1.BackupPhaseNew).WithUploaderType("rsync"),
+			expectBackup: false,
+		},
+		{
+			name:             "backup records the cluster it's taken in",
+			key:              "heptio-ark/backup1",
+			backup:           NewTestBackup().WithName("backup1").WithPhase(v1.BackupPhaseNew),
+			clusterName:      "cluster-1",
+			clusterUID:       "uid-1",
+			expectedIncludes: []string{"*"},
+			expectBackup:     true,
+		},
+		{
+			name:           "immutable backup that already exists in object storage fails validation",
+			key:            "heptio-ark/backup1",
+			backup:         NewTestBackup().WithName("backup1").WithPhase(v1.BackupPhaseNew),
+			immutable:      true,
+			existingBackup: NewTestBackup().WithName("backup1").WithPhase(v1.BackupPhaseCompleted),
+			expectBackup:   false,
+			expectedEvents: []string{
+				"Warning FailedValidation Backup failed validation: Backup backup1 already exists in object storage and backups are immutable",
+			},
+		},
+		{
+			name:             "immutable backup that doesn't exist in object storage gets executed",
+			key:              "heptio-ark/backup1",
+			backup:           NewTestBackup().WithName("backup1").WithPhase(v1.BackupPhaseNew),
+			immutable:        true,
+			existingBackup:   NewTestBackup().WithName("backup2").WithPhase(v1.BackupPhaseCompleted),
+			expectedIncludes: []string{"*"},
+			expectBackup:     true,
+		},
+		{
+			name:      "immutable backup fails validation if object storage can't be checked for an existing backup",
+			key:       "heptio-ark/backup1",
+			backup:    NewTestBackup().WithName("backup1").WithPhase(v1.BackupPhaseNew),
+			immutable: true,
+			expectedEvents: []string{
+				"Warning FailedValidation Backup failed validation: Error checking whether backup backup1 already exists in object storage, which immutable backups mustn't overwrite: bucket not found",
+			},
+		},
+		{
+			name:         "incremental backup with nonexistent parent fails validation",
+			key:          "heptio-ark/backup1",
+			backup:       NewTestBackup().WithName("backup1").WithPhase(v1.BackupPhaseNew).WithParentBackup("parent"),
+			expectBackup: false,
+		},
+		{
+			name:         "incremental backup with incomplete parent fails validation",
+			key:          "heptio-ark/backup1",
+			backup:       NewTestBackup().WithName("backup1").WithPhase(v1.BackupPhaseNew).WithParentBackup("parent"),
+			parentBackup: NewTestBackup().WithName("parent").WithPhase(v1.BackupPhaseFailed),
+			expectBackup: false,
+		},
+		{
+			name:         "backup with an unconfigured storage location fails validation",
+			key:          "heptio-ark/backup1",
+			backup:       NewTestBackup().WithName("backup1").WithPhase(v1.BackupPhaseNew).WithStorageLocation("secondary"),
+			expectBackup: false,
+			expectedEvents: []string{
+				`Warning FailedValidation Backup failed validation: Backup storage location "secondary" isn't configured`,
+			},
+		},
+		{
+			name:             "backup with a storage location is stored in the location's bucket",
+			key:              "heptio-ark/backup1",
+			backup:           NewTestBackup().WithName("backup1").WithPhase(v1.BackupPhaseNew).WithStorageLocation("secondary"),
+			storageLocations: map[string]string{"secondary": "other-bucket"},
+			expectedBucket:   "other-bucket",
+			expectedIncludes: []string{"*"},
+			expectBackup:     true,
+		},
+		{
+			name:             "backup without a storage location is stored in the default location's bucket",
+			key:              "heptio-ark/backup1",
+			backup:           NewTestBackup().WithName("backup1").WithPhase(v1.BackupPhaseNew),
+			storageLocations: map[string]string{"secondary": "other-bucket"},
+			defaultLocation:  "secondary",
+			expectedBucket:   "other-bucket",
+			expectedIncludes: []string{"*"},
+			expectBackup:     true,
+		},
+		{
+			name:             "backup with a read-only storage location fails validation",
+			key:              "heptio-ark/backup1",
+			backup:           NewTestBackup().WithName("backup1").WithPhase(v1.BackupPhaseNew).WithStorageLocation("secondary"),
+			storageLocations: map[string]string{"secondary": "other-bucket"},
+			readOnlyBucket:   "other-bucket",
+			expectBackup:     false,
+			expectedEvents: []string{
+				`Warning FailedValidation Backup failed validation: Backup storage location "secondary" is read-only`,
+			},
+		},
+		{
+			name:             "backup with an unavailable storage location fails validation",
+			key:              "heptio-ark/backup1",
+			backup:           NewTestBackup().WithName("backup1").WithPhase(v1.BackupPhaseNew).WithStorageLocation("secondary"),
+			storageLocations: map[string]string{"secondary": "other-bucket"},
+			unavailable:      "secondary",
+			expectBackup:     false,
+			expectedEvents: []string{
+				`Warning FailedValidation Backup failed validation: Backup storage location "secondary" is unavailable: error listing bucket other-bucket: access denied`,
+			},
+		},
+		{
+			name:             "incremental backup whose parent is in another storage location fails validation",
+			key:              "heptio-ark/backup1",
+			backup:           NewTestBackup().WithName("backup1").WithPhase(v1.BackupPhaseNew).WithStorageLocation("secondary").WithParentBackup("parent"),
+			parentBackup:     NewTestBackup().WithName("parent").WithPhase(v1.BackupPhaseCompleted),
+			storageLocations: map[string]string{"secondary": "other-bucket"},
+			expectBackup:     false,
+		},
 	}
 
 	// flag.Set("logtostderr", "true")
@@ -156,16 +336,45 @@ func TestProcessBackup(t *testing.T) {
 			backupper := &fakeBackupper{}
 
 			cloudBackups := &fakeBackupService{}
+			if test.existingBackup != nil {
+				cloudBackups.backupsByBucket = map[string][]*v1.Backup{"bucket": {test.existingBackup.Backup}}
+			}
+
+			var backupService cloudprovider.BackupService = cloudBackups
+			if test.readOnlyBucket != "" {
+				backupService = cloudprovider.NewBucketRoutingBackupService(cloudBackups, map[string]cloudprovider.BackupService{
+					test.readOnlyBucket: cloudprovider.NewReadOnlyBackupService(cloudBackups),
+				})
+			}
 
 			sharedInformers := informers.NewSharedInformerFactory(client, 0)
 
+			recorder := &FakeEventRecorder{}
+
 			c := NewBackupController(
 				sharedInformers.Ark().V1().Backups(),
+				sharedInformers.Ark().V1().BackupStorageLocations(),
 				client.ArkV1(),
+				recorder,
 				backupper,
-				cloudBackups,
+				backupService,
+				nil,
+				nil,
+				nil,
 				"bucket",
+				test.storageLocations,
+				test.defaultLocation,
+				test.clusterName,
+				test.clusterUID,
+				test.immutable,
 				test.allowSnapshots,
+				test.resticEnabled,
+				false,
+				nil,
+				test.defaultExcludes,
+				test.excludeCompleted,
+				metrics.NewServerMetrics(),
+				DefaultTerminationGracePeriod,
 			).(*backupController)
 			c.clock = clock.NewFakeClock(time.Now())
 
@@ -173,6 +382,21 @@ func TestProcessBackup(t *testing.T) {
 
 			var expectedNSes []string
 
+			if test.parentBackup != nil {
+				sharedInformers.Ark().V1().Backups().Informer().GetStore().Add(test.parentBackup.Backup)
+			}
+
+			if test.unavailable != "" {
+				sharedInformers.Ark().V1().BackupStorageLocations().Informer().GetStore().Add(&v1.BackupStorageLocation{
+					ObjectMeta: metav1.ObjectMeta{Namespace: v1.DefaultNamespace, Name: test.unavailable},
+					Status: v1.BackupStorageLocationStatus{
+						Phase:   v1.BackupStorageLocationPhaseUnavailable,
+						Reason:  "ListFailed",
+						Message: "error listing bucket other-bucket: access denied",
+					},
+				})
+			}
+
 			if test.backup != nil {
 				// add directly to the informer's store so the lister can function and so we don't have to
 				// start the shared informers.
@@ -188,6 +412,7 @@ func TestProcessBackup(t *testing.T) {
 				backup := copy.(*v1.Backup)
 				backup.Spec.IncludedResources = test.expectedIncludes
 				backup.Spec.ExcludedResources = test.expectedExcludes
+				backup.Spec.ExcludeCompletedPods = test.excludeCompleted && !test.backup.Spec.IgnoreDefaultExcludes
 
 				if test.backup.Spec.IncludedNamespaces == nil {
 					expectedNSes = []string{"*"}
@@ -200,9 +425,20 @@ func TestProcessBackup(t *testing.T) {
 				backup.Status.Phase = v1.BackupPhaseInProgress
 				backup.Status.Expiration.Time = expiration
 				backup.Status.Version = 1
-				backupper.On("Backup", backup, mock.Anything).Return(nil)
+				backup.Status.StorageBucket = test.expectedBucket
+				if test.clusterName != "" {
+					backup.Labels = map[string]string{
+						v1.ClusterNameLabel: test.clusterName,
+						v1.ClusterUIDLabel:  test.clusterUID,
+					}
+				}
+				backupper.On("Backup", backup, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
 
-				cloudBackups.On("UploadBackup", "bucket", backup.Name, mock.Anything, mock.Anything).Return(nil)
+				bucket := test.expectedBucket
+				if bucket == "" {
+					bucket = "bucket"
+				}
+				cloudBackups.On("UploadBackup", bucket, backup.Name, mock.Anything, mock.Anything, mock.Anything).Return(nil)
 			}
 
 			// this is necessary so the Update() call returns the appropriate object
@@ -226,43 +462,52 @@ func TestProcessBackup(t *testing.T) {
 			}
 			require.NoError(t, err, "processBackup unexpected error: %v", err)
 
+			if test.expectedEvents != nil {
+				assert.Equal(t, test.expectedEvents, recorder.Events)
+			}
+
 			if !test.expectBackup {
 				assert.Empty(t, backupper.Calls)
 				assert.Empty(t, cloudBackups.Calls)
 				return
 			}
 
+			expectedBackup := func(phase v1.BackupPhase) *v1.Backup {
+				b := NewTestBackup().
+					WithName(test.backup.Name).
+					WithPhase(phase).
+					WithIncludedResources(test.expectedIncludes...).
+					WithExcludedResources(test.expectedExcludes...).
+					WithIncludedNamespaces(expectedNSes...).
+					WithTTL(test.backup.Spec.TTL.Duration).
+					WithSnapshotVolumesPointer(test.backup.Spec.SnapshotVolumes).
+					WithMoveVolumeData(test.backup.Spec.MoveVolumeData).
+					WithIgnoreDefaultExcludes(test.backup.Spec.IgnoreDefaultExcludes).
+					WithExcludeCompletedPods(test.excludeCompleted && !test.backup.Spec.IgnoreDefaultExcludes).
+					WithStorageLocation(test.backup.Spec.StorageLocation).
+					WithStorageBucket(test.expectedBucket).
+					WithExpiration(expiration).
+					WithVersion(1)
+				if test.clusterName != "" {
+					b = b.WithLabel(v1.ClusterNameLabel, test.clusterName).WithLabel(v1.ClusterUIDLabel, test.clusterUID)
+				}
+				if phase == v1.BackupPhaseCompleted {
+					b = b.WithCompletionTimestamp(c.clock.Now())
+				}
+				return b.Backup
+			}
+
 			expectedActions := []core.Action{
 				core.NewUpdateAction(
 					v1.SchemeGroupVersion.WithResource("backups"),
 					v1.DefaultNamespace,
-					NewTestBackup().
-						WithName(test.backup.Name).
-						WithPhase(v1.BackupPhaseInProgress).
-						WithIncludedResources(test.expectedIncludes...).
-						WithExcludedResources(test.expectedExcludes...).
-						WithIncludedNamespaces(expectedNSes...).
-						WithTTL(test.backup.Spec.TTL.Duration).
-						WithSnapshotVolumesPointer(test.backup.Spec.SnapshotVolumes).
-						WithExpiration(expiration).
-						WithVersion(1).
-						Backup,
+					expectedBackup(v1.BackupPhaseInProgress),
 				),
 
 				core.NewUpdateAction(
 					v1.SchemeGroupVersion.WithResource("backups"),
 					v1.DefaultNamespace,
-					NewTestBackup().
-						WithName(test.backup.Name).
-						WithPhase(v1.BackupPhaseCompleted).
-						WithIncludedResources(test.expectedIncludes...).
-						WithExcludedResources(test.expectedExcludes...).
-						WithIncludedNamespaces(expectedNSes...).
-						WithTTL(test.backup.Spec.TTL.Duration).
-						WithSnapshotVolumesPointer(test.backup.Spec.SnapshotVolumes).
-						WithExpiration(expiration).
-						WithVersion(1).
-						Backup,
+					expectedBackup(v1.BackupPhaseCompleted),
 				),
 			}
 
@@ -270,3 +515,797 @@ func TestProcessBackup(t *testing.T) {
 		})
 	}
 }
+
+func TestProcessBackupWaitsForUnfinishedParent(t *testing.T) {
+	for _, phase := range []v1.BackupPhase{v1.BackupPhaseNew, v1.BackupPhaseInProgress} {
+		t.Run(string(phase), func(t *testing.T) {
+			client := fake.NewSimpleClientset()
+			backupper := &fakeBackupper{}
+			sharedInformers := informers.NewSharedInformerFactory(client, 0)
+
+			c := NewBackupController(
+				sharedInformers.Ark().V1().Backups(),
+				sharedInformers.Ark().V1().BackupStorageLocations(),
+				client.ArkV1(),
+				&FakeEventRecorder{},
+				backupper,
+				&fakeBackupService{},
+				nil,
+				nil,
+				nil,
+				"bucket",
+				nil,
+				"",
+				"",
+				"",
+				false,
+				false,
+				false,
+				false,
+				nil,
+				nil,
+				false,
+				metrics.NewServerMetrics(),
+				DefaultTerminationGracePeriod,
+			).(*backupController)
+
+			sharedInformers.Ark().V1().Backups().Informer().GetStore().Add(NewTestBackup().WithName("parent").WithPhase(phase).Backup)
+			sharedInformers.Ark().V1().Backups().Informer().GetStore().Add(NewTestBackup().WithName("backup1").WithPhase(v1.BackupPhaseNew).WithParentBackup("parent").Backup)
+
+			require.NoError(t, c.processBackup("heptio-ark/backup1"))
+
+			// the backup is left untouched, to be processed again later
+			assert.Empty(t, client.Actions())
+			assert.Empty(t, backupper.Calls)
+
+			c.queue.ShutDown()
+		})
+	}
+}
+
+func TestRunBackupUploadsLogOfFailedBackup(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	backupper := &fakeBackupper{}
+	cloudBackups := &fakeBackupService{}
+	sharedInformers := informers.NewSharedInformerFactory(client, 0)
+
+	c := NewBackupController(
+		sharedInformers.Ark().V1().Backups(),
+		sharedInformers.Ark().V1().BackupStorageLocations(),
+		client.ArkV1(),
+		&FakeEventRecorder{},
+		backupper,
+		cloudBackups,
+		nil,
+		nil,
+		nil,
+		"bucket",
+		nil,
+		"",
+		"",
+		"",
+		false,
+		false,
+		false,
+		false,
+		nil,
+		nil,
+		false,
+		metrics.NewServerMetrics(),
+		DefaultTerminationGracePeriod,
+	).(*backupController)
+
+	backup := NewTestBackup().WithName("backup1").WithPhase(v1.BackupPhaseInProgress).Backup
+
+	backupper.On("Backup", backup, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(errors.New("backup failed"))
+	cloudBackups.On("UploadBackupLog", "bucket", "backup1", mock.Anything).Return(nil)
+
+	assert.Error(t, c.runBackup(context.Background(), backup, "bucket"))
+	cloudBackups.AssertCalled(t, "UploadBackupLog", "bucket", "backup1", mock.Anything)
+	cloudBackups.AssertNotCalled(t, "UploadBackup", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestRunBackupUploadsItemList(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	backupper := &fakeBackupper{}
+	cloudBackups := &fakeBackupService{}
+	sharedInformers := informers.NewSharedInformerFactory(client, 0)
+
+	c := NewBackupController(
+		sharedInformers.Ark().V1().Backups(),
+		sharedInformers.Ark().V1().BackupStorageLocations(),
+		client.ArkV1(),
+		&FakeEventRecorder{},
+		backupper,
+		cloudBackups,
+		nil,
+		nil,
+		nil,
+		"bucket",
+		nil,
+		"",
+		"",
+		"",
+		false,
+		false,
+		false,
+		false,
+		nil,
+		nil,
+		false,
+		metrics.NewServerMetrics(),
+		DefaultTerminationGracePeriod,
+	).(*backupController)
+
+	testBackup := NewTestBackup().WithName("backup1").WithPhase(v1.BackupPhaseInProgress).Backup
+
+	backupper.On("Backup", testBackup, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Run(func(args mock.Arguments) {
+			index := []byte(`{"namespaces/ns-1/pods/pod-1.json": "1", "cluster/namespaces/ns-1.json": "2"}`)
+
+			gzw := gzip.NewWriter(args.Get(2).(io.Writer))
+			tw := tar.NewWriter(gzw)
+			require.NoError(t, tw.WriteHeader(&tar.Header{Name: v1.ItemIndexFile, Size: int64(len(index)), Typeflag: tar.TypeReg, Mode: 0755}))
+			_, err := tw.Write(index)
+			require.NoError(t, err)
+			require.NoError(t, tw.Close())
+			require.NoError(t, gzw.Close())
+		}).
+		Return(nil)
+	cloudBackups.On("UploadBackup", "bucket", "backup1", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	cloudBackups.On("UploadBackupItemList", "bucket", "backup1", mock.Anything).Return(nil)
+
+	require.NoError(t, c.runBackup(context.Background(), testBackup, "bucket"))
+	cloudBackups.AssertCalled(t, "UploadBackupItemList", "bucket", "backup1", mock.Anything)
+
+	var items []backup.Item
+	for _, call := range cloudBackups.Calls {
+		if call.Method != "UploadBackupItemList" {
+			continue
+		}
+		gzr, err := gzip.NewReader(call.Arguments.Get(2).(io.Reader))
+		require.NoError(t, err)
+		require.NoError(t, json.NewDecoder(gzr).Decode(&items))
+	}
+	assert.Equal(t, []backup.Item{
+		{Resource: "namespaces", Name: "ns-1"},
+		{Resource: "pods", Namespace: "ns-1", Name: "pod-1"},
+	}, items)
+}
+
+func TestProcessBackupCanceledBeforeStart(t *testing.T) {
+	backup := NewTestBackup().WithName("backup1").WithPhase(v1.BackupPhaseNew).WithAnnotation(v1.CancelAnnotation, "true").Backup
+	client := fake.NewSimpleClientset(backup)
+	backupper := &fakeBackupper{}
+	recorder := &FakeEventRecorder{}
+	sharedInformers := informers.NewSharedInformerFactory(client, 0)
+
+	c := NewBackupController(
+		sharedInformers.Ark().V1().Backups(),
+		sharedInformers.Ark().V1().BackupStorageLocations(),
+		client.ArkV1(),
+		recorder,
+		backupper,
+		&fakeBackupService{},
+		nil,
+		nil,
+		nil,
+		"bucket",
+		nil,
+		"",
+		"",
+		"",
+		false,
+		false,
+		false,
+		false,
+		nil,
+		nil,
+		false,
+		metrics.NewServerMetrics(),
+		DefaultTerminationGracePeriod,
+	).(*backupController)
+
+	sharedInformers.Ark().V1().Backups().Informer().GetStore().Add(backup)
+
+	require.NoError(t, c.processBackup(runningKey(backup)))
+
+	updated, err := client.ArkV1().Backups(backup.Namespace).Get("backup1", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, v1.BackupPhaseCanceled, updated.Status.Phase)
+	assert.Equal(t, []string{"Normal BackupCanceled Canceled backup before it started"}, recorder.Events)
+	backupper.AssertNotCalled(t, "Backup", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestRunBackupCanceled(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	backupper := &fakeBackupper{}
+	cloudBackups := &fakeBackupService{}
+	sharedInformers := informers.NewSharedInformerFactory(client, 0)
+
+	c := NewBackupController(
+		sharedInformers.Ark().V1().Backups(),
+		sharedInformers.Ark().V1().BackupStorageLocations(),
+		client.ArkV1(),
+		&FakeEventRecorder{},
+		backupper,
+		cloudBackups,
+		nil,
+		nil,
+		nil,
+		"bucket",
+		nil,
+		"",
+		"",
+		"",
+		false,
+		false,
+		false,
+		false,
+		nil,
+		nil,
+		false,
+		metrics.NewServerMetrics(),
+		DefaultTerminationGracePeriod,
+	).(*backupController)
+
+	testBackup := NewTestBackup().WithName("backup1").WithPhase(v1.BackupPhaseInProgress).Backup
+
+	backupper.On("Backup", testBackup, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Run(func(mock.Arguments) { c.cancelRunning(runningKey(testBackup)) }).
+		Return(backup.ErrCanceled)
+
+	assert.Equal(t, context.Canceled, c.runBackup(context.Background(), testBackup, "bucket"))
+	cloudBackups.AssertNotCalled(t, "UploadBackup", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	cloudBackups.AssertNotCalled(t, "UploadBackupLog", mock.Anything, mock.Anything, mock.Anything)
+	assert.Empty(t, c.running)
+}
+
+func TestProcessBackupInterrupted(t *testing.T) {
+	testBackup := NewTestBackup().WithName("backup1").WithPhase(v1.BackupPhaseNew).Backup
+	client := fake.NewSimpleClientset(testBackup)
+	backupper := &fakeBackupper{}
+	cloudBackups := &fakeBackupService{}
+	recorder := &FakeEventRecorder{}
+	sharedInformers := informers.NewSharedInformerFactory(client, 0)
+
+	c := NewBackupController(
+		sharedInformers.Ark().V1().Backups(),
+		sharedInformers.Ark().V1().BackupStorageLocations(),
+		client.ArkV1(),
+		recorder,
+		backupper,
+		cloudBackups,
+		nil,
+		nil,
+		nil,
+		"bucket",
+		nil,
+		"",
+		"",
+		"",
+		false,
+		false,
+		false,
+		false,
+		nil,
+		nil,
+		false,
+		metrics.NewServerMetrics(),
+		DefaultTerminationGracePeriod,
+	).(*backupController)
+
+	sharedInformers.Ark().V1().Backups().Informer().GetStore().Add(testBackup)
+
+	backupper.On("Backup", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Run(func(mock.Arguments) { c.interruptRunning(false) }).
+		Return(backup.ErrCanceled)
+
+	require.NoError(t, c.processBackup(runningKey(testBackup)))
+
+	updated, err := client.ArkV1().Backups(testBackup.Namespace).Get("backup1", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, v1.BackupStatus{Phase: v1.BackupPhaseNew}, updated.Status)
+	assert.Equal(t, []string{
+		"Normal BackupStarted Started backup",
+		"Normal BackupInterrupted Backup was interrupted by the server stopping, and will be run again",
+	}, recorder.Events)
+	cloudBackups.AssertNotCalled(t, "UploadBackup", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	assert.Empty(t, c.interrupted)
+}
+
+func TestInterruptRunningSkipsUploadingBackups(t *testing.T) {
+	c := &backupController{
+		running:     make(map[string]context.CancelFunc),
+		uploading:   sets.NewString(),
+		interrupted: sets.NewString(),
+	}
+
+	running := NewTestBackup().WithName("running").Backup
+	runningCtx, cancel := context.WithCancel(context.Background())
+	c.running[runningKey(running)] = cancel
+
+	uploading := NewTestBackup().WithName("uploading").Backup
+	uploadingCtx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	c.running[runningKey(uploading)] = cancel
+	c.startUploading(uploading)
+
+	c.interruptRunning(false)
+
+	assert.Error(t, runningCtx.Err())
+	assert.NoError(t, uploadingCtx.Err())
+	assert.True(t, c.wasInterrupted(running))
+	assert.False(t, c.wasInterrupted(uploading))
+
+	// once uploads have had their time, they're interrupted too.
+	c.interruptRunning(true)
+
+	assert.Error(t, uploadingCtx.Err())
+	assert.True(t, c.wasInterrupted(uploading))
+}
+
+func TestContextReadSeekerFailsOnceCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	r := contextReadSeeker{strings.NewReader("data"), ctx}
+
+	buf := make([]byte, 2)
+	n, err := r.Read(buf)
+	require.NoError(t, err)
+	assert.Equal(t, "da", string(buf[:n]))
+
+	cancel()
+
+	_, err = r.Read(buf)
+	assert.Equal(t, context.Canceled, err)
+}
+
+type fakeCSISnapshotter struct {
+	deleted []string
+}
+
+func (s *fakeCSISnapshotter) CreateSnapshot(claimNamespace, claimName, name string) (*v1.CSISnapshotInfo, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (s *fakeCSISnapshotter) PrepareRestore(info *v1.CSISnapshotInfo, namespace, name string) error {
+	return errors.New("not implemented")
+}
+
+func (s *fakeCSISnapshotter) DeleteSnapshot(info *v1.CSISnapshotInfo) error {
+	s.deleted = append(s.deleted, info.SnapshotHandle)
+	return nil
+}
+
+type fakePodVolumeSnapshotDeleter struct {
+	// failing is the ref of a snapshot that can't be deleted.
+	failing string
+	deleted []string
+}
+
+func (d *fakePodVolumeSnapshotDeleter) DeleteSnapshot(repoNamespace, ref string) error {
+	if ref == d.failing {
+		return errors.New("snapshot can't be deleted")
+	}
+	d.deleted = append(d.deleted, repoNamespace+"/"+ref)
+	return nil
+}
+
+func TestDeleteSnapshots(t *testing.T) {
+	snapshotService := &FakeSnapshotService{SnapshotsTaken: sets.NewString("snap-1")}
+	csiSnapshotter := &fakeCSISnapshotter{}
+	podVolumeSnapshots := &fakePodVolumeSnapshotDeleter{failing: "kopia:k789"}
+	c := &backupController{snapshotService: snapshotService, csiSnapshotter: csiSnapshotter, podVolumeSnapshots: podVolumeSnapshots}
+
+	backup := NewTestBackup().WithName("backup1").
+		WithSnapshot("pv-1", "snap-1").
+		WithCSISnapshot("pv-2", "csi.example.com", "handle-2").
+		Backup
+	backup.Status.PodVolumeSnapshots = []v1.PodVolumeSnapshotInfo{
+		{RepoNamespace: "ns-1", Snapshot: "abc123"},
+		{RepoNamespace: "ns-2", Snapshot: "kopia:k456"},
+		{RepoNamespace: "ns-3", Snapshot: "kopia:k789"},
+	}
+
+	c.deleteSnapshots(backup)
+
+	assert.Empty(t, snapshotService.SnapshotsTaken)
+	assert.Equal(t, []string{"handle-2"}, csiSnapshotter.deleted)
+	assert.Empty(t, backup.Status.VolumeBackups)
+	assert.Equal(t, []string{"ns-1/abc123", "ns-2/kopia:k456"}, podVolumeSnapshots.deleted)
+	assert.Equal(t, []v1.PodVolumeSnapshotInfo{{RepoNamespace: "ns-3", Snapshot: "kopia:k789"}}, backup.Status.PodVolumeSnapshots)
+}
+
+func TestDeleteSnapshotsWithoutCSISnapshotter(t *testing.T) {
+	c := &backupController{}
+
+	backup := NewTestBackup().WithName("backup1").
+		WithCSISnapshot("pv-2", "csi.example.com", "handle-2").
+		Backup
+
+	c.deleteSnapshots(backup)
+
+	assert.Contains(t, backup.Status.VolumeBackups, "pv-2")
+}
+
+func TestBackupProgressUpdaterRecordsItemErrors(t *testing.T) {
+	recorder := &FakeEventRecorder{}
+	updater := newBackupProgressUpdater(fake.NewSimpleClientset().ArkV1(), recorder, NewTestBackup().WithName("backup1").Backup)
+
+	for i := 0; i < maxItemErrorEvents+5; i++ {
+		updater.ReportItemError(fmt.Errorf("error %d", i))
+	}
+
+	require.Len(t, recorder.Events, maxItemErrorEvents)
+	assert.Equal(t, "Warning ItemBackupFailed error 0", recorder.Events[0])
+	assert.Equal(t, "Warning ItemBackupFailed error 9 (further item errors won't be recorded as events; run 'ark backup logs backup1' for details)", recorder.Events[maxItemErrorEvents-1])
+}
+
+func TestRecordCompletionEvents(t *testing.T) {
+	tests := []struct {
+		name           string
+		backup         *v1.Backup
+		expectedEvents []string
+	}{
+		{
+			name: "completed backup with snapshots",
+			backup: NewTestBackup().WithName("backup1").WithPhase(v1.BackupPhaseCompleted).
+				WithSnapshot("pv-2", "snap-2").
+				WithSnapshot("pv-1", "snap-1").
+				Backup,
+			expectedEvents: []string{
+				"Normal SnapshotCreated Created snapshot snap-1 of PersistentVolume pv-1",
+				"Normal SnapshotCreated Created snapshot snap-2 of PersistentVolume pv-2",
+				"Normal BackupCompleted Backup completed with 0 warning(s)",
+			},
+		},
+		{
+			name: "partially failed backup",
+			backup: func() *v1.Backup {
+				backup := NewTestBackup().WithName("backup1").WithPhase(v1.BackupPhasePartiallyFailed).Backup
+				backup.Status.Errors = 2
+				backup.Status.Warnings = 1
+				return backup
+			}(),
+			expectedEvents: []string{
+				"Warning BackupPartiallyFailed Backup completed with 2 error(s) and 1 warning(s); run 'ark backup logs backup1' for details",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			recorder := &FakeEventRecorder{}
+			c := &backupController{recorder: recorder}
+
+			c.recordCompletionEvents(test.backup)
+
+			assert.Equal(t, test.expectedEvents, recorder.Events)
+		})
+	}
+}
+
+func TestBackupProgressUpdater(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	backup := NewTestBackup().WithName("backup1").Backup
+
+	client.PrependReactor("patch", "backups", func(action core.Action) (bool, runtime.Object, error) {
+		patched := NewTestBackup().WithName("backup1").Backup
+		patched.ResourceVersion = "2"
+		return true, patched, nil
+	})
+
+	updater := newBackupProgressUpdater(client.ArkV1(), &FakeEventRecorder{}, backup)
+
+	// nothing reported yet, so flush should be a no-op
+	updater.flush()
+	assert.Empty(t, client.Actions())
+	assert.Equal(t, "", updater.resourceVersion())
+
+	updater.ReportProgress(v1.BackupProgress{TotalItems: 10, ItemsBackedUp: 3})
+	updater.ReportProgress(v1.BackupProgress{TotalItems: 10, ItemsBackedUp: 4})
+	updater.flush()
+
+	expectedActions := []core.Action{
+		core.NewPatchAction(
+			v1.SchemeGroupVersion.WithResource("backups"),
+			v1.DefaultNamespace,
+			"backup1",
+			[]byte(`{"status":{"progress":{"totalItems":10,"itemsBackedUp":4,"volumeSnapshotsAttempted":0,"volumeSnapshotsCompleted":0,"podVolumeBackupsAttempted":0,"podVolumeBackupsCompleted":0}}}`),
+		),
+	}
+	assert.Equal(t, expectedActions, client.Actions())
+	assert.Equal(t, "2", updater.resourceVersion())
+
+	// no new progress since the last flush, so this shouldn't patch again
+	updater.flush()
+	assert.Len(t, client.Actions(), 1)
+
+	// checkpoints are flushed as soon as they're reported
+	updater.ReportCheckpoint(v1.BackupCheckpoint{CompletedResources: []string{"configmaps"}})
+
+	expectedActions = append(expectedActions,
+		core.NewPatchAction(
+			v1.SchemeGroupVersion.WithResource("backups"),
+			v1.DefaultNamespace,
+			"backup1",
+			[]byte(`{"status":{"checkpoint":{"completedResources":["configmaps"]},"progress":{"totalItems":10,"itemsBackedUp":4,"volumeSnapshotsAttempted":0,"volumeSnapshotsCompleted":0,"podVolumeBackupsAttempted":0,"podVolumeBackupsCompleted":0}}}`),
+		),
+	)
+	assert.Equal(t, expectedActions, client.Actions())
+
+	updater.flush()
+	assert.Len(t, client.Actions(), 2)
+}
+
+func TestFailInterruptedBackups(t *testing.T) {
+	interrupted := NewTestBackup().WithName("interrupted").WithPhase(v1.BackupPhaseInProgress).Backup
+	interrupted.Status.Checkpoint = &v1.BackupCheckpoint{
+		CompletedResources: []string{"configmaps", "persistentvolumes"},
+		VolumeBackups: map[string]*v1.VolumeBackupInfo{
+			"pv-1": {SnapshotID: "snap-1"},
+		},
+	}
+	completed := NewTestBackup().WithName("completed").WithPhase(v1.BackupPhaseCompleted).WithSnapshot("pv-2", "snap-2").Backup
+
+	client := fake.NewSimpleClientset(interrupted, completed)
+	snapshotService := &FakeSnapshotService{SnapshotsTaken: sets.NewString("snap-1", "snap-2")}
+	recorder := &FakeEventRecorder{}
+	sharedInformers := informers.NewSharedInformerFactory(client, 0)
+
+	c := NewBackupController(
+		sharedInformers.Ark().V1().Backups(),
+		sharedInformers.Ark().V1().BackupStorageLocations(),
+		client.ArkV1(),
+		recorder,
+		&fakeBackupper{},
+		&fakeBackupService{},
+		snapshotService,
+		nil,
+		nil,
+		"bucket",
+		nil,
+		"",
+		"",
+		"",
+		false,
+		true,
+		false,
+		false,
+		nil,
+		nil,
+		false,
+		metrics.NewServerMetrics(),
+		DefaultTerminationGracePeriod,
+	).(*backupController)
+
+	sharedInformers.Ark().V1().Backups().Informer().GetStore().Add(interrupted)
+	sharedInformers.Ark().V1().Backups().Informer().GetStore().Add(completed)
+
+	c.failInterruptedBackups()
+
+	updated, err := client.ArkV1().Backups(interrupted.Namespace).Get("interrupted", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, v1.BackupPhaseFailed, updated.Status.Phase)
+	assert.Nil(t, updated.Status.Checkpoint)
+	assert.Empty(t, updated.Status.VolumeBackups)
+	assert.Equal(t, []string{"snap-2"}, snapshotService.SnapshotsTaken.List())
+	assert.Equal(t, []string{"Warning BackupFailed Backup was interrupted by a server restart after backing up 2 resource(s)"}, recorder.Events)
+
+	updated, err = client.ArkV1().Backups(completed.Namespace).Get("completed", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, v1.BackupPhaseCompleted, updated.Status.Phase)
+}
+
+func TestGetValidationErrorsInTenantMode(t *testing.T) {
+	tests := []struct {
+		name     string
+		backup   *v1.Backup
+		expected []string
+	}{
+		{
+			name:   "backups in the server's namespace aren't constrained",
+			backup: NewTestBackup().WithName("backup-1").WithIncludedResources("*").WithIncludedNamespaces("team-a", "team-b").Backup,
+		},
+		{
+			name:   "backups in tenant namespaces can include their own namespace",
+			backup: NewTestBackup().WithNamespace("team-a").WithName("backup-1").WithIncludedResources("*").WithIncludedNamespaces("team-a").Backup,
+		},
+		{
+			name:   "backups in tenant namespaces can't include other namespaces",
+			backup: NewTestBackup().WithNamespace("team-a").WithName("backup-1").WithIncludedResources("*").WithIncludedNamespaces("team-a", "team-b").Backup,
+			expected: []string{
+				"Namespace team-b can't be included; backups and restores in namespace team-a can only include it",
+			},
+		},
+		{
+			name:   "backup names must be unique across namespaces",
+			backup: NewTestBackup().WithNamespace("team-a").WithName("existing").WithIncludedResources("*").WithIncludedNamespaces("*").Backup,
+			expected: []string{
+				"Backup name existing is already used in namespace team-b; backup names must be unique across namespaces",
+			},
+		},
+		{
+			name:   "backup names must be unique across namespaces in object storage",
+			backup: NewTestBackup().WithNamespace("team-a").WithName("stored").WithIncludedResources("*").WithIncludedNamespaces("*").Backup,
+			expected: []string{
+				"Backup name stored is already used in object storage by a backup in namespace team-c; backup names must be unique across namespaces",
+			},
+		},
+		{
+			name:   "backup names must be unique across namespaces for backups being processed",
+			backup: NewTestBackup().WithNamespace("team-a").WithName("processing").WithIncludedResources("*").WithIncludedNamespaces("*").Backup,
+			expected: []string{
+				"Backup name processing is already used in namespace team-d; backup names must be unique across namespaces",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			client := fake.NewSimpleClientset()
+			sharedInformers := informers.NewSharedInformerFactory(client, 0)
+
+			c := NewBackupController(
+				sharedInformers.Ark().V1().Backups(),
+				sharedInformers.Ark().V1().BackupStorageLocations(),
+				client.ArkV1(),
+				&FakeEventRecorder{},
+				&fakeBackupper{},
+				&fakeBackupService{backupsByBucket: map[string][]*v1.Backup{
+					"bucket": {NewTestBackup().WithNamespace("team-c").WithName("stored").Backup},
+				}},
+				nil,
+				nil,
+				nil,
+				"bucket",
+				nil,
+				"",
+				"",
+				"",
+				false,
+				false,
+				false,
+				true,
+				nil,
+				nil,
+				false,
+				metrics.NewServerMetrics(),
+				DefaultTerminationGracePeriod,
+			).(*backupController)
+
+			sharedInformers.Ark().V1().Backups().Informer().GetStore().Add(NewTestBackup().WithNamespace("team-b").WithName("existing").Backup)
+			require.NoError(t, c.reserveBackupName(NewTestBackup().WithNamespace("team-d").WithName("processing").Backup, "bucket"))
+
+			assert.Equal(t, test.expected, c.getValidationErrors(test.backup))
+		})
+	}
+}
+
+func TestProcessBackupInTenantNamespace(t *testing.T) {
+	testBackup := NewTestBackup().WithNamespace("team-a").WithName("backup1").WithPhase(v1.BackupPhaseNew).Backup
+	client := fake.NewSimpleClientset(testBackup)
+	backupper := &fakeBackupper{}
+	cloudBackups := &fakeBackupService{backupsByBucket: map[string][]*v1.Backup{"bucket": nil}}
+	sharedInformers := informers.NewSharedInformerFactory(client, 0)
+
+	c := NewBackupController(
+		sharedInformers.Ark().V1().Backups(),
+		sharedInformers.Ark().V1().BackupStorageLocations(),
+		client.ArkV1(),
+		&FakeEventRecorder{},
+		backupper,
+		cloudBackups,
+		nil,
+		nil,
+		nil,
+		"bucket",
+		nil,
+		"",
+		"",
+		"",
+		false,
+		false,
+		false,
+		true,
+		nil,
+		nil,
+		false,
+		metrics.NewServerMetrics(),
+		DefaultTerminationGracePeriod,
+	).(*backupController)
+
+	sharedInformers.Ark().V1().Backups().Informer().GetStore().Add(testBackup)
+
+	var spec v1.BackupSpec
+	backupper.On("Backup", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Run(func(args mock.Arguments) { spec = args.Get(0).(*v1.Backup).Spec }).
+		Return(errors.New("backup failed"))
+	cloudBackups.On("UploadBackupLog", "bucket", "backup1", mock.Anything).Return(nil)
+
+	require.NoError(t, c.processBackup("team-a/backup1"))
+
+	assert.Equal(t, []string{"team-a"}, spec.IncludedNamespaces)
+	require.NotNil(t, spec.IncludeClusterResources)
+	assert.False(t, *spec.IncludeClusterResources)
+
+	// the backup's name is released once it has been processed.
+	assert.Empty(t, c.backupNames)
+}
+
+func TestGetQuotaErrors(t *testing.T) {
+	withSize := func(b *v1.Backup, size int64) *v1.Backup {
+		b.Status.TarballSize = size
+		return b
+	}
+
+	tests := []struct {
+		name     string
+		quota    v1.TenantQuotaConfig
+		expected []string
+	}{
+		{
+			name:  "backups within the quota are allowed",
+			quota: v1.TenantQuotaConfig{MaxConcurrentBackups: 2, MaxBackups: 4, MaxStoredBytes: resource.MustParse("2Ki")},
+		},
+		{
+			name:  "no limits",
+			quota: v1.TenantQuotaConfig{},
+		},
+		{
+			name:  "backups exceeding the quota are rejected",
+			quota: v1.TenantQuotaConfig{MaxConcurrentBackups: 1, MaxBackups: 3, MaxStoredBytes: resource.MustParse("1Ki")},
+			expected: []string{
+				"Namespace team-a already has 1 backup(s) running, the most its quota allows",
+				"Namespace team-a already has 3 backup(s) stored, the most its quota allows",
+				"Namespace team-a's stored backup tarballs already use 1100, the most its quota allows",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			client := fake.NewSimpleClientset()
+			sharedInformers := informers.NewSharedInformerFactory(client, 0)
+
+			c := NewBackupController(
+				sharedInformers.Ark().V1().Backups(),
+				sharedInformers.Ark().V1().BackupStorageLocations(),
+				client.ArkV1(),
+				&FakeEventRecorder{},
+				&fakeBackupper{},
+				&fakeBackupService{},
+				nil,
+				nil,
+				nil,
+				"bucket",
+				nil,
+				"",
+				"",
+				"",
+				false,
+				false,
+				false,
+				true,
+				&test.quota,
+				nil,
+				false,
+				metrics.NewServerMetrics(),
+				DefaultTerminationGracePeriod,
+			).(*backupController)
+
+			for _, backup := range []*v1.Backup{
+				NewTestBackup().WithNamespace("team-a").WithName("running").WithPhase(v1.BackupPhaseInProgress).Backup,
+				withSize(NewTestBackup().WithNamespace("team-a").WithName("completed").WithPhase(v1.BackupPhaseCompleted).Backup, 600),
+				withSize(NewTestBackup().WithNamespace("team-a").WithName("partially-failed").WithPhase(v1.BackupPhasePartiallyFailed).Backup, 500),
+				NewTestBackup().WithNamespace("team-a").WithName("failed").WithPhase(v1.BackupPhaseFailed).Backup,
+				withSize(NewTestBackup().WithNamespace("team-b").WithName("other-namespace").WithPhase(v1.BackupPhaseCompleted).Backup, 5000),
+			} {
+				sharedInformers.Ark().V1().Backups().Informer().GetStore().Add(backup)
+			}
+
+			backup := NewTestBackup().WithNamespace("team-a").WithName("new").WithPhase(v1.BackupPhaseNew).Backup
+			assert.Equal(t, test.expected, c.getQuotaErrors(backup))
+		})
+	}
+}
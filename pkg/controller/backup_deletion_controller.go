@@ -0,0 +1,408 @@
+/*
+Copyright 2017 Heptio Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/clock"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/pkg/api/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+
+	api "github.com/heptio/ark/pkg/apis/ark/v1"
+	"github.com/heptio/ark/pkg/cloudprovider"
+	"github.com/heptio/ark/pkg/csi"
+	"github.com/heptio/ark/pkg/event"
+	"github.com/heptio/ark/pkg/generated/clientset/scheme"
+	arkv1client "github.com/heptio/ark/pkg/generated/clientset/typed/ark/v1"
+	informers "github.com/heptio/ark/pkg/generated/informers/externalversions/ark/v1"
+	listers "github.com/heptio/ark/pkg/generated/listers/ark/v1"
+	"github.com/heptio/ark/pkg/restic"
+)
+
+type backupDeletionController struct {
+	requestClient   arkv1client.DeleteBackupRequestsGetter
+	backupClient    arkv1client.BackupsGetter
+	backupService   cloudprovider.BackupService
+	snapshotService cloudprovider.SnapshotService
+	csiSnapshotter  csi.Snapshotter
+	// podVolumeSnapshots deletes the backup's restic and kopia snapshots.
+	podVolumeSnapshots restic.SnapshotDeleter
+	bucket             string
+	immutableBackups   bool
+	recorder           event.Recorder
+
+	requestLister       listers.DeleteBackupRequestLister
+	requestListerSynced cache.InformerSynced
+	backupLister        listers.BackupLister
+	backupListerSynced  cache.InformerSynced
+	syncHandler         func(requestName string) error
+	queue               workqueue.RateLimitingInterface
+
+	clock clock.Clock
+}
+
+// NewBackupDeletionController returns a controller that deletes backups, along with their
+// volume snapshots and data in object storage, in response to DeleteBackupRequests.
+// snapshotService may be nil if the server isn't configured for PV snapshots, csiSnapshotter if it
+// isn't configured for CSI snapshots, and podVolumeSnapshots if it isn't configured for restic. If
+// immutableBackups is true, backups that have run to completion aren't deleted until they expire.
+func NewBackupDeletionController(
+	requestInformer informers.DeleteBackupRequestInformer,
+	requestClient arkv1client.DeleteBackupRequestsGetter,
+	backupInformer informers.BackupInformer,
+	backupClient arkv1client.BackupsGetter,
+	backupService cloudprovider.BackupService,
+	snapshotService cloudprovider.SnapshotService,
+	csiSnapshotter csi.Snapshotter,
+	podVolumeSnapshots restic.SnapshotDeleter,
+	bucket string,
+	immutableBackups bool,
+	recorder event.Recorder,
+) Interface {
+	c := &backupDeletionController{
+		requestClient:       requestClient,
+		backupClient:        backupClient,
+		backupService:       backupService,
+		snapshotService:     snapshotService,
+		csiSnapshotter:      csiSnapshotter,
+		podVolumeSnapshots:  podVolumeSnapshots,
+		bucket:              bucket,
+		immutableBackups:    immutableBackups,
+		recorder:            recorder,
+		requestLister:       requestInformer.Lister(),
+		requestListerSynced: requestInformer.Informer().HasSynced,
+		backupLister:        backupInformer.Lister(),
+		backupListerSynced:  backupInformer.Informer().HasSynced,
+		queue:               workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "backupdeletion"),
+
+		clock: &clock.RealClock{},
+	}
+
+	c.syncHandler = c.processRequest
+
+	requestInformer.Informer().AddEventHandler(
+		cache.ResourceEventHandlerFuncs{
+			AddFunc: func(obj interface{}) {
+				req := obj.(*api.DeleteBackupRequest)
+
+				switch req.Status.Phase {
+				case "", api.DeleteBackupRequestPhaseNew:
+					// only process new requests
+				default:
+					glog.V(4).Infof("DeleteBackupRequest %s/%s has phase %s - skipping", req.Namespace, req.Name, req.Status.Phase)
+					return
+				}
+
+				key, err := cache.MetaNamespaceKeyFunc(req)
+				if err != nil {
+					glog.Errorf("error creating queue key for %#v: %v", req, err)
+					return
+				}
+				c.queue.Add(key)
+			},
+		},
+	)
+
+	return c
+}
+
+// Run is a blocking function that runs the specified number of worker goroutines
+// to process items in the work queue. It will return when it receives on the
+// ctx.Done() channel.
+func (controller *backupDeletionController) Run(ctx context.Context, numWorkers int) error {
+	var wg sync.WaitGroup
+
+	defer func() {
+		glog.Infof("Waiting for workers to finish their work")
+
+		controller.queue.ShutDown()
+
+		// We have to wait here in the deferred function instead of at the bottom of the function body
+		// because we have to shut down the queue in order for the workers to shut down gracefully, and
+		// we want to shut down the queue via defer and not at the end of the body.
+		wg.Wait()
+
+		glog.Infof("All workers have finished")
+	}()
+
+	glog.Info("Starting BackupDeletionController")
+	defer glog.Info("Shutting down BackupDeletionController")
+
+	glog.Info("Waiting for caches to sync")
+	if !cache.WaitForCacheSync(ctx.Done(), controller.requestListerSynced, controller.backupListerSynced) {
+		return errors.New("timed out waiting for caches to sync")
+	}
+	glog.Info("Caches are synced")
+
+	wg.Add(numWorkers)
+	for i := 0; i < numWorkers; i++ {
+		go func() {
+			wait.Until(controller.runWorker, time.Second, ctx.Done())
+			wg.Done()
+		}()
+	}
+
+	<-ctx.Done()
+
+	return nil
+}
+
+func (controller *backupDeletionController) runWorker() {
+	// continually take items off the queue (waits if it's
+	// empty) until we get a shutdown signal from the queue
+	for controller.processNextWorkItem() {
+	}
+}
+
+func (controller *backupDeletionController) processNextWorkItem() bool {
+	key, quit := controller.queue.Get()
+	if quit {
+		return false
+	}
+	// always call done on this item, since if it fails we'll add
+	// it back with rate-limiting below
+	defer controller.queue.Done(key)
+
+	err := controller.syncHandler(key.(string))
+	if err == nil {
+		// If you had no error, tell the queue to stop tracking history for your key. This will reset
+		// things like failure counts for per-item rate limiting.
+		controller.queue.Forget(key)
+		return true
+	}
+
+	glog.Errorf("syncHandler error: %v", err)
+	// we had an error processing the item so add it back
+	// into the queue for re-processing with rate-limiting
+	controller.queue.AddRateLimited(key)
+
+	return true
+}
+
+func (controller *backupDeletionController) processRequest(key string) error {
+	glog.V(4).Infof("processRequest for key %q", key)
+	ns, name, err := cache.SplitMetaNamespaceKey(key)
+	if err != nil {
+		glog.V(4).Infof("error splitting key %q: %v", key, err)
+		return err
+	}
+
+	glog.V(4).Infof("Getting delete backup request %s", key)
+	req, err := controller.requestLister.DeleteBackupRequests(ns).Get(name)
+	if err != nil {
+		glog.V(4).Infof("error getting delete backup request %s: %v", key, err)
+		return err
+	}
+
+	switch req.Status.Phase {
+	case "", api.DeleteBackupRequestPhaseNew:
+		// only process new requests
+	default:
+		return nil
+	}
+
+	glog.V(4).Infof("Cloning delete backup request %s", key)
+	// don't modify items in the cache
+	req, err = cloneDeleteBackupRequest(req)
+	if err != nil {
+		glog.V(4).Infof("error cloning delete backup request %s: %v", key, err)
+		return err
+	}
+
+	req.Status.Phase = api.DeleteBackupRequestPhaseInProgress
+
+	// update status
+	updatedReq, err := controller.requestClient.DeleteBackupRequests(ns).Update(req)
+	if err != nil {
+		glog.V(4).Infof("error updating status to %s: %v", req.Status.Phase, err)
+		return err
+	}
+	req = updatedReq
+
+	glog.Infof("Deleting backup %s/%s as requested by %s", ns, req.Spec.BackupName, key)
+	req.Status.Errors = controller.deleteBackup(ns, req.Spec.BackupName, &req.Status)
+	req.Status.Phase = api.DeleteBackupRequestPhaseProcessed
+	req.Status.ProcessedTimestamp = metav1.NewTime(controller.clock.Now())
+
+	if len(req.Status.Errors) > 0 {
+		controller.recorder.Eventf(req, v1.EventTypeWarning, event.ReasonBackupDeletionFailed, "Failed to delete backup %s: %s", req.Spec.BackupName, strings.Join(req.Status.Errors, "; "))
+	} else {
+		controller.recorder.Eventf(req, v1.EventTypeNormal, event.ReasonBackupDeleted, "Deleted backup %s", req.Spec.BackupName)
+	}
+
+	glog.V(4).Infof("updating delete backup request %s final status", key)
+	if _, err = controller.requestClient.DeleteBackupRequests(ns).Update(req); err != nil {
+		glog.V(4).Infof("error updating delete backup request %s final status: %v", key, err)
+	}
+
+	return nil
+}
+
+func cloneDeleteBackupRequest(in interface{}) (*api.DeleteBackupRequest, error) {
+	clone, err := scheme.Scheme.DeepCopy(in)
+	if err != nil {
+		return nil, err
+	}
+
+	out, ok := clone.(*api.DeleteBackupRequest)
+	if !ok {
+		return nil, fmt.Errorf("unexpected type: %T", clone)
+	}
+
+	return out, nil
+}
+
+// deleteBackup deletes a backup's volume snapshots, its data in object storage, and finally the
+// Backup itself, and returns the errors encountered. The Backup is only deleted if everything
+// else was, so that a failed deletion can be retried with a new request. What was deleted is
+// recorded in status.
+func (controller *backupDeletionController) deleteBackup(namespace, name string, status *api.DeleteBackupRequestStatus) []string {
+	backup, err := controller.backupLister.Backups(namespace).Get(name)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return []string{fmt.Sprintf("backup %s not found", name)}
+		}
+		return []string{fmt.Sprintf("error getting backup %s: %v", name, err)}
+	}
+
+	switch backup.Status.Phase {
+	case "", api.BackupPhaseNew, api.BackupPhaseInProgress:
+		return []string{fmt.Sprintf("backup %s hasn't finished running; cancel it with 'ark backup cancel %s' before deleting it", name, name)}
+	}
+
+	// the admission webhook would refuse to delete the API object, so don't
+	// delete anything else first.
+	if controller.immutableBackups && (backup.Status.Phase == api.BackupPhaseCompleted || backup.Status.Phase == api.BackupPhasePartiallyFailed) {
+		if expiration := backupExpiration(backup); !expiration.Before(controller.clock.Now()) {
+			return []string{fmt.Sprintf("backup %s is immutable and can't be deleted until it expires at %s", name, expiration.UTC().Format(time.RFC3339))}
+		}
+	}
+
+	children, err := controller.incrementalChildren(backup)
+	if err != nil {
+		return []string{fmt.Sprintf("error listing backups: %v", err)}
+	}
+	if len(children) > 0 {
+		return []string{fmt.Sprintf("backup %s is the parent of incremental backup(s) %v, which must be deleted first", name, children)}
+	}
+
+	snapshotIDs := cloudSnapshotIDs(backup)
+	if controller.snapshotService == nil && len(snapshotIDs) > 0 {
+		return []string{fmt.Sprintf("backup %s includes snapshots but the server has no volume snapshot locations", name)}
+	}
+
+	volumeSnapshots := csiSnapshots(backup)
+	if controller.csiSnapshotter == nil && len(volumeSnapshots) > 0 {
+		return []string{fmt.Sprintf("backup %s includes CSI snapshots but the server isn't configured for CSI snapshots", name)}
+	}
+
+	podVolumeSnapshots := backup.Status.PodVolumeSnapshots
+	if controller.podVolumeSnapshots == nil && len(podVolumeSnapshots) > 0 {
+		return []string{fmt.Sprintf("backup %s includes restic snapshots but the server isn't configured for restic", name)}
+	}
+
+	var errs []string
+
+	snapshotServices, err := snapshotServicesForLocations(controller.snapshotService, snapshotIDs)
+	if err != nil {
+		return []string{err.Error()}
+	}
+
+	for location, ids := range snapshotIDs {
+		for _, snapshotID := range ids {
+			glog.Infof("Removing snapshot %s associated with backup %s/%s", snapshotID, namespace, name)
+			if err := snapshotServices[location].DeleteSnapshot(snapshotID); err != nil {
+				errs = append(errs, fmt.Sprintf("error deleting snapshot %s: %v", snapshotID, err))
+				continue
+			}
+			status.DeletedSnapshots = append(status.DeletedSnapshots, snapshotID)
+		}
+	}
+
+	for _, snapshot := range volumeSnapshots {
+		glog.Infof("Removing VolumeSnapshot %s/%s associated with backup %s/%s", snapshot.VolumeSnapshotNamespace, snapshot.VolumeSnapshotName, namespace, name)
+		if err := controller.csiSnapshotter.DeleteSnapshot(snapshot); err != nil {
+			errs = append(errs, fmt.Sprintf("error deleting VolumeSnapshot %s/%s: %v", snapshot.VolumeSnapshotNamespace, snapshot.VolumeSnapshotName, err))
+			continue
+		}
+		status.DeletedSnapshots = append(status.DeletedSnapshots, snapshot.SnapshotHandle)
+	}
+
+	for _, snapshot := range podVolumeSnapshots {
+		glog.Infof("Removing pod volume snapshot %s associated with backup %s/%s", snapshot.Snapshot, namespace, name)
+		if err := controller.podVolumeSnapshots.DeleteSnapshot(snapshot.RepoNamespace, snapshot.Snapshot); err != nil {
+			errs = append(errs, fmt.Sprintf("error deleting pod volume snapshot %s: %v", snapshot.Snapshot, err))
+			continue
+		}
+		status.DeletedSnapshots = append(status.DeletedSnapshots, snapshot.Snapshot)
+	}
+
+	// only backups that ran to completion were uploaded.
+	if backup.Status.Phase == api.BackupPhaseCompleted || backup.Status.Phase == api.BackupPhasePartiallyFailed {
+		glog.Infof("Removing backup %s/%s from object storage", namespace, name)
+		if err := controller.backupService.DeleteBackup(backupBucket(backup, controller.bucket), name); err != nil {
+			errs = append(errs, fmt.Sprintf("error deleting backup from object storage: %v", err))
+		} else {
+			status.BackupDataDeleted = true
+		}
+	} else {
+		status.BackupDataDeleted = true
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+
+	glog.Infof("Removing backup API object %s/%s", namespace, name)
+	if err := controller.backupClient.Backups(namespace).Delete(name, &metav1.DeleteOptions{}); err != nil {
+		return []string{fmt.Sprintf("error deleting backup API object: %v", err)}
+	}
+
+	return nil
+}
+
+// incrementalChildren returns the sorted names of the incremental backups whose parent is backup.
+func (controller *backupDeletionController) incrementalChildren(backup *api.Backup) ([]string, error) {
+	backups, err := controller.backupLister.Backups(backup.Namespace).List(labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+
+	var children []string
+	for _, other := range backups {
+		if other.Spec.ParentBackup == backup.Name {
+			children = append(children, other.Name)
+		}
+	}
+	sort.Strings(children)
+
+	return children, nil
+}
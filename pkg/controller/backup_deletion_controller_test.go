@@ -0,0 +1,391 @@
+/*
+Copyright 2017 Heptio Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/clock"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	api "github.com/heptio/ark/pkg/apis/ark/v1"
+	"github.com/heptio/ark/pkg/cloudprovider"
+	"github.com/heptio/ark/pkg/generated/clientset/fake"
+	informers "github.com/heptio/ark/pkg/generated/informers/externalversions"
+	. "github.com/heptio/ark/pkg/util/test"
+)
+
+func TestBackupDeletionDeleteBackup(t *testing.T) {
+	tests := []struct {
+		name              string
+		backups           []*api.Backup
+		immutable         bool
+		snapshotService   *FakeSnapshotService
+		expectedErrors    []string
+		expectedSnapshots []string
+		expectedStatus    api.DeleteBackupRequestStatus
+		expectDeleted     bool
+	}{
+		{
+			name:              "completed backup is deleted along with its snapshots and data",
+			backups:           []*api.Backup{NewTestBackup().WithName("backup-1").WithPhase(api.BackupPhaseCompleted).WithSnapshot("pv-1", "snap-1").Backup},
+			snapshotService:   &FakeSnapshotService{SnapshotsTaken: sets.NewString("snap-1", "snap-2")},
+			expectedSnapshots: []string{"snap-2"},
+			expectedStatus:    api.DeleteBackupRequestStatus{DeletedSnapshots: []string{"snap-1"}, BackupDataDeleted: true},
+			expectDeleted:     true,
+		},
+		{
+			name:           "missing backup is reported",
+			expectedErrors: []string{"backup backup-1 not found"},
+		},
+		{
+			name:           "running backup isn't deleted",
+			backups:        []*api.Backup{NewTestBackup().WithName("backup-1").WithPhase(api.BackupPhaseInProgress).Backup},
+			expectedErrors: []string{"backup backup-1 hasn't finished running; cancel it with 'ark backup cancel backup-1' before deleting it"},
+		},
+		{
+			name: "parent of an incremental backup isn't deleted",
+			backups: []*api.Backup{
+				NewTestBackup().WithName("backup-1").WithPhase(api.BackupPhaseCompleted).Backup,
+				NewTestBackup().WithName("backup-2").WithPhase(api.BackupPhaseCompleted).WithParentBackup("backup-1").Backup,
+			},
+			expectedErrors: []string{"backup backup-1 is the parent of incremental backup(s) [backup-2], which must be deleted first"},
+		},
+		{
+			name:           "backup with snapshots isn't deleted without a snapshot service",
+			backups:        []*api.Backup{NewTestBackup().WithName("backup-1").WithPhase(api.BackupPhaseCompleted).WithSnapshot("pv-1", "snap-1").Backup},
+			expectedErrors: []string{"backup backup-1 includes snapshots but the server has no volume snapshot locations"},
+		},
+		{
+			name:              "failure to delete a snapshot leaves the backup in place",
+			backups:           []*api.Backup{NewTestBackup().WithName("backup-1").WithPhase(api.BackupPhaseCompleted).WithSnapshot("pv-1", "snap-1").Backup},
+			snapshotService:   &FakeSnapshotService{SnapshotsTaken: sets.NewString("snap-1", "snap-2"), DeleteSnapshotError: errors.New("rate limited")},
+			expectedErrors:    []string{"error deleting snapshot snap-1: rate limited"},
+			expectedSnapshots: []string{"snap-1", "snap-2"},
+			expectedStatus:    api.DeleteBackupRequestStatus{BackupDataDeleted: true},
+		},
+		{
+			name:              "snapshot that was already deleted doesn't fail a retried deletion",
+			backups:           []*api.Backup{NewTestBackup().WithName("backup-1").WithPhase(api.BackupPhaseCompleted).WithSnapshot("pv-1", "snap-1").Backup},
+			snapshotService:   &FakeSnapshotService{SnapshotsTaken: sets.NewString("snap-2")},
+			expectedSnapshots: []string{"snap-2"},
+			expectedStatus:    api.DeleteBackupRequestStatus{DeletedSnapshots: []string{"snap-1"}, BackupDataDeleted: true},
+			expectDeleted:     true,
+		},
+		{
+			name:           "unexpired immutable backup isn't deleted",
+			backups:        []*api.Backup{NewTestBackup().WithName("backup-1").WithPhase(api.BackupPhaseCompleted).WithExpiration(time.Date(2017, 3, 9, 0, 0, 0, 0, time.UTC)).Backup},
+			immutable:      true,
+			expectedErrors: []string{"backup backup-1 is immutable and can't be deleted until it expires at 2017-03-09T00:00:00Z"},
+		},
+		{
+			name:           "expired immutable backup is deleted",
+			backups:        []*api.Backup{NewTestBackup().WithName("backup-1").WithPhase(api.BackupPhaseCompleted).WithExpiration(time.Date(2017, 3, 7, 0, 0, 0, 0, time.UTC)).Backup},
+			immutable:      true,
+			expectedStatus: api.DeleteBackupRequestStatus{BackupDataDeleted: true},
+			expectDeleted:  true,
+		},
+		{
+			name:           "failed backup is deleted without touching object storage",
+			backups:        []*api.Backup{NewTestBackup().WithName("backup-1").WithPhase(api.BackupPhaseFailed).Backup},
+			expectedStatus: api.DeleteBackupRequestStatus{BackupDataDeleted: true},
+			expectDeleted:  true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			client := fake.NewSimpleClientset()
+			sharedInformers := informers.NewSharedInformerFactory(client, 0)
+			backupService := &fakeBackupService{backupsByBucket: map[string][]*api.Backup{"bucket": nil}}
+
+			for _, backup := range test.backups {
+				sharedInformers.Ark().V1().Backups().Informer().GetStore().Add(backup)
+				_, err := client.ArkV1().Backups(backup.Namespace).Create(backup)
+				require.NoError(t, err)
+
+				if backup.Status.Phase == api.BackupPhaseCompleted {
+					backupService.backupsByBucket["bucket"] = append(backupService.backupsByBucket["bucket"], backup)
+				}
+			}
+
+			c := NewBackupDeletionController(
+				sharedInformers.Ark().V1().DeleteBackupRequests(),
+				client.ArkV1(),
+				sharedInformers.Ark().V1().Backups(),
+				client.ArkV1(),
+				backupService,
+				nil,
+				nil,
+				nil,
+				"bucket",
+				test.immutable,
+				&FakeEventRecorder{},
+			).(*backupDeletionController)
+			c.clock = clock.NewFakeClock(time.Date(2017, 3, 8, 0, 0, 0, 0, time.UTC))
+			// assigning a nil *FakeSnapshotService would leave a non-nil interface
+			if test.snapshotService != nil {
+				c.snapshotService = test.snapshotService
+			}
+
+			var status api.DeleteBackupRequestStatus
+			errs := c.deleteBackup(api.DefaultNamespace, "backup-1", &status)
+			assert.Equal(t, test.expectedErrors, errs)
+			assert.Equal(t, test.expectedStatus, status)
+
+			if test.snapshotService != nil {
+				assert.Equal(t, test.expectedSnapshots, test.snapshotService.SnapshotsTaken.List())
+			}
+
+			_, err := client.ArkV1().Backups(api.DefaultNamespace).Get("backup-1", metav1.GetOptions{})
+			if test.expectDeleted {
+				assert.True(t, apierrors.IsNotFound(err), "expected backup to be deleted, got %v", err)
+				_, err = backupService.GetBackup("bucket", "backup-1")
+				assert.Error(t, err, "expected backup to be deleted from object storage")
+			} else if len(test.backups) > 0 {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestBackupDeletionDeleteSnapshotsInLocations(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	sharedInformers := informers.NewSharedInformerFactory(client, 0)
+	backupService := &fakeBackupService{backupsByBucket: map[string][]*api.Backup{"bucket": nil}}
+
+	backup := NewTestBackup().WithName("backup-1").WithPhase(api.BackupPhaseCompleted).
+		WithVolumeSnapshotLocations("east").
+		WithSnapshot("pv-1", "snap-1").
+		WithSnapshot("pv-2", "snap-2").Backup
+	backup.Status.VolumeBackups["pv-2"].Location = "east"
+
+	sharedInformers.Ark().V1().Backups().Informer().GetStore().Add(backup)
+	_, err := client.ArkV1().Backups(backup.Namespace).Create(backup)
+	require.NoError(t, err)
+	backupService.backupsByBucket["bucket"] = []*api.Backup{backup}
+
+	defaultService := &FakeSnapshotService{SnapshotsTaken: sets.NewString("snap-1")}
+	eastService := &FakeSnapshotService{SnapshotsTaken: sets.NewString("snap-2")}
+
+	c := NewBackupDeletionController(
+		sharedInformers.Ark().V1().DeleteBackupRequests(),
+		client.ArkV1(),
+		sharedInformers.Ark().V1().Backups(),
+		client.ArkV1(),
+		backupService,
+		cloudprovider.NewSnapshotServiceWithLocations(
+			map[string]cloudprovider.SnapshotService{"default": defaultService, "east": eastService},
+			map[string]string{"default": "aws", "east": "aws"},
+			nil,
+		),
+		nil,
+		nil,
+		"bucket",
+		false,
+		&FakeEventRecorder{},
+	).(*backupDeletionController)
+
+	var status api.DeleteBackupRequestStatus
+	errs := c.deleteBackup(api.DefaultNamespace, "backup-1", &status)
+	assert.Empty(t, errs)
+	assert.Equal(t, []string{"snap-1", "snap-2"}, sets.NewString(status.DeletedSnapshots...).List())
+
+	// each snapshot is deleted from the location it was taken in
+	assert.Empty(t, defaultService.SnapshotsTaken.List())
+	assert.Empty(t, eastService.SnapshotsTaken.List())
+}
+func TestBackupDeletionProcessRequest(t *testing.T) {
+	req := &api.DeleteBackupRequest{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: api.DefaultNamespace,
+			Name:      "backup-1-abcde",
+		},
+		Spec: api.DeleteBackupRequestSpec{
+			BackupName: "backup-1",
+			Requester:  "alice",
+		},
+	}
+
+	client := fake.NewSimpleClientset(req)
+	sharedInformers := informers.NewSharedInformerFactory(client, 0)
+	sharedInformers.Ark().V1().DeleteBackupRequests().Informer().GetStore().Add(req)
+	recorder := &FakeEventRecorder{}
+
+	c := NewBackupDeletionController(
+		sharedInformers.Ark().V1().DeleteBackupRequests(),
+		client.ArkV1(),
+		sharedInformers.Ark().V1().Backups(),
+		client.ArkV1(),
+		&fakeBackupService{},
+		nil,
+		nil,
+		nil,
+		"bucket",
+		false,
+		recorder,
+	).(*backupDeletionController)
+	now := time.Now().Round(time.Second)
+	c.clock = clock.NewFakeClock(now)
+
+	require.NoError(t, c.processRequest(api.DefaultNamespace+"/backup-1-abcde"))
+
+	updated, err := client.ArkV1().DeleteBackupRequests(api.DefaultNamespace).Get("backup-1-abcde", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, api.DeleteBackupRequestPhaseProcessed, updated.Status.Phase)
+	assert.Equal(t, []string{"backup backup-1 not found"}, updated.Status.Errors)
+	assert.Equal(t, now, updated.Status.ProcessedTimestamp.Time)
+	assert.Equal(t, []string{"Warning BackupDeletionFailed Failed to delete backup backup-1: backup backup-1 not found"}, recorder.Events)
+}
+
+func TestBackupDeletionDeletesCSISnapshots(t *testing.T) {
+	tests := []struct {
+		name            string
+		csiSnapshotter  *fakeCSISnapshotter
+		expectedErrors  []string
+		expectedDeleted []string
+		expectedStatus  api.DeleteBackupRequestStatus
+	}{
+		{
+			name:            "VolumeSnapshots are deleted with the backup",
+			csiSnapshotter:  &fakeCSISnapshotter{},
+			expectedDeleted: []string{"handle-1"},
+			expectedStatus:  api.DeleteBackupRequestStatus{DeletedSnapshots: []string{"handle-1"}, BackupDataDeleted: true},
+		},
+		{
+			name:           "backup isn't deleted without a CSI snapshotter",
+			expectedErrors: []string{"backup backup-1 includes CSI snapshots but the server isn't configured for CSI snapshots"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			backup := NewTestBackup().WithName("backup-1").WithPhase(api.BackupPhaseCompleted).
+				WithCSISnapshot("pv-1", "csi.example.com", "handle-1").
+				Backup
+			backup.Status.VolumeBackups["pv-1"].CSISnapshot.VolumeSnapshotNamespace = "ns-1"
+			backup.Status.VolumeBackups["pv-1"].CSISnapshot.VolumeSnapshotName = "backup-1-pv-1"
+
+			client := fake.NewSimpleClientset(backup)
+			sharedInformers := informers.NewSharedInformerFactory(client, 0)
+			sharedInformers.Ark().V1().Backups().Informer().GetStore().Add(backup)
+			backupService := &fakeBackupService{backupsByBucket: map[string][]*api.Backup{"bucket": {backup}}}
+
+			c := NewBackupDeletionController(
+				sharedInformers.Ark().V1().DeleteBackupRequests(),
+				client.ArkV1(),
+				sharedInformers.Ark().V1().Backups(),
+				client.ArkV1(),
+				backupService,
+				nil,
+				nil,
+				nil,
+				"bucket",
+				false,
+				&FakeEventRecorder{},
+			).(*backupDeletionController)
+			// assigning a nil *fakeCSISnapshotter would leave a non-nil interface
+			if test.csiSnapshotter != nil {
+				c.csiSnapshotter = test.csiSnapshotter
+			}
+
+			var status api.DeleteBackupRequestStatus
+			errs := c.deleteBackup(api.DefaultNamespace, "backup-1", &status)
+			assert.Equal(t, test.expectedErrors, errs)
+			assert.Equal(t, test.expectedStatus, status)
+			if test.csiSnapshotter != nil {
+				assert.Equal(t, test.expectedDeleted, test.csiSnapshotter.deleted)
+			}
+		})
+	}
+}
+
+func TestBackupDeletionDeletesPodVolumeSnapshots(t *testing.T) {
+	tests := []struct {
+		name               string
+		podVolumeSnapshots *fakePodVolumeSnapshotDeleter
+		expectedErrors     []string
+		expectedDeleted    []string
+		expectedStatus     api.DeleteBackupRequestStatus
+	}{
+		{
+			name:               "restic and kopia snapshots are deleted with the backup",
+			podVolumeSnapshots: &fakePodVolumeSnapshotDeleter{},
+			expectedDeleted:    []string{"ns-1/abc123", "ns-2/kopia:k456"},
+			expectedStatus:     api.DeleteBackupRequestStatus{DeletedSnapshots: []string{"abc123", "kopia:k456"}, BackupDataDeleted: true},
+		},
+		{
+			name:               "backup isn't deleted if a snapshot can't be",
+			podVolumeSnapshots: &fakePodVolumeSnapshotDeleter{failing: "kopia:k456"},
+			expectedErrors:     []string{"error deleting pod volume snapshot kopia:k456: snapshot can't be deleted"},
+			expectedDeleted:    []string{"ns-1/abc123"},
+			expectedStatus:     api.DeleteBackupRequestStatus{DeletedSnapshots: []string{"abc123"}, BackupDataDeleted: true},
+		},
+		{
+			name:           "backup isn't deleted without restic",
+			expectedErrors: []string{"backup backup-1 includes restic snapshots but the server isn't configured for restic"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			backup := NewTestBackup().WithName("backup-1").WithPhase(api.BackupPhaseCompleted).Backup
+			backup.Status.PodVolumeSnapshots = []api.PodVolumeSnapshotInfo{
+				{RepoNamespace: "ns-1", Snapshot: "abc123"},
+				{RepoNamespace: "ns-2", Snapshot: "kopia:k456"},
+			}
+
+			client := fake.NewSimpleClientset(backup)
+			sharedInformers := informers.NewSharedInformerFactory(client, 0)
+			sharedInformers.Ark().V1().Backups().Informer().GetStore().Add(backup)
+			backupService := &fakeBackupService{backupsByBucket: map[string][]*api.Backup{"bucket": {backup}}}
+
+			c := NewBackupDeletionController(
+				sharedInformers.Ark().V1().DeleteBackupRequests(),
+				client.ArkV1(),
+				sharedInformers.Ark().V1().Backups(),
+				client.ArkV1(),
+				backupService,
+				nil,
+				nil,
+				nil,
+				"bucket",
+				false,
+				&FakeEventRecorder{},
+			).(*backupDeletionController)
+			// assigning a nil *fakePodVolumeSnapshotDeleter would leave a non-nil interface
+			if test.podVolumeSnapshots != nil {
+				c.podVolumeSnapshots = test.podVolumeSnapshots
+			}
+
+			var status api.DeleteBackupRequestStatus
+			errs := c.deleteBackup(api.DefaultNamespace, "backup-1", &status)
+			assert.Equal(t, test.expectedErrors, errs)
+			assert.Equal(t, test.expectedStatus, status)
+			if test.podVolumeSnapshots != nil {
+				assert.Equal(t, test.expectedDeleted, test.podVolumeSnapshots.deleted)
+			}
+		})
+	}
+}
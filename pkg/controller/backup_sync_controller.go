@@ -30,22 +30,24 @@ import (
 )
 
 type backupSyncController struct {
-	client        arkv1client.BackupsGetter
-	backupService cloudprovider.BackupService
-	bucket        string
-	syncPeriod    time.Duration
+	client          arkv1client.BackupsGetter
+	backupService   cloudprovider.BackupService
+	bucket          string
+	locationBuckets []string
+	syncPeriod      time.Duration
 }
 
-func NewBackupSyncController(client arkv1client.BackupsGetter, backupService cloudprovider.BackupService, bucket string, syncPeriod time.Duration) Interface {
+func NewBackupSyncController(client arkv1client.BackupsGetter, backupService cloudprovider.BackupService, bucket string, storageLocations map[string]string, syncPeriod time.Duration) Interface {
 	if syncPeriod < time.Minute {
 		glog.Infof("Backup sync period %v is too short. Setting to 1 minute", syncPeriod)
 		syncPeriod = time.Minute
 	}
 	return &backupSyncController{
-		client:        client,
-		backupService: backupService,
-		bucket:        bucket,
-		syncPeriod:    syncPeriod,
+		client:          client,
+		backupService:   backupService,
+		bucket:          bucket,
+		locationBuckets: sortedBuckets(bucket, storageLocations),
+		syncPeriod:      syncPeriod,
 	}
 }
 
@@ -59,8 +61,16 @@ func (c *backupSyncController) Run(ctx context.Context, workers int) error {
 }
 
 func (c *backupSyncController) run() {
-	glog.Info("Syncing backups from object storage")
-	backups, err := c.backupService.GetAllBackups(c.bucket)
+	c.syncBucket(c.bucket)
+	for _, bucket := range c.locationBuckets {
+		c.syncBucket(bucket)
+	}
+}
+
+// syncBucket creates API objects for the backups in bucket that don't have them.
+func (c *backupSyncController) syncBucket(bucket string) {
+	glog.Infof("Syncing backups from object storage bucket %s", bucket)
+	backups, err := c.backupService.GetAllBackups(bucket)
 	if err != nil {
 		glog.Errorf("error listing backups: %v", err)
 		return
@@ -70,6 +80,16 @@ func (c *backupSyncController) run() {
 	for _, cloudBackup := range backups {
 		glog.Infof("Syncing backup %s/%s", cloudBackup.Namespace, cloudBackup.Name)
 		cloudBackup.ResourceVersion = ""
+		// the schedule that owned the backup may not exist in this cluster, and the garbage
+		// collector deletes objects whose owners don't exist
+		cloudBackup.OwnerReferences = nil
+		// the backup may have been stored in this bucket by another cluster, where it was in a
+		// different location
+		if bucket == c.bucket {
+			cloudBackup.Status.StorageBucket = ""
+		} else {
+			cloudBackup.Status.StorageBucket = bucket
+		}
 		if _, err := c.client.Backups(cloudBackup.Namespace).Create(cloudBackup); err != nil && !errors.IsAlreadyExists(err) {
 			glog.Errorf("error syncing backup %s/%s from object storage: %v", cloudBackup.Namespace, cloudBackup.Name, err)
 		}
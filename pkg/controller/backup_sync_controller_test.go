@@ -22,6 +22,7 @@ import (
 
 	"github.com/stretchr/testify/assert"
 
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	core "k8s.io/client-go/testing"
 
 	api "github.com/heptio/ark/pkg/apis/ark/v1"
@@ -31,9 +32,10 @@ import (
 
 func TestRun(t *testing.T) {
 	tests := []struct {
-		name         string
-		cloudBackups map[string][]*api.Backup
-		backupSvcErr error
+		name             string
+		cloudBackups     map[string][]*api.Backup
+		storageLocations map[string]string
+		backupSvcErr     error
 	}{
 		{
 			name: "no cloud backups",
@@ -56,6 +58,30 @@ func TestRun(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "owner references are removed",
+			cloudBackups: map[string][]*api.Backup{
+				"bucket": []*api.Backup{
+					NewTestBackup().WithNamespace("ns-1").WithName("backup-1").
+						WithOwnerReference(metav1.OwnerReference{Kind: "Schedule", Name: "daily", UID: "uid"}).Backup,
+				},
+			},
+		},
+		{
+			name: "backups in storage locations are synced with their bucket",
+			cloudBackups: map[string][]*api.Backup{
+				"bucket": []*api.Backup{
+					NewTestBackup().WithNamespace("ns-1").WithName("backup-1").WithStorageBucket("stale-bucket").Backup,
+				},
+				"other-bucket": []*api.Backup{
+					NewTestBackup().WithNamespace("ns-1").WithName("backup-2").WithStorageLocation("secondary").Backup,
+				},
+				"unconfigured-bucket": []*api.Backup{
+					NewTestBackup().WithNamespace("ns-1").WithName("backup-3").Backup,
+				},
+			},
+			storageLocations: map[string]string{"default": "bucket", "secondary": "other-bucket"},
+		},
 	}
 
 	for _, test := range tests {
@@ -69,6 +95,7 @@ func TestRun(t *testing.T) {
 				client.ArkV1(),
 				bs,
 				"bucket",
+				test.storageLocations,
 				time.Duration(0),
 			).(*backupSyncController)
 
@@ -76,18 +103,31 @@ func TestRun(t *testing.T) {
 
 			expectedActions := make([]core.Action, 0)
 
-			// we only expect creates for items within the target bucket
-			for _, cloudBackup := range test.cloudBackups["bucket"] {
-				action := core.NewCreateAction(
-					api.SchemeGroupVersion.WithResource("backups"),
-					cloudBackup.Namespace,
-					cloudBackup,
-				)
-
-				expectedActions = append(expectedActions, action)
+			// we only expect creates for items within the target bucket and the storage locations' buckets
+			expectedBuckets := map[string]string{}
+			for _, bucket := range append([]string{"bucket"}, sortedBuckets("bucket", test.storageLocations)...) {
+				for _, cloudBackup := range test.cloudBackups[bucket] {
+					action := core.NewCreateAction(
+						api.SchemeGroupVersion.WithResource("backups"),
+						cloudBackup.Namespace,
+						cloudBackup,
+					)
+
+					expectedActions = append(expectedActions, action)
+
+					if bucket != "bucket" {
+						expectedBuckets[cloudBackup.Name] = bucket
+					}
+				}
 			}
 
 			assert.Equal(t, expectedActions, client.Actions())
+
+			for _, action := range client.Actions() {
+				backup := action.(core.CreateAction).GetObject().(*api.Backup)
+				assert.Nil(t, backup.OwnerReferences)
+				assert.Equal(t, expectedBuckets[backup.Name], backup.Status.StorageBucket)
+			}
 		})
 	}
 }
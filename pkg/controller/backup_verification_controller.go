@@ -0,0 +1,380 @@
+/*
+Copyright 2017 Heptio Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+
+	api "github.com/heptio/ark/pkg/apis/ark/v1"
+	"github.com/heptio/ark/pkg/backup"
+	"github.com/heptio/ark/pkg/cloudprovider"
+	"github.com/heptio/ark/pkg/generated/clientset/scheme"
+	arkv1client "github.com/heptio/ark/pkg/generated/clientset/typed/ark/v1"
+	informers "github.com/heptio/ark/pkg/generated/informers/externalversions/ark/v1"
+	listers "github.com/heptio/ark/pkg/generated/listers/ark/v1"
+)
+
+type backupVerificationController struct {
+	verificationClient arkv1client.BackupVerificationsGetter
+	backupService      cloudprovider.BackupService
+	snapshotService    cloudprovider.SnapshotService
+	bucket             string
+
+	verificationLister       listers.BackupVerificationLister
+	verificationListerSynced cache.InformerSynced
+	backupLister             listers.BackupLister
+	backupListerSynced       cache.InformerSynced
+	syncHandler              func(verificationName string) error
+	queue                    workqueue.RateLimitingInterface
+}
+
+// NewBackupVerificationController returns a controller that checks the integrity of backups
+// in object storage in response to BackupVerifications. snapshotService may be nil if the
+// server isn't configured for PV snapshots.
+func NewBackupVerificationController(
+	verificationInformer informers.BackupVerificationInformer,
+	backupInformer informers.BackupInformer,
+	verificationClient arkv1client.BackupVerificationsGetter,
+	backupService cloudprovider.BackupService,
+	snapshotService cloudprovider.SnapshotService,
+	bucket string,
+) Interface {
+	c := &backupVerificationController{
+		verificationClient:       verificationClient,
+		backupService:            backupService,
+		snapshotService:          snapshotService,
+		bucket:                   bucket,
+		verificationLister:       verificationInformer.Lister(),
+		verificationListerSynced: verificationInformer.Informer().HasSynced,
+		backupLister:             backupInformer.Lister(),
+		backupListerSynced:       backupInformer.Informer().HasSynced,
+		queue:                    workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "backupverification"),
+	}
+
+	c.syncHandler = c.processVerification
+
+	verificationInformer.Informer().AddEventHandler(
+		cache.ResourceEventHandlerFuncs{
+			AddFunc: func(obj interface{}) {
+				verification := obj.(*api.BackupVerification)
+
+				switch verification.Status.Phase {
+				case "", api.BackupVerificationPhaseNew:
+					// only process new verifications
+				default:
+					glog.V(4).Infof("BackupVerification %s/%s has phase %s - skipping", verification.Namespace, verification.Name, verification.Status.Phase)
+					return
+				}
+
+				key, err := cache.MetaNamespaceKeyFunc(verification)
+				if err != nil {
+					glog.Errorf("error creating queue key for %#v: %v", verification, err)
+					return
+				}
+				c.queue.Add(key)
+			},
+		},
+	)
+
+	return c
+}
+
+// Run is a blocking function that runs the specified number of worker goroutines
+// to process items in the work queue. It will return when it receives on the
+// ctx.Done() channel.
+func (controller *backupVerificationController) Run(ctx context.Context, numWorkers int) error {
+	var wg sync.WaitGroup
+
+	defer func() {
+		glog.Infof("Waiting for workers to finish their work")
+
+		controller.queue.ShutDown()
+
+		// We have to wait here in the deferred function instead of at the bottom of the function body
+		// because we have to shut down the queue in order for the workers to shut down gracefully, and
+		// we want to shut down the queue via defer and not at the end of the body.
+		wg.Wait()
+
+		glog.Infof("All workers have finished")
+	}()
+
+	glog.Info("Starting BackupVerificationController")
+	defer glog.Info("Shutting down BackupVerificationController")
+
+	glog.Info("Waiting for caches to sync")
+	if !cache.WaitForCacheSync(ctx.Done(), controller.verificationListerSynced, controller.backupListerSynced) {
+		return errors.New("timed out waiting for caches to sync")
+	}
+	glog.Info("Caches are synced")
+
+	wg.Add(numWorkers)
+	for i := 0; i < numWorkers; i++ {
+		go func() {
+			wait.Until(controller.runWorker, time.Second, ctx.Done())
+			wg.Done()
+		}()
+	}
+
+	<-ctx.Done()
+
+	return nil
+}
+
+func (controller *backupVerificationController) runWorker() {
+	// continually take items off the queue (waits if it's
+	// empty) until we get a shutdown signal from the queue
+	for controller.processNextWorkItem() {
+	}
+}
+
+func (controller *backupVerificationController) processNextWorkItem() bool {
+	key, quit := controller.queue.Get()
+	if quit {
+		return false
+	}
+	// always call done on this item, since if it fails we'll add
+	// it back with rate-limiting below
+	defer controller.queue.Done(key)
+
+	err := controller.syncHandler(key.(string))
+	if err == nil {
+		// If you had no error, tell the queue to stop tracking history for your key. This will reset
+		// things like failure counts for per-item rate limiting.
+		controller.queue.Forget(key)
+		return true
+	}
+
+	glog.Errorf("syncHandler error: %v", err)
+	// we had an error processing the item so add it back
+	// into the queue for re-processing with rate-limiting
+	controller.queue.AddRateLimited(key)
+
+	return true
+}
+
+func (controller *backupVerificationController) processVerification(key string) error {
+	glog.V(4).Infof("processVerification for key %q", key)
+	ns, name, err := cache.SplitMetaNamespaceKey(key)
+	if err != nil {
+		glog.V(4).Infof("error splitting key %q: %v", key, err)
+		return err
+	}
+
+	glog.V(4).Infof("Getting backup verification %s", key)
+	verification, err := controller.verificationLister.BackupVerifications(ns).Get(name)
+	if err != nil {
+		glog.V(4).Infof("error getting backup verification %s: %v", key, err)
+		return err
+	}
+
+	switch verification.Status.Phase {
+	case "", api.BackupVerificationPhaseNew:
+		// only process new verifications
+	default:
+		return nil
+	}
+
+	glog.V(4).Infof("Cloning backup verification %s", key)
+	// don't modify items in the cache
+	verification, err = cloneBackupVerification(verification)
+	if err != nil {
+		glog.V(4).Infof("error cloning backup verification %s: %v", key, err)
+		return err
+	}
+
+	verification.Status.Phase = api.BackupVerificationPhaseInProgress
+
+	// update status
+	updatedVerification, err := controller.verificationClient.BackupVerifications(ns).Update(verification)
+	if err != nil {
+		glog.V(4).Infof("error updating status to %s: %v", verification.Status.Phase, err)
+		return err
+	}
+	verification = updatedVerification
+
+	glog.V(4).Infof("running checks for backup verification %s", key)
+	verification.Status.Checks = controller.runChecks(ns, verification.Spec.BackupName)
+
+	verification.Status.Passed, verification.Status.Unverified = verificationResult(verification.Status.Checks)
+	verification.Status.Phase = api.BackupVerificationPhaseCompleted
+
+	glog.V(4).Infof("updating backup verification %s final status", key)
+	if _, err = controller.verificationClient.BackupVerifications(ns).Update(verification); err != nil {
+		glog.V(4).Infof("error updating backup verification %s final status: %v", key, err)
+	}
+
+	return nil
+}
+
+func cloneBackupVerification(in interface{}) (*api.BackupVerification, error) {
+	clone, err := scheme.Scheme.DeepCopy(in)
+	if err != nil {
+		return nil, err
+	}
+
+	out, ok := clone.(*api.BackupVerification)
+	if !ok {
+		return nil, fmt.Errorf("unexpected type: %T", clone)
+	}
+
+	return out, nil
+}
+
+// runChecks runs each of the integrity checks against the backup in object storage and
+// returns their results.
+func (controller *backupVerificationController) runChecks(namespace, backupName string) []api.BackupVerificationCheck {
+	var checks []api.BackupVerificationCheck
+
+	// backups that haven't been synced into the cluster yet live in the default bucket
+	bucket := controller.bucket
+	if backup, err := controller.backupLister.Backups(namespace).Get(backupName); err == nil {
+		bucket = backupBucket(backup, controller.bucket)
+	}
+
+	metadata, err := controller.backupService.GetBackup(bucket, backupName)
+	if err != nil {
+		checks = append(checks, failedCheck(api.BackupVerificationCheckMetadata, "error reading backup metadata: %v", err))
+	} else {
+		checks = append(checks, passedCheck(api.BackupVerificationCheckMetadata, "backup metadata is valid"))
+	}
+
+	checksum, err := controller.archiveChecksum(bucket, backupName)
+	if err != nil {
+		checks = append(checks, failedCheck(api.BackupVerificationCheckArchive, "error reading backup archive: %v", err))
+	} else {
+		checks = append(checks, passedCheck(api.BackupVerificationCheckArchive, "backup archive is valid"))
+	}
+
+	switch {
+	case metadata == nil || checksum == "":
+		checks = append(checks, failedCheck(api.BackupVerificationCheckChecksum, "unable to compare checksums because the backup metadata or archive couldn't be read"))
+	case metadata.Status.ContentChecksum == "":
+		checks = append(checks, unverifiedCheck(api.BackupVerificationCheckChecksum, "backup has no recorded checksum to compare"))
+	case metadata.Status.ContentChecksum != checksum:
+		checks = append(checks, failedCheck(api.BackupVerificationCheckChecksum, "checksum %s does not match recorded checksum %s", checksum, metadata.Status.ContentChecksum))
+	default:
+		checks = append(checks, passedCheck(api.BackupVerificationCheckChecksum, "checksum matches"))
+	}
+
+	if metadata == nil {
+		checks = append(checks, failedCheck(api.BackupVerificationCheckSnapshots, "unable to check snapshots because the backup metadata couldn't be read"))
+	} else {
+		checks = append(checks, controller.checkSnapshots(metadata))
+	}
+
+	return checks
+}
+
+// archiveChecksum downloads the backup's tarball and returns its content checksum.
+func (controller *backupVerificationController) archiveChecksum(bucket, backupName string) (string, error) {
+	file, err := downloadToTempFile(backupName, controller.backupService, bucket)
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		if err := file.Close(); err != nil {
+			glog.Errorf("error closing file %s: %v", file.Name(), err)
+		}
+		if err := os.Remove(file.Name()); err != nil {
+			glog.Errorf("error removing file %s: %v", file.Name(), err)
+		}
+	}()
+
+	return backup.ContentChecksum(file)
+}
+
+// checkSnapshots verifies that each of the backup's volume snapshots exists in the
+// cloud provider. CSI snapshots aren't checked.
+func (controller *backupVerificationController) checkSnapshots(metadata *api.Backup) api.BackupVerificationCheck {
+	snapshotIDs := cloudSnapshotIDs(metadata)
+	if len(snapshotIDs) == 0 {
+		return passedCheck(api.BackupVerificationCheckSnapshots, "backup has no volume snapshots")
+	}
+
+	if controller.snapshotService == nil {
+		return failedCheck(api.BackupVerificationCheckSnapshots, "server is not configured for PV snapshots")
+	}
+
+	snapshotServices, err := snapshotServicesForLocations(controller.snapshotService, snapshotIDs)
+	if err != nil {
+		return failedCheck(api.BackupVerificationCheckSnapshots, "%v", err)
+	}
+
+	var missing []string
+	count := 0
+	for location, ids := range snapshotIDs {
+		snapshots, err := snapshotServices[location].GetAllSnapshots()
+		if err != nil {
+			return failedCheck(api.BackupVerificationCheckSnapshots, "error getting snapshots: %v", err)
+		}
+		existing := sets.NewString(snapshots...)
+
+		for _, snapshotID := range ids {
+			if !existing.Has(snapshotID) {
+				missing = append(missing, snapshotID)
+			}
+		}
+		count += len(ids)
+	}
+
+	if len(missing) > 0 {
+		return failedCheck(api.BackupVerificationCheckSnapshots, "missing snapshots: %s", strings.Join(sets.NewString(missing...).List(), ", "))
+	}
+
+	return passedCheck(api.BackupVerificationCheckSnapshots, "all %d volume snapshots exist", count)
+}
+
+func passedCheck(name, format string, args ...interface{}) api.BackupVerificationCheck {
+	return api.BackupVerificationCheck{Name: name, Passed: true, Message: fmt.Sprintf(format, args...)}
+}
+
+func failedCheck(name, format string, args ...interface{}) api.BackupVerificationCheck {
+	return api.BackupVerificationCheck{Name: name, Passed: false, Message: fmt.Sprintf(format, args...)}
+}
+
+func unverifiedCheck(name, format string, args ...interface{}) api.BackupVerificationCheck {
+	return api.BackupVerificationCheck{Name: name, Unverified: true, Message: fmt.Sprintf(format, args...)}
+}
+
+// verificationResult returns whether all of checks passed, and if not, whether none of them failed
+// but some couldn't be run.
+func verificationResult(checks []api.BackupVerificationCheck) (passed, unverified bool) {
+	passed = true
+	for _, check := range checks {
+		if check.Passed {
+			continue
+		}
+		if !check.Unverified {
+			return false, false
+		}
+		passed, unverified = false, true
+	}
+	return passed, unverified
+}
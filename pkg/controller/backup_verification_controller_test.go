@@ -0,0 +1,233 @@
+/*
+Copyright 2017 Heptio Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io/ioutil"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	api "github.com/heptio/ark/pkg/apis/ark/v1"
+	"github.com/heptio/ark/pkg/backup"
+	"github.com/heptio/ark/pkg/cloudprovider"
+	"github.com/heptio/ark/pkg/generated/clientset/fake"
+	informers "github.com/heptio/ark/pkg/generated/informers/externalversions"
+	. "github.com/heptio/ark/pkg/util/test"
+)
+
+func newTestTarball(t *testing.T, files map[string]string) []byte {
+	buf := new(bytes.Buffer)
+	gzw := gzip.NewWriter(buf)
+	tw := tar.NewWriter(gzw)
+
+	for name, data := range files {
+		require.NoError(t, tw.WriteHeader(&tar.Header{
+			Name:     name,
+			Size:     int64(len(data)),
+			Typeflag: tar.TypeReg,
+			Mode:     0755,
+		}))
+		_, err := tw.Write([]byte(data))
+		require.NoError(t, err)
+	}
+
+	require.NoError(t, tw.Close())
+	require.NoError(t, gzw.Close())
+
+	return buf.Bytes()
+}
+
+func TestBackupVerificationRunChecks(t *testing.T) {
+	tarball := newTestTarball(t, map[string]string{"namespaces/ns-1/pods/pod-1.json": "{}"})
+	checksum, err := backup.ContentChecksum(bytes.NewReader(tarball))
+	require.NoError(t, err)
+
+	tests := []struct {
+		name               string
+		metadata           *api.Backup
+		metadataErr        error
+		tarball            []byte
+		snapshotService    *FakeSnapshotService
+		expected           map[string]bool
+		expectedUnverified []string
+	}{
+		{
+			name:            "valid backup passes all checks",
+			metadata:        NewTestBackup().WithName("backup-1").WithContentChecksum(checksum).WithSnapshot("pv-1", "snap-1").Backup,
+			tarball:         tarball,
+			snapshotService: &FakeSnapshotService{SnapshotsTaken: sets.NewString("snap-1")},
+			expected: map[string]bool{
+				api.BackupVerificationCheckMetadata:  true,
+				api.BackupVerificationCheckArchive:   true,
+				api.BackupVerificationCheckChecksum:  true,
+				api.BackupVerificationCheckSnapshots: true,
+			},
+		},
+		{
+			name:        "unreadable metadata fails checks that depend on it",
+			metadataErr: errors.New("bad metadata"),
+			tarball:     tarball,
+			expected: map[string]bool{
+				api.BackupVerificationCheckMetadata:  false,
+				api.BackupVerificationCheckArchive:   true,
+				api.BackupVerificationCheckChecksum:  false,
+				api.BackupVerificationCheckSnapshots: false,
+			},
+		},
+		{
+			name:     "corrupt archive fails archive and checksum checks",
+			metadata: NewTestBackup().WithName("backup-1").WithContentChecksum(checksum).Backup,
+			tarball:  []byte("not a tarball"),
+			expected: map[string]bool{
+				api.BackupVerificationCheckMetadata:  true,
+				api.BackupVerificationCheckArchive:   false,
+				api.BackupVerificationCheckChecksum:  false,
+				api.BackupVerificationCheckSnapshots: true,
+			},
+		},
+		{
+			name:     "checksum mismatch fails checksum check",
+			metadata: NewTestBackup().WithName("backup-1").WithContentChecksum("abc").Backup,
+			tarball:  tarball,
+			expected: map[string]bool{
+				api.BackupVerificationCheckMetadata:  true,
+				api.BackupVerificationCheckArchive:   true,
+				api.BackupVerificationCheckChecksum:  false,
+				api.BackupVerificationCheckSnapshots: true,
+			},
+		},
+		{
+			name:     "backup without a recorded checksum leaves checksum check unverified",
+			metadata: NewTestBackup().WithName("backup-1").Backup,
+			tarball:  tarball,
+			expected: map[string]bool{
+				api.BackupVerificationCheckMetadata:  true,
+				api.BackupVerificationCheckArchive:   true,
+				api.BackupVerificationCheckChecksum:  false,
+				api.BackupVerificationCheckSnapshots: true,
+			},
+			expectedUnverified: []string{api.BackupVerificationCheckChecksum},
+		},
+		{
+			name:            "missing snapshot fails snapshots check",
+			metadata:        NewTestBackup().WithName("backup-1").WithContentChecksum(checksum).WithSnapshot("pv-1", "snap-1").WithSnapshot("pv-2", "snap-2").Backup,
+			tarball:         tarball,
+			snapshotService: &FakeSnapshotService{SnapshotsTaken: sets.NewString("snap-1")},
+			expected: map[string]bool{
+				api.BackupVerificationCheckMetadata:  true,
+				api.BackupVerificationCheckArchive:   true,
+				api.BackupVerificationCheckChecksum:  true,
+				api.BackupVerificationCheckSnapshots: false,
+			},
+		},
+		{
+			name:     "snapshots can't be checked without a snapshot service",
+			metadata: NewTestBackup().WithName("backup-1").WithContentChecksum(checksum).WithSnapshot("pv-1", "snap-1").Backup,
+			tarball:  tarball,
+			expected: map[string]bool{
+				api.BackupVerificationCheckMetadata:  true,
+				api.BackupVerificationCheckArchive:   true,
+				api.BackupVerificationCheckChecksum:  true,
+				api.BackupVerificationCheckSnapshots: false,
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var (
+				client          = fake.NewSimpleClientset()
+				sharedInformers = informers.NewSharedInformerFactory(client, 0)
+				backupService   = &FakeBackupService{}
+				snapshotService cloudprovider.SnapshotService
+			)
+
+			if test.snapshotService != nil {
+				snapshotService = test.snapshotService
+			}
+
+			c := NewBackupVerificationController(
+				sharedInformers.Ark().V1().BackupVerifications(),
+				sharedInformers.Ark().V1().Backups(),
+				client.ArkV1(),
+				backupService,
+				snapshotService,
+				"bucket",
+			).(*backupVerificationController)
+
+			backupService.On("GetBackup", "bucket", "backup-1").Return(test.metadata, test.metadataErr)
+			backupService.On("DownloadBackup", "bucket", "backup-1").Return(ioutil.NopCloser(bytes.NewReader(test.tarball)), nil)
+
+			checks := c.runChecks(api.DefaultNamespace, "backup-1")
+
+			actual := make(map[string]bool)
+			var unverified []string
+			for _, check := range checks {
+				actual[check.Name] = check.Passed
+				if check.Unverified {
+					unverified = append(unverified, check.Name)
+				}
+			}
+			assert.Equal(t, test.expected, actual)
+			assert.Equal(t, test.expectedUnverified, unverified)
+		})
+	}
+}
+
+func TestVerificationResult(t *testing.T) {
+	passed := api.BackupVerificationCheck{Name: "passed", Passed: true}
+	failed := api.BackupVerificationCheck{Name: "failed"}
+	unverified := api.BackupVerificationCheck{Name: "unverified", Unverified: true}
+
+	tests := []struct {
+		name               string
+		checks             []api.BackupVerificationCheck
+		expectedPassed     bool
+		expectedUnverified bool
+	}{
+		{
+			name:           "all checks passed",
+			checks:         []api.BackupVerificationCheck{passed, passed},
+			expectedPassed: true,
+		},
+		{
+			name:               "unverified check isn't passed",
+			checks:             []api.BackupVerificationCheck{passed, unverified},
+			expectedUnverified: true,
+		},
+		{
+			name:   "failed check outweighs unverified check",
+			checks: []api.BackupVerificationCheck{unverified, failed, passed},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			passed, unverified := verificationResult(test.checks)
+			assert.Equal(t, test.expectedPassed, passed)
+			assert.Equal(t, test.expectedUnverified, unverified)
+		})
+	}
+}
@@ -0,0 +1,310 @@
+/*
+Copyright 2017 Heptio Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/clock"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+
+	api "github.com/heptio/ark/pkg/apis/ark/v1"
+	"github.com/heptio/ark/pkg/cloudprovider"
+	"github.com/heptio/ark/pkg/generated/clientset/scheme"
+	arkv1client "github.com/heptio/ark/pkg/generated/clientset/typed/ark/v1"
+	informers "github.com/heptio/ark/pkg/generated/informers/externalversions/ark/v1"
+	listers "github.com/heptio/ark/pkg/generated/listers/ark/v1"
+)
+
+const (
+	// signedURLTTL is how long the URLs generated for DownloadRequests are valid for. Processed
+	// DownloadRequests are deleted once their URLs have expired.
+	signedURLTTL = 10 * time.Minute
+
+	// downloadRequestResyncPeriod is how often DownloadRequests are checked for expiration.
+	downloadRequestResyncPeriod = time.Minute
+)
+
+type downloadRequestController struct {
+	downloadRequestClient arkv1client.DownloadRequestsGetter
+	backupService         cloudprovider.BackupService
+	bucket                string
+	tenantMode            bool
+
+	downloadRequestLister       listers.DownloadRequestLister
+	downloadRequestListerSynced cache.InformerSynced
+	restoreLister               listers.RestoreLister
+	restoreListerSynced         cache.InformerSynced
+	backupLister                listers.BackupLister
+	backupListerSynced          cache.InformerSynced
+	syncHandler                 func(key string) error
+	queue                       workqueue.RateLimitingInterface
+
+	clock clock.Clock
+}
+
+// NewDownloadRequestController returns a controller that fulfills DownloadRequests by generating
+// signed URLs for the requested files in object storage.
+func NewDownloadRequestController(
+	downloadRequestClient arkv1client.DownloadRequestsGetter,
+	downloadRequestInformer informers.DownloadRequestInformer,
+	restoreInformer informers.RestoreInformer,
+	backupInformer informers.BackupInformer,
+	backupService cloudprovider.BackupService,
+	bucket string,
+	tenantMode bool,
+) Interface {
+	c := &downloadRequestController{
+		downloadRequestClient:       downloadRequestClient,
+		backupService:               backupService,
+		bucket:                      bucket,
+		tenantMode:                  tenantMode,
+		downloadRequestLister:       downloadRequestInformer.Lister(),
+		downloadRequestListerSynced: downloadRequestInformer.Informer().HasSynced,
+		restoreLister:               restoreInformer.Lister(),
+		restoreListerSynced:         restoreInformer.Informer().HasSynced,
+		backupLister:                backupInformer.Lister(),
+		backupListerSynced:          backupInformer.Informer().HasSynced,
+		queue:                       workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "downloadrequest"),
+
+		clock: &clock.RealClock{},
+	}
+
+	c.syncHandler = c.processDownloadRequest
+
+	downloadRequestInformer.Informer().AddEventHandler(
+		cache.ResourceEventHandlerFuncs{
+			AddFunc: func(obj interface{}) {
+				key, err := cache.MetaNamespaceKeyFunc(obj)
+				if err != nil {
+					downloadRequest := obj.(*api.DownloadRequest)
+					glog.Errorf("error creating queue key for %#v: %v", downloadRequest, err)
+					return
+				}
+				c.queue.Add(key)
+			},
+		},
+	)
+
+	return c
+}
+
+// Run is a blocking function that runs the specified number of worker goroutines
+// to process items in the work queue. It will return when it receives on the
+// ctx.Done() channel.
+func (c *downloadRequestController) Run(ctx context.Context, numWorkers int) error {
+	var wg sync.WaitGroup
+
+	defer func() {
+		glog.Infof("Waiting for workers to finish their work")
+
+		c.queue.ShutDown()
+
+		// We have to wait here in the deferred function instead of at the bottom of the function body
+		// because we have to shut down the queue in order for the workers to shut down gracefully, and
+		// we want to shut down the queue via defer and not at the end of the body.
+		wg.Wait()
+
+		glog.Infof("All workers have finished")
+	}()
+
+	glog.Info("Starting DownloadRequestController")
+	defer glog.Infof("Shutting down DownloadRequestController")
+
+	glog.Info("Waiting for caches to sync")
+	if !cache.WaitForCacheSync(ctx.Done(), c.downloadRequestListerSynced, c.restoreListerSynced, c.backupListerSynced) {
+		return errors.New("timed out waiting for caches to sync")
+	}
+	glog.Info("Caches are synced")
+
+	wg.Add(numWorkers)
+	for i := 0; i < numWorkers; i++ {
+		go func() {
+			wait.Until(c.runWorker, time.Second, ctx.Done())
+			wg.Done()
+		}()
+	}
+
+	wg.Add(1)
+	go func() {
+		wait.Until(c.resync, downloadRequestResyncPeriod, ctx.Done())
+		wg.Done()
+	}()
+
+	<-ctx.Done()
+
+	return nil
+}
+
+// resync requeues all the DownloadRequests, so that expired ones are deleted.
+func (c *downloadRequestController) resync() {
+	list, err := c.downloadRequestLister.List(labels.Everything())
+	if err != nil {
+		glog.Errorf("error listing download requests: %v", err)
+		return
+	}
+
+	for _, dr := range list {
+		key, err := cache.MetaNamespaceKeyFunc(dr)
+		if err != nil {
+			glog.Errorf("error generating key for download request %s/%s: %v", dr.Namespace, dr.Name, err)
+			continue
+		}
+
+		c.queue.Add(key)
+	}
+}
+
+func (c *downloadRequestController) runWorker() {
+	// continually take items off the queue (waits if it's
+	// empty) until we get a shutdown signal from the queue
+	for c.processNextWorkItem() {
+	}
+}
+
+func (c *downloadRequestController) processNextWorkItem() bool {
+	key, quit := c.queue.Get()
+	if quit {
+		return false
+	}
+	// always call done on this item, since if it fails we'll add
+	// it back with rate-limiting below
+	defer c.queue.Done(key)
+
+	err := c.syncHandler(key.(string))
+	if err == nil {
+		// If you had no error, tell the queue to stop tracking history for your key. This will reset
+		// things like failure counts for per-item rate limiting.
+		c.queue.Forget(key)
+		return true
+	}
+
+	glog.Errorf("syncHandler error: %v", err)
+	// we had an error processing the item so add it back
+	// into the queue for re-processing with rate-limiting
+	c.queue.AddRateLimited(key)
+
+	return true
+}
+
+// processDownloadRequest generates a signed URL for a new DownloadRequest, and deletes a
+// processed one whose URL has expired.
+func (c *downloadRequestController) processDownloadRequest(key string) error {
+	glog.V(4).Infof("processDownloadRequest for key %q", key)
+	ns, name, err := cache.SplitMetaNamespaceKey(key)
+	if err != nil {
+		glog.V(4).Infof("error splitting key %q: %v", key, err)
+		return err
+	}
+
+	downloadRequest, err := c.downloadRequestLister.DownloadRequests(ns).Get(name)
+	if apierrors.IsNotFound(err) {
+		glog.V(4).Infof("unable to find download request %q: %v", key, err)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("error getting download request %q: %v", key, err)
+	}
+
+	switch downloadRequest.Status.Phase {
+	case "", api.DownloadRequestPhaseNew:
+		return c.generatePreSignedURL(downloadRequest)
+	case api.DownloadRequestPhaseProcessed:
+		return c.deleteIfExpired(downloadRequest)
+	}
+
+	return nil
+}
+
+// generatePreSignedURL generates a pre-signed URL for downloadRequest, changes the phase to
+// Processed, and persists the changes to storage.
+func (c *downloadRequestController) generatePreSignedURL(downloadRequest *api.DownloadRequest) error {
+	backupName := downloadRequest.Spec.Target.Name
+	switch downloadRequest.Spec.Target.Kind {
+	case api.DownloadTargetKindRestoreLog, api.DownloadTargetKindRestorePlan, api.DownloadTargetKindRestoreResults:
+		restore, err := c.restoreLister.Restores(downloadRequest.Namespace).Get(downloadRequest.Spec.Target.Name)
+		if err != nil {
+			return fmt.Errorf("error getting restore %s: %v", downloadRequest.Spec.Target.Name, err)
+		}
+		backupName = restore.Spec.BackupName
+	}
+
+	// backups that haven't been synced into the cluster yet live in the default bucket
+	bucket := c.bucket
+	backup, err := c.backupLister.Backups(downloadRequest.Namespace).Get(backupName)
+	switch {
+	case err == nil:
+		bucket = backupBucket(backup, c.bucket)
+	case isTenantNamespace(c.tenantMode, downloadRequest.Namespace):
+		// object storage isn't partitioned by namespace, so requests in tenant namespaces are
+		// limited to the backups in the cluster in their own namespace.
+		return fmt.Errorf("error getting backup %s in namespace %s: %v", backupName, downloadRequest.Namespace, err)
+	}
+
+	clone, err := cloneDownloadRequest(downloadRequest)
+	if err != nil {
+		return err
+	}
+
+	clone.Status.DownloadURL, err = c.backupService.CreateSignedURL(downloadRequest.Spec.Target, bucket, backupName, signedURLTTL)
+	if err != nil {
+		return err
+	}
+
+	clone.Status.Phase = api.DownloadRequestPhaseProcessed
+	clone.Status.Expiration = metav1.NewTime(c.clock.Now().Add(signedURLTTL))
+
+	_, err = c.downloadRequestClient.DownloadRequests(clone.Namespace).Update(clone)
+	return err
+}
+
+// deleteIfExpired deletes downloadRequest if it has expired.
+func (c *downloadRequestController) deleteIfExpired(downloadRequest *api.DownloadRequest) error {
+	glog.V(4).Infof("checking for expiration of %s/%s", downloadRequest.Namespace, downloadRequest.Name)
+	if c.clock.Now().Before(downloadRequest.Status.Expiration.Time) {
+		glog.V(4).Infof("%s/%s has not expired", downloadRequest.Namespace, downloadRequest.Name)
+		return nil
+	}
+
+	glog.V(4).Infof("%s/%s has expired - deleting", downloadRequest.Namespace, downloadRequest.Name)
+	return c.downloadRequestClient.DownloadRequests(downloadRequest.Namespace).Delete(downloadRequest.Name, nil)
+}
+
+func cloneDownloadRequest(in interface{}) (*api.DownloadRequest, error) {
+	clone, err := scheme.Scheme.DeepCopy(in)
+	if err != nil {
+		return nil, err
+	}
+
+	out, ok := clone.(*api.DownloadRequest)
+	if !ok {
+		return nil, fmt.Errorf("unexpected type: %T", clone)
+	}
+
+	return out, nil
+}
@@ -0,0 +1,270 @@
+/*
+Copyright 2017 Heptio Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/clock"
+
+	api "github.com/heptio/ark/pkg/apis/ark/v1"
+	"github.com/heptio/ark/pkg/generated/clientset/fake"
+	informers "github.com/heptio/ark/pkg/generated/informers/externalversions"
+	. "github.com/heptio/ark/pkg/util/test"
+)
+
+func newDownloadRequest(phase api.DownloadRequestPhase, targetKind api.DownloadTargetKind, targetName string) *api.DownloadRequest {
+	return &api.DownloadRequest{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "a-download-request",
+			Namespace: api.DefaultNamespace,
+		},
+		Spec: api.DownloadRequestSpec{
+			Target: api.DownloadTarget{
+				Kind: targetKind,
+				Name: targetName,
+			},
+		},
+		Status: api.DownloadRequestStatus{
+			Phase: phase,
+		},
+	}
+}
+
+func TestProcessDownloadRequest(t *testing.T) {
+	tests := []struct {
+		name               string
+		key                string
+		namespace          string
+		tenantMode         bool
+		phase              api.DownloadRequestPhase
+		targetKind         api.DownloadTargetKind
+		targetName         string
+		restore            *api.Restore
+		backup             *api.Backup
+		expectedBackupName string
+		expectedBucket     string
+		expectedError      string
+	}{
+		{
+			name: "empty key",
+			key:  "",
+		},
+		{
+			name:          "bad key format",
+			key:           "a/b/c",
+			expectedError: `unexpected key format: "a/b/c"`,
+		},
+		{
+			name:               "backup contents request with phase '' gets a url",
+			key:                "heptio-ark/a-download-request",
+			targetKind:         api.DownloadTargetKindBackupContents,
+			targetName:         "backup1",
+			expectedBackupName: "backup1",
+		},
+		{
+			name:               "backup log request with phase 'New' gets a url",
+			key:                "heptio-ark/a-download-request",
+			phase:              api.DownloadRequestPhaseNew,
+			targetKind:         api.DownloadTargetKindBackupLog,
+			targetName:         "backup1",
+			expectedBackupName: "backup1",
+		},
+		{
+			name:               "backup log request for a backup in a storage location uses the location's bucket",
+			key:                "heptio-ark/a-download-request",
+			targetKind:         api.DownloadTargetKindBackupLog,
+			targetName:         "backup1",
+			backup:             NewTestBackup().WithName("backup1").WithStorageLocation("secondary").WithStorageBucket("other-bucket").Backup,
+			expectedBackupName: "backup1",
+			expectedBucket:     "other-bucket",
+		},
+		{
+			name:               "restore log request gets a url for the restore's backup",
+			key:                "heptio-ark/a-download-request",
+			targetKind:         api.DownloadTargetKindRestoreLog,
+			targetName:         "restore1",
+			restore:            NewTestRestore(api.DefaultNamespace, "restore1", api.RestorePhaseCompleted).WithBackup("backup1").Restore,
+			expectedBackupName: "backup1",
+		},
+		{
+			name:          "restore log request for a missing restore returns an error",
+			key:           "heptio-ark/a-download-request",
+			targetKind:    api.DownloadTargetKindRestoreLog,
+			targetName:    "restore1",
+			expectedError: `error getting restore restore1: restore.ark.heptio.com "restore1" not found`,
+		},
+		{
+			name:               "restore plan request gets a url for the restore's backup",
+			key:                "heptio-ark/a-download-request",
+			targetKind:         api.DownloadTargetKindRestorePlan,
+			targetName:         "restore1",
+			restore:            NewTestRestore(api.DefaultNamespace, "restore1", api.RestorePhaseCompleted).WithBackup("backup1").WithPreview(true).Restore,
+			expectedBackupName: "backup1",
+		},
+		{
+			name:               "request in a tenant namespace gets a url for a backup in its namespace",
+			key:                "team-a/a-download-request",
+			namespace:          "team-a",
+			tenantMode:         true,
+			targetKind:         api.DownloadTargetKindBackupLog,
+			targetName:         "backup1",
+			backup:             NewTestBackup().WithNamespace("team-a").WithName("backup1").Backup,
+			expectedBackupName: "backup1",
+		},
+		{
+			name:          "request in a tenant namespace for a backup in another namespace returns an error",
+			key:           "team-a/a-download-request",
+			namespace:     "team-a",
+			tenantMode:    true,
+			targetKind:    api.DownloadTargetKindBackupLog,
+			targetName:    "backup1",
+			backup:        NewTestBackup().WithNamespace("team-b").WithName("backup1").Backup,
+			expectedError: `error getting backup backup1 in namespace team-a: backup.ark.heptio.com "backup1" not found`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			client := fake.NewSimpleClientset()
+			sharedInformers := informers.NewSharedInformerFactory(client, 0)
+			downloadRequestsInformer := sharedInformers.Ark().V1().DownloadRequests()
+			restoresInformer := sharedInformers.Ark().V1().Restores()
+			backupsInformer := sharedInformers.Ark().V1().Backups()
+			backupService := &fakeBackupService{}
+			defer backupService.AssertExpectations(t)
+
+			c := NewDownloadRequestController(
+				client.ArkV1(),
+				downloadRequestsInformer,
+				restoresInformer,
+				backupsInformer,
+				backupService,
+				"bucket",
+				test.tenantMode,
+			).(*downloadRequestController)
+
+			now := time.Date(2017, 10, 1, 12, 0, 0, 0, time.UTC)
+			c.clock = clock.NewFakeClock(now)
+
+			var downloadRequest *api.DownloadRequest
+			if test.targetKind != "" {
+				downloadRequest = newDownloadRequest(test.phase, test.targetKind, test.targetName)
+				if test.namespace != "" {
+					downloadRequest.Namespace = test.namespace
+				}
+				downloadRequestsInformer.Informer().GetStore().Add(downloadRequest)
+				_, err := client.ArkV1().DownloadRequests(downloadRequest.Namespace).Create(downloadRequest)
+				require.NoError(t, err)
+			}
+
+			if test.restore != nil {
+				restoresInformer.Informer().GetStore().Add(test.restore)
+			}
+
+			if test.backup != nil {
+				backupsInformer.Informer().GetStore().Add(test.backup)
+			}
+
+			if test.expectedBackupName != "" {
+				bucket := test.expectedBucket
+				if bucket == "" {
+					bucket = "bucket"
+				}
+				backupService.On("CreateSignedURL", downloadRequest.Spec.Target, bucket, test.expectedBackupName, signedURLTTL).Return("signedURL", nil)
+			}
+
+			err := c.processDownloadRequest(test.key)
+
+			if test.expectedError != "" {
+				assert.EqualError(t, err, test.expectedError)
+				return
+			}
+			require.NoError(t, err)
+
+			if downloadRequest == nil {
+				return
+			}
+
+			res, err := client.ArkV1().DownloadRequests(downloadRequest.Namespace).Get(downloadRequest.Name, metav1.GetOptions{})
+			require.NoError(t, err)
+			assert.Equal(t, api.DownloadRequestPhaseProcessed, res.Status.Phase)
+			assert.Equal(t, "signedURL", res.Status.DownloadURL)
+			assert.Equal(t, now.Add(signedURLTTL), res.Status.Expiration.Time)
+		})
+	}
+}
+
+func TestDownloadRequestDeleteIfExpired(t *testing.T) {
+	now := time.Date(2017, 10, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name          string
+		expiration    time.Time
+		expectDeleted bool
+	}{
+		{
+			name:       "unexpired request is kept",
+			expiration: now.Add(time.Minute),
+		},
+		{
+			name:          "expired request is deleted",
+			expiration:    now.Add(-time.Minute),
+			expectDeleted: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			client := fake.NewSimpleClientset()
+			sharedInformers := informers.NewSharedInformerFactory(client, 0)
+			downloadRequestsInformer := sharedInformers.Ark().V1().DownloadRequests()
+
+			c := NewDownloadRequestController(
+				client.ArkV1(),
+				downloadRequestsInformer,
+				sharedInformers.Ark().V1().Restores(),
+				sharedInformers.Ark().V1().Backups(),
+				&fakeBackupService{},
+				"bucket",
+				false,
+			).(*downloadRequestController)
+			c.clock = clock.NewFakeClock(now)
+
+			downloadRequest := newDownloadRequest(api.DownloadRequestPhaseProcessed, api.DownloadTargetKindBackupLog, "backup1")
+			downloadRequest.Status.Expiration = metav1.NewTime(test.expiration)
+			downloadRequestsInformer.Informer().GetStore().Add(downloadRequest)
+			_, err := client.ArkV1().DownloadRequests(downloadRequest.Namespace).Create(downloadRequest)
+			require.NoError(t, err)
+
+			require.NoError(t, c.processDownloadRequest("heptio-ark/a-download-request"))
+
+			_, err = client.ArkV1().DownloadRequests(downloadRequest.Namespace).Get(downloadRequest.Name, metav1.GetOptions{})
+			if test.expectDeleted {
+				assert.True(t, apierrors.IsNotFound(err), "expected download request to be deleted, got %v", err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
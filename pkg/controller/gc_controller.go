@@ -19,6 +19,8 @@ package controller
 import (
 	"context"
 	"errors"
+	"sort"
+	"sync"
 	"time"
 
 	"github.com/golang/glog"
@@ -26,35 +28,55 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/util/clock"
+	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/pkg/api/v1"
 	"k8s.io/client-go/tools/cache"
 
+	api "github.com/heptio/ark/pkg/apis/ark/v1"
 	"github.com/heptio/ark/pkg/cloudprovider"
+	"github.com/heptio/ark/pkg/csi"
+	"github.com/heptio/ark/pkg/event"
 	arkv1client "github.com/heptio/ark/pkg/generated/clientset/typed/ark/v1"
 	informers "github.com/heptio/ark/pkg/generated/informers/externalversions/ark/v1"
 	listers "github.com/heptio/ark/pkg/generated/listers/ark/v1"
+	"github.com/heptio/ark/pkg/metrics"
+	"github.com/heptio/ark/pkg/restic"
+	"github.com/heptio/ark/pkg/schedule"
 )
 
 // gcController removes expired backup content from object storage.
 type gcController struct {
 	backupService   cloudprovider.BackupService
 	snapshotService cloudprovider.SnapshotService
-	bucket          string
-	syncPeriod      time.Duration
-	clock           clock.Clock
-	lister          listers.BackupLister
-	listerSynced    cache.InformerSynced
-	client          arkv1client.BackupsGetter
+	csiSnapshotter  csi.Snapshotter
+	// podVolumeSnapshots deletes the backups' restic and kopia snapshots.
+	podVolumeSnapshots restic.SnapshotDeleter
+	bucket             string
+	locationBuckets    []string
+	syncPeriod         time.Duration
+	workers            int
+	clock              clock.Clock
+	lister             listers.BackupLister
+	listerSynced       cache.InformerSynced
+	client             arkv1client.BackupsGetter
+	recorder           event.Recorder
+	metrics            *metrics.ServerMetrics
 }
 
 // NewGCController constructs a new gcController.
 func NewGCController(
 	backupService cloudprovider.BackupService,
 	snapshotService cloudprovider.SnapshotService,
+	csiSnapshotter csi.Snapshotter,
+	podVolumeSnapshots restic.SnapshotDeleter,
 	bucket string,
+	storageLocations map[string]string,
 	syncPeriod time.Duration,
 	backupInformer informers.BackupInformer,
 	client arkv1client.BackupsGetter,
+	recorder event.Recorder,
+	metrics *metrics.ServerMetrics,
 ) Interface {
 	if syncPeriod < time.Minute {
 		glog.Infof("GC sync period %v is too short. Setting to 1 minute", syncPeriod)
@@ -62,23 +84,30 @@ func NewGCController(
 	}
 
 	return &gcController{
-		backupService:   backupService,
-		snapshotService: snapshotService,
-		bucket:          bucket,
-		syncPeriod:      syncPeriod,
-		clock:           clock.RealClock{},
-		lister:          backupInformer.Lister(),
-		listerSynced:    backupInformer.Informer().HasSynced,
-		client:          client,
+		backupService:      backupService,
+		snapshotService:    snapshotService,
+		csiSnapshotter:     csiSnapshotter,
+		podVolumeSnapshots: podVolumeSnapshots,
+		bucket:             bucket,
+		locationBuckets:    sortedBuckets(bucket, storageLocations),
+		syncPeriod:         syncPeriod,
+		clock:              clock.RealClock{},
+		lister:             backupInformer.Lister(),
+		listerSynced:       backupInformer.Informer().HasSynced,
+		client:             client,
+		recorder:           recorder,
+		metrics:            metrics,
 	}
 }
 
 var _ Interface = &gcController{}
 
-// Run is a blocking function that runs a single worker to garbage-collect backups
-// from object/block storage and the Ark API. It will return when it receives on the
-// ctx.Done() channel.
+// Run is a blocking function that periodically garbage-collects backups from
+// object/block storage and the Ark API, removing up to workers expired backups at a
+// time. It will return when it receives on the ctx.Done() channel.
 func (c *gcController) Run(ctx context.Context, workers int) error {
+	c.workers = workers
+
 	glog.Info("Waiting for caches to sync")
 	if !cache.WaitForCacheSync(ctx.Done(), c.listerSynced) {
 		return errors.New("timed out waiting for caches to sync")
@@ -93,67 +122,331 @@ func (c *gcController) run() {
 	c.cleanBackups()
 }
 
+// getRequiredParentBackups returns the names of all backups that are ancestors of unexpired
+// incremental backups, and are therefore needed to restore them.
+func getRequiredParentBackups(backups []*api.Backup, now time.Time) sets.String {
+	parents := make(map[string]string, len(backups))
+	for _, backup := range backups {
+		parents[backup.Name] = backup.Spec.ParentBackup
+	}
+
+	required := sets.NewString()
+	for _, backup := range backups {
+		if backupExpiration(backup).Before(now) {
+			continue
+		}
+
+		for parent := backup.Spec.ParentBackup; parent != "" && !required.Has(parent); parent = parents[parent] {
+			required.Insert(parent)
+		}
+	}
+
+	return required
+}
+
 // cleanBackups deletes expired backups.
 func (c *gcController) cleanBackups() {
-	backups, err := c.backupService.GetAllBackups(c.bucket)
+	// buckets[i] is the bucket that backups[i] is stored in
+	var backups []*api.Backup
+	var buckets []string
+	// a bucket that can't be read, e.g. because its location is unreachable, doesn't stop the
+	// backups in the others from being garbage-collected
+	var unreadBuckets []string
+	for _, bucket := range append([]string{c.bucket}, c.locationBuckets...) {
+		bucketBackups, err := c.backupService.GetAllBackups(bucket)
+		if err != nil {
+			glog.Errorf("error getting all backups in bucket %s, skipping it: %v", bucket, err)
+			unreadBuckets = append(unreadBuckets, bucket)
+			continue
+		}
+
+		for _, backup := range bucketBackups {
+			backups = append(backups, backup)
+			buckets = append(buckets, bucket)
+		}
+	}
+
+	// the API objects record changes made to backups after they're uploaded, such as expirations
+	// and retention by their schedules' retention policies, so they take precedence.
+	for i, backup := range backups {
+		if apiBackup, err := c.lister.Backups(backup.Namespace).Get(backup.Name); err == nil {
+			backups[i] = apiBackup
+		}
+	}
+
+	apiBackups, err := c.lister.List(labels.NewSelector())
 	if err != nil {
-		glog.Errorf("error getting all backups: %v", err)
-		return
+		glog.Errorf("error getting all backup API objects: %v", err)
 	}
 
 	now := c.clock.Now()
 	glog.Infof("garbage-collecting backups that have expired as of %v", now)
 
-	// GC backup files and associated snapshots/API objects. Note that deletion from object
-	// storage should happen first because otherwise there's a possibility the backup sync
-	// controller would re-create the API object after deletion.
-	for _, backup := range backups {
-		if !backup.Status.Expiration.Time.Before(now) {
+	// the API objects include the unexpired incremental backups stored in buckets that couldn't
+	// be read, whose parents still need to be kept.
+	requiredParents := getRequiredParentBackups(append(backups, apiBackups...), now)
+	var expired []expiredBackup
+
+	for i, backup := range backups {
+		if !backupExpiration(backup).Before(now) {
 			glog.Infof("Backup %s/%s has not expired yet, skipping", backup.Namespace, backup.Name)
 			continue
 		}
 
+		if requiredParents.Has(backup.Name) {
+			glog.Infof("Backup %s/%s has expired but is the parent of an unexpired incremental backup, skipping", backup.Namespace, backup.Name)
+			continue
+		}
+
+		if isRetained(backup) {
+			glog.Infof("Backup %s/%s has expired but is retained by its schedule's retention policy, skipping", backup.Namespace, backup.Name)
+			continue
+		}
+
+		if cloudprovider.IsReadOnly(c.backupService, buckets[i]) {
+			glog.Infof("Backup %s/%s has expired but is in a read-only backup storage location, skipping", backup.Namespace, backup.Name)
+			continue
+		}
+
+		snapshotIDs := cloudSnapshotIDs(backup)
+		volumeSnapshots := csiSnapshots(backup)
+
 		// if the backup includes snapshots but we don't currently have a PVProvider, we don't
 		// want to orphan the snapshots so skip garbage-collection entirely.
-		if c.snapshotService == nil && len(backup.Status.VolumeBackups) > 0 {
-			glog.Warningf("Cannot garbage-collect backup %s/%s because backup includes snapshots and server is not configured with PersistentVolumeProvider",
+		if c.snapshotService == nil && len(snapshotIDs) > 0 {
+			glog.Warningf("Cannot garbage-collect backup %s/%s because backup includes snapshots and server has no volume snapshot locations",
 				backup.Namespace, backup.Name)
 			continue
 		}
 
-		glog.Infof("Removing backup %s/%s", backup.Namespace, backup.Name)
-		if err := c.backupService.DeleteBackup(c.bucket, backup.Name); err != nil {
-			glog.Errorf("error deleting backup %s/%s: %v", backup.Namespace, backup.Name, err)
+		if c.csiSnapshotter == nil && len(volumeSnapshots) > 0 {
+			glog.Warningf("Cannot garbage-collect backup %s/%s because backup includes CSI snapshots and server isn't configured for CSI snapshots",
+				backup.Namespace, backup.Name)
+			continue
 		}
 
-		for _, volumeBackup := range backup.Status.VolumeBackups {
-			glog.Infof("Removing snapshot %s associated with backup %s/%s", volumeBackup.SnapshotID, backup.Namespace, backup.Name)
-			if err := c.snapshotService.DeleteSnapshot(volumeBackup.SnapshotID); err != nil {
-				glog.Errorf("error deleting snapshot %v: %v", volumeBackup.SnapshotID, err)
-			}
+		podVolumeSnapshots := backup.Status.PodVolumeSnapshots
+		if c.podVolumeSnapshots == nil && len(podVolumeSnapshots) > 0 {
+			glog.Warningf("Cannot garbage-collect backup %s/%s because backup includes restic snapshots and server isn't configured for restic",
+				backup.Namespace, backup.Name)
+			continue
 		}
 
-		glog.Infof("Removing backup API object %s/%s", backup.Namespace, backup.Name)
-		if err := c.client.Backups(backup.Namespace).Delete(backup.Name, &metav1.DeleteOptions{}); err != nil {
-			glog.Errorf("error deleting backup API object %s/%s: %v", backup.Namespace, backup.Name, err)
+		snapshotServices, err := snapshotServicesForLocations(c.snapshotService, snapshotIDs)
+		if err != nil {
+			glog.Warningf("Cannot garbage-collect backup %s/%s because its snapshots can't be deleted: %v", backup.Namespace, backup.Name, err)
+			continue
 		}
 
+		expired = append(expired, expiredBackup{
+			backup:             backup,
+			bucket:             buckets[i],
+			snapshotIDs:        snapshotIDs,
+			snapshotServices:   snapshotServices,
+			csiSnapshots:       volumeSnapshots,
+			podVolumeSnapshots: podVolumeSnapshots,
+		})
 	}
 
-	// also GC any Backup API objects without files in object storage
-	apiBackups, err := c.lister.List(labels.NewSelector())
-	if err != nil {
-		glog.Errorf("error getting all backup API objects: %v", err)
+	removed := c.removeBackups(expired)
+
+	// also GC any Backup API objects without files in object storage. If a bucket couldn't be
+	// read, the API objects of the backups in it can't be told apart from those without files, so
+	// none are removed until it can be.
+	if len(unreadBuckets) > 0 {
+		glog.Warningf("Not garbage-collecting backup API objects without files, since buckets %v couldn't be read", unreadBuckets)
+		return
 	}
 
 	for _, backup := range apiBackups {
-		if backup.Status.Expiration.Time.Before(now) {
+		if removed.Has(backup.Name) {
+			continue
+		}
+
+		if requiredParents.Has(backup.Name) {
+			glog.Infof("Backup %s/%s is the parent of an unexpired incremental backup, skipping", backup.Namespace, backup.Name)
+			continue
+		}
+
+		if isRetained(backup) {
+			glog.Infof("Backup %s/%s is retained by its schedule's retention policy, skipping", backup.Namespace, backup.Name)
+			continue
+		}
+
+		if backupExpiration(backup).Before(now) {
 			glog.Infof("Removing backup API object %s/%s", backup.Namespace, backup.Name)
 			if err := c.client.Backups(backup.Namespace).Delete(backup.Name, &metav1.DeleteOptions{}); err != nil {
 				glog.Errorf("error deleting backup API object %s/%s: %v", backup.Namespace, backup.Name, err)
+			} else {
+				c.recordExpired(backup)
 			}
 		} else {
 			glog.Infof("Backup %s/%s has not expired yet, skipping", backup.Namespace, backup.Name)
 		}
 	}
 }
+
+// expiredBackup is a backup that the gcController removes, with the bucket it's stored in, the
+// cloud provider snapshots to delete with it, by volume snapshot location, and the CSI and restic
+// snapshots to delete with it.
+type expiredBackup struct {
+	backup             *api.Backup
+	bucket             string
+	snapshotIDs        map[string][]string
+	snapshotServices   map[string]cloudprovider.SnapshotService
+	csiSnapshots       []*api.CSISnapshotInfo
+	podVolumeSnapshots []api.PodVolumeSnapshotInfo
+}
+
+// removeBackups removes the given backups, up to c.workers at a time, and returns the names of
+// the ones it removed.
+func (c *gcController) removeBackups(expired []expiredBackup) sets.String {
+	workers := c.workers
+	if workers < 1 {
+		workers = 1
+	}
+
+	var (
+		lock    sync.Mutex
+		removed = sets.NewString()
+		wg      sync.WaitGroup
+		work    = make(chan expiredBackup)
+	)
+
+	wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go func() {
+			defer wg.Done()
+			for item := range work {
+				c.removeBackup(item)
+
+				lock.Lock()
+				removed.Insert(item.backup.Name)
+				lock.Unlock()
+			}
+		}()
+	}
+
+	for _, item := range expired {
+		work <- item
+	}
+	close(work)
+	wg.Wait()
+
+	return removed
+}
+
+// removeBackup deletes an expired backup's files, snapshots, and API object. Note that deletion
+// from object storage should happen first because otherwise there's a possibility the backup sync
+// controller would re-create the API object after deletion.
+func (c *gcController) removeBackup(item expiredBackup) {
+	backup := item.backup
+
+	glog.Infof("Removing backup %s/%s", backup.Namespace, backup.Name)
+	if err := c.backupService.DeleteBackup(item.bucket, backup.Name); err != nil {
+		glog.Errorf("error deleting backup %s/%s: %v", backup.Namespace, backup.Name, err)
+	}
+
+	for location, ids := range item.snapshotIDs {
+		for _, snapshotID := range ids {
+			glog.Infof("Removing snapshot %s associated with backup %s/%s", snapshotID, backup.Namespace, backup.Name)
+			if err := item.snapshotServices[location].DeleteSnapshot(snapshotID); err != nil {
+				glog.Errorf("error deleting snapshot %v: %v", snapshotID, err)
+			}
+		}
+	}
+
+	for _, snapshot := range item.csiSnapshots {
+		glog.Infof("Removing VolumeSnapshot %s/%s associated with backup %s/%s", snapshot.VolumeSnapshotNamespace, snapshot.VolumeSnapshotName, backup.Namespace, backup.Name)
+		if err := c.csiSnapshotter.DeleteSnapshot(snapshot); err != nil {
+			glog.Errorf("error deleting VolumeSnapshot %s/%s: %v", snapshot.VolumeSnapshotNamespace, snapshot.VolumeSnapshotName, err)
+		}
+	}
+
+	for _, snapshot := range item.podVolumeSnapshots {
+		glog.Infof("Removing pod volume snapshot %s associated with backup %s/%s", snapshot.Snapshot, backup.Namespace, backup.Name)
+		if err := c.podVolumeSnapshots.DeleteSnapshot(snapshot.RepoNamespace, snapshot.Snapshot); err != nil {
+			glog.Errorf("error deleting pod volume snapshot %s: %v", snapshot.Snapshot, err)
+		}
+	}
+
+	glog.Infof("Removing backup API object %s/%s", backup.Namespace, backup.Name)
+	if err := c.client.Backups(backup.Namespace).Delete(backup.Name, &metav1.DeleteOptions{}); err != nil {
+		glog.Errorf("error deleting backup API object %s/%s: %v", backup.Namespace, backup.Name, err)
+	}
+
+	c.recordExpired(backup)
+}
+
+// sortedBuckets returns the buckets of the given backup storage locations, other than
+// defaultBucket, sorted.
+func sortedBuckets(defaultBucket string, storageLocations map[string]string) []string {
+	buckets := make([]string, 0, len(storageLocations))
+	for _, bucket := range storageLocations {
+		if bucket != defaultBucket {
+			buckets = append(buckets, bucket)
+		}
+	}
+	sort.Strings(buckets)
+	return buckets
+}
+
+// isRetained returns whether a backup is kept by its schedule's retention policy.
+func isRetained(backup *api.Backup) bool {
+	return backup.Annotations[api.RetentionAnnotation] != ""
+}
+
+// backupExpiration returns when a backup expires. It's shared with the admission webhook, which
+// only lets immutable backups be deleted once they've expired.
+var backupExpiration = schedule.Expiration
+
+// recordExpired records an event and a metric about a backup having been deleted because it
+// expired.
+func (c *gcController) recordExpired(backup *api.Backup) {
+	c.metrics.RegisterGCDeletion(metricLabels(backup))
+	c.recorder.Eventf(backup, v1.EventTypeNormal, event.ReasonBackupExpired, "Deleted backup, which expired at %s", backupExpiration(backup))
+}
+
+// cloudSnapshotIDs returns the IDs of the backup's volume snapshots that were taken using the
+// cloud provider API, as opposed to the CSI VolumeSnapshot API, keyed by the name of the volume
+// snapshot location they were taken in.
+func cloudSnapshotIDs(backup *api.Backup) map[string][]string {
+	ids := make(map[string][]string)
+	for _, volumeBackup := range backup.Status.VolumeBackups {
+		if volumeBackup.CSISnapshot == nil {
+			location := cloudprovider.VolumeSnapshotLocation(backup, volumeBackup)
+			ids[location] = append(ids[location], volumeBackup.SnapshotID)
+		}
+	}
+	return ids
+}
+
+// csiSnapshots returns the backup's snapshots that were taken using the CSI VolumeSnapshot API,
+// sorted by the name of their VolumeSnapshot. Snapshots taken by older servers, which didn't record
+// their VolumeSnapshots, aren't returned, so they're left to be deleted by hand.
+func csiSnapshots(backup *api.Backup) []*api.CSISnapshotInfo {
+	var snapshots []*api.CSISnapshotInfo
+	for _, volumeBackup := range backup.Status.VolumeBackups {
+		if volumeBackup.CSISnapshot != nil && volumeBackup.CSISnapshot.VolumeSnapshotName != "" {
+			snapshots = append(snapshots, volumeBackup.CSISnapshot)
+		}
+	}
+	sort.Slice(snapshots, func(i, j int) bool {
+		return snapshots[i].VolumeSnapshotNamespace+"/"+snapshots[i].VolumeSnapshotName < snapshots[j].VolumeSnapshotNamespace+"/"+snapshots[j].VolumeSnapshotName
+	})
+	return snapshots
+}
+
+// snapshotServicesForLocations returns the SnapshotService of each of the volume snapshot
+// locations in snapshotIDs, keyed by name. It returns an error if any of them isn't configured.
+func snapshotServicesForLocations(service cloudprovider.SnapshotService, snapshotIDs map[string][]string) (map[string]cloudprovider.SnapshotService, error) {
+	services := make(map[string]cloudprovider.SnapshotService, len(snapshotIDs))
+	for location := range snapshotIDs {
+		locationService, err := cloudprovider.SnapshotServiceForLocation(service, location)
+		if err != nil {
+			return nil, err
+		}
+		services[location] = locationService
+	}
+	return services, nil
+}
@@ -19,8 +19,10 @@ package controller
 import (
 	"bytes"
 	"errors"
+	"fmt"
 	"io"
 	"io/ioutil"
+	"sync"
 	"testing"
 	"time"
 
@@ -29,11 +31,14 @@ import (
 
 	"k8s.io/apimachinery/pkg/util/clock"
 	"k8s.io/apimachinery/pkg/util/sets"
+	core "k8s.io/client-go/testing"
 
 	api "github.com/heptio/ark/pkg/apis/ark/v1"
 	"github.com/heptio/ark/pkg/cloudprovider"
+	"github.com/heptio/ark/pkg/csi"
 	"github.com/heptio/ark/pkg/generated/clientset/fake"
 	informers "github.com/heptio/ark/pkg/generated/informers/externalversions"
+	"github.com/heptio/ark/pkg/metrics"
 	. "github.com/heptio/ark/pkg/util/test"
 )
 
@@ -41,8 +46,10 @@ type gcTest struct {
 	name               string
 	bucket             string
 	backups            map[string][]*api.Backup
+	apiBackups         []*api.Backup
 	snapshots          sets.String
 	nilSnapshotService bool
+	readOnly           bool
 
 	expectedBackupsRemaining   map[string]sets.String
 	expectedSnapshotsRemaining sets.String
@@ -52,6 +59,24 @@ func TestGarbageCollect(t *testing.T) {
 	fakeClock := clock.NewFakeClock(time.Now())
 
 	tests := []gcTest{
+		gcTest{
+			name:   "expired backup in a read-only location",
+			bucket: "bucket-1",
+			backups: map[string][]*api.Backup{
+				"bucket-1": []*api.Backup{
+					NewTestBackup().WithName("backup-1").
+						WithExpiration(fakeClock.Now().Add(-1*time.Second)).
+						WithSnapshot("pv-1", "snapshot-1").
+						Backup,
+				},
+			},
+			readOnly:  true,
+			snapshots: sets.NewString("snapshot-1"),
+			expectedBackupsRemaining: map[string]sets.String{
+				"bucket-1": sets.NewString("backup-1"),
+			},
+			expectedSnapshotsRemaining: sets.NewString("snapshot-1"),
+		},
 		gcTest{
 			name:   "basic-expired",
 			bucket: "bucket-1",
@@ -135,6 +160,36 @@ func TestGarbageCollect(t *testing.T) {
 			},
 			expectedSnapshotsRemaining: sets.NewString("snapshot-1", "snapshot-2", "snapshot-3", "snapshot-4"),
 		},
+		gcTest{
+			name:   "CSI snapshots aren't deleted",
+			bucket: "bucket-1",
+			backups: map[string][]*api.Backup{
+				"bucket-1": []*api.Backup{
+					NewTestBackup().WithName("backup-1").
+						WithExpiration(fakeClock.Now().Add(-1*time.Second)).
+						WithSnapshot("pv-1", "snapshot-1").
+						WithCSISnapshot("pv-2", "csi.example.com", "snapshot-2").
+						Backup,
+				},
+			},
+			snapshots:                  sets.NewString("snapshot-1", "snapshot-2"),
+			expectedBackupsRemaining:   make(map[string]sets.String),
+			expectedSnapshotsRemaining: sets.NewString("snapshot-2"),
+		},
+		gcTest{
+			name:   "backup with only CSI snapshots is deleted without a snapshot service",
+			bucket: "bucket-1",
+			backups: map[string][]*api.Backup{
+				"bucket-1": []*api.Backup{
+					NewTestBackup().WithName("backup-1").
+						WithExpiration(fakeClock.Now().Add(-1*time.Second)).
+						WithCSISnapshot("pv-1", "csi.example.com", "snapshot-1").
+						Backup,
+				},
+			},
+			nilSnapshotService:       true,
+			expectedBackupsRemaining: make(map[string]sets.String),
+		},
 		gcTest{
 			name:   "orphan snapshots",
 			bucket: "bucket-1",
@@ -172,6 +227,94 @@ func TestGarbageCollect(t *testing.T) {
 				"bucket-1": sets.NewString("backup-1"),
 			},
 		},
+		gcTest{
+			name:   "expired parents of unexpired incremental backups are kept",
+			bucket: "bucket-1",
+			backups: map[string][]*api.Backup{
+				"bucket-1": []*api.Backup{
+					NewTestBackup().WithName("full").
+						WithExpiration(fakeClock.Now().Add(-1 * time.Second)).
+						Backup,
+					NewTestBackup().WithName("incremental-1").
+						WithParentBackup("full").
+						WithExpiration(fakeClock.Now().Add(-1 * time.Second)).
+						Backup,
+					NewTestBackup().WithName("incremental-2").
+						WithParentBackup("incremental-1").
+						WithExpiration(fakeClock.Now().Add(1 * time.Minute)).
+						Backup,
+					NewTestBackup().WithName("unrelated").
+						WithExpiration(fakeClock.Now().Add(-1 * time.Second)).
+						Backup,
+				},
+			},
+			snapshots: sets.NewString(),
+			expectedBackupsRemaining: map[string]sets.String{
+				"bucket-1": sets.NewString("full", "incremental-1", "incremental-2"),
+			},
+			expectedSnapshotsRemaining: sets.NewString(),
+		},
+		gcTest{
+			name:   "expired backups retained by their schedules' retention policies are kept",
+			bucket: "bucket-1",
+			backups: map[string][]*api.Backup{
+				"bucket-1": []*api.Backup{
+					NewTestBackup().WithName("backup-1").
+						WithExpiration(fakeClock.Now().Add(-1 * time.Second)).
+						Backup,
+				},
+			},
+			apiBackups: []*api.Backup{
+				NewTestBackup().WithName("backup-1").
+					WithAnnotation(api.RetentionAnnotation, "daily,weekly").
+					WithExpiration(fakeClock.Now().Add(-1 * time.Second)).
+					Backup,
+			},
+			snapshots: sets.NewString(),
+			expectedBackupsRemaining: map[string]sets.String{
+				"bucket-1": sets.NewString("backup-1"),
+			},
+			expectedSnapshotsRemaining: sets.NewString(),
+		},
+		gcTest{
+			name:   "backup API objects' expirations take precedence",
+			bucket: "bucket-1",
+			backups: map[string][]*api.Backup{
+				"bucket-1": []*api.Backup{
+					NewTestBackup().WithName("backup-1").
+						WithExpiration(fakeClock.Now().Add(1 * time.Minute)).
+						Backup,
+				},
+			},
+			apiBackups: []*api.Backup{
+				NewTestBackup().WithName("backup-1").
+					WithExpiration(fakeClock.Now().Add(-1 * time.Second)).
+					Backup,
+			},
+			snapshots:                  sets.NewString(),
+			expectedBackupsRemaining:   make(map[string]sets.String),
+			expectedSnapshotsRemaining: sets.NewString(),
+		},
+		gcTest{
+			name:   "backups expired by their schedules' retention policies are removed before their TTLs expire",
+			bucket: "bucket-1",
+			backups: map[string][]*api.Backup{
+				"bucket-1": []*api.Backup{
+					NewTestBackup().WithName("backup-1").
+						WithExpiration(fakeClock.Now().Add(1 * time.Minute)).
+						Backup,
+				},
+			},
+			apiBackups: []*api.Backup{
+				NewTestBackup().WithName("backup-1").
+					WithAnnotation(api.RetentionExpiredAnnotation, fakeClock.Now().Add(-1*time.Second).Format(time.RFC3339)).
+					WithExpiration(fakeClock.Now().Add(1 * time.Minute)).
+					Backup,
+			},
+			snapshots:                  sets.NewString(),
+			expectedBackupsRemaining:   make(map[string]sets.String),
+			expectedSnapshotsRemaining: sets.NewString(),
+		},
 	}
 
 	for _, test := range tests {
@@ -200,24 +343,46 @@ func TestGarbageCollect(t *testing.T) {
 				client          = fake.NewSimpleClientset()
 				sharedInformers = informers.NewSharedInformerFactory(client, 0)
 				snapSvc         cloudprovider.SnapshotService
+				recorder        = &FakeEventRecorder{}
 			)
 
 			if snapshotService != nil {
 				snapSvc = snapshotService
 			}
 
+			var bs cloudprovider.BackupService = backupService
+			if test.readOnly {
+				bs = cloudprovider.NewReadOnlyBackupService(backupService)
+			}
+
+			for _, backup := range test.apiBackups {
+				sharedInformers.Ark().V1().Backups().Informer().GetStore().Add(backup)
+			}
+
 			controller := NewGCController(
-				backupService,
+				bs,
 				snapSvc,
+				nil,
+				nil,
 				test.bucket,
+				nil,
 				1*time.Millisecond,
 				sharedInformers.Ark().V1().Backups(),
 				client.ArkV1(),
+				recorder,
+				metrics.NewServerMetrics(),
 			).(*gcController)
 			controller.clock = fakeClock
 
 			controller.cleanBackups()
 
+			// an event is recorded for each expired backup that's removed
+			expectedEvents := len(test.backups[test.bucket]) - len(test.expectedBackupsRemaining[test.bucket])
+			assert.Len(t, recorder.Events, expectedEvents)
+			for _, e := range recorder.Events {
+				assert.Contains(t, e, "Normal BackupExpired Deleted backup, which expired at ")
+			}
+
 			// verify every bucket has the backups we expect
 			for bucket, backups := range backupService.backupsByBucket {
 				// if actual and expected are both empty, no further verification needed
@@ -285,10 +450,15 @@ func TestGarbageCollectPicksUpBackupUponExpiration(t *testing.T) {
 	controller := NewGCController(
 		backupService,
 		snapshotService,
+		nil,
+		nil,
 		scenario.bucket,
+		nil,
 		1*time.Millisecond,
 		sharedInformers.Ark().V1().Backups(),
 		client.ArkV1(),
+		&FakeEventRecorder{},
+		metrics.NewServerMetrics(),
 	).(*gcController)
 	controller.clock = fakeClock
 
@@ -306,6 +476,128 @@ func TestGarbageCollectPicksUpBackupUponExpiration(t *testing.T) {
 	assert.Equal(0, len(snapshotService.SnapshotsTaken), "snapshots should have been garbage-collected.")
 }
 
+func TestGarbageCollectSkipsUnreadableBuckets(t *testing.T) {
+	var (
+		fakeClock = clock.NewFakeClock(time.Now())
+		expired   = fakeClock.Now().Add(-1 * time.Minute)
+		unexpired = fakeClock.Now().Add(1 * time.Minute)
+
+		// bucket-2 can't be read, so its backups are only known from their API objects
+		backupService = &fakeBackupService{
+			backupsByBucket: map[string][]*api.Backup{
+				"bucket-1": {
+					NewTestBackup().WithName("backup-1").WithExpiration(expired).Backup,
+					NewTestBackup().WithName("parent").WithExpiration(expired).Backup,
+				},
+				"bucket-3": {
+					NewTestBackup().WithName("backup-3").WithExpiration(expired).Backup,
+				},
+			},
+		}
+
+		client          = fake.NewSimpleClientset()
+		sharedInformers = informers.NewSharedInformerFactory(client, 0)
+	)
+
+	for _, backup := range []*api.Backup{
+		NewTestBackup().WithName("child").WithParentBackup("parent").WithExpiration(unexpired).Backup,
+		NewTestBackup().WithName("backup-2").WithExpiration(expired).Backup,
+	} {
+		sharedInformers.Ark().V1().Backups().Informer().GetStore().Add(backup)
+	}
+
+	controller := NewGCController(
+		backupService,
+		nil,
+		nil,
+		nil,
+		"bucket-1",
+		map[string]string{"location-2": "bucket-2", "location-3": "bucket-3"},
+		1*time.Millisecond,
+		sharedInformers.Ark().V1().Backups(),
+		client.ArkV1(),
+		&FakeEventRecorder{},
+		metrics.NewServerMetrics(),
+	).(*gcController)
+	controller.clock = fakeClock
+
+	controller.cleanBackups()
+
+	assert.Len(t, backupService.backupsByBucket["bucket-1"], 1, "only the child's parent should be kept")
+	assert.Equal(t, "parent", backupService.backupsByBucket["bucket-1"][0].Name)
+	assert.Len(t, backupService.backupsByBucket["bucket-3"], 0, "backups in the other readable buckets should be garbage-collected")
+	for _, action := range client.Actions() {
+		deleted := action.(core.DeleteAction).GetName()
+		assert.NotEqual(t, "backup-2", deleted, "API objects of backups in an unreadable bucket shouldn't be deleted")
+	}
+}
+
+// concurrentDeleteBackupService is a BackupService whose DeleteBackup is safe to call
+// concurrently, and records the most calls that were running at once.
+type concurrentDeleteBackupService struct {
+	cloudprovider.BackupService
+
+	lock      sync.Mutex
+	active    int
+	maxActive int
+	deleted   sets.String
+}
+
+func (s *concurrentDeleteBackupService) DeleteBackup(bucket, backupName string) error {
+	s.lock.Lock()
+	s.active++
+	if s.active > s.maxActive {
+		s.maxActive = s.active
+	}
+	s.lock.Unlock()
+
+	time.Sleep(10 * time.Millisecond)
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.active--
+	s.deleted.Insert(backupName)
+	return nil
+}
+
+func TestRemoveBackupsUsesWorkers(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	sharedInformers := informers.NewSharedInformerFactory(client, 0)
+	backupService := &concurrentDeleteBackupService{deleted: sets.NewString()}
+	recorder := &FakeEventRecorder{}
+
+	controller := NewGCController(
+		backupService,
+		nil,
+		nil,
+		nil,
+		"bucket",
+		nil,
+		time.Minute,
+		sharedInformers.Ark().V1().Backups(),
+		client.ArkV1(),
+		recorder,
+		metrics.NewServerMetrics(),
+	).(*gcController)
+	controller.workers = 3
+
+	var expired []expiredBackup
+	names := sets.NewString()
+	for i := 0; i < 6; i++ {
+		name := fmt.Sprintf("backup-%d", i)
+		names.Insert(name)
+		expired = append(expired, expiredBackup{backup: NewTestBackup().WithName(name).Backup, bucket: "bucket"})
+	}
+
+	removed := controller.removeBackups(expired)
+
+	assert.Equal(t, names, removed)
+	assert.Equal(t, names, backupService.deleted)
+	assert.True(t, backupService.maxActive > 1, "expected backups to be deleted concurrently")
+	assert.True(t, backupService.maxActive <= 3, "expected at most 3 backups to be deleted at once, got %d", backupService.maxActive)
+	assert.Len(t, recorder.Events, 6)
+}
+
 type fakeBackupService struct {
 	backupsByBucket map[string][]*api.Backup
 	mock.Mock
@@ -319,8 +611,48 @@ func (s *fakeBackupService) GetAllBackups(bucket string) ([]*api.Backup, error)
 	return backups, nil
 }
 
-func (bs *fakeBackupService) UploadBackup(bucket, name string, metadata, backup io.ReadSeeker) error {
-	args := bs.Called(bucket, name, metadata, backup)
+func (s *fakeBackupService) GetBackup(bucket, name string) (*api.Backup, error) {
+	backups, err := s.GetAllBackups(bucket)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, backup := range backups {
+		if backup.Name == name {
+			return backup, nil
+		}
+	}
+
+	return nil, cloudprovider.NewObjectNotFoundError(bucket, name+"/ark-backup.json")
+}
+
+func (bs *fakeBackupService) UploadBackup(bucket, name string, metadata, backup, log io.ReadSeeker) error {
+	args := bs.Called(bucket, name, metadata, backup, log)
+	return args.Error(0)
+}
+
+func (bs *fakeBackupService) UploadBackupLog(bucket, name string, log io.ReadSeeker) error {
+	args := bs.Called(bucket, name, log)
+	return args.Error(0)
+}
+
+func (bs *fakeBackupService) UploadBackupItemList(bucket, name string, items io.ReadSeeker) error {
+	args := bs.Called(bucket, name, items)
+	return args.Error(0)
+}
+
+func (bs *fakeBackupService) UploadRestorePlan(bucket, backupName, restoreName string, plan io.ReadSeeker) error {
+	args := bs.Called(bucket, backupName, restoreName, plan)
+	return args.Error(0)
+}
+
+func (bs *fakeBackupService) UploadRestoreLog(bucket, backupName, restoreName string, log io.ReadSeeker) error {
+	args := bs.Called(bucket, backupName, restoreName, log)
+	return args.Error(0)
+}
+
+func (bs *fakeBackupService) UploadRestoreResults(bucket, backupName, restoreName string, results io.ReadSeeker) error {
+	args := bs.Called(bucket, backupName, restoreName, results)
 	return args.Error(0)
 }
 
@@ -328,6 +660,15 @@ func (s *fakeBackupService) DownloadBackup(bucket, name string) (io.ReadCloser,
 	return ioutil.NopCloser(bytes.NewReader([]byte("hello world"))), nil
 }
 
+func (s *fakeBackupService) DownloadBackupLogs(bucket, name string) (io.ReadCloser, error) {
+	return ioutil.NopCloser(bytes.NewReader([]byte("hello world"))), nil
+}
+
+func (s *fakeBackupService) CreateSignedURL(target api.DownloadTarget, bucket, backupName string, ttl time.Duration) (string, error) {
+	args := s.Called(target, bucket, backupName, ttl)
+	return args.String(0), args.Error(1)
+}
+
 func (s *fakeBackupService) DeleteBackup(bucket, backupName string) error {
 	backups, err := s.GetAllBackups(bucket)
 	if err != nil {
@@ -350,3 +691,76 @@ func (s *fakeBackupService) DeleteBackup(bucket, backupName string) error {
 
 	return nil
 }
+
+func TestGarbageCollectDeletesCSISnapshots(t *testing.T) {
+	fakeClock := clock.NewFakeClock(time.Now())
+
+	newBackup := func() *api.Backup {
+		backup := NewTestBackup().WithName("backup-1").
+			WithExpiration(fakeClock.Now().Add(-1*time.Second)).
+			WithCSISnapshot("pv-1", "csi.example.com", "handle-1").
+			WithCSISnapshot("pv-2", "csi.example.com", "handle-2").
+			Backup
+		backup.Status.VolumeBackups["pv-1"].CSISnapshot.VolumeSnapshotNamespace = "ns-1"
+		backup.Status.VolumeBackups["pv-1"].CSISnapshot.VolumeSnapshotName = "backup-1-pv-1"
+		// pv-2's snapshot was taken by an older server, so its VolumeSnapshot isn't known and
+		// it's left in place.
+		return backup
+	}
+
+	tests := []struct {
+		name              string
+		csiSnapshotter    *fakeCSISnapshotter
+		expectedDeleted   []string
+		expectedRemaining []string
+	}{
+		{
+			name:            "VolumeSnapshots are deleted with the backup",
+			csiSnapshotter:  &fakeCSISnapshotter{},
+			expectedDeleted: []string{"handle-1"},
+		},
+		{
+			name:              "backup isn't garbage-collected without a CSI snapshotter",
+			expectedRemaining: []string{"backup-1"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			backupService := &fakeBackupService{backupsByBucket: map[string][]*api.Backup{"bucket-1": {newBackup()}}}
+			client := fake.NewSimpleClientset()
+			sharedInformers := informers.NewSharedInformerFactory(client, 0)
+
+			var csiSnapshotter csi.Snapshotter
+			if test.csiSnapshotter != nil {
+				csiSnapshotter = test.csiSnapshotter
+			}
+
+			controller := NewGCController(
+				backupService,
+				nil,
+				csiSnapshotter,
+				nil,
+				"bucket-1",
+				nil,
+				time.Minute,
+				sharedInformers.Ark().V1().Backups(),
+				client.ArkV1(),
+				&FakeEventRecorder{},
+				metrics.NewServerMetrics(),
+			).(*gcController)
+			controller.clock = fakeClock
+
+			controller.cleanBackups()
+
+			var remaining []string
+			for _, backup := range backupService.backupsByBucket["bucket-1"] {
+				remaining = append(remaining, backup.Name)
+			}
+			assert.Equal(t, test.expectedRemaining, remaining)
+			if test.csiSnapshotter != nil {
+				assert.Equal(t, test.expectedDeleted, test.csiSnapshotter.deleted)
+			}
+		})
+	}
+}
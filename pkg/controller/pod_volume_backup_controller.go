@@ -0,0 +1,311 @@
+/*
+Copyright 2017 Heptio Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/clock"
+	"k8s.io/apimachinery/pkg/util/wait"
+	corev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/pkg/api/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+
+	api "github.com/heptio/ark/pkg/apis/ark/v1"
+	"github.com/heptio/ark/pkg/generated/clientset/scheme"
+	arkv1client "github.com/heptio/ark/pkg/generated/clientset/typed/ark/v1"
+	informers "github.com/heptio/ark/pkg/generated/informers/externalversions/ark/v1"
+	listers "github.com/heptio/ark/pkg/generated/listers/ark/v1"
+	"github.com/heptio/ark/pkg/restic"
+	"github.com/heptio/ark/pkg/uploader"
+)
+
+type podVolumeBackupController struct {
+	podVolumeBackupClient arkv1client.PodVolumeBackupsGetter
+	podClient             corev1.PodsGetter
+	pvcClient             corev1.PersistentVolumeClaimsGetter
+	node                  string
+	volumeSlots           chan struct{}
+	limits                uploader.Limits
+
+	podVolumeBackupLister       listers.PodVolumeBackupLister
+	podVolumeBackupListerSynced cache.InformerSynced
+	syncHandler                 func(key string) error
+	queue                       workqueue.RateLimitingInterface
+
+	volumePath   func(pvcClient corev1.PersistentVolumeClaimsGetter, pod *v1.Pod, volumeName string) (string, error)
+	volumeSize   func(path string) (int64, error)
+	backupVolume func(uploaderType string, limits uploader.Limits, repo, path string, tags map[string]string) (string, error)
+	clock        clock.Clock
+}
+
+// NewPodVolumeBackupController returns a controller, run by the node agent on node, that backs
+// up the pod volumes requested by the PodVolumeBackups for node using restic, or kopia, within
+// limits. Each backup takes one of volumeSlots, if it isn't nil, which the node agent shares
+// between its controllers to bound how many volumes it backs up and restores at the same time.
+func NewPodVolumeBackupController(
+	podVolumeBackupClient arkv1client.PodVolumeBackupsGetter,
+	podVolumeBackupInformer informers.PodVolumeBackupInformer,
+	podClient corev1.PodsGetter,
+	pvcClient corev1.PersistentVolumeClaimsGetter,
+	node string,
+	volumeSlots chan struct{},
+	limits uploader.Limits,
+) Interface {
+	c := &podVolumeBackupController{
+		podVolumeBackupClient:       podVolumeBackupClient,
+		podClient:                   podClient,
+		pvcClient:                   pvcClient,
+		node:                        node,
+		volumeSlots:                 volumeSlots,
+		limits:                      limits,
+		podVolumeBackupLister:       podVolumeBackupInformer.Lister(),
+		podVolumeBackupListerSynced: podVolumeBackupInformer.Informer().HasSynced,
+		queue:                       workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "podvolumebackup"),
+
+		volumePath:   restic.VolumePath,
+		volumeSize:   restic.VolumeSize,
+		backupVolume: restic.BackupVolume,
+		clock:        &clock.RealClock{},
+	}
+
+	c.syncHandler = c.processPodVolumeBackup
+
+	podVolumeBackupInformer.Informer().AddEventHandler(
+		cache.ResourceEventHandlerFuncs{
+			AddFunc: func(obj interface{}) {
+				podVolumeBackup := obj.(*api.PodVolumeBackup)
+				if podVolumeBackup.Spec.Node != c.node {
+					return
+				}
+
+				key, err := cache.MetaNamespaceKeyFunc(obj)
+				if err != nil {
+					glog.Errorf("error creating queue key for %#v: %v", podVolumeBackup, err)
+					return
+				}
+				c.queue.Add(key)
+			},
+		},
+	)
+
+	return c
+}
+
+// Run is a blocking function that runs the specified number of worker goroutines
+// to process items in the work queue. It will return when it receives on the
+// ctx.Done() channel.
+func (c *podVolumeBackupController) Run(ctx context.Context, numWorkers int) error {
+	var wg sync.WaitGroup
+
+	defer func() {
+		glog.Infof("Waiting for workers to finish their work")
+
+		c.queue.ShutDown()
+
+		// We have to wait here in the deferred function instead of at the bottom of the function body
+		// because we have to shut down the queue in order for the workers to shut down gracefully, and
+		// we want to shut down the queue via defer and not at the end of the body.
+		wg.Wait()
+
+		glog.Infof("All workers have finished")
+	}()
+
+	glog.Info("Starting PodVolumeBackupController")
+	defer glog.Infof("Shutting down PodVolumeBackupController")
+
+	glog.Info("Waiting for caches to sync")
+	if !cache.WaitForCacheSync(ctx.Done(), c.podVolumeBackupListerSynced) {
+		return errors.New("timed out waiting for caches to sync")
+	}
+	glog.Info("Caches are synced")
+
+	wg.Add(numWorkers)
+	for i := 0; i < numWorkers; i++ {
+		go func() {
+			wait.Until(c.runWorker, time.Second, ctx.Done())
+			wg.Done()
+		}()
+	}
+
+	<-ctx.Done()
+
+	return nil
+}
+
+func (c *podVolumeBackupController) runWorker() {
+	// continually take items off the queue (waits if it's
+	// empty) until we get a shutdown signal from the queue
+	for c.processNextWorkItem() {
+	}
+}
+
+func (c *podVolumeBackupController) processNextWorkItem() bool {
+	key, quit := c.queue.Get()
+	if quit {
+		return false
+	}
+	// always call done on this item, since if it fails we'll add
+	// it back with rate-limiting below
+	defer c.queue.Done(key)
+
+	err := c.syncHandler(key.(string))
+	if err == nil {
+		// If you had no error, tell the queue to stop tracking history for your key. This will reset
+		// things like failure counts for per-item rate limiting.
+		c.queue.Forget(key)
+		return true
+	}
+
+	glog.Errorf("syncHandler error: %v", err)
+	// we had an error processing the item so add it back
+	// into the queue for re-processing with rate-limiting
+	c.queue.AddRateLimited(key)
+
+	return true
+}
+
+// processPodVolumeBackup backs up the volume of a new PodVolumeBackup, recording the snapshot's ID,
+// or why it failed, in its status.
+func (c *podVolumeBackupController) processPodVolumeBackup(key string) error {
+	glog.V(4).Infof("processPodVolumeBackup for key %q", key)
+	ns, name, err := cache.SplitMetaNamespaceKey(key)
+	if err != nil {
+		glog.V(4).Infof("error splitting key %q: %v", key, err)
+		return err
+	}
+
+	podVolumeBackup, err := c.podVolumeBackupLister.PodVolumeBackups(ns).Get(name)
+	if apierrors.IsNotFound(err) {
+		glog.V(4).Infof("unable to find pod volume backup %q: %v", key, err)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("error getting pod volume backup %q: %v", key, err)
+	}
+
+	switch podVolumeBackup.Status.Phase {
+	case "", api.PodVolumeBackupPhaseNew:
+	default:
+		return nil
+	}
+
+	// wait for one of the node agent's slots for moving volume data, leaving the
+	// PodVolumeBackup New until one is free.
+	if c.volumeSlots != nil {
+		c.volumeSlots <- struct{}{}
+		defer func() { <-c.volumeSlots }()
+	}
+
+	clone, err := clonePodVolumeBackup(podVolumeBackup)
+	if err != nil {
+		return err
+	}
+	clone.Status.Phase = api.PodVolumeBackupPhaseInProgress
+	clone.Status.StartTimestamp = metav1.NewTime(c.clock.Now())
+	if clone, err = c.podVolumeBackupClient.PodVolumeBackups(ns).Update(clone); err != nil {
+		return fmt.Errorf("error updating pod volume backup %q: %v", key, err)
+	}
+
+	volume := describePodVolume(clone.Spec.Pod, clone.Spec.Volume, clone.Spec.HostPath)
+	glog.Infof("Backing up %s", volume)
+	snapshotID, err := c.backupPodVolume(clone)
+	clone.Status.CompletionTimestamp = metav1.NewTime(c.clock.Now())
+	if err != nil {
+		glog.Errorf("error backing up %s: %v", volume, err)
+		clone.Status.Phase = api.PodVolumeBackupPhaseFailed
+		clone.Status.Message = err.Error()
+	} else {
+		clone.Status.Phase = api.PodVolumeBackupPhaseCompleted
+		clone.Status.SnapshotID = snapshotID
+		clone.Status.Progress.BytesDone = clone.Status.Progress.TotalBytes
+	}
+
+	if _, err := c.podVolumeBackupClient.PodVolumeBackups(ns).Update(clone); err != nil {
+		return fmt.Errorf("error updating pod volume backup %q: %v", key, err)
+	}
+	return nil
+}
+
+// backupPodVolume backs up the volume, or host path, podVolumeBackup requests, returning the
+// snapshot's ID. Before running restic, it records the size of the volume's data in
+// podVolumeBackup's progress.
+func (c *podVolumeBackupController) backupPodVolume(podVolumeBackup *api.PodVolumeBackup) (string, error) {
+	var path string
+	if podVolumeBackup.Spec.HostPath != "" {
+		path = restic.HostVolumePath(podVolumeBackup.Spec.HostPath)
+	} else {
+		ref := podVolumeBackup.Spec.Pod
+		pod, err := c.podClient.Pods(ref.Namespace).Get(ref.Name, metav1.GetOptions{})
+		if err != nil {
+			return "", fmt.Errorf("error getting pod: %v", err)
+		}
+		if pod.UID != ref.UID {
+			return "", fmt.Errorf("pod has been replaced since the backup was requested")
+		}
+
+		if path, err = c.volumePath(c.pvcClient, pod, podVolumeBackup.Spec.Volume); err != nil {
+			return "", err
+		}
+	}
+
+	// progress is best-effort, so failing to measure or record it doesn't fail the backup
+	if size, err := c.volumeSize(path); err != nil {
+		glog.Errorf("error measuring %s: %v", describePodVolume(podVolumeBackup.Spec.Pod, podVolumeBackup.Spec.Volume, podVolumeBackup.Spec.HostPath), err)
+	} else {
+		podVolumeBackup.Status.Progress.TotalBytes = size
+		if updated, err := c.podVolumeBackupClient.PodVolumeBackups(podVolumeBackup.Namespace).Update(podVolumeBackup); err != nil {
+			glog.Errorf("error updating progress of pod volume backup %s/%s: %v", podVolumeBackup.Namespace, podVolumeBackup.Name, err)
+		} else {
+			*podVolumeBackup = *updated
+		}
+	}
+
+	return c.backupVolume(podVolumeBackup.Spec.UploaderType, c.limits, podVolumeBackup.Spec.RepoIdentifier, path, podVolumeBackup.Spec.Tags)
+}
+
+// describePodVolume describes, for logs, the volume of pod or, if hostPath is set, the host path
+// of the PersistentVolume named volume, that a PodVolumeBackup or PodVolumeRestore moves data for.
+func describePodVolume(pod v1.ObjectReference, volume, hostPath string) string {
+	if hostPath != "" {
+		return fmt.Sprintf("host path %s of PersistentVolume %s", hostPath, volume)
+	}
+	return fmt.Sprintf("volume %s of pod %s/%s", volume, pod.Namespace, pod.Name)
+}
+
+func clonePodVolumeBackup(in interface{}) (*api.PodVolumeBackup, error) {
+	clone, err := scheme.Scheme.DeepCopy(in)
+	if err != nil {
+		return nil, err
+	}
+
+	out, ok := clone.(*api.PodVolumeBackup)
+	if !ok {
+		return nil, fmt.Errorf("unexpected type: %T", clone)
+	}
+
+	return out, nil
+}
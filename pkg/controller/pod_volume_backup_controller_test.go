@@ -0,0 +1,238 @@
+/*
+Copyright 2017 Heptio Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/clock"
+	corev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/pkg/api/v1"
+
+	api "github.com/heptio/ark/pkg/apis/ark/v1"
+	"github.com/heptio/ark/pkg/generated/clientset/fake"
+	informers "github.com/heptio/ark/pkg/generated/informers/externalversions"
+	"github.com/heptio/ark/pkg/uploader"
+)
+
+type fakePodsGetter struct {
+	pods map[string]*v1.Pod
+}
+
+func (g *fakePodsGetter) Pods(namespace string) corev1.PodInterface {
+	return &fakePodClient{getter: g}
+}
+
+type fakePodClient struct {
+	corev1.PodInterface
+	getter *fakePodsGetter
+}
+
+func (c *fakePodClient) Get(name string, opts metav1.GetOptions) (*v1.Pod, error) {
+	if pod, ok := c.getter.pods[name]; ok {
+		return pod, nil
+	}
+	return nil, apierrors.NewNotFound(v1.Resource("pods"), name)
+}
+
+func TestProcessPodVolumeBackup(t *testing.T) {
+	pod := &v1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "ns-1", Name: "pod-1", UID: "uid-1"}}
+
+	tests := []struct {
+		name               string
+		phase              api.PodVolumeBackupPhase
+		podUID             types.UID
+		backupErr          error
+		expectedPhase      api.PodVolumeBackupPhase
+		expectedSnapshotID string
+		expectedMessage    string
+		expectedProgress   api.PodVolumeOperationProgress
+	}{
+		{
+			name:               "new backup is completed",
+			phase:              api.PodVolumeBackupPhaseNew,
+			podUID:             "uid-1",
+			expectedPhase:      api.PodVolumeBackupPhaseCompleted,
+			expectedSnapshotID: "abc123",
+			expectedProgress:   api.PodVolumeOperationProgress{TotalBytes: 1024, BytesDone: 1024},
+		},
+		{
+			name:             "restic error fails the backup",
+			podUID:           "uid-1",
+			backupErr:        errors.New("error running restic backup"),
+			expectedPhase:    api.PodVolumeBackupPhaseFailed,
+			expectedMessage:  "error running restic backup",
+			expectedProgress: api.PodVolumeOperationProgress{TotalBytes: 1024},
+		},
+		{
+			name:            "replaced pod fails the backup",
+			podUID:          "uid-0",
+			expectedPhase:   api.PodVolumeBackupPhaseFailed,
+			expectedMessage: "pod has been replaced since the backup was requested",
+		},
+		{
+			name:          "completed backup is ignored",
+			phase:         api.PodVolumeBackupPhaseCompleted,
+			podUID:        "uid-1",
+			expectedPhase: api.PodVolumeBackupPhaseCompleted,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			client := fake.NewSimpleClientset()
+			sharedInformers := informers.NewSharedInformerFactory(client, 0)
+			podVolumeBackupInformer := sharedInformers.Ark().V1().PodVolumeBackups()
+
+			c := NewPodVolumeBackupController(
+				client.ArkV1(),
+				podVolumeBackupInformer,
+				&fakePodsGetter{pods: map[string]*v1.Pod{"pod-1": pod}},
+				nil,
+				"node-1",
+				nil,
+				uploader.Limits{},
+			).(*podVolumeBackupController)
+
+			var backedUpPath string
+			c.volumePath = func(_ corev1.PersistentVolumeClaimsGetter, pod *v1.Pod, volumeName string) (string, error) {
+				return "/host_pods/" + string(pod.UID) + "/volumes/kubernetes.io~empty-dir/" + volumeName, nil
+			}
+			c.volumeSize = func(path string) (int64, error) {
+				return 1024, nil
+			}
+			c.backupVolume = func(uploaderType string, limits uploader.Limits, repo, path string, tags map[string]string) (string, error) {
+				assert.Equal(t, "", uploaderType)
+				assert.Equal(t, "s3:s3.amazonaws.com/bucket/.ark-restic/ns-1", repo)
+				assert.Equal(t, map[string]string{"backup": "backup-1"}, tags)
+				backedUpPath = path
+				return "abc123", test.backupErr
+			}
+			now := time.Date(2017, 11, 1, 12, 0, 0, 0, time.UTC)
+			c.clock = clock.NewFakeClock(now)
+
+			req := &api.PodVolumeBackup{
+				ObjectMeta: metav1.ObjectMeta{Namespace: api.DefaultNamespace, Name: "backup-1-abcde"},
+				Spec: api.PodVolumeBackupSpec{
+					Node:           "node-1",
+					Pod:            v1.ObjectReference{Kind: "Pod", Namespace: "ns-1", Name: "pod-1", UID: test.podUID},
+					Volume:         "data",
+					RepoIdentifier: "s3:s3.amazonaws.com/bucket/.ark-restic/ns-1",
+					Tags:           map[string]string{"backup": "backup-1"},
+				},
+				Status: api.PodVolumeBackupStatus{Phase: test.phase},
+			}
+			podVolumeBackupInformer.Informer().GetStore().Add(req)
+			_, err := client.ArkV1().PodVolumeBackups(req.Namespace).Create(req)
+			require.NoError(t, err)
+
+			require.NoError(t, c.processPodVolumeBackup("heptio-ark/backup-1-abcde"))
+
+			res, err := client.ArkV1().PodVolumeBackups(req.Namespace).Get(req.Name, metav1.GetOptions{})
+			require.NoError(t, err)
+			assert.Equal(t, test.expectedPhase, res.Status.Phase)
+			assert.Equal(t, test.expectedSnapshotID, res.Status.SnapshotID)
+			assert.Equal(t, test.expectedMessage, res.Status.Message)
+			assert.Equal(t, test.expectedProgress, res.Status.Progress)
+			if test.phase == "" || test.phase == api.PodVolumeBackupPhaseNew {
+				assert.Equal(t, now, res.Status.StartTimestamp.Time.UTC())
+				assert.Equal(t, now, res.Status.CompletionTimestamp.Time.UTC())
+			}
+			if test.expectedSnapshotID != "" {
+				assert.Equal(t, "/host_pods/uid-1/volumes/kubernetes.io~empty-dir/data", backedUpPath)
+			}
+		})
+	}
+}
+
+func TestProcessPodVolumeBackupWaitsForVolumeSlot(t *testing.T) {
+	pod := &v1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "ns-1", Name: "pod-1", UID: "uid-1"}}
+
+	client := fake.NewSimpleClientset()
+	sharedInformers := informers.NewSharedInformerFactory(client, 0)
+	podVolumeBackupInformer := sharedInformers.Ark().V1().PodVolumeBackups()
+
+	// the node agent's only slot is taken by another volume
+	volumeSlots := make(chan struct{}, 1)
+	volumeSlots <- struct{}{}
+
+	c := NewPodVolumeBackupController(
+		client.ArkV1(),
+		podVolumeBackupInformer,
+		&fakePodsGetter{pods: map[string]*v1.Pod{"pod-1": pod}},
+		nil,
+		"node-1",
+		volumeSlots,
+		uploader.Limits{UploadKiBps: 1024},
+	).(*podVolumeBackupController)
+
+	c.volumePath = func(_ corev1.PersistentVolumeClaimsGetter, pod *v1.Pod, volumeName string) (string, error) {
+		return "/host_pods/" + string(pod.UID) + "/volumes/kubernetes.io~empty-dir/" + volumeName, nil
+	}
+	c.volumeSize = func(path string) (int64, error) {
+		return 1024, nil
+	}
+	c.backupVolume = func(uploaderType string, limits uploader.Limits, repo, path string, tags map[string]string) (string, error) {
+		assert.Equal(t, uploader.Limits{UploadKiBps: 1024}, limits)
+		return "abc123", nil
+	}
+
+	req := &api.PodVolumeBackup{
+		ObjectMeta: metav1.ObjectMeta{Namespace: api.DefaultNamespace, Name: "backup-1-abcde"},
+		Spec: api.PodVolumeBackupSpec{
+			Node:           "node-1",
+			Pod:            v1.ObjectReference{Kind: "Pod", Namespace: "ns-1", Name: "pod-1", UID: "uid-1"},
+			Volume:         "data",
+			RepoIdentifier: "s3:s3.amazonaws.com/bucket/.ark-restic/ns-1",
+		},
+		Status: api.PodVolumeBackupStatus{Phase: api.PodVolumeBackupPhaseNew},
+	}
+	podVolumeBackupInformer.Informer().GetStore().Add(req)
+	_, err := client.ArkV1().PodVolumeBackups(req.Namespace).Create(req)
+	require.NoError(t, err)
+
+	done := make(chan error)
+	go func() {
+		done <- c.processPodVolumeBackup("heptio-ark/backup-1-abcde")
+	}()
+
+	select {
+	case <-done:
+		t.Fatal("backup didn't wait for a volume slot")
+	case <-time.After(100 * time.Millisecond):
+	}
+	res, err := client.ArkV1().PodVolumeBackups(req.Namespace).Get(req.Name, metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, api.PodVolumeBackupPhaseNew, res.Status.Phase)
+
+	// free the slot
+	<-volumeSlots
+	require.NoError(t, <-done)
+
+	res, err = client.ArkV1().PodVolumeBackups(req.Namespace).Get(req.Name, metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, api.PodVolumeBackupPhaseCompleted, res.Status.Phase)
+	assert.Empty(t, volumeSlots, "expected the backup to give back its slot")
+}
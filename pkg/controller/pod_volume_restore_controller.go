@@ -0,0 +1,288 @@
+/*
+Copyright 2017 Heptio Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/clock"
+	"k8s.io/apimachinery/pkg/util/wait"
+	corev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/pkg/api/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+
+	api "github.com/heptio/ark/pkg/apis/ark/v1"
+	"github.com/heptio/ark/pkg/generated/clientset/scheme"
+	arkv1client "github.com/heptio/ark/pkg/generated/clientset/typed/ark/v1"
+	informers "github.com/heptio/ark/pkg/generated/informers/externalversions/ark/v1"
+	listers "github.com/heptio/ark/pkg/generated/listers/ark/v1"
+	"github.com/heptio/ark/pkg/restic"
+	"github.com/heptio/ark/pkg/uploader"
+)
+
+type podVolumeRestoreController struct {
+	podVolumeRestoreClient arkv1client.PodVolumeRestoresGetter
+	podClient              corev1.PodsGetter
+	pvcClient              corev1.PersistentVolumeClaimsGetter
+	node                   string
+	volumeSlots            chan struct{}
+	limits                 uploader.Limits
+
+	podVolumeRestoreLister       listers.PodVolumeRestoreLister
+	podVolumeRestoreListerSynced cache.InformerSynced
+	syncHandler                  func(key string) error
+	queue                        workqueue.RateLimitingInterface
+
+	volumePath    func(pvcClient corev1.PersistentVolumeClaimsGetter, pod *v1.Pod, volumeName string) (string, error)
+	restoreVolume func(uploaderType string, limits uploader.Limits, repo, snapshotID, path string, restoreUID types.UID) (int64, error)
+	clock         clock.Clock
+}
+
+// NewPodVolumeRestoreController returns a controller, run by the node agent on node, that
+// restores the snapshots requested by the PodVolumeRestores for node into pod volumes, within
+// limits. Each restore takes one of volumeSlots, if it isn't nil, which the node agent shares
+// between its controllers to bound how many volumes it backs up and restores at the same time.
+func NewPodVolumeRestoreController(
+	podVolumeRestoreClient arkv1client.PodVolumeRestoresGetter,
+	podVolumeRestoreInformer informers.PodVolumeRestoreInformer,
+	podClient corev1.PodsGetter,
+	pvcClient corev1.PersistentVolumeClaimsGetter,
+	node string,
+	volumeSlots chan struct{},
+	limits uploader.Limits,
+) Interface {
+	c := &podVolumeRestoreController{
+		podVolumeRestoreClient:       podVolumeRestoreClient,
+		podClient:                    podClient,
+		pvcClient:                    pvcClient,
+		node:                         node,
+		volumeSlots:                  volumeSlots,
+		limits:                       limits,
+		podVolumeRestoreLister:       podVolumeRestoreInformer.Lister(),
+		podVolumeRestoreListerSynced: podVolumeRestoreInformer.Informer().HasSynced,
+		queue:                        workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "podvolumerestore"),
+
+		volumePath:    restic.VolumePath,
+		restoreVolume: restic.RestoreVolume,
+		clock:         &clock.RealClock{},
+	}
+
+	c.syncHandler = c.processPodVolumeRestore
+
+	podVolumeRestoreInformer.Informer().AddEventHandler(
+		cache.ResourceEventHandlerFuncs{
+			AddFunc: func(obj interface{}) {
+				podVolumeRestore := obj.(*api.PodVolumeRestore)
+				if podVolumeRestore.Spec.Node != c.node {
+					return
+				}
+
+				key, err := cache.MetaNamespaceKeyFunc(obj)
+				if err != nil {
+					glog.Errorf("error creating queue key for %#v: %v", podVolumeRestore, err)
+					return
+				}
+				c.queue.Add(key)
+			},
+		},
+	)
+
+	return c
+}
+
+// Run is a blocking function that runs the specified number of worker goroutines
+// to process items in the work queue. It will return when it receives on the
+// ctx.Done() channel.
+func (c *podVolumeRestoreController) Run(ctx context.Context, numWorkers int) error {
+	var wg sync.WaitGroup
+
+	defer func() {
+		glog.Infof("Waiting for workers to finish their work")
+
+		c.queue.ShutDown()
+
+		// We have to wait here in the deferred function instead of at the bottom of the function body
+		// because we have to shut down the queue in order for the workers to shut down gracefully, and
+		// we want to shut down the queue via defer and not at the end of the body.
+		wg.Wait()
+
+		glog.Infof("All workers have finished")
+	}()
+
+	glog.Info("Starting PodVolumeRestoreController")
+	defer glog.Infof("Shutting down PodVolumeRestoreController")
+
+	glog.Info("Waiting for caches to sync")
+	if !cache.WaitForCacheSync(ctx.Done(), c.podVolumeRestoreListerSynced) {
+		return errors.New("timed out waiting for caches to sync")
+	}
+	glog.Info("Caches are synced")
+
+	wg.Add(numWorkers)
+	for i := 0; i < numWorkers; i++ {
+		go func() {
+			wait.Until(c.runWorker, time.Second, ctx.Done())
+			wg.Done()
+		}()
+	}
+
+	<-ctx.Done()
+
+	return nil
+}
+
+func (c *podVolumeRestoreController) runWorker() {
+	// continually take items off the queue (waits if it's
+	// empty) until we get a shutdown signal from the queue
+	for c.processNextWorkItem() {
+	}
+}
+
+func (c *podVolumeRestoreController) processNextWorkItem() bool {
+	key, quit := c.queue.Get()
+	if quit {
+		return false
+	}
+	// always call done on this item, since if it fails we'll add
+	// it back with rate-limiting below
+	defer c.queue.Done(key)
+
+	err := c.syncHandler(key.(string))
+	if err == nil {
+		// If you had no error, tell the queue to stop tracking history for your key. This will reset
+		// things like failure counts for per-item rate limiting.
+		c.queue.Forget(key)
+		return true
+	}
+
+	glog.Errorf("syncHandler error: %v", err)
+	// we had an error processing the item so add it back
+	// into the queue for re-processing with rate-limiting
+	c.queue.AddRateLimited(key)
+
+	return true
+}
+
+// processPodVolumeRestore restores the snapshot of a new PodVolumeRestore into its volume,
+// recording whether it succeeded in its status.
+func (c *podVolumeRestoreController) processPodVolumeRestore(key string) error {
+	glog.V(4).Infof("processPodVolumeRestore for key %q", key)
+	ns, name, err := cache.SplitMetaNamespaceKey(key)
+	if err != nil {
+		glog.V(4).Infof("error splitting key %q: %v", key, err)
+		return err
+	}
+
+	podVolumeRestore, err := c.podVolumeRestoreLister.PodVolumeRestores(ns).Get(name)
+	if apierrors.IsNotFound(err) {
+		glog.V(4).Infof("unable to find pod volume restore %q: %v", key, err)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("error getting pod volume restore %q: %v", key, err)
+	}
+
+	switch podVolumeRestore.Status.Phase {
+	case "", api.PodVolumeRestorePhaseNew:
+	default:
+		return nil
+	}
+
+	// wait for one of the node agent's slots for moving volume data, leaving the
+	// PodVolumeRestore New until one is free.
+	if c.volumeSlots != nil {
+		c.volumeSlots <- struct{}{}
+		defer func() { <-c.volumeSlots }()
+	}
+
+	clone, err := clonePodVolumeRestore(podVolumeRestore)
+	if err != nil {
+		return err
+	}
+	clone.Status.Phase = api.PodVolumeRestorePhaseInProgress
+	clone.Status.StartTimestamp = metav1.NewTime(c.clock.Now())
+	if clone, err = c.podVolumeRestoreClient.PodVolumeRestores(ns).Update(clone); err != nil {
+		return fmt.Errorf("error updating pod volume restore %q: %v", key, err)
+	}
+
+	volume := describePodVolume(clone.Spec.Pod, clone.Spec.Volume, clone.Spec.HostPath)
+	glog.Infof("Restoring %s", volume)
+	size, err := c.restorePodVolume(clone)
+	clone.Status.CompletionTimestamp = metav1.NewTime(c.clock.Now())
+	if err != nil {
+		glog.Errorf("error restoring %s: %v", volume, err)
+		clone.Status.Phase = api.PodVolumeRestorePhaseFailed
+		clone.Status.Message = err.Error()
+	} else {
+		clone.Status.Phase = api.PodVolumeRestorePhaseCompleted
+		clone.Status.Progress = api.PodVolumeOperationProgress{TotalBytes: size, BytesDone: size}
+	}
+
+	if _, err := c.podVolumeRestoreClient.PodVolumeRestores(ns).Update(clone); err != nil {
+		return fmt.Errorf("error updating pod volume restore %q: %v", key, err)
+	}
+	return nil
+}
+
+// restorePodVolume restores the snapshot podVolumeRestore requests into its volume, or host path,
+// returning the size of the restored data.
+func (c *podVolumeRestoreController) restorePodVolume(podVolumeRestore *api.PodVolumeRestore) (int64, error) {
+	if podVolumeRestore.Spec.HostPath != "" {
+		path := restic.HostVolumePath(podVolumeRestore.Spec.HostPath)
+		return c.restoreVolume(podVolumeRestore.Spec.UploaderType, c.limits, podVolumeRestore.Spec.RepoIdentifier, podVolumeRestore.Spec.SnapshotID, path, "")
+	}
+
+	ref := podVolumeRestore.Spec.Pod
+	pod, err := c.podClient.Pods(ref.Namespace).Get(ref.Name, metav1.GetOptions{})
+	if err != nil {
+		return 0, fmt.Errorf("error getting pod: %v", err)
+	}
+	if pod.UID != ref.UID {
+		return 0, fmt.Errorf("pod has been replaced since the restore was requested")
+	}
+
+	path, err := c.volumePath(c.pvcClient, pod, podVolumeRestore.Spec.Volume)
+	if err != nil {
+		return 0, err
+	}
+
+	return c.restoreVolume(podVolumeRestore.Spec.UploaderType, c.limits, podVolumeRestore.Spec.RepoIdentifier, podVolumeRestore.Spec.SnapshotID, path, podVolumeRestore.Spec.RestoreUID)
+}
+
+func clonePodVolumeRestore(in interface{}) (*api.PodVolumeRestore, error) {
+	clone, err := scheme.Scheme.DeepCopy(in)
+	if err != nil {
+		return nil, err
+	}
+
+	out, ok := clone.(*api.PodVolumeRestore)
+	if !ok {
+		return nil, fmt.Errorf("unexpected type: %T", clone)
+	}
+
+	return out, nil
+}
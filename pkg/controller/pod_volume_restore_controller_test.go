@@ -0,0 +1,191 @@
+/*
+Copyright 2017 Heptio Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/clock"
+	corev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/pkg/api/v1"
+
+	api "github.com/heptio/ark/pkg/apis/ark/v1"
+	"github.com/heptio/ark/pkg/generated/clientset/fake"
+	informers "github.com/heptio/ark/pkg/generated/informers/externalversions"
+	"github.com/heptio/ark/pkg/uploader"
+)
+
+func TestProcessPodVolumeRestore(t *testing.T) {
+	pod := &v1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "ns-1", Name: "pod-1", UID: "uid-1"}}
+
+	tests := []struct {
+		name            string
+		phase           api.PodVolumeRestorePhase
+		podUID          types.UID
+		restoreErr      error
+		expectRestore   bool
+		expectedPhase   api.PodVolumeRestorePhase
+		expectedMessage string
+	}{
+		{
+			name:          "new restore is completed",
+			phase:         api.PodVolumeRestorePhaseNew,
+			podUID:        "uid-1",
+			expectRestore: true,
+			expectedPhase: api.PodVolumeRestorePhaseCompleted,
+		},
+		{
+			name:            "restic error fails the restore",
+			podUID:          "uid-1",
+			restoreErr:      errors.New("error running restic restore"),
+			expectRestore:   true,
+			expectedPhase:   api.PodVolumeRestorePhaseFailed,
+			expectedMessage: "error running restic restore",
+		},
+		{
+			name:            "replaced pod fails the restore",
+			podUID:          "uid-0",
+			expectedPhase:   api.PodVolumeRestorePhaseFailed,
+			expectedMessage: "pod has been replaced since the restore was requested",
+		},
+		{
+			name:          "failed restore is ignored",
+			phase:         api.PodVolumeRestorePhaseFailed,
+			podUID:        "uid-1",
+			expectedPhase: api.PodVolumeRestorePhaseFailed,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			client := fake.NewSimpleClientset()
+			sharedInformers := informers.NewSharedInformerFactory(client, 0)
+			podVolumeRestoreInformer := sharedInformers.Ark().V1().PodVolumeRestores()
+
+			c := NewPodVolumeRestoreController(
+				client.ArkV1(),
+				podVolumeRestoreInformer,
+				&fakePodsGetter{pods: map[string]*v1.Pod{"pod-1": pod}},
+				nil,
+				"node-1",
+				nil,
+				uploader.Limits{},
+			).(*podVolumeRestoreController)
+
+			restored := false
+			c.volumePath = func(_ corev1.PersistentVolumeClaimsGetter, pod *v1.Pod, volumeName string) (string, error) {
+				return "/host_pods/" + string(pod.UID) + "/volumes/kubernetes.io~empty-dir/" + volumeName, nil
+			}
+			c.restoreVolume = func(uploaderType string, limits uploader.Limits, repo, snapshotID, path string, restoreUID types.UID) (int64, error) {
+				assert.Equal(t, "", uploaderType)
+				assert.Equal(t, "s3:s3.amazonaws.com/bucket/.ark-restic/ns-1", repo)
+				assert.Equal(t, "abc123", snapshotID)
+				assert.Equal(t, "/host_pods/uid-1/volumes/kubernetes.io~empty-dir/data", path)
+				assert.Equal(t, types.UID("restore-uid"), restoreUID)
+				restored = true
+				return 1024, test.restoreErr
+			}
+			now := time.Date(2017, 11, 1, 12, 0, 0, 0, time.UTC)
+			c.clock = clock.NewFakeClock(now)
+
+			req := &api.PodVolumeRestore{
+				ObjectMeta: metav1.ObjectMeta{Namespace: api.DefaultNamespace, Name: "restore-1-abcde"},
+				Spec: api.PodVolumeRestoreSpec{
+					Node:           "node-1",
+					Pod:            v1.ObjectReference{Kind: "Pod", Namespace: "ns-1", Name: "pod-1", UID: test.podUID},
+					Volume:         "data",
+					RepoIdentifier: "s3:s3.amazonaws.com/bucket/.ark-restic/ns-1",
+					SnapshotID:     "abc123",
+					RestoreUID:     "restore-uid",
+				},
+				Status: api.PodVolumeRestoreStatus{Phase: test.phase},
+			}
+			podVolumeRestoreInformer.Informer().GetStore().Add(req)
+			_, err := client.ArkV1().PodVolumeRestores(req.Namespace).Create(req)
+			require.NoError(t, err)
+
+			require.NoError(t, c.processPodVolumeRestore("heptio-ark/restore-1-abcde"))
+
+			res, err := client.ArkV1().PodVolumeRestores(req.Namespace).Get(req.Name, metav1.GetOptions{})
+			require.NoError(t, err)
+			assert.Equal(t, test.expectedPhase, res.Status.Phase)
+			assert.Equal(t, test.expectedMessage, res.Status.Message)
+			assert.Equal(t, test.expectRestore, restored)
+			if test.phase == "" || test.phase == api.PodVolumeRestorePhaseNew {
+				assert.Equal(t, now, res.Status.StartTimestamp.Time.UTC())
+				assert.Equal(t, now, res.Status.CompletionTimestamp.Time.UTC())
+			}
+			if test.expectedPhase == api.PodVolumeRestorePhaseCompleted {
+				assert.Equal(t, api.PodVolumeOperationProgress{TotalBytes: 1024, BytesDone: 1024}, res.Status.Progress)
+			} else {
+				assert.Equal(t, api.PodVolumeOperationProgress{}, res.Status.Progress)
+			}
+		})
+	}
+}
+
+func TestProcessPodVolumeRestoreHostPath(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	sharedInformers := informers.NewSharedInformerFactory(client, 0)
+	podVolumeRestoreInformer := sharedInformers.Ark().V1().PodVolumeRestores()
+
+	c := NewPodVolumeRestoreController(
+		client.ArkV1(),
+		podVolumeRestoreInformer,
+		&fakePodsGetter{},
+		nil,
+		"node-1",
+		nil,
+		uploader.Limits{},
+	).(*podVolumeRestoreController)
+
+	restored := false
+	c.restoreVolume = func(uploaderType string, limits uploader.Limits, repo, snapshotID, path string, restoreUID types.UID) (int64, error) {
+		assert.Equal(t, "/host_root/mnt/disks/ssd1", path)
+		assert.Equal(t, types.UID(""), restoreUID)
+		restored = true
+		return 1024, nil
+	}
+
+	req := &api.PodVolumeRestore{
+		ObjectMeta: metav1.ObjectMeta{Namespace: api.DefaultNamespace, Name: "restore-1-abcde"},
+		Spec: api.PodVolumeRestoreSpec{
+			Node:           "node-1",
+			Volume:         "pv-1",
+			HostPath:       "/mnt/disks/ssd1",
+			RepoIdentifier: "s3:s3.amazonaws.com/bucket/.ark-restic/ns-1",
+			SnapshotID:     "abc123",
+		},
+	}
+	podVolumeRestoreInformer.Informer().GetStore().Add(req)
+	_, err := client.ArkV1().PodVolumeRestores(req.Namespace).Create(req)
+	require.NoError(t, err)
+
+	require.NoError(t, c.processPodVolumeRestore("heptio-ark/restore-1-abcde"))
+
+	res, err := client.ArkV1().PodVolumeRestores(req.Namespace).Get(req.Name, metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, api.PodVolumeRestorePhaseCompleted, res.Status.Phase)
+	assert.True(t, restored)
+}
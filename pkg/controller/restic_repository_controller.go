@@ -0,0 +1,275 @@
+/*
+Copyright 2017 Heptio Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/golang/glog"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/clock"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/tools/cache"
+
+	api "github.com/heptio/ark/pkg/apis/ark/v1"
+	"github.com/heptio/ark/pkg/cloudprovider"
+	"github.com/heptio/ark/pkg/generated/clientset/scheme"
+	arkv1client "github.com/heptio/ark/pkg/generated/clientset/typed/ark/v1"
+	informers "github.com/heptio/ark/pkg/generated/informers/externalversions/ark/v1"
+	listers "github.com/heptio/ark/pkg/generated/listers/ark/v1"
+	"github.com/heptio/ark/pkg/restic"
+)
+
+// resticRepositorySyncPeriod is how often the resticRepositoryController looks for new
+// repositories and for repositories whose maintenance is due.
+const resticRepositorySyncPeriod = 5 * time.Minute
+
+// resticRepositoryController maintains the restic repositories in a backup storage location. It
+// creates a ResticRepository for each namespace that has a repository in the location, and
+// prunes and checks each repository when its maintenance is due.
+type resticRepositoryController struct {
+	client               arkv1client.ResticRepositoriesGetter
+	lister               listers.ResticRepositoryLister
+	listerSynced         cache.InformerSynced
+	backupLister         listers.BackupLister
+	backupListerSynced   cache.InformerSynced
+	restoreLister        listers.RestoreLister
+	restoreListerSynced  cache.InformerSynced
+	objectStorage        cloudprovider.ObjectStorageAdapter
+	location             *api.BackupStorageLocation
+	maintainer           restic.Maintainer
+	maintenanceFrequency time.Duration
+	namespace            string
+	syncPeriod           time.Duration
+	clock                clock.Clock
+}
+
+// NewResticRepositoryController constructs a controller that maintains the restic repositories
+// in location, whose object storage is objectStorage, using maintainer. The ResticRepositories
+// it creates, in namespace, are maintained every maintenanceFrequency unless they're changed.
+// Repositories aren't maintained while any backup or restore is running, since pruning a
+// repository locks it exclusively.
+func NewResticRepositoryController(
+	resticRepositoryInformer informers.ResticRepositoryInformer,
+	client arkv1client.ResticRepositoriesGetter,
+	backupInformer informers.BackupInformer,
+	restoreInformer informers.RestoreInformer,
+	objectStorage cloudprovider.ObjectStorageAdapter,
+	location *api.BackupStorageLocation,
+	maintainer restic.Maintainer,
+	maintenanceFrequency time.Duration,
+	namespace string,
+) Interface {
+	return &resticRepositoryController{
+		client:               client,
+		lister:               resticRepositoryInformer.Lister(),
+		listerSynced:         resticRepositoryInformer.Informer().HasSynced,
+		backupLister:         backupInformer.Lister(),
+		backupListerSynced:   backupInformer.Informer().HasSynced,
+		restoreLister:        restoreInformer.Lister(),
+		restoreListerSynced:  restoreInformer.Informer().HasSynced,
+		objectStorage:        objectStorage,
+		location:             location,
+		maintainer:           maintainer,
+		maintenanceFrequency: maintenanceFrequency,
+		namespace:            namespace,
+		syncPeriod:           resticRepositorySyncPeriod,
+		clock:                &clock.RealClock{},
+	}
+}
+
+var _ Interface = &resticRepositoryController{}
+
+// Run is a blocking function that periodically creates ResticRepositories for new repositories
+// and maintains the ones that are due, one at a time. It will return when it receives on the
+// ctx.Done() channel.
+func (c *resticRepositoryController) Run(ctx context.Context, workers int) error {
+	glog.Info("Waiting for caches to sync")
+	if !cache.WaitForCacheSync(ctx.Done(), c.listerSynced, c.backupListerSynced, c.restoreListerSynced) {
+		return errors.New("timed out waiting for caches to sync")
+	}
+	glog.Info("Caches are synced")
+
+	wait.Until(c.run, c.syncPeriod, ctx.Done())
+	return nil
+}
+
+func (c *resticRepositoryController) run() {
+	if err := c.ensureRepositories(); err != nil {
+		glog.Errorf("error creating restic repositories: %v", err)
+	}
+
+	repos, err := c.lister.ResticRepositories(c.namespace).List(labels.Everything())
+	if err != nil {
+		glog.Errorf("error listing restic repositories: %v", err)
+		return
+	}
+	sort.Slice(repos, func(i, j int) bool { return repos[i].Name < repos[j].Name })
+
+	for _, repo := range repos {
+		if err := c.maintainIfDue(repo); err != nil {
+			glog.Errorf("error maintaining restic repository %s/%s: %v", repo.Namespace, repo.Name, err)
+		}
+	}
+}
+
+// ensureRepositories creates a ResticRepository for each namespace that has a restic repository
+// in c.location but doesn't have one yet.
+func (c *resticRepositoryController) ensureRepositories() error {
+	namespaces, err := restic.RepoNamespaces(c.objectStorage, c.location.Spec.Bucket)
+	if err != nil {
+		return err
+	}
+
+	repos, err := c.lister.ResticRepositories(c.namespace).List(labels.Everything())
+	if err != nil {
+		return err
+	}
+	existing := sets.NewString()
+	for _, repo := range repos {
+		if repo.Spec.BackupStorageLocation == c.location.Name {
+			existing.Insert(repo.Spec.VolumeNamespace)
+		}
+	}
+
+	for _, namespace := range namespaces {
+		if existing.Has(namespace) {
+			continue
+		}
+
+		identifier, err := restic.RepoIdentifier(c.location.Spec.ObjectStorageProviderConfig, namespace)
+		if err != nil {
+			return err
+		}
+
+		repo := &api.ResticRepository{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: c.namespace,
+				Name:      namespace + "-" + c.location.Name,
+			},
+			Spec: api.ResticRepositorySpec{
+				VolumeNamespace:       namespace,
+				BackupStorageLocation: c.location.Name,
+				ResticIdentifier:      identifier,
+				MaintenanceFrequency:  metav1.Duration{Duration: c.maintenanceFrequency},
+			},
+			Status: api.ResticRepositoryStatus{
+				Phase: api.ResticRepositoryPhaseNew,
+			},
+		}
+
+		glog.Infof("Creating restic repository %s/%s for namespace %s", repo.Namespace, repo.Name, namespace)
+		if _, err := c.client.ResticRepositories(c.namespace).Create(repo); err != nil && !apierrors.IsAlreadyExists(err) {
+			return fmt.Errorf("error creating restic repository %s/%s: %v", repo.Namespace, repo.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// maintainIfDue maintains repo if it's never been maintained or its maintenance frequency has
+// passed since it last was, unless a backup or restore is running, and records the outcome in
+// its status.
+func (c *resticRepositoryController) maintainIfDue(repo *api.ResticRepository) error {
+	if repo.Spec.BackupStorageLocation != c.location.Name {
+		glog.V(2).Infof("Skipping restic repository %s/%s since it's in backup storage location %s", repo.Namespace, repo.Name, repo.Spec.BackupStorageLocation)
+		return nil
+	}
+
+	now := c.clock.Now()
+	if last := repo.Status.LastMaintenanceTime; !last.IsZero() && now.Before(last.Add(repo.Spec.MaintenanceFrequency.Duration)) {
+		return nil
+	}
+
+	if running, err := c.runningOperation(); err != nil {
+		return err
+	} else if running != "" {
+		glog.Infof("Postponing maintenance of restic repository %s/%s while %s is running", repo.Namespace, repo.Name, running)
+		return nil
+	}
+
+	glog.Infof("Maintaining restic repository %s/%s", repo.Namespace, repo.Name)
+	size, err := c.maintainer.MaintainRepository(repo.Spec.ResticIdentifier)
+
+	clone, cloneErr := cloneResticRepository(repo)
+	if cloneErr != nil {
+		return cloneErr
+	}
+	clone.Status.LastMaintenanceTime = metav1.NewTime(c.clock.Now())
+	if err != nil {
+		glog.Errorf("error maintaining restic repository %s/%s: %v", repo.Namespace, repo.Name, err)
+		clone.Status.Phase = api.ResticRepositoryPhaseNotReady
+		clone.Status.Message = err.Error()
+	} else {
+		clone.Status.Phase = api.ResticRepositoryPhaseReady
+		clone.Status.Message = ""
+		clone.Status.SizeBytes = size
+	}
+
+	if _, err := c.client.ResticRepositories(clone.Namespace).Update(clone); err != nil {
+		return fmt.Errorf("error updating restic repository: %v", err)
+	}
+	return nil
+}
+
+// runningOperation returns a description of a backup or restore that's running, or "" if none
+// are.
+func (c *resticRepositoryController) runningOperation() (string, error) {
+	backups, err := c.backupLister.List(labels.Everything())
+	if err != nil {
+		return "", err
+	}
+	for _, backup := range backups {
+		if backup.Status.Phase == api.BackupPhaseInProgress {
+			return fmt.Sprintf("backup %s/%s", backup.Namespace, backup.Name), nil
+		}
+	}
+
+	restores, err := c.restoreLister.List(labels.Everything())
+	if err != nil {
+		return "", err
+	}
+	for _, restore := range restores {
+		if restore.Status.Phase == api.RestorePhaseInProgress {
+			return fmt.Sprintf("restore %s/%s", restore.Namespace, restore.Name), nil
+		}
+	}
+
+	return "", nil
+}
+
+func cloneResticRepository(in interface{}) (*api.ResticRepository, error) {
+	clone, err := scheme.Scheme.DeepCopy(in)
+	if err != nil {
+		return nil, err
+	}
+
+	out, ok := clone.(*api.ResticRepository)
+	if !ok {
+		return nil, fmt.Errorf("unexpected type: %T", clone)
+	}
+
+	return out, nil
+}
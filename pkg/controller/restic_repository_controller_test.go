@@ -0,0 +1,217 @@
+/*
+Copyright 2017 Heptio Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/clock"
+
+	api "github.com/heptio/ark/pkg/apis/ark/v1"
+	"github.com/heptio/ark/pkg/generated/clientset/fake"
+	informers "github.com/heptio/ark/pkg/generated/informers/externalversions"
+)
+
+type fakeResticMaintainer struct {
+	size       int64
+	err        error
+	maintained []string
+}
+
+func (m *fakeResticMaintainer) MaintainRepository(repo string) (int64, error) {
+	m.maintained = append(m.maintained, repo)
+	return m.size, m.err
+}
+
+func newTestResticRepositoryController(client *fake.Clientset, storage *fakeObjectStorage, maintainer *fakeResticMaintainer) (*resticRepositoryController, informers.SharedInformerFactory) {
+	sharedInformers := informers.NewSharedInformerFactory(client, 0)
+	location := &api.BackupStorageLocation{
+		ObjectMeta: metav1.ObjectMeta{Namespace: api.DefaultNamespace, Name: "default"},
+		Spec: api.BackupStorageLocationSpec{
+			ObjectStorageProviderConfig: api.ObjectStorageProviderConfig{
+				CloudProviderConfig: api.CloudProviderConfig{AWS: &api.AWSConfig{Region: "us-west-2"}},
+				Bucket:              "bucket",
+			},
+		},
+	}
+
+	c := NewResticRepositoryController(
+		sharedInformers.Ark().V1().ResticRepositories(),
+		client.ArkV1(),
+		sharedInformers.Ark().V1().Backups(),
+		sharedInformers.Ark().V1().Restores(),
+		storage,
+		location,
+		maintainer,
+		24*time.Hour,
+		api.DefaultNamespace,
+	).(*resticRepositoryController)
+
+	return c, sharedInformers
+}
+
+func TestEnsureResticRepositories(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	storage := &fakeObjectStorage{prefixes: []string{".ark-restic/ns-1/", ".ark-restic/ns-2/"}}
+	c, sharedInformers := newTestResticRepositoryController(client, storage, &fakeResticMaintainer{})
+
+	existing := &api.ResticRepository{
+		ObjectMeta: metav1.ObjectMeta{Namespace: api.DefaultNamespace, Name: "ns-1-default"},
+		Spec:       api.ResticRepositorySpec{VolumeNamespace: "ns-1", BackupStorageLocation: "default"},
+	}
+	sharedInformers.Ark().V1().ResticRepositories().Informer().GetStore().Add(existing)
+
+	require.NoError(t, c.ensureRepositories())
+
+	list, err := client.ArkV1().ResticRepositories(api.DefaultNamespace).List(metav1.ListOptions{})
+	require.NoError(t, err)
+	require.Len(t, list.Items, 1)
+
+	expected := api.ResticRepository{
+		ObjectMeta: metav1.ObjectMeta{Namespace: api.DefaultNamespace, Name: "ns-2-default"},
+		Spec: api.ResticRepositorySpec{
+			VolumeNamespace:       "ns-2",
+			BackupStorageLocation: "default",
+			ResticIdentifier:      "s3:s3.us-west-2.amazonaws.com/bucket/.ark-restic/ns-2",
+			MaintenanceFrequency:  metav1.Duration{Duration: 24 * time.Hour},
+		},
+		Status: api.ResticRepositoryStatus{Phase: api.ResticRepositoryPhaseNew},
+	}
+	assert.Equal(t, expected, list.Items[0])
+}
+
+func TestMaintainResticRepository(t *testing.T) {
+	now := time.Date(2017, 11, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name             string
+		location         string
+		lastMaintenance  time.Time
+		maintainErr      error
+		runningBackup    bool
+		expectMaintained bool
+		expectedStatus   api.ResticRepositoryStatus
+	}{
+		{
+			name:             "new repository is maintained",
+			location:         "default",
+			expectMaintained: true,
+			expectedStatus: api.ResticRepositoryStatus{
+				Phase:               api.ResticRepositoryPhaseReady,
+				LastMaintenanceTime: metav1.NewTime(now),
+				SizeBytes:           4096,
+			},
+		},
+		{
+			name:             "repository whose maintenance is due is maintained",
+			location:         "default",
+			lastMaintenance:  now.Add(-25 * time.Hour),
+			expectMaintained: true,
+			expectedStatus: api.ResticRepositoryStatus{
+				Phase:               api.ResticRepositoryPhaseReady,
+				LastMaintenanceTime: metav1.NewTime(now),
+				SizeBytes:           4096,
+			},
+		},
+		{
+			name:            "repository whose maintenance isn't due is skipped",
+			location:        "default",
+			lastMaintenance: now.Add(-23 * time.Hour),
+			expectedStatus: api.ResticRepositoryStatus{
+				Phase:               api.ResticRepositoryPhaseReady,
+				LastMaintenanceTime: metav1.NewTime(now.Add(-23 * time.Hour)),
+			},
+		},
+		{
+			name:             "failed maintenance makes the repository not ready",
+			location:         "default",
+			maintainErr:      errors.New("restic check failed"),
+			expectMaintained: true,
+			expectedStatus: api.ResticRepositoryStatus{
+				Phase:               api.ResticRepositoryPhaseNotReady,
+				Message:             "restic check failed",
+				LastMaintenanceTime: metav1.NewTime(now),
+			},
+		},
+		{
+			name:          "maintenance is postponed while a backup is running",
+			location:      "default",
+			runningBackup: true,
+			expectedStatus: api.ResticRepositoryStatus{
+				Phase: api.ResticRepositoryPhaseReady,
+			},
+		},
+		{
+			name:     "repository in another location is skipped",
+			location: "secondary",
+			expectedStatus: api.ResticRepositoryStatus{
+				Phase: api.ResticRepositoryPhaseReady,
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			client := fake.NewSimpleClientset()
+			maintainer := &fakeResticMaintainer{size: 4096, err: test.maintainErr}
+			c, sharedInformers := newTestResticRepositoryController(client, &fakeObjectStorage{}, maintainer)
+			c.clock = clock.NewFakeClock(now)
+
+			if test.runningBackup {
+				backup := &api.Backup{
+					ObjectMeta: metav1.ObjectMeta{Namespace: api.DefaultNamespace, Name: "backup-1"},
+					Status:     api.BackupStatus{Phase: api.BackupPhaseInProgress},
+				}
+				sharedInformers.Ark().V1().Backups().Informer().GetStore().Add(backup)
+			}
+
+			repo := &api.ResticRepository{
+				ObjectMeta: metav1.ObjectMeta{Namespace: api.DefaultNamespace, Name: "ns-1-" + test.location},
+				Spec: api.ResticRepositorySpec{
+					VolumeNamespace:       "ns-1",
+					BackupStorageLocation: test.location,
+					ResticIdentifier:      "s3:s3.us-west-2.amazonaws.com/bucket/.ark-restic/ns-1",
+					MaintenanceFrequency:  metav1.Duration{Duration: 24 * time.Hour},
+				},
+				Status: api.ResticRepositoryStatus{Phase: api.ResticRepositoryPhaseReady},
+			}
+			if !test.lastMaintenance.IsZero() {
+				repo.Status.LastMaintenanceTime = metav1.NewTime(test.lastMaintenance)
+			}
+			_, err := client.ArkV1().ResticRepositories(repo.Namespace).Create(repo)
+			require.NoError(t, err)
+
+			require.NoError(t, c.maintainIfDue(repo))
+
+			if test.expectMaintained {
+				assert.Equal(t, []string{repo.Spec.ResticIdentifier}, maintainer.maintained)
+			} else {
+				assert.Empty(t, maintainer.maintained)
+			}
+
+			res, err := client.ArkV1().ResticRepositories(repo.Namespace).Get(repo.Name, metav1.GetOptions{})
+			require.NoError(t, err)
+			assert.Equal(t, test.expectedStatus, res.Status)
+		})
+	}
+}
@@ -17,28 +17,40 @@ limitations under the License.
 package controller
 
 import (
+	"bytes"
+	"compress/gzip"
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"os"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/golang/glog"
 
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/pkg/api/v1"
 	"k8s.io/client-go/tools/cache"
 	"k8s.io/client-go/util/workqueue"
 
 	api "github.com/heptio/ark/pkg/apis/ark/v1"
 	"github.com/heptio/ark/pkg/cloudprovider"
+	"github.com/heptio/ark/pkg/event"
 	"github.com/heptio/ark/pkg/generated/clientset/scheme"
 	arkv1client "github.com/heptio/ark/pkg/generated/clientset/typed/ark/v1"
 	informers "github.com/heptio/ark/pkg/generated/informers/externalversions/ark/v1"
 	listers "github.com/heptio/ark/pkg/generated/listers/ark/v1"
+	"github.com/heptio/ark/pkg/logging"
+	"github.com/heptio/ark/pkg/metrics"
 	"github.com/heptio/ark/pkg/restore"
+	"github.com/heptio/ark/pkg/tracing"
 )
 
 type restoreController struct {
@@ -48,6 +60,10 @@ type restoreController struct {
 	backupService    cloudprovider.BackupService
 	bucket           string
 	pvProviderExists bool
+	tenantMode       bool
+	metrics          *metrics.ServerMetrics
+	recorder         event.Recorder
+	gracePeriod      time.Duration
 
 	backupLister        listers.BackupLister
 	backupListerSynced  cache.InformerSynced
@@ -66,6 +82,10 @@ func NewRestoreController(
 	bucket string,
 	backupInformer informers.BackupInformer,
 	pvProviderExists bool,
+	tenantMode bool,
+	metrics *metrics.ServerMetrics,
+	recorder event.Recorder,
+	terminationGracePeriod time.Duration,
 ) Interface {
 	c := &restoreController{
 		restoreClient:       restoreClient,
@@ -74,6 +94,10 @@ func NewRestoreController(
 		backupService:       backupService,
 		bucket:              bucket,
 		pvProviderExists:    pvProviderExists,
+		tenantMode:          tenantMode,
+		metrics:             metrics,
+		recorder:            recorder,
+		gracePeriod:         terminationGracePeriod,
 		backupLister:        backupInformer.Lister(),
 		backupListerSynced:  backupInformer.Informer().HasSynced,
 		restoreLister:       restoreInformer.Lister(),
@@ -111,7 +135,9 @@ func NewRestoreController(
 
 // Run is a blocking function that runs the specified number of worker goroutines
 // to process items in the work queue. It will return when it receives on the
-// ctx.Done() channel.
+// ctx.Done() channel, once the running restores have finished or the shutdown
+// timeout has passed. Restores that are still running then are left
+// InProgress, and are run again when the server next starts.
 func (controller *restoreController) Run(ctx context.Context, numWorkers int) error {
 	var wg sync.WaitGroup
 
@@ -123,7 +149,11 @@ func (controller *restoreController) Run(ctx context.Context, numWorkers int) er
 		// We have to wait here in the deferred function instead of at the bottom of the function body
 		// because we have to shut down the queue in order for the workers to shut down gracefully, and
 		// we want to shut down the queue via defer and not at the end of the body.
-		wg.Wait()
+		runTimeout, _ := shutdownTimeouts(controller.gracePeriod)
+		if !waitTimeout(&wg, runTimeout) {
+			glog.Infof("Restores are still running after %s; they'll be run again when the server next starts", runTimeout)
+			return
+		}
 
 		glog.Infof("All workers have finished")
 	}()
@@ -137,6 +167,8 @@ func (controller *restoreController) Run(ctx context.Context, numWorkers int) er
 	}
 	glog.Info("Caches are synced")
 
+	controller.retryInterruptedRestores()
+
 	wg.Add(numWorkers)
 	for i := 0; i < numWorkers; i++ {
 		go func() {
@@ -150,6 +182,50 @@ func (controller *restoreController) Run(ctx context.Context, numWorkers int) er
 	return nil
 }
 
+// retryInterruptedRestores resets the restores left InProgress by a previous run of the server to
+// the New phase, and queues them to be run again from the start. The items they'd already
+// restored are handled like any other existing items, according to their existing resource
+// policies.
+func (controller *restoreController) retryInterruptedRestores() {
+	restores, err := controller.restoreLister.List(labels.Everything())
+	if err != nil {
+		glog.Errorf("error listing restores: %v", err)
+		return
+	}
+
+	for _, restore := range restores {
+		if restore.Status.Phase != api.RestorePhaseInProgress {
+			continue
+		}
+
+		glog.Infof("Restore %s/%s was interrupted by a server restart, running it again", restore.Namespace, restore.Name)
+		if err := controller.retryInterruptedRestore(restore); err != nil {
+			glog.Errorf("error retrying interrupted restore %s/%s: %v", restore.Namespace, restore.Name, err)
+		}
+	}
+}
+
+func (controller *restoreController) retryInterruptedRestore(restore *api.Restore) error {
+	restore, err := cloneRestore(restore)
+	if err != nil {
+		return err
+	}
+
+	restore.Status = api.RestoreStatus{Phase: api.RestorePhaseNew}
+	if restore, err = controller.restoreClient.Restores(restore.Namespace).Update(restore); err != nil {
+		return err
+	}
+	controller.recorder.Eventf(restore, v1.EventTypeNormal, event.ReasonRestoreInterrupted, "Restore was interrupted by a server restart, and will be run again")
+
+	key, err := cache.MetaNamespaceKeyFunc(restore)
+	if err != nil {
+		return err
+	}
+	controller.queue.Add(key)
+
+	return nil
+}
+
 func (controller *restoreController) runWorker() {
 	// continually take items off the queue (waits if it's
 	// empty) until we get a shutdown signal from the queue
@@ -189,11 +265,12 @@ func (controller *restoreController) processRestore(key string) error {
 		glog.V(4).Infof("error splitting key %q: %v", key, err)
 		return err
 	}
+	log := logging.WithFields(logging.Fields{"restore": name, "namespace": ns})
 
-	glog.V(4).Infof("Getting restore %s", key)
+	log.V(4).Infof("Getting restore %s", key)
 	restore, err := controller.restoreLister.Restores(ns).Get(name)
 	if err != nil {
-		glog.V(4).Infof("error getting restore %s: %v", key, err)
+		log.V(4).Infof("error getting restore %s: %v", key, err)
 		return err
 	}
 
@@ -211,52 +288,120 @@ func (controller *restoreController) processRestore(key string) error {
 		return nil
 	}
 
-	glog.V(4).Infof("Cloning restore %s", key)
+	log.V(4).Infof("Cloning restore %s", key)
 	// don't modify items in the cache
 	restore, err = cloneRestore(restore)
 	if err != nil {
-		glog.V(4).Infof("error cloning restore %s: %v", key, err)
+		log.V(4).Infof("error cloning restore %s: %v", key, err)
 		return err
 	}
 
+	spanCtx, span := tracing.Start(context.Background(), "restore")
+	span.SetAttribute("ark.restore", key)
+	span.SetAttribute("ark.backup", restore.Spec.BackupName)
+	defer func() {
+		span.SetAttribute("ark.phase", string(restore.Status.Phase))
+		span.End()
+	}()
+
 	// validation
+	_, validateSpan := tracing.Start(spanCtx, "validate")
 	if restore.Status.ValidationErrors = controller.getValidationErrors(restore); len(restore.Status.ValidationErrors) > 0 {
 		restore.Status.Phase = api.RestorePhaseFailedValidation
 	} else {
 		restore.Status.Phase = api.RestorePhaseInProgress
 	}
+	validateSpan.End()
 
 	if len(restore.Spec.Namespaces) == 0 {
 		restore.Spec.Namespaces = []string{"*"}
 	}
 
+	// restores in tenant namespaces only include their own namespace
+	if isTenantNamespace(controller.tenantMode, restore.Namespace) {
+		restore.Spec.Namespaces = []string{restore.Namespace}
+	}
+
+	// record the cluster the restore's backup was taken in, and find the bucket it's stored in
+	bucket := controller.bucket
+	var schedule, location string
+	if backup, err := controller.getBackup(restore); err == nil {
+		setClusterLabels(&restore.ObjectMeta, backup.Labels[api.ClusterNameLabel], backup.Labels[api.ClusterUIDLabel])
+		bucket = backupBucket(backup, controller.bucket)
+		schedule, location = metricLabels(backup)
+	}
+
 	// update status
 	updatedRestore, err := controller.restoreClient.Restores(ns).Update(restore)
 	if err != nil {
-		glog.V(4).Infof("error updating status to %s: %v", restore.Status.Phase, err)
+		log.V(4).Infof("error updating status to %s: %v", restore.Status.Phase, err)
 		return err
 	}
 	restore = updatedRestore
 
 	if restore.Status.Phase == api.RestorePhaseFailedValidation {
+		controller.metrics.RegisterRestore(schedule, location, string(restore.Status.Phase))
+		controller.recorder.Eventf(restore, v1.EventTypeWarning, event.ReasonRestoreFailedValidation, "Restore failed validation: %s", strings.Join(restore.Status.ValidationErrors, "; "))
 		return nil
 	}
 
-	glog.V(4).Infof("running restore for %s", key)
+	controller.recorder.Eventf(restore, v1.EventTypeNormal, event.ReasonRestoreStarted, "Started restore from backup %s", restore.Spec.BackupName)
+
+	log.V(4).Infof("running restore for %s", key)
 	// execution & upload of restore
-	restore.Status.Warnings, restore.Status.Errors = controller.runRestore(restore, controller.bucket)
+	warnings, errors, failed := controller.runRestore(spanCtx, restore, bucket)
+	restore.Status.WarningCounts, restore.Status.ErrorCounts = countResults(warnings), countResults(errors)
+
+	// the results can be too large to keep in the restore, so they're stored alongside its
+	// backup, unless that isn't possible
+	_, uploadSpan := tracing.Start(spanCtx, "upload results")
+	err = controller.uploadResults(restore, warnings, errors, bucket)
+	uploadSpan.RecordError(err)
+	uploadSpan.End()
+	if err != nil {
+		log.Errorf("error uploading results of restore %s: %v", key, err)
+		restore.Status.Warnings, restore.Status.Errors = warnings, errors
+	}
 
-	glog.V(4).Infof("restore %s completed", key)
-	restore.Status.Phase = api.RestorePhaseCompleted
+	switch {
+	case failed:
+		log.V(4).Infof("restore %s failed", key)
+		restore.Status.Phase = api.RestorePhaseFailed
+	case restore.Status.ErrorCounts.Total() > 0:
+		log.V(4).Infof("restore %s partially failed", key)
+		restore.Status.Phase = api.RestorePhasePartiallyFailed
+	default:
+		log.V(4).Infof("restore %s completed", key)
+		restore.Status.Phase = api.RestorePhaseCompleted
+	}
+	controller.metrics.RegisterRestore(schedule, location, string(restore.Status.Phase))
+	controller.recordCompletionEvent(restore)
 
-	glog.V(4).Infof("updating restore %s final status", key)
+	log.V(4).Infof("updating restore %s final status", key)
 	if _, err = controller.restoreClient.Restores(ns).Update(restore); err != nil {
-		glog.V(4).Infof("error updating restore %s final status: %v", key, err)
+		log.V(4).Infof("error updating restore %s final status: %v", key, err)
 	}
 
 	return nil
 }
 
+// restoreLogger returns a logger whose lines identify restore.
+func restoreLogger(restore *api.Restore) logging.Logger {
+	return logging.WithFields(logging.Fields{"restore": restore.Name, "namespace": restore.Namespace})
+}
+
+// recordCompletionEvent records an event for the phase a restore finished in.
+func (controller *restoreController) recordCompletionEvent(restore *api.Restore) {
+	switch restore.Status.Phase {
+	case api.RestorePhaseFailed:
+		controller.recorder.Eventf(restore, v1.EventTypeWarning, event.ReasonRestoreFailed, "Restore failed; run 'ark restore logs %s' for details", restore.Name)
+	case api.RestorePhasePartiallyFailed:
+		controller.recorder.Eventf(restore, v1.EventTypeWarning, event.ReasonRestorePartiallyFailed, "Restore completed with %d error(s) and %d warning(s); run 'ark restore describe %s' for details", restore.Status.ErrorCounts.Total(), restore.Status.WarningCounts.Total(), restore.Name)
+	default:
+		controller.recorder.Eventf(restore, v1.EventTypeNormal, event.ReasonRestoreCompleted, "Restore completed with %d warning(s)", restore.Status.WarningCounts.Total())
+	}
+}
+
 func cloneRestore(in interface{}) (*api.Restore, error) {
 	clone, err := scheme.Scheme.DeepCopy(in)
 	if err != nil {
@@ -278,39 +423,279 @@ func (controller *restoreController) getValidationErrors(itm *api.Restore) []str
 		validationErrors = append(validationErrors, "BackupName must be non-empty and correspond to the name of a backup in object storage.")
 	}
 
+	if isTenantNamespace(controller.tenantMode, itm.Namespace) {
+		validationErrors = append(validationErrors, getTenantValidationErrors(itm.Namespace, itm.Spec.Namespaces, itm.Spec.IncludeClusterResources)...)
+
+		for source, target := range itm.Spec.NamespaceMapping {
+			if target != itm.Namespace {
+				validationErrors = append(validationErrors, fmt.Sprintf("Namespace %s can't be mapped to %s; restores in namespace %s can only restore into it", source, target, itm.Namespace))
+			}
+		}
+	}
+
 	if !controller.pvProviderExists && itm.Spec.RestorePVs != nil && *itm.Spec.RestorePVs {
 		validationErrors = append(validationErrors, "Server is not configured for PV snapshot restores")
 	}
 
+	switch itm.Spec.UnsnapshottedVolumePolicy {
+	case "", api.UnsnapshottedVolumePolicyRetain, api.UnsnapshottedVolumePolicyProvision:
+	default:
+		validationErrors = append(validationErrors, fmt.Sprintf("Invalid unsnapshotted volume policy %q", itm.Spec.UnsnapshottedVolumePolicy))
+	}
+
+	// pruning deletes whatever isn't in the backup, so it's only safe against backups that hold
+	// everything they were meant to.
+	if itm.Spec.Prune {
+		if itm.Spec.DataOnly {
+			validationErrors = append(validationErrors, "Prune can't be used with a data-only restore")
+		} else if backup, err := controller.getBackup(itm); err != nil {
+			validationErrors = append(validationErrors, fmt.Sprintf("Error getting backup %s to check that it can be pruned against: %v", itm.Spec.BackupName, err))
+		} else if backup.Status.Phase != api.BackupPhaseCompleted {
+			validationErrors = append(validationErrors, fmt.Sprintf("Backup %s has phase %s; restores can only prune against %s backups", backup.Name, backup.Status.Phase, api.BackupPhaseCompleted))
+		}
+	}
+
+	validationErrors = append(validationErrors, restore.ValidateHooks(itm.Spec.Hooks)...)
+	validationErrors = append(validationErrors, restore.ValidateExistingResourcePolicies(itm.Spec)...)
+
 	return validationErrors
 }
 
-func (controller *restoreController) runRestore(restore *api.Restore, bucket string) (warnings, errors api.RestoreResult) {
-	backup, err := controller.backupLister.Backups(api.DefaultNamespace).Get(restore.Spec.BackupName)
+// getBackup returns restore's backup, which is in the server's namespace. In tenant mode,
+// restores in tenant namespaces can only use backups in their own namespace, and restores in the
+// server's namespace can use backups in any namespace, since backup names are unique across them.
+func (controller *restoreController) getBackup(restore *api.Restore) (*api.Backup, error) {
+	switch {
+	case !controller.tenantMode:
+		return controller.backupLister.Backups(api.DefaultNamespace).Get(restore.Spec.BackupName)
+	case isTenantNamespace(controller.tenantMode, restore.Namespace):
+		return controller.backupLister.Backups(restore.Namespace).Get(restore.Spec.BackupName)
+	}
+
+	backups, err := controller.backupLister.List(labels.Everything())
 	if err != nil {
-		glog.Errorf("error getting backup: %v", err)
-		errors.Cluster = append(errors.Ark, err.Error())
+		return nil, err
+	}
+	for _, backup := range backups {
+		if backup.Name == restore.Spec.BackupName {
+			return backup, nil
+		}
+	}
+	return nil, apierrors.NewNotFound(api.Resource("backups"), restore.Spec.BackupName)
+}
+
+// runRestore downloads restore's backup and its parents and restores or previews them. failed is
+// true if the backup couldn't be retrieved, meaning nothing was restored.
+func (controller *restoreController) runRestore(ctx context.Context, restore *api.Restore, bucket string) (warnings, errors api.RestoreResult, failed bool) {
+	log := restoreLogger(restore)
+
+	backup, err := controller.getBackup(restore)
+	if err != nil {
+		log.Errorf("error getting backup: %v", err)
+		errors.Cluster = append(errors.Cluster, err.Error())
+		failed = true
 		return
 	}
 
+	_, span := tracing.Start(ctx, "download backup")
 	tmpFile, err := downloadToTempFile(restore.Spec.BackupName, controller.backupService, bucket)
+	span.RecordError(err)
+	span.End()
 	if err != nil {
-		glog.Errorf("error downloading backup: %v", err)
-		errors.Cluster = append(errors.Ark, err.Error())
+		log.Errorf("error downloading backup: %v", err)
+		errors.Cluster = append(errors.Cluster, err.Error())
+		failed = true
 		return
 	}
 
 	defer func() {
 		if err := tmpFile.Close(); err != nil {
-			errors.Cluster = append(errors.Ark, err.Error())
+			errors.Cluster = append(errors.Cluster, err.Error())
 		}
 
 		if err := os.Remove(tmpFile.Name()); err != nil {
-			errors.Cluster = append(errors.Ark, err.Error())
+			errors.Cluster = append(errors.Cluster, err.Error())
 		}
 	}()
 
-	return controller.restorer.Restore(restore, backup, tmpFile)
+	_, span = tracing.Start(ctx, "download parent backups")
+	parentFiles, err := controller.downloadParentBackups(backup, bucket)
+	span.SetAttribute("ark.parents", len(parentFiles))
+	span.RecordError(err)
+	span.End()
+	defer func() {
+		for _, file := range parentFiles {
+			if err := file.Close(); err != nil {
+				errors.Cluster = append(errors.Cluster, err.Error())
+			}
+
+			if err := os.Remove(file.Name()); err != nil {
+				errors.Cluster = append(errors.Cluster, err.Error())
+			}
+		}
+	}()
+	if err != nil {
+		log.Errorf("error downloading parent backups: %v", err)
+		errors.Cluster = append(errors.Cluster, err.Error())
+		failed = true
+		return
+	}
+
+	parentReaders := make([]io.Reader, 0, len(parentFiles))
+	for _, file := range parentFiles {
+		parentReaders = append(parentReaders, file)
+	}
+
+	if !restore.Spec.Preview {
+		warnings, errors = controller.restoreWithLog(ctx, restore, backup, tmpFile, parentReaders, bucket)
+		return
+	}
+
+	_, span = tracing.Start(ctx, "preview")
+	plan, warnings, errors := controller.restorer.Preview(restore, backup, tmpFile, parentReaders)
+	span.End()
+
+	_, span = tracing.Start(ctx, "upload plan")
+	err = controller.uploadPlan(restore, plan, bucket)
+	span.RecordError(err)
+	span.End()
+	if err != nil {
+		log.Errorf("error uploading restore plan: %v", err)
+		errors.Ark = append(errors.Ark, err.Error())
+	}
+
+	return warnings, errors, false
+}
+
+// restoreWithLog runs restore, writing its log to a gzip-compressed temp file, which is then stored
+// alongside its backup. Failures to store the log are logged but otherwise ignored.
+func (controller *restoreController) restoreWithLog(ctx context.Context, restore *api.Restore, backup *api.Backup, backupReader io.Reader, parentReaders []io.Reader, bucket string) (api.RestoreResult, api.RestoreResult) {
+	log := restoreLogger(restore)
+
+	logFile, err := ioutil.TempFile("", "")
+	if err != nil {
+		log.Errorf("error creating log file for restore %s/%s: %v", restore.Namespace, restore.Name, err)
+		return controller.restorer.Restore(restore, backup, backupReader, parentReaders, nil)
+	}
+	defer func() {
+		if closeErr := logFile.Close(); closeErr != nil {
+			log.Errorf("error closing log file %s: %v", logFile.Name(), closeErr)
+		}
+		if removeErr := os.Remove(logFile.Name()); removeErr != nil {
+			log.Errorf("error removing log file %s: %v", logFile.Name(), removeErr)
+		}
+	}()
+	logGzip := gzip.NewWriter(logFile)
+
+	_, span := tracing.Start(ctx, "restore items")
+	warnings, errors := controller.restorer.Restore(restore, backup, backupReader, parentReaders, logGzip)
+	span.SetAttribute("ark.errors", countResults(errors).Total())
+	span.End()
+
+	if err := logGzip.Close(); err != nil {
+		log.Errorf("error closing log of restore %s/%s: %v", restore.Namespace, restore.Name, err)
+		return warnings, errors
+	}
+	if _, err := logFile.Seek(0, 0); err != nil {
+		log.Errorf("error reading log of restore %s/%s: %v", restore.Namespace, restore.Name, err)
+		return warnings, errors
+	}
+	_, span = tracing.Start(ctx, "upload log")
+	err = controller.backupService.UploadRestoreLog(bucket, backup.Name, restore.Name, logFile)
+	span.RecordError(err)
+	span.End()
+	if err != nil {
+		log.Errorf("error uploading log of restore %s/%s: %v", restore.Namespace, restore.Name, err)
+	}
+
+	return warnings, errors
+}
+
+// uploadPlan stores the plan of a previewed restore, gzip-compressed, alongside its backup.
+func (controller *restoreController) uploadPlan(itm *api.Restore, plan *restore.Plan, bucket string) error {
+	data, err := gzipJSON(plan)
+	if err != nil {
+		return err
+	}
+
+	return controller.backupService.UploadRestorePlan(bucket, itm.Spec.BackupName, itm.Name, data)
+}
+
+// uploadResults stores the warnings and errors of a restore, gzip-compressed, alongside its backup.
+func (controller *restoreController) uploadResults(itm *api.Restore, warnings, errors api.RestoreResult, bucket string) error {
+	if _, err := controller.getBackup(itm); err != nil {
+		return fmt.Errorf("error getting backup: %v", err)
+	}
+
+	data, err := gzipJSON(map[string]api.RestoreResult{
+		"warnings": warnings,
+		"errors":   errors,
+	})
+	if err != nil {
+		return err
+	}
+
+	return controller.backupService.UploadRestoreResults(bucket, itm.Spec.BackupName, itm.Name, data)
+}
+
+func gzipJSON(obj interface{}) (io.ReadSeeker, error) {
+	var buf bytes.Buffer
+	gzw := gzip.NewWriter(&buf)
+	if err := json.NewEncoder(gzw).Encode(obj); err != nil {
+		return nil, err
+	}
+	if err := gzw.Close(); err != nil {
+		return nil, err
+	}
+
+	return bytes.NewReader(buf.Bytes()), nil
+}
+
+// countResults returns the number of messages in each part of result.
+func countResults(result api.RestoreResult) api.RestoreResultCounts {
+	counts := api.RestoreResultCounts{
+		Ark:     len(result.Ark),
+		Cluster: len(result.Cluster),
+	}
+
+	for ns, messages := range result.Namespaces {
+		if counts.Namespaces == nil {
+			counts.Namespaces = make(map[string]int)
+		}
+		counts.Namespaces[ns] = len(messages)
+	}
+
+	return counts
+}
+
+// downloadParentBackups downloads each of the ancestors of an incremental backup to a temp file,
+// returning them ordered from the original full backup to the backup's immediate parent. Any
+// files that were downloaded are returned even if an error occurs, so they can be cleaned up.
+func (controller *restoreController) downloadParentBackups(backup *api.Backup, bucket string) ([]*os.File, error) {
+	var files []*os.File
+
+	seen := sets.NewString(backup.Name)
+	for name := backup.Spec.ParentBackup; name != ""; {
+		if seen.Has(name) {
+			return files, fmt.Errorf("backup %s has a cycle in its parent backups", backup.Name)
+		}
+		seen.Insert(name)
+
+		parent, err := controller.backupLister.Backups(backup.Namespace).Get(name)
+		if err != nil {
+			return files, fmt.Errorf("error getting parent backup %s: %v", name, err)
+		}
+
+		file, err := downloadToTempFile(name, controller.backupService, bucket)
+		if err != nil {
+			return files, fmt.Errorf("error downloading parent backup %s: %v", name, err)
+		}
+		files = append([]*os.File{file}, files...)
+
+		name = parent.Spec.ParentBackup
+	}
+
+	return files, nil
 }
 
 func downloadToTempFile(backupName string, backupService cloudprovider.BackupService, bucket string) (*os.File, error) {
@@ -17,13 +17,20 @@ limitations under the License.
 package controller
 
 import (
+	"compress/gzip"
+	"encoding/json"
 	"errors"
 	"io"
+	"io/ioutil"
+	"os"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/kubernetes/scheme"
 	core "k8s.io/client-go/testing"
@@ -32,9 +39,56 @@ import (
 	api "github.com/heptio/ark/pkg/apis/ark/v1"
 	"github.com/heptio/ark/pkg/generated/clientset/fake"
 	informers "github.com/heptio/ark/pkg/generated/informers/externalversions"
+	"github.com/heptio/ark/pkg/metrics"
+	"github.com/heptio/ark/pkg/restore"
 	. "github.com/heptio/ark/pkg/util/test"
 )
 
+func TestRetryInterruptedRestores(t *testing.T) {
+	interrupted := NewTestRestore(api.DefaultNamespace, "interrupted", api.RestorePhaseInProgress).WithBackup("backup-1").Restore
+	interrupted.Status.Warnings = api.RestoreResult{Cluster: []string{"warning"}}
+	completed := NewTestRestore(api.DefaultNamespace, "completed", api.RestorePhaseCompleted).WithBackup("backup-1").Restore
+
+	var (
+		client          = fake.NewSimpleClientset(interrupted, completed)
+		sharedInformers = informers.NewSharedInformerFactory(client, 0)
+		recorder        = &FakeEventRecorder{}
+	)
+
+	c := NewRestoreController(
+		sharedInformers.Ark().V1().Restores(),
+		client.ArkV1(),
+		client.ArkV1(),
+		&fakeRestorer{},
+		&fakeBackupService{},
+		"bucket",
+		sharedInformers.Ark().V1().Backups(),
+		false,
+		false,
+		metrics.NewServerMetrics(),
+		recorder,
+		DefaultTerminationGracePeriod,
+	).(*restoreController)
+
+	sharedInformers.Ark().V1().Restores().Informer().GetStore().Add(interrupted)
+	sharedInformers.Ark().V1().Restores().Informer().GetStore().Add(completed)
+
+	c.retryInterruptedRestores()
+
+	updated, err := client.ArkV1().Restores(api.DefaultNamespace).Get("interrupted", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, api.RestoreStatus{Phase: api.RestorePhaseNew}, updated.Status)
+	assert.Equal(t, []string{"Normal RestoreInterrupted Restore was interrupted by a server restart, and will be run again"}, recorder.Events)
+
+	updated, err = client.ArkV1().Restores(api.DefaultNamespace).Get("completed", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, api.RestorePhaseCompleted, updated.Status.Phase)
+
+	assert.Equal(t, 1, c.queue.Len())
+	key, _ := c.queue.Get()
+	assert.Equal(t, api.DefaultNamespace+"/interrupted", key)
+}
+
 func TestProcessRestore(t *testing.T) {
 	tests := []struct {
 		name                   string
@@ -42,10 +96,12 @@ func TestProcessRestore(t *testing.T) {
 		restore                *api.Restore
 		backup                 *api.Backup
 		restorerError          error
+		uploadResultsError     error
 		allowRestoreSnapshots  bool
 		expectedErr            bool
 		expectedRestoreUpdates []*api.Restore
 		expectedRestorerCall   *api.Restore
+		expectedEvents         []string
 	}{
 		{
 			name:        "invalid key returns error",
@@ -81,6 +137,7 @@ func TestProcessRestore(t *testing.T) {
 					WithRestorableNamespace("ns-1").
 					WithValidationError("BackupName must be non-empty and correspond to the name of a backup in object storage.").Restore,
 			},
+			expectedEvents: []string{"Warning FailedValidation Restore failed validation: BackupName must be non-empty and correspond to the name of a backup in object storage."},
 		},
 		{
 			name:        "restore with non-existent backup name fails",
@@ -88,33 +145,62 @@ func TestProcessRestore(t *testing.T) {
 			expectedErr: false,
 			expectedRestoreUpdates: []*api.Restore{
 				NewTestRestore("foo", "bar", api.RestorePhaseInProgress).WithBackup("backup-1").WithRestorableNamespace("ns-1").Restore,
-				NewTestRestore("foo", "bar", api.RestorePhaseCompleted).
+				NewTestRestore("foo", "bar", api.RestorePhaseFailed).
 					WithBackup("backup-1").
 					WithRestorableNamespace("ns-1").
 					WithErrors(api.RestoreResult{
 						Cluster: []string{"backup.ark.heptio.com \"backup-1\" not found"},
 					}).
+					WithErrorCounts(api.RestoreResultCounts{Cluster: 1}).
 					Restore,
 			},
+			expectedEvents: []string{"Normal RestoreStarted Started restore from backup backup-1", "Warning RestoreFailed Restore failed; run 'ark restore logs bar' for details"},
 		},
 		{
-			name:          "restorer throwing an error causes the restore to fail",
+			name:          "restorer throwing an error causes the restore to partially fail",
 			restore:       NewTestRestore("foo", "bar", api.RestorePhaseNew).WithBackup("backup-1").WithRestorableNamespace("ns-1").Restore,
 			backup:        NewTestBackup().WithName("backup-1").Backup,
 			restorerError: errors.New("blarg"),
 			expectedErr:   false,
 			expectedRestoreUpdates: []*api.Restore{
 				NewTestRestore("foo", "bar", api.RestorePhaseInProgress).WithBackup("backup-1").WithRestorableNamespace("ns-1").Restore,
-				NewTestRestore("foo", "bar", api.RestorePhaseCompleted).
+				NewTestRestore("foo", "bar", api.RestorePhasePartiallyFailed).
+					WithBackup("backup-1").
+					WithRestorableNamespace("ns-1").
+					WithErrorCounts(api.RestoreResultCounts{
+						Namespaces: map[string]int{
+							"ns-1": 1,
+						},
+					}).Restore,
+			},
+			expectedRestorerCall: NewTestRestore("foo", "bar", api.RestorePhaseInProgress).WithBackup("backup-1").WithRestorableNamespace("ns-1").Restore,
+			expectedEvents:       []string{"Normal RestoreStarted Started restore from backup backup-1", "Warning RestorePartiallyFailed Restore completed with 1 error(s) and 0 warning(s); run 'ark restore describe bar' for details"},
+		},
+		{
+			name:               "results are kept in the restore if they can't be stored alongside its backup",
+			restore:            NewTestRestore("foo", "bar", api.RestorePhaseNew).WithBackup("backup-1").WithRestorableNamespace("ns-1").Restore,
+			backup:             NewTestBackup().WithName("backup-1").Backup,
+			restorerError:      errors.New("blarg"),
+			uploadResultsError: errors.New("upload failed"),
+			expectedErr:        false,
+			expectedRestoreUpdates: []*api.Restore{
+				NewTestRestore("foo", "bar", api.RestorePhaseInProgress).WithBackup("backup-1").WithRestorableNamespace("ns-1").Restore,
+				NewTestRestore("foo", "bar", api.RestorePhasePartiallyFailed).
 					WithBackup("backup-1").
 					WithRestorableNamespace("ns-1").
 					WithErrors(api.RestoreResult{
 						Namespaces: map[string][]string{
 							"ns-1": {"blarg"},
 						},
+					}).
+					WithErrorCounts(api.RestoreResultCounts{
+						Namespaces: map[string]int{
+							"ns-1": 1,
+						},
 					}).Restore,
 			},
 			expectedRestorerCall: NewTestRestore("foo", "bar", api.RestorePhaseInProgress).WithBackup("backup-1").WithRestorableNamespace("ns-1").Restore,
+			expectedEvents:       []string{"Normal RestoreStarted Started restore from backup backup-1", "Warning RestorePartiallyFailed Restore completed with 1 error(s) and 0 warning(s); run 'ark restore describe bar' for details"},
 		},
 		{
 			name:        "valid restore gets executed",
@@ -126,6 +212,24 @@ func TestProcessRestore(t *testing.T) {
 				NewTestRestore("foo", "bar", api.RestorePhaseCompleted).WithBackup("backup-1").WithRestorableNamespace("ns-1").Restore,
 			},
 			expectedRestorerCall: NewTestRestore("foo", "bar", api.RestorePhaseInProgress).WithBackup("backup-1").WithRestorableNamespace("ns-1").Restore,
+			expectedEvents:       []string{"Normal RestoreStarted Started restore from backup backup-1", "Normal RestoreCompleted Restore completed with 0 warning(s)"},
+		},
+		{
+			name:    "restore records the cluster its backup was taken in",
+			restore: NewTestRestore("foo", "bar", api.RestorePhaseNew).WithBackup("backup-1").WithRestorableNamespace("ns-1").Restore,
+			backup: NewTestBackup().WithName("backup-1").
+				WithLabel(api.ClusterNameLabel, "cluster-1").
+				WithLabel(api.ClusterUIDLabel, "uid-1").
+				Backup,
+			expectedErr: false,
+			expectedRestoreUpdates: []*api.Restore{
+				NewTestRestore("foo", "bar", api.RestorePhaseInProgress).WithBackup("backup-1").WithRestorableNamespace("ns-1").
+					WithLabel(api.ClusterNameLabel, "cluster-1").WithLabel(api.ClusterUIDLabel, "uid-1").Restore,
+				NewTestRestore("foo", "bar", api.RestorePhaseCompleted).WithBackup("backup-1").WithRestorableNamespace("ns-1").
+					WithLabel(api.ClusterNameLabel, "cluster-1").WithLabel(api.ClusterUIDLabel, "uid-1").Restore,
+			},
+			expectedRestorerCall: NewTestRestore("foo", "bar", api.RestorePhaseInProgress).WithBackup("backup-1").WithRestorableNamespace("ns-1").
+				WithLabel(api.ClusterNameLabel, "cluster-1").WithLabel(api.ClusterUIDLabel, "uid-1").Restore,
 		},
 		{
 			name:        "restore with no restorable namespaces gets defaulted to *",
@@ -138,6 +242,17 @@ func TestProcessRestore(t *testing.T) {
 			},
 			expectedRestorerCall: NewTestRestore("foo", "bar", api.RestorePhaseInProgress).WithBackup("backup-1").WithRestorableNamespace("*").Restore,
 		},
+		{
+			name:        "preview restore gets previewed and its plan uploaded",
+			restore:     NewTestRestore("foo", "bar", api.RestorePhaseNew).WithBackup("backup-1").WithRestorableNamespace("ns-1").WithPreview(true).Restore,
+			backup:      NewTestBackup().WithName("backup-1").Backup,
+			expectedErr: false,
+			expectedRestoreUpdates: []*api.Restore{
+				NewTestRestore("foo", "bar", api.RestorePhaseInProgress).WithBackup("backup-1").WithRestorableNamespace("ns-1").WithPreview(true).Restore,
+				NewTestRestore("foo", "bar", api.RestorePhaseCompleted).WithBackup("backup-1").WithRestorableNamespace("ns-1").WithPreview(true).Restore,
+			},
+			expectedRestorerCall: NewTestRestore("foo", "bar", api.RestorePhaseInProgress).WithBackup("backup-1").WithRestorableNamespace("ns-1").WithPreview(true).Restore,
+		},
 		{
 			name:                  "valid restore with RestorePVs=true gets executed when allowRestoreSnapshots=true",
 			restore:               NewTestRestore("foo", "bar", api.RestorePhaseNew).WithBackup("backup-1").WithRestorableNamespace("ns-1").WithRestorePVs(true).Restore,
@@ -150,6 +265,16 @@ func TestProcessRestore(t *testing.T) {
 			},
 			expectedRestorerCall: NewTestRestore("foo", "bar", api.RestorePhaseInProgress).WithBackup("backup-1").WithRestorableNamespace("ns-1").WithRestorePVs(true).Restore,
 		},
+		{
+			name:        "restore with invalid unsnapshotted volume policy fails validation",
+			restore:     NewTestRestore("foo", "bar", api.RestorePhaseNew).WithBackup("backup-1").WithRestorableNamespace("ns-1").WithUnsnapshottedVolumePolicy("Delete").Restore,
+			backup:      NewTestBackup().WithName("backup-1").Backup,
+			expectedErr: false,
+			expectedRestoreUpdates: []*api.Restore{
+				NewTestRestore("foo", "bar", api.RestorePhaseFailedValidation).WithBackup("backup-1").WithRestorableNamespace("ns-1").WithUnsnapshottedVolumePolicy("Delete").
+					WithValidationError(`Invalid unsnapshotted volume policy "Delete"`).Restore,
+			},
+		},
 		{
 			name:        "restore with RestorePVs=true fails validation when allowRestoreSnapshots=false",
 			restore:     NewTestRestore("foo", "bar", api.RestorePhaseNew).WithBackup("backup-1").WithRestorableNamespace("ns-1").WithRestorePVs(true).Restore,
@@ -160,6 +285,26 @@ func TestProcessRestore(t *testing.T) {
 					WithValidationError("Server is not configured for PV snapshot restores").Restore,
 			},
 		},
+		{
+			name:        "data-only restore with Prune=true fails validation",
+			restore:     NewTestRestore("foo", "bar", api.RestorePhaseNew).WithBackup("backup-1").WithRestorableNamespace("ns-1").WithDataOnly(true).WithPrune(true).Restore,
+			backup:      NewTestBackup().WithName("backup-1").Backup,
+			expectedErr: false,
+			expectedRestoreUpdates: []*api.Restore{
+				NewTestRestore("foo", "bar", api.RestorePhaseFailedValidation).WithBackup("backup-1").WithRestorableNamespace("ns-1").WithDataOnly(true).WithPrune(true).
+					WithValidationError("Prune can't be used with a data-only restore").Restore,
+			},
+		},
+		{
+			name:        "restore with Prune=true fails validation when its backup isn't Completed",
+			restore:     NewTestRestore("foo", "bar", api.RestorePhaseNew).WithBackup("backup-1").WithRestorableNamespace("ns-1").WithPrune(true).Restore,
+			backup:      NewTestBackup().WithName("backup-1").WithPhase(api.BackupPhasePartiallyFailed).Backup,
+			expectedErr: false,
+			expectedRestoreUpdates: []*api.Restore{
+				NewTestRestore("foo", "bar", api.RestorePhaseFailedValidation).WithBackup("backup-1").WithRestorableNamespace("ns-1").WithPrune(true).
+					WithValidationError("Backup backup-1 has phase PartiallyFailed; restores can only prune against Completed backups").Restore,
+			},
+		},
 	}
 
 	// flag.Set("logtostderr", "true")
@@ -173,6 +318,7 @@ func TestProcessRestore(t *testing.T) {
 				restorer        = &fakeRestorer{}
 				sharedInformers = informers.NewSharedInformerFactory(client, 0)
 				backupSvc       = &fakeBackupService{}
+				recorder        = &FakeEventRecorder{}
 			)
 
 			c := NewRestoreController(
@@ -184,6 +330,10 @@ func TestProcessRestore(t *testing.T) {
 				"bucket",
 				sharedInformers.Ark().V1().Backups(),
 				test.allowRestoreSnapshots,
+				false,
+				metrics.NewServerMetrics(),
+				recorder,
+				DefaultTerminationGracePeriod,
 			).(*restoreController)
 
 			if test.restore != nil {
@@ -210,7 +360,19 @@ func TestProcessRestore(t *testing.T) {
 			if test.restorerError != nil {
 				errors.Namespaces = map[string][]string{"ns-1": {test.restorerError.Error()}}
 			}
-			restorer.On("Restore", mock.Anything, mock.Anything, mock.Anything).Return(warnings, errors)
+			restorer.On("Restore", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(warnings, errors)
+			restorer.On("Preview", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(&restore.Plan{}, warnings, errors)
+
+			if test.restore != nil && test.restore.Spec.Preview {
+				backupSvc.On("UploadRestorePlan", "bucket", test.restore.Spec.BackupName, test.restore.Name, mock.Anything).Return(nil)
+			}
+			if test.backup != nil && test.expectedRestorerCall != nil {
+				backupSvc.On("UploadRestoreResults", "bucket", test.backup.Name, test.restore.Name, mock.Anything).Return(test.uploadResultsError)
+				if !test.restore.Spec.Preview {
+					backupSvc.On("UploadRestoreLog", "bucket", test.backup.Name, test.restore.Name, mock.Anything).Return(nil)
+				}
+			}
+			defer backupSvc.AssertExpectations(t)
 
 			var (
 				key = test.restoreKey
@@ -242,6 +404,10 @@ func TestProcessRestore(t *testing.T) {
 				assert.Equal(t, expectedActions, client.Actions())
 			}
 
+			if test.expectedEvents != nil {
+				assert.Equal(t, test.expectedEvents, recorder.Events)
+			}
+
 			if test.expectedRestorerCall == nil {
 				assert.Empty(t, restorer.Calls)
 				assert.Zero(t, restorer.calledWithArg)
@@ -262,10 +428,225 @@ type fakeRestorer struct {
 	calledWithArg api.Restore
 }
 
-func (r *fakeRestorer) Restore(restore *api.Restore, backup *api.Backup, backupReader io.Reader) (api.RestoreResult, api.RestoreResult) {
-	res := r.Called(restore, backup, backupReader)
+func (r *fakeRestorer) Preview(itm *api.Restore, backup *api.Backup, backupReader io.Reader, parentReaders []io.Reader) (*restore.Plan, api.RestoreResult, api.RestoreResult) {
+	res := r.Called(itm, backup, backupReader, parentReaders)
+
+	r.calledWithArg = *itm
+
+	return res.Get(0).(*restore.Plan), res.Get(1).(api.RestoreResult), res.Get(2).(api.RestoreResult)
+}
+
+func (r *fakeRestorer) Restore(restore *api.Restore, backup *api.Backup, backupReader io.Reader, parentReaders []io.Reader, log io.Writer) (api.RestoreResult, api.RestoreResult) {
+	res := r.Called(restore, backup, backupReader, parentReaders, log)
 
 	r.calledWithArg = *restore
 
 	return res.Get(0).(api.RestoreResult), res.Get(1).(api.RestoreResult)
 }
+
+func TestRestoresInTenantMode(t *testing.T) {
+	var (
+		client          = fake.NewSimpleClientset()
+		sharedInformers = informers.NewSharedInformerFactory(client, 0)
+	)
+
+	c := NewRestoreController(
+		sharedInformers.Ark().V1().Restores(),
+		client.ArkV1(),
+		client.ArkV1(),
+		&fakeRestorer{},
+		&fakeBackupService{},
+		"bucket",
+		sharedInformers.Ark().V1().Backups(),
+		false,
+		true,
+		metrics.NewServerMetrics(),
+		&FakeEventRecorder{},
+		DefaultTerminationGracePeriod,
+	).(*restoreController)
+
+	sharedInformers.Ark().V1().Backups().Informer().GetStore().Add(NewTestBackup().WithNamespace("team-a").WithName("team-a-backup").Backup)
+	sharedInformers.Ark().V1().Backups().Informer().GetStore().Add(NewTestBackup().WithName("admin-backup").Backup)
+
+	t.Run("restores in tenant namespaces can only use their own backups", func(t *testing.T) {
+		backup, err := c.getBackup(NewTestRestore("team-a", "restore-1", api.RestorePhaseNew).WithBackup("team-a-backup").Restore)
+		require.NoError(t, err)
+		assert.Equal(t, "team-a", backup.Namespace)
+
+		_, err = c.getBackup(NewTestRestore("team-a", "restore-1", api.RestorePhaseNew).WithBackup("admin-backup").Restore)
+		assert.Error(t, err)
+	})
+
+	t.Run("restores in the server's namespace can use backups in any namespace", func(t *testing.T) {
+		backup, err := c.getBackup(NewTestRestore(api.DefaultNamespace, "restore-1", api.RestorePhaseNew).WithBackup("team-a-backup").Restore)
+		require.NoError(t, err)
+		assert.Equal(t, "team-a", backup.Namespace)
+
+		_, err = c.getBackup(NewTestRestore(api.DefaultNamespace, "restore-1", api.RestorePhaseNew).WithBackup("missing").Restore)
+		assert.Error(t, err)
+	})
+
+	t.Run("restores in tenant namespaces can't include other namespaces or all cluster resources", func(t *testing.T) {
+		restore := NewTestRestore("team-a", "restore-1", api.RestorePhaseNew).
+			WithBackup("team-a-backup").
+			WithRestorableNamespace("team-b").
+			WithMappedNamespace("team-a", "team-c").
+			WithIncludeClusterResources(true).
+			Restore
+
+		assert.Equal(t, []string{
+			"Namespace team-b can't be included; backups and restores in namespace team-a can only include it",
+			"Backups and restores in namespace team-a can't include cluster-scoped resources",
+			"Namespace team-a can't be mapped to team-c; restores in namespace team-a can only restore into it",
+		}, c.getValidationErrors(restore))
+	})
+}
+
+func TestUploadResults(t *testing.T) {
+	var (
+		client          = fake.NewSimpleClientset()
+		sharedInformers = informers.NewSharedInformerFactory(client, 0)
+		backupSvc       = &fakeBackupService{}
+	)
+
+	c := NewRestoreController(
+		sharedInformers.Ark().V1().Restores(),
+		client.ArkV1(),
+		client.ArkV1(),
+		&fakeRestorer{},
+		backupSvc,
+		"bucket",
+		sharedInformers.Ark().V1().Backups(),
+		false,
+		false,
+		metrics.NewServerMetrics(),
+		&FakeEventRecorder{},
+		DefaultTerminationGracePeriod,
+	).(*restoreController)
+
+	sharedInformers.Ark().V1().Backups().Informer().GetStore().Add(NewTestBackup().WithName("backup-1").Backup)
+
+	warnings := api.RestoreResult{Cluster: []string{"warning"}}
+	errs := api.RestoreResult{Ark: []string{"error-1"}, Namespaces: map[string][]string{"ns-1": {"error-2"}}}
+
+	t.Run("results are stored gzipped alongside the restore's backup", func(t *testing.T) {
+		var uploaded map[string]api.RestoreResult
+		backupSvc.On("UploadRestoreResults", "bucket", "backup-1", "restore-1", mock.Anything).Return(nil).Once().Run(func(args mock.Arguments) {
+			gzr, err := gzip.NewReader(args.Get(3).(io.Reader))
+			require.NoError(t, err)
+			require.NoError(t, json.NewDecoder(gzr).Decode(&uploaded))
+		})
+
+		restore := NewTestRestore(api.DefaultNamespace, "restore-1", api.RestorePhaseInProgress).WithBackup("backup-1").Restore
+		require.NoError(t, c.uploadResults(restore, warnings, errs, "bucket"))
+
+		assert.Equal(t, map[string]api.RestoreResult{"warnings": warnings, "errors": errs}, uploaded)
+		backupSvc.AssertExpectations(t)
+	})
+
+	t.Run("results of restores of missing backups aren't stored", func(t *testing.T) {
+		restore := NewTestRestore(api.DefaultNamespace, "restore-2", api.RestorePhaseInProgress).WithBackup("missing").Restore
+		assert.Error(t, c.uploadResults(restore, warnings, errs, "bucket"))
+		backupSvc.AssertNotCalled(t, "UploadRestoreResults", "bucket", "missing", "restore-2", mock.Anything)
+	})
+}
+
+func TestCountResults(t *testing.T) {
+	counts := countResults(api.RestoreResult{
+		Ark:     []string{"a"},
+		Cluster: []string{"b", "c"},
+		Namespaces: map[string][]string{
+			"ns-1": {"d"},
+			"ns-2": {"e", "f", "g"},
+		},
+	})
+
+	assert.Equal(t, api.RestoreResultCounts{Ark: 1, Cluster: 2, Namespaces: map[string]int{"ns-1": 1, "ns-2": 3}}, counts)
+	assert.Equal(t, 7, counts.Total())
+
+	assert.Equal(t, api.RestoreResultCounts{}, countResults(api.RestoreResult{}))
+}
+
+func TestDownloadParentBackups(t *testing.T) {
+	tests := []struct {
+		name          string
+		backups       []*api.Backup
+		expectedFiles []string
+		expectedError string
+	}{
+		{
+			name:    "full backups have no parents",
+			backups: []*api.Backup{NewTestBackup().WithName("backup-1").Backup},
+		},
+		{
+			name: "parents are returned from the full backup to the immediate parent",
+			backups: []*api.Backup{
+				NewTestBackup().WithName("backup-1").WithParentBackup("backup-2").Backup,
+				NewTestBackup().WithName("backup-2").WithParentBackup("backup-3").Backup,
+				NewTestBackup().WithName("backup-3").Backup,
+			},
+			expectedFiles: []string{"backup-3", "backup-2"},
+		},
+		{
+			name: "missing parents are an error, but the parents downloaded so far are returned",
+			backups: []*api.Backup{
+				NewTestBackup().WithName("backup-1").WithParentBackup("backup-2").Backup,
+				NewTestBackup().WithName("backup-2").WithParentBackup("backup-3").Backup,
+			},
+			expectedFiles: []string{"backup-2"},
+			expectedError: `error getting parent backup backup-3: backup.ark.heptio.com "backup-3" not found`,
+		},
+		{
+			name: "cycles are an error",
+			backups: []*api.Backup{
+				NewTestBackup().WithName("backup-1").WithParentBackup("backup-2").Backup,
+				NewTestBackup().WithName("backup-2").WithParentBackup("backup-1").Backup,
+			},
+			expectedFiles: []string{"backup-2"},
+			expectedError: "backup backup-1 has a cycle in its parent backups",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var (
+				client          = fake.NewSimpleClientset()
+				sharedInformers = informers.NewSharedInformerFactory(client, 0)
+				backupSvc       = &FakeBackupService{}
+			)
+
+			c := &restoreController{
+				backupLister:  sharedInformers.Ark().V1().Backups().Lister(),
+				backupService: backupSvc,
+			}
+
+			for _, backup := range test.backups {
+				require.NoError(t, sharedInformers.Ark().V1().Backups().Informer().GetStore().Add(backup))
+				// each backup's tarball holds its name, so the files' order can be checked
+				backupSvc.On("DownloadBackup", "bucket", backup.Name).Return(ioutil.NopCloser(strings.NewReader(backup.Name)), nil)
+			}
+
+			files, err := c.downloadParentBackups(test.backups[0], "bucket")
+			defer func() {
+				for _, file := range files {
+					file.Close()
+					os.Remove(file.Name())
+				}
+			}()
+
+			if test.expectedError != "" {
+				assert.EqualError(t, err, test.expectedError)
+			} else {
+				assert.NoError(t, err)
+			}
+
+			var contents []string
+			for _, file := range files {
+				data, err := ioutil.ReadAll(file)
+				require.NoError(t, err)
+				contents = append(contents, string(data))
+			}
+			assert.Equal(t, test.expectedFiles, contents)
+		})
+	}
+}
@@ -0,0 +1,236 @@
+/*
+Copyright 2017 Heptio Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/golang/glog"
+
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/clock"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/tools/cache"
+
+	api "github.com/heptio/ark/pkg/apis/ark/v1"
+	arkv1client "github.com/heptio/ark/pkg/generated/clientset/typed/ark/v1"
+	informers "github.com/heptio/ark/pkg/generated/informers/externalversions/ark/v1"
+	listers "github.com/heptio/ark/pkg/generated/listers/ark/v1"
+)
+
+// retentionController applies schedules' retention policies to their backups, tagging the ones
+// each policy keeps and expiring the rest, so the GC controller deletes them.
+type retentionController struct {
+	schedulesLister       listers.ScheduleLister
+	schedulesListerSynced cache.InformerSynced
+	backupsLister         listers.BackupLister
+	backupsListerSynced   cache.InformerSynced
+	backupsClient         arkv1client.BackupsGetter
+	syncPeriod            time.Duration
+	clock                 clock.Clock
+}
+
+// NewRetentionController constructs a new retentionController.
+func NewRetentionController(
+	schedulesInformer informers.ScheduleInformer,
+	backupsInformer informers.BackupInformer,
+	backupsClient arkv1client.BackupsGetter,
+	syncPeriod time.Duration,
+) Interface {
+	if syncPeriod < time.Minute {
+		glog.Infof("Retention sync period %v is too short. Setting to 1 minute", syncPeriod)
+		syncPeriod = time.Minute
+	}
+
+	return &retentionController{
+		schedulesLister:       schedulesInformer.Lister(),
+		schedulesListerSynced: schedulesInformer.Informer().HasSynced,
+		backupsLister:         backupsInformer.Lister(),
+		backupsListerSynced:   backupsInformer.Informer().HasSynced,
+		backupsClient:         backupsClient,
+		syncPeriod:            syncPeriod,
+		clock:                 clock.RealClock{},
+	}
+}
+
+var _ Interface = &retentionController{}
+
+// Run is a blocking function that periodically applies schedules' retention policies to their
+// backups. It will return when it receives on the ctx.Done() channel.
+func (c *retentionController) Run(ctx context.Context, workers int) error {
+	glog.Info("Waiting for caches to sync")
+	if !cache.WaitForCacheSync(ctx.Done(), c.schedulesListerSynced, c.backupsListerSynced) {
+		return errors.New("timed out waiting for caches to sync")
+	}
+	glog.Info("Caches are synced")
+
+	wait.Until(c.run, c.syncPeriod, ctx.Done())
+	return nil
+}
+
+func (c *retentionController) run() {
+	schedules, err := c.schedulesLister.List(labels.Everything())
+	if err != nil {
+		glog.Errorf("error listing schedules: %v", err)
+		return
+	}
+
+	for _, schedule := range schedules {
+		// a policy that keeps nothing would expire every backup, so it's treated as no policy
+		if retention := schedule.Spec.Retention; retention == nil ||
+			(retention.Last <= 0 && retention.Daily <= 0 && retention.Weekly <= 0 && retention.Monthly <= 0 && retention.Yearly <= 0) {
+			continue
+		}
+
+		if err := c.applyRetention(schedule); err != nil {
+			glog.Errorf("error applying the retention policy of schedule %s/%s: %v", schedule.Namespace, schedule.Name, err)
+		}
+	}
+}
+
+// applyRetention tags the completed and partially failed backups of schedule that its retention
+// policy keeps with the rules that keep them, and expires the rest.
+func (c *retentionController) applyRetention(schedule *api.Schedule) error {
+	location := time.Local
+	if schedule.Spec.Timezone != "" {
+		var err error
+		if location, err = time.LoadLocation(schedule.Spec.Timezone); err != nil {
+			return err
+		}
+	}
+
+	selector := labels.SelectorFromSet(labels.Set{api.ScheduleNameLabel: schedule.Name})
+	backups, err := c.backupsLister.Backups(schedule.Namespace).List(selector)
+	if err != nil {
+		return err
+	}
+
+	// only backups that finished are subject to the retention policy; the rest keep their TTLs
+	var completed []*api.Backup
+	for _, backup := range backups {
+		if backup.Status.Phase == api.BackupPhaseCompleted || backup.Status.Phase == api.BackupPhasePartiallyFailed {
+			completed = append(completed, backup)
+		}
+	}
+
+	retained := getRetainedBackups(schedule.Spec.Retention, completed, location)
+	now := c.clock.Now()
+
+	for _, backup := range completed {
+		rules := strings.Join(retained[backup.Name], ",")
+
+		if backup.Annotations[api.RetentionAnnotation] == rules && (rules != "" || !backupExpiration(backup).After(now)) {
+			continue
+		}
+
+		updated, err := cloneBackup(backup)
+		if err != nil {
+			return err
+		}
+
+		if rules != "" {
+			glog.V(4).Infof("Retaining backup %s/%s for the %s rules of schedule %s", backup.Namespace, backup.Name, rules, schedule.Name)
+			if updated.Annotations == nil {
+				updated.Annotations = make(map[string]string)
+			}
+			updated.Annotations[api.RetentionAnnotation] = rules
+			delete(updated.Annotations, api.RetentionExpiredAnnotation)
+		} else {
+			glog.Infof("Backup %s/%s isn't retained by the retention policy of schedule %s, expiring it", backup.Namespace, backup.Name, schedule.Name)
+			delete(updated.Annotations, api.RetentionAnnotation)
+			if backupExpiration(updated).After(now) {
+				if updated.Annotations == nil {
+					updated.Annotations = make(map[string]string)
+				}
+				updated.Annotations[api.RetentionExpiredAnnotation] = now.UTC().Format(time.RFC3339)
+			}
+		}
+
+		if _, err := c.backupsClient.Backups(updated.Namespace).Update(updated); err != nil {
+			return fmt.Errorf("error updating backup %s/%s: %v", updated.Namespace, updated.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// retentionRule is a rule of a retention policy, which keeps the most recent backup in each of
+// the most recent count periods that have one.
+type retentionRule struct {
+	name  string
+	count int
+	// period returns the key of the period that a backup taken at t is in. If it's nil, each backup
+	// is in a period of its own.
+	period func(t time.Time) string
+}
+
+// getRetainedBackups returns the names of the backups that policy keeps, mapped to the names of the
+// rules that keep them. Backups' times are evaluated in location.
+func getRetainedBackups(policy *api.RetentionPolicy, backups []*api.Backup, location *time.Location) map[string][]string {
+	rules := []retentionRule{
+		{name: "last", count: policy.Last},
+		{name: "daily", count: policy.Daily, period: func(t time.Time) string { return t.Format("2006-01-02") }},
+		{name: "weekly", count: policy.Weekly, period: func(t time.Time) string {
+			year, week := t.ISOWeek()
+			return fmt.Sprintf("%d-W%02d", year, week)
+		}},
+		{name: "monthly", count: policy.Monthly, period: func(t time.Time) string { return t.Format("2006-01") }},
+		{name: "yearly", count: policy.Yearly, period: func(t time.Time) string { return t.Format("2006") }},
+	}
+
+	// newest first, with backups created in the same second ordered by name, so the same backups
+	// are kept every time
+	sorted := make([]*api.Backup, len(backups))
+	copy(sorted, backups)
+	sort.Slice(sorted, func(i, j int) bool {
+		ti, tj := sorted[i].CreationTimestamp.Time, sorted[j].CreationTimestamp.Time
+		if !ti.Equal(tj) {
+			return tj.Before(ti)
+		}
+		return sorted[i].Name > sorted[j].Name
+	})
+
+	retained := make(map[string][]string)
+	for _, rule := range rules {
+		kept := 0
+		lastPeriod := ""
+		for _, backup := range sorted {
+			if kept >= rule.count {
+				break
+			}
+
+			period := backup.Name
+			if rule.period != nil {
+				period = rule.period(backup.CreationTimestamp.Time.In(location))
+			}
+			if period == lastPeriod {
+				continue
+			}
+
+			retained[backup.Name] = append(retained[backup.Name], rule.name)
+			lastPeriod = period
+			kept++
+		}
+	}
+
+	return retained
+}
@@ -0,0 +1,251 @@
+/*
+Copyright 2017 Heptio Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/clock"
+	core "k8s.io/client-go/testing"
+
+	api "github.com/heptio/ark/pkg/apis/ark/v1"
+	"github.com/heptio/ark/pkg/generated/clientset/fake"
+	informers "github.com/heptio/ark/pkg/generated/informers/externalversions"
+	. "github.com/heptio/ark/pkg/util/test"
+)
+
+// scheduledBackup returns a completed backup of schedule "daily" that was created at timeString.
+func scheduledBackup(name, timeString string) *TestBackup {
+	t, _ := time.Parse("2006-01-02 15:04", timeString)
+
+	backup := NewTestBackup().WithNamespace("ns").WithName(name).WithLabel("ark-schedule", "daily").WithPhase(api.BackupPhaseCompleted)
+	backup.CreationTimestamp = metav1.NewTime(t)
+	return backup
+}
+
+func TestGetRetainedBackups(t *testing.T) {
+	backups := []*api.Backup{
+		scheduledBackup("2017-01-31", "2017-01-31 00:00").Backup,
+		scheduledBackup("2017-02-28", "2017-02-28 00:00").Backup,
+		scheduledBackup("2017-03-01", "2017-03-01 00:00").Backup,
+		scheduledBackup("2017-03-05", "2017-03-05 00:00").Backup,
+		scheduledBackup("2017-03-06", "2017-03-06 00:00").Backup,
+		scheduledBackup("2017-03-07-morning", "2017-03-07 00:00").Backup,
+		scheduledBackup("2017-03-07-evening", "2017-03-07 12:00").Backup,
+	}
+
+	tests := []struct {
+		name     string
+		policy   api.RetentionPolicy
+		location *time.Location
+		// backups overrides the default backups if set
+		backups  []*api.Backup
+		expected map[string][]string
+	}{
+		{
+			name:     "an empty policy keeps nothing",
+			location: time.UTC,
+			expected: map[string][]string{},
+		},
+		{
+			name:     "last keeps the most recent backups",
+			policy:   api.RetentionPolicy{Last: 2},
+			location: time.UTC,
+			expected: map[string][]string{
+				"2017-03-07-evening": {"last"},
+				"2017-03-07-morning": {"last"},
+			},
+		},
+		{
+			name:     "last keeps backups created at the same time, ordered by name",
+			policy:   api.RetentionPolicy{Last: 2},
+			location: time.UTC,
+			backups: []*api.Backup{
+				scheduledBackup("b", "2017-03-07 00:00").Backup,
+				scheduledBackup("a", "2017-03-07 00:00").Backup,
+				scheduledBackup("c", "2017-03-07 00:00").Backup,
+			},
+			expected: map[string][]string{
+				"c": {"last"},
+				"b": {"last"},
+			},
+		},
+		{
+			name:     "daily keeps the most recent backup of each day",
+			policy:   api.RetentionPolicy{Daily: 3},
+			location: time.UTC,
+			expected: map[string][]string{
+				"2017-03-07-evening": {"daily"},
+				"2017-03-06":         {"daily"},
+				"2017-03-05":         {"daily"},
+			},
+		},
+		{
+			name:     "weekly keeps the most recent backup of each ISO week",
+			policy:   api.RetentionPolicy{Weekly: 2},
+			location: time.UTC,
+			expected: map[string][]string{
+				"2017-03-07-evening": {"weekly"},
+				"2017-03-05":         {"weekly"},
+			},
+		},
+		{
+			name:     "rules are combined",
+			policy:   api.RetentionPolicy{Daily: 2, Monthly: 3, Yearly: 1},
+			location: time.UTC,
+			expected: map[string][]string{
+				"2017-03-07-evening": {"daily", "monthly", "yearly"},
+				"2017-03-06":         {"daily"},
+				"2017-02-28":         {"monthly"},
+				"2017-01-31":         {"monthly"},
+			},
+		},
+		{
+			name:     "periods are in the given time zone",
+			policy:   api.RetentionPolicy{Monthly: 2},
+			location: time.FixedZone("UTC+1", 60*60),
+			expected: map[string][]string{
+				"2017-03-07-evening": {"monthly"},
+				// 2017-03-01 00:00 UTC is in March in UTC+1 too, but 2017-02-28 00:00 UTC is still in February
+				"2017-02-28": {"monthly"},
+			},
+		},
+		{
+			name:     "periods are in the given time zone when it moves a backup into the next period",
+			policy:   api.RetentionPolicy{Monthly: 2},
+			location: time.FixedZone("UTC-1", -60*60),
+			expected: map[string][]string{
+				"2017-03-07-evening": {"monthly"},
+				// 2017-03-01 00:00 UTC is on February 28 in UTC-1
+				"2017-03-01": {"monthly"},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			testBackups := backups
+			if test.backups != nil {
+				testBackups = test.backups
+			}
+			assert.Equal(t, test.expected, getRetainedBackups(&test.policy, testBackups, test.location))
+		})
+	}
+}
+
+func TestApplyRetention(t *testing.T) {
+	now, _ := time.Parse("2006-01-02 15:04", "2017-03-08 00:00")
+
+	schedule := NewTestSchedule("ns", "daily").WithCronSchedule("0 0 * * *").Schedule
+	schedule.Spec.Retention = &api.RetentionPolicy{Daily: 2}
+
+	backups := []*api.Backup{
+		// newly retained, even though it partially failed
+		scheduledBackup("2017-03-07", "2017-03-07 00:00").WithPhase(api.BackupPhasePartiallyFailed).WithExpiration(now.Add(time.Hour)).Backup,
+		// already retained
+		scheduledBackup("2017-03-06", "2017-03-06 00:00").WithAnnotation(api.RetentionAnnotation, "daily").Backup,
+		// no longer retained
+		scheduledBackup("2017-03-05", "2017-03-05 00:00").WithAnnotation(api.RetentionAnnotation, "daily").WithExpiration(now.Add(time.Hour)).Backup,
+		// already expired
+		scheduledBackup("2017-03-04", "2017-03-04 00:00").WithExpiration(now.Add(-time.Hour)).Backup,
+		// already expired by the retention policy
+		scheduledBackup("2017-03-03", "2017-03-03 00:00").WithAnnotation(api.RetentionExpiredAnnotation, "2017-03-07T00:00:00Z").WithExpiration(now.Add(time.Hour)).Backup,
+		// still running, so it keeps its TTL
+		scheduledBackup("2017-03-08", "2017-03-08 00:00").WithPhase(api.BackupPhaseInProgress).WithExpiration(now.Add(time.Hour)).Backup,
+		// another schedule's
+		NewTestBackup().WithNamespace("ns").WithName("other").WithLabel("ark-schedule", "other").WithPhase(api.BackupPhaseCompleted).WithExpiration(now.Add(time.Hour)).Backup,
+	}
+
+	var objects []runtime.Object
+	for _, backup := range backups {
+		objects = append(objects, backup)
+	}
+
+	var (
+		client          = fake.NewSimpleClientset(objects...)
+		sharedInformers = informers.NewSharedInformerFactory(client, 0)
+	)
+
+	c := NewRetentionController(
+		sharedInformers.Ark().V1().Schedules(),
+		sharedInformers.Ark().V1().Backups(),
+		client.ArkV1(),
+		time.Minute,
+	).(*retentionController)
+	c.clock = clock.NewFakeClock(now)
+
+	for _, backup := range backups {
+		require.NoError(t, sharedInformers.Ark().V1().Backups().Informer().GetStore().Add(backup))
+	}
+
+	require.NoError(t, c.applyRetention(schedule))
+
+	retained := scheduledBackup("2017-03-07", "2017-03-07 00:00").WithPhase(api.BackupPhasePartiallyFailed).WithExpiration(now.Add(time.Hour)).
+		WithAnnotation(api.RetentionAnnotation, "daily").Backup
+	// the expiration is recorded in an annotation, since the status of immutable backups can't change
+	expired := scheduledBackup("2017-03-05", "2017-03-05 00:00").WithExpiration(now.Add(time.Hour)).
+		WithAnnotation(api.RetentionExpiredAnnotation, "2017-03-08T00:00:00Z").Backup
+
+	expectedUpdates := map[string]*api.Backup{
+		retained.Name: retained,
+		expired.Name:  expired,
+	}
+
+	actions := client.Actions()
+	require.Len(t, actions, len(expectedUpdates))
+	for _, action := range actions {
+		update, ok := action.(core.UpdateAction)
+		require.True(t, ok, "unexpected action %v", action)
+
+		backup := update.GetObject().(*api.Backup)
+		assert.Equal(t, expectedUpdates[backup.Name], backup)
+	}
+}
+
+func TestRunIgnoresPoliciesThatKeepNothing(t *testing.T) {
+	now, _ := time.Parse("2006-01-02 15:04", "2017-03-08 00:00")
+
+	schedule := NewTestSchedule("ns", "daily").WithCronSchedule("0 0 * * *").WithRetention(api.RetentionPolicy{}).Schedule
+	backup := scheduledBackup("2017-03-07", "2017-03-07 00:00").WithExpiration(now.Add(time.Hour)).Backup
+
+	var (
+		client          = fake.NewSimpleClientset(backup)
+		sharedInformers = informers.NewSharedInformerFactory(client, 0)
+	)
+
+	c := NewRetentionController(
+		sharedInformers.Ark().V1().Schedules(),
+		sharedInformers.Ark().V1().Backups(),
+		client.ArkV1(),
+		time.Minute,
+	).(*retentionController)
+	c.clock = clock.NewFakeClock(now)
+
+	require.NoError(t, sharedInformers.Ark().V1().Schedules().Informer().GetStore().Add(schedule))
+	require.NoError(t, sharedInformers.Ark().V1().Backups().Informer().GetStore().Add(backup))
+
+	c.run()
+
+	// the backup keeps its TTL rather than being expired
+	assert.Empty(t, client.Actions())
+}
@@ -18,8 +18,10 @@ package controller
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"hash/fnv"
 	"sync"
 	"time"
 
@@ -29,6 +31,7 @@ import (
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/clock"
 	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/client-go/tools/cache"
@@ -39,6 +42,9 @@ import (
 	arkv1client "github.com/heptio/ark/pkg/generated/clientset/typed/ark/v1"
 	informers "github.com/heptio/ark/pkg/generated/informers/externalversions/ark/v1"
 	listers "github.com/heptio/ark/pkg/generated/listers/ark/v1"
+	"github.com/heptio/ark/pkg/notification"
+	"github.com/heptio/ark/pkg/schedule"
+	"github.com/heptio/ark/pkg/util/nametemplate"
 )
 
 type scheduleController struct {
@@ -46,17 +52,23 @@ type scheduleController struct {
 	backupsClient         arkv1client.BackupsGetter
 	schedulesLister       listers.ScheduleLister
 	schedulesListerSynced cache.InformerSynced
+	backupsLister         listers.BackupLister
+	backupsListerSynced   cache.InformerSynced
 	syncHandler           func(scheduleName string) error
 	queue                 workqueue.RateLimitingInterface
 	syncPeriod            time.Duration
 	clock                 clock.Clock
+	// clusterName is the name of the cluster, for backup name templates.
+	clusterName string
 }
 
 func NewScheduleController(
 	schedulesClient arkv1client.SchedulesGetter,
 	backupsClient arkv1client.BackupsGetter,
 	schedulesInformer informers.ScheduleInformer,
+	backupsInformer informers.BackupInformer,
 	syncPeriod time.Duration,
+	clusterName string,
 ) *scheduleController {
 	if syncPeriod < time.Minute {
 		glog.Infof("Schedule sync period %v is too short. Setting to 1 minute", syncPeriod)
@@ -68,9 +80,12 @@ func NewScheduleController(
 		backupsClient:         backupsClient,
 		schedulesLister:       schedulesInformer.Lister(),
 		schedulesListerSynced: schedulesInformer.Informer().HasSynced,
-		queue:      workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "schedule"),
-		syncPeriod: syncPeriod,
-		clock:      clock.RealClock{},
+		backupsLister:         backupsInformer.Lister(),
+		backupsListerSynced:   backupsInformer.Informer().HasSynced,
+		queue:                 workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "schedule"),
+		syncPeriod:            syncPeriod,
+		clock:                 clock.RealClock{},
+		clusterName:           clusterName,
 	}
 
 	c.syncHandler = c.processSchedule
@@ -98,6 +113,23 @@ func NewScheduleController(
 		},
 	)
 
+	// re-sync schedules when their backups' phases change, to record them in their statuses
+	backupsInformer.Informer().AddEventHandler(
+		cache.ResourceEventHandlerFuncs{
+			UpdateFunc: func(oldObj, newObj interface{}) {
+				oldBackup := oldObj.(*api.Backup)
+				newBackup := newObj.(*api.Backup)
+
+				scheduleName := newBackup.Labels[api.ScheduleNameLabel]
+				if scheduleName == "" || oldBackup.Status.Phase == newBackup.Status.Phase {
+					return
+				}
+
+				c.queue.Add(newBackup.Namespace + "/" + scheduleName)
+			},
+		},
+	)
+
 	return c
 }
 
@@ -124,7 +156,7 @@ func (controller *scheduleController) Run(ctx context.Context, numWorkers int) e
 	defer glog.Info("Shutting down ScheduleController")
 
 	glog.Info("Waiting for caches to sync")
-	if !cache.WaitForCacheSync(ctx.Done(), controller.schedulesListerSynced) {
+	if !cache.WaitForCacheSync(ctx.Done(), controller.schedulesListerSynced, controller.backupsListerSynced) {
 		return errors.New("timed out waiting for caches to sync")
 	}
 	glog.Info("Caches are synced")
@@ -236,6 +268,30 @@ func (controller *scheduleController) processSchedule(key string) error {
 	currentPhase := schedule.Status.Phase
 
 	cronSchedule, errs := parseCronSchedule(schedule)
+	if schedule.Spec.BackupNameTemplate != "" {
+		if err := nametemplate.Validate(schedule.Spec.BackupNameTemplate, schedule.Name, controller.clusterName); err != nil {
+			errs = append(errs, fmt.Sprintf("invalid backup name template: %v", err))
+		}
+	}
+	switch schedule.Spec.ConcurrencyPolicy {
+	case "", api.ConcurrencyPolicyAllow, api.ConcurrencyPolicyForbid, api.ConcurrencyPolicyReplace:
+	default:
+		errs = append(errs, fmt.Sprintf("invalid concurrency policy %q", schedule.Spec.ConcurrencyPolicy))
+	}
+	if schedule.Spec.Jitter.Duration < 0 {
+		errs = append(errs, fmt.Sprintf("jitter must not be negative, but is %s", schedule.Spec.Jitter.Duration))
+	}
+	if retention := schedule.Spec.Retention; retention != nil {
+		if retention.Last < 0 || retention.Daily < 0 || retention.Weekly < 0 || retention.Monthly < 0 || retention.Yearly < 0 {
+			errs = append(errs, "retention counts must not be negative")
+		}
+		if retention.Last <= 0 && retention.Daily <= 0 && retention.Weekly <= 0 && retention.Monthly <= 0 && retention.Yearly <= 0 {
+			errs = append(errs, "retention must keep at least one backup")
+		}
+	}
+	if err := notification.ValidateOverrides(schedule.Spec.Notifications); err != nil {
+		errs = append(errs, fmt.Sprintf("invalid notification overrides: %v", err))
+	}
 	if len(errs) > 0 {
 		schedule.Status.Phase = api.SchedulePhaseFailedValidation
 		schedule.Status.ValidationErrors = errs
@@ -257,6 +313,16 @@ func (controller *scheduleController) processSchedule(key string) error {
 		return nil
 	}
 
+	if schedule, err = controller.updateLastBackupStatus(schedule); err != nil {
+		glog.V(4).Infof("error updating last backup status of Schedule %v/%v: %v", ns, name, err)
+		return err
+	}
+
+	if schedule.Spec.Paused {
+		glog.V(4).Infof("Schedule %v/%v is paused, skipping...", schedule.Namespace, schedule.Name)
+		return nil
+	}
+
 	// check for the schedule being due to run, and submit a Backup if so
 	if err := controller.submitBackupIfDue(schedule, cronSchedule); err != nil {
 		glog.V(4).Infof("error processing Schedule %v/%v: err=%v", schedule.Namespace, schedule.Name, err)
@@ -308,6 +374,17 @@ func parseCronSchedule(itm *api.Schedule) (cron.Schedule, []string) {
 		}
 	}()
 
+	if itm.Spec.Timezone != "" {
+		location, err := time.LoadLocation(itm.Spec.Timezone)
+		if err != nil {
+			glog.V(4).Infof("error loading time zone for schedule %v/%v, timezone=%v: %v", itm.Namespace, itm.Name, itm.Spec.Timezone, err)
+			validationErrors = append(validationErrors, fmt.Sprintf("invalid timezone %q: %v", itm.Spec.Timezone, err))
+		} else if _, isInterval := schedule.(cron.ConstantDelaySchedule); schedule != nil && !isInterval {
+			// @every schedules are intervals, which don't depend on the time zone
+			schedule = &zonedSchedule{schedule: schedule, location: location}
+		}
+	}
+
 	if len(validationErrors) > 0 {
 		return nil, validationErrors
 	}
@@ -315,6 +392,43 @@ func parseCronSchedule(itm *api.Schedule) (cron.Schedule, []string) {
 	return schedule, nil
 }
 
+// zonedSchedule is a cron schedule that's evaluated in a time zone.
+type zonedSchedule struct {
+	schedule cron.Schedule
+	location *time.Location
+}
+
+// Next returns the first time after t that the schedule runs. The cron schedule is evaluated on
+// wall clock times in the schedule's time zone, so a daylight saving time change doesn't move it.
+// Wall clock times that are skipped when the clocks go forward run as much later as the clocks
+// went forward, and wall clock times that are repeated when the clocks go back run once.
+func (s *zonedSchedule) Next(t time.Time) time.Time {
+	next := wallClock(t.In(s.location))
+	for {
+		next = s.schedule.Next(next)
+		if next.IsZero() {
+			return next
+		}
+
+		run := time.Date(next.Year(), next.Month(), next.Day(), next.Hour(), next.Minute(), next.Second(), next.Nanosecond(), s.location)
+		if wall := wallClock(run); wall.Before(next) {
+			// next was skipped when the clocks went forward, and time.Date used the offset from
+			// after the change
+			run = run.Add(next.Sub(wall))
+		}
+
+		// when the clocks go back, a repeated wall clock time can map to an earlier time than t
+		if run.After(t) {
+			return run
+		}
+	}
+}
+
+// wallClock returns t's wall clock time as a UTC time, which has no daylight saving time changes.
+func wallClock(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), time.UTC)
+}
+
 func (controller *scheduleController) submitBackupIfDue(item *api.Schedule, cronSchedule cron.Schedule) error {
 	now := controller.clock.Now()
 
@@ -322,19 +436,61 @@ func (controller *scheduleController) submitBackupIfDue(item *api.Schedule, cron
 
 	if !isDue {
 		glog.Infof("Next run time for %v/%v is %v, skipping...", item.Namespace, item.Name, nextRunTime)
+		if item.Status.NextRunTime.Time.Equal(nextRunTime) {
+			return nil
+		}
+
+		schedule, err := cloneSchedule(item)
+		if err != nil {
+			glog.V(4).Infof("error cloning Schedule %v/%v: %v", item.Namespace, item.Name, err)
+			return err
+		}
+
+		schedule.Status.NextRunTime = metav1.NewTime(nextRunTime)
+
+		if _, err := controller.schedulesClient.Schedules(schedule.Namespace).Update(schedule); err != nil {
+			glog.V(4).Infof("error updating NextRunTime for Schedule %v/%v: %v", schedule.Namespace, schedule.Name, err)
+			return err
+		}
 		return nil
 	}
 
 	// Don't attempt to "catch up" if there are any missed or failed runs - simply
 	// trigger a Backup if it's time.
-	//
-	// It might also make sense in the future to explicitly check for currently-running
-	// backups so that we don't overlap runs (for disk snapshots in particular, this can
-	// lead to performance issues).
+
+	if policy := item.Spec.ConcurrencyPolicy; policy == api.ConcurrencyPolicyForbid || policy == api.ConcurrencyPolicyReplace {
+		running, err := controller.getRunningBackups(item)
+		if err != nil {
+			glog.V(4).Infof("error listing running Backups for Schedule %v/%v: %v", item.Namespace, item.Name, err)
+			return err
+		}
+
+		if len(running) > 0 && policy == api.ConcurrencyPolicyForbid {
+			glog.Infof("Backup %v/%v is still running, so Schedule %v/%v is skipping this run", running[0].Namespace, running[0].Name, item.Namespace, item.Name)
+			return controller.skipRun(item, cronSchedule, now)
+		}
+
+		for _, backup := range running {
+			glog.Infof("Schedule %v/%v is replacing running Backup %v/%v, canceling it", item.Namespace, item.Name, backup.Namespace, backup.Name)
+			if err := controller.cancelBackup(backup); err != nil {
+				glog.V(4).Infof("error canceling Backup %v/%v: %v", backup.Namespace, backup.Name, err)
+				return err
+			}
+		}
+	}
 
 	glog.Infof("Next run time for %v/%v is %v, submitting Backup...", item.Namespace, item.Name, nextRunTime)
-	backup := getBackup(item, now)
-	if _, err := controller.backupsClient.Backups(backup.Namespace).Create(backup); err != nil {
+	name, err := schedule.BackupName(item, now, controller.clusterName)
+	if err != nil {
+		glog.V(4).Infof("error generating Backup for Schedule %v/%v: %v", item.Namespace, item.Name, err)
+		return err
+	}
+	backup := schedule.NewBackup(item, name)
+	if _, err := controller.backupsClient.Backups(backup.Namespace).Create(backup); apierrors.IsAlreadyExists(err) {
+		// the backup name template doesn't generate a new name for each run, so this run's
+		// backup already exists
+		glog.Warningf("Backup %v/%v already exists, so Schedule %v/%v isn't creating it", backup.Namespace, backup.Name, item.Namespace, item.Name)
+	} else if err != nil {
 		glog.V(4).Infof("error creating Backup: %v", err)
 		return err
 	}
@@ -346,6 +502,11 @@ func (controller *scheduleController) submitBackupIfDue(item *api.Schedule, cron
 	}
 
 	schedule.Status.LastBackup = metav1.NewTime(now)
+	schedule.Status.LastBackupName = backup.Name
+	schedule.Status.LastBackupPhase = api.BackupPhaseNew
+	schedule.Status.LastBackupCompletionTimestamp = metav1.Time{}
+	_, nextRunTime = getNextRunTime(schedule, cronSchedule, now)
+	schedule.Status.NextRunTime = metav1.NewTime(nextRunTime)
 
 	if _, err := controller.schedulesClient.Schedules(schedule.Namespace).Update(schedule); err != nil {
 		glog.V(4).Infof("error updating LastBackup for Schedule %v/%v: %v", schedule.Namespace, schedule.Name, err)
@@ -355,27 +516,120 @@ func (controller *scheduleController) submitBackupIfDue(item *api.Schedule, cron
 	return nil
 }
 
+// getRunningBackups returns the Backups created by schedule that haven't finished running.
+func (controller *scheduleController) getRunningBackups(schedule *api.Schedule) ([]*api.Backup, error) {
+	selector := labels.SelectorFromSet(labels.Set{api.ScheduleNameLabel: schedule.Name})
+
+	backups, err := controller.backupsLister.Backups(schedule.Namespace).List(selector)
+	if err != nil {
+		return nil, err
+	}
+
+	var running []*api.Backup
+	for _, backup := range backups {
+		switch backup.Status.Phase {
+		case "", api.BackupPhaseNew, api.BackupPhaseInProgress:
+			running = append(running, backup)
+		}
+	}
+
+	return running, nil
+}
+
+// skipRun records in item's status that its run at now was skipped, so that it next runs at the
+// following scheduled time.
+func (controller *scheduleController) skipRun(item *api.Schedule, cronSchedule cron.Schedule, now time.Time) error {
+	schedule, err := cloneSchedule(item)
+	if err != nil {
+		glog.V(4).Infof("error cloning Schedule %v/%v: %v", item.Namespace, item.Name, err)
+		return err
+	}
+
+	schedule.Status.LastSkipped = metav1.NewTime(now)
+	_, nextRunTime := getNextRunTime(schedule, cronSchedule, now)
+	schedule.Status.NextRunTime = metav1.NewTime(nextRunTime)
+
+	if _, err := controller.schedulesClient.Schedules(schedule.Namespace).Update(schedule); err != nil {
+		glog.V(4).Infof("error updating LastSkipped for Schedule %v/%v: %v", schedule.Namespace, schedule.Name, err)
+		return err
+	}
+
+	return nil
+}
+
+// cancelBackup requests the cancellation of backup, if it hasn't been requested already.
+func (controller *scheduleController) cancelBackup(backup *api.Backup) error {
+	if backup.Annotations[api.CancelAnnotation] == "true" {
+		return nil
+	}
+
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": map[string]string{
+				api.CancelAnnotation: "true",
+			},
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = controller.backupsClient.Backups(backup.Namespace).Patch(backup.Name, types.MergePatchType, patch)
+	return err
+}
+
+// updateLastBackupStatus records the phase and completion time of the last backup created for
+// schedule in its status, if they've changed, returning the updated schedule. schedule must not be
+// from the cache.
+func (controller *scheduleController) updateLastBackupStatus(schedule *api.Schedule) (*api.Schedule, error) {
+	if schedule.Status.LastBackupName == "" {
+		return schedule, nil
+	}
+
+	backup, err := controller.backupsLister.Backups(schedule.Namespace).Get(schedule.Status.LastBackupName)
+	if apierrors.IsNotFound(err) {
+		// the backup's been deleted, so its last known status is kept
+		return schedule, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if backup.Status.Phase == schedule.Status.LastBackupPhase &&
+		backup.Status.CompletionTimestamp.Time.Equal(schedule.Status.LastBackupCompletionTimestamp.Time) {
+		return schedule, nil
+	}
+
+	schedule.Status.LastBackupPhase = backup.Status.Phase
+	schedule.Status.LastBackupCompletionTimestamp = backup.Status.CompletionTimestamp
+
+	return controller.schedulesClient.Schedules(schedule.Namespace).Update(schedule)
+}
+
 func getNextRunTime(schedule *api.Schedule, cronSchedule cron.Schedule, asOf time.Time) (bool, time.Time) {
 	// get the latest run time (if the schedule hasn't run yet, this will be the zero value which will trigger
-	// an immediate backup)
+	// an immediate backup). Skipped runs count as runs.
 	lastBackupTime := schedule.Status.LastBackup.Time
+	if schedule.Status.LastSkipped.Time.After(lastBackupTime) {
+		lastBackupTime = schedule.Status.LastSkipped.Time
+	}
 
 	nextRunTime := cronSchedule.Next(lastBackupTime)
+	nextRunTime = nextRunTime.Add(jitterOffset(schedule, nextRunTime))
 
 	return asOf.After(nextRunTime), nextRunTime
 }
 
-func getBackup(item *api.Schedule, timestamp time.Time) *api.Backup {
-	backup := &api.Backup{
-		Spec: item.Spec.Template,
-		ObjectMeta: metav1.ObjectMeta{
-			Namespace: item.Namespace,
-			Name:      fmt.Sprintf("%s-%s", item.Name, timestamp.Format("20060102150405")),
-			Labels: map[string]string{
-				"ark-schedule": item.Name,
-			},
-		},
+// jitterOffset returns how long after scheduledTime schedule's run at that time starts, within its
+// jitter window. The offset is derived from the schedule's name and scheduledTime rather than
+// chosen at random, so it's the same each time the schedule's synced.
+func jitterOffset(schedule *api.Schedule, scheduledTime time.Time) time.Duration {
+	if schedule.Spec.Jitter.Duration <= 0 {
+		return 0
 	}
 
-	return backup
+	hash := fnv.New64a()
+	fmt.Fprintf(hash, "%s/%s/%d", schedule.Namespace, schedule.Name, scheduledTime.Unix())
+
+	return time.Duration(hash.Sum64() % uint64(schedule.Spec.Jitter.Duration))
 }
@@ -17,6 +17,7 @@ limitations under the License.
 package controller
 
 import (
+	"fmt"
 	"testing"
 	"time"
 
@@ -34,6 +35,7 @@ import (
 	api "github.com/heptio/ark/pkg/apis/ark/v1"
 	"github.com/heptio/ark/pkg/generated/clientset/fake"
 	informers "github.com/heptio/ark/pkg/generated/informers/externalversions"
+	"github.com/heptio/ark/pkg/schedule"
 	. "github.com/heptio/ark/pkg/util/test"
 )
 
@@ -42,9 +44,12 @@ func TestProcessSchedule(t *testing.T) {
 		name                             string
 		scheduleKey                      string
 		schedule                         *api.Schedule
+		backups                          []*api.Backup
 		fakeClockTime                    string
 		expectedErr                      bool
 		expectedSchedulePhaseUpdate      *api.Schedule
+		expectedScheduleStatusUpdate     *api.Schedule
+		expectedBackupCancels            []string
 		expectedScheduleLastBackupUpdate *api.Schedule
 		expectedBackupCreate             *api.Backup
 	}{
@@ -84,34 +89,146 @@ func TestProcessSchedule(t *testing.T) {
 			expectedSchedulePhaseUpdate: NewTestSchedule("ns", "name").WithPhase(api.SchedulePhaseFailedValidation).
 				WithValidationError("Schedule must be a non-empty valid Cron expression").Schedule,
 		},
+		{
+			name:        "schedule with an invalid timezone fails validation",
+			schedule:    NewTestSchedule("ns", "name").WithPhase(api.SchedulePhaseNew).WithCronSchedule("0 9 * * *").WithTimezone("Mars/Olympus_Mons").Schedule,
+			expectedErr: false,
+			expectedSchedulePhaseUpdate: NewTestSchedule("ns", "name").WithPhase(api.SchedulePhaseFailedValidation).WithCronSchedule("0 9 * * *").WithTimezone("Mars/Olympus_Mons").
+				WithValidationError(`invalid timezone "Mars/Olympus_Mons": unknown time zone Mars/Olympus_Mons`).Schedule,
+		},
+		{
+			name:        "schedule with an invalid backup name template fails validation",
+			schedule:    NewTestSchedule("ns", "name").WithPhase(api.SchedulePhaseNew).WithCronSchedule("0 9 * * *").WithBackupNameTemplate("{schedule}-{zone}").Schedule,
+			expectedErr: false,
+			expectedSchedulePhaseUpdate: NewTestSchedule("ns", "name").WithPhase(api.SchedulePhaseFailedValidation).WithCronSchedule("0 9 * * *").WithBackupNameTemplate("{schedule}-{zone}").
+				WithValidationError(`invalid backup name template: unknown placeholder {zone} in "{schedule}-{zone}"`).Schedule,
+		},
 		{
 			name:                        "schedule with phase New gets validated and triggers a backup",
 			schedule:                    NewTestSchedule("ns", "name").WithPhase(api.SchedulePhaseNew).WithCronSchedule("@every 5m").Schedule,
 			fakeClockTime:               "2017-01-01 12:00:00",
 			expectedErr:                 false,
 			expectedSchedulePhaseUpdate: NewTestSchedule("ns", "name").WithPhase(api.SchedulePhaseEnabled).WithCronSchedule("@every 5m").Schedule,
-			expectedBackupCreate:        NewTestBackup().WithNamespace("ns").WithName("name-20170101120000").WithLabel("ark-schedule", "name").Backup,
+			expectedBackupCreate: NewTestBackup().WithNamespace("ns").WithName("name-20170101120000").WithLabel("ark-schedule", "name").
+				WithOwnerReference(schedule.OwnerReference(NewTestSchedule("ns", "name").Schedule)).Backup,
 			expectedScheduleLastBackupUpdate: NewTestSchedule("ns", "name").WithPhase(api.SchedulePhaseEnabled).
-				WithCronSchedule("@every 5m").WithLastBackupTime("2017-01-01 12:00:00").Schedule,
+				WithCronSchedule("@every 5m").WithLastBackupTime("2017-01-01 12:00:00").
+				WithLastBackup("name-20170101120000", api.BackupPhaseNew).WithNextRunTime("2017-01-01 12:05:00").Schedule,
 		},
 		{
-			name:                 "schedule with phase Enabled gets re-validated and triggers a backup if valid",
-			schedule:             NewTestSchedule("ns", "name").WithPhase(api.SchedulePhaseEnabled).WithCronSchedule("@every 5m").Schedule,
-			fakeClockTime:        "2017-01-01 12:00:00",
-			expectedErr:          false,
-			expectedBackupCreate: NewTestBackup().WithNamespace("ns").WithName("name-20170101120000").WithLabel("ark-schedule", "name").Backup,
+			name:          "schedule with phase Enabled gets re-validated and triggers a backup if valid",
+			schedule:      NewTestSchedule("ns", "name").WithPhase(api.SchedulePhaseEnabled).WithCronSchedule("@every 5m").Schedule,
+			fakeClockTime: "2017-01-01 12:00:00",
+			expectedErr:   false,
+			expectedBackupCreate: NewTestBackup().WithNamespace("ns").WithName("name-20170101120000").WithLabel("ark-schedule", "name").
+				WithOwnerReference(schedule.OwnerReference(NewTestSchedule("ns", "name").Schedule)).Backup,
 			expectedScheduleLastBackupUpdate: NewTestSchedule("ns", "name").WithPhase(api.SchedulePhaseEnabled).
-				WithCronSchedule("@every 5m").WithLastBackupTime("2017-01-01 12:00:00").Schedule,
+				WithCronSchedule("@every 5m").WithLastBackupTime("2017-01-01 12:00:00").
+				WithLastBackup("name-20170101120000", api.BackupPhaseNew).WithNextRunTime("2017-01-01 12:05:00").Schedule,
+		},
+		{
+			name:          "paused schedule doesn't trigger a backup",
+			schedule:      NewTestSchedule("ns", "name").WithPhase(api.SchedulePhaseEnabled).WithCronSchedule("@every 5m").WithPaused(true).Schedule,
+			fakeClockTime: "2017-01-01 12:00:00",
+			expectedErr:   false,
 		},
 		{
 			name: "schedule that's already run gets LastBackup updated",
 			schedule: NewTestSchedule("ns", "name").WithPhase(api.SchedulePhaseEnabled).
 				WithCronSchedule("@every 5m").WithLastBackupTime("2000-01-01 00:00:00").Schedule,
-			fakeClockTime:        "2017-01-01 12:00:00",
-			expectedErr:          false,
-			expectedBackupCreate: NewTestBackup().WithNamespace("ns").WithName("name-20170101120000").WithLabel("ark-schedule", "name").Backup,
+			fakeClockTime: "2017-01-01 12:00:00",
+			expectedErr:   false,
+			expectedBackupCreate: NewTestBackup().WithNamespace("ns").WithName("name-20170101120000").WithLabel("ark-schedule", "name").
+				WithOwnerReference(schedule.OwnerReference(NewTestSchedule("ns", "name").Schedule)).Backup,
 			expectedScheduleLastBackupUpdate: NewTestSchedule("ns", "name").WithPhase(api.SchedulePhaseEnabled).
-				WithCronSchedule("@every 5m").WithLastBackupTime("2017-01-01 12:00:00").Schedule,
+				WithCronSchedule("@every 5m").WithLastBackupTime("2017-01-01 12:00:00").
+				WithLastBackup("name-20170101120000", api.BackupPhaseNew).WithNextRunTime("2017-01-01 12:05:00").Schedule,
+		},
+		{
+			name: "schedule that isn't due records its last backup's result and next run time",
+			schedule: NewTestSchedule("ns", "name").WithPhase(api.SchedulePhaseEnabled).WithCronSchedule("@every 5m").
+				WithLastBackupTime("2017-01-01 12:00:00").WithLastBackup("name-20170101120000", api.BackupPhaseNew).Schedule,
+			backups: []*api.Backup{
+				NewTestBackup().WithNamespace("ns").WithName("name-20170101120000").WithLabel("ark-schedule", "name").
+					WithPhase(api.BackupPhaseCompleted).WithCompletionTimestamp(time.Date(2017, 1, 1, 12, 1, 0, 0, time.UTC)).Backup,
+			},
+			fakeClockTime: "2017-01-01 12:02:00",
+			expectedErr:   false,
+			expectedScheduleStatusUpdate: NewTestSchedule("ns", "name").WithPhase(api.SchedulePhaseEnabled).WithCronSchedule("@every 5m").
+				WithLastBackupTime("2017-01-01 12:00:00").WithLastBackup("name-20170101120000", api.BackupPhaseCompleted).
+				WithLastBackupCompletionTime("2017-01-01 12:01:00").Schedule,
+			expectedScheduleLastBackupUpdate: NewTestSchedule("ns", "name").WithPhase(api.SchedulePhaseEnabled).WithCronSchedule("@every 5m").
+				WithLastBackupTime("2017-01-01 12:00:00").WithLastBackup("name-20170101120000", api.BackupPhaseCompleted).
+				WithLastBackupCompletionTime("2017-01-01 12:01:00").WithNextRunTime("2017-01-01 12:05:00").Schedule,
+		},
+		{
+			name:        "schedule with an invalid concurrency policy fails validation",
+			schedule:    NewTestSchedule("ns", "name").WithPhase(api.SchedulePhaseNew).WithCronSchedule("@every 5m").WithConcurrencyPolicy("Queue").Schedule,
+			expectedErr: false,
+			expectedSchedulePhaseUpdate: NewTestSchedule("ns", "name").WithPhase(api.SchedulePhaseFailedValidation).WithCronSchedule("@every 5m").WithConcurrencyPolicy("Queue").
+				WithValidationError(`invalid concurrency policy "Queue"`).Schedule,
+		},
+		{
+			name:        "schedule with a retention policy that keeps nothing fails validation",
+			schedule:    NewTestSchedule("ns", "name").WithPhase(api.SchedulePhaseNew).WithCronSchedule("@every 5m").WithRetention(api.RetentionPolicy{}).Schedule,
+			expectedErr: false,
+			expectedSchedulePhaseUpdate: NewTestSchedule("ns", "name").WithPhase(api.SchedulePhaseFailedValidation).WithCronSchedule("@every 5m").WithRetention(api.RetentionPolicy{}).
+				WithValidationError("retention must keep at least one backup").Schedule,
+		},
+		{
+			name: "schedule with concurrency policy Forbid skips a run while its last backup is running",
+			schedule: NewTestSchedule("ns", "name").WithPhase(api.SchedulePhaseEnabled).WithCronSchedule("@every 5m").WithConcurrencyPolicy(api.ConcurrencyPolicyForbid).
+				WithLastBackupTime("2017-01-01 12:00:00").WithLastBackup("name-20170101120000", api.BackupPhaseInProgress).Schedule,
+			backups: []*api.Backup{
+				NewTestBackup().WithNamespace("ns").WithName("name-20170101120000").WithLabel("ark-schedule", "name").WithPhase(api.BackupPhaseInProgress).Backup,
+			},
+			fakeClockTime: "2017-01-01 12:05:01",
+			expectedErr:   false,
+			expectedScheduleLastBackupUpdate: NewTestSchedule("ns", "name").WithPhase(api.SchedulePhaseEnabled).WithCronSchedule("@every 5m").WithConcurrencyPolicy(api.ConcurrencyPolicyForbid).
+				WithLastBackupTime("2017-01-01 12:00:00").WithLastBackup("name-20170101120000", api.BackupPhaseInProgress).
+				WithLastSkippedTime("2017-01-01 12:05:01").WithNextRunTime("2017-01-01 12:10:01").Schedule,
+		},
+		{
+			name: "schedule with concurrency policy Forbid runs once its last backup has finished",
+			schedule: NewTestSchedule("ns", "name").WithPhase(api.SchedulePhaseEnabled).WithCronSchedule("@every 5m").WithConcurrencyPolicy(api.ConcurrencyPolicyForbid).
+				WithLastBackupTime("2017-01-01 11:54:00").Schedule,
+			backups: []*api.Backup{
+				NewTestBackup().WithNamespace("ns").WithName("name-20170101115500").WithLabel("ark-schedule", "name").WithPhase(api.BackupPhaseCompleted).Backup,
+			},
+			fakeClockTime: "2017-01-01 12:00:00",
+			expectedErr:   false,
+			expectedBackupCreate: NewTestBackup().WithNamespace("ns").WithName("name-20170101120000").WithLabel("ark-schedule", "name").
+				WithOwnerReference(schedule.OwnerReference(NewTestSchedule("ns", "name").Schedule)).Backup,
+			expectedScheduleLastBackupUpdate: NewTestSchedule("ns", "name").WithPhase(api.SchedulePhaseEnabled).WithCronSchedule("@every 5m").WithConcurrencyPolicy(api.ConcurrencyPolicyForbid).
+				WithLastBackupTime("2017-01-01 12:00:00").WithLastBackup("name-20170101120000", api.BackupPhaseNew).WithNextRunTime("2017-01-01 12:05:00").Schedule,
+		},
+		{
+			name: "schedule with concurrency policy Replace cancels its running backups",
+			schedule: NewTestSchedule("ns", "name").WithPhase(api.SchedulePhaseEnabled).WithCronSchedule("@every 5m").WithConcurrencyPolicy(api.ConcurrencyPolicyReplace).
+				WithLastBackupTime("2017-01-01 11:54:00").Schedule,
+			backups: []*api.Backup{
+				NewTestBackup().WithNamespace("ns").WithName("name-20170101115500").WithLabel("ark-schedule", "name").WithPhase(api.BackupPhaseInProgress).Backup,
+				NewTestBackup().WithNamespace("ns").WithName("other").WithPhase(api.BackupPhaseInProgress).Backup,
+			},
+			fakeClockTime:         "2017-01-01 12:00:00",
+			expectedErr:           false,
+			expectedBackupCancels: []string{"name-20170101115500"},
+			expectedBackupCreate: NewTestBackup().WithNamespace("ns").WithName("name-20170101120000").WithLabel("ark-schedule", "name").
+				WithOwnerReference(schedule.OwnerReference(NewTestSchedule("ns", "name").Schedule)).Backup,
+			expectedScheduleLastBackupUpdate: NewTestSchedule("ns", "name").WithPhase(api.SchedulePhaseEnabled).WithCronSchedule("@every 5m").WithConcurrencyPolicy(api.ConcurrencyPolicyReplace).
+				WithLastBackupTime("2017-01-01 12:00:00").WithLastBackup("name-20170101120000", api.BackupPhaseNew).WithNextRunTime("2017-01-01 12:05:00").Schedule,
+		},
+		{
+			name: "schedule whose status is current isn't updated",
+			schedule: NewTestSchedule("ns", "name").WithPhase(api.SchedulePhaseEnabled).WithCronSchedule("@every 5m").
+				WithLastBackupTime("2017-01-01 12:00:00").WithLastBackup("name-20170101120000", api.BackupPhaseInProgress).
+				WithNextRunTime("2017-01-01 12:05:00").Schedule,
+			backups: []*api.Backup{
+				NewTestBackup().WithNamespace("ns").WithName("name-20170101120000").WithLabel("ark-schedule", "name").
+					WithPhase(api.BackupPhaseInProgress).Backup,
+			},
+			fakeClockTime: "2017-01-01 12:02:00",
+			expectedErr:   false,
 		},
 	}
 
@@ -129,7 +246,9 @@ func TestProcessSchedule(t *testing.T) {
 				client.ArkV1(),
 				client.ArkV1(),
 				sharedInformers.Ark().V1().Schedules(),
+				sharedInformers.Ark().V1().Backups(),
 				time.Duration(0),
+				"cluster-1",
 			)
 
 			var (
@@ -142,6 +261,10 @@ func TestProcessSchedule(t *testing.T) {
 			}
 			c.clock = clock.NewFakeClock(testTime)
 
+			for _, backup := range test.backups {
+				sharedInformers.Ark().V1().Backups().Informer().GetStore().Add(backup)
+			}
+
 			if test.schedule != nil {
 				sharedInformers.Ark().V1().Schedules().Informer().GetStore().Add(test.schedule)
 
@@ -178,6 +301,23 @@ func TestProcessSchedule(t *testing.T) {
 				expectedActions = append(expectedActions, action)
 			}
 
+			if upd := test.expectedScheduleStatusUpdate; upd != nil {
+				action := core.NewUpdateAction(
+					api.SchemeGroupVersion.WithResource("schedules"),
+					upd.Namespace,
+					upd)
+				expectedActions = append(expectedActions, action)
+			}
+
+			for _, name := range test.expectedBackupCancels {
+				action := core.NewPatchAction(
+					api.SchemeGroupVersion.WithResource("backups"),
+					"ns",
+					name,
+					[]byte(`{"metadata":{"annotations":{"ark.heptio.com/cancel":"true"}}}`))
+				expectedActions = append(expectedActions, action)
+			}
+
 			if created := test.expectedBackupCreate; created != nil {
 				action := core.NewCreateAction(
 					api.SchemeGroupVersion.WithResource("backups"),
@@ -272,6 +412,40 @@ func TestGetNextRunTime(t *testing.T) {
 	}
 }
 
+func TestGetNextRunTimeWithJitter(t *testing.T) {
+	cronSchedule, err := cron.ParseStandard("0 0 * * *")
+	require.NoError(t, err)
+
+	lastBackup := time.Date(2017, 1, 1, 0, 10, 0, 0, time.UTC)
+	scheduledTime := time.Date(2017, 1, 2, 0, 0, 0, 0, time.UTC)
+
+	offsets := make(map[time.Duration]bool)
+	for i := 0; i < 10; i++ {
+		schedule := NewTestSchedule("ns", fmt.Sprintf("schedule-%d", i)).WithLastBackupTime("2017-01-01 00:10:00").Schedule
+		schedule.Spec.Jitter.Duration = time.Hour
+
+		due, nextRunTime := getNextRunTime(schedule, cronSchedule, lastBackup)
+		assert.False(t, due)
+
+		// runs start within the jitter window
+		offset := nextRunTime.Sub(scheduledTime)
+		assert.True(t, offset >= 0 && offset < time.Hour, "offset %v is outside the jitter window", offset)
+		offsets[offset] = true
+
+		// the same run gets the same offset each time
+		_, again := getNextRunTime(schedule, cronSchedule, lastBackup.Add(time.Minute))
+		assert.Equal(t, nextRunTime, again)
+
+		// the run after a jittered one is at the next scheduled time
+		schedule.Status.LastBackup = metav1.NewTime(nextRunTime.Add(time.Second))
+		_, following := getNextRunTime(schedule, cronSchedule, nextRunTime.Add(time.Second))
+		assert.True(t, !following.Before(scheduledTime.AddDate(0, 0, 1)), "following run %v is before the next scheduled time", following)
+	}
+
+	// different schedules' runs are spread out
+	assert.True(t, len(offsets) > 1, "all schedules got the same offset")
+}
+
 func TestParseCronSchedule(t *testing.T) {
 	// From https://github.com/heptio/ark/issues/30, where we originally were using cron.Parse(),
 	// which treats the first field as seconds, and not minutes. We want to use cron.ParseStandard()
@@ -321,99 +495,86 @@ func TestParseCronSchedule(t *testing.T) {
 	assert.Equal(t, time.Date(2017, 8, 12, 9, 0, 0, 0, time.UTC), next)
 }
 
-func TestGetBackup(t *testing.T) {
+func TestZonedSchedule(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	require.NoError(t, err)
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	require.NoError(t, err)
+
 	tests := []struct {
-		name           string
-		schedule       *api.Schedule
-		testClockTime  string
-		expectedBackup *api.Backup
+		name     string
+		schedule string
+		location *time.Location
+		after    time.Time
+		expected time.Time
 	}{
 		{
-			name: "ensure name is formatted correctly (AM time)",
-			schedule: &api.Schedule{
-				ObjectMeta: metav1.ObjectMeta{
-					Namespace: "foo",
-					Name:      "bar",
-				},
-				Spec: api.ScheduleSpec{
-					Template: api.BackupSpec{},
-				},
-			},
-			testClockTime: "2017-07-25 09:15:00",
-			expectedBackup: &api.Backup{
-				ObjectMeta: metav1.ObjectMeta{
-					Namespace: "foo",
-					Name:      "bar-20170725091500",
-				},
-				Spec: api.BackupSpec{},
-			},
+			name:     "schedules run at wall clock times in their time zone",
+			schedule: "30 2 * * *",
+			location: newYork,
+			after:    time.Date(2017, 8, 10, 12, 0, 0, 0, time.UTC),
+			expected: time.Date(2017, 8, 11, 6, 30, 0, 0, time.UTC),
 		},
 		{
-			name: "ensure name is formatted correctly (PM time)",
-			schedule: &api.Schedule{
-				ObjectMeta: metav1.ObjectMeta{
-					Namespace: "foo",
-					Name:      "bar",
-				},
-				Spec: api.ScheduleSpec{
-					Template: api.BackupSpec{},
-				},
-			},
-			testClockTime: "2017-07-25 14:15:00",
-			expectedBackup: &api.Backup{
-				ObjectMeta: metav1.ObjectMeta{
-					Namespace: "foo",
-					Name:      "bar-20170725141500",
-				},
-				Spec: api.BackupSpec{},
-			},
+			name:     "wall clock times don't move when daylight saving time ends",
+			schedule: "30 2 * * *",
+			location: newYork,
+			after:    time.Date(2017, 11, 5, 12, 0, 0, 0, newYork),
+			expected: time.Date(2017, 11, 6, 7, 30, 0, 0, time.UTC),
 		},
 		{
-			name: "ensure schedule backup template is copied",
-			schedule: &api.Schedule{
-				ObjectMeta: metav1.ObjectMeta{
-					Namespace: "foo",
-					Name:      "bar",
-				},
-				Spec: api.ScheduleSpec{
-					Template: api.BackupSpec{
-						IncludedNamespaces: []string{"ns-1", "ns-2"},
-						ExcludedNamespaces: []string{"ns-3"},
-						IncludedResources:  []string{"foo", "bar"},
-						ExcludedResources:  []string{"baz"},
-						LabelSelector:      &metav1.LabelSelector{MatchLabels: map[string]string{"label": "value"}},
-						TTL:                metav1.Duration{Duration: time.Duration(300)},
-					},
-				},
-			},
-			testClockTime: "2017-07-25 09:15:00",
-			expectedBackup: &api.Backup{
-				ObjectMeta: metav1.ObjectMeta{
-					Namespace: "foo",
-					Name:      "bar-20170725091500",
-				},
-				Spec: api.BackupSpec{
-					IncludedNamespaces: []string{"ns-1", "ns-2"},
-					ExcludedNamespaces: []string{"ns-3"},
-					IncludedResources:  []string{"foo", "bar"},
-					ExcludedResources:  []string{"baz"},
-					LabelSelector:      &metav1.LabelSelector{MatchLabels: map[string]string{"label": "value"}},
-					TTL:                metav1.Duration{Duration: time.Duration(300)},
-				},
-			},
+			name:     "wall clock times skipped when the clocks go forward run an hour later",
+			schedule: "30 2 * * *",
+			location: newYork,
+			after:    time.Date(2018, 3, 10, 3, 0, 0, 0, newYork),
+			expected: time.Date(2018, 3, 11, 3, 30, 0, 0, newYork),
+		},
+		{
+			name:     "the day after the clocks go forward, schedules run as usual",
+			schedule: "30 2 * * *",
+			location: newYork,
+			after:    time.Date(2018, 3, 11, 3, 30, 30, 0, newYork),
+			expected: time.Date(2018, 3, 12, 2, 30, 0, 0, newYork),
+		},
+		{
+			name:     "wall clock times repeated when the clocks go back run the first time",
+			schedule: "30 1 * * *",
+			location: newYork,
+			after:    time.Date(2017, 11, 4, 12, 0, 0, 0, newYork),
+			// 1:30 EDT
+			expected: time.Date(2017, 11, 5, 5, 30, 0, 0, time.UTC),
+		},
+		{
+			name:     "wall clock times repeated when the clocks go back don't run again",
+			schedule: "30 1 * * *",
+			location: newYork,
+			after:    time.Date(2017, 11, 5, 5, 30, 30, 0, time.UTC),
+			expected: time.Date(2017, 11, 6, 1, 30, 0, 0, newYork),
+		},
+		{
+			name:     "repeated wall clock times that map to before the last run are skipped",
+			schedule: "*/30 * * * *",
+			location: newYork,
+			// 1:10 EST, after 1:30 EDT
+			after:    time.Date(2017, 11, 5, 6, 10, 0, 0, time.UTC),
+			expected: time.Date(2017, 11, 5, 2, 0, 0, 0, newYork),
+		},
+		{
+			name:     "repeated wall clock times run once in time zones ahead of UTC",
+			schedule: "30 2 * * *",
+			location: berlin,
+			after:    time.Date(2017, 10, 29, 2, 0, 0, 0, time.UTC),
+			expected: time.Date(2017, 10, 30, 2, 30, 0, 0, berlin),
 		},
 	}
 
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
-			testTime, err := time.Parse("2006-01-02 15:04:05", test.testClockTime)
-			require.NoError(t, err, "unable to parse test.testClockTime: %v", err)
-
-			backup := getBackup(test.schedule, clock.NewFakeClock(testTime).Now())
+			s, errs := parseCronSchedule(&api.Schedule{Spec: api.ScheduleSpec{Schedule: test.schedule, Timezone: test.location.String()}})
+			require.Empty(t, errs)
 
-			assert.Equal(t, test.expectedBackup.Namespace, backup.Namespace)
-			assert.Equal(t, test.expectedBackup.Name, backup.Name)
-			assert.Equal(t, test.expectedBackup.Spec, backup.Spec)
+			next := s.Next(test.after)
+			assert.True(t, test.expected.Equal(next), "expected %v, got %v", test.expected, next.UTC())
 		})
 	}
 }
@@ -0,0 +1,258 @@
+/*
+Copyright 2017 Heptio Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/clock"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+
+	api "github.com/heptio/ark/pkg/apis/ark/v1"
+	"github.com/heptio/ark/pkg/generated/clientset/scheme"
+	arkv1client "github.com/heptio/ark/pkg/generated/clientset/typed/ark/v1"
+	informers "github.com/heptio/ark/pkg/generated/informers/externalversions/ark/v1"
+	listers "github.com/heptio/ark/pkg/generated/listers/ark/v1"
+)
+
+const (
+	// serverStatusRequestTTL is how long processed ServerStatusRequests are kept before they're
+	// deleted, in case their clients didn't delete them.
+	serverStatusRequestTTL = time.Minute
+
+	// serverStatusRequestResyncPeriod is how often ServerStatusRequests are checked for expiration.
+	serverStatusRequestResyncPeriod = time.Minute
+)
+
+type serverStatusRequestController struct {
+	serverStatusRequestClient arkv1client.ServerStatusRequestsGetter
+	serverVersion             string
+	serverGitSHA              string
+
+	serverStatusRequestLister       listers.ServerStatusRequestLister
+	serverStatusRequestListerSynced cache.InformerSynced
+	syncHandler                     func(key string) error
+	queue                           workqueue.RateLimitingInterface
+
+	clock clock.Clock
+}
+
+// NewServerStatusRequestController returns a controller that fulfills ServerStatusRequests by
+// recording the server's version and git commit in them.
+func NewServerStatusRequestController(
+	serverStatusRequestClient arkv1client.ServerStatusRequestsGetter,
+	serverStatusRequestInformer informers.ServerStatusRequestInformer,
+	serverVersion string,
+	serverGitSHA string,
+) Interface {
+	c := &serverStatusRequestController{
+		serverStatusRequestClient:       serverStatusRequestClient,
+		serverVersion:                   serverVersion,
+		serverGitSHA:                    serverGitSHA,
+		serverStatusRequestLister:       serverStatusRequestInformer.Lister(),
+		serverStatusRequestListerSynced: serverStatusRequestInformer.Informer().HasSynced,
+		queue:                           workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "serverstatusrequest"),
+
+		clock: &clock.RealClock{},
+	}
+
+	c.syncHandler = c.processServerStatusRequest
+
+	serverStatusRequestInformer.Informer().AddEventHandler(
+		cache.ResourceEventHandlerFuncs{
+			AddFunc: func(obj interface{}) {
+				key, err := cache.MetaNamespaceKeyFunc(obj)
+				if err != nil {
+					serverStatusRequest := obj.(*api.ServerStatusRequest)
+					glog.Errorf("error creating queue key for %#v: %v", serverStatusRequest, err)
+					return
+				}
+				c.queue.Add(key)
+			},
+		},
+	)
+
+	return c
+}
+
+// Run is a blocking function that runs the specified number of worker goroutines
+// to process items in the work queue. It will return when it receives on the
+// ctx.Done() channel.
+func (c *serverStatusRequestController) Run(ctx context.Context, numWorkers int) error {
+	var wg sync.WaitGroup
+
+	defer func() {
+		glog.Infof("Waiting for workers to finish their work")
+
+		c.queue.ShutDown()
+
+		// We have to wait here in the deferred function instead of at the bottom of the function body
+		// because we have to shut down the queue in order for the workers to shut down gracefully, and
+		// we want to shut down the queue via defer and not at the end of the body.
+		wg.Wait()
+
+		glog.Infof("All workers have finished")
+	}()
+
+	glog.Info("Starting ServerStatusRequestController")
+	defer glog.Infof("Shutting down ServerStatusRequestController")
+
+	glog.Info("Waiting for caches to sync")
+	if !cache.WaitForCacheSync(ctx.Done(), c.serverStatusRequestListerSynced) {
+		return errors.New("timed out waiting for caches to sync")
+	}
+	glog.Info("Caches are synced")
+
+	wg.Add(numWorkers)
+	for i := 0; i < numWorkers; i++ {
+		go func() {
+			wait.Until(c.runWorker, time.Second, ctx.Done())
+			wg.Done()
+		}()
+	}
+
+	wg.Add(1)
+	go func() {
+		wait.Until(c.resync, serverStatusRequestResyncPeriod, ctx.Done())
+		wg.Done()
+	}()
+
+	<-ctx.Done()
+
+	return nil
+}
+
+// resync requeues all the ServerStatusRequests, so that expired ones are deleted.
+func (c *serverStatusRequestController) resync() {
+	list, err := c.serverStatusRequestLister.List(labels.Everything())
+	if err != nil {
+		glog.Errorf("error listing server status requests: %v", err)
+		return
+	}
+
+	for _, req := range list {
+		key, err := cache.MetaNamespaceKeyFunc(req)
+		if err != nil {
+			glog.Errorf("error generating key for server status request %s/%s: %v", req.Namespace, req.Name, err)
+			continue
+		}
+
+		c.queue.Add(key)
+	}
+}
+
+func (c *serverStatusRequestController) runWorker() {
+	// continually take items off the queue (waits if it's
+	// empty) until we get a shutdown signal from the queue
+	for c.processNextWorkItem() {
+	}
+}
+
+func (c *serverStatusRequestController) processNextWorkItem() bool {
+	key, quit := c.queue.Get()
+	if quit {
+		return false
+	}
+	// always call done on this item, since if it fails we'll add
+	// it back with rate-limiting below
+	defer c.queue.Done(key)
+
+	err := c.syncHandler(key.(string))
+	if err == nil {
+		// If you had no error, tell the queue to stop tracking history for your key. This will reset
+		// things like failure counts for per-item rate limiting.
+		c.queue.Forget(key)
+		return true
+	}
+
+	glog.Errorf("syncHandler error: %v", err)
+	// we had an error processing the item so add it back
+	// into the queue for re-processing with rate-limiting
+	c.queue.AddRateLimited(key)
+
+	return true
+}
+
+// processServerStatusRequest records the server's status in a new ServerStatusRequest, and
+// deletes a processed one that has expired.
+func (c *serverStatusRequestController) processServerStatusRequest(key string) error {
+	glog.V(4).Infof("processServerStatusRequest for key %q", key)
+	ns, name, err := cache.SplitMetaNamespaceKey(key)
+	if err != nil {
+		glog.V(4).Infof("error splitting key %q: %v", key, err)
+		return err
+	}
+
+	serverStatusRequest, err := c.serverStatusRequestLister.ServerStatusRequests(ns).Get(name)
+	if apierrors.IsNotFound(err) {
+		glog.V(4).Infof("unable to find server status request %q: %v", key, err)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("error getting server status request %q: %v", key, err)
+	}
+
+	switch serverStatusRequest.Status.Phase {
+	case "", api.ServerStatusRequestPhaseNew:
+		clone, err := cloneServerStatusRequest(serverStatusRequest)
+		if err != nil {
+			return err
+		}
+
+		clone.Status.ServerVersion = c.serverVersion
+		clone.Status.ServerGitSHA = c.serverGitSHA
+		clone.Status.Phase = api.ServerStatusRequestPhaseProcessed
+		clone.Status.ProcessedTimestamp = metav1.NewTime(c.clock.Now())
+
+		_, err = c.serverStatusRequestClient.ServerStatusRequests(clone.Namespace).Update(clone)
+		return err
+	case api.ServerStatusRequestPhaseProcessed:
+		if c.clock.Now().Before(serverStatusRequest.Status.ProcessedTimestamp.Add(serverStatusRequestTTL)) {
+			return nil
+		}
+
+		glog.V(4).Infof("%s/%s has expired - deleting", serverStatusRequest.Namespace, serverStatusRequest.Name)
+		return c.serverStatusRequestClient.ServerStatusRequests(serverStatusRequest.Namespace).Delete(serverStatusRequest.Name, nil)
+	}
+
+	return nil
+}
+
+func cloneServerStatusRequest(in interface{}) (*api.ServerStatusRequest, error) {
+	clone, err := scheme.Scheme.DeepCopy(in)
+	if err != nil {
+		return nil, err
+	}
+
+	out, ok := clone.(*api.ServerStatusRequest)
+	if !ok {
+		return nil, fmt.Errorf("unexpected type: %T", clone)
+	}
+
+	return out, nil
+}
@@ -0,0 +1,115 @@
+/*
+Copyright 2017 Heptio Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/clock"
+
+	api "github.com/heptio/ark/pkg/apis/ark/v1"
+	"github.com/heptio/ark/pkg/generated/clientset/fake"
+	informers "github.com/heptio/ark/pkg/generated/informers/externalversions"
+)
+
+func TestProcessServerStatusRequest(t *testing.T) {
+	now := time.Date(2017, 10, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name              string
+		phase             api.ServerStatusRequestPhase
+		processed         time.Time
+		expectedProcessed bool
+		expectDeleted     bool
+	}{
+		{
+			name:              "request with phase '' gets the server version",
+			expectedProcessed: true,
+		},
+		{
+			name:              "request with phase New gets the server version",
+			phase:             api.ServerStatusRequestPhaseNew,
+			expectedProcessed: true,
+		},
+		{
+			name:      "recently processed request is kept",
+			phase:     api.ServerStatusRequestPhaseProcessed,
+			processed: now.Add(-30 * time.Second),
+		},
+		{
+			name:          "expired request is deleted",
+			phase:         api.ServerStatusRequestPhaseProcessed,
+			processed:     now.Add(-2 * time.Minute),
+			expectDeleted: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			client := fake.NewSimpleClientset()
+			sharedInformers := informers.NewSharedInformerFactory(client, 0)
+			serverStatusRequestsInformer := sharedInformers.Ark().V1().ServerStatusRequests()
+
+			c := NewServerStatusRequestController(
+				client.ArkV1(),
+				serverStatusRequestsInformer,
+				"v1.0.0",
+				"abc123",
+			).(*serverStatusRequestController)
+			c.clock = clock.NewFakeClock(now)
+
+			req := &api.ServerStatusRequest{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: api.DefaultNamespace,
+					Name:      "a-server-status-request",
+				},
+				Status: api.ServerStatusRequestStatus{
+					Phase:              test.phase,
+					ProcessedTimestamp: metav1.NewTime(test.processed),
+				},
+			}
+			serverStatusRequestsInformer.Informer().GetStore().Add(req)
+			_, err := client.ArkV1().ServerStatusRequests(req.Namespace).Create(req)
+			require.NoError(t, err)
+
+			require.NoError(t, c.processServerStatusRequest("heptio-ark/a-server-status-request"))
+
+			res, err := client.ArkV1().ServerStatusRequests(req.Namespace).Get(req.Name, metav1.GetOptions{})
+			if test.expectDeleted {
+				assert.True(t, apierrors.IsNotFound(err), "expected server status request to be deleted, got %v", err)
+				return
+			}
+			require.NoError(t, err)
+
+			if !test.expectedProcessed {
+				assert.Equal(t, req, res)
+				return
+			}
+
+			assert.Equal(t, api.ServerStatusRequestPhaseProcessed, res.Status.Phase)
+			assert.Equal(t, "v1.0.0", res.Status.ServerVersion)
+			assert.Equal(t, "abc123", res.Status.ServerGitSHA)
+			assert.Equal(t, now, res.Status.ProcessedTimestamp.Time)
+		})
+	}
+}
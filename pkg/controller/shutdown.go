@@ -0,0 +1,50 @@
+/*
+Copyright 2017 Heptio Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"sync"
+	"time"
+)
+
+// DefaultTerminationGracePeriod is how long the server has to stop once it receives SIGTERM
+// before it's killed. It matches the terminationGracePeriodSeconds of the example deployments.
+const DefaultTerminationGracePeriod = 60 * time.Second
+
+// shutdownTimeouts divides the termination grace period of a stopping server between waiting for
+// running backups and restores to finish, which gets the first half, and then waiting for the
+// backups that are being uploaded, which gets the next quarter. The last quarter is left for
+// cleaning up after the backups that are interrupted and recording their status.
+func shutdownTimeouts(gracePeriod time.Duration) (run, upload time.Duration) {
+	return gracePeriod / 2, gracePeriod / 4
+}
+
+// waitTimeout waits for wg, and returns false if timeout passes first.
+func waitTimeout(wg *sync.WaitGroup, timeout time.Duration) bool {
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return true
+	case <-time.After(timeout):
+		return false
+	}
+}
@@ -0,0 +1,172 @@
+/*
+Copyright 2017 Heptio Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/golang/glog"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/clock"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/tools/cache"
+
+	api "github.com/heptio/ark/pkg/apis/ark/v1"
+	"github.com/heptio/ark/pkg/cloudprovider"
+	"github.com/heptio/ark/pkg/generated/clientset/scheme"
+	arkv1client "github.com/heptio/ark/pkg/generated/clientset/typed/ark/v1"
+	informers "github.com/heptio/ark/pkg/generated/informers/externalversions/ark/v1"
+	listers "github.com/heptio/ark/pkg/generated/listers/ark/v1"
+)
+
+// storageLocationProbeKey is the key of the object that's written to, and deleted from, each
+// read-write backup storage location to check that it can be. It's at the top level of the
+// location, where only backups' directories are listed, so it's never mistaken for a backup.
+const storageLocationProbeKey = "ark-location-probe"
+
+// Reasons a backup storage location is unavailable.
+const (
+	storageLocationReasonListFailed   = "ListFailed"
+	storageLocationReasonWriteFailed  = "WriteFailed"
+	storageLocationReasonDeleteFailed = "DeleteFailed"
+)
+
+// storageLocationController periodically checks that each of the server's backup storage
+// locations can be listed and, unless it's read-only, written to, and records the result in the
+// BackupStorageLocation's status.
+type storageLocationController struct {
+	objectStorage map[string]cloudprovider.ObjectStorageAdapter
+	probePeriod   time.Duration
+	clock         clock.Clock
+	lister        listers.BackupStorageLocationLister
+	listerSynced  cache.InformerSynced
+	client        arkv1client.BackupStorageLocationsGetter
+}
+
+// NewStorageLocationController constructs a new storageLocationController. objectStorage has the
+// ObjectStorageAdapter of each of the server's backup storage locations, by name.
+func NewStorageLocationController(
+	objectStorage map[string]cloudprovider.ObjectStorageAdapter,
+	probePeriod time.Duration,
+	locationInformer informers.BackupStorageLocationInformer,
+	client arkv1client.BackupStorageLocationsGetter,
+) Interface {
+	if probePeriod < 10*time.Second {
+		glog.Infof("Storage location probe period %v is too short. Setting to 10 seconds", probePeriod)
+		probePeriod = 10 * time.Second
+	}
+
+	return &storageLocationController{
+		objectStorage: objectStorage,
+		probePeriod:   probePeriod,
+		clock:         clock.RealClock{},
+		lister:        locationInformer.Lister(),
+		listerSynced:  locationInformer.Informer().HasSynced,
+		client:        client,
+	}
+}
+
+var _ Interface = &storageLocationController{}
+
+// Run is a blocking function that runs a single worker to check the backup storage locations.
+// It will return when it receives on the ctx.Done() channel.
+func (c *storageLocationController) Run(ctx context.Context, workers int) error {
+	glog.Info("Waiting for caches to sync")
+	if !cache.WaitForCacheSync(ctx.Done(), c.listerSynced) {
+		return errors.New("timed out waiting for caches to sync")
+	}
+	glog.Info("Caches are synced")
+
+	wait.Until(c.run, c.probePeriod, ctx.Done())
+	return nil
+}
+
+func (c *storageLocationController) run() {
+	locations, err := c.lister.BackupStorageLocations(api.DefaultNamespace).List(labels.Everything())
+	if err != nil {
+		glog.Errorf("error listing backup storage locations: %v", err)
+		return
+	}
+
+	for _, location := range locations {
+		objectStorage, ok := c.objectStorage[location.Name]
+		if !ok {
+			// the location was created after the server started, which restarts it
+			continue
+		}
+		c.updateStatus(location, probeStorageLocation(objectStorage, location))
+	}
+}
+
+// probeStorageLocation lists the top level of location's bucket and, if it's a read-write
+// location, writes an object to it and deletes it. It returns the location's status, without its
+// LastValidationTime.
+func probeStorageLocation(objectStorage cloudprovider.ObjectStorageAdapter, location *api.BackupStorageLocation) api.BackupStorageLocationStatus {
+	unavailable := func(reason string, err error) api.BackupStorageLocationStatus {
+		return api.BackupStorageLocationStatus{
+			Phase:   api.BackupStorageLocationPhaseUnavailable,
+			Reason:  reason,
+			Message: err.Error(),
+		}
+	}
+
+	bucket := location.Spec.Bucket
+	if _, err := objectStorage.ListCommonPrefixes(bucket, "", "/"); err != nil {
+		return unavailable(storageLocationReasonListFailed, fmt.Errorf("error listing bucket %s: %v", bucket, err))
+	}
+
+	if location.Spec.AccessMode != api.BackupStorageLocationAccessModeReadOnly {
+		if err := objectStorage.PutObject(bucket, storageLocationProbeKey, bytes.NewReader([]byte{})); err != nil {
+			return unavailable(storageLocationReasonWriteFailed, fmt.Errorf("error writing to bucket %s: %v", bucket, err))
+		}
+		if err := objectStorage.DeleteObject(bucket, storageLocationProbeKey); err != nil {
+			return unavailable(storageLocationReasonDeleteFailed, fmt.Errorf("error deleting from bucket %s: %v", bucket, err))
+		}
+	}
+
+	return api.BackupStorageLocationStatus{Phase: api.BackupStorageLocationPhaseAvailable}
+}
+
+// updateStatus records status, as of now, in location's status, logging when the location
+// becomes unavailable or available again.
+func (c *storageLocationController) updateStatus(location *api.BackupStorageLocation, status api.BackupStorageLocationStatus) {
+	switch {
+	case status.Phase == api.BackupStorageLocationPhaseUnavailable && status.Message != location.Status.Message:
+		glog.Warningf("Backup storage location %s is unavailable: %s", location.Name, status.Message)
+	case status.Phase == api.BackupStorageLocationPhaseAvailable && location.Status.Phase == api.BackupStorageLocationPhaseUnavailable:
+		glog.Infof("Backup storage location %s is available again", location.Name)
+	}
+
+	obj, err := scheme.Scheme.DeepCopy(location)
+	if err != nil {
+		glog.Errorf("error copying backup storage location %s: %v", location.Name, err)
+		return
+	}
+	updated := obj.(*api.BackupStorageLocation)
+	updated.Status = status
+	updated.Status.LastValidationTime = metav1.NewTime(c.clock.Now())
+
+	if _, err := c.client.BackupStorageLocations(location.Namespace).Update(updated); err != nil {
+		glog.Errorf("error updating status of backup storage location %s: %v", location.Name, err)
+	}
+}
@@ -0,0 +1,185 @@
+/*
+Copyright 2017 Heptio Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/clock"
+
+	api "github.com/heptio/ark/pkg/apis/ark/v1"
+	"github.com/heptio/ark/pkg/cloudprovider"
+	"github.com/heptio/ark/pkg/generated/clientset/fake"
+	informers "github.com/heptio/ark/pkg/generated/informers/externalversions"
+)
+
+// fakeObjectStorage is an ObjectStorageAdapter that records the keys put and deleted, and returns
+// the configured prefixes and errors.
+type fakeObjectStorage struct {
+	cloudprovider.ObjectStorageAdapter
+
+	prefixes                   []string
+	listErr, putErr, deleteErr error
+	put, deleted               []string
+}
+
+func (s *fakeObjectStorage) ListCommonPrefixes(bucket, prefix, delimiter string) ([]string, error) {
+	return s.prefixes, s.listErr
+}
+
+func (s *fakeObjectStorage) PutObject(bucket string, key string, body io.ReadSeeker) error {
+	s.put = append(s.put, key)
+	return s.putErr
+}
+
+func (s *fakeObjectStorage) DeleteObject(bucket string, key string) error {
+	s.deleted = append(s.deleted, key)
+	return s.deleteErr
+}
+
+func TestProbeStorageLocation(t *testing.T) {
+	tests := []struct {
+		name           string
+		storage        *fakeObjectStorage
+		accessMode     api.BackupStorageLocationAccessMode
+		expectedStatus api.BackupStorageLocationStatus
+		expectWrite    bool
+	}{
+		{
+			name:           "location that can be listed and written to is available",
+			storage:        &fakeObjectStorage{},
+			expectedStatus: api.BackupStorageLocationStatus{Phase: api.BackupStorageLocationPhaseAvailable},
+			expectWrite:    true,
+		},
+		{
+			name:    "location that can't be listed is unavailable",
+			storage: &fakeObjectStorage{listErr: errors.New("access denied")},
+			expectedStatus: api.BackupStorageLocationStatus{
+				Phase:   api.BackupStorageLocationPhaseUnavailable,
+				Reason:  "ListFailed",
+				Message: "error listing bucket bucket-1: access denied",
+			},
+		},
+		{
+			name:    "location that can't be written to is unavailable",
+			storage: &fakeObjectStorage{putErr: errors.New("access denied")},
+			expectedStatus: api.BackupStorageLocationStatus{
+				Phase:   api.BackupStorageLocationPhaseUnavailable,
+				Reason:  "WriteFailed",
+				Message: "error writing to bucket bucket-1: access denied",
+			},
+		},
+		{
+			name:    "location that can't be deleted from is unavailable",
+			storage: &fakeObjectStorage{deleteErr: errors.New("access denied")},
+			expectedStatus: api.BackupStorageLocationStatus{
+				Phase:   api.BackupStorageLocationPhaseUnavailable,
+				Reason:  "DeleteFailed",
+				Message: "error deleting from bucket bucket-1: access denied",
+			},
+			expectWrite: true,
+		},
+		{
+			name:           "read-only location isn't written to",
+			storage:        &fakeObjectStorage{putErr: errors.New("access denied")},
+			accessMode:     api.BackupStorageLocationAccessModeReadOnly,
+			expectedStatus: api.BackupStorageLocationStatus{Phase: api.BackupStorageLocationPhaseAvailable},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			location := &api.BackupStorageLocation{
+				ObjectMeta: metav1.ObjectMeta{Namespace: api.DefaultNamespace, Name: "default"},
+				Spec: api.BackupStorageLocationSpec{
+					ObjectStorageProviderConfig: api.ObjectStorageProviderConfig{Bucket: "bucket-1"},
+					AccessMode:                  test.accessMode,
+				},
+			}
+
+			assert.Equal(t, test.expectedStatus, probeStorageLocation(test.storage, location))
+
+			if test.expectWrite {
+				assert.Equal(t, []string{storageLocationProbeKey}, test.storage.deleted)
+			} else {
+				assert.Empty(t, test.storage.deleted)
+			}
+		})
+	}
+}
+
+func TestStorageLocationControllerRun(t *testing.T) {
+	location := func(name string) *api.BackupStorageLocation {
+		return &api.BackupStorageLocation{
+			ObjectMeta: metav1.ObjectMeta{Namespace: api.DefaultNamespace, Name: name},
+			Spec: api.BackupStorageLocationSpec{
+				ObjectStorageProviderConfig: api.ObjectStorageProviderConfig{Bucket: name},
+			},
+		}
+	}
+
+	unavailable := location("unavailable")
+	unavailable.Status = api.BackupStorageLocationStatus{Phase: api.BackupStorageLocationPhaseUnavailable, Reason: "ListFailed", Message: "old error"}
+	locations := []*api.BackupStorageLocation{location("available"), unavailable, location("unconfigured")}
+
+	client := fake.NewSimpleClientset(locations[0], locations[1], locations[2])
+	sharedInformers := informers.NewSharedInformerFactory(client, 0)
+	for _, location := range locations {
+		sharedInformers.Ark().V1().BackupStorageLocations().Informer().GetStore().Add(location)
+	}
+
+	c := NewStorageLocationController(
+		map[string]cloudprovider.ObjectStorageAdapter{
+			"available":   &fakeObjectStorage{},
+			"unavailable": &fakeObjectStorage{putErr: errors.New("bucket deleted")},
+		},
+		time.Minute,
+		sharedInformers.Ark().V1().BackupStorageLocations(),
+		client.ArkV1(),
+	).(*storageLocationController)
+	now := time.Now().Round(time.Second)
+	c.clock = clock.NewFakeClock(now)
+
+	c.run()
+
+	expected := map[string]api.BackupStorageLocationStatus{
+		"available": {
+			Phase:              api.BackupStorageLocationPhaseAvailable,
+			LastValidationTime: metav1.NewTime(now),
+		},
+		"unavailable": {
+			Phase:              api.BackupStorageLocationPhaseUnavailable,
+			Reason:             "WriteFailed",
+			Message:            "error writing to bucket unavailable: bucket deleted",
+			LastValidationTime: metav1.NewTime(now),
+		},
+		// locations created after the server started aren't checked
+		"unconfigured": {},
+	}
+	for name, status := range expected {
+		updated, err := client.ArkV1().BackupStorageLocations(api.DefaultNamespace).Get(name, metav1.GetOptions{})
+		require.NoError(t, err)
+		assert.Equal(t, status, updated.Status, name)
+	}
+}
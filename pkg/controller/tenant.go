@@ -0,0 +1,172 @@
+/*
+Copyright 2017 Heptio Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/tools/cache"
+
+	api "github.com/heptio/ark/pkg/apis/ark/v1"
+	"github.com/heptio/ark/pkg/cloudprovider"
+)
+
+// isTenantNamespace returns whether the backups and restores in namespace are constrained to it,
+// which they are in tenant mode unless namespace is the server's.
+func isTenantNamespace(tenantMode bool, namespace string) bool {
+	return tenantMode && namespace != api.DefaultNamespace
+}
+
+// getTenantValidationErrors returns the validation errors of a backup or restore in a tenant
+// namespace that includes namespaces other than its own, or all cluster-scoped resources.
+func getTenantValidationErrors(namespace string, includedNamespaces []string, includeClusterResources *bool) []string {
+	var validationErrors []string
+
+	for _, included := range includedNamespaces {
+		if included != "*" && included != namespace {
+			validationErrors = append(validationErrors, fmt.Sprintf("Namespace %s can't be included; backups and restores in namespace %s can only include it", included, namespace))
+		}
+	}
+
+	if includeClusterResources != nil && *includeClusterResources {
+		validationErrors = append(validationErrors, fmt.Sprintf("Backups and restores in namespace %s can't include cluster-scoped resources", namespace))
+	}
+
+	return validationErrors
+}
+
+// getQuotaErrors returns the validation errors of a backup in a tenant namespace that would
+// exceed the namespace's quota, and records a metric for each limit that's exceeded. Stored bytes
+// are the backups' tarball sizes; volume data and deduplicated chunks aren't counted.
+func (controller *backupController) getQuotaErrors(backup *api.Backup) []string {
+	quota := controller.tenantQuota
+	if quota == nil {
+		return nil
+	}
+
+	backups, err := controller.lister.Backups(backup.Namespace).List(labels.Everything())
+	if err != nil {
+		return []string{fmt.Sprintf("Error listing backups to check namespace %s's quota: %v", backup.Namespace, err)}
+	}
+
+	var (
+		running     = controller.runningBackupNames(backup.Namespace)
+		stored      = sets.NewString()
+		storedBytes int64
+	)
+	for _, other := range backups {
+		if other.Name == backup.Name {
+			continue
+		}
+
+		switch other.Status.Phase {
+		case api.BackupPhaseInProgress:
+			running.Insert(other.Name)
+			stored.Insert(other.Name)
+		case api.BackupPhaseCompleted, api.BackupPhasePartiallyFailed:
+			stored.Insert(other.Name)
+			storedBytes += other.Status.TarballSize
+		}
+	}
+	running.Delete(backup.Name)
+
+	var validationErrors []string
+	exceeded := func(limit string, format string, args ...interface{}) {
+		validationErrors = append(validationErrors, fmt.Sprintf(format, args...))
+		controller.metrics.RegisterBackupQuotaExceeded(backup.Namespace, limit)
+	}
+
+	if quota.MaxConcurrentBackups > 0 && running.Len() >= quota.MaxConcurrentBackups {
+		exceeded("maxConcurrentBackups", "Namespace %s already has %d backup(s) running, the most its quota allows", backup.Namespace, running.Len())
+	}
+
+	if quota.MaxBackups > 0 && stored.Len() >= quota.MaxBackups {
+		exceeded("maxBackups", "Namespace %s already has %d backup(s) stored, the most its quota allows", backup.Namespace, stored.Len())
+	}
+
+	if maxBytes := quota.MaxStoredBytes.Value(); maxBytes > 0 && storedBytes >= maxBytes {
+		exceeded("maxStoredBytes", "Namespace %s's stored backup tarballs already use %s, the most its quota allows", backup.Namespace, resource.NewQuantity(storedBytes, resource.BinarySI))
+	}
+
+	return validationErrors
+}
+
+// runningBackupNames returns the names of the backups in namespace that the controller is running.
+func (controller *backupController) runningBackupNames(namespace string) sets.String {
+	controller.runningLock.Lock()
+	defer controller.runningLock.Unlock()
+
+	names := sets.NewString()
+	for key := range controller.running {
+		if ns, name, err := cache.SplitMetaNamespaceKey(key); err == nil && ns == namespace {
+			names.Insert(name)
+		}
+	}
+	return names
+}
+
+// reserveBackupName returns an error if backup's name is already used by a backup in another
+// namespace: one in the cluster, one being processed, or one stored in bucket. Otherwise, the
+// name is reserved for backup until releaseBackupName is called, so that another backup with the
+// same name can't be validated while it runs.
+func (controller *backupController) reserveBackupName(backup *api.Backup, bucket string) error {
+	backups, err := controller.lister.List(labels.Everything())
+	if err != nil {
+		return fmt.Errorf("Error listing backups to check that backup name %s is unique: %v", backup.Name, err)
+	}
+	for _, other := range backups {
+		if other.Name == backup.Name && other.Namespace != backup.Namespace {
+			return fmt.Errorf("Backup name %s is already used in namespace %s; backup names must be unique across namespaces", backup.Name, other.Namespace)
+		}
+	}
+
+	// backups whose API objects have been deleted, or that haven't been synced yet, are still
+	// in object storage.
+	stored, err := controller.backupService.GetBackup(bucket, backup.Name)
+	switch {
+	case err == nil && stored.Namespace != backup.Namespace:
+		return fmt.Errorf("Backup name %s is already used in object storage by a backup in namespace %s; backup names must be unique across namespaces", backup.Name, stored.Namespace)
+	case err != nil && !cloudprovider.IsObjectNotFound(err):
+		return fmt.Errorf("Error checking whether backup name %s is used in object storage: %v", backup.Name, err)
+	}
+
+	key := runningKey(backup)
+
+	controller.runningLock.Lock()
+	defer controller.runningLock.Unlock()
+
+	if other, ok := controller.backupNames[backup.Name]; ok && other != key {
+		ns, _, _ := cache.SplitMetaNamespaceKey(other)
+		return fmt.Errorf("Backup name %s is already used in namespace %s; backup names must be unique across namespaces", backup.Name, ns)
+	}
+	controller.backupNames[backup.Name] = key
+
+	return nil
+}
+
+// releaseBackupName releases backup's name if it was reserved by reserveBackupName.
+func (controller *backupController) releaseBackupName(backup *api.Backup) {
+	controller.runningLock.Lock()
+	defer controller.runningLock.Unlock()
+
+	if controller.backupNames[backup.Name] == runningKey(backup) {
+		delete(controller.backupNames, backup.Name)
+	}
+}
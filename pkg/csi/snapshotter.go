@@ -0,0 +1,289 @@
+/*
+Copyright 2017 Heptio Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package csi
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/golang/glog"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/wait"
+
+	api "github.com/heptio/ark/pkg/apis/ark/v1"
+	"github.com/heptio/ark/pkg/client"
+	"github.com/heptio/ark/pkg/util/collections"
+)
+
+const (
+	// GroupName is the API group of the CSI external-snapshotter's resources.
+	GroupName = "snapshot.storage.k8s.io"
+
+	// Version is the version of the CSI external-snapshotter's API that Ark uses.
+	Version = "v1beta1"
+
+	pollInterval = time.Second
+)
+
+var (
+	volumeSnapshots = metav1.APIResource{Name: "volumesnapshots", Namespaced: true}
+
+	volumeSnapshotContents = metav1.APIResource{Name: "volumesnapshotcontents", Namespaced: false}
+)
+
+// Snapshotter snapshots and restores PersistentVolumes backed by CSI drivers using the CSI
+// external-snapshotter's VolumeSnapshot API.
+type Snapshotter interface {
+	// CreateSnapshot creates a VolumeSnapshot with the given name of the claim, waits for it to
+	// be ready to use, and returns the information needed to restore it.
+	CreateSnapshot(claimNamespace, claimName, name string) (*api.CSISnapshotInfo, error)
+
+	// PrepareRestore creates a VolumeSnapshot with the given name in namespace, which is bound to
+	// a new VolumeSnapshotContent for the snapshot described by info, so that a claim can be
+	// restored from it by naming it as its data source.
+	PrepareRestore(info *api.CSISnapshotInfo, namespace, name string) error
+
+	// DeleteSnapshot deletes the VolumeSnapshot created to take the snapshot described by info.
+	// The snapshot in the driver's storage system is deleted along with it if its
+	// VolumeSnapshotClass's deletionPolicy is Delete. A VolumeSnapshot that no longer exists
+	// isn't an error.
+	DeleteSnapshot(info *api.CSISnapshotInfo) error
+}
+
+// dynamicSnapshotter implements Snapshotter using dynamic clients, so that Ark doesn't depend
+// on the external-snapshotter's client library.
+type dynamicSnapshotter struct {
+	dynamicFactory client.DynamicFactory
+	snapshotClass  string
+	timeout        time.Duration
+}
+
+var _ Snapshotter = &dynamicSnapshotter{}
+
+// NewSnapshotter creates a Snapshotter that creates VolumeSnapshots of the given
+// VolumeSnapshotClass, or of the cluster's default class if snapshotClass is empty, and waits up
+// to timeout for each to become ready to use.
+func NewSnapshotter(dynamicFactory client.DynamicFactory, snapshotClass string, timeout time.Duration) Snapshotter {
+	return &dynamicSnapshotter{
+		dynamicFactory: dynamicFactory,
+		snapshotClass:  snapshotClass,
+		timeout:        timeout,
+	}
+}
+
+func (s *dynamicSnapshotter) client(resource metav1.APIResource, namespace string) (client.Dynamic, error) {
+	gvr := schema.GroupVersionResource{Group: GroupName, Version: Version, Resource: resource.Name}
+	return s.dynamicFactory.ClientForGroupVersionResource(gvr, resource, namespace)
+}
+
+func (s *dynamicSnapshotter) CreateSnapshot(claimNamespace, claimName, name string) (*api.CSISnapshotInfo, error) {
+	snapshotClient, err := s.client(volumeSnapshots, claimNamespace)
+	if err != nil {
+		return nil, err
+	}
+
+	spec := map[string]interface{}{
+		"source": map[string]interface{}{
+			"persistentVolumeClaimName": claimName,
+		},
+	}
+	if s.snapshotClass != "" {
+		spec["volumeSnapshotClassName"] = s.snapshotClass
+	}
+
+	snapshot := &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"apiVersion": GroupName + "/" + Version,
+			"kind":       "VolumeSnapshot",
+			"metadata": map[string]interface{}{
+				"namespace": claimNamespace,
+				"name":      name,
+			},
+			"spec": spec,
+		},
+	}
+
+	glog.V(2).Infof("Creating VolumeSnapshot %s/%s of PersistentVolumeClaim %s", claimNamespace, name, claimName)
+	if _, err := snapshotClient.Create(snapshot); err != nil {
+		return nil, fmt.Errorf("error creating VolumeSnapshot %s/%s: %v", claimNamespace, name, err)
+	}
+
+	var contentName string
+	err = wait.PollImmediate(pollInterval, s.timeout, func() (bool, error) {
+		res, err := snapshotClient.Get(name, metav1.GetOptions{})
+		if err != nil {
+			return false, err
+		}
+
+		if message, err := collections.GetString(res.Object, "status.error.message"); err == nil && message != "" {
+			return false, errors.New(message)
+		}
+
+		status, err := collections.GetMap(res.Object, "status")
+		if err != nil {
+			return false, nil
+		}
+		if ready, _ := status["readyToUse"].(bool); !ready {
+			return false, nil
+		}
+
+		contentName, _ = status["boundVolumeSnapshotContentName"].(string)
+		return contentName != "", nil
+	})
+	if err == wait.ErrWaitTimeout {
+		return nil, fmt.Errorf("timed out waiting for VolumeSnapshot %s/%s to be ready to use", claimNamespace, name)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error waiting for VolumeSnapshot %s/%s to be ready to use: %v", claimNamespace, name, err)
+	}
+
+	contentClient, err := s.client(volumeSnapshotContents, "")
+	if err != nil {
+		return nil, err
+	}
+
+	content, err := contentClient.Get(contentName, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("error getting VolumeSnapshotContent %s: %v", contentName, err)
+	}
+
+	driver, err := collections.GetString(content.Object, "spec.driver")
+	if err != nil {
+		return nil, fmt.Errorf("error getting driver of VolumeSnapshotContent %s: %v", contentName, err)
+	}
+	handle, err := collections.GetString(content.Object, "status.snapshotHandle")
+	if err != nil {
+		return nil, fmt.Errorf("error getting snapshot handle of VolumeSnapshotContent %s: %v", contentName, err)
+	}
+
+	return &api.CSISnapshotInfo{
+		Driver:                  driver,
+		SnapshotHandle:          handle,
+		VolumeSnapshotClassName: s.snapshotClass,
+		VolumeSnapshotNamespace: claimNamespace,
+		VolumeSnapshotName:      name,
+	}, nil
+}
+
+func (s *dynamicSnapshotter) DeleteSnapshot(info *api.CSISnapshotInfo) error {
+	if info.VolumeSnapshotName == "" {
+		return fmt.Errorf("snapshot %s doesn't record the VolumeSnapshot it was taken with", info.SnapshotHandle)
+	}
+
+	snapshotClient, err := s.client(volumeSnapshots, info.VolumeSnapshotNamespace)
+	if err != nil {
+		return err
+	}
+
+	glog.V(2).Infof("Deleting VolumeSnapshot %s/%s", info.VolumeSnapshotNamespace, info.VolumeSnapshotName)
+	if err := snapshotClient.Delete(info.VolumeSnapshotName, &metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+		return fmt.Errorf("error deleting VolumeSnapshot %s/%s: %v", info.VolumeSnapshotNamespace, info.VolumeSnapshotName, err)
+	}
+	return nil
+}
+
+func (s *dynamicSnapshotter) PrepareRestore(info *api.CSISnapshotInfo, namespace, name string) error {
+	contentClient, err := s.client(volumeSnapshotContents, "")
+	if err != nil {
+		return err
+	}
+
+	// VolumeSnapshotContents are cluster-scoped, so include the namespace in the name to keep
+	// it unique.
+	contentName := fmt.Sprintf("%s-%s", namespace, name)
+
+	contentSpec := map[string]interface{}{
+		// don't delete the snapshot when the restored VolumeSnapshot is deleted, since it
+		// still belongs to the backup
+		"deletionPolicy": "Retain",
+		"driver":         info.Driver,
+		"source": map[string]interface{}{
+			"snapshotHandle": info.SnapshotHandle,
+		},
+		"volumeSnapshotRef": map[string]interface{}{
+			"namespace": namespace,
+			"name":      name,
+		},
+	}
+	if info.VolumeSnapshotClassName != "" {
+		contentSpec["volumeSnapshotClassName"] = info.VolumeSnapshotClassName
+	}
+
+	content := &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"apiVersion": GroupName + "/" + Version,
+			"kind":       "VolumeSnapshotContent",
+			"metadata": map[string]interface{}{
+				"name": contentName,
+			},
+			"spec": contentSpec,
+		},
+	}
+
+	glog.V(2).Infof("Creating VolumeSnapshotContent %s for snapshot %s", contentName, info.SnapshotHandle)
+	if _, err := contentClient.Create(content); err != nil {
+		return fmt.Errorf("error creating VolumeSnapshotContent %s: %v", contentName, err)
+	}
+
+	snapshotClient, err := s.client(volumeSnapshots, namespace)
+	if err != nil {
+		return err
+	}
+
+	snapshot := &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"apiVersion": GroupName + "/" + Version,
+			"kind":       "VolumeSnapshot",
+			"metadata": map[string]interface{}{
+				"namespace": namespace,
+				"name":      name,
+			},
+			"spec": map[string]interface{}{
+				"source": map[string]interface{}{
+					"volumeSnapshotContentName": contentName,
+				},
+			},
+		},
+	}
+
+	glog.V(2).Infof("Creating VolumeSnapshot %s/%s", namespace, name)
+	if _, err := snapshotClient.Create(snapshot); err != nil {
+		return fmt.Errorf("error creating VolumeSnapshot %s/%s: %v", namespace, name, err)
+	}
+
+	return nil
+}
+
+// SnapshotToRestore returns the CSI snapshot of the named PersistentVolume that the restore
+// should restore the volume from, or nil if there isn't one or the restore isn't restoring PVs.
+func SnapshotToRestore(restore *api.Restore, backup *api.Backup, volumeName string) *api.CSISnapshotInfo {
+	if restore.Spec.RestorePVs != nil && !*restore.Spec.RestorePVs {
+		return nil
+	}
+
+	info := backup.Status.VolumeBackups[volumeName]
+	if info == nil {
+		return nil
+	}
+
+	return info.CSISnapshot
+}
@@ -0,0 +1,204 @@
+/*
+Copyright 2017 Heptio Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package csi
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	api "github.com/heptio/ark/pkg/apis/ark/v1"
+	arktest "github.com/heptio/ark/pkg/util/test"
+)
+
+var (
+	snapshotsGVR = schema.GroupVersionResource{Group: GroupName, Version: Version, Resource: "volumesnapshots"}
+	contentsGVR  = schema.GroupVersionResource{Group: GroupName, Version: Version, Resource: "volumesnapshotcontents"}
+)
+
+func TestCreateSnapshot(t *testing.T) {
+	tests := []struct {
+		name         string
+		status       map[string]interface{}
+		expectErr    bool
+		expectedInfo *api.CSISnapshotInfo
+	}{
+		{
+			name: "ready snapshot returns its content's handle",
+			status: map[string]interface{}{
+				"readyToUse":                     true,
+				"boundVolumeSnapshotContentName": "snapcontent-1",
+			},
+			expectedInfo: &api.CSISnapshotInfo{
+				Driver:                  "csi.example.com",
+				SnapshotHandle:          "handle-1",
+				VolumeSnapshotClassName: "class-1",
+				VolumeSnapshotNamespace: "ns-1",
+				VolumeSnapshotName:      "backup-1-pv-1",
+			},
+		},
+		{
+			name: "snapshot error is returned",
+			status: map[string]interface{}{
+				"error": map[string]interface{}{"message": "snapshot failed"},
+			},
+			expectErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dynamicFactory := &arktest.FakeDynamicFactory{}
+			snapshotClient := &arktest.FakeDynamicClient{}
+			contentClient := &arktest.FakeDynamicClient{}
+
+			dynamicFactory.On("ClientForGroupVersionResource", snapshotsGVR, volumeSnapshots, "ns-1").Return(snapshotClient, nil)
+			dynamicFactory.On("ClientForGroupVersionResource", contentsGVR, volumeSnapshotContents, "").Return(contentClient, nil)
+
+			snapshotClient.On("Create", mock.Anything).Return(&unstructured.Unstructured{}, nil)
+			snapshotClient.On("Get", "backup-1-pv-1", metav1.GetOptions{}).Return(&unstructured.Unstructured{
+				Object: map[string]interface{}{"status": test.status},
+			}, nil)
+			contentClient.On("Get", "snapcontent-1", metav1.GetOptions{}).Return(&unstructured.Unstructured{
+				Object: map[string]interface{}{
+					"spec":   map[string]interface{}{"driver": "csi.example.com"},
+					"status": map[string]interface{}{"snapshotHandle": "handle-1"},
+				},
+			}, nil)
+
+			snapshotter := NewSnapshotter(dynamicFactory, "class-1", time.Minute)
+
+			info, err := snapshotter.CreateSnapshot("ns-1", "claim-1", "backup-1-pv-1")
+			if test.expectErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.expectedInfo, info)
+
+			created := snapshotClient.Calls[0].Arguments.Get(0).(*unstructured.Unstructured)
+			assert.Equal(t, map[string]interface{}{
+				"source":                  map[string]interface{}{"persistentVolumeClaimName": "claim-1"},
+				"volumeSnapshotClassName": "class-1",
+			}, created.Object["spec"])
+		})
+	}
+}
+
+func TestPrepareRestore(t *testing.T) {
+	dynamicFactory := &arktest.FakeDynamicFactory{}
+	snapshotClient := &arktest.FakeDynamicClient{}
+	contentClient := &arktest.FakeDynamicClient{}
+
+	dynamicFactory.On("ClientForGroupVersionResource", snapshotsGVR, volumeSnapshots, "ns-1").Return(snapshotClient, nil)
+	dynamicFactory.On("ClientForGroupVersionResource", contentsGVR, volumeSnapshotContents, "").Return(contentClient, nil)
+
+	contentClient.On("Create", mock.Anything).Return(&unstructured.Unstructured{}, nil)
+	snapshotClient.On("Create", mock.Anything).Return(&unstructured.Unstructured{}, nil)
+
+	snapshotter := NewSnapshotter(dynamicFactory, "", time.Minute)
+
+	info := &api.CSISnapshotInfo{Driver: "csi.example.com", SnapshotHandle: "handle-1"}
+	require.NoError(t, snapshotter.PrepareRestore(info, "ns-1", "restore-1-claim-1"))
+
+	content := contentClient.Calls[0].Arguments.Get(0).(*unstructured.Unstructured)
+	assert.Equal(t, "ns-1-restore-1-claim-1", content.GetName())
+	assert.Equal(t, map[string]interface{}{
+		"deletionPolicy":    "Retain",
+		"driver":            "csi.example.com",
+		"source":            map[string]interface{}{"snapshotHandle": "handle-1"},
+		"volumeSnapshotRef": map[string]interface{}{"namespace": "ns-1", "name": "restore-1-claim-1"},
+	}, content.Object["spec"])
+
+	snapshot := snapshotClient.Calls[0].Arguments.Get(0).(*unstructured.Unstructured)
+	assert.Equal(t, "restore-1-claim-1", snapshot.GetName())
+	assert.Equal(t, map[string]interface{}{
+		"source": map[string]interface{}{"volumeSnapshotContentName": "ns-1-restore-1-claim-1"},
+	}, snapshot.Object["spec"])
+}
+
+func TestDeleteSnapshot(t *testing.T) {
+	tests := []struct {
+		name      string
+		info      *api.CSISnapshotInfo
+		deleteErr error
+		expectErr bool
+	}{
+		{
+			name: "VolumeSnapshot is deleted",
+			info: &api.CSISnapshotInfo{SnapshotHandle: "handle-1", VolumeSnapshotNamespace: "ns-1", VolumeSnapshotName: "backup-1-pv-1"},
+		},
+		{
+			name:      "VolumeSnapshot that's already gone isn't an error",
+			info:      &api.CSISnapshotInfo{SnapshotHandle: "handle-1", VolumeSnapshotNamespace: "ns-1", VolumeSnapshotName: "backup-1-pv-1"},
+			deleteErr: apierrors.NewNotFound(schema.GroupResource{Group: GroupName, Resource: "volumesnapshots"}, "backup-1-pv-1"),
+		},
+		{
+			name:      "other errors are returned",
+			info:      &api.CSISnapshotInfo{SnapshotHandle: "handle-1", VolumeSnapshotNamespace: "ns-1", VolumeSnapshotName: "backup-1-pv-1"},
+			deleteErr: errors.New("forbidden"),
+			expectErr: true,
+		},
+		{
+			name:      "snapshot without a VolumeSnapshot name is an error",
+			info:      &api.CSISnapshotInfo{SnapshotHandle: "handle-1"},
+			expectErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dynamicFactory := &arktest.FakeDynamicFactory{}
+			snapshotClient := &arktest.FakeDynamicClient{}
+
+			dynamicFactory.On("ClientForGroupVersionResource", snapshotsGVR, volumeSnapshots, "ns-1").Return(snapshotClient, nil)
+			snapshotClient.On("Delete", "backup-1-pv-1", &metav1.DeleteOptions{}).Return(test.deleteErr)
+
+			snapshotter := NewSnapshotter(dynamicFactory, "", time.Minute)
+
+			err := snapshotter.DeleteSnapshot(test.info)
+			if test.expectErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			snapshotClient.AssertCalled(t, "Delete", "backup-1-pv-1", &metav1.DeleteOptions{})
+		})
+	}
+}
+
+func TestSnapshotToRestore(t *testing.T) {
+	info := &api.CSISnapshotInfo{Driver: "csi.example.com", SnapshotHandle: "handle-1"}
+	backup := arktest.NewTestBackup().WithCSISnapshot("pv-1", "csi.example.com", "handle-1").WithSnapshot("pv-2", "snap-2").Backup
+
+	restore := arktest.NewDefaultTestRestore().Restore
+	assert.Equal(t, info, SnapshotToRestore(restore, backup, "pv-1"))
+	assert.Nil(t, SnapshotToRestore(restore, backup, "pv-2"))
+	assert.Nil(t, SnapshotToRestore(restore, backup, "pv-3"))
+
+	restore = arktest.NewDefaultTestRestore().WithRestorePVs(false).Restore
+	assert.Nil(t, SnapshotToRestore(restore, backup, "pv-1"))
+}
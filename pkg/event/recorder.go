@@ -0,0 +1,141 @@
+/*
+Copyright 2017 Heptio Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package event records Kubernetes Events about Ark API objects.
+package event
+
+import (
+	"fmt"
+
+	"github.com/golang/glog"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/clock"
+	corev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/pkg/api/v1"
+
+	"github.com/heptio/ark/pkg/generated/clientset/scheme"
+)
+
+// Component is the source component of the events recorded by the Ark server.
+const Component = "ark"
+
+// Reasons for the events recorded about backups.
+const (
+	ReasonBackupStarted          = "BackupStarted"
+	ReasonBackupFailedValidation = "FailedValidation"
+	ReasonItemBackupFailed       = "ItemBackupFailed"
+	ReasonSnapshotCreated        = "SnapshotCreated"
+	ReasonBackupCompleted        = "BackupCompleted"
+	ReasonBackupPartiallyFailed  = "BackupPartiallyFailed"
+	ReasonBackupFailed           = "BackupFailed"
+	ReasonBackupExpired          = "BackupExpired"
+	ReasonBackupCanceled         = "BackupCanceled"
+	ReasonBackupInterrupted      = "BackupInterrupted"
+)
+
+// Reasons for the events recorded about restores.
+const (
+	ReasonRestoreFailedValidation = "FailedValidation"
+	ReasonRestoreStarted          = "RestoreStarted"
+	ReasonRestoreInterrupted      = "RestoreInterrupted"
+	ReasonRestoreCompleted        = "RestoreCompleted"
+	ReasonRestorePartiallyFailed  = "RestorePartiallyFailed"
+	ReasonRestoreFailed           = "RestoreFailed"
+)
+
+// Reasons for the events recorded about DeleteBackupRequests.
+const (
+	ReasonBackupDeleted        = "BackupDeleted"
+	ReasonBackupDeletionFailed = "BackupDeletionFailed"
+)
+
+// Recorder records Events about Ark API objects.
+type Recorder interface {
+	// Eventf records an Event of eventType (v1.EventTypeNormal or v1.EventTypeWarning) about obj,
+	// which must be an Ark API object such as a *v1.Backup. Failures are logged but otherwise
+	// ignored, since Events are informational.
+	Eventf(obj runtime.Object, eventType, reason, messageFmt string, args ...interface{})
+}
+
+type recorder struct {
+	client    corev1.EventsGetter
+	component string
+	clock     clock.Clock
+}
+
+// NewRecorder returns a Recorder that creates Events with the given source component.
+func NewRecorder(client corev1.EventsGetter, component string) Recorder {
+	return &recorder{
+		client:    client,
+		component: component,
+		clock:     clock.RealClock{},
+	}
+}
+
+func (r *recorder) Eventf(obj runtime.Object, eventType, reason, messageFmt string, args ...interface{}) {
+	ref, err := objectReference(obj)
+	if err != nil {
+		glog.Errorf("error recording %s event: %v", reason, err)
+		return
+	}
+
+	now := metav1.NewTime(r.clock.Now())
+	event := &v1.Event{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: ref.Namespace,
+			Name:      fmt.Sprintf("%s.%x", ref.Name, now.UnixNano()),
+		},
+		InvolvedObject: *ref,
+		Reason:         reason,
+		Message:        fmt.Sprintf(messageFmt, args...),
+		Source:         v1.EventSource{Component: r.component},
+		FirstTimestamp: now,
+		LastTimestamp:  now,
+		Count:          1,
+		Type:           eventType,
+	}
+
+	if _, err := r.client.Events(ref.Namespace).Create(event); err != nil {
+		glog.Errorf("error recording %s event for %s %s/%s: %v", reason, ref.Kind, ref.Namespace, ref.Name, err)
+	}
+}
+
+// objectReference returns a reference to obj, looking up its kind in Ark's scheme since objects
+// from listers don't have their TypeMeta set.
+func objectReference(obj runtime.Object) (*v1.ObjectReference, error) {
+	accessor, err := meta.Accessor(obj)
+	if err != nil {
+		return nil, err
+	}
+
+	kinds, _, err := scheme.Scheme.ObjectKinds(obj)
+	if err != nil {
+		return nil, err
+	}
+	gvk := kinds[0]
+
+	return &v1.ObjectReference{
+		APIVersion:      gvk.GroupVersion().String(),
+		Kind:            gvk.Kind,
+		Namespace:       accessor.GetNamespace(),
+		Name:            accessor.GetName(),
+		UID:             accessor.GetUID(),
+		ResourceVersion: accessor.GetResourceVersion(),
+	}, nil
+}
@@ -0,0 +1,106 @@
+/*
+Copyright 2017 Heptio Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package event
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/clock"
+	corev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/pkg/api/v1"
+
+	api "github.com/heptio/ark/pkg/apis/ark/v1"
+	. "github.com/heptio/ark/pkg/util/test"
+)
+
+type fakeEventsClient struct {
+	corev1.EventInterface
+
+	namespace string
+	created   []*v1.Event
+	err       error
+}
+
+func (c *fakeEventsClient) Events(namespace string) corev1.EventInterface {
+	c.namespace = namespace
+	return c
+}
+
+func (c *fakeEventsClient) Create(event *v1.Event) (*v1.Event, error) {
+	c.created = append(c.created, event)
+	return event, c.err
+}
+
+func TestEventf(t *testing.T) {
+	now := time.Date(2017, 10, 1, 12, 0, 0, 0, time.UTC)
+	client := &fakeEventsClient{}
+	r := &recorder{
+		client:    client,
+		component: "ark",
+		clock:     clock.NewFakeClock(now),
+	}
+
+	backup := NewTestBackup().WithName("backup-1").Backup
+	backup.UID = "uid-1"
+	backup.ResourceVersion = "5"
+
+	r.Eventf(backup, v1.EventTypeWarning, "BackupFailed", "Backup failed: %v", "blarg")
+
+	require.Len(t, client.created, 1)
+	assert.Equal(t, api.DefaultNamespace, client.namespace)
+
+	expected := &v1.Event{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: api.DefaultNamespace,
+			Name:      "backup-1.14e970756e878000",
+		},
+		InvolvedObject: v1.ObjectReference{
+			APIVersion:      "ark.heptio.com/v1",
+			Kind:            "Backup",
+			Namespace:       api.DefaultNamespace,
+			Name:            "backup-1",
+			UID:             "uid-1",
+			ResourceVersion: "5",
+		},
+		Reason:         "BackupFailed",
+		Message:        "Backup failed: blarg",
+		Source:         v1.EventSource{Component: "ark"},
+		FirstTimestamp: metav1.NewTime(now),
+		LastTimestamp:  metav1.NewTime(now),
+		Count:          1,
+		Type:           v1.EventTypeWarning,
+	}
+	assert.Equal(t, expected, client.created[0])
+}
+
+func TestEventfIgnoresErrors(t *testing.T) {
+	client := &fakeEventsClient{err: errors.New("blarg")}
+	r := NewRecorder(client, "ark")
+
+	// unknown types can't be referenced, so no event is created
+	r.Eventf(&v1.Pod{}, v1.EventTypeNormal, "Foo", "bar")
+	assert.Empty(t, client.created)
+
+	r.Eventf(NewTestBackup().WithName("backup-1").Backup, v1.EventTypeNormal, "Foo", "bar")
+	assert.Len(t, client.created, 1)
+}
@@ -10,9 +10,18 @@ import (
 type ArkV1Interface interface {
 	RESTClient() rest.Interface
 	BackupsGetter
+	BackupStorageLocationsGetter
+	BackupVerificationsGetter
 	ConfigsGetter
+	DeleteBackupRequestsGetter
+	DownloadRequestsGetter
+	PodVolumeBackupsGetter
+	PodVolumeRestoresGetter
+	ResticRepositoriesGetter
 	RestoresGetter
 	SchedulesGetter
+	ServerStatusRequestsGetter
+	VolumeSnapshotLocationsGetter
 }
 
 // ArkV1Client is used to interact with features provided by the ark.heptio.com group.
@@ -24,10 +33,38 @@ func (c *ArkV1Client) Backups(namespace string) BackupInterface {
 	return newBackups(c, namespace)
 }
 
+func (c *ArkV1Client) BackupStorageLocations(namespace string) BackupStorageLocationInterface {
+	return newBackupStorageLocations(c, namespace)
+}
+
+func (c *ArkV1Client) BackupVerifications(namespace string) BackupVerificationInterface {
+	return newBackupVerifications(c, namespace)
+}
+
 func (c *ArkV1Client) Configs(namespace string) ConfigInterface {
 	return newConfigs(c, namespace)
 }
 
+func (c *ArkV1Client) DeleteBackupRequests(namespace string) DeleteBackupRequestInterface {
+	return newDeleteBackupRequests(c, namespace)
+}
+
+func (c *ArkV1Client) DownloadRequests(namespace string) DownloadRequestInterface {
+	return newDownloadRequests(c, namespace)
+}
+
+func (c *ArkV1Client) PodVolumeBackups(namespace string) PodVolumeBackupInterface {
+	return newPodVolumeBackups(c, namespace)
+}
+
+func (c *ArkV1Client) PodVolumeRestores(namespace string) PodVolumeRestoreInterface {
+	return newPodVolumeRestores(c, namespace)
+}
+
+func (c *ArkV1Client) ResticRepositories(namespace string) ResticRepositoryInterface {
+	return newResticRepositories(c, namespace)
+}
+
 func (c *ArkV1Client) Restores(namespace string) RestoreInterface {
 	return newRestores(c, namespace)
 }
@@ -36,6 +73,14 @@ func (c *ArkV1Client) Schedules(namespace string) ScheduleInterface {
 	return newSchedules(c, namespace)
 }
 
+func (c *ArkV1Client) ServerStatusRequests(namespace string) ServerStatusRequestInterface {
+	return newServerStatusRequests(c, namespace)
+}
+
+func (c *ArkV1Client) VolumeSnapshotLocations(namespace string) VolumeSnapshotLocationInterface {
+	return newVolumeSnapshotLocations(c, namespace)
+}
+
 // NewForConfig creates a new ArkV1Client for the given config.
 func NewForConfig(c *rest.Config) (*ArkV1Client, error) {
 	config := *c
@@ -0,0 +1,139 @@
+package v1
+
+import (
+	v1 "github.com/heptio/ark/pkg/apis/ark/v1"
+	scheme "github.com/heptio/ark/pkg/generated/clientset/scheme"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	types "k8s.io/apimachinery/pkg/types"
+	watch "k8s.io/apimachinery/pkg/watch"
+	rest "k8s.io/client-go/rest"
+)
+
+// BackupStorageLocationsGetter has a method to return a BackupStorageLocationInterface.
+// A group's client should implement this interface.
+type BackupStorageLocationsGetter interface {
+	BackupStorageLocations(namespace string) BackupStorageLocationInterface
+}
+
+// BackupStorageLocationInterface has methods to work with BackupStorageLocation resources.
+type BackupStorageLocationInterface interface {
+	Create(*v1.BackupStorageLocation) (*v1.BackupStorageLocation, error)
+	Update(*v1.BackupStorageLocation) (*v1.BackupStorageLocation, error)
+	Delete(name string, options *meta_v1.DeleteOptions) error
+	DeleteCollection(options *meta_v1.DeleteOptions, listOptions meta_v1.ListOptions) error
+	Get(name string, options meta_v1.GetOptions) (*v1.BackupStorageLocation, error)
+	List(opts meta_v1.ListOptions) (*v1.BackupStorageLocationList, error)
+	Watch(opts meta_v1.ListOptions) (watch.Interface, error)
+	Patch(name string, pt types.PatchType, data []byte, subresources ...string) (result *v1.BackupStorageLocation, err error)
+	BackupStorageLocationExpansion
+}
+
+// backupStorageLocations implements BackupStorageLocationInterface
+type backupStorageLocations struct {
+	client rest.Interface
+	ns     string
+}
+
+// newBackupStorageLocations returns a BackupStorageLocations
+func newBackupStorageLocations(c *ArkV1Client, namespace string) *backupStorageLocations {
+	return &backupStorageLocations{
+		client: c.RESTClient(),
+		ns:     namespace,
+	}
+}
+
+// Create takes the representation of a backupStorageLocation and creates it.  Returns the server's representation of the backupStorageLocation, and an error, if there is any.
+func (c *backupStorageLocations) Create(backupStorageLocation *v1.BackupStorageLocation) (result *v1.BackupStorageLocation, err error) {
+	result = &v1.BackupStorageLocation{}
+	err = c.client.Post().
+		Namespace(c.ns).
+		Resource("backupstoragelocations").
+		Body(backupStorageLocation).
+		Do().
+		Into(result)
+	return
+}
+
+// Update takes the representation of a backupStorageLocation and updates it. Returns the server's representation of the backupStorageLocation, and an error, if there is any.
+func (c *backupStorageLocations) Update(backupStorageLocation *v1.BackupStorageLocation) (result *v1.BackupStorageLocation, err error) {
+	result = &v1.BackupStorageLocation{}
+	err = c.client.Put().
+		Namespace(c.ns).
+		Resource("backupstoragelocations").
+		Name(backupStorageLocation.Name).
+		Body(backupStorageLocation).
+		Do().
+		Into(result)
+	return
+}
+
+// Delete takes name of the backupStorageLocation and deletes it. Returns an error if one occurs.
+func (c *backupStorageLocations) Delete(name string, options *meta_v1.DeleteOptions) error {
+	return c.client.Delete().
+		Namespace(c.ns).
+		Resource("backupstoragelocations").
+		Name(name).
+		Body(options).
+		Do().
+		Error()
+}
+
+// DeleteCollection deletes a collection of objects.
+func (c *backupStorageLocations) DeleteCollection(options *meta_v1.DeleteOptions, listOptions meta_v1.ListOptions) error {
+	return c.client.Delete().
+		Namespace(c.ns).
+		Resource("backupstoragelocations").
+		VersionedParams(&listOptions, scheme.ParameterCodec).
+		Body(options).
+		Do().
+		Error()
+}
+
+// Get takes name of the backupStorageLocation, and returns the corresponding backupStorageLocation object, and an error if there is any.
+func (c *backupStorageLocations) Get(name string, options meta_v1.GetOptions) (result *v1.BackupStorageLocation, err error) {
+	result = &v1.BackupStorageLocation{}
+	err = c.client.Get().
+		Namespace(c.ns).
+		Resource("backupstoragelocations").
+		Name(name).
+		VersionedParams(&options, scheme.ParameterCodec).
+		Do().
+		Into(result)
+	return
+}
+
+// List takes label and field selectors, and returns the list of BackupStorageLocations that match those selectors.
+func (c *backupStorageLocations) List(opts meta_v1.ListOptions) (result *v1.BackupStorageLocationList, err error) {
+	result = &v1.BackupStorageLocationList{}
+	err = c.client.Get().
+		Namespace(c.ns).
+		Resource("backupstoragelocations").
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Do().
+		Into(result)
+	return
+}
+
+// Watch returns a watch.Interface that watches the requested backupStorageLocations.
+func (c *backupStorageLocations) Watch(opts meta_v1.ListOptions) (watch.Interface, error) {
+	opts.Watch = true
+	return c.client.Get().
+		Namespace(c.ns).
+		Resource("backupstoragelocations").
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Watch()
+}
+
+// Patch applies the patch and returns the patched backupStorageLocation.
+func (c *backupStorageLocations) Patch(name string, pt types.PatchType, data []byte, subresources ...string) (result *v1.BackupStorageLocation, err error) {
+	result = &v1.BackupStorageLocation{}
+	err = c.client.Patch(pt).
+		Namespace(c.ns).
+		Resource("backupstoragelocations").
+		SubResource(subresources...).
+		Name(name).
+		Body(data).
+		Do().
+		Into(result)
+	return
+}
@@ -0,0 +1,156 @@
+package v1
+
+import (
+	v1 "github.com/heptio/ark/pkg/apis/ark/v1"
+	scheme "github.com/heptio/ark/pkg/generated/clientset/scheme"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	types "k8s.io/apimachinery/pkg/types"
+	watch "k8s.io/apimachinery/pkg/watch"
+	rest "k8s.io/client-go/rest"
+)
+
+// BackupVerificationsGetter has a method to return a BackupVerificationInterface.
+// A group's client should implement this interface.
+type BackupVerificationsGetter interface {
+	BackupVerifications(namespace string) BackupVerificationInterface
+}
+
+// BackupVerificationInterface has methods to work with BackupVerification resources.
+type BackupVerificationInterface interface {
+	Create(*v1.BackupVerification) (*v1.BackupVerification, error)
+	Update(*v1.BackupVerification) (*v1.BackupVerification, error)
+	UpdateStatus(*v1.BackupVerification) (*v1.BackupVerification, error)
+	Delete(name string, options *meta_v1.DeleteOptions) error
+	DeleteCollection(options *meta_v1.DeleteOptions, listOptions meta_v1.ListOptions) error
+	Get(name string, options meta_v1.GetOptions) (*v1.BackupVerification, error)
+	List(opts meta_v1.ListOptions) (*v1.BackupVerificationList, error)
+	Watch(opts meta_v1.ListOptions) (watch.Interface, error)
+	Patch(name string, pt types.PatchType, data []byte, subresources ...string) (result *v1.BackupVerification, err error)
+	BackupVerificationExpansion
+}
+
+// backupVerifications implements BackupVerificationInterface
+type backupVerifications struct {
+	client rest.Interface
+	ns     string
+}
+
+// newBackupVerifications returns a BackupVerifications
+func newBackupVerifications(c *ArkV1Client, namespace string) *backupVerifications {
+	return &backupVerifications{
+		client: c.RESTClient(),
+		ns:     namespace,
+	}
+}
+
+// Create takes the representation of a backupVerification and creates it.  Returns the server's representation of the backupVerification, and an error, if there is any.
+func (c *backupVerifications) Create(backupVerification *v1.BackupVerification) (result *v1.BackupVerification, err error) {
+	result = &v1.BackupVerification{}
+	err = c.client.Post().
+		Namespace(c.ns).
+		Resource("backupverifications").
+		Body(backupVerification).
+		Do().
+		Into(result)
+	return
+}
+
+// Update takes the representation of a backupVerification and updates it. Returns the server's representation of the backupVerification, and an error, if there is any.
+func (c *backupVerifications) Update(backupVerification *v1.BackupVerification) (result *v1.BackupVerification, err error) {
+	result = &v1.BackupVerification{}
+	err = c.client.Put().
+		Namespace(c.ns).
+		Resource("backupverifications").
+		Name(backupVerification.Name).
+		Body(backupVerification).
+		Do().
+		Into(result)
+	return
+}
+
+// UpdateStatus was generated because the type contains a Status member.
+// Add a +genclientstatus=false comment above the type to avoid generating UpdateStatus().
+
+func (c *backupVerifications) UpdateStatus(backupVerification *v1.BackupVerification) (result *v1.BackupVerification, err error) {
+	result = &v1.BackupVerification{}
+	err = c.client.Put().
+		Namespace(c.ns).
+		Resource("backupverifications").
+		Name(backupVerification.Name).
+		SubResource("status").
+		Body(backupVerification).
+		Do().
+		Into(result)
+	return
+}
+
+// Delete takes name of the backupVerification and deletes it. Returns an error if one occurs.
+func (c *backupVerifications) Delete(name string, options *meta_v1.DeleteOptions) error {
+	return c.client.Delete().
+		Namespace(c.ns).
+		Resource("backupverifications").
+		Name(name).
+		Body(options).
+		Do().
+		Error()
+}
+
+// DeleteCollection deletes a collection of objects.
+func (c *backupVerifications) DeleteCollection(options *meta_v1.DeleteOptions, listOptions meta_v1.ListOptions) error {
+	return c.client.Delete().
+		Namespace(c.ns).
+		Resource("backupverifications").
+		VersionedParams(&listOptions, scheme.ParameterCodec).
+		Body(options).
+		Do().
+		Error()
+}
+
+// Get takes name of the backupVerification, and returns the corresponding backupVerification object, and an error if there is any.
+func (c *backupVerifications) Get(name string, options meta_v1.GetOptions) (result *v1.BackupVerification, err error) {
+	result = &v1.BackupVerification{}
+	err = c.client.Get().
+		Namespace(c.ns).
+		Resource("backupverifications").
+		Name(name).
+		VersionedParams(&options, scheme.ParameterCodec).
+		Do().
+		Into(result)
+	return
+}
+
+// List takes label and field selectors, and returns the list of BackupVerifications that match those selectors.
+func (c *backupVerifications) List(opts meta_v1.ListOptions) (result *v1.BackupVerificationList, err error) {
+	result = &v1.BackupVerificationList{}
+	err = c.client.Get().
+		Namespace(c.ns).
+		Resource("backupverifications").
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Do().
+		Into(result)
+	return
+}
+
+// Watch returns a watch.Interface that watches the requested backupVerifications.
+func (c *backupVerifications) Watch(opts meta_v1.ListOptions) (watch.Interface, error) {
+	opts.Watch = true
+	return c.client.Get().
+		Namespace(c.ns).
+		Resource("backupverifications").
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Watch()
+}
+
+// Patch applies the patch and returns the patched backupVerification.
+func (c *backupVerifications) Patch(name string, pt types.PatchType, data []byte, subresources ...string) (result *v1.BackupVerification, err error) {
+	result = &v1.BackupVerification{}
+	err = c.client.Patch(pt).
+		Namespace(c.ns).
+		Resource("backupverifications").
+		SubResource(subresources...).
+		Name(name).
+		Body(data).
+		Do().
+		Into(result)
+	return
+}
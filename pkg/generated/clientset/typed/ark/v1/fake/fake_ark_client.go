@@ -14,10 +14,38 @@ func (c *FakeArkV1) Backups(namespace string) v1.BackupInterface {
 	return &FakeBackups{c, namespace}
 }
 
+func (c *FakeArkV1) BackupStorageLocations(namespace string) v1.BackupStorageLocationInterface {
+	return &FakeBackupStorageLocations{c, namespace}
+}
+
+func (c *FakeArkV1) BackupVerifications(namespace string) v1.BackupVerificationInterface {
+	return &FakeBackupVerifications{c, namespace}
+}
+
 func (c *FakeArkV1) Configs(namespace string) v1.ConfigInterface {
 	return &FakeConfigs{c, namespace}
 }
 
+func (c *FakeArkV1) DeleteBackupRequests(namespace string) v1.DeleteBackupRequestInterface {
+	return &FakeDeleteBackupRequests{c, namespace}
+}
+
+func (c *FakeArkV1) DownloadRequests(namespace string) v1.DownloadRequestInterface {
+	return &FakeDownloadRequests{c, namespace}
+}
+
+func (c *FakeArkV1) PodVolumeBackups(namespace string) v1.PodVolumeBackupInterface {
+	return &FakePodVolumeBackups{c, namespace}
+}
+
+func (c *FakeArkV1) PodVolumeRestores(namespace string) v1.PodVolumeRestoreInterface {
+	return &FakePodVolumeRestores{c, namespace}
+}
+
+func (c *FakeArkV1) ResticRepositories(namespace string) v1.ResticRepositoryInterface {
+	return &FakeResticRepositories{c, namespace}
+}
+
 func (c *FakeArkV1) Restores(namespace string) v1.RestoreInterface {
 	return &FakeRestores{c, namespace}
 }
@@ -26,6 +54,14 @@ func (c *FakeArkV1) Schedules(namespace string) v1.ScheduleInterface {
 	return &FakeSchedules{c, namespace}
 }
 
+func (c *FakeArkV1) ServerStatusRequests(namespace string) v1.ServerStatusRequestInterface {
+	return &FakeServerStatusRequests{c, namespace}
+}
+
+func (c *FakeArkV1) VolumeSnapshotLocations(namespace string) v1.VolumeSnapshotLocationInterface {
+	return &FakeVolumeSnapshotLocations{c, namespace}
+}
+
 // RESTClient returns a RESTClient that is used to communicate
 // with API server by this client implementation.
 func (c *FakeArkV1) RESTClient() rest.Interface {
@@ -0,0 +1,104 @@
+package fake
+
+import (
+	v1 "github.com/heptio/ark/pkg/apis/ark/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	labels "k8s.io/apimachinery/pkg/labels"
+	schema "k8s.io/apimachinery/pkg/runtime/schema"
+	types "k8s.io/apimachinery/pkg/types"
+	watch "k8s.io/apimachinery/pkg/watch"
+	testing "k8s.io/client-go/testing"
+)
+
+// FakeBackupStorageLocations implements BackupStorageLocationInterface
+type FakeBackupStorageLocations struct {
+	Fake *FakeArkV1
+	ns   string
+}
+
+var backupStorageLocationsResource = schema.GroupVersionResource{Group: "ark.heptio.com", Version: "v1", Resource: "backupstoragelocations"}
+
+var backupStorageLocationsKind = schema.GroupVersionKind{Group: "ark.heptio.com", Version: "v1", Kind: "BackupStorageLocation"}
+
+func (c *FakeBackupStorageLocations) Create(backupStorageLocation *v1.BackupStorageLocation) (result *v1.BackupStorageLocation, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewCreateAction(backupStorageLocationsResource, c.ns, backupStorageLocation), &v1.BackupStorageLocation{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1.BackupStorageLocation), err
+}
+
+func (c *FakeBackupStorageLocations) Update(backupStorageLocation *v1.BackupStorageLocation) (result *v1.BackupStorageLocation, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewUpdateAction(backupStorageLocationsResource, c.ns, backupStorageLocation), &v1.BackupStorageLocation{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1.BackupStorageLocation), err
+}
+
+func (c *FakeBackupStorageLocations) Delete(name string, options *meta_v1.DeleteOptions) error {
+	_, err := c.Fake.
+		Invokes(testing.NewDeleteAction(backupStorageLocationsResource, c.ns, name), &v1.BackupStorageLocation{})
+
+	return err
+}
+
+func (c *FakeBackupStorageLocations) DeleteCollection(options *meta_v1.DeleteOptions, listOptions meta_v1.ListOptions) error {
+	action := testing.NewDeleteCollectionAction(backupStorageLocationsResource, c.ns, listOptions)
+
+	_, err := c.Fake.Invokes(action, &v1.BackupStorageLocationList{})
+	return err
+}
+
+func (c *FakeBackupStorageLocations) Get(name string, options meta_v1.GetOptions) (result *v1.BackupStorageLocation, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewGetAction(backupStorageLocationsResource, c.ns, name), &v1.BackupStorageLocation{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1.BackupStorageLocation), err
+}
+
+func (c *FakeBackupStorageLocations) List(opts meta_v1.ListOptions) (result *v1.BackupStorageLocationList, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewListAction(backupStorageLocationsResource, backupStorageLocationsKind, c.ns, opts), &v1.BackupStorageLocationList{})
+
+	if obj == nil {
+		return nil, err
+	}
+
+	label, _, _ := testing.ExtractFromListOptions(opts)
+	if label == nil {
+		label = labels.Everything()
+	}
+	list := &v1.BackupStorageLocationList{}
+	for _, item := range obj.(*v1.BackupStorageLocationList).Items {
+		if label.Matches(labels.Set(item.Labels)) {
+			list.Items = append(list.Items, item)
+		}
+	}
+	return list, err
+}
+
+// Watch returns a watch.Interface that watches the requested backupStorageLocations.
+func (c *FakeBackupStorageLocations) Watch(opts meta_v1.ListOptions) (watch.Interface, error) {
+	return c.Fake.
+		InvokesWatch(testing.NewWatchAction(backupStorageLocationsResource, c.ns, opts))
+
+}
+
+// Patch applies the patch and returns the patched backupStorageLocation.
+func (c *FakeBackupStorageLocations) Patch(name string, pt types.PatchType, data []byte, subresources ...string) (result *v1.BackupStorageLocation, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewPatchSubresourceAction(backupStorageLocationsResource, c.ns, name, data, subresources...), &v1.BackupStorageLocation{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1.BackupStorageLocation), err
+}
@@ -0,0 +1,114 @@
+package fake
+
+import (
+	v1 "github.com/heptio/ark/pkg/apis/ark/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	labels "k8s.io/apimachinery/pkg/labels"
+	schema "k8s.io/apimachinery/pkg/runtime/schema"
+	types "k8s.io/apimachinery/pkg/types"
+	watch "k8s.io/apimachinery/pkg/watch"
+	testing "k8s.io/client-go/testing"
+)
+
+// FakeBackupVerifications implements BackupVerificationInterface
+type FakeBackupVerifications struct {
+	Fake *FakeArkV1
+	ns   string
+}
+
+var backupVerificationsResource = schema.GroupVersionResource{Group: "ark.heptio.com", Version: "v1", Resource: "backupverifications"}
+
+var backupVerificationsKind = schema.GroupVersionKind{Group: "ark.heptio.com", Version: "v1", Kind: "BackupVerification"}
+
+func (c *FakeBackupVerifications) Create(backupVerification *v1.BackupVerification) (result *v1.BackupVerification, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewCreateAction(backupVerificationsResource, c.ns, backupVerification), &v1.BackupVerification{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1.BackupVerification), err
+}
+
+func (c *FakeBackupVerifications) Update(backupVerification *v1.BackupVerification) (result *v1.BackupVerification, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewUpdateAction(backupVerificationsResource, c.ns, backupVerification), &v1.BackupVerification{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1.BackupVerification), err
+}
+
+func (c *FakeBackupVerifications) UpdateStatus(backupVerification *v1.BackupVerification) (*v1.BackupVerification, error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewUpdateSubresourceAction(backupVerificationsResource, "status", c.ns, backupVerification), &v1.BackupVerification{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1.BackupVerification), err
+}
+
+func (c *FakeBackupVerifications) Delete(name string, options *meta_v1.DeleteOptions) error {
+	_, err := c.Fake.
+		Invokes(testing.NewDeleteAction(backupVerificationsResource, c.ns, name), &v1.BackupVerification{})
+
+	return err
+}
+
+func (c *FakeBackupVerifications) DeleteCollection(options *meta_v1.DeleteOptions, listOptions meta_v1.ListOptions) error {
+	action := testing.NewDeleteCollectionAction(backupVerificationsResource, c.ns, listOptions)
+
+	_, err := c.Fake.Invokes(action, &v1.BackupVerificationList{})
+	return err
+}
+
+func (c *FakeBackupVerifications) Get(name string, options meta_v1.GetOptions) (result *v1.BackupVerification, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewGetAction(backupVerificationsResource, c.ns, name), &v1.BackupVerification{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1.BackupVerification), err
+}
+
+func (c *FakeBackupVerifications) List(opts meta_v1.ListOptions) (result *v1.BackupVerificationList, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewListAction(backupVerificationsResource, backupVerificationsKind, c.ns, opts), &v1.BackupVerificationList{})
+
+	if obj == nil {
+		return nil, err
+	}
+
+	label, _, _ := testing.ExtractFromListOptions(opts)
+	if label == nil {
+		label = labels.Everything()
+	}
+	list := &v1.BackupVerificationList{}
+	for _, item := range obj.(*v1.BackupVerificationList).Items {
+		if label.Matches(labels.Set(item.Labels)) {
+			list.Items = append(list.Items, item)
+		}
+	}
+	return list, err
+}
+
+// Watch returns a watch.Interface that watches the requested backupVerifications.
+func (c *FakeBackupVerifications) Watch(opts meta_v1.ListOptions) (watch.Interface, error) {
+	return c.Fake.
+		InvokesWatch(testing.NewWatchAction(backupVerificationsResource, c.ns, opts))
+
+}
+
+// Patch applies the patch and returns the patched backupVerification.
+func (c *FakeBackupVerifications) Patch(name string, pt types.PatchType, data []byte, subresources ...string) (result *v1.BackupVerification, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewPatchSubresourceAction(backupVerificationsResource, c.ns, name, data, subresources...), &v1.BackupVerification{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1.BackupVerification), err
+}
@@ -0,0 +1,114 @@
+package fake
+
+import (
+	v1 "github.com/heptio/ark/pkg/apis/ark/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	labels "k8s.io/apimachinery/pkg/labels"
+	schema "k8s.io/apimachinery/pkg/runtime/schema"
+	types "k8s.io/apimachinery/pkg/types"
+	watch "k8s.io/apimachinery/pkg/watch"
+	testing "k8s.io/client-go/testing"
+)
+
+// FakeDeleteBackupRequests implements DeleteBackupRequestInterface
+type FakeDeleteBackupRequests struct {
+	Fake *FakeArkV1
+	ns   string
+}
+
+var deleteBackupRequestsResource = schema.GroupVersionResource{Group: "ark.heptio.com", Version: "v1", Resource: "deletebackuprequests"}
+
+var deleteBackupRequestsKind = schema.GroupVersionKind{Group: "ark.heptio.com", Version: "v1", Kind: "DeleteBackupRequest"}
+
+func (c *FakeDeleteBackupRequests) Create(deleteBackupRequest *v1.DeleteBackupRequest) (result *v1.DeleteBackupRequest, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewCreateAction(deleteBackupRequestsResource, c.ns, deleteBackupRequest), &v1.DeleteBackupRequest{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1.DeleteBackupRequest), err
+}
+
+func (c *FakeDeleteBackupRequests) Update(deleteBackupRequest *v1.DeleteBackupRequest) (result *v1.DeleteBackupRequest, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewUpdateAction(deleteBackupRequestsResource, c.ns, deleteBackupRequest), &v1.DeleteBackupRequest{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1.DeleteBackupRequest), err
+}
+
+func (c *FakeDeleteBackupRequests) UpdateStatus(deleteBackupRequest *v1.DeleteBackupRequest) (*v1.DeleteBackupRequest, error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewUpdateSubresourceAction(deleteBackupRequestsResource, "status", c.ns, deleteBackupRequest), &v1.DeleteBackupRequest{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1.DeleteBackupRequest), err
+}
+
+func (c *FakeDeleteBackupRequests) Delete(name string, options *meta_v1.DeleteOptions) error {
+	_, err := c.Fake.
+		Invokes(testing.NewDeleteAction(deleteBackupRequestsResource, c.ns, name), &v1.DeleteBackupRequest{})
+
+	return err
+}
+
+func (c *FakeDeleteBackupRequests) DeleteCollection(options *meta_v1.DeleteOptions, listOptions meta_v1.ListOptions) error {
+	action := testing.NewDeleteCollectionAction(deleteBackupRequestsResource, c.ns, listOptions)
+
+	_, err := c.Fake.Invokes(action, &v1.DeleteBackupRequestList{})
+	return err
+}
+
+func (c *FakeDeleteBackupRequests) Get(name string, options meta_v1.GetOptions) (result *v1.DeleteBackupRequest, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewGetAction(deleteBackupRequestsResource, c.ns, name), &v1.DeleteBackupRequest{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1.DeleteBackupRequest), err
+}
+
+func (c *FakeDeleteBackupRequests) List(opts meta_v1.ListOptions) (result *v1.DeleteBackupRequestList, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewListAction(deleteBackupRequestsResource, deleteBackupRequestsKind, c.ns, opts), &v1.DeleteBackupRequestList{})
+
+	if obj == nil {
+		return nil, err
+	}
+
+	label, _, _ := testing.ExtractFromListOptions(opts)
+	if label == nil {
+		label = labels.Everything()
+	}
+	list := &v1.DeleteBackupRequestList{}
+	for _, item := range obj.(*v1.DeleteBackupRequestList).Items {
+		if label.Matches(labels.Set(item.Labels)) {
+			list.Items = append(list.Items, item)
+		}
+	}
+	return list, err
+}
+
+// Watch returns a watch.Interface that watches the requested deleteBackupRequests.
+func (c *FakeDeleteBackupRequests) Watch(opts meta_v1.ListOptions) (watch.Interface, error) {
+	return c.Fake.
+		InvokesWatch(testing.NewWatchAction(deleteBackupRequestsResource, c.ns, opts))
+
+}
+
+// Patch applies the patch and returns the patched deleteBackupRequest.
+func (c *FakeDeleteBackupRequests) Patch(name string, pt types.PatchType, data []byte, subresources ...string) (result *v1.DeleteBackupRequest, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewPatchSubresourceAction(deleteBackupRequestsResource, c.ns, name, data, subresources...), &v1.DeleteBackupRequest{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1.DeleteBackupRequest), err
+}
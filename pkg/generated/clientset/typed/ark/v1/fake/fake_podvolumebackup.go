@@ -0,0 +1,114 @@
+package fake
+
+import (
+	v1 "github.com/heptio/ark/pkg/apis/ark/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	labels "k8s.io/apimachinery/pkg/labels"
+	schema "k8s.io/apimachinery/pkg/runtime/schema"
+	types "k8s.io/apimachinery/pkg/types"
+	watch "k8s.io/apimachinery/pkg/watch"
+	testing "k8s.io/client-go/testing"
+)
+
+// FakePodVolumeBackups implements PodVolumeBackupInterface
+type FakePodVolumeBackups struct {
+	Fake *FakeArkV1
+	ns   string
+}
+
+var podVolumeBackupsResource = schema.GroupVersionResource{Group: "ark.heptio.com", Version: "v1", Resource: "podvolumebackups"}
+
+var podVolumeBackupsKind = schema.GroupVersionKind{Group: "ark.heptio.com", Version: "v1", Kind: "PodVolumeBackup"}
+
+func (c *FakePodVolumeBackups) Create(podVolumeBackup *v1.PodVolumeBackup) (result *v1.PodVolumeBackup, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewCreateAction(podVolumeBackupsResource, c.ns, podVolumeBackup), &v1.PodVolumeBackup{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1.PodVolumeBackup), err
+}
+
+func (c *FakePodVolumeBackups) Update(podVolumeBackup *v1.PodVolumeBackup) (result *v1.PodVolumeBackup, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewUpdateAction(podVolumeBackupsResource, c.ns, podVolumeBackup), &v1.PodVolumeBackup{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1.PodVolumeBackup), err
+}
+
+func (c *FakePodVolumeBackups) UpdateStatus(podVolumeBackup *v1.PodVolumeBackup) (*v1.PodVolumeBackup, error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewUpdateSubresourceAction(podVolumeBackupsResource, "status", c.ns, podVolumeBackup), &v1.PodVolumeBackup{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1.PodVolumeBackup), err
+}
+
+func (c *FakePodVolumeBackups) Delete(name string, options *meta_v1.DeleteOptions) error {
+	_, err := c.Fake.
+		Invokes(testing.NewDeleteAction(podVolumeBackupsResource, c.ns, name), &v1.PodVolumeBackup{})
+
+	return err
+}
+
+func (c *FakePodVolumeBackups) DeleteCollection(options *meta_v1.DeleteOptions, listOptions meta_v1.ListOptions) error {
+	action := testing.NewDeleteCollectionAction(podVolumeBackupsResource, c.ns, listOptions)
+
+	_, err := c.Fake.Invokes(action, &v1.PodVolumeBackupList{})
+	return err
+}
+
+func (c *FakePodVolumeBackups) Get(name string, options meta_v1.GetOptions) (result *v1.PodVolumeBackup, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewGetAction(podVolumeBackupsResource, c.ns, name), &v1.PodVolumeBackup{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1.PodVolumeBackup), err
+}
+
+func (c *FakePodVolumeBackups) List(opts meta_v1.ListOptions) (result *v1.PodVolumeBackupList, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewListAction(podVolumeBackupsResource, podVolumeBackupsKind, c.ns, opts), &v1.PodVolumeBackupList{})
+
+	if obj == nil {
+		return nil, err
+	}
+
+	label, _, _ := testing.ExtractFromListOptions(opts)
+	if label == nil {
+		label = labels.Everything()
+	}
+	list := &v1.PodVolumeBackupList{}
+	for _, item := range obj.(*v1.PodVolumeBackupList).Items {
+		if label.Matches(labels.Set(item.Labels)) {
+			list.Items = append(list.Items, item)
+		}
+	}
+	return list, err
+}
+
+// Watch returns a watch.Interface that watches the requested podVolumeBackups.
+func (c *FakePodVolumeBackups) Watch(opts meta_v1.ListOptions) (watch.Interface, error) {
+	return c.Fake.
+		InvokesWatch(testing.NewWatchAction(podVolumeBackupsResource, c.ns, opts))
+
+}
+
+// Patch applies the patch and returns the patched podVolumeBackup.
+func (c *FakePodVolumeBackups) Patch(name string, pt types.PatchType, data []byte, subresources ...string) (result *v1.PodVolumeBackup, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewPatchSubresourceAction(podVolumeBackupsResource, c.ns, name, data, subresources...), &v1.PodVolumeBackup{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1.PodVolumeBackup), err
+}
@@ -0,0 +1,114 @@
+package fake
+
+import (
+	v1 "github.com/heptio/ark/pkg/apis/ark/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	labels "k8s.io/apimachinery/pkg/labels"
+	schema "k8s.io/apimachinery/pkg/runtime/schema"
+	types "k8s.io/apimachinery/pkg/types"
+	watch "k8s.io/apimachinery/pkg/watch"
+	testing "k8s.io/client-go/testing"
+)
+
+// FakePodVolumeRestores implements PodVolumeRestoreInterface
+type FakePodVolumeRestores struct {
+	Fake *FakeArkV1
+	ns   string
+}
+
+var podVolumeRestoresResource = schema.GroupVersionResource{Group: "ark.heptio.com", Version: "v1", Resource: "podvolumerestores"}
+
+var podVolumeRestoresKind = schema.GroupVersionKind{Group: "ark.heptio.com", Version: "v1", Kind: "PodVolumeRestore"}
+
+func (c *FakePodVolumeRestores) Create(podVolumeRestore *v1.PodVolumeRestore) (result *v1.PodVolumeRestore, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewCreateAction(podVolumeRestoresResource, c.ns, podVolumeRestore), &v1.PodVolumeRestore{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1.PodVolumeRestore), err
+}
+
+func (c *FakePodVolumeRestores) Update(podVolumeRestore *v1.PodVolumeRestore) (result *v1.PodVolumeRestore, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewUpdateAction(podVolumeRestoresResource, c.ns, podVolumeRestore), &v1.PodVolumeRestore{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1.PodVolumeRestore), err
+}
+
+func (c *FakePodVolumeRestores) UpdateStatus(podVolumeRestore *v1.PodVolumeRestore) (*v1.PodVolumeRestore, error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewUpdateSubresourceAction(podVolumeRestoresResource, "status", c.ns, podVolumeRestore), &v1.PodVolumeRestore{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1.PodVolumeRestore), err
+}
+
+func (c *FakePodVolumeRestores) Delete(name string, options *meta_v1.DeleteOptions) error {
+	_, err := c.Fake.
+		Invokes(testing.NewDeleteAction(podVolumeRestoresResource, c.ns, name), &v1.PodVolumeRestore{})
+
+	return err
+}
+
+func (c *FakePodVolumeRestores) DeleteCollection(options *meta_v1.DeleteOptions, listOptions meta_v1.ListOptions) error {
+	action := testing.NewDeleteCollectionAction(podVolumeRestoresResource, c.ns, listOptions)
+
+	_, err := c.Fake.Invokes(action, &v1.PodVolumeRestoreList{})
+	return err
+}
+
+func (c *FakePodVolumeRestores) Get(name string, options meta_v1.GetOptions) (result *v1.PodVolumeRestore, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewGetAction(podVolumeRestoresResource, c.ns, name), &v1.PodVolumeRestore{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1.PodVolumeRestore), err
+}
+
+func (c *FakePodVolumeRestores) List(opts meta_v1.ListOptions) (result *v1.PodVolumeRestoreList, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewListAction(podVolumeRestoresResource, podVolumeRestoresKind, c.ns, opts), &v1.PodVolumeRestoreList{})
+
+	if obj == nil {
+		return nil, err
+	}
+
+	label, _, _ := testing.ExtractFromListOptions(opts)
+	if label == nil {
+		label = labels.Everything()
+	}
+	list := &v1.PodVolumeRestoreList{}
+	for _, item := range obj.(*v1.PodVolumeRestoreList).Items {
+		if label.Matches(labels.Set(item.Labels)) {
+			list.Items = append(list.Items, item)
+		}
+	}
+	return list, err
+}
+
+// Watch returns a watch.Interface that watches the requested podVolumeRestores.
+func (c *FakePodVolumeRestores) Watch(opts meta_v1.ListOptions) (watch.Interface, error) {
+	return c.Fake.
+		InvokesWatch(testing.NewWatchAction(podVolumeRestoresResource, c.ns, opts))
+
+}
+
+// Patch applies the patch and returns the patched podVolumeRestore.
+func (c *FakePodVolumeRestores) Patch(name string, pt types.PatchType, data []byte, subresources ...string) (result *v1.PodVolumeRestore, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewPatchSubresourceAction(podVolumeRestoresResource, c.ns, name, data, subresources...), &v1.PodVolumeRestore{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1.PodVolumeRestore), err
+}
@@ -0,0 +1,114 @@
+package fake
+
+import (
+	v1 "github.com/heptio/ark/pkg/apis/ark/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	labels "k8s.io/apimachinery/pkg/labels"
+	schema "k8s.io/apimachinery/pkg/runtime/schema"
+	types "k8s.io/apimachinery/pkg/types"
+	watch "k8s.io/apimachinery/pkg/watch"
+	testing "k8s.io/client-go/testing"
+)
+
+// FakeResticRepositories implements ResticRepositoryInterface
+type FakeResticRepositories struct {
+	Fake *FakeArkV1
+	ns   string
+}
+
+var resticRepositoriesResource = schema.GroupVersionResource{Group: "ark.heptio.com", Version: "v1", Resource: "resticrepositories"}
+
+var resticRepositoriesKind = schema.GroupVersionKind{Group: "ark.heptio.com", Version: "v1", Kind: "ResticRepository"}
+
+func (c *FakeResticRepositories) Create(resticRepository *v1.ResticRepository) (result *v1.ResticRepository, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewCreateAction(resticRepositoriesResource, c.ns, resticRepository), &v1.ResticRepository{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1.ResticRepository), err
+}
+
+func (c *FakeResticRepositories) Update(resticRepository *v1.ResticRepository) (result *v1.ResticRepository, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewUpdateAction(resticRepositoriesResource, c.ns, resticRepository), &v1.ResticRepository{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1.ResticRepository), err
+}
+
+func (c *FakeResticRepositories) UpdateStatus(resticRepository *v1.ResticRepository) (*v1.ResticRepository, error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewUpdateSubresourceAction(resticRepositoriesResource, "status", c.ns, resticRepository), &v1.ResticRepository{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1.ResticRepository), err
+}
+
+func (c *FakeResticRepositories) Delete(name string, options *meta_v1.DeleteOptions) error {
+	_, err := c.Fake.
+		Invokes(testing.NewDeleteAction(resticRepositoriesResource, c.ns, name), &v1.ResticRepository{})
+
+	return err
+}
+
+func (c *FakeResticRepositories) DeleteCollection(options *meta_v1.DeleteOptions, listOptions meta_v1.ListOptions) error {
+	action := testing.NewDeleteCollectionAction(resticRepositoriesResource, c.ns, listOptions)
+
+	_, err := c.Fake.Invokes(action, &v1.ResticRepositoryList{})
+	return err
+}
+
+func (c *FakeResticRepositories) Get(name string, options meta_v1.GetOptions) (result *v1.ResticRepository, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewGetAction(resticRepositoriesResource, c.ns, name), &v1.ResticRepository{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1.ResticRepository), err
+}
+
+func (c *FakeResticRepositories) List(opts meta_v1.ListOptions) (result *v1.ResticRepositoryList, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewListAction(resticRepositoriesResource, resticRepositoriesKind, c.ns, opts), &v1.ResticRepositoryList{})
+
+	if obj == nil {
+		return nil, err
+	}
+
+	label, _, _ := testing.ExtractFromListOptions(opts)
+	if label == nil {
+		label = labels.Everything()
+	}
+	list := &v1.ResticRepositoryList{}
+	for _, item := range obj.(*v1.ResticRepositoryList).Items {
+		if label.Matches(labels.Set(item.Labels)) {
+			list.Items = append(list.Items, item)
+		}
+	}
+	return list, err
+}
+
+// Watch returns a watch.Interface that watches the requested resticRepositories.
+func (c *FakeResticRepositories) Watch(opts meta_v1.ListOptions) (watch.Interface, error) {
+	return c.Fake.
+		InvokesWatch(testing.NewWatchAction(resticRepositoriesResource, c.ns, opts))
+
+}
+
+// Patch applies the patch and returns the patched resticRepository.
+func (c *FakeResticRepositories) Patch(name string, pt types.PatchType, data []byte, subresources ...string) (result *v1.ResticRepository, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewPatchSubresourceAction(resticRepositoriesResource, c.ns, name, data, subresources...), &v1.ResticRepository{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1.ResticRepository), err
+}
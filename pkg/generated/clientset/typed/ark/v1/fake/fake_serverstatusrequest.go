@@ -0,0 +1,114 @@
+package fake
+
+import (
+	v1 "github.com/heptio/ark/pkg/apis/ark/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	labels "k8s.io/apimachinery/pkg/labels"
+	schema "k8s.io/apimachinery/pkg/runtime/schema"
+	types "k8s.io/apimachinery/pkg/types"
+	watch "k8s.io/apimachinery/pkg/watch"
+	testing "k8s.io/client-go/testing"
+)
+
+// FakeServerStatusRequests implements ServerStatusRequestInterface
+type FakeServerStatusRequests struct {
+	Fake *FakeArkV1
+	ns   string
+}
+
+var serverStatusRequestsResource = schema.GroupVersionResource{Group: "ark.heptio.com", Version: "v1", Resource: "serverstatusrequests"}
+
+var serverStatusRequestsKind = schema.GroupVersionKind{Group: "ark.heptio.com", Version: "v1", Kind: "ServerStatusRequest"}
+
+func (c *FakeServerStatusRequests) Create(serverStatusRequest *v1.ServerStatusRequest) (result *v1.ServerStatusRequest, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewCreateAction(serverStatusRequestsResource, c.ns, serverStatusRequest), &v1.ServerStatusRequest{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1.ServerStatusRequest), err
+}
+
+func (c *FakeServerStatusRequests) Update(serverStatusRequest *v1.ServerStatusRequest) (result *v1.ServerStatusRequest, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewUpdateAction(serverStatusRequestsResource, c.ns, serverStatusRequest), &v1.ServerStatusRequest{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1.ServerStatusRequest), err
+}
+
+func (c *FakeServerStatusRequests) UpdateStatus(serverStatusRequest *v1.ServerStatusRequest) (*v1.ServerStatusRequest, error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewUpdateSubresourceAction(serverStatusRequestsResource, "status", c.ns, serverStatusRequest), &v1.ServerStatusRequest{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1.ServerStatusRequest), err
+}
+
+func (c *FakeServerStatusRequests) Delete(name string, options *meta_v1.DeleteOptions) error {
+	_, err := c.Fake.
+		Invokes(testing.NewDeleteAction(serverStatusRequestsResource, c.ns, name), &v1.ServerStatusRequest{})
+
+	return err
+}
+
+func (c *FakeServerStatusRequests) DeleteCollection(options *meta_v1.DeleteOptions, listOptions meta_v1.ListOptions) error {
+	action := testing.NewDeleteCollectionAction(serverStatusRequestsResource, c.ns, listOptions)
+
+	_, err := c.Fake.Invokes(action, &v1.ServerStatusRequestList{})
+	return err
+}
+
+func (c *FakeServerStatusRequests) Get(name string, options meta_v1.GetOptions) (result *v1.ServerStatusRequest, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewGetAction(serverStatusRequestsResource, c.ns, name), &v1.ServerStatusRequest{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1.ServerStatusRequest), err
+}
+
+func (c *FakeServerStatusRequests) List(opts meta_v1.ListOptions) (result *v1.ServerStatusRequestList, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewListAction(serverStatusRequestsResource, serverStatusRequestsKind, c.ns, opts), &v1.ServerStatusRequestList{})
+
+	if obj == nil {
+		return nil, err
+	}
+
+	label, _, _ := testing.ExtractFromListOptions(opts)
+	if label == nil {
+		label = labels.Everything()
+	}
+	list := &v1.ServerStatusRequestList{}
+	for _, item := range obj.(*v1.ServerStatusRequestList).Items {
+		if label.Matches(labels.Set(item.Labels)) {
+			list.Items = append(list.Items, item)
+		}
+	}
+	return list, err
+}
+
+// Watch returns a watch.Interface that watches the requested serverStatusRequests.
+func (c *FakeServerStatusRequests) Watch(opts meta_v1.ListOptions) (watch.Interface, error) {
+	return c.Fake.
+		InvokesWatch(testing.NewWatchAction(serverStatusRequestsResource, c.ns, opts))
+
+}
+
+// Patch applies the patch and returns the patched serverStatusRequest.
+func (c *FakeServerStatusRequests) Patch(name string, pt types.PatchType, data []byte, subresources ...string) (result *v1.ServerStatusRequest, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewPatchSubresourceAction(serverStatusRequestsResource, c.ns, name, data, subresources...), &v1.ServerStatusRequest{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1.ServerStatusRequest), err
+}
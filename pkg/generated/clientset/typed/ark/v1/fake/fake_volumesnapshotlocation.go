@@ -0,0 +1,104 @@
+package fake
+
+import (
+	v1 "github.com/heptio/ark/pkg/apis/ark/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	labels "k8s.io/apimachinery/pkg/labels"
+	schema "k8s.io/apimachinery/pkg/runtime/schema"
+	types "k8s.io/apimachinery/pkg/types"
+	watch "k8s.io/apimachinery/pkg/watch"
+	testing "k8s.io/client-go/testing"
+)
+
+// FakeVolumeSnapshotLocations implements VolumeSnapshotLocationInterface
+type FakeVolumeSnapshotLocations struct {
+	Fake *FakeArkV1
+	ns   string
+}
+
+var volumeSnapshotLocationsResource = schema.GroupVersionResource{Group: "ark.heptio.com", Version: "v1", Resource: "volumesnapshotlocations"}
+
+var volumeSnapshotLocationsKind = schema.GroupVersionKind{Group: "ark.heptio.com", Version: "v1", Kind: "VolumeSnapshotLocation"}
+
+func (c *FakeVolumeSnapshotLocations) Create(volumeSnapshotLocation *v1.VolumeSnapshotLocation) (result *v1.VolumeSnapshotLocation, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewCreateAction(volumeSnapshotLocationsResource, c.ns, volumeSnapshotLocation), &v1.VolumeSnapshotLocation{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1.VolumeSnapshotLocation), err
+}
+
+func (c *FakeVolumeSnapshotLocations) Update(volumeSnapshotLocation *v1.VolumeSnapshotLocation) (result *v1.VolumeSnapshotLocation, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewUpdateAction(volumeSnapshotLocationsResource, c.ns, volumeSnapshotLocation), &v1.VolumeSnapshotLocation{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1.VolumeSnapshotLocation), err
+}
+
+func (c *FakeVolumeSnapshotLocations) Delete(name string, options *meta_v1.DeleteOptions) error {
+	_, err := c.Fake.
+		Invokes(testing.NewDeleteAction(volumeSnapshotLocationsResource, c.ns, name), &v1.VolumeSnapshotLocation{})
+
+	return err
+}
+
+func (c *FakeVolumeSnapshotLocations) DeleteCollection(options *meta_v1.DeleteOptions, listOptions meta_v1.ListOptions) error {
+	action := testing.NewDeleteCollectionAction(volumeSnapshotLocationsResource, c.ns, listOptions)
+
+	_, err := c.Fake.Invokes(action, &v1.VolumeSnapshotLocationList{})
+	return err
+}
+
+func (c *FakeVolumeSnapshotLocations) Get(name string, options meta_v1.GetOptions) (result *v1.VolumeSnapshotLocation, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewGetAction(volumeSnapshotLocationsResource, c.ns, name), &v1.VolumeSnapshotLocation{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1.VolumeSnapshotLocation), err
+}
+
+func (c *FakeVolumeSnapshotLocations) List(opts meta_v1.ListOptions) (result *v1.VolumeSnapshotLocationList, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewListAction(volumeSnapshotLocationsResource, volumeSnapshotLocationsKind, c.ns, opts), &v1.VolumeSnapshotLocationList{})
+
+	if obj == nil {
+		return nil, err
+	}
+
+	label, _, _ := testing.ExtractFromListOptions(opts)
+	if label == nil {
+		label = labels.Everything()
+	}
+	list := &v1.VolumeSnapshotLocationList{}
+	for _, item := range obj.(*v1.VolumeSnapshotLocationList).Items {
+		if label.Matches(labels.Set(item.Labels)) {
+			list.Items = append(list.Items, item)
+		}
+	}
+	return list, err
+}
+
+// Watch returns a watch.Interface that watches the requested volumeSnapshotLocations.
+func (c *FakeVolumeSnapshotLocations) Watch(opts meta_v1.ListOptions) (watch.Interface, error) {
+	return c.Fake.
+		InvokesWatch(testing.NewWatchAction(volumeSnapshotLocationsResource, c.ns, opts))
+
+}
+
+// Patch applies the patch and returns the patched volumeSnapshotLocation.
+func (c *FakeVolumeSnapshotLocations) Patch(name string, pt types.PatchType, data []byte, subresources ...string) (result *v1.VolumeSnapshotLocation, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewPatchSubresourceAction(volumeSnapshotLocationsResource, c.ns, name, data, subresources...), &v1.VolumeSnapshotLocation{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1.VolumeSnapshotLocation), err
+}
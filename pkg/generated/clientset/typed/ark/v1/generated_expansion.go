@@ -2,8 +2,26 @@ package v1
 
 type BackupExpansion interface{}
 
+type BackupStorageLocationExpansion interface{}
+
+type BackupVerificationExpansion interface{}
+
 type ConfigExpansion interface{}
 
+type DeleteBackupRequestExpansion interface{}
+
+type DownloadRequestExpansion interface{}
+
+type PodVolumeBackupExpansion interface{}
+
+type PodVolumeRestoreExpansion interface{}
+
+type ResticRepositoryExpansion interface{}
+
 type RestoreExpansion interface{}
 
 type ScheduleExpansion interface{}
+
+type ServerStatusRequestExpansion interface{}
+
+type VolumeSnapshotLocationExpansion interface{}
@@ -0,0 +1,156 @@
+package v1
+
+import (
+	v1 "github.com/heptio/ark/pkg/apis/ark/v1"
+	scheme "github.com/heptio/ark/pkg/generated/clientset/scheme"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	types "k8s.io/apimachinery/pkg/types"
+	watch "k8s.io/apimachinery/pkg/watch"
+	rest "k8s.io/client-go/rest"
+)
+
+// ServerStatusRequestsGetter has a method to return a ServerStatusRequestInterface.
+// A group's client should implement this interface.
+type ServerStatusRequestsGetter interface {
+	ServerStatusRequests(namespace string) ServerStatusRequestInterface
+}
+
+// ServerStatusRequestInterface has methods to work with ServerStatusRequest resources.
+type ServerStatusRequestInterface interface {
+	Create(*v1.ServerStatusRequest) (*v1.ServerStatusRequest, error)
+	Update(*v1.ServerStatusRequest) (*v1.ServerStatusRequest, error)
+	UpdateStatus(*v1.ServerStatusRequest) (*v1.ServerStatusRequest, error)
+	Delete(name string, options *meta_v1.DeleteOptions) error
+	DeleteCollection(options *meta_v1.DeleteOptions, listOptions meta_v1.ListOptions) error
+	Get(name string, options meta_v1.GetOptions) (*v1.ServerStatusRequest, error)
+	List(opts meta_v1.ListOptions) (*v1.ServerStatusRequestList, error)
+	Watch(opts meta_v1.ListOptions) (watch.Interface, error)
+	Patch(name string, pt types.PatchType, data []byte, subresources ...string) (result *v1.ServerStatusRequest, err error)
+	ServerStatusRequestExpansion
+}
+
+// serverStatusRequests implements ServerStatusRequestInterface
+type serverStatusRequests struct {
+	client rest.Interface
+	ns     string
+}
+
+// newServerStatusRequests returns a ServerStatusRequests
+func newServerStatusRequests(c *ArkV1Client, namespace string) *serverStatusRequests {
+	return &serverStatusRequests{
+		client: c.RESTClient(),
+		ns:     namespace,
+	}
+}
+
+// Create takes the representation of a serverStatusRequest and creates it.  Returns the server's representation of the serverStatusRequest, and an error, if there is any.
+func (c *serverStatusRequests) Create(serverStatusRequest *v1.ServerStatusRequest) (result *v1.ServerStatusRequest, err error) {
+	result = &v1.ServerStatusRequest{}
+	err = c.client.Post().
+		Namespace(c.ns).
+		Resource("serverstatusrequests").
+		Body(serverStatusRequest).
+		Do().
+		Into(result)
+	return
+}
+
+// Update takes the representation of a serverStatusRequest and updates it. Returns the server's representation of the serverStatusRequest, and an error, if there is any.
+func (c *serverStatusRequests) Update(serverStatusRequest *v1.ServerStatusRequest) (result *v1.ServerStatusRequest, err error) {
+	result = &v1.ServerStatusRequest{}
+	err = c.client.Put().
+		Namespace(c.ns).
+		Resource("serverstatusrequests").
+		Name(serverStatusRequest.Name).
+		Body(serverStatusRequest).
+		Do().
+		Into(result)
+	return
+}
+
+// UpdateStatus was generated because the type contains a Status member.
+// Add a +genclientstatus=false comment above the type to avoid generating UpdateStatus().
+
+func (c *serverStatusRequests) UpdateStatus(serverStatusRequest *v1.ServerStatusRequest) (result *v1.ServerStatusRequest, err error) {
+	result = &v1.ServerStatusRequest{}
+	err = c.client.Put().
+		Namespace(c.ns).
+		Resource("serverstatusrequests").
+		Name(serverStatusRequest.Name).
+		SubResource("status").
+		Body(serverStatusRequest).
+		Do().
+		Into(result)
+	return
+}
+
+// Delete takes name of the serverStatusRequest and deletes it. Returns an error if one occurs.
+func (c *serverStatusRequests) Delete(name string, options *meta_v1.DeleteOptions) error {
+	return c.client.Delete().
+		Namespace(c.ns).
+		Resource("serverstatusrequests").
+		Name(name).
+		Body(options).
+		Do().
+		Error()
+}
+
+// DeleteCollection deletes a collection of objects.
+func (c *serverStatusRequests) DeleteCollection(options *meta_v1.DeleteOptions, listOptions meta_v1.ListOptions) error {
+	return c.client.Delete().
+		Namespace(c.ns).
+		Resource("serverstatusrequests").
+		VersionedParams(&listOptions, scheme.ParameterCodec).
+		Body(options).
+		Do().
+		Error()
+}
+
+// Get takes name of the serverStatusRequest, and returns the corresponding serverStatusRequest object, and an error if there is any.
+func (c *serverStatusRequests) Get(name string, options meta_v1.GetOptions) (result *v1.ServerStatusRequest, err error) {
+	result = &v1.ServerStatusRequest{}
+	err = c.client.Get().
+		Namespace(c.ns).
+		Resource("serverstatusrequests").
+		Name(name).
+		VersionedParams(&options, scheme.ParameterCodec).
+		Do().
+		Into(result)
+	return
+}
+
+// List takes label and field selectors, and returns the list of ServerStatusRequests that match those selectors.
+func (c *serverStatusRequests) List(opts meta_v1.ListOptions) (result *v1.ServerStatusRequestList, err error) {
+	result = &v1.ServerStatusRequestList{}
+	err = c.client.Get().
+		Namespace(c.ns).
+		Resource("serverstatusrequests").
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Do().
+		Into(result)
+	return
+}
+
+// Watch returns a watch.Interface that watches the requested serverStatusRequests.
+func (c *serverStatusRequests) Watch(opts meta_v1.ListOptions) (watch.Interface, error) {
+	opts.Watch = true
+	return c.client.Get().
+		Namespace(c.ns).
+		Resource("serverstatusrequests").
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Watch()
+}
+
+// Patch applies the patch and returns the patched serverStatusRequest.
+func (c *serverStatusRequests) Patch(name string, pt types.PatchType, data []byte, subresources ...string) (result *v1.ServerStatusRequest, err error) {
+	result = &v1.ServerStatusRequest{}
+	err = c.client.Patch(pt).
+		Namespace(c.ns).
+		Resource("serverstatusrequests").
+		SubResource(subresources...).
+		Name(name).
+		Body(data).
+		Do().
+		Into(result)
+	return
+}
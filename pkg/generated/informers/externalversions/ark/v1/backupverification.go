@@ -0,0 +1,52 @@
+// This file was automatically generated by informer-gen
+
+package v1
+
+import (
+	ark_v1 "github.com/heptio/ark/pkg/apis/ark/v1"
+	clientset "github.com/heptio/ark/pkg/generated/clientset"
+	internalinterfaces "github.com/heptio/ark/pkg/generated/informers/externalversions/internalinterfaces"
+	v1 "github.com/heptio/ark/pkg/generated/listers/ark/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	runtime "k8s.io/apimachinery/pkg/runtime"
+	watch "k8s.io/apimachinery/pkg/watch"
+	cache "k8s.io/client-go/tools/cache"
+	time "time"
+)
+
+// BackupVerificationInformer provides access to a shared informer and lister for
+// BackupVerifications.
+type BackupVerificationInformer interface {
+	Informer() cache.SharedIndexInformer
+	Lister() v1.BackupVerificationLister
+}
+
+type backupVerificationInformer struct {
+	factory internalinterfaces.SharedInformerFactory
+}
+
+func newBackupVerificationInformer(client clientset.Interface, resyncPeriod time.Duration) cache.SharedIndexInformer {
+	sharedIndexInformer := cache.NewSharedIndexInformer(
+		&cache.ListWatch{
+			ListFunc: func(options meta_v1.ListOptions) (runtime.Object, error) {
+				return client.ArkV1().BackupVerifications(meta_v1.NamespaceAll).List(options)
+			},
+			WatchFunc: func(options meta_v1.ListOptions) (watch.Interface, error) {
+				return client.ArkV1().BackupVerifications(meta_v1.NamespaceAll).Watch(options)
+			},
+		},
+		&ark_v1.BackupVerification{},
+		resyncPeriod,
+		cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc},
+	)
+
+	return sharedIndexInformer
+}
+
+func (f *backupVerificationInformer) Informer() cache.SharedIndexInformer {
+	return f.factory.InformerFor(&ark_v1.BackupVerification{}, newBackupVerificationInformer)
+}
+
+func (f *backupVerificationInformer) Lister() v1.BackupVerificationLister {
+	return v1.NewBackupVerificationLister(f.Informer().GetIndexer())
+}
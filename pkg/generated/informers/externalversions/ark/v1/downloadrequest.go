@@ -0,0 +1,52 @@
+// This file was automatically generated by informer-gen
+
+package v1
+
+import (
+	ark_v1 "github.com/heptio/ark/pkg/apis/ark/v1"
+	clientset "github.com/heptio/ark/pkg/generated/clientset"
+	internalinterfaces "github.com/heptio/ark/pkg/generated/informers/externalversions/internalinterfaces"
+	v1 "github.com/heptio/ark/pkg/generated/listers/ark/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	runtime "k8s.io/apimachinery/pkg/runtime"
+	watch "k8s.io/apimachinery/pkg/watch"
+	cache "k8s.io/client-go/tools/cache"
+	time "time"
+)
+
+// DownloadRequestInformer provides access to a shared informer and lister for
+// DownloadRequests.
+type DownloadRequestInformer interface {
+	Informer() cache.SharedIndexInformer
+	Lister() v1.DownloadRequestLister
+}
+
+type downloadRequestInformer struct {
+	factory internalinterfaces.SharedInformerFactory
+}
+
+func newDownloadRequestInformer(client clientset.Interface, resyncPeriod time.Duration) cache.SharedIndexInformer {
+	sharedIndexInformer := cache.NewSharedIndexInformer(
+		&cache.ListWatch{
+			ListFunc: func(options meta_v1.ListOptions) (runtime.Object, error) {
+				return client.ArkV1().DownloadRequests(meta_v1.NamespaceAll).List(options)
+			},
+			WatchFunc: func(options meta_v1.ListOptions) (watch.Interface, error) {
+				return client.ArkV1().DownloadRequests(meta_v1.NamespaceAll).Watch(options)
+			},
+		},
+		&ark_v1.DownloadRequest{},
+		resyncPeriod,
+		cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc},
+	)
+
+	return sharedIndexInformer
+}
+
+func (f *downloadRequestInformer) Informer() cache.SharedIndexInformer {
+	return f.factory.InformerFor(&ark_v1.DownloadRequest{}, newDownloadRequestInformer)
+}
+
+func (f *downloadRequestInformer) Lister() v1.DownloadRequestLister {
+	return v1.NewDownloadRequestLister(f.Informer().GetIndexer())
+}
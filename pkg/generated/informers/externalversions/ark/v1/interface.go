@@ -10,12 +10,30 @@ import (
 type Interface interface {
 	// Backups returns a BackupInformer.
 	Backups() BackupInformer
+	// BackupStorageLocations returns a BackupStorageLocationInformer.
+	BackupStorageLocations() BackupStorageLocationInformer
+	// BackupVerifications returns a BackupVerificationInformer.
+	BackupVerifications() BackupVerificationInformer
 	// Configs returns a ConfigInformer.
 	Configs() ConfigInformer
+	// DeleteBackupRequests returns a DeleteBackupRequestInformer.
+	DeleteBackupRequests() DeleteBackupRequestInformer
+	// DownloadRequests returns a DownloadRequestInformer.
+	DownloadRequests() DownloadRequestInformer
+	// PodVolumeBackups returns a PodVolumeBackupInformer.
+	PodVolumeBackups() PodVolumeBackupInformer
+	// PodVolumeRestores returns a PodVolumeRestoreInformer.
+	PodVolumeRestores() PodVolumeRestoreInformer
+	// ResticRepositories returns a ResticRepositoryInformer.
+	ResticRepositories() ResticRepositoryInformer
 	// Restores returns a RestoreInformer.
 	Restores() RestoreInformer
 	// Schedules returns a ScheduleInformer.
 	Schedules() ScheduleInformer
+	// ServerStatusRequests returns a ServerStatusRequestInformer.
+	ServerStatusRequests() ServerStatusRequestInformer
+	// VolumeSnapshotLocations returns a VolumeSnapshotLocationInformer.
+	VolumeSnapshotLocations() VolumeSnapshotLocationInformer
 }
 
 type version struct {
@@ -32,11 +50,46 @@ func (v *version) Backups() BackupInformer {
 	return &backupInformer{factory: v.SharedInformerFactory}
 }
 
+// BackupStorageLocations returns a BackupStorageLocationInformer.
+func (v *version) BackupStorageLocations() BackupStorageLocationInformer {
+	return &backupStorageLocationInformer{factory: v.SharedInformerFactory}
+}
+
+// BackupVerifications returns a BackupVerificationInformer.
+func (v *version) BackupVerifications() BackupVerificationInformer {
+	return &backupVerificationInformer{factory: v.SharedInformerFactory}
+}
+
 // Configs returns a ConfigInformer.
 func (v *version) Configs() ConfigInformer {
 	return &configInformer{factory: v.SharedInformerFactory}
 }
 
+// DeleteBackupRequests returns a DeleteBackupRequestInformer.
+func (v *version) DeleteBackupRequests() DeleteBackupRequestInformer {
+	return &deleteBackupRequestInformer{factory: v.SharedInformerFactory}
+}
+
+// DownloadRequests returns a DownloadRequestInformer.
+func (v *version) DownloadRequests() DownloadRequestInformer {
+	return &downloadRequestInformer{factory: v.SharedInformerFactory}
+}
+
+// PodVolumeBackups returns a PodVolumeBackupInformer.
+func (v *version) PodVolumeBackups() PodVolumeBackupInformer {
+	return &podVolumeBackupInformer{factory: v.SharedInformerFactory}
+}
+
+// PodVolumeRestores returns a PodVolumeRestoreInformer.
+func (v *version) PodVolumeRestores() PodVolumeRestoreInformer {
+	return &podVolumeRestoreInformer{factory: v.SharedInformerFactory}
+}
+
+// ResticRepositories returns a ResticRepositoryInformer.
+func (v *version) ResticRepositories() ResticRepositoryInformer {
+	return &resticRepositoryInformer{factory: v.SharedInformerFactory}
+}
+
 // Restores returns a RestoreInformer.
 func (v *version) Restores() RestoreInformer {
 	return &restoreInformer{factory: v.SharedInformerFactory}
@@ -46,3 +99,13 @@ func (v *version) Restores() RestoreInformer {
 func (v *version) Schedules() ScheduleInformer {
 	return &scheduleInformer{factory: v.SharedInformerFactory}
 }
+
+// ServerStatusRequests returns a ServerStatusRequestInformer.
+func (v *version) ServerStatusRequests() ServerStatusRequestInformer {
+	return &serverStatusRequestInformer{factory: v.SharedInformerFactory}
+}
+
+// VolumeSnapshotLocations returns a VolumeSnapshotLocationInformer.
+func (v *version) VolumeSnapshotLocations() VolumeSnapshotLocationInformer {
+	return &volumeSnapshotLocationInformer{factory: v.SharedInformerFactory}
+}
@@ -0,0 +1,78 @@
+// This file was automatically generated by lister-gen
+
+package v1
+
+import (
+	v1 "github.com/heptio/ark/pkg/apis/ark/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/tools/cache"
+)
+
+// BackupVerificationLister helps list BackupVerifications.
+type BackupVerificationLister interface {
+	// List lists all BackupVerifications in the indexer.
+	List(selector labels.Selector) (ret []*v1.BackupVerification, err error)
+	// BackupVerifications returns an object that can list and get BackupVerifications.
+	BackupVerifications(namespace string) BackupVerificationNamespaceLister
+	BackupVerificationListerExpansion
+}
+
+// backupVerificationLister implements the BackupVerificationLister interface.
+type backupVerificationLister struct {
+	indexer cache.Indexer
+}
+
+// NewBackupVerificationLister returns a new BackupVerificationLister.
+func NewBackupVerificationLister(indexer cache.Indexer) BackupVerificationLister {
+	return &backupVerificationLister{indexer: indexer}
+}
+
+// List lists all BackupVerifications in the indexer.
+func (s *backupVerificationLister) List(selector labels.Selector) (ret []*v1.BackupVerification, err error) {
+	err = cache.ListAll(s.indexer, selector, func(m interface{}) {
+		ret = append(ret, m.(*v1.BackupVerification))
+	})
+	return ret, err
+}
+
+// BackupVerifications returns an object that can list and get BackupVerifications.
+func (s *backupVerificationLister) BackupVerifications(namespace string) BackupVerificationNamespaceLister {
+	return backupVerificationNamespaceLister{indexer: s.indexer, namespace: namespace}
+}
+
+// BackupVerificationNamespaceLister helps list and get BackupVerifications.
+type BackupVerificationNamespaceLister interface {
+	// List lists all BackupVerifications in the indexer for a given namespace.
+	List(selector labels.Selector) (ret []*v1.BackupVerification, err error)
+	// Get retrieves the BackupVerification from the indexer for a given namespace and name.
+	Get(name string) (*v1.BackupVerification, error)
+	BackupVerificationNamespaceListerExpansion
+}
+
+// backupVerificationNamespaceLister implements the BackupVerificationNamespaceLister
+// interface.
+type backupVerificationNamespaceLister struct {
+	indexer   cache.Indexer
+	namespace string
+}
+
+// List lists all BackupVerifications in the indexer for a given namespace.
+func (s backupVerificationNamespaceLister) List(selector labels.Selector) (ret []*v1.BackupVerification, err error) {
+	err = cache.ListAllByNamespace(s.indexer, s.namespace, selector, func(m interface{}) {
+		ret = append(ret, m.(*v1.BackupVerification))
+	})
+	return ret, err
+}
+
+// Get retrieves the BackupVerification from the indexer for a given namespace and name.
+func (s backupVerificationNamespaceLister) Get(name string) (*v1.BackupVerification, error) {
+	obj, exists, err := s.indexer.GetByKey(s.namespace + "/" + name)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, errors.NewNotFound(v1.Resource("backupverification"), name)
+	}
+	return obj.(*v1.BackupVerification), nil
+}
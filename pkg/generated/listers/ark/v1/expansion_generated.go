@@ -10,6 +10,22 @@ type BackupListerExpansion interface{}
 // BackupNamespaceLister.
 type BackupNamespaceListerExpansion interface{}
 
+// BackupStorageLocationListerExpansion allows custom methods to be added to
+// BackupStorageLocationLister.
+type BackupStorageLocationListerExpansion interface{}
+
+// BackupStorageLocationNamespaceListerExpansion allows custom methods to be added to
+// BackupStorageLocationNamespaceLister.
+type BackupStorageLocationNamespaceListerExpansion interface{}
+
+// BackupVerificationListerExpansion allows custom methods to be added to
+// BackupVerificationLister.
+type BackupVerificationListerExpansion interface{}
+
+// BackupVerificationNamespaceListerExpansion allows custom methods to be added to
+// BackupVerificationNamespaceLister.
+type BackupVerificationNamespaceListerExpansion interface{}
+
 // ConfigListerExpansion allows custom methods to be added to
 // ConfigLister.
 type ConfigListerExpansion interface{}
@@ -18,6 +34,46 @@ type ConfigListerExpansion interface{}
 // ConfigNamespaceLister.
 type ConfigNamespaceListerExpansion interface{}
 
+// DeleteBackupRequestListerExpansion allows custom methods to be added to
+// DeleteBackupRequestLister.
+type DeleteBackupRequestListerExpansion interface{}
+
+// DeleteBackupRequestNamespaceListerExpansion allows custom methods to be added to
+// DeleteBackupRequestNamespaceLister.
+type DeleteBackupRequestNamespaceListerExpansion interface{}
+
+// DownloadRequestListerExpansion allows custom methods to be added to
+// DownloadRequestLister.
+type DownloadRequestListerExpansion interface{}
+
+// DownloadRequestNamespaceListerExpansion allows custom methods to be added to
+// DownloadRequestNamespaceLister.
+type DownloadRequestNamespaceListerExpansion interface{}
+
+// PodVolumeBackupListerExpansion allows custom methods to be added to
+// PodVolumeBackupLister.
+type PodVolumeBackupListerExpansion interface{}
+
+// PodVolumeBackupNamespaceListerExpansion allows custom methods to be added to
+// PodVolumeBackupNamespaceLister.
+type PodVolumeBackupNamespaceListerExpansion interface{}
+
+// PodVolumeRestoreListerExpansion allows custom methods to be added to
+// PodVolumeRestoreLister.
+type PodVolumeRestoreListerExpansion interface{}
+
+// PodVolumeRestoreNamespaceListerExpansion allows custom methods to be added to
+// PodVolumeRestoreNamespaceLister.
+type PodVolumeRestoreNamespaceListerExpansion interface{}
+
+// ResticRepositoryListerExpansion allows custom methods to be added to
+// ResticRepositoryLister.
+type ResticRepositoryListerExpansion interface{}
+
+// ResticRepositoryNamespaceListerExpansion allows custom methods to be added to
+// ResticRepositoryNamespaceLister.
+type ResticRepositoryNamespaceListerExpansion interface{}
+
 // RestoreListerExpansion allows custom methods to be added to
 // RestoreLister.
 type RestoreListerExpansion interface{}
@@ -33,3 +89,19 @@ type ScheduleListerExpansion interface{}
 // ScheduleNamespaceListerExpansion allows custom methods to be added to
 // ScheduleNamespaceLister.
 type ScheduleNamespaceListerExpansion interface{}
+
+// ServerStatusRequestListerExpansion allows custom methods to be added to
+// ServerStatusRequestLister.
+type ServerStatusRequestListerExpansion interface{}
+
+// ServerStatusRequestNamespaceListerExpansion allows custom methods to be added to
+// ServerStatusRequestNamespaceLister.
+type ServerStatusRequestNamespaceListerExpansion interface{}
+
+// VolumeSnapshotLocationListerExpansion allows custom methods to be added to
+// VolumeSnapshotLocationLister.
+type VolumeSnapshotLocationListerExpansion interface{}
+
+// VolumeSnapshotLocationNamespaceListerExpansion allows custom methods to be added to
+// VolumeSnapshotLocationNamespaceLister.
+type VolumeSnapshotLocationNamespaceListerExpansion interface{}
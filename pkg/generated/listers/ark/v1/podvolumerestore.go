@@ -0,0 +1,78 @@
+// This file was automatically generated by lister-gen
+
+package v1
+
+import (
+	v1 "github.com/heptio/ark/pkg/apis/ark/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/tools/cache"
+)
+
+// PodVolumeRestoreLister helps list PodVolumeRestores.
+type PodVolumeRestoreLister interface {
+	// List lists all PodVolumeRestores in the indexer.
+	List(selector labels.Selector) (ret []*v1.PodVolumeRestore, err error)
+	// PodVolumeRestores returns an object that can list and get PodVolumeRestores.
+	PodVolumeRestores(namespace string) PodVolumeRestoreNamespaceLister
+	PodVolumeRestoreListerExpansion
+}
+
+// podVolumeRestoreLister implements the PodVolumeRestoreLister interface.
+type podVolumeRestoreLister struct {
+	indexer cache.Indexer
+}
+
+// NewPodVolumeRestoreLister returns a new PodVolumeRestoreLister.
+func NewPodVolumeRestoreLister(indexer cache.Indexer) PodVolumeRestoreLister {
+	return &podVolumeRestoreLister{indexer: indexer}
+}
+
+// List lists all PodVolumeRestores in the indexer.
+func (s *podVolumeRestoreLister) List(selector labels.Selector) (ret []*v1.PodVolumeRestore, err error) {
+	err = cache.ListAll(s.indexer, selector, func(m interface{}) {
+		ret = append(ret, m.(*v1.PodVolumeRestore))
+	})
+	return ret, err
+}
+
+// PodVolumeRestores returns an object that can list and get PodVolumeRestores.
+func (s *podVolumeRestoreLister) PodVolumeRestores(namespace string) PodVolumeRestoreNamespaceLister {
+	return podVolumeRestoreNamespaceLister{indexer: s.indexer, namespace: namespace}
+}
+
+// PodVolumeRestoreNamespaceLister helps list and get PodVolumeRestores.
+type PodVolumeRestoreNamespaceLister interface {
+	// List lists all PodVolumeRestores in the indexer for a given namespace.
+	List(selector labels.Selector) (ret []*v1.PodVolumeRestore, err error)
+	// Get retrieves the PodVolumeRestore from the indexer for a given namespace and name.
+	Get(name string) (*v1.PodVolumeRestore, error)
+	PodVolumeRestoreNamespaceListerExpansion
+}
+
+// podVolumeRestoreNamespaceLister implements the PodVolumeRestoreNamespaceLister
+// interface.
+type podVolumeRestoreNamespaceLister struct {
+	indexer   cache.Indexer
+	namespace string
+}
+
+// List lists all PodVolumeRestores in the indexer for a given namespace.
+func (s podVolumeRestoreNamespaceLister) List(selector labels.Selector) (ret []*v1.PodVolumeRestore, err error) {
+	err = cache.ListAllByNamespace(s.indexer, s.namespace, selector, func(m interface{}) {
+		ret = append(ret, m.(*v1.PodVolumeRestore))
+	})
+	return ret, err
+}
+
+// Get retrieves the PodVolumeRestore from the indexer for a given namespace and name.
+func (s podVolumeRestoreNamespaceLister) Get(name string) (*v1.PodVolumeRestore, error) {
+	obj, exists, err := s.indexer.GetByKey(s.namespace + "/" + name)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, errors.NewNotFound(v1.Resource("podvolumerestore"), name)
+	}
+	return obj.(*v1.PodVolumeRestore), nil
+}
@@ -0,0 +1,78 @@
+// This file was automatically generated by lister-gen
+
+package v1
+
+import (
+	v1 "github.com/heptio/ark/pkg/apis/ark/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/tools/cache"
+)
+
+// ServerStatusRequestLister helps list ServerStatusRequests.
+type ServerStatusRequestLister interface {
+	// List lists all ServerStatusRequests in the indexer.
+	List(selector labels.Selector) (ret []*v1.ServerStatusRequest, err error)
+	// ServerStatusRequests returns an object that can list and get ServerStatusRequests.
+	ServerStatusRequests(namespace string) ServerStatusRequestNamespaceLister
+	ServerStatusRequestListerExpansion
+}
+
+// serverStatusRequestLister implements the ServerStatusRequestLister interface.
+type serverStatusRequestLister struct {
+	indexer cache.Indexer
+}
+
+// NewServerStatusRequestLister returns a new ServerStatusRequestLister.
+func NewServerStatusRequestLister(indexer cache.Indexer) ServerStatusRequestLister {
+	return &serverStatusRequestLister{indexer: indexer}
+}
+
+// List lists all ServerStatusRequests in the indexer.
+func (s *serverStatusRequestLister) List(selector labels.Selector) (ret []*v1.ServerStatusRequest, err error) {
+	err = cache.ListAll(s.indexer, selector, func(m interface{}) {
+		ret = append(ret, m.(*v1.ServerStatusRequest))
+	})
+	return ret, err
+}
+
+// ServerStatusRequests returns an object that can list and get ServerStatusRequests.
+func (s *serverStatusRequestLister) ServerStatusRequests(namespace string) ServerStatusRequestNamespaceLister {
+	return serverStatusRequestNamespaceLister{indexer: s.indexer, namespace: namespace}
+}
+
+// ServerStatusRequestNamespaceLister helps list and get ServerStatusRequests.
+type ServerStatusRequestNamespaceLister interface {
+	// List lists all ServerStatusRequests in the indexer for a given namespace.
+	List(selector labels.Selector) (ret []*v1.ServerStatusRequest, err error)
+	// Get retrieves the ServerStatusRequest from the indexer for a given namespace and name.
+	Get(name string) (*v1.ServerStatusRequest, error)
+	ServerStatusRequestNamespaceListerExpansion
+}
+
+// serverStatusRequestNamespaceLister implements the ServerStatusRequestNamespaceLister
+// interface.
+type serverStatusRequestNamespaceLister struct {
+	indexer   cache.Indexer
+	namespace string
+}
+
+// List lists all ServerStatusRequests in the indexer for a given namespace.
+func (s serverStatusRequestNamespaceLister) List(selector labels.Selector) (ret []*v1.ServerStatusRequest, err error) {
+	err = cache.ListAllByNamespace(s.indexer, s.namespace, selector, func(m interface{}) {
+		ret = append(ret, m.(*v1.ServerStatusRequest))
+	})
+	return ret, err
+}
+
+// Get retrieves the ServerStatusRequest from the indexer for a given namespace and name.
+func (s serverStatusRequestNamespaceLister) Get(name string) (*v1.ServerStatusRequest, error) {
+	obj, exists, err := s.indexer.GetByKey(s.namespace + "/" + name)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, errors.NewNotFound(v1.Resource("serverstatusrequest"), name)
+	}
+	return obj.(*v1.ServerStatusRequest), nil
+}
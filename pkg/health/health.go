@@ -0,0 +1,116 @@
+/*
+Copyright 2017 Heptio Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package health serves the Ark server's liveness and readiness probes.
+package health
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/golang/glog"
+)
+
+const (
+	// DefaultAddress is the address the probes are served on if none is configured.
+	DefaultAddress = ":8086"
+
+	// LivenessPath is the path the liveness probe is served at. It fails if the server is
+	// wedged, and should be restarted.
+	LivenessPath = "/healthz"
+
+	// ReadinessPath is the path the readiness probe is served at. It fails if the server
+	// can't currently do its work.
+	ReadinessPath = "/readyz"
+)
+
+// Check is a named check of the server's health.
+type Check struct {
+	Name string
+
+	// Func returns an error describing what's wrong, or nil if the check passes.
+	Func func() error
+}
+
+// Server serves the liveness and readiness probes over HTTP.
+type Server struct {
+	server *http.Server
+}
+
+// NewServer returns a Server that listens on address, and serves probes that pass if all of
+// their checks do.
+func NewServer(address string, liveness, readiness []Check) *Server {
+	mux := http.NewServeMux()
+	mux.Handle(LivenessPath, Handler(liveness))
+	mux.Handle(ReadinessPath, Handler(readiness))
+
+	return &Server{
+		server: &http.Server{
+			Addr:    address,
+			Handler: mux,
+		},
+	}
+}
+
+// Run serves the probes until ctx is done.
+func (s *Server) Run(ctx context.Context) error {
+	go func() {
+		<-ctx.Done()
+		if err := s.server.Shutdown(context.Background()); err != nil {
+			glog.Errorf("error shutting down health server: %v", err)
+		}
+	}()
+
+	glog.Infof("Serving health probes on %s%s and %s%s", s.server.Addr, LivenessPath, s.server.Addr, ReadinessPath)
+	if err := s.server.ListenAndServe(); err != http.ErrServerClosed {
+		return err
+	}
+	return nil
+}
+
+// Handler returns a handler that runs checks, responding with 200 and "ok" if they all pass,
+// or 503 and the failed checks' errors otherwise. With the verbose query parameter, every
+// check's result is listed.
+func Handler(checks []Check) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, verbose := r.URL.Query()["verbose"]
+
+		var (
+			failed bool
+			buf    bytes.Buffer
+		)
+		for _, check := range checks {
+			if err := check.Func(); err != nil {
+				failed = true
+				fmt.Fprintf(&buf, "[-]%s failed: %v\n", check.Name, err)
+			} else if verbose {
+				fmt.Fprintf(&buf, "[+]%s ok\n", check.Name)
+			}
+		}
+
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		if failed {
+			glog.V(4).Infof("%s failed: %s", r.URL.Path, buf.String())
+			w.WriteHeader(http.StatusServiceUnavailable)
+			buf.WriteString("failed\n")
+		} else {
+			buf.WriteString("ok\n")
+		}
+		w.Write(buf.Bytes())
+	})
+}
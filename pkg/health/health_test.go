@@ -0,0 +1,84 @@
+/*
+Copyright 2017 Heptio Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package health
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHandler(t *testing.T) {
+	passing := Check{Name: "informers", Func: func() error { return nil }}
+	failing := Check{Name: "leader", Func: func() error { return errors.New("not the leader") }}
+
+	tests := []struct {
+		name         string
+		checks       []Check
+		url          string
+		expectedCode int
+		expectedBody string
+	}{
+		{
+			name:         "no checks",
+			url:          "/readyz",
+			expectedCode: http.StatusOK,
+			expectedBody: "ok\n",
+		},
+		{
+			name:         "passing checks",
+			checks:       []Check{passing},
+			url:          "/readyz",
+			expectedCode: http.StatusOK,
+			expectedBody: "ok\n",
+		},
+		{
+			name:         "passing checks, verbose",
+			checks:       []Check{passing},
+			url:          "/readyz?verbose",
+			expectedCode: http.StatusOK,
+			expectedBody: "[+]informers ok\nok\n",
+		},
+		{
+			name:         "failing check",
+			checks:       []Check{passing, failing},
+			url:          "/readyz",
+			expectedCode: http.StatusServiceUnavailable,
+			expectedBody: "[-]leader failed: not the leader\nfailed\n",
+		},
+		{
+			name:         "failing check, verbose",
+			checks:       []Check{passing, failing},
+			url:          "/readyz?verbose",
+			expectedCode: http.StatusServiceUnavailable,
+			expectedBody: "[+]informers ok\n[-]leader failed: not the leader\nfailed\n",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			res := httptest.NewRecorder()
+			Handler(test.checks).ServeHTTP(res, httptest.NewRequest("GET", test.url, nil))
+
+			assert.Equal(t, test.expectedCode, res.Code)
+			assert.Equal(t, test.expectedBody, res.Body.String())
+		})
+	}
+}
@@ -0,0 +1,343 @@
+/*
+Copyright 2017 Heptio Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package leaderelection elects one leader among the replicas of the Ark server, so that only
+// one of them runs the controllers at a time. The leader holds a lease, recorded in an annotation
+// on a ConfigMap, which it renews periodically. If the leader stops renewing it, another replica
+// takes over once the lease has expired.
+package leaderelection
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/clock"
+	corev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/pkg/api/v1"
+)
+
+const (
+	// LeaderAnnotation is the annotation on the lock ConfigMap that holds the leader's Record.
+	LeaderAnnotation = "ark.heptio.com/leader"
+
+	// DefaultLockName is the name of the ConfigMap used as the lock if none is configured.
+	DefaultLockName = "ark-leader"
+
+	// DefaultLeaseDuration, DefaultRenewDeadline, and DefaultRetryPeriod are the defaults for
+	// the corresponding Config fields.
+	DefaultLeaseDuration = 15 * time.Second
+	DefaultRenewDeadline = 10 * time.Second
+	DefaultRetryPeriod   = 2 * time.Second
+)
+
+// ErrLeaseLost is returned by Elector.Run when the lease couldn't be renewed.
+var ErrLeaseLost = errors.New("lost the leader election lease")
+
+// Record is the lease held by the leader.
+type Record struct {
+	HolderIdentity       string      `json:"holderIdentity"`
+	LeaseDurationSeconds int         `json:"leaseDurationSeconds"`
+	AcquireTime          metav1.Time `json:"acquireTime"`
+	RenewTime            metav1.Time `json:"renewTime"`
+	LeaderTransitions    int         `json:"leaderTransitions"`
+}
+
+// Config configures an Elector.
+type Config struct {
+	// Namespace and Name identify the ConfigMap used as the lock. It's created if it doesn't exist.
+	Namespace string
+	Name      string
+
+	// Identity uniquely identifies this candidate among all the candidates.
+	Identity string
+
+	// LeaseDuration is how long candidates wait, after last seeing the lease change, before
+	// taking it over from a leader that hasn't renewed it.
+	LeaseDuration time.Duration
+	// RenewDeadline is how long the leader keeps retrying to renew the lease before giving up
+	// leadership. It must be less than LeaseDuration.
+	RenewDeadline time.Duration
+	// RetryPeriod is how often candidates try to acquire the lease, and the leader tries to
+	// renew it. It must be less than RenewDeadline.
+	RetryPeriod time.Duration
+}
+
+// Elector acquires and renews the lease for one candidate.
+type Elector struct {
+	config Config
+	client corev1.ConfigMapsGetter
+	clock  clock.Clock
+
+	// observedRecord is the raw Record last read from the lock, and observedTime is when it
+	// was first seen. Leases expire based on this candidate's clock rather than the holder's
+	// timestamps, so clock skew between them doesn't matter.
+	observedRecord string
+	observedTime   time.Time
+
+	// renewed is when this candidate last acquired or renewed the lease, or zero if it doesn't
+	// hold it.
+	renewedLock sync.Mutex
+	renewed     time.Time
+}
+
+// NewElector returns an Elector that uses the ConfigMap named in config as its lock.
+func NewElector(client corev1.ConfigMapsGetter, config Config) (*Elector, error) {
+	if config.Name == "" || config.Namespace == "" {
+		return nil, errors.New("the lock's namespace and name must be specified")
+	}
+	if config.Identity == "" {
+		return nil, errors.New("the candidate's identity must be specified")
+	}
+	if config.RetryPeriod <= 0 {
+		return nil, errors.New("the retry period must be greater than zero")
+	}
+	if config.RenewDeadline <= config.RetryPeriod {
+		return nil, fmt.Errorf("the renew deadline (%v) must be greater than the retry period (%v)", config.RenewDeadline, config.RetryPeriod)
+	}
+	if config.LeaseDuration <= config.RenewDeadline {
+		return nil, fmt.Errorf("the lease duration (%v) must be greater than the renew deadline (%v)", config.LeaseDuration, config.RenewDeadline)
+	}
+
+	return &Elector{
+		config: config,
+		client: client,
+		clock:  clock.RealClock{},
+	}, nil
+}
+
+// Run blocks until the lease is acquired, then runs lead while renewing the lease. The context
+// passed to lead is canceled when the lease is lost or ctx is done. When ctx is done, the lease
+// is renewed until lead returns, so it can finish its work without another candidate taking
+// over, and Run returns nil after releasing the lease so another candidate can take over without
+// waiting for it to expire. When the lease is lost, another candidate can take over once it
+// expires, so Run returns ErrLeaseLost once lead has returned or the lease has expired,
+// whichever is first; in the latter case lead is still running, and the caller must stop it,
+// e.g. by exiting. Run returns ctx's error if ctx was done before the lease was acquired.
+func (e *Elector) Run(ctx context.Context, lead func(ctx context.Context)) error {
+	glog.Infof("Attempting to acquire leader lease %s/%s as %s", e.config.Namespace, e.config.Name, e.config.Identity)
+	if !e.acquire(ctx) {
+		return ctx.Err()
+	}
+	glog.Infof("Acquired leader lease %s/%s", e.config.Namespace, e.config.Name)
+
+	e.setRenewed(e.clock.Now())
+
+	leadCtx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		lead(leadCtx)
+		close(done)
+	}()
+
+	lost := e.renew(done)
+	e.setRenewed(time.Time{})
+	cancel()
+
+	if lost {
+		// the lease wasn't renewed for RenewDeadline, so another candidate can take it over
+		// once the rest of the lease duration has passed.
+		select {
+		case <-done:
+		case <-e.clock.After(e.config.LeaseDuration - e.config.RenewDeadline):
+			glog.Errorf("Still leading after leader lease %s/%s expired", e.config.Namespace, e.config.Name)
+		}
+		return ErrLeaseLost
+	}
+
+	<-done
+
+	e.release()
+	return nil
+}
+
+// Held returns whether this candidate holds the lease: it's acquired or renewed it within the
+// last RenewDeadline, so no other candidate can have taken it over.
+func (e *Elector) Held() bool {
+	e.renewedLock.Lock()
+	defer e.renewedLock.Unlock()
+
+	return !e.renewed.IsZero() && e.clock.Since(e.renewed) < e.config.RenewDeadline
+}
+
+func (e *Elector) setRenewed(renewed time.Time) {
+	e.renewedLock.Lock()
+	defer e.renewedLock.Unlock()
+
+	e.renewed = renewed
+}
+
+// acquire tries to acquire the lease every RetryPeriod until it succeeds, and returns false if
+// ctx is done first.
+func (e *Elector) acquire(ctx context.Context) bool {
+	for {
+		if e.tryAcquireOrRenew() {
+			return true
+		}
+
+		select {
+		case <-ctx.Done():
+			return false
+		case <-e.clock.After(e.config.RetryPeriod):
+		}
+	}
+}
+
+// renew renews the lease every RetryPeriod until stop is closed, and returns true if it couldn't
+// be renewed for RenewDeadline.
+func (e *Elector) renew(stop <-chan struct{}) bool {
+	lastRenewed := e.clock.Now()
+	for {
+		select {
+		case <-stop:
+			return false
+		case <-e.clock.After(e.config.RetryPeriod):
+		}
+
+		if e.tryAcquireOrRenew() {
+			lastRenewed = e.clock.Now()
+			e.setRenewed(lastRenewed)
+			continue
+		}
+
+		if e.clock.Since(lastRenewed) >= e.config.RenewDeadline {
+			glog.Errorf("Failed to renew leader lease %s/%s for %v", e.config.Namespace, e.config.Name, e.config.RenewDeadline)
+			return true
+		}
+	}
+}
+
+// tryAcquireOrRenew acquires the lease if it's free or has expired, or renews it if it's already
+// held by this candidate, and returns whether this candidate holds it.
+func (e *Elector) tryAcquireOrRenew() bool {
+	now := metav1.NewTime(e.clock.Now())
+	record := Record{
+		HolderIdentity:       e.config.Identity,
+		LeaseDurationSeconds: int(e.config.LeaseDuration / time.Second),
+		AcquireTime:          now,
+		RenewTime:            now,
+	}
+
+	configMap, err := e.client.ConfigMaps(e.config.Namespace).Get(e.config.Name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		configMap = &v1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: e.config.Namespace,
+				Name:      e.config.Name,
+			},
+		}
+		if err := e.setRecord(configMap, record); err != nil {
+			glog.Errorf("error encoding leader election record: %v", err)
+			return false
+		}
+		if _, err := e.client.ConfigMaps(e.config.Namespace).Create(configMap); err != nil {
+			glog.Errorf("error creating leader election lock %s/%s: %v", e.config.Namespace, e.config.Name, err)
+			return false
+		}
+		e.observe(configMap.Annotations[LeaderAnnotation])
+		return true
+	}
+	if err != nil {
+		glog.Errorf("error getting leader election lock %s/%s: %v", e.config.Namespace, e.config.Name, err)
+		return false
+	}
+
+	var current Record
+	raw := configMap.Annotations[LeaderAnnotation]
+	if raw != "" {
+		if err := json.Unmarshal([]byte(raw), &current); err != nil {
+			glog.Errorf("error decoding leader election record of %s/%s: %v", e.config.Namespace, e.config.Name, err)
+			return false
+		}
+	}
+	if raw != e.observedRecord {
+		e.observe(raw)
+	}
+
+	if current.HolderIdentity != "" && current.HolderIdentity != e.config.Identity && e.clock.Since(e.observedTime) < e.config.LeaseDuration {
+		glog.V(4).Infof("Leader lease %s/%s is held by %s", e.config.Namespace, e.config.Name, current.HolderIdentity)
+		return false
+	}
+
+	if current.HolderIdentity == e.config.Identity {
+		record.AcquireTime = current.AcquireTime
+		record.LeaderTransitions = current.LeaderTransitions
+	} else {
+		record.LeaderTransitions = current.LeaderTransitions + 1
+	}
+
+	if err := e.setRecord(configMap, record); err != nil {
+		glog.Errorf("error encoding leader election record: %v", err)
+		return false
+	}
+	// the update fails if another candidate updated the lock since it was read
+	if _, err := e.client.ConfigMaps(e.config.Namespace).Update(configMap); err != nil {
+		glog.Errorf("error updating leader election lock %s/%s: %v", e.config.Namespace, e.config.Name, err)
+		return false
+	}
+	e.observe(configMap.Annotations[LeaderAnnotation])
+	return true
+}
+
+// release gives up the lease, if this candidate still holds it, by clearing its holder.
+func (e *Elector) release() {
+	configMap, err := e.client.ConfigMaps(e.config.Namespace).Get(e.config.Name, metav1.GetOptions{})
+	if err != nil {
+		glog.Errorf("error getting leader election lock %s/%s: %v", e.config.Namespace, e.config.Name, err)
+		return
+	}
+
+	var current Record
+	if err := json.Unmarshal([]byte(configMap.Annotations[LeaderAnnotation]), &current); err != nil || current.HolderIdentity != e.config.Identity {
+		return
+	}
+
+	current.HolderIdentity = ""
+	if err := e.setRecord(configMap, current); err != nil {
+		glog.Errorf("error encoding leader election record: %v", err)
+		return
+	}
+	if _, err := e.client.ConfigMaps(e.config.Namespace).Update(configMap); err != nil {
+		glog.Errorf("error releasing leader election lock %s/%s: %v", e.config.Namespace, e.config.Name, err)
+		return
+	}
+	glog.Infof("Released leader lease %s/%s", e.config.Namespace, e.config.Name)
+}
+
+func (e *Elector) setRecord(configMap *v1.ConfigMap, record Record) error {
+	raw, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	if configMap.Annotations == nil {
+		configMap.Annotations = make(map[string]string)
+	}
+	configMap.Annotations[LeaderAnnotation] = string(raw)
+	return nil
+}
+
+func (e *Elector) observe(raw string) {
+	e.observedRecord = raw
+	e.observedTime = e.clock.Now()
+}
@@ -0,0 +1,291 @@
+/*
+Copyright 2017 Heptio Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package leaderelection
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/clock"
+	corev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/pkg/api/v1"
+)
+
+// fakeConfigMaps stores ConfigMaps in memory, rejecting updates based on a stale resource
+// version the way the API server does. Only the methods used by Elector are implemented.
+type fakeConfigMaps struct {
+	corev1.ConfigMapInterface
+
+	configMaps map[string]*v1.ConfigMap
+	version    int
+}
+
+func newFakeConfigMaps() *fakeConfigMaps {
+	return &fakeConfigMaps{configMaps: make(map[string]*v1.ConfigMap)}
+}
+
+func (f *fakeConfigMaps) ConfigMaps(namespace string) corev1.ConfigMapInterface {
+	return f
+}
+
+func (f *fakeConfigMaps) Get(name string, options metav1.GetOptions) (*v1.ConfigMap, error) {
+	configMap, ok := f.configMaps[name]
+	if !ok {
+		return nil, apierrors.NewNotFound(schema.GroupResource{Resource: "configmaps"}, name)
+	}
+	return copyConfigMap(configMap), nil
+}
+
+func (f *fakeConfigMaps) Create(configMap *v1.ConfigMap) (*v1.ConfigMap, error) {
+	if _, ok := f.configMaps[configMap.Name]; ok {
+		return nil, apierrors.NewAlreadyExists(schema.GroupResource{Resource: "configmaps"}, configMap.Name)
+	}
+	return f.store(configMap), nil
+}
+
+func (f *fakeConfigMaps) Update(configMap *v1.ConfigMap) (*v1.ConfigMap, error) {
+	if existing := f.configMaps[configMap.Name]; existing.ResourceVersion != configMap.ResourceVersion {
+		return nil, apierrors.NewConflict(schema.GroupResource{Resource: "configmaps"}, configMap.Name, nil)
+	}
+	return f.store(configMap), nil
+}
+
+func (f *fakeConfigMaps) store(configMap *v1.ConfigMap) *v1.ConfigMap {
+	f.version++
+	stored := copyConfigMap(configMap)
+	stored.ResourceVersion = strconv.Itoa(f.version)
+	f.configMaps[stored.Name] = stored
+	return copyConfigMap(stored)
+}
+
+func copyConfigMap(in *v1.ConfigMap) *v1.ConfigMap {
+	out := *in
+	out.Annotations = make(map[string]string, len(in.Annotations))
+	for k, v := range in.Annotations {
+		out.Annotations[k] = v
+	}
+	return &out
+}
+
+func newTestElector(t *testing.T, client corev1.ConfigMapsGetter, identity string, fakeClock clock.Clock) *Elector {
+	e, err := NewElector(client, Config{
+		Namespace:     "heptio-ark",
+		Name:          DefaultLockName,
+		Identity:      identity,
+		LeaseDuration: DefaultLeaseDuration,
+		RenewDeadline: DefaultRenewDeadline,
+		RetryPeriod:   DefaultRetryPeriod,
+	})
+	require.NoError(t, err)
+	e.clock = fakeClock
+	return e
+}
+
+func getRecord(t *testing.T, client *fakeConfigMaps) Record {
+	var record Record
+	require.NoError(t, json.Unmarshal([]byte(client.configMaps[DefaultLockName].Annotations[LeaderAnnotation]), &record))
+	return record
+}
+
+func TestTryAcquireOrRenew(t *testing.T) {
+	client := newFakeConfigMaps()
+	fakeClock := clock.NewFakeClock(time.Now())
+
+	a := newTestElector(t, client, "a", fakeClock)
+	b := newTestElector(t, client, "b", fakeClock)
+
+	// the lock is created by the first candidate
+	require.True(t, a.tryAcquireOrRenew())
+	assert.Equal(t, "a", getRecord(t, client).HolderIdentity)
+	acquired := getRecord(t, client).AcquireTime
+
+	// other candidates can't acquire the lease while it's held
+	assert.False(t, b.tryAcquireOrRenew())
+
+	// the holder can renew it, keeping its acquire time
+	fakeClock.Step(5 * time.Second)
+	require.True(t, a.tryAcquireOrRenew())
+	assert.False(t, b.tryAcquireOrRenew())
+	assert.Equal(t, acquired, getRecord(t, client).AcquireTime)
+	assert.Equal(t, 0, getRecord(t, client).LeaderTransitions)
+
+	// once the holder stops renewing it and it expires, it's taken over
+	fakeClock.Step(DefaultLeaseDuration - time.Second)
+	assert.False(t, b.tryAcquireOrRenew())
+	fakeClock.Step(time.Second)
+	require.True(t, b.tryAcquireOrRenew())
+	assert.Equal(t, "b", getRecord(t, client).HolderIdentity)
+	assert.Equal(t, 1, getRecord(t, client).LeaderTransitions)
+
+	// the previous holder can't get it back
+	assert.False(t, a.tryAcquireOrRenew())
+}
+
+func TestReleasedLeaseIsAcquiredImmediately(t *testing.T) {
+	client := newFakeConfigMaps()
+	fakeClock := clock.NewFakeClock(time.Now())
+
+	a := newTestElector(t, client, "a", fakeClock)
+	b := newTestElector(t, client, "b", fakeClock)
+
+	require.True(t, a.tryAcquireOrRenew())
+	assert.False(t, b.tryAcquireOrRenew())
+
+	a.release()
+	assert.Equal(t, "", getRecord(t, client).HolderIdentity)
+
+	require.True(t, b.tryAcquireOrRenew())
+	assert.Equal(t, "b", getRecord(t, client).HolderIdentity)
+
+	// releasing a lease held by another candidate does nothing
+	a.release()
+	assert.Equal(t, "b", getRecord(t, client).HolderIdentity)
+}
+
+func TestRunReleasesLeaseWhenDone(t *testing.T) {
+	client := newFakeConfigMaps()
+	e := newTestElector(t, client, "a", clock.RealClock{})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	led := false
+	assert.False(t, e.Held())
+	err := e.Run(ctx, func(leadCtx context.Context) {
+		led = true
+		assert.Equal(t, "a", getRecord(t, client).HolderIdentity)
+		assert.True(t, e.Held())
+		cancel()
+		<-leadCtx.Done()
+	})
+
+	assert.NoError(t, err)
+	assert.True(t, led)
+	assert.Equal(t, "", getRecord(t, client).HolderIdentity)
+	assert.False(t, e.Held())
+}
+
+func TestRunRenewsLeaseUntilLeadReturns(t *testing.T) {
+	client := newFakeConfigMaps()
+	fakeClock := clock.NewFakeClock(time.Now())
+	e := newTestElector(t, client, "a", fakeClock)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	err := e.Run(ctx, func(leadCtx context.Context) {
+		cancel()
+		<-leadCtx.Done()
+
+		// keep finishing up for longer than the lease lasts
+		for elapsed := time.Duration(0); elapsed <= DefaultLeaseDuration; elapsed += DefaultRetryPeriod {
+			for !fakeClock.HasWaiters() {
+				time.Sleep(time.Millisecond)
+			}
+			fakeClock.Step(DefaultRetryPeriod)
+		}
+		for !fakeClock.HasWaiters() {
+			time.Sleep(time.Millisecond)
+		}
+
+		assert.True(t, e.Held())
+		b := newTestElector(t, client, "b", fakeClock)
+		assert.False(t, b.tryAcquireOrRenew())
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "", getRecord(t, client).HolderIdentity)
+}
+
+func TestRunStopsWaitingForLeadWhenLeaseIsLost(t *testing.T) {
+	client := newFakeConfigMaps()
+	fakeClock := clock.NewFakeClock(time.Now())
+	e := newTestElector(t, client, "a", fakeClock)
+
+	step := func(d time.Duration) {
+		for !fakeClock.HasWaiters() {
+			time.Sleep(time.Millisecond)
+		}
+		fakeClock.Step(d)
+	}
+
+	stuck := make(chan struct{})
+	defer close(stuck)
+
+	err := e.Run(context.Background(), func(leadCtx context.Context) {
+		// another candidate takes the lease over, so it can't be renewed
+		for !fakeClock.HasWaiters() {
+			time.Sleep(time.Millisecond)
+		}
+		lock := copyConfigMap(client.configMaps[DefaultLockName])
+		lock.Annotations[LeaderAnnotation] = `{"holderIdentity": "b", "leaseDurationSeconds": 15}`
+		client.store(lock)
+
+		for elapsed := time.Duration(0); elapsed < DefaultRenewDeadline; elapsed += DefaultRetryPeriod {
+			step(DefaultRetryPeriod)
+		}
+		<-leadCtx.Done()
+		assert.False(t, e.Held())
+
+		// lead doesn't stop, but Run only waits for it until the lease has expired
+		step(DefaultLeaseDuration - DefaultRenewDeadline)
+		<-stuck
+	})
+
+	assert.Equal(t, ErrLeaseLost, err)
+	assert.Equal(t, "b", getRecord(t, client).HolderIdentity)
+}
+
+func TestNewElectorValidatesConfig(t *testing.T) {
+	valid := Config{
+		Namespace:     "heptio-ark",
+		Name:          DefaultLockName,
+		Identity:      "a",
+		LeaseDuration: DefaultLeaseDuration,
+		RenewDeadline: DefaultRenewDeadline,
+		RetryPeriod:   DefaultRetryPeriod,
+	}
+
+	tests := []struct {
+		name   string
+		modify func(*Config)
+	}{
+		{name: "no name", modify: func(c *Config) { c.Name = "" }},
+		{name: "no identity", modify: func(c *Config) { c.Identity = "" }},
+		{name: "no retry period", modify: func(c *Config) { c.RetryPeriod = 0 }},
+		{name: "renew deadline not greater than retry period", modify: func(c *Config) { c.RenewDeadline = c.RetryPeriod }},
+		{name: "lease duration not greater than renew deadline", modify: func(c *Config) { c.LeaseDuration = c.RenewDeadline }},
+	}
+
+	_, err := NewElector(newFakeConfigMaps(), valid)
+	assert.NoError(t, err)
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			config := valid
+			test.modify(&config)
+
+			_, err := NewElector(newFakeConfigMaps(), config)
+			assert.Error(t, err)
+		})
+	}
+}
@@ -0,0 +1,123 @@
+/*
+Copyright 2017 Heptio Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package logging
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+)
+
+var (
+	// stderrPipe is what os.Stderr, originally origStderr, is replaced with while the log is
+	// written as JSON, and pipeDone is closed once everything written to it has been re-encoded.
+	origStderr *os.File
+	stderrPipe *os.File
+	pipeDone   chan struct{}
+)
+
+// SetFormat sets the format the log is written in. For FormatJSON, glog's own lines, and anything
+// else written to os.Stderr, are re-encoded as JSON objects, so glog must be logging to stderr;
+// Flush must be called before the process exits so none of them are lost.
+func SetFormat(f string) error {
+	switch f {
+	case FormatText:
+		Flush()
+		return nil
+	case FormatJSON:
+	default:
+		return fmt.Errorf("invalid log format %q; valid formats are %s", f, strings.Join(Formats, ", "))
+	}
+
+	lock.Lock()
+	defer lock.Unlock()
+
+	if stderrPipe != nil {
+		return nil
+	}
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		return fmt.Errorf("error redirecting stderr: %v", err)
+	}
+
+	origStderr = os.Stderr
+	out = origStderr
+	os.Stderr = w
+	stderrPipe = w
+	pipeDone = make(chan struct{})
+	format = FormatJSON
+
+	go func(done chan struct{}) {
+		reencode(r)
+		r.Close()
+		close(done)
+	}(pipeDone)
+
+	return nil
+}
+
+// Flush waits for the lines written to stderr while the log is written as JSON to be re-encoded,
+// and restores stderr and the text format.
+func Flush() {
+	lock.Lock()
+	w, done := stderrPipe, pipeDone
+	if w != nil {
+		os.Stderr = origStderr
+		stderrPipe, pipeDone = nil, nil
+	}
+	format = FormatText
+	lock.Unlock()
+
+	if w != nil {
+		w.Close()
+		<-done
+	}
+}
+
+// glogLine matches the header of a line written by glog, e.g.
+// "I0102 15:04:05.000000    1 file.go:123] msg".
+var glogLine = regexp.MustCompile(`^([IWEF])\d{4} \d{2}:\d{2}:\d{2}\.\d{6}\s+\d+ ([^\]]+)\] (.*)$`)
+
+var glogSeverities = map[string]severity{
+	"I": severityInfo,
+	"W": severityWarning,
+	"E": severityError,
+	"F": severityFatal,
+}
+
+// reencode writes each line read from r as a JSON object. Lines written by glog are parsed for
+// their level and caller; other lines, e.g. the continuation of a multi-line message, are
+// attributed to the line before them.
+func reencode(r io.Reader) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	sev, caller := severityInfo, ""
+	for scanner.Scan() {
+		line := scanner.Text()
+		msg := line
+		if match := glogLine.FindStringSubmatch(line); match != nil {
+			sev, caller, msg = glogSeverities[match[1]], match[2], match[3]
+		}
+		writeJSON(time.Now(), sev, caller, msg, nil)
+	}
+}
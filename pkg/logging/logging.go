@@ -0,0 +1,205 @@
+/*
+Copyright 2017 Heptio Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package logging writes the Ark server's log, either as glog's text lines or as JSON objects, one
+// per line, for log aggregators. Lines logged through a Logger carry fields identifying what they're
+// about, e.g. the backup or restore, which are included in JSON lines.
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+)
+
+const (
+	// FormatText is the default format: glog's text lines. Fields aren't included.
+	FormatText = "text"
+
+	// FormatJSON formats each line as a JSON object with the line's time, level, caller, msg,
+	// and fields.
+	FormatJSON = "json"
+)
+
+// Formats are the formats the log can be written in.
+var Formats = []string{FormatText, FormatJSON}
+
+type severity string
+
+const (
+	severityInfo    severity = "info"
+	severityWarning severity = "warning"
+	severityError   severity = "error"
+	severityFatal   severity = "fatal"
+)
+
+var (
+	lock   sync.Mutex
+	format = FormatText
+	// out is where JSON lines are written: stderr, as it was before glog's output was redirected.
+	out io.Writer = os.Stderr
+)
+
+func getFormat() string {
+	lock.Lock()
+	defer lock.Unlock()
+
+	return format
+}
+
+// Fields are key-value pairs identifying what a line of the log is about.
+type Fields map[string]interface{}
+
+// Logger logs lines with a set of fields. The zero value logs lines without fields.
+type Logger struct {
+	fields Fields
+}
+
+// WithFields returns a Logger that logs lines with fields.
+func WithFields(fields Fields) Logger {
+	return Logger{}.WithFields(fields)
+}
+
+// WithFields returns a Logger that logs lines with l's fields and fields, which take precedence.
+func (l Logger) WithFields(fields Fields) Logger {
+	merged := make(Fields, len(l.fields)+len(fields))
+	for k, v := range l.fields {
+		merged[k] = v
+	}
+	for k, v := range fields {
+		merged[k] = v
+	}
+	return Logger{fields: merged}
+}
+
+// Infof logs an informational line.
+func (l Logger) Infof(format string, args ...interface{}) {
+	l.output(1, severityInfo, fmt.Sprintf(format, args...))
+}
+
+// Warningf logs a warning.
+func (l Logger) Warningf(format string, args ...interface{}) {
+	l.output(1, severityWarning, fmt.Sprintf(format, args...))
+}
+
+// Errorf logs an error.
+func (l Logger) Errorf(format string, args ...interface{}) {
+	l.output(1, severityError, fmt.Sprintf(format, args...))
+}
+
+// InfoDepth logs an informational line, attributed to the caller depth frames up the stack from
+// InfoDepth's caller, as with glog.InfoDepth.
+func (l Logger) InfoDepth(depth int, msg string) {
+	l.output(depth+1, severityInfo, msg)
+}
+
+// WarningDepth logs a warning, attributed as with InfoDepth.
+func (l Logger) WarningDepth(depth int, msg string) {
+	l.output(depth+1, severityWarning, msg)
+}
+
+// ErrorDepth logs an error, attributed as with InfoDepth.
+func (l Logger) ErrorDepth(depth int, msg string) {
+	l.output(depth+1, severityError, msg)
+}
+
+// Verbose logs informational lines only if the log's verbosity is at least its level, as with
+// glog.Verbose.
+type Verbose struct {
+	logger  Logger
+	enabled bool
+}
+
+// V returns a Verbose that logs lines with l's fields if the verbosity set with glog's -v flag is
+// at least level.
+func (l Logger) V(level glog.Level) Verbose {
+	return Verbose{logger: l, enabled: bool(glog.V(level))}
+}
+
+// Infof logs an informational line if v is enabled.
+func (v Verbose) Infof(format string, args ...interface{}) {
+	if v.enabled {
+		v.logger.output(1, severityInfo, fmt.Sprintf(format, args...))
+	}
+}
+
+// output logs msg, attributed to the caller depth frames up the stack from output's caller.
+func (l Logger) output(depth int, sev severity, msg string) {
+	if getFormat() != FormatJSON {
+		switch sev {
+		case severityWarning:
+			glog.WarningDepth(depth+1, msg)
+		case severityError:
+			glog.ErrorDepth(depth+1, msg)
+		default:
+			glog.InfoDepth(depth+1, msg)
+		}
+		return
+	}
+
+	caller := "???"
+	if _, file, line, ok := runtime.Caller(depth + 1); ok {
+		caller = fmt.Sprintf("%s:%d", filepath.Base(file), line)
+	}
+	writeJSON(time.Now(), sev, caller, msg, l.fields)
+}
+
+// writeJSON writes a line of the log as a JSON object. Its time, level, caller, and msg come
+// first, followed by its fields in alphabetical order.
+func writeJSON(t time.Time, sev severity, caller, msg string, fields Fields) {
+	buf := new(bytes.Buffer)
+	fmt.Fprintf(buf, `{"time":%q,"level":%q,"caller":%s,"msg":%s`, t.UTC().Format(time.RFC3339Nano), sev, quote(caller), quote(msg))
+
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		switch k {
+		case "time", "level", "caller", "msg":
+			// fields can't replace the line's own keys.
+		default:
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		value, err := json.Marshal(fields[k])
+		if err != nil {
+			value = quote(fmt.Sprint(fields[k]))
+		}
+		fmt.Fprintf(buf, ",%s:%s", quote(k), value)
+	}
+	buf.WriteString("}\n")
+
+	lock.Lock()
+	defer lock.Unlock()
+
+	out.Write(buf.Bytes())
+}
+
+// quote returns s encoded as a JSON string.
+func quote(s string) []byte {
+	data, _ := json.Marshal(s)
+	return data
+}
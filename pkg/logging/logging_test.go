@@ -0,0 +1,127 @@
+/*
+Copyright 2017 Heptio Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"strings"
+	"testing"
+
+	"github.com/golang/glog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// captureJSON makes the log be written as JSON to a buffer, until the returned func is called.
+func captureJSON() (*bytes.Buffer, func()) {
+	buf := new(bytes.Buffer)
+
+	lock.Lock()
+	prevFormat, prevOut := format, out
+	format, out = FormatJSON, buf
+	lock.Unlock()
+
+	return buf, func() {
+		lock.Lock()
+		format, out = prevFormat, prevOut
+		lock.Unlock()
+	}
+}
+
+func decodeLines(t *testing.T, buf *bytes.Buffer) []map[string]interface{} {
+	var lines []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n") {
+		if line == "" {
+			continue
+		}
+		var decoded map[string]interface{}
+		require.NoError(t, json.Unmarshal([]byte(line), &decoded), line)
+		lines = append(lines, decoded)
+	}
+	return lines
+}
+
+func TestLoggerJSON(t *testing.T) {
+	buf, restore := captureJSON()
+	defer restore()
+
+	backupLogger := WithFields(Fields{"backup": "backup-1", "namespace": "heptio-ark"})
+	backupLogger.Infof("Starting backup %s", "backup-1")
+	backupLogger.WithFields(Fields{"resource": "pods", "items": 3, "msg": "ignored"}).Warningf("slow")
+	Logger{}.Errorf("no fields")
+	backupLogger.V(100).Infof("not logged")
+
+	lines := decodeLines(t, buf)
+	require.Len(t, lines, 3)
+
+	for _, line := range lines {
+		assert.NotEmpty(t, line["time"])
+		assert.True(t, strings.HasPrefix(line["caller"].(string), "logging_test.go:"), line["caller"])
+		delete(line, "time")
+		delete(line, "caller")
+	}
+
+	assert.Equal(t, map[string]interface{}{"level": "info", "msg": "Starting backup backup-1", "backup": "backup-1", "namespace": "heptio-ark"}, lines[0])
+	assert.Equal(t, map[string]interface{}{"level": "warning", "msg": "slow", "backup": "backup-1", "namespace": "heptio-ark", "resource": "pods", "items": float64(3)}, lines[1])
+	assert.Equal(t, map[string]interface{}{"level": "error", "msg": "no fields"}, lines[2])
+
+	// the fields are written in order after the line's own keys
+	assert.Contains(t, buf.String(), `"msg":"slow","backup":"backup-1","items":3,"namespace":"heptio-ark","resource":"pods"}`)
+}
+
+func TestReencode(t *testing.T) {
+	buf, restore := captureJSON()
+	defer restore()
+
+	reencode(strings.NewReader(strings.Join([]string{
+		"I0102 15:04:05.000000       1 server.go:123] Starting server",
+		"E0102 15:04:05.000000       1 gc_controller.go:45] error: multi-line",
+		"message",
+		"An error occurred: exiting",
+	}, "\n")))
+
+	lines := decodeLines(t, buf)
+	require.Len(t, lines, 4)
+	for _, line := range lines {
+		delete(line, "time")
+	}
+
+	assert.Equal(t, map[string]interface{}{"level": "info", "caller": "server.go:123", "msg": "Starting server"}, lines[0])
+	assert.Equal(t, map[string]interface{}{"level": "error", "caller": "gc_controller.go:45", "msg": "error: multi-line"}, lines[1])
+	assert.Equal(t, map[string]interface{}{"level": "error", "caller": "gc_controller.go:45", "msg": "message"}, lines[2])
+	assert.Equal(t, map[string]interface{}{"level": "error", "caller": "gc_controller.go:45", "msg": "An error occurred: exiting"}, lines[3])
+}
+
+func TestSetFormat(t *testing.T) {
+	assert.EqualError(t, SetFormat("xml"), `invalid log format "xml"; valid formats are text, json`)
+	assert.NoError(t, SetFormat(FormatText))
+	assert.Equal(t, FormatText, getFormat())
+}
+
+func TestSetVerbosity(t *testing.T) {
+	flagValue := flag.Lookup("v").Value.String()
+
+	level := int32(4)
+	require.NoError(t, SetVerbosity(&level))
+	assert.Equal(t, "4", flag.Lookup("v").Value.String())
+	assert.True(t, bool(glog.V(4)))
+
+	require.NoError(t, SetVerbosity(nil))
+	assert.Equal(t, flagValue, flag.Lookup("v").Value.String())
+}
@@ -0,0 +1,46 @@
+/*
+Copyright 2017 Heptio Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package logging
+
+import (
+	"flag"
+	"strconv"
+)
+
+var (
+	// flagVerbosity is the value of glog's -v flag before SetVerbosity first changed it.
+	flagVerbosity    string
+	flagVerbositySet bool
+)
+
+// SetVerbosity sets glog's verbosity, as its -v flag does, taking effect immediately. If level is
+// nil, the verbosity is set back to the flag's value.
+func SetVerbosity(level *int32) error {
+	v := flag.Lookup("v")
+
+	lock.Lock()
+	defer lock.Unlock()
+
+	if !flagVerbositySet {
+		flagVerbosity, flagVerbositySet = v.Value.String(), true
+	}
+
+	if level == nil {
+		return v.Value.Set(flagVerbosity)
+	}
+	return v.Value.Set(strconv.Itoa(int(*level)))
+}
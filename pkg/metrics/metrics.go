@@ -0,0 +1,149 @@
+/*
+Copyright 2017 Heptio Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metrics
+
+import (
+	"time"
+)
+
+const (
+	// DefaultAddress is the address the metrics are served on if none is configured.
+	DefaultAddress = ":8085"
+
+	// Path is the path the metrics are served at.
+	Path = "/metrics"
+
+	namespace = "ark"
+
+	scheduleLabel  = "schedule"
+	locationLabel  = "location"
+	resultLabel    = "result"
+	operationLabel = "operation"
+	namespaceLabel = "namespace"
+	quotaLabel     = "quota"
+)
+
+// ServerMetrics are the metrics recorded by the Ark server. Backups and restores are labeled
+// with the schedule that created the backup ("" for ad-hoc backups) and the backup storage
+// location named in its spec ("" for the server's default location).
+type ServerMetrics struct {
+	registry *Registry
+
+	backupAttempts        *CounterVec
+	backupSuccesses       *CounterVec
+	backupPartialFailures *CounterVec
+	backupFailures        *CounterVec
+	backupQuotaExceeded   *CounterVec
+	backupDuration        *HistogramVec
+	backupSize            *HistogramVec
+	volumeSnapshots       *CounterVec
+	restores              *CounterVec
+	gcDeletions           *CounterVec
+	cloudAPIRequests      *CounterVec
+	cloudAPIErrors        *CounterVec
+}
+
+// NewServerMetrics returns a ServerMetrics with all of its metrics registered.
+func NewServerMetrics() *ServerMetrics {
+	r := NewRegistry()
+
+	return &ServerMetrics{
+		registry: r,
+
+		backupAttempts:        r.NewCounterVec(namespace+"_backup_attempt_total", "Total number of attempted backups", scheduleLabel, locationLabel),
+		backupSuccesses:       r.NewCounterVec(namespace+"_backup_success_total", "Total number of successful backups", scheduleLabel, locationLabel),
+		backupPartialFailures: r.NewCounterVec(namespace+"_backup_partial_failure_total", "Total number of partially failed backups", scheduleLabel, locationLabel),
+		backupFailures:        r.NewCounterVec(namespace+"_backup_failure_total", "Total number of failed backups", scheduleLabel, locationLabel),
+		backupQuotaExceeded:   r.NewCounterVec(namespace+"_backup_quota_exceeded_total", "Total number of backups rejected because they'd exceed their namespace's quota", namespaceLabel, quotaLabel),
+		// 1 second to about 4.5 hours
+		backupDuration: r.NewHistogramVec(namespace+"_backup_duration_seconds", "Time taken to complete backups, in seconds", ExponentialBuckets(1, 2, 15), scheduleLabel, locationLabel),
+		// 1 KiB to 1 TiB
+		backupSize:      r.NewHistogramVec(namespace+"_backup_tarball_size_bytes", "Size, in bytes, of backup tarballs", ExponentialBuckets(1024, 4, 16), scheduleLabel, locationLabel),
+		volumeSnapshots: r.NewCounterVec(namespace+"_volume_snapshot_total", "Total number of volume snapshots taken by completed backups", scheduleLabel, locationLabel),
+		restores:        r.NewCounterVec(namespace+"_restore_total", "Total number of restores, by their final phase", scheduleLabel, locationLabel, resultLabel),
+		gcDeletions:     r.NewCounterVec(namespace+"_gc_deletion_total", "Total number of expired backups deleted by garbage collection", scheduleLabel, locationLabel),
+		// the location label of cloud API metrics names the configured provider, e.g.
+		// backupStorageLocations.<name> or volumeSnapshotLocations.<name>
+		cloudAPIRequests: r.NewCounterVec(namespace+"_cloud_api_request_total", "Total number of requests made to cloud provider APIs", locationLabel, operationLabel),
+		cloudAPIErrors:   r.NewCounterVec(namespace+"_cloud_api_error_total", "Total number of requests to cloud provider APIs that returned an error", locationLabel, operationLabel),
+	}
+}
+
+// Registry returns the Registry that holds the server's metrics.
+func (m *ServerMetrics) Registry() *Registry {
+	return m.registry
+}
+
+// RegisterBackupAttempt records that a backup started running.
+func (m *ServerMetrics) RegisterBackupAttempt(schedule, location string) {
+	m.backupAttempts.Inc(schedule, location)
+}
+
+// RegisterBackupSuccess records that a backup completed without errors.
+func (m *ServerMetrics) RegisterBackupSuccess(schedule, location string) {
+	m.backupSuccesses.Inc(schedule, location)
+}
+
+// RegisterBackupPartialFailure records that a backup completed with errors.
+func (m *ServerMetrics) RegisterBackupPartialFailure(schedule, location string) {
+	m.backupPartialFailures.Inc(schedule, location)
+}
+
+// RegisterBackupFailure records that a backup failed.
+func (m *ServerMetrics) RegisterBackupFailure(schedule, location string) {
+	m.backupFailures.Inc(schedule, location)
+}
+
+// RegisterBackupQuotaExceeded records that a backup in namespace was rejected because it would
+// exceed the namespace's quota, where quota is the name of the limit, e.g. maxBackups.
+func (m *ServerMetrics) RegisterBackupQuotaExceeded(namespace, quota string) {
+	m.backupQuotaExceeded.Inc(namespace, quota)
+}
+
+// ObserveBackupDuration records how long a backup took to run.
+func (m *ServerMetrics) ObserveBackupDuration(schedule, location string, duration time.Duration) {
+	m.backupDuration.Observe(duration.Seconds(), schedule, location)
+}
+
+// ObserveBackupSize records the size of a backup's tarball.
+func (m *ServerMetrics) ObserveBackupSize(schedule, location string, size int64) {
+	m.backupSize.Observe(float64(size), schedule, location)
+}
+
+// RegisterVolumeSnapshots records that a backup took count volume snapshots.
+func (m *ServerMetrics) RegisterVolumeSnapshots(schedule, location string, count int) {
+	m.volumeSnapshots.Add(float64(count), schedule, location)
+}
+
+// RegisterRestore records that a restore finished in the given phase.
+func (m *ServerMetrics) RegisterRestore(schedule, location, phase string) {
+	m.restores.Inc(schedule, location, phase)
+}
+
+// RegisterGCDeletion records that garbage collection deleted an expired backup.
+func (m *ServerMetrics) RegisterGCDeletion(schedule, location string) {
+	m.gcDeletions.Inc(schedule, location)
+}
+
+// RegisterCloudAPIRequest records a request to the cloud provider configured as location, and
+// whether it returned an error.
+func (m *ServerMetrics) RegisterCloudAPIRequest(location, operation string, err error) {
+	m.cloudAPIRequests.Inc(location, operation)
+	if err != nil {
+		m.cloudAPIErrors.Inc(location, operation)
+	}
+}
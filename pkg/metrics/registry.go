@@ -0,0 +1,296 @@
+/*
+Copyright 2017 Heptio Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package metrics implements the metrics the Ark server exposes, and serves them in the
+// Prometheus text exposition format.
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/golang/glog"
+)
+
+// Registry holds a set of metrics and writes them in the Prometheus text exposition format.
+type Registry struct {
+	lock    sync.Mutex
+	metrics []metric
+}
+
+// NewRegistry returns an empty Registry.
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+type metric interface {
+	write(w io.Writer)
+}
+
+// NewCounterVec registers and returns a counter named name, partitioned by the given labels.
+func (r *Registry) NewCounterVec(name, help string, labels ...string) *CounterVec {
+	c := &CounterVec{vec: newVec(name, help, "counter", labels)}
+	r.register(c)
+	return c
+}
+
+// NewHistogramVec registers and returns a histogram named name with the given upper bucket
+// bounds, in increasing order, partitioned by the given labels.
+func (r *Registry) NewHistogramVec(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	h := &HistogramVec{vec: newVec(name, help, "histogram", labels), buckets: buckets}
+	r.register(h)
+	return h
+}
+
+func (r *Registry) register(m metric) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.metrics = append(r.metrics, m)
+}
+
+// Write writes every metric in the registry to w, in the order they were registered.
+func (r *Registry) Write(w io.Writer) error {
+	r.lock.Lock()
+	metrics := r.metrics
+	r.lock.Unlock()
+
+	bw := bufio.NewWriter(w)
+	for _, m := range metrics {
+		m.write(bw)
+	}
+	return bw.Flush()
+}
+
+// Handler returns an http.Handler that serves the registry's metrics.
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		if err := r.Write(w); err != nil {
+			glog.Errorf("error writing metrics: %v", err)
+		}
+	})
+}
+
+// vec holds the series of a metric, keyed by their label values.
+type vec struct {
+	name   string
+	help   string
+	typ    string
+	labels []string
+
+	lock   sync.Mutex
+	series map[string]interface{}
+}
+
+func newVec(name, help, typ string, labels []string) vec {
+	return vec{
+		name:   name,
+		help:   help,
+		typ:    typ,
+		labels: labels,
+		series: make(map[string]interface{}),
+	}
+}
+
+// key returns the series key for labelValues, which must have one value for each label.
+func (v *vec) key(labelValues []string) string {
+	if len(labelValues) != len(v.labels) {
+		panic(fmt.Sprintf("metric %s has %d labels, but %d values were given", v.name, len(v.labels), len(labelValues)))
+	}
+	return strings.Join(labelValues, "\xff")
+}
+
+// get returns the series for labelValues, creating it with newSeries if it doesn't exist.
+// It must be called with v.lock held.
+func (v *vec) get(labelValues []string, newSeries func() interface{}) interface{} {
+	key := v.key(labelValues)
+	s, ok := v.series[key]
+	if !ok {
+		s = newSeries()
+		v.series[key] = s
+	}
+	return s
+}
+
+// writeHeader writes the metric's HELP and TYPE lines, and returns its series keys, sorted.
+// It must be called with v.lock held.
+func (v *vec) writeHeader(w io.Writer) []string {
+	fmt.Fprintf(w, "# HELP %s %s\n", v.name, escape(v.help, false))
+	fmt.Fprintf(w, "# TYPE %s %s\n", v.name, v.typ)
+
+	keys := make([]string, 0, len(v.series))
+	for key := range v.series {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// labelPairs formats the labels of the series with the given key, followed by extra, which is
+// a pre-formatted label pair or "".
+func (v *vec) labelPairs(key, extra string) string {
+	var pairs []string
+	if len(v.labels) > 0 {
+		for i, value := range strings.Split(key, "\xff") {
+			pairs = append(pairs, fmt.Sprintf("%s=\"%s\"", v.labels[i], escape(value, true)))
+		}
+	}
+	if extra != "" {
+		pairs = append(pairs, extra)
+	}
+
+	if len(pairs) == 0 {
+		return ""
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+// CounterVec is a counter partitioned by a set of labels.
+type CounterVec struct {
+	vec
+}
+
+// Inc adds one to the series with the given label values.
+func (c *CounterVec) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+// Add adds delta, which must not be negative, to the series with the given label values.
+func (c *CounterVec) Add(delta float64, labelValues ...string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	value := c.get(labelValues, func() interface{} { return new(float64) }).(*float64)
+	*value += delta
+}
+
+// Value returns the value of the series with the given label values.
+func (c *CounterVec) Value(labelValues ...string) float64 {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if value, ok := c.series[c.key(labelValues)]; ok {
+		return *value.(*float64)
+	}
+	return 0
+}
+
+func (c *CounterVec) write(w io.Writer) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	for _, key := range c.writeHeader(w) {
+		fmt.Fprintf(w, "%s%s %s\n", c.name, c.labelPairs(key, ""), formatFloat(*c.series[key].(*float64)))
+	}
+}
+
+// HistogramVec is a histogram partitioned by a set of labels.
+type HistogramVec struct {
+	vec
+	buckets []float64
+}
+
+type histogram struct {
+	// counts[i] is the number of observations no greater than buckets[i], and not counted
+	// in a lower bucket.
+	counts []uint64
+	count  uint64
+	sum    float64
+}
+
+// Observe adds value to the series with the given label values.
+func (h *HistogramVec) Observe(value float64, labelValues ...string) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	s := h.get(labelValues, func() interface{} {
+		return &histogram{counts: make([]uint64, len(h.buckets))}
+	}).(*histogram)
+
+	if i := sort.SearchFloat64s(h.buckets, value); i < len(h.buckets) {
+		s.counts[i]++
+	}
+	s.count++
+	s.sum += value
+}
+
+// Count returns the number of observations of the series with the given label values.
+func (h *HistogramVec) Count(labelValues ...string) uint64 {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	if s, ok := h.series[h.key(labelValues)]; ok {
+		return s.(*histogram).count
+	}
+	return 0
+}
+
+func (h *HistogramVec) write(w io.Writer) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	for _, key := range h.writeHeader(w) {
+		s := h.series[key].(*histogram)
+
+		var cumulative uint64
+		for i, bound := range h.buckets {
+			cumulative += s.counts[i]
+			fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, h.labelPairs(key, fmt.Sprintf("le=\"%s\"", formatFloat(bound))), cumulative)
+		}
+		fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, h.labelPairs(key, `le="+Inf"`), s.count)
+		fmt.Fprintf(w, "%s_sum%s %s\n", h.name, h.labelPairs(key, ""), formatFloat(s.sum))
+		fmt.Fprintf(w, "%s_count%s %d\n", h.name, h.labelPairs(key, ""), s.count)
+	}
+}
+
+// ExponentialBuckets returns count bucket bounds, the first being start and each subsequent
+// one factor times the previous one.
+func ExponentialBuckets(start, factor float64, count int) []float64 {
+	buckets := make([]float64, count)
+	for i := range buckets {
+		buckets[i] = start
+		start *= factor
+	}
+	return buckets
+}
+
+func formatFloat(f float64) string {
+	switch {
+	case math.IsInf(f, 1):
+		return "+Inf"
+	case math.IsInf(f, -1):
+		return "-Inf"
+	}
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+// escape escapes backslashes and newlines in s, as well as double quotes if it's a label value.
+func escape(s string, labelValue bool) string {
+	s = strings.Replace(s, `\`, `\\`, -1)
+	s = strings.Replace(s, "\n", `\n`, -1)
+	if labelValue {
+		s = strings.Replace(s, `"`, `\"`, -1)
+	}
+	return s
+}
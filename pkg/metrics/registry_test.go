@@ -0,0 +1,80 @@
+/*
+Copyright 2017 Heptio Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metrics
+
+import (
+	"bytes"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegistryWrite(t *testing.T) {
+	r := NewRegistry()
+
+	counter := r.NewCounterVec("test_total", "A test counter", "schedule")
+	counter.Inc("daily")
+	counter.Add(2, "daily")
+	counter.Inc(`a "quoted" name`)
+
+	histogram := r.NewHistogramVec("test_seconds", "A test histogram", []float64{1, 10}, "schedule")
+	histogram.Observe(0.5, "daily")
+	histogram.Observe(5, "daily")
+	histogram.Observe(50, "daily")
+
+	buf := new(bytes.Buffer)
+	require.NoError(t, r.Write(buf))
+
+	expected := `# HELP test_total A test counter
+# TYPE test_total counter
+test_total{schedule="a \"quoted\" name"} 1
+test_total{schedule="daily"} 3
+# HELP test_seconds A test histogram
+# TYPE test_seconds histogram
+test_seconds_bucket{schedule="daily",le="1"} 1
+test_seconds_bucket{schedule="daily",le="10"} 2
+test_seconds_bucket{schedule="daily",le="+Inf"} 3
+test_seconds_sum{schedule="daily"} 55.5
+test_seconds_count{schedule="daily"} 3
+`
+	assert.Equal(t, expected, buf.String())
+	assert.Equal(t, float64(3), counter.Value("daily"))
+	assert.Equal(t, float64(0), counter.Value("weekly"))
+	assert.Equal(t, uint64(3), histogram.Count("daily"))
+}
+
+func TestWrongNumberOfLabelValuesPanics(t *testing.T) {
+	counter := NewRegistry().NewCounterVec("test_total", "A test counter", "schedule", "location")
+
+	assert.Panics(t, func() { counter.Inc("daily") })
+}
+
+func TestHandler(t *testing.T) {
+	m := NewServerMetrics()
+	m.RegisterBackupAttempt("daily", "")
+	m.RegisterCloudAPIRequest("backupStorageProvider", "PutObject", assert.AnError)
+
+	res := httptest.NewRecorder()
+	m.Registry().Handler().ServeHTTP(res, httptest.NewRequest("GET", Path, nil))
+
+	assert.Equal(t, "text/plain; version=0.0.4", res.Header().Get("Content-Type"))
+	assert.Contains(t, res.Body.String(), "ark_backup_attempt_total{schedule=\"daily\",location=\"\"} 1\n")
+	assert.Contains(t, res.Body.String(), "ark_cloud_api_request_total{location=\"backupStorageProvider\",operation=\"PutObject\"} 1\n")
+	assert.Contains(t, res.Body.String(), "ark_cloud_api_error_total{location=\"backupStorageProvider\",operation=\"PutObject\"} 1\n")
+}
@@ -0,0 +1,58 @@
+/*
+Copyright 2017 Heptio Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metrics
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/golang/glog"
+)
+
+// Server serves a Registry's metrics over HTTP at Path.
+type Server struct {
+	server *http.Server
+}
+
+// NewServer returns a Server that listens on address and serves registry's metrics.
+func NewServer(address string, registry *Registry) *Server {
+	mux := http.NewServeMux()
+	mux.Handle(Path, registry.Handler())
+
+	return &Server{
+		server: &http.Server{
+			Addr:    address,
+			Handler: mux,
+		},
+	}
+}
+
+// Run serves the metrics until ctx is done.
+func (s *Server) Run(ctx context.Context) error {
+	go func() {
+		<-ctx.Done()
+		if err := s.server.Shutdown(context.Background()); err != nil {
+			glog.Errorf("error shutting down metrics server: %v", err)
+		}
+	}()
+
+	glog.Infof("Serving metrics on %s%s", s.server.Addr, Path)
+	if err := s.server.ListenAndServe(); err != http.ErrServerClosed {
+		return err
+	}
+	return nil
+}
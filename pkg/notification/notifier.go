@@ -0,0 +1,341 @@
+/*
+Copyright 2017 Heptio Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package notification posts notifications about completed, failed, and expired backups and
+// restores to the HTTP webhooks in Ark's config.
+package notification
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/golang/glog"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	api "github.com/heptio/ark/pkg/apis/ark/v1"
+	listers "github.com/heptio/ark/pkg/generated/listers/ark/v1"
+)
+
+// The events that notifications are sent for.
+const (
+	EventBackupCompleted        = "BackupCompleted"
+	EventBackupPartiallyFailed  = "BackupPartiallyFailed"
+	EventBackupFailed           = "BackupFailed"
+	EventBackupExpired          = "BackupExpired"
+	EventRestoreCompleted       = "RestoreCompleted"
+	EventRestorePartiallyFailed = "RestorePartiallyFailed"
+	EventRestoreFailed          = "RestoreFailed"
+)
+
+// Events are all of the events that notifications are sent for.
+var Events = sets.NewString(
+	EventBackupCompleted,
+	EventBackupPartiallyFailed,
+	EventBackupFailed,
+	EventBackupExpired,
+	EventRestoreCompleted,
+	EventRestorePartiallyFailed,
+	EventRestoreFailed,
+)
+
+const (
+	// FormatJSON is the format of notifications posted as Notifications encoded in JSON.
+	FormatJSON = "json"
+
+	// FormatSlack is the format of notifications posted to Slack incoming webhooks.
+	FormatSlack = "slack"
+
+	defaultMaxRetries   = 3
+	defaultTimeout      = 10 * time.Second
+	initialRetryBackoff = time.Second
+)
+
+// Notification is a notification about a backup or restore.
+type Notification struct {
+	// Event is the event that the notification is about, e.g. BackupCompleted.
+	Event string `json:"event"`
+
+	// Kind is the kind of the object the notification is about: Backup or Restore.
+	Kind string `json:"kind"`
+
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+
+	// Schedule is the name of the schedule that created the backup, if any.
+	Schedule string `json:"schedule,omitempty"`
+
+	// Phase is the backup's or restore's phase.
+	Phase string `json:"phase"`
+
+	// Message describes what happened, as in the event recorded about it.
+	Message string `json:"message"`
+
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// Notifier posts notifications to webhooks.
+type Notifier interface {
+	// Notify posts n to the webhooks that are configured for it, in the background. Failures
+	// are logged.
+	Notify(n Notification)
+}
+
+// webhook is a configured webhook, ready to post to.
+type webhook struct {
+	api.NotificationWebhook
+	events   sets.String
+	template *template.Template
+	client   *http.Client
+}
+
+type notifier struct {
+	webhooks       []*webhook
+	scheduleLister listers.ScheduleLister
+	sleep          func(time.Duration)
+	now            func() time.Time
+
+	// pending tracks the notifications being posted, for tests.
+	pending sync.WaitGroup
+}
+
+// NewNotifier returns a Notifier that posts notifications to config's webhooks, using
+// scheduleLister to look up the overrides of the schedules that backups were created by. It
+// returns an error if config is invalid.
+func NewNotifier(config *api.NotificationsConfig, scheduleLister listers.ScheduleLister) (Notifier, error) {
+	n := &notifier{
+		scheduleLister: scheduleLister,
+		sleep:          time.Sleep,
+		now:            time.Now,
+	}
+	if config == nil {
+		return n, nil
+	}
+
+	names := sets.NewString()
+	for i, config := range config.Webhooks {
+		hook, err := newWebhook(config)
+		if err != nil {
+			return nil, fmt.Errorf("invalid notification webhook #%d: %v", i, err)
+		}
+		if names.Has(hook.Name) {
+			return nil, fmt.Errorf("invalid notification webhook #%d: there's more than one webhook named %s", i, hook.Name)
+		}
+		names.Insert(hook.Name)
+		n.webhooks = append(n.webhooks, hook)
+	}
+
+	return n, nil
+}
+
+func newWebhook(config api.NotificationWebhook) (*webhook, error) {
+	if config.Name == "" {
+		return nil, fmt.Errorf("name is required")
+	}
+	if u, err := url.Parse(config.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return nil, fmt.Errorf("url must be an http or https URL")
+	}
+	switch config.Format {
+	case "":
+		config.Format = FormatJSON
+	case FormatJSON, FormatSlack:
+	default:
+		return nil, fmt.Errorf("unknown format %q; valid formats are %s and %s", config.Format, FormatJSON, FormatSlack)
+	}
+	if err := validateEvents(config.Events); err != nil {
+		return nil, err
+	}
+	if config.MaxRetries == 0 {
+		config.MaxRetries = defaultMaxRetries
+	}
+	if config.Timeout.Duration == 0 {
+		config.Timeout.Duration = defaultTimeout
+	}
+
+	hook := &webhook{
+		NotificationWebhook: config,
+		events:              sets.NewString(config.Events...),
+		client:              &http.Client{Timeout: config.Timeout.Duration},
+	}
+	if len(config.Events) == 0 {
+		hook.events = Events
+	}
+
+	if config.Template != "" {
+		tmpl, err := template.New(config.Name).Parse(config.Template)
+		if err != nil {
+			return nil, fmt.Errorf("invalid template: %v", err)
+		}
+		hook.template = tmpl
+	}
+
+	return hook, nil
+}
+
+// validateEvents returns an error if any of events isn't an event that notifications are sent
+// for.
+func validateEvents(events []string) error {
+	for _, event := range events {
+		if !Events.Has(event) {
+			return fmt.Errorf("unknown event %q; valid events are %s", event, strings.Join(Events.List(), ", "))
+		}
+	}
+	return nil
+}
+
+// ValidateOverrides returns an error if a schedule's notification overrides name an unknown
+// event.
+func ValidateOverrides(overrides *api.NotificationOverrides) error {
+	if overrides == nil {
+		return nil
+	}
+	return validateEvents(overrides.Events)
+}
+
+func (n *notifier) Notify(notification Notification) {
+	if len(n.webhooks) == 0 {
+		return
+	}
+	if notification.Timestamp.IsZero() {
+		notification.Timestamp = n.now().UTC()
+	}
+
+	overrides := n.overrides(notification)
+	if overrides != nil && overrides.Disabled {
+		return
+	}
+
+	for _, hook := range n.webhooks {
+		if !hook.wants(notification.Event, overrides) {
+			continue
+		}
+
+		body, err := hook.body(notification)
+		if err != nil {
+			glog.Errorf("Error creating %s notification for %s %s/%s for webhook %s: %v", notification.Event, notification.Kind, notification.Namespace, notification.Name, hook.Name, err)
+			continue
+		}
+
+		n.pending.Add(1)
+		go func(hook *webhook) {
+			defer n.pending.Done()
+			n.post(hook, notification, body)
+		}(hook)
+	}
+}
+
+// overrides returns the notification overrides of the schedule that created the notification's
+// backup, if any.
+func (n *notifier) overrides(notification Notification) *api.NotificationOverrides {
+	if notification.Schedule == "" || n.scheduleLister == nil {
+		return nil
+	}
+
+	schedule, err := n.scheduleLister.Schedules(notification.Namespace).Get(notification.Schedule)
+	if err != nil {
+		// the schedule may have been deleted since it created the backup
+		glog.V(4).Infof("Error getting schedule %s/%s for notification overrides: %v", notification.Namespace, notification.Schedule, err)
+		return nil
+	}
+	return schedule.Spec.Notifications
+}
+
+// wants returns whether the webhook is notified of event, given the overrides of the schedule
+// that the notification's backup was created by, if any.
+func (hook *webhook) wants(event string, overrides *api.NotificationOverrides) bool {
+	if overrides != nil && len(overrides.Webhooks) > 0 && !sets.NewString(overrides.Webhooks...).Has(hook.Name) {
+		return false
+	}
+	if overrides != nil && len(overrides.Events) > 0 {
+		return sets.NewString(overrides.Events...).Has(event)
+	}
+	return hook.events.Has(event)
+}
+
+// body returns the body of the request that posts notification to the webhook.
+func (hook *webhook) body(notification Notification) ([]byte, error) {
+	if hook.template != nil {
+		buf := new(bytes.Buffer)
+		if err := hook.template.Execute(buf, notification); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+
+	if hook.Format == FormatSlack {
+		return json.Marshal(slackMessage{Text: slackText(notification)})
+	}
+	return json.Marshal(notification)
+}
+
+// slackMessage is the payload of a Slack incoming webhook.
+type slackMessage struct {
+	Text string `json:"text"`
+}
+
+func slackText(n Notification) string {
+	text := fmt.Sprintf("*%s* %s/%s: %s", n.Event, n.Namespace, n.Name, n.Message)
+	if n.Schedule != "" {
+		text += fmt.Sprintf(" (schedule %s)", n.Schedule)
+	}
+	return text
+}
+
+// post posts body to the webhook, retrying with exponential backoff if it can't be reached or
+// replies with a server error.
+func (n *notifier) post(hook *webhook, notification Notification, body []byte) {
+	backoff := initialRetryBackoff
+	for attempt := 0; ; attempt++ {
+		retry, err := hook.postOnce(body)
+		if err == nil {
+			glog.V(4).Infof("Posted %s notification for %s %s/%s to webhook %s", notification.Event, notification.Kind, notification.Namespace, notification.Name, hook.Name)
+			return
+		}
+		if !retry || attempt >= hook.MaxRetries {
+			glog.Errorf("Error posting %s notification for %s %s/%s to webhook %s: %v", notification.Event, notification.Kind, notification.Namespace, notification.Name, hook.Name, err)
+			return
+		}
+
+		glog.Warningf("Error posting %s notification for %s %s/%s to webhook %s, retrying in %v: %v", notification.Event, notification.Kind, notification.Namespace, notification.Name, hook.Name, backoff, err)
+		n.sleep(backoff)
+		backoff *= 2
+	}
+}
+
+// postOnce posts body to the webhook, returning whether a failure is worth retrying.
+func (hook *webhook) postOnce(body []byte) (bool, error) {
+	res, err := hook.client.Post(hook.URL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return true, err
+	}
+	res.Body.Close()
+
+	switch {
+	case res.StatusCode >= 200 && res.StatusCode < 300:
+		return false, nil
+	case res.StatusCode >= 500 || res.StatusCode == http.StatusTooManyRequests:
+		return true, fmt.Errorf("webhook replied %s", res.Status)
+	default:
+		return false, fmt.Errorf("webhook replied %s", res.Status)
+	}
+}
@@ -0,0 +1,265 @@
+/*
+Copyright 2017 Heptio Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package notification
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	api "github.com/heptio/ark/pkg/apis/ark/v1"
+	"github.com/heptio/ark/pkg/generated/clientset/fake"
+	informers "github.com/heptio/ark/pkg/generated/informers/externalversions"
+)
+
+// webhookServer records the bodies posted to it, replying with the given statuses in turn and
+// then 200s.
+type webhookServer struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	statuses []int
+	bodies   []string
+}
+
+func newWebhookServer(statuses ...int) *webhookServer {
+	s := &webhookServer{statuses: statuses}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.bodies = append(s.bodies, string(body))
+		if len(s.statuses) > 0 {
+			w.WriteHeader(s.statuses[0])
+			s.statuses = s.statuses[1:]
+		}
+	}))
+	return s
+}
+
+// posted returns the bodies posted to the server, sorted, since notifications are posted
+// concurrently.
+func (s *webhookServer) posted() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	bodies := append([]string(nil), s.bodies...)
+	sort.Strings(bodies)
+	return bodies
+}
+
+var testNotification = Notification{
+	Event:     EventBackupCompleted,
+	Kind:      "Backup",
+	Namespace: api.DefaultNamespace,
+	Name:      "nightly-20180101000000",
+	Schedule:  "nightly",
+	Phase:     string(api.BackupPhaseCompleted),
+	Message:   "Backup completed with 0 warning(s)",
+	Timestamp: time.Date(2018, 1, 1, 0, 5, 0, 0, time.UTC),
+}
+
+func newTestNotifier(t *testing.T, config *api.NotificationsConfig, schedules ...*api.Schedule) (*notifier, *[]time.Duration) {
+	sharedInformers := informers.NewSharedInformerFactory(fake.NewSimpleClientset(), 0)
+	for _, schedule := range schedules {
+		require.NoError(t, sharedInformers.Ark().V1().Schedules().Informer().GetStore().Add(schedule))
+	}
+
+	n, err := NewNotifier(config, sharedInformers.Ark().V1().Schedules().Lister())
+	require.NoError(t, err)
+
+	var sleeps []time.Duration
+	n.(*notifier).sleep = func(d time.Duration) { sleeps = append(sleeps, d) }
+	return n.(*notifier), &sleeps
+}
+
+func TestNotifyFormats(t *testing.T) {
+	server := newWebhookServer()
+	defer server.Close()
+
+	n, _ := newTestNotifier(t, &api.NotificationsConfig{
+		Webhooks: []api.NotificationWebhook{
+			{Name: "json", URL: server.URL},
+			{Name: "slack", URL: server.URL, Format: FormatSlack},
+			{Name: "template", URL: server.URL, Format: FormatSlack, Template: `{"summary": "{{.Kind}} {{.Name}} {{.Phase}}"}`},
+		},
+	})
+	n.Notify(testNotification)
+	n.pending.Wait()
+
+	assert.Equal(t, []string{
+		`{"event":"BackupCompleted","kind":"Backup","namespace":"heptio-ark","name":"nightly-20180101000000","schedule":"nightly","phase":"Completed","message":"Backup completed with 0 warning(s)","timestamp":"2018-01-01T00:05:00Z"}`,
+		`{"summary": "Backup nightly-20180101000000 Completed"}`,
+		`{"text":"*BackupCompleted* heptio-ark/nightly-20180101000000: Backup completed with 0 warning(s) (schedule nightly)"}`,
+	}, server.posted())
+}
+
+func TestNotifyFiltersEvents(t *testing.T) {
+	server := newWebhookServer()
+	defer server.Close()
+
+	schedules := []*api.Schedule{
+		{
+			ObjectMeta: metav1.ObjectMeta{Namespace: api.DefaultNamespace, Name: "quiet"},
+			Spec:       api.ScheduleSpec{Notifications: &api.NotificationOverrides{Disabled: true}},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Namespace: api.DefaultNamespace, Name: "important"},
+			Spec: api.ScheduleSpec{Notifications: &api.NotificationOverrides{
+				Webhooks: []string{"failures"},
+				Events:   []string{EventBackupCompleted, EventBackupFailed},
+			}},
+		},
+	}
+	n, _ := newTestNotifier(t, &api.NotificationsConfig{
+		Webhooks: []api.NotificationWebhook{
+			{Name: "failures", URL: server.URL, Template: "failures {{.Name}}", Events: []string{EventBackupFailed, EventRestoreFailed}},
+			{Name: "all", URL: server.URL, Template: "all {{.Name}}"},
+		},
+	}, schedules...)
+
+	notify := func(event, name, schedule string) {
+		notification := testNotification
+		notification.Event, notification.Name, notification.Schedule = event, name, schedule
+		n.Notify(notification)
+	}
+	notify(EventBackupCompleted, "backup-1", "")
+	notify(EventBackupFailed, "backup-2", "")
+	notify(EventBackupFailed, "quiet-1", "quiet")
+	notify(EventBackupCompleted, "important-1", "important")
+	notify(EventBackupExpired, "important-2", "important")
+	// a schedule that's been deleted doesn't override anything
+	notify(EventBackupCompleted, "deleted-1", "deleted")
+	n.pending.Wait()
+
+	assert.Equal(t, []string{
+		"all backup-1",
+		"all backup-2",
+		"all deleted-1",
+		"failures backup-2",
+		"failures important-1",
+	}, server.posted())
+}
+
+func TestNotifyRetries(t *testing.T) {
+	tests := []struct {
+		name           string
+		statuses       []int
+		maxRetries     int
+		expectedPosts  int
+		expectedSleeps []time.Duration
+	}{
+		{
+			name:          "success isn't retried",
+			expectedPosts: 1,
+		},
+		{
+			name:           "server errors are retried with backoff",
+			statuses:       []int{http.StatusInternalServerError, http.StatusTooManyRequests},
+			expectedPosts:  3,
+			expectedSleeps: []time.Duration{time.Second, 2 * time.Second},
+		},
+		{
+			name:           "retries are limited",
+			statuses:       []int{http.StatusBadGateway, http.StatusBadGateway, http.StatusBadGateway},
+			maxRetries:     2,
+			expectedPosts:  3,
+			expectedSleeps: []time.Duration{time.Second, 2 * time.Second},
+		},
+		{
+			name:          "client errors aren't retried",
+			statuses:      []int{http.StatusBadRequest},
+			expectedPosts: 1,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			server := newWebhookServer(test.statuses...)
+			defer server.Close()
+
+			n, sleeps := newTestNotifier(t, &api.NotificationsConfig{
+				Webhooks: []api.NotificationWebhook{{Name: "hook", URL: server.URL, MaxRetries: test.maxRetries}},
+			})
+			n.Notify(testNotification)
+			n.pending.Wait()
+
+			assert.Len(t, server.posted(), test.expectedPosts)
+			assert.Equal(t, test.expectedSleeps, *sleeps)
+		})
+	}
+}
+
+func TestNewNotifierValidation(t *testing.T) {
+	tests := []struct {
+		name          string
+		webhooks      []api.NotificationWebhook
+		expectedError string
+	}{
+		{
+			name:          "missing name",
+			webhooks:      []api.NotificationWebhook{{URL: "https://example.com"}},
+			expectedError: "invalid notification webhook #0: name is required",
+		},
+		{
+			name:          "invalid URL",
+			webhooks:      []api.NotificationWebhook{{Name: "hook", URL: "example.com/hook"}},
+			expectedError: "invalid notification webhook #0: url must be an http or https URL",
+		},
+		{
+			name:          "unknown format",
+			webhooks:      []api.NotificationWebhook{{Name: "hook", URL: "https://example.com", Format: "xml"}},
+			expectedError: `invalid notification webhook #0: unknown format "xml"; valid formats are json and slack`,
+		},
+		{
+			name:          "unknown event",
+			webhooks:      []api.NotificationWebhook{{Name: "hook", URL: "https://example.com", Events: []string{"BackupStarted"}}},
+			expectedError: `invalid notification webhook #0: unknown event "BackupStarted"`,
+		},
+		{
+			name:          "invalid template",
+			webhooks:      []api.NotificationWebhook{{Name: "hook", URL: "https://example.com", Template: "{{.Name"}},
+			expectedError: "invalid notification webhook #0: invalid template",
+		},
+		{
+			name: "duplicate names",
+			webhooks: []api.NotificationWebhook{
+				{Name: "hook", URL: "https://example.com/1"},
+				{Name: "hook", URL: "https://example.com/2"},
+			},
+			expectedError: "invalid notification webhook #1: there's more than one webhook named hook",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := NewNotifier(&api.NotificationsConfig{Webhooks: test.webhooks}, nil)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), test.expectedError)
+		})
+	}
+}
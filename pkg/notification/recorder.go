@@ -0,0 +1,104 @@
+/*
+Copyright 2017 Heptio Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package notification
+
+import (
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/runtime"
+
+	api "github.com/heptio/ark/pkg/apis/ark/v1"
+	"github.com/heptio/ark/pkg/event"
+)
+
+// backupEvents and restoreEvents map the reasons of the events recorded about backups and
+// restores to the notification events sent for them.
+var (
+	backupEvents = map[string]string{
+		event.ReasonBackupCompleted:        EventBackupCompleted,
+		event.ReasonBackupPartiallyFailed:  EventBackupPartiallyFailed,
+		event.ReasonBackupFailed:           EventBackupFailed,
+		event.ReasonBackupFailedValidation: EventBackupFailed,
+		event.ReasonBackupExpired:          EventBackupExpired,
+	}
+
+	restoreEvents = map[string]string{
+		event.ReasonRestoreCompleted:        EventRestoreCompleted,
+		event.ReasonRestorePartiallyFailed:  EventRestorePartiallyFailed,
+		event.ReasonRestoreFailed:           EventRestoreFailed,
+		event.ReasonRestoreFailedValidation: EventRestoreFailed,
+	}
+)
+
+type notifyingRecorder struct {
+	recorder event.Recorder
+	notifier Notifier
+}
+
+// NewRecorder returns an event.Recorder that records events with recorder, and sends
+// notifications with notifier about the events that mark backups and restores completing,
+// failing, or expiring, with the events' messages.
+func NewRecorder(recorder event.Recorder, notifier Notifier) event.Recorder {
+	return &notifyingRecorder{
+		recorder: recorder,
+		notifier: notifier,
+	}
+}
+
+func (r *notifyingRecorder) Eventf(obj runtime.Object, eventType, reason, messageFmt string, args ...interface{}) {
+	r.recorder.Eventf(obj, eventType, reason, messageFmt, args...)
+
+	if n, ok := notificationFor(obj, reason, fmt.Sprintf(messageFmt, args...)); ok {
+		r.notifier.Notify(n)
+	}
+}
+
+// notificationFor returns the notification to send about an event, and false if none is sent
+// for it.
+func notificationFor(obj runtime.Object, reason, message string) (Notification, bool) {
+	switch obj := obj.(type) {
+	case *api.Backup:
+		notificationEvent, ok := backupEvents[reason]
+		if !ok {
+			return Notification{}, false
+		}
+		return Notification{
+			Event:     notificationEvent,
+			Kind:      "Backup",
+			Namespace: obj.Namespace,
+			Name:      obj.Name,
+			Schedule:  obj.Labels[api.ScheduleNameLabel],
+			Phase:     string(obj.Status.Phase),
+			Message:   message,
+		}, true
+	case *api.Restore:
+		notificationEvent, ok := restoreEvents[reason]
+		if !ok {
+			return Notification{}, false
+		}
+		return Notification{
+			Event:     notificationEvent,
+			Kind:      "Restore",
+			Namespace: obj.Namespace,
+			Name:      obj.Name,
+			Phase:     string(obj.Status.Phase),
+			Message:   message,
+		}, true
+	default:
+		return Notification{}, false
+	}
+}
@@ -0,0 +1,80 @@
+/*
+Copyright 2017 Heptio Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package notification
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"k8s.io/client-go/pkg/api/v1"
+
+	api "github.com/heptio/ark/pkg/apis/ark/v1"
+	"github.com/heptio/ark/pkg/event"
+	. "github.com/heptio/ark/pkg/util/test"
+)
+
+type fakeNotifier struct {
+	notifications []Notification
+}
+
+func (n *fakeNotifier) Notify(notification Notification) {
+	n.notifications = append(n.notifications, notification)
+}
+
+func TestNotifyingRecorder(t *testing.T) {
+	var (
+		recorder = &FakeEventRecorder{}
+		notifier = &fakeNotifier{}
+		r        = NewRecorder(recorder, notifier)
+	)
+
+	backup := NewTestBackup().WithName("nightly-1").WithPhase(api.BackupPhaseCompleted).WithLabel(api.ScheduleNameLabel, "nightly").Backup
+	restore := NewTestRestore(api.DefaultNamespace, "restore-1", api.RestorePhaseFailedValidation).Restore
+
+	r.Eventf(backup, v1.EventTypeNormal, event.ReasonBackupStarted, "Started backup")
+	r.Eventf(backup, v1.EventTypeNormal, event.ReasonBackupCompleted, "Backup completed with %d warning(s)", 2)
+	r.Eventf(restore, v1.EventTypeWarning, event.ReasonRestoreFailedValidation, "Restore failed validation: %s", "BackupName must be non-empty")
+	r.Eventf(&api.Schedule{}, v1.EventTypeNormal, event.ReasonBackupCompleted, "not a backup")
+
+	assert.Equal(t, []string{
+		"Normal BackupStarted Started backup",
+		"Normal BackupCompleted Backup completed with 2 warning(s)",
+		"Warning FailedValidation Restore failed validation: BackupName must be non-empty",
+		"Normal BackupCompleted not a backup",
+	}, recorder.Events)
+
+	assert.Equal(t, []Notification{
+		{
+			Event:     EventBackupCompleted,
+			Kind:      "Backup",
+			Namespace: api.DefaultNamespace,
+			Name:      "nightly-1",
+			Schedule:  "nightly",
+			Phase:     string(api.BackupPhaseCompleted),
+			Message:   "Backup completed with 2 warning(s)",
+		},
+		{
+			Event:     EventRestoreFailed,
+			Kind:      "Restore",
+			Namespace: api.DefaultNamespace,
+			Name:      "restore-1",
+			Phase:     string(api.RestorePhaseFailedValidation),
+			Message:   "Restore failed validation: BackupName must be non-empty",
+		},
+	}, notifier.notifications)
+}
@@ -0,0 +1,252 @@
+/*
+Copyright 2017 Heptio Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plugin
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/heptio/ark/pkg/cloudprovider"
+)
+
+// CreateVolumeFromSnapshotArgs are the arguments of the BlockStore.CreateVolumeFromSnapshot RPC.
+type CreateVolumeFromSnapshotArgs struct {
+	Instance   int
+	SnapshotID string
+	VolumeType string
+	IOPS       *int64
+}
+
+// VolumeArgs are the arguments of BlockStore RPCs about one volume.
+type VolumeArgs struct {
+	Instance int
+	VolumeID string
+}
+
+// VolumeInfo is the reply of the BlockStore.GetVolumeInfo RPC.
+type VolumeInfo struct {
+	Type string
+	IOPS *int64
+}
+
+// ListSnapshotsArgs are the arguments of the BlockStore.ListSnapshots RPC.
+type ListSnapshotsArgs struct {
+	Instance   int
+	TagFilters map[string]string
+}
+
+// CreateSnapshotArgs are the arguments of the BlockStore.CreateSnapshot RPC.
+type CreateSnapshotArgs struct {
+	Instance int
+	VolumeID string
+	Tags     map[string]string
+}
+
+// SnapshotArgs are the arguments of BlockStore RPCs about one snapshot.
+type SnapshotArgs struct {
+	Instance   int
+	SnapshotID string
+}
+
+// blockStoreServer serves a plugin's BlockStorageAdapters.
+type blockStoreServer struct {
+	newAdapter func(config map[string]string) (cloudprovider.BlockStorageAdapter, error)
+	handles    *handles
+}
+
+func (s *blockStoreServer) Init(args InitArgs, reply *int) error {
+	if s.newAdapter == nil {
+		return errors.New("the plugin doesn't serve a BlockStore")
+	}
+
+	adapter, err := s.newAdapter(args.Config)
+	if err != nil {
+		return err
+	}
+	*reply = s.handles.add(adapter)
+	return nil
+}
+
+func (s *blockStoreServer) adapter(instance int) (cloudprovider.BlockStorageAdapter, error) {
+	value, err := s.handles.get(instance)
+	if err != nil {
+		return nil, err
+	}
+	adapter, ok := value.(cloudprovider.BlockStorageAdapter)
+	if !ok {
+		return nil, fmt.Errorf("handle %d isn't a BlockStore", instance)
+	}
+	return adapter, nil
+}
+
+func (s *blockStoreServer) CreateVolumeFromSnapshot(args CreateVolumeFromSnapshotArgs, reply *string) error {
+	adapter, err := s.adapter(args.Instance)
+	if err != nil {
+		return err
+	}
+	volumeID, err := adapter.CreateVolumeFromSnapshot(args.SnapshotID, args.VolumeType, args.IOPS)
+	if err != nil {
+		return err
+	}
+	*reply = volumeID
+	return nil
+}
+
+func (s *blockStoreServer) GetVolumeInfo(args VolumeArgs, reply *VolumeInfo) error {
+	adapter, err := s.adapter(args.Instance)
+	if err != nil {
+		return err
+	}
+	volumeType, iops, err := adapter.GetVolumeInfo(args.VolumeID)
+	if err != nil {
+		return err
+	}
+	reply.Type, reply.IOPS = volumeType, iops
+	return nil
+}
+
+func (s *blockStoreServer) IsVolumeReady(args VolumeArgs, reply *bool) error {
+	adapter, err := s.adapter(args.Instance)
+	if err != nil {
+		return err
+	}
+	ready, err := adapter.IsVolumeReady(args.VolumeID)
+	if err != nil {
+		return err
+	}
+	*reply = ready
+	return nil
+}
+
+func (s *blockStoreServer) ListSnapshots(args ListSnapshotsArgs, reply *[]string) error {
+	adapter, err := s.adapter(args.Instance)
+	if err != nil {
+		return err
+	}
+	snapshotIDs, err := adapter.ListSnapshots(args.TagFilters)
+	if err != nil {
+		return err
+	}
+	*reply = snapshotIDs
+	return nil
+}
+
+func (s *blockStoreServer) CreateSnapshot(args CreateSnapshotArgs, reply *string) error {
+	adapter, err := s.adapter(args.Instance)
+	if err != nil {
+		return err
+	}
+	snapshotID, err := adapter.CreateSnapshot(args.VolumeID, args.Tags)
+	if err != nil {
+		return err
+	}
+	*reply = snapshotID
+	return nil
+}
+
+func (s *blockStoreServer) DeleteSnapshot(args SnapshotArgs, _ *Empty) error {
+	adapter, err := s.adapter(args.Instance)
+	if err != nil {
+		return err
+	}
+	return adapter.DeleteSnapshot(args.SnapshotID)
+}
+
+// blockStore is a BlockStorageAdapter that's served by a plugin.
+type blockStore struct {
+	instance *instance
+}
+
+var _ cloudprovider.BlockStorageAdapter = &blockStore{}
+
+func newBlockStore(connector connector, config map[string]string) *blockStore {
+	return &blockStore{instance: newInstance(connector, "BlockStore", config)}
+}
+
+func (s *blockStore) CreateVolumeFromSnapshot(snapshotID, volumeType string, iops *int64) (string, error) {
+	conn, id, err := s.instance.connect()
+	if err != nil {
+		return "", err
+	}
+
+	var volumeID string
+	args := CreateVolumeFromSnapshotArgs{Instance: id, SnapshotID: snapshotID, VolumeType: volumeType, IOPS: iops}
+	if err := conn.call("BlockStore.CreateVolumeFromSnapshot", args, &volumeID); err != nil {
+		return "", err
+	}
+	return volumeID, nil
+}
+
+func (s *blockStore) GetVolumeInfo(volumeID string) (string, *int64, error) {
+	conn, id, err := s.instance.connect()
+	if err != nil {
+		return "", nil, err
+	}
+
+	var info VolumeInfo
+	if err := conn.call("BlockStore.GetVolumeInfo", VolumeArgs{Instance: id, VolumeID: volumeID}, &info); err != nil {
+		return "", nil, err
+	}
+	return info.Type, info.IOPS, nil
+}
+
+func (s *blockStore) IsVolumeReady(volumeID string) (bool, error) {
+	conn, id, err := s.instance.connect()
+	if err != nil {
+		return false, err
+	}
+
+	var ready bool
+	if err := conn.call("BlockStore.IsVolumeReady", VolumeArgs{Instance: id, VolumeID: volumeID}, &ready); err != nil {
+		return false, err
+	}
+	return ready, nil
+}
+
+func (s *blockStore) ListSnapshots(tagFilters map[string]string) ([]string, error) {
+	conn, id, err := s.instance.connect()
+	if err != nil {
+		return nil, err
+	}
+
+	var snapshotIDs []string
+	if err := conn.call("BlockStore.ListSnapshots", ListSnapshotsArgs{Instance: id, TagFilters: tagFilters}, &snapshotIDs); err != nil {
+		return nil, err
+	}
+	return snapshotIDs, nil
+}
+
+func (s *blockStore) CreateSnapshot(volumeID string, tags map[string]string) (string, error) {
+	conn, id, err := s.instance.connect()
+	if err != nil {
+		return "", err
+	}
+
+	var snapshotID string
+	if err := conn.call("BlockStore.CreateSnapshot", CreateSnapshotArgs{Instance: id, VolumeID: volumeID, Tags: tags}, &snapshotID); err != nil {
+		return "", err
+	}
+	return snapshotID, nil
+}
+
+func (s *blockStore) DeleteSnapshot(snapshotID string) error {
+	conn, id, err := s.instance.connect()
+	if err != nil {
+		return err
+	}
+	return conn.call("BlockStore.DeleteSnapshot", SnapshotArgs{Instance: id, SnapshotID: snapshotID}, &Empty{})
+}
@@ -0,0 +1,329 @@
+/*
+Copyright 2017 Heptio Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plugin
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net/rpc"
+	"os"
+	"os/exec"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+
+	"github.com/heptio/ark/pkg/cloudprovider"
+)
+
+// startTimeout is how long a plugin has to write its handshake after it's started.
+const startTimeout = 30 * time.Second
+
+// conn is a connection to one run of a plugin's process.
+type conn struct {
+	name   string
+	client *rpc.Client
+
+	// generation counts the runs of the plugin's process. Handles are only valid in the run
+	// they're created in.
+	generation int
+
+	// broken is called if a call fails because the connection is broken, e.g. because the
+	// plugin crashed. Optional.
+	broken func()
+}
+
+// call makes an RPC to the plugin.
+func (c *conn) call(method string, args, reply interface{}) error {
+	err := c.client.Call(method, args, reply)
+	if err == nil {
+		return nil
+	}
+	if _, ok := err.(rpc.ServerError); ok {
+		return err
+	}
+
+	if c.broken != nil {
+		c.broken()
+	}
+	return fmt.Errorf("error calling plugin %s, which will be restarted: %v", c.name, err)
+}
+
+// connector connects to a plugin.
+type connector interface {
+	connect() (*conn, error)
+}
+
+// instance is an adapter that's been created in a plugin.
+type instance struct {
+	connector connector
+	kind      string
+	config    map[string]string
+
+	mu         sync.Mutex
+	id         int
+	generation int
+}
+
+func newInstance(connector connector, kind string, config map[string]string) *instance {
+	return &instance{
+		connector: connector,
+		kind:      kind,
+		config:    config,
+	}
+}
+
+// connect returns a connection to the plugin and the handle of the adapter in it. The adapter is
+// created first if it hasn't been yet in the plugin's current run.
+func (i *instance) connect() (*conn, int, error) {
+	conn, err := i.connector.connect()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	if i.id != 0 && i.generation == conn.generation {
+		return conn, i.id, nil
+	}
+
+	var id int
+	if err := conn.call(i.kind+".Init", InitArgs{Config: i.config}, &id); err != nil {
+		return nil, 0, err
+	}
+	i.id, i.generation = id, conn.generation
+
+	return conn, id, nil
+}
+
+// Client runs a plugin's binary and connects to it. The plugin is started when it's first used,
+// and restarted when it's next used after it exits or stops responding.
+type Client struct {
+	name string
+	path string
+
+	mu         sync.Mutex
+	cmd        *exec.Cmd
+	conn       *conn
+	exited     chan struct{}
+	generation int
+	stopped    bool
+}
+
+// NewClient returns a Client for the plugin named name whose binary is at path.
+func NewClient(name, path string) *Client {
+	return &Client{
+		name: name,
+		path: path,
+	}
+}
+
+// ObjectStore returns an ObjectStorageAdapter that's served by the plugin, configured with config.
+func (c *Client) ObjectStore(config map[string]string) (cloudprovider.ObjectStorageAdapter, error) {
+	store := newObjectStore(c, config)
+	// create the adapter now, so that misconfigurations are reported right away
+	if _, _, err := store.instance.connect(); err != nil {
+		return nil, err
+	}
+	return store, nil
+}
+
+// BlockStore returns a BlockStorageAdapter that's served by the plugin, configured with config.
+func (c *Client) BlockStore(config map[string]string) (cloudprovider.BlockStorageAdapter, error) {
+	store := newBlockStore(c, config)
+	if _, _, err := store.instance.connect(); err != nil {
+		return nil, err
+	}
+	return store, nil
+}
+
+func (c *Client) connect() (*conn, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.stopped {
+		return nil, fmt.Errorf("plugin %s has been stopped", c.name)
+	}
+
+	if c.conn != nil {
+		select {
+		case <-c.exited:
+			glog.Errorf("Plugin %s exited unexpectedly", c.name)
+			c.conn.client.Close()
+			c.conn = nil
+		default:
+			return c.conn, nil
+		}
+	}
+
+	if c.generation > 0 {
+		glog.Warningf("Restarting plugin %s", c.name)
+	}
+	if err := c.start(); err != nil {
+		return nil, fmt.Errorf("error starting plugin %s: %v", c.name, err)
+	}
+	return c.conn, nil
+}
+
+// start starts the plugin's process and connects to it. c.mu must be held.
+func (c *Client) start() error {
+	cmd := exec.Command(c.path)
+	cmd.Env = append(os.Environ(), MagicCookieKey+"="+MagicCookieValue)
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	// the first line of stdout is the handshake; the rest of the plugin's output is logged.
+	// the process can only be waited for once all of its output has been read.
+	handshakes := make(chan string, 1)
+	exited := make(chan struct{})
+	var output sync.WaitGroup
+	output.Add(2)
+	go func() {
+		defer output.Done()
+		c.logOutput(stdout, handshakes)
+	}()
+	go func() {
+		defer output.Done()
+		c.logOutput(stderr, nil)
+	}()
+	go func() {
+		output.Wait()
+		if err := cmd.Wait(); err != nil {
+			glog.Errorf("Plugin %s exited: %v", c.name, err)
+		}
+		close(exited)
+	}()
+
+	kill := func() {
+		cmd.Process.Kill()
+		<-exited
+	}
+
+	var line string
+	select {
+	case line = <-handshakes:
+	case <-exited:
+		return errors.New("it exited before writing its handshake")
+	case <-time.After(startTimeout):
+		kill()
+		return fmt.Errorf("it didn't write its handshake within %v", startTimeout)
+	}
+
+	h, err := parseHandshake(line)
+	if err != nil {
+		kill()
+		return err
+	}
+
+	client, err := rpc.Dial(h.network, h.address)
+	if err != nil {
+		kill()
+		return err
+	}
+
+	c.generation++
+	generation := c.generation
+	c.cmd, c.exited = cmd, exited
+	c.conn = &conn{
+		name:       c.name,
+		client:     client,
+		generation: generation,
+		broken:     func() { c.kill(generation) },
+	}
+	glog.Infof("Started plugin %s (pid %d)", c.name, cmd.Process.Pid)
+
+	return nil
+}
+
+// logOutput logs each line that the plugin writes to out. If handshakes isn't nil, the first
+// line is sent to it instead.
+func (c *Client) logOutput(out io.Reader, handshakes chan<- string) {
+	scanner := bufio.NewScanner(out)
+	for scanner.Scan() {
+		if handshakes != nil {
+			handshakes <- scanner.Text()
+			handshakes = nil
+			continue
+		}
+		glog.Infof("plugin %s: %s", c.name, scanner.Text())
+	}
+}
+
+// kill kills the plugin's process if it's still the given run, so that it's restarted when it's
+// next used.
+func (c *Client) kill(generation int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.conn == nil || c.conn.generation != generation {
+		return
+	}
+	c.killLocked()
+}
+
+// killLocked kills the plugin's process and waits for it to exit. c.mu must be held.
+func (c *Client) killLocked() {
+	c.conn.client.Close()
+	c.cmd.Process.Kill()
+	<-c.exited
+	c.conn = nil
+}
+
+// CheckHealth pings the plugin, starting it if it isn't running. If it doesn't reply within
+// timeout, it's killed, so that it's restarted when it's next used.
+func (c *Client) CheckHealth(timeout time.Duration) error {
+	conn, err := c.connect()
+	if err != nil {
+		return err
+	}
+
+	call := conn.client.Go("Plugin.Ping", Empty{}, new(int), make(chan *rpc.Call, 1))
+	select {
+	case <-call.Done:
+		if call.Error != nil {
+			c.kill(conn.generation)
+			return fmt.Errorf("plugin %s failed its health check, so it's been killed and will be restarted: %v", c.name, call.Error)
+		}
+		return nil
+	case <-time.After(timeout):
+		c.kill(conn.generation)
+		return fmt.Errorf("plugin %s didn't reply to its health check within %v, so it's been killed and will be restarted", c.name, timeout)
+	}
+}
+
+// Stop kills the plugin's process, if it's running, and stops it from being restarted.
+func (c *Client) Stop() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.stopped = true
+	if c.conn != nil {
+		c.killLocked()
+	}
+}
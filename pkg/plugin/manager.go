@@ -0,0 +1,135 @@
+/*
+Copyright 2017 Heptio Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+
+	"github.com/heptio/ark/pkg/cloudprovider"
+)
+
+const (
+	// healthCheckPeriod is how often the plugins' health is checked.
+	healthCheckPeriod = 30 * time.Second
+
+	// healthCheckTimeout is how long a plugin has to reply to a health check.
+	healthCheckTimeout = 10 * time.Second
+)
+
+// Manager runs the plugins in a directory, starting each one the first time it's used.
+type Manager struct {
+	dir string
+
+	mu      sync.Mutex
+	clients map[string]*Client
+}
+
+// NewManager returns a Manager for the plugins in dir.
+func NewManager(dir string) *Manager {
+	return &Manager{
+		dir:     dir,
+		clients: make(map[string]*Client),
+	}
+}
+
+// ObjectStore returns an ObjectStorageAdapter that's served by the named plugin, configured with
+// config.
+func (m *Manager) ObjectStore(name string, config map[string]string) (cloudprovider.ObjectStorageAdapter, error) {
+	client, err := m.client(name)
+	if err != nil {
+		return nil, err
+	}
+	return client.ObjectStore(config)
+}
+
+// BlockStore returns a BlockStorageAdapter that's served by the named plugin, configured with
+// config.
+func (m *Manager) BlockStore(name string, config map[string]string) (cloudprovider.BlockStorageAdapter, error) {
+	client, err := m.client(name)
+	if err != nil {
+		return nil, err
+	}
+	return client.BlockStore(config)
+}
+
+func (m *Manager) client(name string) (*Client, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if client, ok := m.clients[name]; ok {
+		return client, nil
+	}
+
+	if name == "" || filepath.Base(name) != name {
+		return nil, fmt.Errorf("invalid plugin name %q", name)
+	}
+	path := filepath.Join(m.dir, BinaryPrefix+name)
+	if _, err := os.Stat(path); err != nil {
+		return nil, fmt.Errorf("plugin %s not found: %v", name, err)
+	}
+
+	client := NewClient(name, path)
+	m.clients[name] = client
+	return client, nil
+}
+
+// RunHealthChecks checks the health of the plugins that have been used periodically, until ctx
+// is done, so that plugins that have crashed or hung are restarted.
+func (m *Manager) RunHealthChecks(ctx context.Context) {
+	ticker := time.NewTicker(healthCheckPeriod)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			for _, client := range m.sortedClients() {
+				if err := client.CheckHealth(healthCheckTimeout); err != nil {
+					glog.Errorf("Error checking health of plugin %s: %v", client.name, err)
+				}
+			}
+		}
+	}
+}
+
+// Stop stops all of the plugins.
+func (m *Manager) Stop() {
+	for _, client := range m.sortedClients() {
+		client.Stop()
+	}
+}
+
+func (m *Manager) sortedClients() []*Client {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	clients := make([]*Client, 0, len(m.clients))
+	for _, client := range m.clients {
+		clients = append(clients, client)
+	}
+	sort.Slice(clients, func(i, j int) bool { return clients[i].name < clients[j].name })
+	return clients
+}
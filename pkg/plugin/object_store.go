@@ -0,0 +1,382 @@
+/*
+Copyright 2017 Heptio Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plugin
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"time"
+
+	"github.com/heptio/ark/pkg/cloudprovider"
+)
+
+// chunkSize is the most object data sent in one RPC.
+const chunkSize = 1 << 20
+
+// errObjectNotFound is the error the ObjectStore.GetObject RPC replies with when the object
+// doesn't exist, since RPC errors only carry their messages.
+var errObjectNotFound = errors.New("object not found")
+
+// InitArgs are the arguments of the Init RPCs, which create an adapter in the plugin and reply
+// with its handle.
+type InitArgs struct {
+	Config map[string]string
+}
+
+// ObjectArgs are the arguments of ObjectStore RPCs about one object.
+type ObjectArgs struct {
+	Instance int
+	Bucket   string
+	Key      string
+}
+
+// WriteArgs are the arguments of the ObjectStore.Write RPC.
+type WriteArgs struct {
+	Handle int
+	Data   []byte
+}
+
+// PutObjectArgs are the arguments of the ObjectStore.PutObject RPC.
+type PutObjectArgs struct {
+	Instance int
+	Upload   int
+	Bucket   string
+	Key      string
+}
+
+// ReadArgs are the arguments of the ObjectStore.Read RPC.
+type ReadArgs struct {
+	Handle int
+	Size   int
+}
+
+// ReadReply is the reply of the ObjectStore.Read RPC. EOF is whether the object has been read to
+// its end; Data may be non-empty even if it is.
+type ReadReply struct {
+	Data []byte
+	EOF  bool
+}
+
+// ListCommonPrefixesArgs are the arguments of the ObjectStore.ListCommonPrefixes RPC.
+type ListCommonPrefixesArgs struct {
+	Instance  int
+	Bucket    string
+	Prefix    string
+	Delimiter string
+}
+
+// CreateSignedURLArgs are the arguments of the ObjectStore.CreateSignedURL RPC.
+type CreateSignedURLArgs struct {
+	Instance int
+	Bucket   string
+	Key      string
+	TTL      time.Duration
+}
+
+// objectStoreServer serves a plugin's ObjectStorageAdapters. Objects are put by writing them to
+// a temp file in chunks, and then putting the file, since adapters need to be able to seek
+// within them; they're read in chunks.
+type objectStoreServer struct {
+	newAdapter func(config map[string]string) (cloudprovider.ObjectStorageAdapter, error)
+	handles    *handles
+}
+
+func (s *objectStoreServer) Init(args InitArgs, reply *int) error {
+	if s.newAdapter == nil {
+		return errors.New("the plugin doesn't serve an ObjectStore")
+	}
+
+	adapter, err := s.newAdapter(args.Config)
+	if err != nil {
+		return err
+	}
+	*reply = s.handles.add(adapter)
+	return nil
+}
+
+func (s *objectStoreServer) adapter(instance int) (cloudprovider.ObjectStorageAdapter, error) {
+	value, err := s.handles.get(instance)
+	if err != nil {
+		return nil, err
+	}
+	adapter, ok := value.(cloudprovider.ObjectStorageAdapter)
+	if !ok {
+		return nil, fmt.Errorf("handle %d isn't an ObjectStore", instance)
+	}
+	return adapter, nil
+}
+
+// upload is a temp file that an object's written to before it's put.
+type upload struct {
+	*os.File
+}
+
+// Close closes and removes the temp file.
+func (u *upload) Close() error {
+	u.File.Close()
+	return os.Remove(u.Name())
+}
+
+func (s *objectStoreServer) BeginUpload(_ Empty, reply *int) error {
+	file, err := ioutil.TempFile("", "ark-plugin-upload")
+	if err != nil {
+		return err
+	}
+	*reply = s.handles.add(&upload{file})
+	return nil
+}
+
+func (s *objectStoreServer) Write(args WriteArgs, _ *Empty) error {
+	value, err := s.handles.get(args.Handle)
+	if err != nil {
+		return err
+	}
+	u, ok := value.(*upload)
+	if !ok {
+		return fmt.Errorf("handle %d isn't an upload", args.Handle)
+	}
+	_, err = u.Write(args.Data)
+	return err
+}
+
+func (s *objectStoreServer) PutObject(args PutObjectArgs, _ *Empty) error {
+	value, err := s.handles.remove(args.Upload)
+	if err != nil {
+		return err
+	}
+	u, ok := value.(*upload)
+	if !ok {
+		return fmt.Errorf("handle %d isn't an upload", args.Upload)
+	}
+	defer u.Close()
+
+	adapter, err := s.adapter(args.Instance)
+	if err != nil {
+		return err
+	}
+	if _, err := u.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	return adapter.PutObject(args.Bucket, args.Key, u.File)
+}
+
+func (s *objectStoreServer) GetObject(args ObjectArgs, reply *int) error {
+	adapter, err := s.adapter(args.Instance)
+	if err != nil {
+		return err
+	}
+	body, err := adapter.GetObject(args.Bucket, args.Key)
+	if cloudprovider.IsObjectNotFound(err) {
+		return errObjectNotFound
+	}
+	if err != nil {
+		return err
+	}
+	*reply = s.handles.add(body)
+	return nil
+}
+
+func (s *objectStoreServer) Read(args ReadArgs, reply *ReadReply) error {
+	value, err := s.handles.get(args.Handle)
+	if err != nil {
+		return err
+	}
+	body, ok := value.(io.Reader)
+	if !ok {
+		return fmt.Errorf("handle %d isn't an object being read", args.Handle)
+	}
+
+	size := args.Size
+	if size <= 0 || size > chunkSize {
+		size = chunkSize
+	}
+	buf := make([]byte, size)
+	n, err := io.ReadFull(body, buf)
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		reply.EOF = true
+		err = nil
+	}
+	reply.Data = buf[:n]
+	return err
+}
+
+func (s *objectStoreServer) ListCommonPrefixes(args ListCommonPrefixesArgs, reply *[]string) error {
+	adapter, err := s.adapter(args.Instance)
+	if err != nil {
+		return err
+	}
+	prefixes, err := adapter.ListCommonPrefixes(args.Bucket, args.Prefix, args.Delimiter)
+	if err != nil {
+		return err
+	}
+	*reply = prefixes
+	return nil
+}
+
+func (s *objectStoreServer) DeleteObject(args ObjectArgs, _ *Empty) error {
+	adapter, err := s.adapter(args.Instance)
+	if err != nil {
+		return err
+	}
+	return adapter.DeleteObject(args.Bucket, args.Key)
+}
+
+func (s *objectStoreServer) CreateSignedURL(args CreateSignedURLArgs, reply *string) error {
+	adapter, err := s.adapter(args.Instance)
+	if err != nil {
+		return err
+	}
+	url, err := adapter.CreateSignedURL(args.Bucket, args.Key, args.TTL)
+	if err != nil {
+		return err
+	}
+	*reply = url
+	return nil
+}
+
+// objectStore is an ObjectStorageAdapter that's served by a plugin.
+type objectStore struct {
+	instance *instance
+}
+
+var _ cloudprovider.ObjectStorageAdapter = &objectStore{}
+
+func newObjectStore(connector connector, config map[string]string) *objectStore {
+	return &objectStore{instance: newInstance(connector, "ObjectStore", config)}
+}
+
+func (s *objectStore) PutObject(bucket string, key string, body io.ReadSeeker) error {
+	conn, id, err := s.instance.connect()
+	if err != nil {
+		return err
+	}
+
+	var upload int
+	if err := conn.call("ObjectStore.BeginUpload", Empty{}, &upload); err != nil {
+		return err
+	}
+
+	if err := writeUpload(conn, upload, body); err != nil {
+		conn.call("Plugin.Close", CloseArgs{Handle: upload}, &Empty{})
+		return err
+	}
+
+	return conn.call("ObjectStore.PutObject", PutObjectArgs{Instance: id, Upload: upload, Bucket: bucket, Key: key}, &Empty{})
+}
+
+// writeUpload writes body to an upload in the plugin, a chunk at a time.
+func writeUpload(conn *conn, upload int, body io.Reader) error {
+	buf := make([]byte, chunkSize)
+	for {
+		n, err := body.Read(buf)
+		if n > 0 {
+			if err := conn.call("ObjectStore.Write", WriteArgs{Handle: upload, Data: buf[:n]}, &Empty{}); err != nil {
+				return err
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (s *objectStore) GetObject(bucket string, key string) (io.ReadCloser, error) {
+	conn, id, err := s.instance.connect()
+	if err != nil {
+		return nil, err
+	}
+
+	var handle int
+	if err := conn.call("ObjectStore.GetObject", ObjectArgs{Instance: id, Bucket: bucket, Key: key}, &handle); err != nil {
+		if err.Error() == errObjectNotFound.Error() {
+			return nil, cloudprovider.NewObjectNotFoundError(bucket, key)
+		}
+		return nil, err
+	}
+	return &objectReader{conn: conn, handle: handle}, nil
+}
+
+func (s *objectStore) ListCommonPrefixes(bucket, prefix, delimiter string) ([]string, error) {
+	conn, id, err := s.instance.connect()
+	if err != nil {
+		return nil, err
+	}
+
+	var prefixes []string
+	args := ListCommonPrefixesArgs{Instance: id, Bucket: bucket, Prefix: prefix, Delimiter: delimiter}
+	if err := conn.call("ObjectStore.ListCommonPrefixes", args, &prefixes); err != nil {
+		return nil, err
+	}
+	return prefixes, nil
+}
+
+func (s *objectStore) DeleteObject(bucket string, key string) error {
+	conn, id, err := s.instance.connect()
+	if err != nil {
+		return err
+	}
+	return conn.call("ObjectStore.DeleteObject", ObjectArgs{Instance: id, Bucket: bucket, Key: key}, &Empty{})
+}
+
+func (s *objectStore) CreateSignedURL(bucket, key string, ttl time.Duration) (string, error) {
+	conn, id, err := s.instance.connect()
+	if err != nil {
+		return "", err
+	}
+
+	var url string
+	if err := conn.call("ObjectStore.CreateSignedURL", CreateSignedURLArgs{Instance: id, Bucket: bucket, Key: key, TTL: ttl}, &url); err != nil {
+		return "", err
+	}
+	return url, nil
+}
+
+// objectReader reads an object that's being read by a plugin, a chunk at a time.
+type objectReader struct {
+	conn   *conn
+	handle int
+	buf    []byte
+	eof    bool
+}
+
+func (r *objectReader) Read(p []byte) (int, error) {
+	for len(r.buf) == 0 {
+		if r.eof {
+			return 0, io.EOF
+		}
+
+		var reply ReadReply
+		if err := r.conn.call("ObjectStore.Read", ReadArgs{Handle: r.handle, Size: chunkSize}, &reply); err != nil {
+			return 0, err
+		}
+		r.buf, r.eof = reply.Data, reply.EOF
+	}
+
+	n := copy(p, r.buf)
+	r.buf = r.buf[n:]
+	return n, nil
+}
+
+func (r *objectReader) Close() error {
+	return r.conn.call("Plugin.Close", CloseArgs{Handle: r.handle}, &Empty{})
+}
@@ -0,0 +1,109 @@
+/*
+Copyright 2017 Heptio Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package plugin runs implementations of cloudprovider.ObjectStorageAdapter and
+// cloudprovider.BlockStorageAdapter as separate plugin binaries, so that cloud providers don't
+// have to be compiled into Ark. The Ark server starts each plugin as a child process, which
+// writes a handshake line to its stdout naming the unix socket it serves the adapters on over
+// net/rpc. The server checks the plugin's health periodically, and restarts it if it crashes.
+//
+// A plugin binary's main function calls Serve with its adapters.
+package plugin
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	// MagicCookieKey and MagicCookieValue are set in the environment of the plugin processes the
+	// Ark server starts. Serve refuses to run without them, so that a plugin run directly by
+	// mistake exits with an explanation rather than waiting for a connection.
+	MagicCookieKey   = "ARK_PLUGIN_MAGIC_COOKIE"
+	MagicCookieValue = "2d8b4f1e6c7a4b0e9f3d5a1c8e7b6f40"
+
+	// CoreProtocolVersion is the version of the handshake and of the connection between the server
+	// and its plugins.
+	CoreProtocolVersion = 1
+
+	// ProtocolVersion is the version of the ObjectStore and BlockStore RPC interfaces. It's
+	// incremented when they change incompatibly, and plugins built for another version are
+	// refused.
+	ProtocolVersion = 1
+
+	// BinaryPrefix is the prefix of the names of plugin binaries in the plugin directory. The rest
+	// of the name is the plugin's name, e.g. ark-plugin-minio is the plugin named minio.
+	BinaryPrefix = "ark-plugin-"
+
+	// DefaultDir is the directory the Ark server looks for plugin binaries in if it isn't
+	// configured with another one.
+	DefaultDir = "/plugins"
+
+	// netRPCProtocol is the name of the only RPC protocol plugins speak, in handshakes.
+	netRPCProtocol = "netrpc"
+)
+
+// handshake is what a plugin writes to its stdout once it's ready to accept a connection.
+type handshake struct {
+	coreProtocolVersion int
+	protocolVersion     int
+	network             string
+	address             string
+	protocol            string
+}
+
+// String returns the handshake line, without its trailing newline, in the format
+// CORE-PROTOCOL-VERSION|PROTOCOL-VERSION|NETWORK|ADDRESS|PROTOCOL.
+func (h handshake) String() string {
+	return fmt.Sprintf("%d|%d|%s|%s|%s", h.coreProtocolVersion, h.protocolVersion, h.network, h.address, h.protocol)
+}
+
+// parseHandshake parses a plugin's handshake line, returning an error if it's malformed or the
+// plugin speaks a protocol or version that the server doesn't.
+func parseHandshake(line string) (handshake, error) {
+	parts := strings.Split(strings.TrimSpace(line), "|")
+	if len(parts) != 5 {
+		return handshake{}, fmt.Errorf("invalid handshake %q; a plugin must write CORE-PROTOCOL-VERSION|PROTOCOL-VERSION|NETWORK|ADDRESS|PROTOCOL to stdout when it starts", line)
+	}
+
+	var (
+		h   handshake
+		err error
+	)
+	if h.coreProtocolVersion, err = strconv.Atoi(parts[0]); err != nil {
+		return handshake{}, fmt.Errorf("invalid core protocol version in handshake %q", line)
+	}
+	if h.protocolVersion, err = strconv.Atoi(parts[1]); err != nil {
+		return handshake{}, fmt.Errorf("invalid protocol version in handshake %q", line)
+	}
+	h.network, h.address, h.protocol = parts[2], parts[3], parts[4]
+
+	if h.coreProtocolVersion != CoreProtocolVersion {
+		return handshake{}, fmt.Errorf("plugin speaks core protocol version %d, but the server speaks version %d", h.coreProtocolVersion, CoreProtocolVersion)
+	}
+	if h.protocolVersion != ProtocolVersion {
+		return handshake{}, fmt.Errorf("plugin was built for protocol version %d, but the server speaks version %d; use a version of the plugin built for this version of Ark", h.protocolVersion, ProtocolVersion)
+	}
+	if h.protocol != netRPCProtocol {
+		return handshake{}, fmt.Errorf("plugin speaks unsupported protocol %q", h.protocol)
+	}
+	if h.network != "unix" && h.network != "tcp" {
+		return handshake{}, fmt.Errorf("plugin serves on unsupported network %q", h.network)
+	}
+
+	return h, nil
+}
@@ -0,0 +1,308 @@
+/*
+Copyright 2017 Heptio Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plugin
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/rpc"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/heptio/ark/pkg/cloudprovider"
+)
+
+// TestMain runs the test binary as a plugin serving a memoryObjectStore if it's started by a
+// Client in the tests.
+func TestMain(m *testing.M) {
+	if os.Getenv("ARK_PLUGIN_TEST_SERVE") == "1" && os.Getenv(MagicCookieKey) != "" {
+		Serve(ServeConfig{
+			ObjectStore: func(config map[string]string) (cloudprovider.ObjectStorageAdapter, error) {
+				return newMemoryObjectStore(config)
+			},
+		})
+		os.Exit(0)
+	}
+
+	os.Exit(m.Run())
+}
+
+// memoryObjectStore is an ObjectStorageAdapter that stores objects in memory. Putting an object
+// with the key "crash" exits the process.
+type memoryObjectStore struct {
+	mu      sync.Mutex
+	bucket  string
+	objects map[string][]byte
+}
+
+func newMemoryObjectStore(config map[string]string) (*memoryObjectStore, error) {
+	if config["bucket"] == "" {
+		return nil, errors.New("bucket is required")
+	}
+	return &memoryObjectStore{bucket: config["bucket"], objects: make(map[string][]byte)}, nil
+}
+
+func (s *memoryObjectStore) checkBucket(bucket string) error {
+	if bucket != s.bucket {
+		return fmt.Errorf("no bucket %s", bucket)
+	}
+	return nil
+}
+
+func (s *memoryObjectStore) PutObject(bucket string, key string, body io.ReadSeeker) error {
+	if key == "crash" {
+		os.Exit(2)
+	}
+	if err := s.checkBucket(bucket); err != nil {
+		return err
+	}
+	data, err := ioutil.ReadAll(body)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.objects[key] = data
+	return nil
+}
+
+func (s *memoryObjectStore) GetObject(bucket string, key string) (io.ReadCloser, error) {
+	if err := s.checkBucket(bucket); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	data, ok := s.objects[key]
+	if !ok {
+		return nil, cloudprovider.NewObjectNotFoundError(bucket, key)
+	}
+	return ioutil.NopCloser(bytes.NewReader(data)), nil
+}
+
+func (s *memoryObjectStore) ListCommonPrefixes(bucket, prefix, delimiter string) ([]string, error) {
+	if err := s.checkBucket(bucket); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	found := make(map[string]bool)
+	for key := range s.objects {
+		if !strings.HasPrefix(key, prefix) {
+			continue
+		}
+		if i := strings.Index(key[len(prefix):], delimiter); i >= 0 {
+			found[key[:len(prefix)+i+len(delimiter)]] = true
+		}
+	}
+	var prefixes []string
+	for p := range found {
+		prefixes = append(prefixes, p)
+	}
+	sort.Strings(prefixes)
+	return prefixes, nil
+}
+
+func (s *memoryObjectStore) DeleteObject(bucket string, key string) error {
+	if err := s.checkBucket(bucket); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.objects, key)
+	return nil
+}
+
+func (s *memoryObjectStore) CreateSignedURL(bucket, key string, ttl time.Duration) (string, error) {
+	return fmt.Sprintf("memory://%s/%s?ttl=%v", bucket, key, ttl), nil
+}
+
+// pipeConnector connects to a plugin server that's running in the test over a pipe.
+type pipeConnector struct {
+	conn *conn
+}
+
+func newPipeConnector(t *testing.T, config ServeConfig) *pipeConnector {
+	server := rpc.NewServer()
+	handles := newHandles()
+	require.NoError(t, server.RegisterName("Plugin", &pluginServer{handles: handles}))
+	require.NoError(t, server.RegisterName("ObjectStore", &objectStoreServer{newAdapter: config.ObjectStore, handles: handles}))
+	require.NoError(t, server.RegisterName("BlockStore", &blockStoreServer{newAdapter: config.BlockStore, handles: handles}))
+
+	serverConn, clientConn := net.Pipe()
+	go server.ServeConn(serverConn)
+
+	return &pipeConnector{conn: &conn{name: "test", client: rpc.NewClient(clientConn), generation: 1}}
+}
+
+func (c *pipeConnector) connect() (*conn, error) {
+	return c.conn, nil
+}
+
+func TestParseHandshake(t *testing.T) {
+	tests := []struct {
+		name          string
+		line          string
+		expected      handshake
+		expectedError string
+	}{
+		{
+			name:     "valid handshake",
+			line:     "1|1|unix|/tmp/ark-plugin123/plugin.sock|netrpc\n",
+			expected: handshake{1, 1, "unix", "/tmp/ark-plugin123/plugin.sock", "netrpc"},
+		},
+		{
+			name:          "too few fields",
+			line:          "1|1|unix",
+			expectedError: `invalid handshake "1|1|unix"`,
+		},
+		{
+			name:          "other core protocol version",
+			line:          "2|1|unix|/tmp/plugin.sock|netrpc",
+			expectedError: "plugin speaks core protocol version 2",
+		},
+		{
+			name:          "other protocol version",
+			line:          "1|7|unix|/tmp/plugin.sock|netrpc",
+			expectedError: "plugin was built for protocol version 7",
+		},
+		{
+			name:          "unsupported protocol",
+			line:          "1|1|unix|/tmp/plugin.sock|grpc",
+			expectedError: `plugin speaks unsupported protocol "grpc"`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			h, err := parseHandshake(test.line)
+			if test.expectedError != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), test.expectedError)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, h)
+		})
+	}
+}
+
+func TestObjectStore(t *testing.T) {
+	connector := newPipeConnector(t, ServeConfig{
+		ObjectStore: func(config map[string]string) (cloudprovider.ObjectStorageAdapter, error) {
+			return newMemoryObjectStore(config)
+		},
+	})
+	store := newObjectStore(connector, map[string]string{"bucket": "bucket"})
+
+	// larger than a chunk, so that it's sent and read in several
+	data := bytes.Repeat([]byte("0123456789"), chunkSize/5)
+	require.NoError(t, store.PutObject("bucket", "backup-1/ark-backup.json", bytes.NewReader(data)))
+	require.NoError(t, store.PutObject("bucket", "backup-2/ark-backup.json", strings.NewReader("{}")))
+
+	body, err := store.GetObject("bucket", "backup-1/ark-backup.json")
+	require.NoError(t, err)
+	read, err := ioutil.ReadAll(body)
+	require.NoError(t, err)
+	require.NoError(t, body.Close())
+	assert.Equal(t, data, read)
+
+	prefixes, err := store.ListCommonPrefixes("bucket", "", "/")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"backup-1/", "backup-2/"}, prefixes)
+
+	url, err := store.CreateSignedURL("bucket", "backup-2/ark-backup.json", time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, "memory://bucket/backup-2/ark-backup.json?ttl=1m0s", url)
+
+	require.NoError(t, store.DeleteObject("bucket", "backup-2/ark-backup.json"))
+	_, err = store.GetObject("bucket", "backup-2/ark-backup.json")
+	require.Error(t, err)
+	assert.True(t, cloudprovider.IsObjectNotFound(err))
+
+	err = store.PutObject("other-bucket", "key", strings.NewReader("data"))
+	require.Error(t, err)
+	assert.Equal(t, "no bucket other-bucket", err.Error())
+}
+
+func TestInitErrors(t *testing.T) {
+	connector := newPipeConnector(t, ServeConfig{
+		ObjectStore: func(config map[string]string) (cloudprovider.ObjectStorageAdapter, error) {
+			return newMemoryObjectStore(config)
+		},
+	})
+
+	_, err := newObjectStore(connector, nil).ListCommonPrefixes("bucket", "", "/")
+	require.Error(t, err)
+	assert.Equal(t, "bucket is required", err.Error())
+
+	_, err = newBlockStore(connector, nil).IsVolumeReady("volume-1")
+	require.Error(t, err)
+	assert.Equal(t, "the plugin doesn't serve a BlockStore", err.Error())
+}
+
+func TestClientRestartsCrashedPlugin(t *testing.T) {
+	os.Setenv("ARK_PLUGIN_TEST_SERVE", "1")
+	defer os.Unsetenv("ARK_PLUGIN_TEST_SERVE")
+
+	client := NewClient("test", os.Args[0])
+	defer client.Stop()
+
+	store, err := client.ObjectStore(map[string]string{"bucket": "bucket"})
+	require.NoError(t, err)
+	require.NoError(t, store.PutObject("bucket", "key", strings.NewReader("data")))
+	require.NoError(t, client.CheckHealth(10*time.Second))
+
+	err = store.PutObject("bucket", "crash", strings.NewReader("data"))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "error calling plugin test, which will be restarted")
+
+	// the plugin is restarted and the adapter is created in it again, without the objects that
+	// were stored in the crashed process
+	require.NoError(t, store.PutObject("bucket", "other-key", strings.NewReader("other data")))
+	prefixes, err := store.ListCommonPrefixes("bucket", "", "-")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"other-"}, prefixes)
+	assert.Equal(t, 2, client.generation)
+
+	client.Stop()
+	_, err = store.ListCommonPrefixes("bucket", "", "-")
+	require.Error(t, err)
+	assert.Equal(t, "plugin test has been stopped", err.Error())
+}
+
+func TestServeRequiresMagicCookie(t *testing.T) {
+	os.Unsetenv(MagicCookieKey)
+
+	err := serve(ServeConfig{}, ioutil.Discard)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "this binary is an Ark plugin")
+}
@@ -0,0 +1,201 @@
+/*
+Copyright 2017 Heptio Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plugin
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/rpc"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/heptio/ark/pkg/cloudprovider"
+)
+
+// ServeConfig holds the adapters that a plugin serves. A plugin may serve an ObjectStore, a
+// BlockStore, or both.
+type ServeConfig struct {
+	// ObjectStore creates the plugin's ObjectStorageAdapter for a backup storage location,
+	// from the config of the location's plugin.
+	ObjectStore func(config map[string]string) (cloudprovider.ObjectStorageAdapter, error)
+
+	// BlockStore creates the plugin's BlockStorageAdapter for a volume snapshot location, from
+	// the config of the location's plugin.
+	BlockStore func(config map[string]string) (cloudprovider.BlockStorageAdapter, error)
+}
+
+// Serve serves config's adapters to the Ark server that started the plugin, and returns once
+// the server disconnects. It exits the process if the plugin can't be served, e.g. because it
+// wasn't started by an Ark server.
+func Serve(config ServeConfig) {
+	if err := serve(config, os.Stdout); err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func serve(config ServeConfig, out io.Writer) error {
+	if os.Getenv(MagicCookieKey) != MagicCookieValue {
+		return errors.New("this binary is an Ark plugin, which is run by the Ark server rather than directly; put it in the server's plugin directory")
+	}
+	if config.ObjectStore == nil && config.BlockStore == nil {
+		return errors.New("the plugin serves neither an ObjectStore nor a BlockStore")
+	}
+
+	dir, err := ioutil.TempDir("", "ark-plugin")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	listener, err := net.Listen("unix", filepath.Join(dir, "plugin.sock"))
+	if err != nil {
+		return err
+	}
+
+	server := rpc.NewServer()
+	handles := newHandles()
+	if err := server.RegisterName("Plugin", &pluginServer{handles: handles}); err != nil {
+		return err
+	}
+	if err := server.RegisterName("ObjectStore", &objectStoreServer{newAdapter: config.ObjectStore, handles: handles}); err != nil {
+		return err
+	}
+	if err := server.RegisterName("BlockStore", &blockStoreServer{newAdapter: config.BlockStore, handles: handles}); err != nil {
+		return err
+	}
+
+	h := handshake{
+		coreProtocolVersion: CoreProtocolVersion,
+		protocolVersion:     ProtocolVersion,
+		network:             "unix",
+		address:             listener.Addr().String(),
+		protocol:            netRPCProtocol,
+	}
+	if _, err := fmt.Fprintln(out, h.String()); err != nil {
+		return err
+	}
+
+	// the plugin only ever has one client, the server that started it, so stop listening once
+	// it's connected and exit once it disconnects
+	conn, err := listener.Accept()
+	listener.Close()
+	if err != nil {
+		return err
+	}
+	server.ServeConn(conn)
+	handles.closeAll()
+
+	return nil
+}
+
+// Empty is the argument or reply of RPCs that don't need one. It has a field only because gob
+// can't encode structs without exported fields.
+type Empty struct {
+	Unused bool
+}
+
+// CloseArgs are the arguments of the Plugin.Close RPC.
+type CloseArgs struct {
+	Handle int
+}
+
+// pluginServer serves the RPCs that every plugin has.
+type pluginServer struct {
+	handles *handles
+}
+
+// Ping is used by the server to check the plugin's health. It replies with the plugin's
+// protocol version.
+func (s *pluginServer) Ping(_ Empty, reply *int) error {
+	*reply = ProtocolVersion
+	return nil
+}
+
+// Close closes an object that the server was reading or writing, and forgets its handle.
+func (s *pluginServer) Close(args CloseArgs, _ *Empty) error {
+	value, err := s.handles.remove(args.Handle)
+	if err != nil {
+		return err
+	}
+	if closer, ok := value.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// handles holds the adapter instances that the server has created in the plugin, and the
+// objects it's reading or writing, by ID.
+type handles struct {
+	mu     sync.Mutex
+	nextID int
+	values map[int]interface{}
+}
+
+func newHandles() *handles {
+	return &handles{values: make(map[int]interface{})}
+}
+
+func (h *handles) add(value interface{}) int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.nextID++
+	h.values[h.nextID] = value
+	return h.nextID
+}
+
+func (h *handles) get(id int) (interface{}, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	value, ok := h.values[id]
+	if !ok {
+		return nil, fmt.Errorf("no handle with ID %d", id)
+	}
+	return value, nil
+}
+
+func (h *handles) remove(id int) (interface{}, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	value, ok := h.values[id]
+	if !ok {
+		return nil, fmt.Errorf("no handle with ID %d", id)
+	}
+	delete(h.values, id)
+	return value, nil
+}
+
+// closeAll closes every handle that's an io.Closer, e.g. objects that the server was reading when
+// it disconnected.
+func (h *handles) closeAll() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for id, value := range h.values {
+		if closer, ok := value.(io.Closer); ok {
+			closer.Close()
+		}
+		delete(h.values, id)
+	}
+}
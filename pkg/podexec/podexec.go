@@ -0,0 +1,374 @@
+/*
+Copyright 2017 Heptio Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package podexec runs commands in the containers of running pods.
+//
+// Commands are run using the pods/exec subresource over a websocket, using the v4.channel.k8s.io
+// subprotocol that kubectl's websocket clients use. Only the output streams are used; commands
+// don't get any input.
+package podexec
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/golang/glog"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/rest"
+)
+
+const (
+	// channelProtocol is the websocket subprotocol for exec streams. Each message's first byte is
+	// the stream it belongs to, and the error stream carries a JSON metav1.Status when the command
+	// exits.
+	channelProtocol = "v4.channel.k8s.io"
+
+	stdoutChannel = 1
+	stderrChannel = 2
+	errorChannel  = 3
+
+	// websocketGUID is appended to the client's key to compute the server's accept header, per
+	// RFC 6455.
+	websocketGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+	opContinuation = 0x0
+	opText         = 0x1
+	opBinary       = 0x2
+	opClose        = 0x8
+	opPing         = 0x9
+	opPong         = 0xa
+
+	// maxFrameSize is the largest websocket frame that's accepted from the server.
+	maxFrameSize = 1 << 20
+
+	// maxStderr is how much of a command's stderr is kept for its error message.
+	maxStderr = 4096
+)
+
+// Executor runs commands in the containers of pods.
+type Executor interface {
+	// Exec runs command in the named container of a pod. It returns an error if the command
+	// can't be started, exits with a non-zero status, or doesn't finish within timeout.
+	Exec(namespace, pod, container string, command []string, timeout time.Duration) error
+}
+
+type websocketExecutor struct {
+	baseURL   *url.URL
+	transport http.RoundTripper
+}
+
+var _ Executor = &websocketExecutor{}
+
+// NewExecutor creates an Executor that connects to the API server described by config.
+func NewExecutor(config *rest.Config) (Executor, error) {
+	hasCA := len(config.CAFile) != 0 || len(config.CAData) != 0
+	hasCert := len(config.CertFile) != 0 || len(config.CertData) != 0
+	baseURL, _, err := rest.DefaultServerURL(config.Host, "", schema.GroupVersion{}, hasCA || hasCert || config.Insecure)
+	if err != nil {
+		return nil, err
+	}
+
+	tlsConfig, err := rest.TLSConfigFor(config)
+	if err != nil {
+		return nil, err
+	}
+	if tlsConfig != nil {
+		// websocket upgrades aren't possible over HTTP/2
+		tlsConfig.NextProtos = []string{"http/1.1"}
+	}
+
+	transport, err := rest.HTTPWrappersForConfig(config, &http.Transport{
+		Proxy:           http.ProxyFromEnvironment,
+		TLSClientConfig: tlsConfig,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &websocketExecutor{
+		baseURL:   baseURL,
+		transport: transport,
+	}, nil
+}
+
+func (e *websocketExecutor) Exec(namespace, pod, container string, command []string, timeout time.Duration) error {
+	if len(command) == 0 {
+		return errors.New("command is required")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	conn, err := e.connect(ctx, namespace, pod, container, command)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	// reads from an upgraded connection aren't interrupted by the request's context, so close
+	// the connection to stop waiting for a command that's taking too long.
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.Close()
+		case <-done:
+		}
+	}()
+
+	var (
+		stderr []byte
+		status *metav1.Status
+	)
+	err = readMessages(conn, func(message []byte) error {
+		if len(message) < 2 {
+			// the server opens each stream with an empty message
+			return nil
+		}
+
+		switch message[0] {
+		case stdoutChannel:
+			glog.V(4).Infof("%s/%s[%s] stdout: %s", namespace, pod, container, message[1:])
+		case stderrChannel:
+			glog.V(4).Infof("%s/%s[%s] stderr: %s", namespace, pod, container, message[1:])
+			if len(stderr) < maxStderr {
+				stderr = append(stderr, message[1:]...)
+			}
+		case errorChannel:
+			status = new(metav1.Status)
+			if err := json.Unmarshal(message[1:], status); err != nil {
+				return fmt.Errorf("error decoding exec status: %v", err)
+			}
+		}
+		return nil
+	})
+
+	if ctx.Err() == context.DeadlineExceeded {
+		return fmt.Errorf("command timed out after %v", timeout)
+	}
+	if err != nil {
+		return err
+	}
+
+	if status == nil {
+		return errors.New("connection closed without an exit status")
+	}
+	if status.Status != metav1.StatusSuccess {
+		msg := status.Message
+		if len(stderr) > maxStderr {
+			stderr = stderr[:maxStderr]
+		}
+		if s := strings.TrimSpace(string(stderr)); s != "" {
+			msg = fmt.Sprintf("%s: %s", msg, s)
+		}
+		return errors.New(msg)
+	}
+
+	return nil
+}
+
+// connect starts command in the container and returns the upgraded connection its output is
+// streamed over.
+func (e *websocketExecutor) connect(ctx context.Context, namespace, pod, container string, command []string) (io.ReadWriteCloser, error) {
+	u := *e.baseURL
+	u.Path = path.Join(u.Path, "/api/v1/namespaces", namespace, "pods", pod, "exec")
+
+	query := url.Values{}
+	if container != "" {
+		query.Set("container", container)
+	}
+	for _, arg := range command {
+		query.Add("command", arg)
+	}
+	query.Set("stdout", "true")
+	query.Set("stderr", "true")
+	u.RawQuery = query.Encode()
+
+	keyBytes := make([]byte, 16)
+	if _, err := rand.Read(keyBytes); err != nil {
+		return nil, err
+	}
+	key := base64.StdEncoding.EncodeToString(keyBytes)
+
+	req, err := http.NewRequest("GET", u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Sec-WebSocket-Version", "13")
+	req.Header.Set("Sec-WebSocket-Key", key)
+	req.Header.Set("Sec-WebSocket-Protocol", channelProtocol)
+
+	res, err := e.transport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if res.StatusCode != http.StatusSwitchingProtocols {
+		defer res.Body.Close()
+		body, _ := ioutil.ReadAll(io.LimitReader(res.Body, maxStderr))
+		return nil, fmt.Errorf("error starting command: %s: %s", res.Status, strings.TrimSpace(string(body)))
+	}
+
+	conn, ok := res.Body.(io.ReadWriteCloser)
+	if !ok {
+		res.Body.Close()
+		return nil, errors.New("error starting command: connection wasn't upgraded")
+	}
+
+	if res.Header.Get("Sec-WebSocket-Accept") != acceptKey(key) {
+		conn.Close()
+		return nil, errors.New("error starting command: invalid websocket handshake")
+	}
+	if res.Header.Get("Sec-WebSocket-Protocol") != channelProtocol {
+		conn.Close()
+		return nil, fmt.Errorf("error starting command: API server doesn't support the %s protocol", channelProtocol)
+	}
+
+	return conn, nil
+}
+
+// acceptKey returns the Sec-WebSocket-Accept header a server sends in response to key.
+func acceptKey(key string) string {
+	sum := sha1.Sum([]byte(key + websocketGUID))
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// readMessages reads websocket messages from conn, passing each one to handle, until the server
+// closes the connection.
+func readMessages(conn io.ReadWriter, handle func(message []byte) error) error {
+	r := bufio.NewReader(conn)
+
+	var message []byte
+	for {
+		var header [2]byte
+		if _, err := io.ReadFull(r, header[:]); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+
+		fin := header[0]&0x80 != 0
+		opcode := header[0] & 0x0f
+		masked := header[1]&0x80 != 0
+
+		length := uint64(header[1] & 0x7f)
+		switch length {
+		case 126:
+			var ext [2]byte
+			if _, err := io.ReadFull(r, ext[:]); err != nil {
+				return err
+			}
+			length = uint64(binary.BigEndian.Uint16(ext[:]))
+		case 127:
+			var ext [8]byte
+			if _, err := io.ReadFull(r, ext[:]); err != nil {
+				return err
+			}
+			length = binary.BigEndian.Uint64(ext[:])
+		}
+		if length > maxFrameSize {
+			return fmt.Errorf("websocket frame of %d bytes is too large", length)
+		}
+
+		var mask [4]byte
+		if masked {
+			if _, err := io.ReadFull(r, mask[:]); err != nil {
+				return err
+			}
+		}
+
+		payload := make([]byte, length)
+		if _, err := io.ReadFull(r, payload); err != nil {
+			return err
+		}
+		if masked {
+			for i := range payload {
+				payload[i] ^= mask[i%4]
+			}
+		}
+
+		switch opcode {
+		case opClose:
+			return nil
+		case opPing:
+			if err := writeFrame(conn, opPong, payload); err != nil {
+				return err
+			}
+		case opPong:
+		case opContinuation, opText, opBinary:
+			message = append(message, payload...)
+			if !fin {
+				continue
+			}
+			if err := handle(message); err != nil {
+				return err
+			}
+			message = nil
+		default:
+			return fmt.Errorf("unexpected websocket opcode %d", opcode)
+		}
+	}
+}
+
+// writeFrame writes a single, final websocket frame to w. Frames sent by clients must be
+// masked.
+func writeFrame(w io.Writer, opcode byte, payload []byte) error {
+	frame := []byte{0x80 | opcode}
+
+	switch {
+	case len(payload) < 126:
+		frame = append(frame, 0x80|byte(len(payload)))
+	case len(payload) <= 0xffff:
+		frame = append(frame, 0x80|126, 0, 0)
+		binary.BigEndian.PutUint16(frame[2:], uint16(len(payload)))
+	default:
+		frame = append(frame, 0x80|127, 0, 0, 0, 0, 0, 0, 0, 0)
+		binary.BigEndian.PutUint64(frame[2:], uint64(len(payload)))
+	}
+
+	var mask [4]byte
+	if _, err := rand.Read(mask[:]); err != nil {
+		return err
+	}
+	frame = append(frame, mask[:]...)
+	for i, b := range payload {
+		frame = append(frame, b^mask[i%4])
+	}
+
+	_, err := w.Write(frame)
+	return err
+}
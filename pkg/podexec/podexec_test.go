@@ -0,0 +1,209 @@
+/*
+Copyright 2017 Heptio Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package podexec
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"k8s.io/client-go/rest"
+)
+
+// serverFrame returns an unmasked websocket frame, as sent by a server.
+func serverFrame(opcode byte, fin bool, payload []byte) []byte {
+	first := opcode
+	if fin {
+		first |= 0x80
+	}
+	frame := []byte{first}
+
+	switch {
+	case len(payload) < 126:
+		frame = append(frame, byte(len(payload)))
+	case len(payload) <= 0xffff:
+		frame = append(frame, 126, 0, 0)
+		binary.BigEndian.PutUint16(frame[2:], uint16(len(payload)))
+	default:
+		frame = append(frame, 127, 0, 0, 0, 0, 0, 0, 0, 0)
+		binary.BigEndian.PutUint64(frame[2:], uint64(len(payload)))
+	}
+
+	return append(frame, payload...)
+}
+
+func channelMessage(channel byte, data string) []byte {
+	return serverFrame(opBinary, true, append([]byte{channel}, data...))
+}
+
+// newExecServer returns a server that upgrades exec requests and writes frames to the client.
+// If hang is true, the connection is held open until the client closes it.
+func newExecServer(t *testing.T, frames [][]byte, hang bool, requests chan<- *http.Request) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requests != nil {
+			requests <- r
+		}
+
+		conn, rw, err := w.(http.Hijacker).Hijack()
+		require.NoError(t, err)
+		defer conn.Close()
+
+		rw.WriteString("HTTP/1.1 101 Switching Protocols\r\n")
+		rw.WriteString("Upgrade: websocket\r\nConnection: Upgrade\r\n")
+		rw.WriteString("Sec-WebSocket-Accept: " + acceptKey(r.Header.Get("Sec-WebSocket-Key")) + "\r\n")
+		rw.WriteString("Sec-WebSocket-Protocol: " + r.Header.Get("Sec-WebSocket-Protocol") + "\r\n\r\n")
+		for _, frame := range frames {
+			rw.Write(frame)
+		}
+		rw.Flush()
+
+		if hang {
+			rw.ReadByte()
+			return
+		}
+		rw.Write(serverFrame(opClose, true, nil))
+		rw.Flush()
+	}))
+}
+
+func TestExec(t *testing.T) {
+	tests := []struct {
+		name        string
+		frames      [][]byte
+		hang        bool
+		expectedErr string
+	}{
+		{
+			name: "successful command",
+			frames: [][]byte{
+				channelMessage(stdoutChannel, ""),
+				channelMessage(stdoutChannel, "recovered\n"),
+				channelMessage(errorChannel, `{"metadata":{},"status":"Success"}`),
+			},
+		},
+		{
+			name: "failed command includes stderr",
+			frames: [][]byte{
+				channelMessage(stderrChannel, "no such table\n"),
+				channelMessage(errorChannel, `{"metadata":{},"status":"Failure","message":"command terminated with non-zero exit code: exit status 1"}`),
+			},
+			expectedErr: "command terminated with non-zero exit code: exit status 1: no such table",
+		},
+		{
+			name: "fragmented messages are reassembled",
+			frames: [][]byte{
+				serverFrame(opBinary, false, []byte{errorChannel, '{', '"'}),
+				serverFrame(opContinuation, true, []byte(`status":"Failure","message":"failed"}`)),
+			},
+			expectedErr: "failed",
+		},
+		{
+			name:        "connection closed without a status",
+			frames:      [][]byte{channelMessage(stdoutChannel, "output")},
+			expectedErr: "connection closed without an exit status",
+		},
+		{
+			name:        "command that doesn't finish times out",
+			hang:        true,
+			expectedErr: "command timed out after 100ms",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			requests := make(chan *http.Request, 1)
+			server := newExecServer(t, test.frames, test.hang, requests)
+			defer server.Close()
+
+			executor, err := NewExecutor(&rest.Config{Host: server.URL})
+			require.NoError(t, err)
+
+			err = executor.Exec("ns-1", "pod-1", "container-1", []string{"/bin/recover", "--all"}, 100*time.Millisecond)
+			if test.expectedErr != "" {
+				assert.EqualError(t, err, test.expectedErr)
+			} else {
+				assert.NoError(t, err)
+			}
+
+			req := <-requests
+			assert.Equal(t, "/api/v1/namespaces/ns-1/pods/pod-1/exec", req.URL.Path)
+			assert.Equal(t, "container-1", req.URL.Query().Get("container"))
+			assert.Equal(t, []string{"/bin/recover", "--all"}, req.URL.Query()["command"])
+			assert.Equal(t, channelProtocol, req.Header.Get("Sec-WebSocket-Protocol"))
+		})
+	}
+}
+
+func TestExecRejected(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `pods "pod-1" is forbidden`, http.StatusForbidden)
+	}))
+	defer server.Close()
+
+	executor, err := NewExecutor(&rest.Config{Host: server.URL})
+	require.NoError(t, err)
+
+	err = executor.Exec("ns-1", "pod-1", "", []string{"true"}, time.Second)
+	assert.EqualError(t, err, `error starting command: 403 Forbidden: pods "pod-1" is forbidden`)
+}
+
+func TestWriteFrameReadMessages(t *testing.T) {
+	for _, size := range []int{0, 125, 126, 0xffff, 0x10000} {
+		payload := bytes.Repeat([]byte{'a'}, size)
+
+		buf := new(bytes.Buffer)
+		require.NoError(t, writeFrame(buf, opBinary, payload))
+
+		var messages [][]byte
+		require.NoError(t, readMessages(&readWriter{Reader: buf}, func(message []byte) error {
+			messages = append(messages, message)
+			return nil
+		}))
+
+		require.Len(t, messages, 1, "size %d", size)
+		assert.True(t, bytes.Equal(payload, messages[0]), "size %d", size)
+	}
+}
+
+func TestReadMessagesAnswersPings(t *testing.T) {
+	in := bytes.NewBuffer(serverFrame(opPing, true, []byte("hi")))
+	out := new(bytes.Buffer)
+
+	require.NoError(t, readMessages(&readWriter{Reader: in, Writer: out}, func([]byte) error {
+		t.Error("control frames shouldn't be handled as messages")
+		return nil
+	}))
+
+	pong := out.Bytes()
+	require.Len(t, pong, 8)
+	assert.Equal(t, byte(0x80|opPong), pong[0])
+	assert.Equal(t, byte(0x80|2), pong[1])
+	mask := pong[2:6]
+	assert.Equal(t, "hi", string([]byte{pong[6] ^ mask[0], pong[7] ^ mask[1]}))
+}
+
+type readWriter struct {
+	io.Reader
+	io.Writer
+}
@@ -0,0 +1,265 @@
+/*
+Copyright 2017 Heptio Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package quiesce
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/golang/glog"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/wait"
+	corev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/pkg/api/v1"
+)
+
+const (
+	// FreezeAnnotation is the annotation on a pod that, when "true", opts the pod in to having
+	// the filesystems of its PersistentVolumeClaims' volumes frozen while they're snapshotted.
+	FreezeAnnotation = "backup.ark.heptio.com/freeze-volumes"
+
+	// DefaultImage is the container image used to run fsfreeze if none is configured.
+	DefaultImage = "debian:stretch-slim"
+
+	// kubeletPodsDir is the directory on each node where the kubelet mounts pods' volumes, at
+	// <pod UID>/volumes/<volume plugin>/<volume directory>.
+	kubeletPodsDir = "/var/lib/kubelet/pods"
+
+	// hostPodsDir is where kubeletPodsDir is mounted in freeze helper pods.
+	hostPodsDir = "/host_pods"
+
+	// frozenFile is created in freeze helper pods once the filesystem is frozen, and is used as
+	// their readiness probe.
+	frozenFile = "/tmp/frozen"
+
+	// startTimeout is how long to wait for a freeze helper pod to freeze its filesystem.
+	startTimeout = 2 * time.Minute
+
+	pollInterval = time.Second
+)
+
+// Freezer freezes the filesystems of PersistentVolumes while they're snapshotted.
+type Freezer interface {
+	// Freeze freezes the filesystem of the named PersistentVolume, which is bound to the given
+	// claim, on each node where it's mounted by a running pod that has opted in with
+	// FreezeAnnotation. It returns a function that thaws the filesystem, which must be called
+	// once the volume has been snapshotted. If no pod has opted in, Freeze does nothing.
+	Freeze(claimNamespace, claimName, volumeName string) (thaw func() error, err error)
+}
+
+// podFreezer implements Freezer by running fsfreeze in privileged helper pods on the nodes where
+// the volume is mounted. Each helper pod thaws its filesystem by itself after maxFreeze, so that
+// a volume is never left frozen if the backup is interrupted.
+type podFreezer struct {
+	podClient corev1.PodsGetter
+	namespace string
+	image     string
+	maxFreeze time.Duration
+}
+
+var _ Freezer = &podFreezer{}
+
+// NewPodFreezer creates a Freezer that runs image, which must contain fsfreeze, in helper pods
+// in namespace. Filesystems are frozen for at most maxFreeze.
+func NewPodFreezer(podClient corev1.PodsGetter, namespace, image string, maxFreeze time.Duration) Freezer {
+	return &podFreezer{
+		podClient: podClient,
+		namespace: namespace,
+		image:     image,
+		maxFreeze: maxFreeze,
+	}
+}
+
+func (f *podFreezer) Freeze(claimNamespace, claimName, volumeName string) (func() error, error) {
+	pods, err := f.podsToFreeze(claimNamespace, claimName)
+	if err != nil {
+		return nil, err
+	}
+
+	var helpers []*v1.Pod
+	thaw := func() error {
+		var errs []error
+		for _, helper := range helpers {
+			if err := f.thaw(helper); err != nil {
+				errs = append(errs, err)
+			}
+		}
+		return kerrors.NewAggregate(errs)
+	}
+
+	for _, pod := range pods {
+		glog.V(2).Infof("Freezing filesystem of PersistentVolume %s on node %s using pod %s/%s", volumeName, pod.Spec.NodeName, pod.Namespace, pod.Name)
+
+		helper, err := f.freeze(pod, volumeName)
+		if helper != nil {
+			helpers = append(helpers, helper)
+		}
+		if err != nil {
+			if thawErr := thaw(); thawErr != nil {
+				glog.Errorf("error thawing PersistentVolume %s: %v", volumeName, thawErr)
+			}
+			return nil, fmt.Errorf("error freezing filesystem of PersistentVolume %s on node %s: %v", volumeName, pod.Spec.NodeName, err)
+		}
+	}
+
+	return thaw, nil
+}
+
+// podsToFreeze returns a running pod that uses the claim and has opted in to freezing for each
+// node where the claim is mounted. A filesystem only needs to be frozen once per node.
+func (f *podFreezer) podsToFreeze(claimNamespace, claimName string) ([]*v1.Pod, error) {
+	list, err := f.podClient.Pods(claimNamespace).List(metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("error listing pods in namespace %s: %v", claimNamespace, err)
+	}
+
+	var pods []*v1.Pod
+	nodes := make(map[string]bool)
+	for i := range list.Items {
+		pod := &list.Items[i]
+
+		if pod.Status.Phase != v1.PodRunning || pod.Annotations[FreezeAnnotation] != "true" || nodes[pod.Spec.NodeName] {
+			continue
+		}
+
+		for _, volume := range pod.Spec.Volumes {
+			if volume.PersistentVolumeClaim != nil && volume.PersistentVolumeClaim.ClaimName == claimName {
+				pods = append(pods, pod)
+				nodes[pod.Spec.NodeName] = true
+				break
+			}
+		}
+	}
+
+	return pods, nil
+}
+
+// freeze creates a helper pod that freezes the filesystem of the volume mounted by pod, and waits
+// for it to report that the filesystem is frozen. The helper pod is returned, if it was created,
+// even if an error occurs so that it can be cleaned up.
+func (f *podFreezer) freeze(pod *v1.Pod, volumeName string) (*v1.Pod, error) {
+	helper, err := f.podClient.Pods(f.namespace).Create(f.helperPod(pod, volumeName))
+	if err != nil {
+		return nil, fmt.Errorf("error creating freeze pod: %v", err)
+	}
+
+	err = wait.PollImmediate(pollInterval, startTimeout, func() (bool, error) {
+		current, err := f.podClient.Pods(f.namespace).Get(helper.Name, metav1.GetOptions{})
+		if err != nil {
+			return false, err
+		}
+
+		if current.Status.Phase == v1.PodFailed || current.Status.Phase == v1.PodSucceeded {
+			return false, fmt.Errorf("freeze pod %s/%s exited: %s", f.namespace, helper.Name, terminationMessage(current))
+		}
+
+		for _, condition := range current.Status.Conditions {
+			if condition.Type == v1.PodReady && condition.Status == v1.ConditionTrue {
+				return true, nil
+			}
+		}
+		return false, nil
+	})
+
+	return helper, err
+}
+
+// thaw deletes the helper pod, which thaws its filesystem as it terminates. It returns an error if
+// the helper pod already thawed the filesystem because the freeze window elapsed.
+func (f *podFreezer) thaw(helper *v1.Pod) error {
+	var windowErr error
+	if current, err := f.podClient.Pods(f.namespace).Get(helper.Name, metav1.GetOptions{}); err == nil && current.Status.Phase == v1.PodSucceeded {
+		windowErr = fmt.Errorf("filesystem was thawed before the snapshot completed because the maximum freeze duration of %v elapsed", f.maxFreeze)
+	}
+
+	if err := f.podClient.Pods(f.namespace).Delete(helper.Name, &metav1.DeleteOptions{}); err != nil {
+		return fmt.Errorf("error deleting freeze pod %s/%s: %v", f.namespace, helper.Name, err)
+	}
+
+	return windowErr
+}
+
+// helperPod returns the spec of a privileged pod, on the same node as pod, that freezes the
+// filesystem of the named volume of pod until it's terminated or maxFreeze elapses.
+func (f *podFreezer) helperPod(pod *v1.Pod, volumeName string) *v1.Pod {
+	script := strings.Join([]string{
+		fmt.Sprintf("VOLUME_DIR=$(ls -d %s/%s/volumes/*/%s | head -n 1)", hostPodsDir, pod.UID, volumeName),
+		`[ -n "$VOLUME_DIR" ] || { echo "volume not found"; exit 1; }`,
+		`fsfreeze --freeze "$VOLUME_DIR" || exit 1`,
+		`trap 'fsfreeze --unfreeze "$VOLUME_DIR"; exit 0' TERM`,
+		"touch " + frozenFile,
+		fmt.Sprintf("sleep %d & wait $!", int(f.maxFreeze.Seconds())),
+		`fsfreeze --unfreeze "$VOLUME_DIR"`,
+	}, "\n")
+
+	privileged := true
+	gracePeriod := int64(30)
+
+	return &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:    f.namespace,
+			GenerateName: "freeze-" + volumeName + "-",
+			Labels: map[string]string{
+				"component": "ark",
+			},
+		},
+		Spec: v1.PodSpec{
+			NodeName:                      pod.Spec.NodeName,
+			RestartPolicy:                 v1.RestartPolicyNever,
+			TerminationGracePeriodSeconds: &gracePeriod,
+			Containers: []v1.Container{
+				{
+					Name:                     "fsfreeze",
+					Image:                    f.image,
+					Command:                  []string{"/bin/sh", "-c", script},
+					TerminationMessagePolicy: v1.TerminationMessageFallbackToLogsOnError,
+					SecurityContext:          &v1.SecurityContext{Privileged: &privileged},
+					ReadinessProbe: &v1.Probe{
+						Handler: v1.Handler{
+							Exec: &v1.ExecAction{Command: []string{"test", "-f", frozenFile}},
+						},
+						PeriodSeconds: 1,
+					},
+					VolumeMounts: []v1.VolumeMount{
+						{Name: "host-pods", MountPath: hostPodsDir},
+					},
+				},
+			},
+			Volumes: []v1.Volume{
+				{
+					Name: "host-pods",
+					VolumeSource: v1.VolumeSource{
+						HostPath: &v1.HostPathVolumeSource{Path: kubeletPodsDir},
+					},
+				},
+			},
+		},
+	}
+}
+
+// terminationMessage returns the termination message of the pod's terminated container, if any.
+func terminationMessage(pod *v1.Pod) string {
+	for _, status := range pod.Status.ContainerStatuses {
+		if status.State.Terminated != nil {
+			return strings.TrimSpace(status.State.Terminated.Message)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,119 @@
+/*
+Copyright 2017 Heptio Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package quiesce
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	corev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/pkg/api/v1"
+)
+
+type fakePodsGetter struct {
+	pods []v1.Pod
+}
+
+func (g *fakePodsGetter) Pods(namespace string) corev1.PodInterface {
+	return &fakePodClient{getter: g}
+}
+
+type fakePodClient struct {
+	corev1.PodInterface
+	getter *fakePodsGetter
+}
+
+func (c *fakePodClient) List(opts metav1.ListOptions) (*v1.PodList, error) {
+	return &v1.PodList{Items: c.getter.pods}, nil
+}
+
+func newPod(name, node, claim string, phase v1.PodPhase, optIn bool) v1.Pod {
+	pod := v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns-1", Name: name},
+		Spec: v1.PodSpec{
+			NodeName: node,
+			Volumes: []v1.Volume{
+				{
+					Name: "data",
+					VolumeSource: v1.VolumeSource{
+						PersistentVolumeClaim: &v1.PersistentVolumeClaimVolumeSource{ClaimName: claim},
+					},
+				},
+			},
+		},
+		Status: v1.PodStatus{Phase: phase},
+	}
+	if optIn {
+		pod.Annotations = map[string]string{FreezeAnnotation: "true"}
+	}
+	return pod
+}
+
+func TestPodsToFreeze(t *testing.T) {
+	podClient := &fakePodsGetter{pods: []v1.Pod{
+		newPod("not-opted-in", "node-1", "claim-1", v1.PodRunning, false),
+		newPod("not-running", "node-1", "claim-1", v1.PodPending, true),
+		newPod("other-claim", "node-1", "claim-2", v1.PodRunning, true),
+		newPod("node-1-a", "node-1", "claim-1", v1.PodRunning, true),
+		newPod("node-1-b", "node-1", "claim-1", v1.PodRunning, true),
+		newPod("node-2", "node-2", "claim-1", v1.PodRunning, true),
+	}}
+
+	freezer := NewPodFreezer(podClient, "heptio-ark", DefaultImage, time.Minute).(*podFreezer)
+
+	pods, err := freezer.podsToFreeze("ns-1", "claim-1")
+	require.NoError(t, err)
+
+	var names []string
+	for _, pod := range pods {
+		names = append(names, pod.Name)
+	}
+	assert.Equal(t, []string{"node-1-a", "node-2"}, names)
+}
+
+func TestFreezeWithNoPodsDoesNothing(t *testing.T) {
+	freezer := NewPodFreezer(&fakePodsGetter{}, "heptio-ark", DefaultImage, time.Minute)
+
+	thaw, err := freezer.Freeze("ns-1", "claim-1", "pv-1")
+	require.NoError(t, err)
+	assert.NoError(t, thaw())
+}
+
+func TestHelperPod(t *testing.T) {
+	freezer := NewPodFreezer(&fakePodsGetter{}, "heptio-ark", "image", 90*time.Second).(*podFreezer)
+	pod := newPod("pod-1", "node-1", "claim-1", v1.PodRunning, true)
+	pod.UID = "pod-uid"
+
+	helper := freezer.helperPod(&pod, "pv-1")
+
+	assert.Equal(t, "heptio-ark", helper.Namespace)
+	assert.Equal(t, "node-1", helper.Spec.NodeName)
+	require.Len(t, helper.Spec.Containers, 1)
+
+	container := helper.Spec.Containers[0]
+	assert.Equal(t, "image", container.Image)
+	assert.True(t, *container.SecurityContext.Privileged)
+
+	script := container.Command[2]
+	assert.True(t, strings.Contains(script, "/host_pods/pod-uid/volumes/*/pv-1"))
+	assert.True(t, strings.Contains(script, "sleep 90 &"))
+}
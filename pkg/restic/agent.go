@@ -0,0 +1,365 @@
+/*
+Copyright 2017 Heptio Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package restic
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/golang/glog"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	corev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/pkg/api/v1"
+
+	api "github.com/heptio/ark/pkg/apis/ark/v1"
+	arkv1client "github.com/heptio/ark/pkg/generated/clientset/typed/ark/v1"
+	"github.com/heptio/ark/pkg/uploader"
+)
+
+// agentRunner implements Runner by creating PodVolumeBackups and PodVolumeRestores for the node
+// agents, which run on every node, to process, and waiting for them to complete. Repositories are
+// maintained in helper pods, since that doesn't need access to any node's volumes.
+type agentRunner struct {
+	podClient              corev1.PodsGetter
+	namespaceClient        corev1.NamespacesGetter
+	podVolumeBackupClient  arkv1client.PodVolumeBackupsGetter
+	podVolumeRestoreClient arkv1client.PodVolumeRestoresGetter
+	storageConfig          api.ObjectStorageProviderConfig
+	namespace              string
+	image                  string
+	timeout                time.Duration
+	maintainer             *podRunner
+}
+
+var (
+	_ Runner              = &agentRunner{}
+	_ HostVolumeBackupper = &agentRunner{}
+	_ HostVolumeRestorer  = &agentRunner{}
+)
+
+// NewAgentRunner creates a Runner that has the node agents back up and restore pod volumes to
+// and from restic repositories in the bucket described by storageConfig, through
+// PodVolumeBackups and PodVolumeRestores in namespace. Pods that restore existing claims, and
+// that maintain repositories, run image; those that delete kopia snapshots copy kopia from
+// kopiaImage. The pods that maintain repositories have resources, and limit restic's bandwidth
+// to limits; the node agents have limits of their own. Each backup or restore of a volume must
+// complete within timeout.
+// Volumes are backed up using restic, or kopia if their backup or their namespace, read using
+// namespaceClient, chooses it.
+func NewAgentRunner(
+	podClient corev1.PodsGetter,
+	namespaceClient corev1.NamespacesGetter,
+	podVolumeBackupClient arkv1client.PodVolumeBackupsGetter,
+	podVolumeRestoreClient arkv1client.PodVolumeRestoresGetter,
+	storageConfig api.ObjectStorageProviderConfig,
+	namespace string,
+	image string,
+	kopiaImage string,
+	timeout time.Duration,
+	resources v1.ResourceRequirements,
+	limits uploader.Limits,
+) (Runner, error) {
+	if _, err := RepoIdentifier(storageConfig, namespace); err != nil {
+		return nil, err
+	}
+
+	return &agentRunner{
+		podClient:              podClient,
+		namespaceClient:        namespaceClient,
+		podVolumeBackupClient:  podVolumeBackupClient,
+		podVolumeRestoreClient: podVolumeRestoreClient,
+		storageConfig:          storageConfig,
+		namespace:              namespace,
+		image:                  image,
+		timeout:                timeout,
+		maintainer: &podRunner{
+			podClient:     podClient,
+			storageConfig: storageConfig,
+			namespace:     namespace,
+			image:         image,
+			kopiaImage:    kopiaImage,
+			timeout:       timeout,
+			resources:     resources,
+			limits:        limits,
+		},
+	}, nil
+}
+
+func (r *agentRunner) BackupPodVolume(backup *api.Backup, pod *v1.Pod, volumeName string) (string, error) {
+	uploaderType, err := r.uploaderType(backup, pod.Namespace)
+	if err != nil {
+		return "", err
+	}
+
+	repo, err := UploaderRepoIdentifier(r.storageConfig, uploaderType, pod.Namespace)
+	if err != nil {
+		return "", err
+	}
+
+	podVolumeBackup := &api.PodVolumeBackup{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:    r.namespace,
+			GenerateName: backup.Name + "-",
+			Labels:       map[string]string{api.BackupNameLabel: backup.Name},
+		},
+		Spec: api.PodVolumeBackupSpec{
+			Node:           pod.Spec.NodeName,
+			Pod:            podReference(pod),
+			Volume:         volumeName,
+			RepoIdentifier: repo,
+			Tags: map[string]string{
+				"backup": backup.Name,
+				"pod":    pod.Name,
+				"volume": volumeName,
+			},
+			UploaderType: uploaderType,
+		},
+		Status: api.PodVolumeBackupStatus{
+			Phase: api.PodVolumeBackupPhaseNew,
+		},
+	}
+
+	glog.V(2).Infof("Backing up volume %s of pod %s/%s using %s and the node agent on %s", volumeName, pod.Namespace, pod.Name, uploaderType, pod.Spec.NodeName)
+	snapshotID, err := r.backupVolume(podVolumeBackup)
+	if err != nil {
+		return "", fmt.Errorf("error backing up volume %s of pod %s/%s: %v", volumeName, pod.Namespace, pod.Name, err)
+	}
+
+	return snapshotRef(uploaderType, snapshotID), nil
+}
+
+func (r *agentRunner) BackupHostVolume(backup *api.Backup, repoNamespace, volumeName, node, path string) (string, error) {
+	uploaderType, err := r.uploaderType(backup, repoNamespace)
+	if err != nil {
+		return "", err
+	}
+
+	repo, err := UploaderRepoIdentifier(r.storageConfig, uploaderType, repoNamespace)
+	if err != nil {
+		return "", err
+	}
+
+	podVolumeBackup := &api.PodVolumeBackup{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:    r.namespace,
+			GenerateName: backup.Name + "-",
+			Labels:       map[string]string{api.BackupNameLabel: backup.Name},
+		},
+		Spec: api.PodVolumeBackupSpec{
+			Node:           node,
+			Volume:         volumeName,
+			HostPath:       path,
+			RepoIdentifier: repo,
+			Tags: map[string]string{
+				"backup":           backup.Name,
+				"persistentvolume": volumeName,
+			},
+			UploaderType: uploaderType,
+		},
+		Status: api.PodVolumeBackupStatus{
+			Phase: api.PodVolumeBackupPhaseNew,
+		},
+	}
+
+	glog.V(2).Infof("Backing up PersistentVolume %s at %s using %s and the node agent on %s", volumeName, path, uploaderType, node)
+	snapshotID, err := r.backupVolume(podVolumeBackup)
+	if err != nil {
+		return "", fmt.Errorf("error backing up PersistentVolume %s: %v", volumeName, err)
+	}
+
+	return snapshotRef(uploaderType, snapshotID), nil
+}
+
+// backupVolume creates podVolumeBackup, waits for it to complete, and returns the ID of the
+// resulting snapshot.
+func (r *agentRunner) backupVolume(podVolumeBackup *api.PodVolumeBackup) (string, error) {
+	created, err := r.podVolumeBackupClient.PodVolumeBackups(r.namespace).Create(podVolumeBackup)
+	if err != nil {
+		return "", fmt.Errorf("error creating PodVolumeBackup: %v", err)
+	}
+	defer func() {
+		if err := r.podVolumeBackupClient.PodVolumeBackups(r.namespace).Delete(created.Name, &metav1.DeleteOptions{}); err != nil {
+			glog.Errorf("error deleting PodVolumeBackup %s/%s: %v", r.namespace, created.Name, err)
+		}
+	}()
+
+	err = wait.PollImmediate(pollInterval, r.timeout, func() (bool, error) {
+		created, err = r.podVolumeBackupClient.PodVolumeBackups(r.namespace).Get(created.Name, metav1.GetOptions{})
+		if err != nil {
+			return false, err
+		}
+		return created.Status.Phase == api.PodVolumeBackupPhaseCompleted || created.Status.Phase == api.PodVolumeBackupPhaseFailed, nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("error waiting for PodVolumeBackup %s/%s to complete: %v", r.namespace, created.Name, err)
+	}
+
+	if created.Status.Phase == api.PodVolumeBackupPhaseFailed {
+		return "", fmt.Errorf("PodVolumeBackup %s/%s failed: %s", r.namespace, created.Name, created.Status.Message)
+	}
+
+	return created.Status.SnapshotID, nil
+}
+
+// uploaderType returns the uploader that backs up the volumes of backup's pods in namespace:
+// the backup's, if it has one, or else the one the namespace's UploaderAnnotation chooses, or
+// else restic.
+func (r *agentRunner) uploaderType(backup *api.Backup, namespace string) (string, error) {
+	if backup.Spec.UploaderType != "" {
+		return backup.Spec.UploaderType, nil
+	}
+
+	ns, err := r.namespaceClient.Namespaces().Get(namespace, metav1.GetOptions{})
+	if err != nil {
+		return "", fmt.Errorf("error getting namespace %s: %v", namespace, err)
+	}
+	if uploaderType := ns.Annotations[api.UploaderAnnotation]; uploaderType != "" {
+		return uploaderType, nil
+	}
+	return api.UploaderTypeRestic, nil
+}
+
+func (r *agentRunner) RestorePodVolumes(restore *api.Restore, repoNamespace, namespace, podName string, snapshots map[string]string) []error {
+	pod, err := waitForInitContainer(r.podClient, namespace, podName, r.timeout)
+	if err != nil {
+		return volumeErrors(snapshots, err)
+	}
+
+	var errs []error
+	for volumeName, ref := range snapshots {
+		uploaderType, snapshotID := parseSnapshotRef(ref)
+		repo, err := UploaderRepoIdentifier(r.storageConfig, uploaderType, repoNamespace)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		podVolumeRestore := &api.PodVolumeRestore{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace:    r.namespace,
+				GenerateName: restore.Name + "-",
+				Labels:       map[string]string{api.RestoreNameLabel: restore.Name},
+			},
+			Spec: api.PodVolumeRestoreSpec{
+				Node:           pod.Spec.NodeName,
+				Pod:            podReference(pod),
+				Volume:         volumeName,
+				RepoIdentifier: repo,
+				SnapshotID:     snapshotID,
+				UploaderType:   uploaderType,
+				RestoreUID:     restore.UID,
+			},
+			Status: api.PodVolumeRestoreStatus{
+				Phase: api.PodVolumeRestorePhaseNew,
+			},
+		}
+
+		glog.V(2).Infof("Restoring volume %s of pod %s/%s using the node agent on %s", volumeName, namespace, podName, pod.Spec.NodeName)
+		if err := r.restorePodVolume(podVolumeRestore); err != nil {
+			errs = append(errs, fmt.Errorf("error restoring volume %s of pod %s/%s: %v", volumeName, namespace, podName, err))
+		}
+	}
+
+	return errs
+}
+
+// restorePodVolume creates podVolumeRestore and waits for it to complete.
+func (r *agentRunner) restorePodVolume(podVolumeRestore *api.PodVolumeRestore) error {
+	created, err := r.podVolumeRestoreClient.PodVolumeRestores(r.namespace).Create(podVolumeRestore)
+	if err != nil {
+		return fmt.Errorf("error creating PodVolumeRestore: %v", err)
+	}
+	defer func() {
+		if err := r.podVolumeRestoreClient.PodVolumeRestores(r.namespace).Delete(created.Name, &metav1.DeleteOptions{}); err != nil {
+			glog.Errorf("error deleting PodVolumeRestore %s/%s: %v", r.namespace, created.Name, err)
+		}
+	}()
+
+	err = wait.PollImmediate(pollInterval, r.timeout, func() (bool, error) {
+		created, err = r.podVolumeRestoreClient.PodVolumeRestores(r.namespace).Get(created.Name, metav1.GetOptions{})
+		if err != nil {
+			return false, err
+		}
+		return created.Status.Phase == api.PodVolumeRestorePhaseCompleted || created.Status.Phase == api.PodVolumeRestorePhaseFailed, nil
+	})
+	if err != nil {
+		return fmt.Errorf("error waiting for PodVolumeRestore %s/%s to complete: %v", r.namespace, created.Name, err)
+	}
+
+	if created.Status.Phase == api.PodVolumeRestorePhaseFailed {
+		return fmt.Errorf("PodVolumeRestore %s/%s failed: %s", r.namespace, created.Name, created.Status.Message)
+	}
+
+	return nil
+}
+
+func (r *agentRunner) RestoreHostVolume(restore *api.Restore, repoNamespace, volumeName, node, path, ref string) error {
+	uploaderType, snapshotID := parseSnapshotRef(ref)
+	repo, err := UploaderRepoIdentifier(r.storageConfig, uploaderType, repoNamespace)
+	if err != nil {
+		return err
+	}
+
+	podVolumeRestore := &api.PodVolumeRestore{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:    r.namespace,
+			GenerateName: restore.Name + "-",
+			Labels:       map[string]string{api.RestoreNameLabel: restore.Name},
+		},
+		Spec: api.PodVolumeRestoreSpec{
+			Node:           node,
+			Volume:         volumeName,
+			HostPath:       path,
+			RepoIdentifier: repo,
+			SnapshotID:     snapshotID,
+			UploaderType:   uploaderType,
+		},
+		Status: api.PodVolumeRestoreStatus{
+			Phase: api.PodVolumeRestorePhaseNew,
+		},
+	}
+
+	glog.V(2).Infof("Restoring PersistentVolume %s at %s using the node agent on %s", volumeName, path, node)
+	if err := r.restorePodVolume(podVolumeRestore); err != nil {
+		return fmt.Errorf("error restoring PersistentVolume %s: %v", volumeName, err)
+	}
+	return nil
+}
+
+func (r *agentRunner) RestoreClaim(restore *api.Restore, repoNamespace, namespace, claimName, snapshotID string) error {
+	return restoreClaim(r.podClient, r, r.image, restore, repoNamespace, namespace, claimName, snapshotID)
+}
+
+func (r *agentRunner) MaintainRepository(repo string) (int64, error) {
+	return r.maintainer.MaintainRepository(repo)
+}
+
+func (r *agentRunner) DeleteSnapshot(repoNamespace, ref string) error {
+	return r.maintainer.DeleteSnapshot(repoNamespace, ref)
+}
+
+// podReference returns a reference to pod for a PodVolumeBackup or PodVolumeRestore.
+func podReference(pod *v1.Pod) v1.ObjectReference {
+	return v1.ObjectReference{
+		Kind:      "Pod",
+		Namespace: pod.Namespace,
+		Name:      pod.Name,
+		UID:       pod.UID,
+	}
+}
@@ -0,0 +1,155 @@
+/*
+Copyright 2017 Heptio Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package restic
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	corev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/pkg/api/v1"
+	core "k8s.io/client-go/testing"
+
+	api "github.com/heptio/ark/pkg/apis/ark/v1"
+	"github.com/heptio/ark/pkg/generated/clientset/fake"
+	"github.com/heptio/ark/pkg/uploader"
+)
+
+type fakeNamespacesGetter struct {
+	namespaces map[string]*v1.Namespace
+}
+
+func (g *fakeNamespacesGetter) Namespaces() corev1.NamespaceInterface {
+	return &fakeNamespaceClient{getter: g}
+}
+
+type fakeNamespaceClient struct {
+	corev1.NamespaceInterface
+	getter *fakeNamespacesGetter
+}
+
+func (c *fakeNamespaceClient) Get(name string, opts metav1.GetOptions) (*v1.Namespace, error) {
+	if ns, ok := c.getter.namespaces[name]; ok {
+		return ns, nil
+	}
+	return nil, apierrors.NewNotFound(v1.Resource("namespaces"), name)
+}
+
+func TestAgentRunnerBackupPodVolume(t *testing.T) {
+	tests := []struct {
+		name                 string
+		backupUploaderType   string
+		namespaceAnnotations map[string]string
+		status               api.PodVolumeBackupStatus
+		expectedUploaderType string
+		expectedRepo         string
+		expectedSnapshotID   string
+		expectedErr          bool
+	}{
+		{
+			name:                 "completed backup returns its snapshot",
+			status:               api.PodVolumeBackupStatus{Phase: api.PodVolumeBackupPhaseCompleted, SnapshotID: "abc123"},
+			expectedUploaderType: api.UploaderTypeRestic,
+			expectedRepo:         "s3:s3.us-west-2.amazonaws.com/bucket/.ark-restic/ns-1",
+			expectedSnapshotID:   "abc123",
+		},
+		{
+			name:                 "failed backup returns an error",
+			status:               api.PodVolumeBackupStatus{Phase: api.PodVolumeBackupPhaseFailed, Message: "error running restic backup"},
+			expectedUploaderType: api.UploaderTypeRestic,
+			expectedRepo:         "s3:s3.us-west-2.amazonaws.com/bucket/.ark-restic/ns-1",
+			expectedErr:          true,
+		},
+		{
+			name:                 "namespace annotation chooses kopia",
+			namespaceAnnotations: map[string]string{api.UploaderAnnotation: api.UploaderTypeKopia},
+			status:               api.PodVolumeBackupStatus{Phase: api.PodVolumeBackupPhaseCompleted, SnapshotID: "k123"},
+			expectedUploaderType: api.UploaderTypeKopia,
+			expectedRepo:         "s3:s3.us-west-2.amazonaws.com/bucket/.ark-kopia/ns-1",
+			expectedSnapshotID:   "kopia:k123",
+		},
+		{
+			name:                 "backup's uploader takes precedence over the namespace's",
+			backupUploaderType:   api.UploaderTypeRestic,
+			namespaceAnnotations: map[string]string{api.UploaderAnnotation: api.UploaderTypeKopia},
+			status:               api.PodVolumeBackupStatus{Phase: api.PodVolumeBackupPhaseCompleted, SnapshotID: "abc123"},
+			expectedUploaderType: api.UploaderTypeRestic,
+			expectedRepo:         "s3:s3.us-west-2.amazonaws.com/bucket/.ark-restic/ns-1",
+			expectedSnapshotID:   "abc123",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			client := fake.NewSimpleClientset()
+
+			var created *api.PodVolumeBackup
+			client.PrependReactor("create", "podvolumebackups", func(action core.Action) (bool, runtime.Object, error) {
+				// the fake clientset doesn't generate names, and there's no node agent to
+				// process the backup, so do both here.
+				created = action.(core.CreateAction).GetObject().(*api.PodVolumeBackup)
+				created.Name = created.GenerateName + "abcde"
+				created.Status = test.status
+				return false, nil, nil
+			})
+
+			storageConfig := api.ObjectStorageProviderConfig{
+				CloudProviderConfig: api.CloudProviderConfig{AWS: &api.AWSConfig{Region: "us-west-2"}},
+				Bucket:              "bucket",
+			}
+			namespaceClient := &fakeNamespacesGetter{namespaces: map[string]*v1.Namespace{
+				"ns-1": {ObjectMeta: metav1.ObjectMeta{Name: "ns-1", Annotations: test.namespaceAnnotations}},
+			}}
+			runner, err := NewAgentRunner(nil, namespaceClient, client.ArkV1(), client.ArkV1(), storageConfig, api.DefaultNamespace, "gcr.io/heptio-images/ark", DefaultKopiaImage, time.Minute, v1.ResourceRequirements{}, uploader.Limits{})
+			require.NoError(t, err)
+
+			backup := &api.Backup{
+				ObjectMeta: metav1.ObjectMeta{Namespace: api.DefaultNamespace, Name: "backup-1"},
+				Spec:       api.BackupSpec{UploaderType: test.backupUploaderType},
+			}
+			pod := &v1.Pod{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "ns-1", Name: "pod-1", UID: "uid-1"},
+				Spec:       v1.PodSpec{NodeName: "node-1"},
+			}
+
+			snapshotID, err := runner.BackupPodVolume(backup, pod, "data")
+			if test.expectedErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, test.expectedSnapshotID, snapshotID)
+
+			require.NotNil(t, created)
+			assert.Equal(t, map[string]string{api.BackupNameLabel: "backup-1"}, created.Labels)
+			assert.Equal(t, "node-1", created.Spec.Node)
+			assert.Equal(t, v1.ObjectReference{Kind: "Pod", Namespace: "ns-1", Name: "pod-1", UID: "uid-1"}, created.Spec.Pod)
+			assert.Equal(t, test.expectedRepo, created.Spec.RepoIdentifier)
+			assert.Equal(t, test.expectedUploaderType, created.Spec.UploaderType)
+			assert.Equal(t, map[string]string{"backup": "backup-1", "pod": "pod-1", "volume": "data"}, created.Spec.Tags)
+
+			_, err = client.ArkV1().PodVolumeBackups(api.DefaultNamespace).Get("backup-1-abcde", metav1.GetOptions{})
+			assert.True(t, apierrors.IsNotFound(err), "expected the PodVolumeBackup to be deleted, got %v", err)
+		})
+	}
+}
@@ -0,0 +1,164 @@
+/*
+Copyright 2017 Heptio Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package restic
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/golang/glog"
+
+	api "github.com/heptio/ark/pkg/apis/ark/v1"
+	"github.com/heptio/ark/pkg/uploader"
+)
+
+// Maintainer maintains restic repositories.
+type Maintainer interface {
+	// MaintainRepository removes stale locks from the restic repository repo, prunes the data
+	// that's no longer referenced by any of its snapshots, and checks its integrity. It returns
+	// the approximate size, in bytes, of the repository's data once it's been pruned.
+	MaintainRepository(repo string) (int64, error)
+}
+
+// SnapshotDeleter deletes pod volume snapshots, e.g. those taken by a backup that didn't finish.
+type SnapshotDeleter interface {
+	// DeleteSnapshot deletes the snapshot recorded on a backed-up item as ref from the repository
+	// for repoNamespace. The data only a restic snapshot referenced is removed when the
+	// repository is next maintained; kopia repositories are maintained as soon as the snapshot is
+	// deleted. It returns nil if the snapshot doesn't exist, so that deletions can be retried.
+	DeleteSnapshot(repoNamespace, ref string) error
+}
+
+func (r *podRunner) DeleteSnapshot(repoNamespace, ref string) error {
+	uploaderType, snapshotID := parseSnapshotRef(ref)
+	repo, err := UploaderRepoIdentifier(r.storageConfig, uploaderType, repoNamespace)
+	if err != nil {
+		return err
+	}
+
+	if uploaderType == api.UploaderTypeKopia {
+		script, err := uploader.KopiaDeleteScript(repo, snapshotID)
+		if err != nil {
+			return err
+		}
+
+		glog.V(2).Infof("Deleting kopia snapshot %s from repository %s", snapshotID, repo)
+		if _, err := r.runPod(r.kopiaHelperPod("kopia-delete", repo, script)); err != nil {
+			return fmt.Errorf("error deleting kopia snapshot %s from repository %s: %v", snapshotID, repo, err)
+		}
+		return nil
+	}
+
+	glog.V(2).Infof("Deleting restic snapshot %s from repository %s", snapshotID, repo)
+	if _, err := r.run("restic-forget", "", repo, forgetScript(snapshotID)); err != nil {
+		return fmt.Errorf("error deleting restic snapshot %s from repository %s: %v", snapshotID, repo, err)
+	}
+	return nil
+}
+
+// forgetScript forgets the restic snapshot snapshotID, succeeding if it has already been forgotten.
+func forgetScript(snapshotID string) string {
+	return strings.Join([]string{
+		"restic forget " + snapshotID + " > /tmp/forget.log 2>&1 && exit 0",
+		"grep -qiE 'no matching ID found|could not find a snapshot' /tmp/forget.log && exit 0",
+		"cat /tmp/forget.log",
+		"exit 1",
+	}, "\n")
+}
+
+// maintenanceScript unlocks, prunes, and checks the repository, then reports the lines of
+// restic prune's output that give the repository's size and how much pruning freed in the
+// termination message.
+var maintenanceScript = strings.Join([]string{
+	"set -e",
+	"restic unlock",
+	"restic prune > /tmp/prune.log 2>&1 || { cat /tmp/prune.log; exit 1; }",
+	"restic check > /tmp/check.log 2>&1 || { cat /tmp/check.log; exit 1; }",
+	"grep -E 'repository contains|this frees' /tmp/prune.log > /dev/termination-log || true",
+}, "\n")
+
+func (r *podRunner) MaintainRepository(repo string) (int64, error) {
+	glog.V(2).Infof("Maintaining restic repository %s", repo)
+	output, err := r.run("restic-maintenance", "", repo, maintenanceScript)
+	if err != nil {
+		return 0, fmt.Errorf("error maintaining restic repository %s: %v", repo, err)
+	}
+
+	return prunedSize(output)
+}
+
+var (
+	repoSizeRegexp  = regexp.MustCompile(`repository contains \d+ packs \(\d+ blobs\) with ([0-9.]+ [KMGT]?i?B)`)
+	freedSizeRegexp = regexp.MustCompile(`this frees ([0-9.]+ [KMGT]?i?B)`)
+)
+
+// prunedSize returns the size of a repository after it's been pruned, given the output of
+// restic prune: the size of the repository before it was pruned, less what pruning freed, if
+// anything. restic reports sizes rounded to three decimal places, so the result is approximate.
+func prunedSize(output string) (int64, error) {
+	match := repoSizeRegexp.FindStringSubmatch(output)
+	if match == nil {
+		return 0, fmt.Errorf("restic didn't report the size of the repository: %s", output)
+	}
+	size, err := parseResticBytes(match[1])
+	if err != nil {
+		return 0, err
+	}
+
+	if match := freedSizeRegexp.FindStringSubmatch(output); match != nil {
+		freed, err := parseResticBytes(match[1])
+		if err != nil {
+			return 0, err
+		}
+		size -= freed
+	}
+
+	if size < 0 {
+		size = 0
+	}
+	return size, nil
+}
+
+var resticByteUnits = map[string]float64{
+	"B":   1,
+	"KiB": 1 << 10,
+	"MiB": 1 << 20,
+	"GiB": 1 << 30,
+	"TiB": 1 << 40,
+}
+
+// parseResticBytes parses a size formatted by restic, e.g. "76.548 MiB".
+func parseResticBytes(s string) (int64, error) {
+	fields := strings.Fields(s)
+	if len(fields) != 2 {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+
+	unit, ok := resticByteUnits[fields[1]]
+	if !ok {
+		return 0, fmt.Errorf("invalid size %q: unknown unit %s", s, fields[1])
+	}
+
+	value, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid size %q: %v", s, err)
+	}
+
+	return int64(value * unit), nil
+}
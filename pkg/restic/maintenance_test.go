@@ -0,0 +1,66 @@
+/*
+Copyright 2017 Heptio Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package restic
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPrunedSize(t *testing.T) {
+	tests := []struct {
+		name        string
+		output      string
+		expected    int64
+		expectedErr bool
+	}{
+		{
+			name:     "nothing freed",
+			output:   "repository contains 12 packs (340 blobs) with 2.000 MiB\n",
+			expected: 2 << 20,
+		},
+		{
+			name: "size less what was freed",
+			output: "repository contains 35 packs (4214 blobs) with 1.500 GiB\n" +
+				"will delete 3 packs and rewrite 2 packs, this frees 512.000 MiB\n",
+			expected: 1 << 30,
+		},
+		{
+			name:     "bytes",
+			output:   "repository contains 0 packs (0 blobs) with 0 B\n",
+			expected: 0,
+		},
+		{
+			name:        "no size reported",
+			output:      "counting files in repo\n",
+			expectedErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			size, err := prunedSize(test.output)
+			if test.expectedErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected, size)
+		})
+	}
+}
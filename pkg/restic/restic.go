@@ -0,0 +1,215 @@
+/*
+Copyright 2017 Heptio Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package restic
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	api "github.com/heptio/ark/pkg/apis/ark/v1"
+	"github.com/heptio/ark/pkg/cloudprovider"
+	"github.com/heptio/ark/pkg/util/collections"
+)
+
+const (
+	// VolumesToBackupAnnotation is the annotation on a pod whose value is a comma-separated
+	// list of the names of the pod's volumes to back up using restic.
+	VolumesToBackupAnnotation = "backup.ark.heptio.com/backup-volumes"
+
+	// snapshotAnnotationPrefix is the prefix of the annotations recording, on the backed-up
+	// copy of a pod, the ID of the restic snapshot of each of its volumes. The rest of the
+	// annotation's key is the name of the volume.
+	snapshotAnnotationPrefix = "snapshot.ark.heptio.com/"
+
+	// hostVolumeSnapshotAnnotation is the annotation recording, on the backed-up copy of a
+	// local or hostPath PersistentVolume, the ID of the snapshot of its data.
+	hostVolumeSnapshotAnnotation = "backup.ark.heptio.com/host-volume-snapshot"
+
+	// InitContainer is the name of the init container added to restored pods that have
+	// restic snapshots. It waits for the snapshots to be restored into the pod's volumes
+	// before letting the pod's other containers start.
+	InitContainer = "restic-wait"
+
+	// DefaultImage is the container image used to run restic if none is configured.
+	DefaultImage = "restic/restic:0.8.1"
+
+	// DefaultKopiaImage is the container image kopia is copied from, into the helper pods that
+	// delete kopia snapshots, if none is configured.
+	DefaultKopiaImage = "kopia/kopia:0.8.4"
+
+	// CredentialsSecret is the name of the secret, in the Ark server's namespace, holding
+	// the password for the restic repositories under the key CredentialsKey.
+	CredentialsSecret = "restic-credentials"
+
+	// CredentialsKey is the key of the restic repository password in CredentialsSecret.
+	CredentialsKey = "repository-password"
+
+	// repoDir is the top-level "directory" in the backup bucket where restic repositories
+	// are stored, one per namespace.
+	repoDir = ".ark-restic"
+
+	// kopiaRepoDir is the top-level "directory" in the backup bucket where kopia repositories
+	// are stored, one per namespace.
+	kopiaRepoDir = ".ark-kopia"
+
+	// kopiaSnapshotPrefix is the prefix of the IDs of kopia snapshots recorded on backed-up
+	// pods, which tells them apart from restic snapshots.
+	kopiaSnapshotPrefix = "kopia:"
+)
+
+// GetVolumesToBackup returns the names of the pod volumes that should be backed up using
+// restic, as listed in the pod's VolumesToBackupAnnotation.
+func GetVolumesToBackup(obj metav1.Object) []string {
+	value := obj.GetAnnotations()[VolumesToBackupAnnotation]
+	if value == "" {
+		return nil
+	}
+
+	var volumes []string
+	for _, volume := range strings.Split(value, ",") {
+		if volume = strings.TrimSpace(volume); volume != "" {
+			volumes = append(volumes, volume)
+		}
+	}
+	return volumes
+}
+
+// GetSnapshots returns a map of volume name to restic snapshot ID for each of the pod's
+// volumes that were backed up using restic.
+func GetSnapshots(obj metav1.Object) map[string]string {
+	snapshots := make(map[string]string)
+	for key, value := range obj.GetAnnotations() {
+		if strings.HasPrefix(key, snapshotAnnotationPrefix) {
+			snapshots[strings.TrimPrefix(key, snapshotAnnotationPrefix)] = value
+		}
+	}
+	return snapshots
+}
+
+// SetSnapshot records on the pod that the named volume was backed up as the restic snapshot
+// with the given ID.
+func SetSnapshot(obj metav1.Object, volumeName, snapshotID string) {
+	annotations := obj.GetAnnotations()
+	if annotations == nil {
+		annotations = make(map[string]string)
+	}
+	annotations[snapshotAnnotationPrefix+volumeName] = snapshotID
+	obj.SetAnnotations(annotations)
+}
+
+// GetHostVolumeSnapshot returns the ID of the snapshot of the data of the local or hostPath
+// PersistentVolume, or "" if its data wasn't backed up.
+func GetHostVolumeSnapshot(obj metav1.Object) string {
+	return obj.GetAnnotations()[hostVolumeSnapshotAnnotation]
+}
+
+// SetHostVolumeSnapshot records on the local or hostPath PersistentVolume that its data was
+// backed up as the snapshot with the given ID.
+func SetHostVolumeSnapshot(obj metav1.Object, snapshotID string) {
+	annotations := obj.GetAnnotations()
+	if annotations == nil {
+		annotations = make(map[string]string)
+	}
+	annotations[hostVolumeSnapshotAnnotation] = snapshotID
+	obj.SetAnnotations(annotations)
+}
+
+// HostVolumeRepoNamespace returns the namespace whose repository holds the data of the local or
+// hostPath PersistentVolume pv: its claim's namespace, or Ark's default namespace if it isn't bound.
+func HostVolumeRepoNamespace(pv map[string]interface{}) string {
+	if namespace, err := collections.GetString(pv, "spec.claimRef.namespace"); err == nil && namespace != "" {
+		return namespace
+	}
+	return api.DefaultNamespace
+}
+
+// snapshotRef returns the snapshot ID recorded on a backed-up pod or PersistentVolume for the
+// snapshot snapshotID taken by the uploader uploaderType.
+func snapshotRef(uploaderType, snapshotID string) string {
+	if uploaderType == api.UploaderTypeKopia {
+		return kopiaSnapshotPrefix + snapshotID
+	}
+	return snapshotID
+}
+
+// parseSnapshotRef returns the uploader that took the snapshot recorded on a backed-up item as
+// ref, and the snapshot's ID.
+func parseSnapshotRef(ref string) (string, string) {
+	if strings.HasPrefix(ref, kopiaSnapshotPrefix) {
+		return api.UploaderTypeKopia, strings.TrimPrefix(ref, kopiaSnapshotPrefix)
+	}
+	return api.UploaderTypeRestic, ref
+}
+
+// RepoIdentifier returns the restic identifier of the repository, in the backup bucket described
+// by config, that holds the pod volume snapshots for the given namespace. It's under the bucket's
+// prefix, if config has one.
+func RepoIdentifier(config api.ObjectStorageProviderConfig, namespace string) (string, error) {
+	return repoIdentifier(config, repoDir, namespace)
+}
+
+// UploaderRepoIdentifier returns the identifier of the repository, in the backup bucket
+// described by config, that the uploader uploaderType backs up the given namespace's pod
+// volumes to. Kopia repositories are identified like restic ones.
+func UploaderRepoIdentifier(config api.ObjectStorageProviderConfig, uploaderType, namespace string) (string, error) {
+	if uploaderType == api.UploaderTypeKopia {
+		return repoIdentifier(config, kopiaRepoDir, namespace)
+	}
+	return repoIdentifier(config, repoDir, namespace)
+}
+
+// repoIdentifier returns the restic identifier of the repository for namespace under the
+// top-level directory dir of the backup bucket described by config.
+func repoIdentifier(config api.ObjectStorageProviderConfig, dir, namespace string) (string, error) {
+	dir = path.Join(strings.Trim(config.Prefix, "/"), dir, namespace)
+
+	switch {
+	case config.AWS != nil:
+		endpoint := config.AWS.S3Url
+		if endpoint == "" {
+			endpoint = fmt.Sprintf("s3.%s.amazonaws.com", config.AWS.Region)
+		}
+		return fmt.Sprintf("s3:%s/%s/%s", strings.TrimSuffix(endpoint, "/"), config.Bucket, dir), nil
+	case config.GCP != nil:
+		return fmt.Sprintf("gs:%s:/%s", config.Bucket, dir), nil
+	case config.Azure != nil:
+		return fmt.Sprintf("azure:%s:/%s", config.Bucket, dir), nil
+	}
+
+	return "", errors.New("backup storage provider must be one of aws, gcp, or azure")
+}
+
+// RepoNamespaces returns the namespaces that have restic repositories in bucket, listed using
+// objectStorage, which adds the bucket's prefix, if it has one.
+func RepoNamespaces(objectStorage cloudprovider.ObjectStorageAdapter, bucket string) ([]string, error) {
+	prefixes, err := objectStorage.ListCommonPrefixes(bucket, repoDir+"/", "/")
+	if err != nil {
+		return nil, err
+	}
+
+	namespaces := make([]string, 0, len(prefixes))
+	for _, prefix := range prefixes {
+		if namespace := strings.Trim(strings.TrimPrefix(prefix, repoDir+"/"), "/"); namespace != "" {
+			namespaces = append(namespaces, namespace)
+		}
+	}
+	return namespaces, nil
+}
@@ -0,0 +1,165 @@
+/*
+Copyright 2017 Heptio Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package restic
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	api "github.com/heptio/ark/pkg/apis/ark/v1"
+)
+
+func TestGetVolumesToBackup(t *testing.T) {
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		expected    []string
+	}{
+		{
+			name: "no annotation",
+		},
+		{
+			name:        "empty annotation",
+			annotations: map[string]string{VolumesToBackupAnnotation: ""},
+		},
+		{
+			name:        "single volume",
+			annotations: map[string]string{VolumesToBackupAnnotation: "data"},
+			expected:    []string{"data"},
+		},
+		{
+			name:        "multiple volumes with whitespace and empty entries",
+			annotations: map[string]string{VolumesToBackupAnnotation: "data, cache,,"},
+			expected:    []string{"data", "cache"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			pod := &metav1.ObjectMeta{Annotations: test.annotations}
+			assert.Equal(t, test.expected, GetVolumesToBackup(pod))
+		})
+	}
+}
+
+func TestSnapshotAnnotations(t *testing.T) {
+	pod := &metav1.ObjectMeta{Annotations: map[string]string{"foo": "bar"}}
+	assert.Empty(t, GetSnapshots(pod))
+
+	SetSnapshot(pod, "data", "snapshot-1")
+	SetSnapshot(pod, "cache", "snapshot-2")
+
+	assert.Equal(t, map[string]string{"data": "snapshot-1", "cache": "snapshot-2"}, GetSnapshots(pod))
+	assert.Equal(t, "bar", pod.Annotations["foo"])
+
+	// annotations are initialized if needed
+	pod = &metav1.ObjectMeta{}
+	SetSnapshot(pod, "data", "snapshot-1")
+	assert.Equal(t, map[string]string{"data": "snapshot-1"}, GetSnapshots(pod))
+}
+
+func TestRepoIdentifier(t *testing.T) {
+	tests := []struct {
+		name        string
+		config      api.CloudProviderConfig
+		prefix      string
+		expected    string
+		expectedErr bool
+	}{
+		{
+			name:     "aws",
+			config:   api.CloudProviderConfig{AWS: &api.AWSConfig{Region: "us-west-2"}},
+			expected: "s3:s3.us-west-2.amazonaws.com/bucket/.ark-restic/ns-1",
+		},
+		{
+			name:     "aws with custom url",
+			config:   api.CloudProviderConfig{AWS: &api.AWSConfig{S3Url: "http://minio:9000/"}},
+			expected: "s3:http://minio:9000/bucket/.ark-restic/ns-1",
+		},
+		{
+			name:     "gcp",
+			config:   api.CloudProviderConfig{GCP: &api.GCPConfig{}},
+			expected: "gs:bucket:/.ark-restic/ns-1",
+		},
+		{
+			name:     "azure",
+			config:   api.CloudProviderConfig{Azure: &api.AzureConfig{}},
+			expected: "azure:bucket:/.ark-restic/ns-1",
+		},
+		{
+			name:     "aws with prefix",
+			config:   api.CloudProviderConfig{AWS: &api.AWSConfig{Region: "us-west-2"}},
+			prefix:   "cluster-1/",
+			expected: "s3:s3.us-west-2.amazonaws.com/bucket/cluster-1/.ark-restic/ns-1",
+		},
+		{
+			name:     "gcp with prefix",
+			config:   api.CloudProviderConfig{GCP: &api.GCPConfig{}},
+			prefix:   "cluster-1",
+			expected: "gs:bucket:/cluster-1/.ark-restic/ns-1",
+		},
+		{
+			name:        "no provider",
+			expectedErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			res, err := RepoIdentifier(api.ObjectStorageProviderConfig{CloudProviderConfig: test.config, Bucket: "bucket", Prefix: test.prefix}, "ns-1")
+			if test.expectedErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, res)
+		})
+	}
+}
+
+func TestUploaderRepoIdentifier(t *testing.T) {
+	config := api.ObjectStorageProviderConfig{
+		CloudProviderConfig: api.CloudProviderConfig{GCP: &api.GCPConfig{}},
+		Bucket:              "bucket",
+	}
+
+	res, err := UploaderRepoIdentifier(config, api.UploaderTypeKopia, "ns-1")
+	require.NoError(t, err)
+	assert.Equal(t, "gs:bucket:/.ark-kopia/ns-1", res)
+
+	res, err = UploaderRepoIdentifier(config, api.UploaderTypeRestic, "ns-1")
+	require.NoError(t, err)
+	assert.Equal(t, "gs:bucket:/.ark-restic/ns-1", res)
+}
+
+func TestSnapshotRef(t *testing.T) {
+	ref := snapshotRef(api.UploaderTypeKopia, "k123")
+	assert.Equal(t, "kopia:k123", ref)
+	uploaderType, snapshotID := parseSnapshotRef(ref)
+	assert.Equal(t, api.UploaderTypeKopia, uploaderType)
+	assert.Equal(t, "k123", snapshotID)
+
+	ref = snapshotRef(api.UploaderTypeRestic, "abc123")
+	assert.Equal(t, "abc123", ref)
+	uploaderType, snapshotID = parseSnapshotRef(ref)
+	assert.Equal(t, api.UploaderTypeRestic, uploaderType)
+	assert.Equal(t, "abc123", snapshotID)
+}
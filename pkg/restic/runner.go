@@ -0,0 +1,526 @@
+/*
+Copyright 2017 Heptio Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package restic
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/golang/glog"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	corev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/pkg/api/v1"
+
+	api "github.com/heptio/ark/pkg/apis/ark/v1"
+	"github.com/heptio/ark/pkg/uploader"
+)
+
+const (
+	// kubeletPodsDir is the directory on each node where the kubelet mounts pods' volumes, at
+	// <pod UID>/volumes/<volume plugin>/<volume directory>.
+	kubeletPodsDir = "/var/lib/kubelet/pods"
+
+	// hostPodsDir is where kubeletPodsDir is mounted in restic helper pods.
+	hostPodsDir = "/host_pods"
+
+	// hostRootDir is where the host's root directory is mounted in the node agent's container.
+	hostRootDir = "/host_root"
+
+	// helperPodLabel is the label applied to all restic helper pods.
+	helperPodLabel = "ark.heptio.com/restic-helper"
+
+	pollInterval = time.Second
+)
+
+// Backupper backs up pod volumes using restic.
+type Backupper interface {
+	// BackupPodVolume backs up the data in the named volume of pod to the restic repository for
+	// the pod's namespace, returning the ID of the resulting restic snapshot.
+	BackupPodVolume(backup *api.Backup, pod *v1.Pod, volumeName string) (string, error)
+}
+
+// Restorer restores pod volumes using restic.
+type Restorer interface {
+	// RestorePodVolumes waits for the restored pod in namespace to start its InitContainer, then
+	// restores each of the restic snapshots, which are keyed by volume name, into the pod's
+	// volumes. repoNamespace is the namespace the pod was backed up from. It returns an error
+	// for each volume that couldn't be restored.
+	RestorePodVolumes(restore *api.Restore, repoNamespace, namespace, podName string, snapshots map[string]string) []error
+
+	// RestoreClaim replaces the contents of the existing PersistentVolumeClaim claimName in
+	// namespace with the restic snapshot snapshotID. repoNamespace is the namespace the snapshot
+	// was backed up from. The claim shouldn't be in use by any pods.
+	RestoreClaim(restore *api.Restore, repoNamespace, namespace, claimName, snapshotID string) error
+}
+
+// HostVolumeBackupper backs up the data of local and hostPath PersistentVolumes, which is only
+// reachable from their nodes.
+type HostVolumeBackupper interface {
+	// BackupHostVolume backs up the data of the named PersistentVolume, which is at path on
+	// node, to the repository for repoNamespace, returning the ID of the resulting snapshot.
+	BackupHostVolume(backup *api.Backup, repoNamespace, volumeName, node, path string) (string, error)
+}
+
+// HostVolumeRestorer restores the data of local and hostPath PersistentVolumes.
+type HostVolumeRestorer interface {
+	// RestoreHostVolume restores the snapshot snapshotID, from the repository for repoNamespace,
+	// into path on node, which is where the named PersistentVolume keeps its data.
+	RestoreHostVolume(restore *api.Restore, repoNamespace, volumeName, node, path, snapshotID string) error
+}
+
+// Runner backs up and restores pod volumes using restic, and maintains the repositories they're
+// backed up to and the snapshots in them.
+type Runner interface {
+	Backupper
+	Restorer
+	Maintainer
+	SnapshotDeleter
+}
+
+// podRunner implements Runner by running restic in short-lived helper pods on the same node
+// as the pod whose volumes are being backed up or restored. The helper pods mount the kubelet's pods directory from the host to access the volumes' data.
+type podRunner struct {
+	podClient     corev1.PodsGetter
+	pvcClient     corev1.PersistentVolumeClaimsGetter
+	storageConfig api.ObjectStorageProviderConfig
+	namespace     string
+	image         string
+	kopiaImage    string
+	timeout       time.Duration
+	resources     v1.ResourceRequirements
+	limits        uploader.Limits
+}
+
+var _ Runner = &podRunner{}
+
+// NewPodRunner creates a Runner that runs the restic image in helper pods in namespace, storing
+// restic repositories in the bucket described by storageConfig. Each backup or restore of a
+// volume must complete within timeout. The helper pods' containers have resources, and restic's
+// bandwidth is limited to limits. The helper pods that delete kopia snapshots copy kopia from
+// kopiaImage.
+func NewPodRunner(
+	podClient corev1.PodsGetter,
+	pvcClient corev1.PersistentVolumeClaimsGetter,
+	storageConfig api.ObjectStorageProviderConfig,
+	namespace string,
+	image string,
+	kopiaImage string,
+	timeout time.Duration,
+	resources v1.ResourceRequirements,
+	limits uploader.Limits,
+) (Runner, error) {
+	if _, err := RepoIdentifier(storageConfig, namespace); err != nil {
+		return nil, err
+	}
+
+	return &podRunner{
+		podClient:     podClient,
+		pvcClient:     pvcClient,
+		storageConfig: storageConfig,
+		namespace:     namespace,
+		image:         image,
+		kopiaImage:    kopiaImage,
+		timeout:       timeout,
+		resources:     resources,
+		limits:        limits,
+	}, nil
+}
+
+func (r *podRunner) BackupPodVolume(backup *api.Backup, pod *v1.Pod, volumeName string) (string, error) {
+	if backup.Spec.UploaderType != "" && backup.Spec.UploaderType != api.UploaderTypeRestic {
+		return "", fmt.Errorf("the %s uploader needs the node agents, which the server isn't configured to use", backup.Spec.UploaderType)
+	}
+
+	repo, err := RepoIdentifier(r.storageConfig, pod.Namespace)
+	if err != nil {
+		return "", err
+	}
+
+	dir, err := volumeDir(r.pvcClient, pod, volumeName)
+	if err != nil {
+		return "", err
+	}
+
+	// initialize the namespace's repository the first time it's used, then back up the
+	// volume and report the new snapshot's ID in the termination message.
+	script := strings.Join([]string{
+		"set -e",
+		fmt.Sprintf("VOLUME_DIR=$(ls -d %s/%s/volumes/*/%s | head -n 1)", hostPodsDir, pod.UID, dir),
+		`[ -n "$VOLUME_DIR" ]`,
+		"restic snapshots > /dev/null 2>&1 || restic init",
+		fmt.Sprintf(`restic backup --tag backup=%s --tag pod=%s --tag volume=%s "$VOLUME_DIR" > /tmp/backup.log 2>&1 || { cat /tmp/backup.log; exit 1; }`, backup.Name, pod.Name, volumeName),
+		"SNAPSHOT_ID=$(grep -o 'snapshot [0-9a-f]* saved' /tmp/backup.log | cut -d ' ' -f 2)",
+		`[ -n "$SNAPSHOT_ID" ]`,
+		`echo -n "$SNAPSHOT_ID" > /dev/termination-log`,
+	}, "\n")
+
+	glog.V(2).Infof("Backing up volume %s of pod %s/%s using restic", volumeName, pod.Namespace, pod.Name)
+	snapshotID, err := r.run("restic-backup-"+backup.Name, pod.Spec.NodeName, repo, script)
+	if err != nil {
+		return "", fmt.Errorf("error backing up volume %s of pod %s/%s: %v", volumeName, pod.Namespace, pod.Name, err)
+	}
+
+	return snapshotID, nil
+}
+
+func (r *podRunner) RestorePodVolumes(restore *api.Restore, repoNamespace, namespace, podName string, snapshots map[string]string) []error {
+	repo, err := RepoIdentifier(r.storageConfig, repoNamespace)
+	if err != nil {
+		return volumeErrors(snapshots, err)
+	}
+
+	pod, err := waitForInitContainer(r.podClient, namespace, podName, r.timeout)
+	if err != nil {
+		return volumeErrors(snapshots, err)
+	}
+
+	var errs []error
+	for volumeName, ref := range snapshots {
+		uploaderType, snapshotID := parseSnapshotRef(ref)
+		if uploaderType != api.UploaderTypeRestic {
+			errs = append(errs, fmt.Errorf("error restoring volume %s of pod %s/%s: its %s snapshot can only be restored by the node agents", volumeName, namespace, podName, uploaderType))
+			continue
+		}
+
+		dir, err := volumeDir(r.pvcClient, pod, volumeName)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		// restic restores the snapshot under the absolute path it was backed up from, so copy
+		// its contents into the volume, then signal the pod's InitContainer that it's done.
+		script := strings.Join([]string{
+			"set -e",
+			fmt.Sprintf("VOLUME_DIR=$(ls -d %s/%s/volumes/*/%s | head -n 1)", hostPodsDir, pod.UID, dir),
+			`[ -n "$VOLUME_DIR" ]`,
+			fmt.Sprintf("restic restore %s --target /tmp/restore", snapshotID),
+			fmt.Sprintf(`cp -a /tmp/restore%s/*/volumes/*/*/. "$VOLUME_DIR"/`, hostPodsDir),
+			`mkdir -p "$VOLUME_DIR/.ark"`,
+			fmt.Sprintf(`touch "$VOLUME_DIR/.ark/%s"`, restore.UID),
+		}, "\n")
+
+		glog.V(2).Infof("Restoring volume %s of pod %s/%s using restic", volumeName, namespace, podName)
+		if _, err := r.run("restic-restore-"+restore.Name, pod.Spec.NodeName, repo, script); err != nil {
+			errs = append(errs, fmt.Errorf("error restoring volume %s of pod %s/%s: %v", volumeName, namespace, podName, err))
+		}
+	}
+
+	return errs
+}
+
+// volumeErrors returns err once for each of the volumes snapshots are keyed by, for when none of
+// them can be restored.
+func volumeErrors(snapshots map[string]string, err error) []error {
+	errs := make([]error, 0, len(snapshots))
+	for range snapshots {
+		errs = append(errs, err)
+	}
+	return errs
+}
+
+func (r *podRunner) RestoreClaim(restore *api.Restore, repoNamespace, namespace, claimName, snapshotID string) error {
+	return restoreClaim(r.podClient, r, r.image, restore, repoNamespace, namespace, claimName, snapshotID)
+}
+
+// restoreClaim implements Restorer.RestoreClaim by restoring the snapshot, using restorer, into
+// a pod that mounts the claim, whose containers run image.
+func restoreClaim(podClient corev1.PodsGetter, restorer Restorer, image string, restore *api.Restore, repoNamespace, namespace, claimName, snapshotID string) error {
+	pod, err := podClient.Pods(namespace).Create(claimPod(image, restore, namespace, claimName))
+	if err != nil {
+		return fmt.Errorf("error creating pod to restore PersistentVolumeClaim %s/%s into: %v", namespace, claimName, err)
+	}
+	defer func() {
+		if err := podClient.Pods(namespace).Delete(pod.Name, &metav1.DeleteOptions{}); err != nil {
+			glog.Errorf("error deleting pod %s/%s: %v", namespace, pod.Name, err)
+		}
+	}()
+
+	if errs := restorer.RestorePodVolumes(restore, repoNamespace, namespace, pod.Name, map[string]string{claimVolume: snapshotID}); len(errs) > 0 {
+		return errs[0]
+	}
+	return nil
+}
+
+// claimVolume is the name of the volume of the pods that restore existing claims.
+const claimVolume = "claim"
+
+// claimPod returns the spec of a pod that mounts the claim claimName, clears its contents, then
+// waits for a restic snapshot to be restored into it like a restored pod's InitContainer. Its
+// containers run image.
+func claimPod(image string, restore *api.Restore, namespace, claimName string) *v1.Pod {
+	mountPath := "/restores/" + claimVolume
+	volumeMounts := []v1.VolumeMount{{Name: claimVolume, MountPath: mountPath}}
+
+	return &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:    namespace,
+			GenerateName: "restic-restore-" + claimName + "-",
+			Labels: map[string]string{
+				"component":    "ark",
+				helperPodLabel: "true",
+			},
+		},
+		Spec: v1.PodSpec{
+			RestartPolicy: v1.RestartPolicyNever,
+			InitContainers: []v1.Container{
+				{
+					Name:         "clear",
+					Image:        image,
+					Command:      []string{"/bin/sh", "-c", fmt.Sprintf("find %s -mindepth 1 -delete", mountPath)},
+					VolumeMounts: volumeMounts,
+				},
+				{
+					Name:         InitContainer,
+					Image:        image,
+					Command:      []string{"/bin/sh", "-c", fmt.Sprintf("while [ ! -f %s/.ark/%s ]; do sleep 1; done", mountPath, restore.UID)},
+					VolumeMounts: volumeMounts,
+				},
+			},
+			Containers: []v1.Container{
+				{
+					Name:    "done",
+					Image:   image,
+					Command: []string{"/bin/true"},
+				},
+			},
+			Volumes: []v1.Volume{
+				{
+					Name: claimVolume,
+					VolumeSource: v1.VolumeSource{
+						PersistentVolumeClaim: &v1.PersistentVolumeClaimVolumeSource{ClaimName: claimName},
+					},
+				},
+			},
+		},
+	}
+}
+
+// waitForInitContainer waits, for up to timeout, until the restored pod's InitContainer is
+// running, at which point the pod has been scheduled and all of its volumes have been mounted.
+func waitForInitContainer(podClient corev1.PodsGetter, namespace, podName string, timeout time.Duration) (*v1.Pod, error) {
+	var pod *v1.Pod
+
+	err := wait.PollImmediate(pollInterval, timeout, func() (bool, error) {
+		var err error
+		pod, err = podClient.Pods(namespace).Get(podName, metav1.GetOptions{})
+		if err != nil {
+			return false, err
+		}
+
+		for _, status := range pod.Status.InitContainerStatuses {
+			if status.Name == InitContainer && status.State.Running != nil {
+				return true, nil
+			}
+		}
+		return false, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error waiting for pod %s/%s to start its %s init container: %v", namespace, podName, InitContainer, err)
+	}
+
+	return pod, nil
+}
+
+// volumeDir returns the name of the directory, under the kubelet's directory for the volume's
+// plugin, where the named volume of pod is mounted.
+func volumeDir(pvcClient corev1.PersistentVolumeClaimsGetter, pod *v1.Pod, volumeName string) (string, error) {
+	for _, volume := range pod.Spec.Volumes {
+		if volume.Name != volumeName {
+			continue
+		}
+
+		if volume.PersistentVolumeClaim == nil {
+			return volume.Name, nil
+		}
+
+		// PVC-backed volumes are mounted in a directory named after their PV.
+		claimName := volume.PersistentVolumeClaim.ClaimName
+		pvc, err := pvcClient.PersistentVolumeClaims(pod.Namespace).Get(claimName, metav1.GetOptions{})
+		if err != nil {
+			return "", fmt.Errorf("error getting PersistentVolumeClaim %s/%s: %v", pod.Namespace, claimName, err)
+		}
+		if pvc.Spec.VolumeName == "" {
+			return "", fmt.Errorf("PersistentVolumeClaim %s/%s is not bound", pod.Namespace, claimName)
+		}
+		return pvc.Spec.VolumeName, nil
+	}
+
+	return "", fmt.Errorf("pod %s/%s has no volume named %s", pod.Namespace, pod.Name, volumeName)
+}
+
+// run runs script in a restic helper pod on node, or on any node if node is empty, waits for the
+// pod to complete, and returns the pod's termination message.
+func (r *podRunner) run(generateName, node, repo, script string) (string, error) {
+	return r.runPod(r.helperPod(generateName, node, repo, script))
+}
+
+// runPod creates the helper pod pod, waits for it to complete, and returns its termination
+// message.
+func (r *podRunner) runPod(pod *v1.Pod) (string, error) {
+	pod, err := r.podClient.Pods(r.namespace).Create(pod)
+	if err != nil {
+		return "", fmt.Errorf("error creating restic pod: %v", err)
+	}
+	defer func() {
+		if err := r.podClient.Pods(r.namespace).Delete(pod.Name, &metav1.DeleteOptions{}); err != nil {
+			glog.Errorf("error deleting restic pod %s/%s: %v", r.namespace, pod.Name, err)
+		}
+	}()
+
+	err = wait.PollImmediate(pollInterval, r.timeout, func() (bool, error) {
+		pod, err = r.podClient.Pods(r.namespace).Get(pod.Name, metav1.GetOptions{})
+		if err != nil {
+			return false, err
+		}
+		return pod.Status.Phase == v1.PodSucceeded || pod.Status.Phase == v1.PodFailed, nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("error waiting for restic pod %s/%s to complete: %v", r.namespace, pod.Name, err)
+	}
+
+	var message string
+	for _, status := range pod.Status.ContainerStatuses {
+		if status.State.Terminated != nil {
+			message = strings.TrimSpace(status.State.Terminated.Message)
+		}
+	}
+
+	if pod.Status.Phase == v1.PodFailed {
+		return "", fmt.Errorf("restic pod %s/%s failed: %s", r.namespace, pod.Name, message)
+	}
+
+	return message, nil
+}
+
+// helperPod returns the spec of a pod that runs script on node, with restic configured to use
+// the repository repo within r's limits.
+func (r *podRunner) helperPod(generateName, node, repo, script string) *v1.Pod {
+	// restic has no environment variables for its bandwidth limits, so wrap it in a shell
+	// function that adds them to every run.
+	if limitArgs := uploader.ResticLimitArgs(r.limits); len(limitArgs) > 0 {
+		script = fmt.Sprintf(`restic() { command restic %s "$@"; }`, strings.Join(limitArgs, " ")) + "\n" + script
+	}
+
+	env := []v1.EnvVar{
+		{Name: "RESTIC_REPOSITORY", Value: repo},
+		{
+			Name: "RESTIC_PASSWORD",
+			ValueFrom: &v1.EnvVarSource{
+				SecretKeyRef: &v1.SecretKeySelector{
+					LocalObjectReference: v1.LocalObjectReference{Name: CredentialsSecret},
+					Key:                  CredentialsKey,
+				},
+			},
+		},
+	}
+
+	switch {
+	case r.storageConfig.AWS != nil:
+		env = append(env, v1.EnvVar{Name: "AWS_SHARED_CREDENTIALS_FILE", Value: "/credentials/cloud"})
+	case r.storageConfig.GCP != nil:
+		env = append(env, v1.EnvVar{Name: "GOOGLE_APPLICATION_CREDENTIALS", Value: "/credentials/cloud"})
+	case r.storageConfig.Azure != nil:
+		env = append(env,
+			secretEnvVar("AZURE_ACCOUNT_NAME", "AZURE_STORAGE_ACCOUNT_ID"),
+			secretEnvVar("AZURE_ACCOUNT_KEY", "AZURE_STORAGE_KEY"),
+		)
+	}
+
+	return &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:    r.namespace,
+			GenerateName: generateName + "-",
+			Labels: map[string]string{
+				"component":    "ark",
+				helperPodLabel: "true",
+			},
+		},
+		Spec: v1.PodSpec{
+			NodeName:      node,
+			RestartPolicy: v1.RestartPolicyNever,
+			Containers: []v1.Container{
+				{
+					Name:                     "restic",
+					Image:                    r.image,
+					Command:                  []string{"/bin/sh", "-c", script},
+					Env:                      env,
+					Resources:                r.resources,
+					TerminationMessagePolicy: v1.TerminationMessageFallbackToLogsOnError,
+					VolumeMounts: []v1.VolumeMount{
+						{Name: "host-pods", MountPath: hostPodsDir},
+						{Name: "cloud-credentials", MountPath: "/credentials"},
+					},
+				},
+			},
+			Volumes: []v1.Volume{
+				{
+					Name: "host-pods",
+					VolumeSource: v1.VolumeSource{
+						HostPath: &v1.HostPathVolumeSource{Path: kubeletPodsDir},
+					},
+				},
+				{
+					Name: "cloud-credentials",
+					VolumeSource: v1.VolumeSource{
+						Secret: &v1.SecretVolumeSource{SecretName: "cloud-credentials"},
+					},
+				},
+			},
+		},
+	}
+}
+
+// kopiaHelperPod returns the spec of a helper pod, on any node, that runs script with kopia on
+// its PATH. An init container copies kopia from r's kopia image, since the restic image doesn't
+// have it.
+func (r *podRunner) kopiaHelperPod(generateName, repo, script string) *v1.Pod {
+	pod := r.helperPod(generateName, "", repo, "export PATH=/kopia:$PATH\n"+script)
+
+	mount := v1.VolumeMount{Name: "kopia", MountPath: "/kopia"}
+	pod.Spec.InitContainers = []v1.Container{
+		{
+			Name:         "kopia",
+			Image:        r.kopiaImage,
+			Command:      []string{"cp", "/bin/kopia", "/kopia/kopia"},
+			VolumeMounts: []v1.VolumeMount{mount},
+		},
+	}
+	pod.Spec.Containers[0].VolumeMounts = append(pod.Spec.Containers[0].VolumeMounts, mount)
+	pod.Spec.Volumes = append(pod.Spec.Volumes, v1.Volume{
+		Name:         "kopia",
+		VolumeSource: v1.VolumeSource{EmptyDir: &v1.EmptyDirVolumeSource{}},
+	})
+	return pod
+}
+
+// secretEnvVar returns an environment variable named name whose value is read from key in the
+// cloud-credentials secret.
+func secretEnvVar(name, key string) v1.EnvVar {
+	return v1.EnvVar{
+		Name: name,
+		ValueFrom: &v1.EnvVarSource{
+			SecretKeyRef: &v1.SecretKeySelector{
+				LocalObjectReference: v1.LocalObjectReference{Name: "cloud-credentials"},
+				Key:                  key,
+			},
+		},
+	}
+}
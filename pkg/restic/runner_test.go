@@ -0,0 +1,75 @@
+/*
+Copyright 2017 Heptio Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package restic
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/client-go/pkg/api/v1"
+
+	api "github.com/heptio/ark/pkg/apis/ark/v1"
+	"github.com/heptio/ark/pkg/uploader"
+)
+
+func TestHelperPodLimits(t *testing.T) {
+	resources := v1.ResourceRequirements{
+		Limits: v1.ResourceList{
+			v1.ResourceCPU:    resource.MustParse("500m"),
+			v1.ResourceMemory: resource.MustParse("512Mi"),
+		},
+	}
+	r := &podRunner{
+		storageConfig: api.ObjectStorageProviderConfig{CloudProviderConfig: api.CloudProviderConfig{GCP: &api.GCPConfig{}}},
+		namespace:     api.DefaultNamespace,
+		image:         DefaultImage,
+		resources:     resources,
+		limits:        uploader.Limits{UploadKiBps: 1024},
+	}
+
+	pod := r.helperPod("restic-backup-1", "node-1", "gs:bucket:/.ark-restic/ns-1", "restic backup /data")
+	require.Len(t, pod.Spec.Containers, 1)
+	assert.Equal(t, resources, pod.Spec.Containers[0].Resources)
+	assert.Equal(t, []string{"/bin/sh", "-c", "restic() { command restic --limit-upload 1024 \"$@\"; }\nrestic backup /data"}, pod.Spec.Containers[0].Command)
+
+	r.limits = uploader.Limits{}
+	pod = r.helperPod("restic-backup-1", "node-1", "gs:bucket:/.ark-restic/ns-1", "restic backup /data")
+	assert.Equal(t, []string{"/bin/sh", "-c", "restic backup /data"}, pod.Spec.Containers[0].Command)
+}
+
+func TestKopiaHelperPod(t *testing.T) {
+	r := &podRunner{
+		storageConfig: api.ObjectStorageProviderConfig{CloudProviderConfig: api.CloudProviderConfig{GCP: &api.GCPConfig{}}},
+		namespace:     api.DefaultNamespace,
+		image:         DefaultImage,
+		kopiaImage:    DefaultKopiaImage,
+	}
+
+	pod := r.kopiaHelperPod("kopia-delete", "gs:bucket:/.ark-kopia/ns-1", "kopia snapshot delete k123 --delete")
+	assert.Empty(t, pod.Spec.NodeName)
+	require.Len(t, pod.Spec.InitContainers, 1)
+	assert.Equal(t, DefaultKopiaImage, pod.Spec.InitContainers[0].Image)
+	assert.Equal(t, []string{"cp", "/bin/kopia", "/kopia/kopia"}, pod.Spec.InitContainers[0].Command)
+	require.Len(t, pod.Spec.Containers, 1)
+	assert.Equal(t, DefaultImage, pod.Spec.Containers[0].Image)
+	assert.Equal(t, []string{"/bin/sh", "-c", "export PATH=/kopia:$PATH\nkopia snapshot delete k123 --delete"}, pod.Spec.Containers[0].Command)
+	assert.Contains(t, pod.Spec.Containers[0].VolumeMounts, v1.VolumeMount{Name: "kopia", MountPath: "/kopia"})
+	assert.Contains(t, pod.Spec.Volumes, v1.Volume{Name: "kopia", VolumeSource: v1.VolumeSource{EmptyDir: &v1.EmptyDirVolumeSource{}}})
+}
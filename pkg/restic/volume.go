@@ -0,0 +1,214 @@
+/*
+Copyright 2017 Heptio Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package restic
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/types"
+	corev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/pkg/api/v1"
+
+	"github.com/heptio/ark/pkg/uploader"
+)
+
+// VolumePath returns the path, under the kubelet's pods directory as mounted in the node agent's
+// container, of the named volume of pod, which must be running on the agent's node.
+func VolumePath(pvcClient corev1.PersistentVolumeClaimsGetter, pod *v1.Pod, volumeName string) (string, error) {
+	dir, err := volumeDir(pvcClient, pod, volumeName)
+	if err != nil {
+		return "", err
+	}
+
+	matches, err := filepath.Glob(filepath.Join(hostPodsDir, string(pod.UID), "volumes", "*", dir))
+	if err != nil {
+		return "", err
+	}
+	if len(matches) == 0 {
+		return "", fmt.Errorf("volume %s of pod %s/%s isn't mounted on this node", volumeName, pod.Namespace, pod.Name)
+	}
+	path := matches[0]
+
+	// the kubelet mounts PVC-backed volumes on the directory, so if the mount hasn't propagated
+	// into the node agent's container, the directory is empty, and backing it up or restoring
+	// into it would silently lose the volume's data.
+	if isClaimVolume(pod, volumeName) {
+		mounted, err := isMountPoint(path)
+		if err != nil {
+			return "", err
+		}
+		if !mounted {
+			return "", fmt.Errorf("volume %s of pod %s/%s isn't a mount point in the node agent's container; its mount must propagate from the host to %s", volumeName, pod.Namespace, pod.Name, hostPodsDir)
+		}
+	}
+
+	return path, nil
+}
+
+// isClaimVolume returns whether pod's volume volumeName is backed by a PersistentVolumeClaim.
+func isClaimVolume(pod *v1.Pod, volumeName string) bool {
+	for _, volume := range pod.Spec.Volumes {
+		if volume.Name == volumeName {
+			return volume.PersistentVolumeClaim != nil
+		}
+	}
+	return false
+}
+
+// mountInfoFile lists the mount points in the node agent's mount namespace.
+var mountInfoFile = "/proc/self/mountinfo"
+
+// mountPathUnescaper undoes the escaping of whitespace and backslashes in mountInfoFile's paths.
+var mountPathUnescaper = strings.NewReplacer(`\040`, " ", `\011`, "\t", `\012`, "\n", `\134`, `\`)
+
+// isMountPoint returns whether path is a mount point in the node agent's mount namespace.
+func isMountPoint(path string) (bool, error) {
+	data, err := ioutil.ReadFile(mountInfoFile)
+	if err != nil {
+		return false, fmt.Errorf("error reading mount points: %v", err)
+	}
+
+	path = filepath.Clean(path)
+	for _, line := range strings.Split(string(data), "\n") {
+		// the fifth field is the mount point.
+		fields := strings.Fields(line)
+		if len(fields) > 4 && filepath.Clean(mountPathUnescaper.Replace(fields[4])) == path {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// HostVolumePath returns the path, under the host's root directory as mounted in the node agent's
+// container, of path on the host.
+func HostVolumePath(path string) string {
+	return filepath.Join(hostRootDir, path)
+}
+
+// BackupVolume backs up the directory path, using the uploader uploaderType within limits, to
+// the repository repo, which is initialized if it doesn't exist yet, and returns the ID of the
+// resulting snapshot, which is tagged with tags.
+func BackupVolume(uploaderType string, limits uploader.Limits, repo, path string, tags map[string]string) (string, error) {
+	u, err := uploader.New(uploaderType, limits)
+	if err != nil {
+		return "", err
+	}
+	return u.Backup(repo, path, tags)
+}
+
+// VolumeSize returns the total size, in bytes, of the regular files under the directory path.
+func VolumeSize(path string) (int64, error) {
+	var size int64
+	err := filepath.Walk(path, func(_ string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.Mode().IsRegular() {
+			size += info.Size()
+		}
+		return nil
+	})
+	return size, err
+}
+
+// RestoreVolume restores the snapshot snapshotID, using the uploader uploaderType within limits,
+// from the repository repo, into the directory path, then creates the file .ark/<restoreUID> in
+// it to tell the restored pod's InitContainer that the restore is done. If restoreUID is empty,
+// as it is for host paths, which no InitContainer waits for, path is created if needed and no
+// file is. It returns the size of the restored data.
+func RestoreVolume(uploaderType string, limits uploader.Limits, repo, snapshotID, path string, restoreUID types.UID) (int64, error) {
+	u, err := uploader.New(uploaderType, limits)
+	if err != nil {
+		return 0, err
+	}
+
+	if restoreUID == "" {
+		if err := os.MkdirAll(path, 0755); err != nil {
+			return 0, err
+		}
+	}
+
+	// the snapshot is restored into a directory in the volume itself, so that its data is moved
+	// into place by renaming it rather than copying it, and doesn't have to fit in the node
+	// agent's container.
+	staging, err := ioutil.TempDir(path, ".ark-restore-")
+	if err != nil {
+		return 0, err
+	}
+	defer os.RemoveAll(staging)
+
+	restored, err := u.Restore(repo, snapshotID, staging)
+	if err != nil {
+		return 0, err
+	}
+
+	size, err := VolumeSize(restored)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := moveContents(restored, path); err != nil {
+		return 0, fmt.Errorf("error moving restored data into the volume: %v", err)
+	}
+
+	if restoreUID == "" {
+		return size, nil
+	}
+
+	if err := os.MkdirAll(filepath.Join(path, ".ark"), 0755); err != nil {
+		return 0, err
+	}
+	if err := ioutil.WriteFile(filepath.Join(path, ".ark", string(restoreUID)), nil, 0644); err != nil {
+		return 0, err
+	}
+	return size, nil
+}
+
+// moveContents moves the files and directories in the directory src into the directory dst,
+// which is on the same filesystem, merging the directories that are in both and replacing the
+// other files that are.
+func moveContents(src, dst string) error {
+	entries, err := ioutil.ReadDir(src)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		from, to := filepath.Join(src, entry.Name()), filepath.Join(dst, entry.Name())
+
+		if existing, err := os.Lstat(to); err == nil {
+			if entry.IsDir() && existing.IsDir() {
+				if err := moveContents(from, to); err != nil {
+					return err
+				}
+				continue
+			}
+			if err := os.RemoveAll(to); err != nil {
+				return err
+			}
+		}
+
+		if err := os.Rename(from, to); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+/*
+Copyright 2017 Heptio Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package restic
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsMountPoint(t *testing.T) {
+	dir, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	mountInfo := filepath.Join(dir, "mountinfo")
+	require.NoError(t, ioutil.WriteFile(mountInfo, []byte(
+		"22 1 8:1 / / rw,relatime shared:1 - ext4 /dev/sda1 rw\n"+
+			"40 22 8:16 / /host_pods/uid/volumes/kubernetes.io~aws-ebs/pv-1 rw,relatime master:5 - ext4 /dev/xvdb rw\n"+
+			"41 22 0:45 / /host_pods/uid/volumes/kubernetes.io~nfs/my\\040pv rw,relatime master:6 - nfs4 server:/export rw\n",
+	), 0644))
+
+	defer func(orig string) { mountInfoFile = orig }(mountInfoFile)
+	mountInfoFile = mountInfo
+
+	for path, expected := range map[string]bool{
+		"/host_pods/uid/volumes/kubernetes.io~aws-ebs/pv-1":  true,
+		"/host_pods/uid/volumes/kubernetes.io~aws-ebs/pv-1/": true,
+		"/host_pods/uid/volumes/kubernetes.io~nfs/my pv":     true,
+		"/host_pods/uid/volumes/kubernetes.io~aws-ebs/pv-2":  false,
+		"/host_pods/uid/volumes":                             false,
+	} {
+		mounted, err := isMountPoint(path)
+		require.NoError(t, err)
+		assert.Equal(t, expected, mounted, path)
+	}
+}
+
+func TestMoveContents(t *testing.T) {
+	dir, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	write := func(path, contents string) {
+		require.NoError(t, os.MkdirAll(filepath.Dir(filepath.Join(dir, path)), 0755))
+		require.NoError(t, ioutil.WriteFile(filepath.Join(dir, path), []byte(contents), 0644))
+	}
+	write("src/a", "restored a")
+	write("src/sub/b", "restored b")
+	write("src/replaced/c", "restored c")
+	write("dst/sub/kept", "kept")
+	write("dst/sub/b", "old b")
+	write("dst/replaced", "a file")
+
+	require.NoError(t, moveContents(filepath.Join(dir, "src"), filepath.Join(dir, "dst")))
+
+	for path, expected := range map[string]string{
+		"dst/a":          "restored a",
+		"dst/sub/b":      "restored b",
+		"dst/sub/kept":   "kept",
+		"dst/replaced/c": "restored c",
+	} {
+		contents, err := ioutil.ReadFile(filepath.Join(dir, path))
+		require.NoError(t, err)
+		assert.Equal(t, expected, string(contents), path)
+	}
+}
@@ -0,0 +1,532 @@
+/*
+Copyright 2017 Heptio Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package restore
+
+import (
+	"fmt"
+	"path"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/pkg/api/v1"
+
+	api "github.com/heptio/ark/pkg/apis/ark/v1"
+	"github.com/heptio/ark/pkg/client"
+	"github.com/heptio/ark/pkg/util/collections"
+)
+
+// dataOnlyTimeout is how long a data-only restore waits for the pods using the claims it restores
+// to be deleted once their workloads are scaled down, and for claims it replaces to be deleted.
+const dataOnlyTimeout = 5 * time.Minute
+
+// dataOnlyPollInterval is how often a data-only restore checks whether what it's waiting for has
+// happened. It's a variable so tests can shorten it.
+var dataOnlyPollInterval = time.Second
+
+var (
+	claimsResource  = schema.GroupResource{Resource: "persistentvolumeclaims"}
+	volumesResource = schema.GroupResource{Resource: "persistentvolumes"}
+)
+
+// scalableKinds maps the kinds of the workloads that a data-only restore can scale down to their
+// resources.
+var scalableKinds = map[string]string{
+	"Deployment":            "deployments",
+	"ReplicaSet":            "replicasets",
+	"ReplicationController": "replicationcontrollers",
+	"StatefulSet":           "statefulsets",
+}
+
+// dataOnlyClaim is a PersistentVolumeClaim whose data is restored by a data-only restore.
+type dataOnlyClaim struct {
+	// backupNamespace is the claim's namespace in the backup, and namespace is the namespace
+	// it's restored into.
+	backupNamespace string
+	namespace       string
+	name            string
+
+	// volumeName is the name of the PersistentVolume bound to the claim in the backup.
+	volumeName string
+
+	// resticSnapshot is the ID of the claim's restic snapshot if its data was backed up using
+	// restic. Otherwise, its volume is restored from snapshot, the ID of the volume's snapshot.
+	resticSnapshot string
+	snapshot       string
+}
+
+// restoreData restores the data of the claims in the backup extracted to dir into the existing
+// claims of the same names, for a data-only restore, or if plan isn't nil, records what it would
+// do in plan.
+func (kr *kubernetesRestorer) restoreData(
+	dir string,
+	restore *api.Restore,
+	backup *api.Backup,
+	selector *itemSelector,
+	resticVolumes *resticVolumes,
+	plan *Plan,
+	log *restoreLog,
+) (warnings, errors api.RestoreResult) {
+	claims, warnings, err := kr.getDataOnlyClaims(dir, restore, backup, selector, resticVolumes)
+	if err != nil {
+		addArkError(&errors, err)
+		return warnings, errors
+	}
+
+	if plan != nil {
+		for _, claim := range claims {
+			if claim.resticSnapshot != "" {
+				plan.Volumes = append(plan.Volumes, PlanVolume{
+					Source:    PlanVolumeSourceRestic,
+					Resource:  claimsResource.String(),
+					Namespace: claim.namespace,
+					Name:      claim.name,
+					Snapshot:  claim.resticSnapshot,
+				})
+				continue
+			}
+
+			plan.addItem(claimsResource, claim.namespace, claim.name, PlanActionReplace, "")
+			plan.addItem(volumesResource, "", claim.volumeName, PlanActionCreate, "")
+			plan.Volumes = append(plan.Volumes, PlanVolume{
+				Source:   PlanVolumeSourceSnapshot,
+				Resource: volumesResource.String(),
+				Name:     claim.volumeName,
+				Snapshot: claim.snapshot,
+			})
+		}
+		return warnings, errors
+	}
+
+	byNamespace := make(map[string][]dataOnlyClaim)
+	var namespaces []string
+	for _, claim := range claims {
+		if _, found := byNamespace[claim.namespace]; !found {
+			namespaces = append(namespaces, claim.namespace)
+		}
+		byNamespace[claim.namespace] = append(byNamespace[claim.namespace], claim)
+	}
+
+	for _, namespace := range namespaces {
+		w, e := kr.restoreNamespaceData(dir, restore, backup, namespace, byNamespace[namespace], log)
+		merge(&warnings, &w)
+		merge(&errors, &e)
+	}
+
+	return warnings, errors
+}
+
+// getDataOnlyClaims returns the claims in the backup extracted to dir whose data a data-only
+// restore restores, along with warnings about selected claims whose data wasn't backed up.
+func (kr *kubernetesRestorer) getDataOnlyClaims(dir string, restore *api.Restore, backup *api.Backup, selector *itemSelector, resticVolumes *resticVolumes) ([]dataOnlyClaim, api.RestoreResult, error) {
+	var warnings api.RestoreResult
+
+	namespacesPath := path.Join(dir, api.NamespaceScopedDir)
+	nses, err := kr.readDirIfExists(namespacesPath)
+	if err != nil {
+		return nil, warnings, err
+	}
+
+	namespacesToRestore := sets.NewString(restore.Spec.Namespaces...)
+	restorePVs := restore.Spec.RestorePVs == nil || *restore.Spec.RestorePVs
+
+	var claims []dataOnlyClaim
+	for _, ns := range nses {
+		if !namespacesToRestore.Has("*") && !namespacesToRestore.Has(ns) {
+			continue
+		}
+
+		namespace := ns
+		if target, ok := restore.Spec.NamespaceMapping[ns]; ok {
+			namespace = target
+		}
+
+		items, err := kr.readItems(path.Join(namespacesPath, ns, claimsResource.Resource))
+		if err != nil {
+			return nil, warnings, err
+		}
+
+		for _, item := range items {
+			if !selector.matches(claimsResource, namespace, item) {
+				continue
+			}
+
+			claim := dataOnlyClaim{
+				backupNamespace: ns,
+				namespace:       namespace,
+				name:            item.GetName(),
+				resticSnapshot:  resticVolumes.snapshot(ns, item.GetName()),
+			}
+			claim.volumeName, _ = collections.GetString(item.Object, "spec.volumeName")
+
+			if claim.resticSnapshot == "" {
+				if volumeBackup := backup.Status.VolumeBackups[claim.volumeName]; volumeBackup != nil && restorePVs && kr.snapshotRestoresEnabled {
+					claim.snapshot = volumeBackup.SnapshotID
+				}
+			}
+
+			if claim.resticSnapshot == "" && claim.snapshot == "" {
+				addToResult(&warnings, namespace, fmt.Errorf("not restoring the data of PersistentVolumeClaim %s since it has no restic or volume snapshot to restore from", claim.name))
+				continue
+			}
+
+			claims = append(claims, claim)
+		}
+	}
+
+	return claims, warnings, nil
+}
+
+// restoreNamespaceData restores the data of claims, which are all being restored into namespace,
+// scaling down the workloads that use them while it does.
+func (kr *kubernetesRestorer) restoreNamespaceData(dir string, restore *api.Restore, backup *api.Backup, namespace string, claims []dataOnlyClaim, log *restoreLog) (warnings, errors api.RestoreResult) {
+	claimClient, err := kr.dynamicFactory.ClientForGroupVersionKind(
+		schema.GroupVersionKind{Version: "v1", Kind: "PersistentVolumeClaim"},
+		metav1.APIResource{Name: claimsResource.Resource, Namespaced: true},
+		namespace,
+	)
+	if err != nil {
+		addArkError(&errors, err)
+		return warnings, errors
+	}
+
+	// only claims that still exist can have their data restored in place
+	var existing []dataOnlyClaim
+	claimNames := sets.NewString()
+	for _, claim := range claims {
+		if _, err := claimClient.Get(claim.name, metav1.GetOptions{}); err != nil {
+			addToResult(&errors, namespace, fmt.Errorf("error getting PersistentVolumeClaim %s to restore its data into: %v", claim.name, err))
+			continue
+		}
+		existing = append(existing, claim)
+		claimNames.Insert(claim.name)
+	}
+	if len(existing) == 0 {
+		return warnings, errors
+	}
+
+	scaled, err := kr.scaleDownClaimUsers(namespace, claimNames, log)
+	defer func() {
+		for _, err := range kr.scaleUp(scaled, log) {
+			addToResult(&errors, namespace, err)
+		}
+	}()
+	if err != nil {
+		addToResult(&errors, namespace, err)
+		return warnings, errors
+	}
+
+	var replaced []dataOnlyClaim
+	for _, claim := range existing {
+		if claim.resticSnapshot == "" {
+			warning, err := kr.deleteClaim(claimClient, claim, log)
+			if warning != nil {
+				addToResult(&warnings, namespace, warning)
+			}
+			if err != nil {
+				addToResult(&errors, namespace, err)
+				continue
+			}
+			replaced = append(replaced, claim)
+			continue
+		}
+
+		if kr.resticRestorer == nil {
+			addToResult(&errors, namespace, fmt.Errorf("not restoring the data of PersistentVolumeClaim %s since restic isn't configured", claim.name))
+			continue
+		}
+
+		log.Infof("Restoring the data of PersistentVolumeClaim %s/%s using restic", namespace, claim.name)
+		restore.Status.PodVolumeRestoresAttempted++
+		if err := kr.resticRestorer.RestoreClaim(restore, claim.backupNamespace, namespace, claim.name, claim.resticSnapshot); err != nil {
+			addToResult(&errors, namespace, err)
+			restore.Status.PodVolumeRestoresFailed++
+		}
+	}
+
+	if len(replaced) == 0 {
+		return warnings, errors
+	}
+
+	// the replaced claims and their volumes are re-created from the backup, with the volumes
+	// restored from their snapshots
+	selector := &itemSelector{
+		labels:  labels.Nothing(),
+		volumes: sets.NewString(),
+		claims:  sets.NewString(),
+	}
+	backupNamespaces := sets.NewString()
+	for _, claim := range replaced {
+		selector.volumes.Insert(claim.volumeName)
+		selector.claims.Insert(claim.backupNamespace + "/" + claim.name)
+		backupNamespaces.Insert(claim.backupNamespace)
+	}
+
+	hooks := new(hookTracker)
+	w, e := kr.restoreResourceForNamespace("", path.Join(dir, api.ClusterScopedDir, volumesResource.Resource), selector, restore, backup, nil, nil, hooks, nil, log)
+	merge(&warnings, &w)
+	merge(&errors, &e)
+
+	for _, backupNamespace := range backupNamespaces.List() {
+		w, e := kr.restoreResourceForNamespace(namespace, path.Join(dir, api.NamespaceScopedDir, backupNamespace, claimsResource.Resource), selector, restore, backup, nil, nil, hooks, nil, log)
+		merge(&warnings, &w)
+		merge(&errors, &e)
+	}
+
+	return warnings, errors
+}
+
+// deleteClaim deletes claim from the cluster so that it can be re-created bound to a volume
+// restored from snapshot. The claim's current volume is retained rather than deleted with it,
+// and a warning saying so is returned. Its PersistentVolume is only deleted if it has the same
+// name as the volume being restored.
+func (kr *kubernetesRestorer) deleteClaim(claimClient client.Dynamic, claim dataOnlyClaim, log *restoreLog) (error, error) {
+	current, err := claimClient.Get(claim.name, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("error getting PersistentVolumeClaim %s: %v", claim.name, err)
+	}
+
+	volumeClient, err := kr.dynamicFactory.ClientForGroupVersionKind(
+		schema.GroupVersionKind{Version: "v1", Kind: "PersistentVolume"},
+		metav1.APIResource{Name: volumesResource.Resource},
+		"",
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	var warning error
+	currentVolume, _ := collections.GetString(current.Object, "spec.volumeName")
+	if currentVolume != "" {
+		log.Infof("Retaining PersistentVolume %s, the current volume of PersistentVolumeClaim %s/%s", currentVolume, claim.namespace, claim.name)
+		if _, err := volumeClient.Patch(currentVolume, types.MergePatchType, []byte(`{"spec":{"persistentVolumeReclaimPolicy":"Retain"}}`)); err != nil {
+			return nil, fmt.Errorf("error retaining PersistentVolume %s of PersistentVolumeClaim %s: %v", currentVolume, claim.name, err)
+		}
+		warning = fmt.Errorf("the previous volume of PersistentVolumeClaim %s, PersistentVolume %s, was retained; delete it once it's no longer needed", claim.name, currentVolume)
+	}
+
+	log.Infof("Deleting PersistentVolumeClaim %s/%s to restore it bound to a volume restored from snapshot", claim.namespace, claim.name)
+	if err := claimClient.Delete(claim.name, &metav1.DeleteOptions{}); err != nil {
+		return warning, fmt.Errorf("error deleting PersistentVolumeClaim %s: %v", claim.name, err)
+	}
+	if err := waitForDeletion(claimClient, claim.name); err != nil {
+		return warning, fmt.Errorf("error waiting for PersistentVolumeClaim %s to be deleted: %v", claim.name, err)
+	}
+
+	if currentVolume != "" && currentVolume == claim.volumeName {
+		log.Infof("Deleting PersistentVolume %s to restore it from snapshot", currentVolume)
+		if err := volumeClient.Delete(currentVolume, &metav1.DeleteOptions{}); err != nil {
+			return warning, fmt.Errorf("error deleting PersistentVolume %s: %v", currentVolume, err)
+		}
+		if err := waitForDeletion(volumeClient, currentVolume); err != nil {
+			return warning, fmt.Errorf("error waiting for PersistentVolume %s to be deleted: %v", currentVolume, err)
+		}
+		warning = fmt.Errorf("the previous volume of PersistentVolumeClaim %s was retained, but its PersistentVolume, %s, was deleted to restore it from snapshot; delete the volume from your cloud provider once it's no longer needed", claim.name, currentVolume)
+	}
+
+	return warning, nil
+}
+
+// waitForDeletion waits for the item called name to be deleted.
+func waitForDeletion(resourceClient client.Dynamic, name string) error {
+	return wait.PollImmediate(dataOnlyPollInterval, dataOnlyTimeout, func() (bool, error) {
+		_, err := resourceClient.Get(name, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			return true, nil
+		}
+		return false, err
+	})
+}
+
+// scaledWorkload is a workload that a data-only restore scaled down, and its replica count
+// beforehand.
+type scaledWorkload struct {
+	namespace string
+	ref       metav1.OwnerReference
+	replicas  int64
+}
+
+// scaleDownClaimUsers scales down the workloads whose pods in namespace use any of the named
+// claims to zero replicas, then waits for the pods to be deleted. It returns the workloads that it
+// scaled down, which should be scaled back up even if it returns an error. It's an error if a pod
+// using the claims isn't controlled by a Deployment, ReplicaSet, ReplicationController, or
+// StatefulSet.
+func (kr *kubernetesRestorer) scaleDownClaimUsers(namespace string, claimNames sets.String, log *restoreLog) ([]scaledWorkload, error) {
+	users, err := kr.getClaimUsers(namespace, claimNames)
+	if err != nil {
+		return nil, err
+	}
+
+	// find all of the workloads before scaling any down, so nothing is scaled down if some
+	// can't be
+	workloads := make(map[string]metav1.OwnerReference)
+	var keys []string
+	for _, pod := range users {
+		ref, err := kr.getPodWorkload(namespace, pod)
+		if err != nil {
+			return nil, err
+		}
+
+		key := ref.Kind + "/" + ref.Name
+		if _, found := workloads[key]; !found {
+			keys = append(keys, key)
+		}
+		workloads[key] = ref
+	}
+
+	var scaled []scaledWorkload
+	for _, key := range keys {
+		ref := workloads[key]
+
+		workloadClient, err := kr.workloadClient(namespace, ref)
+		if err != nil {
+			return scaled, err
+		}
+
+		obj, err := workloadClient.Get(ref.Name, metav1.GetOptions{})
+		if err != nil {
+			return scaled, fmt.Errorf("error getting %s %s: %v", ref.Kind, ref.Name, err)
+		}
+
+		// replicas defaults to 1
+		replicas := int64(1)
+		switch value, _ := collections.GetValue(obj.Object, "spec.replicas"); value := value.(type) {
+		case int64:
+			replicas = value
+		case float64:
+			replicas = int64(value)
+		}
+
+		log.Infof("Scaling down %s %s/%s from %d replicas to restore the data of its PersistentVolumeClaims", ref.Kind, namespace, ref.Name, replicas)
+		if err := scale(workloadClient, ref.Name, 0); err != nil {
+			return scaled, fmt.Errorf("error scaling down %s %s: %v", ref.Kind, ref.Name, err)
+		}
+		scaled = append(scaled, scaledWorkload{namespace: namespace, ref: ref, replicas: replicas})
+	}
+
+	err = wait.PollImmediate(dataOnlyPollInterval, dataOnlyTimeout, func() (bool, error) {
+		users, err := kr.getClaimUsers(namespace, claimNames)
+		return len(users) == 0, err
+	})
+	if err != nil {
+		return scaled, fmt.Errorf("error waiting for the pods using PersistentVolumeClaims %v to be deleted: %v", claimNames.List(), err)
+	}
+
+	return scaled, nil
+}
+
+// scaleUp scales workloads back up to the replica counts they had before they were scaled down,
+// returning any errors.
+func (kr *kubernetesRestorer) scaleUp(workloads []scaledWorkload, log *restoreLog) []error {
+	var errs []error
+	for _, workload := range workloads {
+		ref := workload.ref
+
+		workloadClient, err := kr.workloadClient(workload.namespace, ref)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		log.Infof("Scaling %s %s/%s back up to %d replicas", ref.Kind, workload.namespace, ref.Name, workload.replicas)
+		if err := scale(workloadClient, ref.Name, workload.replicas); err != nil {
+			errs = append(errs, fmt.Errorf("error scaling %s %s back up to %d replicas: %v", ref.Kind, ref.Name, workload.replicas, err))
+		}
+	}
+	return errs
+}
+
+// getClaimUsers returns the pods in namespace that use any of the named claims.
+func (kr *kubernetesRestorer) getClaimUsers(namespace string, claimNames sets.String) ([]*v1.Pod, error) {
+	pods, err := kr.podClient.Pods(namespace).List(metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("error listing pods: %v", err)
+	}
+
+	var users []*v1.Pod
+	for i := range pods.Items {
+		for _, volume := range pods.Items[i].Spec.Volumes {
+			if volume.PersistentVolumeClaim != nil && claimNames.Has(volume.PersistentVolumeClaim.ClaimName) {
+				users = append(users, &pods.Items[i])
+				break
+			}
+		}
+	}
+	return users, nil
+}
+
+// getPodWorkload returns a reference to the workload that controls pod, which is the Deployment
+// that controls pod's ReplicaSet, if any.
+func (kr *kubernetesRestorer) getPodWorkload(namespace string, pod *v1.Pod) (metav1.OwnerReference, error) {
+	ref := controllerRef(pod.OwnerReferences)
+	if ref == nil || scalableKinds[ref.Kind] == "" {
+		return metav1.OwnerReference{}, fmt.Errorf("pod %s uses a PersistentVolumeClaim whose data is being restored, but can't be stopped since it isn't controlled by a Deployment, ReplicaSet, ReplicationController, or StatefulSet", pod.Name)
+	}
+
+	if ref.Kind != "ReplicaSet" {
+		return *ref, nil
+	}
+
+	replicaSetClient, err := kr.workloadClient(namespace, *ref)
+	if err != nil {
+		return metav1.OwnerReference{}, err
+	}
+	replicaSet, err := replicaSetClient.Get(ref.Name, metav1.GetOptions{})
+	if err != nil {
+		return metav1.OwnerReference{}, fmt.Errorf("error getting ReplicaSet %s: %v", ref.Name, err)
+	}
+
+	if deploymentRef := controllerRef(replicaSet.GetOwnerReferences()); deploymentRef != nil && deploymentRef.Kind == "Deployment" {
+		return *deploymentRef, nil
+	}
+	return *ref, nil
+}
+
+// workloadClient returns a client for the kind of workload ref refers to in namespace.
+func (kr *kubernetesRestorer) workloadClient(namespace string, ref metav1.OwnerReference) (client.Dynamic, error) {
+	gv, err := schema.ParseGroupVersion(ref.APIVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	return kr.dynamicFactory.ClientForGroupVersionKind(
+		gv.WithKind(ref.Kind),
+		metav1.APIResource{Name: scalableKinds[ref.Kind], Namespaced: true},
+		namespace,
+	)
+}
+
+// scale sets the replica count of the workload called name.
+func scale(workloadClient client.Dynamic, name string, replicas int64) error {
+	_, err := workloadClient.Patch(name, types.MergePatchType, []byte(fmt.Sprintf(`{"spec":{"replicas":%d}}`, replicas)))
+	return err
+}
+
+// controllerRef returns the reference to the controller in refs, or nil if there isn't one.
+func controllerRef(refs []metav1.OwnerReference) *metav1.OwnerReference {
+	for i := range refs {
+		if refs[i].Controller != nil && *refs[i].Controller {
+			return &refs[i]
+		}
+	}
+	return nil
+}
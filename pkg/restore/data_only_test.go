@@ -0,0 +1,293 @@
+/*
+Copyright 2017 Heptio Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package restore
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/pkg/api/v1"
+
+	api "github.com/heptio/ark/pkg/apis/ark/v1"
+	"github.com/heptio/ark/pkg/client"
+	. "github.com/heptio/ark/pkg/util/test"
+)
+
+func TestGetDataOnlyClaims(t *testing.T) {
+	claim := func(name, volumeName string) []byte {
+		return []byte(`{"apiVersion": "v1", "kind": "PersistentVolumeClaim", "metadata": {"name": "` + name + `"}, "spec": {"volumeName": "` + volumeName + `"}}`)
+	}
+
+	fileSystem := newFakeFileSystem().
+		WithFile("/backup/namespaces/ns-1/persistentvolumeclaims/restic.json", claim("restic", "pv-1")).
+		WithFile("/backup/namespaces/ns-1/persistentvolumeclaims/snapshot.json", claim("snapshot", "pv-2")).
+		WithFile("/backup/namespaces/ns-1/persistentvolumeclaims/none.json", claim("none", "pv-3")).
+		WithFile("/backup/namespaces/ns-2/persistentvolumeclaims/other.json", claim("other", "pv-4"))
+
+	backup := NewTestBackup().
+		WithSnapshot("pv-2", "snap-2").
+		WithSnapshot("pv-4", "snap-4").
+		Backup
+
+	resticVolumes := &resticVolumes{snapshots: map[string]string{"ns-1/restic": "restic-1"}}
+	selector := &itemSelector{labels: labels.Everything()}
+	falseVal := false
+
+	tests := []struct {
+		name                    string
+		restore                 *api.Restore
+		snapshotRestoresEnabled bool
+		expectedClaims          []dataOnlyClaim
+		expectedWarnings        api.RestoreResult
+	}{
+		{
+			name:                    "claims are restored from restic and volume snapshots",
+			restore:                 NewTestRestore("", "", "").WithRestorePVs(true).WithRestorableNamespace("ns-1").WithMappedNamespace("ns-1", "ns-3").Restore,
+			snapshotRestoresEnabled: true,
+			expectedClaims: []dataOnlyClaim{
+				{backupNamespace: "ns-1", namespace: "ns-3", name: "restic", volumeName: "pv-1", resticSnapshot: "restic-1"},
+				{backupNamespace: "ns-1", namespace: "ns-3", name: "snapshot", volumeName: "pv-2", snapshot: "snap-2"},
+			},
+			expectedWarnings: api.RestoreResult{
+				Namespaces: map[string][]string{
+					"ns-3": {"not restoring the data of PersistentVolumeClaim none since it has no restic or volume snapshot to restore from"},
+				},
+			},
+		},
+		{
+			name:    "volume snapshots aren't used if snapshot restores are disabled",
+			restore: NewTestRestore("", "", "").WithRestorableNamespace("ns-1").Restore,
+			expectedClaims: []dataOnlyClaim{
+				{backupNamespace: "ns-1", namespace: "ns-1", name: "restic", volumeName: "pv-1", resticSnapshot: "restic-1"},
+			},
+			expectedWarnings: api.RestoreResult{
+				Namespaces: map[string][]string{
+					"ns-1": {
+						"not restoring the data of PersistentVolumeClaim none since it has no restic or volume snapshot to restore from",
+						"not restoring the data of PersistentVolumeClaim snapshot since it has no restic or volume snapshot to restore from",
+					},
+				},
+			},
+		},
+		{
+			name: "volume snapshots aren't used if the restore excludes them",
+			restore: func() *api.Restore {
+				restore := NewTestRestore("", "", "").WithRestorableNamespace("ns-2").Restore
+				restore.Spec.RestorePVs = &falseVal
+				return restore
+			}(),
+			snapshotRestoresEnabled: true,
+			expectedWarnings: api.RestoreResult{
+				Namespaces: map[string][]string{
+					"ns-2": {"not restoring the data of PersistentVolumeClaim other since it has no restic or volume snapshot to restore from"},
+				},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			restorer := &kubernetesRestorer{
+				fileSystem:              fileSystem,
+				snapshotRestoresEnabled: test.snapshotRestoresEnabled,
+			}
+
+			claims, warnings, err := restorer.getDataOnlyClaims("/backup", test.restore, backup, selector, resticVolumes)
+			require.NoError(t, err)
+
+			assert.Equal(t, test.expectedClaims, claims)
+			assert.Equal(t, test.expectedWarnings, warnings)
+		})
+	}
+}
+
+func TestScaleDownClaimUsers(t *testing.T) {
+	trueVal := true
+	controller := func(apiVersion, kind, name string) []metav1.OwnerReference {
+		return []metav1.OwnerReference{{APIVersion: apiVersion, Kind: kind, Name: name, Controller: &trueVal}}
+	}
+	pod := func(name, claimName string, owners []metav1.OwnerReference) *v1.Pod {
+		return &v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "ns-1", Name: name, OwnerReferences: owners},
+			Spec: v1.PodSpec{
+				Volumes: []v1.Volume{{
+					Name:         "data",
+					VolumeSource: v1.VolumeSource{PersistentVolumeClaim: &v1.PersistentVolumeClaimVolumeSource{ClaimName: claimName}},
+				}},
+			},
+		}
+	}
+
+	defer func(interval time.Duration) { dataOnlyPollInterval = interval }(dataOnlyPollInterval)
+	dataOnlyPollInterval = time.Millisecond
+
+	tests := []struct {
+		name             string
+		pods             map[string]*v1.Pod
+		expectedScaled   []scaledWorkload
+		expectedPatches  []string
+		expectedErr      bool
+		expectedPodsLeft int
+	}{
+		{
+			name: "deployments and statefulsets are scaled down",
+			pods: map[string]*v1.Pod{
+				"web-1": pod("web-1", "web-data", controller("extensions/v1beta1", "ReplicaSet", "web-abc")),
+				"web-2": pod("web-2", "web-data", controller("extensions/v1beta1", "ReplicaSet", "web-abc")),
+				"db-0":  pod("db-0", "data-db-0", controller("apps/v1beta1", "StatefulSet", "db")),
+			},
+			expectedScaled: []scaledWorkload{
+				{namespace: "ns-1", ref: controller("apps/v1beta1", "Deployment", "web")[0], replicas: 3},
+				{namespace: "ns-1", ref: controller("apps/v1beta1", "StatefulSet", "db")[0], replicas: 1},
+			},
+			expectedPatches: []string{"Deployment/web:0", "StatefulSet/db:0"},
+		},
+		{
+			name: "pods using other claims are left alone",
+			pods: map[string]*v1.Pod{
+				"other": pod("other", "other-data", nil),
+			},
+			expectedPodsLeft: 1,
+		},
+		{
+			name: "pods without scalable controllers are an error",
+			pods: map[string]*v1.Pod{
+				"db-0": pod("db-0", "data-db-0", controller("apps/v1beta1", "StatefulSet", "db")),
+				"bare": pod("bare", "web-data", nil),
+			},
+			expectedErr:      true,
+			expectedPodsLeft: 2,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			podsGetter := &fakePodsGetter{pods: test.pods}
+			workloads := &fakeWorkloadClient{
+				objs: map[string]*unstructured.Unstructured{
+					"ReplicaSet/web-abc": {Object: map[string]interface{}{
+						"metadata": map[string]interface{}{
+							"name":            "web-abc",
+							"ownerReferences": []interface{}{map[string]interface{}{"apiVersion": "apps/v1beta1", "kind": "Deployment", "name": "web", "controller": true}},
+						},
+						"spec": map[string]interface{}{"replicas": int64(3)},
+					}},
+					"Deployment/web": {Object: map[string]interface{}{"spec": map[string]interface{}{"replicas": float64(3)}}},
+					"StatefulSet/db": {Object: map[string]interface{}{"spec": map[string]interface{}{}}},
+				},
+				// scaling a workload down deletes all of its pods
+				onScaleDown: func() {
+					for name, pod := range podsGetter.pods {
+						if len(pod.OwnerReferences) > 0 {
+							delete(podsGetter.pods, name)
+						}
+					}
+				},
+			}
+
+			dynamicFactory := &FakeDynamicFactory{}
+			for _, kind := range []schema.GroupVersionKind{
+				{Group: "extensions", Version: "v1beta1", Kind: "ReplicaSet"},
+				{Group: "apps", Version: "v1beta1", Kind: "Deployment"},
+				{Group: "apps", Version: "v1beta1", Kind: "StatefulSet"},
+			} {
+				resource := metav1.APIResource{Name: scalableKinds[kind.Kind], Namespaced: true}
+				dynamicFactory.On("ClientForGroupVersionKind", kind, resource, "ns-1").Return(&kindClient{kind: kind.Kind, workloads: workloads}, nil)
+			}
+
+			restorer := &kubernetesRestorer{
+				dynamicFactory: dynamicFactory,
+				podClient:      podsGetter,
+			}
+
+			scaled, err := restorer.scaleDownClaimUsers("ns-1", sets.NewString("web-data", "data-db-0"), nil)
+			if test.expectedErr {
+				assert.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+
+			sort.Slice(scaled, func(i, j int) bool { return scaled[i].ref.Kind < scaled[j].ref.Kind })
+			assert.Equal(t, test.expectedScaled, scaled)
+			sort.Strings(workloads.patches)
+			assert.Equal(t, test.expectedPatches, workloads.patches)
+			assert.Len(t, podsGetter.pods, test.expectedPodsLeft)
+
+			workloads.patches = nil
+			assert.Empty(t, restorer.scaleUp(scaled, nil))
+			var expectedScaleUps []string
+			for _, workload := range test.expectedScaled {
+				expectedScaleUps = append(expectedScaleUps, fmt.Sprintf("%s/%s:%d", workload.ref.Kind, workload.ref.Name, workload.replicas))
+			}
+			sort.Strings(workloads.patches)
+			assert.Equal(t, expectedScaleUps, workloads.patches)
+		})
+	}
+}
+
+// fakeWorkloadClient fakes the workloads that data-only restores scale.
+type fakeWorkloadClient struct {
+	// objs holds the workloads by <KIND>/<NAME>.
+	objs map[string]*unstructured.Unstructured
+	// patches records the replica counts workloads were scaled to, as <KIND>/<NAME>:<REPLICAS>.
+	patches     []string
+	onScaleDown func()
+}
+
+// kindClient is a client.Dynamic for the workloads of kind.
+type kindClient struct {
+	client.Dynamic
+	kind      string
+	workloads *fakeWorkloadClient
+}
+
+func (c *kindClient) Get(name string, opts metav1.GetOptions) (*unstructured.Unstructured, error) {
+	obj, found := c.workloads.objs[c.kind+"/"+name]
+	if !found {
+		return nil, fmt.Errorf("%s %s not found", c.kind, name)
+	}
+	return obj, nil
+}
+
+func (c *kindClient) Patch(name string, pt types.PatchType, data []byte) (*unstructured.Unstructured, error) {
+	var patch struct {
+		Spec struct {
+			Replicas int64 `json:"replicas"`
+		} `json:"spec"`
+	}
+	if err := json.Unmarshal(data, &patch); err != nil {
+		return nil, err
+	}
+
+	c.workloads.patches = append(c.workloads.patches, fmt.Sprintf("%s/%s:%d", c.kind, name, patch.Spec.Replicas))
+	if patch.Spec.Replicas == 0 && c.workloads.onScaleDown != nil {
+		c.workloads.onScaleDown()
+	}
+	return c.Get(name, metav1.GetOptions{})
+}
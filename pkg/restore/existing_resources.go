@@ -0,0 +1,129 @@
+/*
+Copyright 2017 Heptio Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package restore
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+
+	api "github.com/heptio/ark/pkg/apis/ark/v1"
+	"github.com/heptio/ark/pkg/client"
+)
+
+var (
+	// replaceTimeout is how long to wait for an existing resource to be deleted before it's
+	// re-created.
+	replaceTimeout = time.Minute
+
+	// replacePollInterval is how often to check whether an existing resource has been deleted.
+	replacePollInterval = time.Second
+)
+
+// ValidateExistingResourcePolicies returns a description of each invalid existing resource policy
+// in spec.
+func ValidateExistingResourcePolicies(spec api.RestoreSpec) []string {
+	var errs []string
+
+	validate := func(policy api.ExistingResourcePolicy, description string) {
+		switch policy {
+		case "", api.ExistingResourcePolicySkip, api.ExistingResourcePolicyPatch, api.ExistingResourcePolicyReplace:
+		default:
+			errs = append(errs, fmt.Sprintf("invalid existing resource policy %q%s", policy, description))
+		}
+	}
+
+	validate(spec.ExistingResourcePolicy, "")
+	for resource, policy := range spec.ExistingResourcePolicyOverrides {
+		validate(policy, " for "+resource)
+	}
+
+	return errs
+}
+
+// existingResourcePolicy returns the policy for existing resources of type groupResource. An
+// override that doesn't specify a group applies to resources of that name in any group, unless
+// there's also an override for the resource's group.
+func existingResourcePolicy(restore *api.Restore, groupResource schema.GroupResource) api.ExistingResourcePolicy {
+	var ungrouped api.ExistingResourcePolicy
+	for resource, policy := range restore.Spec.ExistingResourcePolicyOverrides {
+		override := schema.ParseGroupResource(resource)
+		if override == groupResource {
+			return policy
+		}
+		if override.Group == "" && override.Resource == groupResource.Resource {
+			ungrouped = policy
+		}
+	}
+
+	if ungrouped != "" {
+		return ungrouped
+	}
+	if restore.Spec.ExistingResourcePolicy != "" {
+		return restore.Spec.ExistingResourcePolicy
+	}
+	return api.ExistingResourcePolicySkip
+}
+
+// patchExisting patches the existing copy of obj with obj's contents. A strategic merge patch is
+// used for types that support it, so that lists such as a pod's containers are merged by key;
+// other types, such as custom resources, get a JSON merge patch.
+func patchExisting(resourceClient client.Dynamic, obj *unstructured.Unstructured) error {
+	data, err := json.Marshal(obj.Object)
+	if err != nil {
+		return err
+	}
+
+	_, err = resourceClient.Patch(obj.GetName(), types.StrategicMergePatchType, data)
+	if statusErr, ok := err.(*apierrors.StatusError); ok && statusErr.ErrStatus.Code == http.StatusUnsupportedMediaType {
+		_, err = resourceClient.Patch(obj.GetName(), types.MergePatchType, data)
+	}
+
+	return err
+}
+
+// replaceExisting deletes the existing copy of obj, waits for it to be gone, and creates obj.
+func replaceExisting(resourceClient client.Dynamic, obj *unstructured.Unstructured) error {
+	if err := resourceClient.Delete(obj.GetName(), nil); err != nil && !apierrors.IsNotFound(err) {
+		return fmt.Errorf("error deleting existing copy: %v", err)
+	}
+
+	err := wait.PollImmediate(replacePollInterval, replaceTimeout, func() (bool, error) {
+		_, err := resourceClient.Get(obj.GetName(), metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			return true, nil
+		}
+		return false, err
+	})
+	if err == wait.ErrWaitTimeout {
+		return fmt.Errorf("timed out after %v waiting for existing copy to be deleted", replaceTimeout)
+	}
+	if err != nil {
+		return err
+	}
+
+	_, err = resourceClient.Create(obj)
+	return err
+}
@@ -0,0 +1,243 @@
+/*
+Copyright 2017 Heptio Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package restore
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+
+	api "github.com/heptio/ark/pkg/apis/ark/v1"
+	. "github.com/heptio/ark/pkg/util/test"
+)
+
+func TestValidateExistingResourcePolicies(t *testing.T) {
+	tests := []struct {
+		name     string
+		spec     api.RestoreSpec
+		expected []string
+	}{
+		{
+			name: "empty spec is valid",
+		},
+		{
+			name: "valid policies",
+			spec: api.RestoreSpec{
+				ExistingResourcePolicy: api.ExistingResourcePolicyPatch,
+				ExistingResourcePolicyOverrides: map[string]api.ExistingResourcePolicy{
+					"pods":             api.ExistingResourcePolicyReplace,
+					"deployments.apps": api.ExistingResourcePolicySkip,
+				},
+			},
+		},
+		{
+			name: "invalid policies",
+			spec: api.RestoreSpec{
+				ExistingResourcePolicy: "patch",
+				ExistingResourcePolicyOverrides: map[string]api.ExistingResourcePolicy{
+					"pods": "Delete",
+				},
+			},
+			expected: []string{
+				`invalid existing resource policy "patch"`,
+				`invalid existing resource policy "Delete" for pods`,
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.expected, ValidateExistingResourcePolicies(test.spec))
+		})
+	}
+}
+
+func TestExistingResourcePolicy(t *testing.T) {
+	tests := []struct {
+		name          string
+		policy        api.ExistingResourcePolicy
+		overrides     map[string]api.ExistingResourcePolicy
+		groupResource schema.GroupResource
+		expected      api.ExistingResourcePolicy
+	}{
+		{
+			name:          "defaults to skip",
+			groupResource: schema.GroupResource{Resource: "pods"},
+			expected:      api.ExistingResourcePolicySkip,
+		},
+		{
+			name:          "restore policy is used without overrides",
+			policy:        api.ExistingResourcePolicyPatch,
+			groupResource: schema.GroupResource{Resource: "pods"},
+			expected:      api.ExistingResourcePolicyPatch,
+		},
+		{
+			name:   "override for another resource is ignored",
+			policy: api.ExistingResourcePolicyPatch,
+			overrides: map[string]api.ExistingResourcePolicy{
+				"configmaps": api.ExistingResourcePolicyReplace,
+			},
+			groupResource: schema.GroupResource{Resource: "pods"},
+			expected:      api.ExistingResourcePolicyPatch,
+		},
+		{
+			name: "override without group matches any group",
+			overrides: map[string]api.ExistingResourcePolicy{
+				"deployments": api.ExistingResourcePolicyReplace,
+			},
+			groupResource: schema.GroupResource{Group: "apps", Resource: "deployments"},
+			expected:      api.ExistingResourcePolicyReplace,
+		},
+		{
+			name: "override with group takes precedence over override without group",
+			overrides: map[string]api.ExistingResourcePolicy{
+				"deployments":      api.ExistingResourcePolicyReplace,
+				"deployments.apps": api.ExistingResourcePolicyPatch,
+			},
+			groupResource: schema.GroupResource{Group: "apps", Resource: "deployments"},
+			expected:      api.ExistingResourcePolicyPatch,
+		},
+		{
+			name: "override for another group is ignored",
+			overrides: map[string]api.ExistingResourcePolicy{
+				"deployments.extensions": api.ExistingResourcePolicyReplace,
+			},
+			groupResource: schema.GroupResource{Group: "apps", Resource: "deployments"},
+			expected:      api.ExistingResourcePolicySkip,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			restore := NewDefaultTestRestore().Restore
+			restore.Spec.ExistingResourcePolicy = test.policy
+			restore.Spec.ExistingResourcePolicyOverrides = test.overrides
+
+			assert.Equal(t, test.expected, existingResourcePolicy(restore, test.groupResource))
+		})
+	}
+}
+
+func TestPatchExisting(t *testing.T) {
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{"metadata": map[string]interface{}{"name": "foo"}}}
+	data := []byte(`{"metadata":{"name":"foo"}}`)
+	unsupported := &apierrors.StatusError{ErrStatus: metav1.Status{Code: http.StatusUnsupportedMediaType}}
+
+	tests := []struct {
+		name          string
+		strategicErr  error
+		expectMerge   bool
+		mergeErr      error
+		expectedError error
+	}{
+		{
+			name: "strategic merge patch succeeds",
+		},
+		{
+			name:          "strategic merge patch error is returned",
+			strategicErr:  errors.New("bad patch"),
+			expectedError: errors.New("bad patch"),
+		},
+		{
+			name:         "unsupported strategic merge patch falls back to merge patch",
+			strategicErr: unsupported,
+			expectMerge:  true,
+		},
+		{
+			name:          "merge patch error is returned",
+			strategicErr:  unsupported,
+			expectMerge:   true,
+			mergeErr:      errors.New("bad patch"),
+			expectedError: errors.New("bad patch"),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			resourceClient := &FakeDynamicClient{}
+			defer resourceClient.AssertExpectations(t)
+
+			resourceClient.On("Patch", "foo", types.StrategicMergePatchType, data).Return(obj, test.strategicErr)
+			if test.expectMerge {
+				resourceClient.On("Patch", "foo", types.MergePatchType, data).Return(obj, test.mergeErr)
+			}
+
+			assert.Equal(t, test.expectedError, patchExisting(resourceClient, obj))
+		})
+	}
+}
+
+func TestReplaceExisting(t *testing.T) {
+	defer func(timeout, interval time.Duration) {
+		replaceTimeout, replacePollInterval = timeout, interval
+	}(replaceTimeout, replacePollInterval)
+	replaceTimeout, replacePollInterval = 50*time.Millisecond, time.Millisecond
+
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{"metadata": map[string]interface{}{"name": "foo"}}}
+	notFound := apierrors.NewNotFound(schema.GroupResource{Resource: "pods"}, "foo")
+
+	t.Run("existing copy is deleted and obj is created", func(t *testing.T) {
+		resourceClient := &FakeDynamicClient{}
+		defer resourceClient.AssertExpectations(t)
+
+		resourceClient.On("Delete", "foo", (*metav1.DeleteOptions)(nil)).Return(nil)
+		resourceClient.On("Get", "foo", metav1.GetOptions{}).Return(obj, nil).Twice()
+		resourceClient.On("Get", "foo", metav1.GetOptions{}).Return((*unstructured.Unstructured)(nil), notFound)
+		resourceClient.On("Create", obj).Return(obj, nil)
+
+		assert.NoError(t, replaceExisting(resourceClient, obj))
+	})
+
+	t.Run("delete error is returned", func(t *testing.T) {
+		resourceClient := &FakeDynamicClient{}
+		defer resourceClient.AssertExpectations(t)
+
+		resourceClient.On("Delete", "foo", (*metav1.DeleteOptions)(nil)).Return(errors.New("forbidden"))
+
+		assert.EqualError(t, replaceExisting(resourceClient, obj), "error deleting existing copy: forbidden")
+	})
+
+	t.Run("times out if the existing copy isn't deleted", func(t *testing.T) {
+		resourceClient := &FakeDynamicClient{}
+		defer resourceClient.AssertExpectations(t)
+
+		resourceClient.On("Delete", "foo", (*metav1.DeleteOptions)(nil)).Return(nil)
+		resourceClient.On("Get", "foo", metav1.GetOptions{}).Return(obj, nil)
+
+		assert.EqualError(t, replaceExisting(resourceClient, obj), "timed out after 50ms waiting for existing copy to be deleted")
+	})
+
+	t.Run("create error is returned", func(t *testing.T) {
+		resourceClient := &FakeDynamicClient{}
+		defer resourceClient.AssertExpectations(t)
+
+		resourceClient.On("Delete", "foo", (*metav1.DeleteOptions)(nil)).Return(notFound)
+		resourceClient.On("Get", "foo", metav1.GetOptions{}).Return((*unstructured.Unstructured)(nil), notFound)
+		resourceClient.On("Create", obj).Return((*unstructured.Unstructured)(nil), errors.New("invalid"))
+
+		assert.EqualError(t, replaceExisting(resourceClient, obj), "invalid")
+	})
+}
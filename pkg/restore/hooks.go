@@ -0,0 +1,303 @@
+/*
+Copyright 2017 Heptio Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package restore
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/pkg/api/v1"
+
+	api "github.com/heptio/ark/pkg/apis/ark/v1"
+	"github.com/heptio/ark/pkg/restic"
+	"github.com/heptio/ark/pkg/util/collections"
+)
+
+const (
+	defaultHookExecTimeout = 30 * time.Second
+	defaultHookWaitTimeout = 5 * time.Minute
+)
+
+// hookPollInterval is how often a pod is checked while waiting for the container an exec hook
+// runs in to start. It's a variable so tests can shorten it.
+var hookPollInterval = time.Second
+
+// ValidateHooks returns a description of each problem with hooks.
+func ValidateHooks(hooks api.RestoreHooks) []string {
+	var errs []string
+
+	for i, spec := range hooks.Resources {
+		name := spec.Name
+		if name == "" {
+			name = fmt.Sprintf("#%d", i)
+		}
+
+		if _, err := metav1.LabelSelectorAsSelector(spec.LabelSelector); err != nil {
+			errs = append(errs, fmt.Sprintf("hook spec %s has an invalid label selector: %v", name, err))
+		}
+
+		for j, hook := range spec.PostHooks {
+			switch {
+			case (hook.Init == nil) == (hook.Exec == nil):
+				errs = append(errs, fmt.Sprintf("hook %d of hook spec %s must have exactly one of init and exec", j, name))
+			case hook.Init != nil && len(hook.Init.InitContainers) == 0:
+				errs = append(errs, fmt.Sprintf("init hook %d of hook spec %s has no init containers", j, name))
+			case hook.Exec != nil && len(hook.Exec.Command) == 0:
+				errs = append(errs, fmt.Sprintf("exec hook %d of hook spec %s has no command", j, name))
+			case hook.Exec != nil && hook.Exec.OnError != "" && hook.Exec.OnError != api.HookErrorModeContinue && hook.Exec.OnError != api.HookErrorModeFail:
+				errs = append(errs, fmt.Sprintf("exec hook %d of hook spec %s has invalid onError %q", j, name, hook.Exec.OnError))
+			}
+		}
+	}
+
+	return errs
+}
+
+// podHook is a hook that applies to a pod, along with the name of the spec it came from.
+type podHook struct {
+	specName string
+	hook     api.RestoreResourceHook
+}
+
+// getPodHooks returns the hooks from restore that apply to a pod with the given labels that's
+// being restored into namespace.
+func getPodHooks(restore *api.Restore, namespace string, podLabels labels.Set) ([]podHook, error) {
+	var hooks []podHook
+
+	for _, spec := range restore.Spec.Hooks.Resources {
+		namespaces := collections.NewIncludesExcludes().Includes(spec.IncludedNamespaces...).Excludes(spec.ExcludedNamespaces...)
+		if len(spec.IncludedNamespaces) == 0 {
+			namespaces.Includes("*")
+		}
+		if !namespaces.ShouldInclude(namespace) {
+			continue
+		}
+
+		if spec.LabelSelector != nil {
+			selector, err := metav1.LabelSelectorAsSelector(spec.LabelSelector)
+			if err != nil {
+				return nil, fmt.Errorf("invalid label selector in hook spec %s: %v", spec.Name, err)
+			}
+			if !selector.Matches(podLabels) {
+				continue
+			}
+		}
+
+		for _, hook := range spec.PostHooks {
+			hooks = append(hooks, podHook{specName: spec.Name, hook: hook})
+		}
+	}
+
+	return hooks, nil
+}
+
+// addInitContainers adds the init containers from hooks' init hooks to pod. They're added after
+// the restic init container, if there is one, so they run once the pod's volume data has been
+// restored, and before the pod's own init containers.
+func addInitContainers(pod *unstructured.Unstructured, hooks []podHook) error {
+	var hookContainers []interface{}
+	for _, h := range hooks {
+		if h.hook.Init == nil {
+			continue
+		}
+		for _, container := range h.hook.Init.InitContainers {
+			obj, err := toUnstructuredMap(container)
+			if err != nil {
+				return err
+			}
+			hookContainers = append(hookContainers, obj)
+		}
+	}
+	if len(hookContainers) == 0 {
+		return nil
+	}
+
+	spec, err := collections.GetMap(pod.Object, "spec")
+	if err != nil {
+		return err
+	}
+
+	existing, _ := collections.GetSlice(spec, "initContainers")
+
+	var initContainers []interface{}
+	if len(existing) > 0 {
+		if first, ok := existing[0].(map[string]interface{}); ok && first["name"] == restic.InitContainer {
+			initContainers = append(initContainers, first)
+			existing = existing[1:]
+		}
+	}
+	initContainers = append(initContainers, hookContainers...)
+	initContainers = append(initContainers, existing...)
+
+	spec["initContainers"] = initContainers
+
+	return nil
+}
+
+func toUnstructuredMap(obj interface{}) (map[string]interface{}, error) {
+	data, err := json.Marshal(obj)
+	if err != nil {
+		return nil, err
+	}
+
+	var res map[string]interface{}
+	if err := json.Unmarshal(data, &res); err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// hookTracker runs the exec hooks of restored pods in the background, so that the restore can
+// continue while pods start, and collects their results.
+type hookTracker struct {
+	wg       sync.WaitGroup
+	lock     sync.Mutex
+	warnings api.RestoreResult
+	errors   api.RestoreResult
+	// attempted and failed count the hooks that were run, and that failed.
+	attempted int
+	failed    int
+}
+
+// wait waits for all of the hooks to finish, and returns their warnings and errors.
+func (t *hookTracker) wait() (api.RestoreResult, api.RestoreResult) {
+	t.wg.Wait()
+	return t.warnings, t.errors
+}
+
+// runExecHooks runs the exec hooks in hooks in the named pod, in order, in the background. A
+// failed hook whose OnError is Fail is recorded as an error and stops the pod's remaining
+// hooks from running; other failed hooks are recorded as warnings.
+func (kr *kubernetesRestorer) runExecHooks(tracker *hookTracker, namespace, podName string, hooks []podHook) {
+	var execHooks []podHook
+	for _, h := range hooks {
+		if h.hook.Exec != nil {
+			execHooks = append(execHooks, h)
+		}
+	}
+	if len(execHooks) == 0 {
+		return
+	}
+
+	tracker.wg.Add(1)
+	go func() {
+		defer tracker.wg.Done()
+
+		for _, h := range execHooks {
+			glog.Infof("Running exec hook %s in pod %s/%s", h.specName, namespace, podName)
+
+			err := kr.runExecHook(namespace, podName, h.hook.Exec)
+
+			tracker.lock.Lock()
+			tracker.attempted++
+			if err == nil {
+				tracker.lock.Unlock()
+				continue
+			}
+			tracker.failed++
+
+			err = fmt.Errorf("exec hook %s failed in pod %s: %v", h.specName, podName, err)
+			glog.Error(err)
+
+			if h.hook.Exec.OnError == api.HookErrorModeContinue {
+				addToResult(&tracker.warnings, namespace, err)
+				tracker.lock.Unlock()
+				continue
+			}
+			addToResult(&tracker.errors, namespace, err)
+			tracker.lock.Unlock()
+			return
+		}
+	}()
+}
+
+// runExecHook waits for the hook's container to be running, then runs its command.
+func (kr *kubernetesRestorer) runExecHook(namespace, podName string, hook *api.ExecRestoreHook) error {
+	if kr.podCommandExecutor == nil {
+		return errors.New("Ark server is not configured to run commands in pods")
+	}
+
+	waitTimeout := hook.WaitTimeout.Duration
+	if waitTimeout == 0 {
+		waitTimeout = defaultHookWaitTimeout
+	}
+
+	container, err := kr.waitForContainer(namespace, podName, hook.Container, waitTimeout)
+	if err != nil {
+		return err
+	}
+
+	execTimeout := hook.ExecTimeout.Duration
+	if execTimeout == 0 {
+		execTimeout = defaultHookExecTimeout
+	}
+
+	return kr.podCommandExecutor.Exec(namespace, podName, container, hook.Command, execTimeout)
+}
+
+// waitForContainer waits for the named container of a pod, or its first container if container is
+// empty, to be running, and returns the container's name.
+func (kr *kubernetesRestorer) waitForContainer(namespace, podName, container string, timeout time.Duration) (string, error) {
+	err := wait.PollImmediate(hookPollInterval, timeout, func() (bool, error) {
+		pod, err := kr.podClient.Pods(namespace).Get(podName, metav1.GetOptions{})
+		if err != nil {
+			return false, err
+		}
+
+		if pod.Status.Phase == v1.PodSucceeded || pod.Status.Phase == v1.PodFailed {
+			return false, fmt.Errorf("pod is %s", pod.Status.Phase)
+		}
+
+		if container == "" {
+			if len(pod.Spec.Containers) == 0 {
+				return false, errors.New("pod has no containers")
+			}
+			container = pod.Spec.Containers[0].Name
+		}
+
+		found := false
+		for _, c := range pod.Spec.Containers {
+			found = found || c.Name == container
+		}
+		if !found {
+			return false, fmt.Errorf("pod has no container %s", container)
+		}
+
+		for _, status := range pod.Status.ContainerStatuses {
+			if status.Name == container {
+				return status.State.Running != nil, nil
+			}
+		}
+
+		return false, nil
+	})
+	if err == wait.ErrWaitTimeout {
+		return "", fmt.Errorf("timed out after %v waiting for container %s to be running", timeout, container)
+	}
+
+	return container, err
+}
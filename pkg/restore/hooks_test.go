@@ -0,0 +1,342 @@
+/*
+Copyright 2017 Heptio Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package restore
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	corev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/pkg/api/v1"
+
+	api "github.com/heptio/ark/pkg/apis/ark/v1"
+	"github.com/heptio/ark/pkg/restic"
+)
+
+func TestValidateHooks(t *testing.T) {
+	tests := []struct {
+		name     string
+		hooks    api.RestoreHooks
+		expected []string
+	}{
+		{
+			name: "valid hooks",
+			hooks: api.RestoreHooks{Resources: []api.RestoreResourceHookSpec{{
+				Name: "db",
+				PostHooks: []api.RestoreResourceHook{
+					{Init: &api.InitRestoreHook{InitContainers: []v1.Container{{Name: "init", Image: "busybox"}}}},
+					{Exec: &api.ExecRestoreHook{Command: []string{"/bin/recover"}, OnError: api.HookErrorModeContinue}},
+				},
+			}}},
+		},
+		{
+			name: "invalid hooks",
+			hooks: api.RestoreHooks{Resources: []api.RestoreResourceHookSpec{{
+				LabelSelector: &metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{{Key: "a", Operator: "Foo"}}},
+				PostHooks: []api.RestoreResourceHook{
+					{},
+					{Init: &api.InitRestoreHook{}, Exec: &api.ExecRestoreHook{}},
+					{Init: &api.InitRestoreHook{}},
+					{Exec: &api.ExecRestoreHook{}},
+					{Exec: &api.ExecRestoreHook{Command: []string{"true"}, OnError: "Ignore"}},
+				},
+			}}},
+			expected: []string{
+				`hook spec #0 has an invalid label selector: "Foo" is not a valid pod selector operator`,
+				"hook 0 of hook spec #0 must have exactly one of init and exec",
+				"hook 1 of hook spec #0 must have exactly one of init and exec",
+				"init hook 2 of hook spec #0 has no init containers",
+				"exec hook 3 of hook spec #0 has no command",
+				`exec hook 4 of hook spec #0 has invalid onError "Ignore"`,
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.expected, ValidateHooks(test.hooks))
+		})
+	}
+}
+
+func TestGetPodHooks(t *testing.T) {
+	hook := func(command string) api.RestoreResourceHook {
+		return api.RestoreResourceHook{Exec: &api.ExecRestoreHook{Command: []string{command}}}
+	}
+
+	restore := &api.Restore{Spec: api.RestoreSpec{Hooks: api.RestoreHooks{Resources: []api.RestoreResourceHookSpec{
+		{
+			Name:      "all",
+			PostHooks: []api.RestoreResourceHook{hook("all")},
+		},
+		{
+			Name:               "ns-1",
+			IncludedNamespaces: []string{"ns-1"},
+			PostHooks:          []api.RestoreResourceHook{hook("ns-1")},
+		},
+		{
+			Name:               "not-ns-1",
+			ExcludedNamespaces: []string{"ns-1"},
+			PostHooks:          []api.RestoreResourceHook{hook("not-ns-1")},
+		},
+		{
+			Name:          "db",
+			LabelSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "db"}},
+			PostHooks:     []api.RestoreResourceHook{hook("db-1"), hook("db-2")},
+		},
+	}}}}
+
+	tests := []struct {
+		name      string
+		namespace string
+		labels    labels.Set
+		expected  []string
+	}{
+		{
+			name:      "hooks without a selector apply to pods in included namespaces",
+			namespace: "ns-1",
+			expected:  []string{"all", "ns-1"},
+		},
+		{
+			name:      "excluded namespaces are respected",
+			namespace: "ns-2",
+			expected:  []string{"all", "not-ns-1"},
+		},
+		{
+			name:      "label selectors are respected",
+			namespace: "ns-2",
+			labels:    labels.Set{"app": "db"},
+			expected:  []string{"all", "not-ns-1", "db-1", "db-2"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			hooks, err := getPodHooks(restore, test.namespace, test.labels)
+			require.NoError(t, err)
+
+			var commands []string
+			for _, h := range hooks {
+				commands = append(commands, h.hook.Exec.Command[0])
+			}
+			assert.Equal(t, test.expected, commands)
+		})
+	}
+}
+
+func TestAddInitContainers(t *testing.T) {
+	hooks := []podHook{
+		{hook: api.RestoreResourceHook{Init: &api.InitRestoreHook{InitContainers: []v1.Container{{Name: "hook-1", Image: "busybox"}}}}},
+		{hook: api.RestoreResourceHook{Exec: &api.ExecRestoreHook{Command: []string{"true"}}}},
+		{hook: api.RestoreResourceHook{Init: &api.InitRestoreHook{InitContainers: []v1.Container{{Name: "hook-2", Image: "busybox"}}}}},
+	}
+
+	tests := []struct {
+		name     string
+		existing []interface{}
+		expected []string
+	}{
+		{
+			name:     "pod without init containers",
+			expected: []string{"hook-1", "hook-2"},
+		},
+		{
+			name: "hooks run after the restic init container and before the pod's own",
+			existing: []interface{}{
+				map[string]interface{}{"name": restic.InitContainer},
+				map[string]interface{}{"name": "own"},
+			},
+			expected: []string{restic.InitContainer, "hook-1", "hook-2", "own"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			spec := map[string]interface{}{}
+			if test.existing != nil {
+				spec["initContainers"] = test.existing
+			}
+			pod := &unstructured.Unstructured{Object: map[string]interface{}{"spec": spec}}
+
+			require.NoError(t, addInitContainers(pod, hooks))
+
+			var names []string
+			for _, c := range spec["initContainers"].([]interface{}) {
+				names = append(names, c.(map[string]interface{})["name"].(string))
+			}
+			assert.Equal(t, test.expected, names)
+		})
+	}
+}
+
+type fakePodsGetter struct {
+	pods map[string]*v1.Pod
+}
+
+func (g *fakePodsGetter) Pods(namespace string) corev1.PodInterface {
+	return &fakePodClient{getter: g}
+}
+
+type fakePodClient struct {
+	corev1.PodInterface
+	getter *fakePodsGetter
+}
+
+func (c *fakePodClient) Get(name string, opts metav1.GetOptions) (*v1.Pod, error) {
+	if pod, ok := c.getter.pods[name]; ok {
+		return pod, nil
+	}
+	return nil, apierrors.NewNotFound(schema.GroupResource{Resource: "pods"}, name)
+}
+
+func (c *fakePodClient) List(opts metav1.ListOptions) (*v1.PodList, error) {
+	list := &v1.PodList{}
+	for _, pod := range c.getter.pods {
+		list.Items = append(list.Items, *pod)
+	}
+	return list, nil
+}
+
+type execCall struct {
+	pod       string
+	container string
+	command   string
+}
+
+type fakeExecutor struct {
+	lock   sync.Mutex
+	calls  []execCall
+	errors map[string]error
+}
+
+func (e *fakeExecutor) Exec(namespace, pod, container string, command []string, timeout time.Duration) error {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	e.calls = append(e.calls, execCall{pod: pod, container: container, command: command[0]})
+	return e.errors[command[0]]
+}
+
+func TestRunExecHooks(t *testing.T) {
+	hookPollInterval = time.Millisecond
+
+	runningPod := func(name string) *v1.Pod {
+		return &v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "ns-1", Name: name},
+			Spec:       v1.PodSpec{Containers: []v1.Container{{Name: "app"}, {Name: "sidecar"}}},
+			Status: v1.PodStatus{
+				Phase: v1.PodRunning,
+				ContainerStatuses: []v1.ContainerStatus{
+					{Name: "app", State: v1.ContainerState{Running: &v1.ContainerStateRunning{}}},
+					{Name: "sidecar", State: v1.ContainerState{Waiting: &v1.ContainerStateWaiting{}}},
+				},
+			},
+		}
+	}
+
+	exec := func(container, command string, onError api.HookErrorMode) podHook {
+		return podHook{
+			specName: "spec-1",
+			hook: api.RestoreResourceHook{Exec: &api.ExecRestoreHook{
+				Container:   container,
+				Command:     []string{command},
+				OnError:     onError,
+				WaitTimeout: metav1.Duration{Duration: 20 * time.Millisecond},
+			}},
+		}
+	}
+
+	tests := []struct {
+		name             string
+		hooks            []podHook
+		execErrors       map[string]error
+		expectedCalls    []execCall
+		expectedWarnings []string
+		expectedErrors   []string
+		expectedAttempts int
+		expectedFailures int
+	}{
+		{
+			name:             "hooks run in the pod's first container by default",
+			hooks:            []podHook{exec("", "first", ""), exec("app", "second", "")},
+			expectedCalls:    []execCall{{"pod-1", "app", "first"}, {"pod-1", "app", "second"}},
+			expectedAttempts: 2,
+		},
+		{
+			name:             "failed hook that continues is a warning",
+			hooks:            []podHook{exec("", "first", api.HookErrorModeContinue), exec("", "second", "")},
+			execErrors:       map[string]error{"first": errors.New("exit status 1")},
+			expectedCalls:    []execCall{{"pod-1", "app", "first"}, {"pod-1", "app", "second"}},
+			expectedWarnings: []string{"exec hook spec-1 failed in pod pod-1: exit status 1"},
+			expectedAttempts: 2,
+			expectedFailures: 1,
+		},
+		{
+			name:             "failed hook that fails is an error and stops later hooks",
+			hooks:            []podHook{exec("", "first", api.HookErrorModeFail), exec("", "second", "")},
+			execErrors:       map[string]error{"first": errors.New("exit status 1")},
+			expectedCalls:    []execCall{{"pod-1", "app", "first"}},
+			expectedErrors:   []string{"exec hook spec-1 failed in pod pod-1: exit status 1"},
+			expectedAttempts: 1,
+			expectedFailures: 1,
+		},
+		{
+			name:             "container that doesn't start times out",
+			hooks:            []podHook{exec("sidecar", "first", "")},
+			expectedErrors:   []string{"exec hook spec-1 failed in pod pod-1: timed out after 20ms waiting for container sidecar to be running"},
+			expectedAttempts: 1,
+			expectedFailures: 1,
+		},
+		{
+			name:             "missing container is an error",
+			hooks:            []podHook{exec("missing", "first", "")},
+			expectedErrors:   []string{"exec hook spec-1 failed in pod pod-1: pod has no container missing"},
+			expectedAttempts: 1,
+			expectedFailures: 1,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			executor := &fakeExecutor{errors: test.execErrors}
+			restorer := &kubernetesRestorer{
+				podClient:          &fakePodsGetter{pods: map[string]*v1.Pod{"pod-1": runningPod("pod-1")}},
+				podCommandExecutor: executor,
+			}
+
+			tracker := new(hookTracker)
+			restorer.runExecHooks(tracker, "ns-1", "pod-1", test.hooks)
+			warnings, errors := tracker.wait()
+
+			assert.Equal(t, test.expectedCalls, executor.calls)
+			assert.Equal(t, test.expectedWarnings, warnings.Namespaces["ns-1"])
+			assert.Equal(t, test.expectedErrors, errors.Namespaces["ns-1"])
+			assert.Equal(t, test.expectedAttempts, tracker.attempted)
+			assert.Equal(t, test.expectedFailures, tracker.failed)
+		})
+	}
+}
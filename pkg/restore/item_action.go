@@ -0,0 +1,93 @@
+/*
+Copyright 2017 Heptio Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package restore
+
+import (
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	api "github.com/heptio/ark/pkg/apis/ark/v1"
+	"github.com/heptio/ark/pkg/util/itemaction"
+)
+
+// ItemAction is an extension point that lets code outside of Ark modify individual items while
+// they're restored (e.g. to rewrite references to resources that differ in the target cluster), or
+// skip them.
+type ItemAction interface {
+	// AppliesTo returns a ResourceSelector describing the items the action is executed on.
+	AppliesTo() (ResourceSelector, error)
+
+	// Execute is invoked on each item the action applies to, after it's been prepared for the
+	// target cluster and before it's created. It may modify item in place, and returns whether to
+	// skip the item. If an error is returned, the item isn't restored.
+	Execute(item *unstructured.Unstructured, restore *api.Restore) (bool, error)
+}
+
+// ResourceSelector selects the items an ItemAction applies to.
+type ResourceSelector = itemaction.ResourceSelector
+
+var itemActions = itemaction.NewRegistry("restore")
+
+// RegisterItemAction registers action under name so that the Ark server executes it during every
+// restore. It's intended to be called from an init function of a package compiled into the server
+// binary. It panics if an action is already registered under name.
+func RegisterItemAction(name string, action ItemAction) {
+	itemActions.Register(name, action)
+}
+
+// RegisteredItemActions returns the names of the registered ItemActions and the actions
+// themselves, sorted by name.
+func RegisteredItemActions() ([]string, []ItemAction) {
+	names, registered := itemActions.Registered()
+
+	var actions []ItemAction
+	for _, action := range registered {
+		actions = append(actions, action.(ItemAction))
+	}
+
+	return names, actions
+}
+
+// resolvedItemAction is an ItemAction whose ResourceSelector has been resolved.
+type resolvedItemAction struct {
+	ItemAction
+	*itemaction.Selector
+}
+
+// resolveItemActions resolves the ResourceSelector of each of actions using mapper.
+func resolveItemActions(mapper meta.RESTMapper, actions []ItemAction) ([]resolvedItemAction, error) {
+	var ret []resolvedItemAction
+
+	for _, action := range actions {
+		selector, err := action.AppliesTo()
+		if err != nil {
+			return nil, err
+		}
+
+		resolved, err := itemaction.Resolve(mapper, selector)
+		if err != nil {
+			return nil, err
+		}
+
+		ret = append(ret, resolvedItemAction{
+			ItemAction: action,
+			Selector:   resolved,
+		})
+	}
+
+	return ret, nil
+}
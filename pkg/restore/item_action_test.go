@@ -0,0 +1,143 @@
+/*
+Copyright 2017 Heptio Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package restore
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	api "github.com/heptio/ark/pkg/apis/ark/v1"
+	"github.com/heptio/ark/pkg/util/itemaction"
+	. "github.com/heptio/ark/pkg/util/test"
+)
+
+type fakeItemAction struct {
+	selector ResourceSelector
+	skip     bool
+	err      error
+}
+
+var _ ItemAction = &fakeItemAction{}
+
+func (a *fakeItemAction) AppliesTo() (ResourceSelector, error) {
+	return a.selector, nil
+}
+
+func (a *fakeItemAction) Execute(item *unstructured.Unstructured, restore *api.Restore) (bool, error) {
+	if a.err != nil {
+		return false, a.err
+	}
+
+	itemLabels := item.GetLabels()
+	if itemLabels == nil {
+		itemLabels = make(map[string]string)
+	}
+	itemLabels["item-action"] = "executed"
+	item.SetLabels(itemLabels)
+
+	return a.skip, nil
+}
+
+func TestRegisterItemAction(t *testing.T) {
+	defer func() { itemActions = itemaction.NewRegistry("restore") }()
+
+	a, b := &fakeItemAction{}, &fakeItemAction{}
+	RegisterItemAction("b", b)
+	RegisterItemAction("a", a)
+
+	names, actions := RegisteredItemActions()
+	assert.Equal(t, []string{"a", "b"}, names)
+	require.Len(t, actions, 2)
+	assert.True(t, actions[0] == a)
+	assert.True(t, actions[1] == b)
+
+	assert.Panics(t, func() { RegisterItemAction("a", &fakeItemAction{}) })
+}
+
+func TestItemActionAppliesTo(t *testing.T) {
+	mapper := &FakeMapper{
+		Resources: map[schema.GroupVersionResource]schema.GroupVersionResource{
+			schema.GroupVersionResource{Resource: "configmaps"}: schema.GroupVersionResource{Version: "v1", Resource: "configmaps"},
+			schema.GroupVersionResource{Resource: "secrets"}:    schema.GroupVersionResource{Version: "v1", Resource: "secrets"},
+		},
+	}
+
+	tests := []struct {
+		name          string
+		selector      ResourceSelector
+		groupResource string
+		namespace     string
+		labels        labels.Set
+		expected      bool
+	}{
+		{
+			name:          "empty selector matches everything",
+			groupResource: "configmaps",
+			namespace:     "ns-1",
+			expected:      true,
+		},
+		{
+			name:          "empty selector matches cluster-scoped items",
+			groupResource: "persistentvolumes",
+			expected:      true,
+		},
+		{
+			name:          "other resource doesn't match",
+			selector:      ResourceSelector{IncludedResources: []string{"configmaps"}},
+			groupResource: "secrets",
+			namespace:     "ns-1",
+			expected:      false,
+		},
+		{
+			name:          "excluded namespace doesn't match",
+			selector:      ResourceSelector{ExcludedNamespaces: []string{"ns-1"}},
+			groupResource: "configmaps",
+			namespace:     "ns-1",
+			expected:      false,
+		},
+		{
+			name:          "cluster-scoped item doesn't match explicit namespaces",
+			selector:      ResourceSelector{IncludedNamespaces: []string{"ns-1"}},
+			groupResource: "persistentvolumes",
+			expected:      false,
+		},
+		{
+			name:          "label selector doesn't match",
+			selector:      ResourceSelector{LabelSelector: labels.SelectorFromSet(labels.Set{"app": "db"})},
+			groupResource: "configmaps",
+			namespace:     "ns-1",
+			labels:        labels.Set{"app": "web"},
+			expected:      false,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			resolved, err := resolveItemActions(mapper, []ItemAction{&fakeItemAction{selector: test.selector}})
+			require.NoError(t, err)
+			require.Len(t, resolved, 1)
+
+			assert.Equal(t, test.expected, resolved[0].Matches(test.groupResource, test.namespace, test.labels))
+		})
+	}
+}
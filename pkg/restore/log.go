@@ -0,0 +1,108 @@
+/*
+Copyright 2017 Heptio Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package restore
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+
+	"github.com/heptio/ark/pkg/logging"
+)
+
+// restoreLog records messages about a single restore, writing them both to the server's log, with
+// fields identifying what they're about, and to the restore's own log file.
+type restoreLog struct {
+	// lock is shared by the copies of the log made by withFields, which write to the same file.
+	lock   *sync.Mutex
+	w      io.Writer
+	logger logging.Logger
+}
+
+func newRestoreLog(w io.Writer) *restoreLog {
+	return &restoreLog{lock: &sync.Mutex{}, w: w}
+}
+
+// withFields returns a copy of l whose messages are written to the server's log with fields as
+// well as l's.
+func (l *restoreLog) withFields(fields logging.Fields) *restoreLog {
+	if l == nil {
+		return newRestoreLog(nil).withFields(fields)
+	}
+	return &restoreLog{lock: l.lock, w: l.w, logger: l.logger.WithFields(fields)}
+}
+
+// server returns the logger that l's messages are written to the server's log with.
+func (l *restoreLog) server() logging.Logger {
+	if l == nil {
+		return logging.Logger{}
+	}
+	return l.logger
+}
+
+// Debugf logs details about the restore to the server's log at verbosity level 4 or higher. They
+// aren't written to the restore's log file.
+func (l *restoreLog) Debugf(format string, args ...interface{}) {
+	if glog.V(4) {
+		l.server().InfoDepth(1, fmt.Sprintf(format, args...))
+	}
+}
+
+// Infof logs progress information about the restore. It's only written to the server's log at
+// verbosity level 2 or higher, but is always written to the restore's log file.
+func (l *restoreLog) Infof(format string, args ...interface{}) {
+	msg := fmt.Sprintf(format, args...)
+	if glog.V(2) {
+		l.server().InfoDepth(1, msg)
+	}
+	l.write("info", msg)
+}
+
+// Warningf logs a warning about the restore.
+func (l *restoreLog) Warningf(format string, args ...interface{}) {
+	msg := fmt.Sprintf(format, args...)
+	l.server().WarningDepth(1, msg)
+	l.write("warning", msg)
+}
+
+// Errorf logs an error about the restore.
+func (l *restoreLog) Errorf(format string, args ...interface{}) {
+	msg := fmt.Sprintf(format, args...)
+	l.server().ErrorDepth(1, msg)
+	l.write("error", msg)
+}
+
+// write appends a single line to the restore's log file in logfmt format. Failures to write are
+// reported to the server's log but are otherwise ignored.
+func (l *restoreLog) write(level, msg string) {
+	if l == nil || l.w == nil {
+		return
+	}
+
+	line := fmt.Sprintf("time=%q level=%s msg=%s\n", time.Now().UTC().Format(time.RFC3339), level, strconv.Quote(msg))
+
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	if _, err := io.WriteString(l.w, line); err != nil {
+		l.server().Errorf("error writing to restore log: %v", err)
+	}
+}
@@ -0,0 +1,246 @@
+/*
+Copyright 2017 Heptio Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package restore
+
+import (
+	"sort"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	api "github.com/heptio/ark/pkg/apis/ark/v1"
+	"github.com/heptio/ark/pkg/client"
+	"github.com/heptio/ark/pkg/csi"
+)
+
+// Plan describes what a restore would do, as worked out by previewing it.
+type Plan struct {
+	// Items lists each item in the backup that's within the restore's scope, and what would be
+	// done with it, followed by the items that pruning would delete.
+	Items []PlanItem `json:"items"`
+
+	// Volumes lists the volumes whose data would be restored, and where it would come from.
+	Volumes []PlanVolume `json:"volumes"`
+}
+
+// PlanAction is what a restore would do with an item.
+type PlanAction string
+
+const (
+	// PlanActionCreate means the item would be created.
+	PlanActionCreate PlanAction = "Create"
+
+	// PlanActionSkip means the item wouldn't be restored, e.g. because it has a controller that
+	// would re-create it.
+	PlanActionSkip PlanAction = "Skip"
+
+	// PlanActionConflict means the item already exists, and would be left as it is.
+	PlanActionConflict PlanAction = "Conflict"
+
+	// PlanActionPatch means the item already exists, and would be patched.
+	PlanActionPatch PlanAction = "Patch"
+
+	// PlanActionReplace means the item already exists, and would be deleted and re-created.
+	PlanActionReplace PlanAction = "Replace"
+
+	// PlanActionDelete means the item isn't in the backup, and would be deleted by pruning.
+	PlanActionDelete PlanAction = "Delete"
+)
+
+// PlanItem is an item in a restore plan.
+type PlanItem struct {
+	// Resource is the item's resource, in <RESOURCE>.<GROUP> form.
+	Resource string `json:"resource"`
+
+	// Namespace is the namespace the item would be restored into, after any remapping. It's empty
+	// for cluster-scoped items.
+	Namespace string `json:"namespace,omitempty"`
+
+	// Name is the item's name.
+	Name string `json:"name"`
+
+	// Action is what would be done with the item.
+	Action PlanAction `json:"action"`
+
+	// Reason explains why the item would be skipped.
+	Reason string `json:"reason,omitempty"`
+}
+
+// PlanVolumeSource is where a volume's data would be restored from.
+type PlanVolumeSource string
+
+const (
+	// PlanVolumeSourceSnapshot means a PersistentVolume would be restored from its snapshot,
+	// taken in one of the server's volume snapshot locations.
+	PlanVolumeSourceSnapshot PlanVolumeSource = "Snapshot"
+
+	// PlanVolumeSourceCSISnapshot means a PersistentVolumeClaim's volume would be provisioned
+	// from its CSI snapshot.
+	PlanVolumeSourceCSISnapshot PlanVolumeSource = "CSISnapshot"
+
+	// PlanVolumeSourceRestic means a pod volume would be restored from its restic snapshot.
+	PlanVolumeSourceRestic PlanVolumeSource = "Restic"
+)
+
+// PlanVolume is a volume in a restore plan.
+type PlanVolume struct {
+	// Source is where the volume's data would come from.
+	Source PlanVolumeSource `json:"source"`
+
+	// Resource is the resource of the item the volume belongs to: a PersistentVolume, a
+	// PersistentVolumeClaim, or a pod.
+	Resource string `json:"resource"`
+
+	// Namespace is the namespace the item would be restored into.
+	Namespace string `json:"namespace,omitempty"`
+
+	// Name is the name of the item.
+	Name string `json:"name"`
+
+	// Volume is the name of the pod volume, for restic volumes.
+	Volume string `json:"volume,omitempty"`
+
+	// Snapshot is the ID of the snapshot the volume would be restored from.
+	Snapshot string `json:"snapshot"`
+}
+
+func (p *Plan) addItem(groupResource schema.GroupResource, namespace, name string, action PlanAction, reason string) {
+	p.Items = append(p.Items, PlanItem{
+		Resource:  groupResource.String(),
+		Namespace: namespace,
+		Name:      name,
+		Action:    action,
+		Reason:    reason,
+	})
+}
+
+// addVolumes records the volumes of obj, which would be restored into namespace, that would have
+// their data restored.
+func (p *Plan) addVolumes(restore *api.Restore, backup *api.Backup, groupResource schema.GroupResource, namespace, name string, spec map[string]interface{}, resticSnapshots map[string]string) {
+	p.Volumes = append(p.Volumes, volumesToRestore(restore, backup, groupResource, namespace, name, spec, resticSnapshots)...)
+}
+
+// volumesToRestore returns the volumes of obj, restored into namespace, that have their data
+// restored, in the order they're restored in.
+func volumesToRestore(restore *api.Restore, backup *api.Backup, groupResource schema.GroupResource, namespace, name string, spec map[string]interface{}, resticSnapshots map[string]string) []PlanVolume {
+	var volumes []PlanVolume
+
+	switch groupResource.String() {
+	case "persistentvolumes":
+		if restore.Spec.RestorePVs != nil && !*restore.Spec.RestorePVs {
+			return nil
+		}
+		if info := backup.Status.VolumeBackups[name]; info != nil && info.SnapshotID != "" {
+			volumes = append(volumes, PlanVolume{
+				Source:   PlanVolumeSourceSnapshot,
+				Resource: groupResource.String(),
+				Name:     name,
+				Snapshot: info.SnapshotID,
+			})
+		}
+	case "persistentvolumeclaims":
+		volumeName, _ := spec["volumeName"].(string)
+		if info := csi.SnapshotToRestore(restore, backup, volumeName); info != nil {
+			volumes = append(volumes, PlanVolume{
+				Source:    PlanVolumeSourceCSISnapshot,
+				Resource:  groupResource.String(),
+				Namespace: namespace,
+				Name:      name,
+				Snapshot:  info.SnapshotHandle,
+			})
+		}
+	case "pods":
+		podVolumes := make([]string, 0, len(resticSnapshots))
+		for volume := range resticSnapshots {
+			podVolumes = append(podVolumes, volume)
+		}
+		sort.Strings(podVolumes)
+
+		for _, volume := range podVolumes {
+			volumes = append(volumes, PlanVolume{
+				Source:    PlanVolumeSourceRestic,
+				Resource:  groupResource.String(),
+				Namespace: namespace,
+				Name:      name,
+				Volume:    volume,
+				Snapshot:  resticSnapshots[volume],
+			})
+		}
+	}
+
+	return volumes
+}
+
+// recordRestoredVolumes records volumes in restore's status as having been restored.
+func recordRestoredVolumes(restore *api.Restore, volumes []PlanVolume) {
+	for _, volume := range volumes {
+		restore.Status.RestoredVolumes = append(restore.Status.RestoredVolumes, api.RestoredVolume{
+			Source:    api.RestoredVolumeSource(volume.Source),
+			Resource:  volume.Resource,
+			Namespace: volume.Namespace,
+			Name:      volume.Name,
+			Volume:    volume.Volume,
+			Snapshot:  volume.Snapshot,
+		})
+	}
+}
+
+// previewItem records what restoring obj into namespace would do in plan.
+func previewItem(
+	plan *Plan,
+	resourceClient client.Dynamic,
+	restore *api.Restore,
+	backup *api.Backup,
+	groupResource schema.GroupResource,
+	namespace string,
+	obj *unstructured.Unstructured,
+	resticSnapshots map[string]string,
+) error {
+	name := obj.GetName()
+	if target, ok := restore.Spec.NamespaceMapping[name]; ok && groupResource.String() == "namespaces" {
+		name = target
+	}
+
+	action := PlanActionCreate
+	_, err := resourceClient.Get(name, metav1.GetOptions{})
+	switch {
+	case apierrors.IsNotFound(err):
+	case err != nil:
+		return err
+	default:
+		switch existingResourcePolicy(restore, groupResource) {
+		case api.ExistingResourcePolicyPatch:
+			action = PlanActionPatch
+		case api.ExistingResourcePolicyReplace:
+			action = PlanActionReplace
+			if groupResource.String() == "namespaces" {
+				action = PlanActionPatch
+			}
+		default:
+			action = PlanActionConflict
+		}
+	}
+
+	plan.addItem(groupResource, namespace, name, action, "")
+
+	spec, _ := obj.Object["spec"].(map[string]interface{})
+	plan.addVolumes(restore, backup, groupResource, namespace, name, spec, resticSnapshots)
+
+	return nil
+}
@@ -0,0 +1,97 @@
+/*
+Copyright 2017 Heptio Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package restore
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	api "github.com/heptio/ark/pkg/apis/ark/v1"
+	. "github.com/heptio/ark/pkg/util/test"
+)
+
+func TestPlanAddVolumes(t *testing.T) {
+	tests := []struct {
+		name            string
+		restore         *api.Restore
+		backup          *api.Backup
+		resource        string
+		spec            map[string]interface{}
+		resticSnapshots map[string]string
+		expected        []PlanVolume
+	}{
+		{
+			name:     "persistent volume with a snapshot",
+			restore:  NewDefaultTestRestore().Restore,
+			backup:   NewTestBackup().WithSnapshot("pv-1", "snap-1").Backup,
+			resource: "persistentvolumes",
+			expected: []PlanVolume{
+				{Source: PlanVolumeSourceSnapshot, Resource: "persistentvolumes", Name: "pv-1", Snapshot: "snap-1"},
+			},
+		},
+		{
+			name:     "persistent volume snapshot isn't restored when restorePVs is false",
+			restore:  NewDefaultTestRestore().WithRestorePVs(false).Restore,
+			backup:   NewTestBackup().WithSnapshot("pv-1", "snap-1").Backup,
+			resource: "persistentvolumes",
+		},
+		{
+			name:     "persistent volume without a snapshot",
+			restore:  NewDefaultTestRestore().Restore,
+			backup:   NewTestBackup().Backup,
+			resource: "persistentvolumes",
+		},
+		{
+			name:     "claim with a CSI snapshot",
+			restore:  NewDefaultTestRestore().Restore,
+			backup:   NewTestBackup().WithCSISnapshot("pv-2", "csi.example.com", "handle-1").Backup,
+			resource: "persistentvolumeclaims",
+			spec:     map[string]interface{}{"volumeName": "pv-2"},
+			expected: []PlanVolume{
+				{Source: PlanVolumeSourceCSISnapshot, Resource: "persistentvolumeclaims", Namespace: "ns-1", Name: "pv-1", Snapshot: "handle-1"},
+			},
+		},
+		{
+			name:            "pod with restic snapshots",
+			restore:         NewDefaultTestRestore().Restore,
+			backup:          NewTestBackup().Backup,
+			resource:        "pods",
+			resticSnapshots: map[string]string{"data": "snap-2", "config": "snap-1"},
+			expected: []PlanVolume{
+				{Source: PlanVolumeSourceRestic, Resource: "pods", Namespace: "ns-1", Name: "pv-1", Volume: "config", Snapshot: "snap-1"},
+				{Source: PlanVolumeSourceRestic, Resource: "pods", Namespace: "ns-1", Name: "pv-1", Volume: "data", Snapshot: "snap-2"},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			plan := &Plan{}
+			namespace := "ns-1"
+			if test.resource == "persistentvolumes" {
+				namespace = ""
+			}
+
+			plan.addVolumes(test.restore, test.backup, schema.GroupResource{Resource: test.resource}, namespace, "pv-1", test.spec, test.resticSnapshots)
+
+			assert.Equal(t, test.expected, plan.Volumes)
+		})
+	}
+}
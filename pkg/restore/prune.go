@@ -0,0 +1,314 @@
+/*
+Copyright 2017 Heptio Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package restore
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	api "github.com/heptio/ark/pkg/apis/ark/v1"
+	"github.com/heptio/ark/pkg/util/collections"
+)
+
+// protectedResources are never pruned, since their items are created and managed by Kubernetes
+// rather than by users, so they're expected to differ from those in the backup.
+var protectedResources = sets.NewString(
+	"events",
+	"events.events.k8s.io",
+	"endpoints",
+	"controllerrevisions.apps",
+)
+
+// cohabitatingResources maps the resources that the extensions API group serves along with
+// another group to the other group's resource, so that their items are identified the same way
+// whichever group they're read through.
+var cohabitatingResources = map[schema.GroupResource]schema.GroupResource{
+	{Group: "extensions", Resource: "daemonsets"}:      {Group: "apps", Resource: "daemonsets"},
+	{Group: "extensions", Resource: "deployments"}:     {Group: "apps", Resource: "deployments"},
+	{Group: "extensions", Resource: "replicasets"}:     {Group: "apps", Resource: "replicasets"},
+	{Group: "extensions", Resource: "ingresses"}:       {Group: "networking.k8s.io", Resource: "ingresses"},
+	{Group: "extensions", Resource: "networkpolicies"}: {Group: "networking.k8s.io", Resource: "networkpolicies"},
+}
+
+// pruneKey returns the key that identifies the item name, in namespace, of groupResource, when
+// comparing the items in the cluster with those in the backup.
+func pruneKey(groupResource schema.GroupResource, namespace, name string) string {
+	if canonical, ok := cohabitatingResources[groupResource]; ok {
+		groupResource = canonical
+	}
+	return groupResource.Group + "/" + groupResource.Resource + "/" + namespace + "/" + name
+}
+
+// prunableResource is a resource whose items a restore can prune.
+type prunableResource struct {
+	groupVersion schema.GroupVersion
+	resource     metav1.APIResource
+}
+
+func (r prunableResource) groupResource() schema.GroupResource {
+	return schema.GroupResource{Group: r.groupVersion.Group, Resource: r.resource.Name}
+}
+
+// prune deletes the items in the namespaces that the backup extracted to dir is restored into
+// that aren't in the backup, or if plan isn't nil, records that they would be deleted in plan.
+// Only items of resources that the backup includes, and with labels that both the backup's and
+// the restore's label selectors match, are deleted.
+func (kr *kubernetesRestorer) prune(dir string, restore *api.Restore, backup *api.Backup, selector *itemSelector, plan *Plan, log *restoreLog) (warnings, errors api.RestoreResult) {
+	resources, resolveWarnings := kr.getPrunableResources(restore, backup)
+	for _, warning := range resolveWarnings {
+		addArkError(&warnings, warning)
+	}
+
+	backupSelector := labels.Everything()
+	if backup.Spec.LabelSelector != nil {
+		var err error
+		if backupSelector, err = metav1.LabelSelectorAsSelector(backup.Spec.LabelSelector); err != nil {
+			addArkError(&errors, err)
+			return warnings, errors
+		}
+	}
+
+	namespacesPath := path.Join(dir, api.NamespaceScopedDir)
+	nses, err := kr.readDirIfExists(namespacesPath)
+	if err != nil {
+		addArkError(&errors, err)
+		return warnings, errors
+	}
+
+	namespacesToRestore := sets.NewString(restore.Spec.Namespaces...)
+	for _, ns := range nses {
+		if !namespacesToRestore.Has("*") && !namespacesToRestore.Has(ns) {
+			continue
+		}
+
+		namespace := ns
+		if target, ok := restore.Spec.NamespaceMapping[ns]; ok {
+			namespace = target
+		}
+
+		if namespace == restore.Namespace {
+			addToResult(&warnings, namespace, fmt.Errorf("not pruning namespace %s since it's the Ark server's namespace", namespace))
+			continue
+		}
+
+		backedUp, err := kr.getBackedUpItems(path.Join(namespacesPath, ns), namespace)
+		if err != nil {
+			addToResult(&errors, namespace, err)
+			continue
+		}
+
+		log.Infof("Pruning namespace %s", namespace)
+		w, e := kr.pruneNamespace(namespace, resources, backedUp, backupSelector, selector.labels, plan, log)
+		merge(&warnings, &w)
+		merge(&errors, &e)
+	}
+
+	return warnings, errors
+}
+
+// getPrunableResources returns the namespaced resources whose items a restore can prune: those
+// that the cluster serves, that the backup includes, and that aren't protected or excluded from
+// pruning by the restore. It also returns warnings for the restore's exclusions that can't be
+// resolved.
+func (kr *kubernetesRestorer) getPrunableResources(restore *api.Restore, backup *api.Backup) ([]prunableResource, []error) {
+	mapper := kr.discoveryHelper.Mapper()
+	resolve := func(resource string) (string, error) {
+		gvr, err := mapper.ResourceFor(schema.ParseGroupResource(resource).WithVersion(""))
+		if err != nil {
+			return "", err
+		}
+		gr := gvr.GroupResource()
+		return gr.String(), nil
+	}
+
+	// the backup's resources were validated when it was taken, so any that can't be resolved
+	// now aren't served by the cluster, and have no items to prune
+	backupResources := collections.NewIncludesExcludes()
+	if len(backup.Spec.IncludedResources) == 0 {
+		backupResources.Includes("*")
+	}
+	for _, resource := range backup.Spec.IncludedResources {
+		if resource == "*" {
+			backupResources.Includes("*")
+			continue
+		}
+		if gr, err := resolve(resource); err == nil {
+			backupResources.Includes(gr)
+		}
+	}
+	for _, resource := range backup.Spec.ExcludedResources {
+		if gr, err := resolve(resource); err == nil {
+			backupResources.Excludes(gr)
+		}
+	}
+
+	var warnings []error
+	excluded := sets.NewString()
+	for _, resource := range restore.Spec.PruneExcludedResources {
+		gr, err := resolve(resource)
+		if err != nil {
+			warnings = append(warnings, fmt.Errorf("unable to resolve resource %q excluded from pruning: %v", resource, err))
+			continue
+		}
+		excluded.Insert(gr)
+	}
+
+	var resources []prunableResource
+	for _, resourceList := range kr.discoveryHelper.Resources() {
+		gv, err := schema.ParseGroupVersion(resourceList.GroupVersion)
+		if err != nil {
+			warnings = append(warnings, err)
+			continue
+		}
+
+		for _, resource := range resourceList.APIResources {
+			if !resource.Namespaced || strings.Contains(resource.Name, "/") || !sets.NewString(resource.Verbs...).HasAll("list", "delete") {
+				continue
+			}
+
+			r := prunableResource{groupVersion: gv, resource: resource}
+			groupResource := r.groupResource()
+			gr := groupResource.String()
+			if protectedResources.Has(gr) || excluded.Has(gr) || !backupResources.ShouldInclude(gr) {
+				continue
+			}
+
+			resources = append(resources, r)
+		}
+	}
+
+	return resources, warnings
+}
+
+// getBackedUpItems returns the pruneKeys of the items in the backup's directory for a namespace,
+// nsPath, which is restored into namespace.
+func (kr *kubernetesRestorer) getBackedUpItems(nsPath, namespace string) (sets.String, error) {
+	resourceDirs, err := kr.readDirIfExists(nsPath)
+	if err != nil {
+		return nil, err
+	}
+
+	backedUp := sets.NewString()
+	for _, resourceDir := range resourceDirs {
+		items, err := kr.readItems(path.Join(nsPath, resourceDir))
+		if err != nil {
+			return nil, err
+		}
+		groupResource := schema.ParseGroupResource(resourceDir)
+		for _, item := range items {
+			backedUp.Insert(pruneKey(groupResource, namespace, item.GetName()))
+		}
+	}
+	return backedUp, nil
+}
+
+// pruneNamespace deletes the items of resources in namespace that aren't in backedUp and whose
+// labels match both selectors, or if plan isn't nil, records that they would be deleted in plan.
+func (kr *kubernetesRestorer) pruneNamespace(
+	namespace string,
+	resources []prunableResource,
+	backedUp sets.String,
+	backupSelector labels.Selector,
+	restoreSelector labels.Selector,
+	plan *Plan,
+	log *restoreLog,
+) (warnings, errors api.RestoreResult) {
+	// items served by more than one API group are listed once for each, but only pruned once
+	pruned := sets.NewString()
+	propagation := metav1.DeletePropagationBackground
+
+	for _, r := range resources {
+		groupResource := r.groupResource()
+
+		resourceClient, err := kr.dynamicFactory.ClientForGroupVersionResource(r.groupVersion.WithResource(r.resource.Name), r.resource, namespace)
+		if err != nil {
+			addToResult(&errors, namespace, err)
+			continue
+		}
+
+		list, err := resourceClient.List(metav1.ListOptions{LabelSelector: backupSelector.String()})
+		if err != nil {
+			addToResult(&errors, namespace, fmt.Errorf("error listing %s: %v", groupResource, err))
+			continue
+		}
+		items, err := meta.ExtractList(list)
+		if err != nil {
+			addToResult(&errors, namespace, fmt.Errorf("error listing %s: %v", groupResource, err))
+			continue
+		}
+
+		for _, item := range items {
+			obj, ok := item.(*unstructured.Unstructured)
+			if !ok {
+				addToResult(&errors, namespace, fmt.Errorf("unexpected type %T for resource %s", item, groupResource))
+				continue
+			}
+
+			key := pruneKey(groupResource, namespace, obj.GetName())
+			if backedUp.Has(key) || pruned.Has(key) || !restoreSelector.Matches(labels.Set(obj.GetLabels())) {
+				continue
+			}
+			if reason := pruneProtection(obj); reason != "" {
+				log.Infof("Not pruning %s %s/%s since %s", groupResource, namespace, obj.GetName(), reason)
+				continue
+			}
+			pruned.Insert(key)
+
+			if plan != nil {
+				plan.addItem(groupResource, namespace, obj.GetName(), PlanActionDelete, "")
+				continue
+			}
+
+			log.Infof("Deleting %s %s/%s since it isn't in the backup", groupResource, namespace, obj.GetName())
+			err := resourceClient.Delete(obj.GetName(), &metav1.DeleteOptions{PropagationPolicy: &propagation})
+			if err != nil && !apierrors.IsNotFound(err) {
+				addToResult(&errors, namespace, fmt.Errorf("error pruning %s %s: %v", groupResource, obj.GetName(), err))
+			}
+		}
+	}
+
+	return warnings, errors
+}
+
+// pruneProtection returns why obj is never pruned, or an empty string if it can be.
+func pruneProtection(obj *unstructured.Unstructured) string {
+	switch {
+	case obj.GetDeletionTimestamp() != nil:
+		return "it's already being deleted"
+	case controllerRef(obj.GetOwnerReferences()) != nil:
+		return "it's managed by its controller"
+	case obj.GetKind() == "ServiceAccount" && obj.GetName() == "default":
+		return "it's the namespace's default service account"
+	}
+
+	if obj.GetKind() == "Secret" {
+		if secretType, _ := collections.GetString(obj.Object, "type"); secretType == "kubernetes.io/service-account-token" {
+			return "it's a service account token"
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,240 @@
+/*
+Copyright 2017 Heptio Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package restore
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	api "github.com/heptio/ark/pkg/apis/ark/v1"
+	. "github.com/heptio/ark/pkg/util/test"
+)
+
+func TestPrune(t *testing.T) {
+	item := func(kind, name string, labels map[string]interface{}, extra map[string]interface{}) unstructured.Unstructured {
+		obj := map[string]interface{}{
+			"kind":     kind,
+			"metadata": map[string]interface{}{"name": name, "labels": labels},
+		}
+		for k, v := range extra {
+			obj[k] = v
+		}
+		return unstructured.Unstructured{Object: obj}
+	}
+	web := map[string]interface{}{"app": "web"}
+	controlled := map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"name":            "web-abc-1",
+			"labels":          web,
+			"ownerReferences": []interface{}{map[string]interface{}{"apiVersion": "apps/v1beta1", "kind": "ReplicaSet", "name": "web-abc", "controller": true}},
+		},
+	}
+
+	fileSystem := newFakeFileSystem().
+		WithFile("/backup/namespaces/ns-1/configmaps/keep.json", []byte(`{"apiVersion": "v1", "kind": "ConfigMap", "metadata": {"name": "keep"}}`)).
+		WithFile("/backup/namespaces/ns-1/deployments.apps/web.json", []byte(`{"apiVersion": "apps/v1beta1", "kind": "Deployment", "metadata": {"name": "web"}}`))
+
+	verbs := []string{"list", "create", "delete"}
+	discoveryHelper := &fakeDiscoveryHelper{
+		mapper: &FakeMapper{AutoReturnResource: true},
+		resources: []*metav1.APIResourceList{
+			{
+				GroupVersion: "v1",
+				APIResources: []metav1.APIResource{
+					{Name: "configmaps", Namespaced: true, Verbs: verbs},
+					{Name: "events", Namespaced: true, Verbs: verbs},
+					{Name: "namespaces", Verbs: verbs},
+					{Name: "pods", Namespaced: true, Verbs: verbs},
+					{Name: "secrets", Namespaced: true, Verbs: verbs},
+				},
+			},
+			{
+				GroupVersion: "apps/v1beta1",
+				APIResources: []metav1.APIResource{{Name: "deployments", Namespaced: true, Verbs: verbs}},
+			},
+			{
+				GroupVersion: "extensions/v1beta1",
+				APIResources: []metav1.APIResource{{Name: "deployments", Namespaced: true, Verbs: verbs}},
+			},
+			{
+				GroupVersion: "example.com/v1",
+				APIResources: []metav1.APIResource{{Name: "configmaps", Namespaced: true, Verbs: verbs}},
+			},
+		},
+	}
+
+	deployments := []unstructured.Unstructured{
+		item("Deployment", "web", web, nil),
+		item("Deployment", "new", web, nil),
+	}
+	items := map[schema.GroupVersionResource][]unstructured.Unstructured{
+		{Version: "v1", Resource: "configmaps"}: {
+			item("ConfigMap", "keep", nil, nil),
+			item("ConfigMap", "extra", map[string]interface{}{"app": "other"}, nil),
+		},
+		{Version: "v1", Resource: "pods"}: {
+			item("Pod", "bare", web, nil),
+			item("Pod", "web-abc-1", web, controlled),
+		},
+		{Version: "v1", Resource: "secrets"}: {
+			item("Secret", "app-secret", web, nil),
+			item("Secret", "default-token-abcde", nil, map[string]interface{}{"type": "kubernetes.io/service-account-token"}),
+		},
+		{Group: "apps", Version: "v1beta1", Resource: "deployments"}:       deployments,
+		{Group: "extensions", Version: "v1beta1", Resource: "deployments"}: deployments,
+		// items of other groups' resources aren't in the backup, even with the same kind and name.
+		{Group: "example.com", Version: "v1", Resource: "configmaps"}: {
+			item("ConfigMap", "keep", nil, nil),
+		},
+	}
+
+	tests := []struct {
+		name             string
+		restore          *api.Restore
+		backup           *api.Backup
+		preview          bool
+		expectedDeleted  []string
+		expectedWarnings api.RestoreResult
+	}{
+		{
+			name:            "items that aren't in the backup are deleted",
+			restore:         NewTestRestore("heptio-ark", "", "").WithRestorableNamespace("*").Restore,
+			backup:          NewTestBackup().Backup,
+			expectedDeleted: []string{"configmaps.example.com/keep", "configmaps/extra", "deployments.apps/new", "pods/bare", "secrets/app-secret"},
+		},
+		{
+			name:            "previews record the items that would be deleted",
+			restore:         NewTestRestore("heptio-ark", "", "").WithRestorableNamespace("*").WithMappedNamespace("ns-1", "ns-2").Restore,
+			backup:          NewTestBackup().Backup,
+			preview:         true,
+			expectedDeleted: []string{"configmaps.example.com/keep", "configmaps/extra", "deployments.apps/new", "pods/bare", "secrets/app-secret"},
+		},
+		{
+			name: "excluded resources and unselected items aren't deleted",
+			restore: func() *api.Restore {
+				restore := NewTestRestore("heptio-ark", "", "").WithRestorableNamespace("ns-1").Restore
+				restore.Spec.LabelSelector = &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}}
+				restore.Spec.PruneExcludedResources = []string{"pods"}
+				return restore
+			}(),
+			backup:          NewTestBackup().WithIncludedResources("configmaps", "deployments.apps", "pods").Backup,
+			expectedDeleted: []string{"deployments.apps/new"},
+		},
+		{
+			name:    "the Ark server's namespace isn't pruned",
+			restore: NewTestRestore("ns-1", "", "").WithRestorableNamespace("*").Restore,
+			backup:  NewTestBackup().Backup,
+			expectedWarnings: api.RestoreResult{
+				Namespaces: map[string][]string{"ns-1": {"not pruning namespace ns-1 since it's the Ark server's namespace"}},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			namespace := "ns-1"
+			if target, ok := test.restore.Spec.NamespaceMapping[namespace]; ok {
+				namespace = target
+			}
+
+			dynamicFactory := &FakeDynamicFactory{}
+			clients := make(map[schema.GroupResource]*FakeDynamicClient)
+			for _, resourceList := range discoveryHelper.resources {
+				gv, err := schema.ParseGroupVersion(resourceList.GroupVersion)
+				require.NoError(t, err)
+
+				for _, resource := range resourceList.APIResources {
+					gvr := gv.WithResource(resource.Name)
+
+					resourceClient := &FakeDynamicClient{}
+					resourceClient.On("List", mock.Anything).Return(&unstructured.UnstructuredList{Items: items[gvr]}, nil)
+					resourceClient.On("Delete", mock.Anything, mock.Anything).Return(nil)
+					clients[gvr.GroupResource()] = resourceClient
+
+					dynamicFactory.On("ClientForGroupVersionResource", gvr, resource, namespace).Return(resourceClient, nil)
+				}
+			}
+
+			restorer := &kubernetesRestorer{
+				discoveryHelper: discoveryHelper,
+				dynamicFactory:  dynamicFactory,
+				fileSystem:      fileSystem,
+			}
+
+			selector, err := metav1.LabelSelectorAsSelector(test.restore.Spec.LabelSelector)
+			require.NoError(t, err)
+			if test.restore.Spec.LabelSelector == nil {
+				selector = labels.Everything()
+			}
+
+			var plan *Plan
+			if test.preview {
+				plan = new(Plan)
+			}
+
+			warnings, errors := restorer.prune("/backup", test.restore, test.backup, &itemSelector{labels: selector}, plan, nil)
+			assert.Equal(t, api.RestoreResult{}, errors)
+			assert.Equal(t, test.expectedWarnings, warnings)
+
+			var deleted []string
+			for groupResource, resourceClient := range clients {
+				for _, call := range resourceClient.Calls {
+					if call.Method == "Delete" {
+						deleted = append(deleted, groupResource.String()+"/"+call.Arguments.String(0))
+					}
+				}
+			}
+			if plan != nil {
+				assert.Empty(t, deleted)
+				for _, item := range plan.Items {
+					assert.Equal(t, PlanActionDelete, item.Action)
+					assert.Equal(t, namespace, item.Namespace)
+					deleted = append(deleted, item.Resource+"/"+item.Name)
+				}
+			}
+			sort.Strings(deleted)
+			assert.Equal(t, test.expectedDeleted, deleted)
+		})
+	}
+}
+
+type fakeDiscoveryHelper struct {
+	resources []*metav1.APIResourceList
+	mapper    meta.RESTMapper
+}
+
+func (dh *fakeDiscoveryHelper) Resources() []*metav1.APIResourceList {
+	return dh.resources
+}
+
+func (dh *fakeDiscoveryHelper) Mapper() meta.RESTMapper {
+	return dh.mapper
+}
+
+func (dh *fakeDiscoveryHelper) Refresh() error {
+	return nil
+}
@@ -0,0 +1,120 @@
+/*
+Copyright 2017 Heptio Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package restore
+
+import (
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"github.com/heptio/ark/pkg/util/collections"
+)
+
+// podSpecPaths maps the resources whose items contain pod specs to the path of the pod spec.
+var podSpecPaths = map[string]string{
+	"pods":                   "spec",
+	"replicationcontrollers": "spec.template.spec",
+	"deployments":            "spec.template.spec",
+	"replicasets":            "spec.template.spec",
+	"statefulsets":           "spec.template.spec",
+	"daemonsets":             "spec.template.spec",
+	"jobs":                   "spec.template.spec",
+	"cronjobs":               "spec.jobTemplate.spec.template.spec",
+}
+
+// mapImageRegistries rewrites the images of obj's containers and init containers according to
+// mapping, if obj is of a resource that contains a pod spec.
+func mapImageRegistries(groupResource schema.GroupResource, obj *unstructured.Unstructured, mapping map[string]string) error {
+	if len(mapping) == 0 {
+		return nil
+	}
+
+	path, found := podSpecPaths[groupResource.Resource]
+	if !found {
+		return nil
+	}
+
+	spec, err := collections.GetMap(obj.UnstructuredContent(), path)
+	if err != nil {
+		// the pod spec is required, so this is only hit for items that wouldn't be valid anyway
+		return nil
+	}
+
+	for _, containers := range []string{"initContainers", "containers"} {
+		if _, err := collections.GetSlice(spec, containers); err != nil {
+			continue
+		}
+
+		err := collections.ForEach(spec, containers, func(container map[string]interface{}) error {
+			image, err := collections.GetString(container, "image")
+			if err != nil {
+				return nil
+			}
+
+			container["image"] = mapImageRegistry(image, mapping)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// mapImageRegistry returns image with its registry, and optionally a repository path prefix or
+// the whole repository, replaced according to mapping. Images without a registry are taken to be
+// from Docker Hub, whose registry is "docker.io" and whose official images are under
+// "docker.io/library". The longest matching key of mapping is used. If none matches, image is
+// returned unchanged.
+func mapImageRegistry(image string, mapping map[string]string) string {
+	qualified := qualifyImage(image)
+
+	var match string
+	for from := range mapping {
+		if len(from) <= len(match) || !strings.HasPrefix(qualified, from) {
+			continue
+		}
+		// keys only match whole path components, followed by the rest of the path, or by a tag
+		// or digest if the key is a whole repository rather than a registry (whose port would
+		// otherwise look like a tag)
+		rest := qualified[len(from):]
+		if rest == "" || rest[0] == '/' || (strings.Contains(from, "/") && strings.ContainsAny(rest[:1], ":@")) {
+			match = from
+		}
+	}
+	if match == "" {
+		return image
+	}
+
+	return mapping[match] + strings.TrimPrefix(qualified, match)
+}
+
+// qualifyImage returns image with Docker Hub's registry, and the "library" repository path for
+// official images, added if it has no registry.
+func qualifyImage(image string) string {
+	parts := strings.SplitN(image, "/", 2)
+	if len(parts) == 2 && (strings.ContainsAny(parts[0], ".:") || parts[0] == "localhost") {
+		return image
+	}
+
+	if len(parts) == 1 {
+		return "docker.io/library/" + image
+	}
+	return "docker.io/" + image
+}
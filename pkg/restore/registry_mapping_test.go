@@ -0,0 +1,162 @@
+/*
+Copyright 2017 Heptio Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package restore
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"github.com/heptio/ark/pkg/util/collections"
+)
+
+func TestMapImageRegistry(t *testing.T) {
+	mapping := map[string]string{
+		"gcr.io":                    "mirror.internal:5000/gcr",
+		"docker.io":                 "mirror.internal:5000/dockerhub",
+		"docker.io/heptio":          "mirror.internal:5000/heptio",
+		"quay.io/coreos/etcd":       "mirror.internal:5000/etcd",
+		"registry.example.com:5000": "mirror.internal:5000",
+		"registry.example.com":      "mirror.internal",
+	}
+
+	tests := []struct {
+		image    string
+		expected string
+	}{
+		{"nginx", "mirror.internal:5000/dockerhub/library/nginx"},
+		{"nginx:1.13", "mirror.internal:5000/dockerhub/library/nginx:1.13"},
+		{"bitnami/redis:4.0", "mirror.internal:5000/dockerhub/bitnami/redis:4.0"},
+		{"heptio/ark:v0.5.0", "mirror.internal:5000/heptio/ark:v0.5.0"},
+		{"docker.io/heptio/ark", "mirror.internal:5000/heptio/ark"},
+		{"gcr.io/google_containers/pause:3.0", "mirror.internal:5000/gcr/google_containers/pause:3.0"},
+		{"gcr.io/app@sha256:abc", "mirror.internal:5000/gcr/app@sha256:abc"},
+		{"registry.example.com:5000/app", "mirror.internal:5000/app"},
+		{"registry.example.com/app:1.0", "mirror.internal/app:1.0"},
+		{"registry.example.com:6000/app", "registry.example.com:6000/app"},
+		{"quay.io/coreos/etcd:v3.2", "mirror.internal:5000/etcd:v3.2"},
+		{"quay.io/coreos/etcd-operator", "quay.io/coreos/etcd-operator"},
+		{"quay.io/prometheus/prometheus", "quay.io/prometheus/prometheus"},
+		{"localhost/app", "localhost/app"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.image, func(t *testing.T) {
+			assert.Equal(t, test.expected, mapImageRegistry(test.image, mapping))
+		})
+	}
+}
+
+func TestMapImageRegistries(t *testing.T) {
+	mapping := map[string]string{"docker.io": "mirror.internal"}
+
+	podSpec := func() map[string]interface{} {
+		return map[string]interface{}{
+			"initContainers": []interface{}{
+				map[string]interface{}{"name": "init", "image": "busybox"},
+			},
+			"containers": []interface{}{
+				map[string]interface{}{"name": "app", "image": "nginx"},
+				map[string]interface{}{"name": "sidecar", "image": "gcr.io/sidecar"},
+			},
+		}
+	}
+
+	tests := []struct {
+		name          string
+		groupResource schema.GroupResource
+		obj           *unstructured.Unstructured
+		specPath      string
+		mapping       map[string]string
+		expected      []string
+	}{
+		{
+			name:          "pod images are mapped",
+			groupResource: schema.GroupResource{Resource: "pods"},
+			obj:           &unstructured.Unstructured{Object: map[string]interface{}{"spec": podSpec()}},
+			specPath:      "spec",
+			mapping:       mapping,
+			expected:      []string{"mirror.internal/library/busybox", "mirror.internal/library/nginx", "gcr.io/sidecar"},
+		},
+		{
+			name:          "deployment template images are mapped",
+			groupResource: schema.GroupResource{Group: "apps", Resource: "deployments"},
+			obj: &unstructured.Unstructured{Object: map[string]interface{}{
+				"spec": map[string]interface{}{"template": map[string]interface{}{"spec": podSpec()}},
+			}},
+			specPath: "spec.template.spec",
+			mapping:  mapping,
+			expected: []string{"mirror.internal/library/busybox", "mirror.internal/library/nginx", "gcr.io/sidecar"},
+		},
+		{
+			name:          "cronjob job template images are mapped",
+			groupResource: schema.GroupResource{Group: "batch", Resource: "cronjobs"},
+			obj: &unstructured.Unstructured{Object: map[string]interface{}{
+				"spec": map[string]interface{}{"jobTemplate": map[string]interface{}{
+					"spec": map[string]interface{}{"template": map[string]interface{}{"spec": podSpec()}},
+				}},
+			}},
+			specPath: "spec.jobTemplate.spec.template.spec",
+			mapping:  mapping,
+			expected: []string{"mirror.internal/library/busybox", "mirror.internal/library/nginx", "gcr.io/sidecar"},
+		},
+		{
+			name:          "images aren't changed without a mapping",
+			groupResource: schema.GroupResource{Resource: "pods"},
+			obj:           &unstructured.Unstructured{Object: map[string]interface{}{"spec": podSpec()}},
+			specPath:      "spec",
+			expected:      []string{"busybox", "nginx", "gcr.io/sidecar"},
+		},
+		{
+			name:          "other resources aren't changed",
+			groupResource: schema.GroupResource{Resource: "configmaps"},
+			obj:           &unstructured.Unstructured{Object: map[string]interface{}{"spec": podSpec()}},
+			specPath:      "spec",
+			mapping:       mapping,
+			expected:      []string{"busybox", "nginx", "gcr.io/sidecar"},
+		},
+		{
+			name:          "items without containers are left alone",
+			groupResource: schema.GroupResource{Resource: "pods"},
+			obj:           &unstructured.Unstructured{Object: map[string]interface{}{"spec": map[string]interface{}{}}},
+			specPath:      "spec",
+			mapping:       mapping,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			require.NoError(t, mapImageRegistries(test.groupResource, test.obj, test.mapping))
+
+			spec, err := collections.GetMap(test.obj.Object, test.specPath)
+			require.NoError(t, err)
+
+			var images []string
+			for _, containers := range []string{"initContainers", "containers"} {
+				collections.ForEach(spec, containers, func(container map[string]interface{}) error {
+					images = append(images, container["image"].(string))
+					return nil
+				})
+			}
+			assert.Equal(t, test.expected, images)
+		})
+	}
+}
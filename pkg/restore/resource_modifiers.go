@@ -0,0 +1,172 @@
+/*
+Copyright 2017 Heptio Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package restore
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+
+	"github.com/ghodss/yaml"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/pkg/api/v1"
+
+	api "github.com/heptio/ark/pkg/apis/ark/v1"
+	"github.com/heptio/ark/pkg/util/collections"
+	"github.com/heptio/ark/pkg/util/jsonpatch"
+)
+
+// resourceModifierRule is a rule in a restore's resource modifiers ConfigMap, which patches the
+// items it matches before they're restored.
+type resourceModifierRule struct {
+	// Group and Kind select the items the rule applies to. Group is empty for the core group.
+	Group string `json:"group"`
+	Kind  string `json:"kind"`
+	// Name is a regular expression that the names of the items the rule applies to must match in
+	// full. If it's empty, items of all names match.
+	Name string `json:"name"`
+	// Namespaces are the namespaces, as restored into, of the items the rule applies to. If it's
+	// empty, items in all namespaces, and cluster-scoped items, match.
+	Namespaces []string `json:"namespaces"`
+	// Patches is the JSON patch (RFC 6902) applied to matching items.
+	Patches jsonpatch.Patch `json:"patches"`
+}
+
+// resourceModifier is a parsed resourceModifierRule.
+type resourceModifier struct {
+	resourceModifierRule
+
+	name       *regexp.Regexp
+	namespaces *collections.IncludesExcludes
+}
+
+// getResourceModifiers returns the resource modifiers in the ConfigMap named by restore's
+// spec.resourceModifiers, or nil if it's not set.
+func (kr *kubernetesRestorer) getResourceModifiers(restore *api.Restore) ([]resourceModifier, error) {
+	if restore.Spec.ResourceModifiers == "" {
+		return nil, nil
+	}
+
+	configMap, err := kr.configMapClient.ConfigMaps(restore.Namespace).Get(restore.Spec.ResourceModifiers, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("error getting resource modifiers ConfigMap: %v", err)
+	}
+
+	return parseResourceModifiers(configMap)
+}
+
+// parseResourceModifiers parses the rules in each of configMap's values, in order of their keys.
+func parseResourceModifiers(configMap *v1.ConfigMap) ([]resourceModifier, error) {
+	var keys []string
+	for key := range configMap.Data {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var modifiers []resourceModifier
+	for _, key := range keys {
+		var rules []resourceModifierRule
+		if err := yaml.Unmarshal([]byte(configMap.Data[key]), &rules); err != nil {
+			return nil, fmt.Errorf("error parsing resource modifiers in %s of ConfigMap %s: %v", key, configMap.Name, err)
+		}
+
+		for i, rule := range rules {
+			modifier, err := newResourceModifier(rule)
+			if err != nil {
+				return nil, fmt.Errorf("resource modifier %d in %s of ConfigMap %s is invalid: %v", i, key, configMap.Name, err)
+			}
+			modifiers = append(modifiers, modifier)
+		}
+	}
+
+	return modifiers, nil
+}
+
+func newResourceModifier(rule resourceModifierRule) (resourceModifier, error) {
+	if rule.Kind == "" {
+		return resourceModifier{}, fmt.Errorf("kind is required")
+	}
+
+	if err := rule.Patches.Validate(); err != nil {
+		return resourceModifier{}, err
+	}
+
+	namePattern := rule.Name
+	if namePattern == "" {
+		namePattern = ".*"
+	}
+	name, err := regexp.Compile("^(?:" + namePattern + ")$")
+	if err != nil {
+		return resourceModifier{}, fmt.Errorf("invalid name: %v", err)
+	}
+
+	namespaces := collections.NewIncludesExcludes().Includes(rule.Namespaces...)
+	if len(rule.Namespaces) == 0 {
+		namespaces.Includes("*")
+	}
+
+	return resourceModifier{
+		resourceModifierRule: rule,
+		name:                 name,
+		namespaces:           namespaces,
+	}, nil
+}
+
+// appliesTo returns whether the modifier applies to obj, which is being restored into namespace.
+func (m *resourceModifier) appliesTo(namespace string, obj *unstructured.Unstructured) bool {
+	gvk := obj.GroupVersionKind()
+	if gvk.Group != m.Group || gvk.Kind != m.Kind {
+		return false
+	}
+
+	if namespace == "" {
+		if !m.namespaces.ShouldInclude("*") {
+			return false
+		}
+	} else if !m.namespaces.ShouldInclude(namespace) {
+		return false
+	}
+
+	return m.name.MatchString(obj.GetName())
+}
+
+// applyResourceModifiers patches obj, which is being restored into namespace, with each of
+// modifiers that applies to it, in order.
+func applyResourceModifiers(modifiers []resourceModifier, namespace string, obj *unstructured.Unstructured) error {
+	for _, modifier := range modifiers {
+		if !modifier.appliesTo(namespace, obj) {
+			continue
+		}
+
+		data, err := obj.MarshalJSON()
+		if err != nil {
+			return err
+		}
+
+		if data, err = modifier.Patches.Apply(data); err != nil {
+			return err
+		}
+
+		if err := obj.UnmarshalJSON(data); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,196 @@
+/*
+Copyright 2017 Heptio Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package restore
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/pkg/api/v1"
+
+	"github.com/heptio/ark/pkg/util/jsonpatch"
+)
+
+func TestParseResourceModifiers(t *testing.T) {
+	tests := []struct {
+		name          string
+		data          map[string]string
+		expectedRules []resourceModifierRule
+		expectedErr   bool
+	}{
+		{
+			name: "rules are parsed in order of their keys",
+			data: map[string]string{
+				"2-ingresses.yaml": `
+- group: extensions
+  kind: Ingress
+  patches:
+  - op: replace
+    path: /spec/rules/0/host
+    value: dr.example.com
+`,
+				"1-deployments.json": `[{"group": "apps", "kind": "Deployment", "name": "web-.*", "namespaces": ["prod"], "patches": [{"op": "replace", "path": "/spec/replicas", "value": 1}]}]`,
+			},
+			expectedRules: []resourceModifierRule{
+				{
+					Group:      "apps",
+					Kind:       "Deployment",
+					Name:       "web-.*",
+					Namespaces: []string{"prod"},
+					Patches:    jsonpatch.Patch{{Op: "replace", Path: "/spec/replicas", Value: float64(1)}},
+				},
+				{
+					Group:   "extensions",
+					Kind:    "Ingress",
+					Patches: jsonpatch.Patch{{Op: "replace", Path: "/spec/rules/0/host", Value: "dr.example.com"}},
+				},
+			},
+		},
+		{
+			name:        "invalid YAML is an error",
+			data:        map[string]string{"rules": "kind: Deployment"},
+			expectedErr: true,
+		},
+		{
+			name:        "missing kind is an error",
+			data:        map[string]string{"rules": `[{"patches": [{"op": "remove", "path": "/spec/replicas"}]}]`},
+			expectedErr: true,
+		},
+		{
+			name:        "invalid name is an error",
+			data:        map[string]string{"rules": `[{"kind": "Deployment", "name": "web-(", "patches": []}]`},
+			expectedErr: true,
+		},
+		{
+			name:        "invalid patch is an error",
+			data:        map[string]string{"rules": `[{"kind": "Deployment", "patches": [{"op": "remove", "path": "spec"}]}]`},
+			expectedErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			modifiers, err := parseResourceModifiers(&v1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Name: "modifiers"},
+				Data:       test.data,
+			})
+			if test.expectedErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+
+			var rules []resourceModifierRule
+			for _, modifier := range modifiers {
+				rules = append(rules, modifier.resourceModifierRule)
+			}
+			assert.Equal(t, test.expectedRules, rules)
+		})
+	}
+}
+
+func TestResourceModifierAppliesTo(t *testing.T) {
+	deployment := func(name string) *unstructured.Unstructured {
+		return &unstructured.Unstructured{Object: map[string]interface{}{
+			"apiVersion": "apps/v1beta1",
+			"kind":       "Deployment",
+			"metadata":   map[string]interface{}{"name": name},
+		}}
+	}
+
+	tests := []struct {
+		name      string
+		rule      resourceModifierRule
+		namespace string
+		obj       *unstructured.Unstructured
+		expected  bool
+	}{
+		{
+			name:      "group and kind match",
+			rule:      resourceModifierRule{Group: "apps", Kind: "Deployment"},
+			namespace: "ns-1",
+			obj:       deployment("web"),
+			expected:  true,
+		},
+		{
+			name:      "group doesn't match",
+			rule:      resourceModifierRule{Group: "extensions", Kind: "Deployment"},
+			namespace: "ns-1",
+			obj:       deployment("web"),
+		},
+		{
+			name:      "kind doesn't match",
+			rule:      resourceModifierRule{Group: "apps", Kind: "StatefulSet"},
+			namespace: "ns-1",
+			obj:       deployment("web"),
+		},
+		{
+			name:      "name matches",
+			rule:      resourceModifierRule{Group: "apps", Kind: "Deployment", Name: "web-.*"},
+			namespace: "ns-1",
+			obj:       deployment("web-1"),
+			expected:  true,
+		},
+		{
+			name:      "name must match in full",
+			rule:      resourceModifierRule{Group: "apps", Kind: "Deployment", Name: "web"},
+			namespace: "ns-1",
+			obj:       deployment("web-1"),
+		},
+		{
+			name:      "namespace matches",
+			rule:      resourceModifierRule{Group: "apps", Kind: "Deployment", Namespaces: []string{"ns-1", "ns-2"}},
+			namespace: "ns-2",
+			obj:       deployment("web"),
+			expected:  true,
+		},
+		{
+			name:      "namespace doesn't match",
+			rule:      resourceModifierRule{Group: "apps", Kind: "Deployment", Namespaces: []string{"ns-1"}},
+			namespace: "ns-2",
+			obj:       deployment("web"),
+		},
+		{
+			name:     "cluster-scoped items don't match rules with namespaces",
+			rule:     resourceModifierRule{Group: "apps", Kind: "Deployment", Namespaces: []string{"ns-1"}},
+			obj:      deployment("web"),
+			expected: false,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			modifier, err := newResourceModifier(test.rule)
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, modifier.appliesTo(test.namespace, test.obj))
+		})
+	}
+}
+
+func newTestResourceModifiers(t *testing.T, rules ...resourceModifierRule) []resourceModifier {
+	var modifiers []resourceModifier
+	for _, rule := range rules {
+		modifier, err := newResourceModifier(rule)
+		require.NoError(t, err)
+		modifiers = append(modifiers, modifier)
+	}
+	return modifiers
+}
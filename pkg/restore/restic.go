@@ -0,0 +1,139 @@
+/*
+Copyright 2017 Heptio Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package restore
+
+import (
+	"path"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	api "github.com/heptio/ark/pkg/apis/ark/v1"
+	"github.com/heptio/ark/pkg/restic"
+	"github.com/heptio/ark/pkg/util/collections"
+)
+
+// resticVolumes records the PersistentVolumeClaims in a backup, and the PersistentVolumes bound
+// to them, whose data was backed up using restic. Rather than being restored as-is, the claims
+// are restored without their volumes, so that fresh volumes are dynamically provisioned for
+// restic to restore the data into.
+type resticVolumes struct {
+	// claims holds the claims' "namespace/name" keys, using their namespaces in the backup.
+	claims  sets.String
+	volumes sets.String
+	// snapshots maps the claims' keys to the IDs of their restic snapshots.
+	snapshots map[string]string
+}
+
+func (rv *resticVolumes) hasClaim(namespace, name string) bool {
+	return rv != nil && rv.claims.Has(namespace+"/"+name)
+}
+
+func (rv *resticVolumes) hasVolume(name string) bool {
+	return rv != nil && rv.volumes.Has(name)
+}
+
+// snapshot returns the ID of the restic snapshot of the claim, or "" if it has none.
+func (rv *resticVolumes) snapshot(namespace, name string) string {
+	if rv == nil {
+		return ""
+	}
+	return rv.snapshots[namespace+"/"+name]
+}
+
+// getResticVolumes finds the claims used by pods in the backup extracted to dir for volumes
+// that have restic snapshots.
+func (kr *kubernetesRestorer) getResticVolumes(dir string) (*resticVolumes, error) {
+	rv := &resticVolumes{
+		claims:    sets.NewString(),
+		volumes:   sets.NewString(),
+		snapshots: make(map[string]string),
+	}
+
+	namespacesPath := path.Join(dir, api.NamespaceScopedDir)
+	exists, err := kr.fileSystem.DirExists(namespacesPath)
+	if err != nil || !exists {
+		return rv, err
+	}
+
+	nses, err := kr.fileSystem.ReadDir(namespacesPath)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, ns := range nses {
+		podsPath := path.Join(namespacesPath, ns.Name(), "pods")
+		exists, err := kr.fileSystem.DirExists(podsPath)
+		if err != nil {
+			return nil, err
+		}
+		if !exists {
+			continue
+		}
+
+		pods, err := kr.fileSystem.ReadDir(podsPath)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, file := range pods {
+			pod, err := kr.unmarshal(path.Join(podsPath, file.Name()))
+			if err != nil {
+				return nil, err
+			}
+
+			snapshots := restic.GetSnapshots(pod)
+			if len(snapshots) == 0 {
+				continue
+			}
+
+			err = collections.ForEach(pod.Object, "spec.volumes", func(volume map[string]interface{}) error {
+				name, err := collections.GetString(volume, "name")
+				if err != nil {
+					return err
+				}
+				snapshotID, found := snapshots[name]
+				if !found {
+					return nil
+				}
+
+				claimName, err := collections.GetString(volume, "persistentVolumeClaim.claimName")
+				if err != nil {
+					// not a PVC-backed volume
+					return nil
+				}
+				rv.claims.Insert(ns.Name() + "/" + claimName)
+				rv.snapshots[ns.Name()+"/"+claimName] = snapshotID
+
+				// the claim may not have been included in the backup
+				claim, err := kr.unmarshal(path.Join(namespacesPath, ns.Name(), "persistentvolumeclaims", claimName+".json"))
+				if err != nil {
+					return nil
+				}
+				if volumeName, err := collections.GetString(claim.Object, "spec.volumeName"); err == nil {
+					rv.volumes.Insert(volumeName)
+				}
+
+				return nil
+			})
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return rv, nil
+}
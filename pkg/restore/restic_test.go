@@ -0,0 +1,65 @@
+/*
+Copyright 2017 Heptio Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package restore
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetResticVolumes(t *testing.T) {
+	fileSystem := newFakeFileSystem().
+		WithFile("/backup/namespaces/ns-1/pods/with-snapshots.json", []byte(`{
+			"apiVersion": "v1",
+			"kind": "Pod",
+			"metadata": {"annotations": {"snapshot.ark.heptio.com/data": "snapshot-1", "snapshot.ark.heptio.com/scratch": "snapshot-2"}},
+			"spec": {"volumes": [
+				{"name": "data", "persistentVolumeClaim": {"claimName": "data-claim"}},
+				{"name": "scratch", "emptyDir": {}},
+				{"name": "other", "persistentVolumeClaim": {"claimName": "other-claim"}}
+			]}
+		}`)).
+		WithFile("/backup/namespaces/ns-1/pods/without-snapshots.json", []byte(`{
+			"apiVersion": "v1",
+			"kind": "Pod",
+			"spec": {"volumes": [{"name": "data", "persistentVolumeClaim": {"claimName": "unrelated-claim"}}]}
+		}`)).
+		WithFile("/backup/namespaces/ns-1/persistentvolumeclaims/data-claim.json", []byte(`{"apiVersion": "v1", "kind": "PersistentVolumeClaim", "spec": {"volumeName": "pv-1"}}`)).
+		WithFile("/backup/namespaces/ns-1/persistentvolumeclaims/other-claim.json", []byte(`{"apiVersion": "v1", "kind": "PersistentVolumeClaim", "spec": {"volumeName": "pv-2"}}`)).
+		WithDirectory("/backup/namespaces/ns-2")
+
+	restorer := &kubernetesRestorer{fileSystem: fileSystem}
+
+	rv, err := restorer.getResticVolumes("/backup")
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"ns-1/data-claim"}, rv.claims.List())
+	assert.Equal(t, []string{"pv-1"}, rv.volumes.List())
+
+	assert.True(t, rv.hasClaim("ns-1", "data-claim"))
+	assert.False(t, rv.hasClaim("ns-2", "data-claim"))
+	assert.True(t, rv.hasVolume("pv-1"))
+	assert.Equal(t, "snapshot-1", rv.snapshot("ns-1", "data-claim"))
+	assert.Equal(t, "", rv.snapshot("ns-1", "other-claim"))
+
+	var nilVolumes *resticVolumes
+	assert.False(t, nilVolumes.hasClaim("ns-1", "data-claim"))
+	assert.False(t, nilVolumes.hasVolume("pv-1"))
+	assert.Equal(t, "", nilVolumes.snapshot("ns-1", "data-claim"))
+}
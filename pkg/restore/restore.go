@@ -42,16 +42,28 @@ import (
 	api "github.com/heptio/ark/pkg/apis/ark/v1"
 	"github.com/heptio/ark/pkg/client"
 	"github.com/heptio/ark/pkg/cloudprovider"
+	"github.com/heptio/ark/pkg/csi"
 	"github.com/heptio/ark/pkg/discovery"
 	arkv1client "github.com/heptio/ark/pkg/generated/clientset/typed/ark/v1"
+	"github.com/heptio/ark/pkg/logging"
+	"github.com/heptio/ark/pkg/podexec"
+	"github.com/heptio/ark/pkg/restic"
 	"github.com/heptio/ark/pkg/restore/restorers"
+	"github.com/heptio/ark/pkg/util/collections"
 	"github.com/heptio/ark/pkg/util/kube"
 )
 
 // Restorer knows how to restore a backup.
 type Restorer interface {
-	// Restore restores the backup data from backupReader, returning warnings and errors.
-	Restore(restore *api.Restore, backup *api.Backup, backupReader io.Reader) (api.RestoreResult, api.RestoreResult)
+	// Restore restores the backup data from backupReader, returning warnings and errors. If the
+	// backup is incremental, parentReaders must provide the data of each of its ancestors, ordered
+	// from the original full backup to the backup's immediate parent.
+	// The restore's log is written to log.
+	Restore(restore *api.Restore, backup *api.Backup, backupReader io.Reader, parentReaders []io.Reader, log io.Writer) (api.RestoreResult, api.RestoreResult)
+
+	// Preview works out what Restore would do with the same arguments, without changing the
+	// cluster, returning the plan along with warnings and errors.
+	Preview(restore *api.Restore, backup *api.Backup, backupReader io.Reader, parentReaders []io.Reader) (*Plan, api.RestoreResult, api.RestoreResult)
 }
 
 var _ Restorer = &kubernetesRestorer{}
@@ -64,31 +76,58 @@ type kubernetesRestorer struct {
 	discoveryHelper    discovery.Helper
 	dynamicFactory     client.DynamicFactory
 	restorers          map[schema.GroupResource]restorers.ResourceRestorer
+	itemActions        []resolvedItemAction
 	backupService      cloudprovider.BackupService
 	backupClient       arkv1client.BackupsGetter
 	namespaceClient    corev1.NamespaceInterface
+	configMapClient    corev1.ConfigMapsGetter
 	resourcePriorities []string
+	resticRestorer     restic.Restorer
+	podClient          corev1.PodsGetter
+	podCommandExecutor podexec.Executor
 	fileSystem         FileSystem
+
+	// snapshotRestoresEnabled is whether the server can restore volumes from snapshots taken
+	// in its volume snapshot locations.
+	snapshotRestoresEnabled bool
 }
 
 // prioritizeResources takes a list of pre-prioritized resources and a full list of resources to restore,
 // and returns an ordered list of GroupResource-resolved resources in the order that they should be
-// restored.
+// restored. A "*" in priorities marks where the resources that aren't in the list are restored;
+// without one, they're restored after all of the prioritized resources. Prioritized resources that
+// the cluster doesn't serve are skipped. PersistentVolumeClaims are always restored before
+// StatefulSets, so that StatefulSets' pods use their restored claims rather than getting new ones.
 func prioritizeResources(mapper meta.RESTMapper, priorities []string, resources []*metav1.APIResourceList) ([]schema.GroupResource, error) {
-	var ret []schema.GroupResource
+	var before, after []schema.GroupResource
 
 	// set keeps track of resolved GroupResource names
 	set := sets.NewString()
 
-	// start by resolving priorities into GroupResources and adding them to ret
+	// start by resolving priorities into GroupResources and adding them to before or after,
+	// depending on which side of the "*" they're on
+	seenWildcard := false
 	for _, r := range priorities {
+		if r == "*" {
+			if seenWildcard {
+				return nil, fmt.Errorf(`resource priorities may contain only one "*"`)
+			}
+			seenWildcard = true
+			continue
+		}
+
 		gr := schema.ParseGroupResource(r)
 		gvr, err := mapper.ResourceFor(gr.WithVersion(""))
 		if err != nil {
-			return nil, err
+			glog.Warningf("Skipping prioritized resource %s: %v", r, err)
+			continue
 		}
 		gr = gvr.GroupResource()
-		ret = append(ret, gr)
+		if seenWildcard {
+			after = append(after, gr)
+		} else {
+			before = append(before, gr)
+		}
 		set.Insert(gr.String())
 	}
 
@@ -115,20 +154,63 @@ func prioritizeResources(mapper meta.RESTMapper, priorities []string, resources
 	})
 
 	// combine prioritized with by-name
-	ret = append(ret, byName...)
+	ret := append(before, byName...)
+	ret = append(ret, after...)
 
-	return ret, nil
+	return claimsBeforeStatefulSets(ret), nil
 }
 
-// NewKubernetesRestorer creates a new kubernetesRestorer.
+// claimsBeforeStatefulSets returns resources with PersistentVolumeClaims moved to just before
+// StatefulSets if they'd otherwise be restored after them.
+func claimsBeforeStatefulSets(resources []schema.GroupResource) []schema.GroupResource {
+	claims, statefulSets := -1, -1
+	for i, resource := range resources {
+		switch resource.String() {
+		case "persistentvolumeclaims":
+			claims = i
+		case "statefulsets.apps":
+			statefulSets = i
+		}
+	}
+
+	if claims < 0 || statefulSets < 0 || claims < statefulSets {
+		return resources
+	}
+
+	glog.Infof("Restoring persistentvolumeclaims before statefulsets.apps so that their pods use the restored claims")
+
+	var ret []schema.GroupResource
+	for i, resource := range resources {
+		switch i {
+		case claims:
+			continue
+		case statefulSets:
+			ret = append(ret, resources[claims])
+		}
+		ret = append(ret, resource)
+	}
+	return ret
+}
+
+// NewKubernetesRestorer creates a new kubernetesRestorer. itemActions are executed, in order, on
+// each item they apply to before it's created. configMapClient is used to get restores' resource
+// modifiers. podClient and podCommandExecutor are used
+// to run restore exec hooks in restored pods. snapshotRestoresEnabled is whether the server has
+// volume snapshot locations to restore volume snapshots from.
 func NewKubernetesRestorer(
 	discoveryHelper discovery.Helper,
 	dynamicFactory client.DynamicFactory,
 	customRestorers map[string]restorers.ResourceRestorer,
+	itemActions []ItemAction,
 	backupService cloudprovider.BackupService,
 	resourcePriorities []string,
 	backupClient arkv1client.BackupsGetter,
 	namespaceClient corev1.NamespaceInterface,
+	configMapClient corev1.ConfigMapsGetter,
+	resticRestorer restic.Restorer,
+	podClient corev1.PodsGetter,
+	podCommandExecutor podexec.Executor,
+	snapshotRestoresEnabled bool,
 ) (Restorer, error) {
 	mapper := discoveryHelper.Mapper()
 	r := make(map[schema.GroupResource]restorers.ResourceRestorer)
@@ -140,22 +222,49 @@ func NewKubernetesRestorer(
 		r[gvr.GroupResource()] = restorer
 	}
 
+	resolvedItemActions, err := resolveItemActions(mapper, itemActions)
+	if err != nil {
+		return nil, err
+	}
+
 	return &kubernetesRestorer{
 		discoveryHelper:    discoveryHelper,
 		dynamicFactory:     dynamicFactory,
 		restorers:          r,
+		itemActions:        resolvedItemActions,
 		backupService:      backupService,
 		backupClient:       backupClient,
 		namespaceClient:    namespaceClient,
+		configMapClient:    configMapClient,
 		resourcePriorities: resourcePriorities,
+		resticRestorer:     resticRestorer,
+		podClient:          podClient,
+		podCommandExecutor: podCommandExecutor,
 		fileSystem:         &osFileSystem{},
+
+		snapshotRestoresEnabled: snapshotRestoresEnabled,
 	}, nil
 }
 
 // Restore executes a restore into the target Kubernetes cluster according to the restore spec
-// and using data from the provided backup/backup reader. Returns a warnings and errors RestoreResult,
-// respectively, summarizing info about the restore.
-func (kr *kubernetesRestorer) Restore(restore *api.Restore, backup *api.Backup, backupReader io.Reader) (api.RestoreResult, api.RestoreResult) {
+// and using data from the provided backup/backup reader, writing its log to log. Returns a warnings
+// and errors RestoreResult, respectively, summarizing info about the restore.
+func (kr *kubernetesRestorer) Restore(restore *api.Restore, backup *api.Backup, backupReader io.Reader, parentReaders []io.Reader, log io.Writer) (api.RestoreResult, api.RestoreResult) {
+	return kr.restore(restore, backup, backupReader, parentReaders, nil, newRestoreLog(log))
+}
+
+// Preview works out what a restore would do, without creating, changing, or deleting anything in
+// the cluster.
+func (kr *kubernetesRestorer) Preview(restore *api.Restore, backup *api.Backup, backupReader io.Reader, parentReaders []io.Reader) (*Plan, api.RestoreResult, api.RestoreResult) {
+	plan := &Plan{}
+	warnings, errors := kr.restore(restore, backup, backupReader, parentReaders, plan, nil)
+	return plan, warnings, errors
+}
+
+// restore runs a restore or, if plan isn't nil, records what the restore would do in plan.
+func (kr *kubernetesRestorer) restore(restore *api.Restore, backup *api.Backup, backupReader io.Reader, parentReaders []io.Reader, plan *Plan, log *restoreLog) (api.RestoreResult, api.RestoreResult) {
+	log = log.withFields(logging.Fields{"restore": restore.Name, "namespace": restore.Namespace})
+
 	// metav1.LabelSelectorAsSelector converts a nil LabelSelector to a
 	// Nothing Selector, i.e. a selector that matches nothing. We want
 	// a selector that matches everything. This can be accomplished by
@@ -175,14 +284,76 @@ func (kr *kubernetesRestorer) Restore(restore *api.Restore, backup *api.Backup,
 		return api.RestoreResult{}, api.RestoreResult{Ark: []string{err.Error()}}
 	}
 
-	dir, err := kr.unzipAndExtractBackup(backupReader)
+	modifiers, err := kr.getResourceModifiers(restore)
+	if err != nil {
+		log.Errorf("error getting resource modifiers: %v", err)
+		return api.RestoreResult{}, api.RestoreResult{Ark: []string{err.Error()}}
+	}
+
+	dir, err := kr.fileSystem.TempDir("", "")
 	if err != nil {
-		glog.Errorf("error unzipping and extracting: %v", err)
+		log.Errorf("error creating temp dir: %v", err)
 		return api.RestoreResult{}, api.RestoreResult{Ark: []string{err.Error()}}
 	}
 	defer kr.fileSystem.RemoveAll(dir)
 
-	return kr.restoreFromDir(dir, restore, backup, prioritizedResources, selector)
+	// incremental backups are restored by layering each backup in the chain over
+	// its parent, then removing any items that no longer existed when the last
+	// backup was taken.
+	for _, parentReader := range append(parentReaders, backupReader) {
+		if err := kr.unzipAndExtractBackup(parentReader, dir); err != nil {
+			log.Errorf("error unzipping and extracting: %v", err)
+			return api.RestoreResult{}, api.RestoreResult{Ark: []string{err.Error()}}
+		}
+	}
+
+	if len(parentReaders) > 0 {
+		if err := kr.pruneUnindexedItems(dir); err != nil {
+			log.Errorf("error pruning items not in incremental backup: %v", err)
+			return api.RestoreResult{}, api.RestoreResult{Ark: []string{err.Error()}}
+		}
+	}
+
+	conversionWarnings, err := kr.convertUnservedVersions(dir, kr.discoveryHelper.Mapper(), log)
+	if err != nil {
+		log.Errorf("error converting items to versions the cluster serves: %v", err)
+		return api.RestoreResult{}, api.RestoreResult{Ark: []string{err.Error()}}
+	}
+
+	var resticVolumes *resticVolumes
+	if kr.resticRestorer != nil || restore.Spec.DataOnly {
+		if resticVolumes, err = kr.getResticVolumes(dir); err != nil {
+			log.Errorf("error finding volumes backed up using restic: %v", err)
+			return api.RestoreResult{}, api.RestoreResult{Ark: []string{err.Error()}}
+		}
+	}
+
+	itemSelector, err := kr.getItemSelector(dir, restore, selector)
+	if err != nil {
+		log.Errorf("error finding volumes of selected claims: %v", err)
+		return api.RestoreResult{}, api.RestoreResult{Ark: []string{err.Error()}}
+	}
+
+	var warnings, errors api.RestoreResult
+	if restore.Spec.DataOnly {
+		warnings, errors = kr.restoreData(dir, restore, backup, itemSelector, resticVolumes, plan, log)
+	} else {
+		warnings, errors = kr.restoreFromDir(dir, restore, backup, prioritizedResources, itemSelector, resticVolumes, modifiers, plan, log)
+	}
+	if restore.Spec.Prune {
+		// pruning deletes whatever isn't in the backup, so it's only safe once all of the backup's
+		// items have been restored.
+		if hasErrors(errors) {
+			addArkError(&warnings, fmt.Errorf("not pruning since the restore has errors"))
+		} else {
+			w, e := kr.prune(dir, restore, backup, itemSelector, plan, log)
+			merge(&warnings, &w)
+			merge(&errors, &e)
+		}
+	}
+	merge(&warnings, &conversionWarnings)
+
+	return warnings, errors
 }
 
 // restoreFromDir executes a restore based on backup data contained within a local
@@ -192,9 +363,21 @@ func (kr *kubernetesRestorer) restoreFromDir(
 	restore *api.Restore,
 	backup *api.Backup,
 	prioritizedResources []schema.GroupResource,
-	selector labels.Selector,
-) (api.RestoreResult, api.RestoreResult) {
-	warnings, errors := api.RestoreResult{}, api.RestoreResult{}
+	selector *itemSelector,
+	resticVolumes *resticVolumes,
+	modifiers []resourceModifier,
+	plan *Plan,
+	log *restoreLog,
+) (warnings, errors api.RestoreResult) {
+	// exec hooks run while the rest of the restore continues, but the restore isn't done until
+	// they've finished.
+	hooks := new(hookTracker)
+	defer func() {
+		w, e := hooks.wait()
+		merge(&warnings, &w)
+		merge(&errors, &e)
+		restore.Status.HooksAttempted, restore.Status.HooksFailed = hooks.attempted, hooks.failed
+	}()
 
 	// cluster-scoped
 	clusterPath := path.Join(dir, api.ClusterScopedDir)
@@ -202,8 +385,10 @@ func (kr *kubernetesRestorer) restoreFromDir(
 	if err != nil {
 		errors.Cluster = []string{err.Error()}
 	}
-	if exists {
-		w, e := kr.restoreNamespace(restore, "", clusterPath, prioritizedResources, selector, backup)
+	if restore.Spec.IncludeClusterResources != nil && !*restore.Spec.IncludeClusterResources {
+		log.Infof("Skipping cluster-scoped resources since the restore excludes them")
+	} else if exists {
+		w, e := kr.restoreNamespace(restore, "", clusterPath, prioritizedResources, selector, backup, resticVolumes, modifiers, hooks, plan, log)
 		merge(&warnings, &w)
 		merge(&errors, &e)
 	}
@@ -234,10 +419,10 @@ func (kr *kubernetesRestorer) restoreFromDir(
 		nsPath := path.Join(namespacesPath, ns.Name())
 
 		if !namespacesToRestore.Has("*") && !namespacesToRestore.Has(ns.Name()) {
-			glog.Infof("Skipping namespace %s", ns.Name())
+			log.Infof("Skipping namespace %s", ns.Name())
 			continue
 		}
-		w, e := kr.restoreNamespace(restore, ns.Name(), nsPath, prioritizedResources, selector, backup)
+		w, e := kr.restoreNamespace(restore, ns.Name(), nsPath, prioritizedResources, selector, backup, resticVolumes, modifiers, hooks, plan, log)
 		merge(&warnings, &w)
 		merge(&errors, &e)
 	}
@@ -258,6 +443,19 @@ func merge(a, b *api.RestoreResult) {
 	}
 }
 
+// hasErrors returns whether r has any errors.
+func hasErrors(r api.RestoreResult) bool {
+	if len(r.Ark) > 0 || len(r.Cluster) > 0 {
+		return true
+	}
+	for _, errs := range r.Namespaces {
+		if len(errs) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
 // addArkError appends an error to the provided RestoreResult's Ark list.
 func addArkError(r *api.RestoreResult, err error) {
 	r.Ark = append(r.Ark, err.Error())
@@ -284,15 +482,20 @@ func (kr *kubernetesRestorer) restoreNamespace(
 	nsName string,
 	nsPath string,
 	prioritizedResources []schema.GroupResource,
-	labelSelector labels.Selector,
+	selector *itemSelector,
 	backup *api.Backup,
+	resticVolumes *resticVolumes,
+	modifiers []resourceModifier,
+	hooks *hookTracker,
+	plan *Plan,
+	log *restoreLog,
 ) (api.RestoreResult, api.RestoreResult) {
 	warnings, errors := api.RestoreResult{}, api.RestoreResult{}
 
 	if nsName == "" {
-		glog.Info("Restoring cluster-scoped resources")
+		log.Infof("Restoring cluster-scoped resources")
 	} else {
-		glog.Infof("Restoring namespace %s", nsName)
+		log.Infof("Restoring namespace %s", nsName)
 	}
 
 	resourceDirs, err := kr.fileSystem.ReadDir(nsPath)
@@ -314,16 +517,18 @@ func (kr *kubernetesRestorer) restoreNamespace(
 			nsName = target
 		}
 
-		// ensure namespace exists
+		// ensure namespace exists, unless previewing
 		ns := &v1.Namespace{
 			ObjectMeta: metav1.ObjectMeta{
 				Name: nsName,
 			},
 		}
 
-		if _, err := kube.EnsureNamespaceExists(ns, kr.namespaceClient); err != nil {
-			addArkError(&errors, err)
-			return warnings, errors
+		if plan == nil {
+			if _, err := kube.EnsureNamespaceExists(ns, kr.namespaceClient); err != nil {
+				addArkError(&errors, err)
+				return warnings, errors
+			}
 		}
 	}
 
@@ -335,7 +540,7 @@ func (kr *kubernetesRestorer) restoreNamespace(
 
 		resourcePath := path.Join(nsPath, rscDir.Name())
 
-		w, e := kr.restoreResourceForNamespace(nsName, resourcePath, labelSelector, restore, backup)
+		w, e := kr.restoreResourceForNamespace(nsName, resourcePath, selector, restore, backup, resticVolumes, modifiers, hooks, plan, log)
 		merge(&warnings, &w)
 		merge(&errors, &e)
 	}
@@ -348,14 +553,20 @@ func (kr *kubernetesRestorer) restoreNamespace(
 func (kr *kubernetesRestorer) restoreResourceForNamespace(
 	namespace string,
 	resourcePath string,
-	labelSelector labels.Selector,
+	selector *itemSelector,
 	restore *api.Restore,
 	backup *api.Backup,
+	resticVolumes *resticVolumes,
+	modifiers []resourceModifier,
+	hooks *hookTracker,
+	plan *Plan,
+	log *restoreLog,
 ) (api.RestoreResult, api.RestoreResult) {
 	warnings, errors := api.RestoreResult{}, api.RestoreResult{}
 	resource := path.Base(resourcePath)
 
-	glog.Infof("Restoring resource %v into namespace %v\n", resource, namespace)
+	log = log.withFields(logging.Fields{"resource": resource, "itemNamespace": namespace})
+	log.Infof("Restoring resource %v into namespace %v", resource, namespace)
 
 	files, err := kr.fileSystem.ReadDir(resourcePath)
 	if err != nil {
@@ -381,14 +592,17 @@ func (kr *kubernetesRestorer) restoreResourceForNamespace(
 			continue
 		}
 
-		if !labelSelector.Matches(labels.Set(obj.GetLabels())) {
+		if !selector.matches(groupResource, namespace, obj) {
 			continue
 		}
 
+		// the item's messages identify it.
+		log := log.withFields(logging.Fields{"itemName": obj.GetName()})
+
 		if restorer == nil {
 			// initialize client & restorer for this Resource. we need
 			// metadata from an object to do this.
-			glog.Infof("Getting client for %s", obj.GroupVersionKind().String())
+			log.Infof("Getting client for %s", obj.GroupVersionKind().String())
 
 			resource := metav1.APIResource{
 				Namespaced: len(namespace) > 0,
@@ -404,13 +618,13 @@ func (kr *kubernetesRestorer) restoreResourceForNamespace(
 
 			restorer = kr.restorers[groupResource]
 			if restorer == nil {
-				glog.Infof("Using default restorer for %s", groupResource.String())
+				log.Infof("Using default restorer for %s", groupResource.String())
 				restorer = restorers.NewBasicRestorer(true)
 			} else {
-				glog.Infof("Using custom restorer for %s", groupResource.String())
+				log.Infof("Using custom restorer for %s", groupResource.String())
 			}
 
-			if restorer.Wait() {
+			if restorer.Wait() && plan == nil {
 				itmWatch, err := resourceClient.Watch(metav1.ListOptions{})
 				if err != nil {
 					addArkError(&errors, fmt.Errorf("error watching for namespace %q, resource %q: %v", namespace, groupResource.String(), err))
@@ -427,11 +641,83 @@ func (kr *kubernetesRestorer) restoreResourceForNamespace(
 			continue
 		}
 
-		if hasControllerOwner(obj.GetOwnerReferences()) {
-			glog.V(4).Infof("%s/%s has a controller owner - skipping", obj.GetNamespace(), obj.GetName())
+		// the namespace the item was backed up from, before any remapping
+		backupNamespace := obj.GetNamespace()
+
+		var resticSnapshots map[string]string
+		if kr.resticRestorer != nil && groupResource.String() == "pods" {
+			resticSnapshots = restic.GetSnapshots(obj)
+		}
+
+		// pods with restic snapshots are restored even if they have a controller, since
+		// their data can only be restored into the original pod.
+		if hasControllerOwner(obj.GetOwnerReferences()) && len(resticSnapshots) == 0 {
+			log.Infof("%s/%s has a controller owner - skipping", obj.GetNamespace(), obj.GetName())
+			if plan != nil {
+				plan.addItem(groupResource, namespace, obj.GetName(), PlanActionSkip, "has a controller owner")
+			}
+			continue
+		}
+
+		switch groupResource.String() {
+		case "persistentvolumes":
+			if resticVolumes.hasVolume(obj.GetName()) {
+				log.Infof("Skipping PersistentVolume %s since its data will be restored using restic into a new volume", obj.GetName())
+				if plan != nil {
+					plan.addItem(groupResource, namespace, obj.GetName(), PlanActionSkip, "data is restored using restic into a new volume")
+				}
+				continue
+			}
+			if csi.SnapshotToRestore(restore, backup, obj.GetName()) != nil {
+				log.Infof("Skipping PersistentVolume %s since it will be provisioned from its CSI snapshot when its claim is restored", obj.GetName())
+				if plan != nil {
+					plan.addItem(groupResource, namespace, obj.GetName(), PlanActionSkip, "provisioned from its CSI snapshot when its claim is restored")
+				}
+				continue
+			}
+			if kr.provisionsVolume(restore, backup, obj.GetName()) {
+				log.Infof("Skipping PersistentVolume %s since it has no snapshot to restore from, and a new volume will be provisioned for its claim", obj.GetName())
+				if plan != nil {
+					plan.addItem(groupResource, namespace, obj.GetName(), PlanActionSkip, "no snapshot to restore from, so a new volume is provisioned for its claim")
+				}
+				continue
+			}
+		case "persistentvolumeclaims":
+			volumeName, _ := collections.GetString(obj.Object, "spec.volumeName")
+
+			switch {
+			case resticVolumes.hasClaim(backupNamespace, obj.GetName()):
+				log.Infof("Resetting volume binding of PersistentVolumeClaim %s/%s so its data can be restored using restic", backupNamespace, obj.GetName())
+				kube.ResetPVCVolumeBinding(obj)
+			case volumeName != "" && kr.provisionsVolume(restore, backup, volumeName):
+				log.Infof("Resetting volume binding of PersistentVolumeClaim %s/%s so a new volume is provisioned for it", backupNamespace, obj.GetName())
+				kube.ResetPVCVolumeBinding(obj)
+			}
+		}
+
+		// restorers may create volumes and snapshots when preparing items, so previews stop here
+		if plan != nil {
+			if err := previewItem(plan, resourceClient, restore, backup, groupResource, namespace, obj, resticSnapshots); err != nil {
+				addToResult(&errors, namespace, fmt.Errorf("error previewing %s: %v", fullPath, err))
+			}
 			continue
 		}
 
+		// work out which volumes' data is restored before restorers change the item
+		spec, _ := obj.Object["spec"].(map[string]interface{})
+		volumes := volumesToRestore(restore, backup, groupResource, namespace, obj.GetName(), spec, resticSnapshots)
+
+		// the data of local and hostPath PVs that was backed up through the node agents is
+		// restored through them once the PV exists
+		var hostVolumeRestorer restic.HostVolumeRestorer
+		var hostVolumeSnapshot, hostVolumeRepoNamespace string
+		if groupResource.String() == "persistentvolumes" && (restore.Spec.RestorePVs == nil || *restore.Spec.RestorePVs) {
+			if hostVolumeRestorer, _ = kr.resticRestorer.(restic.HostVolumeRestorer); hostVolumeRestorer != nil {
+				hostVolumeSnapshot = restic.GetHostVolumeSnapshot(obj)
+				hostVolumeRepoNamespace = restic.HostVolumeRepoNamespace(obj.Object)
+			}
+		}
+
 		preparedObj, warning, err := restorer.Prepare(obj, restore, backup)
 		if warning != nil {
 			addToResult(&warnings, namespace, fmt.Errorf("warning preparing %s: %v", fullPath, warning))
@@ -450,17 +736,62 @@ func (kr *kubernetesRestorer) restoreResourceForNamespace(
 		// necessary because we may have remapped the namespace
 		unstructuredObj.SetNamespace(namespace)
 
+		if err := mapImageRegistries(groupResource, unstructuredObj, restore.Spec.RegistryMapping); err != nil {
+			addToResult(&errors, namespace, fmt.Errorf("error mapping image registries of %s: %v", fullPath, err))
+			continue
+		}
+
+		if err := applyResourceModifiers(modifiers, namespace, unstructuredObj); err != nil {
+			addToResult(&errors, namespace, fmt.Errorf("error applying resource modifiers to %s: %v", fullPath, err))
+			continue
+		}
+
+		skip, err := kr.executeItemActions(groupResource, namespace, unstructuredObj, restore)
+		if err != nil {
+			addToResult(&errors, namespace, fmt.Errorf("error executing item actions on %s: %v", fullPath, err))
+			continue
+		}
+		if skip {
+			log.Infof("Skipping %s since an item action skipped it", fullPath)
+			continue
+		}
+
 		// add an ark-restore label to each resource for easy ID
 		addLabel(unstructuredObj, api.RestoreLabelKey, restore.Name)
 
-		glog.Infof("Restoring item %v", unstructuredObj.GetName())
+		var podHooks []podHook
+		if groupResource.String() == "pods" {
+			if podHooks, err = getPodHooks(restore, namespace, labels.Set(unstructuredObj.GetLabels())); err != nil {
+				addToResult(&errors, namespace, err)
+			}
+			if err := addInitContainers(unstructuredObj, podHooks); err != nil {
+				addToResult(&errors, namespace, fmt.Errorf("error adding init hooks to %s: %v", fullPath, err))
+				continue
+			}
+		}
+
+		log.Infof("Restoring item %v", unstructuredObj.GetName())
 		_, err = resourceClient.Create(unstructuredObj)
 		if apierrors.IsAlreadyExists(err) {
-			addToResult(&warnings, namespace, err)
-			continue
+			// namespaces are never replaced, since deleting one would delete everything in it
+			switch policy := existingResourcePolicy(restore, groupResource); {
+			case policy == api.ExistingResourcePolicyPatch,
+				policy == api.ExistingResourcePolicyReplace && groupResource.String() == "namespaces":
+				log.Infof("Patching existing %s", fullPath)
+				if err := patchExisting(resourceClient, unstructuredObj); err != nil {
+					addToResult(&errors, namespace, fmt.Errorf("error patching existing %s: %v", fullPath, err))
+				}
+				continue
+			case policy == api.ExistingResourcePolicyReplace:
+				log.Infof("Replacing existing %s", fullPath)
+				err = replaceExisting(resourceClient, unstructuredObj)
+			default:
+				addToResult(&warnings, namespace, err)
+				continue
+			}
 		}
 		if err != nil {
-			glog.Errorf("error restoring %s: %v", unstructuredObj.GetName(), err)
+			log.Errorf("error restoring %s: %v", unstructuredObj.GetName(), err)
 			addToResult(&errors, namespace, fmt.Errorf("error restoring %s: %v", fullPath, err))
 			continue
 		}
@@ -468,6 +799,27 @@ func (kr *kubernetesRestorer) restoreResourceForNamespace(
 		if waiter != nil {
 			waiter.RegisterItem(unstructuredObj.GetName())
 		}
+
+		recordRestoredVolumes(restore, volumes)
+
+		kr.runExecHooks(hooks, namespace, unstructuredObj.GetName(), podHooks)
+
+		if len(resticSnapshots) > 0 {
+			errs := kr.resticRestorer.RestorePodVolumes(restore, backupNamespace, namespace, unstructuredObj.GetName(), resticSnapshots)
+			for _, err := range errs {
+				addToResult(&errors, namespace, err)
+			}
+			restore.Status.PodVolumeRestoresAttempted += len(resticSnapshots)
+			restore.Status.PodVolumeRestoresFailed += len(errs)
+		}
+
+		if hostVolumeSnapshot != "" {
+			restore.Status.PodVolumeRestoresAttempted++
+			if err := restoreHostVolume(hostVolumeRestorer, restore, unstructuredObj, hostVolumeRepoNamespace, hostVolumeSnapshot); err != nil {
+				addToResult(&errors, namespace, err)
+				restore.Status.PodVolumeRestoresFailed++
+			}
+		}
 	}
 
 	if waiter != nil {
@@ -479,6 +831,61 @@ func (kr *kubernetesRestorer) restoreResourceForNamespace(
 	return warnings, errors
 }
 
+// executeItemActions executes the item actions that apply to obj, in order, returning whether one
+// of them skipped it. Actions after the one that skips obj aren't executed.
+func (kr *kubernetesRestorer) executeItemActions(groupResource schema.GroupResource, namespace string, obj *unstructured.Unstructured, restore *api.Restore) (bool, error) {
+	for _, action := range kr.itemActions {
+		if !action.Matches(groupResource.String(), namespace, labels.Set(obj.GetLabels())) {
+			continue
+		}
+
+		skip, err := action.Execute(obj, restore)
+		if err != nil {
+			return false, err
+		}
+		if skip {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// restoreHostVolume restores the snapshot snapshotID, from the repository for repoNamespace, into
+// the local or hostPath PersistentVolume pv, on the node that its node affinity names once any
+// node mapping has been applied.
+func restoreHostVolume(restorer restic.HostVolumeRestorer, restore *api.Restore, pv *unstructured.Unstructured, repoNamespace, snapshotID string) error {
+	path, node, err := kube.GetHostVolume(pv.Object)
+	if err != nil {
+		return fmt.Errorf("error restoring data of PersistentVolume %s: %v", pv.GetName(), err)
+	}
+	if path == "" || node == "" {
+		return fmt.Errorf("error restoring data of PersistentVolume %s: its node affinity doesn't name a single node", pv.GetName())
+	}
+
+	return restorer.RestoreHostVolume(restore, repoNamespace, pv.GetName(), node, path, snapshotID)
+}
+
+// provisionsVolume returns whether the named PersistentVolume is left out of the restore so that a
+// new volume is dynamically provisioned for its claim, which is the case when the restore's
+// unsnapshotted volume policy is Provision and the volume isn't being restored from a snapshot.
+func (kr *kubernetesRestorer) provisionsVolume(restore *api.Restore, backup *api.Backup, name string) bool {
+	if restore.Spec.UnsnapshottedVolumePolicy != api.UnsnapshottedVolumePolicyProvision {
+		return false
+	}
+
+	// volumes with CSI snapshots are provisioned from them
+	if csi.SnapshotToRestore(restore, backup, name) != nil {
+		return false
+	}
+
+	if restore.Spec.RestorePVs != nil && !*restore.Spec.RestorePVs {
+		return true
+	}
+
+	return !kr.snapshotRestoresEnabled || backup.Status.VolumeBackups[name] == nil
+}
+
 // addLabel applies the specified key/value to an object as a label.
 func addLabel(obj *unstructured.Unstructured, key string, val string) {
 	labels := obj.GetLabels()
@@ -496,12 +903,7 @@ func addLabel(obj *unstructured.Unstructured, key string, val string) {
 // owner ref. Used to identify whether or not an object should be explicitly
 // recreated during a restore.
 func hasControllerOwner(refs []metav1.OwnerReference) bool {
-	for _, ref := range refs {
-		if ref.Controller != nil && *ref.Controller {
-			return true
-		}
-	}
-	return false
+	return controllerRef(refs) != nil
 }
 
 // unmarshal reads the specified file, unmarshals the JSON contained within it
@@ -522,27 +924,77 @@ func (kr *kubernetesRestorer) unmarshal(filePath string) (*unstructured.Unstruct
 	return &obj, nil
 }
 
-// unzipAndExtractBackup extracts a reader on a gzipped tarball to a local temp directory
-func (kr *kubernetesRestorer) unzipAndExtractBackup(src io.Reader) (string, error) {
-	gzr, err := gzip.NewReader(src)
+// pruneUnindexedItems removes any item files from dir that aren't listed in the item index
+// at the root of dir.
+func (kr *kubernetesRestorer) pruneUnindexedItems(dir string) error {
+	data, err := kr.fileSystem.ReadFile(path.Join(dir, api.ItemIndexFile))
 	if err != nil {
-		glog.Errorf("error creating gzip reader: %v", err)
-		return "", err
+		return fmt.Errorf("error reading item index: %v", err)
+	}
+
+	index := make(map[string]string)
+	if err := json.Unmarshal(data, &index); err != nil {
+		return fmt.Errorf("error parsing item index: %v", err)
 	}
-	defer gzr.Close()
 
-	return kr.readBackup(tar.NewReader(gzr))
+	for _, scopeDir := range []string{api.ClusterScopedDir, api.NamespaceScopedDir} {
+		if err := kr.pruneUnindexedItemsInDir(dir, scopeDir, index); err != nil {
+			return err
+		}
+	}
+
+	return nil
 }
 
-// readBackup extracts a tar reader to a local directory/file tree within a
-// temp directory.
-func (kr *kubernetesRestorer) readBackup(tarRdr *tar.Reader) (string, error) {
-	dir, err := kr.fileSystem.TempDir("", "")
+// pruneUnindexedItemsInDir recursively removes any files under root/relPath whose paths relative
+// to root aren't in index.
+func (kr *kubernetesRestorer) pruneUnindexedItemsInDir(root, relPath string, index map[string]string) error {
+	exists, err := kr.fileSystem.DirExists(path.Join(root, relPath))
+	if err != nil || !exists {
+		return err
+	}
+
+	entries, err := kr.fileSystem.ReadDir(path.Join(root, relPath))
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		entryPath := path.Join(relPath, entry.Name())
+
+		if entry.IsDir() {
+			if err := kr.pruneUnindexedItemsInDir(root, entryPath, index); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if _, found := index[entryPath]; !found {
+			glog.V(4).Infof("Removing %s since it's not in the incremental backup's index", entryPath)
+			if err := kr.fileSystem.RemoveAll(path.Join(root, entryPath)); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// unzipAndExtractBackup extracts a reader on a gzipped tarball into dir.
+func (kr *kubernetesRestorer) unzipAndExtractBackup(src io.Reader, dir string) error {
+	gzr, err := gzip.NewReader(src)
 	if err != nil {
-		glog.Errorf("error creating temp dir: %v", err)
-		return "", err
+		glog.Errorf("error creating gzip reader: %v", err)
+		return err
 	}
+	defer gzr.Close()
+
+	return kr.readBackup(tar.NewReader(gzr), dir)
+}
 
+// readBackup extracts a tar reader to a local directory/file tree within dir,
+// overwriting any files that already exist.
+func (kr *kubernetesRestorer) readBackup(tarRdr *tar.Reader, dir string) error {
 	for {
 		header, err := tarRdr.Next()
 
@@ -552,7 +1004,7 @@ func (kr *kubernetesRestorer) readBackup(tarRdr *tar.Reader) (string, error) {
 		}
 		if err != nil {
 			glog.Errorf("error reading tar: %v", err)
-			return "", err
+			return err
 		}
 
 		target := path.Join(dir, header.Name)
@@ -562,7 +1014,7 @@ func (kr *kubernetesRestorer) readBackup(tarRdr *tar.Reader) (string, error) {
 			err := kr.fileSystem.MkdirAll(target, header.FileInfo().Mode())
 			if err != nil {
 				glog.Errorf("mkdirall error: %v", err)
-				return "", err
+				return err
 			}
 
 		case tar.TypeReg:
@@ -570,22 +1022,28 @@ func (kr *kubernetesRestorer) readBackup(tarRdr *tar.Reader) (string, error) {
 			err := kr.fileSystem.MkdirAll(path.Dir(target), header.FileInfo().Mode())
 			if err != nil {
 				glog.Errorf("mkdirall error: %v", err)
-				return "", err
+				return err
 			}
 
 			// create the file
 			file, err := kr.fileSystem.Create(target)
 			if err != nil {
-				return "", err
+				return err
 			}
-			defer file.Close()
 
-			if _, err := io.Copy(file, tarRdr); err != nil {
+			// close each file as soon as it's written, since a backup can have more items
+			// than the process can have open files.
+			_, err = io.Copy(file, tarRdr)
+			closeErr := file.Close()
+			if err != nil {
 				glog.Errorf("error copying: %v", err)
-				return "", err
+				return err
+			}
+			if closeErr != nil {
+				return closeErr
 			}
 		}
 	}
 
-	return dir, nil
+	return nil
 }
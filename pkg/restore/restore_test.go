@@ -18,13 +18,16 @@ package restore
 
 import (
 	"encoding/json"
+	"errors"
 	"io"
 	"os"
 	"testing"
 
 	"github.com/spf13/afero"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/labels"
@@ -36,12 +39,11 @@ import (
 	api "github.com/heptio/ark/pkg/apis/ark/v1"
 	"github.com/heptio/ark/pkg/restore/restorers"
 	"github.com/heptio/ark/pkg/util/collections"
+	"github.com/heptio/ark/pkg/util/jsonpatch"
 	. "github.com/heptio/ark/pkg/util/test"
 )
 
 func TestPrioritizeResources(t *testing.T) {
-	mapper := &FakeMapper{AutoReturnResource: true}
-	priorities := []string{"namespaces", "configmaps", "pods"}
 	resources := []*metav1.APIResourceList{
 		{
 			GroupVersion: "v1",
@@ -58,21 +60,102 @@ func TestPrioritizeResources(t *testing.T) {
 		},
 	}
 
-	result, err := prioritizeResources(mapper, priorities, resources)
-	if err != nil {
-		t.Fatalf("unexpected error: %v", err)
+	tests := []struct {
+		name        string
+		mapper      *FakeMapper
+		priorities  []string
+		expected    []string
+		expectedErr bool
+	}{
+		{
+			name:       "unprioritized resources are restored alphabetically after prioritized ones",
+			mapper:     &FakeMapper{AutoReturnResource: true},
+			priorities: []string{"namespaces", "configmaps", "pods"},
+			expected:   []string{"namespaces", "configmaps", "pods", "aaa", "bbb", "ddd", "ooo", "sss"},
+		},
+		{
+			name:       "unprioritized resources are restored in place of the wildcard",
+			mapper:     &FakeMapper{AutoReturnResource: true},
+			priorities: []string{"namespaces", "configmaps", "*", "pods"},
+			expected:   []string{"namespaces", "configmaps", "aaa", "bbb", "ddd", "ooo", "sss", "pods"},
+		},
+		{
+			name:       "a leading wildcard restores prioritized resources last",
+			mapper:     &FakeMapper{AutoReturnResource: true},
+			priorities: []string{"*", "namespaces"},
+			expected:   []string{"aaa", "bbb", "configmaps", "ddd", "ooo", "pods", "sss", "namespaces"},
+		},
+		{
+			name: "prioritized resources that can't be resolved are skipped",
+			mapper: &FakeMapper{
+				Resources: map[schema.GroupVersionResource]schema.GroupVersionResource{
+					schema.GroupVersionResource{Resource: "namespaces"}: schema.GroupVersionResource{Resource: "namespaces"},
+					schema.GroupVersionResource{Resource: "pods"}:       schema.GroupVersionResource{Resource: "pods"},
+				},
+			},
+			priorities: []string{"customresourcedefinitions.apiextensions.k8s.io", "namespaces", "pods"},
+			expected:   []string{"namespaces", "pods", "aaa", "bbb", "configmaps", "ddd", "ooo", "sss"},
+		},
+		{
+			name:        "more than one wildcard is an error",
+			mapper:      &FakeMapper{AutoReturnResource: true},
+			priorities:  []string{"namespaces", "*", "pods", "*"},
+			expectedErr: true,
+		},
 	}
 
-	expected := []string{"namespaces", "configmaps", "pods", "aaa", "bbb", "ddd", "ooo", "sss"}
-	for i := range result {
-		if len(expected) < i+1 {
-			t.Errorf("result is too small: %v", result)
-			break
-		}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result, err := prioritizeResources(test.mapper, test.priorities, resources)
+			if test.expectedErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
 
-		if e, a := expected[i], result[i].Resource; e != a {
-			t.Errorf("index %d, expected %s, got %s", i, e, a)
-		}
+			var actual []string
+			for _, gr := range result {
+				actual = append(actual, gr.Resource)
+			}
+			assert.Equal(t, test.expected, actual)
+		})
+	}
+}
+
+func TestClaimsBeforeStatefulSets(t *testing.T) {
+	var (
+		claims       = schema.GroupResource{Resource: "persistentvolumeclaims"}
+		statefulSets = schema.GroupResource{Group: "apps", Resource: "statefulsets"}
+		pods         = schema.GroupResource{Resource: "pods"}
+		services     = schema.GroupResource{Resource: "services"}
+	)
+
+	tests := []struct {
+		name      string
+		resources []schema.GroupResource
+		expected  []schema.GroupResource
+	}{
+		{
+			name:      "claims already before statefulsets are left alone",
+			resources: []schema.GroupResource{claims, pods, statefulSets, services},
+			expected:  []schema.GroupResource{claims, pods, statefulSets, services},
+		},
+		{
+			name:      "claims after statefulsets are moved before them",
+			resources: []schema.GroupResource{pods, statefulSets, services, claims},
+			expected:  []schema.GroupResource{pods, claims, statefulSets, services},
+		},
+		{
+			name:      "resources without statefulsets are left alone",
+			resources: []schema.GroupResource{services, claims, pods},
+			expected:  []schema.GroupResource{services, claims, pods},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.expected, claimsBeforeStatefulSets(test.resources))
+		})
 	}
 }
 
@@ -112,6 +195,13 @@ func TestRestoreMethod(t *testing.T) {
 			restore:          &api.Restore{Spec: api.RestoreSpec{Namespaces: []string{"b", "c"}}},
 			expectedReadDirs: []string{"bak/cluster", "bak/namespaces", "bak/namespaces/b", "bak/namespaces/c"},
 		},
+		{
+			name:             "cluster-scoped resources are skipped when excluded",
+			fileSystem:       newFakeFileSystem().WithDirectories("bak/cluster", "bak/namespaces/a"),
+			baseDir:          "bak",
+			restore:          NewDefaultTestRestore().WithRestorableNamespace("*").WithIncludeClusterResources(false).Restore,
+			expectedReadDirs: []string{"bak/namespaces", "bak/namespaces/a"},
+		},
 	}
 
 	for _, test := range tests {
@@ -127,7 +217,7 @@ func TestRestoreMethod(t *testing.T) {
 				fileSystem:         test.fileSystem,
 			}
 
-			warnings, errors := restorer.restoreFromDir(test.baseDir, test.restore, nil, nil, nil)
+			warnings, errors := restorer.restoreFromDir(test.baseDir, test.restore, nil, nil, nil, nil, nil, nil, nil)
 
 			assert.Empty(t, warnings.Ark)
 			assert.Empty(t, warnings.Cluster)
@@ -223,7 +313,7 @@ func TestRestoreNamespace(t *testing.T) {
 				fileSystem:         test.fileSystem,
 			}
 
-			warnings, errors := restorer.restoreNamespace(test.restore, test.namespace, test.path, test.prioritizedResources, nil, nil)
+			warnings, errors := restorer.restoreNamespace(test.restore, test.namespace, test.path, test.prioritizedResources, nil, nil, nil, nil, new(hookTracker), nil, nil)
 
 			assert.Empty(t, warnings.Ark)
 			assert.Empty(t, warnings.Cluster)
@@ -243,6 +333,8 @@ func TestRestoreResourceForNamespace(t *testing.T) {
 		labelSelector  labels.Selector
 		fileSystem     *fakeFileSystem
 		restorers      map[schema.GroupResource]restorers.ResourceRestorer
+		itemActions    []ItemAction
+		modifiers      []resourceModifier
 		expectedErrors api.RestoreResult
 		expectedObjs   []unstructured.Unstructured
 	}{
@@ -342,6 +434,79 @@ func TestRestoreResourceForNamespace(t *testing.T) {
 			restorers:     map[schema.GroupResource]restorers.ResourceRestorer{schema.GroupResource{Resource: "foo-resource"}: newFakeCustomRestorer()},
 			expectedObjs:  toUnstructured(newTestConfigMap().WithArkLabel("my-restore").ConfigMap),
 		},
+		{
+			name:          "item action modifies the item",
+			namespace:     "ns-1",
+			resourcePath:  "configmaps",
+			labelSelector: labels.NewSelector(),
+			fileSystem:    newFakeFileSystem().WithFile("configmaps/cm-1.json", newTestConfigMap().ToJSON()),
+			itemActions:   []ItemAction{&fakeItemAction{}},
+			expectedObjs:  toUnstructured(newTestConfigMap().WithLabels(map[string]string{"item-action": "executed"}).WithArkLabel("my-restore").ConfigMap),
+		},
+		{
+			name:          "item action for different resource is not executed",
+			namespace:     "ns-1",
+			resourcePath:  "configmaps",
+			labelSelector: labels.NewSelector(),
+			fileSystem:    newFakeFileSystem().WithFile("configmaps/cm-1.json", newTestConfigMap().ToJSON()),
+			itemActions:   []ItemAction{&fakeItemAction{selector: ResourceSelector{IncludedResources: []string{"secrets"}}}},
+			expectedObjs:  toUnstructured(newTestConfigMap().WithArkLabel("my-restore").ConfigMap),
+		},
+		{
+			name:          "item skipped by item action is not restored",
+			namespace:     "ns-1",
+			resourcePath:  "configmaps",
+			labelSelector: labels.NewSelector(),
+			fileSystem:    newFakeFileSystem().WithFile("configmaps/cm-1.json", newTestConfigMap().ToJSON()),
+			itemActions:   []ItemAction{&fakeItemAction{skip: true}},
+		},
+		{
+			name:          "item action error fails the item",
+			namespace:     "ns-1",
+			resourcePath:  "configmaps",
+			labelSelector: labels.NewSelector(),
+			fileSystem:    newFakeFileSystem().WithFile("configmaps/cm-1.json", newTestConfigMap().ToJSON()),
+			itemActions:   []ItemAction{&fakeItemAction{err: errors.New("bad")}},
+			expectedErrors: api.RestoreResult{
+				Namespaces: map[string][]string{
+					"ns-1": {"error executing item actions on configmaps/cm-1.json: bad"},
+				},
+			},
+		},
+		{
+			name:          "resource modifier patches matching items",
+			namespace:     "ns-1",
+			resourcePath:  "configmaps",
+			labelSelector: labels.NewSelector(),
+			fileSystem: newFakeFileSystem().
+				WithFile("configmaps/cm-1.json", newNamedTestConfigMap("cm-1").ToJSON()).
+				WithFile("configmaps/other.json", newNamedTestConfigMap("other").ToJSON()),
+			modifiers: newTestResourceModifiers(t, resourceModifierRule{
+				Kind:    "ConfigMap",
+				Name:    "cm-.*",
+				Patches: jsonpatch.Patch{{Op: "add", Path: "/metadata/labels", Value: map[string]string{"modified": "true"}}},
+			}),
+			expectedObjs: toUnstructured(
+				newNamedTestConfigMap("cm-1").WithLabels(map[string]string{"modified": "true"}).WithArkLabel("my-restore").ConfigMap,
+				newNamedTestConfigMap("other").WithArkLabel("my-restore").ConfigMap,
+			),
+		},
+		{
+			name:          "resource modifier error fails the item",
+			namespace:     "ns-1",
+			resourcePath:  "configmaps",
+			labelSelector: labels.NewSelector(),
+			fileSystem:    newFakeFileSystem().WithFile("configmaps/cm-1.json", newTestConfigMap().ToJSON()),
+			modifiers: newTestResourceModifiers(t, resourceModifierRule{
+				Kind:    "ConfigMap",
+				Patches: jsonpatch.Patch{{Op: "replace", Path: "/data/missing", Value: "value"}},
+			}),
+			expectedErrors: api.RestoreResult{
+				Namespaces: map[string][]string{
+					"ns-1": {`error applying resource modifiers to configmaps/cm-1.json: error applying operation 0 (replace /data/missing): member "missing" not found`},
+				},
+			},
+		},
 	}
 
 	for _, test := range tests {
@@ -356,10 +521,19 @@ func TestRestoreResourceForNamespace(t *testing.T) {
 			gvk := schema.GroupVersionKind{Group: "", Version: "v1", Kind: "ConfigMap"}
 			dynamicFactory.On("ClientForGroupVersionKind", gvk, resource, test.namespace).Return(resourceClient, nil)
 
+			mapper := &FakeMapper{
+				Resources: map[schema.GroupVersionResource]schema.GroupVersionResource{
+					schema.GroupVersionResource{Resource: "secrets"}: schema.GroupVersionResource{Version: "v1", Resource: "secrets"},
+				},
+			}
+			itemActions, err := resolveItemActions(mapper, test.itemActions)
+			require.NoError(t, err)
+
 			restorer := &kubernetesRestorer{
 				discoveryHelper:    nil,
 				dynamicFactory:     dynamicFactory,
 				restorers:          test.restorers,
+				itemActions:        itemActions,
 				backupService:      nil,
 				backupClient:       nil,
 				namespaceClient:    nil,
@@ -377,7 +551,7 @@ func TestRestoreResourceForNamespace(t *testing.T) {
 				backup = &api.Backup{}
 			)
 
-			warnings, errors := restorer.restoreResourceForNamespace(test.namespace, test.resourcePath, test.labelSelector, restore, backup)
+			warnings, errors := restorer.restoreResourceForNamespace(test.namespace, test.resourcePath, &itemSelector{labels: test.labelSelector}, restore, backup, nil, test.modifiers, new(hookTracker), nil, nil)
 
 			assert.Empty(t, warnings.Ark)
 			assert.Empty(t, warnings.Cluster)
@@ -387,6 +561,93 @@ func TestRestoreResourceForNamespace(t *testing.T) {
 	}
 }
 
+func TestPreviewResourceForNamespace(t *testing.T) {
+	fileSystem := newFakeFileSystem().
+		WithFile("configmaps/cm-1.json", newNamedTestConfigMap("cm-1").ToJSON()).
+		WithFile("configmaps/cm-2.json", newNamedTestConfigMap("cm-2").ToJSON()).
+		WithFile("configmaps/cm-3.json", newNamedTestConfigMap("cm-3").WithControllerOwner().ToJSON())
+
+	tests := []struct {
+		name          string
+		policy        api.ExistingResourcePolicy
+		expectedItems []PlanItem
+	}{
+		{
+			name: "existing items conflict by default",
+			expectedItems: []PlanItem{
+				{Resource: "configmaps", Namespace: "ns-2", Name: "cm-1", Action: PlanActionCreate},
+				{Resource: "configmaps", Namespace: "ns-2", Name: "cm-2", Action: PlanActionConflict},
+				{Resource: "configmaps", Namespace: "ns-2", Name: "cm-3", Action: PlanActionSkip, Reason: "has a controller owner"},
+			},
+		},
+		{
+			name:   "existing items are replaced with the replace policy",
+			policy: api.ExistingResourcePolicyReplace,
+			expectedItems: []PlanItem{
+				{Resource: "configmaps", Namespace: "ns-2", Name: "cm-1", Action: PlanActionCreate},
+				{Resource: "configmaps", Namespace: "ns-2", Name: "cm-2", Action: PlanActionReplace},
+				{Resource: "configmaps", Namespace: "ns-2", Name: "cm-3", Action: PlanActionSkip, Reason: "has a controller owner"},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// the client has no Create expectations, so creating anything fails the test
+			resourceClient := &FakeDynamicClient{}
+			resourceClient.On("Get", "cm-1", metav1.GetOptions{}).Return((*unstructured.Unstructured)(nil), apierrors.NewNotFound(schema.GroupResource{Resource: "configmaps"}, "cm-1"))
+			resourceClient.On("Get", "cm-2", metav1.GetOptions{}).Return(&unstructured.Unstructured{}, nil)
+
+			dynamicFactory := &FakeDynamicFactory{}
+			resource := metav1.APIResource{Name: "configmaps", Namespaced: true}
+			gvk := schema.GroupVersionKind{Group: "", Version: "v1", Kind: "ConfigMap"}
+			dynamicFactory.On("ClientForGroupVersionKind", gvk, resource, "ns-2").Return(resourceClient, nil)
+
+			restorer := &kubernetesRestorer{
+				dynamicFactory: dynamicFactory,
+				fileSystem:     fileSystem,
+			}
+
+			restore := NewTestRestore(api.DefaultNamespace, "my-restore", api.RestorePhaseInProgress).WithMappedNamespace("ns-1", "ns-2").Restore
+			restore.Spec.ExistingResourcePolicy = test.policy
+			plan := &Plan{}
+
+			warnings, errors := restorer.restoreResourceForNamespace("ns-2", "configmaps", &itemSelector{labels: labels.NewSelector()}, restore, &api.Backup{}, nil, nil, new(hookTracker), plan, nil)
+
+			assert.Equal(t, api.RestoreResult{}, warnings)
+			assert.Equal(t, api.RestoreResult{}, errors)
+			assert.Equal(t, test.expectedItems, plan.Items)
+			assert.Empty(t, plan.Volumes)
+		})
+	}
+}
+
+func TestPruneUnindexedItems(t *testing.T) {
+	fileSystem := newFakeFileSystem().
+		WithFile("/backup/index.json", []byte(`{"cluster/persistentvolumes/pv-1.json":"1","namespaces/ns-1/configmaps/cm-1.json":"2"}`)).
+		WithFile("/backup/cluster/persistentvolumes/pv-1.json", []byte("{}")).
+		WithFile("/backup/cluster/persistentvolumes/pv-2.json", []byte("{}")).
+		WithFile("/backup/namespaces/ns-1/configmaps/cm-1.json", []byte("{}")).
+		WithFile("/backup/namespaces/ns-1/configmaps/cm-2.json", []byte("{}")).
+		WithFile("/backup/namespaces/ns-2/secrets/secret-1.json", []byte("{}"))
+
+	restorer := &kubernetesRestorer{fileSystem: fileSystem}
+
+	assert.NoError(t, restorer.pruneUnindexedItems("/backup"))
+
+	for file, expected := range map[string]bool{
+		"/backup/cluster/persistentvolumes/pv-1.json":   true,
+		"/backup/cluster/persistentvolumes/pv-2.json":   false,
+		"/backup/namespaces/ns-1/configmaps/cm-1.json":  true,
+		"/backup/namespaces/ns-1/configmaps/cm-2.json":  false,
+		"/backup/namespaces/ns-2/secrets/secret-1.json": false,
+	} {
+		exists, err := afero.Exists(fileSystem.fs, file)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, exists, file)
+	}
+}
+
 func TestHasControllerOwner(t *testing.T) {
 	tests := []struct {
 		name        string
@@ -450,6 +711,67 @@ func TestHasControllerOwner(t *testing.T) {
 	}
 }
 
+func TestProvisionsVolume(t *testing.T) {
+	tests := []struct {
+		name             string
+		restore          *api.Restore
+		backup           *api.Backup
+		snapshotsEnabled bool
+		expected         bool
+	}{
+		{
+			name:             "retain policy never provisions",
+			restore:          NewDefaultTestRestore().WithRestorePVs(false).Restore,
+			backup:           NewTestBackup().Backup,
+			snapshotsEnabled: true,
+			expected:         false,
+		},
+		{
+			name:             "provision policy provisions volumes when restorePVs is false",
+			restore:          NewDefaultTestRestore().WithRestorePVs(false).WithUnsnapshottedVolumePolicy(api.UnsnapshottedVolumePolicyProvision).Restore,
+			backup:           NewTestBackup().WithSnapshot("pv-1", "snap-1").Backup,
+			snapshotsEnabled: true,
+			expected:         true,
+		},
+		{
+			name:             "provision policy provisions volumes without snapshots",
+			restore:          NewDefaultTestRestore().WithUnsnapshottedVolumePolicy(api.UnsnapshottedVolumePolicyProvision).Restore,
+			backup:           NewTestBackup().WithSnapshot("pv-2", "snap-1").Backup,
+			snapshotsEnabled: true,
+			expected:         true,
+		},
+		{
+			name:             "provision policy provisions volumes when snapshot restores aren't enabled",
+			restore:          NewDefaultTestRestore().WithUnsnapshottedVolumePolicy(api.UnsnapshottedVolumePolicyProvision).Restore,
+			backup:           NewTestBackup().WithSnapshot("pv-1", "snap-1").Backup,
+			snapshotsEnabled: false,
+			expected:         true,
+		},
+		{
+			name:             "provision policy doesn't provision volumes restored from snapshots",
+			restore:          NewDefaultTestRestore().WithUnsnapshottedVolumePolicy(api.UnsnapshottedVolumePolicyProvision).Restore,
+			backup:           NewTestBackup().WithSnapshot("pv-1", "snap-1").Backup,
+			snapshotsEnabled: true,
+			expected:         false,
+		},
+		{
+			name:             "provision policy doesn't provision volumes with CSI snapshots",
+			restore:          NewDefaultTestRestore().WithUnsnapshottedVolumePolicy(api.UnsnapshottedVolumePolicyProvision).Restore,
+			backup:           NewTestBackup().WithCSISnapshot("pv-1", "csi.example.com", "handle-1").Backup,
+			snapshotsEnabled: false,
+			expected:         false,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			restorer := &kubernetesRestorer{snapshotRestoresEnabled: test.snapshotsEnabled}
+
+			assert.Equal(t, test.expected, restorer.provisionsVolume(test.restore, test.backup, "pv-1"))
+		})
+	}
+}
+
 func toUnstructured(objs ...runtime.Object) []unstructured.Unstructured {
 	res := make([]unstructured.Unstructured, 0, len(objs))
 
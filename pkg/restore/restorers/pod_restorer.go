@@ -17,22 +17,35 @@ limitations under the License.
 package restorers
 
 import (
+	"errors"
+	"fmt"
 	"regexp"
+	"sort"
+	"strings"
 
 	"github.com/golang/glog"
 
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
 
 	api "github.com/heptio/ark/pkg/apis/ark/v1"
+	"github.com/heptio/ark/pkg/restic"
 	"github.com/heptio/ark/pkg/util/collections"
 )
 
-type podRestorer struct{}
+type podRestorer struct {
+	resticImage string
+}
 
 var _ ResourceRestorer = &podRestorer{}
 
-func NewPodRestorer() ResourceRestorer {
-	return &podRestorer{}
+// NewPodRestorer creates a restorer for pods. If resticImage isn't empty, pods whose volumes were
+// backed up using restic get an init container, running resticImage, that waits for the
+// volumes' data to be restored.
+func NewPodRestorer(resticImage string) ResourceRestorer {
+	return &podRestorer{
+		resticImage: resticImage,
+	}
 }
 
 func (nsr *podRestorer) Handles(obj runtime.Unstructured, restore *api.Restore) bool {
@@ -117,7 +130,68 @@ func (nsr *podRestorer) Prepare(obj runtime.Unstructured, restore *api.Restore,
 		return nil, nil, err
 	}
 
-	return obj, nil, nil
+	return nsr.prepareInitContainers(obj, spec, restore)
+}
+
+// prepareInitContainers removes any restic init container left over from an earlier restore of
+// the pod, and adds a new one if the pod has restic snapshots to restore.
+func (nsr *podRestorer) prepareInitContainers(obj runtime.Unstructured, spec map[string]interface{}, restore *api.Restore) (runtime.Unstructured, error, error) {
+	var initContainers []interface{}
+	if existing, err := collections.GetSlice(spec, "initContainers"); err == nil {
+		for _, container := range existing {
+			if containerMap, ok := container.(map[string]interface{}); ok && containerMap["name"] == restic.InitContainer {
+				continue
+			}
+			initContainers = append(initContainers, container)
+		}
+	}
+
+	var volumes []string
+	for volume := range restic.GetSnapshots(&unstructured.Unstructured{Object: obj.UnstructuredContent()}) {
+		volumes = append(volumes, volume)
+	}
+	sort.Strings(volumes)
+
+	var warning error
+	switch {
+	case len(volumes) == 0:
+	case nsr.resticImage == "":
+		warning = errors.New("pod has volumes backed up using restic, but restic isn't configured; their data won't be restored")
+	default:
+		initContainers = append([]interface{}{nsr.resticInitContainer(volumes, restore)}, initContainers...)
+	}
+
+	if len(initContainers) > 0 {
+		spec["initContainers"] = initContainers
+	} else {
+		delete(spec, "initContainers")
+	}
+
+	return obj, warning, nil
+}
+
+// resticInitContainer returns an init container that mounts each of volumes and waits for the
+// restic restorer to mark it as restored.
+func (nsr *podRestorer) resticInitContainer(volumes []string, restore *api.Restore) map[string]interface{} {
+	var (
+		volumeMounts []interface{}
+		waits        []string
+	)
+	for _, volume := range volumes {
+		mountPath := "/restores/" + volume
+		volumeMounts = append(volumeMounts, map[string]interface{}{
+			"name":      volume,
+			"mountPath": mountPath,
+		})
+		waits = append(waits, fmt.Sprintf("while [ ! -f %s/.ark/%s ]; do sleep 1; done", mountPath, restore.UID))
+	}
+
+	return map[string]interface{}{
+		"name":         restic.InitContainer,
+		"image":        nsr.resticImage,
+		"command":      []interface{}{"/bin/sh", "-c", strings.Join(waits, "\n")},
+		"volumeMounts": volumeMounts,
+	}
 }
 
 func (nsr *podRestorer) Wait() bool {
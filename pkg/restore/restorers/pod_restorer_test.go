@@ -20,8 +20,12 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+
+	api "github.com/heptio/ark/pkg/apis/ark/v1"
 )
 
 func TestPodRestorerPrepare(t *testing.T) {
@@ -96,7 +100,7 @@ func TestPodRestorerPrepare(t *testing.T) {
 
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
-			restorer := NewPodRestorer()
+			restorer := NewPodRestorer("")
 
 			res, _, err := restorer.Prepare(test.obj, nil, nil)
 
@@ -106,3 +110,68 @@ func TestPodRestorerPrepare(t *testing.T) {
 		})
 	}
 }
+
+func TestPodRestorerPrepareResticInitContainer(t *testing.T) {
+	restore := &api.Restore{ObjectMeta: metav1.ObjectMeta{UID: "restore-uid"}}
+
+	newPod := func(initContainers ...interface{}) *testUnstructured {
+		return NewTestUnstructured().WithName("pod-1").
+			WithAnnotations("snapshot.ark.heptio.com/data", "snapshot.ark.heptio.com/cache").
+			WithSpecField("volumes", []interface{}{}).
+			WithSpecField("containers", []interface{}{}).
+			WithSpecField("initContainers", initContainers)
+	}
+
+	resticInitContainer := map[string]interface{}{
+		"name":    "restic-wait",
+		"image":   "restic/restic",
+		"command": []interface{}{"/bin/sh", "-c", "while [ ! -f /restores/cache/.ark/restore-uid ]; do sleep 1; done\nwhile [ ! -f /restores/data/.ark/restore-uid ]; do sleep 1; done"},
+		"volumeMounts": []interface{}{
+			map[string]interface{}{"name": "cache", "mountPath": "/restores/cache"},
+			map[string]interface{}{"name": "data", "mountPath": "/restores/data"},
+		},
+	}
+	otherInitContainer := map[string]interface{}{"name": "other"}
+	staleInitContainer := map[string]interface{}{"name": "restic-wait", "image": "stale"}
+
+	tests := []struct {
+		name            string
+		resticImage     string
+		obj             *testUnstructured
+		expectedWarning bool
+		expectedRes     *testUnstructured
+	}{
+		{
+			name:        "init container is added before existing ones",
+			resticImage: "restic/restic",
+			obj:         newPod(otherInitContainer),
+			expectedRes: newPod(resticInitContainer, otherInitContainer),
+		},
+		{
+			name:        "stale init container is replaced",
+			resticImage: "restic/restic",
+			obj:         newPod(staleInitContainer, otherInitContainer),
+			expectedRes: newPod(resticInitContainer, otherInitContainer),
+		},
+		{
+			name:            "restic not configured warns and removes stale init container",
+			obj:             newPod(staleInitContainer),
+			expectedWarning: true,
+			expectedRes: NewTestUnstructured().WithName("pod-1").
+				WithAnnotations("snapshot.ark.heptio.com/data", "snapshot.ark.heptio.com/cache").
+				WithSpecField("volumes", []interface{}{}).
+				WithSpecField("containers", []interface{}{}),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			restorer := NewPodRestorer(test.resticImage)
+
+			res, warning, err := restorer.Prepare(test.obj.Unstructured, restore, nil)
+			require.NoError(t, err)
+			assert.Equal(t, test.expectedWarning, warning != nil)
+			assert.Equal(t, test.expectedRes.Unstructured, res)
+		})
+	}
+}
@@ -21,6 +21,7 @@ import (
 	"fmt"
 
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/pkg/api/v1"
 
 	api "github.com/heptio/ark/pkg/apis/ark/v1"
 	"github.com/heptio/ark/pkg/cloudprovider"
@@ -45,18 +46,52 @@ func (sr *persistentVolumeRestorer) Handles(obj runtime.Unstructured, restore *a
 }
 
 func (sr *persistentVolumeRestorer) Prepare(obj runtime.Unstructured, restore *api.Restore, backup *api.Backup) (runtime.Unstructured, error, error) {
+	// local and hostPath volumes are restored onto the nodes their data is restored to
+	if err := kubeutil.MapHostVolumeNode(obj.UnstructuredContent(), restore.Spec.NodeMapping); err != nil {
+		return nil, nil, err
+	}
+
+	// the alpha node affinity annotation, which clusters older than Kubernetes 1.10 use in place
+	// of spec.nodeAffinity, is the only annotation a volume keeps.
+	annotations, _ := collections.GetMap(obj.UnstructuredContent(), "metadata.annotations")
+	nodeAffinity, _ := annotations[v1.AlphaStorageNodeAffinityAnnotation].(string)
+
 	if _, err := resetMetadataAndStatus(obj, false); err != nil {
 		return nil, nil, err
 	}
 
+	if nodeAffinity != "" {
+		metadata, _ := collections.GetMap(obj.UnstructuredContent(), "metadata")
+		metadata["annotations"] = map[string]interface{}{v1.AlphaStorageNodeAffinityAnnotation: nodeAffinity}
+	}
+
 	spec, err := collections.GetMap(obj.UnstructuredContent(), "spec")
 	if err != nil {
 		return nil, nil, err
 	}
 
+	claimNamespace, _ := collections.GetString(spec, "claimRef.namespace")
+	claimName, _ := collections.GetString(spec, "claimRef.name")
+	storageClass, _ := collections.GetString(spec, "storageClassName")
+
 	delete(spec, "claimRef")
 	delete(spec, "storageClassName")
 
+	// a volume whose storage class is mapped keeps the new class, so that it can be bound by
+	// claims whose storage classes are mapped the same way.
+	if target, ok := restore.Spec.StorageClassMapping[storageClass]; ok && storageClass != "" {
+		spec["storageClassName"] = target
+	}
+
+	// if the volume's claim is being restored into a different namespace, pre-bind the volume
+	// to the claim's new location so the volume isn't bound by another claim first.
+	if target, ok := restore.Spec.NamespaceMapping[claimNamespace]; ok && claimName != "" {
+		spec["claimRef"] = map[string]interface{}{
+			"namespace": target,
+			"name":      claimName,
+		}
+	}
+
 	pvName, err := collections.GetString(obj.UnstructuredContent(), "metadata.name")
 	if err != nil {
 		return nil, nil, err
@@ -100,7 +135,12 @@ func (sr *persistentVolumeRestorer) Prepare(obj runtime.Unstructured, restore *a
 	if restoreFromSnapshot {
 		backupInfo := backup.Status.VolumeBackups[pvName]
 
-		volumeID, err := sr.snapshotService.CreateVolumeFromSnapshot(backupInfo.SnapshotID, backupInfo.Type, backupInfo.Iops)
+		snapshotService, err := cloudprovider.SnapshotServiceForLocation(sr.snapshotService, cloudprovider.VolumeSnapshotLocation(backup, backupInfo))
+		if err != nil {
+			return nil, nil, err
+		}
+
+		volumeID, err := snapshotService.CreateVolumeFromSnapshot(backupInfo.SnapshotID, backupInfo.Type, backupInfo.Iops)
 		if err != nil {
 			return nil, nil, err
 		}
@@ -113,7 +153,7 @@ func (sr *persistentVolumeRestorer) Prepare(obj runtime.Unstructured, restore *a
 	var warning error
 
 	if sr.snapshotService == nil && len(backup.Status.VolumeBackups) > 0 {
-		warning = errors.New("unable to restore PV snapshots: Ark server is not configured with a PersistentVolumeProvider")
+		warning = errors.New("unable to restore PV snapshots: Ark server has no volume snapshot locations")
 	}
 
 	return obj, warning, nil
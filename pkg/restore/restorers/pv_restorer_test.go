@@ -93,6 +93,55 @@ func TestPVRestorerPrepare(t *testing.T) {
 				WithSpecField("foo", "bar").
 				Unstructured,
 		},
+		{
+			name: "claimRef should be pointed at the claim's new namespace when it's remapped",
+			obj: NewTestUnstructured().
+				WithName("pv-1").
+				WithSpecField("claimRef", map[string]interface{}{"namespace": "ns-1", "name": "pvc-1", "uid": "uid-1"}).
+				WithSpecField("storageClassName", "foo").
+				Unstructured,
+			restore:     NewDefaultTestRestore().WithRestorePVs(false).WithMappedNamespace("ns-1", "ns-2").Restore,
+			expectedErr: false,
+			expectedRes: NewTestUnstructured().
+				WithName("pv-1").
+				WithSpecField("claimRef", map[string]interface{}{"namespace": "ns-2", "name": "pvc-1"}).
+				Unstructured,
+		},
+		{
+			name: "local volume's node affinity should name the node it's mapped to",
+			obj: NewTestUnstructured().
+				WithName("pv-1").
+				WithSpecField("local", map[string]interface{}{"path": "/mnt/disks/ssd1"}).
+				WithSpecField("nodeAffinity", hostnameAffinity("node-1")).
+				Unstructured,
+			restore:     NewDefaultTestRestore().WithRestorePVs(true).WithMappedNode("node-1", "node-2").Restore,
+			expectedErr: false,
+			expectedRes: NewTestUnstructured().
+				WithName("pv-1").
+				WithSpecField("local", map[string]interface{}{"path": "/mnt/disks/ssd1"}).
+				WithSpecField("nodeAffinity", hostnameAffinity("node-2")).
+				Unstructured,
+		},
+		{
+			name: "alpha node affinity annotation should be kept",
+			obj: NewTestUnstructured().
+				WithName("pv-1").
+				WithMetadataField("annotations", map[string]interface{}{
+					"volume.alpha.kubernetes.io/node-affinity": "{}",
+					"foo": "bar",
+				}).
+				WithSpecField("local", map[string]interface{}{"path": "/mnt/disks/ssd1"}).
+				Unstructured,
+			restore:     NewDefaultTestRestore().WithRestorePVs(true).Restore,
+			expectedErr: false,
+			expectedRes: NewTestUnstructured().
+				WithName("pv-1").
+				WithMetadataField("annotations", map[string]interface{}{
+					"volume.alpha.kubernetes.io/node-affinity": "{}",
+				}).
+				WithSpecField("local", map[string]interface{}{"path": "/mnt/disks/ssd1"}).
+				Unstructured,
+		},
 		{
 			name:        "when RestorePVs=true, AWS volume ID should be set correctly",
 			obj:         NewTestUnstructured().WithName("pv-1").WithSpecField("awsElasticBlockStore", make(map[string]interface{})).Unstructured,
@@ -149,6 +198,20 @@ func TestPVRestorerPrepare(t *testing.T) {
 			expectedWarn:      true,
 			expectedRes:       NewTestUnstructured().WithName("pv-1").WithSpecField("awsElasticBlockStore", make(map[string]interface{})).Unstructured,
 		},
+		{
+			name:        "unmapped storage class is removed",
+			obj:         NewTestUnstructured().WithName("pv-1").WithSpecField("storageClassName", "gp2").Unstructured,
+			restore:     NewDefaultTestRestore().WithMappedStorageClass("standard", "premium").Restore,
+			backup:      &api.Backup{},
+			expectedRes: NewTestUnstructured().WithName("pv-1").WithSpec().Unstructured,
+		},
+		{
+			name:        "mapped storage class is replaced",
+			obj:         NewTestUnstructured().WithName("pv-1").WithSpecField("storageClassName", "gp2").Unstructured,
+			restore:     NewDefaultTestRestore().WithMappedStorageClass("gp2", "gp3").Restore,
+			backup:      &api.Backup{},
+			expectedRes: NewTestUnstructured().WithName("pv-1").WithSpecField("storageClassName", "gp3").Unstructured,
+		},
 	}
 
 	for _, test := range tests {
@@ -211,3 +274,18 @@ func TestPVRestorerReady(t *testing.T) {
 		})
 	}
 }
+
+// hostnameAffinity returns the unstructured node affinity of a volume whose data is on node.
+func hostnameAffinity(node string) map[string]interface{} {
+	return map[string]interface{}{
+		"required": map[string]interface{}{
+			"nodeSelectorTerms": []interface{}{
+				map[string]interface{}{
+					"matchExpressions": []interface{}{
+						map[string]interface{}{"key": "kubernetes.io/hostname", "operator": "In", "values": []interface{}{node}},
+					},
+				},
+			},
+		},
+	}
+}
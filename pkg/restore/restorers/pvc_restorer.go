@@ -17,18 +17,35 @@ limitations under the License.
 package restorers
 
 import (
+	"errors"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
 
 	api "github.com/heptio/ark/pkg/apis/ark/v1"
+	"github.com/heptio/ark/pkg/csi"
 	"github.com/heptio/ark/pkg/util/collections"
+	"github.com/heptio/ark/pkg/util/kube"
 )
 
-type persistentVolumeClaimRestorer struct{}
+// storageClassAnnotation is the beta annotation that older claims use instead of
+// spec.storageClassName.
+const storageClassAnnotation = "volume.beta.kubernetes.io/storage-class"
+
+type persistentVolumeClaimRestorer struct {
+	csiSnapshotter csi.Snapshotter
+}
 
 var _ ResourceRestorer = &persistentVolumeClaimRestorer{}
 
-func NewPersistentVolumeClaimRestorer() ResourceRestorer {
-	return &persistentVolumeClaimRestorer{}
+// NewPersistentVolumeClaimRestorer creates a ResourceRestorer for PersistentVolumeClaims. If
+// csiSnapshotter isn't nil, it's used to restore claims whose volumes were snapshotted using the
+// CSI VolumeSnapshot API from their snapshots.
+func NewPersistentVolumeClaimRestorer(csiSnapshotter csi.Snapshotter) ResourceRestorer {
+	return &persistentVolumeClaimRestorer{
+		csiSnapshotter: csiSnapshotter,
+	}
 }
 
 func (sr *persistentVolumeClaimRestorer) Handles(obj runtime.Unstructured, restore *api.Restore) bool {
@@ -37,8 +54,89 @@ func (sr *persistentVolumeClaimRestorer) Handles(obj runtime.Unstructured, resto
 
 func (sr *persistentVolumeClaimRestorer) Prepare(obj runtime.Unstructured, restore *api.Restore, backup *api.Backup) (runtime.Unstructured, error, error) {
 	res, err := resetMetadataAndStatus(obj, true)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err := mapClaimStorageClass(res, restore); err != nil {
+		return nil, nil, err
+	}
+
+	volumeName, err := collections.GetString(res.UnstructuredContent(), "spec.volumeName")
+	if err != nil {
+		// not bound to a volume
+		return res, nil, nil
+	}
+
+	info := csi.SnapshotToRestore(restore, backup, volumeName)
+	if info == nil {
+		return res, nil, nil
+	}
+
+	claim, ok := res.(*unstructured.Unstructured)
+	if !ok {
+		return nil, nil, fmt.Errorf("unexpected type %T", res)
+	}
+
+	// the claim's volume isn't restored, so a new one has to be provisioned for it
+	kube.ResetPVCVolumeBinding(claim)
+
+	if sr.csiSnapshotter == nil {
+		return claim, errors.New("unable to restore CSI snapshot: Ark server is not configured for CSI snapshots"), nil
+	}
+
+	namespace := claim.GetNamespace()
+	if target, ok := restore.Spec.NamespaceMapping[namespace]; ok {
+		namespace = target
+	}
+	snapshotName := fmt.Sprintf("%s-%s", restore.Name, claim.GetName())
+
+	if err := sr.csiSnapshotter.PrepareRestore(info, namespace, snapshotName); err != nil {
+		return nil, nil, err
+	}
+
+	spec, err := collections.GetMap(claim.Object, "spec")
+	if err != nil {
+		return nil, nil, err
+	}
+	spec["dataSource"] = map[string]interface{}{
+		"apiGroup": csi.GroupName,
+		"kind":     "VolumeSnapshot",
+		"name":     snapshotName,
+	}
+
+	return claim, nil, nil
+}
+
+// mapClaimStorageClass replaces claim's storage class with its target in the restore's storage
+// class mapping, if it has one.
+func mapClaimStorageClass(claim runtime.Unstructured, restore *api.Restore) error {
+	if len(restore.Spec.StorageClassMapping) == 0 {
+		return nil
+	}
+
+	spec, err := collections.GetMap(claim.UnstructuredContent(), "spec")
+	if err != nil {
+		return err
+	}
+	if class, ok := spec["storageClassName"].(string); ok {
+		if target, mapped := restore.Spec.StorageClassMapping[class]; mapped {
+			spec["storageClassName"] = target
+		}
+	}
+
+	annotations, err := collections.GetMap(claim.UnstructuredContent(), "metadata.annotations")
+	if err != nil {
+		// no annotations
+		return nil
+	}
+	if class, ok := annotations[storageClassAnnotation].(string); ok {
+		if target, mapped := restore.Spec.StorageClassMapping[class]; mapped {
+			annotations[storageClassAnnotation] = target
+		}
+	}
 
-	return res, nil, err
+	return nil
 }
 
 func (sr *persistentVolumeClaimRestorer) Wait() bool {
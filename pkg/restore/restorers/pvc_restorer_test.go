@@ -17,13 +17,188 @@ limitations under the License.
 package restorers
 
 import (
+	"errors"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	api "github.com/heptio/ark/pkg/apis/ark/v1"
+	"github.com/heptio/ark/pkg/csi"
+	. "github.com/heptio/ark/pkg/util/test"
 )
 
+type fakeCSISnapshotter struct {
+	err      error
+	restored []string
+}
+
+func (s *fakeCSISnapshotter) CreateSnapshot(claimNamespace, claimName, name string) (*api.CSISnapshotInfo, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (s *fakeCSISnapshotter) PrepareRestore(info *api.CSISnapshotInfo, namespace, name string) error {
+	if s.err != nil {
+		return s.err
+	}
+	s.restored = append(s.restored, info.SnapshotHandle+" -> "+namespace+"/"+name)
+	return nil
+}
+
+func (s *fakeCSISnapshotter) DeleteSnapshot(info *api.CSISnapshotInfo) error {
+	return errors.New("not implemented")
+}
+
+func TestPVCRestorerPrepare(t *testing.T) {
+	newClaim := func() *unstructured.Unstructured {
+		return NewTestUnstructured().
+			WithMetadataField("namespace", "ns-1").
+			WithName("claim-1").
+			WithMetadataField("annotations", map[string]interface{}{"pv.kubernetes.io/bind-completed": "yes"}).
+			WithSpecField("volumeName", "pv-1").
+			Unstructured
+	}
+
+	tests := []struct {
+		name             string
+		snapshotter      *fakeCSISnapshotter
+		restore          *api.Restore
+		backup           *api.Backup
+		expectErr        bool
+		expectWarning    bool
+		expectedRestored []string
+		expectedSpec     map[string]interface{}
+	}{
+		{
+			name:         "claim without a CSI snapshot is restored as-is",
+			snapshotter:  &fakeCSISnapshotter{},
+			restore:      NewTestRestore(api.DefaultNamespace, "restore-1", api.RestorePhase("")).Restore,
+			backup:       NewTestBackup().WithSnapshot("pv-1", "snap-1").Backup,
+			expectedSpec: map[string]interface{}{"volumeName": "pv-1"},
+		},
+		{
+			name:             "claim with a CSI snapshot is restored from it",
+			snapshotter:      &fakeCSISnapshotter{},
+			restore:          NewTestRestore(api.DefaultNamespace, "restore-1", api.RestorePhase("")).Restore,
+			backup:           NewTestBackup().WithCSISnapshot("pv-1", "csi.example.com", "handle-1").Backup,
+			expectedRestored: []string{"handle-1 -> ns-1/restore-1-claim-1"},
+			expectedSpec: map[string]interface{}{
+				"dataSource": map[string]interface{}{"apiGroup": "snapshot.storage.k8s.io", "kind": "VolumeSnapshot", "name": "restore-1-claim-1"},
+			},
+		},
+		{
+			name:             "VolumeSnapshot is created in the claim's mapped namespace",
+			snapshotter:      &fakeCSISnapshotter{},
+			restore:          NewTestRestore(api.DefaultNamespace, "restore-1", api.RestorePhase("")).WithMappedNamespace("ns-1", "ns-2").Restore,
+			backup:           NewTestBackup().WithCSISnapshot("pv-1", "csi.example.com", "handle-1").Backup,
+			expectedRestored: []string{"handle-1 -> ns-2/restore-1-claim-1"},
+			expectedSpec: map[string]interface{}{
+				"dataSource": map[string]interface{}{"apiGroup": "snapshot.storage.k8s.io", "kind": "VolumeSnapshot", "name": "restore-1-claim-1"},
+			},
+		},
+		{
+			name:         "CSI snapshot is ignored when restorePVs is false",
+			snapshotter:  &fakeCSISnapshotter{},
+			restore:      NewTestRestore(api.DefaultNamespace, "restore-1", api.RestorePhase("")).WithRestorePVs(false).Restore,
+			backup:       NewTestBackup().WithCSISnapshot("pv-1", "csi.example.com", "handle-1").Backup,
+			expectedSpec: map[string]interface{}{"volumeName": "pv-1"},
+		},
+		{
+			name:          "CSI snapshot without a snapshotter returns a warning",
+			restore:       NewTestRestore(api.DefaultNamespace, "restore-1", api.RestorePhase("")).Restore,
+			backup:        NewTestBackup().WithCSISnapshot("pv-1", "csi.example.com", "handle-1").Backup,
+			expectWarning: true,
+			expectedSpec:  map[string]interface{}{},
+		},
+		{
+			name:        "VolumeSnapshot failure returns an error",
+			snapshotter: &fakeCSISnapshotter{err: errors.New("create failed")},
+			restore:     NewTestRestore(api.DefaultNamespace, "restore-1", api.RestorePhase("")).Restore,
+			backup:      NewTestBackup().WithCSISnapshot("pv-1", "csi.example.com", "handle-1").Backup,
+			expectErr:   true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var snapshotter csi.Snapshotter
+			if test.snapshotter != nil {
+				snapshotter = test.snapshotter
+			}
+			restorer := NewPersistentVolumeClaimRestorer(snapshotter)
+
+			res, warning, err := restorer.Prepare(newClaim(), test.restore, test.backup)
+			if test.expectErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.expectWarning, warning != nil)
+
+			if test.snapshotter != nil {
+				assert.Equal(t, test.expectedRestored, test.snapshotter.restored)
+			}
+			assert.Equal(t, test.expectedSpec, res.UnstructuredContent()["spec"])
+		})
+	}
+}
+
+func TestPVCRestorerMapsStorageClass(t *testing.T) {
+	tests := []struct {
+		name                string
+		obj                 *unstructured.Unstructured
+		restore             *api.Restore
+		expectedClass       interface{}
+		expectedAnnotations interface{}
+	}{
+		{
+			name:          "storage class is unchanged without a mapping",
+			obj:           NewTestUnstructured().WithName("claim-1").WithSpecField("storageClassName", "gp2").Unstructured,
+			restore:       NewDefaultTestRestore().Restore,
+			expectedClass: "gp2",
+		},
+		{
+			name:          "unmapped storage class is unchanged",
+			obj:           NewTestUnstructured().WithName("claim-1").WithSpecField("storageClassName", "gp2").Unstructured,
+			restore:       NewDefaultTestRestore().WithMappedStorageClass("standard", "premium").Restore,
+			expectedClass: "gp2",
+		},
+		{
+			name:          "mapped storage class is replaced",
+			obj:           NewTestUnstructured().WithName("claim-1").WithSpecField("storageClassName", "gp2").Unstructured,
+			restore:       NewDefaultTestRestore().WithMappedStorageClass("gp2", "gp3").Restore,
+			expectedClass: "gp3",
+		},
+		{
+			name: "mapped storage class annotation is replaced",
+			obj: NewTestUnstructured().
+				WithName("claim-1").
+				WithMetadataField("annotations", map[string]interface{}{storageClassAnnotation: "standard", "foo": "bar"}).
+				WithSpec().
+				Unstructured,
+			restore:             NewDefaultTestRestore().WithMappedStorageClass("standard", "premium").Restore,
+			expectedAnnotations: map[string]interface{}{storageClassAnnotation: "premium", "foo": "bar"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			restorer := NewPersistentVolumeClaimRestorer(nil)
+
+			res, _, err := restorer.Prepare(test.obj, test.restore, NewTestBackup().Backup)
+			require.NoError(t, err)
+
+			spec := res.UnstructuredContent()["spec"].(map[string]interface{})
+			assert.Equal(t, test.expectedClass, spec["storageClassName"])
+
+			metadata := res.UnstructuredContent()["metadata"].(map[string]interface{})
+			assert.Equal(t, test.expectedAnnotations, metadata["annotations"])
+		})
+	}
+}
+
 func TestPVCRestorerReady(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -59,7 +234,7 @@ func TestPVCRestorerReady(t *testing.T) {
 
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
-			restorer := NewPersistentVolumeClaimRestorer()
+			restorer := NewPersistentVolumeClaimRestorer(nil)
 
 			assert.Equal(t, test.expected, restorer.Ready(test.obj))
 		})
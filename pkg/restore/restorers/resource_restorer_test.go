@@ -118,6 +118,11 @@ func (obj *testUnstructured) WithStatusField(field string, value interface{}) *t
 	return obj.withMapEntry("status", field, value)
 }
 
+func (obj *testUnstructured) WithField(field string, value interface{}) *testUnstructured {
+	obj.Object[field] = value
+	return obj
+}
+
 func (obj *testUnstructured) WithAnnotations(fields ...string) *testUnstructured {
 	annotations := make(map[string]interface{})
 	for _, field := range fields {
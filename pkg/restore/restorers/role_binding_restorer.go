@@ -0,0 +1,114 @@
+/*
+Copyright 2017 Heptio Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package restorers
+
+import (
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/runtime"
+
+	api "github.com/heptio/ark/pkg/apis/ark/v1"
+	"github.com/heptio/ark/pkg/util/collections"
+)
+
+const (
+	serviceAccountUserPrefix  = "system:serviceaccount:"
+	serviceAccountGroupPrefix = "system:serviceaccounts:"
+)
+
+type roleBindingRestorer struct{}
+
+var _ ResourceRestorer = &roleBindingRestorer{}
+
+// NewRoleBindingRestorer creates a ResourceRestorer for RoleBindings and ClusterRoleBindings that
+// rewrites the namespaces of their subjects according to the restore's namespace mapping, so
+// that bindings for service accounts in a remapped namespace apply to the restored service
+// accounts.
+func NewRoleBindingRestorer() ResourceRestorer {
+	return &roleBindingRestorer{}
+}
+
+func (rbr *roleBindingRestorer) Handles(obj runtime.Unstructured, restore *api.Restore) bool {
+	return true
+}
+
+func (rbr *roleBindingRestorer) Prepare(obj runtime.Unstructured, restore *api.Restore, backup *api.Backup) (runtime.Unstructured, error, error) {
+	res, err := resetMetadataAndStatus(obj, true)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if len(restore.Spec.NamespaceMapping) == 0 {
+		return res, nil, nil
+	}
+
+	subjects, err := collections.GetSlice(res.UnstructuredContent(), "subjects")
+	if err != nil {
+		// a binding without subjects doesn't reference any namespaces
+		return res, nil, nil
+	}
+
+	for _, s := range subjects {
+		subject, ok := s.(map[string]interface{})
+		if !ok {
+			return nil, nil, fmt.Errorf("unexpected subject type %T", s)
+		}
+
+		kind, _ := collections.GetString(subject, "kind")
+		name, _ := collections.GetString(subject, "name")
+
+		switch kind {
+		case "ServiceAccount":
+			namespace, _ := collections.GetString(subject, "namespace")
+			if target, ok := restore.Spec.NamespaceMapping[namespace]; ok {
+				subject["namespace"] = target
+			}
+		case "User":
+			// service accounts can also be referred to by username, i.e.
+			// system:serviceaccount:<namespace>:<name>
+			if !strings.HasPrefix(name, serviceAccountUserPrefix) {
+				continue
+			}
+			parts := strings.SplitN(strings.TrimPrefix(name, serviceAccountUserPrefix), ":", 2)
+			if len(parts) != 2 {
+				continue
+			}
+			if target, ok := restore.Spec.NamespaceMapping[parts[0]]; ok {
+				subject["name"] = serviceAccountUserPrefix + target + ":" + parts[1]
+			}
+		case "Group":
+			// system:serviceaccounts:<namespace> is the group of all service accounts in a namespace
+			if !strings.HasPrefix(name, serviceAccountGroupPrefix) {
+				continue
+			}
+			if target, ok := restore.Spec.NamespaceMapping[strings.TrimPrefix(name, serviceAccountGroupPrefix)]; ok {
+				subject["name"] = serviceAccountGroupPrefix + target
+			}
+		}
+	}
+
+	return res, nil, nil
+}
+
+func (rbr *roleBindingRestorer) Wait() bool {
+	return false
+}
+
+func (rbr *roleBindingRestorer) Ready(obj runtime.Unstructured) bool {
+	return true
+}
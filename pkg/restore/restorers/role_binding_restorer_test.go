@@ -0,0 +1,94 @@
+/*
+Copyright 2017 Heptio Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package restorers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"k8s.io/apimachinery/pkg/runtime"
+
+	api "github.com/heptio/ark/pkg/apis/ark/v1"
+	. "github.com/heptio/ark/pkg/util/test"
+)
+
+func TestRoleBindingRestorerPrepare(t *testing.T) {
+	subjects := func(subjects ...map[string]interface{}) []interface{} {
+		var res []interface{}
+		for _, s := range subjects {
+			res = append(res, s)
+		}
+		return res
+	}
+
+	tests := []struct {
+		name        string
+		obj         runtime.Unstructured
+		restore     *api.Restore
+		expectedRes runtime.Unstructured
+	}{
+		{
+			name:        "binding without subjects is unchanged",
+			obj:         NewTestUnstructured().WithName("rb-1").Unstructured,
+			restore:     NewDefaultTestRestore().WithMappedNamespace("ns-1", "ns-2").Restore,
+			expectedRes: NewTestUnstructured().WithName("rb-1").Unstructured,
+		},
+		{
+			name: "subjects are unchanged without a namespace mapping",
+			obj: NewTestUnstructured().WithName("rb-1").WithField("subjects", subjects(
+				map[string]interface{}{"kind": "ServiceAccount", "namespace": "ns-1", "name": "sa-1"},
+			)).Unstructured,
+			restore: NewDefaultTestRestore().Restore,
+			expectedRes: NewTestUnstructured().WithName("rb-1").WithField("subjects", subjects(
+				map[string]interface{}{"kind": "ServiceAccount", "namespace": "ns-1", "name": "sa-1"},
+			)).Unstructured,
+		},
+		{
+			name: "service account subjects in mapped namespaces are remapped",
+			obj: NewTestUnstructured().WithName("rb-1").WithField("subjects", subjects(
+				map[string]interface{}{"kind": "ServiceAccount", "namespace": "ns-1", "name": "sa-1"},
+				map[string]interface{}{"kind": "ServiceAccount", "namespace": "ns-3", "name": "sa-2"},
+				map[string]interface{}{"kind": "User", "name": "system:serviceaccount:ns-1:sa-3"},
+				map[string]interface{}{"kind": "User", "name": "jane"},
+				map[string]interface{}{"kind": "Group", "name": "system:serviceaccounts:ns-1"},
+				map[string]interface{}{"kind": "Group", "name": "system:authenticated"},
+			)).Unstructured,
+			restore: NewDefaultTestRestore().WithMappedNamespace("ns-1", "ns-2").Restore,
+			expectedRes: NewTestUnstructured().WithName("rb-1").WithField("subjects", subjects(
+				map[string]interface{}{"kind": "ServiceAccount", "namespace": "ns-2", "name": "sa-1"},
+				map[string]interface{}{"kind": "ServiceAccount", "namespace": "ns-3", "name": "sa-2"},
+				map[string]interface{}{"kind": "User", "name": "system:serviceaccount:ns-2:sa-3"},
+				map[string]interface{}{"kind": "User", "name": "jane"},
+				map[string]interface{}{"kind": "Group", "name": "system:serviceaccounts:ns-2"},
+				map[string]interface{}{"kind": "Group", "name": "system:authenticated"},
+			)).Unstructured,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			restorer := NewRoleBindingRestorer()
+
+			res, warn, err := restorer.Prepare(test.obj, test.restore, nil)
+			require.NoError(t, err)
+			assert.NoError(t, warn)
+			assert.Equal(t, test.expectedRes, res)
+		})
+	}
+}
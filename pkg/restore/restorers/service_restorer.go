@@ -45,16 +45,24 @@ func (sr *serviceRestorer) Prepare(obj runtime.Unstructured, restore *api.Restor
 		return nil, nil, err
 	}
 
-	delete(spec, "clusterIP")
+	// headless services have a clusterIP of "None", which has to be kept for them to stay
+	// headless. Other cluster IPs are allocated anew unless the restore preserves them, since
+	// they may be in use or outside the cluster's service IP range.
+	if clusterIP, _ := spec["clusterIP"].(string); clusterIP != "None" && !restore.Spec.PreserveClusterIPs {
+		delete(spec, "clusterIP")
+	}
 
 	ports, err := collections.GetSlice(obj.UnstructuredContent(), "spec.ports")
 	if err != nil {
 		return nil, nil, err
 	}
 
-	for _, port := range ports {
-		p := port.(map[string]interface{})
-		delete(p, "nodePort")
+	if !restore.Spec.PreserveNodePorts {
+		for _, port := range ports {
+			p := port.(map[string]interface{})
+			delete(p, "nodePort")
+		}
+		delete(spec, "healthCheckNodePort")
 	}
 
 	return obj, nil, nil
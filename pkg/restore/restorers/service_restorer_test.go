@@ -22,12 +22,16 @@ import (
 	"github.com/stretchr/testify/assert"
 
 	"k8s.io/apimachinery/pkg/runtime"
+
+	api "github.com/heptio/ark/pkg/apis/ark/v1"
+	. "github.com/heptio/ark/pkg/util/test"
 )
 
 func TestServiceRestorerPrepare(t *testing.T) {
 	tests := []struct {
 		name        string
 		obj         runtime.Unstructured
+		restore     *api.Restore
 		expectedErr bool
 		expectedRes runtime.Unstructured
 	}{
@@ -56,13 +60,50 @@ func TestServiceRestorerPrepare(t *testing.T) {
 					map[string]interface{}{"foo": "bar"},
 				}).Unstructured,
 		},
+		{
+			name:        "headless service's clusterIP should be kept",
+			obj:         NewTestUnstructured().WithName("svc-1").WithSpecField("clusterIP", "None").WithSpecField("ports", []interface{}{}).Unstructured,
+			expectedErr: false,
+			expectedRes: NewTestUnstructured().WithName("svc-1").WithSpecField("clusterIP", "None").WithSpecField("ports", []interface{}{}).Unstructured,
+		},
+		{
+			name:        "clusterIP should be kept when restore preserves cluster IPs",
+			obj:         NewTestUnstructured().WithName("svc-1").WithSpecField("clusterIP", "10.0.0.1").WithSpecField("ports", []interface{}{}).Unstructured,
+			restore:     NewDefaultTestRestore().WithPreservedClusterIPs(true).Restore,
+			expectedErr: false,
+			expectedRes: NewTestUnstructured().WithName("svc-1").WithSpecField("clusterIP", "10.0.0.1").WithSpecField("ports", []interface{}{}).Unstructured,
+		},
+		{
+			name: "healthCheckNodePort should be deleted",
+			obj: NewTestUnstructured().WithName("svc-1").
+				WithSpecField("healthCheckNodePort", int64(30001)).
+				WithSpecField("ports", []interface{}{}).Unstructured,
+			expectedErr: false,
+			expectedRes: NewTestUnstructured().WithName("svc-1").WithSpecField("ports", []interface{}{}).Unstructured,
+		},
+		{
+			name: "node ports should be kept when restore preserves node ports",
+			obj: NewTestUnstructured().WithName("svc-1").
+				WithSpecField("healthCheckNodePort", int64(30001)).
+				WithSpecField("ports", []interface{}{map[string]interface{}{"nodePort": int64(30000)}}).Unstructured,
+			restore:     NewDefaultTestRestore().WithPreservedNodePorts(true).Restore,
+			expectedErr: false,
+			expectedRes: NewTestUnstructured().WithName("svc-1").
+				WithSpecField("healthCheckNodePort", int64(30001)).
+				WithSpecField("ports", []interface{}{map[string]interface{}{"nodePort": int64(30000)}}).Unstructured,
+		},
 	}
 
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
 			restorer := NewServiceRestorer()
 
-			res, _, err := restorer.Prepare(test.obj, nil, nil)
+			restore := test.restore
+			if restore == nil {
+				restore = NewDefaultTestRestore().Restore
+			}
+
+			res, _, err := restorer.Prepare(test.obj, restore, nil)
 
 			if assert.Equal(t, test.expectedErr, err != nil) {
 				assert.Equal(t, test.expectedRes, res)
@@ -0,0 +1,201 @@
+/*
+Copyright 2017 Heptio Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package restore
+
+import (
+	"path"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	api "github.com/heptio/ark/pkg/apis/ark/v1"
+	"github.com/heptio/ark/pkg/util/collections"
+)
+
+// itemSelector decides which items in a backup are restored, based on the restore's label
+// selector and whether it includes cluster-scoped resources.
+type itemSelector struct {
+	labels labels.Selector
+
+	// volumes holds the names of the PersistentVolumes bound to claims that the label selector
+	// matches. They're restored along with their claims, since volumes rarely carry the labels of
+	// the workloads using them.
+	volumes sets.String
+
+	// claims holds the <NAMESPACE>/<NAME> keys, in the backup's namespaces, of the
+	// PersistentVolumeClaims of StatefulSets that the label selector matches, which are restored
+	// along with their StatefulSets so that the StatefulSets' pods don't get new volumes.
+	claims sets.String
+
+	// volumesOnly is whether the only cluster-scoped items restored are namespaces and the
+	// volumes above, which is the case when specific namespaces are restored and cluster-scoped
+	// resources aren't explicitly included.
+	volumesOnly bool
+}
+
+// matches returns whether obj, an item of groupResource being restored into namespace, is
+// selected. namespace is "" for cluster-scoped items.
+func (s *itemSelector) matches(groupResource schema.GroupResource, namespace string, obj *unstructured.Unstructured) bool {
+	isVolume := groupResource.String() == "persistentvolumes" && s.volumes.Has(obj.GetName())
+
+	if namespace == "" && s.volumesOnly && groupResource.String() != "namespaces" {
+		return isVolume
+	}
+
+	if groupResource.String() == "persistentvolumeclaims" && s.claims.Has(obj.GetNamespace()+"/"+obj.GetName()) {
+		return true
+	}
+
+	return isVolume || s.labels.Matches(labels.Set(obj.GetLabels()))
+}
+
+// getItemSelector returns an itemSelector for selector, finding the claims of the selected
+// StatefulSets and the volumes of the selected claims in the backup extracted to dir that are in
+// namespaces the restore includes.
+func (kr *kubernetesRestorer) getItemSelector(dir string, restore *api.Restore, selector labels.Selector) (*itemSelector, error) {
+	namespacesToRestore := sets.NewString(restore.Spec.Namespaces...)
+
+	s := &itemSelector{
+		labels:      selector,
+		volumes:     sets.NewString(),
+		claims:      sets.NewString(),
+		volumesOnly: restore.Spec.IncludeClusterResources == nil && !namespacesToRestore.Has("*"),
+	}
+
+	// everything is selected, including all volumes
+	if selector.Empty() && !s.volumesOnly {
+		return s, nil
+	}
+
+	namespacesPath := path.Join(dir, api.NamespaceScopedDir)
+	exists, err := kr.fileSystem.DirExists(namespacesPath)
+	if err != nil || !exists {
+		return s, err
+	}
+
+	nses, err := kr.fileSystem.ReadDir(namespacesPath)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, ns := range nses {
+		if !namespacesToRestore.Has("*") && !namespacesToRestore.Has(ns.Name()) {
+			continue
+		}
+
+		claims, err := kr.readItems(path.Join(namespacesPath, ns.Name(), "persistentvolumeclaims"))
+		if err != nil {
+			return nil, err
+		}
+
+		statefulSets, err := kr.readItems(path.Join(namespacesPath, ns.Name(), "statefulsets.apps"))
+		if err != nil {
+			return nil, err
+		}
+
+		for _, statefulSet := range statefulSets {
+			if !selector.Matches(labels.Set(statefulSet.GetLabels())) {
+				continue
+			}
+
+			for _, claim := range claims {
+				if isStatefulSetClaim(statefulSet, claim.GetName()) {
+					s.claims.Insert(ns.Name() + "/" + claim.GetName())
+				}
+			}
+		}
+
+		for _, claim := range claims {
+			if !s.claims.Has(ns.Name()+"/"+claim.GetName()) && !selector.Matches(labels.Set(claim.GetLabels())) {
+				continue
+			}
+			if volumeName, err := collections.GetString(claim.Object, "spec.volumeName"); err == nil {
+				s.volumes.Insert(volumeName)
+			}
+		}
+	}
+
+	return s, nil
+}
+
+// readItems returns the items in the resource directory dir, or nil if it doesn't exist.
+func (kr *kubernetesRestorer) readItems(dir string) ([]*unstructured.Unstructured, error) {
+	exists, err := kr.fileSystem.DirExists(dir)
+	if err != nil || !exists {
+		return nil, err
+	}
+
+	files, err := kr.fileSystem.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var items []*unstructured.Unstructured
+	for _, file := range files {
+		item, err := kr.unmarshal(path.Join(dir, file.Name()))
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+
+	return items, nil
+}
+
+// isStatefulSetClaim returns whether the PersistentVolumeClaim named claimName was created from one
+// of statefulSet's volume claim templates, i.e. is named <TEMPLATE>-<STATEFULSET>-<ORDINAL>.
+// Claims of all ordinals are included, not just those below the StatefulSet's replica count, since
+// the StatefulSet reuses them when it's scaled up.
+func isStatefulSetClaim(statefulSet *unstructured.Unstructured, claimName string) bool {
+	templates, err := collections.GetSlice(statefulSet.Object, "spec.volumeClaimTemplates")
+	if err != nil {
+		return false
+	}
+
+	for _, template := range templates {
+		templateMap, ok := template.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		templateName, err := collections.GetString(templateMap, "metadata.name")
+		if err != nil {
+			continue
+		}
+
+		prefix := templateName + "-" + statefulSet.GetName() + "-"
+		if ordinal := strings.TrimPrefix(claimName, prefix); ordinal != claimName && isOrdinal(ordinal) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func isOrdinal(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
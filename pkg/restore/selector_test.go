@@ -0,0 +1,173 @@
+/*
+Copyright 2017 Heptio Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package restore
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	. "github.com/heptio/ark/pkg/util/test"
+)
+
+func TestGetItemSelector(t *testing.T) {
+	claim := func(name, app, volumeName string) []byte {
+		return []byte(`{"apiVersion": "v1", "kind": "PersistentVolumeClaim", "metadata": {"name": "` + name + `", "labels": {"app": "` + app + `"}}, "spec": {"volumeName": "` + volumeName + `"}}`)
+	}
+
+	statefulSet := func(name, app string, templates ...string) []byte {
+		var claimTemplates []string
+		for _, template := range templates {
+			claimTemplates = append(claimTemplates, `{"metadata": {"name": "`+template+`"}}`)
+		}
+		return []byte(`{"apiVersion": "apps/v1beta1", "kind": "StatefulSet", "metadata": {"name": "` + name + `", "labels": {"app": "` + app + `"}}, "spec": {"volumeClaimTemplates": [` + strings.Join(claimTemplates, ",") + `]}}`)
+	}
+
+	fileSystem := newFakeFileSystem().
+		WithFile("/backup/namespaces/ns-1/persistentvolumeclaims/db.json", claim("db", "db", "pv-1")).
+		WithFile("/backup/namespaces/ns-1/persistentvolumeclaims/web.json", claim("web", "web", "pv-2")).
+		WithFile("/backup/namespaces/ns-2/persistentvolumeclaims/db.json", claim("db", "db", "pv-3")).
+		WithFile("/backup/namespaces/ns-3/statefulsets.apps/zk.json", statefulSet("zk", "zk", "data", "logs")).
+		WithFile("/backup/namespaces/ns-3/persistentvolumeclaims/data-zk-0.json", claim("data-zk-0", "", "pv-4")).
+		WithFile("/backup/namespaces/ns-3/persistentvolumeclaims/data-zk-1.json", claim("data-zk-1", "", "pv-5")).
+		WithFile("/backup/namespaces/ns-3/persistentvolumeclaims/logs-zk-0.json", claim("logs-zk-0", "", "pv-6")).
+		WithFile("/backup/namespaces/ns-3/persistentvolumeclaims/data-zk-other.json", claim("data-zk-other", "", "pv-7")).
+		WithFile("/backup/namespaces/ns-3/persistentvolumeclaims/data-zk2-0.json", claim("data-zk2-0", "", "pv-8")).
+		WithDirectory("/backup/namespaces/ns-4")
+
+	restorer := &kubernetesRestorer{fileSystem: fileSystem}
+	trueVal := true
+
+	tests := []struct {
+		name                    string
+		namespaces              []string
+		includeClusterResources *bool
+		selector                labels.Selector
+		expectedVolumes         []string
+		expectedClaims          []string
+		expectedVolumesOnly     bool
+	}{
+		{
+			name:            "empty selector doesn't look for volumes",
+			namespaces:      []string{"*"},
+			selector:        labels.Everything(),
+			expectedVolumes: []string{},
+			expectedClaims:  []string{},
+		},
+		{
+			name:            "volumes of selected claims in all namespaces",
+			namespaces:      []string{"*"},
+			selector:        labels.SelectorFromSet(labels.Set{"app": "db"}),
+			expectedVolumes: []string{"pv-1", "pv-3"},
+			expectedClaims:  []string{},
+		},
+		{
+			name:                "volumes of selected claims in included namespaces",
+			namespaces:          []string{"ns-2", "ns-4"},
+			selector:            labels.SelectorFromSet(labels.Set{"app": "db"}),
+			expectedVolumes:     []string{"pv-3"},
+			expectedClaims:      []string{},
+			expectedVolumesOnly: true,
+		},
+		{
+			name:            "claims of selected statefulsets and their volumes",
+			namespaces:      []string{"*"},
+			selector:        labels.SelectorFromSet(labels.Set{"app": "zk"}),
+			expectedVolumes: []string{"pv-4", "pv-5", "pv-6"},
+			expectedClaims:  []string{"ns-3/data-zk-0", "ns-3/data-zk-1", "ns-3/logs-zk-0"},
+		},
+		{
+			name:                "volumes of all claims in included namespaces when cluster resources aren't included",
+			namespaces:          []string{"ns-1"},
+			selector:            labels.Everything(),
+			expectedVolumes:     []string{"pv-1", "pv-2"},
+			expectedClaims:      []string{},
+			expectedVolumesOnly: true,
+		},
+		{
+			name:                    "explicitly included cluster resources are all restored",
+			namespaces:              []string{"ns-1"},
+			includeClusterResources: &trueVal,
+			selector:                labels.Everything(),
+			expectedVolumes:         []string{},
+			expectedClaims:          []string{},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			restore := NewDefaultTestRestore().Restore
+			restore.Spec.Namespaces = test.namespaces
+			restore.Spec.IncludeClusterResources = test.includeClusterResources
+
+			s, err := restorer.getItemSelector("/backup", restore, test.selector)
+			require.NoError(t, err)
+
+			assert.Equal(t, test.expectedVolumes, s.volumes.List())
+			assert.Equal(t, test.expectedClaims, s.claims.List())
+			assert.Equal(t, test.expectedVolumesOnly, s.volumesOnly)
+		})
+	}
+}
+
+func TestItemSelectorMatches(t *testing.T) {
+	newObj := func(name string, labels map[string]string) *unstructured.Unstructured {
+		obj := &unstructured.Unstructured{Object: map[string]interface{}{}}
+		obj.SetName(name)
+		obj.SetLabels(labels)
+		return obj
+	}
+
+	s := &itemSelector{
+		labels:  labels.SelectorFromSet(labels.Set{"app": "db"}),
+		volumes: sets.NewString("pv-1"),
+		claims:  sets.NewString("ns-1/data-db-0"),
+	}
+
+	pvs := schema.GroupResource{Resource: "persistentvolumes"}
+	pvcs := schema.GroupResource{Resource: "persistentvolumeclaims"}
+	pods := schema.GroupResource{Resource: "pods"}
+
+	dbClaim := newObj("data-db-0", nil)
+	dbClaim.SetNamespace("ns-1")
+	assert.True(t, s.matches(pvcs, "ns-2", dbClaim), "claim of a selected statefulset should match, even if its namespace is remapped")
+	dbClaim.SetName("data-db-1")
+	assert.False(t, s.matches(pvcs, "ns-1", dbClaim))
+
+	assert.True(t, s.matches(pods, "ns-1", newObj("db", map[string]string{"app": "db"})))
+	assert.False(t, s.matches(pods, "ns-1", newObj("web", map[string]string{"app": "web"})))
+	assert.True(t, s.matches(pvs, "", newObj("pv-1", nil)), "volume of a selected claim should match")
+	assert.False(t, s.matches(pvs, "", newObj("pv-2", nil)))
+	assert.False(t, s.matches(pods, "ns-1", newObj("pv-1", nil)), "only volumes should match by name")
+
+	s.volumesOnly = true
+	crbs := schema.GroupResource{Group: "rbac.authorization.k8s.io", Resource: "clusterrolebindings"}
+	namespaces := schema.GroupResource{Resource: "namespaces"}
+
+	assert.True(t, s.matches(pvs, "", newObj("pv-1", nil)))
+	assert.False(t, s.matches(pvs, "", newObj("pv-2", map[string]string{"app": "db"})), "only volumes of selected claims should match")
+	assert.False(t, s.matches(crbs, "", newObj("crb", map[string]string{"app": "db"})), "other cluster-scoped items shouldn't match")
+	assert.True(t, s.matches(namespaces, "", newObj("ns-1", map[string]string{"app": "db"})))
+	assert.True(t, s.matches(pods, "ns-1", newObj("db", map[string]string{"app": "db"})))
+}
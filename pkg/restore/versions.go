@@ -0,0 +1,214 @@
+/*
+Copyright 2017 Heptio Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package restore
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	api "github.com/heptio/ark/pkg/apis/ark/v1"
+)
+
+// convertUnservedVersions rewrites the items in the backup extracted to dir whose API versions the
+// cluster doesn't serve (e.g. items backed up at v1beta1 being restored into a cluster that only
+// serves v1), so that they're restored at a version the cluster does serve instead: the first of
+// the versions the backup recorded for the resource that the cluster serves, which can be in
+// another group (e.g. extensions/v1beta1 deployments restored as apps/v1), or, for backups that
+// didn't record versions, the cluster's preferred version of the items' group. Only the items'
+// apiVersion is changed; fields that differ between the versions can be adjusted by restore item
+// actions. A warning is returned for each resource that's converted.
+func (kr *kubernetesRestorer) convertUnservedVersions(dir string, mapper meta.RESTMapper, log *restoreLog) (api.RestoreResult, error) {
+	var warnings api.RestoreResult
+
+	recorded, err := kr.readResourceVersions(dir)
+	if err != nil {
+		return warnings, err
+	}
+
+	// all of a resource's items are backed up at the same version, so the version each resource
+	// is restored at only needs to be worked out once
+	converted := make(map[string]*schema.GroupVersion)
+
+	convertDir := func(resourcePath string) error {
+		files, err := kr.fileSystem.ReadDir(resourcePath)
+		if err != nil {
+			return err
+		}
+
+		for _, file := range files {
+			if file.IsDir() {
+				continue
+			}
+			filePath := path.Join(resourcePath, file.Name())
+
+			obj, err := kr.unmarshal(filePath)
+			if err != nil {
+				return fmt.Errorf("error decoding %q: %v", filePath, err)
+			}
+
+			resource := path.Base(resourcePath)
+			target, found := converted[resource]
+			if !found {
+				gvk := obj.GroupVersionKind()
+				if target, err = servedVersion(mapper, gvk, recorded[resource]); err != nil {
+					log.Warningf("Unable to find a version of %s that the cluster serves: %v", resource, err)
+				}
+				if target != nil {
+					log.Infof("Restoring %s as %s, since the cluster doesn't serve %s", resource, target.String(), gvk.GroupVersion().String())
+					addArkError(&warnings, fmt.Errorf("%s were backed up as %s, which the cluster doesn't serve, so they're restored as %s", resource, gvk.GroupVersion().String(), target.String()))
+				}
+				converted[resource] = target
+			}
+			if target == nil {
+				// the resource's items are restored at the version they were backed up at
+				return nil
+			}
+
+			obj.SetAPIVersion(target.String())
+			data, err := json.Marshal(obj)
+			if err != nil {
+				return err
+			}
+			if err := kr.writeFile(filePath, data); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	var resourcePaths []string
+
+	clusterPath := path.Join(dir, api.ClusterScopedDir)
+	resourceDirs, err := kr.readDirIfExists(clusterPath)
+	if err != nil {
+		return warnings, err
+	}
+	for _, resourceDir := range resourceDirs {
+		resourcePaths = append(resourcePaths, path.Join(clusterPath, resourceDir))
+	}
+
+	namespacesPath := path.Join(dir, api.NamespaceScopedDir)
+	nses, err := kr.readDirIfExists(namespacesPath)
+	if err != nil {
+		return warnings, err
+	}
+	for _, ns := range nses {
+		nsPath := path.Join(namespacesPath, ns)
+		resourceDirs, err := kr.readDirIfExists(nsPath)
+		if err != nil {
+			return warnings, err
+		}
+		for _, resourceDir := range resourceDirs {
+			resourcePaths = append(resourcePaths, path.Join(nsPath, resourceDir))
+		}
+	}
+
+	for _, resourcePath := range resourcePaths {
+		if err := convertDir(resourcePath); err != nil {
+			return warnings, err
+		}
+	}
+
+	return warnings, nil
+}
+
+// servedVersion returns nil if the cluster serves gvk, or otherwise the first of the recorded group
+// versions that the cluster serves gvk's kind at, falling back to the cluster's preferred version of
+// gvk's group and kind.
+func servedVersion(mapper meta.RESTMapper, gvk schema.GroupVersionKind, recorded []string) (*schema.GroupVersion, error) {
+	if _, err := mapper.RESTMapping(gvk.GroupKind(), gvk.Version); err == nil {
+		return nil, nil
+	}
+
+	for _, version := range recorded {
+		gv, err := schema.ParseGroupVersion(version)
+		if err != nil {
+			continue
+		}
+		if _, err := mapper.RESTMapping(schema.GroupKind{Group: gv.Group, Kind: gvk.Kind}, gv.Version); err == nil {
+			return &gv, nil
+		}
+	}
+
+	mapping, err := mapper.RESTMapping(gvk.GroupKind())
+	if err != nil {
+		return nil, err
+	}
+
+	gv := mapping.GroupVersionKind.GroupVersion()
+	return &gv, nil
+}
+
+// readResourceVersions reads the group versions recorded for each resource from the backup
+// extracted to dir. Backups taken before versions were recorded don't have any, so nothing is
+// returned for them.
+func (kr *kubernetesRestorer) readResourceVersions(dir string) (map[string][]string, error) {
+	data, err := kr.fileSystem.ReadFile(path.Join(dir, api.ResourceVersionsFile))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading resource versions: %v", err)
+	}
+
+	versions := make(map[string][]string)
+	if err := json.Unmarshal(data, &versions); err != nil {
+		return nil, fmt.Errorf("error parsing resource versions: %v", err)
+	}
+	return versions, nil
+}
+
+// readDirIfExists returns the names of the subdirectories of dir, or nothing if dir doesn't exist.
+func (kr *kubernetesRestorer) readDirIfExists(dir string) ([]string, error) {
+	exists, err := kr.fileSystem.DirExists(dir)
+	if err != nil || !exists {
+		return nil, err
+	}
+
+	entries, err := kr.fileSystem.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, entry := range entries {
+		if entry.IsDir() {
+			names = append(names, entry.Name())
+		}
+	}
+	return names, nil
+}
+
+// writeFile replaces the contents of the file at filePath with data.
+func (kr *kubernetesRestorer) writeFile(filePath string, data []byte) error {
+	file, err := kr.fileSystem.Create(filePath)
+	if err != nil {
+		return err
+	}
+
+	_, err = file.Write(data)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
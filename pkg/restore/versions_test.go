@@ -0,0 +1,150 @@
+/*
+Copyright 2017 Heptio Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package restore
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	api "github.com/heptio/ark/pkg/apis/ark/v1"
+	. "github.com/heptio/ark/pkg/util/test"
+)
+
+func TestConvertUnservedVersions(t *testing.T) {
+	cronJob := func(name, apiVersion string) []byte {
+		return []byte(`{"apiVersion": "` + apiVersion + `", "kind": "CronJob", "metadata": {"namespace": "ns-1", "name": "` + name + `"}}`)
+	}
+	configMap := []byte(`{"apiVersion": "v1", "kind": "ConfigMap", "metadata": {"namespace": "ns-1", "name": "cm-1"}}`)
+
+	mapper := &FakeMapper{
+		KindVersions: map[schema.GroupKind][]string{
+			{Group: "batch", Kind: "CronJob"}: {"v1"},
+			{Kind: "ConfigMap"}:               {"v1"},
+		},
+	}
+
+	fileSystem := newFakeFileSystem().
+		WithFile("/backup/namespaces/ns-1/cronjobs.batch/job-1.json", cronJob("job-1", "batch/v1beta1")).
+		WithFile("/backup/namespaces/ns-1/cronjobs.batch/job-2.json", cronJob("job-2", "batch/v1beta1")).
+		WithFile("/backup/namespaces/ns-2/cronjobs.batch/job-3.json", cronJob("job-3", "batch/v1beta1")).
+		WithFile("/backup/namespaces/ns-1/configmaps/cm-1.json", configMap)
+
+	restorer := &kubernetesRestorer{fileSystem: fileSystem}
+
+	warnings, err := restorer.convertUnservedVersions("/backup", mapper, nil)
+	require.NoError(t, err)
+	assert.Equal(t, api.RestoreResult{
+		Ark: []string{"cronjobs.batch were backed up as batch/v1beta1, which the cluster doesn't serve, so they're restored as batch/v1"},
+	}, warnings)
+
+	for _, item := range []string{"ns-1/cronjobs.batch/job-1.json", "ns-1/cronjobs.batch/job-2.json", "ns-2/cronjobs.batch/job-3.json"} {
+		obj, err := restorer.unmarshal("/backup/namespaces/" + item)
+		require.NoError(t, err)
+		assert.Equal(t, "batch/v1", obj.GetAPIVersion(), item)
+	}
+
+	data, err := fileSystem.ReadFile("/backup/namespaces/ns-1/configmaps/cm-1.json")
+	require.NoError(t, err)
+	assert.Equal(t, configMap, data, "items of served versions shouldn't be rewritten")
+}
+
+func TestConvertUnservedVersionsUsesRecordedVersions(t *testing.T) {
+	deployment := []byte(`{"apiVersion": "extensions/v1beta1", "kind": "Deployment", "metadata": {"namespace": "ns-1", "name": "deploy-1"}}`)
+
+	mapper := &FakeMapper{
+		KindVersions: map[schema.GroupKind][]string{
+			{Group: "apps", Kind: "Deployment"}: {"v1", "v1beta2"},
+		},
+	}
+
+	fileSystem := newFakeFileSystem().
+		WithFile("/backup/resource-versions.json", []byte(`{"deployments.extensions": ["extensions/v1beta1", "apps/v1beta2", "apps/v1beta1"]}`)).
+		WithFile("/backup/namespaces/ns-1/deployments.extensions/deploy-1.json", deployment)
+
+	restorer := &kubernetesRestorer{fileSystem: fileSystem}
+
+	warnings, err := restorer.convertUnservedVersions("/backup", mapper, nil)
+	require.NoError(t, err)
+	assert.Equal(t, api.RestoreResult{
+		Ark: []string{"deployments.extensions were backed up as extensions/v1beta1, which the cluster doesn't serve, so they're restored as apps/v1beta2"},
+	}, warnings)
+
+	obj, err := restorer.unmarshal("/backup/namespaces/ns-1/deployments.extensions/deploy-1.json")
+	require.NoError(t, err)
+	assert.Equal(t, "apps/v1beta2", obj.GetAPIVersion())
+}
+
+func TestServedVersion(t *testing.T) {
+	mapper := &FakeMapper{
+		KindVersions: map[schema.GroupKind][]string{
+			{Group: "apps", Kind: "Deployment"}: {"v1", "v1beta2"},
+			{Group: "batch", Kind: "CronJob"}:   {"v1"},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		gvk      schema.GroupVersionKind
+		recorded []string
+		expected *schema.GroupVersion
+		err      bool
+	}{
+		{
+			name:     "served version isn't converted",
+			gvk:      schema.GroupVersionKind{Group: "apps", Version: "v1beta2", Kind: "Deployment"},
+			recorded: []string{"apps/v1beta2", "apps/v1"},
+		},
+		{
+			name:     "first served recorded version is used",
+			gvk:      schema.GroupVersionKind{Group: "extensions", Version: "v1beta1", Kind: "Deployment"},
+			recorded: []string{"extensions/v1beta1", "apps/v1beta1", "apps/v1beta2", "apps/v1"},
+			expected: &schema.GroupVersion{Group: "apps", Version: "v1beta2"},
+		},
+		{
+			name:     "group's preferred version is used when no recorded version is served",
+			gvk:      schema.GroupVersionKind{Group: "batch", Version: "v1beta1", Kind: "CronJob"},
+			recorded: []string{"batch/v1beta1", "batch/v2alpha1"},
+			expected: &schema.GroupVersion{Group: "batch", Version: "v1"},
+		},
+		{
+			name:     "group's preferred version is used when no versions were recorded",
+			gvk:      schema.GroupVersionKind{Group: "apps", Version: "v1beta1", Kind: "Deployment"},
+			expected: &schema.GroupVersion{Group: "apps", Version: "v1"},
+		},
+		{
+			name: "unserved kind is an error",
+			gvk:  schema.GroupVersionKind{Group: "extensions", Version: "v1beta1", Kind: "Deployment"},
+			err:  true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			gv, err := servedVersion(mapper, test.gvk, test.recorded)
+			if test.err {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, gv)
+		})
+	}
+}
@@ -0,0 +1,99 @@
+/*
+Copyright 2017 Heptio Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package schedule creates schedules' backups and works out when they expire. It's shared by the
+// schedule controller and `ark backup create --from-schedule`, so that backups run from the CLI are
+// named and owned like the ones the schedule creates itself, and by the garbage collector and the
+// admission webhook, so that they agree on which backups have expired.
+package schedule
+
+import (
+	"time"
+
+	"github.com/golang/glog"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	api "github.com/heptio/ark/pkg/apis/ark/v1"
+	"github.com/heptio/ark/pkg/util/nametemplate"
+)
+
+// BackupName returns the name of the backup that schedule creates at timestamp in the cluster
+// called clusterName, generated from the schedule's backup name template, with the date parts of
+// the name in its time zone.
+func BackupName(schedule *api.Schedule, timestamp time.Time, clusterName string) (string, error) {
+	if schedule.Spec.Timezone != "" {
+		location, err := time.LoadLocation(schedule.Spec.Timezone)
+		if err != nil {
+			return "", err
+		}
+		timestamp = timestamp.In(location)
+	}
+
+	template := schedule.Spec.BackupNameTemplate
+	if template == "" {
+		template = nametemplate.Default
+	}
+	return nametemplate.Render(template, nametemplate.Vars{Schedule: schedule.Name, Cluster: clusterName, Time: timestamp})
+}
+
+// NewBackup returns a Backup of schedule called name, with the schedule's backup spec, and labeled
+// with and owned by the schedule.
+func NewBackup(schedule *api.Schedule, name string) *api.Backup {
+	return &api.Backup{
+		Spec: schedule.Spec.Template,
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: schedule.Namespace,
+			Name:      name,
+			Labels: map[string]string{
+				api.ScheduleNameLabel: schedule.Name,
+			},
+			OwnerReferences: []metav1.OwnerReference{OwnerReference(schedule)},
+		},
+	}
+}
+
+// OwnerReference returns an owner reference to schedule, for the backups it creates. Deleting the
+// schedule with the default propagation policy, e.g. with `kubectl delete schedule`, has the
+// garbage collector delete the API objects of its backups, so `ark schedule delete` orphans them.
+func OwnerReference(schedule *api.Schedule) metav1.OwnerReference {
+	controller := true
+
+	return metav1.OwnerReference{
+		APIVersion: api.SchemeGroupVersion.String(),
+		Kind:       "Schedule",
+		Name:       schedule.Name,
+		UID:        schedule.UID,
+		Controller: &controller,
+	}
+}
+
+// Expiration returns when backup expires: when its TTL expires, or when its schedule's retention
+// policy stopped keeping it, if that's sooner.
+func Expiration(backup *api.Backup) time.Time {
+	expiration := backup.Status.Expiration.Time
+
+	if value := backup.Annotations[api.RetentionExpiredAnnotation]; value != "" {
+		retentionExpiration, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			glog.Errorf("error parsing annotation %s of backup %s/%s: %v", api.RetentionExpiredAnnotation, backup.Namespace, backup.Name, err)
+		} else if retentionExpiration.Before(expiration) {
+			expiration = retentionExpiration
+		}
+	}
+
+	return expiration
+}
@@ -0,0 +1,173 @@
+/*
+Copyright 2017 Heptio Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package schedule
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/clock"
+
+	api "github.com/heptio/ark/pkg/apis/ark/v1"
+)
+
+func TestNewBackup(t *testing.T) {
+	tests := []struct {
+		name           string
+		schedule       *api.Schedule
+		testClockTime  string
+		expectedBackup *api.Backup
+	}{
+		{
+			name: "ensure name is formatted correctly (AM time)",
+			schedule: &api.Schedule{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: "foo",
+					Name:      "bar",
+				},
+				Spec: api.ScheduleSpec{
+					Template: api.BackupSpec{},
+				},
+			},
+			testClockTime: "2017-07-25 09:15:00",
+			expectedBackup: &api.Backup{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: "foo",
+					Name:      "bar-20170725091500",
+				},
+				Spec: api.BackupSpec{},
+			},
+		},
+		{
+			name: "ensure name is formatted correctly (PM time)",
+			schedule: &api.Schedule{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: "foo",
+					Name:      "bar",
+				},
+				Spec: api.ScheduleSpec{
+					Template: api.BackupSpec{},
+				},
+			},
+			testClockTime: "2017-07-25 14:15:00",
+			expectedBackup: &api.Backup{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: "foo",
+					Name:      "bar-20170725141500",
+				},
+				Spec: api.BackupSpec{},
+			},
+		},
+		{
+			name: "ensure name is generated from the backup name template",
+			schedule: &api.Schedule{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: "foo",
+					Name:      "bar",
+				},
+				Spec: api.ScheduleSpec{
+					BackupNameTemplate: "{cluster}-{schedule}-{date}",
+				},
+			},
+			testClockTime: "2017-07-25 14:15:00",
+			expectedBackup: &api.Backup{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: "foo",
+					Name:      "cluster-1-bar-20170725",
+				},
+			},
+		},
+		{
+			name: "ensure name's date parts are in the schedule's time zone",
+			schedule: &api.Schedule{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: "foo",
+					Name:      "bar",
+				},
+				Spec: api.ScheduleSpec{
+					Timezone: "Asia/Tokyo",
+				},
+			},
+			testClockTime: "2017-07-25 18:15:00",
+			expectedBackup: &api.Backup{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: "foo",
+					Name:      "bar-20170726031500",
+				},
+			},
+		},
+		{
+			name: "ensure schedule backup template is copied",
+			schedule: &api.Schedule{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: "foo",
+					Name:      "bar",
+				},
+				Spec: api.ScheduleSpec{
+					Template: api.BackupSpec{
+						IncludedNamespaces: []string{"ns-1", "ns-2"},
+						ExcludedNamespaces: []string{"ns-3"},
+						IncludedResources:  []string{"foo", "bar"},
+						ExcludedResources:  []string{"baz"},
+						LabelSelector:      &metav1.LabelSelector{MatchLabels: map[string]string{"label": "value"}},
+						TTL:                metav1.Duration{Duration: time.Duration(300)},
+					},
+				},
+			},
+			testClockTime: "2017-07-25 09:15:00",
+			expectedBackup: &api.Backup{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: "foo",
+					Name:      "bar-20170725091500",
+				},
+				Spec: api.BackupSpec{
+					IncludedNamespaces: []string{"ns-1", "ns-2"},
+					ExcludedNamespaces: []string{"ns-3"},
+					IncludedResources:  []string{"foo", "bar"},
+					ExcludedResources:  []string{"baz"},
+					LabelSelector:      &metav1.LabelSelector{MatchLabels: map[string]string{"label": "value"}},
+					TTL:                metav1.Duration{Duration: time.Duration(300)},
+				},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			testTime, err := time.Parse("2006-01-02 15:04:05", test.testClockTime)
+			require.NoError(t, err, "unable to parse test.testClockTime: %v", err)
+
+			name, err := BackupName(test.schedule, clock.NewFakeClock(testTime).Now(), "cluster-1")
+			require.NoError(t, err)
+			backup := NewBackup(test.schedule, name)
+
+			assert.Equal(t, test.expectedBackup.Namespace, backup.Namespace)
+			assert.Equal(t, test.expectedBackup.Name, backup.Name)
+			assert.Equal(t, test.expectedBackup.Spec, backup.Spec)
+			assert.Equal(t, map[string]string{api.ScheduleNameLabel: test.schedule.Name}, backup.Labels)
+			require.Len(t, backup.OwnerReferences, 1)
+			assert.Equal(t, "Schedule", backup.OwnerReferences[0].Kind)
+			assert.Equal(t, test.schedule.Name, backup.OwnerReferences[0].Name)
+			assert.Equal(t, test.schedule.UID, backup.OwnerReferences[0].UID)
+			assert.True(t, *backup.OwnerReferences[0].Controller)
+		})
+	}
+}
@@ -0,0 +1,210 @@
+/*
+Copyright 2017 Heptio Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tracing
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// tracesPath is the path under an OTLP/HTTP endpoint that spans are posted to.
+	tracesPath = "/v1/traces"
+
+	// exportTimeout is how long to wait for the endpoint to accept a batch of spans.
+	exportTimeout = 30 * time.Second
+
+	// maxExportBatch is the most spans sent in one request.
+	maxExportBatch = 1000
+
+	// instrumentationScope names the code that recorded the spans.
+	instrumentationScope = "github.com/heptio/ark"
+
+	spanKindInternal = 1
+	statusCodeError  = 2
+)
+
+// otlpExporter posts spans to an OTLP/HTTP endpoint, using its JSON encoding.
+type otlpExporter struct {
+	url         string
+	serviceName string
+	client      *http.Client
+}
+
+func newOTLPExporter(endpoint, serviceName string) *otlpExporter {
+	return &otlpExporter{
+		url:         strings.TrimSuffix(endpoint, "/") + tracesPath,
+		serviceName: serviceName,
+		client:      &http.Client{Timeout: exportTimeout},
+	}
+}
+
+func (e *otlpExporter) export(spans []*Span) error {
+	for len(spans) > 0 {
+		n := len(spans)
+		if n > maxExportBatch {
+			n = maxExportBatch
+		}
+
+		if err := e.post(spans[:n]); err != nil {
+			return err
+		}
+		spans = spans[n:]
+	}
+
+	return nil
+}
+
+func (e *otlpExporter) post(spans []*Span) error {
+	body, err := json.Marshal(e.request(spans))
+	if err != nil {
+		return err
+	}
+
+	res, err := e.client.Post(e.url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		msg, _ := ioutil.ReadAll(io.LimitReader(res.Body, 1024))
+		return fmt.Errorf("%s returned %s: %s", e.url, res.Status, strings.TrimSpace(string(msg)))
+	}
+
+	return nil
+}
+
+// The types below are the parts of the JSON encoding of an OTLP ExportTraceServiceRequest that
+// Ark uses.
+
+type otlpRequest struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+type otlpResourceSpans struct {
+	Resource   otlpResource     `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpResource struct {
+	Attributes []otlpKeyValue `json:"attributes"`
+}
+
+type otlpScopeSpans struct {
+	Scope otlpScope  `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type otlpScope struct {
+	Name string `json:"name"`
+}
+
+type otlpSpan struct {
+	TraceID           string         `json:"traceId"`
+	SpanID            string         `json:"spanId"`
+	ParentSpanID      string         `json:"parentSpanId,omitempty"`
+	Name              string         `json:"name"`
+	Kind              int            `json:"kind"`
+	StartTimeUnixNano string         `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string         `json:"endTimeUnixNano"`
+	Attributes        []otlpKeyValue `json:"attributes,omitempty"`
+	Status            otlpStatus     `json:"status"`
+}
+
+type otlpKeyValue struct {
+	Key   string    `json:"key"`
+	Value otlpValue `json:"value"`
+}
+
+// otlpValue is an attribute's value. Exactly one of its fields is set. 64-bit integers are
+// encoded as strings, as in the protobuf JSON mapping.
+type otlpValue struct {
+	StringValue *string `json:"stringValue,omitempty"`
+	BoolValue   *bool   `json:"boolValue,omitempty"`
+	IntValue    *string `json:"intValue,omitempty"`
+}
+
+type otlpStatus struct {
+	Code    int    `json:"code,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+func (e *otlpExporter) request(spans []*Span) otlpRequest {
+	scopeSpans := otlpScopeSpans{Scope: otlpScope{Name: instrumentationScope}}
+	for _, s := range spans {
+		scopeSpans.Spans = append(scopeSpans.Spans, s.otlp())
+	}
+
+	return otlpRequest{
+		ResourceSpans: []otlpResourceSpans{
+			{
+				Resource: otlpResource{
+					Attributes: []otlpKeyValue{stringKeyValue("service.name", e.serviceName)},
+				},
+				ScopeSpans: []otlpScopeSpans{scopeSpans},
+			},
+		},
+	}
+}
+
+func (s *Span) otlp() otlpSpan {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	res := otlpSpan{
+		TraceID:           hex.EncodeToString(s.traceID[:]),
+		SpanID:            hex.EncodeToString(s.spanID[:]),
+		Name:              s.name,
+		Kind:              spanKindInternal,
+		StartTimeUnixNano: strconv.FormatInt(s.start.UnixNano(), 10),
+		EndTimeUnixNano:   strconv.FormatInt(s.end.UnixNano(), 10),
+	}
+	if s.parentID != [8]byte{} {
+		res.ParentSpanID = hex.EncodeToString(s.parentID[:])
+	}
+
+	for _, a := range s.attributes {
+		switch v := a.value.(type) {
+		case string:
+			res.Attributes = append(res.Attributes, stringKeyValue(a.key, v))
+		case bool:
+			res.Attributes = append(res.Attributes, otlpKeyValue{Key: a.key, Value: otlpValue{BoolValue: &v}})
+		case int64:
+			i := strconv.FormatInt(v, 10)
+			res.Attributes = append(res.Attributes, otlpKeyValue{Key: a.key, Value: otlpValue{IntValue: &i}})
+		}
+	}
+
+	if s.err != nil {
+		res.Status = otlpStatus{Code: statusCodeError, Message: s.err.Error()}
+	}
+
+	return res
+}
+
+func stringKeyValue(key, value string) otlpKeyValue {
+	return otlpKeyValue{Key: key, Value: otlpValue{StringValue: &value}}
+}
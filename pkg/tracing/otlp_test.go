@@ -0,0 +1,144 @@
+/*
+Copyright 2017 Heptio Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tracing
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOTLPExporter(t *testing.T) {
+	var (
+		path    string
+		request map[string]interface{}
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+	}))
+	defer server.Close()
+
+	start := time.Unix(1514764800, 0)
+	parent := &Span{
+		name:    "backup",
+		traceID: [16]byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16},
+		spanID:  [8]byte{1, 1, 1, 1, 1, 1, 1, 1},
+		start:   start,
+		end:     start.Add(time.Second),
+		attributes: []attribute{
+			{key: "ark.backup", value: "ns/name"},
+			{key: "ark.incremental", value: true},
+		},
+	}
+	child := &Span{
+		name:       "collect pods",
+		traceID:    parent.traceID,
+		spanID:     [8]byte{2, 2, 2, 2, 2, 2, 2, 2},
+		parentID:   parent.spanID,
+		start:      start,
+		end:        start.Add(500 * time.Millisecond),
+		attributes: []attribute{{key: "ark.items", value: int64(3)}},
+		err:        errors.New("failed"),
+	}
+
+	exporter := newOTLPExporter(server.URL+"/", "ark-test")
+	require.NoError(t, exporter.export([]*Span{child, parent}))
+
+	assert.Equal(t, "/v1/traces", path)
+
+	expected := map[string]interface{}{
+		"resourceSpans": []interface{}{
+			map[string]interface{}{
+				"resource": map[string]interface{}{
+					"attributes": []interface{}{
+						map[string]interface{}{"key": "service.name", "value": map[string]interface{}{"stringValue": "ark-test"}},
+					},
+				},
+				"scopeSpans": []interface{}{
+					map[string]interface{}{
+						"scope": map[string]interface{}{"name": "github.com/heptio/ark"},
+						"spans": []interface{}{
+							map[string]interface{}{
+								"traceId":           "0102030405060708090a0b0c0d0e0f10",
+								"spanId":            "0202020202020202",
+								"parentSpanId":      "0101010101010101",
+								"name":              "collect pods",
+								"kind":              float64(1),
+								"startTimeUnixNano": "1514764800000000000",
+								"endTimeUnixNano":   "1514764800500000000",
+								"attributes": []interface{}{
+									map[string]interface{}{"key": "ark.items", "value": map[string]interface{}{"intValue": "3"}},
+								},
+								"status": map[string]interface{}{"code": float64(2), "message": "failed"},
+							},
+							map[string]interface{}{
+								"traceId":           "0102030405060708090a0b0c0d0e0f10",
+								"spanId":            "0101010101010101",
+								"name":              "backup",
+								"kind":              float64(1),
+								"startTimeUnixNano": "1514764800000000000",
+								"endTimeUnixNano":   "1514764801000000000",
+								"attributes": []interface{}{
+									map[string]interface{}{"key": "ark.backup", "value": map[string]interface{}{"stringValue": "ns/name"}},
+									map[string]interface{}{"key": "ark.incremental", "value": map[string]interface{}{"boolValue": true}},
+								},
+								"status": map[string]interface{}{},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+	assert.Equal(t, expected, request)
+}
+
+func TestOTLPExporterBatches(t *testing.T) {
+	var batches []int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request otlpRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+		batches = append(batches, len(request.ResourceSpans[0].ScopeSpans[0].Spans))
+	}))
+	defer server.Close()
+
+	spans := make([]*Span, maxExportBatch+1)
+	for i := range spans {
+		spans[i] = &Span{name: "span"}
+	}
+
+	require.NoError(t, newOTLPExporter(server.URL, "ark").export(spans))
+	assert.Equal(t, []int{maxExportBatch, 1}, batches)
+}
+
+func TestOTLPExporterError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "bad request", http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	err := newOTLPExporter(server.URL, "ark").export([]*Span{{name: "span"}})
+	assert.EqualError(t, err, server.URL+"/v1/traces returned 400 Bad Request: bad request")
+}
@@ -0,0 +1,242 @@
+/*
+Copyright 2017 Heptio Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package tracing records spans of the work the Ark server does taking and restoring backups,
+// and exports them to an OpenTelemetry collector using OTLP over HTTP, so slow backups and
+// restores can be profiled in a tracing backend such as Jaeger or Tempo.
+//
+// Spans are started with Start, which returns a nil *Span when tracing isn't enabled. A nil
+// *Span is valid, and does nothing, so code is traced unconditionally.
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+
+	"k8s.io/apimachinery/pkg/util/clock"
+)
+
+// maxPendingSpans is the number of ended spans that are kept until the next export. Spans
+// ended while there are already this many are dropped.
+const maxPendingSpans = 10000
+
+// exporter sends ended spans to a tracing backend.
+type exporter interface {
+	export(spans []*Span) error
+}
+
+// Tracer starts spans and exports them once they've ended.
+type Tracer struct {
+	exporter exporter
+	clock    clock.Clock
+
+	lock    sync.Mutex
+	pending []*Span
+	dropped int
+}
+
+// NewTracer returns a Tracer that exports spans to the OTLP/HTTP endpoint, e.g.
+// http://otel-collector:4318, identifying them as coming from serviceName. Run must be called
+// for them to be exported.
+func NewTracer(endpoint, serviceName string) *Tracer {
+	return newTracer(newOTLPExporter(endpoint, serviceName), clock.RealClock{})
+}
+
+func newTracer(exporter exporter, clock clock.Clock) *Tracer {
+	return &Tracer{
+		exporter: exporter,
+		clock:    clock,
+	}
+}
+
+// Run exports the spans that have ended since the last export every flushInterval, until ctx is
+// done, when it exports the remaining spans and returns.
+func (t *Tracer) Run(ctx context.Context, flushInterval time.Duration) {
+	ticker := time.NewTicker(flushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := t.flush(); err != nil {
+				glog.Errorf("error exporting trace spans: %v", err)
+			}
+		case <-ctx.Done():
+			if err := t.flush(); err != nil {
+				glog.Errorf("error exporting trace spans: %v", err)
+			}
+			return
+		}
+	}
+}
+
+// flush exports the pending spans. They're discarded whether or not the export succeeds, since
+// traces are best-effort and holding on to them could use unbounded memory.
+func (t *Tracer) flush() error {
+	t.lock.Lock()
+	spans, dropped := t.pending, t.dropped
+	t.pending, t.dropped = nil, 0
+	t.lock.Unlock()
+
+	if dropped > 0 {
+		glog.Warningf("Dropped %d trace span(s) because too many were waiting to be exported", dropped)
+	}
+	if len(spans) == 0 {
+		return nil
+	}
+
+	return t.exporter.export(spans)
+}
+
+// ended queues s to be exported.
+func (t *Tracer) ended(s *Span) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if len(t.pending) >= maxPendingSpans {
+		t.dropped++
+		return
+	}
+	t.pending = append(t.pending, s)
+}
+
+var (
+	globalLock   sync.RWMutex
+	globalTracer *Tracer
+)
+
+// SetTracer sets the Tracer that Start starts new traces with. If it's nil, which it is by
+// default, tracing is disabled.
+func SetTracer(t *Tracer) {
+	globalLock.Lock()
+	defer globalLock.Unlock()
+
+	globalTracer = t
+}
+
+func getTracer() *Tracer {
+	globalLock.RLock()
+	defer globalLock.RUnlock()
+
+	return globalTracer
+}
+
+// Span is a timed operation in a trace.
+type Span struct {
+	tracer   *Tracer
+	name     string
+	traceID  [16]byte
+	spanID   [8]byte
+	parentID [8]byte
+	start    time.Time
+
+	lock       sync.Mutex
+	end        time.Time
+	attributes []attribute
+	err        error
+}
+
+// attribute is a key-value pair describing a span. Its value is a string, bool, or int64.
+type attribute struct {
+	key   string
+	value interface{}
+}
+
+type spanKey struct{}
+
+// Start starts a span named name. If ctx has a span, the new span is its child; otherwise, it
+// starts a new trace, unless tracing is disabled, in which case it returns ctx and a nil span.
+// The returned context has the new span, for starting its children. End must be called on the
+// span when the operation it represents is finished.
+func Start(ctx context.Context, name string) (context.Context, *Span) {
+	parent, _ := ctx.Value(spanKey{}).(*Span)
+
+	s := &Span{name: name}
+	if parent != nil {
+		s.tracer = parent.tracer
+		s.traceID = parent.traceID
+		s.parentID = parent.spanID
+	} else {
+		if s.tracer = getTracer(); s.tracer == nil {
+			return ctx, nil
+		}
+		randomID(s.traceID[:])
+	}
+	randomID(s.spanID[:])
+	s.start = s.tracer.clock.Now()
+
+	return context.WithValue(ctx, spanKey{}, s), s
+}
+
+func randomID(id []byte) {
+	if _, err := rand.Read(id); err != nil {
+		glog.Errorf("error generating trace ID: %v", err)
+	}
+}
+
+// SetAttribute records an attribute of the span. value must be a string, bool, or integer;
+// other values are ignored.
+func (s *Span) SetAttribute(key string, value interface{}) {
+	if s == nil {
+		return
+	}
+
+	switch v := value.(type) {
+	case string, bool, int64:
+	case int:
+		value = int64(v)
+	default:
+		return
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.attributes = append(s.attributes, attribute{key: key, value: value})
+}
+
+// RecordError marks the span as having failed with err, if it isn't nil.
+func (s *Span) RecordError(err error) {
+	if s == nil || err == nil {
+		return
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.err = err
+}
+
+// End ends the span, and queues it to be exported. Only the first call has any effect.
+func (s *Span) End() {
+	if s == nil {
+		return
+	}
+
+	s.lock.Lock()
+	if !s.end.IsZero() {
+		s.lock.Unlock()
+		return
+	}
+	s.end = s.tracer.clock.Now()
+	s.lock.Unlock()
+
+	s.tracer.ended(s)
+}
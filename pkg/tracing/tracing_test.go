@@ -0,0 +1,130 @@
+/*
+Copyright 2017 Heptio Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tracing
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"k8s.io/apimachinery/pkg/util/clock"
+)
+
+type fakeExporter struct {
+	spans []*Span
+}
+
+func (e *fakeExporter) export(spans []*Span) error {
+	e.spans = append(e.spans, spans...)
+	return nil
+}
+
+func TestStartWithoutTracer(t *testing.T) {
+	SetTracer(nil)
+
+	ctx := context.Background()
+	spanCtx, span := Start(ctx, "backup")
+
+	assert.Nil(t, span)
+	assert.Equal(t, ctx, spanCtx)
+
+	// a nil span's methods do nothing
+	span.SetAttribute("ark.backup", "ns/name")
+	span.RecordError(errors.New("failed"))
+	span.End()
+}
+
+func TestSpans(t *testing.T) {
+	exporter := &fakeExporter{}
+	now := time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC)
+	fakeClock := clock.NewFakeClock(now)
+	tracer := newTracer(exporter, fakeClock)
+
+	SetTracer(tracer)
+	defer SetTracer(nil)
+
+	ctx, parent := Start(context.Background(), "backup")
+	require.NotNil(t, parent)
+	parent.SetAttribute("ark.backup", "ns/name")
+
+	fakeClock.Step(time.Second)
+	_, child := Start(ctx, "collect pods")
+	require.NotNil(t, child)
+	child.SetAttribute("ark.items", 3)
+	child.SetAttribute("ark.ignored", 1.5)
+	child.RecordError(errors.New("failed"))
+	fakeClock.Step(time.Second)
+	child.End()
+	parent.End()
+
+	// ending a span again has no effect
+	fakeClock.Step(time.Second)
+	parent.End()
+
+	// a new trace isn't related to the first
+	_, other := Start(context.Background(), "restore")
+	other.End()
+
+	require.NoError(t, tracer.flush())
+	require.Len(t, exporter.spans, 3)
+
+	assert.Equal(t, child, exporter.spans[0])
+	assert.Equal(t, parent, exporter.spans[1])
+	assert.Equal(t, other, exporter.spans[2])
+
+	assert.Equal(t, parent.traceID, child.traceID)
+	assert.Equal(t, parent.spanID, child.parentID)
+	assert.Equal(t, [8]byte{}, parent.parentID)
+	assert.NotEqual(t, parent.spanID, child.spanID)
+	assert.NotEqual(t, parent.traceID, other.traceID)
+
+	assert.Equal(t, now, parent.start)
+	assert.Equal(t, now.Add(2*time.Second), parent.end)
+	assert.Equal(t, now.Add(time.Second), child.start)
+	assert.Equal(t, now.Add(2*time.Second), child.end)
+
+	assert.Equal(t, []attribute{{key: "ark.backup", value: "ns/name"}}, parent.attributes)
+	assert.Equal(t, []attribute{{key: "ark.items", value: int64(3)}}, child.attributes)
+	assert.EqualError(t, child.err, "failed")
+	assert.NoError(t, parent.err)
+
+	// exported spans aren't exported again
+	exporter.spans = nil
+	require.NoError(t, tracer.flush())
+	assert.Empty(t, exporter.spans)
+}
+
+func TestPendingSpansLimit(t *testing.T) {
+	exporter := &fakeExporter{}
+	tracer := newTracer(exporter, clock.NewFakeClock(time.Now()))
+
+	SetTracer(tracer)
+	defer SetTracer(nil)
+
+	for i := 0; i < maxPendingSpans+5; i++ {
+		_, span := Start(context.Background(), "span")
+		span.End()
+	}
+
+	require.NoError(t, tracer.flush())
+	assert.Len(t, exporter.spans, maxPendingSpans)
+	assert.Equal(t, 0, tracer.dropped)
+}
@@ -0,0 +1,216 @@
+/*
+Copyright 2017 Heptio Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package uploader
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// kopiaUploader implements Uploader by running kopia. Kopia compresses the data it stores, and
+// handles volumes with millions of small files better than restic.
+type kopiaUploader struct {
+	limits Limits
+}
+
+func (u kopiaUploader) Backup(repo, path string, tags map[string]string) (string, error) {
+	configFile, cleanup, err := u.connect(repo)
+	if err != nil {
+		return "", err
+	}
+	defer cleanup()
+
+	args := []string{"snapshot", "create", path, "--json"}
+	for _, key := range sortedKeys(tags) {
+		args = append(args, "--tags", key+":"+tags[key])
+	}
+
+	output, err := u.run(repo, configFile, args...)
+	if err != nil {
+		return "", err
+	}
+
+	var manifest struct {
+		ID string `json:"id"`
+	}
+	if err := json.Unmarshal([]byte(output), &manifest); err != nil || manifest.ID == "" {
+		return "", fmt.Errorf("kopia didn't report the ID of the snapshot: %s", output)
+	}
+	return manifest.ID, nil
+}
+
+func (u kopiaUploader) Restore(repo, snapshotID, target string) (string, error) {
+	configFile, cleanup, err := u.connect(repo)
+	if err != nil {
+		return "", err
+	}
+	defer cleanup()
+
+	// unlike restic, kopia restores the snapshotted directory's contents directly into target.
+	if _, err := u.run(repo, configFile, "snapshot", "restore", snapshotID, target); err != nil {
+		return "", err
+	}
+	return target, nil
+}
+
+// connect connects to the kopia repository repo, creating it if it doesn't exist yet, and
+// returns the kopia config file that records the connection, and a function that removes it.
+// The connection's bandwidth is limited to u's limits.
+func (u kopiaUploader) connect(repo string) (string, func(), error) {
+	storageArgs, err := kopiaStorageArgs(repo)
+	if err != nil {
+		return "", nil, err
+	}
+	storageArgs = append(storageArgs, kopiaLimitArgs(u.limits)...)
+
+	dir, err := ioutil.TempDir("", "ark-kopia-")
+	if err != nil {
+		return "", nil, err
+	}
+	cleanup := func() { os.RemoveAll(dir) }
+	configFile := filepath.Join(dir, "repository.config")
+
+	if _, err := u.run(repo, configFile, append([]string{"repository", "connect"}, storageArgs...)...); err != nil {
+		if _, err := u.run(repo, configFile, append([]string{"repository", "create"}, storageArgs...)...); err != nil {
+			cleanup()
+			return "", nil, fmt.Errorf("error initializing kopia repository: %v", err)
+		}
+	}
+	return configFile, cleanup, nil
+}
+
+// run runs kopia with args, using the connection to the repository repo recorded in
+// configFile, and returns its combined output. The repository's password is read from the
+// RESTIC_PASSWORD environment variable, so that kopia and restic repositories share it.
+func (u kopiaUploader) run(repo, configFile string, args ...string) (string, error) {
+	env := []string{
+		"KOPIA_PASSWORD=" + os.Getenv("RESTIC_PASSWORD"),
+		"KOPIA_CHECK_FOR_UPDATES=false",
+	}
+	env = append(env, u.limits.env()...)
+
+	switch {
+	case strings.HasPrefix(repo, "s3:"):
+		// kopia's S3 client reads the shared credentials file, like restic's.
+		env = append(env, "AWS_SHARED_CREDENTIALS_FILE="+filepath.Join(credentialsDir, "cloud"))
+	case strings.HasPrefix(repo, "azure:"):
+		azureEnv, err := azureCredentialsEnv("AZURE_STORAGE_ACCOUNT", "AZURE_STORAGE_KEY")
+		if err != nil {
+			return "", err
+		}
+		env = append(env, azureEnv...)
+	}
+
+	return run(env, "kopia", append(args, "--config-file", configFile)...)
+}
+
+// KopiaDeleteScript returns a shell script that deletes the kopia snapshot snapshotID from the
+// repository repo, succeeding if it has already been deleted, then runs the repository's full
+// maintenance to remove the data only it referenced. It's run in the server's helper pods, which
+// have the repository's password in RESTIC_PASSWORD, the cloud-credentials secret mounted at
+// credentialsDir, and kopia on their PATH. The helper pods always connect as the same user and
+// host, so that they stay the owner of the repository's maintenance.
+func KopiaDeleteScript(repo, snapshotID string) (string, error) {
+	storageArgs, err := kopiaStorageArgs(repo)
+	if err != nil {
+		return "", err
+	}
+
+	lines := []string{
+		"set -e",
+		`export KOPIA_PASSWORD="$RESTIC_PASSWORD" KOPIA_CHECK_FOR_UPDATES=false KOPIA_CONFIG_PATH=/tmp/repository.config`,
+	}
+	switch {
+	case strings.HasPrefix(repo, "s3:"):
+		lines = append(lines, "export AWS_SHARED_CREDENTIALS_FILE="+filepath.Join(credentialsDir, "cloud"))
+	case strings.HasPrefix(repo, "azure:"):
+		lines = append(lines, fmt.Sprintf(`export AZURE_STORAGE_ACCOUNT="$(cat %s)" AZURE_STORAGE_KEY="$(cat %s)"`,
+			filepath.Join(credentialsDir, "AZURE_STORAGE_ACCOUNT_ID"), filepath.Join(credentialsDir, "AZURE_STORAGE_KEY")))
+	}
+	lines = append(lines,
+		"kopia repository connect "+strings.Join(storageArgs, " ")+" --override-username=ark --override-hostname=ark-server",
+		"kopia snapshot delete "+snapshotID+" --delete > /tmp/delete.log 2>&1 || grep -qi 'not found' /tmp/delete.log || { cat /tmp/delete.log; exit 1; }",
+		"kopia maintenance set --owner=me",
+		"kopia maintenance run --full",
+	)
+	return strings.Join(lines, "\n"), nil
+}
+
+// kopiaLimitArgs returns the arguments of kopia's repository connect and create commands that
+// limit the repository's bandwidth to limits. Kopia's limits are in bytes per second.
+func kopiaLimitArgs(limits Limits) []string {
+	var args []string
+	if limits.UploadKiBps > 0 {
+		args = append(args, "--max-upload-speed", strconv.Itoa(limits.UploadKiBps*1024))
+	}
+	if limits.DownloadKiBps > 0 {
+		args = append(args, "--max-download-speed", strconv.Itoa(limits.DownloadKiBps*1024))
+	}
+	return args
+}
+
+// kopiaStorageArgs returns the arguments of kopia's repository connect and create commands that
+// give the storage of the repository with the restic-style identifier repo.
+func kopiaStorageArgs(repo string) ([]string, error) {
+	switch {
+	case strings.HasPrefix(repo, "s3:"):
+		// s3:[<scheme>://]<endpoint>/<bucket>/<prefix>
+		location := strings.TrimPrefix(repo, "s3:")
+		insecure := strings.HasPrefix(location, "http://")
+		location = strings.TrimPrefix(strings.TrimPrefix(location, "http://"), "https://")
+
+		parts := strings.SplitN(location, "/", 3)
+		if len(parts) != 3 || parts[0] == "" || parts[1] == "" {
+			return nil, fmt.Errorf("invalid repository identifier %q", repo)
+		}
+		args := []string{"s3", "--endpoint", parts[0], "--bucket", parts[1], "--prefix", parts[2] + "/"}
+		if insecure {
+			args = append(args, "--disable-tls")
+		}
+		return args, nil
+	case strings.HasPrefix(repo, "gs:"):
+		// gs:<bucket>:/<prefix>
+		bucket, prefix, err := splitBucketPrefix(repo, "gs:")
+		if err != nil {
+			return nil, err
+		}
+		return []string{"gcs", "--bucket", bucket, "--prefix", prefix, "--credentials-file", filepath.Join(credentialsDir, "cloud")}, nil
+	case strings.HasPrefix(repo, "azure:"):
+		// azure:<container>:/<prefix>
+		container, prefix, err := splitBucketPrefix(repo, "azure:")
+		if err != nil {
+			return nil, err
+		}
+		return []string{"azure", "--container", container, "--prefix", prefix}, nil
+	}
+	return nil, fmt.Errorf("invalid repository identifier %q", repo)
+}
+
+// splitBucketPrefix splits the repository identifier repo, of the form
+// <scheme><bucket>:/<prefix>, into its bucket and its prefix, which ends with a slash.
+func splitBucketPrefix(repo, scheme string) (string, string, error) {
+	parts := strings.SplitN(strings.TrimPrefix(repo, scheme), ":/", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", fmt.Errorf("invalid repository identifier %q", repo)
+	}
+	return parts[0], parts[1] + "/", nil
+}
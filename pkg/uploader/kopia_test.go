@@ -0,0 +1,94 @@
+/*
+Copyright 2017 Heptio Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package uploader
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKopiaStorageArgs(t *testing.T) {
+	tests := []struct {
+		name         string
+		repo         string
+		expectedArgs []string
+		expectedErr  bool
+	}{
+		{
+			name:         "s3",
+			repo:         "s3:s3.us-west-2.amazonaws.com/bucket/prefix/.ark-kopia/ns-1",
+			expectedArgs: []string{"s3", "--endpoint", "s3.us-west-2.amazonaws.com", "--bucket", "bucket", "--prefix", "prefix/.ark-kopia/ns-1/"},
+		},
+		{
+			name:         "s3 with an http endpoint disables TLS",
+			repo:         "s3:http://minio.ark.svc:9000/bucket/.ark-kopia/ns-1",
+			expectedArgs: []string{"s3", "--endpoint", "minio.ark.svc:9000", "--bucket", "bucket", "--prefix", ".ark-kopia/ns-1/", "--disable-tls"},
+		},
+		{
+			name:         "gcs",
+			repo:         "gs:bucket:/.ark-kopia/ns-1",
+			expectedArgs: []string{"gcs", "--bucket", "bucket", "--prefix", ".ark-kopia/ns-1/", "--credentials-file", "/credentials/cloud"},
+		},
+		{
+			name:         "azure",
+			repo:         "azure:container:/prefix/.ark-kopia/ns-1",
+			expectedArgs: []string{"azure", "--container", "container", "--prefix", "prefix/.ark-kopia/ns-1/"},
+		},
+		{
+			name:        "s3 without a bucket is an error",
+			repo:        "s3:s3.us-west-2.amazonaws.com",
+			expectedErr: true,
+		},
+		{
+			name:        "unknown storage is an error",
+			repo:        "b2:bucket:/.ark-kopia/ns-1",
+			expectedErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			args, err := kopiaStorageArgs(test.repo)
+			if test.expectedErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, test.expectedArgs, args)
+		})
+	}
+}
+
+func TestKopiaDeleteScript(t *testing.T) {
+	script, err := KopiaDeleteScript("azure:container:/.ark-kopia/ns-1", "k123")
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"set -e",
+		`export KOPIA_PASSWORD="$RESTIC_PASSWORD" KOPIA_CHECK_FOR_UPDATES=false KOPIA_CONFIG_PATH=/tmp/repository.config`,
+		`export AZURE_STORAGE_ACCOUNT="$(cat /credentials/AZURE_STORAGE_ACCOUNT_ID)" AZURE_STORAGE_KEY="$(cat /credentials/AZURE_STORAGE_KEY)"`,
+		"kopia repository connect azure --container container --prefix .ark-kopia/ns-1/ --override-username=ark --override-hostname=ark-server",
+		"kopia snapshot delete k123 --delete > /tmp/delete.log 2>&1 || grep -qi 'not found' /tmp/delete.log || { cat /tmp/delete.log; exit 1; }",
+		"kopia maintenance set --owner=me",
+		"kopia maintenance run --full",
+	}, strings.Split(script, "\n"))
+
+	_, err = KopiaDeleteScript("b2:bucket:/.ark-kopia/ns-1", "k123")
+	assert.Error(t, err)
+}
@@ -0,0 +1,113 @@
+/*
+Copyright 2017 Heptio Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package uploader
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var snapshotSavedRegexp = regexp.MustCompile(`snapshot ([0-9a-f]+) saved`)
+
+// resticUploader implements Uploader by running restic.
+type resticUploader struct {
+	limits Limits
+}
+
+func (u resticUploader) Backup(repo, path string, tags map[string]string) (string, error) {
+	if _, err := u.run(repo, "snapshots"); err != nil {
+		if _, err := u.run(repo, "init"); err != nil {
+			return "", fmt.Errorf("error initializing restic repository: %v", err)
+		}
+	}
+
+	args := []string{"backup"}
+	for _, key := range sortedKeys(tags) {
+		args = append(args, "--tag", key+"="+tags[key])
+	}
+	args = append(args, path)
+
+	output, err := u.run(repo, args...)
+	if err != nil {
+		return "", err
+	}
+
+	match := snapshotSavedRegexp.FindStringSubmatch(output)
+	if match == nil {
+		return "", fmt.Errorf("restic didn't report the ID of the snapshot: %s", output)
+	}
+	return match[1], nil
+}
+
+func (u resticUploader) Restore(repo, snapshotID, target string) (string, error) {
+	if _, err := u.run(repo, "restore", snapshotID, "--target", target); err != nil {
+		return "", err
+	}
+
+	// restic restores the snapshot under the absolute path it was backed up from, which is the
+	// volume's directory, <pod UID>/volumes/<volume plugin>/<volume directory>, under the
+	// kubelet's pods directory as mounted in the node agent's container.
+	restored, err := filepath.Glob(filepath.Join(target, "*", "*", "volumes", "*", "*"))
+	if err != nil {
+		return "", err
+	}
+	if len(restored) != 1 {
+		return "", fmt.Errorf("expected snapshot %s to hold one volume, found %d", snapshotID, len(restored))
+	}
+	return restored[0], nil
+}
+
+// run runs restic with args against the repository repo, within u's limits, returning its
+// combined output. The repository's password is read from the RESTIC_PASSWORD environment
+// variable.
+func (u resticUploader) run(repo string, args ...string) (string, error) {
+	credentialsEnv, err := resticCredentialsEnv(repo)
+	if err != nil {
+		return "", err
+	}
+	env := append([]string{"RESTIC_REPOSITORY=" + repo}, credentialsEnv...)
+	return run(append(env, u.limits.env()...), "restic", append(args, ResticLimitArgs(u.limits)...)...)
+}
+
+// ResticLimitArgs returns restic's arguments that limit its bandwidth to limits.
+func ResticLimitArgs(limits Limits) []string {
+	var args []string
+	if limits.UploadKiBps > 0 {
+		args = append(args, "--limit-upload", strconv.Itoa(limits.UploadKiBps))
+	}
+	if limits.DownloadKiBps > 0 {
+		args = append(args, "--limit-download", strconv.Itoa(limits.DownloadKiBps))
+	}
+	return args
+}
+
+// resticCredentialsEnv returns the environment variables restic needs to access the repository
+// repo with the cloud credentials in credentialsDir.
+func resticCredentialsEnv(repo string) ([]string, error) {
+	switch {
+	case strings.HasPrefix(repo, "s3:"):
+		return []string{"AWS_SHARED_CREDENTIALS_FILE=" + filepath.Join(credentialsDir, "cloud")}, nil
+	case strings.HasPrefix(repo, "gs:"):
+		return []string{"GOOGLE_APPLICATION_CREDENTIALS=" + filepath.Join(credentialsDir, "cloud")}, nil
+	case strings.HasPrefix(repo, "azure:"):
+		return azureCredentialsEnv("AZURE_ACCOUNT_NAME", "AZURE_ACCOUNT_KEY")
+	}
+	return nil, nil
+}
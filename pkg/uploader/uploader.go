@@ -0,0 +1,119 @@
+/*
+Copyright 2017 Heptio Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package uploader copies the data of pod volumes to and from repositories in object storage,
+// using either restic or kopia.
+package uploader
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	api "github.com/heptio/ark/pkg/apis/ark/v1"
+)
+
+// credentialsDir is where the node agent's container mounts the cloud-credentials secret.
+const credentialsDir = "/credentials"
+
+// Uploader backs up directories to repositories, and restores them from the repositories. Repos
+// are identified by restic-style identifiers like s3:<endpoint>/<bucket>/<prefix>.
+type Uploader interface {
+	// Backup backs up the directory path to the repository repo, which is initialized if it
+	// doesn't exist yet, and returns the ID of the resulting snapshot, which is tagged with tags.
+	Backup(repo, path string, tags map[string]string) (string, error)
+
+	// Restore restores the snapshot snapshotID, from the repository repo, into the empty
+	// directory target, and returns the directory under target that holds the snapshot's data.
+	Restore(repo, snapshotID, target string) (string, error)
+}
+
+// Limits limits the resources an Uploader uses, so that moving volume data doesn't starve the
+// node's workloads. Zero values mean no limit.
+type Limits struct {
+	// UploadKiBps is the bandwidth, in KiB/s, used to write to the repository.
+	UploadKiBps int
+
+	// DownloadKiBps is the bandwidth, in KiB/s, used to read from the repository.
+	DownloadKiBps int
+
+	// CPUs is the number of CPUs the uploader uses at the same time.
+	CPUs int
+}
+
+// env returns the environment variables that apply limits to restic and kopia, both of which
+// are written in Go.
+func (l Limits) env() []string {
+	if l.CPUs > 0 {
+		return []string{"GOMAXPROCS=" + strconv.Itoa(l.CPUs)}
+	}
+	return nil
+}
+
+// New returns the Uploader for uploaderType, which is restic if it's empty, that keeps within
+// limits.
+func New(uploaderType string, limits Limits) (Uploader, error) {
+	switch uploaderType {
+	case "", api.UploaderTypeRestic:
+		return resticUploader{limits: limits}, nil
+	case api.UploaderTypeKopia:
+		return kopiaUploader{limits: limits}, nil
+	}
+	return nil, fmt.Errorf("uploader must be %s or %s, not %q", api.UploaderTypeRestic, api.UploaderTypeKopia, uploaderType)
+}
+
+// sortedKeys returns the keys of tags in order, so snapshots' tags are always given in the
+// same order.
+func sortedKeys(tags map[string]string) []string {
+	keys := make([]string, 0, len(tags))
+	for key := range tags {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// run runs name with args and the additional environment variables env, returning its combined
+// output.
+func run(env []string, name string, args ...string) (string, error) {
+	cmd := exec.Command(name, args...)
+	cmd.Env = append(os.Environ(), env...)
+
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("error running %s %s: %v: %s", name, args[0], err, strings.TrimSpace(string(output)))
+	}
+	return string(output), nil
+}
+
+// azureCredentialsEnv returns the environment variables accountName and accountKey, set to the
+// name and key of the Azure storage account in credentialsDir.
+func azureCredentialsEnv(accountName, accountKey string) ([]string, error) {
+	var env []string
+	for name, key := range map[string]string{accountName: "AZURE_STORAGE_ACCOUNT_ID", accountKey: "AZURE_STORAGE_KEY"} {
+		value, err := ioutil.ReadFile(filepath.Join(credentialsDir, key))
+		if err != nil {
+			return nil, fmt.Errorf("error reading Azure credentials: %v", err)
+		}
+		env = append(env, name+"="+strings.TrimSpace(string(value)))
+	}
+	return env, nil
+}
@@ -0,0 +1,48 @@
+/*
+Copyright 2017 Heptio Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package uploader
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNew(t *testing.T) {
+	u, err := New("", Limits{})
+	assert.NoError(t, err)
+	assert.Equal(t, resticUploader{}, u)
+
+	u, err = New("kopia", Limits{CPUs: 1})
+	assert.NoError(t, err)
+	assert.Equal(t, kopiaUploader{limits: Limits{CPUs: 1}}, u)
+
+	_, err = New("rsync", Limits{})
+	assert.Error(t, err)
+}
+
+func TestLimitArgs(t *testing.T) {
+	limits := Limits{UploadKiBps: 1024, DownloadKiBps: 2048, CPUs: 2}
+
+	assert.Equal(t, []string{"--limit-upload", "1024", "--limit-download", "2048"}, ResticLimitArgs(limits))
+	assert.Equal(t, []string{"--max-upload-speed", "1048576", "--max-download-speed", "2097152"}, kopiaLimitArgs(limits))
+	assert.Equal(t, []string{"GOMAXPROCS=2"}, limits.env())
+
+	assert.Empty(t, ResticLimitArgs(Limits{}))
+	assert.Empty(t, kopiaLimitArgs(Limits{}))
+	assert.Empty(t, Limits{}.env())
+}
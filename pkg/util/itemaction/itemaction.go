@@ -0,0 +1,175 @@
+/*
+Copyright 2017 Heptio Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package itemaction has the parts of backup and restore item actions that are the same for both:
+// selecting the items an action applies to, and registering actions with the server.
+package itemaction
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"github.com/heptio/ark/pkg/util/collections"
+)
+
+// ResourceSelector selects the items an item action applies to. Empty includes lists match all
+// namespaces or resources.
+type ResourceSelector struct {
+	// IncludedNamespaces and ExcludedNamespaces filter items by namespace: the namespace they're
+	// backed up from, or the one they're restored into. Cluster-scoped items are matched by the
+	// includes list only if it's empty or contains "*".
+	IncludedNamespaces []string
+	ExcludedNamespaces []string
+	// IncludedResources and ExcludedResources filter items by resource, in
+	// <RESOURCE>.<GROUP> format.
+	IncludedResources []string
+	ExcludedResources []string
+	// LabelSelector restricts the action to items whose labels match it. If it's nil, items
+	// aren't filtered by label.
+	LabelSelector labels.Selector
+}
+
+// Selector is a ResourceSelector whose resources have been resolved.
+type Selector struct {
+	namespaces *collections.IncludesExcludes
+	resources  *collections.IncludesExcludes
+	labels     labels.Selector
+}
+
+// Resolve resolves selector's resources using mapper.
+func Resolve(mapper meta.RESTMapper, selector ResourceSelector) (*Selector, error) {
+	resolve := func(resource string) (string, error) {
+		gvr, err := mapper.ResourceFor(schema.ParseGroupResource(resource).WithVersion(""))
+		if err != nil {
+			return "", fmt.Errorf("error resolving resource %q of item action: %v", resource, err)
+		}
+		gr := gvr.GroupResource()
+		return gr.String(), nil
+	}
+
+	resources := collections.NewIncludesExcludes()
+	if len(selector.IncludedResources) == 0 {
+		resources.Includes("*")
+	}
+	for _, resource := range selector.IncludedResources {
+		if resource == "*" {
+			resources.Includes("*")
+			continue
+		}
+
+		gr, err := resolve(resource)
+		if err != nil {
+			return nil, err
+		}
+		resources.Includes(gr)
+	}
+	for _, resource := range selector.ExcludedResources {
+		gr, err := resolve(resource)
+		if err != nil {
+			return nil, err
+		}
+		resources.Excludes(gr)
+	}
+
+	namespaces := collections.NewIncludesExcludes().
+		Includes(selector.IncludedNamespaces...).
+		Excludes(selector.ExcludedNamespaces...)
+	if len(selector.IncludedNamespaces) == 0 {
+		namespaces.Includes("*")
+	}
+
+	labelSelector := selector.LabelSelector
+	if labelSelector == nil {
+		labelSelector = labels.Everything()
+	}
+
+	return &Selector{
+		namespaces: namespaces,
+		resources:  resources,
+		labels:     labelSelector,
+	}, nil
+}
+
+// Matches returns whether the selector matches the item of groupResource in namespace, which is
+// "" for cluster-scoped items.
+func (s *Selector) Matches(groupResource, namespace string, itemLabels labels.Set) bool {
+	if !s.resources.ShouldInclude(groupResource) {
+		return false
+	}
+
+	if namespace == "" {
+		if !s.namespaces.ShouldInclude("*") {
+			return false
+		}
+	} else if !s.namespaces.ShouldInclude(namespace) {
+		return false
+	}
+
+	return s.labels.Matches(itemLabels)
+}
+
+// Registry holds the item actions registered under their names.
+type Registry struct {
+	// kind is the kind of item actions held, e.g. "backup", for error messages.
+	kind string
+
+	lock    sync.Mutex
+	actions map[string]interface{}
+}
+
+// NewRegistry returns an empty Registry of kind item actions.
+func NewRegistry(kind string) *Registry {
+	return &Registry{
+		kind:    kind,
+		actions: make(map[string]interface{}),
+	}
+}
+
+// Register registers action under name. It panics if an action is already registered under name.
+func (r *Registry) Register(name string, action interface{}) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if _, found := r.actions[name]; found {
+		panic(fmt.Sprintf("%s item action %q is already registered", r.kind, name))
+	}
+	r.actions[name] = action
+}
+
+// Registered returns the names of the registered actions and the actions themselves, sorted by
+// name.
+func (r *Registry) Registered() ([]string, []interface{}) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	var names []string
+	for name := range r.actions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var actions []interface{}
+	for _, name := range names {
+		actions = append(actions, r.actions[name])
+	}
+
+	return names, actions
+}
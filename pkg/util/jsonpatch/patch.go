@@ -0,0 +1,292 @@
+/*
+Copyright 2017 Heptio Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package jsonpatch applies JSON patches, as defined by RFC 6902, to JSON documents.
+package jsonpatch
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Operation is a single operation of a JSON patch.
+type Operation struct {
+	// Op is one of "add", "remove", "replace", "move", "copy", or "test".
+	Op string `json:"op"`
+	// Path is a JSON pointer (RFC 6901) to the location the operation applies to.
+	Path string `json:"path"`
+	// From is a JSON pointer to the location a "move" or "copy" operation takes its value from.
+	From string `json:"from,omitempty"`
+	// Value is the value an "add", "replace", or "test" operation uses.
+	Value interface{} `json:"value,omitempty"`
+}
+
+// Patch is a JSON patch: a list of operations that are applied in order.
+type Patch []Operation
+
+// Validate returns an error if any of the patch's operations is malformed.
+func (p Patch) Validate() error {
+	for i, op := range p {
+		if err := op.validate(); err != nil {
+			return fmt.Errorf("operation %d: %v", i, err)
+		}
+	}
+	return nil
+}
+
+func (op Operation) validate() error {
+	switch op.Op {
+	case "add", "remove", "replace", "test":
+	case "move", "copy":
+		if _, err := parsePointer(op.From); err != nil {
+			return fmt.Errorf("invalid from: %v", err)
+		}
+	default:
+		return fmt.Errorf("invalid op %q", op.Op)
+	}
+
+	if _, err := parsePointer(op.Path); err != nil {
+		return fmt.Errorf("invalid path: %v", err)
+	}
+
+	return nil
+}
+
+// Apply applies the patch to the JSON document doc, returning the patched document. If any
+// operation fails, an error is returned and none of the patch is applied.
+func (p Patch) Apply(doc []byte) ([]byte, error) {
+	var obj interface{}
+	if err := json.Unmarshal(doc, &obj); err != nil {
+		return nil, err
+	}
+
+	for i, op := range p {
+		var err error
+		if obj, err = op.apply(obj); err != nil {
+			return nil, fmt.Errorf("error applying operation %d (%s %s): %v", i, op.Op, op.Path, err)
+		}
+	}
+
+	return json.Marshal(obj)
+}
+
+func (op Operation) apply(doc interface{}) (interface{}, error) {
+	if err := op.validate(); err != nil {
+		return nil, err
+	}
+	path, _ := parsePointer(op.Path)
+
+	// values are normalized to what they'd be decoded from JSON as, so they can be compared
+	// with the document's values
+	value, err := normalize(op.Value)
+	if err != nil {
+		return nil, err
+	}
+
+	switch op.Op {
+	case "add":
+		return add(doc, path, value)
+	case "remove":
+		return remove(doc, path)
+	case "replace":
+		if doc, err = remove(doc, path); err != nil {
+			return nil, err
+		}
+		return add(doc, path, value)
+	case "test":
+		current, err := get(doc, path)
+		if err != nil {
+			return nil, err
+		}
+		if !reflect.DeepEqual(current, value) {
+			return nil, fmt.Errorf("test failed: value is %v", current)
+		}
+		return doc, nil
+	}
+
+	// move or copy
+	from, _ := parsePointer(op.From)
+	if value, err = get(doc, from); err != nil {
+		return nil, err
+	}
+
+	if op.Op == "move" {
+		if len(path) > len(from) && reflect.DeepEqual(path[:len(from)], from) {
+			return nil, fmt.Errorf("can't move %s into one of its children", op.From)
+		}
+		if doc, err = remove(doc, from); err != nil {
+			return nil, err
+		}
+	} else if value, err = normalize(value); err != nil {
+		// normalizing the value deep copies it
+		return nil, err
+	}
+
+	return add(doc, path, value)
+}
+
+// parsePointer returns the reference tokens of the JSON pointer pointer.
+func parsePointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("%q doesn't start with /", pointer)
+	}
+
+	tokens := strings.Split(pointer[1:], "/")
+	for i := range tokens {
+		tokens[i] = strings.Replace(strings.Replace(tokens[i], "~1", "/", -1), "~0", "~", -1)
+	}
+	return tokens, nil
+}
+
+// normalize returns value as it would be decoded from its JSON encoding.
+func normalize(value interface{}) (interface{}, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+
+	var ret interface{}
+	if err := json.Unmarshal(data, &ret); err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
+
+// get returns the value at path in doc.
+func get(doc interface{}, path []string) (interface{}, error) {
+	for _, token := range path {
+		var err error
+		if doc, err = child(doc, token); err != nil {
+			return nil, err
+		}
+	}
+	return doc, nil
+}
+
+// child returns the value of container's member or element token.
+func child(container interface{}, token string) (interface{}, error) {
+	switch c := container.(type) {
+	case map[string]interface{}:
+		value, found := c[token]
+		if !found {
+			return nil, fmt.Errorf("member %q not found", token)
+		}
+		return value, nil
+	case []interface{}:
+		i, err := index(token, len(c)-1)
+		if err != nil {
+			return nil, err
+		}
+		return c[i], nil
+	}
+	return nil, fmt.Errorf("can't get %q of a %T", token, container)
+}
+
+// index returns token as an array index, returning an error if it's not a valid index or is
+// greater than max.
+func index(token string, max int) (int, error) {
+	i, err := strconv.Atoi(token)
+	if err != nil || strings.Trim(token, "0123456789") != "" || (len(token) > 1 && token[0] == '0') {
+		return 0, fmt.Errorf("invalid array index %q", token)
+	}
+	if i > max {
+		return 0, fmt.Errorf("array index %d out of bounds", i)
+	}
+	return i, nil
+}
+
+// update returns doc with the container of the value at path replaced by the result of calling
+// fn with it and the last token of path.
+func update(doc interface{}, path []string, fn func(container interface{}, token string) (interface{}, error)) (interface{}, error) {
+	if len(path) == 1 {
+		return fn(doc, path[0])
+	}
+
+	value, err := child(doc, path[0])
+	if err != nil {
+		return nil, err
+	}
+	if value, err = update(value, path[1:], fn); err != nil {
+		return nil, err
+	}
+
+	// the updated value may be a new slice, so it's set in its container
+	switch c := doc.(type) {
+	case map[string]interface{}:
+		c[path[0]] = value
+	case []interface{}:
+		i, _ := index(path[0], len(c)-1)
+		c[i] = value
+	}
+	return doc, nil
+}
+
+// add returns doc with value added at path.
+func add(doc interface{}, path []string, value interface{}) (interface{}, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+
+	return update(doc, path, func(container interface{}, token string) (interface{}, error) {
+		switch c := container.(type) {
+		case map[string]interface{}:
+			c[token] = value
+			return c, nil
+		case []interface{}:
+			if token == "-" {
+				return append(c, value), nil
+			}
+			i, err := index(token, len(c))
+			if err != nil {
+				return nil, err
+			}
+			c = append(c, nil)
+			copy(c[i+1:], c[i:])
+			c[i] = value
+			return c, nil
+		}
+		return nil, fmt.Errorf("can't add %q to a %T", token, container)
+	})
+}
+
+// remove returns doc with the value at path removed.
+func remove(doc interface{}, path []string) (interface{}, error) {
+	if len(path) == 0 {
+		return nil, fmt.Errorf("can't remove the whole document")
+	}
+
+	return update(doc, path, func(container interface{}, token string) (interface{}, error) {
+		if _, err := child(container, token); err != nil {
+			return nil, err
+		}
+
+		switch c := container.(type) {
+		case map[string]interface{}:
+			delete(c, token)
+			return c, nil
+		case []interface{}:
+			i, _ := index(token, len(c)-1)
+			return append(c[:i], c[i+1:]...), nil
+		}
+		return container, nil
+	})
+}
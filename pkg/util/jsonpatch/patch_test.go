@@ -0,0 +1,154 @@
+/*
+Copyright 2017 Heptio Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package jsonpatch
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestApply(t *testing.T) {
+	doc := `{"spec": {"replicas": 3, "hosts": ["a.example.com", "b.example.com"], "a/b": {"c~d": 1}}}`
+
+	tests := []struct {
+		name        string
+		patch       Patch
+		expected    string
+		expectedErr bool
+	}{
+		{
+			name:     "replace a member",
+			patch:    Patch{{Op: "replace", Path: "/spec/replicas", Value: 1}},
+			expected: `{"spec": {"replicas": 1, "hosts": ["a.example.com", "b.example.com"], "a/b": {"c~d": 1}}}`,
+		},
+		{
+			name:     "add a member",
+			patch:    Patch{{Op: "add", Path: "/spec/paused", Value: true}},
+			expected: `{"spec": {"replicas": 3, "paused": true, "hosts": ["a.example.com", "b.example.com"], "a/b": {"c~d": 1}}}`,
+		},
+		{
+			name:     "add an object",
+			patch:    Patch{{Op: "add", Path: "/metadata", Value: map[string]interface{}{"labels": map[string]string{"env": "dr"}}}},
+			expected: `{"metadata": {"labels": {"env": "dr"}}, "spec": {"replicas": 3, "hosts": ["a.example.com", "b.example.com"], "a/b": {"c~d": 1}}}`,
+		},
+		{
+			name:     "insert into an array",
+			patch:    Patch{{Op: "add", Path: "/spec/hosts/1", Value: "c.example.com"}},
+			expected: `{"spec": {"replicas": 3, "hosts": ["a.example.com", "c.example.com", "b.example.com"], "a/b": {"c~d": 1}}}`,
+		},
+		{
+			name:     "append to an array",
+			patch:    Patch{{Op: "add", Path: "/spec/hosts/-", Value: "c.example.com"}},
+			expected: `{"spec": {"replicas": 3, "hosts": ["a.example.com", "b.example.com", "c.example.com"], "a/b": {"c~d": 1}}}`,
+		},
+		{
+			name:     "replace an array element",
+			patch:    Patch{{Op: "replace", Path: "/spec/hosts/0", Value: "dr.example.com"}},
+			expected: `{"spec": {"replicas": 3, "hosts": ["dr.example.com", "b.example.com"], "a/b": {"c~d": 1}}}`,
+		},
+		{
+			name:     "remove an array element",
+			patch:    Patch{{Op: "remove", Path: "/spec/hosts/0"}},
+			expected: `{"spec": {"replicas": 3, "hosts": ["b.example.com"], "a/b": {"c~d": 1}}}`,
+		},
+		{
+			name:     "remove an escaped member",
+			patch:    Patch{{Op: "remove", Path: "/spec/a~1b/c~0d"}},
+			expected: `{"spec": {"replicas": 3, "hosts": ["a.example.com", "b.example.com"], "a/b": {}}}`,
+		},
+		{
+			name:     "move a member",
+			patch:    Patch{{Op: "move", From: "/spec/hosts", Path: "/hosts"}},
+			expected: `{"hosts": ["a.example.com", "b.example.com"], "spec": {"replicas": 3, "a/b": {"c~d": 1}}}`,
+		},
+		{
+			name: "copy a member",
+			patch: Patch{
+				{Op: "copy", From: "/spec/hosts", Path: "/spec/oldHosts"},
+				{Op: "remove", Path: "/spec/hosts/1"},
+			},
+			expected: `{"spec": {"replicas": 3, "hosts": ["a.example.com"], "oldHosts": ["a.example.com", "b.example.com"], "a/b": {"c~d": 1}}}`,
+		},
+		{
+			name: "passing test",
+			patch: Patch{
+				{Op: "test", Path: "/spec/replicas", Value: 3},
+				{Op: "replace", Path: "/spec/replicas", Value: 1},
+			},
+			expected: `{"spec": {"replicas": 1, "hosts": ["a.example.com", "b.example.com"], "a/b": {"c~d": 1}}}`,
+		},
+		{
+			name: "failing test",
+			patch: Patch{
+				{Op: "replace", Path: "/spec/replicas", Value: 1},
+				{Op: "test", Path: "/spec/replicas", Value: 3},
+			},
+			expectedErr: true,
+		},
+		{
+			name:        "replacing a missing member fails",
+			patch:       Patch{{Op: "replace", Path: "/spec/paused", Value: true}},
+			expectedErr: true,
+		},
+		{
+			name:        "adding to a missing object fails",
+			patch:       Patch{{Op: "add", Path: "/status/phase", Value: "Running"}},
+			expectedErr: true,
+		},
+		{
+			name:        "out of bounds index fails",
+			patch:       Patch{{Op: "add", Path: "/spec/hosts/3", Value: "c.example.com"}},
+			expectedErr: true,
+		},
+		{
+			name:        "invalid index fails",
+			patch:       Patch{{Op: "remove", Path: "/spec/hosts/01"}},
+			expectedErr: true,
+		},
+		{
+			name:        "moving into a child fails",
+			patch:       Patch{{Op: "move", From: "/spec", Path: "/spec/spec"}},
+			expectedErr: true,
+		},
+		{
+			name:        "invalid op fails",
+			patch:       Patch{{Op: "merge", Path: "/spec"}},
+			expectedErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			patched, err := test.patch.Apply([]byte(doc))
+			if test.expectedErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.JSONEq(t, test.expected, string(patched))
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	assert.NoError(t, Patch{{Op: "copy", From: "/a", Path: "/b"}, {Op: "remove", Path: ""}}.Validate())
+	assert.Error(t, Patch{{Op: "copy", From: "a", Path: "/b"}}.Validate())
+	assert.Error(t, Patch{{Op: "add", Path: "spec"}}.Validate())
+	assert.Error(t, Patch{{Op: "merge", Path: "/spec"}}.Validate())
+}
@@ -0,0 +1,147 @@
+/*
+Copyright 2017 Heptio Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kube
+
+import (
+	"encoding/json"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/pkg/api/v1"
+
+	"github.com/heptio/ark/pkg/util/collections"
+)
+
+// hostnameLabel is the node label that the node affinity of local and hostPath PersistentVolumes
+// uses to name the node their data is on.
+const hostnameLabel = "kubernetes.io/hostname"
+
+// GetHostVolume returns the path, on its node, of the data of a local or hostPath
+// PersistentVolume, and the name of the node, which the volume's node affinity must require
+// using the kubernetes.io/hostname label. The path is empty if pv isn't a local or hostPath
+// volume, and the node is empty if pv's node affinity doesn't name a single node. It returns an
+// error if pv's node affinity can't be parsed.
+func GetHostVolume(pv map[string]interface{}) (string, string, error) {
+	path, err := collections.GetString(pv, "spec.local.path")
+	if err != nil {
+		if path, err = collections.GetString(pv, "spec.hostPath.path"); err != nil {
+			return "", "", nil
+		}
+	}
+
+	nodes := sets.NewString()
+	err = updateHostnameRequirements(pv, false, func(requirement *v1.NodeSelectorRequirement) {
+		if requirement.Operator == v1.NodeSelectorOpIn {
+			nodes.Insert(requirement.Values...)
+		}
+	})
+	if err != nil {
+		return "", "", err
+	}
+
+	if nodes.Len() != 1 {
+		return path, "", nil
+	}
+	return path, nodes.List()[0], nil
+}
+
+// MapHostVolumeNode replaces the nodes that pv's node affinity names, using the
+// kubernetes.io/hostname label, that are keys of mapping with their values, so that a local or
+// hostPath PersistentVolume is restored onto the node its data is restored to.
+func MapHostVolumeNode(pv map[string]interface{}, mapping map[string]string) error {
+	if len(mapping) == 0 {
+		return nil
+	}
+
+	return updateHostnameRequirements(pv, true, func(requirement *v1.NodeSelectorRequirement) {
+		for i, node := range requirement.Values {
+			if target, ok := mapping[node]; ok {
+				requirement.Values[i] = target
+			}
+		}
+	})
+}
+
+// updateHostnameRequirements calls fn with each kubernetes.io/hostname requirement of pv's node
+// affinity, which is in its spec or, on clusters older than Kubernetes 1.10, its alpha
+// annotation. If write is true, the changes fn makes are written back to pv.
+func updateHostnameRequirements(pv map[string]interface{}, write bool, fn func(*v1.NodeSelectorRequirement)) error {
+	if nodeAffinity, err := collections.GetMap(pv, "spec.nodeAffinity"); err == nil && nodeAffinity["required"] != nil {
+		var required v1.NodeSelector
+		if err := convert(nodeAffinity["required"], &required); err != nil {
+			return err
+		}
+
+		visitHostnameRequirements(&required, fn)
+
+		if write {
+			var out map[string]interface{}
+			if err := convert(required, &out); err != nil {
+				return err
+			}
+			nodeAffinity["required"] = out
+		}
+	}
+
+	annotations, err := collections.GetMap(pv, "metadata.annotations")
+	if err != nil {
+		return nil
+	}
+	value, ok := annotations[v1.AlphaStorageNodeAffinityAnnotation].(string)
+	if !ok {
+		return nil
+	}
+
+	var affinity v1.NodeAffinity
+	if err := json.Unmarshal([]byte(value), &affinity); err != nil {
+		return err
+	}
+	if affinity.RequiredDuringSchedulingIgnoredDuringExecution == nil {
+		return nil
+	}
+
+	visitHostnameRequirements(affinity.RequiredDuringSchedulingIgnoredDuringExecution, fn)
+
+	if write {
+		data, err := json.Marshal(affinity)
+		if err != nil {
+			return err
+		}
+		annotations[v1.AlphaStorageNodeAffinityAnnotation] = string(data)
+	}
+	return nil
+}
+
+// visitHostnameRequirements calls fn with each kubernetes.io/hostname requirement of selector.
+func visitHostnameRequirements(selector *v1.NodeSelector, fn func(*v1.NodeSelectorRequirement)) {
+	for i := range selector.NodeSelectorTerms {
+		expressions := selector.NodeSelectorTerms[i].MatchExpressions
+		for j := range expressions {
+			if expressions[j].Key == hostnameLabel {
+				fn(&expressions[j])
+			}
+		}
+	}
+}
+
+// convert converts in to out by way of JSON.
+func convert(in, out interface{}) error {
+	data, err := json.Marshal(in)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, out)
+}
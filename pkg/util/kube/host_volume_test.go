@@ -0,0 +1,127 @@
+/*
+Copyright 2017 Heptio Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kube
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func localVolume(nodes ...interface{}) map[string]interface{} {
+	return map[string]interface{}{
+		"metadata": map[string]interface{}{"name": "pv-1"},
+		"spec": map[string]interface{}{
+			"local": map[string]interface{}{"path": "/mnt/disks/ssd1"},
+			"nodeAffinity": map[string]interface{}{
+				"required": map[string]interface{}{
+					"nodeSelectorTerms": []interface{}{
+						map[string]interface{}{
+							"matchExpressions": []interface{}{
+								map[string]interface{}{"key": "kubernetes.io/hostname", "operator": "In", "values": nodes},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func TestGetHostVolume(t *testing.T) {
+	tests := []struct {
+		name         string
+		pv           map[string]interface{}
+		expectedPath string
+		expectedNode string
+	}{
+		{
+			name:         "local volume with node affinity",
+			pv:           localVolume("node-1"),
+			expectedPath: "/mnt/disks/ssd1",
+			expectedNode: "node-1",
+		},
+		{
+			name: "hostPath volume with alpha node affinity annotation",
+			pv: map[string]interface{}{
+				"metadata": map[string]interface{}{
+					"name": "pv-1",
+					"annotations": map[string]interface{}{
+						"volume.alpha.kubernetes.io/node-affinity": `{"requiredDuringSchedulingIgnoredDuringExecution":{"nodeSelectorTerms":[{"matchExpressions":[{"key":"kubernetes.io/hostname","operator":"In","values":["node-2"]}]}]}}`,
+					},
+				},
+				"spec": map[string]interface{}{
+					"hostPath": map[string]interface{}{"path": "/data"},
+				},
+			},
+			expectedPath: "/data",
+			expectedNode: "node-2",
+		},
+		{
+			name:         "volume that could be on several nodes has no node",
+			pv:           localVolume("node-1", "node-2"),
+			expectedPath: "/mnt/disks/ssd1",
+		},
+		{
+			name: "hostPath volume without node affinity has no node",
+			pv: map[string]interface{}{
+				"spec": map[string]interface{}{
+					"hostPath": map[string]interface{}{"path": "/data"},
+				},
+			},
+			expectedPath: "/data",
+		},
+		{
+			name: "cloud volume isn't a host volume",
+			pv: map[string]interface{}{
+				"spec": map[string]interface{}{
+					"gcePersistentDisk": map[string]interface{}{"pdName": "disk-1"},
+				},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			path, node, err := GetHostVolume(test.pv)
+			require.NoError(t, err)
+			assert.Equal(t, test.expectedPath, path)
+			assert.Equal(t, test.expectedNode, node)
+		})
+	}
+}
+
+func TestMapHostVolumeNode(t *testing.T) {
+	pv := localVolume("node-1")
+	pv["metadata"].(map[string]interface{})["annotations"] = map[string]interface{}{
+		"volume.alpha.kubernetes.io/node-affinity": `{"requiredDuringSchedulingIgnoredDuringExecution":{"nodeSelectorTerms":[{"matchExpressions":[{"key":"kubernetes.io/hostname","operator":"In","values":["node-1"]}]}]}}`,
+	}
+
+	require.NoError(t, MapHostVolumeNode(pv, map[string]string{"node-1": "node-3"}))
+
+	_, node, err := GetHostVolume(pv)
+	require.NoError(t, err)
+	assert.Equal(t, "node-3", node)
+	assert.Contains(t, pv["metadata"].(map[string]interface{})["annotations"].(map[string]interface{})["volume.alpha.kubernetes.io/node-affinity"], `"values":["node-3"]`)
+
+	// nodes that aren't mapped are left alone
+	require.NoError(t, MapHostVolumeNode(pv, map[string]string{"node-1": "node-4"}))
+	_, node, err = GetHostVolume(pv)
+	require.NoError(t, err)
+	assert.Equal(t, "node-3", node)
+}
@@ -21,6 +21,7 @@ import (
 	"regexp"
 
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	corev1 "k8s.io/client-go/kubernetes/typed/core/v1"
 	"k8s.io/client-go/pkg/api/v1"
 
@@ -43,6 +44,9 @@ func EnsureNamespaceExists(namespace *v1.Namespace, client corev1.NamespaceInter
 
 var ebsVolumeIDRegex = regexp.MustCompile("vol-.*")
 
+// efsCSIDriver is the name of the Amazon EFS CSI driver.
+const efsCSIDriver = "efs.csi.aws.com"
+
 var supportedVolumeTypes = map[string]string{
 	"awsElasticBlockStore": "volumeID",
 	"gcePersistentDisk":    "pdName",
@@ -90,6 +94,19 @@ func GetPVSource(spec map[string]interface{}) (string, map[string]interface{}) {
 	return "", nil
 }
 
+// IsFileSystemVolume returns whether the PV unstructured data describes an NFS share, which
+// includes Amazon EFS file systems mounted over NFS, or an EFS file system provisioned by its CSI
+// driver. These can't be snapshotted like block volumes, so their data is backed up at the file
+// level instead.
+func IsFileSystemVolume(pv map[string]interface{}) bool {
+	if _, err := collections.GetMap(pv, "spec.nfs"); err == nil {
+		return true
+	}
+
+	driver, _ := collections.GetString(pv, "spec.csi.driver")
+	return driver == efsCSIDriver
+}
+
 // SetVolumeID looks for a supported PV source within the provided PV spec data.
 // If sets the appropriate ID field within the source if found, and returns an
 // error if a supported PV source is not found.
@@ -104,3 +121,16 @@ func SetVolumeID(spec map[string]interface{}, volumeID string) error {
 
 	return nil
 }
+
+// ResetPVCVolumeBinding clears a PersistentVolumeClaim's binding to its volume so that a new
+// volume is provisioned for it when it's restored.
+func ResetPVCVolumeBinding(claim *unstructured.Unstructured) {
+	if spec, err := collections.GetMap(claim.Object, "spec"); err == nil {
+		delete(spec, "volumeName")
+	}
+
+	annotations := claim.GetAnnotations()
+	delete(annotations, "pv.kubernetes.io/bind-completed")
+	delete(annotations, "pv.kubernetes.io/bound-by-controller")
+	claim.SetAnnotations(annotations)
+}
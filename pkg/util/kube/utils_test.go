@@ -0,0 +1,80 @@
+/*
+Copyright 2017 Heptio Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kube
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestResetPVCVolumeBinding(t *testing.T) {
+	claim := &unstructured.Unstructured{Object: map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"name": "claim-1",
+			"annotations": map[string]interface{}{
+				"pv.kubernetes.io/bind-completed":      "yes",
+				"pv.kubernetes.io/bound-by-controller": "yes",
+				"foo":                                  "bar",
+			},
+		},
+		"spec": map[string]interface{}{
+			"volumeName":  "pv-1",
+			"accessModes": []interface{}{"ReadWriteOnce"},
+		},
+	}}
+
+	ResetPVCVolumeBinding(claim)
+
+	assert.Equal(t, map[string]string{"foo": "bar"}, claim.GetAnnotations())
+	assert.Equal(t, map[string]interface{}{"accessModes": []interface{}{"ReadWriteOnce"}}, claim.Object["spec"])
+}
+
+func TestIsFileSystemVolume(t *testing.T) {
+	tests := []struct {
+		name     string
+		spec     map[string]interface{}
+		expected bool
+	}{
+		{
+			name:     "NFS volume",
+			spec:     map[string]interface{}{"nfs": map[string]interface{}{"server": "fs-1.efs.us-east-1.amazonaws.com", "path": "/"}},
+			expected: true,
+		},
+		{
+			name:     "EFS CSI volume",
+			spec:     map[string]interface{}{"csi": map[string]interface{}{"driver": "efs.csi.aws.com", "volumeHandle": "fs-1"}},
+			expected: true,
+		},
+		{
+			name: "other CSI volume",
+			spec: map[string]interface{}{"csi": map[string]interface{}{"driver": "ebs.csi.aws.com", "volumeHandle": "vol-1"}},
+		},
+		{
+			name: "block volume",
+			spec: map[string]interface{}{"awsElasticBlockStore": map[string]interface{}{"volumeID": "vol-1"}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.expected, IsFileSystemVolume(map[string]interface{}{"spec": test.spec}))
+		})
+	}
+}
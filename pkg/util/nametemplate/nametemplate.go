@@ -0,0 +1,107 @@
+/*
+Copyright 2017 Heptio Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package nametemplate generates the names of scheduled backups from templates such as
+// "{schedule}-{cluster}-{date}", so that tooling can rely on predictable names.
+package nametemplate
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// Default is the template used when a schedule doesn't specify one.
+const Default = "{schedule}-{timestamp}"
+
+// Vars are the values a template's placeholders are replaced with.
+type Vars struct {
+	// Schedule is the name of the schedule.
+	Schedule string
+
+	// Cluster is the name of the cluster the backup is taken in.
+	Cluster string
+
+	// Time is when the backup is created, in the time zone its date parts are formatted in.
+	Time time.Time
+}
+
+// placeholders maps the placeholders templates can contain, without their braces, to functions
+// returning their values.
+var placeholders = map[string]func(Vars) string{
+	"schedule":  func(v Vars) string { return v.Schedule },
+	"cluster":   func(v Vars) string { return v.Cluster },
+	"timestamp": func(v Vars) string { return v.Time.Format("20060102150405") },
+	"date":      func(v Vars) string { return v.Time.Format("20060102") },
+	"time":      func(v Vars) string { return v.Time.Format("150405") },
+	"year":      func(v Vars) string { return v.Time.Format("2006") },
+	"month":     func(v Vars) string { return v.Time.Format("01") },
+	"day":       func(v Vars) string { return v.Time.Format("02") },
+	"hour":      func(v Vars) string { return v.Time.Format("15") },
+	"minute":    func(v Vars) string { return v.Time.Format("04") },
+	"second":    func(v Vars) string { return v.Time.Format("05") },
+	"weekday":   func(v Vars) string { return strings.ToLower(v.Time.Weekday().String()) },
+}
+
+// Render returns the name template generates for vars. It returns an error if template is
+// malformed or generates an invalid name.
+func Render(template string, vars Vars) (string, error) {
+	var name bytes.Buffer
+	rest := template
+	for {
+		open := strings.IndexAny(rest, "{}")
+		if open == -1 {
+			name.WriteString(rest)
+			break
+		}
+		if rest[open] == '}' {
+			return "", fmt.Errorf("unexpected '}' in %q", template)
+		}
+		name.WriteString(rest[:open])
+
+		length := strings.IndexAny(rest[open+1:], "{}")
+		if length == -1 || rest[open+1+length] != '}' {
+			return "", fmt.Errorf("unclosed '{' in %q", template)
+		}
+
+		placeholder := rest[open+1 : open+1+length]
+		value, found := placeholders[placeholder]
+		if !found {
+			return "", fmt.Errorf("unknown placeholder {%s} in %q", placeholder, template)
+		}
+		if value(vars) == "" {
+			return "", fmt.Errorf("placeholder {%s} in %q has no value", placeholder, template)
+		}
+		name.WriteString(value(vars))
+
+		rest = rest[open+1+length+1:]
+	}
+
+	if errs := validation.IsDNS1123Subdomain(name.String()); len(errs) > 0 {
+		return "", fmt.Errorf("%q generates invalid backup name %q: %s", template, name.String(), strings.Join(errs, "; "))
+	}
+	return name.String(), nil
+}
+
+// Validate returns an error if template is malformed, or if it generates an invalid name for
+// the schedule called schedule in the cluster called cluster.
+func Validate(template, schedule, cluster string) error {
+	_, err := Render(template, Vars{Schedule: schedule, Cluster: cluster, Time: time.Now()})
+	return err
+}
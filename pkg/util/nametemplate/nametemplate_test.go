@@ -0,0 +1,100 @@
+/*
+Copyright 2017 Heptio Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nametemplate
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRender(t *testing.T) {
+	vars := Vars{
+		Schedule: "nightly",
+		Cluster:  "prod-1",
+		Time:     time.Date(2017, 9, 3, 4, 5, 6, 0, time.UTC),
+	}
+
+	tests := []struct {
+		name          string
+		template      string
+		vars          Vars
+		expected      string
+		expectedError string
+	}{
+		{
+			name:     "the default template generates the schedule's name and a timestamp",
+			template: Default,
+			expected: "nightly-20170903040506",
+		},
+		{
+			name:     "all placeholders are replaced",
+			template: "{cluster}.{schedule}.{date}.{time}.{year}-{month}-{day}-{hour}-{minute}-{second}.{weekday}",
+			expected: "prod-1.nightly.20170903.040506.2017-09-03-04-05-06.sunday",
+		},
+		{
+			name:     "templates without placeholders are used as they are",
+			template: "backup",
+			expected: "backup",
+		},
+		{
+			name:          "unknown placeholders are an error",
+			template:      "{schedule}-{zone}",
+			expectedError: `unknown placeholder {zone} in "{schedule}-{zone}"`,
+		},
+		{
+			name:          "unclosed placeholders are an error",
+			template:      "{schedule-{date}",
+			expectedError: `unclosed '{' in "{schedule-{date}"`,
+		},
+		{
+			name:          "unopened placeholders are an error",
+			template:      "schedule}-{date}",
+			expectedError: `unexpected '}' in "schedule}-{date}"`,
+		},
+		{
+			name:          "placeholders without values are an error",
+			template:      "{cluster}-{date}",
+			vars:          Vars{Schedule: "nightly", Time: vars.Time},
+			expectedError: `placeholder {cluster} in "{cluster}-{date}" has no value`,
+		},
+		{
+			name:          "templates generating invalid names are an error",
+			template:      "{schedule}_{date}",
+			expectedError: `"{schedule}_{date}" generates invalid backup name "nightly_20170903": a DNS-1123 subdomain must consist of lower case alphanumeric characters, '-' or '.', and must start and end with an alphanumeric character (e.g. 'example.com', regex used for validation is '[a-z0-9]([-a-z0-9]*[a-z0-9])?(\.[a-z0-9]([-a-z0-9]*[a-z0-9])?)*')`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			testVars := test.vars
+			if testVars == (Vars{}) {
+				testVars = vars
+			}
+
+			name, err := Render(test.template, testVars)
+			if test.expectedError != "" {
+				assert.EqualError(t, err, test.expectedError)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected, name)
+		})
+	}
+}
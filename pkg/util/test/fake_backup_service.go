@@ -18,6 +18,7 @@ package test
 
 import (
 	"io"
+	"time"
 
 	"github.com/stretchr/testify/mock"
 
@@ -41,8 +42,46 @@ func (f *FakeBackupService) GetAllBackups(bucket string) ([]*v1.Backup, error) {
 	return backups, args.Error(1)
 }
 
-func (f *FakeBackupService) UploadBackup(bucket, name string, metadata, backup io.ReadSeeker) error {
-	args := f.Called(bucket, name, metadata, backup)
+func (f *FakeBackupService) GetBackup(bucket, name string) (*v1.Backup, error) {
+	args := f.Called(bucket, name)
+
+	var backup *v1.Backup
+
+	b := args.Get(0)
+	if b != nil {
+		backup = b.(*v1.Backup)
+	}
+
+	return backup, args.Error(1)
+}
+
+func (f *FakeBackupService) UploadBackup(bucket, name string, metadata, backup, log io.ReadSeeker) error {
+	args := f.Called(bucket, name, metadata, backup, log)
+	return args.Error(0)
+}
+
+func (f *FakeBackupService) UploadBackupLog(bucket, name string, log io.ReadSeeker) error {
+	args := f.Called(bucket, name, log)
+	return args.Error(0)
+}
+
+func (f *FakeBackupService) UploadBackupItemList(bucket, name string, items io.ReadSeeker) error {
+	args := f.Called(bucket, name, items)
+	return args.Error(0)
+}
+
+func (f *FakeBackupService) UploadRestorePlan(bucket, backupName, restoreName string, plan io.ReadSeeker) error {
+	args := f.Called(bucket, backupName, restoreName, plan)
+	return args.Error(0)
+}
+
+func (f *FakeBackupService) UploadRestoreLog(bucket, backupName, restoreName string, log io.ReadSeeker) error {
+	args := f.Called(bucket, backupName, restoreName, log)
+	return args.Error(0)
+}
+
+func (f *FakeBackupService) UploadRestoreResults(bucket, backupName, restoreName string, results io.ReadSeeker) error {
+	args := f.Called(bucket, backupName, restoreName, results)
 	return args.Error(0)
 }
 
@@ -51,7 +90,17 @@ func (f *FakeBackupService) DownloadBackup(bucket, name string) (io.ReadCloser,
 	return args.Get(0).(io.ReadCloser), args.Error(1)
 }
 
+func (f *FakeBackupService) DownloadBackupLogs(bucket, name string) (io.ReadCloser, error) {
+	args := f.Called(bucket, name)
+	return args.Get(0).(io.ReadCloser), args.Error(1)
+}
+
 func (f *FakeBackupService) DeleteBackup(bucket, backupName string) error {
 	args := f.Called(bucket, backupName)
 	return args.Error(0)
 }
+
+func (f *FakeBackupService) CreateSignedURL(target v1.DownloadTarget, bucket, backupName string, ttl time.Duration) (string, error) {
+	args := f.Called(target, bucket, backupName, ttl)
+	return args.String(0), args.Error(1)
+}
@@ -0,0 +1,104 @@
+/*
+Copyright 2017 Heptio Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	core "k8s.io/client-go/testing"
+
+	api "github.com/heptio/ark/pkg/apis/ark/v1"
+	"github.com/heptio/ark/pkg/generated/clientset/fake"
+)
+
+// FakeDownloadServer plays the part of the Ark server and object storage for DownloadRequests
+// created with a fake clientset: requests for the targets in its files are processed as soon as
+// they're created, with download URLs that it serves the files from.
+type FakeDownloadServer struct {
+	*httptest.Server
+
+	lock  sync.Mutex
+	files map[api.DownloadTarget][]byte
+	// Requests are the DownloadRequests that have been created, in order.
+	Requests []*api.DownloadRequest
+}
+
+// NewFakeDownloadServer returns a FakeDownloadServer for the DownloadRequests created with client.
+// A target whose file is nil is processed, but its URL returns 404 Not Found. The server must be
+// closed when it's no longer needed.
+func NewFakeDownloadServer(client *fake.Clientset, files map[api.DownloadTarget][]byte) *FakeDownloadServer {
+	s := &FakeDownloadServer{files: files}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serve))
+
+	client.PrependReactor("create", "downloadrequests", func(action core.Action) (bool, runtime.Object, error) {
+		s.lock.Lock()
+		defer s.lock.Unlock()
+
+		req := action.(core.CreateAction).GetObject().(*api.DownloadRequest)
+		s.Requests = append(s.Requests, req)
+		return false, nil, nil
+	})
+
+	client.PrependReactor("get", "downloadrequests", func(action core.Action) (bool, runtime.Object, error) {
+		req := s.request(action.GetNamespace(), action.(core.GetAction).GetName())
+		if req == nil {
+			return false, nil, nil
+		}
+		if _, found := s.files[req.Spec.Target]; !found {
+			return false, nil, nil
+		}
+
+		processed := *req
+		processed.Status.Phase = api.DownloadRequestPhaseProcessed
+		processed.Status.DownloadURL = s.URL + "/" + req.Namespace + "/" + req.Name
+		return true, &processed, nil
+	})
+
+	return s
+}
+
+func (s *FakeDownloadServer) request(namespace, name string) *api.DownloadRequest {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	for _, req := range s.Requests {
+		if req.Namespace == namespace && req.Name == name {
+			return req
+		}
+	}
+	return nil
+}
+
+func (s *FakeDownloadServer) serve(w http.ResponseWriter, r *http.Request) {
+	parts := strings.SplitN(strings.TrimPrefix(r.URL.Path, "/"), "/", 2)
+	if len(parts) != 2 {
+		http.NotFound(w, r)
+		return
+	}
+
+	req := s.request(parts[0], parts[1])
+	if req == nil || s.files[req.Spec.Target] == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	w.Write(s.files[req.Spec.Target])
+}
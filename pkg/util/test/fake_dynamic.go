@@ -23,6 +23,7 @@ import (
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/watch"
 
 	"github.com/heptio/ark/pkg/client"
@@ -55,12 +56,32 @@ func (c *FakeDynamicClient) List(options metav1.ListOptions) (runtime.Object, er
 	return args.Get(0).(runtime.Object), args.Error(1)
 }
 
+func (c *FakeDynamicClient) ListPage(options metav1.ListOptions, limit int64, continueToken string) (runtime.Object, string, error) {
+	args := c.Called(options, limit, continueToken)
+	return args.Get(0).(runtime.Object), args.String(1), args.Error(2)
+}
+
 func (c *FakeDynamicClient) Create(obj *unstructured.Unstructured) (*unstructured.Unstructured, error) {
 	args := c.Called(obj)
 	return args.Get(0).(*unstructured.Unstructured), args.Error(1)
 }
 
+func (c *FakeDynamicClient) Get(name string, opts metav1.GetOptions) (*unstructured.Unstructured, error) {
+	args := c.Called(name, opts)
+	return args.Get(0).(*unstructured.Unstructured), args.Error(1)
+}
+
 func (c *FakeDynamicClient) Watch(options metav1.ListOptions) (watch.Interface, error) {
 	args := c.Called(options)
 	return args.Get(0).(watch.Interface), args.Error(1)
 }
+
+func (c *FakeDynamicClient) Patch(name string, pt types.PatchType, data []byte) (*unstructured.Unstructured, error) {
+	args := c.Called(name, pt, data)
+	return args.Get(0).(*unstructured.Unstructured), args.Error(1)
+}
+
+func (c *FakeDynamicClient) Delete(name string, opts *metav1.DeleteOptions) error {
+	args := c.Called(name, opts)
+	return args.Error(0)
+}
@@ -0,0 +1,56 @@
+/*
+Copyright 2017 Heptio Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package test
+
+import (
+	"github.com/spf13/pflag"
+
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/heptio/ark/pkg/generated/clientset"
+)
+
+// FakeFactory is a client.Factory that returns the clients it was created with.
+type FakeFactory struct {
+	arkClient  clientset.Interface
+	kubeClient kubernetes.Interface
+	namespace  string
+}
+
+// NewFakeFactory returns a FakeFactory for the Ark server installed in namespace. Either client
+// may be nil if the code under test doesn't use it.
+func NewFakeFactory(arkClient clientset.Interface, kubeClient kubernetes.Interface, namespace string) *FakeFactory {
+	return &FakeFactory{
+		arkClient:  arkClient,
+		kubeClient: kubeClient,
+		namespace:  namespace,
+	}
+}
+
+func (f *FakeFactory) BindFlags(flags *pflag.FlagSet) {}
+
+func (f *FakeFactory) Client() (clientset.Interface, error) {
+	return f.arkClient, nil
+}
+
+func (f *FakeFactory) KubeClient() (kubernetes.Interface, error) {
+	return f.kubeClient, nil
+}
+
+func (f *FakeFactory) Namespace() string {
+	return f.namespace
+}
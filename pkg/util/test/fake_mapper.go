@@ -27,6 +27,8 @@ type FakeMapper struct {
 	meta.RESTMapper
 	AutoReturnResource bool
 	Resources          map[schema.GroupVersionResource]schema.GroupVersionResource
+	// KindVersions maps group kinds to the versions they're served at, preferred version first.
+	KindVersions map[schema.GroupKind][]string
 }
 
 func (m *FakeMapper) ResourceFor(input schema.GroupVersionResource) (schema.GroupVersionResource, error) {
@@ -47,3 +49,36 @@ func (m *FakeMapper) ResourceFor(input schema.GroupVersionResource) (schema.Grou
 
 	return schema.GroupVersionResource{}, errors.New("invalid resource")
 }
+
+func (m *FakeMapper) RESTMapping(gk schema.GroupKind, versions ...string) (*meta.RESTMapping, error) {
+	served := m.KindVersions[gk]
+	if len(served) == 0 {
+		return nil, errors.New("invalid kind")
+	}
+
+	if len(versions) == 0 {
+		return &meta.RESTMapping{GroupVersionKind: gk.WithVersion(served[0])}, nil
+	}
+
+	for _, version := range served {
+		if version == versions[0] {
+			return &meta.RESTMapping{GroupVersionKind: gk.WithVersion(version)}, nil
+		}
+	}
+
+	return nil, errors.New("invalid version")
+}
+
+func (m *FakeMapper) RESTMappings(gk schema.GroupKind, versions ...string) ([]*meta.RESTMapping, error) {
+	served := m.KindVersions[gk]
+	if len(served) == 0 {
+		return nil, errors.New("invalid kind")
+	}
+
+	var mappings []*meta.RESTMapping
+	for _, version := range served {
+		mappings = append(mappings, &meta.RESTMapping{GroupVersionKind: gk.WithVersion(version)})
+	}
+
+	return mappings, nil
+}
@@ -33,6 +33,9 @@ type FakeSnapshotService struct {
 
 	// VolumeBackupInfo -> VolumeID
 	RestorableVolumes map[api.VolumeBackupInfo]string
+
+	// DeleteSnapshotError, if set, is returned by DeleteSnapshot.
+	DeleteSnapshotError error
 }
 
 func (s *FakeSnapshotService) GetAllSnapshots() ([]string, error) {
@@ -63,10 +66,11 @@ func (s *FakeSnapshotService) CreateVolumeFromSnapshot(snapshotID, volumeType st
 }
 
 func (s *FakeSnapshotService) DeleteSnapshot(snapshotID string) error {
-	if !s.SnapshotsTaken.Has(snapshotID) {
-		return errors.New("snapshot not found")
+	if s.DeleteSnapshotError != nil {
+		return s.DeleteSnapshotError
 	}
 
+	// like the real services, deleting a snapshot that doesn't exist succeeds
 	s.SnapshotsTaken.Delete(snapshotID)
 
 	return nil
@@ -57,6 +57,20 @@ func (b *TestBackup) WithLabel(key, value string) *TestBackup {
 	return b
 }
 
+func (b *TestBackup) WithAnnotation(key, value string) *TestBackup {
+	if b.Annotations == nil {
+		b.Annotations = make(map[string]string)
+	}
+	b.Annotations[key] = value
+
+	return b
+}
+
+func (b *TestBackup) WithOwnerReference(ref metav1.OwnerReference) *TestBackup {
+	b.OwnerReferences = append(b.OwnerReferences, ref)
+	return b
+}
+
 func (b *TestBackup) WithPhase(phase v1.BackupPhase) *TestBackup {
 	b.Status.Phase = phase
 	return b
@@ -87,6 +101,16 @@ func (b *TestBackup) WithTTL(ttl time.Duration) *TestBackup {
 	return b
 }
 
+func (b *TestBackup) WithParentBackup(name string) *TestBackup {
+	b.Spec.ParentBackup = name
+	return b
+}
+
+func (b *TestBackup) WithCompletionTimestamp(t time.Time) *TestBackup {
+	b.Status.CompletionTimestamp = metav1.Time{Time: t}
+	return b
+}
+
 func (b *TestBackup) WithExpiration(expiration time.Time) *TestBackup {
 	b.Status.Expiration = metav1.Time{Time: expiration}
 	return b
@@ -105,12 +129,68 @@ func (b *TestBackup) WithSnapshot(pv string, snapshot string) *TestBackup {
 	return b
 }
 
+func (b *TestBackup) WithCSISnapshot(pv string, driver, snapshotHandle string) *TestBackup {
+	if b.Status.VolumeBackups == nil {
+		b.Status.VolumeBackups = make(map[string]*v1.VolumeBackupInfo)
+	}
+	b.Status.VolumeBackups[pv] = &v1.VolumeBackupInfo{
+		SnapshotID:  snapshotHandle,
+		CSISnapshot: &v1.CSISnapshotInfo{Driver: driver, SnapshotHandle: snapshotHandle},
+	}
+	return b
+}
+
+func (b *TestBackup) WithContentChecksum(checksum string) *TestBackup {
+	b.Status.ContentChecksum = checksum
+	return b
+}
+
 func (b *TestBackup) WithSnapshotVolumes(value bool) *TestBackup {
 	b.Spec.SnapshotVolumes = &value
 	return b
 }
 
+func (b *TestBackup) WithMoveVolumeData(value bool) *TestBackup {
+	b.Spec.MoveVolumeData = value
+	return b
+}
+
+func (b *TestBackup) WithUploaderType(uploaderType string) *TestBackup {
+	b.Spec.UploaderType = uploaderType
+	return b
+}
+
+func (b *TestBackup) WithIgnoreDefaultExcludes(value bool) *TestBackup {
+	b.Spec.IgnoreDefaultExcludes = value
+	return b
+}
+
+func (b *TestBackup) WithExcludeCompletedPods(value bool) *TestBackup {
+	b.Spec.ExcludeCompletedPods = value
+	return b
+}
+
 func (b *TestBackup) WithSnapshotVolumesPointer(value *bool) *TestBackup {
 	b.Spec.SnapshotVolumes = value
 	return b
 }
+
+func (b *TestBackup) WithStorageLocation(location string) *TestBackup {
+	b.Spec.StorageLocation = location
+	return b
+}
+
+func (b *TestBackup) WithVolumeSnapshotLocation(location string) *TestBackup {
+	b.Spec.VolumeSnapshotLocation = location
+	return b
+}
+
+func (b *TestBackup) WithVolumeSnapshotLocations(locations ...string) *TestBackup {
+	b.Spec.VolumeSnapshotLocations = locations
+	return b
+}
+
+func (b *TestBackup) WithStorageBucket(bucket string) *TestBackup {
+	b.Status.StorageBucket = bucket
+	return b
+}
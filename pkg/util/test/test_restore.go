@@ -45,6 +45,24 @@ func NewDefaultTestRestore() *TestRestore {
 	return NewTestRestore(api.DefaultNamespace, "", api.RestorePhase(""))
 }
 
+func (r *TestRestore) WithLabel(key, value string) *TestRestore {
+	if r.Labels == nil {
+		r.Labels = make(map[string]string)
+	}
+	r.Labels[key] = value
+
+	return r
+}
+
+func (r *TestRestore) WithAnnotation(key, value string) *TestRestore {
+	if r.Annotations == nil {
+		r.Annotations = make(map[string]string)
+	}
+	r.Annotations[key] = value
+
+	return r
+}
+
 func (r *TestRestore) WithRestorableNamespace(name string) *TestRestore {
 	r.Spec.Namespaces = append(r.Spec.Namespaces, name)
 	return r
@@ -65,11 +83,21 @@ func (r *TestRestore) WithErrors(e api.RestoreResult) *TestRestore {
 	return r
 }
 
+func (r *TestRestore) WithErrorCounts(c api.RestoreResultCounts) *TestRestore {
+	r.Status.ErrorCounts = c
+	return r
+}
+
 func (r *TestRestore) WithRestorePVs(value bool) *TestRestore {
 	r.Spec.RestorePVs = &value
 	return r
 }
 
+func (r *TestRestore) WithIncludeClusterResources(value bool) *TestRestore {
+	r.Spec.IncludeClusterResources = &value
+	return r
+}
+
 func (r *TestRestore) WithMappedNamespace(from string, to string) *TestRestore {
 	if r.Spec.NamespaceMapping == nil {
 		r.Spec.NamespaceMapping = make(map[string]string)
@@ -77,3 +105,49 @@ func (r *TestRestore) WithMappedNamespace(from string, to string) *TestRestore {
 	r.Spec.NamespaceMapping[from] = to
 	return r
 }
+
+func (r *TestRestore) WithUnsnapshottedVolumePolicy(policy api.UnsnapshottedVolumePolicy) *TestRestore {
+	r.Spec.UnsnapshottedVolumePolicy = policy
+	return r
+}
+
+func (r *TestRestore) WithPreservedClusterIPs(value bool) *TestRestore {
+	r.Spec.PreserveClusterIPs = value
+	return r
+}
+
+func (r *TestRestore) WithPreservedNodePorts(value bool) *TestRestore {
+	r.Spec.PreserveNodePorts = value
+	return r
+}
+
+func (r *TestRestore) WithPreview(value bool) *TestRestore {
+	r.Spec.Preview = value
+	return r
+}
+
+func (r *TestRestore) WithDataOnly(value bool) *TestRestore {
+	r.Spec.DataOnly = value
+	return r
+}
+
+func (r *TestRestore) WithPrune(value bool) *TestRestore {
+	r.Spec.Prune = value
+	return r
+}
+
+func (r *TestRestore) WithMappedStorageClass(from string, to string) *TestRestore {
+	if r.Spec.StorageClassMapping == nil {
+		r.Spec.StorageClassMapping = make(map[string]string)
+	}
+	r.Spec.StorageClassMapping[from] = to
+	return r
+}
+
+func (r *TestRestore) WithMappedNode(from string, to string) *TestRestore {
+	if r.Spec.NodeMapping == nil {
+		r.Spec.NodeMapping = make(map[string]string)
+	}
+	r.Spec.NodeMapping[from] = to
+	return r
+}
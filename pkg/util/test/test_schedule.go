@@ -54,8 +54,57 @@ func (s *TestSchedule) WithCronSchedule(cronExpression string) *TestSchedule {
 	return s
 }
 
+func (s *TestSchedule) WithTimezone(timezone string) *TestSchedule {
+	s.Spec.Timezone = timezone
+	return s
+}
+
+func (s *TestSchedule) WithPaused(value bool) *TestSchedule {
+	s.Spec.Paused = value
+	return s
+}
+
+func (s *TestSchedule) WithBackupNameTemplate(template string) *TestSchedule {
+	s.Spec.BackupNameTemplate = template
+	return s
+}
+
+func (s *TestSchedule) WithConcurrencyPolicy(policy api.ConcurrencyPolicy) *TestSchedule {
+	s.Spec.ConcurrencyPolicy = policy
+	return s
+}
+
+func (s *TestSchedule) WithRetention(policy api.RetentionPolicy) *TestSchedule {
+	s.Spec.Retention = &policy
+	return s
+}
+
+func (s *TestSchedule) WithLastSkippedTime(timeString string) *TestSchedule {
+	t, _ := time.Parse("2006-01-02 15:04:05", timeString)
+	s.Status.LastSkipped = metav1.Time{Time: t}
+	return s
+}
+
 func (s *TestSchedule) WithLastBackupTime(timeString string) *TestSchedule {
 	t, _ := time.Parse("2006-01-02 15:04:05", timeString)
 	s.Status.LastBackup = metav1.Time{Time: t}
 	return s
 }
+
+func (s *TestSchedule) WithLastBackup(name string, phase api.BackupPhase) *TestSchedule {
+	s.Status.LastBackupName = name
+	s.Status.LastBackupPhase = phase
+	return s
+}
+
+func (s *TestSchedule) WithLastBackupCompletionTime(timeString string) *TestSchedule {
+	t, _ := time.Parse("2006-01-02 15:04:05", timeString)
+	s.Status.LastBackupCompletionTimestamp = metav1.Time{Time: t}
+	return s
+}
+
+func (s *TestSchedule) WithNextRunTime(timeString string) *TestSchedule {
+	t, _ := time.Parse("2006-01-02 15:04:05", timeString)
+	s.Status.NextRunTime = metav1.Time{Time: t}
+	return s
+}
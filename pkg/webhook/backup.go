@@ -0,0 +1,184 @@
+/*
+Copyright 2017 Heptio Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/clock"
+
+	api "github.com/heptio/ark/pkg/apis/ark/v1"
+	"github.com/heptio/ark/pkg/discovery"
+	"github.com/heptio/ark/pkg/schedule"
+	"github.com/heptio/ark/pkg/util/collections"
+)
+
+// BackupsPath is the path Backups are validated at.
+const BackupsPath = "/validate/backups"
+
+type backupValidator struct {
+	discoveryHelper discovery.Helper
+	immutable       bool
+	clock           clock.Clock
+}
+
+// NewBackupValidator returns a Validator for Backups, which denies backups with invalid specs. If
+// immutable is true, updates that change the spec or status of a backup that has run to completion
+// are also denied, as is deleting it before it expires.
+func NewBackupValidator(discoveryHelper discovery.Helper, immutable bool) Validator {
+	return &backupValidator{
+		discoveryHelper: discoveryHelper,
+		immutable:       immutable,
+		clock:           &clock.RealClock{},
+	}
+}
+
+func (v *backupValidator) Validate(req *AdmissionRequest) ([]string, error) {
+	if req.Operation == "DELETE" {
+		return v.validateDelete(req)
+	}
+
+	backup, err := decodeBackup(req.Object.Raw)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Operation != "UPDATE" {
+		return validateBackupSpec(v.discoveryHelper, &backup.Spec, ""), nil
+	}
+
+	oldBackup, err := decodeBackup(req.OldObject.Raw)
+	if err != nil {
+		return nil, err
+	}
+
+	var reasons []string
+
+	// only validate changed specs, so that backups whose specs were valid when
+	// they were created can still be updated.
+	if !reflect.DeepEqual(oldBackup.Spec, backup.Spec) {
+		reasons = append(reasons, validateBackupSpec(v.discoveryHelper, &backup.Spec, "")...)
+	}
+
+	if v.immutable && completed(oldBackup) {
+		if !reflect.DeepEqual(oldBackup.Spec, backup.Spec) {
+			reasons = append(reasons, fmt.Sprintf("backup %q is completed and immutable, so its spec can't be changed", backup.Name))
+		}
+		if !reflect.DeepEqual(oldBackup.Status, backup.Status) {
+			reasons = append(reasons, fmt.Sprintf("backup %q is completed and immutable, so its status can't be changed", backup.Name))
+		}
+	}
+
+	return reasons, nil
+}
+
+// validateDelete denies deleting an immutable backup that has run to completion until it expires,
+// which is when the garbage collector deletes it.
+func (v *backupValidator) validateDelete(req *AdmissionRequest) ([]string, error) {
+	if !v.immutable {
+		return nil, nil
+	}
+
+	// API servers older than Kubernetes 1.15 don't send the object being
+	// deleted, so there's no telling whether it's protected.
+	if len(req.OldObject.Raw) == 0 {
+		return nil, fmt.Errorf("can't tell whether backup %s/%s is immutable because the API server didn't send it", req.Namespace, req.Name)
+	}
+
+	backup, err := decodeBackup(req.OldObject.Raw)
+	if err != nil {
+		return nil, err
+	}
+
+	if !completed(backup) {
+		return nil, nil
+	}
+
+	if expiration := schedule.Expiration(backup); !expiration.Before(v.clock.Now()) {
+		return []string{fmt.Sprintf("backup %q is completed and immutable, so it can't be deleted until it expires at %s", backup.Name, expiration.UTC().Format(time.RFC3339))}, nil
+	}
+
+	return nil, nil
+}
+
+// completed returns whether backup has run to completion, after which an immutable backup can't
+// be changed.
+func completed(backup *api.Backup) bool {
+	return backup.Status.Phase == api.BackupPhaseCompleted || backup.Status.Phase == api.BackupPhasePartiallyFailed
+}
+
+func decodeBackup(raw []byte) (*api.Backup, error) {
+	backup := new(api.Backup)
+	if err := json.Unmarshal(raw, backup); err != nil {
+		return nil, fmt.Errorf("error decoding backup: %v", err)
+	}
+	return backup, nil
+}
+
+// validateBackupSpec returns the reasons spec is invalid, if any. The name of each invalid field is
+// prefixed with fieldPrefix.
+func validateBackupSpec(discoveryHelper discovery.Helper, spec *api.BackupSpec, fieldPrefix string) []string {
+	var reasons []string
+
+	if spec.TTL.Duration < 0 {
+		reasons = append(reasons, fmt.Sprintf("%sttl must not be negative, but is %s", fieldPrefix, spec.TTL.Duration))
+	}
+
+	// empty includes lists default to "*" when the backup is processed
+	defaultIncludes := func(includes []string) []string {
+		if len(includes) == 0 {
+			return []string{"*"}
+		}
+		return includes
+	}
+
+	for _, err := range collections.ValidateIncludesExcludes(defaultIncludes(spec.IncludedNamespaces), spec.ExcludedNamespaces) {
+		reasons = append(reasons, fmt.Sprintf("%sincludedNamespaces and %sexcludedNamespaces are invalid: %v", fieldPrefix, fieldPrefix, err))
+	}
+
+	for _, err := range collections.ValidateIncludesExcludes(defaultIncludes(spec.IncludedResources), spec.ExcludedResources) {
+		reasons = append(reasons, fmt.Sprintf("%sincludedResources and %sexcludedResources are invalid: %v", fieldPrefix, fieldPrefix, err))
+	}
+
+	mapper := discoveryHelper.Mapper()
+	for field, resources := range map[string][]string{
+		"includedResources": spec.IncludedResources,
+		"excludedResources": spec.ExcludedResources,
+	} {
+		for _, resource := range resources {
+			if resource == "*" {
+				continue
+			}
+			if _, err := mapper.ResourceFor(schema.ParseGroupResource(resource).WithVersion("")); err != nil {
+				reasons = append(reasons, fmt.Sprintf("%s%s contains %q, which isn't a resource served by the cluster; resources must be given as <RESOURCE> or <RESOURCE>.<GROUP>, e.g. deployments.apps", fieldPrefix, field, resource))
+			}
+		}
+	}
+
+	if spec.LabelSelector != nil {
+		if _, err := metav1.LabelSelectorAsSelector(spec.LabelSelector); err != nil {
+			reasons = append(reasons, fmt.Sprintf("%slabelSelector is invalid: %v", fieldPrefix, err))
+		}
+	}
+
+	return reasons
+}
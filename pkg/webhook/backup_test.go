@@ -0,0 +1,245 @@
+/*
+Copyright 2017 Heptio Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/clock"
+
+	api "github.com/heptio/ark/pkg/apis/ark/v1"
+	. "github.com/heptio/ark/pkg/util/test"
+)
+
+func TestBackupValidator(t *testing.T) {
+	now := time.Date(2017, 3, 8, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name            string
+		immutable       bool
+		operation       string
+		oldBackup       *api.Backup
+		backup          *api.Backup
+		expectedReasons []string
+	}{
+		{
+			name:      "valid backups are allowed",
+			operation: "CREATE",
+			backup: NewTestBackup().WithName("backup-1").
+				WithIncludedNamespaces("ns-1").
+				WithIncludedResources("pods", "deployments.apps").
+				WithExcludedResources("secrets").
+				WithTTL(time.Hour).
+				Backup,
+		},
+		{
+			name:            "backups with negative TTLs are denied",
+			operation:       "CREATE",
+			backup:          NewTestBackup().WithName("backup-1").WithTTL(-time.Hour).Backup,
+			expectedReasons: []string{"ttl must not be negative, but is -1h0m0s"},
+		},
+		{
+			name:            "backups including and excluding the same namespace are denied",
+			operation:       "CREATE",
+			backup:          NewTestBackup().WithName("backup-1").WithIncludedNamespaces("ns-1").WithExcludedNamespaces("ns-1").Backup,
+			expectedReasons: []string{"includedNamespaces and excludedNamespaces are invalid: excludes list cannot contain an item in the includes list: ns-1"},
+		},
+		{
+			name:      "backups with unknown resources are denied",
+			operation: "CREATE",
+			backup:    NewTestBackup().WithName("backup-1").WithIncludedResources("pods", "foos").Backup,
+			expectedReasons: []string{
+				`includedResources contains "foos", which isn't a resource served by the cluster; resources must be given as <RESOURCE> or <RESOURCE>.<GROUP>, e.g. deployments.apps`,
+			},
+		},
+		{
+			name:      "backups with invalid label selectors are denied",
+			operation: "CREATE",
+			backup: func() *api.Backup {
+				backup := NewTestBackup().WithName("backup-1").Backup
+				backup.Spec.LabelSelector = &metav1.LabelSelector{MatchLabels: map[string]string{"foo": "bar baz"}}
+				return backup
+			}(),
+			expectedReasons: []string{
+				`labelSelector is invalid: invalid label value: "bar baz": a valid label must be an empty string or consist of alphanumeric characters, '-', '_' or '.', and must start and end with an alphanumeric character (e.g. 'MyValue',  or 'my_value',  or '12345', regex used for validation is '(([A-Za-z0-9][-A-Za-z0-9_.]*)?[A-Za-z0-9])?')`,
+			},
+		},
+		{
+			name:      "updates that don't change invalid specs are allowed",
+			operation: "UPDATE",
+			oldBackup: NewTestBackup().WithName("backup-1").WithIncludedResources("foos").Backup,
+			backup:    NewTestBackup().WithName("backup-1").WithIncludedResources("foos").WithPhase(api.BackupPhaseInProgress).Backup,
+		},
+		{
+			name:      "spec changes are allowed when backups aren't immutable",
+			operation: "UPDATE",
+			oldBackup: NewTestBackup().WithName("backup-1").WithPhase(api.BackupPhaseCompleted).Backup,
+			backup:    NewTestBackup().WithName("backup-1").WithPhase(api.BackupPhaseCompleted).WithTTL(time.Hour).Backup,
+		},
+		{
+			name:      "creates are allowed when backups are immutable",
+			immutable: true,
+			operation: "CREATE",
+			backup:    NewTestBackup().WithName("backup-1").WithPhase(api.BackupPhaseCompleted).Backup,
+		},
+		{
+			name:      "spec changes to in-progress backups are allowed when backups are immutable",
+			immutable: true,
+			operation: "UPDATE",
+			oldBackup: NewTestBackup().WithName("backup-1").WithPhase(api.BackupPhaseInProgress).Backup,
+			backup:    NewTestBackup().WithName("backup-1").WithPhase(api.BackupPhaseCompleted).WithTTL(time.Hour).Backup,
+		},
+		{
+			name:      "label changes to completed backups are allowed when backups are immutable",
+			immutable: true,
+			operation: "UPDATE",
+			oldBackup: NewTestBackup().WithName("backup-1").WithPhase(api.BackupPhaseCompleted).Backup,
+			backup:    NewTestBackup().WithName("backup-1").WithPhase(api.BackupPhaseCompleted).WithLabel("foo", "bar").Backup,
+		},
+		{
+			name:            "spec changes to completed backups are denied when backups are immutable",
+			immutable:       true,
+			operation:       "UPDATE",
+			oldBackup:       NewTestBackup().WithName("backup-1").WithPhase(api.BackupPhaseCompleted).Backup,
+			backup:          NewTestBackup().WithName("backup-1").WithPhase(api.BackupPhaseCompleted).WithTTL(time.Hour).Backup,
+			expectedReasons: []string{`backup "backup-1" is completed and immutable, so its spec can't be changed`},
+		},
+		{
+			name:            "status changes to partially failed backups are denied when backups are immutable",
+			immutable:       true,
+			operation:       "UPDATE",
+			oldBackup:       NewTestBackup().WithName("backup-1").WithPhase(api.BackupPhasePartiallyFailed).Backup,
+			backup:          NewTestBackup().WithName("backup-1").WithPhase(api.BackupPhaseNew).Backup,
+			expectedReasons: []string{`backup "backup-1" is completed and immutable, so its status can't be changed`},
+		},
+		{
+			name:      "deletes of unexpired completed backups are allowed when backups aren't immutable",
+			operation: "DELETE",
+			oldBackup: NewTestBackup().WithName("backup-1").WithPhase(api.BackupPhaseCompleted).WithExpiration(now.Add(time.Hour)).Backup,
+		},
+		{
+			name:            "deletes of unexpired completed backups are denied when backups are immutable",
+			immutable:       true,
+			operation:       "DELETE",
+			oldBackup:       NewTestBackup().WithName("backup-1").WithPhase(api.BackupPhaseCompleted).WithExpiration(now.Add(time.Hour)).Backup,
+			expectedReasons: []string{`backup "backup-1" is completed and immutable, so it can't be deleted until it expires at 2017-03-08T13:00:00Z`},
+		},
+		{
+			name:      "deletes of expired completed backups are allowed when backups are immutable",
+			immutable: true,
+			operation: "DELETE",
+			oldBackup: NewTestBackup().WithName("backup-1").WithPhase(api.BackupPhaseCompleted).WithExpiration(now.Add(-time.Hour)).Backup,
+		},
+		{
+			name:      "deletes of completed backups that their retention policy expired are allowed when backups are immutable",
+			immutable: true,
+			operation: "DELETE",
+			oldBackup: NewTestBackup().WithName("backup-1").WithPhase(api.BackupPhaseCompleted).
+				WithExpiration(now.Add(time.Hour)).
+				WithAnnotation(api.RetentionExpiredAnnotation, "2017-03-08T00:00:00Z").
+				Backup,
+		},
+		{
+			name:      "deletes of failed backups are allowed when backups are immutable",
+			immutable: true,
+			operation: "DELETE",
+			oldBackup: NewTestBackup().WithName("backup-1").WithPhase(api.BackupPhaseFailed).WithExpiration(now.Add(time.Hour)).Backup,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req := &AdmissionRequest{
+				Operation: test.operation,
+				Object:    rawBackup(t, test.backup),
+				OldObject: rawBackup(t, test.oldBackup),
+			}
+
+			validator := NewBackupValidator(newFakeDiscoveryHelper(), test.immutable).(*backupValidator)
+			validator.clock = clock.NewFakeClock(now)
+
+			reasons, err := validator.Validate(req)
+			require.NoError(t, err)
+			assert.Equal(t, test.expectedReasons, reasons)
+		})
+	}
+}
+
+func TestBackupValidatorFailsDeletesWithoutTheBackup(t *testing.T) {
+	req := &AdmissionRequest{
+		Operation: "DELETE",
+		Namespace: "heptio-ark",
+		Name:      "backup-1",
+	}
+
+	_, err := NewBackupValidator(newFakeDiscoveryHelper(), true).Validate(req)
+	assert.EqualError(t, err, "can't tell whether backup heptio-ark/backup-1 is immutable because the API server didn't send it")
+
+	reasons, err := NewBackupValidator(newFakeDiscoveryHelper(), false).Validate(req)
+	assert.NoError(t, err)
+	assert.Empty(t, reasons)
+}
+
+func rawBackup(t *testing.T, backup *api.Backup) runtime.RawExtension {
+	if backup == nil {
+		return runtime.RawExtension{}
+	}
+
+	raw, err := json.Marshal(backup)
+	require.NoError(t, err)
+
+	return runtime.RawExtension{Raw: raw}
+}
+
+type fakeDiscoveryHelper struct {
+	mapper meta.RESTMapper
+}
+
+// newFakeDiscoveryHelper returns a discovery helper that serves pods, secrets, and deployments.
+func newFakeDiscoveryHelper() *fakeDiscoveryHelper {
+	resources := make(map[schema.GroupVersionResource]schema.GroupVersionResource)
+	for _, gr := range []schema.GroupResource{
+		{Resource: "pods"},
+		{Resource: "secrets"},
+		{Group: "apps", Resource: "deployments"},
+	} {
+		resources[gr.WithVersion("")] = gr.WithVersion("v1")
+	}
+
+	return &fakeDiscoveryHelper{mapper: &FakeMapper{Resources: resources}}
+}
+
+func (dh *fakeDiscoveryHelper) Mapper() meta.RESTMapper {
+	return dh.mapper
+}
+
+func (dh *fakeDiscoveryHelper) Resources() []*metav1.APIResourceList {
+	return nil
+}
+
+func (dh *fakeDiscoveryHelper) Refresh() error {
+	return nil
+}
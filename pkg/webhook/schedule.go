@@ -0,0 +1,135 @@
+/*
+Copyright 2017 Heptio Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/robfig/cron"
+
+	api "github.com/heptio/ark/pkg/apis/ark/v1"
+	"github.com/heptio/ark/pkg/discovery"
+	"github.com/heptio/ark/pkg/util/nametemplate"
+)
+
+// SchedulesPath is the path Schedules are validated at.
+const SchedulesPath = "/validate/schedules"
+
+type scheduleValidator struct {
+	discoveryHelper discovery.Helper
+}
+
+// NewScheduleValidator returns a Validator for Schedules, which denies schedules with invalid cron
+// expressions, time zones, backup name templates, or backup templates. Updates are only validated
+// if they change the spec, so a schedule's status can always be updated.
+func NewScheduleValidator(discoveryHelper discovery.Helper) Validator {
+	return &scheduleValidator{discoveryHelper: discoveryHelper}
+}
+
+func (v *scheduleValidator) Validate(req *AdmissionRequest) ([]string, error) {
+	schedule, err := decodeSchedule(req.Object.Raw)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Operation == "UPDATE" {
+		oldSchedule, err := decodeSchedule(req.OldObject.Raw)
+		if err != nil {
+			return nil, err
+		}
+
+		// only validate changed specs, so that the schedule controller can still
+		// update the status of schedules whose specs were valid when they were
+		// created.
+		if reflect.DeepEqual(oldSchedule.Spec, schedule.Spec) {
+			return nil, nil
+		}
+	}
+
+	return v.validateSpec(schedule), nil
+}
+
+func decodeSchedule(raw []byte) (*api.Schedule, error) {
+	schedule := new(api.Schedule)
+	if err := json.Unmarshal(raw, schedule); err != nil {
+		return nil, fmt.Errorf("error decoding schedule: %v", err)
+	}
+	return schedule, nil
+}
+
+// validateSpec returns the reasons schedule's spec is invalid, if any.
+func (v *scheduleValidator) validateSpec(schedule *api.Schedule) []string {
+	var reasons []string
+
+	if schedule.Spec.Schedule == "" {
+		reasons = append(reasons, "schedule must be a non-empty cron expression, e.g. \"0 1 * * *\"")
+	} else if err := validateCronSchedule(schedule.Spec.Schedule); err != nil {
+		reasons = append(reasons, fmt.Sprintf("schedule %q is not a valid cron expression: %v", schedule.Spec.Schedule, err))
+	}
+
+	if schedule.Spec.Timezone != "" {
+		if _, err := time.LoadLocation(schedule.Spec.Timezone); err != nil {
+			reasons = append(reasons, fmt.Sprintf("timezone %q is not a valid time zone: %v", schedule.Spec.Timezone, err))
+		}
+	}
+
+	// the server's cluster name isn't known here, so any name stands in for it
+	if schedule.Spec.BackupNameTemplate != "" {
+		if err := nametemplate.Validate(schedule.Spec.BackupNameTemplate, schedule.Name, "cluster"); err != nil {
+			reasons = append(reasons, fmt.Sprintf("backupNameTemplate is invalid: %v", err))
+		}
+	}
+
+	switch schedule.Spec.ConcurrencyPolicy {
+	case "", api.ConcurrencyPolicyAllow, api.ConcurrencyPolicyForbid, api.ConcurrencyPolicyReplace:
+	default:
+		reasons = append(reasons, fmt.Sprintf("concurrencyPolicy %q must be Allow, Forbid, or Replace", schedule.Spec.ConcurrencyPolicy))
+	}
+
+	if schedule.Spec.Jitter.Duration < 0 {
+		reasons = append(reasons, fmt.Sprintf("jitter must not be negative, but is %s", schedule.Spec.Jitter.Duration))
+	}
+
+	if retention := schedule.Spec.Retention; retention != nil {
+		if retention.Last < 0 || retention.Daily < 0 || retention.Weekly < 0 || retention.Monthly < 0 || retention.Yearly < 0 {
+			reasons = append(reasons, "retention counts must not be negative")
+		}
+		if retention.Last <= 0 && retention.Daily <= 0 && retention.Weekly <= 0 && retention.Monthly <= 0 && retention.Yearly <= 0 {
+			reasons = append(reasons, "retention must keep at least one backup; omit it to use the backups' TTL")
+		}
+	}
+
+	reasons = append(reasons, validateBackupSpec(v.discoveryHelper, &schedule.Spec.Template, "template.")...)
+
+	return reasons
+}
+
+// validateCronSchedule returns an error if schedule isn't a valid standard cron expression.
+func validateCronSchedule(schedule string) (err error) {
+	// cron.ParseStandard can panic on malformed expressions
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%v", r)
+		}
+	}()
+
+	_, err = cron.ParseStandard(schedule)
+	return err
+}
@@ -0,0 +1,166 @@
+/*
+Copyright 2017 Heptio Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"k8s.io/apimachinery/pkg/runtime"
+
+	api "github.com/heptio/ark/pkg/apis/ark/v1"
+	. "github.com/heptio/ark/pkg/util/test"
+)
+
+func TestScheduleValidator(t *testing.T) {
+	tests := []struct {
+		name            string
+		schedule        *api.Schedule
+		expectedReasons []string
+	}{
+		{
+			name:     "valid schedules are allowed",
+			schedule: NewTestSchedule("ns", "name").WithCronSchedule("0 1 * * *").Schedule,
+		},
+		{
+			name:            "schedules without cron expressions are denied",
+			schedule:        NewTestSchedule("ns", "name").Schedule,
+			expectedReasons: []string{`schedule must be a non-empty cron expression, e.g. "0 1 * * *"`},
+		},
+		{
+			name:            "schedules with invalid cron expressions are denied",
+			schedule:        NewTestSchedule("ns", "name").WithCronSchedule("0 1 * *").Schedule,
+			expectedReasons: []string{`schedule "0 1 * *" is not a valid cron expression: Expected exactly 5 fields, found 4: 0 1 * *`},
+		},
+		{
+			name:            "schedules with invalid time zones are denied",
+			schedule:        NewTestSchedule("ns", "name").WithCronSchedule("0 1 * * *").WithTimezone("Mars/Olympus_Mons").Schedule,
+			expectedReasons: []string{`timezone "Mars/Olympus_Mons" is not a valid time zone: unknown time zone Mars/Olympus_Mons`},
+		},
+		{
+			name:            "schedules with invalid backup name templates are denied",
+			schedule:        NewTestSchedule("ns", "name").WithCronSchedule("0 1 * * *").WithBackupNameTemplate("{schedule}-{zone}").Schedule,
+			expectedReasons: []string{`backupNameTemplate is invalid: unknown placeholder {zone} in "{schedule}-{zone}"`},
+		},
+		{
+			name:            "schedules with invalid concurrency policies are denied",
+			schedule:        NewTestSchedule("ns", "name").WithCronSchedule("0 1 * * *").WithConcurrencyPolicy("Queue").Schedule,
+			expectedReasons: []string{`concurrencyPolicy "Queue" must be Allow, Forbid, or Replace`},
+		},
+		{
+			name: "schedules with negative jitter are denied",
+			schedule: func() *api.Schedule {
+				schedule := NewTestSchedule("ns", "name").WithCronSchedule("0 1 * * *").Schedule
+				schedule.Spec.Jitter.Duration = -time.Minute
+				return schedule
+			}(),
+			expectedReasons: []string{"jitter must not be negative, but is -1m0s"},
+		},
+		{
+			name: "schedules with negative retention counts are denied",
+			schedule: func() *api.Schedule {
+				schedule := NewTestSchedule("ns", "name").WithCronSchedule("0 1 * * *").Schedule
+				schedule.Spec.Retention = &api.RetentionPolicy{Daily: 7, Weekly: -1}
+				return schedule
+			}(),
+			expectedReasons: []string{"retention counts must not be negative"},
+		},
+		{
+			name:            "schedules with retention policies that keep nothing are denied",
+			schedule:        NewTestSchedule("ns", "name").WithCronSchedule("0 1 * * *").WithRetention(api.RetentionPolicy{}).Schedule,
+			expectedReasons: []string{"retention must keep at least one backup; omit it to use the backups' TTL"},
+		},
+		{
+			name: "schedules with invalid templates are denied",
+			schedule: func() *api.Schedule {
+				schedule := NewTestSchedule("ns", "name").WithCronSchedule("0 1 * * *").Schedule
+				schedule.Spec.Template.TTL.Duration = -time.Hour
+				schedule.Spec.Template.ExcludedResources = []string{"foos"}
+				return schedule
+			}(),
+			expectedReasons: []string{
+				"template.ttl must not be negative, but is -1h0m0s",
+				`template.excludedResources contains "foos", which isn't a resource served by the cluster; resources must be given as <RESOURCE> or <RESOURCE>.<GROUP>, e.g. deployments.apps`,
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			raw, err := json.Marshal(test.schedule)
+			require.NoError(t, err)
+
+			req := &AdmissionRequest{
+				Operation: "CREATE",
+				Object:    runtime.RawExtension{Raw: raw},
+			}
+
+			reasons, err := NewScheduleValidator(newFakeDiscoveryHelper()).Validate(req)
+			require.NoError(t, err)
+			assert.Equal(t, test.expectedReasons, reasons)
+		})
+	}
+}
+
+func TestScheduleValidatorUpdates(t *testing.T) {
+	tests := []struct {
+		name            string
+		oldSchedule     *api.Schedule
+		schedule        *api.Schedule
+		expectedReasons []string
+	}{
+		{
+			name:        "status updates to schedules with invalid specs are allowed",
+			oldSchedule: NewTestSchedule("ns", "name").WithCronSchedule("not a schedule").Schedule,
+			schedule:    NewTestSchedule("ns", "name").WithCronSchedule("not a schedule").WithPhase(api.SchedulePhaseEnabled).WithLastBackupTime("2017-01-01 12:00:00").Schedule,
+		},
+		{
+			name:            "spec updates are validated",
+			oldSchedule:     NewTestSchedule("ns", "name").WithCronSchedule("0 1 * * *").Schedule,
+			schedule:        NewTestSchedule("ns", "name").WithCronSchedule("0 1 * * *").WithTimezone("Mars/Olympus_Mons").Schedule,
+			expectedReasons: []string{`timezone "Mars/Olympus_Mons" is not a valid time zone: unknown time zone Mars/Olympus_Mons`},
+		},
+		{
+			name:        "valid spec updates are allowed",
+			oldSchedule: NewTestSchedule("ns", "name").WithCronSchedule("not a schedule").Schedule,
+			schedule:    NewTestSchedule("ns", "name").WithCronSchedule("0 1 * * *").Schedule,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			raw, err := json.Marshal(test.schedule)
+			require.NoError(t, err)
+			oldRaw, err := json.Marshal(test.oldSchedule)
+			require.NoError(t, err)
+
+			req := &AdmissionRequest{
+				Operation: "UPDATE",
+				Object:    runtime.RawExtension{Raw: raw},
+				OldObject: runtime.RawExtension{Raw: oldRaw},
+			}
+
+			reasons, err := NewScheduleValidator(newFakeDiscoveryHelper()).Validate(req)
+			require.NoError(t, err)
+			assert.Equal(t, test.expectedReasons, reasons)
+		})
+	}
+}
@@ -0,0 +1,167 @@
+/*
+Copyright 2017 Heptio Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package webhook implements the Ark server's validating admission webhook, which rejects
+// requests to create or modify Ark API objects that the server wouldn't accept.
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/golang/glog"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// DefaultPort is the port the webhook is served on if none is configured.
+const DefaultPort = 8443
+
+// AdmissionReview is the admission.k8s.io/v1beta1 object that the API server sends to a
+// webhook, and that the webhook sends back with its response filled in.
+type AdmissionReview struct {
+	metav1.TypeMeta `json:",inline"`
+
+	Request  *AdmissionRequest  `json:"request,omitempty"`
+	Response *AdmissionResponse `json:"response,omitempty"`
+}
+
+// AdmissionRequest describes the operation being admitted.
+type AdmissionRequest struct {
+	UID       types.UID                   `json:"uid"`
+	Kind      metav1.GroupVersionKind     `json:"kind"`
+	Resource  metav1.GroupVersionResource `json:"resource"`
+	Namespace string                      `json:"namespace,omitempty"`
+	Name      string                      `json:"name,omitempty"`
+	Operation string                      `json:"operation"`
+
+	// Object is the object being created or updated to.
+	Object runtime.RawExtension `json:"object,omitempty"`
+
+	// OldObject is the existing object, for updates and deletes.
+	OldObject runtime.RawExtension `json:"oldObject,omitempty"`
+}
+
+// AdmissionResponse is the webhook's decision on an AdmissionRequest.
+type AdmissionResponse struct {
+	UID     types.UID      `json:"uid"`
+	Allowed bool           `json:"allowed"`
+	Result  *metav1.Status `json:"status,omitempty"`
+}
+
+// Validator decides whether admission requests for a kind of object are allowed.
+type Validator interface {
+	// Validate returns the reasons req should be denied, if any.
+	Validate(req *AdmissionRequest) ([]string, error)
+}
+
+// Handler returns an http.Handler that answers AdmissionReviews using validator.
+func Handler(validator Validator) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		review := new(AdmissionReview)
+		if err := json.NewDecoder(r.Body).Decode(review); err != nil {
+			http.Error(w, fmt.Sprintf("error decoding admission review: %v", err), http.StatusBadRequest)
+			return
+		}
+		if review.Request == nil {
+			http.Error(w, "admission review has no request", http.StatusBadRequest)
+			return
+		}
+
+		review.Response = review.admit(validator)
+		review.Request = nil
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(review); err != nil {
+			glog.Errorf("error writing admission review response: %v", err)
+		}
+	})
+}
+
+func (review *AdmissionReview) admit(validator Validator) *AdmissionResponse {
+	req := review.Request
+	res := &AdmissionResponse{UID: req.UID}
+
+	reasons, err := validator.Validate(req)
+	if err != nil {
+		glog.Errorf("error validating %s %s/%s: %v", req.Kind.Kind, req.Namespace, req.Name, err)
+		res.Result = &metav1.Status{
+			Status:  metav1.StatusFailure,
+			Message: err.Error(),
+			Reason:  metav1.StatusReasonInternalError,
+			Code:    http.StatusInternalServerError,
+		}
+		return res
+	}
+
+	if len(reasons) > 0 {
+		res.Result = &metav1.Status{
+			Status:  metav1.StatusFailure,
+			Message: strings.Join(reasons, "; "),
+			Reason:  metav1.StatusReasonInvalid,
+			Code:    http.StatusUnprocessableEntity,
+		}
+		return res
+	}
+
+	res.Allowed = true
+	return res
+}
+
+// Server serves validators over HTTPS.
+type Server struct {
+	server   *http.Server
+	certFile string
+	keyFile  string
+}
+
+// NewServer returns a Server that listens on port and serves each validator at its path.
+func NewServer(port int, certFile, keyFile string, validators map[string]Validator) *Server {
+	mux := http.NewServeMux()
+	for path, validator := range validators {
+		mux.Handle(path, Handler(validator))
+	}
+
+	return &Server{
+		server: &http.Server{
+			Addr:    fmt.Sprintf(":%d", port),
+			Handler: mux,
+		},
+		certFile: certFile,
+		keyFile:  keyFile,
+	}
+}
+
+// Run serves the webhook until ctx is done.
+func (s *Server) Run(ctx context.Context) error {
+	go func() {
+		<-ctx.Done()
+		if err := s.server.Shutdown(context.Background()); err != nil {
+			glog.Errorf("error shutting down admission webhook: %v", err)
+		}
+	}()
+
+	glog.Infof("Serving admission webhook on %s", s.server.Addr)
+	if err := s.server.ListenAndServeTLS(s.certFile, s.keyFile); err != http.ErrServerClosed {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,102 @@
+/*
+Copyright 2017 Heptio Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeValidator struct {
+	reasons []string
+	err     error
+}
+
+func (v *fakeValidator) Validate(req *AdmissionRequest) ([]string, error) {
+	return v.reasons, v.err
+}
+
+func TestHandler(t *testing.T) {
+	tests := []struct {
+		name            string
+		validator       *fakeValidator
+		expectedAllowed bool
+		expectedMessage string
+		expectedCode    int32
+	}{
+		{
+			name:            "no reasons allows the request",
+			validator:       &fakeValidator{},
+			expectedAllowed: true,
+		},
+		{
+			name:            "reasons deny the request",
+			validator:       &fakeValidator{reasons: []string{"foo", "bar"}},
+			expectedMessage: "foo; bar",
+			expectedCode:    http.StatusUnprocessableEntity,
+		},
+		{
+			name:            "validation error denies the request",
+			validator:       &fakeValidator{err: errors.New("baz")},
+			expectedMessage: "baz",
+			expectedCode:    http.StatusInternalServerError,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			body, err := json.Marshal(&AdmissionReview{
+				Request: &AdmissionRequest{UID: "uid-1", Operation: "CREATE"},
+			})
+			require.NoError(t, err)
+
+			res := httptest.NewRecorder()
+			Handler(test.validator).ServeHTTP(res, httptest.NewRequest("POST", BackupsPath, bytes.NewReader(body)))
+			require.Equal(t, http.StatusOK, res.Code)
+
+			review := new(AdmissionReview)
+			require.NoError(t, json.NewDecoder(res.Body).Decode(review))
+			require.NotNil(t, review.Response)
+
+			assert.Nil(t, review.Request)
+			assert.Equal(t, "uid-1", string(review.Response.UID))
+			assert.Equal(t, test.expectedAllowed, review.Response.Allowed)
+			if test.expectedAllowed {
+				assert.Nil(t, review.Response.Result)
+			} else {
+				require.NotNil(t, review.Response.Result)
+				assert.Equal(t, test.expectedMessage, review.Response.Result.Message)
+				assert.Equal(t, test.expectedCode, review.Response.Result.Code)
+			}
+		})
+	}
+}
+
+func TestHandlerRejectsMalformedReviews(t *testing.T) {
+	for _, body := range []string{"not json", "{}"} {
+		res := httptest.NewRecorder()
+		Handler(&fakeValidator{}).ServeHTTP(res, httptest.NewRequest("POST", BackupsPath, bytes.NewBufferString(body)))
+		assert.Equal(t, http.StatusBadRequest, res.Code, body)
+	}
+}